@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/hongminglow/all-in-be/internal/models"
+	"github.com/hongminglow/all-in-be/internal/models/dto"
+)
+
+func searchUsers(ctx context.Context, c *client, out printer, args []string, stderr io.Writer) error {
+	if len(args) != 1 {
+		fmt.Fprintln(stderr, "usage: adminctl users search QUERY")
+		return errUsage
+	}
+	var page dto.UserSearchResponse
+	if err := c.call(ctx, http.MethodGet, "/admin/users/search?q="+url.QueryEscape(args[0]), nil, &page); err != nil {
+		return err
+	}
+	rows := make([][]string, 0, len(page.Users))
+	for _, u := range page.Users {
+		rows = append(rows, []string{strconv.FormatInt(u.ID, 10), u.Username, u.Email, u.Role, money(u.Balance)})
+	}
+	return out.table(page.Users, []string{"ID", "USERNAME", "EMAIL", "ROLE", "BALANCE"}, rows)
+}
+
+func resetPassword(ctx context.Context, c *client, out printer, args []string, stderr io.Writer) error {
+	if len(args) != 1 {
+		fmt.Fprintln(stderr, "usage: adminctl users reset-password USER_ID")
+		return errUsage
+	}
+	id, err := strconv.ParseInt(args[0], 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid user ID %q", args[0])
+	}
+	var opened models.SecurityCase
+	if err := c.call(ctx, http.MethodPost, fmt.Sprintf("/admin/users/%d/force-password-reset", id), nil, &opened); err != nil {
+		return err
+	}
+	return out.table(opened, []string{"CASE", "USER", "STATUS", "REASON"}, [][]string{
+		{strconv.FormatInt(opened.ID, 10), opened.Username, opened.Status, opened.Reason},
+	})
+}
+
+func adjustBalance(ctx context.Context, c *client, out printer, args []string, stderr io.Writer) error {
+	flags := flag.NewFlagSet("adjust-balance", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	key := flags.String("key", "", "operation key; reuse the printed key to retry without applying twice")
+	if err := flags.Parse(args); err != nil {
+		return errUsage
+	}
+	if flags.NArg() != 3 {
+		fmt.Fprintln(stderr, "usage: adminctl users adjust-balance [-key KEY] USER_ID AMOUNT NOTE")
+		return errUsage
+	}
+	id, err := strconv.ParseInt(flags.Arg(0), 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid user ID %q", flags.Arg(0))
+	}
+	amount, err := strconv.ParseFloat(flags.Arg(1), 64)
+	if err != nil {
+		return fmt.Errorf("invalid amount %q", flags.Arg(1))
+	}
+	if *key == "" {
+		buf := make([]byte, 12)
+		if _, err := rand.Read(buf); err != nil {
+			return err
+		}
+		*key = "adminctl-" + hex.EncodeToString(buf)
+	}
+	fmt.Fprintf(stderr, "operation key: %s\n", *key)
+
+	var entry models.Transaction
+	req := dto.BalanceAdjustmentRequest{Amount: amount, Note: flags.Arg(2), Key: *key}
+	if err := c.call(ctx, http.MethodPost, fmt.Sprintf("/admin/users/%d/balance-adjustments", id), req, &entry); err != nil {
+		return err
+	}
+	return out.table(entry, []string{"ENTRY", "USER", "AMOUNT", "BALANCE"}, [][]string{
+		{strconv.FormatInt(entry.ID, 10), strconv.FormatInt(entry.UserID, 10), money(entry.Amount), money(entry.BalanceAfter)},
+	})
+}
+
+func listFlags(ctx context.Context, c *client, out printer) error {
+	var flags []models.FeatureFlag
+	if err := c.call(ctx, http.MethodGet, "/admin/feature-flags", nil, &flags); err != nil {
+		return err
+	}
+	rows := make([][]string, 0, len(flags))
+	for _, f := range flags {
+		rows = append(rows, flagRow(f))
+	}
+	return out.table(flags, flagHeader, rows)
+}
+
+func setFlag(ctx context.Context, c *client, out printer, args []string, stderr io.Writer) error {
+	if len(args) != 2 || (args[1] != "on" && args[1] != "off") {
+		fmt.Fprintln(stderr, "usage: adminctl flags set NAME on|off")
+		return errUsage
+	}
+	var saved models.FeatureFlag
+	if err := c.call(ctx, http.MethodPut, "/admin/feature-flags/"+url.PathEscape(args[0]), dto.SetFeatureFlagRequest{Enabled: ptr(args[1] == "on")}, &saved); err != nil {
+		return err
+	}
+	return out.table(saved, flagHeader, [][]string{flagRow(saved)})
+}
+
+var flagHeader = []string{"NAME", "ENABLED", "SOURCE", "UPDATED"}
+
+func flagRow(f models.FeatureFlag) []string {
+	updated := "-"
+	if f.UpdatedAt != nil {
+		updated = f.UpdatedAt.Local().Format(time.DateTime)
+	}
+	return []string{f.Name, strconv.FormatBool(f.Enabled), f.Source, updated}
+}
+
+func money(amount float64) string {
+	return strconv.FormatFloat(amount, 'f', 2, 64)
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
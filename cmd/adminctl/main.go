@@ -0,0 +1,92 @@
+// Command adminctl runs everyday operator tasks against the admin API, for
+// operators without an admin UI.
+//
+//	adminctl [-url URL] [-token TOKEN] [-output table|json] COMMAND [ARGS]
+//
+// Commands:
+//
+//	users search QUERY                              find accounts (users:read)
+//	users reset-password USER_ID                    force a password reset (users:lock)
+//	users adjust-balance [-key KEY] USER_ID AMOUNT NOTE
+//	                                                credit or debit a balance (balance:adjust)
+//	flags list                                      show feature flags (config:manage)
+//	flags set NAME on|off                           switch a feature flag (config:manage)
+//
+// -url and -token default to ALLIN_URL and ALLIN_TOKEN. The token is an access
+// token of an account holding the permission each command needs.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+)
+
+// errUsage is returned for malformed command lines; usage has been printed.
+var errUsage = errors.New("usage")
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	if err := run(ctx, os.Args[1:], os.Stdout, os.Stderr, os.Getenv); err != nil {
+		if !errors.Is(err, errUsage) {
+			fmt.Fprintln(os.Stderr, "adminctl:", err)
+		}
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context, args []string, stdout, stderr io.Writer, getenv func(string) string) error {
+	global := flag.NewFlagSet("adminctl", flag.ContinueOnError)
+	global.SetOutput(stderr)
+	baseURL := global.String("url", fallback(getenv("ALLIN_URL"), "http://localhost:8080"), "API base URL")
+	token := global.String("token", getenv("ALLIN_TOKEN"), "access token")
+	output := global.String("output", "table", "output format: table or json")
+	global.Usage = func() {
+		fmt.Fprintln(stderr, "usage: adminctl [flags] users search|reset-password|adjust-balance ... | flags list|set ...")
+		global.PrintDefaults()
+	}
+	if err := global.Parse(args); err != nil {
+		return errUsage
+	}
+	if *output != "table" && *output != "json" {
+		fmt.Fprintln(stderr, "-output must be table or json")
+		return errUsage
+	}
+	if *token == "" {
+		return errors.New("no access token; pass -token or set ALLIN_TOKEN")
+	}
+	c := &client{baseURL: *baseURL, token: *token}
+	out := printer{w: stdout, json: *output == "json"}
+
+	args = global.Args()
+	if len(args) < 2 {
+		global.Usage()
+		return errUsage
+	}
+	switch args[0] + " " + args[1] {
+	case "users search":
+		return searchUsers(ctx, c, out, args[2:], stderr)
+	case "users reset-password":
+		return resetPassword(ctx, c, out, args[2:], stderr)
+	case "users adjust-balance":
+		return adjustBalance(ctx, c, out, args[2:], stderr)
+	case "flags list":
+		return listFlags(ctx, c, out)
+	case "flags set":
+		return setFlag(ctx, c, out, args[2:], stderr)
+	}
+	global.Usage()
+	return errUsage
+}
+
+func fallback(value, def string) string {
+	if value == "" {
+		return def
+	}
+	return value
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCommands(t *testing.T) {
+	var got struct {
+		method, path, auth string
+		body               map[string]any
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got.method, got.path, got.auth = r.Method, r.URL.RequestURI(), r.Header.Get("Authorization")
+		got.body = nil
+		_ = json.NewDecoder(r.Body).Decode(&got.body)
+		switch r.URL.Path {
+		case "/admin/users/search":
+			_, _ = io.WriteString(w, `{"code":200,"message":"ok","data":{"users":[{"id":7,"username":"ana","email":"ana@example.com","role":"player","balance":12.5}]}}`)
+		case "/admin/users/7/balance-adjustments":
+			_, _ = io.WriteString(w, `{"code":200,"message":"balance adjusted","data":{"id":90,"user_id":7,"amount":-2.5,"balance_after":10}}`)
+		case "/admin/feature-flags/beta":
+			_, _ = io.WriteString(w, `{"code":200,"message":"saved","data":{"name":"beta","enabled":false,"source":"admin"}}`)
+		default:
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = io.WriteString(w, `{"code":403,"message":"forbidden"}`)
+		}
+	}))
+	defer srv.Close()
+	env := map[string]string{"ALLIN_URL": srv.URL, "ALLIN_TOKEN": "t0ken"}
+	adminctl := func(args ...string) (string, error) {
+		var stdout bytes.Buffer
+		err := run(context.Background(), args, &stdout, io.Discard, func(k string) string { return env[k] })
+		return stdout.String(), err
+	}
+
+	out, err := adminctl("users", "search", "ana smith")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.auth != "Bearer t0ken" || got.path != "/admin/users/search?q=ana+smith" {
+		t.Fatalf("request = %+v", got)
+	}
+	if lines := strings.Split(strings.TrimSpace(out), "\n"); len(lines) != 2 || strings.Fields(lines[1])[4] != "12.50" {
+		t.Fatalf("table output:\n%s", out)
+	}
+
+	out, err = adminctl("-output", "json", "users", "adjust-balance", "-key", "k1", "7", "-2.5", "goodwill reversal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.method != http.MethodPost || got.body["key"] != "k1" || got.body["amount"] != -2.5 || got.body["note"] != "goodwill reversal" {
+		t.Fatalf("request = %+v", got)
+	}
+	var entry map[string]any
+	if err := json.Unmarshal([]byte(out), &entry); err != nil || entry["balance_after"] != 10.0 {
+		t.Fatalf("json output %q: %v", out, err)
+	}
+
+	if _, err := adminctl("flags", "set", "beta", "off"); err != nil || got.method != http.MethodPut || got.body["enabled"] != false {
+		t.Fatalf("flags set: %+v, %v", got, err)
+	}
+	if _, err := adminctl("flags", "list"); err == nil || !strings.Contains(err.Error(), "403: forbidden") {
+		t.Fatalf("refused request: err = %v", err)
+	}
+	if _, err := adminctl("flags", "set", "beta", "maybe"); !errors.Is(err, errUsage) {
+		t.Fatalf("bad state: err = %v", err)
+	}
+	delete(env, "ALLIN_TOKEN")
+	if _, err := adminctl("flags", "list"); err == nil || !strings.Contains(err.Error(), "token") {
+		t.Fatalf("no token: err = %v", err)
+	}
+}
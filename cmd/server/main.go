@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"log"
 	"net/http"
 	"os"
@@ -9,13 +10,21 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/hongminglow/all-in-be/internal/clock"
 	"github.com/hongminglow/all-in-be/internal/config"
 	"github.com/hongminglow/all-in-be/internal/server"
+	"github.com/hongminglow/all-in-be/internal/storage"
 	postgres "github.com/hongminglow/all-in-be/internal/storage/postgres"
+	"github.com/hongminglow/all-in-be/internal/storage/storagetest"
+	"github.com/hongminglow/all-in-be/internal/tracing"
 	"github.com/joho/godotenv"
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "seed" {
+		runSeed(os.Args[2:])
+		return
+	}
 	loadLocalEnv()
 
 	cfg, err := config.Load()
@@ -23,14 +32,31 @@ func main() {
 		log.Fatalf("load config: %v", err)
 	}
 
+	var tracer *tracing.Tracer
+	if cfg.Tracing.Endpoint != "" {
+		exporter := tracing.NewOTLPExporter(cfg.Tracing.Endpoint, cfg.Tracing.ServiceName, cfg.Tracing.Headers)
+		tracer = tracing.NewTracer(exporter, cfg.Tracing.SampleRatio)
+		tracing.SetTracer(tracer)
+		log.Printf("exporting traces to %s", cfg.Tracing.Endpoint)
+	}
+
 	ctx := context.Background()
-	userStore, err := postgres.NewUserStore(ctx, cfg.DatabaseURL)
+	store, closeStore, err := openStore(ctx, cfg)
 	if err != nil {
 		log.Fatalf("init database: %v", err)
 	}
-	defer userStore.Close()
+	defer closeStore()
 
-	srv := server.New(cfg, userStore)
+	srv, err := server.New(cfg, store)
+	if err != nil {
+		log.Fatalf("init server: %v", err)
+	}
+
+	watcher := config.NewWatcher(cfg)
+	watcher.OnReload(srv.Reload)
+	watchCtx, stopWatching := context.WithCancel(ctx)
+	defer stopWatching()
+	go watcher.Watch(watchCtx)
 
 	go func() {
 		log.Printf("ALL-IN backend listening on %s", cfg.HTTPAddress())
@@ -49,6 +75,38 @@ func main() {
 	if err := srv.Shutdown(ctxShutdown); err != nil {
 		log.Printf("graceful shutdown error: %v", err)
 	}
+	if tracer != nil {
+		if err := tracer.Shutdown(ctxShutdown); err != nil {
+			log.Printf("flush traces error: %v", err)
+		}
+	}
+}
+
+// openStore connects to Postgres, or builds an empty in-memory store when
+// DATABASE_URL is config.MemoryURL.
+func openStore(ctx context.Context, cfg config.Config) (storage.Store, func(), error) {
+	if cfg.DB.InMemory() {
+		log.Println("WARNING: DATABASE_URL=memory: keeps all data in memory; it is lost on shutdown")
+		if cfg.FaultInjection {
+			log.Println("the in-memory store does not inject query faults; only HTTP faults apply")
+		}
+		return storagetest.NewMemoryStore(clock.System{}), func() {}, nil
+	}
+	userStore, err := postgres.NewUserStore(ctx, cfg.DB.URL, cfg.DB.Pool, cfg.PhoneRegion)
+	if err != nil {
+		return nil, nil, err
+	}
+	if cfg.DB.ReadURL != "" {
+		if err := userStore.AttachReadReplica(ctx, cfg.DB.ReadURL, cfg.DB.ReplicaMaxLag); err != nil {
+			userStore.Close()
+			return nil, nil, fmt.Errorf("read replica: %w", err)
+		}
+	}
+	if cfg.FaultInjection {
+		log.Println("WARNING: fault injection is enabled; do not run this configuration in production")
+		userStore.EnableFaultInjection()
+	}
+	return userStore, userStore.Close, nil
 }
 
 func loadLocalEnv() {
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+
+	"github.com/hongminglow/all-in-be/internal/config"
+	"github.com/hongminglow/all-in-be/internal/seed"
+	postgres "github.com/hongminglow/all-in-be/internal/storage/postgres"
+)
+
+// runSeed implements `server seed`, which fills the configured database with
+// fake users and activity for local development.
+func runSeed(args []string) {
+	flags := flag.NewFlagSet("seed", flag.ExitOnError)
+	users := flags.Int("users", 50, "number of players to create")
+	seedValue := flags.Uint64("seed", 1, "random seed; the same seed creates the same data")
+	password := flags.String("password", "password123", "password of every seeded account")
+	transactions := flags.Int("transactions", 30, "most deposits, bets and withdrawals per player")
+	_ = flags.Parse(args)
+
+	loadLocalEnv()
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("load config: %v", err)
+	}
+	if cfg.DB.InMemory() {
+		log.Fatal("seed needs a Postgres DATABASE_URL; an in-memory store is gone when the command exits")
+	}
+	ctx := context.Background()
+	store, err := postgres.NewUserStore(ctx, cfg.DB.URL, cfg.DB.Pool, cfg.PhoneRegion)
+	if err != nil {
+		log.Fatalf("init database: %v", err)
+	}
+	defer store.Close()
+
+	summary, err := seed.Run(ctx, store, seed.Options{
+		Users:        *users,
+		Seed:         *seedValue,
+		Password:     *password,
+		InitBalance:  cfg.InitBalance,
+		Transactions: *transactions,
+	})
+	if err != nil {
+		log.Fatalf("seed: %v", err)
+	}
+	log.Printf("seeded %d accounts (%d already existed), %d transactions and %d bonuses; created %d roles and %d permissions",
+		summary.Users, summary.Skipped, summary.Transactions, summary.Bonuses, summary.Roles, summary.Permissions)
+}
@@ -0,0 +1,467 @@
+// Package accounts opens accounts and signs users in with their password.
+// Sign-in checks the password, the network the request comes from and the
+// device before a token is issued, and every attempt goes into the login
+// history. The HTTP layer only decodes requests and sets the session cookie.
+package accounts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/hongminglow/all-in-be/internal/auth"
+	"github.com/hongminglow/all-in-be/internal/captcha"
+	"github.com/hongminglow/all-in-be/internal/config"
+	"github.com/hongminglow/all-in-be/internal/events"
+	"github.com/hongminglow/all-in-be/internal/iprisk"
+	"github.com/hongminglow/all-in-be/internal/models"
+	"github.com/hongminglow/all-in-be/internal/outbox"
+	"github.com/hongminglow/all-in-be/internal/phone"
+	"github.com/hongminglow/all-in-be/internal/security"
+	"github.com/hongminglow/all-in-be/internal/storage"
+	"github.com/hongminglow/all-in-be/internal/wallet"
+)
+
+var (
+	// ErrMissingFields is returned for a registration without a username,
+	// email or phone number.
+	ErrMissingFields = errors.New("username, email, and phone are required")
+	// ErrPasswordTooShort is returned for passwords under 8 characters or
+	// that are not valid UTF-8.
+	ErrPasswordTooShort = errors.New("password must be at least 8 characters")
+	// ErrPasswordTooLong is returned for passwords bcrypt cannot hash.
+	ErrPasswordTooLong = errors.New("password must be at most 72 bytes")
+	// ErrMissingCredentials is returned for a sign-in without an identifier or password.
+	ErrMissingCredentials = errors.New("identifier and password are required")
+	// ErrInvalidCredentials is returned for an unknown user or a wrong password.
+	ErrInvalidCredentials = errors.New("invalid credentials")
+	// ErrResetRequired is returned when staff forced a password reset or the
+	// user denied a sign-in alert.
+	ErrResetRequired = errors.New("password reset required")
+	// ErrAccountMerged is returned for an account merged into another as a
+	// duplicate.
+	ErrAccountMerged = errors.New("this account was merged into another; sign in with that account")
+	// ErrNetworkBlocked is returned when the IP risk policy blocks the
+	// network a sign-in comes from.
+	ErrNetworkBlocked = errors.New("sign-in from this network is not allowed; turn off any VPN or proxy and try again")
+	// ErrVerificationUnavailable is returned when the network calls for a
+	// device confirmation but no device checker is configured.
+	ErrVerificationUnavailable = errors.New("sign-in from this network requires verification that is not available")
+	// ErrDeviceUnconfirmed is returned until a new device is confirmed by email.
+	ErrDeviceUnconfirmed = errors.New("new device must be confirmed; check your email")
+	// ErrBlankProfileField is returned for a profile edit that clears the
+	// username, email or phone number.
+	ErrBlankProfileField = errors.New("username, email, and phone cannot be empty")
+)
+
+// ScopeError is returned for a requested scope the user does not hold.
+type ScopeError struct {
+	Scope string
+}
+
+func (e *ScopeError) Error() string {
+	return fmt.Sprintf("scope %q is not granted to this user", e.Scope)
+}
+
+// maxPasswordBytes is bcrypt's input limit; longer passwords cannot be hashed.
+const maxPasswordBytes = 72
+
+// BcryptCost is a variable so tests can use a cheaper cost.
+var BcryptCost = bcrypt.DefaultCost
+
+// DeviceChecker vets the device a sign-in comes from before it is accepted.
+type DeviceChecker interface {
+	CheckDevice(ctx context.Context, user models.User, device security.Device) (security.DeviceCheck, error)
+}
+
+// IPScreener decides what to do about a sign-in from the client's network,
+// returning one of the models.IPRisk actions.
+type IPScreener interface {
+	Check(ctx context.Context, req iprisk.Request) string
+}
+
+// UsernameChecker refuses usernames the username policy does not allow, with
+// a *usernames.Violation.
+type UsernameChecker interface {
+	Check(ctx context.Context, username string) error
+}
+
+// EmailChecker refuses email addresses accounts may not be opened with, such
+// as malformed or disposable ones.
+type EmailChecker interface {
+	Check(ctx context.Context, email string) error
+}
+
+// CaptchaGate decides whether sign-ups and sign-ins must solve a CAPTCHA
+// and checks the token, returning one of the captcha errors when they may not
+// go ahead.
+type CaptchaGate interface {
+	CheckSignup(ctx context.Context, token, ip string) error
+	CheckLogin(ctx context.Context, token, ip string, userID *int64) error
+}
+
+// Registration is a new account's details as submitted. IP is the client's
+// address, passed to the CAPTCHA provider.
+type Registration struct {
+	Username     string
+	Email        string
+	Phone        string
+	Password     string
+	CaptchaToken string
+	IP           string
+}
+
+// Credentials identify a user signing in. Scopes, if any, restrict the token.
+type Credentials struct {
+	Identifier   string
+	Password     string
+	Scopes       []string
+	CaptchaToken string
+}
+
+// Session is the outcome of a successful sign-in.
+type Session struct {
+	User  models.User
+	Token string
+	// TTL is how long Token stays valid.
+	TTL       time.Duration
+	Scopes    []string
+	NewDevice bool
+}
+
+// ProfileUpdate is an edit to a user's profile; nil fields are left as they are.
+type ProfileUpdate struct {
+	Username *string
+	Email    *string
+	Phone    *string
+}
+
+// Service registers users, signs them in and edits their profiles.
+type Service struct {
+	store   storage.Store
+	devices DeviceChecker
+	ips     IPScreener
+	names   UsernameChecker
+	emails  EmailChecker
+	captcha CaptchaGate
+	tokens  *auth.TokenManager
+	events  events.Publisher
+	outbox  *outbox.Relay
+	cfg     *config.Config
+}
+
+// NewService builds the service. devices flags new devices, ips screens the
+// networks sign-ins come from, names and emails vet new usernames and email
+// addresses, captcha asks bots for a CAPTCHA, publisher receives
+// events.TypeUserLoggedIn and relay publishes the events.TypeUserRegistered
+// events added to the outbox with new users; all seven may be nil.
+func NewService(store storage.Store, devices DeviceChecker, ips IPScreener, names UsernameChecker, emails EmailChecker, captcha CaptchaGate, tokens *auth.TokenManager, publisher events.Publisher, relay *outbox.Relay, cfg *config.Config) *Service {
+	return &Service{store: store, devices: devices, ips: ips, names: names, emails: emails, captcha: captcha, tokens: tokens, events: publisher, outbox: relay, cfg: cfg}
+}
+
+// Register validates reg and stores a normal user, granting the configured
+// starting balance as a welcome bonus ledger entry and adding
+// events.TypeUserRegistered to the outbox in the same transaction. Validation failures are ErrMissingFields, a captcha
+// error, a username policy violation, an emailcheck error, the password errors
+// or a phone error; storage.ErrAlreadyExists means the username, email
+// or phone is taken.
+func (s *Service) Register(ctx context.Context, reg Registration) (models.User, error) {
+	if strings.TrimSpace(reg.Username) == "" || strings.TrimSpace(reg.Email) == "" || strings.TrimSpace(reg.Phone) == "" {
+		return models.User{}, ErrMissingFields
+	}
+	if s.captcha != nil {
+		if err := s.captcha.CheckSignup(ctx, strings.TrimSpace(reg.CaptchaToken), reg.IP); err != nil {
+			return models.User{}, err
+		}
+	}
+	if err := s.checkUsername(ctx, strings.TrimSpace(reg.Username)); err != nil {
+		return models.User{}, err
+	}
+	if err := s.checkEmail(ctx, strings.TrimSpace(reg.Email)); err != nil {
+		return models.User{}, err
+	}
+	if err := ValidatePassword(reg.Password); err != nil {
+		return models.User{}, err
+	}
+	phoneNumber, err := phone.Normalize(strings.TrimSpace(reg.Phone), s.cfg.PhoneRegion)
+	if err != nil {
+		return models.User{}, err
+	}
+	passwordHash, err := HashPassword(reg.Password)
+	if err != nil {
+		return models.User{}, fmt.Errorf("hash password: %w", err)
+	}
+
+	user := models.User{
+		Username:     strings.TrimSpace(reg.Username),
+		Email:        strings.TrimSpace(reg.Email),
+		Phone:        phoneNumber,
+		Role:         models.NormalUser,
+		PasswordHash: passwordHash,
+		HomeRegion:   s.cfg.Region.Name,
+	}
+	var created models.User
+	err = s.store.WithTx(ctx, func(tx storage.Repositories) error {
+		var err error
+		if created, err = tx.CreateUser(ctx, user); err != nil {
+			return err
+		}
+		event := events.UserRegistered{UserID: created.ID, Username: created.Username, Email: created.Email, Phone: created.Phone}
+		if err := outbox.Add(ctx, tx, events.TypeUserRegistered, event); err != nil {
+			return err
+		}
+		if s.cfg.InitBalance <= 0 {
+			return nil
+		}
+		if created, err = grantWelcomeBonus(ctx, tx, created.ID, s.cfg.InitBalance); err != nil {
+			return fmt.Errorf("grant welcome bonus to user %d: %w", created.ID, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return models.User{}, err
+	}
+	s.outbox.Wake()
+	return created, nil
+}
+
+// grantWelcomeBonus credits a new user's starting balance through the ledger,
+// so it shows in statements and reconciliation like any other movement, and
+// returns the user as it now stands.
+func grantWelcomeBonus(ctx context.Context, tx storage.Repositories, userID int64, amount float64) (models.User, error) {
+	op := wallet.Operation{Kind: models.OperationWelcomeBonus, Key: strconv.FormatInt(userID, 10)}
+	entry := models.Transaction{UserID: userID, Amount: amount, Reason: models.TransactionWelcomeBonus}
+	saved, _, err := wallet.Apply(ctx, tx, op, entry)
+	if err != nil {
+		return models.User{}, err
+	}
+	if err := outbox.Add(ctx, tx, events.TypeBalanceChanged, wallet.BalanceChanged(saved)); err != nil {
+		return models.User{}, err
+	}
+	return tx.FindByID(ctx, userID)
+}
+
+// UpdateProfile applies update to the user if they are still at version, the
+// version the edit was based on. It returns storage.ErrVersionConflict when
+// someone else changed the user first, ErrBlankProfileField, a username
+// policy violation, an emailcheck error or a phone error for invalid values
+// and storage.ErrAlreadyExists when a new value is taken. Only a changed
+// username or email is vetted.
+func (s *Service) UpdateProfile(ctx context.Context, userID, version int64, update ProfileUpdate) (models.User, error) {
+	user, err := s.store.FindByID(ctx, userID)
+	if err != nil {
+		return models.User{}, err
+	}
+	if user.Version != version {
+		return models.User{}, storage.ErrVersionConflict
+	}
+	previous := user
+	for _, field := range []struct {
+		value *string
+		dest  *string
+	}{{update.Username, &user.Username}, {update.Email, &user.Email}, {update.Phone, &user.Phone}} {
+		if field.value == nil {
+			continue
+		}
+		if strings.TrimSpace(*field.value) == "" {
+			return models.User{}, ErrBlankProfileField
+		}
+		*field.dest = strings.TrimSpace(*field.value)
+	}
+	if user.Username != previous.Username {
+		if err := s.checkUsername(ctx, user.Username); err != nil {
+			return models.User{}, err
+		}
+	}
+	if user.Email != previous.Email {
+		if err := s.checkEmail(ctx, user.Email); err != nil {
+			return models.User{}, err
+		}
+	}
+	if update.Phone != nil {
+		if user.Phone, err = phone.Normalize(user.Phone, s.cfg.PhoneRegion); err != nil {
+			return models.User{}, err
+		}
+	}
+	return s.store.UpdateProfile(ctx, user)
+}
+
+func (s *Service) checkUsername(ctx context.Context, username string) error {
+	if s.names == nil {
+		return nil
+	}
+	return s.names.Check(ctx, username)
+}
+
+func (s *Service) checkEmail(ctx context.Context, email string) error {
+	if s.emails == nil {
+		return nil
+	}
+	return s.emails.Check(ctx, email)
+}
+
+// Login checks creds and issues a token for a sign-in from device. Besides
+// the sentinel errors above it returns a *ScopeError for a scope the user
+// lacks, a captcha error after repeated failures and storage.ErrUnavailable
+// when the user cannot be looked up.
+func (s *Service) Login(ctx context.Context, creds Credentials, device security.Device) (Session, error) {
+	identifier := strings.TrimSpace(creds.Identifier)
+	if identifier == "" || strings.TrimSpace(creds.Password) == "" {
+		return Session{}, ErrMissingCredentials
+	}
+	user, err := s.store.FindByUsernameOrEmail(ctx, identifier)
+	found := err == nil
+	if err != nil && !errors.Is(err, storage.ErrNotFound) {
+		return Session{}, fmt.Errorf("fetch user %s: %w", identifier, err)
+	}
+	if s.captcha != nil {
+		// Unknown identifiers are gated on the IP alone, so the CAPTCHA does
+		// not tell which accounts exist.
+		var userID *int64
+		var known *models.User
+		if found {
+			userID, known = &user.ID, &user
+		}
+		if err := s.captcha.CheckLogin(ctx, strings.TrimSpace(creds.CaptchaToken), device.IP, userID); err != nil {
+			if errors.Is(err, captcha.ErrRequired) || errors.Is(err, captcha.ErrInvalid) {
+				s.recordAttempt(ctx, identifier, device, known, models.LoginCaptchaFailed)
+			}
+			return Session{}, err
+		}
+	}
+	if !found {
+		// Log the error even for not found to help debug if it's a join failure
+		log.Printf("login failed: user not found or join failed for identifier %s: %v", identifier, err)
+		s.recordAttempt(ctx, identifier, device, nil, models.LoginUnknownUser)
+		return Session{}, ErrInvalidCredentials
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(creds.Password)); err != nil {
+		s.recordAttempt(ctx, identifier, device, &user, models.LoginInvalidPassword)
+		return Session{}, ErrInvalidCredentials
+	}
+	if user.MergedInto != nil {
+		s.recordAttempt(ctx, identifier, device, &user, models.LoginAccountMerged)
+		return Session{}, ErrAccountMerged
+	}
+	if user.PasswordResetRequired {
+		s.recordAttempt(ctx, identifier, device, &user, models.LoginResetRequired)
+		return Session{}, ErrResetRequired
+	}
+	scopes, err := GrantedScopes(creds.Scopes, user)
+	if err != nil {
+		return Session{}, err
+	}
+	if s.ips != nil {
+		// There is no tenant model yet, so every sign-in uses the default tenant's policies.
+		switch s.ips.Check(ctx, iprisk.Request{Checkpoint: models.CheckpointLogin, IP: device.IP, Country: device.Country, UserID: &user.ID}) {
+		case models.IPRiskBlock:
+			s.recordAttempt(ctx, identifier, device, &user, models.LoginIPBlocked)
+			return Session{}, ErrNetworkBlocked
+		case models.IPRiskStepUp:
+			if s.devices == nil {
+				s.recordAttempt(ctx, identifier, device, &user, models.LoginIPBlocked)
+				return Session{}, ErrVerificationUnavailable
+			}
+			device.StepUp = true
+		}
+	}
+	var check security.DeviceCheck
+	if s.devices != nil {
+		if check, err = s.devices.CheckDevice(ctx, user, device); err != nil {
+			return Session{}, fmt.Errorf("check device for user %d: %w", user.ID, err)
+		}
+		if check.ConfirmationRequired {
+			s.recordAttempt(ctx, identifier, device, &user, models.LoginDeviceUnconfirmed)
+			return Session{}, ErrDeviceUnconfirmed
+		}
+	}
+	token, err := s.tokens.GenerateScoped(user, scopes)
+	if err != nil {
+		return Session{}, fmt.Errorf("generate token for user %d: %w", user.ID, err)
+	}
+	s.recordAttempt(ctx, identifier, device, &user, "")
+	if s.events != nil {
+		event := events.UserLoggedIn{
+			UserID:         user.ID,
+			Username:       user.Username,
+			Email:          user.Email,
+			DeviceID:       device.ID,
+			UserAgent:      device.UserAgent,
+			AcceptLanguage: device.AcceptLanguage,
+			AcceptEncoding: device.AcceptEncoding,
+			IP:             device.IP,
+			Country:        device.Country,
+			NewDevice:      check.New,
+		}
+		if err := s.events.Publish(ctx, events.TypeUserLoggedIn, event); err != nil {
+			log.Printf("publish %s for user %d: %v", events.TypeUserLoggedIn, user.ID, err)
+		}
+	}
+	return Session{User: user, Token: token, TTL: s.tokens.TTL(), Scopes: scopes, NewDevice: check.New}, nil
+}
+
+// recordAttempt adds a sign-in attempt to the login history; failure is empty
+// for a successful one. Errors are logged so the history never blocks a login.
+func (s *Service) recordAttempt(ctx context.Context, identifier string, device security.Device, user *models.User, failure string) {
+	attempt := models.LoginAttempt{
+		Identifier:    identifier,
+		Success:       failure == "",
+		FailureReason: failure,
+		IP:            device.IP,
+		UserAgent:     device.UserAgent,
+		Country:       device.Country,
+	}
+	if user != nil {
+		attempt.UserID = &user.ID
+	}
+	if _, err := s.store.RecordLoginAttempt(ctx, attempt); err != nil {
+		log.Printf("record login attempt for %s: %v", identifier, err)
+	}
+}
+
+// GrantedScopes validates the scopes a token or API key asks for. Each must
+// be one of the user's permissions; none requested means an unrestricted token.
+func GrantedScopes(requested []string, user models.User) ([]string, error) {
+	if len(requested) == 0 {
+		return nil, nil
+	}
+	scopes := make([]string, 0, len(requested))
+	for _, scope := range requested {
+		scope = strings.TrimSpace(scope)
+		if !user.HasPermission(scope) {
+			return nil, &ScopeError{Scope: scope}
+		}
+		if !slices.Contains(scopes, scope) {
+			scopes = append(scopes, scope)
+		}
+	}
+	return scopes, nil
+}
+
+// ValidatePassword enforces the password rules for new and changed passwords.
+func ValidatePassword(password string) error {
+	if len(strings.TrimSpace(password)) < 8 || !utf8.ValidString(password) {
+		return ErrPasswordTooShort
+	}
+	if len(password) > maxPasswordBytes {
+		return ErrPasswordTooLong
+	}
+	return nil
+}
+
+// HashPassword hashes a password that passed ValidatePassword.
+func HashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), BcryptCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
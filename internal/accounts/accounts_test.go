@@ -0,0 +1,190 @@
+package accounts
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/hongminglow/all-in-be/internal/auth"
+	"github.com/hongminglow/all-in-be/internal/clock"
+	"github.com/hongminglow/all-in-be/internal/config"
+	"github.com/hongminglow/all-in-be/internal/events"
+	"github.com/hongminglow/all-in-be/internal/iprisk"
+	"github.com/hongminglow/all-in-be/internal/models"
+	"github.com/hongminglow/all-in-be/internal/outbox"
+	"github.com/hongminglow/all-in-be/internal/phone"
+	"github.com/hongminglow/all-in-be/internal/security"
+	"github.com/hongminglow/all-in-be/internal/storage"
+	"github.com/hongminglow/all-in-be/internal/storage/storagetest"
+)
+
+type recordingPublisher struct {
+	types []string
+}
+
+func (p *recordingPublisher) Publish(_ context.Context, eventType string, _ any) error {
+	p.types = append(p.types, eventType)
+	return nil
+}
+
+// scriptedDevices answers device checks in order.
+type scriptedDevices []security.DeviceCheck
+
+func (d *scriptedDevices) CheckDevice(context.Context, models.User, security.Device) (security.DeviceCheck, error) {
+	check := (*d)[0]
+	*d = (*d)[1:]
+	return check, nil
+}
+
+type fixedScreen string
+
+func (s fixedScreen) Check(context.Context, iprisk.Request) string { return string(s) }
+
+func newService(t *testing.T, ips IPScreener) (*Service, *storagetest.MemoryStore) {
+	t.Helper()
+	BcryptCost = bcrypt.MinCost
+	clk := storagetest.NewFakeClock(time.Date(2026, 3, 11, 9, 0, 0, 0, time.UTC))
+	store := storagetest.NewMemoryStore(clk)
+	tokens := auth.NewTokenManager(auth.SigningKey{Secret: "secret"}, nil, "all-in-be", "", time.Hour, clk, clock.UUID{})
+	cfg := &config.Config{PhoneRegion: "MY", InitBalance: 100}
+	return NewService(store, nil, ips, nil, nil, nil, tokens, nil, nil, cfg), store
+}
+
+func TestRegister(t *testing.T) {
+	ctx := context.Background()
+	service, store := newService(t, nil)
+	for _, tc := range []struct {
+		name string
+		reg  Registration
+		err  error
+	}{
+		{"missing email", Registration{Username: "ana", Phone: "0123456789", Password: "longenough"}, ErrMissingFields},
+		{"short password", Registration{Username: "ana", Email: "ana@example.com", Phone: "0123456789", Password: "short"}, ErrPasswordTooShort},
+		{"bad phone", Registration{Username: "ana", Email: "ana@example.com", Phone: "+abc", Password: "longenough"}, phone.ErrInvalid},
+	} {
+		if _, err := service.Register(ctx, tc.reg); !errors.Is(err, tc.err) {
+			t.Errorf("%s: err = %v, want %v", tc.name, err, tc.err)
+		}
+	}
+
+	reg := Registration{Username: " ana ", Email: "ana@example.com", Phone: "012-345 6789", Password: "longenough"}
+	user, err := service.Register(ctx, reg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.Username != "ana" || user.Phone != "+60123456789" || user.Role != models.NormalUser || user.Balance != 100 {
+		t.Fatalf("registered user = %+v", user)
+	}
+	ledger, err := store.ListTransactions(ctx, user.ID)
+	if err != nil || len(ledger) != 1 || ledger[0].Reason != models.TransactionWelcomeBonus || ledger[0].Amount != 100 || ledger[0].BalanceAfter != 100 {
+		t.Fatalf("ledger = %+v, err = %v; want the welcome bonus", ledger, err)
+	}
+	if _, err := service.Register(ctx, reg); !errors.Is(err, storage.ErrAlreadyExists) {
+		t.Fatalf("second registration: err = %v", err)
+	}
+}
+
+func TestLogin(t *testing.T) {
+	ctx := context.Background()
+	service, store := newService(t, nil)
+	if _, err := service.Register(ctx, Registration{Username: "ana", Email: "ana@example.com", Phone: "0123456789", Password: "longenough"}); err != nil {
+		t.Fatal(err)
+	}
+	device := security.Device{IP: "203.0.113.7", UserAgent: "test"}
+
+	if _, err := service.Login(ctx, Credentials{Identifier: "ana", Password: "wrong password"}, device); !errors.Is(err, ErrInvalidCredentials) {
+		t.Fatalf("wrong password: err = %v", err)
+	}
+	if _, err := service.Login(ctx, Credentials{Identifier: "bob", Password: "longenough"}, device); !errors.Is(err, ErrInvalidCredentials) {
+		t.Fatalf("unknown user: err = %v", err)
+	}
+	var scopeErr *ScopeError
+	if _, err := service.Login(ctx, Credentials{Identifier: "ana", Password: "longenough", Scopes: []string{models.PermStatsRead}}, device); !errors.As(err, &scopeErr) || scopeErr.Scope != models.PermStatsRead {
+		t.Fatalf("scope the user lacks: err = %v", err)
+	}
+	session, err := service.Login(ctx, Credentials{Identifier: "ana@example.com", Password: "longenough"}, device)
+	if err != nil || session.Token == "" || session.TTL != time.Hour || session.User.Username != "ana" {
+		t.Fatalf("session = %+v, err = %v", session, err)
+	}
+
+	attempts, _ := store.ListLoginAttempts(ctx, models.LoginAttemptFilter{}, 10)
+	if len(attempts) != 3 || !attempts[0].Success || attempts[0].IP != device.IP || attempts[1].FailureReason != models.LoginUnknownUser {
+		t.Fatalf("login history = %+v, want the two failures and the success", attempts)
+	}
+
+	blocked, _ := newService(t, fixedScreen(models.IPRiskStepUp))
+	if _, err := blocked.Register(ctx, Registration{Username: "ana", Email: "ana@example.com", Phone: "0123456789", Password: "longenough"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := blocked.Login(ctx, Credentials{Identifier: "ana", Password: "longenough"}, device); !errors.Is(err, ErrVerificationUnavailable) {
+		t.Fatalf("step-up without a device checker: err = %v", err)
+	}
+}
+
+func TestLoginChecksDeviceAndPublishes(t *testing.T) {
+	ctx := context.Background()
+	service, store := newService(t, nil)
+	publisher := &recordingPublisher{}
+	devices := &scriptedDevices{{New: true, ConfirmationRequired: true}, {New: true}}
+	service.events, service.devices = publisher, devices
+	service.outbox = outbox.NewRelay(store, publisher, clock.System{}, config.EventsConfig{})
+	user, err := service.Register(ctx, Registration{Username: "ana", Email: "ana@example.com", Phone: "0123456789", Password: "longenough"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(publisher.types) != 0 {
+		t.Fatalf("published %v before the relay ran", publisher.types)
+	}
+	// The registration and the welcome bonus wait in the outbox until the
+	// relay runs.
+	if n, err := service.outbox.Flush(ctx); n != 2 || err != nil {
+		t.Fatalf("relayed %d events, err = %v", n, err)
+	}
+	creds := Credentials{Identifier: "ana", Password: "longenough"}
+
+	if _, err := service.Login(ctx, creds, security.Device{}); !errors.Is(err, ErrDeviceUnconfirmed) {
+		t.Fatalf("unconfirmed device: err = %v", err)
+	}
+	session, err := service.Login(ctx, creds, security.Device{})
+	if err != nil || !session.NewDevice {
+		t.Fatalf("confirmed device: session = %+v, err = %v", session, err)
+	}
+	if want := []string{events.TypeUserRegistered, events.TypeBalanceChanged, events.TypeUserLoggedIn}; !slices.Equal(publisher.types, want) {
+		t.Fatalf("published %v, want %v", publisher.types, want)
+	}
+
+	if err := store.LockAccount(ctx, user.ID, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := service.Login(ctx, creds, security.Device{}); !errors.Is(err, ErrResetRequired) {
+		t.Fatalf("locked account: err = %v", err)
+	}
+	if len(*devices) != 0 || len(publisher.types) != 3 {
+		t.Fatalf("a refused sign-in reached the device check or published an event")
+	}
+}
+
+func TestUpdateProfile(t *testing.T) {
+	ctx := context.Background()
+	service, _ := newService(t, nil)
+	ana, err := service.Register(ctx, Registration{Username: "ana", Email: "ana@example.com", Phone: "0123456789", Password: "longenough"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	blank, number := " ", "013-999 8888"
+
+	if _, err := service.UpdateProfile(ctx, ana.ID, ana.Version, ProfileUpdate{Email: &blank}); !errors.Is(err, ErrBlankProfileField) {
+		t.Fatalf("blank email: err = %v", err)
+	}
+	updated, err := service.UpdateProfile(ctx, ana.ID, ana.Version, ProfileUpdate{Phone: &number})
+	if err != nil || updated.Phone != "+60139998888" || updated.Email != ana.Email || updated.Version != ana.Version+1 {
+		t.Fatalf("updated = %+v, err = %v", updated, err)
+	}
+	if _, err := service.UpdateProfile(ctx, ana.ID, ana.Version, ProfileUpdate{Phone: &number}); !errors.Is(err, storage.ErrVersionConflict) {
+		t.Fatalf("edit based on the old version: err = %v", err)
+	}
+}
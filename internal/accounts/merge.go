@@ -0,0 +1,160 @@
+package accounts
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/hongminglow/all-in-be/internal/clock"
+	"github.com/hongminglow/all-in-be/internal/limits"
+	"github.com/hongminglow/all-in-be/internal/models"
+	"github.com/hongminglow/all-in-be/internal/storage"
+)
+
+// Reasons Merge refuses to merge two accounts.
+var (
+	ErrMergeSelf           = errors.New("an account cannot be merged into itself")
+	ErrMergeNotPlayer      = errors.New("only player accounts can be merged")
+	ErrAlreadyMerged       = errors.New("account was already merged into another")
+	ErrMergeWithdrawals    = errors.New("the duplicate account has withdrawals in progress")
+	ErrMergeFundsHeld      = errors.New("the duplicate account has funds on hold")
+	ErrMergeLegalHold      = errors.New("the duplicate account is under a legal hold")
+	ErrMergeSelfExcluded   = errors.New("the duplicate account is self-excluded")
+	errMergeDryRunRollback = errors.New("dry run")
+)
+
+// Merger folds a player's duplicate account, opened under another email, into
+// their main account.
+type Merger struct {
+	store   storage.Store
+	clock   clock.Clock
+	revoked func()
+}
+
+// NewMerger builds the service. revoked, if not nil, is called after a merge
+// revokes the duplicate's sessions, so cached session state can be dropped.
+func NewMerger(store storage.Store, clk clock.Clock, revoked func()) *Merger {
+	return &Merger{store: store, clock: clk, revoked: revoked}
+}
+
+// Merge moves duplicateID's balance, ledger, login history and known devices
+// to userID and deactivates the duplicate, signing it out everywhere, all in
+// one transaction. The balance moves as an account_merge debit on the
+// duplicate and a matching credit on userID. With dryRun the merge is worked
+// out and rolled back, and the record of what would have moved is returned.
+//
+// Accounts with withdrawals in progress, funds on hold, a legal hold or a
+// self-exclusion cannot be merged as the duplicate, since their money or data must stay put
+// or the exclusion would be lost. storage.ErrNotFound means either user does
+// not exist and storage.ErrVersionConflict that one changed during the merge.
+func (m *Merger) Merge(ctx context.Context, userID, duplicateID, actorID int64, dryRun bool) (models.AccountMerge, error) {
+	if userID == duplicateID {
+		return models.AccountMerge{}, ErrMergeSelf
+	}
+	var merged models.AccountMerge
+	err := m.store.WithTx(ctx, func(tx storage.Repositories) error {
+		kept, dup, err := m.lockPair(ctx, tx, userID, duplicateID)
+		if err != nil {
+			return err
+		}
+		if err := m.checkDuplicate(ctx, tx, dup); err != nil {
+			return err
+		}
+		if dup.Balance > 0 {
+			debit := models.Transaction{UserID: dup.ID, Amount: -dup.Balance, Reason: models.TransactionAccountMerge, Reference: fmt.Sprintf("user:%d", kept.ID)}
+			if _, err := tx.ApplyTransaction(ctx, debit); err != nil {
+				return fmt.Errorf("debit user %d: %w", dup.ID, err)
+			}
+		}
+		merged, err = tx.MergeAccounts(ctx, models.AccountMerge{UserID: kept.ID, DuplicateID: dup.ID, MergedBy: actorID, Balance: dup.Balance})
+		if err != nil {
+			return err
+		}
+		if dup.Balance > 0 {
+			credit := models.Transaction{UserID: kept.ID, Amount: dup.Balance, Reason: models.TransactionAccountMerge, Reference: fmt.Sprintf("user:%d", dup.ID)}
+			if _, err := tx.ApplyTransaction(ctx, credit); err != nil {
+				return fmt.Errorf("credit user %d: %w", kept.ID, err)
+			}
+		}
+		if dryRun {
+			return errMergeDryRunRollback
+		}
+		return nil
+	})
+	switch {
+	case dryRun && errors.Is(err, errMergeDryRunRollback):
+		merged.ID, merged.DryRun = 0, true
+		return merged, nil
+	case err != nil:
+		return models.AccountMerge{}, err
+	}
+	if m.revoked != nil {
+		m.revoked()
+	}
+	return merged, nil
+}
+
+// lockPair loads both players and locks their rows, lower ID first so two
+// merges of the same pair cannot deadlock.
+func (m *Merger) lockPair(ctx context.Context, tx storage.Repositories, userID, duplicateID int64) (models.User, models.User, error) {
+	kept, err := tx.FindByID(ctx, userID)
+	if err != nil {
+		return models.User{}, models.User{}, err
+	}
+	dup, err := tx.FindByID(ctx, duplicateID)
+	if err != nil {
+		return models.User{}, models.User{}, err
+	}
+	for _, u := range []models.User{kept, dup} {
+		if !slices.Contains(models.PlayerRoles, u.Role) {
+			return models.User{}, models.User{}, ErrMergeNotPlayer
+		}
+		if u.MergedInto != nil {
+			return models.User{}, models.User{}, ErrAlreadyMerged
+		}
+	}
+	pair := []models.User{kept, dup}
+	slices.SortFunc(pair, func(a, b models.User) int { return cmp.Compare(a.ID, b.ID) })
+	for _, u := range pair {
+		if err := tx.CheckUserVersion(ctx, u.ID, u.Version); err != nil {
+			return models.User{}, models.User{}, err
+		}
+	}
+	return kept, dup, nil
+}
+
+func (m *Merger) checkDuplicate(ctx context.Context, tx storage.Repositories, dup models.User) error {
+	for _, status := range []string{models.WithdrawalPending, models.WithdrawalApproved} {
+		open, err := tx.ListWithdrawals(ctx, models.WithdrawalFilter{UserID: dup.ID, Status: status}, 1)
+		if err != nil {
+			return fmt.Errorf("list withdrawals of user %d: %w", dup.ID, err)
+		}
+		if len(open) > 0 {
+			return ErrMergeWithdrawals
+		}
+	}
+	held, err := tx.HeldBalance(ctx, dup.ID)
+	if err != nil {
+		return fmt.Errorf("held balance of user %d: %w", dup.ID, err)
+	}
+	if held > 0 {
+		return ErrMergeFundsHeld
+	}
+	holds, err := tx.ListLegalHolds(ctx, dup.ID)
+	if err != nil {
+		return fmt.Errorf("list legal holds of user %d: %w", dup.ID, err)
+	}
+	if slices.ContainsFunc(holds, func(h models.LegalHold) bool { return h.ReleasedAt == nil }) {
+		return ErrMergeLegalHold
+	}
+	g, err := limits.Find(ctx, tx, dup.ID)
+	if err != nil {
+		return err
+	}
+	if g.Excluded(m.clock.Now()) {
+		return ErrMergeSelfExcluded
+	}
+	return nil
+}
@@ -0,0 +1,155 @@
+// Package archive moves cold rows, ledger entries and login history older
+// than the retention window, out of the hot tables into archive tables. The
+// store reads archived rows back for admin lookups, so archiving changes
+// where a row lives but not what staff can see.
+package archive
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"time"
+
+	"github.com/hongminglow/all-in-be/internal/bulk"
+	"github.com/hongminglow/all-in-be/internal/clock"
+	"github.com/hongminglow/all-in-be/internal/config"
+	"github.com/hongminglow/all-in-be/internal/models"
+	"github.com/hongminglow/all-in-be/internal/storage"
+)
+
+// ErrDisabled is returned by Run when no retention window is configured.
+var ErrDisabled = errors.New("archiving is disabled")
+
+// Service archives rows on a schedule and on demand.
+type Service struct {
+	store storage.ArchiveStore
+	clock clock.Clock
+	cfg   config.ArchiveConfig
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewService builds an archiver; call Start to run it on cfg.Interval.
+func NewService(store storage.ArchiveStore, clk clock.Clock, cfg config.ArchiveConfig) *Service {
+	return &Service{store: store, clock: clk, cfg: cfg}
+}
+
+// Run archives every dataset's rows older than the retention window, a batch
+// at a time, and reports how many rows moved. It is safe to run on several
+// instances at once.
+func (s *Service) Run(ctx context.Context) ([]models.ArchiveRun, error) {
+	if s.cfg.AfterMonths <= 0 {
+		return nil, ErrDisabled
+	}
+	before := s.clock.Now().AddDate(0, -s.cfg.AfterMonths, 0)
+	runs := make([]models.ArchiveRun, 0, len(models.ArchiveDatasets))
+	for _, dataset := range models.ArchiveDatasets {
+		run := models.ArchiveRun{Dataset: dataset, Before: before}
+		for {
+			moved, err := s.store.ArchiveRecords(ctx, dataset, before, s.cfg.BatchSize)
+			run.Moved += moved
+			if err != nil {
+				return append(runs, run), fmt.Errorf("archive %s: %w", dataset, err)
+			}
+			if moved < s.cfg.BatchSize {
+				break
+			}
+			if err := ctx.Err(); err != nil {
+				return append(runs, run), err
+			}
+		}
+		runs = append(runs, run)
+	}
+	return runs, nil
+}
+
+// backfill is the checkpoint of an archive bulk job: the cutoff fixed when
+// the job started and the dataset it is working through.
+type backfill struct {
+	Before  time.Time `json:"before"`
+	Dataset string    `json:"dataset"`
+}
+
+// Step archives one batch as part of a bulk job, which suits backfills too
+// large to finish within a request. It works through the datasets in order
+// with the cutoff taken at the first step.
+func (s *Service) Step(ctx context.Context, checkpoint json.RawMessage) (bulk.Batch, error) {
+	if s.cfg.AfterMonths <= 0 {
+		return bulk.Batch{}, ErrDisabled
+	}
+	cp := backfill{Before: s.clock.Now().AddDate(0, -s.cfg.AfterMonths, 0), Dataset: models.ArchiveDatasets[0]}
+	if len(checkpoint) > 0 {
+		if err := json.Unmarshal(checkpoint, &cp); err != nil {
+			return bulk.Batch{}, fmt.Errorf("archive checkpoint: %w", err)
+		}
+	}
+	i := slices.Index(models.ArchiveDatasets, cp.Dataset)
+	if i < 0 {
+		// Finished, or a dataset this release no longer archives.
+		return bulk.Batch{Checkpoint: checkpoint, Done: true}, nil
+	}
+	moved, err := s.store.ArchiveRecords(ctx, cp.Dataset, cp.Before, s.cfg.BatchSize)
+	if err != nil {
+		return bulk.Batch{}, fmt.Errorf("archive %s: %w", cp.Dataset, err)
+	}
+	if moved < s.cfg.BatchSize {
+		cp.Dataset = ""
+		if i+1 < len(models.ArchiveDatasets) {
+			cp.Dataset = models.ArchiveDatasets[i+1]
+		}
+	}
+	next, err := json.Marshal(cp)
+	if err != nil {
+		return bulk.Batch{}, err
+	}
+	return bulk.Batch{Checkpoint: next, Processed: int64(moved), Done: cp.Dataset == ""}, nil
+}
+
+// Start runs the archiver every cfg.Interval until Close. It does nothing
+// when archiving is disabled.
+func (s *Service) Start() {
+	if s.cfg.AfterMonths <= 0 || s.stop != nil {
+		return
+	}
+	s.stop, s.done = make(chan struct{}), make(chan struct{})
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-s.stop
+		cancel()
+	}()
+	go func() {
+		defer close(s.done)
+		ticker := time.NewTicker(s.cfg.Interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				runs, err := s.Run(ctx)
+				for _, run := range runs {
+					if run.Moved > 0 {
+						log.Printf("archive: moved %d %s rows created before %s", run.Moved, run.Dataset, run.Before.Format(time.DateOnly))
+					}
+				}
+				if err != nil && ctx.Err() == nil {
+					log.Printf("archive: %v", err)
+				}
+			}
+		}
+	}()
+}
+
+// Close stops the schedule, cancelling a run in progress, and waits for it to end.
+func (s *Service) Close() {
+	if s.stop == nil {
+		return
+	}
+	close(s.stop)
+	<-s.done
+	s.stop = nil
+}
@@ -0,0 +1,154 @@
+package archive
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/hongminglow/all-in-be/internal/config"
+	"github.com/hongminglow/all-in-be/internal/models"
+	"github.com/hongminglow/all-in-be/internal/storage/storagetest"
+)
+
+func TestRunMovesOnlyColdRows(t *testing.T) {
+	ctx := context.Background()
+	clk := storagetest.NewFakeClock(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	store := storagetest.NewMemoryStore(clk)
+	user, err := store.CreateUser(ctx, models.User{Username: "ana", Email: "ana@example.com", Role: models.NormalUser})
+	if err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	record := func(amount float64) models.Transaction {
+		t.Helper()
+		tx, err := store.ApplyTransaction(ctx, models.Transaction{UserID: user.ID, Amount: amount, Reason: models.TransactionDeposit})
+		if err != nil {
+			t.Fatalf("apply transaction: %v", err)
+		}
+		if _, err := store.RecordLoginAttempt(ctx, models.LoginAttempt{UserID: &user.ID, Identifier: "ana", Success: true}); err != nil {
+			t.Fatalf("record login: %v", err)
+		}
+		return tx
+	}
+	cold := []models.Transaction{record(10), record(5), record(1)}
+	clk.Advance(13 * 30 * 24 * time.Hour)
+	hot := record(2)
+
+	archiver := NewService(store, clk, config.ArchiveConfig{AfterMonths: 12, BatchSize: 2})
+	runs, err := archiver.Run(ctx)
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	for _, run := range runs {
+		if run.Moved != len(cold) {
+			t.Fatalf("%s: moved %d rows, want %d", run.Dataset, run.Moved, len(cold))
+		}
+	}
+	if runs, _ := archiver.Run(ctx); runs[0].Moved != 0 || runs[1].Moved != 0 {
+		t.Fatalf("second run moved rows again: %+v", runs)
+	}
+
+	for _, tx := range append(cold, hot) {
+		if got, err := store.FindTransaction(ctx, tx.ID); err != nil || got != tx {
+			t.Fatalf("find transaction %d = %+v, %v; want it found wherever it lives", tx.ID, got, err)
+		}
+	}
+	filter := models.LoginAttemptFilter{UserID: user.ID}
+	if recent, _ := store.ListLoginAttempts(ctx, filter, 10); len(recent) != 1 {
+		t.Fatalf("hot login history has %d rows, want 1", len(recent))
+	}
+	filter.IncludeArchived = true
+	if all, _ := store.ListLoginAttempts(ctx, filter, 10); len(all) != 4 {
+		t.Fatalf("login history with archive has %d rows, want 4", len(all))
+	}
+}
+
+func TestRunIsDisabledWithoutRetention(t *testing.T) {
+	store := storagetest.NewMemoryStore(storagetest.NewFakeClock(time.Now()))
+	if _, err := NewService(store, storagetest.NewFakeClock(time.Now()), config.ArchiveConfig{BatchSize: 10}).Run(context.Background()); !errors.Is(err, ErrDisabled) {
+		t.Fatalf("err = %v, want ErrDisabled", err)
+	}
+}
+
+func TestRunLeavesHeldRows(t *testing.T) {
+	ctx := context.Background()
+	clk := storagetest.NewFakeClock(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	store := storagetest.NewMemoryStore(clk)
+	ana, _ := store.CreateUser(ctx, models.User{Username: "ana", Email: "ana@example.com", Role: models.NormalUser})
+	ben, _ := store.CreateUser(ctx, models.User{Username: "ben", Email: "ben@example.com", Role: models.NormalUser})
+	for _, id := range []int64{ana.ID, ben.ID} {
+		if _, err := store.ApplyTransaction(ctx, models.Transaction{UserID: id, Amount: 10, Reason: models.TransactionDeposit}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := store.RecordLoginAttempt(ctx, models.LoginAttempt{UserID: &id, Identifier: "x", Success: true}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	hold, err := store.PlaceLegalHold(ctx, models.LegalHold{UserID: ana.ID, Dataset: models.ArchiveLedger, Reason: "chargeback dispute", PlacedBy: ben.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	clk.Advance(13 * 30 * 24 * time.Hour)
+
+	archiver := NewService(store, clk, config.ArchiveConfig{AfterMonths: 12, BatchSize: 10})
+	runs, err := archiver.Run(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if runs[0].Moved != 1 || runs[1].Moved != 2 {
+		t.Fatalf("runs = %+v, want ana's ledger held and every login archived", runs)
+	}
+
+	if _, err := store.ReleaseLegalHold(ctx, ana.ID, hold.ID, ben.ID); err != nil {
+		t.Fatal(err)
+	}
+	if runs, _ := archiver.Run(ctx); runs[0].Moved != 1 {
+		t.Fatalf("after release: %+v, want ana's ledger archived", runs)
+	}
+}
+
+func TestStepResumesFromCheckpoint(t *testing.T) {
+	ctx := context.Background()
+	clk := storagetest.NewFakeClock(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	store := storagetest.NewMemoryStore(clk)
+	user, _ := store.CreateUser(ctx, models.User{Username: "ana", Email: "ana@example.com", Role: models.NormalUser})
+	for range 3 {
+		if _, err := store.ApplyTransaction(ctx, models.Transaction{UserID: user.ID, Amount: 1, Reason: models.TransactionDeposit}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := store.RecordLoginAttempt(ctx, models.LoginAttempt{UserID: &user.ID, Identifier: "ana", Success: true}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	clk.Advance(13 * 30 * 24 * time.Hour)
+
+	archiver := NewService(store, clk, config.ArchiveConfig{AfterMonths: 12, BatchSize: 2})
+	var checkpoint []byte
+	var processed int64
+	for steps := 1; ; steps++ {
+		batch, err := archiver.Step(ctx, checkpoint)
+		if err != nil {
+			t.Fatalf("step %d: %v", steps, err)
+		}
+		processed += batch.Processed
+		checkpoint = batch.Checkpoint
+		if batch.Done {
+			// Two batches per dataset: a full one and the remainder.
+			if steps != 4 {
+				t.Fatalf("done after %d steps, want 4", steps)
+			}
+			break
+		}
+		// Rows aging past the cutoff mid-job wait for the next job.
+		clk.Advance(60 * 24 * time.Hour)
+		if _, err := store.ApplyTransaction(ctx, models.Transaction{UserID: user.ID, Amount: 1, Reason: models.TransactionDeposit}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if processed != 6 {
+		t.Fatalf("processed %d rows, want 6", processed)
+	}
+	if batch, err := archiver.Step(ctx, checkpoint); err != nil || !batch.Done || batch.Processed != 0 {
+		t.Fatalf("step after done = %+v, %v", batch, err)
+	}
+}
@@ -0,0 +1,80 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/hongminglow/all-in-be/internal/models"
+	"github.com/hongminglow/all-in-be/internal/storage"
+)
+
+// Reasons Verify rejects an impersonation token.
+var (
+	ErrInvalidImpersonation = errors.New("invalid impersonation token")
+	ErrImpersonationRevoked = errors.New("impersonation revoked")
+)
+
+// Impersonations issues the tokens support staff act as players with and
+// checks them on every request, so an impersonation ends as soon as it is
+// revoked or its staff member loses the permission.
+type Impersonations struct {
+	store  storage.ImpersonationStore
+	users  storage.UserStore
+	tokens *TokenManager
+}
+
+// NewImpersonations builds the service; tokens are issued by tokens.
+func NewImpersonations(store storage.ImpersonationStore, users storage.UserStore, tokens *TokenManager) *Impersonations {
+	return &Impersonations{store: store, users: users, tokens: tokens}
+}
+
+// Start issues a token acting as user on behalf of actorID that expires after
+// ttl, and records it with the reason given. The caller has already checked
+// that actorID may impersonate user. The returned impersonation carries the
+// token in Token.
+func (i *Impersonations) Start(ctx context.Context, user models.User, actorID int64, reason string, ttl time.Duration) (models.Impersonation, error) {
+	token, claims, err := i.tokens.GenerateImpersonation(user, actorID, ttl)
+	if err != nil {
+		return models.Impersonation{}, err
+	}
+	imp, err := i.store.CreateImpersonation(ctx, models.Impersonation{
+		UserID:    user.ID,
+		ActorID:   actorID,
+		Reason:    reason,
+		TokenID:   claims.ID,
+		ExpiresAt: claims.ExpiresAt,
+	})
+	if err != nil {
+		return models.Impersonation{}, err
+	}
+	imp.Token = token
+	return imp, nil
+}
+
+// Verify returns the impersonation an impersonation token's claims belong to
+// if it is not revoked and its staff member still holds
+// models.PermUsersImpersonate. Expiry is checked when the token is parsed.
+func (i *Impersonations) Verify(ctx context.Context, claims Claims) (models.Impersonation, error) {
+	imp, err := i.store.FindImpersonationByToken(ctx, claims.ID)
+	switch {
+	case errors.Is(err, storage.ErrNotFound):
+		return models.Impersonation{}, ErrInvalidImpersonation
+	case err != nil:
+		return models.Impersonation{}, err
+	case imp.UserID != claims.UserID || imp.ActorID != claims.ActorID:
+		return models.Impersonation{}, ErrInvalidImpersonation
+	case imp.RevokedAt != nil:
+		return models.Impersonation{}, ErrImpersonationRevoked
+	}
+	actor, err := i.users.FindByID(ctx, imp.ActorID)
+	switch {
+	case errors.Is(err, storage.ErrNotFound):
+		return models.Impersonation{}, ErrImpersonationRevoked
+	case err != nil:
+		return models.Impersonation{}, err
+	case actor.PasswordResetRequired || !actor.HasPermission(models.PermUsersImpersonate):
+		return models.Impersonation{}, ErrImpersonationRevoked
+	}
+	return imp, nil
+}
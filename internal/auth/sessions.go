@@ -0,0 +1,87 @@
+package auth
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/hongminglow/all-in-be/internal/clock"
+	"github.com/hongminglow/all-in-be/internal/storage"
+)
+
+// SessionState is what a user's account says about the tokens issued to
+// them, beyond what the tokens carry.
+type SessionState struct {
+	// RevokedAt invalidates every token issued before it.
+	RevokedAt *time.Time
+	// ResetRequired is set while the account is locked.
+	ResetRequired bool
+	// MergedInto is set once the account was merged into another.
+	MergedInto *int64
+	// PermissionsVersion refuses tokens issued with an older one, whose
+	// permissions may since have been revoked.
+	PermissionsVersion int64
+}
+
+// Sessions caches the session state of the users making requests, so tokens
+// are checked for revocation without loading the user on every request. An
+// entry is reused for ttl; Invalidate drops them all, and is registered as
+// cache.Sessions so revocations reach every instance at once.
+type Sessions struct {
+	users storage.UserStore
+	clock clock.Clock
+	ttl   time.Duration
+
+	mu      sync.Mutex
+	entries map[int64]sessionEntry
+	// generation counts invalidations, so a state loaded before one is not
+	// cached after it.
+	generation uint64
+}
+
+type sessionEntry struct {
+	state    SessionState
+	loadedAt time.Time
+}
+
+// NewSessions builds the cache; entries expire by clk.
+func NewSessions(users storage.UserStore, clk clock.Clock, ttl time.Duration) *Sessions {
+	return &Sessions{users: users, clock: clk, ttl: ttl, entries: make(map[int64]sessionEntry)}
+}
+
+// State returns the user's session state, loading it when it is not cached
+// or older than the ttl. It returns storage.ErrNotFound for unknown users.
+func (s *Sessions) State(ctx context.Context, userID int64) (SessionState, error) {
+	now := s.clock.Now()
+	s.mu.Lock()
+	entry, ok := s.entries[userID]
+	generation := s.generation
+	s.mu.Unlock()
+	if ok && now.Sub(entry.loadedAt) < s.ttl {
+		return entry.state, nil
+	}
+	user, err := s.users.FindByID(ctx, userID)
+	if err != nil {
+		return SessionState{}, err
+	}
+	state := SessionState{
+		RevokedAt:          user.SessionsRevokedAt,
+		ResetRequired:      user.PasswordResetRequired,
+		MergedInto:         user.MergedInto,
+		PermissionsVersion: user.PermissionsVersion,
+	}
+	s.mu.Lock()
+	if s.generation == generation {
+		s.entries[userID] = sessionEntry{state: state, loadedAt: now}
+	}
+	s.mu.Unlock()
+	return state, nil
+}
+
+// Invalidate drops every cached state so the next request reloads it.
+func (s *Sessions) Invalidate() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	clear(s.entries)
+	s.generation++
+}
@@ -0,0 +1,62 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/hongminglow/all-in-be/internal/models"
+	"github.com/hongminglow/all-in-be/internal/storage"
+	"github.com/hongminglow/all-in-be/internal/storage/storagetest"
+)
+
+// countingUsers counts the users loaded from the store.
+type countingUsers struct {
+	*storagetest.MemoryStore
+	loads int
+}
+
+func (c *countingUsers) FindByID(ctx context.Context, id int64) (models.User, error) {
+	c.loads++
+	return c.MemoryStore.FindByID(ctx, id)
+}
+
+func TestSessionsAreCachedUntilInvalidated(t *testing.T) {
+	ctx := context.Background()
+	clk := storagetest.NewFakeClock(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	users := &countingUsers{MemoryStore: storagetest.NewMemoryStore(clk)}
+	user, err := users.CreateUser(ctx, models.User{Username: "ana", Email: "ana@example.com", Role: models.NormalUser})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sessions := NewSessions(users, clk, time.Minute)
+
+	for range 3 {
+		if state, err := sessions.State(ctx, user.ID); err != nil || state.RevokedAt != nil {
+			t.Fatalf("state = %+v, %v", state, err)
+		}
+	}
+	if users.loads != 1 {
+		t.Fatalf("loads = %d, want 1 while cached", users.loads)
+	}
+
+	if err := users.LockAccount(ctx, user.ID, clk.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if state, _ := sessions.State(ctx, user.ID); state.ResetRequired {
+		t.Fatal("lock seen before the cache was invalidated or expired")
+	}
+	sessions.Invalidate()
+	if state, _ := sessions.State(ctx, user.ID); !state.ResetRequired || state.RevokedAt == nil {
+		t.Fatalf("state after invalidation = %+v, want the lock", state)
+	}
+
+	clk.Advance(time.Minute)
+	if _, err := sessions.State(ctx, user.ID); err != nil || users.loads != 3 {
+		t.Fatalf("after the ttl: loads = %d, err = %v; want a reload", users.loads, err)
+	}
+	if _, err := sessions.State(ctx, 999); !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("unknown user: err = %v, want ErrNotFound", err)
+	}
+}
@@ -1,41 +1,282 @@
 package auth
 
 import (
+	"errors"
 	"fmt"
+	"log"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/hongminglow/all-in-be/internal/clock"
 	"github.com/hongminglow/all-in-be/internal/models"
 )
 
+// CookieName is the HttpOnly cookie that carries the session token for browser clients.
+const CookieName = "all_in_token"
+
+// retiringReportInterval limits how often use of a retiring key is logged.
+const retiringReportInterval = time.Minute
+
+// SigningKey is an HMAC secret identified by the JWT "kid" header.
+type SigningKey struct {
+	ID     string
+	Secret string
+}
+
 // TokenManager issues signed JWTs for authenticated users.
 type TokenManager struct {
-	secret []byte
-	issuer string
-	ttl    time.Duration
+	current  SigningKey
+	retiring map[string][]byte
+	issuer   string
+	region   string
+	ttl      time.Duration
+	clock    clock.Clock
+	ids      clock.IDGenerator
+
+	mu       sync.Mutex
+	seen     map[string]int
+	reported time.Time
 }
 
-// NewTokenManager creates a manager with the provided secret, issuer, and lifetime.
-func NewTokenManager(secret, issuer string, ttl time.Duration) *TokenManager {
+// NewTokenManager creates a manager that signs with current and still accepts
+// tokens signed with the retiring keys, so JWT_SECRET can be rotated without
+// logging everyone out. Tokens are tagged with the issuing region when one is
+// configured. Expiry is computed from clk and each token gets a unique ID from ids.
+func NewTokenManager(current SigningKey, retiring []SigningKey, issuer, region string, ttl time.Duration, clk clock.Clock, ids clock.IDGenerator) *TokenManager {
+	byID := make(map[string][]byte, len(retiring))
+	for _, key := range retiring {
+		byID[key.ID] = []byte(key.Secret)
+	}
 	return &TokenManager{
-		secret: []byte(secret),
-		issuer: issuer,
-		ttl:    ttl,
+		current:  current,
+		retiring: byID,
+		issuer:   issuer,
+		region:   region,
+		ttl:      ttl,
+		clock:    clk,
+		ids:      ids,
+		seen:     make(map[string]int),
 	}
 }
 
+// tokenClaims is the payload of the tokens this manager issues.
+type tokenClaims struct {
+	jwt.RegisteredClaims
+	Username string `json:"username"`
+	Email    string `json:"email"`
+	Role     string `json:"role,omitempty"`
+	// Permissions lets clients adapt their UI without another request.
+	Permissions []string `json:"permissions,omitempty"`
+	// PermissionsVersion is the user's permissions version when the token
+	// was issued.
+	PermissionsVersion int64 `json:"pv,omitempty"`
+	// Tenant is omitted for the default tenant.
+	Tenant string `json:"tenant,omitempty"`
+	Region string `json:"region,omitempty"`
+	// Scope is the space-separated scopes of a scoped token, present even
+	// when empty; unrestricted tokens have none.
+	Scope *string `json:"scope,omitempty"`
+	// Act names the staff member acting as the subject (RFC 8693) in an
+	// impersonation token.
+	Act *actClaim `json:"act,omitempty"`
+}
+
+type actClaim struct {
+	Subject string `json:"sub"`
+}
+
 // Generate issues a signed JWT string for the provided user ID.
 func (t *TokenManager) Generate(user models.User) (string, error) {
-	now := time.Now()
-	claims := jwt.MapClaims{
-		"iss":      t.issuer,
-		"sub":      fmt.Sprintf("%d", user.ID),
-		"username": user.Username,
-		"email":    user.Email,
-		"iat":      now.Unix(),
-		"nbf":      now.Unix(),
-		"exp":      now.Add(t.ttl).Unix(),
+	return t.GenerateScoped(user, nil)
+}
+
+// GenerateScoped issues a token limited to scopes, a subset of the user's
+// permissions the caller has already checked. A nil scopes issues an
+// unrestricted token.
+func (t *TokenManager) GenerateScoped(user models.User, scopes []string) (string, error) {
+	claims := t.claims(user, t.ttl)
+	if scopes != nil {
+		scope := strings.Join(scopes, " ")
+		claims.Scope = &scope
+		claims.Permissions = slices.DeleteFunc(slices.Clone(claims.Permissions), func(p string) bool { return !slices.Contains(scopes, p) })
 	}
+	return t.sign(claims)
+}
+
+// GenerateImpersonation issues a token acting as user on behalf of actorID
+// that expires after ttl. It returns the token with its claims, whose ID the
+// caller records so the impersonation can be revoked.
+func (t *TokenManager) GenerateImpersonation(user models.User, actorID int64, ttl time.Duration) (string, Claims, error) {
+	claims := t.claims(user, ttl)
+	claims.Act = &actClaim{Subject: strconv.FormatInt(actorID, 10)}
+	token, err := t.sign(claims)
+	if err != nil {
+		return "", Claims{}, err
+	}
+	parsed, err := claims.parsed()
+	return token, parsed, err
+}
+
+func (t *TokenManager) claims(user models.User, ttl time.Duration) tokenClaims {
+	now := t.clock.Now()
+	return tokenClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    t.issuer,
+			Subject:   strconv.FormatInt(user.ID, 10),
+			ID:        t.ids.NewID(),
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+		},
+		Username:           user.Username,
+		Email:              user.Email,
+		Role:               user.Role,
+		Permissions:        user.Permissions,
+		PermissionsVersion: user.PermissionsVersion,
+		Region:             t.region,
+	}
+}
+
+func (t *TokenManager) sign(claims tokenClaims) (string, error) {
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(t.secret)
+	if t.current.ID != "" {
+		token.Header["kid"] = t.current.ID
+	}
+	return token.SignedString([]byte(t.current.Secret))
+}
+
+// TTL reports how long issued tokens remain valid.
+func (t *TokenManager) TTL() time.Duration {
+	return t.ttl
+}
+
+// Claims are the verified contents of a token. Role, Permissions and Tenant
+// are as they were when the token was issued.
+type Claims struct {
+	UserID int64
+	// ID is the token's unique jti.
+	ID          string
+	Username    string
+	Email       string
+	Role        string
+	Permissions []string
+	// PermissionsVersion is the user's permissions version when the token
+	// was issued; Permissions are stale once the user's has moved on.
+	PermissionsVersion int64
+	// Tenant is empty for the default tenant.
+	Tenant    string
+	Region    string
+	IssuedAt  time.Time
+	ExpiresAt time.Time
+	// ActorID is the staff member acting as the user in an impersonation
+	// token, and 0 otherwise.
+	ActorID int64
+	// Scopes limits the token to these permissions on top of the user's role.
+	// Nil means the token is unrestricted.
+	Scopes []string
+}
+
+// Parse validates a signed JWT issued by this manager and returns its claims.
+func (t *TokenManager) Parse(tokenString string) (Claims, error) {
+	var payload tokenClaims
+	_, err := jwt.ParseWithClaims(tokenString, &payload, t.verificationKey,
+		jwt.WithIssuer(t.issuer),
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+		jwt.WithTimeFunc(t.clock.Now),
+		jwt.WithIssuedAt(),
+	)
+	if err != nil {
+		return Claims{}, fmt.Errorf("parse token: %w", err)
+	}
+	return payload.parsed()
+}
+
+// User returns the user the token was issued to as it describes them: their
+// ID, username, email, role and permissions. Other fields are left zero.
+func (c Claims) User() models.User {
+	return models.User{ID: c.UserID, Username: c.Username, Email: c.Email, Role: c.Role, Permissions: c.Permissions}
+}
+
+// parsed converts the payload of a verified token to Claims.
+func (c tokenClaims) parsed() (Claims, error) {
+	id, err := strconv.ParseInt(c.Subject, 10, 64)
+	if err != nil {
+		return Claims{}, fmt.Errorf("invalid subject %q: %w", c.Subject, err)
+	}
+	claims := Claims{
+		UserID:             id,
+		ID:                 c.ID,
+		Username:           c.Username,
+		Email:              c.Email,
+		Role:               c.Role,
+		Permissions:        c.Permissions,
+		PermissionsVersion: c.PermissionsVersion,
+		Tenant:             c.Tenant,
+		Region:             c.Region,
+	}
+	if c.IssuedAt != nil {
+		claims.IssuedAt = c.IssuedAt.Time
+	}
+	if c.ExpiresAt != nil {
+		claims.ExpiresAt = c.ExpiresAt.Time
+	}
+	if c.Scope != nil {
+		claims.Scopes = strings.Fields(*c.Scope)
+		if claims.Scopes == nil {
+			claims.Scopes = []string{}
+		}
+	}
+	if c.Act != nil {
+		if claims.ActorID, err = strconv.ParseInt(c.Act.Subject, 10, 64); err != nil || claims.ActorID <= 0 {
+			return Claims{}, fmt.Errorf("invalid act subject %q", c.Act.Subject)
+		}
+	}
+	return claims, nil
+}
+
+// verificationKey picks the secret named by the token's kid. Tokens issued
+// before key IDs were configured carry none and may match any active key.
+func (t *TokenManager) verificationKey(token *jwt.Token) (any, error) {
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" {
+		if len(t.retiring) == 0 {
+			return []byte(t.current.Secret), nil
+		}
+		keys := jwt.VerificationKeySet{Keys: []jwt.VerificationKey{[]byte(t.current.Secret)}}
+		for _, secret := range t.retiring {
+			keys.Keys = append(keys.Keys, secret)
+		}
+		return keys, nil
+	}
+	if kid == t.current.ID {
+		return []byte(t.current.Secret), nil
+	}
+	if secret, ok := t.retiring[kid]; ok {
+		t.noteRetiring(kid)
+		return secret, nil
+	}
+	return nil, errors.New("unknown signing key")
+}
+
+// noteRetiring counts tokens verified with a retiring key and logs a warning at
+// most once per retiringReportInterval, so operators know when it is safe to
+// drop the key.
+func (t *TokenManager) noteRetiring(kid string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.seen[kid]++
+	now := t.clock.Now()
+	if now.Sub(t.reported) < retiringReportInterval {
+		return
+	}
+	for id, count := range t.seen {
+		log.Printf("auth: %d token(s) signed with retiring key %q verified in the last %s; keep the key configured until they expire (at most %s after rotation)",
+			count, id, retiringReportInterval, t.ttl)
+	}
+	t.seen = make(map[string]int)
+	t.reported = now
 }
@@ -0,0 +1,185 @@
+package auth
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/hongminglow/all-in-be/internal/models"
+	"github.com/hongminglow/all-in-be/internal/storage/storagetest"
+)
+
+func TestTokenExpiryFollowsClock(t *testing.T) {
+	clk := storagetest.NewFakeClock(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	tokens := NewTokenManager(SigningKey{Secret: "secret"}, nil, "test", "", time.Hour, clk, &storagetest.SequentialIDs{})
+
+	token, err := tokens.Generate(models.User{ID: 42})
+	if err != nil {
+		t.Fatalf("generate: %v", err)
+	}
+
+	clk.Advance(59 * time.Minute)
+	claims, err := tokens.Parse(token)
+	if err != nil {
+		t.Fatalf("parse before expiry: %v", err)
+	}
+	if claims.UserID != 42 {
+		t.Fatalf("expected subject 42, got %d", claims.UserID)
+	}
+	if want := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC); !claims.IssuedAt.Equal(want) {
+		t.Fatalf("issued at = %s, want %s", claims.IssuedAt, want)
+	}
+
+	clk.Advance(2 * time.Minute)
+	if _, err := tokens.Parse(token); err == nil {
+		t.Fatal("expected token to be expired")
+	}
+}
+
+func TestTokenCarriesRegion(t *testing.T) {
+	clk := storagetest.NewFakeClock(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	tokens := NewTokenManager(SigningKey{Secret: "secret"}, nil, "test", "eu-west", time.Hour, clk, &storagetest.SequentialIDs{})
+
+	token, err := tokens.Generate(models.User{ID: 7})
+	if err != nil {
+		t.Fatalf("generate: %v", err)
+	}
+	claims := jwt.MapClaims{}
+	if _, _, err := jwt.NewParser().ParseUnverified(token, claims); err != nil {
+		t.Fatalf("decode claims: %v", err)
+	}
+	if claims["region"] != "eu-west" {
+		t.Fatalf("region claim = %v, want eu-west", claims["region"])
+	}
+}
+
+func TestTokenRotationAcceptsRetiringKeys(t *testing.T) {
+	clk := storagetest.NewFakeClock(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	ids := &storagetest.SequentialIDs{}
+	legacy := NewTokenManager(SigningKey{Secret: "old"}, nil, "test", "", time.Hour, clk, ids)
+	before := NewTokenManager(SigningKey{ID: "k1", Secret: "old"}, nil, "test", "", time.Hour, clk, ids)
+	after := NewTokenManager(SigningKey{ID: "k2", Secret: "new"}, []SigningKey{{ID: "k1", Secret: "old"}}, "test", "", time.Hour, clk, ids)
+	dropped := NewTokenManager(SigningKey{ID: "k2", Secret: "new"}, nil, "test", "", time.Hour, clk, ids)
+
+	for name, issuer := range map[string]*TokenManager{"without kid": legacy, "retiring kid": before, "current kid": after} {
+		token, err := issuer.Generate(models.User{ID: 9})
+		if err != nil {
+			t.Fatalf("%s: generate: %v", name, err)
+		}
+		if claims, err := after.Parse(token); err != nil || claims.UserID != 9 {
+			t.Fatalf("%s: parse after rotation = %d, %v", name, claims.UserID, err)
+		}
+	}
+
+	old, _ := before.Generate(models.User{ID: 9})
+	if _, err := dropped.Parse(old); err == nil {
+		t.Fatal("tokens signed with a removed key must be rejected")
+	}
+	forged, _ := NewTokenManager(SigningKey{ID: "k1", Secret: "guess"}, nil, "test", "", time.Hour, clk, ids).Generate(models.User{ID: 9})
+	if _, err := after.Parse(forged); err == nil {
+		t.Fatal("a token whose signature does not match its kid must be rejected")
+	}
+}
+
+func TestTokenScopes(t *testing.T) {
+	clk := storagetest.NewFakeClock(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	tokens := NewTokenManager(SigningKey{Secret: "secret"}, nil, "test", "", time.Hour, clk, &storagetest.SequentialIDs{})
+
+	for name, tc := range map[string]struct {
+		scopes []string
+		want   []string
+	}{
+		"unrestricted": {scopes: nil, want: nil},
+		"narrowed":     {scopes: []string{"stats:read", "notes:read"}, want: []string{"stats:read", "notes:read"}},
+	} {
+		token, err := tokens.GenerateScoped(models.User{ID: 3}, tc.scopes)
+		if err != nil {
+			t.Fatalf("%s: generate: %v", name, err)
+		}
+		claims, err := tokens.Parse(token)
+		if err != nil {
+			t.Fatalf("%s: parse: %v", name, err)
+		}
+		if !slices.Equal(claims.Scopes, tc.want) || (claims.Scopes == nil) != (tc.want == nil) {
+			t.Fatalf("%s: scopes = %#v, want %#v", name, claims.Scopes, tc.want)
+		}
+	}
+}
+
+func TestTokenCarriesEffectivePermissions(t *testing.T) {
+	clk := storagetest.NewFakeClock(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	tokens := NewTokenManager(SigningKey{Secret: "secret"}, nil, "test", "", time.Hour, clk, &storagetest.SequentialIDs{})
+	user := models.User{ID: 5, Permissions: []string{"game:play", "bonus:claim"}}
+
+	for name, tc := range map[string]struct {
+		scopes []string
+		want   []any
+	}{
+		"unrestricted": {scopes: nil, want: []any{"game:play", "bonus:claim"}},
+		"narrowed":     {scopes: []string{"bonus:claim"}, want: []any{"bonus:claim"}},
+	} {
+		token, err := tokens.GenerateScoped(user, tc.scopes)
+		if err != nil {
+			t.Fatalf("%s: generate: %v", name, err)
+		}
+		claims := jwt.MapClaims{}
+		if _, _, err := jwt.NewParser().ParseUnverified(token, claims); err != nil {
+			t.Fatalf("%s: decode claims: %v", name, err)
+		}
+		if got, _ := claims["permissions"].([]any); !slices.Equal(got, tc.want) {
+			t.Fatalf("%s: permissions claim = %v, want %v", name, claims["permissions"], tc.want)
+		}
+	}
+}
+
+func TestParseReturnsIssuedClaims(t *testing.T) {
+	clk := storagetest.NewFakeClock(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	tokens := NewTokenManager(SigningKey{Secret: "secret"}, nil, "test", "eu-west", time.Hour, clk, &storagetest.SequentialIDs{})
+	user := models.User{ID: 5, Username: "ana", Role: models.NormalUser, Permissions: []string{"game:play", "bonus:claim"}}
+
+	first, _ := tokens.Generate(user)
+	second, _ := tokens.Generate(user)
+	claims, err := tokens.Parse(first)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if claims.UserID != 5 || claims.Username != "ana" || claims.Role != models.NormalUser || claims.Region != "eu-west" || claims.Tenant != "" {
+		t.Fatalf("claims = %+v", claims)
+	}
+	if !slices.Equal(claims.Permissions, user.Permissions) || claims.Scopes != nil {
+		t.Fatalf("permissions = %v, scopes = %v", claims.Permissions, claims.Scopes)
+	}
+	if again, _ := tokens.Parse(second); claims.ID == "" || again.ID == claims.ID {
+		t.Fatalf("token IDs %q and %q, want unique", claims.ID, again.ID)
+	}
+
+	// A token whose claims do not have the issued types is refused.
+	forged := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"iss": "test", "sub": "5", "scope": 7})
+	raw, _ := forged.SignedString([]byte("secret"))
+	if _, err := tokens.Parse(raw); err == nil {
+		t.Fatal("a scope claim that is not a string must be rejected")
+	}
+}
+
+func TestImpersonationTokenNamesTheActor(t *testing.T) {
+	clk := storagetest.NewFakeClock(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	tokens := NewTokenManager(SigningKey{Secret: "secret"}, nil, "test", "", time.Hour, clk, &storagetest.SequentialIDs{})
+
+	token, issued, err := tokens.GenerateImpersonation(models.User{ID: 5}, 2, 10*time.Minute)
+	if err != nil {
+		t.Fatalf("generate: %v", err)
+	}
+	claims, err := tokens.Parse(token)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if claims.UserID != 5 || claims.ActorID != 2 || claims.ID != issued.ID || !claims.ExpiresAt.Equal(clk.Now().Add(10*time.Minute)) {
+		t.Fatalf("claims = %+v, issued %+v", claims, issued)
+	}
+	if plain, _ := tokens.Generate(models.User{ID: 5}); plain != "" {
+		if claims, _ := tokens.Parse(plain); claims.ActorID != 0 {
+			t.Fatalf("a sign-in token names actor %d", claims.ActorID)
+		}
+	}
+}
@@ -0,0 +1,41 @@
+// Package blob stores opaque files (avatars, KYC documents, exports) behind a
+// small interface with local-filesystem and S3-compatible implementations.
+package blob
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"time"
+)
+
+// ErrNotFound indicates no object exists under the key.
+var ErrNotFound = errors.New("blob not found")
+
+// ErrInvalidKey indicates a key that is empty or tries to escape its namespace.
+var ErrInvalidKey = errors.New("invalid blob key")
+
+// Store persists objects by key. Keys are slash-separated paths such as
+// "avatars/42.png".
+type Store interface {
+	Put(ctx context.Context, key string, body io.Reader, contentType string) error
+	// Get opens the object; callers must close the returned reader.
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// SignedURL returns a URL granting read access to the object until it expires.
+	SignedURL(ctx context.Context, key string, expires time.Duration) (string, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// validKey rejects keys that are empty, absolute, or contain dot segments.
+func validKey(key string) bool {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "\\") {
+		return false
+	}
+	for _, part := range strings.Split(key, "/") {
+		if part == "" || part == "." || part == ".." {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,150 @@
+package blob
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// LocalStore keeps objects on the local filesystem for development. Signed URLs
+// point at Handler, which must be mounted at the URL prefix's path.
+type LocalStore struct {
+	root    string
+	baseURL string
+	key     []byte
+	now     func() time.Time
+}
+
+// NewLocal stores objects under root. baseURL is the externally reachable prefix
+// that Handler serves (e.g. http://localhost:8080/blobs) and signingKey
+// authenticates the URLs it hands out.
+func NewLocal(root, baseURL, signingKey string) (*LocalStore, error) {
+	if err := os.MkdirAll(root, 0o755); err != nil {
+		return nil, fmt.Errorf("create blob dir: %w", err)
+	}
+	return &LocalStore{
+		root:    root,
+		baseURL: strings.TrimRight(baseURL, "/"),
+		key:     []byte(signingKey),
+		now:     time.Now,
+	}, nil
+}
+
+// Put writes the object atomically by renaming a temp file into place.
+func (s *LocalStore) Put(_ context.Context, key string, body io.Reader, _ string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("create blob dir: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return fmt.Errorf("create temp blob: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := io.Copy(tmp, body); err != nil {
+		tmp.Close()
+		return fmt.Errorf("write blob: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("write blob: %w", err)
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Get opens the object for reading.
+func (s *LocalStore) Get(_ context.Context, key string) (io.ReadCloser, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	return f, err
+}
+
+// SignedURL returns a Handler URL carrying an expiry and HMAC signature.
+func (s *LocalStore) SignedURL(_ context.Context, key string, expires time.Duration) (string, error) {
+	if !validKey(key) {
+		return "", ErrInvalidKey
+	}
+	exp := strconv.FormatInt(s.now().Add(expires).Unix(), 10)
+	q := url.Values{"expires": {exp}, "signature": {s.sign(key, exp)}}
+	return s.baseURL + "/" + (&url.URL{Path: key}).EscapedPath() + "?" + q.Encode(), nil
+}
+
+// Delete removes the object. Deleting a missing object is not an error.
+func (s *LocalStore) Delete(_ context.Context, key string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// Handler serves objects addressed by SignedURL. Mount it with the URL prefix
+// stripped, e.g. http.StripPrefix("/blobs", store.Handler()).
+func (s *LocalStore) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		key := strings.TrimPrefix(r.URL.Path, "/")
+		exp := r.URL.Query().Get("expires")
+		expUnix, err := strconv.ParseInt(exp, 10, 64)
+		if err != nil || s.now().Unix() > expUnix ||
+			!hmac.Equal([]byte(r.URL.Query().Get("signature")), []byte(s.sign(key, exp))) {
+			http.Error(w, "invalid or expired signature", http.StatusForbidden)
+			return
+		}
+		rc, err := s.Get(r.Context(), key)
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		defer rc.Close()
+		if ct := mime.TypeByExtension(filepath.Ext(key)); ct != "" {
+			w.Header().Set("Content-Type", ct)
+		}
+		if f, ok := rc.(*os.File); ok {
+			if info, err := f.Stat(); err == nil {
+				http.ServeContent(w, r, key, info.ModTime(), f)
+				return
+			}
+		}
+		io.Copy(w, rc)
+	})
+}
+
+func (s *LocalStore) path(key string) (string, error) {
+	if !validKey(key) {
+		return "", ErrInvalidKey
+	}
+	return filepath.Join(s.root, filepath.FromSlash(key)), nil
+}
+
+func (s *LocalStore) sign(key, expires string) string {
+	mac := hmac.New(sha256.New, s.key)
+	mac.Write([]byte(key + "\n" + expires))
+	return hex.EncodeToString(mac.Sum(nil))
+}
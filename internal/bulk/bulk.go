@@ -0,0 +1,294 @@
+// Package bulk runs long jobs, e.g. backfills, a batch at a time on the
+// background job queue. After each batch the job's checkpoint is saved, so a
+// job whose instance crashed, or that failed and was resumed, carries on from
+// the last finished batch instead of starting over.
+//
+// A running job is owned by one instance. Owners save progress with every
+// batch; a job that has not progressed for the stale window is presumed
+// orphaned and claimed by whichever instance sweeps first. A batch may
+// therefore run twice, once on the old owner and once on the new one, so
+// tasks must be safe to repeat from a checkpoint.
+package bulk
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/hongminglow/all-in-be/internal/clock"
+	"github.com/hongminglow/all-in-be/internal/jobs"
+	"github.com/hongminglow/all-in-be/internal/models"
+	"github.com/hongminglow/all-in-be/internal/storage"
+)
+
+var (
+	// ErrUnknownKind is returned by Submit for a kind no task is registered for.
+	ErrUnknownKind = errors.New("unknown bulk job kind")
+	// ErrNotAllowed is returned by Resume and Cancel when the job is not in a
+	// status they apply to.
+	ErrNotAllowed = errors.New("bulk job cannot make that transition")
+)
+
+// batchAttempts is how often a batch is tried before its job is marked failed.
+const batchAttempts = 3
+
+// Batch is the outcome of one step of a task.
+type Batch struct {
+	// Checkpoint is passed to the next step, or to the first step after a
+	// resume.
+	Checkpoint json.RawMessage
+	// Processed counts the items this step handled.
+	Processed int64
+	// Done reports that nothing is left to do.
+	Done bool
+}
+
+// Task does the work of one kind of job. Step does one batch from checkpoint,
+// which is nil for the first, and must finish well within the stale window.
+type Task interface {
+	Step(ctx context.Context, checkpoint json.RawMessage) (Batch, error)
+}
+
+// Queue is the part of the job queue batches run on.
+type Queue interface {
+	Enqueue(job jobs.Job) error
+}
+
+// Runner submits, advances and recovers bulk jobs.
+type Runner struct {
+	store      storage.BulkJobStore
+	queue      Queue
+	clock      clock.Clock
+	owner      string
+	staleAfter time.Duration
+	tasks      map[string]Task
+
+	mu     sync.Mutex
+	active map[int64]bool
+	closed bool
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewRunner builds a runner. owner must be unique per instance. Register the
+// tasks, then call Start to recover orphaned jobs.
+func NewRunner(store storage.BulkJobStore, queue Queue, clk clock.Clock, owner string, staleAfter time.Duration) *Runner {
+	return &Runner{
+		store:      store,
+		queue:      queue,
+		clock:      clk,
+		owner:      owner,
+		staleAfter: staleAfter,
+		tasks:      make(map[string]Task),
+		active:     make(map[int64]bool),
+	}
+}
+
+// Register sets the task for a kind. It must be called before Start.
+func (r *Runner) Register(kind string, task Task) {
+	r.tasks[kind] = task
+}
+
+// Submit starts a job of the given kind on this instance.
+func (r *Runner) Submit(ctx context.Context, kind string, createdBy int64) (models.BulkJob, error) {
+	if _, ok := r.tasks[kind]; !ok {
+		return models.BulkJob{}, fmt.Errorf("%w %q", ErrUnknownKind, kind)
+	}
+	job, err := r.store.CreateBulkJob(ctx, models.BulkJob{
+		Kind:      kind,
+		Status:    models.BulkJobRunning,
+		Owner:     r.owner,
+		CreatedBy: createdBy,
+	})
+	if err != nil {
+		return models.BulkJob{}, err
+	}
+	r.schedule(job.ID)
+	return job, nil
+}
+
+// Resume restarts a failed job from its last checkpoint on this instance.
+func (r *Runner) Resume(ctx context.Context, id int64) (models.BulkJob, error) {
+	job, err := r.transition(ctx, id, []string{models.BulkJobFailed}, models.BulkJobRunning)
+	if err != nil {
+		return models.BulkJob{}, err
+	}
+	r.schedule(job.ID)
+	return job, nil
+}
+
+// Cancel stops a running or failed job. A batch in progress finishes, but its
+// progress is not saved and no further batch runs.
+func (r *Runner) Cancel(ctx context.Context, id int64) (models.BulkJob, error) {
+	return r.transition(ctx, id, []string{models.BulkJobRunning, models.BulkJobFailed}, models.BulkJobCancelled)
+}
+
+// transition tells a missing job apart from one in the wrong status.
+func (r *Runner) transition(ctx context.Context, id int64, from []string, status string) (models.BulkJob, error) {
+	job, err := r.store.TransitionBulkJob(ctx, id, from, status, r.owner, r.clock.Now())
+	if errors.Is(err, storage.ErrNotFound) {
+		if _, findErr := r.store.FindBulkJob(ctx, id); findErr == nil {
+			return models.BulkJob{}, ErrNotAllowed
+		}
+	}
+	return job, err
+}
+
+// Start claims orphaned jobs now and then every half stale window, until
+// Close. It does nothing without a stale window.
+func (r *Runner) Start() {
+	if r.staleAfter <= 0 || r.stop != nil {
+		return
+	}
+	r.stop, r.done = make(chan struct{}), make(chan struct{})
+	go func() {
+		defer close(r.done)
+		ticker := time.NewTicker(r.staleAfter / 2)
+		defer ticker.Stop()
+		for {
+			if err := r.sweep(context.Background()); err != nil {
+				log.Printf("bulk: claim stale jobs: %v", err)
+			}
+			select {
+			case <-r.stop:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Close stops the sweep and lets batches in progress finish without
+// scheduling the next one. Jobs left running are claimed by another instance,
+// or by this one after a restart, once they go stale.
+func (r *Runner) Close() {
+	r.mu.Lock()
+	r.closed = true
+	r.mu.Unlock()
+	if r.stop == nil {
+		return
+	}
+	close(r.stop)
+	<-r.done
+	r.stop = nil
+}
+
+// sweep claims the jobs whose owner stopped saving progress and schedules
+// those not already advancing here.
+func (r *Runner) sweep(ctx context.Context) error {
+	now := r.clock.Now()
+	claimed, err := r.store.ClaimStaleBulkJobs(ctx, r.owner, now.Add(-r.staleAfter), now)
+	if err != nil {
+		return err
+	}
+	for _, job := range claimed {
+		log.Printf("bulk: resuming stale %s job %d after %d batches", job.Kind, job.ID, job.Batches)
+		r.schedule(job.ID)
+	}
+	return nil
+}
+
+// schedule queues the next batch of a job unless one is already queued or
+// running here.
+func (r *Runner) schedule(id int64) {
+	r.mu.Lock()
+	if r.active[id] || r.closed {
+		r.mu.Unlock()
+		return
+	}
+	r.active[id] = true
+	r.mu.Unlock()
+	r.enqueue(id)
+}
+
+func (r *Runner) enqueue(id int64) {
+	err := r.queue.Enqueue(jobs.Job{
+		Type:        "bulk_job",
+		Name:        fmt.Sprintf("bulk job %d", id),
+		Priority:    jobs.PriorityLow,
+		MaxAttempts: batchAttempts,
+		Run: func(ctx context.Context, attempt int) error {
+			more, err := r.step(ctx, id)
+			switch {
+			case err != nil && attempt >= batchAttempts:
+				r.fail(id, err)
+				r.release(id)
+			case err != nil:
+				// The queue retries the batch.
+			case more:
+				r.enqueue(id)
+			default:
+				r.release(id)
+			}
+			return err
+		},
+	})
+	if err != nil {
+		// The job goes stale and the sweep schedules it again.
+		log.Printf("bulk: queue job %d: %v", id, err)
+		r.release(id)
+	}
+}
+
+func (r *Runner) release(id int64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.active, id)
+}
+
+// step runs one batch and reports whether another should follow. It stops
+// quietly when the job was cancelled or claimed by another instance.
+func (r *Runner) step(ctx context.Context, id int64) (bool, error) {
+	job, err := r.store.FindBulkJob(ctx, id)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+	if job.Status != models.BulkJobRunning || job.Owner != r.owner {
+		return false, nil
+	}
+	task, ok := r.tasks[job.Kind]
+	if !ok {
+		// Submitted by a release that knows the kind; leave it for that one.
+		log.Printf("bulk: no task for %s job %d", job.Kind, job.ID)
+		return false, nil
+	}
+	batch, err := task.Step(ctx, job.Checkpoint)
+	if err != nil {
+		return false, err
+	}
+	err = r.store.SaveBulkJobProgress(ctx, id, r.owner, batch.Checkpoint, batch.Processed, r.clock.Now())
+	if errors.Is(err, storage.ErrNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if batch.Done {
+		err := r.store.FinishBulkJob(ctx, id, r.owner, models.BulkJobSucceeded, "", r.clock.Now())
+		if err != nil && !errors.Is(err, storage.ErrNotFound) {
+			return false, err
+		}
+		return false, nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return !r.closed, nil
+}
+
+// fail records the error that stopped a job so an operator can resume it.
+func (r *Runner) fail(id int64, cause error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	err := r.store.FinishBulkJob(ctx, id, r.owner, models.BulkJobFailed, cause.Error(), r.clock.Now())
+	if err != nil && !errors.Is(err, storage.ErrNotFound) {
+		log.Printf("bulk: mark job %d failed: %v", id, err)
+	}
+}
@@ -0,0 +1,198 @@
+package bulk
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hongminglow/all-in-be/internal/jobs"
+	"github.com/hongminglow/all-in-be/internal/models"
+	"github.com/hongminglow/all-in-be/internal/storage/storagetest"
+)
+
+// countTask counts to total two at a time, recording every number it sees,
+// and fails at brokenAt while broken is set.
+type countTask struct {
+	total, brokenAt int
+
+	mu     sync.Mutex
+	broken bool
+	seen   []int
+}
+
+func (c *countTask) Step(_ context.Context, checkpoint json.RawMessage) (Batch, error) {
+	next := 0
+	if checkpoint != nil {
+		if err := json.Unmarshal(checkpoint, &next); err != nil {
+			return Batch{}, err
+		}
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.broken && next == c.brokenAt {
+		return Batch{}, errors.New("downstream unavailable")
+	}
+	end := min(next+2, c.total)
+	for i := next; i < end; i++ {
+		c.seen = append(c.seen, i)
+	}
+	cp, _ := json.Marshal(end)
+	return Batch{Checkpoint: cp, Processed: int64(end - next), Done: end == c.total}, nil
+}
+
+func (c *countTask) numbers() []int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]int(nil), c.seen...)
+}
+
+type fixture struct {
+	ctx   context.Context
+	clock *storagetest.FakeClock
+	store *storagetest.MemoryStore
+	queue *jobs.Queue
+	admin models.User
+}
+
+func newFixture(t *testing.T) fixture {
+	t.Helper()
+	ctx := context.Background()
+	clk := storagetest.NewFakeClock(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	store := storagetest.NewMemoryStore(clk)
+	admin, err := store.CreateUser(ctx, models.User{Username: "ops", Email: "ops@example.com", Role: models.AdminUser})
+	if err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	queue := jobs.NewQueue(1, 16, time.Millisecond)
+	t.Cleanup(func() { queue.Close(context.Background()) })
+	return fixture{ctx: ctx, clock: clk, store: store, queue: queue, admin: admin}
+}
+
+func (f fixture) runner(owner string, task Task) *Runner {
+	r := NewRunner(f.store, f.queue, f.clock, owner, time.Minute)
+	r.Register("count", task)
+	return r
+}
+
+// waitFor polls the job until it reaches status.
+func (f fixture) waitFor(t *testing.T, id int64, status string) models.BulkJob {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		job, err := f.store.FindBulkJob(f.ctx, id)
+		if err != nil {
+			t.Fatalf("find job: %v", err)
+		}
+		if job.Status == status {
+			return job
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("job %d is %s, want %s", id, job.Status, status)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestResumeContinuesFromCheckpoint(t *testing.T) {
+	f := newFixture(t)
+	task := &countTask{total: 7, brokenAt: 4, broken: true}
+	r := f.runner("a", task)
+
+	job, err := r.Submit(f.ctx, "count", f.admin.ID)
+	if err != nil {
+		t.Fatalf("submit: %v", err)
+	}
+	failed := f.waitFor(t, job.ID, models.BulkJobFailed)
+	if failed.Processed != 4 || failed.Batches != 2 || failed.Error != "downstream unavailable" {
+		t.Fatalf("failed job = %+v, want 4 items in 2 batches and the error", failed)
+	}
+
+	task.mu.Lock()
+	task.broken = false
+	task.mu.Unlock()
+	if _, err := r.Resume(f.ctx, job.ID); err != nil {
+		t.Fatalf("resume: %v", err)
+	}
+	done := f.waitFor(t, job.ID, models.BulkJobSucceeded)
+	if done.Processed != 7 || done.Batches != 4 || done.Error != "" || done.FinishedAt == nil {
+		t.Fatalf("finished job = %+v", done)
+	}
+	seen := task.numbers()
+	for i, n := range seen {
+		if n != i {
+			t.Fatalf("task saw %v, want every number once in order", seen)
+		}
+	}
+	if len(seen) != 7 {
+		t.Fatalf("task saw %v, want 0 to 6", seen)
+	}
+
+	if _, err := r.Resume(f.ctx, job.ID); !errors.Is(err, ErrNotAllowed) {
+		t.Fatalf("resume finished job: err = %v, want ErrNotAllowed", err)
+	}
+}
+
+func TestSweepClaimsStaleJobs(t *testing.T) {
+	f := newFixture(t)
+	task := &countTask{total: 5}
+	// An instance that crashed after its first batch.
+	orphan, err := f.store.CreateBulkJob(f.ctx, models.BulkJob{Kind: "count", Status: models.BulkJobRunning, Owner: "gone", CreatedBy: f.admin.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := f.store.SaveBulkJobProgress(f.ctx, orphan.ID, "gone", []byte("2"), 2, f.clock.Now()); err != nil {
+		t.Fatal(err)
+	}
+	r := f.runner("b", task)
+
+	f.clock.Advance(30 * time.Second)
+	if err := r.sweep(f.ctx); err != nil {
+		t.Fatal(err)
+	}
+	if job, _ := f.store.FindBulkJob(f.ctx, orphan.ID); job.Owner != "gone" {
+		t.Fatalf("job claimed by %q before it went stale", job.Owner)
+	}
+
+	f.clock.Advance(time.Minute)
+	if err := r.sweep(f.ctx); err != nil {
+		t.Fatal(err)
+	}
+	done := f.waitFor(t, orphan.ID, models.BulkJobSucceeded)
+	if done.Processed != 5 {
+		t.Fatalf("processed = %d, want 5", done.Processed)
+	}
+	if seen := task.numbers(); len(seen) != 3 || seen[0] != 2 {
+		t.Fatalf("task saw %v, want to start from the checkpoint at 2", seen)
+	}
+	// The old owner coming back finds the job is no longer its own.
+	if err := f.store.SaveBulkJobProgress(f.ctx, orphan.ID, "gone", []byte("4"), 2, f.clock.Now()); err == nil {
+		t.Fatal("previous owner saved progress on a claimed job")
+	}
+}
+
+func TestCancel(t *testing.T) {
+	f := newFixture(t)
+	r := f.runner("a", &countTask{total: 4, brokenAt: 0, broken: true})
+
+	if _, err := r.Submit(f.ctx, "reindex", f.admin.ID); !errors.Is(err, ErrUnknownKind) {
+		t.Fatalf("submit unknown kind: err = %v, want ErrUnknownKind", err)
+	}
+	job, err := r.Submit(f.ctx, "count", f.admin.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.waitFor(t, job.ID, models.BulkJobFailed)
+	cancelled, err := r.Cancel(f.ctx, job.ID)
+	if err != nil || cancelled.Status != models.BulkJobCancelled {
+		t.Fatalf("cancel = %+v, %v", cancelled, err)
+	}
+	if _, err := r.Resume(f.ctx, job.ID); !errors.Is(err, ErrNotAllowed) {
+		t.Fatalf("resume cancelled job: err = %v, want ErrNotAllowed", err)
+	}
+	if _, err := r.Cancel(f.ctx, job.ID+100); errors.Is(err, ErrNotAllowed) || err == nil {
+		t.Fatalf("cancel missing job: err = %v, want not found", err)
+	}
+}
@@ -0,0 +1,171 @@
+// Package cache keeps the in-process caches of every instance coherent. Each
+// cache registers how to drop itself under a name; invalidating a name drops
+// it here and, when a transport is attached, on every other instance too.
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"sync"
+)
+
+// Names of the caches the server keeps in process.
+const (
+	// RateLimits holds the per-tenant rate-limit policies.
+	RateLimits = "rate_limits"
+	// IPRisk holds the IP risk policies.
+	IPRisk = "ip_risk"
+	// Stats holds the admin dashboard aggregates.
+	Stats = "stats"
+	// Leaderboards holds the top of each leaderboard.
+	Leaderboards = "leaderboards"
+	// BigWins holds the public big wins feed.
+	BigWins = "big_wins"
+	// DisposableEmails holds the disposable email domain list.
+	DisposableEmails = "disposable_emails"
+	// Sessions holds the session state tokens are checked against.
+	Sessions = "sessions"
+)
+
+var (
+	// ErrUnknownCache is returned for a name no cache registered.
+	ErrUnknownCache = errors.New("unknown cache")
+	// ErrBroadcast is returned when the caches were dropped here but the
+	// other instances could not be told.
+	ErrBroadcast = errors.New("broadcast cache invalidation")
+)
+
+// Transport carries invalidations between instances.
+type Transport interface {
+	Publish(ctx context.Context, payload []byte) error
+	Close() error
+}
+
+// Message is what instances broadcast to each other.
+type Message struct {
+	// Origin identifies the instance that sent the message, which has
+	// already dropped the caches itself.
+	Origin string   `json:"origin"`
+	Caches []string `json:"caches"`
+}
+
+// Invalidator drops named caches on this instance and broadcasts the names to
+// the others.
+type Invalidator struct {
+	origin string
+
+	mu        sync.RWMutex
+	drops     map[string]func()
+	transport Transport
+}
+
+// NewInvalidator returns an invalidator that only reaches this instance until
+// a transport is attached. origin must be unique per instance.
+func NewInvalidator(origin string) *Invalidator {
+	return &Invalidator{origin: origin, drops: make(map[string]func())}
+}
+
+// Handle registers drop as the way to empty the named cache.
+func (i *Invalidator) Handle(name string, drop func()) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.drops[name] = drop
+}
+
+// Attach broadcasts later invalidations through t. Messages t receives should
+// be passed to Receive.
+func (i *Invalidator) Attach(t Transport) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.transport = t
+}
+
+// Broadcasts reports whether invalidations reach other instances.
+func (i *Invalidator) Broadcasts() bool {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	return i.transport != nil
+}
+
+// Names lists the registered caches in order.
+func (i *Invalidator) Names() []string {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	names := make([]string, 0, len(i.drops))
+	for name := range i.drops {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// Invalidate drops the named caches here, then broadcasts them. Nothing is
+// dropped when a name is unknown. A broadcast error means the other instances
+// keep their copies until they expire.
+func (i *Invalidator) Invalidate(ctx context.Context, names ...string) error {
+	i.mu.RLock()
+	drops := make([]func(), 0, len(names))
+	for _, name := range names {
+		drop, ok := i.drops[name]
+		if !ok {
+			i.mu.RUnlock()
+			return fmt.Errorf("%w %q", ErrUnknownCache, name)
+		}
+		drops = append(drops, drop)
+	}
+	transport := i.transport
+	i.mu.RUnlock()
+
+	for _, drop := range drops {
+		drop()
+	}
+	if transport == nil || len(names) == 0 {
+		return nil
+	}
+	payload, err := json.Marshal(Message{Origin: i.origin, Caches: names})
+	if err != nil {
+		return err
+	}
+	if err := transport.Publish(ctx, payload); err != nil {
+		return fmt.Errorf("%w: %v", ErrBroadcast, err)
+	}
+	return nil
+}
+
+// Func returns a callback that invalidates the named caches, for code that
+// only needs to say its data changed. Broadcast errors are logged.
+func (i *Invalidator) Func(names ...string) func() {
+	return func() {
+		if err := i.Invalidate(context.Background(), names...); err != nil {
+			log.Printf("cache: invalidate %v: %v", names, err)
+		}
+	}
+}
+
+// Receive applies an invalidation broadcast by another instance. Names this
+// instance does not know, e.g. from a newer release, are skipped.
+func (i *Invalidator) Receive(payload []byte) {
+	var msg Message
+	if err := json.Unmarshal(payload, &msg); err != nil {
+		log.Printf("cache: dropping malformed invalidation: %v", err)
+		return
+	}
+	if msg.Origin == i.origin {
+		return
+	}
+	i.mu.RLock()
+	drops := make([]func(), 0, len(msg.Caches))
+	for _, name := range msg.Caches {
+		if drop, ok := i.drops[name]; ok {
+			drops = append(drops, drop)
+		}
+	}
+	i.mu.RUnlock()
+	for _, drop := range drops {
+		drop()
+	}
+}
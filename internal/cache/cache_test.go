@@ -0,0 +1,168 @@
+package cache
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type fakeRedisServer struct {
+	ln net.Listener
+
+	mu    sync.Mutex
+	auths [][]any
+	subs  map[string][]*fakeRedisConn
+}
+
+type fakeRedisConn struct {
+	mu sync.Mutex
+	w  *bufio.Writer
+}
+
+func (c *fakeRedisConn) write(format string, args ...any) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	fmt.Fprintf(c.w, format, args...)
+	c.w.Flush()
+}
+
+func startFakeRedis(t *testing.T) *fakeRedisServer {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	s := &fakeRedisServer{ln: ln, subs: make(map[string][]*fakeRedisConn)}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	t.Cleanup(func() { ln.Close() })
+	return s
+}
+
+func (s *fakeRedisServer) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	c := &fakeRedisConn{w: bufio.NewWriter(conn)}
+	for {
+		reply, err := readReply(r)
+		if err != nil {
+			return
+		}
+		args, _ := reply.([]any)
+		if len(args) == 0 {
+			return
+		}
+		switch args[0] {
+		case "AUTH":
+			s.mu.Lock()
+			s.auths = append(s.auths, args[1:])
+			s.mu.Unlock()
+			c.write("+OK\r\n")
+		case "SUBSCRIBE":
+			channel := args[1].(string)
+			s.mu.Lock()
+			s.subs[channel] = append(s.subs[channel], c)
+			s.mu.Unlock()
+			c.write("*3\r\n$9\r\nsubscribe\r\n$%d\r\n%s\r\n:1\r\n", len(channel), channel)
+		case "PUBLISH":
+			channel, payload := args[1].(string), args[2].(string)
+			s.mu.Lock()
+			targets := s.subs[channel]
+			s.mu.Unlock()
+			for _, sub := range targets {
+				sub.write("*3\r\n$7\r\nmessage\r\n$%d\r\n%s\r\n$%d\r\n%s\r\n", len(channel), channel, len(payload), payload)
+			}
+			c.write(":%d\r\n", len(targets))
+		default:
+			c.write("-ERR unknown command\r\n")
+		}
+	}
+}
+
+// instance is one server's invalidator, counting how often each cache dropped.
+type instance struct {
+	*Invalidator
+	drops map[string]*atomic.Int32
+}
+
+func newInstance(t *testing.T, origin, url string) instance {
+	t.Helper()
+	inv := NewInvalidator(origin)
+	in := instance{Invalidator: inv, drops: map[string]*atomic.Int32{}}
+	for _, name := range []string{RateLimits, Stats} {
+		n := &atomic.Int32{}
+		in.drops[name] = n
+		inv.Handle(name, func() { n.Add(1) })
+	}
+	redis, err := NewRedis(context.Background(), RedisConfig{URL: url, Channel: "allin.cache"}, inv.Receive)
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	t.Cleanup(func() { redis.Close() })
+	inv.Attach(redis)
+	return in
+}
+
+func TestRedisBroadcastsInvalidations(t *testing.T) {
+	server := startFakeRedis(t)
+	url := "redis://svc:hunter2@" + server.ln.Addr().String()
+	a := newInstance(t, "a", url)
+	b := newInstance(t, "b", url)
+
+	if err := a.Invalidate(context.Background(), RateLimits); err != nil {
+		t.Fatalf("invalidate: %v", err)
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for b.drops[RateLimits].Load() == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if got := b.drops[RateLimits].Load(); got != 1 {
+		t.Fatalf("other instance dropped rate limits %d times, want 1", got)
+	}
+	// Give a's own message time to come back before checking it was skipped.
+	time.Sleep(50 * time.Millisecond)
+	if got := a.drops[RateLimits].Load(); got != 1 {
+		t.Fatalf("sending instance dropped rate limits %d times, want 1", got)
+	}
+	if a.drops[Stats].Load() != 0 || b.drops[Stats].Load() != 0 {
+		t.Fatal("stats dropped without being named")
+	}
+
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	for _, auth := range server.auths {
+		if len(auth) != 2 || auth[0] != "svc" || auth[1] != "hunter2" {
+			t.Fatalf("AUTH arguments = %v", server.auths)
+		}
+	}
+}
+
+func TestInvalidateRejectsUnknownNames(t *testing.T) {
+	inv := NewInvalidator("a")
+	var dropped bool
+	inv.Handle(Stats, func() { dropped = true })
+
+	if err := inv.Invalidate(context.Background(), Stats, "sessions"); !errors.Is(err, ErrUnknownCache) {
+		t.Fatalf("err = %v, want ErrUnknownCache", err)
+	}
+	if dropped {
+		t.Fatal("known cache dropped although the request named an unknown one")
+	}
+	inv.Receive([]byte(`{"origin":"b","caches":["sessions","stats"]}`))
+	if !dropped {
+		t.Fatal("broadcast from another instance was not applied")
+	}
+}
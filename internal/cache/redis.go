@@ -0,0 +1,302 @@
+package cache
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RedisConfig locates the Redis server and names the channel invalidations
+// are broadcast on.
+type RedisConfig struct {
+	// URL is redis://[[user]:pass@]host:port.
+	URL     string
+	Channel string
+}
+
+// Redis broadcasts invalidations over Redis pub/sub, so every subscribed
+// instance receives each one. It speaks RESP directly, keeping one connection
+// subscribed and another for publishing, and resubscribes with backoff when
+// the subscription drops. Pub/sub is at-most-once: instances that are
+// disconnected miss invalidations and rely on their caches expiring.
+type Redis struct {
+	cfg      RedisConfig
+	addr     string
+	user     string
+	password string
+	deliver  func([]byte)
+
+	pubMu sync.Mutex
+	pub   net.Conn
+	pubR  *bufio.Reader
+
+	mu     sync.Mutex
+	sub    net.Conn
+	closed bool
+	done   chan struct{}
+}
+
+// NewRedis connects, subscribes to the channel and passes every message
+// received on it to deliver.
+func NewRedis(ctx context.Context, cfg RedisConfig, deliver func([]byte)) (*Redis, error) {
+	u, err := url.Parse(cfg.URL)
+	if err != nil || u.Scheme != "redis" || u.Host == "" {
+		return nil, fmt.Errorf("invalid Redis URL %q", cfg.URL)
+	}
+	t := &Redis{cfg: cfg, addr: u.Host, deliver: deliver, done: make(chan struct{})}
+	if u.Port() == "" {
+		t.addr = net.JoinHostPort(u.Hostname(), "6379")
+	}
+	if u.User != nil {
+		t.user = u.User.Username()
+		t.password, _ = u.User.Password()
+	}
+	conn, r, err := t.subscribe(ctx)
+	if err != nil {
+		return nil, err
+	}
+	t.sub = conn
+	go t.readLoop(conn, r)
+	return t, nil
+}
+
+// dial opens a connection and authenticates it.
+func (t *Redis) dial(ctx context.Context) (net.Conn, *bufio.Reader, error) {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", t.addr)
+	if err != nil {
+		return nil, nil, fmt.Errorf("dial redis: %w", err)
+	}
+	r := bufio.NewReader(conn)
+	if t.password != "" {
+		args := []string{"AUTH", t.password}
+		if t.user != "" {
+			args = []string{"AUTH", t.user, t.password}
+		}
+		_ = conn.SetDeadline(time.Now().Add(10 * time.Second))
+		if _, err := t.command(conn, r, args...); err != nil {
+			conn.Close()
+			return nil, nil, fmt.Errorf("redis auth: %w", err)
+		}
+		_ = conn.SetDeadline(time.Time{})
+	}
+	return conn, r, nil
+}
+
+// subscribe dials and subscribes to the channel.
+func (t *Redis) subscribe(ctx context.Context) (net.Conn, *bufio.Reader, error) {
+	conn, r, err := t.dial(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	_ = conn.SetDeadline(time.Now().Add(10 * time.Second))
+	if _, err := t.command(conn, r, "SUBSCRIBE", t.cfg.Channel); err != nil {
+		conn.Close()
+		return nil, nil, fmt.Errorf("redis subscribe: %w", err)
+	}
+	_ = conn.SetDeadline(time.Time{})
+	return conn, r, nil
+}
+
+// command writes one command and reads its reply.
+func (t *Redis) command(conn net.Conn, r *bufio.Reader, args ...string) (any, error) {
+	if _, err := conn.Write(encodeCommand(args...)); err != nil {
+		return nil, err
+	}
+	return readReply(r)
+}
+
+// Publish sends payload to the channel, connecting first if needed.
+func (t *Redis) Publish(ctx context.Context, payload []byte) error {
+	t.pubMu.Lock()
+	defer t.pubMu.Unlock()
+	if t.pub == nil {
+		conn, r, err := t.dial(ctx)
+		if err != nil {
+			return err
+		}
+		t.pub, t.pubR = conn, r
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = t.pub.SetDeadline(deadline)
+	} else {
+		_ = t.pub.SetDeadline(time.Now().Add(10 * time.Second))
+	}
+	if _, err := t.command(t.pub, t.pubR, "PUBLISH", t.cfg.Channel, string(payload)); err != nil {
+		// The connection may be out of step with its replies; start afresh.
+		t.pub.Close()
+		t.pub, t.pubR = nil, nil
+		return fmt.Errorf("redis publish: %w", err)
+	}
+	return nil
+}
+
+// Close disconnects and stops receiving.
+func (t *Redis) Close() error {
+	t.mu.Lock()
+	if t.closed {
+		t.mu.Unlock()
+		return nil
+	}
+	t.closed = true
+	sub := t.sub
+	t.mu.Unlock()
+	if sub != nil {
+		sub.Close()
+	}
+	<-t.done
+	t.pubMu.Lock()
+	defer t.pubMu.Unlock()
+	if t.pub != nil {
+		t.pub.Close()
+		t.pub, t.pubR = nil, nil
+	}
+	return nil
+}
+
+func (t *Redis) readLoop(conn net.Conn, r *bufio.Reader) {
+	defer close(t.done)
+	for {
+		err := t.consume(r)
+		t.mu.Lock()
+		closed := t.closed
+		t.sub = nil
+		t.mu.Unlock()
+		conn.Close()
+		if closed {
+			return
+		}
+		log.Printf("redis: subscription lost: %v", err)
+		if conn, r = t.resubscribe(); conn == nil {
+			return
+		}
+	}
+}
+
+// consume delivers messages until the connection fails.
+func (t *Redis) consume(r *bufio.Reader) error {
+	for {
+		reply, err := readReply(r)
+		if err != nil {
+			var serverErr redisError
+			if errors.As(err, &serverErr) {
+				log.Printf("redis: server error: %v", err)
+				continue
+			}
+			return err
+		}
+		// Pushed messages are ["message", channel, payload].
+		parts, ok := reply.([]any)
+		if !ok || len(parts) != 3 || parts[0] != "message" {
+			continue
+		}
+		if payload, ok := parts[2].(string); ok {
+			t.deliver([]byte(payload))
+		}
+	}
+}
+
+// resubscribe dials with exponential backoff until it succeeds or the
+// transport is closed.
+func (t *Redis) resubscribe() (net.Conn, *bufio.Reader) {
+	delay := 500 * time.Millisecond
+	for {
+		t.mu.Lock()
+		closed := t.closed
+		t.mu.Unlock()
+		if closed {
+			return nil, nil
+		}
+		conn, r, err := t.subscribe(context.Background())
+		if err == nil {
+			t.mu.Lock()
+			if t.closed {
+				t.mu.Unlock()
+				conn.Close()
+				return nil, nil
+			}
+			t.sub = conn
+			t.mu.Unlock()
+			log.Printf("redis: resubscribed to %s on %s", t.cfg.Channel, t.addr)
+			return conn, r
+		}
+		log.Printf("redis: resubscribe failed: %v", err)
+		time.Sleep(delay)
+		delay = min(delay*2, 30*time.Second)
+	}
+}
+
+// redisError is an error reply from the server.
+type redisError string
+
+func (e redisError) Error() string { return string(e) }
+
+// encodeCommand encodes args as a RESP array of bulk strings.
+func encodeCommand(args ...string) []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(&b, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	return []byte(b.String())
+}
+
+// readReply reads one RESP value: a string, an int64, nil or a []any of those.
+// Error replies are returned as redisError.
+func readReply(r *bufio.Reader) (any, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	line = strings.TrimRight(line, "\r\n")
+	if line == "" {
+		return nil, errors.New("redis: empty reply")
+	}
+	switch kind, rest := line[0], line[1:]; kind {
+	case '+':
+		return rest, nil
+	case '-':
+		return nil, redisError(rest)
+	case ':':
+		return strconv.ParseInt(rest, 10, 64)
+	case '$':
+		size, err := strconv.Atoi(rest)
+		if err != nil {
+			return nil, fmt.Errorf("redis: malformed bulk length %q", rest)
+		}
+		if size < 0 {
+			return nil, nil
+		}
+		buf := make([]byte, size+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		return string(buf[:size]), nil
+	case '*':
+		n, err := strconv.Atoi(rest)
+		if err != nil {
+			return nil, fmt.Errorf("redis: malformed array length %q", rest)
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		items := make([]any, n)
+		for i := range items {
+			if items[i], err = readReply(r); err != nil {
+				return nil, err
+			}
+		}
+		return items, nil
+	default:
+		return nil, fmt.Errorf("redis: unexpected reply %q", line)
+	}
+}
@@ -0,0 +1,104 @@
+package captcha
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/hongminglow/all-in-be/internal/models"
+	"github.com/hongminglow/all-in-be/internal/storage/storagetest"
+)
+
+// tokens passes the tokens it holds; a nil one stands for a provider that is
+// down.
+type tokens map[string]bool
+
+func (v tokens) Verify(_ context.Context, token, _ string) (bool, error) {
+	if v == nil {
+		return false, errors.New("provider down")
+	}
+	return v[token], nil
+}
+
+func TestCheckLoginAfterFailures(t *testing.T) {
+	ctx := context.Background()
+	clk := storagetest.NewFakeClock(time.Date(2026, 3, 14, 12, 0, 0, 0, time.UTC))
+	store := storagetest.NewMemoryStore(clk)
+	gate := NewGate(tokens{"solved": true}, store, clk, Policy{LoginAfterFailures: 3, LoginWindow: 15 * time.Minute})
+	userID := int64(7)
+	fail := func(ip string, user *int64) {
+		t.Helper()
+		if _, err := store.RecordLoginAttempt(ctx, models.LoginAttempt{Identifier: "player", IP: ip, UserID: user, FailureReason: models.LoginInvalidPassword}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	fail("192.0.2.1", &userID)
+	fail("192.0.2.1", &userID)
+	if err := gate.CheckLogin(ctx, "", "192.0.2.1", &userID); err != nil {
+		t.Fatalf("CheckLogin after two failures = %v; want nil", err)
+	}
+	fail("192.0.2.1", &userID)
+	if err := gate.CheckLogin(ctx, "", "192.0.2.1", &userID); !errors.Is(err, ErrRequired) {
+		t.Fatalf("CheckLogin after three failures = %v; want ErrRequired", err)
+	}
+	// The account is gated from other networks too.
+	if err := gate.CheckLogin(ctx, "", "198.51.100.9", &userID); !errors.Is(err, ErrRequired) {
+		t.Errorf("CheckLogin for the account from another IP = %v; want ErrRequired", err)
+	}
+	if err := gate.CheckLogin(ctx, "wrong", "192.0.2.1", nil); !errors.Is(err, ErrInvalid) {
+		t.Errorf("CheckLogin with a bad token = %v; want ErrInvalid", err)
+	}
+	if err := gate.CheckLogin(ctx, "solved", "192.0.2.1", nil); err != nil {
+		t.Errorf("CheckLogin with a solved token = %v", err)
+	}
+
+	clk.Advance(16 * time.Minute)
+	if err := gate.CheckLogin(ctx, "", "192.0.2.1", &userID); err != nil {
+		t.Errorf("CheckLogin after the window = %v; want nil", err)
+	}
+}
+
+func TestCheckSignup(t *testing.T) {
+	ctx := context.Background()
+	clk := storagetest.NewFakeClock(time.Now())
+	store := storagetest.NewMemoryStore(clk)
+	if err := NewGate(nil, store, clk, Policy{}).CheckSignup(ctx, "", "192.0.2.1"); err != nil {
+		t.Errorf("CheckSignup when not required = %v", err)
+	}
+	gate := NewGate(Bypass{Token: "e2e"}, store, clk, Policy{Signup: true})
+	for token, want := range map[string]error{"": ErrRequired, "e2e": nil, "other": ErrInvalid} {
+		if err := gate.CheckSignup(ctx, token, "192.0.2.1"); !errors.Is(err, want) {
+			t.Errorf("CheckSignup(%q) = %v; want %v", token, err, want)
+		}
+	}
+	down := NewGate(Bypass{Token: "e2e", Next: tokens(nil)}, store, clk, Policy{Signup: true})
+	if err := down.CheckSignup(ctx, "other", "192.0.2.1"); !errors.Is(err, ErrUnavailable) {
+		t.Errorf("CheckSignup with the provider down = %v; want ErrUnavailable", err)
+	}
+}
+
+func TestSiteVerify(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("secret") != "s3cret" || r.FormValue("remoteip") != "192.0.2.1" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte(`{"success": ` + map[bool]string{true: "true", false: "false"}[r.FormValue("response") == "solved"] + `}`))
+	}))
+	defer srv.Close()
+
+	v := SiteVerify{URL: srv.URL, Secret: "s3cret"}
+	if ok, err := v.Verify(context.Background(), "solved", "192.0.2.1"); !ok || err != nil {
+		t.Errorf("Verify(solved) = %v, %v", ok, err)
+	}
+	if ok, err := v.Verify(context.Background(), "forged", "192.0.2.1"); ok || err != nil {
+		t.Errorf("Verify(forged) = %v, %v", ok, err)
+	}
+	if _, err := (SiteVerify{URL: srv.URL, Secret: "wrong"}).Verify(context.Background(), "solved", "192.0.2.1"); err == nil {
+		t.Error("Verify with a rejected request succeeded")
+	}
+}
@@ -0,0 +1,26 @@
+package challenge
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/hongminglow/all-in-be/internal/captcha"
+	"github.com/hongminglow/all-in-be/internal/models"
+)
+
+// SiteVerify passes users whose CAPTCHA token a siteverify endpoint accepts
+// (see captcha.SiteVerify).
+type SiteVerify struct {
+	URL    string
+	Secret string
+	Client *http.Client
+}
+
+// Start does nothing: the widget is solved in the client.
+func (SiteVerify) Start(context.Context, models.User, models.Challenge) (string, error) {
+	return "", nil
+}
+
+func (v SiteVerify) Verify(ctx context.Context, _ models.User, _ models.Challenge, answer Answer) (bool, error) {
+	return captcha.SiteVerify{URL: v.URL, Secret: v.Secret, Client: v.Client}.Verify(ctx, answer.CaptchaToken, answer.IP)
+}
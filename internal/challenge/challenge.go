@@ -0,0 +1,295 @@
+// Package challenge asks users to prove it is really them before risky
+// actions. A policy per action (see config.ChallengeConfig) picks the method:
+// re-entering the password, a one-time code sent by email, or a CAPTCHA. The
+// caller gets a challenge to answer; answering it yields a token that lets
+// the action through once.
+package challenge
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/hongminglow/all-in-be/internal/clock"
+	"github.com/hongminglow/all-in-be/internal/config"
+	"github.com/hongminglow/all-in-be/internal/models"
+	"github.com/hongminglow/all-in-be/internal/notify"
+	"github.com/hongminglow/all-in-be/internal/storage"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	// ErrRequired is returned with a freshly issued challenge the user must
+	// pass before the action can go ahead.
+	ErrRequired = errors.New("step-up challenge required")
+	// ErrIncorrect is returned for a wrong answer.
+	ErrIncorrect = errors.New("incorrect challenge answer")
+	// ErrExpired is returned for a challenge answered too late.
+	ErrExpired = errors.New("challenge expired")
+	// ErrTooManyAttempts is returned once a challenge has had too many wrong
+	// answers; the action has to be retried for a new one.
+	ErrTooManyAttempts = errors.New("too many wrong answers")
+	// ErrPassed is returned when a challenge is answered again after passing.
+	ErrPassed = errors.New("challenge already passed")
+)
+
+// Store is the data challenges are issued and checked against.
+type Store interface {
+	storage.ChallengeStore
+	ListLoginDevices(ctx context.Context, userID int64) ([]models.LoginDevice, error)
+}
+
+// Request describes an action that may need a challenge.
+type Request struct {
+	Action string
+	// Amount is the sum involved, for actions such as withdrawals.
+	Amount float64
+	// Device is the fingerprint of the device the request comes from, as
+	// computed by security.Device.Fingerprint.
+	Device string
+}
+
+// Answer is the user's response to a challenge; each method reads its own field.
+type Answer struct {
+	Password     string
+	Code         string
+	CaptchaToken string
+	// IP is the client address, passed on to CAPTCHA providers.
+	IP string
+}
+
+// Method is one way of passing a challenge.
+type Method interface {
+	// Start runs when a challenge is issued, for example to send a code. It
+	// returns the hash of the code the user must answer with, if any.
+	Start(ctx context.Context, user models.User, c models.Challenge) (codeHash string, err error)
+	// Verify reports whether answer passes c.
+	Verify(ctx context.Context, user models.User, c models.Challenge, answer Answer) (bool, error)
+}
+
+// Service issues challenges for risky actions and checks the answers.
+type Service struct {
+	store   Store
+	clock   clock.Clock
+	cfg     config.ChallengeConfig
+	methods map[string]Method
+}
+
+// NewService builds a service with the reauth and OTP methods, and the
+// CAPTCHA method when cfg has a CAPTCHA secret. OTP codes are sent through
+// notifier.
+func NewService(store Store, notifier notify.Notifier, clk clock.Clock, cfg config.ChallengeConfig) *Service {
+	s := &Service{store: store, clock: clk, cfg: cfg, methods: map[string]Method{
+		models.ChallengeReauth: Reauth{},
+		models.ChallengeOTP:    OTP{Notifier: notifier, TTL: cfg.TTL},
+	}}
+	if cfg.CaptchaSecret != "" {
+		s.methods[models.ChallengeCaptcha] = SiteVerify{URL: cfg.CaptchaVerifyURL, Secret: cfg.CaptchaSecret}
+	}
+	return s
+}
+
+// Register adds or replaces the method used for challenges of the given name,
+// for example to plug in a different CAPTCHA provider.
+func (s *Service) Register(name string, m Method) {
+	s.methods[name] = m
+}
+
+// Require decides whether req may go ahead. It returns nil when no policy
+// applies, or when token redeems a challenge the user passed for the action.
+// Otherwise it issues a challenge and returns it with ErrRequired.
+func (s *Service) Require(ctx context.Context, user models.User, req Request, token string) (models.Challenge, error) {
+	policy, ok := s.cfg.Policies[req.Action]
+	if !ok || req.Amount < policy.MinAmount {
+		return models.Challenge{}, nil
+	}
+	if policy.NewDeviceOnly {
+		isNew, err := s.newDevice(ctx, user.ID, req.Device)
+		if err != nil || !isNew {
+			return models.Challenge{}, err
+		}
+	}
+	now := s.clock.Now()
+	if token != "" {
+		_, err := s.store.UseChallenge(ctx, user.ID, req.Action, hashToken(token), now)
+		if err == nil {
+			return models.Challenge{}, nil
+		}
+		if !errors.Is(err, storage.ErrNotFound) {
+			return models.Challenge{}, err
+		}
+		// An unknown, used or expired token earns a new challenge.
+	}
+	method, ok := s.methods[policy.Method]
+	if !ok {
+		return models.Challenge{}, fmt.Errorf("no %s challenge method registered", policy.Method)
+	}
+	c := models.Challenge{UserID: user.ID, Action: req.Action, Method: policy.Method, ExpiresAt: now.Add(s.cfg.TTL)}
+	codeHash, err := method.Start(ctx, user, c)
+	if err != nil {
+		return models.Challenge{}, fmt.Errorf("start %s challenge: %w", policy.Method, err)
+	}
+	c.CodeHash = codeHash
+	if c, err = s.store.CreateChallenge(ctx, c); err != nil {
+		return models.Challenge{}, err
+	}
+	if c.Method == models.ChallengeCaptcha {
+		c.SiteKey = s.cfg.CaptchaSiteKey
+	}
+	return c, ErrRequired
+}
+
+// Verify checks answer against one of the user's challenges and, when it
+// passes, returns the token that lets the action through once. Other users'
+// challenges are reported as storage.ErrNotFound.
+func (s *Service) Verify(ctx context.Context, user models.User, id int64, answer Answer) (string, models.Challenge, error) {
+	c, err := s.store.FindChallenge(ctx, id)
+	if err != nil {
+		return "", models.Challenge{}, err
+	}
+	if c.UserID != user.ID {
+		return "", models.Challenge{}, storage.ErrNotFound
+	}
+	now := s.clock.Now()
+	switch {
+	case c.PassedAt != nil:
+		return "", c, ErrPassed
+	case !now.Before(c.ExpiresAt):
+		return "", c, ErrExpired
+	case c.Attempts >= s.cfg.MaxAttempts:
+		return "", c, ErrTooManyAttempts
+	}
+	method, ok := s.methods[c.Method]
+	if !ok {
+		return "", c, fmt.Errorf("no %s challenge method registered", c.Method)
+	}
+	passed, err := method.Verify(ctx, user, c, answer)
+	if err != nil {
+		return "", c, fmt.Errorf("verify %s challenge: %w", c.Method, err)
+	}
+	if !passed {
+		failed, err := s.store.FailChallenge(ctx, c.ID)
+		if errors.Is(err, storage.ErrNotFound) {
+			return "", c, ErrPassed
+		}
+		if err != nil {
+			return "", c, err
+		}
+		if failed.Attempts >= s.cfg.MaxAttempts {
+			return "", failed, ErrTooManyAttempts
+		}
+		return "", failed, ErrIncorrect
+	}
+	token, hash, err := newToken()
+	if err != nil {
+		return "", c, err
+	}
+	c.ExpiresAt = now.Add(s.cfg.TTL)
+	err = s.store.PassChallenge(ctx, c.ID, hash, now, c.ExpiresAt)
+	if errors.Is(err, storage.ErrNotFound) {
+		// A concurrent answer passed it first.
+		return "", c, ErrPassed
+	}
+	if err != nil {
+		return "", c, err
+	}
+	c.PassedAt = &now
+	return token, c, nil
+}
+
+// newDevice reports whether the user first signed in from the device less
+// than NewDeviceAge ago, or never has.
+func (s *Service) newDevice(ctx context.Context, userID int64, fingerprint string) (bool, error) {
+	devices, err := s.store.ListLoginDevices(ctx, userID)
+	if err != nil {
+		return false, fmt.Errorf("list login devices: %w", err)
+	}
+	var first time.Time
+	for _, d := range devices {
+		if d.Fingerprint == fingerprint && (first.IsZero() || d.FirstSeen.Before(first)) {
+			first = d.FirstSeen
+		}
+	}
+	return first.IsZero() || s.clock.Now().Sub(first) < s.cfg.NewDeviceAge, nil
+}
+
+// Reauth passes users who enter their current password.
+type Reauth struct{}
+
+func (Reauth) Start(context.Context, models.User, models.Challenge) (string, error) {
+	return "", nil
+}
+
+func (Reauth) Verify(_ context.Context, user models.User, _ models.Challenge, answer Answer) (bool, error) {
+	if answer.Password == "" || user.PasswordHash == "" {
+		return false, nil
+	}
+	return bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(answer.Password)) == nil, nil
+}
+
+// OTP emails a six-digit code and passes users who send it back.
+type OTP struct {
+	Notifier notify.Notifier
+	TTL      time.Duration
+}
+
+// actionLabels phrase each action for the code email.
+var actionLabels = map[string]string{
+	models.ActionPasswordChange: "change your password",
+	models.ActionWithdrawal:     "confirm your withdrawal",
+}
+
+func (o OTP) Start(ctx context.Context, user models.User, c models.Challenge) (string, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(1_000_000))
+	if err != nil {
+		return "", err
+	}
+	code := fmt.Sprintf("%06d", n.Int64())
+	label, ok := actionLabels[c.Action]
+	if !ok {
+		label = "continue"
+	}
+	err = o.Notifier.Notify(ctx, notify.Notification{
+		Channel:  notify.ChannelEmail,
+		To:       user.Email,
+		Template: notify.TemplateChallengeCode,
+		Data: map[string]any{
+			"Username":  user.Username,
+			"Action":    label,
+			"Code":      code,
+			"ExpiresIn": o.TTL.String(),
+		},
+	})
+	if err != nil {
+		return "", err
+	}
+	return hashToken(code), nil
+}
+
+func (OTP) Verify(_ context.Context, _ models.User, c models.Challenge, answer Answer) (bool, error) {
+	if answer.Code == "" || c.CodeHash == "" {
+		return false, nil
+	}
+	return subtle.ConstantTimeCompare([]byte(hashToken(answer.Code)), []byte(c.CodeHash)) == 1, nil
+}
+
+func newToken() (token, hash string, err error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", err
+	}
+	token = base64.RawURLEncoding.EncodeToString(buf)
+	return token, hashToken(token), nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,167 @@
+package challenge
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/hongminglow/all-in-be/internal/config"
+	"github.com/hongminglow/all-in-be/internal/models"
+	"github.com/hongminglow/all-in-be/internal/notify"
+	"github.com/hongminglow/all-in-be/internal/storage/storagetest"
+)
+
+type recordingNotifier struct {
+	mu    sync.Mutex
+	codes []string
+}
+
+func (n *recordingNotifier) Notify(_ context.Context, msg notify.Notification) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.codes = append(n.codes, msg.Data.(map[string]any)["Code"].(string))
+	return nil
+}
+
+func (n *recordingNotifier) last() string {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.codes[len(n.codes)-1]
+}
+
+func testConfig() config.ChallengeConfig {
+	return config.ChallengeConfig{
+		Policies: map[string]models.ChallengePolicy{
+			models.ActionPasswordChange: {Method: models.ChallengeReauth, NewDeviceOnly: true},
+			models.ActionWithdrawal:     {Method: models.ChallengeOTP, MinAmount: 1000},
+		},
+		NewDeviceAge: 24 * time.Hour,
+		TTL:          10 * time.Minute,
+		MaxAttempts:  2,
+	}
+}
+
+func TestPasswordChangeIsChallengedFromNewDevices(t *testing.T) {
+	ctx := context.Background()
+	clk := storagetest.NewFakeClock(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	store := storagetest.NewMemoryStore(clk)
+	hash, _ := bcrypt.GenerateFromPassword([]byte("correct-horse"), bcrypt.MinCost)
+	ana, _ := store.CreateUser(ctx, models.User{Username: "ana", Email: "ana@example.com", Role: models.NormalUser, PasswordHash: string(hash)})
+	if err := store.TouchLoginDevice(ctx, models.LoginDevice{UserID: ana.ID, Fingerprint: "laptop", LastSeen: clk.Now()}); err != nil {
+		t.Fatal(err)
+	}
+	challenges := NewService(store, &recordingNotifier{}, clk, testConfig())
+	change := Request{Action: models.ActionPasswordChange, Device: "laptop"}
+
+	if _, err := challenges.Require(ctx, ana, change, ""); !errors.Is(err, ErrRequired) {
+		t.Fatalf("device first seen just now: err = %v, want ErrRequired", err)
+	}
+	clk.Advance(25 * time.Hour)
+	if _, err := challenges.Require(ctx, ana, change, ""); err != nil {
+		t.Fatalf("familiar device: %v", err)
+	}
+
+	change.Device = "phone"
+	c, err := challenges.Require(ctx, ana, change, "")
+	if !errors.Is(err, ErrRequired) || c.Method != models.ChallengeReauth {
+		t.Fatalf("unknown device: %+v, %v", c, err)
+	}
+	if _, _, err := challenges.Verify(ctx, ana, c.ID, Answer{Password: "wrong"}); !errors.Is(err, ErrIncorrect) {
+		t.Fatalf("wrong password: err = %v", err)
+	}
+	token, _, err := challenges.Verify(ctx, ana, c.ID, Answer{Password: "correct-horse"})
+	if err != nil || token == "" {
+		t.Fatalf("right password: %q, %v", token, err)
+	}
+	if _, _, err := challenges.Verify(ctx, ana, c.ID, Answer{Password: "correct-horse"}); !errors.Is(err, ErrPassed) {
+		t.Fatalf("answering twice: err = %v", err)
+	}
+	if _, err := challenges.Require(ctx, ana, Request{Action: models.ActionWithdrawal, Amount: 5000}, token); !errors.Is(err, ErrRequired) {
+		t.Fatalf("token used for another action: err = %v, want ErrRequired", err)
+	}
+	if _, err := challenges.Require(ctx, ana, change, token); err != nil {
+		t.Fatalf("redeeming the token: %v", err)
+	}
+	if _, err := challenges.Require(ctx, ana, change, token); !errors.Is(err, ErrRequired) {
+		t.Fatalf("reusing the token: err = %v, want ErrRequired", err)
+	}
+}
+
+func TestWithdrawalOTP(t *testing.T) {
+	ctx := context.Background()
+	clk := storagetest.NewFakeClock(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	store := storagetest.NewMemoryStore(clk)
+	ana, _ := store.CreateUser(ctx, models.User{Username: "ana", Email: "ana@example.com", Role: models.NormalUser})
+	ben, _ := store.CreateUser(ctx, models.User{Username: "ben", Email: "ben@example.com", Role: models.NormalUser})
+	codes := &recordingNotifier{}
+	challenges := NewService(store, codes, clk, testConfig())
+
+	if _, err := challenges.Require(ctx, ana, Request{Action: models.ActionWithdrawal, Amount: 999}, ""); err != nil {
+		t.Fatalf("withdrawal below the threshold: %v", err)
+	}
+	c, err := challenges.Require(ctx, ana, Request{Action: models.ActionWithdrawal, Amount: 1000}, "")
+	if !errors.Is(err, ErrRequired) || c.Method != models.ChallengeOTP {
+		t.Fatalf("large withdrawal: %+v, %v", c, err)
+	}
+	code := codes.last()
+	if _, _, err := challenges.Verify(ctx, ben, c.ID, Answer{Code: code}); err == nil {
+		t.Fatal("another user answered the challenge")
+	}
+	if _, _, err := challenges.Verify(ctx, ana, c.ID, Answer{Code: "000000x"}); !errors.Is(err, ErrIncorrect) {
+		t.Fatalf("first wrong code: err = %v", err)
+	}
+	if _, _, err := challenges.Verify(ctx, ana, c.ID, Answer{Code: "000000y"}); !errors.Is(err, ErrTooManyAttempts) {
+		t.Fatalf("last wrong code: err = %v", err)
+	}
+	if _, _, err := challenges.Verify(ctx, ana, c.ID, Answer{Code: code}); !errors.Is(err, ErrTooManyAttempts) {
+		t.Fatalf("right code after the attempts ran out: err = %v", err)
+	}
+
+	c, _ = challenges.Require(ctx, ana, Request{Action: models.ActionWithdrawal, Amount: 1000}, "")
+	clk.Advance(11 * time.Minute)
+	if _, _, err := challenges.Verify(ctx, ana, c.ID, Answer{Code: codes.last()}); !errors.Is(err, ErrExpired) {
+		t.Fatalf("late answer: err = %v", err)
+	}
+
+	c, _ = challenges.Require(ctx, ana, Request{Action: models.ActionWithdrawal, Amount: 1000}, "")
+	token, _, err := challenges.Verify(ctx, ana, c.ID, Answer{Code: codes.last()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	clk.Advance(11 * time.Minute)
+	if _, err := challenges.Require(ctx, ana, Request{Action: models.ActionWithdrawal, Amount: 1000}, token); !errors.Is(err, ErrRequired) {
+		t.Fatalf("expired token: err = %v, want ErrRequired", err)
+	}
+}
+
+func TestSiteVerify(t *testing.T) {
+	var got map[string]string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		got = map[string]string{"secret": r.PostForm.Get("secret"), "response": r.PostForm.Get("response"), "remoteip": r.PostForm.Get("remoteip")}
+		if r.PostForm.Get("response") == "solved" {
+			_, _ = w.Write([]byte(`{"success": true}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"success": false, "error-codes": ["invalid-input-response"]}`))
+	}))
+	defer srv.Close()
+	v := SiteVerify{URL: srv.URL, Secret: "s3cret"}
+
+	ok, err := v.Verify(context.Background(), models.User{}, models.Challenge{}, Answer{CaptchaToken: "solved", IP: "203.0.113.9"})
+	if err != nil || !ok {
+		t.Fatalf("solved captcha: %v, %v", ok, err)
+	}
+	if got["secret"] != "s3cret" || got["remoteip"] != "203.0.113.9" {
+		t.Fatalf("siteverify form = %v", got)
+	}
+	if ok, err := v.Verify(context.Background(), models.User{}, models.Challenge{}, Answer{CaptchaToken: "bot"}); err != nil || ok {
+		t.Fatalf("rejected captcha: %v, %v", ok, err)
+	}
+}
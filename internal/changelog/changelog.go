@@ -0,0 +1,55 @@
+package changelog
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+//go:embed changelog.json
+var raw []byte
+
+// Release describes one published version of the API.
+type Release struct {
+	Version string   `json:"version"`
+	Date    string   `json:"date"`
+	Changes []Change `json:"changes"`
+}
+
+// Change is a single entry in a release. Breaking marks changes that require client updates.
+type Change struct {
+	Description string `json:"description"`
+	Breaking    bool   `json:"breaking"`
+}
+
+// Breaking reports whether any change in the release is breaking.
+func (r Release) Breaking() bool {
+	for _, c := range r.Changes {
+		if c.Breaking {
+			return true
+		}
+	}
+	return false
+}
+
+// Load parses the embedded changelog. Releases are listed newest first in the file.
+func Load() ([]Release, error) {
+	var releases []Release
+	if err := json.Unmarshal(raw, &releases); err != nil {
+		return nil, fmt.Errorf("decode changelog: %w", err)
+	}
+	for _, r := range releases {
+		if r.Version == "" {
+			return nil, errors.New("changelog: release without version")
+		}
+		if _, err := time.Parse(time.DateOnly, r.Date); err != nil {
+			return nil, fmt.Errorf("changelog: release %s has invalid date %q", r.Version, r.Date)
+		}
+		if len(r.Changes) == 0 {
+			return nil, fmt.Errorf("changelog: release %s lists no changes", r.Version)
+		}
+	}
+	return releases, nil
+}
@@ -0,0 +1,18 @@
+package changelog
+
+import "testing"
+
+// TestEmbeddedChangelogIsValid guards hand edits to changelog.json.
+func TestEmbeddedChangelogIsValid(t *testing.T) {
+	releases, err := Load()
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	seen := make(map[string]bool)
+	for _, r := range releases {
+		if seen[r.Version] {
+			t.Fatalf("duplicate release %s", r.Version)
+		}
+		seen[r.Version] = true
+	}
+}
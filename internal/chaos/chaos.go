@@ -0,0 +1,146 @@
+// Package chaos injects latency, HTTP errors, and database failures into
+// matching requests so client retry logic and failure handling can be exercised
+// outside production.
+package chaos
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrInjectedDBFailure is returned by the store for requests selected to lose their database connection.
+var ErrInjectedDBFailure = errors.New("chaos: injected database connection failure")
+
+// Rule describes one fault. A request matches when its path starts with
+// PathPrefix and, if Method is set, its method equals Method. Matching requests
+// are affected with probability Percentage/100.
+type Rule struct {
+	ID          int64     `json:"id"`
+	PathPrefix  string    `json:"path_prefix"`
+	Method      string    `json:"method,omitempty"`
+	Percentage  float64   `json:"percentage"`
+	LatencyMS   int       `json:"latency_ms,omitempty"`
+	ErrorStatus int       `json:"error_status,omitempty"`
+	DropDB      bool      `json:"drop_db,omitempty"`
+	ExpiresAt   time.Time `json:"expires_at,omitzero"`
+}
+
+// Validate checks that the rule does something and targets a path.
+func (r Rule) Validate() error {
+	if !strings.HasPrefix(r.PathPrefix, "/") {
+		return errors.New("path_prefix must start with /")
+	}
+	if r.Percentage <= 0 || r.Percentage > 100 {
+		return errors.New("percentage must be in (0, 100]")
+	}
+	if r.LatencyMS < 0 || r.LatencyMS > 60_000 {
+		return errors.New("latency_ms must be between 0 and 60000")
+	}
+	if r.ErrorStatus != 0 && (r.ErrorStatus < 400 || r.ErrorStatus > 599) {
+		return errors.New("error_status must be a 4xx or 5xx code")
+	}
+	if r.LatencyMS == 0 && r.ErrorStatus == 0 && !r.DropDB {
+		return errors.New("rule must inject latency, an error status, or a database failure")
+	}
+	return nil
+}
+
+// Fault is the combined effect of every rule that fired for a request.
+type Fault struct {
+	Latency     time.Duration
+	ErrorStatus int
+	DropDB      bool
+}
+
+// Injector holds the active rules.
+type Injector struct {
+	mu     sync.RWMutex
+	rules  []Rule
+	nextID int64
+	roll   func() float64 // returns [0, 100)
+	now    func() time.Time
+}
+
+// NewInjector returns an injector with no rules.
+func NewInjector() *Injector {
+	return &Injector{
+		roll: func() float64 { return rand.Float64() * 100 },
+		now:  time.Now,
+	}
+}
+
+// Add registers a rule and returns it with its assigned ID.
+func (i *Injector) Add(rule Rule) Rule {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.nextID++
+	rule.ID = i.nextID
+	rule.Method = strings.ToUpper(rule.Method)
+	i.rules = append(i.rules, rule)
+	return rule
+}
+
+// Remove deletes a rule, reporting whether it existed.
+func (i *Injector) Remove(id int64) bool {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	for idx, rule := range i.rules {
+		if rule.ID == id {
+			i.rules = append(i.rules[:idx], i.rules[idx+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// Rules returns the rules that have not expired.
+func (i *Injector) Rules() []Rule {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	now := i.now()
+	out := []Rule{}
+	for _, rule := range i.rules {
+		if rule.ExpiresAt.IsZero() || now.Before(rule.ExpiresAt) {
+			out = append(out, rule)
+		}
+	}
+	return out
+}
+
+// Decide rolls every matching rule and merges the ones that fire.
+func (i *Injector) Decide(method, path string) (Fault, bool) {
+	var fault Fault
+	fired := false
+	for _, rule := range i.Rules() {
+		if !strings.HasPrefix(path, rule.PathPrefix) || (rule.Method != "" && rule.Method != method) {
+			continue
+		}
+		if i.roll() >= rule.Percentage {
+			continue
+		}
+		fired = true
+		fault.Latency += time.Duration(rule.LatencyMS) * time.Millisecond
+		if rule.ErrorStatus != 0 && fault.ErrorStatus == 0 {
+			fault.ErrorStatus = rule.ErrorStatus
+		}
+		fault.DropDB = fault.DropDB || rule.DropDB
+	}
+	return fault, fired
+}
+
+type dbFaultKey struct{}
+
+// WithDBFailure marks ctx so database calls made with it fail.
+func WithDBFailure(ctx context.Context) context.Context {
+	return context.WithValue(ctx, dbFaultKey{}, true)
+}
+
+// DBFailure reports whether database calls made with ctx should fail.
+func DBFailure(ctx context.Context) bool {
+	dropped, _ := ctx.Value(dbFaultKey{}).(bool)
+	return dropped
+}
@@ -0,0 +1,48 @@
+package chaos
+
+import (
+	"testing"
+	"time"
+)
+
+func TestInjectorDecide(t *testing.T) {
+	inj := NewInjector()
+	roll := 0.0
+	inj.roll = func() float64 { return roll }
+	now := time.Unix(1000, 0)
+	inj.now = func() time.Time { return now }
+
+	inj.Add(Rule{PathPrefix: "/login", Method: "post", Percentage: 50, LatencyMS: 200})
+	inj.Add(Rule{PathPrefix: "/login", Percentage: 100, ErrorStatus: 503})
+	expiring := inj.Add(Rule{PathPrefix: "/me", Percentage: 100, DropDB: true, ExpiresAt: now.Add(time.Minute)})
+
+	roll = 10
+	fault, fired := inj.Decide("POST", "/login")
+	if !fired || fault.Latency != 200*time.Millisecond || fault.ErrorStatus != 503 {
+		t.Fatalf("POST /login at roll 10 = %+v, %v", fault, fired)
+	}
+
+	roll = 75
+	fault, _ = inj.Decide("POST", "/login")
+	if fault.Latency != 0 || fault.ErrorStatus != 503 {
+		t.Fatalf("roll above 50%% should skip latency rule: %+v", fault)
+	}
+
+	if fault, _ := inj.Decide("GET", "/login"); fault.Latency != 0 {
+		t.Fatalf("method filter ignored: %+v", fault)
+	}
+	if _, fired := inj.Decide("GET", "/health"); fired {
+		t.Fatal("unmatched path should not fire")
+	}
+
+	if fault, _ := inj.Decide("GET", "/me"); !fault.DropDB {
+		t.Fatal("db rule should fire before expiry")
+	}
+	now = now.Add(2 * time.Minute)
+	if fault, _ := inj.Decide("GET", "/me"); fault.DropDB {
+		t.Fatal("expired rule should not fire")
+	}
+	if !inj.Remove(expiring.ID) || inj.Remove(expiring.ID) {
+		t.Fatal("Remove should succeed once")
+	}
+}
@@ -0,0 +1,40 @@
+// Package clock abstracts wall-clock time and identifier generation so that
+// expiry and ID-dependent logic can be driven deterministically in tests.
+package clock
+
+import (
+	"crypto/rand"
+	"fmt"
+	"time"
+)
+
+// Clock reports the current time.
+type Clock interface {
+	Now() time.Time
+}
+
+// IDGenerator produces unique opaque identifiers.
+type IDGenerator interface {
+	NewID() string
+}
+
+// System is the real wall clock.
+type System struct{}
+
+// Now returns time.Now.
+func (System) Now() time.Time {
+	return time.Now()
+}
+
+// UUID generates random RFC 9562 version 4 UUIDs.
+type UUID struct{}
+
+// NewID returns a new UUID in its canonical string form.
+func (UUID) NewID() string {
+	var b [16]byte
+	// crypto/rand.Read never returns an error since Go 1.24.
+	_, _ = rand.Read(b[:])
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
@@ -0,0 +1,21 @@
+package clock
+
+import (
+	"regexp"
+	"testing"
+)
+
+func TestUUIDFormat(t *testing.T) {
+	pattern := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+	seen := make(map[string]bool)
+	for range 100 {
+		id := UUID{}.NewID()
+		if !pattern.MatchString(id) {
+			t.Fatalf("malformed UUID %q", id)
+		}
+		if seen[id] {
+			t.Fatalf("duplicate UUID %q", id)
+		}
+		seen[id] = true
+	}
+}
@@ -3,54 +3,946 @@ package config
 import (
 	"errors"
 	"fmt"
+	"maps"
+	"net/http"
+	"net/mail"
 	"os"
+	"regexp"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
+	"unicode"
+
+	"github.com/hongminglow/all-in-be/internal/blob"
+	"github.com/hongminglow/all-in-be/internal/captcha"
+	"github.com/hongminglow/all-in-be/internal/jobs"
+	"github.com/hongminglow/all-in-be/internal/models"
+	"github.com/hongminglow/all-in-be/internal/money"
+	"github.com/hongminglow/all-in-be/internal/phone"
+	"github.com/hongminglow/all-in-be/internal/storage/postgres"
+	"github.com/joho/godotenv"
 )
 
-// Config holds runtime configuration sourced from env vars.
+// Config holds runtime configuration sourced from env vars and, when CONFIG_FILE
+// is set, a KEY=value file whose entries take precedence over the environment.
+// CORS, RateLimit and Features can be reloaded while running (see Watcher); the
+// other sections require a restart.
 type Config struct {
-	Port        string
-	DatabaseURL string
-	JWTSecret   string
-	JWTIssuer   string
-	JWTTTL      time.Duration
+	HTTP        HTTPConfig
+	Region      RegionConfig
+	DB          DBConfig
+	JWT         JWTConfig
+	CORS        CORSConfig
+	RateLimit   RateLimitConfig
+	Features    FeatureFlags
 	InitBalance float64
-	CORSOrigins []string
+	PhoneRegion string
+	Cookie      CookieConfig
+	// StatsCacheTTL is how long /admin/stats results are reused.
+	StatsCacheTTL time.Duration
+	// BusinessMetricsTTL is how long the business KPIs on /metrics are reused.
+	BusinessMetricsTTL time.Duration
+	Blob               BlobConfig
+	Notify             NotifyConfig
+	Events             EventsConfig
+	Cache              CacheConfig
+	Jobs               JobsConfig
+	Archive            ArchiveConfig
+	Reconcile          ReconcileConfig
+	Reports            ReportsConfig
+	DBInsights         DBInsightsConfig
+	Onboarding         OnboardingConfig
+	Spectator          SpectatorConfig
+	Leaderboard        LeaderboardConfig
+	Exports            ExportsConfig
+	Support            SupportConfig
+	Tracing            TracingConfig
+	Logging            LoggingConfig
+	Security           SecurityConfig
+	GeoIP              GeoIPConfig
+	IPRisk             IPRiskConfig
+	Usernames          UsernameConfig
+	EmailCheck         EmailCheckConfig
+	OIDC               OIDCConfig
+	Challenges         ChallengeConfig
+	Captcha            CaptchaConfig
+	Games              GamesConfig
+	Payments           PaymentsConfig
+	Money              MoneyConfig
+	// FaultInjection enables the chaos middleware and /admin/faults. Never enable in production.
+	FaultInjection bool
+}
+
+// HTTPConfig configures the listener.
+type HTTPConfig struct {
+	Port string
+}
+
+// RegionConfig describes this deployment's region and its sibling deployments.
+// An empty Name means a single-region deployment.
+type RegionConfig struct {
+	Name  string
+	Peers []RegionPeer
+}
+
+// RegionPeer is another regional deployment clients can be steered to.
+type RegionPeer struct {
+	Name string
+	URL  string
+}
+
+// DBConfig configures the Postgres connections.
+type DBConfig struct {
+	// URL is a Postgres connection string, or MemoryURL.
+	URL string
+	// ReadURL is an optional read replica for read-only queries.
+	ReadURL string
+	// ReplicaMaxLag is how far behind the primary the replica may fall before
+	// reads go back to the primary.
+	ReplicaMaxLag time.Duration
+	Pool          postgres.PoolConfig
+}
+
+// MemoryURL as DATABASE_URL runs the server on an in-memory store that starts
+// empty on every boot, for demos and CI without Postgres.
+const MemoryURL = "memory:"
+
+// InMemory reports whether the server runs without a database.
+func (c DBConfig) InMemory() bool {
+	return c.URL == MemoryURL
+}
+
+// JWTConfig configures session tokens.
+type JWTConfig struct {
+	Secret string
+	// KeyID names Secret in the "kid" header of issued tokens.
+	KeyID string
+	// Previous are retiring secrets that still verify tokens but no longer sign them.
+	Previous []JWTKey
+	Issuer   string
+	TTL      time.Duration
+	// SessionCacheTTL is how long a user's session state is cached before a
+	// token is checked against the database again.
+	SessionCacheTTL time.Duration
+}
+
+// JWTKey is a retiring signing secret and its key ID.
+type JWTKey struct {
+	ID     string
+	Secret string
+}
+
+// RateLimitConfig holds the static auth rate limit and the policy cache settings.
+type RateLimitConfig struct {
+	// AuthRequests caps requests per AuthWindow per client IP on /login and /register
+	// unless a database policy overrides it.
+	AuthRequests int
+	AuthWindow   time.Duration
+	// PolicyTTL is how long database rate-limit policies are cached.
+	PolicyTTL time.Duration
+}
+
+// FeatureFlags is the set of features switched on through FEATURE_FLAGS.
+type FeatureFlags map[string]bool
+
+// Enabled reports whether the named feature is switched on.
+func (f FeatureFlags) Enabled(name string) bool {
+	return f[name]
+}
+
+// BlobConfig selects and configures file storage.
+type BlobConfig struct {
+	// Backend is "local" (default, for development) or "s3".
+	Backend string
+	// LocalDir is where the local backend keeps files.
+	LocalDir string
+	// PublicBaseURL is the externally reachable URL of the local /blobs route,
+	// used to build signed download links.
+	PublicBaseURL string
+	// SigningKey authenticates local signed URLs; defaults to JWT_SECRET.
+	SigningKey string
+	S3         blob.S3Config
+}
+
+// NotifyConfig selects the email and SMS providers used for notifications.
+type NotifyConfig struct {
+	// EmailProvider is "log" (default), "smtp", or "sendgrid".
+	EmailProvider string
+	// SMSProvider is "log" (default) or "twilio".
+	SMSProvider    string
+	FromEmail      string
+	SMTPHost       string
+	SMTPPort       string
+	SMTPUsername   string
+	SMTPPassword   string
+	SendGridAPIKey string
+	TwilioSID      string
+	TwilioFrom     string
+}
+
+// EventsConfig selects the domain event bus.
+type EventsConfig struct {
+	// Backend is "inprocess" (default) or "nats".
+	Backend           string
+	NATSURL           string
+	NATSSubjectPrefix string
+	NATSQueueGroup    string
+	// OutboxInterval is how often the outbox relay looks for events that were
+	// not published straight after their transaction committed, e.g. because
+	// the process stopped in between.
+	OutboxInterval time.Duration
+	// OutboxRetention is how long published events stay in the outbox.
+	OutboxRetention time.Duration
+	// StreamPollInterval is how often GET /events streams look for movements
+	// this instance was not told about.
+	StreamPollInterval time.Duration
+	// StreamHeartbeat is how often an idle stream sends a keepalive comment.
+	StreamHeartbeat time.Duration
+}
+
+// CacheConfig selects how cache invalidations reach the other instances.
+type CacheConfig struct {
+	// Backend is "local" (default), reaching only this instance, or "redis".
+	Backend      string
+	RedisURL     string
+	RedisChannel string
+}
+
+// JobsConfig sizes the background job queue.
+type JobsConfig struct {
+	// Workers take jobs from every priority lane.
+	Workers   int
+	QueueSize int
+	// Reserved counts extra workers dedicated to a single lane.
+	Reserved map[jobs.Priority]int
+	// MaxWait is how long a job may wait before it runs ahead of more urgent
+	// lanes; zero serves lanes strictly by priority.
+	MaxWait time.Duration
+	// BulkStaleAfter is how long a running bulk job may go without saving
+	// progress before another instance takes it over.
+	BulkStaleAfter time.Duration
+}
+
+// OnboardingConfig configures welcome journeys.
+type OnboardingConfig struct {
+	// NudgeInterval is the least time between two reminders about the same step.
+	NudgeInterval time.Duration
 }
 
-// Load reads configuration from the environment and performs minimal validation.
+// SpectatorConfig configures the public activity feeds.
+type SpectatorConfig struct {
+	// BigWinMin is the smallest bet settlement listed as a big win.
+	BigWinMin float64
+	// CacheTTL is how long feeds are reused, in process and by clients.
+	CacheTTL time.Duration
+}
+
+// LeaderboardConfig configures the player leaderboards.
+type LeaderboardConfig struct {
+	// RefreshInterval is how often standings are recomputed; zero stops
+	// scheduled refreshes, e.g. for all but one instance.
+	RefreshInterval time.Duration
+	// CacheTTL is how long a leaderboard page is reused in process.
+	CacheTTL time.Duration
+}
+
+// ExportsConfig configures self-serve personal data exports.
+type ExportsConfig struct {
+	// LinkTTL is how long a download link stays valid.
+	LinkTTL time.Duration
+}
+
+// SupportConfig configures support ticket attachments.
+type SupportConfig struct {
+	// AttachmentMaxBytes bounds the size of one attachment.
+	AttachmentMaxBytes int64
+	// AttachmentLinkTTL is how long an attachment's download link stays valid.
+	AttachmentLinkTTL time.Duration
+}
+
+// ArchiveConfig controls how cold ledger and login history rows are archived.
+type ArchiveConfig struct {
+	// AfterMonths is the age at which rows are archived; zero disables archiving.
+	AfterMonths int
+	// Interval is how often the archiver runs.
+	Interval time.Duration
+	// BatchSize bounds the rows moved per statement.
+	BatchSize int
+}
+
+// ReconcileConfig schedules the balance reconciliation job.
+type ReconcileConfig struct {
+	// Interval is how often balances are checked against the ledger; zero
+	// leaves only on-demand runs, e.g. for all but one instance.
+	Interval time.Duration
+}
+
+// ReportsConfig schedules operator reports and says who receives them.
+type ReportsConfig struct {
+	// Interval is how often the last full day's and week's reports are
+	// generated if they are missing; zero leaves only ad-hoc reports.
+	Interval time.Duration
+	// Recipients are the email addresses scheduled reports are sent to.
+	Recipients []string
+	// LinkTTL is how long the download links in report emails stay valid.
+	LinkTTL time.Duration
+}
+
+// DBInsightsConfig schedules the database index advisor.
+type DBInsightsConfig struct {
+	// Interval is how often workload statistics are analyzed; zero leaves
+	// only on-demand runs.
+	Interval time.Duration
+	// SlowQuery is the mean execution time from which a statement is slow.
+	SlowQuery time.Duration
+	// MinRows is the size below which a table's sequential scans are cheap
+	// enough not to report.
+	MinRows int64
+}
+
+// TracingConfig configures OTLP trace export. Tracing is off when Endpoint is empty.
+type TracingConfig struct {
+	// Endpoint is the full OTLP/HTTP traces URL, e.g. http://collector:4318/v1/traces.
+	Endpoint    string
+	Headers     map[string]string
+	ServiceName string
+	// SampleRatio is the fraction of new root traces that are recorded.
+	SampleRatio float64
+}
+
+// LoggingConfig configures the request log.
+type LoggingConfig struct {
+	// BodySampleRate is the fraction of requests, from 0 to 1, whose redacted
+	// request and response bodies are logged. 0 turns body logging off.
+	BodySampleRate float64
+	// BodyMaxBytes caps how much of each body is kept for the log.
+	BodyMaxBytes int
+}
+
+// SecurityConfig configures login alerts and password resets.
+type SecurityConfig struct {
+	// PublicURL is the externally reachable base URL of this API, used to build
+	// the approve/deny links in login alert emails.
+	PublicURL string
+	// PasswordResetURL is the page that collects a new password; reset emails
+	// link to it with the token in a ?token= query parameter.
+	PasswordResetURL string
+	PasswordResetTTL time.Duration
+	// CountryHeader names the request header carrying the client's ISO country
+	// code, as set by a CDN or load balancer.
+	CountryHeader string
+	// ConfirmDeviceRoles lists the roles whose users must confirm a new device
+	// by email before signing in from it; other roles only get an alert.
+	ConfirmDeviceRoles    []string
+	DeviceConfirmationTTL time.Duration
+}
+
+// GeoIPConfig configures how callers are located and which jurisdictions may
+// not sign up.
+type GeoIPConfig struct {
+	// DatabasePath is a MaxMind DB file (GeoLite2 or GeoIP2 Country/City). When
+	// empty, the country comes from Security.CountryHeader alone.
+	DatabasePath string
+	// BlockedCountries lists the ISO codes refused on registration and deposit routes.
+	BlockedCountries []string
+}
+
+// IPRiskConfig configures VPN, proxy and datacenter IP screening.
+type IPRiskConfig struct {
+	// ListPath is a file of risky networks (see iprisk.List). When empty, no
+	// IPs are screened unless a provider is plugged in with server.WithIPIntelligence.
+	ListPath string
+	// DefaultAction applies to risky IPs no stored policy covers.
+	DefaultAction string
+	// PolicyTTL is how long stored policies are cached.
+	PolicyTTL time.Duration
+}
+
+// UsernameConfig configures which usernames may be registered.
+type UsernameConfig struct {
+	MinLength int
+	MaxLength int
+	// Unicode allows letters and digits of any script; otherwise only ASCII.
+	Unicode bool
+	// Symbols are the characters other than letters and digits allowed
+	// inside a username.
+	Symbols string
+	// ReservedPath is a file of reserved names and blocked words (see
+	// usernames.OpenList), used alongside those stored in the database.
+	ReservedPath string
+}
+
+// EmailCheckConfig configures how sign-up email addresses are vetted beyond
+// their syntax.
+type EmailCheckConfig struct {
+	// CheckMX refuses addresses whose domain has no mail servers.
+	CheckMX   bool
+	MXTimeout time.Duration
+	// DisposableList is a file or http(s) URL of disposable email domains,
+	// one per line. When empty, disposable addresses are allowed.
+	DisposableList string
+}
+
+// ChallengeConfig configures the step-up challenges risky actions require.
+type ChallengeConfig struct {
+	// Policies maps each guarded action to its policy. Actions not listed go
+	// ahead without a challenge.
+	Policies map[string]models.ChallengePolicy
+	// NewDeviceAge is how long after its first sign-in a device stops being new.
+	NewDeviceAge time.Duration
+	// TTL bounds both answering a challenge and using it once passed.
+	TTL         time.Duration
+	MaxAttempts int
+	// CaptchaVerifyURL is a siteverify endpoint taking secret, response and
+	// remoteip form fields, as Cloudflare Turnstile, hCaptcha and reCAPTCHA do.
+	CaptchaVerifyURL string
+	CaptchaSecret    string
+	// CaptchaSiteKey is handed to clients to render the widget.
+	CaptchaSiteKey string
+}
+
+// CaptchaConfig configures the CAPTCHA provider and when sign-ups and
+// sign-ins must solve one. Step-up challenges use the same provider.
+type CaptchaConfig struct {
+	// Provider is turnstile, hcaptcha or recaptcha; it picks the default VerifyURL.
+	Provider  string
+	VerifyURL string
+	Secret    string
+	// SiteKey is handed to clients to render the widget.
+	SiteKey string
+	// Signup requires a CAPTCHA on every registration.
+	Signup bool
+	// LoginAfterFailures requires one on sign-ins once the client's IP or the
+	// account has failed that many times in a row within LoginWindow; 0
+	// never does.
+	LoginAfterFailures int
+	LoginWindow        time.Duration
+	// BypassToken passes as a solved CAPTCHA without asking the provider,
+	// for integration tests. Never set it in production.
+	BypassToken string
+}
+
+// GamesConfig configures launching games at external providers.
+type GamesConfig struct {
+	// Providers maps each game provider to the URL its games are launched at.
+	Providers map[string]string
+	// Secrets maps the game providers that call the seamless wallet to the
+	// secret their callbacks are signed with.
+	Secrets map[string]string
+	// LaunchTTL is how long the provider has to start a launched session.
+	LaunchTTL time.Duration
+	// SessionTTL is how long a started session accepts wallet callbacks.
+	SessionTTL time.Duration
+	// LimitIncreaseDelay is how long a player waits for a looser responsible
+	// gaming limit to take effect.
+	LimitIncreaseDelay time.Duration
+	// Jackpots maps each jackpot to the percentage of every stake added to
+	// its pot.
+	Jackpots map[string]float64
+	// TournamentCloseInterval is how often tournaments that have ended are
+	// looked for, to be closed and their prizes paid.
+	TournamentCloseInterval time.Duration
+}
+
+// PaymentsConfig configures the payment providers.
+type PaymentsConfig struct {
+	// SandboxSecret enables the "sandbox" provider, whose callbacks are
+	// signed with it. Leave it empty in production.
+	SandboxSecret string
+	// WithdrawalApprovalMin is the smallest withdrawal held for an admin's
+	// approval; 0 holds them all.
+	WithdrawalApprovalMin float64
+}
+
+// MoneyConfig configures how amounts are formatted in responses.
+type MoneyConfig struct {
+	// Currency is the ISO 4217 code balances are kept in.
+	Currency string
+	// DefaultLocale formats amounts for callers whose Accept-Language matches
+	// none of Locales.
+	DefaultLocale string
+	// Locales are the locales callers may get amounts in; empty allows every
+	// locale the money package supports.
+	Locales []string
+}
+
+// OIDCConfig configures the OpenID Connect provider companion apps sign in
+// through. The provider is off when SigningKeyPath is empty.
+type OIDCConfig struct {
+	// SigningKeyPath is a PEM RSA private key used to sign ID and access tokens.
+	SigningKeyPath string
+	// Issuer is the iss claim and the base of the discovery document's URLs.
+	Issuer string
+	// LoginURL is the page that signs a user in and then sends them back to
+	// the ?return_to= authorization URL.
+	LoginURL string
+	TokenTTL time.Duration
+}
+
+// CORSConfig is the cross-origin policy applied to every route.
+type CORSConfig struct {
+	AllowedOrigins   []string
+	OriginPatterns   []*regexp.Regexp
+	AllowedMethods   []string
+	AllowedHeaders   []string
+	ExposedHeaders   []string
+	AllowCredentials bool
+	MaxAge           time.Duration
+}
+
+// CookieConfig controls delivery of the session token as an HttpOnly cookie.
+type CookieConfig struct {
+	// Always sets the cookie on every login; otherwise clients opt in per request.
+	Always bool
+	Domain string
+	Secure bool
+	// SameSite is lax or strict, never none: the cookie is the only proof
+	// of a cookie-authenticated request, so it must stay off cross-site ones.
+	SameSite http.SameSite
+}
+
+// Load reads configuration from CONFIG_FILE and the environment and performs
+// minimal validation.
 func Load() (Config, error) {
+	env, err := newLookup()
+	if err != nil {
+		return Config{}, err
+	}
 	cfg := Config{
-		Port:        fallback(os.Getenv("PORT"), "8080"),
-		DatabaseURL: strings.TrimSpace(os.Getenv("DATABASE_URL")),
-		JWTSecret:   strings.TrimSpace(os.Getenv("JWT_SECRET")),
-		JWTIssuer:   fallback(os.Getenv("JWT_ISSUER"), "all-in-backend"),
-		CORSOrigins: parseCSV(fallback(os.Getenv("CORS_ALLOWED_ORIGINS"), "*")),
+		HTTP: HTTPConfig{Port: fallback(env("PORT"), "8080")},
+		DB: DBConfig{
+			URL:     strings.TrimSpace(env("DATABASE_URL")),
+			ReadURL: strings.TrimSpace(env("DATABASE_READ_URL")),
+		},
+		JWT: JWTConfig{
+			Secret: strings.TrimSpace(env("JWT_SECRET")),
+			KeyID:  strings.TrimSpace(env("JWT_KEY_ID")),
+			Issuer: fallback(env("JWT_ISSUER"), "all-in-backend"),
+		},
+		Features:    FeatureFlags{},
 		InitBalance: 100000.00,
+		PhoneRegion: strings.ToUpper(strings.TrimSpace(env("PHONE_DEFAULT_REGION"))),
+		Cookie: CookieConfig{
+			Always: parseBool(env("AUTH_TOKEN_COOKIE"), false),
+			Domain: strings.TrimSpace(env("AUTH_COOKIE_DOMAIN")),
+			Secure: parseBool(env("AUTH_COOKIE_SECURE"), true),
+		},
+	}
+
+	cfg.Region.Name = strings.ToLower(strings.TrimSpace(env("REGION")))
+	for _, pair := range strings.Split(env("REGION_PEERS"), ",") {
+		if pair = strings.TrimSpace(pair); pair == "" {
+			continue
+		}
+		name, url, ok := strings.Cut(pair, "=")
+		url = strings.TrimSpace(url)
+		if !ok || strings.TrimSpace(name) == "" || !(strings.HasPrefix(url, "https://") || strings.HasPrefix(url, "http://")) {
+			return Config{}, fmt.Errorf("REGION_PEERS entries must be region=https://host (got %q)", pair)
+		}
+		cfg.Region.Peers = append(cfg.Region.Peers, RegionPeer{Name: strings.ToLower(strings.TrimSpace(name)), URL: strings.TrimRight(url, "/")})
+	}
+	if len(cfg.Region.Peers) > 0 && cfg.Region.Name == "" {
+		return Config{}, errors.New("REGION_PEERS requires REGION")
+	}
+
+	lag, err := time.ParseDuration(fallback(env("DATABASE_REPLICA_MAX_LAG"), "5s"))
+	if err != nil || lag <= 0 {
+		return Config{}, fmt.Errorf("DATABASE_REPLICA_MAX_LAG must be a positive duration (got %q)", env("DATABASE_REPLICA_MAX_LAG"))
+	}
+	cfg.DB.ReplicaMaxLag = lag
+	pool, err := loadPool(env)
+	if err != nil {
+		return Config{}, err
+	}
+	cfg.DB.Pool = pool
+
+	for _, pair := range strings.Split(env("JWT_PREVIOUS_SECRETS"), ",") {
+		if pair = strings.TrimSpace(pair); pair == "" {
+			continue
+		}
+		id, secret, ok := strings.Cut(pair, "=")
+		id, secret = strings.TrimSpace(id), strings.TrimSpace(secret)
+		if !ok || id == "" || secret == "" {
+			return Config{}, errors.New("JWT_PREVIOUS_SECRETS entries must be kid=secret")
+		}
+		if id == cfg.JWT.KeyID {
+			return Config{}, fmt.Errorf("JWT_PREVIOUS_SECRETS reuses the current JWT_KEY_ID %q", id)
+		}
+		cfg.JWT.Previous = append(cfg.JWT.Previous, JWTKey{ID: id, Secret: secret})
+	}
+	if len(cfg.JWT.Previous) > 0 && cfg.JWT.KeyID == "" {
+		return Config{}, errors.New("JWT_PREVIOUS_SECRETS requires JWT_KEY_ID for the current secret")
+	}
+
+	cfg.RateLimit.AuthRequests = 10
+	if limit, err := strconv.Atoi(fallback(env("AUTH_RATE_LIMIT"), "10")); err == nil && limit > 0 {
+		cfg.RateLimit.AuthRequests = limit
+	}
+	cfg.RateLimit.AuthWindow = time.Minute
+	if window, err := time.ParseDuration(fallback(env("AUTH_RATE_WINDOW"), "1m")); err == nil && window > 0 {
+		cfg.RateLimit.AuthWindow = window
+	}
+
+	for _, name := range strings.Split(env("FEATURE_FLAGS"), ",") {
+		if name = strings.ToLower(strings.TrimSpace(name)); name != "" {
+			cfg.Features[name] = true
+		}
+	}
+
+	cors, err := loadCORS(env)
+	if err != nil {
+		return Config{}, err
+	}
+	cfg.CORS = cors
+
+	cfg.RateLimit.PolicyTTL = 30 * time.Second
+	if ttl, err := time.ParseDuration(fallback(env("RATE_LIMIT_POLICY_TTL"), "30s")); err == nil && ttl > 0 {
+		cfg.RateLimit.PolicyTTL = ttl
+	}
+
+	cfg.StatsCacheTTL = time.Minute
+	if ttl, err := time.ParseDuration(fallback(env("STATS_CACHE_TTL"), "1m")); err == nil && ttl >= 0 {
+		cfg.StatsCacheTTL = ttl
+	}
+	nudgeInterval, err := time.ParseDuration(fallback(env("ONBOARDING_NUDGE_INTERVAL"), "24h"))
+	if err != nil || nudgeInterval < 0 {
+		return Config{}, fmt.Errorf("ONBOARDING_NUDGE_INTERVAL must be a non-negative duration (got %q)", env("ONBOARDING_NUDGE_INTERVAL"))
+	}
+	cfg.Onboarding.NudgeInterval = nudgeInterval
+
+	bigWinMin, err := strconv.ParseFloat(fallback(env("SPECTATOR_BIG_WIN_MIN"), "1000"), 64)
+	if err != nil || bigWinMin <= 0 {
+		return Config{}, fmt.Errorf("SPECTATOR_BIG_WIN_MIN must be a positive amount (got %q)", env("SPECTATOR_BIG_WIN_MIN"))
+	}
+	cfg.Spectator.BigWinMin = bigWinMin
+	spectatorTTL, err := time.ParseDuration(fallback(env("SPECTATOR_CACHE_TTL"), "30s"))
+	if err != nil || spectatorTTL < 0 {
+		return Config{}, fmt.Errorf("SPECTATOR_CACHE_TTL must be a non-negative duration (got %q)", env("SPECTATOR_CACHE_TTL"))
+	}
+	cfg.Spectator.CacheTTL = spectatorTTL
+
+	for _, setting := range []struct {
+		key string
+		def string
+		dst *time.Duration
+	}{
+		{"LEADERBOARD_REFRESH_INTERVAL", "5m", &cfg.Leaderboard.RefreshInterval},
+		{"LEADERBOARD_CACHE_TTL", "1m", &cfg.Leaderboard.CacheTTL},
+		{"BUSINESS_METRICS_TTL", "1m", &cfg.BusinessMetricsTTL},
+	} {
+		d, err := time.ParseDuration(fallback(env(setting.key), setting.def))
+		if err != nil || d < 0 {
+			return Config{}, fmt.Errorf("%s must be a non-negative duration (got %q)", setting.key, env(setting.key))
+		}
+		*setting.dst = d
+	}
+
+	exportTTL, err := time.ParseDuration(fallback(env("DATA_EXPORT_LINK_TTL"), "24h"))
+	if err != nil || exportTTL <= 0 || exportTTL > 7*24*time.Hour {
+		return Config{}, fmt.Errorf("DATA_EXPORT_LINK_TTL must be a positive duration of at most 168h (got %q)", env("DATA_EXPORT_LINK_TTL"))
+	}
+	cfg.Exports.LinkTTL = exportTTL
+
+	attachmentMax, err := strconv.ParseInt(fallback(env("SUPPORT_ATTACHMENT_MAX_BYTES"), "5242880"), 10, 64)
+	if err != nil || attachmentMax <= 0 {
+		return Config{}, fmt.Errorf("SUPPORT_ATTACHMENT_MAX_BYTES must be a positive number of bytes (got %q)", env("SUPPORT_ATTACHMENT_MAX_BYTES"))
+	}
+	cfg.Support.AttachmentMaxBytes = attachmentMax
+	attachmentTTL, err := time.ParseDuration(fallback(env("SUPPORT_ATTACHMENT_LINK_TTL"), "1h"))
+	if err != nil || attachmentTTL <= 0 || attachmentTTL > 7*24*time.Hour {
+		return Config{}, fmt.Errorf("SUPPORT_ATTACHMENT_LINK_TTL must be a positive duration of at most 168h (got %q)", env("SUPPORT_ATTACHMENT_LINK_TTL"))
+	}
+	cfg.Support.AttachmentLinkTTL = attachmentTTL
+
+	cfg.Blob = BlobConfig{
+		Backend:       strings.ToLower(fallback(env("BLOB_BACKEND"), "local")),
+		LocalDir:      fallback(env("BLOB_LOCAL_DIR"), "./data/blobs"),
+		PublicBaseURL: fallback(env("BLOB_PUBLIC_BASE_URL"), "http://localhost:"+cfg.HTTP.Port+"/blobs"),
+		SigningKey:    fallback(env("BLOB_SIGNING_KEY"), cfg.JWT.Secret),
+		S3: blob.S3Config{
+			Endpoint:        fallback(env("S3_ENDPOINT"), "https://s3.amazonaws.com"),
+			Region:          fallback(env("S3_REGION"), "us-east-1"),
+			Bucket:          strings.TrimSpace(env("S3_BUCKET")),
+			AccessKeyID:     strings.TrimSpace(env("S3_ACCESS_KEY_ID")),
+			SecretAccessKey: strings.TrimSpace(env("S3_SECRET_ACCESS_KEY")),
+			PathStyle:       parseBool(env("S3_USE_PATH_STYLE"), false),
+		},
+	}
+	switch cfg.Blob.Backend {
+	case "local":
+	case "s3":
+		if cfg.Blob.S3.Bucket == "" || cfg.Blob.S3.AccessKeyID == "" || cfg.Blob.S3.SecretAccessKey == "" {
+			return Config{}, errors.New("BLOB_BACKEND=s3 requires S3_BUCKET, S3_ACCESS_KEY_ID, and S3_SECRET_ACCESS_KEY")
+		}
+	default:
+		return Config{}, fmt.Errorf("BLOB_BACKEND must be local or s3 (got %q)", cfg.Blob.Backend)
+	}
+
+	notify, err := loadNotify(env)
+	if err != nil {
+		return Config{}, err
+	}
+	cfg.Notify = notify
+
+	cfg.Events = EventsConfig{
+		Backend:           strings.ToLower(fallback(env("EVENT_BUS"), "inprocess")),
+		NATSURL:           strings.TrimSpace(env("NATS_URL")),
+		NATSSubjectPrefix: fallback(env("NATS_SUBJECT_PREFIX"), "allin.events"),
+		NATSQueueGroup:    fallback(env("NATS_QUEUE_GROUP"), "all-in-be"),
+	}
+	switch cfg.Events.Backend {
+	case "inprocess":
+	case "nats":
+		if cfg.Events.NATSURL == "" {
+			return Config{}, errors.New("EVENT_BUS=nats requires NATS_URL")
+		}
+	default:
+		return Config{}, fmt.Errorf("EVENT_BUS must be inprocess or nats (got %q)", cfg.Events.Backend)
+	}
+	outboxInterval, err := time.ParseDuration(fallback(env("OUTBOX_RELAY_INTERVAL"), "5s"))
+	if err != nil || outboxInterval <= 0 {
+		return Config{}, fmt.Errorf("OUTBOX_RELAY_INTERVAL must be a positive duration (got %q)", env("OUTBOX_RELAY_INTERVAL"))
+	}
+	cfg.Events.OutboxInterval = outboxInterval
+	outboxRetention, err := time.ParseDuration(fallback(env("OUTBOX_RETENTION"), "72h"))
+	if err != nil || outboxRetention <= 0 {
+		return Config{}, fmt.Errorf("OUTBOX_RETENTION must be a positive duration (got %q)", env("OUTBOX_RETENTION"))
+	}
+	cfg.Events.OutboxRetention = outboxRetention
+	for _, setting := range []struct {
+		key string
+		def string
+		dst *time.Duration
+	}{
+		{"EVENT_STREAM_POLL_INTERVAL", "2s", &cfg.Events.StreamPollInterval},
+		{"EVENT_STREAM_HEARTBEAT", "15s", &cfg.Events.StreamHeartbeat},
+	} {
+		d, err := time.ParseDuration(fallback(env(setting.key), setting.def))
+		if err != nil || d <= 0 {
+			return Config{}, fmt.Errorf("%s must be a positive duration (got %q)", setting.key, env(setting.key))
+		}
+		*setting.dst = d
+	}
+
+	cfg.Cache = CacheConfig{
+		Backend:      strings.ToLower(fallback(env("CACHE_INVALIDATION"), "local")),
+		RedisURL:     strings.TrimSpace(env("REDIS_URL")),
+		RedisChannel: fallback(env("CACHE_INVALIDATION_CHANNEL"), "allin.cache"),
+	}
+	switch cfg.Cache.Backend {
+	case "local":
+	case "redis":
+		if cfg.Cache.RedisURL == "" {
+			return Config{}, errors.New("CACHE_INVALIDATION=redis requires REDIS_URL")
+		}
+	default:
+		return Config{}, fmt.Errorf("CACHE_INVALIDATION must be local or redis (got %q)", cfg.Cache.Backend)
+	}
+
+	jobQueue, err := loadJobs(env)
+	if err != nil {
+		return Config{}, err
+	}
+	cfg.Jobs = jobQueue
+
+	archive, err := loadArchive(env)
+	if err != nil {
+		return Config{}, err
+	}
+	cfg.Archive = archive
+
+	reconcileInterval, err := time.ParseDuration(fallback(env("RECONCILE_INTERVAL"), "24h"))
+	if err != nil || reconcileInterval < 0 {
+		return Config{}, fmt.Errorf("RECONCILE_INTERVAL must be a non-negative duration (got %q)", env("RECONCILE_INTERVAL"))
+	}
+	cfg.Reconcile.Interval = reconcileInterval
+
+	reports, err := loadReports(env)
+	if err != nil {
+		return Config{}, err
+	}
+	cfg.Reports = reports
+
+	insights, err := loadDBInsights(env)
+	if err != nil {
+		return Config{}, err
+	}
+	cfg.DBInsights = insights
+
+	tracing, err := loadTracing(env)
+	if err != nil {
+		return Config{}, err
+	}
+	cfg.Tracing = tracing
+	logging, err := loadLogging(env)
+	if err != nil {
+		return Config{}, err
+	}
+	cfg.Logging = logging
+
+	publicURL := strings.TrimRight(fallback(env("PUBLIC_URL"), "http://localhost:"+cfg.HTTP.Port), "/")
+	cfg.Security = SecurityConfig{
+		PublicURL:        publicURL,
+		PasswordResetURL: fallback(env("PASSWORD_RESET_URL"), publicURL+"/reset-password"),
+		CountryHeader:    fallback(env("LOGIN_COUNTRY_HEADER"), "CF-IPCountry"),
+	}
+	resetTTL, err := time.ParseDuration(fallback(env("PASSWORD_RESET_TTL"), "1h"))
+	if err != nil || resetTTL <= 0 {
+		return Config{}, fmt.Errorf("PASSWORD_RESET_TTL must be a positive duration (got %q)", env("PASSWORD_RESET_TTL"))
+	}
+	cfg.Security.PasswordResetTTL = resetTTL
+	for _, role := range strings.Split(env("DEVICE_CONFIRMATION_ROLES"), ",") {
+		if role = strings.TrimSpace(role); role != "" {
+			cfg.Security.ConfirmDeviceRoles = append(cfg.Security.ConfirmDeviceRoles, role)
+		}
+	}
+	confirmTTL, err := time.ParseDuration(fallback(env("DEVICE_CONFIRMATION_TTL"), "15m"))
+	if err != nil || confirmTTL <= 0 {
+		return Config{}, fmt.Errorf("DEVICE_CONFIRMATION_TTL must be a positive duration (got %q)", env("DEVICE_CONFIRMATION_TTL"))
+	}
+	cfg.Security.DeviceConfirmationTTL = confirmTTL
+
+	cfg.GeoIP.DatabasePath = strings.TrimSpace(env("GEOIP_DATABASE_PATH"))
+	for _, country := range strings.Split(env("GEOIP_BLOCKED_COUNTRIES"), ",") {
+		if country = strings.ToUpper(strings.TrimSpace(country)); country == "" {
+			continue
+		}
+		if len(country) != 2 {
+			return Config{}, fmt.Errorf("GEOIP_BLOCKED_COUNTRIES must list ISO 3166-1 alpha-2 codes (got %q)", country)
+		}
+		cfg.GeoIP.BlockedCountries = append(cfg.GeoIP.BlockedCountries, country)
+	}
+
+	cfg.IPRisk = IPRiskConfig{
+		ListPath:      strings.TrimSpace(env("IP_RISK_LIST_FILE")),
+		DefaultAction: strings.ToLower(fallback(env("IP_RISK_DEFAULT_ACTION"), models.IPRiskFlag)),
+	}
+	if !slices.Contains(models.IPRiskActions, cfg.IPRisk.DefaultAction) {
+		return Config{}, fmt.Errorf("IP_RISK_DEFAULT_ACTION must be one of %s (got %q)", strings.Join(models.IPRiskActions, ", "), cfg.IPRisk.DefaultAction)
+	}
+	ipRiskTTL, err := time.ParseDuration(fallback(env("IP_RISK_POLICY_TTL"), "30s"))
+	if err != nil || ipRiskTTL < 0 {
+		return Config{}, fmt.Errorf("IP_RISK_POLICY_TTL must be a non-negative duration (got %q)", env("IP_RISK_POLICY_TTL"))
+	}
+	cfg.IPRisk.PolicyTTL = ipRiskTTL
+
+	names, err := loadUsernames(env)
+	if err != nil {
+		return Config{}, err
+	}
+	cfg.Usernames = names
+
+	cfg.EmailCheck = EmailCheckConfig{
+		CheckMX:        parseBool(env("EMAIL_CHECK_MX"), false),
+		DisposableList: strings.TrimSpace(env("EMAIL_DISPOSABLE_LIST")),
+	}
+	mxTimeout, err := time.ParseDuration(fallback(env("EMAIL_MX_TIMEOUT"), "3s"))
+	if err != nil || mxTimeout <= 0 {
+		return Config{}, fmt.Errorf("EMAIL_MX_TIMEOUT must be a positive duration (got %q)", env("EMAIL_MX_TIMEOUT"))
+	}
+	cfg.EmailCheck.MXTimeout = mxTimeout
+
+	captchaCfg, err := loadCaptcha(env)
+	if err != nil {
+		return Config{}, err
+	}
+	cfg.Captcha = captchaCfg
+
+	challenges, err := loadChallenges(env, captchaCfg)
+	if err != nil {
+		return Config{}, err
+	}
+	cfg.Challenges = challenges
+
+	games, err := loadGames(env)
+	if err != nil {
+		return Config{}, err
+	}
+	cfg.Games = games
+	cfg.Payments.SandboxSecret = strings.TrimSpace(env("PAYMENT_SANDBOX_SECRET"))
+	approvalMin := fallback(env("WITHDRAWAL_APPROVAL_MIN"), "5000")
+	if cfg.Payments.WithdrawalApprovalMin, err = strconv.ParseFloat(approvalMin, 64); err != nil || cfg.Payments.WithdrawalApprovalMin < 0 {
+		return Config{}, fmt.Errorf("WITHDRAWAL_APPROVAL_MIN must be a non-negative amount (got %q)", approvalMin)
+	}
+
+	moneyCfg, err := loadMoney(env)
+	if err != nil {
+		return Config{}, err
+	}
+	cfg.Money = moneyCfg
+
+	oidc, err := loadOIDC(env, publicURL)
+	if err != nil {
+		return Config{}, err
 	}
+	cfg.OIDC = oidc
 
-	minutes := fallback(os.Getenv("JWT_TTL_MINUTES"), "60")
+	cfg.FaultInjection = parseBool(env("FAULT_INJECTION_ENABLED"), false)
+
+	sameSite, err := parseSameSite(fallback(env("AUTH_COOKIE_SAMESITE"), "lax"))
+	if err != nil {
+		return Config{}, err
+	}
+	cfg.Cookie.SameSite = sameSite
+
+	minutes := fallback(env("JWT_TTL_MINUTES"), "60")
 	if ttlMinutes, err := strconv.Atoi(minutes); err == nil && ttlMinutes > 0 {
-		cfg.JWTTTL = time.Duration(ttlMinutes) * time.Minute
+		cfg.JWT.TTL = time.Duration(ttlMinutes) * time.Minute
 	} else {
-		cfg.JWTTTL = 60 * time.Minute
+		cfg.JWT.TTL = 60 * time.Minute
+	}
+	cfg.JWT.SessionCacheTTL = 30 * time.Second
+	if ttl, err := time.ParseDuration(fallback(env("AUTH_SESSION_CACHE_TTL"), "30s")); err == nil && ttl >= 0 {
+		cfg.JWT.SessionCacheTTL = ttl
 	}
 
-	if cfg.DatabaseURL == "" {
+	if cfg.DB.URL == "" {
 		return Config{}, errors.New("DATABASE_URL is required")
 	}
-	if cfg.JWTSecret == "" {
+	switch scheme, _, _ := strings.Cut(cfg.DB.URL, ":"); scheme {
+	case "sqlite", "sqlite3", "file":
+		return Config{}, errors.New("DATABASE_URL: there is no SQLite backend; use a Postgres URL, or memory: for a store that is lost on shutdown")
+	}
+	if cfg.DB.InMemory() && cfg.DB.ReadURL != "" {
+		return Config{}, errors.New("DATABASE_READ_URL cannot be used with DATABASE_URL=memory:")
+	}
+	if cfg.JWT.Secret == "" {
 		return Config{}, errors.New("JWT_SECRET is required")
 	}
+	if cfg.PhoneRegion != "" && !phone.ValidRegion(cfg.PhoneRegion) {
+		return Config{}, fmt.Errorf("PHONE_DEFAULT_REGION %q is not a known region code", cfg.PhoneRegion)
+	}
 
 	return cfg, nil
 }
 
 // HTTPAddress returns the host:port pair for the HTTP server to bind to.
 func (c Config) HTTPAddress() string {
-	return fmt.Sprintf(":%s", c.Port)
+	return fmt.Sprintf(":%s", c.HTTP.Port)
+}
+
+// lookup returns the configured value for key, or "" when unset.
+type lookup func(key string) string
+
+// newLookup reads CONFIG_FILE, if set, and returns a lookup that prefers its
+// entries over the environment. The file is re-read on every Load so removed
+// entries fall back to the environment after a reload.
+func newLookup() (lookup, error) {
+	path := strings.TrimSpace(os.Getenv("CONFIG_FILE"))
+	if path == "" {
+		return os.Getenv, nil
+	}
+	values, err := godotenv.Read(path)
+	if err != nil {
+		return nil, fmt.Errorf("read CONFIG_FILE: %w", err)
+	}
+	return func(key string) string {
+		if value, ok := values[key]; ok {
+			return value
+		}
+		return os.Getenv(key)
+	}, nil
 }
 
 func fallback(value, def string) string {
@@ -74,3 +966,617 @@ func parseCSV(input string) []string {
 	}
 	return out
 }
+
+func loadCORS(env lookup) (CORSConfig, error) {
+	cors := CORSConfig{
+		AllowedOrigins:   parseCSV(fallback(env("CORS_ALLOWED_ORIGINS"), "*")),
+		AllowedMethods:   parseCSV(fallback(env("CORS_ALLOWED_METHODS"), "GET,POST,PUT,PATCH,DELETE,OPTIONS")),
+		AllowedHeaders:   parseCSV(fallback(env("CORS_ALLOWED_HEADERS"), "Content-Type,Authorization,X-Device-ID,X-Challenge-Token")),
+		AllowCredentials: parseBool(env("CORS_ALLOW_CREDENTIALS"), false),
+	}
+	if exposed := strings.TrimSpace(env("CORS_EXPOSED_HEADERS")); exposed != "" {
+		cors.ExposedHeaders = parseCSV(exposed)
+	}
+	for _, expr := range strings.Split(env("CORS_ALLOWED_ORIGIN_PATTERNS"), ",") {
+		if expr = strings.TrimSpace(expr); expr == "" {
+			continue
+		}
+		pattern, err := regexp.Compile("^(?:" + expr + ")$")
+		if err != nil {
+			return CORSConfig{}, fmt.Errorf("CORS_ALLOWED_ORIGIN_PATTERNS: invalid pattern %q: %w", expr, err)
+		}
+		cors.OriginPatterns = append(cors.OriginPatterns, pattern)
+	}
+
+	maxAge, err := time.ParseDuration(fallback(env("CORS_MAX_AGE"), "10m"))
+	if err != nil || maxAge < 0 {
+		return CORSConfig{}, fmt.Errorf("CORS_MAX_AGE must be a non-negative duration (got %q)", env("CORS_MAX_AGE"))
+	}
+	cors.MaxAge = maxAge
+
+	for _, origin := range cors.AllowedOrigins {
+		if origin == "*" {
+			if cors.AllowCredentials {
+				return CORSConfig{}, errors.New("CORS_ALLOW_CREDENTIALS=true cannot be combined with CORS_ALLOWED_ORIGINS=*; list origins explicitly")
+			}
+			continue
+		}
+		if !strings.HasPrefix(origin, "http://") && !strings.HasPrefix(origin, "https://") {
+			return CORSConfig{}, fmt.Errorf("CORS_ALLOWED_ORIGINS: %q must include the scheme (e.g. https://app.example.com)", origin)
+		}
+		if strings.Contains(origin, "*") && !strings.Contains(origin, "://*.") {
+			return CORSConfig{}, fmt.Errorf("CORS_ALLOWED_ORIGINS: %q only supports a leading subdomain wildcard (https://*.example.com)", origin)
+		}
+	}
+	return cors, nil
+}
+
+func loadNotify(env lookup) (NotifyConfig, error) {
+	n := NotifyConfig{
+		EmailProvider:  strings.ToLower(fallback(env("NOTIFY_EMAIL_PROVIDER"), "log")),
+		SMSProvider:    strings.ToLower(fallback(env("NOTIFY_SMS_PROVIDER"), "log")),
+		FromEmail:      fallback(env("NOTIFY_FROM_EMAIL"), "no-reply@all-in.local"),
+		SMTPHost:       strings.TrimSpace(env("SMTP_HOST")),
+		SMTPPort:       fallback(env("SMTP_PORT"), "587"),
+		SMTPUsername:   strings.TrimSpace(env("SMTP_USERNAME")),
+		SMTPPassword:   strings.TrimSpace(env("SMTP_PASSWORD")),
+		SendGridAPIKey: strings.TrimSpace(env("SENDGRID_API_KEY")),
+		TwilioSID:      strings.TrimSpace(env("TWILIO_ACCOUNT_SID")),
+		TwilioFrom:     strings.TrimSpace(env("TWILIO_FROM_NUMBER")),
+	}
+	switch n.EmailProvider {
+	case "log":
+	case "smtp":
+		if n.SMTPHost == "" {
+			return NotifyConfig{}, errors.New("NOTIFY_EMAIL_PROVIDER=smtp requires SMTP_HOST")
+		}
+	case "sendgrid":
+		if n.SendGridAPIKey == "" {
+			return NotifyConfig{}, errors.New("NOTIFY_EMAIL_PROVIDER=sendgrid requires SENDGRID_API_KEY")
+		}
+	default:
+		return NotifyConfig{}, fmt.Errorf("NOTIFY_EMAIL_PROVIDER must be log, smtp, or sendgrid (got %q)", n.EmailProvider)
+	}
+	switch n.SMSProvider {
+	case "log":
+	case "twilio":
+		if n.TwilioSID == "" || n.TwilioFrom == "" {
+			return NotifyConfig{}, errors.New("NOTIFY_SMS_PROVIDER=twilio requires TWILIO_ACCOUNT_SID and TWILIO_FROM_NUMBER")
+		}
+	default:
+		return NotifyConfig{}, fmt.Errorf("NOTIFY_SMS_PROVIDER must be log or twilio (got %q)", n.SMSProvider)
+	}
+	return n, nil
+}
+
+// loadPool reads the connection pool settings. Unset values keep the pgx defaults;
+// Neon's connection limits usually call for a lower DB_MAX_CONNS.
+func loadPool(env lookup) (postgres.PoolConfig, error) {
+	var pool postgres.PoolConfig
+	for _, setting := range []struct {
+		key string
+		dst *int32
+	}{{"DB_MAX_CONNS", &pool.MaxConns}, {"DB_MIN_CONNS", &pool.MinConns}} {
+		raw := strings.TrimSpace(env(setting.key))
+		if raw == "" {
+			continue
+		}
+		n, err := strconv.ParseInt(raw, 10, 32)
+		if err != nil || n < 0 {
+			return postgres.PoolConfig{}, fmt.Errorf("%s must be a non-negative integer (got %q)", setting.key, raw)
+		}
+		*setting.dst = int32(n)
+	}
+	if pool.MaxConns > 0 && pool.MinConns > pool.MaxConns {
+		return postgres.PoolConfig{}, errors.New("DB_MIN_CONNS cannot exceed DB_MAX_CONNS")
+	}
+	for _, setting := range []struct {
+		key string
+		dst *time.Duration
+	}{
+		{"DB_MAX_CONN_LIFETIME", &pool.MaxConnLifetime},
+		{"DB_MAX_CONN_IDLE_TIME", &pool.MaxConnIdleTime},
+		{"DB_HEALTH_CHECK_PERIOD", &pool.HealthCheckPeriod},
+	} {
+		raw := strings.TrimSpace(env(setting.key))
+		if raw == "" {
+			continue
+		}
+		d, err := time.ParseDuration(raw)
+		if err != nil || d <= 0 {
+			return postgres.PoolConfig{}, fmt.Errorf("%s must be a positive duration (got %q)", setting.key, raw)
+		}
+		*setting.dst = d
+	}
+	for _, setting := range []struct {
+		key string
+		dst *time.Duration
+	}{
+		{"DB_STATEMENT_TIMEOUT", &pool.StatementTimeout},
+		{"DB_QUERY_TIMEOUT", &pool.QueryTimeout},
+	} {
+		raw := fallback(env(setting.key), "5s")
+		d, err := time.ParseDuration(raw)
+		if err != nil || d < 0 {
+			return postgres.PoolConfig{}, fmt.Errorf("%s must be a duration, or 0 to disable (got %q)", setting.key, raw)
+		}
+		*setting.dst = d
+	}
+	for _, setting := range []struct {
+		key string
+		def string
+		min int
+		dst *int
+	}{
+		{"DB_RETRY_ATTEMPTS", "3", 1, &pool.Retry.MaxAttempts},
+		{"DB_BREAKER_THRESHOLD", "5", 0, &pool.Retry.BreakerThreshold},
+	} {
+		raw := fallback(env(setting.key), setting.def)
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < setting.min {
+			return postgres.PoolConfig{}, fmt.Errorf("%s must be an integer of at least %d (got %q)", setting.key, setting.min, raw)
+		}
+		*setting.dst = n
+	}
+	for _, setting := range []struct {
+		key string
+		def string
+		dst *time.Duration
+	}{
+		{"DB_RETRY_BASE_DELAY", "50ms", &pool.Retry.BaseDelay},
+		{"DB_RETRY_MAX_DELAY", "1s", &pool.Retry.MaxDelay},
+		{"DB_BREAKER_COOLDOWN", "10s", &pool.Retry.BreakerCooldown},
+	} {
+		raw := fallback(env(setting.key), setting.def)
+		d, err := time.ParseDuration(raw)
+		if err != nil || d <= 0 {
+			return postgres.PoolConfig{}, fmt.Errorf("%s must be a positive duration (got %q)", setting.key, raw)
+		}
+		*setting.dst = d
+	}
+	if pool.Retry.BaseDelay > pool.Retry.MaxDelay {
+		return postgres.PoolConfig{}, errors.New("DB_RETRY_BASE_DELAY cannot exceed DB_RETRY_MAX_DELAY")
+	}
+	pool.ExecMode = strings.ToLower(strings.TrimSpace(env("DB_STATEMENT_CACHE_MODE")))
+	switch pool.ExecMode {
+	case "", "cache_statement", "cache_describe", "describe_exec", "exec", "simple_protocol":
+	default:
+		return postgres.PoolConfig{}, fmt.Errorf("DB_STATEMENT_CACHE_MODE must be cache_statement, cache_describe, describe_exec, exec or simple_protocol (got %q)", pool.ExecMode)
+	}
+	return pool, nil
+}
+
+// loadJobs reads the worker pool sizes and starvation limit of the job queue.
+func loadJobs(env lookup) (JobsConfig, error) {
+	cfg := JobsConfig{Reserved: map[jobs.Priority]int{}}
+	for _, setting := range []struct {
+		key string
+		def string
+		min int
+		dst *int
+	}{
+		{"JOB_WORKERS", "4", 1, &cfg.Workers},
+		{"JOB_QUEUE_SIZE", "1024", 1, &cfg.QueueSize},
+	} {
+		raw := fallback(env(setting.key), setting.def)
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < setting.min {
+			return JobsConfig{}, fmt.Errorf("%s must be an integer of at least %d (got %q)", setting.key, setting.min, raw)
+		}
+		*setting.dst = n
+	}
+	// One high-priority worker is kept free by default so payouts and
+	// security mail never queue behind a backlog.
+	defaults := map[jobs.Priority]string{jobs.PriorityHigh: "1"}
+	for _, p := range jobs.Priorities {
+		key := "JOB_WORKERS_" + strings.ToUpper(string(p))
+		raw := fallback(env(key), fallback(defaults[p], "0"))
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 0 {
+			return JobsConfig{}, fmt.Errorf("%s must be a non-negative integer (got %q)", key, raw)
+		}
+		cfg.Reserved[p] = n
+	}
+	raw := fallback(env("JOB_MAX_WAIT"), "30s")
+	wait, err := time.ParseDuration(raw)
+	if err != nil || wait < 0 {
+		return JobsConfig{}, fmt.Errorf("JOB_MAX_WAIT must be a duration, or 0 to disable (got %q)", raw)
+	}
+	cfg.MaxWait = wait
+	raw = fallback(env("JOB_BULK_STALE_AFTER"), "2m")
+	stale, err := time.ParseDuration(raw)
+	if err != nil || stale <= 0 {
+		return JobsConfig{}, fmt.Errorf("JOB_BULK_STALE_AFTER must be a positive duration (got %q)", raw)
+	}
+	cfg.BulkStaleAfter = stale
+	return cfg, nil
+}
+
+// loadArchive reads the archiver's retention window and schedule.
+func loadArchive(env lookup) (ArchiveConfig, error) {
+	var cfg ArchiveConfig
+	for _, setting := range []struct {
+		key string
+		def string
+		min int
+		dst *int
+	}{
+		{"ARCHIVE_AFTER_MONTHS", "0", 0, &cfg.AfterMonths},
+		{"ARCHIVE_BATCH_SIZE", "1000", 1, &cfg.BatchSize},
+	} {
+		raw := fallback(env(setting.key), setting.def)
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < setting.min {
+			return ArchiveConfig{}, fmt.Errorf("%s must be an integer of at least %d (got %q)", setting.key, setting.min, raw)
+		}
+		*setting.dst = n
+	}
+	raw := fallback(env("ARCHIVE_INTERVAL"), "24h")
+	interval, err := time.ParseDuration(raw)
+	if err != nil || interval <= 0 {
+		return ArchiveConfig{}, fmt.Errorf("ARCHIVE_INTERVAL must be a positive duration (got %q)", raw)
+	}
+	cfg.Interval = interval
+	return cfg, nil
+}
+
+// loadDBInsights reads the index advisor's schedule and thresholds.
+func loadDBInsights(env lookup) (DBInsightsConfig, error) {
+	var cfg DBInsightsConfig
+	interval, err := time.ParseDuration(fallback(env("DB_INSIGHTS_INTERVAL"), "24h"))
+	if err != nil || interval < 0 {
+		return DBInsightsConfig{}, fmt.Errorf("DB_INSIGHTS_INTERVAL must be a non-negative duration (got %q)", env("DB_INSIGHTS_INTERVAL"))
+	}
+	cfg.Interval = interval
+	slow, err := time.ParseDuration(fallback(env("DB_INSIGHTS_SLOW_QUERY"), "100ms"))
+	if err != nil || slow <= 0 {
+		return DBInsightsConfig{}, fmt.Errorf("DB_INSIGHTS_SLOW_QUERY must be a positive duration (got %q)", env("DB_INSIGHTS_SLOW_QUERY"))
+	}
+	cfg.SlowQuery = slow
+	raw := fallback(env("DB_INSIGHTS_MIN_ROWS"), "10000")
+	minRows, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil || minRows < 0 {
+		return DBInsightsConfig{}, fmt.Errorf("DB_INSIGHTS_MIN_ROWS must be a non-negative integer (got %q)", raw)
+	}
+	cfg.MinRows = minRows
+	return cfg, nil
+}
+
+// loadGames reads the game providers from GAME_PROVIDERS, given as
+// provider=launch URL pairs, their callback secrets from
+// GAME_PROVIDER_SECRETS, given as provider=secret pairs, the session
+// lifetimes, the tournament close interval and the jackpots from JACKPOTS,
+// given as name=percent pairs. Secrets are left out of error messages.
+func loadGames(env lookup) (GamesConfig, error) {
+	cfg := GamesConfig{Providers: map[string]string{}}
+	for _, pair := range strings.Split(env("GAME_PROVIDERS"), ",") {
+		if pair = strings.TrimSpace(pair); pair == "" {
+			continue
+		}
+		name, url, ok := strings.Cut(pair, "=")
+		name, url = strings.ToLower(strings.TrimSpace(name)), strings.TrimSpace(url)
+		if !ok || name == "" || strings.Contains(name, ":") || !(strings.HasPrefix(url, "https://") || strings.HasPrefix(url, "http://")) {
+			return GamesConfig{}, fmt.Errorf("GAME_PROVIDERS entries must be provider=https://launch-url (got %q)", pair)
+		}
+		cfg.Providers[name] = url
+	}
+	cfg.Secrets = map[string]string{}
+	for _, pair := range strings.Split(env("GAME_PROVIDER_SECRETS"), ",") {
+		if pair = strings.TrimSpace(pair); pair == "" {
+			continue
+		}
+		name, secret, _ := strings.Cut(pair, "=")
+		name, secret = strings.ToLower(strings.TrimSpace(name)), strings.TrimSpace(secret)
+		if name == "" || secret == "" {
+			return GamesConfig{}, fmt.Errorf("GAME_PROVIDER_SECRETS entries must be provider=secret (got %q)", name+"=...")
+		}
+		if _, ok := cfg.Providers[name]; !ok {
+			return GamesConfig{}, fmt.Errorf("GAME_PROVIDER_SECRETS names a provider missing from GAME_PROVIDERS (got %q)", name)
+		}
+		cfg.Secrets[name] = secret
+	}
+	for _, setting := range []struct {
+		key string
+		def string
+		dst *time.Duration
+	}{
+		{"GAME_LAUNCH_TTL", "2m", &cfg.LaunchTTL},
+		{"GAME_SESSION_TTL", "4h", &cfg.SessionTTL},
+		{"GAMING_LIMIT_INCREASE_DELAY", "24h", &cfg.LimitIncreaseDelay},
+		{"TOURNAMENT_CLOSE_INTERVAL", "1m", &cfg.TournamentCloseInterval},
+	} {
+		raw := fallback(env(setting.key), setting.def)
+		d, err := time.ParseDuration(raw)
+		if err != nil || d <= 0 {
+			return GamesConfig{}, fmt.Errorf("%s must be a positive duration (got %q)", setting.key, raw)
+		}
+		*setting.dst = d
+	}
+	cfg.Jackpots = map[string]float64{}
+	var total float64
+	for _, pair := range strings.Split(env("JACKPOTS"), ",") {
+		if pair = strings.TrimSpace(pair); pair == "" {
+			continue
+		}
+		name, raw, ok := strings.Cut(pair, "=")
+		name = strings.ToLower(strings.TrimSpace(name))
+		percent, err := strconv.ParseFloat(strings.TrimSpace(raw), 64)
+		if !ok || name == "" || strings.Contains(name, ":") || err != nil || !(percent > 0 && percent <= 100) {
+			return GamesConfig{}, fmt.Errorf("JACKPOTS entries must be name=percent of each stake (got %q)", pair)
+		}
+		cfg.Jackpots[name] = percent
+		total += percent
+	}
+	if total > 100 {
+		return GamesConfig{}, fmt.Errorf("JACKPOTS must take at most 100 percent of a stake in all (got %g)", total)
+	}
+	return cfg, nil
+}
+
+// loadReports reads the report schedule, recipients and link lifetime.
+func loadReports(env lookup) (ReportsConfig, error) {
+	var cfg ReportsConfig
+	interval, err := time.ParseDuration(fallback(env("REPORT_INTERVAL"), "1h"))
+	if err != nil || interval < 0 {
+		return ReportsConfig{}, fmt.Errorf("REPORT_INTERVAL must be a non-negative duration (got %q)", env("REPORT_INTERVAL"))
+	}
+	cfg.Interval = interval
+	for _, to := range strings.Split(env("REPORT_RECIPIENTS"), ",") {
+		if to = strings.TrimSpace(to); to == "" {
+			continue
+		}
+		if _, err := mail.ParseAddress(to); err != nil {
+			return ReportsConfig{}, fmt.Errorf("REPORT_RECIPIENTS must list email addresses (got %q)", to)
+		}
+		cfg.Recipients = append(cfg.Recipients, to)
+	}
+	linkTTL, err := time.ParseDuration(fallback(env("REPORT_LINK_TTL"), "72h"))
+	if err != nil || linkTTL <= 0 || linkTTL > 7*24*time.Hour {
+		return ReportsConfig{}, fmt.Errorf("REPORT_LINK_TTL must be a positive duration of at most 168h (got %q)", env("REPORT_LINK_TTL"))
+	}
+	cfg.LinkTTL = linkTTL
+	return cfg, nil
+}
+
+// loadMoney reads the wallet currency and the locales amounts are formatted
+// in, rejecting any the money package cannot format.
+func loadMoney(env lookup) (MoneyConfig, error) {
+	cfg := MoneyConfig{
+		Currency:      strings.ToUpper(fallback(env("MONEY_CURRENCY"), "USD")),
+		DefaultLocale: fallback(env("MONEY_DEFAULT_LOCALE"), "en-US"),
+	}
+	if !money.KnownCurrency(cfg.Currency) {
+		return MoneyConfig{}, fmt.Errorf("MONEY_CURRENCY %q is not a supported currency", cfg.Currency)
+	}
+	for _, tag := range strings.Split(env("MONEY_LOCALES"), ",") {
+		if tag = strings.TrimSpace(tag); tag == "" {
+			continue
+		}
+		if !money.KnownLocale(tag) {
+			return MoneyConfig{}, fmt.Errorf("MONEY_LOCALES must list supported locales such as en-US or de-DE (got %q)", tag)
+		}
+		cfg.Locales = append(cfg.Locales, tag)
+	}
+	if !money.KnownLocale(cfg.DefaultLocale) {
+		return MoneyConfig{}, fmt.Errorf("MONEY_DEFAULT_LOCALE %q is not a supported locale", cfg.DefaultLocale)
+	}
+	if len(cfg.Locales) > 0 && !slices.Contains(cfg.Locales, cfg.DefaultLocale) {
+		return MoneyConfig{}, fmt.Errorf("MONEY_LOCALES must include MONEY_DEFAULT_LOCALE %s", cfg.DefaultLocale)
+	}
+	return cfg, nil
+}
+
+// loadUsernames reads the username rules: 3 to 30 ASCII letters and digits
+// with . _ - inside by default. USERNAME_SYMBOLS=none allows no symbols.
+func loadUsernames(env lookup) (UsernameConfig, error) {
+	cfg := UsernameConfig{
+		Symbols:      fallback(env("USERNAME_SYMBOLS"), "._-"),
+		ReservedPath: strings.TrimSpace(env("USERNAME_RESERVED_FILE")),
+	}
+	if strings.EqualFold(cfg.Symbols, "none") {
+		cfg.Symbols = ""
+	}
+	var err error
+	if cfg.MinLength, err = strconv.Atoi(fallback(env("USERNAME_MIN_LENGTH"), "3")); err != nil || cfg.MinLength < 1 {
+		return UsernameConfig{}, fmt.Errorf("USERNAME_MIN_LENGTH must be a positive integer (got %q)", env("USERNAME_MIN_LENGTH"))
+	}
+	if cfg.MaxLength, err = strconv.Atoi(fallback(env("USERNAME_MAX_LENGTH"), "30")); err != nil || cfg.MaxLength < cfg.MinLength {
+		return UsernameConfig{}, fmt.Errorf("USERNAME_MAX_LENGTH must be an integer of at least USERNAME_MIN_LENGTH (got %q)", env("USERNAME_MAX_LENGTH"))
+	}
+	switch charset := strings.ToLower(fallback(env("USERNAME_CHARSET"), "ascii")); charset {
+	case "ascii":
+	case "unicode":
+		cfg.Unicode = true
+	default:
+		return UsernameConfig{}, fmt.Errorf("USERNAME_CHARSET must be ascii or unicode (got %q)", charset)
+	}
+	if strings.ContainsFunc(cfg.Symbols, func(r rune) bool { return unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.IsSpace(r) }) {
+		return UsernameConfig{}, fmt.Errorf("USERNAME_SYMBOLS must list characters other than letters, digits and spaces (got %q)", cfg.Symbols)
+	}
+	return cfg, nil
+}
+
+// loadCaptcha reads the CAPTCHA provider and where sign-ups and sign-ins must
+// solve one. Neither does by default.
+func loadCaptcha(env lookup) (CaptchaConfig, error) {
+	cfg := CaptchaConfig{
+		Provider:    strings.ToLower(fallback(env("CAPTCHA_PROVIDER"), captcha.Turnstile)),
+		Secret:      strings.TrimSpace(env("CAPTCHA_SECRET")),
+		SiteKey:     strings.TrimSpace(env("CAPTCHA_SITE_KEY")),
+		Signup:      parseBool(env("CAPTCHA_SIGNUP"), false),
+		BypassToken: strings.TrimSpace(env("CAPTCHA_BYPASS_TOKEN")),
+	}
+	defaultURL, ok := captcha.VerifyURLs[cfg.Provider]
+	if !ok {
+		return CaptchaConfig{}, fmt.Errorf("CAPTCHA_PROVIDER must be one of %s (got %q)", strings.Join(slices.Sorted(maps.Keys(captcha.VerifyURLs)), ", "), cfg.Provider)
+	}
+	cfg.VerifyURL = fallback(env("CAPTCHA_VERIFY_URL"), defaultURL)
+	var err error
+	if cfg.LoginAfterFailures, err = strconv.Atoi(fallback(env("CAPTCHA_LOGIN_AFTER_FAILURES"), "0")); err != nil || cfg.LoginAfterFailures < 0 {
+		return CaptchaConfig{}, fmt.Errorf("CAPTCHA_LOGIN_AFTER_FAILURES must be a non-negative integer (got %q)", env("CAPTCHA_LOGIN_AFTER_FAILURES"))
+	}
+	if cfg.LoginWindow, err = time.ParseDuration(fallback(env("CAPTCHA_LOGIN_WINDOW"), "15m")); err != nil || cfg.LoginWindow <= 0 {
+		return CaptchaConfig{}, fmt.Errorf("CAPTCHA_LOGIN_WINDOW must be a positive duration (got %q)", env("CAPTCHA_LOGIN_WINDOW"))
+	}
+	if (cfg.Signup || cfg.LoginAfterFailures > 0) && cfg.Secret == "" && cfg.BypassToken == "" {
+		return CaptchaConfig{}, errors.New("CAPTCHA_SIGNUP and CAPTCHA_LOGIN_AFTER_FAILURES require CAPTCHA_SECRET")
+	}
+	return cfg, nil
+}
+
+// loadChallenges reads the step-up challenge policies. Password changes are
+// challenged from new devices only; withdrawals from CHALLENGE_WITHDRAWAL_MIN up.
+// CAPTCHA challenges use the captcha provider.
+func loadChallenges(env lookup, captcha CaptchaConfig) (ChallengeConfig, error) {
+	cfg := ChallengeConfig{
+		Policies:         map[string]models.ChallengePolicy{},
+		CaptchaVerifyURL: captcha.VerifyURL,
+		CaptchaSecret:    captcha.Secret,
+		CaptchaSiteKey:   captcha.SiteKey,
+	}
+	for _, setting := range []struct {
+		key    string
+		action string
+		policy models.ChallengePolicy
+	}{
+		{"CHALLENGE_PASSWORD_CHANGE", models.ActionPasswordChange, models.ChallengePolicy{NewDeviceOnly: true}},
+		{"CHALLENGE_WITHDRAWAL", models.ActionWithdrawal, models.ChallengePolicy{}},
+	} {
+		method := strings.ToLower(fallback(env(setting.key), models.ChallengeOTP))
+		if method == "none" {
+			continue
+		}
+		if !slices.Contains(models.ChallengeMethods, method) {
+			return ChallengeConfig{}, fmt.Errorf("%s must be none or one of %s (got %q)", setting.key, strings.Join(models.ChallengeMethods, ", "), method)
+		}
+		if method == models.ChallengeCaptcha && cfg.CaptchaSecret == "" {
+			return ChallengeConfig{}, fmt.Errorf("%s=captcha requires CAPTCHA_SECRET", setting.key)
+		}
+		setting.policy.Method = method
+		cfg.Policies[setting.action] = setting.policy
+	}
+	if policy, ok := cfg.Policies[models.ActionWithdrawal]; ok {
+		raw := fallback(env("CHALLENGE_WITHDRAWAL_MIN"), "1000")
+		minAmount, err := strconv.ParseFloat(raw, 64)
+		if err != nil || minAmount < 0 {
+			return ChallengeConfig{}, fmt.Errorf("CHALLENGE_WITHDRAWAL_MIN must be a non-negative amount (got %q)", raw)
+		}
+		policy.MinAmount = minAmount
+		cfg.Policies[models.ActionWithdrawal] = policy
+	}
+	for _, setting := range []struct {
+		key string
+		def string
+		dst *time.Duration
+	}{
+		{"CHALLENGE_NEW_DEVICE_AGE", "24h", &cfg.NewDeviceAge},
+		{"CHALLENGE_TTL", "10m", &cfg.TTL},
+	} {
+		raw := fallback(env(setting.key), setting.def)
+		d, err := time.ParseDuration(raw)
+		if err != nil || d <= 0 {
+			return ChallengeConfig{}, fmt.Errorf("%s must be a positive duration (got %q)", setting.key, raw)
+		}
+		*setting.dst = d
+	}
+	raw := fallback(env("CHALLENGE_MAX_ATTEMPTS"), "5")
+	attempts, err := strconv.Atoi(raw)
+	if err != nil || attempts < 1 {
+		return ChallengeConfig{}, fmt.Errorf("CHALLENGE_MAX_ATTEMPTS must be a positive integer (got %q)", raw)
+	}
+	cfg.MaxAttempts = attempts
+	return cfg, nil
+}
+
+// loadOIDC reads the OpenID Connect provider settings; they are only
+// validated when a signing key is configured.
+func loadOIDC(env lookup, publicURL string) (OIDCConfig, error) {
+	cfg := OIDCConfig{
+		SigningKeyPath: strings.TrimSpace(env("OIDC_SIGNING_KEY_FILE")),
+		Issuer:         strings.TrimRight(fallback(env("OIDC_ISSUER"), publicURL), "/"),
+		LoginURL:       strings.TrimSpace(env("OIDC_LOGIN_URL")),
+	}
+	if cfg.SigningKeyPath == "" {
+		return cfg, nil
+	}
+	if cfg.LoginURL == "" {
+		return OIDCConfig{}, errors.New("OIDC_SIGNING_KEY_FILE requires OIDC_LOGIN_URL")
+	}
+	raw := fallback(env("OIDC_TOKEN_TTL"), "1h")
+	ttl, err := time.ParseDuration(raw)
+	if err != nil || ttl <= 0 {
+		return OIDCConfig{}, fmt.Errorf("OIDC_TOKEN_TTL must be a positive duration (got %q)", raw)
+	}
+	cfg.TokenTTL = ttl
+	return cfg, nil
+}
+
+// loadTracing reads the standard OpenTelemetry exporter variables.
+func loadTracing(env lookup) (TracingConfig, error) {
+	cfg := TracingConfig{
+		Endpoint:    strings.TrimSpace(env("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT")),
+		Headers:     map[string]string{},
+		ServiceName: fallback(env("OTEL_SERVICE_NAME"), "all-in-be"),
+		SampleRatio: 1,
+	}
+	if cfg.Endpoint == "" {
+		if base := strings.TrimSpace(env("OTEL_EXPORTER_OTLP_ENDPOINT")); base != "" {
+			cfg.Endpoint = strings.TrimRight(base, "/") + "/v1/traces"
+		}
+	}
+	for _, pair := range strings.Split(env("OTEL_EXPORTER_OTLP_HEADERS"), ",") {
+		if pair = strings.TrimSpace(pair); pair == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(pair, "=")
+		if !ok || strings.TrimSpace(key) == "" {
+			return TracingConfig{}, fmt.Errorf("OTEL_EXPORTER_OTLP_HEADERS entries must be key=value (got %q)", pair)
+		}
+		cfg.Headers[strings.TrimSpace(key)] = strings.TrimSpace(value)
+	}
+	if raw := strings.TrimSpace(env("OTEL_TRACES_SAMPLER_ARG")); raw != "" {
+		ratio, err := strconv.ParseFloat(raw, 64)
+		if err != nil || ratio < 0 || ratio > 1 {
+			return TracingConfig{}, fmt.Errorf("OTEL_TRACES_SAMPLER_ARG must be between 0 and 1 (got %q)", raw)
+		}
+		cfg.SampleRatio = ratio
+	}
+	return cfg, nil
+}
+
+// loadLogging reads the request body sampling settings.
+func loadLogging(env lookup) (LoggingConfig, error) {
+	var cfg LoggingConfig
+	raw := fallback(env("LOG_BODY_SAMPLE_RATE"), "0")
+	rate, err := strconv.ParseFloat(raw, 64)
+	if err != nil || rate < 0 || rate > 1 {
+		return LoggingConfig{}, fmt.Errorf("LOG_BODY_SAMPLE_RATE must be between 0 and 1 (got %q)", raw)
+	}
+	cfg.BodySampleRate = rate
+	raw = fallback(env("LOG_BODY_MAX_BYTES"), "16384")
+	if cfg.BodyMaxBytes, err = strconv.Atoi(raw); err != nil || cfg.BodyMaxBytes < 1 {
+		return LoggingConfig{}, fmt.Errorf("LOG_BODY_MAX_BYTES must be an integer of at least 1 (got %q)", raw)
+	}
+	return cfg, nil
+}
+
+func parseBool(value string, def bool) bool {
+	parsed, err := strconv.ParseBool(strings.TrimSpace(value))
+	if err != nil {
+		return def
+	}
+	return parsed
+}
+
+func parseSameSite(value string) (http.SameSite, error) {
+	switch strings.ToLower(value) {
+	case "lax":
+		return http.SameSiteLaxMode, nil
+	case "strict":
+		return http.SameSiteStrictMode, nil
+	case "none":
+		return 0, errors.New("AUTH_COOKIE_SAMESITE=none is not supported: requests authenticated by cookie carry no CSRF token, so the cookie must not be sent cross-site")
+	default:
+		return 0, fmt.Errorf("AUTH_COOKIE_SAMESITE must be lax or strict (got %q)", value)
+	}
+}
@@ -0,0 +1,95 @@
+package config
+
+import (
+	"context"
+	"log"
+	"os"
+	"os/signal"
+	"reflect"
+	"sync"
+	"syscall"
+)
+
+// Watcher reloads configuration on SIGHUP. Only the CORS, RateLimit and Features
+// sections are applied; changes to anything else are logged and need a restart.
+type Watcher struct {
+	mu          sync.Mutex
+	current     Config
+	subscribers []func(Config)
+}
+
+// NewWatcher starts from the configuration the process booted with.
+func NewWatcher(initial Config) *Watcher {
+	return &Watcher{current: initial}
+}
+
+// Current returns the configuration in effect.
+func (w *Watcher) Current() Config {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.current
+}
+
+// OnReload registers fn to receive the configuration after every successful reload.
+func (w *Watcher) OnReload(fn func(Config)) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.subscribers = append(w.subscribers, fn)
+}
+
+// Reload re-reads CONFIG_FILE and the environment. An invalid configuration is
+// rejected and the current one stays in effect.
+func (w *Watcher) Reload() error {
+	loaded, err := Load()
+	if err != nil {
+		return err
+	}
+
+	w.mu.Lock()
+	next := w.current.withReloadable(loaded)
+	if !reflect.DeepEqual(restartOnly(loaded), restartOnly(w.current)) {
+		log.Println("config: settings other than CORS, rate limits and feature flags changed; restart to apply them")
+	}
+	w.current = next
+	subscribers := append([]func(Config){}, w.subscribers...)
+	w.mu.Unlock()
+
+	for _, fn := range subscribers {
+		fn(next)
+	}
+	return nil
+}
+
+// Watch reloads on every SIGHUP until ctx is done.
+func (w *Watcher) Watch(ctx context.Context) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			if err := w.Reload(); err != nil {
+				log.Printf("config: reload failed, keeping current configuration: %v", err)
+				continue
+			}
+			log.Println("config: reloaded")
+		}
+	}
+}
+
+// withReloadable returns c with the hot-reloadable sections taken from from.
+// The policy cache TTL is fixed at startup.
+func (c Config) withReloadable(from Config) Config {
+	c.CORS = from.CORS
+	c.RateLimit.AuthRequests = from.RateLimit.AuthRequests
+	c.RateLimit.AuthWindow = from.RateLimit.AuthWindow
+	c.Features = from.Features
+	return c
+}
+
+// restartOnly blanks the hot-reloadable sections so the rest can be compared.
+func restartOnly(c Config) Config {
+	return c.withReloadable(Config{})
+}
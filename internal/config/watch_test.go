@@ -0,0 +1,55 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWatcherReloadAppliesOnlyReloadableSections(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "app.env")
+	write := func(contents string) {
+		t.Helper()
+		if err := os.WriteFile(file, []byte(contents), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Setenv("DATABASE_URL", "postgres://localhost/test")
+	t.Setenv("JWT_SECRET", "secret")
+	t.Setenv("CONFIG_FILE", file)
+	write("CORS_ALLOWED_ORIGINS=https://a.example.com\nAUTH_RATE_LIMIT=10\n")
+
+	initial, err := Load()
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	w := NewWatcher(initial)
+	var applied []Config
+	w.OnReload(func(c Config) { applied = append(applied, c) })
+
+	write("CORS_ALLOWED_ORIGINS=https://b.example.com\nAUTH_RATE_LIMIT=3\nFEATURE_FLAGS=Beta, dark-mode\nJWT_ISSUER=changed\n")
+	if err := w.Reload(); err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+	if len(applied) != 1 {
+		t.Fatalf("subscriber called %d times, want 1", len(applied))
+	}
+	got := w.Current()
+	if got.CORS.AllowedOrigins[0] != "https://b.example.com" || got.RateLimit.AuthRequests != 3 {
+		t.Fatalf("reloadable sections not applied: %+v %+v", got.CORS.AllowedOrigins, got.RateLimit)
+	}
+	if !got.Features.Enabled("beta") || !got.Features.Enabled("dark-mode") {
+		t.Fatalf("features = %v", got.Features)
+	}
+	if got.JWT.Issuer != initial.JWT.Issuer {
+		t.Fatalf("JWT issuer changed to %q without a restart", got.JWT.Issuer)
+	}
+
+	write("CORS_MAX_AGE=-1s\n")
+	if err := w.Reload(); err == nil {
+		t.Fatal("invalid configuration should be rejected")
+	}
+	if len(applied) != 1 || w.Current().RateLimit.AuthRequests != 3 {
+		t.Fatal("failed reload must keep the current configuration")
+	}
+}
@@ -0,0 +1,161 @@
+package configbundle
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/hongminglow/all-in-be/internal/confighistory"
+	"github.com/hongminglow/all-in-be/internal/models"
+	"github.com/hongminglow/all-in-be/internal/storage"
+)
+
+// FormatVersion is bumped whenever the bundle layout changes incompatibly.
+const FormatVersion = 1
+
+// Bundle is a portable snapshot of one tenant's runtime configuration, exported from
+// one environment (e.g. sandbox) and imported into another (e.g. production).
+type Bundle struct {
+	FormatVersion int                      `json:"format_version"`
+	Tenant        string                   `json:"tenant"`
+	ExportedAt    time.Time                `json:"exported_at"`
+	RateLimits    []models.RateLimitPolicy `json:"rate_limits"`
+}
+
+// Diff lists what importing a bundle would change.
+type Diff struct {
+	RateLimits RateLimitDiff `json:"rate_limits"`
+}
+
+// RateLimitDiff groups rate-limit policy changes by kind.
+type RateLimitDiff struct {
+	Added   []models.RateLimitPolicy `json:"added"`
+	Changed []RateLimitChange        `json:"changed"`
+	Removed []models.RateLimitPolicy `json:"removed"`
+}
+
+// RateLimitChange pairs the current and incoming versions of a policy.
+type RateLimitChange struct {
+	Before models.RateLimitPolicy `json:"before"`
+	After  models.RateLimitPolicy `json:"after"`
+}
+
+// Empty reports whether the import would be a no-op.
+func (d Diff) Empty() bool {
+	r := d.RateLimits
+	return len(r.Added) == 0 && len(r.Changed) == 0 && len(r.Removed) == 0
+}
+
+// Export snapshots the tenant's current configuration.
+func Export(ctx context.Context, store storage.RateLimitStore, tenant string) (Bundle, error) {
+	all, err := store.ListRateLimitPolicies(ctx)
+	if err != nil {
+		return Bundle{}, err
+	}
+	return Bundle{
+		FormatVersion: FormatVersion,
+		Tenant:        tenant,
+		ExportedAt:    time.Now().UTC(),
+		RateLimits:    forTenant(all, tenant),
+	}, nil
+}
+
+// Validate checks a bundle before it is diffed or applied.
+func Validate(b Bundle, knownRouteClasses []string) error {
+	if b.FormatVersion != FormatVersion {
+		return fmt.Errorf("unsupported format_version %d (expected %d)", b.FormatVersion, FormatVersion)
+	}
+	known := make(map[string]bool, len(knownRouteClasses))
+	for _, c := range knownRouteClasses {
+		known[c] = true
+	}
+	seen := make(map[string]bool, len(b.RateLimits))
+	for _, p := range b.RateLimits {
+		if p.Tenant != b.Tenant {
+			return fmt.Errorf("rate limit for route class %q belongs to tenant %q, not %q", p.RouteClass, p.Tenant, b.Tenant)
+		}
+		if !known[p.RouteClass] {
+			return fmt.Errorf("unknown route class %q", p.RouteClass)
+		}
+		if seen[p.RouteClass] {
+			return fmt.Errorf("duplicate rate limit for route class %q", p.RouteClass)
+		}
+		seen[p.RouteClass] = true
+		if p.Requests <= 0 || p.WindowSeconds <= 0 {
+			return fmt.Errorf("rate limit for route class %q must have positive requests and window_seconds", p.RouteClass)
+		}
+	}
+	return nil
+}
+
+// Compare computes the changes needed to turn current into incoming. Both must
+// belong to the same tenant.
+func Compare(current, incoming Bundle) Diff {
+	var diff Diff
+	before := make(map[string]models.RateLimitPolicy, len(current.RateLimits))
+	for _, p := range current.RateLimits {
+		before[p.RouteClass] = p
+	}
+	for _, p := range incoming.RateLimits {
+		old, ok := before[p.RouteClass]
+		switch {
+		case !ok:
+			diff.RateLimits.Added = append(diff.RateLimits.Added, p)
+		case old.Requests != p.Requests || old.WindowSeconds != p.WindowSeconds:
+			diff.RateLimits.Changed = append(diff.RateLimits.Changed, RateLimitChange{Before: old, After: p})
+		}
+		delete(before, p.RouteClass)
+	}
+	for _, p := range before {
+		diff.RateLimits.Removed = append(diff.RateLimits.Removed, p)
+	}
+	sort.Slice(diff.RateLimits.Removed, func(i, j int) bool {
+		return diff.RateLimits.Removed[i].RouteClass < diff.RateLimits.Removed[j].RouteClass
+	})
+	return diff
+}
+
+// Apply makes the tenant's configuration match the bundle inside a single
+// transaction and returns the diff that was applied. Each change is recorded in
+// the configuration history under actorID. With dryRun it only computes the diff.
+func Apply(ctx context.Context, uow storage.UnitOfWork, actorID int64, b Bundle, dryRun bool) (Diff, error) {
+	var diff Diff
+	err := uow.WithTx(ctx, func(tx storage.Repositories) error {
+		current, err := Export(ctx, tx, b.Tenant)
+		if err != nil {
+			return err
+		}
+		diff = Compare(current, b)
+		if dryRun {
+			return nil
+		}
+		for _, p := range diff.RateLimits.Removed {
+			if err := confighistory.DeleteRateLimit(ctx, tx, actorID, p.Tenant, p.RouteClass); err != nil {
+				return fmt.Errorf("remove rate limit %s: %w", p.RouteClass, err)
+			}
+		}
+		for _, p := range diff.RateLimits.Added {
+			if _, err := confighistory.SaveRateLimit(ctx, tx, actorID, p); err != nil {
+				return fmt.Errorf("add rate limit %s: %w", p.RouteClass, err)
+			}
+		}
+		for _, c := range diff.RateLimits.Changed {
+			if _, err := confighistory.SaveRateLimit(ctx, tx, actorID, c.After); err != nil {
+				return fmt.Errorf("update rate limit %s: %w", c.After.RouteClass, err)
+			}
+		}
+		return nil
+	})
+	return diff, err
+}
+
+func forTenant(policies []models.RateLimitPolicy, tenant string) []models.RateLimitPolicy {
+	out := []models.RateLimitPolicy{}
+	for _, p := range policies {
+		if p.Tenant == tenant {
+			out = append(out, p)
+		}
+	}
+	return out
+}
@@ -0,0 +1,93 @@
+package configbundle
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/hongminglow/all-in-be/internal/models"
+)
+
+func TestCompare(t *testing.T) {
+	current := Bundle{RateLimits: []models.RateLimitPolicy{
+		{RouteClass: "auth", Requests: 10, WindowSeconds: 60},
+		{RouteClass: "api", Requests: 100, WindowSeconds: 60},
+		{RouteClass: "legacy", Requests: 1, WindowSeconds: 1},
+	}}
+	incoming := Bundle{RateLimits: []models.RateLimitPolicy{
+		{RouteClass: "auth", Requests: 10, WindowSeconds: 60},
+		{RouteClass: "api", Requests: 500, WindowSeconds: 60},
+		{RouteClass: "export", Requests: 2, WindowSeconds: 3600},
+	}}
+
+	diff := Compare(current, incoming)
+	if len(diff.RateLimits.Added) != 1 || diff.RateLimits.Added[0].RouteClass != "export" {
+		t.Fatalf("added = %+v", diff.RateLimits.Added)
+	}
+	if len(diff.RateLimits.Changed) != 1 || diff.RateLimits.Changed[0].Before.Requests != 100 || diff.RateLimits.Changed[0].After.Requests != 500 {
+		t.Fatalf("changed = %+v", diff.RateLimits.Changed)
+	}
+	if len(diff.RateLimits.Removed) != 1 || diff.RateLimits.Removed[0].RouteClass != "legacy" {
+		t.Fatalf("removed = %+v", diff.RateLimits.Removed)
+	}
+	if Compare(current, current).Empty() != true {
+		t.Fatal("comparing a bundle with itself should be empty")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	classes := []string{"auth", "api"}
+	valid := Bundle{FormatVersion: FormatVersion, Tenant: "t1", RateLimits: []models.RateLimitPolicy{
+		{Tenant: "t1", RouteClass: "auth", Requests: 5, WindowSeconds: 60},
+	}}
+	if err := Validate(valid, classes); err != nil {
+		t.Fatalf("valid bundle rejected: %v", err)
+	}
+
+	invalid := map[string]Bundle{
+		"format":       {FormatVersion: 99},
+		"tenant":       {FormatVersion: FormatVersion, Tenant: "t1", RateLimits: []models.RateLimitPolicy{{Tenant: "t2", RouteClass: "auth", Requests: 1, WindowSeconds: 1}}},
+		"route class":  {FormatVersion: FormatVersion, RateLimits: []models.RateLimitPolicy{{RouteClass: "nope", Requests: 1, WindowSeconds: 1}}},
+		"duplicate":    {FormatVersion: FormatVersion, RateLimits: []models.RateLimitPolicy{{RouteClass: "auth", Requests: 1, WindowSeconds: 1}, {RouteClass: "auth", Requests: 2, WindowSeconds: 1}}},
+		"non-positive": {FormatVersion: FormatVersion, RateLimits: []models.RateLimitPolicy{{RouteClass: "api", Requests: 0, WindowSeconds: 1}}},
+	}
+	for name, b := range invalid {
+		if err := Validate(b, classes); err == nil {
+			t.Errorf("%s: expected validation error", name)
+		}
+	}
+}
+
+// FuzzValidate decodes arbitrary JSON as a bundle. Any bundle that validates
+// must survive a JSON round trip unchanged and compare equal to itself.
+func FuzzValidate(f *testing.F) {
+	f.Add(`{"format_version":1,"rate_limits":[{"route_class":"auth","requests":10,"window_seconds":60}]}`)
+	f.Add(`{"format_version":1,"rate_limits":[{"route_class":"auth","requests":10,"window_seconds":60},{"route_class":"auth","requests":1,"window_seconds":1}]}`)
+	f.Add(`{"format_version":2,"rate_limits":[{"route_class":"nope","requests":-1}]}`)
+	f.Add(`[]`)
+	f.Fuzz(func(t *testing.T, data string) {
+		var b Bundle
+		if err := json.Unmarshal([]byte(data), &b); err != nil {
+			return
+		}
+		if err := Validate(b, models.RouteClasses); err != nil {
+			return
+		}
+		if !Compare(b, b).Empty() {
+			t.Fatalf("valid bundle differs from itself: %s", data)
+		}
+		encoded, err := json.Marshal(b)
+		if err != nil {
+			t.Fatalf("marshal valid bundle: %v", err)
+		}
+		var again Bundle
+		if err := json.Unmarshal(encoded, &again); err != nil {
+			t.Fatalf("unmarshal round trip: %v", err)
+		}
+		if err := Validate(again, models.RouteClasses); err != nil {
+			t.Fatalf("round-tripped bundle no longer valid: %v", err)
+		}
+		if !Compare(b, again).Empty() {
+			t.Fatalf("round trip changed bundle: %s -> %s", data, encoded)
+		}
+	})
+}
@@ -0,0 +1,152 @@
+// Package confighistory applies admin configuration changes together with a
+// versioned before/after record, and rolls individual changes back.
+//
+// Every function takes transaction-scoped repositories; callers wrap them in
+// storage.UnitOfWork.WithTx so the change and its history entry commit together.
+package confighistory
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/hongminglow/all-in-be/internal/geoip"
+	"github.com/hongminglow/all-in-be/internal/models"
+	"github.com/hongminglow/all-in-be/internal/storage"
+)
+
+// ErrUnsupportedEntity is returned when rolling back an entity type this package cannot restore.
+var ErrUnsupportedEntity = errors.New("config entity cannot be rolled back")
+
+// SaveRateLimit creates or replaces a rate-limit policy and records the change.
+func SaveRateLimit(ctx context.Context, tx storage.Repositories, actorID int64, policy models.RateLimitPolicy) (models.RateLimitPolicy, error) {
+	saved, _, err := saveRateLimit(ctx, tx, actorID, policy, nil)
+	return saved, err
+}
+
+// DeleteRateLimit removes a rate-limit policy and records the change.
+func DeleteRateLimit(ctx context.Context, tx storage.Repositories, actorID int64, tenant, routeClass string) error {
+	_, err := deleteRateLimit(ctx, tx, actorID, tenant, routeClass, nil)
+	return err
+}
+
+// Rollback restores the entity touched by change id to its state before that
+// change, returning the new history entry that records the restoration.
+func Rollback(ctx context.Context, tx storage.Repositories, actorID, id int64) (models.ConfigChange, error) {
+	change, err := tx.FindConfigChange(ctx, id)
+	if err != nil {
+		return models.ConfigChange{}, err
+	}
+	switch change.Entity {
+	case models.ConfigEntityRateLimit:
+		var target models.RateLimitPolicy
+		source := change.After
+		if len(change.Before) > 0 {
+			source = change.Before
+		}
+		if err := json.Unmarshal(source, &target); err != nil {
+			return models.ConfigChange{}, fmt.Errorf("decode snapshot: %w", err)
+		}
+		if len(change.Before) == 0 {
+			return deleteRateLimit(ctx, tx, actorID, target.Tenant, target.RouteClass, &change.ID)
+		}
+		_, restored, err := saveRateLimit(ctx, tx, actorID, target, &change.ID)
+		return restored, err
+	case models.ConfigEntityOnboarding:
+		var target models.OnboardingJourney
+		source := change.After
+		if len(change.Before) > 0 {
+			source = change.Before
+		}
+		if err := json.Unmarshal(source, &target); err != nil {
+			return models.ConfigChange{}, fmt.Errorf("decode snapshot: %w", err)
+		}
+		if len(change.Before) == 0 {
+			return deleteJourney(ctx, tx, actorID, target.Tenant, &change.ID)
+		}
+		_, restored, err := saveJourney(ctx, tx, actorID, target, &change.ID)
+		return restored, err
+	case models.ConfigEntityIPRisk:
+		var target models.IPRiskPolicy
+		source := change.After
+		if len(change.Before) > 0 {
+			source = change.Before
+		}
+		if err := json.Unmarshal(source, &target); err != nil {
+			return models.ConfigChange{}, fmt.Errorf("decode snapshot: %w", err)
+		}
+		if len(change.Before) == 0 {
+			return deleteIPRisk(ctx, tx, actorID, target.Tenant, target.Country, &change.ID)
+		}
+		_, restored, err := saveIPRisk(ctx, tx, actorID, target, &change.ID)
+		return restored, err
+	default:
+		return models.ConfigChange{}, fmt.Errorf("%w: %s", ErrUnsupportedEntity, change.Entity)
+	}
+}
+
+func saveRateLimit(ctx context.Context, tx storage.Repositories, actorID int64, policy models.RateLimitPolicy, rollbackOf *int64) (models.RateLimitPolicy, models.ConfigChange, error) {
+	before, err := currentRateLimit(ctx, tx, policy.Tenant, policy.RouteClass)
+	if err != nil {
+		return models.RateLimitPolicy{}, models.ConfigChange{}, err
+	}
+	saved, err := tx.UpsertRateLimitPolicy(ctx, policy)
+	if err != nil {
+		return models.RateLimitPolicy{}, models.ConfigChange{}, err
+	}
+	change, err := record(ctx, tx, actorID, models.ConfigEntityRateLimit, rateLimitKey(policy.Tenant, policy.RouteClass), before, &saved, rollbackOf)
+	if err != nil {
+		return models.RateLimitPolicy{}, models.ConfigChange{}, err
+	}
+	return saved, change, nil
+}
+
+func deleteRateLimit(ctx context.Context, tx storage.Repositories, actorID int64, tenant, routeClass string, rollbackOf *int64) (models.ConfigChange, error) {
+	before, err := currentRateLimit(ctx, tx, tenant, routeClass)
+	if err != nil {
+		return models.ConfigChange{}, err
+	}
+	if before == nil {
+		return models.ConfigChange{}, storage.ErrNotFound
+	}
+	if err := tx.DeleteRateLimitPolicy(ctx, tenant, routeClass); err != nil {
+		return models.ConfigChange{}, err
+	}
+	return record[models.RateLimitPolicy](ctx, tx, actorID, models.ConfigEntityRateLimit, rateLimitKey(tenant, routeClass), before, nil, rollbackOf)
+}
+
+func currentRateLimit(ctx context.Context, tx storage.Repositories, tenant, routeClass string) (*models.RateLimitPolicy, error) {
+	policy, err := tx.FindRateLimitPolicy(ctx, tenant, routeClass)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &policy, nil
+}
+
+func record[T any](ctx context.Context, tx storage.Repositories, actorID int64, entity, key string, before, after *T, rollbackOf *int64) (models.ConfigChange, error) {
+	change := models.ConfigChange{Entity: entity, EntityKey: key, ChangedBy: actorID, Country: geoip.CountryFromContext(ctx), RollbackOf: rollbackOf}
+	var err error
+	if before != nil {
+		if change.Before, err = json.Marshal(before); err != nil {
+			return models.ConfigChange{}, err
+		}
+	}
+	if after != nil {
+		if change.After, err = json.Marshal(after); err != nil {
+			return models.ConfigChange{}, err
+		}
+	}
+	recorded, err := tx.RecordConfigChange(ctx, change)
+	if err != nil {
+		return models.ConfigChange{}, fmt.Errorf("record config change: %w", err)
+	}
+	return recorded, nil
+}
+
+func rateLimitKey(tenant, routeClass string) string {
+	return tenant + "/" + routeClass
+}
@@ -0,0 +1,66 @@
+package confighistory
+
+import (
+	"context"
+	"errors"
+
+	"github.com/hongminglow/all-in-be/internal/models"
+	"github.com/hongminglow/all-in-be/internal/storage"
+)
+
+// SaveIPRiskPolicy creates or replaces an IP risk policy and records the change.
+func SaveIPRiskPolicy(ctx context.Context, tx storage.Repositories, actorID int64, policy models.IPRiskPolicy) (models.IPRiskPolicy, error) {
+	saved, _, err := saveIPRisk(ctx, tx, actorID, policy, nil)
+	return saved, err
+}
+
+// DeleteIPRiskPolicy removes an IP risk policy and records the change.
+func DeleteIPRiskPolicy(ctx context.Context, tx storage.Repositories, actorID int64, tenant, country string) error {
+	_, err := deleteIPRisk(ctx, tx, actorID, tenant, country, nil)
+	return err
+}
+
+func saveIPRisk(ctx context.Context, tx storage.Repositories, actorID int64, policy models.IPRiskPolicy, rollbackOf *int64) (models.IPRiskPolicy, models.ConfigChange, error) {
+	before, err := currentIPRisk(ctx, tx, policy.Tenant, policy.Country)
+	if err != nil {
+		return models.IPRiskPolicy{}, models.ConfigChange{}, err
+	}
+	saved, err := tx.UpsertIPRiskPolicy(ctx, policy)
+	if err != nil {
+		return models.IPRiskPolicy{}, models.ConfigChange{}, err
+	}
+	change, err := record(ctx, tx, actorID, models.ConfigEntityIPRisk, ipRiskKey(policy.Tenant, policy.Country), before, &saved, rollbackOf)
+	if err != nil {
+		return models.IPRiskPolicy{}, models.ConfigChange{}, err
+	}
+	return saved, change, nil
+}
+
+func deleteIPRisk(ctx context.Context, tx storage.Repositories, actorID int64, tenant, country string, rollbackOf *int64) (models.ConfigChange, error) {
+	before, err := currentIPRisk(ctx, tx, tenant, country)
+	if err != nil {
+		return models.ConfigChange{}, err
+	}
+	if before == nil {
+		return models.ConfigChange{}, storage.ErrNotFound
+	}
+	if err := tx.DeleteIPRiskPolicy(ctx, tenant, country); err != nil {
+		return models.ConfigChange{}, err
+	}
+	return record[models.IPRiskPolicy](ctx, tx, actorID, models.ConfigEntityIPRisk, ipRiskKey(tenant, country), before, nil, rollbackOf)
+}
+
+func currentIPRisk(ctx context.Context, tx storage.Repositories, tenant, country string) (*models.IPRiskPolicy, error) {
+	policy, err := tx.FindIPRiskPolicy(ctx, tenant, country)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &policy, nil
+}
+
+func ipRiskKey(tenant, country string) string {
+	return tenant + "/" + country
+}
@@ -0,0 +1,62 @@
+package confighistory
+
+import (
+	"context"
+	"errors"
+
+	"github.com/hongminglow/all-in-be/internal/models"
+	"github.com/hongminglow/all-in-be/internal/storage"
+)
+
+// SaveOnboardingJourney creates or replaces a tenant's journey and records the change.
+func SaveOnboardingJourney(ctx context.Context, tx storage.Repositories, actorID int64, journey models.OnboardingJourney) (models.OnboardingJourney, error) {
+	saved, _, err := saveJourney(ctx, tx, actorID, journey, nil)
+	return saved, err
+}
+
+// DeleteOnboardingJourney removes a tenant's journey and records the change.
+func DeleteOnboardingJourney(ctx context.Context, tx storage.Repositories, actorID int64, tenant string) error {
+	_, err := deleteJourney(ctx, tx, actorID, tenant, nil)
+	return err
+}
+
+func saveJourney(ctx context.Context, tx storage.Repositories, actorID int64, journey models.OnboardingJourney, rollbackOf *int64) (models.OnboardingJourney, models.ConfigChange, error) {
+	before, err := currentJourney(ctx, tx, journey.Tenant)
+	if err != nil {
+		return models.OnboardingJourney{}, models.ConfigChange{}, err
+	}
+	saved, err := tx.SaveOnboardingJourney(ctx, journey)
+	if err != nil {
+		return models.OnboardingJourney{}, models.ConfigChange{}, err
+	}
+	change, err := record(ctx, tx, actorID, models.ConfigEntityOnboarding, journey.Tenant, before, &saved, rollbackOf)
+	if err != nil {
+		return models.OnboardingJourney{}, models.ConfigChange{}, err
+	}
+	return saved, change, nil
+}
+
+func deleteJourney(ctx context.Context, tx storage.Repositories, actorID int64, tenant string, rollbackOf *int64) (models.ConfigChange, error) {
+	before, err := currentJourney(ctx, tx, tenant)
+	if err != nil {
+		return models.ConfigChange{}, err
+	}
+	if before == nil {
+		return models.ConfigChange{}, storage.ErrNotFound
+	}
+	if err := tx.DeleteOnboardingJourney(ctx, tenant); err != nil {
+		return models.ConfigChange{}, err
+	}
+	return record[models.OnboardingJourney](ctx, tx, actorID, models.ConfigEntityOnboarding, tenant, before, nil, rollbackOf)
+}
+
+func currentJourney(ctx context.Context, tx storage.Repositories, tenant string) (*models.OnboardingJourney, error) {
+	journey, err := tx.FindOnboardingJourney(ctx, tenant)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &journey, nil
+}
@@ -0,0 +1,125 @@
+package confighistory
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/hongminglow/all-in-be/internal/models"
+	"github.com/hongminglow/all-in-be/internal/storage"
+)
+
+// Role and permission changes are recorded for audit but cannot be rolled back:
+// a snapshot does not capture which users held a role or which roles held a
+// permission at the time.
+
+// CreateRole stores a role, grants it permissionIDs, and records the change.
+func CreateRole(ctx context.Context, tx storage.Repositories, actorID int64, role models.Role, permissionIDs []int64) (models.Role, error) {
+	created, err := tx.CreateRole(ctx, role)
+	if err != nil {
+		return models.Role{}, err
+	}
+	for _, id := range permissionIDs {
+		if err := tx.GrantPermission(ctx, created.ID, id); err != nil {
+			return models.Role{}, err
+		}
+	}
+	if created, err = tx.FindRole(ctx, created.ID); err != nil {
+		return models.Role{}, err
+	}
+	_, err = record[models.Role](ctx, tx, actorID, models.ConfigEntityRole, idKey(created.ID), nil, &created, nil)
+	return created, err
+}
+
+// UpdateRole saves a role's name and description and records the change.
+func UpdateRole(ctx context.Context, tx storage.Repositories, actorID int64, role models.Role) (models.Role, error) {
+	before, err := tx.FindRole(ctx, role.ID)
+	if err != nil {
+		return models.Role{}, err
+	}
+	updated, err := tx.UpdateRole(ctx, role)
+	if err != nil {
+		return models.Role{}, err
+	}
+	_, err = record(ctx, tx, actorID, models.ConfigEntityRole, idKey(role.ID), &before, &updated, nil)
+	return updated, err
+}
+
+// DeleteRole removes a role and records the change.
+func DeleteRole(ctx context.Context, tx storage.Repositories, actorID, id int64) error {
+	before, err := tx.FindRole(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := tx.DeleteRole(ctx, id); err != nil {
+		return err
+	}
+	_, err = record[models.Role](ctx, tx, actorID, models.ConfigEntityRole, idKey(id), &before, nil, nil)
+	return err
+}
+
+// SetRolePermission grants or revokes one permission and records the role's
+// permissions before and after.
+func SetRolePermission(ctx context.Context, tx storage.Repositories, actorID, roleID, permissionID int64, granted bool) (models.Role, error) {
+	before, err := tx.FindRole(ctx, roleID)
+	if err != nil {
+		return models.Role{}, err
+	}
+	if _, err := tx.FindPermission(ctx, permissionID); err != nil {
+		return models.Role{}, err
+	}
+	if granted {
+		err = tx.GrantPermission(ctx, roleID, permissionID)
+	} else {
+		err = tx.RevokePermission(ctx, roleID, permissionID)
+	}
+	if err != nil {
+		return models.Role{}, err
+	}
+	after, err := tx.FindRole(ctx, roleID)
+	if err != nil {
+		return models.Role{}, err
+	}
+	_, err = record(ctx, tx, actorID, models.ConfigEntityRole, idKey(roleID), &before, &after, nil)
+	return after, err
+}
+
+// CreatePermission stores a permission and records the change.
+func CreatePermission(ctx context.Context, tx storage.Repositories, actorID int64, p models.Permission) (models.Permission, error) {
+	created, err := tx.CreatePermission(ctx, p)
+	if err != nil {
+		return models.Permission{}, err
+	}
+	_, err = record[models.Permission](ctx, tx, actorID, models.ConfigEntityPermission, idKey(created.ID), nil, &created, nil)
+	return created, err
+}
+
+// UpdatePermission saves a permission's name and description and records the change.
+func UpdatePermission(ctx context.Context, tx storage.Repositories, actorID int64, p models.Permission) (models.Permission, error) {
+	before, err := tx.FindPermission(ctx, p.ID)
+	if err != nil {
+		return models.Permission{}, err
+	}
+	updated, err := tx.UpdatePermission(ctx, p)
+	if err != nil {
+		return models.Permission{}, err
+	}
+	_, err = record(ctx, tx, actorID, models.ConfigEntityPermission, idKey(p.ID), &before, &updated, nil)
+	return updated, err
+}
+
+// DeletePermission removes a permission from every role and records the change.
+func DeletePermission(ctx context.Context, tx storage.Repositories, actorID, id int64) error {
+	before, err := tx.FindPermission(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := tx.DeletePermission(ctx, id); err != nil {
+		return err
+	}
+	_, err = record[models.Permission](ctx, tx, actorID, models.ConfigEntityPermission, idKey(id), &before, nil, nil)
+	return err
+}
+
+func idKey(id int64) string {
+	return strconv.FormatInt(id, 10)
+}
@@ -0,0 +1,215 @@
+// Package dataexport compiles a user's personal data into a ZIP archive of
+// JSON files on the job queue, for self-serve data subject access requests.
+// Archives are kept in the blob store and handed out through signed links.
+package dataexport
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/hongminglow/all-in-be/internal/blob"
+	"github.com/hongminglow/all-in-be/internal/clock"
+	"github.com/hongminglow/all-in-be/internal/jobs"
+	"github.com/hongminglow/all-in-be/internal/models"
+	"github.com/hongminglow/all-in-be/internal/storage"
+)
+
+// MaxAttempts is how many times building an archive is tried before the
+// export is marked failed.
+const MaxAttempts = 3
+
+// maxLoginRows bounds the login history copied into an archive.
+const maxLoginRows = 10000
+
+// Store is the data an export reads and the record it keeps.
+type Store interface {
+	storage.UserStore
+	storage.WalletStore
+	storage.SecurityStore
+	storage.ConfigHistoryStore
+	storage.SpectatorStore
+	storage.OnboardingStore
+	storage.DataExportStore
+}
+
+// Service starts exports and serves their status.
+type Service struct {
+	store   Store
+	blobs   blob.Store
+	queue   *jobs.Queue
+	clock   clock.Clock
+	linkTTL time.Duration
+}
+
+// NewService constructs the service. Download links stay valid for linkTTL.
+func NewService(store Store, blobs blob.Store, queue *jobs.Queue, clk clock.Clock, linkTTL time.Duration) *Service {
+	return &Service{store: store, blobs: blobs, queue: queue, clock: clk, linkTTL: linkTTL}
+}
+
+// Request starts an export of the user's data, or returns the one already in
+// progress so repeated clicks don't queue duplicate work.
+func (s *Service) Request(ctx context.Context, userID int64) (models.DataExport, error) {
+	exports, err := s.store.ListDataExports(ctx, userID)
+	if err != nil {
+		return models.DataExport{}, fmt.Errorf("list data exports: %w", err)
+	}
+	for _, e := range exports {
+		if e.Status == models.ExportPending {
+			return e, nil
+		}
+	}
+	export, err := s.store.CreateDataExport(ctx, models.DataExport{UserID: userID, Status: models.ExportPending})
+	if err != nil {
+		return models.DataExport{}, fmt.Errorf("create data export: %w", err)
+	}
+	job := jobs.Job{
+		Type:        "data_export",
+		Name:        fmt.Sprintf("data export %d for user %d", export.ID, userID),
+		Priority:    jobs.PriorityLow,
+		MaxAttempts: MaxAttempts,
+		Run: func(ctx context.Context, attempt int) error {
+			err := s.build(ctx, export)
+			if err != nil && attempt >= MaxAttempts {
+				s.fail(export)
+			}
+			return err
+		},
+	}
+	if err := s.queue.Enqueue(job); err != nil {
+		s.fail(export)
+		return models.DataExport{}, fmt.Errorf("queue data export: %w", err)
+	}
+	return export, nil
+}
+
+// Find returns one of the user's exports, with a download link once it is
+// ready. Other users' exports are reported as storage.ErrNotFound.
+func (s *Service) Find(ctx context.Context, userID, id int64) (models.DataExport, error) {
+	export, err := s.store.FindDataExport(ctx, id)
+	if err != nil {
+		return models.DataExport{}, err
+	}
+	if export.UserID != userID {
+		return models.DataExport{}, storage.ErrNotFound
+	}
+	return s.withLink(ctx, export)
+}
+
+// List returns the user's exports, newest first, with links to the ready ones.
+func (s *Service) List(ctx context.Context, userID int64) ([]models.DataExport, error) {
+	exports, err := s.store.ListDataExports(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range exports {
+		if exports[i], err = s.withLink(ctx, exports[i]); err != nil {
+			return nil, err
+		}
+	}
+	return exports, nil
+}
+
+func (s *Service) withLink(ctx context.Context, export models.DataExport) (models.DataExport, error) {
+	if export.Status != models.ExportReady {
+		return export, nil
+	}
+	url, err := s.blobs.SignedURL(ctx, export.BlobKey, s.linkTTL)
+	if err != nil {
+		return models.DataExport{}, fmt.Errorf("sign data export link: %w", err)
+	}
+	export.DownloadURL = url
+	return export, nil
+}
+
+// build compiles the archive, stores it and marks the export ready.
+func (s *Service) build(ctx context.Context, export models.DataExport) error {
+	archive, err := s.compile(ctx, export.UserID)
+	if err != nil {
+		return err
+	}
+	key := fmt.Sprintf("exports/%d/%d.zip", export.UserID, export.ID)
+	if err := s.blobs.Put(ctx, key, bytes.NewReader(archive), "application/zip"); err != nil {
+		return fmt.Errorf("store data export: %w", err)
+	}
+	err = s.store.FinishDataExport(ctx, export.ID, models.ExportReady, key, s.clock.Now())
+	if errors.Is(err, storage.ErrNotFound) {
+		// An earlier attempt already finished it.
+		return nil
+	}
+	return err
+}
+
+// compile gathers the user's data into a ZIP with one JSON file per section.
+func (s *Service) compile(ctx context.Context, userID int64) ([]byte, error) {
+	user, err := s.store.FindByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("find user: %w", err)
+	}
+	privacy, err := s.store.FindPrivacySettings(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("find privacy settings: %w", err)
+	}
+	transactions, err := s.store.ListTransactions(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("list transactions: %w", err)
+	}
+	logins, err := s.store.ListLoginAttempts(ctx, models.LoginAttemptFilter{UserID: userID, IncludeArchived: true}, maxLoginRows)
+	if err != nil {
+		return nil, fmt.Errorf("list login history: %w", err)
+	}
+	devices, err := s.store.ListLoginDevices(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("list login devices: %w", err)
+	}
+	changes, err := s.store.ListConfigChangesBy(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("list config changes: %w", err)
+	}
+	onboarding, err := s.store.ListOnboardingProgress(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("list onboarding progress: %w", err)
+	}
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, file := range []struct {
+		name string
+		data any
+	}{
+		{"profile.json", user},
+		{"privacy.json", privacy},
+		{"transactions.json", transactions},
+		{"login_history.json", logins},
+		{"devices.json", devices},
+		{"audit_trail.json", changes},
+		{"onboarding.json", onboarding},
+	} {
+		w, err := zw.CreateHeader(&zip.FileHeader{Name: file.name, Method: zip.Deflate, Modified: s.clock.Now()})
+		if err != nil {
+			return nil, err
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(file.data); err != nil {
+			return nil, fmt.Errorf("encode %s: %w", file.name, err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// fail marks the export failed outside the request or job context, which may
+// already be cancelled.
+func (s *Service) fail(export models.DataExport) {
+	if err := s.store.FinishDataExport(context.Background(), export.ID, models.ExportFailed, "", s.clock.Now()); err != nil && !errors.Is(err, storage.ErrNotFound) {
+		log.Printf("mark data export %d failed: %v", export.ID, err)
+	}
+}
@@ -0,0 +1,277 @@
+// Package dbinsights is an index advisor. On a schedule it reads the
+// database's own workload statistics, pg_stat_statements when the extension
+// is installed and the per-table scan counters always, and records a report
+// of the slow statements, the large tables read mostly by sequential scans,
+// and the indexes that would serve the filters of those statements. Nothing
+// is created automatically; the report carries the statements to review.
+package dbinsights
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"log"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/hongminglow/all-in-be/internal/config"
+	"github.com/hongminglow/all-in-be/internal/models"
+	"github.com/hongminglow/all-in-be/internal/storage"
+)
+
+const (
+	// sampledStatements is how many statements, by total time, are analyzed.
+	sampledStatements = 200
+	// maxSlowQueries bounds the slow statements kept in a report.
+	maxSlowQueries = 20
+	// maxIndexColumns bounds the columns of a suggested index.
+	maxIndexColumns = 3
+)
+
+// Service runs the advisor on a schedule and on demand.
+type Service struct {
+	store storage.DatabaseInsightsStore
+	cfg   config.DBInsightsConfig
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewService builds an advisor; call Start to run it on cfg.Interval.
+func NewService(store storage.DatabaseInsightsStore, cfg config.DBInsightsConfig) *Service {
+	return &Service{store: store, cfg: cfg}
+}
+
+// Run captures the workload statistics, analyzes them and stores the report.
+func (s *Service) Run(ctx context.Context) (models.DatabaseInsightsReport, error) {
+	stats, available, err := s.store.QueryStats(ctx, sampledStatements)
+	if err != nil {
+		return models.DatabaseInsightsReport{}, err
+	}
+	tables, err := s.store.TableScanStats(ctx)
+	if err != nil {
+		return models.DatabaseInsightsReport{}, err
+	}
+	report := analyze(stats, tables, s.cfg)
+	report.StatementsAvailable = available
+	report, err = s.store.CreateDatabaseInsightsReport(ctx, report)
+	if err != nil {
+		return models.DatabaseInsightsReport{}, fmt.Errorf("save database insights report: %w", err)
+	}
+	return report, nil
+}
+
+// Start runs the advisor every cfg.Interval until Close. It does nothing when
+// no interval is configured.
+func (s *Service) Start() {
+	if s.cfg.Interval <= 0 || s.stop != nil {
+		return
+	}
+	s.stop, s.done = make(chan struct{}), make(chan struct{})
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-s.stop
+		cancel()
+	}()
+	go func() {
+		defer close(s.done)
+		ticker := time.NewTicker(s.cfg.Interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				report, err := s.Run(ctx)
+				if err != nil {
+					if ctx.Err() == nil {
+						log.Printf("db insights: %v", err)
+					}
+					continue
+				}
+				if len(report.Suggestions) > 0 {
+					log.Printf("db insights: %d suggested indexes (report %d)", len(report.Suggestions), report.ID)
+				}
+			}
+		}
+	}()
+}
+
+// Close stops the schedule, cancelling a run in progress, and waits for it to end.
+func (s *Service) Close() {
+	if s.stop == nil {
+		return
+	}
+	close(s.stop)
+	<-s.done
+	s.stop = nil
+}
+
+// analyze picks the slow statements and the sequential-scan-heavy tables, and
+// suggests an index for each filter that slow statements, or any statement on
+// such a table, run without an index starting with its first column. Tables
+// below cfg.MinRows get no suggestions: scanning them is cheap.
+func analyze(stats []models.QueryStat, tables []models.TableScanStat, cfg config.DBInsightsConfig) models.DatabaseInsightsReport {
+	report := models.DatabaseInsightsReport{
+		SlowQueries:   []models.QueryStat{},
+		SeqScanTables: []models.TableScanStat{},
+		Suggestions:   []models.IndexSuggestion{},
+	}
+	slowMillis := float64(cfg.SlowQuery) / float64(time.Millisecond)
+	for _, q := range stats {
+		if q.MeanMillis >= slowMillis && len(report.SlowQueries) < maxSlowQueries {
+			report.SlowQueries = append(report.SlowQueries, q)
+		}
+	}
+
+	byName := map[string]models.TableScanStat{}
+	heavy := map[string]bool{}
+	for _, t := range tables {
+		byName[strings.ToLower(t.Table)] = t
+		if t.LiveRows >= cfg.MinRows && t.SeqScans > t.IndexScans {
+			heavy[t.Table] = true
+			report.SeqScanTables = append(report.SeqScanTables, t)
+		}
+	}
+	slices.SortStableFunc(report.SeqScanTables, func(a, b models.TableScanStat) int {
+		return cmp.Compare(b.SeqRowsRead, a.SeqRowsRead)
+	})
+
+	suggestions := map[string]*models.IndexSuggestion{}
+	for _, q := range stats {
+		slow := q.MeanMillis >= slowMillis
+		for _, f := range filters(q.Query, byName) {
+			t := byName[strings.ToLower(f.table)]
+			if t.LiveRows < cfg.MinRows || !(slow || heavy[t.Table]) || slices.Contains(t.IndexedColumns, f.columns[0]) {
+				continue
+			}
+			key := t.Table + "(" + strings.Join(f.columns, ",") + ")"
+			s, ok := suggestions[key]
+			if !ok {
+				s = &models.IndexSuggestion{
+					Table:   t.Table,
+					Columns: f.columns,
+					Statement: fmt.Sprintf("CREATE INDEX CONCURRENTLY IF NOT EXISTS %s_%s_idx ON %s (%s);",
+						t.Table, strings.Join(f.columns, "_"), t.Table, strings.Join(f.columns, ", ")),
+				}
+				suggestions[key] = s
+			}
+			s.QueryIDs = append(s.QueryIDs, q.QueryID)
+			s.Calls += q.Calls
+		}
+	}
+	for _, s := range suggestions {
+		report.Suggestions = append(report.Suggestions, *s)
+	}
+	slices.SortFunc(report.Suggestions, func(a, b models.IndexSuggestion) int {
+		return cmp.Or(cmp.Compare(b.Calls, a.Calls), cmp.Compare(a.Statement, b.Statement))
+	})
+	return report
+}
+
+var (
+	tableRef   = regexp.MustCompile(`(?i)\b(?:FROM|JOIN|UPDATE|INTO)\s+(?:ONLY\s+)?"?([a-z_][a-z0-9_]*)"?(?:\s+(?:AS\s+)?([a-z_][a-z0-9_]*))?`)
+	whereStart = regexp.MustCompile(`(?i)\bWHERE\b`)
+	whereEnd   = regexp.MustCompile(`(?i)\b(?:GROUP\s+BY|ORDER\s+BY|LIMIT|OFFSET|RETURNING|HAVING|UNION|FOR\s+UPDATE|FOR\s+SHARE)\b|;`)
+	predicate  = regexp.MustCompile(`(?i)(?:\b([a-z_][a-z0-9_]*)\.)?"?\b([a-z_][a-z0-9_]*)"?\s*(=|<=|>=|<|>|\bIN\b|\bIS\b|\bLIKE\b|\bBETWEEN\b)`)
+)
+
+// notAliases are keywords that may follow a table name in place of an alias.
+var notAliases = map[string]bool{
+	"where": true, "join": true, "left": true, "right": true, "inner": true, "outer": true, "full": true,
+	"cross": true, "natural": true, "on": true, "using": true, "set": true, "values": true, "select": true,
+	"group": true, "order": true, "limit": true, "offset": true, "returning": true, "for": true,
+	"having": true, "union": true, "window": true, "default": true, "lateral": true,
+}
+
+// filter is the columns of one table a statement filters on, equality
+// conditions first as a composite index wants them.
+type filter struct {
+	table   string
+	columns []string
+}
+
+// filters finds the columns of known tables the statement's WHERE clauses
+// compare. Qualified columns are matched through table aliases; unqualified
+// ones go to each referenced table that has the column. It is a heuristic
+// over normalized statement text, not a SQL parser.
+func filters(query string, tables map[string]models.TableScanStat) []filter {
+	aliases := map[string]string{}
+	var referenced []string
+	for _, m := range tableRef.FindAllStringSubmatch(query, -1) {
+		name := strings.ToLower(m[1])
+		if _, ok := tables[name]; !ok {
+			continue
+		}
+		if !slices.Contains(referenced, name) {
+			referenced = append(referenced, name)
+		}
+		aliases[name] = name
+		if alias := strings.ToLower(m[2]); alias != "" && !notAliases[alias] {
+			aliases[alias] = name
+		}
+	}
+	if len(referenced) == 0 {
+		return nil
+	}
+
+	type column struct {
+		name     string
+		equality bool
+	}
+	found := map[string][]column{}
+	add := func(table, name string, equality bool) {
+		if !slices.ContainsFunc(tables[table].Columns, func(c string) bool { return strings.EqualFold(c, name) }) {
+			return
+		}
+		if slices.ContainsFunc(found[table], func(c column) bool { return c.name == name }) {
+			return
+		}
+		found[table] = append(found[table], column{name: name, equality: equality})
+	}
+	for _, loc := range whereStart.FindAllStringIndex(query, -1) {
+		clause := query[loc[1]:]
+		if end := whereEnd.FindStringIndex(clause); end != nil {
+			clause = clause[:end[0]]
+		}
+		for _, m := range predicate.FindAllStringSubmatch(clause, -1) {
+			qualifier, name, op := strings.ToLower(m[1]), strings.ToLower(m[2]), strings.ToUpper(m[3])
+			equality := op == "=" || op == "IN" || op == "IS"
+			if qualifier != "" {
+				if table, ok := aliases[qualifier]; ok {
+					add(table, name, equality)
+				}
+				continue
+			}
+			for _, table := range referenced {
+				add(table, name, equality)
+			}
+		}
+	}
+
+	var out []filter
+	for _, table := range referenced {
+		cols := found[table]
+		if len(cols) == 0 {
+			continue
+		}
+		slices.SortStableFunc(cols, func(a, b column) int {
+			switch {
+			case a.equality == b.equality:
+				return 0
+			case a.equality:
+				return -1
+			default:
+				return 1
+			}
+		})
+		f := filter{table: tables[table].Table}
+		for _, c := range cols[:min(len(cols), maxIndexColumns)] {
+			f.columns = append(f.columns, c.name)
+		}
+		out = append(out, f)
+	}
+	return out
+}
@@ -0,0 +1,70 @@
+package dbinsights
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/hongminglow/all-in-be/internal/config"
+	"github.com/hongminglow/all-in-be/internal/models"
+	"github.com/hongminglow/all-in-be/internal/storage/storagetest"
+)
+
+func TestAnalyze(t *testing.T) {
+	cfg := config.DBInsightsConfig{SlowQuery: 100 * time.Millisecond, MinRows: 10_000}
+	tables := []models.TableScanStat{
+		{Table: "wallet_transactions", SeqScans: 900, SeqRowsRead: 9_000_000, IndexScans: 100, LiveRows: 500_000,
+			Columns: []string{"id", "user_id", "amount", "reason", "created_at"}, IndexedColumns: []string{"id"}},
+		{Table: "users", SeqScans: 10, SeqRowsRead: 20_000, IndexScans: 90_000, LiveRows: 50_000,
+			Columns: []string{"id", "username", "email", "role", "created_at"}, IndexedColumns: []string{"id", "username", "email"}},
+		{Table: "roles", SeqScans: 5_000, SeqRowsRead: 25_000, LiveRows: 5,
+			Columns: []string{"id", "role_name"}, IndexedColumns: []string{"id"}},
+	}
+	stats := []models.QueryStat{
+		{QueryID: 1, Calls: 4_000, MeanMillis: 3, Query: `SELECT id, amount FROM wallet_transactions WHERE created_at >= $1 AND user_id = $2 ORDER BY id DESC LIMIT $3`},
+		{QueryID: 2, Calls: 50, MeanMillis: 450, Query: `SELECT u.id, u.username FROM users u JOIN wallet_transactions t ON t.user_id = u.id WHERE u.role = $1 AND t.reason = $2`},
+		{QueryID: 3, Calls: 20_000, MeanMillis: 1, Query: `SELECT id FROM users WHERE email = $1`},
+		{QueryID: 4, Calls: 9_000, MeanMillis: 2, Query: `SELECT id FROM roles WHERE role_name = $1`},
+		{QueryID: 5, Calls: 10, MeanMillis: 2, Query: `UPDATE users SET role = $1 WHERE created_at < $2`},
+	}
+
+	report := analyze(stats, tables, cfg)
+	if len(report.SlowQueries) != 1 || report.SlowQueries[0].QueryID != 2 {
+		t.Fatalf("slow queries = %+v", report.SlowQueries)
+	}
+	if len(report.SeqScanTables) != 1 || report.SeqScanTables[0].Table != "wallet_transactions" {
+		t.Fatalf("sequential-scan tables = %+v, want wallet_transactions only (roles is too small)", report.SeqScanTables)
+	}
+	var got []string
+	for _, s := range report.Suggestions {
+		got = append(got, s.Statement)
+	}
+	want := []string{
+		"CREATE INDEX CONCURRENTLY IF NOT EXISTS wallet_transactions_user_id_created_at_idx ON wallet_transactions (user_id, created_at);",
+		"CREATE INDEX CONCURRENTLY IF NOT EXISTS users_role_idx ON users (role);",
+		"CREATE INDEX CONCURRENTLY IF NOT EXISTS wallet_transactions_reason_idx ON wallet_transactions (reason);",
+	}
+	if !slices.Equal(got, want) {
+		t.Fatalf("suggestions =\n%v\nwant\n%v", got, want)
+	}
+	if s := report.Suggestions[0]; s.Calls != 4_000 || !slices.Equal(s.QueryIDs, []int64{1}) {
+		t.Fatalf("first suggestion = %+v", s)
+	}
+}
+
+func TestRunWithoutStatements(t *testing.T) {
+	store := storagetest.NewMemoryStore(storagetest.NewFakeClock(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)))
+	service := NewService(store, config.DBInsightsConfig{SlowQuery: time.Second})
+	report, err := service.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.StatementsAvailable || report.ID == 0 {
+		t.Fatalf("report = %+v", report)
+	}
+	latest, err := store.LatestDatabaseInsightsReport(context.Background())
+	if err != nil || latest.ID != report.ID {
+		t.Fatalf("latest report = %+v, %v", latest, err)
+	}
+}
@@ -0,0 +1,291 @@
+package e2e
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hongminglow/all-in-be/internal/config"
+	"github.com/hongminglow/all-in-be/internal/models"
+	"github.com/hongminglow/all-in-be/internal/server"
+	"github.com/hongminglow/all-in-be/internal/storage/storagetest"
+)
+
+const initBalance = 1000
+
+// app is a running server wired to in-memory fakes.
+type app struct {
+	t      *testing.T
+	url    string
+	store  *storagetest.MemoryStore
+	clock  *storagetest.FakeClock
+	outbox *storagetest.Outbox
+	// disposable is the disposable email domain list file.
+	disposable string
+}
+
+// mxResolver gives every domain a mail server except those under .invalid.
+type mxResolver struct{}
+
+func (mxResolver) LookupMX(_ context.Context, name string) ([]*net.MX, error) {
+	if strings.HasSuffix(name, ".invalid") {
+		return nil, &net.DNSError{Err: "no such host", Name: name, IsNotFound: true}
+	}
+	return []*net.MX{{Host: "mx." + name + ".", Pref: 10}}, nil
+}
+
+// oidcKey is the provider's signing key, generated once because RSA key
+// generation is slow.
+var oidcKey = sync.OnceValue(func() []byte {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		panic(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+})
+
+// newApp starts a server; opts are applied after the harness's own.
+func newApp(t *testing.T, opts ...server.Option) *app {
+	t.Helper()
+	return newAppWithConfig(t, nil, opts...)
+}
+
+// newAppWithConfig is newApp with tweak applied to the harness's config
+// first, for scenarios that need settings other tests must not see.
+func newAppWithConfig(t *testing.T, tweak func(*config.Config), opts ...server.Option) *app {
+	t.Helper()
+	clk := storagetest.NewFakeClock(time.Date(2026, 3, 14, 12, 0, 0, 0, time.UTC))
+	store := storagetest.NewMemoryStore(clk)
+	outbox := &storagetest.Outbox{}
+	keyPath := filepath.Join(t.TempDir(), "oidc.pem")
+	if err := os.WriteFile(keyPath, oidcKey(), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	disposable := filepath.Join(t.TempDir(), "disposable.txt")
+	if err := os.WriteFile(disposable, []byte("mailinator.com\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cfg := config.Config{
+		JWT:         config.JWTConfig{Secret: "e2e-secret", Issuer: "e2e", TTL: time.Hour},
+		InitBalance: initBalance,
+		RateLimit:   config.RateLimitConfig{AuthRequests: 1000, AuthWindow: time.Minute, PolicyTTL: time.Minute},
+		CORS: config.CORSConfig{
+			AllowedOrigins: []string{"*"},
+			AllowedMethods: []string{"GET", "POST", "PUT", "PATCH", "DELETE"},
+			AllowedHeaders: []string{"Content-Type", "Authorization"},
+		},
+		Blob: config.BlobConfig{
+			Backend:       "local",
+			LocalDir:      t.TempDir(),
+			PublicBaseURL: "http://blobs.invalid/blobs",
+			SigningKey:    "e2e-blob-key",
+		},
+		Notify: config.NotifyConfig{EmailProvider: "log", SMSProvider: "log"},
+		Jobs:   config.JobsConfig{Workers: 4, QueueSize: 1024, MaxWait: 30 * time.Second},
+		Security: config.SecurityConfig{
+			PublicURL:        "http://api.invalid",
+			PasswordResetURL: "http://app.invalid/reset-password",
+			PasswordResetTTL: time.Hour,
+			CountryHeader:    "CF-IPCountry",
+			// Only TestDeviceConfirmationScenario signs VVIP players in from a second device.
+			ConfirmDeviceRoles:    []string{models.VVIPUser},
+			DeviceConfirmationTTL: 15 * time.Minute,
+		},
+		Onboarding: config.OnboardingConfig{NudgeInterval: 24 * time.Hour},
+		Spectator:  config.SpectatorConfig{BigWinMin: 500},
+		Money:      config.MoneyConfig{Currency: "USD", DefaultLocale: "en-US"},
+		Exports:    config.ExportsConfig{LinkTTL: time.Hour},
+		Reports:    config.ReportsConfig{Recipients: []string{"ops@example.com"}, LinkTTL: time.Hour},
+		Support:    config.SupportConfig{AttachmentMaxBytes: 1 << 20, AttachmentLinkTTL: time.Hour},
+		GeoIP:      config.GeoIPConfig{BlockedCountries: []string{"KP"}},
+		IPRisk:     config.IPRiskConfig{DefaultAction: models.IPRiskFlag, PolicyTTL: time.Minute},
+		Usernames:  config.UsernameConfig{MinLength: 3, MaxLength: 30, Symbols: "._-"},
+		EmailCheck: config.EmailCheckConfig{CheckMX: true, MXTimeout: time.Second, DisposableList: disposable},
+		Challenges: config.ChallengeConfig{
+			Policies: map[string]models.ChallengePolicy{
+				models.ActionPasswordChange: {Method: models.ChallengeOTP, NewDeviceOnly: true},
+			},
+			NewDeviceAge: 24 * time.Hour,
+			TTL:          10 * time.Minute,
+			MaxAttempts:  3,
+		},
+		Games: config.GamesConfig{
+			Providers:  map[string]string{"reels": "http://reels.invalid/launch"},
+			LaunchTTL:  2 * time.Minute,
+			SessionTTL: time.Hour,
+
+			LimitIncreaseDelay: 24 * time.Hour,
+		},
+		Payments: config.PaymentsConfig{WithdrawalApprovalMin: 500},
+		OIDC: config.OIDCConfig{
+			SigningKeyPath: keyPath,
+			Issuer:         "http://api.invalid",
+			LoginURL:       "http://app.invalid/login",
+			TokenTTL:       time.Hour,
+		},
+	}
+	if tweak != nil {
+		tweak(&cfg)
+	}
+	srv, err := server.New(cfg, store, append([]server.Option{
+		server.WithClock(clk),
+		server.WithIDGenerator(&storagetest.SequentialIDs{Prefix: "e2e"}),
+		server.WithEmailSender(outbox),
+		server.WithSMSSender(outbox),
+		server.WithMXResolver(mxResolver{}),
+	}, opts...)...)
+	if err != nil {
+		t.Fatalf("init server: %v", err)
+	}
+	ts := httptest.NewServer(srv.Handler())
+	t.Cleanup(func() {
+		ts.Close()
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = srv.Shutdown(ctx)
+	})
+	return &app{t: t, url: ts.URL, store: store, clock: clk, outbox: outbox, disposable: disposable}
+}
+
+// call sends a JSON request and returns the status code and the envelope's data field.
+func (a *app) call(method, path, token string, body any) (int, json.RawMessage) {
+	a.t.Helper()
+	status, raw := a.do(method, path, token, body)
+	var envelope struct {
+		Data json.RawMessage `json:"data"`
+	}
+	_ = json.Unmarshal(raw, &envelope)
+	return status, envelope.Data
+}
+
+// do sends a JSON request and returns the status code and the raw response body.
+func (a *app) do(method, path, token string, body any) (int, []byte) {
+	a.t.Helper()
+	return a.doWithHeader(method, path, token, body, nil)
+}
+
+// doWithHeader is do with extra request headers.
+func (a *app) doWithHeader(method, path, token string, body any, header http.Header) (int, []byte) {
+	a.t.Helper()
+	resp, raw := a.send(method, path, token, body, header)
+	return resp.StatusCode, raw
+}
+
+// send is doWithHeader returning the whole response, whose body has been
+// read into raw.
+func (a *app) send(method, path, token string, body any, header http.Header) (resp *http.Response, raw []byte) {
+	a.t.Helper()
+	var reader io.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			a.t.Fatalf("encode %s %s body: %v", method, path, err)
+		}
+		reader = bytes.NewReader(encoded)
+	}
+	req, err := http.NewRequest(method, a.url+path, reader)
+	if err != nil {
+		a.t.Fatalf("build %s %s: %v", method, path, err)
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		a.t.Fatalf("%s %s: %v", method, path, err)
+	}
+	defer resp.Body.Close()
+	raw, err = io.ReadAll(resp.Body)
+	if err != nil {
+		a.t.Fatalf("%s %s: read body: %v", method, path, err)
+	}
+	return resp, raw
+}
+
+// mustCall is call that fails the test unless the response has the wanted status,
+// decoding the data field into out when it is non-nil.
+func (a *app) mustCall(want int, method, path, token string, body, out any) {
+	a.t.Helper()
+	status, data := a.call(method, path, token, body)
+	if status != want {
+		a.t.Fatalf("%s %s: status %d, want %d (data %s)", method, path, status, want, data)
+	}
+	if out != nil {
+		if err := json.Unmarshal(data, out); err != nil {
+			a.t.Fatalf("%s %s: decode %s: %v", method, path, data, err)
+		}
+	}
+}
+
+// register creates a player and returns it.
+func (a *app) register(username string, phoneSuffix int) models.User {
+	a.t.Helper()
+	var user models.User
+	a.mustCall(http.StatusCreated, http.MethodPost, "/register", "", map[string]string{
+		"username": username,
+		"email":    username + "@example.com",
+		"phone":    fmt.Sprintf("+1202555%04d", phoneSuffix),
+		"password": "correct-horse-battery",
+	}, &user)
+	return user
+}
+
+// login returns a bearer token for the user.
+func (a *app) login(username string) string {
+	a.t.Helper()
+	var resp struct {
+		Token string `json:"token"`
+	}
+	a.mustCall(http.StatusOK, http.MethodPost, "/login", "", map[string]string{
+		"identifier": username,
+		"password":   "correct-horse-battery",
+	}, &resp)
+	if resp.Token == "" {
+		a.t.Fatalf("login %s returned no token", username)
+	}
+	return resp.Token
+}
+
+// registerAs creates a user with the given role and returns it with a token.
+func (a *app) registerAs(username string, phoneSuffix int, role string) (models.User, string) {
+	a.t.Helper()
+	user := a.register(username, phoneSuffix)
+	if role != models.NormalUser {
+		if err := a.store.SetRole(user.ID, role); err != nil {
+			a.t.Fatalf("set role: %v", err)
+		}
+	}
+	return user, a.login(username)
+}
+
+// eventually polls cond until it holds or a deadline passes; background jobs
+// such as notifications and webhooks complete asynchronously.
+func eventually(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(3 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
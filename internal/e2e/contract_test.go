@@ -0,0 +1,75 @@
+package e2e
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/hongminglow/all-in-be/internal/models"
+)
+
+// TestCreationStatusContract pins the status codes of endpoints that create
+// something: 201 Created with a Location header naming the new resource, or
+// 202 Accepted with the URL to poll for work that finishes later. Updates,
+// deletes and reads stay 200.
+func TestCreationStatusContract(t *testing.T) {
+	a := newApp(t)
+	_, adminToken := a.registerAs("admin", 1, models.AdminUser)
+	player, playerToken := a.registerAs("player", 2, models.NormalUser)
+
+	for _, tc := range []struct {
+		name     string
+		method   string
+		path     string
+		token    string
+		body     any
+		status   int
+		location string // with %d for the new resource's ID
+	}{
+		{"register", http.MethodPost, "/register", "", map[string]string{
+			"username": "newcomer", "email": "newcomer@example.com", "phone": "+12025550003", "password": "correct-horse-battery",
+		}, http.StatusCreated, "/me"},
+		{"permission", http.MethodPost, "/admin/permissions", adminToken, map[string]any{"name": "cashier:refund"}, http.StatusCreated, "/admin/permissions/%d"},
+		{"role", http.MethodPost, "/admin/roles", adminToken, map[string]any{"role": "cashier"}, http.StatusCreated, "/admin/roles/%d"},
+		{"note", http.MethodPost, fmt.Sprintf("/admin/users/%d/notes", player.ID), adminToken, map[string]any{"body": "Called in"}, http.StatusCreated, fmt.Sprintf("/admin/users/%d/notes/", player.ID) + "%d"},
+		{"legal hold", http.MethodPost, fmt.Sprintf("/admin/users/%d/legal-holds", player.ID), adminToken, map[string]any{"reason": "chargeback"}, http.StatusCreated, fmt.Sprintf("/admin/users/%d/legal-holds/", player.ID) + "%d"},
+		{"webhook", http.MethodPost, "/admin/webhooks", adminToken, map[string]any{"url": "https://hooks.example.com/allin", "events": []string{models.EventUserCreated}}, http.StatusCreated, "/admin/webhooks/%d"},
+		{"promo code", http.MethodPost, "/admin/promo-codes", adminToken, map[string]any{"code": "WELCOME", "campaign": "launch", "amount": 5}, http.StatusCreated, "/admin/promo-codes/%d"},
+		{"data export", http.MethodPost, "/me/export", playerToken, nil, http.StatusAccepted, "/me/export/%d"},
+		{"login", http.MethodPost, "/login", "", map[string]string{"identifier": "player", "password": "correct-horse-battery"}, http.StatusOK, ""},
+		{"role update", http.MethodPatch, "/admin/roles/1", adminToken, map[string]any{"description": "Players"}, http.StatusOK, ""},
+	} {
+		resp, raw := a.send(tc.method, tc.path, tc.token, tc.body, nil)
+		if resp.StatusCode != tc.status {
+			t.Errorf("%s: status %d, want %d (%s)", tc.name, resp.StatusCode, tc.status, raw)
+			continue
+		}
+		var envelope struct {
+			Code int `json:"code"`
+			Data struct {
+				ID int64 `json:"id"`
+			} `json:"data"`
+		}
+		if err := json.Unmarshal(raw, &envelope); err != nil || envelope.Code != tc.status {
+			t.Errorf("%s: envelope code %d, want %d (%v)", tc.name, envelope.Code, tc.status, err)
+		}
+		want := tc.location
+		if want != "" && want != "/me" {
+			want = fmt.Sprintf(tc.location, envelope.Data.ID)
+		}
+		if got := resp.Header.Get("Location"); got != want {
+			t.Errorf("%s: Location %q, want %q", tc.name, got, want)
+		}
+	}
+
+	resp, raw := a.send(http.MethodPost, "/admin/oauth-clients", adminToken, map[string]any{
+		"name": "Companion", "redirect_uris": []string{"https://companion.invalid/callback"},
+	}, nil)
+	var client struct {
+		Data models.OAuthClient `json:"data"`
+	}
+	if err := json.Unmarshal(raw, &client); err != nil || resp.StatusCode != http.StatusCreated || resp.Header.Get("Location") != "/admin/oauth-clients/"+client.Data.ID {
+		t.Errorf("oauth client: status %d, Location %q, body %s", resp.StatusCode, resp.Header.Get("Location"), raw)
+	}
+}
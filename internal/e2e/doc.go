@@ -0,0 +1,3 @@
+// Package e2e holds black-box scenario tests that boot the whole HTTP server
+// against the in-memory store and fake providers from storagetest.
+package e2e
@@ -0,0 +1,167 @@
+package e2e
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hongminglow/all-in-be/internal/models"
+)
+
+var update = flag.Bool("update", false, "rewrite testdata/golden snapshots from the current responses")
+
+// volatileKeys are response fields whose values legitimately differ between
+// runs or refactors; only their presence and type are part of the contract.
+var volatileKeys = map[string]string{
+	"token":  "<token>",
+	"secret": "<secret>",
+	"uptime": "<duration>",
+}
+
+// TestResponseShapes records the full response envelope of every endpoint the
+// frontend depends on. A failure means a response shape changed: if that was
+// intended, rerun with -update and review the snapshot diff.
+func TestResponseShapes(t *testing.T) {
+	a := newApp(t)
+	_, adminToken := a.registerAs("admin", 1, models.AdminUser)
+
+	golden(t, "health", a, http.MethodGet, "/health", "", nil)
+	golden(t, "region", a, http.MethodGet, "/region", "", nil)
+	golden(t, "errors", a, http.MethodGet, "/errors", "", nil)
+	golden(t, "readyz", a, http.MethodGet, "/readyz", "", nil)
+	golden(t, "register", a, http.MethodPost, "/register", "", map[string]string{
+		"username": "alice", "email": "alice@example.com", "phone": "+12025550002", "password": "correct-horse-battery",
+	})
+	golden(t, "register_conflict", a, http.MethodPost, "/register", "", map[string]string{
+		"username": "alice", "email": "alice@example.com", "phone": "+12025550002", "password": "correct-horse-battery",
+	})
+	golden(t, "register_invalid", a, http.MethodPost, "/register", "", map[string]string{"username": "bob"})
+	golden(t, "login", a, http.MethodPost, "/login", "", map[string]string{
+		"identifier": "alice", "password": "correct-horse-battery",
+	})
+	golden(t, "login_wrong_password", a, http.MethodPost, "/login", "", map[string]string{
+		"identifier": "alice", "password": "nope",
+	})
+	aliceToken := a.login("alice")
+	golden(t, "me", a, http.MethodGet, "/me", aliceToken, nil)
+	golden(t, "me_logins", a, http.MethodGet, "/me/logins?limit=1", aliceToken, nil)
+	golden(t, "me_privacy", a, http.MethodPut, "/me/privacy", aliceToken, map[string]string{"public_activity": models.ActivityMasked})
+	golden(t, "me_unauthenticated", a, http.MethodGet, "/me", "", nil)
+	golden(t, "admin_forbidden", a, http.MethodGet, "/admin/stats", aliceToken, nil)
+
+	golden(t, "rate_limit_upsert", a, http.MethodPut, "/admin/rate-limits", adminToken, map[string]any{
+		"route_class": models.RouteClassAPI, "requests": 100, "window_seconds": 60,
+	})
+	golden(t, "rate_limit_list", a, http.MethodGet, "/admin/rate-limits", adminToken, nil)
+	golden(t, "config_export", a, http.MethodGet, "/admin/config/export", adminToken, nil)
+	golden(t, "config_history", a, http.MethodGet, "/admin/config/history", adminToken, nil)
+	golden(t, "stats", a, http.MethodGet, "/admin/stats?days=2", adminToken, nil)
+
+	var alice models.User
+	a.mustCall(http.StatusOK, http.MethodGet, "/me", aliceToken, nil, &alice)
+	if _, err := a.store.ApplyTransaction(context.Background(), models.Transaction{UserID: alice.ID, Amount: 2500, Reason: models.TransactionBetSettlement}); err != nil {
+		t.Fatalf("settle bet: %v", err)
+	}
+	golden(t, "public_big_wins", a, http.MethodGet, "/public/big-wins", "", nil)
+	notes := fmt.Sprintf("/admin/users/%d/notes", alice.ID)
+	golden(t, "note_create", a, http.MethodPost, notes, adminToken, map[string]any{"body": "Asked about limits", "pinned": true})
+	golden(t, "note_list", a, http.MethodGet, notes, adminToken, nil)
+	golden(t, "note_update", a, http.MethodPatch, notes+"/1", adminToken, map[string]any{"body": "Asked about deposit limits"})
+	golden(t, "note_history", a, http.MethodGet, notes+"/1/history", adminToken, nil)
+
+	golden(t, "webhook_create", a, http.MethodPost, "/admin/webhooks", adminToken, map[string]any{
+		"url": "https://hooks.example.com/allin", "events": []string{models.EventUserCreated},
+	})
+	golden(t, "webhook_list", a, http.MethodGet, "/admin/webhooks", adminToken, nil)
+}
+
+// golden sends the request and compares the normalized response with
+// testdata/golden/<name>.json.
+func golden(t *testing.T, name string, a *app, method, path, token string, body any) {
+	t.Helper()
+	status, raw := a.do(method, path, token, body)
+	var decoded any
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	if err := dec.Decode(&decoded); err != nil {
+		t.Fatalf("%s: %s %s returned non-JSON body %q: %v", name, method, path, raw, err)
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(map[string]any{
+		"request": method + " " + path,
+		"status":  status,
+		"body":    normalize("", decoded),
+	}); err != nil {
+		t.Fatalf("%s: encode snapshot: %v", name, err)
+	}
+	snapshot := buf.Bytes()
+
+	file := filepath.Join("testdata", "golden", name+".json")
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(file, snapshot, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatalf("%s: missing snapshot (run go test ./internal/e2e -run TestResponseShapes -update): %v", name, err)
+	}
+	if !bytes.Equal(want, snapshot) {
+		t.Errorf("%s: response shape changed for %s %s\n--- want (%s)\n%s--- got\n%s", name, method, path, file, want, snapshot)
+	}
+}
+
+// normalize replaces values that vary between runs with placeholders: IDs,
+// timestamps and the fields listed in volatileKeys. ID placeholders keep the
+// JSON type so a switch between numeric and string IDs is still caught.
+func normalize(key string, v any) any {
+	if placeholder, ok := volatileKeys[key]; ok {
+		return placeholder
+	}
+	switch value := v.(type) {
+	case map[string]any:
+		for k, child := range value {
+			value[k] = normalize(k, child)
+		}
+		return value
+	case []any:
+		for i, child := range value {
+			value[i] = normalize("", child)
+		}
+		return value
+	case json.Number:
+		if isIDKey(key) {
+			return "<id:number>"
+		}
+		return value
+	case string:
+		if isIDKey(key) && value != "" {
+			return "<id:string>"
+		}
+		if _, err := time.Parse(time.RFC3339Nano, value); err == nil {
+			return "<timestamp>"
+		}
+		return value
+	default:
+		return value
+	}
+}
+
+func isIDKey(key string) bool {
+	return key == "id" || strings.HasSuffix(key, "_id") || strings.HasSuffix(key, "ID")
+}
@@ -0,0 +1,3092 @@
+package e2e
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hongminglow/all-in-be/internal/config"
+	"github.com/hongminglow/all-in-be/internal/iprisk"
+	"github.com/hongminglow/all-in-be/internal/models"
+	"github.com/hongminglow/all-in-be/internal/models/dto"
+	"github.com/hongminglow/all-in-be/internal/payments"
+	"github.com/hongminglow/all-in-be/internal/seamless"
+	"github.com/hongminglow/all-in-be/internal/security"
+	"github.com/hongminglow/all-in-be/internal/server"
+	"github.com/hongminglow/all-in-be/internal/storage"
+	"github.com/hongminglow/all-in-be/internal/storage/storagetest"
+	"github.com/hongminglow/all-in-be/internal/usernames"
+	"github.com/hongminglow/all-in-be/internal/webhook"
+)
+
+// TestOnboardingScenario walks a new player from registration to an
+// authenticated session and checks every side effect: welcome email, signed
+// user.created webhook, delivery log and operator totals.
+func TestOnboardingScenario(t *testing.T) {
+	a := newApp(t)
+
+	var mu sync.Mutex
+	var received []*http.Request
+	var bodies [][]byte
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		defer mu.Unlock()
+		received = append(received, r)
+		bodies = append(bodies, body)
+	}))
+	defer receiver.Close()
+
+	_, adminToken := a.registerAs("admin", 1, models.AdminUser)
+	var endpoint models.WebhookEndpoint
+	a.mustCall(http.StatusCreated, http.MethodPost, "/admin/webhooks", adminToken, map[string]any{
+		"url":    receiver.URL,
+		"events": []string{models.EventUserCreated},
+		"secret": "whsec_e2e",
+	}, &endpoint)
+
+	alice := a.register("alice", 2)
+	if alice.Balance != initBalance || alice.Role != models.NormalUser {
+		t.Fatalf("new player = %+v", alice)
+	}
+	if status, _ := a.call(http.MethodPost, "/register", "", map[string]string{
+		"username": "alice", "email": "alice@example.com", "phone": "+12025550002", "password": "correct-horse-battery",
+	}); status != http.StatusConflict {
+		t.Fatalf("duplicate registration status %d, want 409", status)
+	}
+
+	eventually(t, "welcome email", func() bool {
+		for _, m := range a.outbox.Sent() {
+			if m.Channel == "email" && m.To == "alice@example.com" && strings.Contains(m.Subject, "alice") {
+				return true
+			}
+		}
+		return false
+	})
+
+	eventually(t, "user.created webhook", func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(received) == 1
+	})
+	mu.Lock()
+	req, body := received[0], bodies[0]
+	mu.Unlock()
+	if got, want := req.Header.Get("X-Webhook-Signature"), webhook.Sign("whsec_e2e", a.clock.Now().Unix(), body); got != want {
+		t.Fatalf("webhook signature %q, want %q", got, want)
+	}
+	var event struct {
+		Type string `json:"type"`
+		Data struct {
+			UserID int64 `json:"user_id"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &event); err != nil || event.Type != models.EventUserCreated || event.Data.UserID != alice.ID {
+		t.Fatalf("webhook body %s (%v)", body, err)
+	}
+	eventually(t, "delivery log", func() bool {
+		var deliveries []models.WebhookDelivery
+		a.mustCall(http.StatusOK, http.MethodGet, fmt.Sprintf("/admin/webhooks/%d/deliveries", endpoint.ID), adminToken, nil, &deliveries)
+		return len(deliveries) == 1 && deliveries[0].Success
+	})
+
+	aliceToken := a.login("alice")
+	var me models.User
+	a.mustCall(http.StatusOK, http.MethodGet, "/me", aliceToken, nil, &me)
+	if me.ID != alice.ID || me.Balance != initBalance {
+		t.Fatalf("/me = %+v", me)
+	}
+	if status, _ := a.call(http.MethodGet, "/admin/stats", aliceToken, nil); status != http.StatusForbidden {
+		t.Fatalf("player reading stats: status %d, want 403", status)
+	}
+
+	var stats models.AdminStats
+	a.mustCall(http.StatusOK, http.MethodGet, "/admin/stats?days=1", adminToken, nil, &stats)
+	if stats.TotalUsers != 2 || stats.TotalBalance != 2*initBalance {
+		t.Fatalf("stats = %+v", stats)
+	}
+	if len(stats.SignupsPerDay) != 1 || stats.SignupsPerDay[0].Count != 2 {
+		t.Fatalf("signups = %+v", stats.SignupsPerDay)
+	}
+}
+
+// TestOnboardingJourneyScenario configures a two-step journey and follows a
+// new player through it: each step emails its template when reached, and
+// signing in completes the first one.
+func TestOnboardingJourneyScenario(t *testing.T) {
+	a := newApp(t)
+	_, adminToken := a.registerAs("admin", 1, models.AdminUser)
+	a.mustCall(http.StatusOK, http.MethodPut, "/admin/onboarding/journeys", adminToken, map[string]any{
+		"steps": []map[string]string{
+			{"key": "welcome", "title": "Welcome", "template": "welcome", "complete_on": "user.logged_in"},
+			{"key": "set_limits", "title": "Set your deposit limits", "template": "onboarding_nudge"},
+		},
+	}, nil)
+	if status, _ := a.call(http.MethodPut, "/admin/onboarding/journeys", adminToken, map[string]any{
+		"steps": []map[string]string{{"key": "welcome", "title": "Welcome", "complete_on": "bonus.claimed"}},
+	}); status != http.StatusBadRequest {
+		t.Fatalf("journey with unknown event: status %d, want 400", status)
+	}
+
+	a.register("bob", 2)
+	eventually(t, "welcome email", func() bool {
+		return countEmails(a, "bob@example.com", "bob") == 1
+	})
+	bobToken := a.login("bob")
+	eventually(t, "next step email", func() bool {
+		return countEmails(a, "bob@example.com", "Set your deposit limits") == 1
+	})
+
+	var status models.OnboardingStatus
+	a.mustCall(http.StatusOK, http.MethodGet, "/me/onboarding", bobToken, nil, &status)
+	if status.Current != "set_limits" || len(status.Steps) != 2 || status.Steps[0].CompletedAt == nil {
+		t.Fatalf("onboarding status = %+v", status)
+	}
+	if code, _ := a.call(http.MethodGet, "/admin/onboarding/journeys", bobToken, nil); code != http.StatusForbidden {
+		t.Fatalf("player listing journeys: status %d, want 403", code)
+	}
+}
+
+// TestSessionExpiryScenario checks that tokens stop working once the clock
+// passes their TTL.
+func TestSessionExpiryScenario(t *testing.T) {
+	a := newApp(t)
+	a.register("bob", 3)
+	token := a.login("bob")
+
+	a.mustCall(http.StatusOK, http.MethodGet, "/me", token, nil, nil)
+	a.clock.Advance(time.Hour + time.Minute)
+	if status, _ := a.call(http.MethodGet, "/me", token, nil); status != http.StatusUnauthorized {
+		t.Fatalf("expired token: status %d, want 401", status)
+	}
+}
+
+// TestLogoutScenario checks that signing out revokes the session, whether the
+// token came in a cookie or a bearer header.
+func TestLogoutScenario(t *testing.T) {
+	a := newApp(t)
+	a.register("lou", 64)
+
+	resp, raw := a.send(http.MethodPost, "/login", "", map[string]any{"identifier": "lou", "password": "correct-horse-battery", "useCookie": true}, nil)
+	cookies := resp.Cookies()
+	if resp.StatusCode != http.StatusOK || len(cookies) != 1 || cookies[0].Value == "" {
+		t.Fatalf("cookie login: status %d, cookies %v, body %s", resp.StatusCode, cookies, raw)
+	}
+	withCookie := http.Header{"Cookie": {cookies[0].Name + "=" + cookies[0].Value}}
+	if status, body := a.doWithHeader(http.MethodGet, "/me", "", nil, withCookie); status != http.StatusOK {
+		t.Fatalf("/me with the cookie: status %d, body %s", status, body)
+	}
+	bearer := a.login("lou")
+
+	a.clock.Advance(time.Second)
+	resp, raw = a.send(http.MethodPost, "/logout", "", nil, withCookie)
+	if cleared := resp.Cookies(); resp.StatusCode != http.StatusOK || len(cleared) != 1 || cleared[0].MaxAge >= 0 {
+		t.Fatalf("logout: status %d, cookies %v, body %s", resp.StatusCode, cleared, raw)
+	}
+	if status, body := a.doWithHeader(http.MethodGet, "/me", "", nil, withCookie); status != http.StatusUnauthorized || !bytes.Contains(body, []byte("session revoked")) {
+		t.Fatalf("/me with the signed out cookie: status %d, body %s", status, body)
+	}
+	if status, _ := a.call(http.MethodGet, "/me", bearer, nil); status != http.StatusUnauthorized {
+		t.Fatalf("bearer token from before the logout: status %d, want 401", status)
+	}
+
+	a.clock.Advance(time.Second)
+	bearer = a.login("lou")
+	a.clock.Advance(time.Second)
+	a.mustCall(http.StatusOK, http.MethodPost, "/logout", bearer, nil, nil)
+	if status, _ := a.call(http.MethodGet, "/me", bearer, nil); status != http.StatusUnauthorized {
+		t.Fatalf("bearer token after its logout: status %d, want 401", status)
+	}
+	a.mustCall(http.StatusOK, http.MethodPost, "/logout", "", nil, nil)
+}
+
+// TestSupportNotesScenario has staff annotate a player's account and edit the note.
+func TestSupportNotesScenario(t *testing.T) {
+	a := newApp(t)
+	player := a.register("carol", 4)
+	_, staffToken := a.registerAs("support", 5, models.StaffUser)
+	playerToken := a.login("carol")
+
+	notesPath := fmt.Sprintf("/admin/users/%d/notes", player.ID)
+	if status, _ := a.call(http.MethodGet, notesPath, playerToken, nil); status != http.StatusForbidden {
+		t.Fatalf("player reading notes: status %d, want 403", status)
+	}
+
+	var note models.UserNote
+	a.mustCall(http.StatusCreated, http.MethodPost, notesPath, staffToken, map[string]any{"body": "asked about limits"}, &note)
+	a.mustCall(http.StatusOK, http.MethodPatch, fmt.Sprintf("%s/%d", notesPath, note.ID), staffToken, map[string]any{"body": "asked about deposit limits", "pinned": true}, nil)
+
+	var notes []models.UserNote
+	a.mustCall(http.StatusOK, http.MethodGet, notesPath, staffToken, nil, &notes)
+	if len(notes) != 1 || !notes[0].Pinned || notes[0].Body != "asked about deposit limits" || notes[0].AuthorUsername != "support" {
+		t.Fatalf("notes = %+v", notes)
+	}
+	var history []models.NoteRevision
+	a.mustCall(http.StatusOK, http.MethodGet, fmt.Sprintf("%s/%d/history", notesPath, note.ID), staffToken, nil, &history)
+	if len(history) != 1 || history[0].Body != "asked about limits" {
+		t.Fatalf("history = %+v", history)
+	}
+}
+
+// TestConfigurationScenario changes a rate-limit policy, exports it, and rolls the change back.
+func TestConfigurationScenario(t *testing.T) {
+	a := newApp(t)
+	_, adminToken := a.registerAs("ops", 6, models.AdminUser)
+
+	a.mustCall(http.StatusOK, http.MethodPut, "/admin/rate-limits", adminToken, map[string]any{
+		"route_class": models.RouteClassAPI, "requests": 50, "window_seconds": 60,
+	}, nil)
+
+	var bundle struct {
+		RateLimits []models.RateLimitPolicy `json:"rate_limits"`
+	}
+	a.mustCall(http.StatusOK, http.MethodGet, "/admin/config/export", adminToken, nil, &bundle)
+	if len(bundle.RateLimits) != 1 || bundle.RateLimits[0].Requests != 50 {
+		t.Fatalf("exported bundle = %+v", bundle)
+	}
+
+	var history []models.ConfigChange
+	a.mustCall(http.StatusOK, http.MethodGet, "/admin/config/history", adminToken, nil, &history)
+	if len(history) != 1 {
+		t.Fatalf("history = %+v", history)
+	}
+	a.mustCall(http.StatusOK, http.MethodPost, fmt.Sprintf("/admin/config/history/%d/rollback", history[0].ID), adminToken, nil, nil)
+
+	var policies []models.RateLimitPolicy
+	a.mustCall(http.StatusOK, http.MethodGet, "/admin/rate-limits", adminToken, nil, &policies)
+	if len(policies) != 0 {
+		t.Fatalf("policies after rollback = %+v", policies)
+	}
+}
+
+// TestLoginAlertScenario signs a player in from an unfamiliar device and
+// country, follows the "this wasn't me" link, and checks the account is locked
+// down until the emailed reset link is used.
+func TestLoginAlertScenario(t *testing.T) {
+	a := newApp(t)
+	_, adminToken := a.registerAs("security", 7, models.AdminUser)
+	carol := a.register("carol", 8)
+	token := a.login("carol")
+	eventually(t, "first device recorded", func() bool {
+		devices, _ := a.store.ListLoginDevices(context.Background(), carol.ID)
+		return len(devices) == 1
+	})
+	a.login("carol")
+
+	login := map[string]string{"identifier": "carol", "password": "correct-horse-battery"}
+	status, _ := a.doWithHeader(http.MethodPost, "/login", "", login, http.Header{
+		"X-Device-Id":  {"stolen-laptop"},
+		"Cf-Ipcountry": {"nz"},
+	})
+	if status != http.StatusOK {
+		t.Fatalf("login from new device: status %d", status)
+	}
+	alert := waitForEmail(t, a, "carol@example.com", "New sign-in")
+	if !strings.Contains(alert.Body, "Country: NZ") {
+		t.Fatalf("alert body missing country:\n%s", alert.Body)
+	}
+	if n := countEmails(a, "carol@example.com", "New sign-in"); n != 1 {
+		t.Fatalf("sent %d login alerts, want 1 (the familiar device must not alert)", n)
+	}
+	denyPath := linkPath(t, alert.Body, "http://api.invalid", "/deny")
+
+	if status, page := a.do(http.MethodGet, denyPath, "", nil); status != http.StatusOK || !strings.Contains(string(page), "<form") {
+		t.Fatalf("GET deny link: status %d, body %s", status, page)
+	}
+	a.mustCall(http.StatusOK, http.MethodGet, "/me", token, nil, nil)
+
+	a.clock.Advance(time.Second)
+	if status, page := a.do(http.MethodPost, denyPath, "", nil); status != http.StatusOK {
+		t.Fatalf("POST deny link: status %d, body %s", status, page)
+	}
+	if status, _ := a.do(http.MethodPost, denyPath, "", nil); status != http.StatusConflict {
+		t.Fatalf("second deny: status %d, want 409", status)
+	}
+	if status, _ := a.call(http.MethodGet, "/me", token, nil); status != http.StatusUnauthorized {
+		t.Fatalf("revoked session: status %d, want 401", status)
+	}
+	if status, _ := a.call(http.MethodPost, "/login", "", login); status != http.StatusForbidden {
+		t.Fatalf("login before reset: status %d, want 403", status)
+	}
+
+	var cases []models.SecurityCase
+	a.mustCall(http.StatusOK, http.MethodGet, "/admin/security-cases", adminToken, nil, &cases)
+	if len(cases) != 1 || cases[0].UserID != carol.ID || cases[0].Status != models.SecurityCaseOpen || cases[0].LoginAlertID == nil {
+		t.Fatalf("security cases = %+v", cases)
+	}
+
+	reset := waitForEmail(t, a, "carol@example.com", "Reset your")
+	resetToken := strings.TrimPrefix(linkPath(t, reset.Body, "http://app.invalid/reset-password", ""), "?token=")
+	a.mustCall(http.StatusOK, http.MethodPost, "/password/reset", "", map[string]string{"token": resetToken, "password": "a-brand-new-secret"}, nil)
+	if status, _ := a.call(http.MethodPost, "/password/reset", "", map[string]string{"token": resetToken, "password": "another-secret"}); status != http.StatusBadRequest {
+		t.Fatalf("reused reset token: status %d, want 400", status)
+	}
+	if status, _ := a.call(http.MethodPost, "/login", "", login); status != http.StatusUnauthorized {
+		t.Fatalf("old password after reset: status %d, want 401", status)
+	}
+	var fresh struct {
+		Token string `json:"token"`
+	}
+	a.mustCall(http.StatusOK, http.MethodPost, "/login", "", map[string]string{"identifier": "carol", "password": "a-brand-new-secret"}, &fresh)
+	a.mustCall(http.StatusOK, http.MethodGet, "/me", fresh.Token, nil, nil)
+}
+
+// TestForcedResetScenario has support staff lock a compromised player's
+// account and checks the player is shut out until the emailed reset link is used.
+func TestForcedResetScenario(t *testing.T) {
+	a := newApp(t)
+	_, staffToken := a.registerAs("support", 1, models.StaffUser)
+	admin, _ := a.registerAs("boss", 2, models.AdminUser)
+	dave := a.register("dave", 3)
+	token := a.login("dave")
+
+	if status, _ := a.call(http.MethodPost, fmt.Sprintf("/admin/users/%d/force-password-reset", admin.ID), staffToken, nil); status != http.StatusForbidden {
+		t.Fatalf("staff locking an admin: status %d, want 403", status)
+	}
+	if status, _ := a.call(http.MethodPost, fmt.Sprintf("/admin/users/%d/force-password-reset", dave.ID), token, nil); status != http.StatusForbidden {
+		t.Fatalf("player locking themselves: status %d, want 403", status)
+	}
+	var opened models.SecurityCase
+	a.mustCall(http.StatusOK, http.MethodPost, fmt.Sprintf("/admin/users/%d/force-password-reset", dave.ID), staffToken, nil, &opened)
+	if opened.UserID != dave.ID || opened.Reason != security.ReasonForcedReset || opened.Status != models.SecurityCaseOpen {
+		t.Fatalf("security case = %+v", opened)
+	}
+
+	// The clock has not moved, so the token outlives the revocation and the
+	// flag alone must keep it out.
+	if status, _ := a.call(http.MethodGet, "/me", token, nil); status != http.StatusForbidden {
+		t.Fatalf("session after forced reset: status %d, want 403", status)
+	}
+	login := map[string]string{"identifier": "dave", "password": "correct-horse-battery"}
+	if status, _ := a.call(http.MethodPost, "/login", "", login); status != http.StatusForbidden {
+		t.Fatalf("login before reset: status %d, want 403", status)
+	}
+
+	reset := waitForEmail(t, a, "dave@example.com", "Reset your")
+	resetToken := strings.TrimPrefix(linkPath(t, reset.Body, "http://app.invalid/reset-password", ""), "?token=")
+	a.clock.Advance(time.Second)
+	a.mustCall(http.StatusOK, http.MethodPost, "/password/reset", "", map[string]string{"token": resetToken, "password": "a-brand-new-secret"}, nil)
+	if status, _ := a.call(http.MethodGet, "/me", token, nil); status != http.StatusUnauthorized {
+		t.Fatalf("old session after reset: status %d, want 401", status)
+	}
+	var fresh struct {
+		Token string `json:"token"`
+	}
+	a.mustCall(http.StatusOK, http.MethodPost, "/login", "", map[string]string{"identifier": "dave", "password": "a-brand-new-secret"}, &fresh)
+	a.mustCall(http.StatusOK, http.MethodGet, "/me", fresh.Token, nil, nil)
+}
+
+// TestDeviceConfirmationScenario signs in from a new device as a player, who
+// is only flagged, and as a VVIP player, whose role must confirm it first.
+func TestDeviceConfirmationScenario(t *testing.T) {
+	a := newApp(t)
+	player := a.register("carol", 8)
+	vip, _ := a.registerAs("dana", 9, models.VVIPUser)
+	a.login("carol")
+	for _, user := range []models.User{player, vip} {
+		eventually(t, user.Username+"'s first device recorded", func() bool {
+			devices, _ := a.store.ListLoginDevices(context.Background(), user.ID)
+			return len(devices) == 1
+		})
+	}
+	phone := http.Header{"X-Device-Id": {"new-phone"}}
+	var resp struct {
+		Token     string `json:"token"`
+		NewDevice bool   `json:"new_device"`
+	}
+	login := func(username string) (int, []byte) {
+		return a.doWithHeader(http.MethodPost, "/login", "", map[string]string{"identifier": username, "password": "correct-horse-battery"}, phone)
+	}
+
+	status, body := login("carol")
+	if err := json.Unmarshal(body, &struct{ Data any }{Data: &resp}); status != http.StatusOK || err != nil || !resp.NewDevice {
+		t.Fatalf("player on a new device: status %d, body %s", status, body)
+	}
+
+	if status, body := login("dana"); status != http.StatusForbidden {
+		t.Fatalf("VVIP player on a new device: status %d, want 403 (body %s)", status, body)
+	}
+	attempts, _ := a.store.ListLoginAttempts(context.Background(), models.LoginAttemptFilter{UserID: vip.ID}, 1)
+	if len(attempts) != 1 || attempts[0].FailureReason != models.LoginDeviceUnconfirmed {
+		t.Fatalf("latest attempt = %+v, want an unconfirmed device failure", attempts)
+	}
+	email := waitForEmail(t, a, "dana@example.com", "Confirm your new device")
+	confirmPath := linkPath(t, email.Body, "http://api.invalid", "")
+	if status, page := a.do(http.MethodGet, confirmPath, "", nil); status != http.StatusOK || !strings.Contains(string(page), "<form") {
+		t.Fatalf("GET confirmation link: status %d, body %s", status, page)
+	}
+	if status, page := a.do(http.MethodPost, confirmPath, "", nil); status != http.StatusOK {
+		t.Fatalf("POST confirmation link: status %d, body %s", status, page)
+	}
+	if status, _ := a.do(http.MethodPost, confirmPath, "", nil); status != http.StatusNotFound {
+		t.Fatalf("reused confirmation link: status %d, want 404", status)
+	}
+
+	resp.NewDevice = false
+	status, body = login("dana")
+	if err := json.Unmarshal(body, &struct{ Data any }{Data: &resp}); status != http.StatusOK || err != nil || resp.Token == "" || resp.NewDevice {
+		t.Fatalf("VVIP player on the confirmed device: status %d, body %s", status, body)
+	}
+	if n := countEmails(a, "dana@example.com", "New sign-in"); n != 0 {
+		t.Fatalf("sent %d login alerts for a confirmed device, want 0", n)
+	}
+}
+
+func waitForEmail(t *testing.T, a *app, to, subject string) storagetest.SentMessage {
+	t.Helper()
+	var found storagetest.SentMessage
+	eventually(t, subject+" email", func() bool {
+		for _, m := range a.outbox.Sent() {
+			if m.Channel == "email" && m.To == to && strings.Contains(m.Subject, subject) {
+				found = m
+				return true
+			}
+		}
+		return false
+	})
+	return found
+}
+
+func countEmails(a *app, to, subject string) int {
+	n := 0
+	for _, m := range a.outbox.Sent() {
+		if m.Channel == "email" && m.To == to && strings.Contains(m.Subject, subject) {
+			n++
+		}
+	}
+	return n
+}
+
+// linkPath finds the link in body that starts with base and ends with suffix
+// and returns it without base.
+func linkPath(t *testing.T, body, base, suffix string) string {
+	t.Helper()
+	for _, field := range strings.Fields(body) {
+		if strings.HasPrefix(field, base) && strings.HasSuffix(field, suffix) {
+			return strings.TrimPrefix(field, base)
+		}
+	}
+	t.Fatalf("no %s...%s link in:\n%s", base, suffix, body)
+	return ""
+}
+
+// TestScopedTokenScenario gives a dashboard widget a token limited to
+// stats:read and checks it cannot use the rest of the staff role.
+func TestScopedTokenScenario(t *testing.T) {
+	a := newApp(t)
+	staff, _ := a.registerAs("widget", 9, models.StaffUser)
+
+	login := map[string]any{"identifier": "widget", "password": "correct-horse-battery", "scopes": []string{models.PermStatsRead}}
+	var resp struct {
+		Token  string   `json:"token"`
+		Scopes []string `json:"scopes"`
+	}
+	a.mustCall(http.StatusOK, http.MethodPost, "/login", "", login, &resp)
+	if len(resp.Scopes) != 1 || resp.Scopes[0] != models.PermStatsRead {
+		t.Fatalf("granted scopes = %v", resp.Scopes)
+	}
+
+	a.mustCall(http.StatusOK, http.MethodGet, "/admin/stats", resp.Token, nil, nil)
+	notes := fmt.Sprintf("/admin/users/%d/notes", staff.ID)
+	if status, _ := a.call(http.MethodGet, notes, resp.Token, nil); status != http.StatusForbidden {
+		t.Fatalf("notes with stats-only token: status %d, want 403", status)
+	}
+	a.mustCall(http.StatusOK, http.MethodGet, notes, a.login("widget"), nil, nil)
+
+	// Routes that declare no scope refuse scoped tokens, so a read-only token
+	// cannot move money or change the account.
+	for _, route := range []struct {
+		method, path string
+		body         any
+	}{
+		{http.MethodPost, "/payments/sandbox/withdrawals", map[string]any{"amount": 10}},
+		{http.MethodPost, "/payments/sandbox/deposits", map[string]any{"amount": 10}},
+		{http.MethodPatch, "/me", map[string]any{"username": "gadget"}},
+		{http.MethodGet, "/me", nil},
+	} {
+		if status, _ := a.call(route.method, route.path, resp.Token, route.body); status != http.StatusForbidden {
+			t.Errorf("%s %s with a stats-only token: status %d, want 403", route.method, route.path, status)
+		}
+	}
+
+	login["scopes"] = []string{models.PermConfigManage}
+	if status, _ := a.call(http.MethodPost, "/login", "", login); status != http.StatusBadRequest {
+		t.Fatalf("scope beyond role: status %d, want 400", status)
+	}
+}
+
+// TestUserSearchScenario pages through ranked search results as support staff.
+func TestUserSearchScenario(t *testing.T) {
+	a := newApp(t)
+	_, staffToken := a.registerAs("support", 10, models.StaffUser)
+	for i, name := range []string{"kali", "alina", "ali", "alice", "bob"} {
+		a.register(name, 11+i)
+	}
+
+	var names []string
+	cursor := ""
+	for page := 0; ; page++ {
+		var resp struct {
+			Users      []models.User `json:"users"`
+			NextCursor string        `json:"next_cursor"`
+		}
+		a.mustCall(http.StatusOK, http.MethodGet, "/admin/users/search?q=ALI&limit=2&cursor="+cursor, staffToken, nil, &resp)
+		for _, u := range resp.Users {
+			names = append(names, u.Username)
+		}
+		if resp.NextCursor == "" {
+			break
+		}
+		if page > 3 {
+			t.Fatal("pagination did not terminate")
+		}
+		cursor = resp.NextCursor
+	}
+	if want := "ali,alina,alice,kali"; strings.Join(names, ",") != want {
+		t.Fatalf("search order = %v, want %s (exact, then prefix, then substring matches)", names, want)
+	}
+
+	if status, _ := a.call(http.MethodGet, "/admin/users/search?q=al", staffToken, nil); status != http.StatusBadRequest {
+		t.Fatalf("short query: status %d, want 400", status)
+	}
+	if status, _ := a.call(http.MethodGet, "/admin/users/search?q=ali", a.login("bob"), nil); status != http.StatusForbidden {
+		t.Fatalf("player search: status %d, want 403", status)
+	}
+}
+
+// fakeProvider accepts callbacks signed "ok" and records the events it applies.
+// Setting down makes Apply fail, as during a database or downstream outage.
+type fakeProvider struct {
+	mu      sync.Mutex
+	down    bool
+	applied []string
+}
+
+func (p *fakeProvider) Verify(header http.Header, payload []byte) (string, error) {
+	if header.Get("X-Provider-Signature") != "ok" {
+		return "", errors.New("bad signature")
+	}
+	var event struct {
+		ID string `json:"id"`
+	}
+	if err := json.Unmarshal(payload, &event); err != nil || event.ID == "" {
+		return "", errors.New("missing event id")
+	}
+	return event.ID, nil
+}
+
+func (p *fakeProvider) Apply(_ context.Context, _ storage.Repositories, payload []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.down {
+		return errors.New("ledger unavailable")
+	}
+	p.applied = append(p.applied, string(payload))
+	return nil
+}
+
+func (p *fakeProvider) setDown(down bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.down = down
+}
+
+func (p *fakeProvider) appliedCount() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.applied)
+}
+
+// fakeGameProvider is a fakeProvider whose callbacks name a game session and
+// the player betting in it.
+type fakeGameProvider struct {
+	fakeProvider
+}
+
+func (p *fakeGameProvider) Session(payload []byte) (string, int64, float64, error) {
+	var bet struct {
+		Session string  `json:"session"`
+		UserID  int64   `json:"user_id"`
+		Amount  float64 `json:"amount"`
+	}
+	if err := json.Unmarshal(payload, &bet); err != nil {
+		return "", 0, 0, err
+	}
+	return bet.Session, bet.UserID, bet.Amount, nil
+}
+
+// TestCallbackReplayScenario loses a payment callback to an outage, has the
+// provider retry into the same outage, then replays the stored deliveries and
+// checks the event is applied exactly once.
+func TestCallbackReplayScenario(t *testing.T) {
+	provider := &fakeProvider{}
+	a := newApp(t, server.WithProcessor("acme", provider))
+	_, adminToken := a.registerAs("ops", 10, models.AdminUser)
+	_, staffToken := a.registerAs("support", 11, models.StaffUser)
+	signed := http.Header{"X-Provider-Signature": {"ok"}}
+	event := map[string]any{"id": "evt_1", "type": "deposit.succeeded", "amount": 500}
+
+	if status, _ := a.doWithHeader(http.MethodPost, "/integrations/acme/callbacks", "", event, http.Header{"X-Provider-Signature": {"forged"}}); status != http.StatusUnauthorized {
+		t.Fatalf("unsigned callback: status %d, want 401", status)
+	}
+	if status, _ := a.doWithHeader(http.MethodPost, "/integrations/other/callbacks", "", event, signed); status != http.StatusNotFound {
+		t.Fatalf("unknown provider: status %d, want 404", status)
+	}
+
+	provider.setDown(true)
+	for range 2 {
+		if status, _ := a.doWithHeader(http.MethodPost, "/integrations/acme/callbacks", "", event, signed); status != http.StatusInternalServerError {
+			t.Fatalf("callback during outage: status %d, want 500", status)
+		}
+	}
+	provider.setDown(false)
+
+	var failed []models.InboundDelivery
+	a.mustCall(http.StatusOK, http.MethodGet, "/admin/integrations/deliveries?provider=acme&status=failed", adminToken, nil, &failed)
+	if len(failed) != 2 {
+		t.Fatalf("failed deliveries = %d, want 2 (the forged callback must not be stored)", len(failed))
+	}
+	if d := failed[0]; d.EventID != "evt_1" || d.Error == "" || !strings.Contains(d.Payload, "deposit.succeeded") || d.Headers["X-Provider-Signature"] != "ok" {
+		t.Fatalf("stored delivery = %+v", d)
+	}
+
+	var replayed models.InboundDelivery
+	a.mustCall(http.StatusOK, http.MethodPost, fmt.Sprintf("/admin/integrations/deliveries/%d/replay", failed[1].ID), adminToken, nil, &replayed)
+	if replayed.Status != models.InboundProcessed || replayed.Attempts != 2 {
+		t.Fatalf("replayed delivery = %+v, want processed after 2 attempts", replayed)
+	}
+	if status, _ := a.call(http.MethodPost, fmt.Sprintf("/admin/integrations/deliveries/%d/replay", failed[0].ID), adminToken, nil); status != http.StatusConflict {
+		t.Fatalf("replay of an applied event: status %d, want 409", status)
+	}
+	if status, _ := a.doWithHeader(http.MethodPost, "/integrations/acme/callbacks", "", event, signed); status != http.StatusOK {
+		t.Fatalf("late provider retry: status %d, want 200", status)
+	}
+	if n := provider.appliedCount(); n != 1 {
+		t.Fatalf("event applied %d times, want 1", n)
+	}
+
+	var duplicates []models.InboundDelivery
+	a.mustCall(http.StatusOK, http.MethodGet, "/admin/integrations/deliveries?event_id=evt_1&status=duplicate", adminToken, nil, &duplicates)
+	if len(duplicates) != 2 {
+		t.Fatalf("duplicate deliveries = %d, want 2", len(duplicates))
+	}
+	if status, _ := a.call(http.MethodGet, "/admin/integrations/deliveries", staffToken, nil); status != http.StatusForbidden {
+		t.Fatalf("staff listing: status %d, want 403", status)
+	}
+}
+
+// TestPaymentDepositScenario starts a sandbox deposit and settles it with a
+// signed callback, which credits the wallet once however often it arrives.
+func TestPaymentDepositScenario(t *testing.T) {
+	sandbox := payments.NewSandbox("sandbox-secret")
+	a := newApp(t, server.WithPaymentProvider("sandbox", sandbox))
+	ana, token := a.registerAs("ana", 20, models.NormalUser)
+
+	if status, _ := a.call(http.MethodPost, "/payments/acme/deposits", token, map[string]any{"amount": 25}); status != http.StatusNotFound {
+		t.Fatalf("deposit at an unknown provider: status %d, want 404", status)
+	}
+	if status, _ := a.call(http.MethodPost, "/payments/sandbox/deposits", token, map[string]any{"amount": -5}); status != http.StatusBadRequest {
+		t.Fatalf("negative deposit: status %d, want 400", status)
+	}
+	if status, _ := a.doWithHeader(http.MethodPost, "/payments/sandbox/deposits", token, map[string]any{"amount": 25}, http.Header{"Cf-Ipcountry": {"KP"}}); status != http.StatusUnavailableForLegalReasons {
+		t.Fatalf("deposit from a blocked country: status %d, want 451", status)
+	}
+	var intent payments.Intent
+	a.mustCall(http.StatusCreated, http.MethodPost, "/payments/sandbox/deposits", token, map[string]any{"amount": 25}, &intent)
+	if intent.Reference == "" || !strings.Contains(intent.RedirectURL, intent.Reference) {
+		t.Fatalf("deposit intent = %+v", intent)
+	}
+	var me models.User
+	a.mustCall(http.StatusOK, http.MethodGet, "/me", token, nil, &me)
+	if me.Balance != initBalance {
+		t.Fatalf("balance before settlement = %v, want %v", me.Balance, float64(initBalance))
+	}
+
+	payload, _ := json.Marshal(map[string]any{"id": "evt_1", "type": payments.EventDepositSucceeded, "reference": intent.Reference, "user_id": ana.ID, "amount": 25})
+	if status, _ := a.doWithHeader(http.MethodPost, "/integrations/sandbox/callbacks", "", json.RawMessage(payload), http.Header{payments.SandboxSignatureHeader: {"forged"}}); status != http.StatusUnauthorized {
+		t.Fatalf("forged callback: status %d, want 401", status)
+	}
+	signed := http.Header{payments.SandboxSignatureHeader: {sandbox.Sign(payload)}}
+	for range 2 {
+		if status, _ := a.doWithHeader(http.MethodPost, "/integrations/sandbox/callbacks", "", json.RawMessage(payload), signed); status != http.StatusOK {
+			t.Fatalf("signed callback: status %d, want 200", status)
+		}
+	}
+	a.mustCall(http.StatusOK, http.MethodGet, "/me", token, nil, &me)
+	if me.Balance != initBalance+25 {
+		t.Fatalf("balance after settlement = %v, want one credit of 25", me.Balance)
+	}
+}
+
+// TestWithdrawalApprovalScenario holds large withdrawals for review: the
+// amount is reserved while pending, returned on rejection, and paid out on
+// approval.
+func TestWithdrawalApprovalScenario(t *testing.T) {
+	sandbox := payments.NewSandbox("sandbox-secret")
+	a := newApp(t, server.WithPaymentProvider("sandbox", sandbox))
+	_, token := a.registerAs("ana", 20, models.NormalUser)
+	_, adminToken := a.registerAs("ops", 10, models.AdminUser)
+	_, staffToken := a.registerAs("support", 11, models.StaffUser)
+	withdraw := func(token string, amount float64) (int, models.WithdrawalRequest) {
+		t.Helper()
+		var request models.WithdrawalRequest
+		status, data := a.call(http.MethodPost, "/payments/sandbox/withdrawals", token, map[string]any{"amount": amount})
+		_ = json.Unmarshal(data, &request)
+		return status, request
+	}
+	balance := func() float64 {
+		t.Helper()
+		var me models.User
+		a.mustCall(http.StatusOK, http.MethodGet, "/me", token, nil, &me)
+		return me.Balance
+	}
+
+	if status, small := withdraw(token, 100); status != http.StatusCreated || small.Status != models.WithdrawalApproved {
+		t.Fatalf("withdrawal below the threshold: status %d, %+v; want 201 approved", status, small)
+	}
+	status, large := withdraw(token, 600)
+	if status != http.StatusCreated || large.Status != models.WithdrawalPending {
+		t.Fatalf("withdrawal above the threshold: status %d, %+v; want 201 pending", status, large)
+	}
+	if got := balance(); got != initBalance-700 {
+		t.Fatalf("balance with 700 held = %v", got)
+	}
+	if status, _ := withdraw(token, 400); status != http.StatusConflict {
+		t.Fatalf("withdrawal of held funds: status %d, want 409", status)
+	}
+
+	if status, _ := a.call(http.MethodGet, "/admin/withdrawals", staffToken, nil); status != http.StatusForbidden {
+		t.Fatalf("staff listing withdrawals: status %d, want 403", status)
+	}
+	var pending []models.WithdrawalRequest
+	a.mustCall(http.StatusOK, http.MethodGet, "/admin/withdrawals?status=pending", adminToken, nil, &pending)
+	if len(pending) != 1 || pending[0].ID != large.ID {
+		t.Fatalf("pending withdrawals = %+v, want the large one", pending)
+	}
+	reject := fmt.Sprintf("/admin/withdrawals/%d/reject", large.ID)
+	if status, _ := a.call(http.MethodPost, reject, adminToken, map[string]any{}); status != http.StatusBadRequest {
+		t.Fatalf("rejection without a note: status %d, want 400", status)
+	}
+	a.mustCall(http.StatusOK, http.MethodPost, reject, adminToken, map[string]any{"note": "verify your identity first"}, nil)
+	if got := balance(); got != initBalance-100 {
+		t.Fatalf("balance after the rejection = %v, want the 600 returned", got)
+	}
+	if status, _ := a.call(http.MethodPost, fmt.Sprintf("/admin/withdrawals/%d/approve", large.ID), adminToken, nil); status != http.StatusConflict {
+		t.Fatalf("approving a rejected withdrawal: status %d, want 409", status)
+	}
+
+	_, large = withdraw(token, 600)
+	var approved models.WithdrawalRequest
+	a.mustCall(http.StatusOK, http.MethodPost, fmt.Sprintf("/admin/withdrawals/%d/approve", large.ID), adminToken, nil, &approved)
+	if approved.Status != models.WithdrawalApproved || approved.ReviewedBy == nil {
+		t.Fatalf("approved withdrawal = %+v", approved)
+	}
+	payload, _ := json.Marshal(map[string]any{"id": "evt_paid", "type": payments.EventPayoutSucceeded, "reference": large.Reference})
+	if status, _ := a.doWithHeader(http.MethodPost, "/integrations/sandbox/callbacks", "", json.RawMessage(payload), http.Header{payments.SandboxSignatureHeader: {sandbox.Sign(payload)}}); status != http.StatusOK {
+		t.Fatalf("payout callback: status %d, want 200", status)
+	}
+	var mine []models.WithdrawalRequest
+	a.mustCall(http.StatusOK, http.MethodGet, "/me/withdrawals", token, nil, &mine)
+	if len(mine) != 3 || mine[0].ID != large.ID || mine[0].Status != models.WithdrawalPaid || mine[1].Status != models.WithdrawalRejected {
+		t.Fatalf("own withdrawals = %+v, want paid, rejected and approved", mine)
+	}
+	if got := balance(); got != initBalance-700 {
+		t.Fatalf("balance after the payout = %v", got)
+	}
+
+	_, own := withdraw(adminToken, 500)
+	if status, _ := a.call(http.MethodPost, fmt.Sprintf("/admin/withdrawals/%d/approve", own.ID), adminToken, nil); status != http.StatusForbidden {
+		t.Fatalf("approving one's own withdrawal: status %d, want 403", status)
+	}
+}
+
+// TestRoleManagementScenario builds a new role at runtime, assigns it, and
+// checks that grants and revocations apply to tokens issued after them, while
+// earlier tokens of the role's users are refused.
+func TestRoleManagementScenario(t *testing.T) {
+	a := newApp(t)
+	_, adminToken := a.registerAs("root", 10, models.AdminUser)
+	_, staffToken := a.registerAs("support", 11, models.StaffUser)
+
+	var refund models.Permission
+	a.mustCall(http.StatusCreated, http.MethodPost, "/admin/permissions", adminToken,
+		map[string]any{"name": "cashier:refund", "description": "Refund deposits"}, &refund)
+	var cashier models.Role
+	a.mustCall(http.StatusCreated, http.MethodPost, "/admin/roles", adminToken,
+		map[string]any{"role": "cashier", "description": "Cashier desk", "permissions": []string{"cashier:refund", models.PermStatsRead}}, &cashier)
+	if len(cashier.Permissions) != 2 || cashier.ID < 100 {
+		t.Fatalf("created role = %+v", cashier)
+	}
+
+	user, token := a.registerAs("till", 12, "cashier")
+	a.mustCall(http.StatusOK, http.MethodGet, "/admin/stats", token, nil, nil)
+
+	var permissions []models.Permission
+	a.mustCall(http.StatusOK, http.MethodGet, "/admin/permissions", adminToken, nil, &permissions)
+	statsRead := permissions[slices.IndexFunc(permissions, func(p models.Permission) bool { return p.PermissionName == models.PermStatsRead })]
+	grant := fmt.Sprintf("/admin/roles/%d/permissions/%d", cashier.ID, statsRead.ID)
+	a.mustCall(http.StatusOK, http.MethodDelete, grant, adminToken, nil, nil)
+	if status, _ := a.call(http.MethodGet, "/admin/stats", token, nil); status != http.StatusForbidden {
+		t.Fatalf("stats with a token issued before the revoke: status %d, want 403", status)
+	}
+	if status, _ := a.call(http.MethodGet, "/admin/stats", a.login("till"), nil); status != http.StatusForbidden {
+		t.Fatalf("stats with a token issued after the revoke: status %d, want 403", status)
+	}
+	a.mustCall(http.StatusOK, http.MethodPut, grant, adminToken, nil, nil)
+	token = a.login("till")
+	a.mustCall(http.StatusOK, http.MethodGet, "/admin/stats", token, nil, nil)
+
+	a.mustCall(http.StatusOK, http.MethodPatch, fmt.Sprintf("/admin/roles/%d", cashier.ID), adminToken, map[string]any{"role": "teller"}, nil)
+	if status, _ := a.call(http.MethodGet, "/me", token, nil); status != http.StatusForbidden {
+		t.Fatalf("/me with a token naming the old role: status %d, want 403", status)
+	}
+	token = a.login("till")
+	var me models.User
+	a.mustCall(http.StatusOK, http.MethodGet, "/me", token, nil, &me)
+	if me.ID != user.ID || me.Role != "teller" || !slices.Contains(me.Permissions, "cashier:refund") {
+		t.Fatalf("/me after rename = %+v", me)
+	}
+
+	for name, tc := range map[string]struct {
+		method, path string
+		body         any
+	}{
+		"delete role in use":   {http.MethodDelete, fmt.Sprintf("/admin/roles/%d", cashier.ID), nil},
+		"delete built-in role": {http.MethodDelete, "/admin/roles/1", nil},
+		"rename built-in":      {http.MethodPatch, "/admin/permissions/1", map[string]any{"name": "game:run"}},
+		"admin lockout":        {http.MethodDelete, fmt.Sprintf("/admin/roles/5/permissions/%d", 11), nil},
+		"duplicate permission": {http.MethodPost, "/admin/permissions", map[string]any{"name": "cashier:refund"}},
+	} {
+		if status, _ := a.call(tc.method, tc.path, adminToken, tc.body); status != http.StatusConflict {
+			t.Errorf("%s: status %d, want 409", name, status)
+		}
+	}
+
+	a.mustCall(http.StatusOK, http.MethodDelete, fmt.Sprintf("/admin/permissions/%d", refund.ID), adminToken, nil, nil)
+	a.mustCall(http.StatusOK, http.MethodGet, "/me", a.login("till"), nil, &me)
+	if slices.Contains(me.Permissions, "cashier:refund") {
+		t.Fatalf("deleted permission still granted: %v", me.Permissions)
+	}
+
+	var history []models.ConfigChange
+	a.mustCall(http.StatusOK, http.MethodGet, "/admin/config/history?entity=role", adminToken, nil, &history)
+	if len(history) != 4 {
+		t.Fatalf("role history entries = %d, want 4 (create, revoke, grant, rename)", len(history))
+	}
+	if status, _ := a.call(http.MethodGet, "/admin/roles", staffToken, nil); status != http.StatusForbidden {
+		t.Fatalf("staff listing roles: status %d, want 403", status)
+	}
+}
+
+// TestPermissionOverrideScenario has support grant a bonus permission to one
+// player and checks the limits on what non-admins may override.
+func TestPermissionOverrideScenario(t *testing.T) {
+	a := newApp(t)
+	_, adminToken := a.registerAs("root", 10, models.AdminUser)
+	support, supportToken := a.registerAs("support", 11, models.StaffUser)
+	colleague, _ := a.registerAs("colleague", 12, models.StaffUser)
+	player, playerToken := a.registerAs("lucky", 13, models.NormalUser)
+	const gamePlay, bonusClaim, configManage = 1, 2, 6
+	override := func(userID int64, permissionID int) string {
+		return fmt.Sprintf("/admin/users/%d/permissions/%d", userID, permissionID)
+	}
+
+	a.mustCall(http.StatusOK, http.MethodPut, override(player.ID, bonusClaim), supportToken, map[string]any{"allow": true}, nil)
+	if status, _ := a.call(http.MethodGet, "/me", playerToken, nil); status != http.StatusForbidden {
+		t.Fatalf("/me with a token issued before the grant: status %d, want 403", status)
+	}
+	playerToken = a.login("lucky")
+	var me models.User
+	a.mustCall(http.StatusOK, http.MethodGet, "/me", playerToken, nil, &me)
+	if me.Role != models.NormalUser || !slices.Equal(me.Permissions, []string{models.PermGamePlay, models.PermBonusClaim}) {
+		t.Fatalf("/me after grant = %+v", me)
+	}
+
+	for name, tc := range map[string]struct {
+		userID       int64
+		permissionID int
+	}{
+		"staff permission": {player.ID, configManage},
+		"staff member":     {colleague.ID, bonusClaim},
+		"own permissions":  {support.ID, bonusClaim},
+	} {
+		if status, _ := a.call(http.MethodPut, override(tc.userID, tc.permissionID), supportToken, map[string]any{"allow": true}); status != http.StatusForbidden {
+			t.Errorf("%s: status %d, want 403", name, status)
+		}
+	}
+
+	a.mustCall(http.StatusOK, http.MethodPut, override(player.ID, gamePlay), adminToken, map[string]any{"allow": false}, nil)
+	var perms struct {
+		Permissions []string                    `json:"permissions"`
+		Overrides   []models.PermissionOverride `json:"overrides"`
+	}
+	a.mustCall(http.StatusOK, http.MethodGet, fmt.Sprintf("/admin/users/%d/permissions", player.ID), supportToken, nil, &perms)
+	if !slices.Equal(perms.Permissions, []string{models.PermBonusClaim}) || len(perms.Overrides) != 2 || perms.Overrides[0].Allow || perms.Overrides[0].SetBy == support.ID {
+		t.Fatalf("permissions after deny = %+v", perms)
+	}
+
+	a.mustCall(http.StatusOK, http.MethodDelete, override(player.ID, gamePlay), adminToken, nil, nil)
+	playerToken = a.login("lucky")
+	a.mustCall(http.StatusOK, http.MethodGet, "/me", playerToken, nil, &me)
+	if !slices.Contains(me.Permissions, models.PermGamePlay) {
+		t.Fatalf("cleared deny still applies: %v", me.Permissions)
+	}
+	if status, _ := a.call(http.MethodGet, fmt.Sprintf("/admin/users/%d/permissions", player.ID), playerToken, nil); status != http.StatusForbidden {
+		t.Fatalf("player listing overrides: status %d, want 403", status)
+	}
+}
+
+func TestLoginHistoryScenario(t *testing.T) {
+	a := newApp(t)
+	_, supportToken := a.registerAs("support", 20, models.StaffUser)
+	player, playerToken := a.registerAs("lucky", 21, models.NormalUser)
+
+	for _, identifier := range []string{"lucky", "nobody"} {
+		if status, _ := a.call(http.MethodPost, "/login", "", map[string]string{"identifier": identifier, "password": "wrong-password"}); status != http.StatusUnauthorized {
+			t.Fatalf("login as %s with a wrong password: status %d", identifier, status)
+		}
+	}
+
+	var mine []models.LoginAttempt
+	a.mustCall(http.StatusOK, http.MethodGet, "/me/logins", playerToken, nil, &mine)
+	if len(mine) != 2 || mine[0].Success || mine[0].FailureReason != models.LoginInvalidPassword || !mine[1].Success {
+		t.Fatalf("/me/logins = %+v, want the failed attempt then the successful one", mine)
+	}
+	if mine[1].UserID == nil || *mine[1].UserID != player.ID || mine[1].IP == "" {
+		t.Fatalf("successful attempt = %+v", mine[1])
+	}
+
+	var failures []models.LoginAttempt
+	a.mustCall(http.StatusOK, http.MethodGet, "/admin/logins?success=false", supportToken, nil, &failures)
+	if len(failures) != 2 || failures[0].Identifier != "nobody" || failures[0].UserID != nil || failures[0].FailureReason != models.LoginUnknownUser {
+		t.Fatalf("failed attempts = %+v", failures)
+	}
+	var players []models.LoginAttempt
+	a.mustCall(http.StatusOK, http.MethodGet, fmt.Sprintf("/admin/logins?user_id=%d", player.ID), supportToken, nil, &players)
+	if len(players) != 2 {
+		t.Fatalf("attempts for user %d = %+v", player.ID, players)
+	}
+	if status, _ := a.call(http.MethodGet, "/admin/logins", playerToken, nil); status != http.StatusForbidden {
+		t.Fatalf("player searching login history: status %d, want 403", status)
+	}
+}
+
+// TestJurisdictionScenario refuses sign-ups from a blocked country and
+// records where configuration changes were made from.
+func TestJurisdictionScenario(t *testing.T) {
+	a := newApp(t)
+	_, adminToken := a.registerAs("ops", 1, models.AdminUser)
+
+	signup := map[string]string{
+		"username": "dave", "email": "dave@example.com", "phone": "+12025550002", "password": "correct-horse-battery",
+	}
+	if status, _ := a.doWithHeader(http.MethodPost, "/register", "", signup, http.Header{"Cf-Ipcountry": {"KP"}}); status != http.StatusUnavailableForLegalReasons {
+		t.Fatalf("registration from a blocked country: status %d, want 451", status)
+	}
+	if status, _ := a.doWithHeader(http.MethodPost, "/register", "", signup, http.Header{"Cf-Ipcountry": {"MY"}}); status != http.StatusCreated {
+		t.Fatalf("registration from an allowed country: status %d, want 201", status)
+	}
+	if status, _ := a.doWithHeader(http.MethodPost, "/login", "", map[string]string{
+		"identifier": "dave", "password": "correct-horse-battery",
+	}, http.Header{"Cf-Ipcountry": {"KP"}}); status != http.StatusOK {
+		t.Fatalf("login from a blocked country: status %d, want 200", status)
+	}
+
+	if status, body := a.doWithHeader(http.MethodPut, "/admin/rate-limits", adminToken, map[string]any{
+		"route_class": models.RouteClassAPI, "requests": 50, "window_seconds": 60,
+	}, http.Header{"Cf-Ipcountry": {"sg"}}); status != http.StatusOK {
+		t.Fatalf("save rate limit: status %d: %s", status, body)
+	}
+	var history []models.ConfigChange
+	a.mustCall(http.StatusOK, http.MethodGet, "/admin/config/history", adminToken, nil, &history)
+	if len(history) != 1 || history[0].Country != "SG" {
+		t.Fatalf("history = %+v", history)
+	}
+}
+
+// TestBigWinsScenario lists large bet settlements on the public feed, named or
+// masked by each winner's privacy settings.
+func TestBigWinsScenario(t *testing.T) {
+	a := newApp(t)
+	settle := func(user models.User, amount float64) {
+		t.Helper()
+		if _, err := a.store.ApplyTransaction(context.Background(), models.Transaction{UserID: user.ID, Amount: amount, Reason: models.TransactionBetSettlement}); err != nil {
+			t.Fatalf("settle bet for %s: %v", user.Username, err)
+		}
+	}
+	erin, frank, gina := a.register("erin", 1), a.register("frank", 2), a.register("gina", 3)
+	a.mustCall(http.StatusOK, http.MethodPut, "/me/privacy", a.login("frank"), map[string]string{"public_activity": models.ActivityPublic}, nil)
+	ginaToken := a.login("gina")
+	a.mustCall(http.StatusOK, http.MethodPut, "/me/privacy", ginaToken, map[string]string{"public_activity": models.ActivityHidden}, nil)
+	if status, _ := a.call(http.MethodPut, "/me/privacy", ginaToken, map[string]string{"public_activity": "friends"}); status != http.StatusBadRequest {
+		t.Fatalf("unknown visibility: status %d, want 400", status)
+	}
+	settle(erin, 1200)
+	settle(frank, 800)
+	settle(gina, 5000)
+	settle(erin, 20)
+
+	var wins []models.BigWin
+	a.mustCall(http.StatusOK, http.MethodGet, "/public/big-wins", "", nil, &wins)
+	if len(wins) != 2 || wins[0].Player != "frank" || wins[0].Amount != 800 || wins[1].Player != "e***n" {
+		t.Fatalf("big wins = %+v", wins)
+	}
+}
+
+// TestDataExportScenario requests a copy of a player's data, waits for the
+// archive job and downloads the ZIP through the signed link.
+func TestDataExportScenario(t *testing.T) {
+	a := newApp(t)
+	a.register("hana", 1)
+	token := a.login("hana")
+	a.register("ivan", 2)
+
+	var started models.DataExport
+	a.mustCall(http.StatusAccepted, http.MethodPost, "/me/export", token, nil, &started)
+	if started.Status != models.ExportPending {
+		t.Fatalf("started export = %+v", started)
+	}
+	path := fmt.Sprintf("/me/export/%d", started.ID)
+	if status, _ := a.call(http.MethodGet, path, a.login("ivan"), nil); status != http.StatusNotFound {
+		t.Fatalf("other user's export: status %d, want 404", status)
+	}
+
+	var export models.DataExport
+	eventually(t, "export ready", func() bool {
+		a.mustCall(http.StatusOK, http.MethodGet, path, token, nil, &export)
+		return export.Status == models.ExportReady
+	})
+	resp, err := http.Get(a.url + strings.TrimPrefix(export.DownloadURL, "http://blobs.invalid"))
+	if err != nil {
+		t.Fatalf("download export: %v", err)
+	}
+	defer resp.Body.Close()
+	archive, err := io.ReadAll(resp.Body)
+	if err != nil || resp.StatusCode != http.StatusOK {
+		t.Fatalf("download export: status %d, %v", resp.StatusCode, err)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+	if err != nil {
+		t.Fatalf("open export: %v", err)
+	}
+	var files []string
+	for _, f := range zr.File {
+		files = append(files, f.Name)
+	}
+	for _, want := range []string{"profile.json", "transactions.json", "login_history.json", "audit_trail.json"} {
+		if !slices.Contains(files, want) {
+			t.Fatalf("export files = %v, missing %s", files, want)
+		}
+	}
+
+	var exports []models.DataExport
+	a.mustCall(http.StatusOK, http.MethodGet, "/me/export", token, nil, &exports)
+	if len(exports) != 1 || exports[0].DownloadURL == "" {
+		t.Fatalf("exports = %+v", exports)
+	}
+}
+
+// TestReconciliationScenario edits a balance behind the ledger's back and
+// checks a reconciliation run reports it, through the API and /metrics.
+func TestReconciliationScenario(t *testing.T) {
+	a := newApp(t)
+	_, adminToken := a.registerAs("auditor", 1, models.AdminUser)
+	jade, kim := a.register("jade", 2), a.register("kim", 3)
+	a.register("lee", 4)
+	for _, entry := range []models.Transaction{
+		{UserID: jade.ID, Amount: 250, Reason: models.TransactionDeposit},
+		{UserID: jade.ID, Amount: -100, Reason: models.TransactionWithdrawal},
+		{UserID: kim.ID, Amount: 40, Reason: models.TransactionBetSettlement},
+	} {
+		if _, err := a.store.ApplyTransaction(context.Background(), entry); err != nil {
+			t.Fatalf("apply %+v: %v", entry, err)
+		}
+	}
+	if err := a.store.SetBalance(kim.ID, initBalance+90); err != nil {
+		t.Fatal(err)
+	}
+
+	var report models.ReconciliationReport
+	a.mustCall(http.StatusOK, http.MethodPost, "/admin/reconciliation/run", adminToken, nil, &report)
+	// Every account's welcome bonus is in the ledger, so all four are checked.
+	if report.UsersChecked != 4 || report.Mismatches != 1 || len(report.Discrepancies) != 1 {
+		t.Fatalf("report = %+v", report)
+	}
+	if d := report.Discrepancies[0]; d.UserID != kim.ID || d.LedgerBalance != initBalance+40 || d.Difference != 50 {
+		t.Fatalf("discrepancy = %+v", d)
+	}
+
+	var reports []models.ReconciliationReport
+	a.mustCall(http.StatusOK, http.MethodGet, "/admin/reconciliation-reports", adminToken, nil, &reports)
+	if len(reports) != 1 || reports[0].ID != report.ID {
+		t.Fatalf("reports = %+v", reports)
+	}
+	if status, _ := a.call(http.MethodPost, "/admin/reconciliation/run", a.login("jade"), nil); status != http.StatusForbidden {
+		t.Fatalf("player running reconciliation: status %d, want 403", status)
+	}
+	_, metrics := a.do(http.MethodGet, "/metrics", "", nil)
+	if !strings.Contains(string(metrics), "balance_reconciliation_mismatches 1\n") {
+		t.Fatalf("metrics missing mismatch count:\n%s", metrics)
+	}
+}
+
+// TestOperatorReportScenario has an admin ask for today's report and fetches
+// the CSV from the emailed link once the background job has built it.
+func TestOperatorReportScenario(t *testing.T) {
+	a := newApp(t)
+	_, adminToken := a.registerAs("owner", 1, models.AdminUser)
+	nora, noraToken := a.registerAs("nora", 2, models.NormalUser)
+	for _, entry := range []models.Transaction{
+		{UserID: nora.ID, Amount: 300, Reason: models.TransactionDeposit},
+		{UserID: nora.ID, Amount: -80, Reason: models.TransactionBetSettlement},
+	} {
+		if _, err := a.store.ApplyTransaction(context.Background(), entry); err != nil {
+			t.Fatalf("apply %+v: %v", entry, err)
+		}
+	}
+
+	for _, body := range []map[string]string{
+		{"period": "monthly", "from": "2026-03-14"},
+		{"period": "daily", "from": "14/03/2026"},
+		{"period": "daily", "from": "2026-03-15"},
+		{"period": "custom", "from": "2026-03-14", "to": "2026-03-01"},
+	} {
+		if status, _ := a.call(http.MethodPost, "/admin/reports", adminToken, body); status != http.StatusBadRequest {
+			t.Fatalf("report %v: status %d, want 400", body, status)
+		}
+	}
+	if status, _ := a.call(http.MethodPost, "/admin/reports", noraToken, map[string]string{"period": "daily", "from": "2026-03-14"}); status != http.StatusForbidden {
+		t.Fatalf("player without stats:read: status %d, want 403", status)
+	}
+
+	var started models.OperatorReport
+	a.mustCall(http.StatusAccepted, http.MethodPost, "/admin/reports", adminToken, map[string]string{"period": "daily", "from": "2026-03-14"}, &started)
+	var report models.OperatorReport
+	eventually(t, "report ready", func() bool {
+		a.mustCall(http.StatusOK, http.MethodGet, fmt.Sprintf("/admin/reports/%d", started.ID), adminToken, nil, &report)
+		return report.Status == models.ReportReady
+	})
+	want := models.ReportMetrics{Signups: 2, GGR: 80, Deposits: 1, DepositAmount: 300}
+	if report.Metrics == nil || *report.Metrics != want {
+		t.Fatalf("report = %+v, want metrics %+v", report, want)
+	}
+
+	email := waitForEmail(t, a, "owner@example.com", "daily report for 2026-03-14")
+	if !strings.Contains(email.Body, "GGR:          80.00") {
+		t.Fatalf("report email = %q", email.Body)
+	}
+	resp, err := http.Get(a.url + strings.TrimPrefix(report.CSVURL, "http://blobs.invalid"))
+	if err != nil {
+		t.Fatalf("download report: %v", err)
+	}
+	defer resp.Body.Close()
+	csvFile, err := io.ReadAll(resp.Body)
+	if err != nil || resp.StatusCode != http.StatusOK || !bytes.Contains(csvFile, []byte("daily,2026-03-14T00:00:00Z,2026-03-15T00:00:00Z,2,80.00,1,300.00,0,0.00")) {
+		t.Fatalf("download report: status %d, %q, %v", resp.StatusCode, csvFile, err)
+	}
+
+	var list []models.OperatorReport
+	a.mustCall(http.StatusOK, http.MethodGet, "/admin/reports?limit=5", adminToken, nil, &list)
+	if len(list) != 1 || list[0].ID != started.ID || list[0].PDFURL == "" {
+		t.Fatalf("reports = %+v", list)
+	}
+}
+
+// TestLeaderboardScenario ranks players by winnings and games played from a
+// refreshed snapshot, respecting each player's privacy settings.
+func TestLeaderboardScenario(t *testing.T) {
+	a := newApp(t)
+	a.registerAs("croupier", 1, models.StaffUser)
+	mia, noah, olga := a.register("mia", 2), a.register("noah", 3), a.register("olga", 4)
+	settle := func(user models.User, amount float64) {
+		t.Helper()
+		if _, err := a.store.ApplyTransaction(context.Background(), models.Transaction{UserID: user.ID, Amount: amount, Reason: models.TransactionBetSettlement}); err != nil {
+			t.Fatalf("settle bet for %s: %v", user.Username, err)
+		}
+	}
+	settle(mia, 300)
+	a.clock.Advance(3 * 24 * time.Hour)
+	settle(noah, 200)
+	settle(noah, -50)
+	settle(olga, 100)
+	a.mustCall(http.StatusOK, http.MethodPut, "/me/privacy", a.login("noah"), map[string]string{"public_activity": models.ActivityPublic}, nil)
+	if err := a.store.RefreshLeaderboards(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	settle(olga, 1000) // after the refresh, so not ranked yet
+
+	token := a.login("olga")
+	var board models.Leaderboard
+	a.mustCall(http.StatusOK, http.MethodGet, "/leaderboard?metric=winnings&window=all-time", token, nil, &board)
+	if len(board.Entries) != 3 || board.Entries[0].Player != "m***" || board.Entries[1].Player != "noah" || board.Entries[2].Value != 100 {
+		t.Fatalf("all-time winnings = %+v", board.Entries)
+	}
+	if board.Me == nil || board.Me.Rank != 3 || board.Me.Player != "olga" {
+		t.Fatalf("own standing = %+v", board.Me)
+	}
+
+	a.mustCall(http.StatusOK, http.MethodGet, "/leaderboard?metric=games_played&window=daily", token, nil, &board)
+	if len(board.Entries) != 2 || board.Entries[0].Player != "noah" || board.Entries[0].Value != 2 {
+		t.Fatalf("daily games = %+v", board.Entries)
+	}
+
+	a.mustCall(http.StatusOK, http.MethodPut, "/me/privacy", token, map[string]string{"public_activity": models.ActivityHidden}, nil)
+	a.mustCall(http.StatusOK, http.MethodGet, "/leaderboard?metric=balance", token, nil, &board)
+	if board.Me != nil || len(board.Entries) != 2 || board.Entries[0].Rank != 1 {
+		t.Fatalf("balance board for hidden player = %+v, me %+v", board.Entries, board.Me)
+	}
+	if status, _ := a.call(http.MethodGet, "/leaderboard?metric=balance&window=daily", token, nil); status != http.StatusBadRequest {
+		t.Fatalf("daily balance: status %d, want 400", status)
+	}
+}
+
+// TestOIDCScenario signs a player into a companion app: staff register the
+// client, /authorize sends anonymous callers to the login page and signed-in
+// ones back with a code, and /token trades the code and PKCE verifier for
+// tokens that /userinfo accepts.
+func TestOIDCScenario(t *testing.T) {
+	a := newApp(t)
+	_, adminToken := a.registerAs("boss", 1, models.AdminUser)
+	pia := a.register("pia", 2)
+	token := a.login("pia")
+
+	var client models.OAuthClient
+	a.mustCall(http.StatusCreated, http.MethodPost, "/admin/oauth-clients", adminToken, map[string]any{
+		"name": "Companion", "redirect_uris": []string{"https://companion.invalid/callback"}, "confidential": true,
+	}, &client)
+	if client.ID == "" || client.Secret == "" {
+		t.Fatalf("created client = %+v", client)
+	}
+	if status, _ := a.call(http.MethodPost, "/admin/oauth-clients", token, map[string]any{"name": "x", "redirect_uris": []string{"https://x.invalid"}}); status != http.StatusForbidden {
+		t.Fatalf("player registering a client: status %d, want 403", status)
+	}
+
+	var discovery map[string]any
+	if status, raw := a.do(http.MethodGet, "/.well-known/openid-configuration", "", nil); status != http.StatusOK || json.Unmarshal(raw, &discovery) != nil || discovery["token_endpoint"] != "http://api.invalid/token" {
+		t.Fatalf("discovery: status %d, body %s", status, raw)
+	}
+	var jwks struct {
+		Keys []struct {
+			Kid string `json:"kid"`
+			N   string `json:"n"`
+		} `json:"keys"`
+	}
+	if status, raw := a.do(http.MethodGet, "/.well-known/jwks.json", "", nil); status != http.StatusOK || json.Unmarshal(raw, &jwks) != nil || len(jwks.Keys) != 1 {
+		t.Fatalf("jwks: status %d, body %s", status, raw)
+	}
+
+	noRedirects := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	authorize := func(bearer string, query url.Values) (int, *url.URL) {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodGet, a.url+"/authorize?"+query.Encode(), nil)
+		if bearer != "" {
+			req.Header.Set("Authorization", "Bearer "+bearer)
+		}
+		resp, err := noRedirects.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		location, _ := resp.Location()
+		return resp.StatusCode, location
+	}
+	verifier := "a-sufficiently-long-and-random-code-verifier-for-pkce"
+	challenge := sha256.Sum256([]byte(verifier))
+	query := url.Values{
+		"client_id":             {client.ID},
+		"redirect_uri":          {"https://companion.invalid/callback"},
+		"response_type":         {"code"},
+		"scope":                 {"openid profile email"},
+		"state":                 {"xyz"},
+		"nonce":                 {"n-0S6"},
+		"code_challenge":        {base64.RawURLEncoding.EncodeToString(challenge[:])},
+		"code_challenge_method": {"S256"},
+	}
+
+	status, location := authorize("", query)
+	if status != http.StatusFound || location.Host != "app.invalid" || !strings.HasPrefix(location.Query().Get("return_to"), "/authorize?") {
+		t.Fatalf("anonymous authorize: status %d, location %v", status, location)
+	}
+	bad := url.Values{}
+	for k, v := range query {
+		bad[k] = v
+	}
+	bad.Set("redirect_uri", "https://evil.invalid/callback")
+	if status, _ := authorize(token, bad); status != http.StatusBadRequest {
+		t.Fatalf("unregistered redirect_uri: status %d, want 400", status)
+	}
+	bad.Set("redirect_uri", "https://companion.invalid/callback")
+	bad.Del("code_challenge")
+	if status, location := authorize(token, bad); status != http.StatusFound || location.Query().Get("error") != "invalid_request" || location.Query().Get("state") != "xyz" {
+		t.Fatalf("missing PKCE: status %d, location %v", status, location)
+	}
+
+	exchange := func(code, verifier, secret string) (int, map[string]any) {
+		t.Helper()
+		form := url.Values{
+			"grant_type":    {"authorization_code"},
+			"code":          {code},
+			"redirect_uri":  {"https://companion.invalid/callback"},
+			"code_verifier": {verifier},
+		}
+		req, _ := http.NewRequest(http.MethodPost, a.url+"/token", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.SetBasicAuth(client.ID, secret)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		var body map[string]any
+		_ = json.NewDecoder(resp.Body).Decode(&body)
+		return resp.StatusCode, body
+	}
+	newCode := func() string {
+		t.Helper()
+		status, location := authorize(token, query)
+		if status != http.StatusFound || location.Host != "companion.invalid" || location.Query().Get("state") != "xyz" || location.Query().Get("code") == "" {
+			t.Fatalf("authorize: status %d, location %v", status, location)
+		}
+		return location.Query().Get("code")
+	}
+
+	if status, body := exchange(newCode(), "not-the-verifier", client.Secret); status != http.StatusBadRequest || body["error"] != "invalid_grant" {
+		t.Fatalf("wrong verifier: status %d, body %v", status, body)
+	}
+	if status, body := exchange(newCode(), verifier, "wrong-secret"); status != http.StatusUnauthorized || body["error"] != "invalid_client" {
+		t.Fatalf("wrong secret: status %d, body %v", status, body)
+	}
+	code := newCode()
+	status, tokens := exchange(code, verifier, client.Secret)
+	if status != http.StatusOK || tokens["token_type"] != "Bearer" {
+		t.Fatalf("exchange: status %d, body %v", status, tokens)
+	}
+	if status, body := exchange(code, verifier, client.Secret); status != http.StatusBadRequest || body["error"] != "invalid_grant" {
+		t.Fatalf("code reuse: status %d, body %v", status, body)
+	}
+
+	idToken, _ := tokens["id_token"].(string)
+	parts := strings.Split(idToken, ".")
+	if len(parts) != 3 {
+		t.Fatalf("id_token = %q", idToken)
+	}
+	var header map[string]any
+	var claims map[string]any
+	headerJSON, _ := base64.RawURLEncoding.DecodeString(parts[0])
+	claimsJSON, _ := base64.RawURLEncoding.DecodeString(parts[1])
+	if json.Unmarshal(headerJSON, &header) != nil || json.Unmarshal(claimsJSON, &claims) != nil {
+		t.Fatalf("undecodable id_token %q", idToken)
+	}
+	if header["alg"] != "RS256" || header["kid"] != jwks.Keys[0].Kid || claims["aud"] != client.ID || claims["nonce"] != "n-0S6" ||
+		claims["iss"] != "http://api.invalid" || claims["preferred_username"] != "pia" || claims["email"] != "pia@example.com" {
+		t.Fatalf("id_token header %v, claims %v", header, claims)
+	}
+
+	accessToken, _ := tokens["access_token"].(string)
+	var info map[string]any
+	if status, raw := a.do(http.MethodGet, "/userinfo", accessToken, nil); status != http.StatusOK || json.Unmarshal(raw, &info) != nil || info["sub"] != claims["sub"] || info["email"] != "pia@example.com" {
+		t.Fatalf("userinfo: status %d, body %s", status, raw)
+	}
+	if status, _ := a.do(http.MethodGet, "/userinfo", idToken, nil); status != http.StatusUnauthorized {
+		t.Fatalf("userinfo with an id_token: status %d, want 401", status)
+	}
+	if status, _ := a.do(http.MethodGet, "/me", accessToken, nil); status != http.StatusUnauthorized {
+		t.Fatalf("API call with an OIDC access token: status %d, want 401", status)
+	}
+
+	// Once pia is merged into another account, neither their tokens nor a code
+	// issued before the merge are honoured.
+	pending := newCode()
+	main := a.register("pim", 3)
+	a.mustCall(http.StatusOK, http.MethodPost, fmt.Sprintf("/admin/users/%d/merge", main.ID), adminToken, map[string]any{"duplicate_id": pia.ID}, nil)
+	if status, _ := a.do(http.MethodGet, "/userinfo", accessToken, nil); status != http.StatusUnauthorized {
+		t.Fatalf("userinfo for a merged account: status %d, want 401", status)
+	}
+	if status, body := exchange(pending, verifier, client.Secret); status != http.StatusBadRequest || body["error"] != "invalid_grant" {
+		t.Fatalf("exchange for a merged account: status %d, body %v", status, body)
+	}
+
+	a.mustCall(http.StatusOK, http.MethodDelete, "/admin/oauth-clients/"+client.ID, adminToken, nil, nil)
+	if status, _ := authorize(token, query); status != http.StatusBadRequest {
+		t.Fatalf("authorize for a deleted client: status %d, want 400", status)
+	}
+}
+
+// fakeIPIntel flags a fixed set of addresses.
+type fakeIPIntel map[string]iprisk.Assessment
+
+func (f fakeIPIntel) Assess(_ context.Context, ip netip.Addr) (iprisk.Assessment, error) {
+	return f[ip.String()], nil
+}
+
+// TestIPRiskScenario screens a player on a VPN: sign-up is flagged under the
+// default policy, then staff block the network outright and finally ask for
+// step-up verification, which sends new devices through email confirmation.
+func TestIPRiskScenario(t *testing.T) {
+	a := newApp(t, server.WithIPIntelligence(fakeIPIntel{"203.0.113.7": {VPN: true}}))
+	_, adminToken := a.registerAs("boss", 1, models.AdminUser)
+	vpn := http.Header{"X-Forwarded-For": {"203.0.113.7"}, "X-Device-Id": {"laptop"}}
+	credentials := map[string]string{"identifier": "vic", "password": "correct-horse-battery"}
+
+	status, body := a.doWithHeader(http.MethodPost, "/register", "", map[string]string{
+		"username": "vic", "email": "vic@example.com", "phone": "+12025550002", "password": "correct-horse-battery",
+	}, vpn)
+	if status != http.StatusCreated {
+		t.Fatalf("flagged sign-up: status %d, body %s", status, body)
+	}
+	var user models.User
+	if err := json.Unmarshal(body, &struct{ Data any }{Data: &user}); err != nil {
+		t.Fatal(err)
+	}
+
+	a.mustCall(http.StatusOK, http.MethodPut, "/admin/ip-risk/policies", adminToken, map[string]string{"action": models.IPRiskBlock}, nil)
+	if status, body := a.doWithHeader(http.MethodPost, "/login", "", credentials, vpn); status != http.StatusForbidden {
+		t.Fatalf("blocked sign-in: status %d, body %s", status, body)
+	}
+	attempts, _ := a.store.ListLoginAttempts(context.Background(), models.LoginAttemptFilter{UserID: user.ID}, 1)
+	if len(attempts) != 1 || attempts[0].FailureReason != models.LoginIPBlocked {
+		t.Fatalf("latest attempt = %+v, want an ip_blocked failure", attempts)
+	}
+	a.login("vic")
+
+	a.mustCall(http.StatusOK, http.MethodPut, "/admin/ip-risk/policies", adminToken, map[string]string{"action": models.IPRiskStepUp}, nil)
+	if status, body := a.doWithHeader(http.MethodPost, "/login", "", credentials, vpn); status != http.StatusForbidden {
+		t.Fatalf("step-up sign-in from a new device: status %d, body %s", status, body)
+	}
+	waitForEmail(t, a, "vic@example.com", "Confirm your new device")
+
+	if status, _ := a.call(http.MethodPut, "/admin/ip-risk/policies", adminToken, map[string]string{"action": "shrug"}); status != http.StatusBadRequest {
+		t.Fatalf("unknown action: status %d, want 400", status)
+	}
+	var events []models.IPRiskEvent
+	a.mustCall(http.StatusOK, http.MethodGet, "/admin/ip-risk/events", adminToken, nil, &events)
+	var actions []string
+	for _, e := range events {
+		if e.IP != "203.0.113.7" || !slices.Equal(e.Signals, []string{models.SignalVPN}) {
+			t.Fatalf("event = %+v", e)
+		}
+		actions = append(actions, e.Checkpoint+":"+e.Action)
+	}
+	if want := []string{"login:step_up", "login:block", "registration:flag"}; !slices.Equal(actions, want) {
+		t.Fatalf("events = %v, want %v", actions, want)
+	}
+
+	a.mustCall(http.StatusOK, http.MethodDelete, "/admin/ip-risk/policies?country=", adminToken, nil, nil)
+	if status, _ := a.call(http.MethodDelete, "/admin/ip-risk/policies", adminToken, nil); status != http.StatusNotFound {
+		t.Fatalf("deleting a missing policy: status %d, want 404", status)
+	}
+}
+
+// TestPromoScenario issues a campaign code, has two players redeem it until it
+// runs out, and checks the campaign report and ledger.
+func TestPromoScenario(t *testing.T) {
+	a := newApp(t)
+	_, adminToken := a.registerAs("boss", 1, models.AdminUser)
+	ana := a.register("ana", 2)
+	anaToken := a.login("ana")
+	_, benToken := a.registerAs("ben", 3, models.NormalUser)
+	_, catToken := a.registerAs("cat", 4, models.NormalUser)
+
+	var code models.PromoCode
+	a.mustCall(http.StatusCreated, http.MethodPost, "/admin/promo-codes", adminToken, map[string]any{
+		"code": "spring-25", "campaign": "spring", "amount": 25, "max_redemptions": 2,
+	}, &code)
+	if code.Code != "SPRING-25" || code.PerUserLimit != 1 || !code.Active {
+		t.Fatalf("created code = %+v", code)
+	}
+	if status, _ := a.call(http.MethodPost, "/admin/promo-codes", anaToken, map[string]any{"code": "FREE", "campaign": "x", "amount": 1}); status != http.StatusForbidden {
+		t.Fatalf("player issuing a code: status %d, want 403", status)
+	}
+
+	var redeemed struct {
+		Redemption models.PromoRedemption `json:"redemption"`
+		Balance    float64                `json:"balance"`
+	}
+	a.mustCall(http.StatusOK, http.MethodPost, "/promo/redeem", anaToken, map[string]string{"code": "Spring-25"}, &redeemed)
+	if redeemed.Balance != initBalance+25 || redeemed.Redemption.UserID != ana.ID {
+		t.Fatalf("redemption = %+v", redeemed)
+	}
+	if status, _ := a.call(http.MethodPost, "/promo/redeem", anaToken, map[string]string{"code": "SPRING-25"}); status != http.StatusConflict {
+		t.Fatalf("second redemption: status %d, want 409", status)
+	}
+	a.mustCall(http.StatusOK, http.MethodPost, "/promo/redeem", benToken, map[string]string{"code": "SPRING-25"}, nil)
+	if status, _ := a.call(http.MethodPost, "/promo/redeem", catToken, map[string]string{"code": "SPRING-25"}); status != http.StatusConflict {
+		t.Fatalf("redeeming a used-up code: status %d, want 409", status)
+	}
+	if status, _ := a.call(http.MethodPost, "/promo/redeem", catToken, map[string]string{"code": "WINTER"}); status != http.StatusNotFound {
+		t.Fatalf("unknown code: status %d, want 404", status)
+	}
+
+	var reports []models.PromoCampaignReport
+	a.mustCall(http.StatusOK, http.MethodGet, "/admin/promo-campaigns", adminToken, nil, &reports)
+	if len(reports) != 1 || reports[0].Redemptions != 2 || reports[0].Players != 2 || reports[0].Amount != 50 {
+		t.Fatalf("campaign reports = %+v", reports)
+	}
+	var redemptions []models.PromoRedemption
+	a.mustCall(http.StatusOK, http.MethodGet, "/admin/promo-campaigns/spring/redemptions", adminToken, nil, &redemptions)
+	if len(redemptions) != 2 || redemptions[1].UserID != ana.ID {
+		t.Fatalf("redemptions = %+v", redemptions)
+	}
+	ledger, _ := a.store.ListTransactions(context.Background(), ana.ID)
+	if n := len(ledger); n == 0 || ledger[n-1].Reason != models.TransactionPromoCredit || ledger[n-1].Reference != "SPRING-25" {
+		t.Fatalf("ledger = %+v", ledger)
+	}
+
+	a.mustCall(http.StatusOK, http.MethodPatch, fmt.Sprintf("/admin/promo-codes/%d", code.ID), adminToken, map[string]any{"active": false}, &code)
+	if code.Active {
+		t.Fatal("code still active after PATCH")
+	}
+}
+
+// TestSecurityCenterScenario signs a player in from two devices after a few
+// wrong passwords and checks what their security overview shows.
+func TestSecurityCenterScenario(t *testing.T) {
+	a := newApp(t)
+	player := a.register("sam", 2)
+	token := a.login("sam")
+	eventually(t, "first device recorded", func() bool {
+		devices, _ := a.store.ListLoginDevices(context.Background(), player.ID)
+		return len(devices) == 1
+	})
+	for range 3 {
+		if status, _ := a.call(http.MethodPost, "/login", "", map[string]string{"identifier": "sam", "password": "wrong-horse-battery"}); status != http.StatusUnauthorized {
+			t.Fatalf("wrong password: status %d, want 401", status)
+		}
+	}
+	a.clock.Advance(time.Minute)
+	phone := http.Header{"X-Device-Id": {"sams-phone"}}
+	if status, body := a.doWithHeader(http.MethodPost, "/login", "", map[string]string{"identifier": "sam", "password": "correct-horse-battery"}, phone); status != http.StatusOK {
+		t.Fatalf("sign-in from a new device: status %d, body %s", status, body)
+	}
+	waitForEmail(t, a, "sam@example.com", "New sign-in")
+
+	var overview models.SecurityOverview
+	a.mustCall(http.StatusOK, http.MethodGet, "/me/security", token, nil, &overview)
+	if len(overview.Sessions) != 2 || len(overview.Devices) != 2 || overview.TwoFactor.Enabled {
+		t.Fatalf("overview = %+v", overview)
+	}
+	var methods []string
+	for _, m := range overview.AuthMethods {
+		methods = append(methods, m.Type)
+	}
+	if !slices.Equal(methods, []string{models.AuthMethodPassword, models.AuthMethodEmail}) {
+		t.Fatalf("auth methods = %v", methods)
+	}
+	counts := map[string]int{}
+	for _, e := range overview.RecentEvents {
+		counts[e.Type]++
+	}
+	if counts[models.SecurityEventLoginFailed] != 3 || counts[models.SecurityEventNewDevice] != 1 || counts[models.SecurityEventLoginAlert] != 1 {
+		t.Fatalf("recent events = %+v", overview.RecentEvents)
+	}
+	var actions []string
+	for _, r := range overview.RecommendedActions {
+		actions = append(actions, r.Action)
+	}
+	if !slices.Equal(actions, []string{security.ActionReviewLoginAlert, security.ActionChangePassword}) {
+		t.Fatalf("recommended actions = %v", actions)
+	}
+
+	a.clock.Advance(2 * time.Hour)
+	a.mustCall(http.StatusOK, http.MethodGet, "/me/security", a.login("sam"), nil, &overview)
+	if len(overview.Sessions) != 1 {
+		t.Fatalf("sessions after the first tokens expired = %+v", overview.Sessions)
+	}
+
+	_, vipToken := a.registerAs("val", 3, models.VVIPUser)
+	a.mustCall(http.StatusOK, http.MethodGet, "/me/security", vipToken, nil, &overview)
+	if !overview.TwoFactor.Enabled || overview.TwoFactor.Method != "email_device_confirmation" {
+		t.Fatalf("VVIP two-factor = %+v", overview.TwoFactor)
+	}
+}
+
+// TestStepUpChallengeScenario changes a password from a device first seen
+// minutes ago, which takes the code emailed to the address just saved, and
+// then from the same device a day later, which does not.
+func TestStepUpChallengeScenario(t *testing.T) {
+	a := newApp(t)
+	tia := a.register("tia", 4)
+	token := a.login("tia")
+	a.mustCall(http.StatusOK, http.MethodPatch, "/me", token, map[string]any{"email": "tia.new@example.com", "version": tia.Version}, nil)
+	change := map[string]string{"current_password": "correct-horse-battery", "new_password": "a-brand-new-secret"}
+
+	status, data := a.call(http.MethodPut, "/me/password", token, change)
+	if status != http.StatusPreconditionRequired {
+		t.Fatalf("change from a new device: status %d, want 428 (data %s)", status, data)
+	}
+	var required struct {
+		Challenge models.Challenge `json:"challenge"`
+	}
+	if err := json.Unmarshal(data, &required); err != nil || required.Challenge.Method != models.ChallengeOTP {
+		t.Fatalf("challenge = %s (%v)", data, err)
+	}
+	verifyPath := fmt.Sprintf("/challenges/%d/verify", required.Challenge.ID)
+	email := waitForEmail(t, a, "tia.new@example.com", "verification code")
+	code := strings.Fields(strings.SplitAfter(email.Body, "is:")[1])[0]
+
+	if status, _ := a.call(http.MethodPost, verifyPath, a.login("tia"), map[string]string{"code": "000000x"}); status != http.StatusUnprocessableEntity {
+		t.Fatalf("wrong code: status %d, want 422", status)
+	}
+	var passed struct {
+		Token string `json:"challenge_token"`
+	}
+	a.mustCall(http.StatusOK, http.MethodPost, verifyPath, token, map[string]string{"code": code}, &passed)
+	if status, _ := a.call(http.MethodPost, verifyPath, token, map[string]string{"code": code}); status != http.StatusGone {
+		t.Fatalf("answering twice: status %d, want 410", status)
+	}
+
+	withToken := http.Header{"X-Challenge-Token": {passed.Token}}
+	if status, body := a.doWithHeader(http.MethodPut, "/me/password", token, map[string]string{"current_password": "wrong-horse-battery", "new_password": "a-brand-new-secret"}, withToken); status != http.StatusForbidden {
+		t.Fatalf("wrong current password: status %d, body %s", status, body)
+	}
+	a.clock.Advance(time.Second)
+	if status, body := a.doWithHeader(http.MethodPut, "/me/password", token, change, withToken); status != http.StatusOK {
+		t.Fatalf("change with the challenge token: status %d, body %s", status, body)
+	}
+	if status, _ := a.call(http.MethodGet, "/me", token, nil); status != http.StatusUnauthorized {
+		t.Fatalf("session after the change: status %d, want 401", status)
+	}
+
+	a.clock.Advance(25 * time.Hour)
+	var fresh struct {
+		Token string `json:"token"`
+	}
+	a.mustCall(http.StatusOK, http.MethodPost, "/login", "", map[string]string{"identifier": "tia", "password": "a-brand-new-secret"}, &fresh)
+	a.mustCall(http.StatusOK, http.MethodPut, "/me/password", fresh.Token, map[string]string{"current_password": "a-brand-new-secret", "new_password": "correct-horse-battery"}, nil)
+}
+
+// TestGameSessionScenario launches a game and checks the provider's bets are
+// only applied within the player's live session.
+func TestGameSessionScenario(t *testing.T) {
+	reels, slots := &fakeGameProvider{}, &fakeGameProvider{}
+	a := newApp(t, server.WithProcessor("reels", reels), server.WithProcessor("slots", slots))
+	ana, token := a.registerAs("ana", 20, models.NormalUser)
+	bob, _ := a.registerAs("bob", 21, models.NormalUser)
+	signed := http.Header{"X-Provider-Signature": {"ok"}}
+	bet := func(provider, id, session string, userID int64) int {
+		t.Helper()
+		status, _ := a.doWithHeader(http.MethodPost, "/integrations/"+provider+"/callbacks", "", map[string]any{"id": id, "session": session, "user_id": userID, "amount": -10}, signed)
+		return status
+	}
+
+	if status, _ := a.call(http.MethodPost, "/games/arcade:pong/launch", token, nil); status != http.StatusNotFound {
+		t.Fatalf("game at an unknown provider: status %d, want 404", status)
+	}
+	var launch struct {
+		LaunchURL string `json:"launch_url"`
+		Token     string `json:"token"`
+	}
+	a.mustCall(http.StatusCreated, http.MethodPost, "/games/reels:starburst/launch", token, nil, &launch)
+	if !strings.HasPrefix(launch.LaunchURL, "http://reels.invalid/launch?") || !strings.Contains(launch.LaunchURL, "token="+launch.Token) {
+		t.Fatalf("launch = %+v", launch)
+	}
+
+	if status := bet("reels", "bet_1", launch.Token, ana.ID); status != http.StatusOK {
+		t.Fatalf("first bet: status %d, want 200", status)
+	}
+	a.clock.Advance(30 * time.Minute)
+	if status := bet("reels", "bet_2", launch.Token, ana.ID); status != http.StatusOK {
+		t.Fatalf("bet after the launch window, within the session: status %d, want 200", status)
+	}
+	if status := bet("reels", "bet_3", launch.Token, bob.ID); status != http.StatusForbidden {
+		t.Fatalf("bet for another player: status %d, want 403", status)
+	}
+	if status := bet("slots", "bet_4", launch.Token, ana.ID); status != http.StatusForbidden {
+		t.Fatalf("bet at another provider: status %d, want 403", status)
+	}
+	if status := bet("reels", "bet_5", "made-up", ana.ID); status != http.StatusForbidden {
+		t.Fatalf("bet on an unknown session: status %d, want 403", status)
+	}
+	a.clock.Advance(31 * time.Minute)
+	if status := bet("reels", "bet_6", launch.Token, ana.ID); status != http.StatusForbidden {
+		t.Fatalf("bet on an expired session: status %d, want 403", status)
+	}
+
+	a.mustCall(http.StatusCreated, http.MethodPost, "/games/reels:starburst/launch", a.login("ana"), nil, &launch)
+	a.clock.Advance(3 * time.Minute)
+	if status := bet("reels", "bet_7", launch.Token, ana.ID); status != http.StatusForbidden {
+		t.Fatalf("bet on a session never started: status %d, want 403", status)
+	}
+	if n := reels.appliedCount() + slots.appliedCount(); n != 2 {
+		t.Fatalf("applied %d bets, want 2", n)
+	}
+}
+
+// TestResponsibleGamingScenario has a player tighten and loosen their limits,
+// then exclude themselves and checks games and deposits close until it ends.
+func TestResponsibleGamingScenario(t *testing.T) {
+	reels := &fakeGameProvider{}
+	a := newApp(t, server.WithProcessor("reels", reels), server.WithPaymentProvider("sandbox", payments.NewSandbox("sandbox-secret")))
+	ana, token := a.registerAs("ana", 20, models.NormalUser)
+	_, staffToken := a.registerAs("support", 21, models.StaffUser)
+	bet := func(id, session string, amount float64) int {
+		t.Helper()
+		status, _ := a.doWithHeader(http.MethodPost, "/integrations/reels/callbacks", "", map[string]any{"id": id, "session": session, "user_id": ana.ID, "amount": amount}, http.Header{"X-Provider-Signature": {"ok"}})
+		return status
+	}
+
+	if status, _ := a.call(http.MethodPut, "/me/limits", token, map[string]any{"session_minutes": 0}); status != http.StatusBadRequest {
+		t.Fatalf("zero session limit: status %d, want 400", status)
+	}
+	var limits models.GamingLimits
+	a.mustCall(http.StatusOK, http.MethodPut, "/me/limits", token, map[string]any{"daily_deposit": 50, "session_minutes": 30}, &limits)
+	if limits.Pending != nil || limits.Limits.DailyDeposit == nil || *limits.Limits.DailyDeposit != 50 {
+		t.Fatalf("new limits = %+v, want them in force", limits)
+	}
+	if status, _ := a.call(http.MethodPost, "/payments/sandbox/deposits", token, map[string]any{"amount": 60}); status != http.StatusUnprocessableEntity {
+		t.Fatalf("deposit past the cap: status %d, want 422", status)
+	}
+	a.mustCall(http.StatusOK, http.MethodPut, "/me/limits", token, map[string]any{"daily_deposit": 500, "session_minutes": 30}, &limits)
+	if *limits.Limits.DailyDeposit != 50 || limits.Pending == nil || *limits.Pending.DailyDeposit != 500 {
+		t.Fatalf("raised limits = %+v, want the raise pending", limits)
+	}
+	a.mustCall(http.StatusOK, http.MethodGet, fmt.Sprintf("/admin/users/%d/limits", ana.ID), staffToken, nil, &limits)
+	if limits.UserID != ana.ID || limits.PendingFrom == nil || !limits.PendingFrom.Equal(a.clock.Now().Add(24*time.Hour)) {
+		t.Fatalf("limits seen by support = %+v", limits)
+	}
+	if status, _ := a.call(http.MethodGet, fmt.Sprintf("/admin/users/%d/limits", ana.ID), token, nil); status != http.StatusForbidden {
+		t.Fatalf("player reading limits through the admin route: status %d, want 403", status)
+	}
+
+	var launch struct {
+		Token string `json:"token"`
+	}
+	a.mustCall(http.StatusCreated, http.MethodPost, "/games/reels:starburst/launch", token, nil, &launch)
+	if status := bet("bet_1", launch.Token, -10); status != http.StatusOK {
+		t.Fatalf("first bet: status %d, want 200", status)
+	}
+	a.clock.Advance(31 * time.Minute)
+	if status := bet("bet_2", launch.Token, -10); status != http.StatusForbidden {
+		t.Fatalf("bet past the session limit: status %d, want 403", status)
+	}
+
+	a.mustCall(http.StatusCreated, http.MethodPost, "/games/reels:starburst/launch", token, nil, &launch)
+	bet("bet_3", launch.Token, -10)
+	if status, _ := a.call(http.MethodPost, "/me/self-exclusion", token, map[string]any{"days": 0}); status != http.StatusBadRequest {
+		t.Fatalf("zero-day exclusion: status %d, want 400", status)
+	}
+	a.mustCall(http.StatusOK, http.MethodPost, "/me/self-exclusion", token, map[string]any{"days": 7}, &limits)
+	if status, _ := a.call(http.MethodPost, "/me/self-exclusion", token, map[string]any{"days": 1}); status != http.StatusConflict {
+		t.Fatalf("shortening the exclusion: status %d, want 409", status)
+	}
+	if status, _ := a.call(http.MethodPost, "/games/reels:starburst/launch", token, nil); status != http.StatusForbidden {
+		t.Fatalf("launch while excluded: status %d, want 403", status)
+	}
+	if status := bet("bet_4", launch.Token, -10); status != http.StatusForbidden {
+		t.Fatalf("stake in an open session while excluded: status %d, want 403", status)
+	}
+	if status := bet("bet_5", launch.Token, 25); status != http.StatusOK {
+		t.Fatalf("win of a bet placed before the exclusion: status %d, want 200", status)
+	}
+	if status, _ := a.call(http.MethodPost, "/payments/sandbox/deposits", token, map[string]any{"amount": 10}); status != http.StatusForbidden {
+		t.Fatalf("deposit while excluded: status %d, want 403", status)
+	}
+	a.clock.Advance(7 * 24 * time.Hour)
+	a.mustCall(http.StatusCreated, http.MethodPost, "/games/reels:starburst/launch", a.login("ana"), nil, nil)
+}
+
+func TestLegalHoldScenario(t *testing.T) {
+	a := newApp(t)
+	admin, adminToken := a.registerAs("root", 10, models.AdminUser)
+	_, supportToken := a.registerAs("support", 11, models.StaffUser)
+	player, _ := a.registerAs("disputed", 12, models.NormalUser)
+	holds := fmt.Sprintf("/admin/users/%d/legal-holds", player.ID)
+
+	if status, _ := a.call(http.MethodPost, holds, supportToken, map[string]any{"reason": "chargeback"}); status != http.StatusForbidden {
+		t.Fatalf("staff placing a hold: status %d, want 403", status)
+	}
+	if status, _ := a.call(http.MethodPost, holds, adminToken, map[string]any{"reason": "chargeback", "dataset": "avatars"}); status != http.StatusBadRequest {
+		t.Fatalf("unknown dataset: status %d, want 400", status)
+	}
+	var hold models.LegalHold
+	a.mustCall(http.StatusCreated, http.MethodPost, holds, adminToken, map[string]any{"reason": "  chargeback dispute  "}, &hold)
+	if hold.UserID != player.ID || hold.Reason != "chargeback dispute" || hold.PlacedBy != admin.ID || hold.ReleasedAt != nil {
+		t.Fatalf("placed hold = %+v", hold)
+	}
+
+	release := fmt.Sprintf("%s/%d", holds, hold.ID)
+	a.mustCall(http.StatusOK, http.MethodDelete, release, adminToken, nil, &hold)
+	if hold.ReleasedAt == nil || *hold.ReleasedBy != admin.ID {
+		t.Fatalf("released hold = %+v", hold)
+	}
+	if status, _ := a.call(http.MethodDelete, release, adminToken, nil); status != http.StatusNotFound {
+		t.Fatalf("releasing twice: status %d, want 404", status)
+	}
+	var listed []models.LegalHold
+	a.mustCall(http.StatusOK, http.MethodGet, holds, adminToken, nil, &listed)
+	if len(listed) != 1 || listed[0].ReleasedAt == nil {
+		t.Fatalf("holds = %+v, want the released hold kept", listed)
+	}
+	if status, _ := a.call(http.MethodGet, "/admin/users/999/legal-holds", adminToken, nil); status != http.StatusNotFound {
+		t.Fatalf("unknown user: status %d, want 404", status)
+	}
+}
+
+func TestAdminToolsScenario(t *testing.T) {
+	a := newApp(t)
+	_, adminToken := a.registerAs("root", 10, models.AdminUser)
+	_, supportToken := a.registerAs("support", 11, models.StaffUser)
+	player, playerToken := a.registerAs("goodwill", 12, models.NormalUser)
+	adjust := fmt.Sprintf("/admin/users/%d/balance-adjustments", player.ID)
+
+	if status, _ := a.call(http.MethodPost, adjust, supportToken, map[string]any{"amount": 5, "note": "sorry", "key": "k1"}); status != http.StatusForbidden {
+		t.Fatalf("staff adjusting a balance: status %d, want 403", status)
+	}
+	var entry models.Transaction
+	credit := map[string]any{"amount": 25.5, "note": "outage goodwill", "key": "ticket-42"}
+	a.mustCall(http.StatusOK, http.MethodPost, adjust, adminToken, credit, &entry)
+	var retried models.Transaction
+	a.mustCall(http.StatusOK, http.MethodPost, adjust, adminToken, credit, &retried)
+	if retried.ID != entry.ID || entry.Reason != models.TransactionAdjustment || entry.BalanceAfter != player.Balance+25.5 {
+		t.Fatalf("adjustment = %+v, retry = %+v", entry, retried)
+	}
+	var me models.User
+	a.mustCall(http.StatusOK, http.MethodGet, "/me", playerToken, nil, &me)
+	if me.Balance != entry.BalanceAfter {
+		t.Fatalf("balance = %v, want %v after one credit", me.Balance, entry.BalanceAfter)
+	}
+	for name, body := range map[string]map[string]any{
+		"key reused":  {"amount": 1, "note": "x", "key": "ticket-42"},
+		"overdrawing": {"amount": -(me.Balance + 1), "note": "x", "key": "ticket-43"},
+	} {
+		if status, _ := a.call(http.MethodPost, adjust, adminToken, body); status != http.StatusConflict {
+			t.Errorf("%s: status %d, want 409", name, status)
+		}
+	}
+	if status, _ := a.call(http.MethodPost, adjust, adminToken, map[string]any{"amount": 0, "note": "x", "key": "k"}); status != http.StatusBadRequest {
+		t.Fatalf("zero amount: status %d, want 400", status)
+	}
+
+	var flag models.FeatureFlag
+	a.mustCall(http.StatusOK, http.MethodPut, "/admin/feature-flags/Beta-Lobby", adminToken, map[string]any{"enabled": true}, &flag)
+	if flag.Name != "beta-lobby" || !flag.Enabled || flag.Source != models.FlagSourceAdmin {
+		t.Fatalf("saved flag = %+v", flag)
+	}
+	var flags []models.FeatureFlag
+	a.mustCall(http.StatusOK, http.MethodGet, "/admin/feature-flags", adminToken, nil, &flags)
+	if len(flags) != 1 || flags[0].Name != "beta-lobby" {
+		t.Fatalf("flags = %+v", flags)
+	}
+	if status, _ := a.call(http.MethodPut, "/admin/feature-flags/beta-lobby", supportToken, map[string]any{"enabled": false}); status != http.StatusForbidden {
+		t.Fatalf("staff switching a flag: status %d, want 403", status)
+	}
+}
+
+func TestProfileEditsAreVersionedScenario(t *testing.T) {
+	a := newApp(t)
+	_, adminToken := a.registerAs("root", 30, models.AdminUser)
+	player, playerToken := a.registerAs("editor", 31, models.NormalUser)
+	profile := fmt.Sprintf("/admin/users/%d", player.ID)
+
+	if status, _ := a.call(http.MethodPatch, "/me", playerToken, map[string]any{"email": "new@example.com"}); status != http.StatusPreconditionRequired {
+		t.Fatalf("edit without a version: status %d, want 428", status)
+	}
+	var edited models.User
+	a.mustCall(http.StatusOK, http.MethodPatch, "/me", playerToken, map[string]any{"email": "new@example.com", "version": player.Version}, &edited)
+	if edited.Email != "new@example.com" || edited.Username != player.Username || edited.Version != player.Version+1 {
+		t.Fatalf("edited profile = %+v", edited)
+	}
+
+	// The admin still holds the copy from before the player's edit.
+	stale := http.Header{"If-Match": {fmt.Sprintf(`"%d"`, player.Version)}}
+	if status, _ := a.doWithHeader(http.MethodPatch, profile, adminToken, map[string]any{"username": "renamed"}, stale); status != http.StatusConflict {
+		t.Fatalf("admin edit on a stale version: status %d, want 409", status)
+	}
+	adjust := map[string]any{"amount": 10, "note": "goodwill", "key": "stale-1", "version": player.Version}
+	if status, _ := a.call(http.MethodPost, profile+"/balance-adjustments", adminToken, adjust); status != http.StatusConflict {
+		t.Fatalf("adjustment on a stale version: status %d, want 409", status)
+	}
+	current := http.Header{"If-Match": {fmt.Sprintf(`"%d"`, edited.Version)}}
+	if status, body := a.doWithHeader(http.MethodPatch, profile, adminToken, map[string]any{"username": "renamed"}, current); status != http.StatusOK {
+		t.Fatalf("admin edit on the current version: status %d, body %s", status, body)
+	}
+
+	// A balance movement bumps the version too, so the player's copy is stale now.
+	adjust["version"], adjust["key"] = edited.Version+1, "current-1"
+	a.mustCall(http.StatusOK, http.MethodPost, profile+"/balance-adjustments", adminToken, adjust, nil)
+	if status, _ := a.call(http.MethodPatch, "/me", playerToken, map[string]any{"phone": "+12025550099", "version": edited.Version + 1}); status != http.StatusConflict {
+		t.Fatalf("edit after a balance change: status %d, want 409", status)
+	}
+	var me models.User
+	a.mustCall(http.StatusOK, http.MethodGet, "/me", playerToken, nil, &me)
+	if me.Username != "renamed" || me.Email != "new@example.com" || me.Balance != player.Balance+10 || me.Version != player.Version+3 {
+		t.Fatalf("profile after the edits = %+v", me)
+	}
+}
+
+func TestEventStreamScenario(t *testing.T) {
+	a := newApp(t)
+	_, adminToken := a.registerAs("root", 32, models.AdminUser)
+	player, playerToken := a.registerAs("streamer", 33, models.NormalUser)
+	adjust := func(amount float64, key string) {
+		a.mustCall(http.StatusOK, http.MethodPost, fmt.Sprintf("/admin/users/%d/balance-adjustments", player.ID), adminToken,
+			map[string]any{"amount": amount, "note": "stream test", "key": key}, nil)
+	}
+	type event struct {
+		id, kind string
+		data     models.Transaction
+	}
+	// open streams /events until the test ends, resuming after lastID if set.
+	open := func(lastID string) (next func() event, closeStream func()) {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, a.url+"/events", nil)
+		req.Header.Set("Authorization", "Bearer "+playerToken)
+		if lastID != "" {
+			req.Header.Set("Last-Event-ID", lastID)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil || resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "text/event-stream" {
+			t.Fatalf("GET /events: %v, %+v", err, resp)
+		}
+		lines := bufio.NewReader(resp.Body)
+		return func() event {
+				var e event
+				for {
+					line, err := lines.ReadString('\n')
+					if err != nil {
+						t.Fatalf("read stream: %v", err)
+					}
+					field, value, _ := strings.Cut(strings.TrimSuffix(line, "\n"), ": ")
+					switch field {
+					case "id":
+						e.id = value
+					case "event":
+						e.kind = value
+					case "data":
+						if err := json.Unmarshal([]byte(value), &e.data); err != nil {
+							t.Fatalf("event data %q: %v", value, err)
+						}
+					case "":
+						if e.id != "" {
+							return e
+						}
+					}
+				}
+			}, func() {
+				cancel()
+				resp.Body.Close()
+			}
+	}
+
+	adjust(5, "before-stream")
+	next, closeStream := open("")
+	adjust(25, "live")
+	live := next()
+	if live.kind != "balance.changed" || live.data.Amount != 25 || live.data.BalanceAfter != player.Balance+30 || live.id != fmt.Sprint(live.data.ID) {
+		t.Fatalf("first event = %+v, want only the credit made while streaming", live)
+	}
+	closeStream()
+
+	adjust(-10, "missed-1")
+	adjust(3, "missed-2")
+	next, closeStream = open(live.id)
+	defer closeStream()
+	for _, want := range []float64{-10, 3} {
+		if e := next(); e.data.Amount != want {
+			t.Fatalf("resumed event = %+v, want the %v movement", e, want)
+		}
+	}
+
+	status, _ := a.doWithHeader(http.MethodGet, "/events", playerToken, nil, http.Header{"Last-Event-ID": {"latest"}})
+	if status != http.StatusBadRequest {
+		t.Fatalf("malformed Last-Event-ID: status %d, want 400", status)
+	}
+}
+
+func TestCacheInvalidationScenario(t *testing.T) {
+	a := newApp(t)
+	_, adminToken := a.registerAs("root", 10, models.AdminUser)
+	_, playerToken := a.registerAs("player", 11, models.NormalUser)
+
+	var listed dto.CacheInvalidationResponse
+	a.mustCall(http.StatusOK, http.MethodGet, "/internal/caches", adminToken, nil, &listed)
+	if !slices.Equal(listed.Caches, []string{"big_wins", "disposable_emails", "ip_risk", "leaderboards", "rate_limits", "sessions", "stats"}) || listed.Broadcast {
+		t.Fatalf("caches = %+v", listed)
+	}
+	var done dto.CacheInvalidationResponse
+	a.mustCall(http.StatusOK, http.MethodPost, "/internal/caches/invalidate", adminToken, map[string]any{"caches": []string{"rate_limits", "stats"}}, &done)
+	if len(done.Caches) != 2 || done.Broadcast {
+		t.Fatalf("invalidated = %+v", done)
+	}
+	for name, body := range map[string]map[string]any{
+		"no caches":     {"caches": []string{}},
+		"unknown cache": {"caches": []string{"stats", "profiles"}},
+	} {
+		if status, _ := a.call(http.MethodPost, "/internal/caches/invalidate", adminToken, body); status != http.StatusBadRequest {
+			t.Errorf("%s: status %d, want 400", name, status)
+		}
+	}
+	if status, _ := a.call(http.MethodPost, "/internal/caches/invalidate", playerToken, map[string]any{"caches": []string{"stats"}}); status != http.StatusForbidden {
+		t.Fatalf("player invalidating caches: status %d, want 403", status)
+	}
+}
+
+func TestMoneyFormatScenario(t *testing.T) {
+	a := newApp(t)
+	_, token := a.registerAs("heidi", 13, models.NormalUser)
+
+	status, raw := a.doWithHeader(http.MethodGet, "/me", token, nil, http.Header{"Accept-Language": {"de-CH, de;q=0.9, en;q=0.5"}})
+	var me struct {
+		Data dto.MeResponse `json:"data"`
+	}
+	if err := json.Unmarshal(raw, &me); status != http.StatusOK || err != nil {
+		t.Fatalf("GET /me: status %d, %v", status, err)
+	}
+	if me.Data.MoneyFormat.Locale != "de-DE" || me.Data.BalanceMoney.Formatted != "1.000,00\u00a0$" || me.Data.BalanceMoney.Minor != 100000 {
+		t.Fatalf("German caller: format %+v, balance %+v", me.Data.MoneyFormat, me.Data.BalanceMoney)
+	}
+
+	if err := a.store.RefreshLeaderboards(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	var board models.Leaderboard
+	a.mustCall(http.StatusOK, http.MethodGet, "/leaderboard?metric=balance", token, nil, &board)
+	if board.MoneyFormat == nil || board.MoneyFormat.Locale != "en-US" || board.Me == nil || board.Me.ValueMoney == nil || board.Me.ValueMoney.Formatted != "$1,000.00" {
+		t.Fatalf("balance board: format %+v, me %+v", board.MoneyFormat, board.Me)
+	}
+	var games models.Leaderboard
+	a.mustCall(http.StatusOK, http.MethodGet, "/leaderboard?metric=games_played", token, nil, &games)
+	if games.MoneyFormat != nil {
+		t.Fatalf("games played board has a money format: %+v", games.MoneyFormat)
+	}
+}
+
+func TestLocalizedMessagesScenario(t *testing.T) {
+	a := newApp(t)
+	_, token := a.registerAs("mei", 15, models.NormalUser)
+
+	message := func(resp *http.Response, raw []byte) string {
+		t.Helper()
+		var envelope struct {
+			Message string `json:"message"`
+		}
+		if err := json.Unmarshal(raw, &envelope); err != nil {
+			t.Fatalf("decode %s: %v", raw, err)
+		}
+		return envelope.Message
+	}
+	zh := http.Header{"Accept-Language": {"zh-CN,zh;q=0.9,en;q=0.8"}}
+	resp, raw := a.send(http.MethodGet, "/me", "", nil, zh)
+	if got := message(resp, raw); resp.StatusCode != http.StatusUnauthorized || got != "缺少 Bearer 令牌" || resp.Header.Get("Content-Language") != "zh" {
+		t.Fatalf("Chinese caller without a token: status %d, message %q, Content-Language %q", resp.StatusCode, got, resp.Header.Get("Content-Language"))
+	}
+	resp, raw = a.send(http.MethodGet, "/leaderboard?metric=winnings&limit=0", token, nil, http.Header{"Accept-Language": {"ms-MY"}})
+	if got := message(resp, raw); resp.StatusCode != http.StatusBadRequest || got != "limit mesti antara 1 hingga 100" {
+		t.Fatalf("Malay caller with a bad limit: status %d, message %q", resp.StatusCode, got)
+	}
+	resp, raw = a.send(http.MethodPost, "/register", "", map[string]string{"username": "x"}, zh)
+	if got := message(resp, raw); resp.StatusCode != http.StatusBadRequest || got != "username、email 和 phone 为必填项" {
+		t.Fatalf("Chinese caller registering without details: status %d, message %q", resp.StatusCode, got)
+	}
+	resp, raw = a.send(http.MethodGet, "/me", token, nil, http.Header{"Accept-Language": {"fr-FR"}})
+	if got := message(resp, raw); got != "profile fetched" || resp.Header.Get("Content-Language") != "en" {
+		t.Fatalf("French caller: message %q, Content-Language %q", got, resp.Header.Get("Content-Language"))
+	}
+	if vary := resp.Header.Values("Vary"); strings.Count(strings.Join(vary, ","), "Accept-Language") != 1 {
+		t.Fatalf("Vary = %v, want Accept-Language once", vary)
+	}
+}
+
+func TestMessagePackScenario(t *testing.T) {
+	a := newApp(t)
+	_, token := a.registerAs("mobile", 14, models.NormalUser)
+
+	resp, body := a.send(http.MethodGet, "/me", token, nil, http.Header{"Accept": {"application/msgpack"}})
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "application/msgpack" {
+		t.Fatalf("status %d, Content-Type %q", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+	if !slices.Contains(resp.Header.Values("Vary"), "Accept") {
+		t.Fatalf("Vary = %v, want Accept", resp.Header.Values("Vary"))
+	}
+	// A three-member map: code, message and data.
+	if len(body) == 0 || body[0] != 0x83 || !bytes.Contains(body, []byte("mobile")) {
+		t.Fatalf("body = % x", body)
+	}
+	resp, body = a.send(http.MethodGet, "/me", token, nil, http.Header{"Accept": {"text/html, */*;q=0.8"}})
+	if resp.Header.Get("Content-Type") != "application/json" || !json.Valid(body) {
+		t.Fatalf("browser Accept: Content-Type %q, body %s", resp.Header.Get("Content-Type"), body)
+	}
+}
+
+func TestRoutesAnswerOtherMethodsWith405(t *testing.T) {
+	a := newApp(t)
+	_, adminToken := a.registerAs("root", 14, models.AdminUser)
+
+	for _, tc := range []struct {
+		method, path, allow string
+	}{
+		{http.MethodDelete, "/me", "GET, HEAD, PATCH"},
+		{http.MethodPut, "/admin/roles", "GET, HEAD, POST"},
+		{http.MethodGet, "/admin/roles/1/permissions/1", "DELETE, PUT"},
+	} {
+		resp, _ := a.send(tc.method, tc.path, adminToken, nil, nil)
+		if resp.StatusCode != http.StatusMethodNotAllowed || resp.Header.Get("Allow") != tc.allow {
+			t.Errorf("%s %s: status %d, Allow %q; want 405 and %q", tc.method, tc.path, resp.StatusCode, resp.Header.Get("Allow"), tc.allow)
+		}
+	}
+}
+
+func TestConditionalGetScenario(t *testing.T) {
+	a := newApp(t)
+	player, token := a.registerAs("poller", 15, models.NormalUser)
+
+	resp, _ := a.send(http.MethodGet, "/me", token, nil, nil)
+	tag := resp.Header.Get("ETag")
+	if resp.StatusCode != http.StatusOK || tag == "" {
+		t.Fatalf("GET /me: status %d, ETag %q", resp.StatusCode, tag)
+	}
+	resp, body := a.send(http.MethodGet, "/me", token, nil, http.Header{"If-None-Match": {tag}})
+	if resp.StatusCode != http.StatusNotModified || len(body) != 0 {
+		t.Fatalf("unchanged profile: status %d, body %q; want 304 without a body", resp.StatusCode, body)
+	}
+
+	if _, err := a.store.ApplyTransaction(context.Background(), models.Transaction{UserID: player.ID, Amount: 25, Reason: models.TransactionDeposit}); err != nil {
+		t.Fatal(err)
+	}
+	resp, _ = a.send(http.MethodGet, "/me", token, nil, http.Header{"If-None-Match": {tag}})
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("ETag") == tag {
+		t.Fatalf("after deposit: status %d, ETag %q; want 200 with a new tag", resp.StatusCode, resp.Header.Get("ETag"))
+	}
+
+	if err := a.store.RefreshLeaderboards(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	resp, _ = a.send(http.MethodGet, "/leaderboard?metric=balance", token, nil, nil)
+	board := resp.Header.Get("ETag")
+	if resp.StatusCode != http.StatusOK || board == "" {
+		t.Fatalf("GET /leaderboard: status %d, ETag %q", resp.StatusCode, board)
+	}
+	if resp, _ = a.send(http.MethodGet, "/leaderboard?metric=balance", token, nil, http.Header{"If-None-Match": {board}}); resp.StatusCode != http.StatusNotModified {
+		t.Fatalf("unchanged leaderboard: status %d, want 304", resp.StatusCode)
+	}
+}
+
+func TestAPIKeyScenario(t *testing.T) {
+	a := newApp(t)
+	admin, adminToken := a.registerAs("root", 16, models.AdminUser)
+	_, staffToken := a.registerAs("support", 17, models.StaffUser)
+
+	var key models.APIKey
+	a.mustCall(http.StatusCreated, http.MethodPost, "/admin/api-keys", adminToken, map[string]any{"name": "reporting", "scopes": []string{models.PermStatsRead}, "expires_in_days": 30}, &key)
+	if !strings.HasPrefix(key.Key, key.Prefix) || len(key.Scopes) != 1 {
+		t.Fatalf("created key = %+v", key)
+	}
+	withKey := func(secret string) http.Header {
+		h := http.Header{}
+		h.Set("X-API-Key", secret)
+		return h
+	}
+	if resp, _ := a.send(http.MethodGet, "/admin/stats", "", nil, withKey(key.Key)); resp.StatusCode != http.StatusOK {
+		t.Fatalf("in-scope call: status %d, want 200", resp.StatusCode)
+	}
+	// Business KPIs are scraped with the key, not from the public /metrics.
+	if resp, body := a.send(http.MethodGet, "/admin/metrics", "", nil, withKey(key.Key)); resp.StatusCode != http.StatusOK || !bytes.Contains(body, []byte("business_registrations_total 2\n")) {
+		t.Fatalf("business metrics with the key: status %d, body %s", resp.StatusCode, body)
+	}
+	if _, body := a.do(http.MethodGet, "/metrics", "", nil); bytes.Contains(body, []byte("business_")) {
+		t.Fatalf("public metrics expose business KPIs:\n%s", body)
+	}
+	_, playerToken := a.registerAs("lena", 18, models.NormalUser)
+	if status, _ := a.call(http.MethodGet, "/admin/metrics", playerToken, nil); status != http.StatusForbidden {
+		t.Fatalf("business metrics without stats:read: status %d, want 403", status)
+	}
+	for path, want := range map[string]int{"/admin/rate-limits": http.StatusForbidden, "/me": http.StatusForbidden} {
+		if resp, _ := a.send(http.MethodGet, path, "", nil, withKey(key.Key)); resp.StatusCode != want {
+			t.Errorf("GET %s with key: status %d, want %d", path, resp.StatusCode, want)
+		}
+	}
+	if resp, _ := a.send(http.MethodPost, "/admin/api-keys", "", map[string]any{"name": "x", "scopes": []string{models.PermStatsRead}}, withKey(key.Key)); resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("key issuing a key: status %d, want 403", resp.StatusCode)
+	}
+	// The key never reaches the creator's own wallet or profile.
+	for _, path := range []string{"/payments/sandbox/withdrawals", "/payments/sandbox/deposits", "/promo/redeem"} {
+		if resp, _ := a.send(http.MethodPost, path, "", map[string]any{"amount": 10, "code": "X"}, withKey(key.Key)); resp.StatusCode != http.StatusForbidden {
+			t.Errorf("POST %s with key: status %d, want 403", path, resp.StatusCode)
+		}
+	}
+	if status, _ := a.call(http.MethodPost, "/admin/api-keys", staffToken, map[string]any{"name": "x", "scopes": []string{models.PermRolesManage}}); status != http.StatusForbidden {
+		t.Fatalf("staff without integrations:manage: status %d, want 403", status)
+	}
+	if status, _ := a.call(http.MethodPost, "/admin/api-keys", adminToken, map[string]any{"name": "x", "scopes": []string{}}); status != http.StatusBadRequest {
+		t.Fatalf("key without scopes: status %d, want 400", status)
+	}
+
+	var keys []models.APIKey
+	a.mustCall(http.StatusOK, http.MethodGet, "/admin/api-keys", adminToken, nil, &keys)
+	if len(keys) != 1 || keys[0].Key != "" {
+		t.Fatalf("listed keys = %+v, want one without its secret", keys)
+	}
+
+	a.clock.Advance(31 * 24 * time.Hour)
+	if resp, body := a.send(http.MethodGet, "/admin/stats", "", nil, withKey(key.Key)); resp.StatusCode != http.StatusUnauthorized || !bytes.Contains(body, []byte("expired")) {
+		t.Fatalf("expired key: status %d, body %s", resp.StatusCode, body)
+	}
+	adminToken = a.login("root")
+	var fresh models.APIKey
+	a.mustCall(http.StatusCreated, http.MethodPost, "/admin/api-keys", adminToken, map[string]any{"name": "reporting-2", "scopes": []string{models.PermStatsRead}}, &fresh)
+	a.mustCall(http.StatusOK, http.MethodDelete, fmt.Sprintf("/admin/api-keys/%d", fresh.ID), adminToken, nil, nil)
+	if resp, body := a.send(http.MethodGet, "/admin/stats", "", nil, withKey(fresh.Key)); resp.StatusCode != http.StatusUnauthorized || !bytes.Contains(body, []byte("revoked")) {
+		t.Fatalf("revoked key: status %d, body %s", resp.StatusCode, body)
+	}
+	if resp, _ := a.send(http.MethodGet, "/admin/stats", "", nil, withKey("aik_guess")); resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("unknown key: status %d, want 401", resp.StatusCode)
+	}
+
+	// Revoking the creator's sessions retires the keys they issued before.
+	var live models.APIKey
+	a.mustCall(http.StatusCreated, http.MethodPost, "/admin/api-keys", adminToken, map[string]any{"name": "reporting-3", "scopes": []string{models.PermStatsRead}}, &live)
+	if resp, _ := a.send(http.MethodGet, "/admin/stats", "", nil, withKey(live.Key)); resp.StatusCode != http.StatusOK {
+		t.Fatalf("fresh key: status %d, want 200", resp.StatusCode)
+	}
+	a.clock.Advance(time.Second)
+	stored, _ := a.store.FindByID(context.Background(), admin.ID)
+	if err := a.store.SetPassword(context.Background(), admin.ID, stored.PasswordHash, a.clock.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if resp, body := a.send(http.MethodGet, "/admin/stats", "", nil, withKey(live.Key)); resp.StatusCode != http.StatusUnauthorized || !bytes.Contains(body, []byte("session revoked")) {
+		t.Fatalf("key after the creator's sessions were revoked: status %d, body %s", resp.StatusCode, body)
+	}
+}
+
+func TestImpersonationScenario(t *testing.T) {
+	a := newApp(t)
+	support, staffToken := a.registerAs("helpdesk", 34, models.StaffUser)
+	_, otherStaffToken := a.registerAs("helpdesk2", 35, models.StaffUser)
+	player, playerToken := a.registerAs("reporter", 36, models.NormalUser)
+	impersonate := fmt.Sprintf("/admin/users/%d/impersonate", player.ID)
+
+	var imp models.Impersonation
+	a.mustCall(http.StatusCreated, http.MethodPost, impersonate, staffToken, map[string]any{"reason": "ticket 4411: bet slip missing", "expires_in_minutes": 10}, &imp)
+	if imp.Token == "" || imp.UserID != player.ID || imp.ActorID != support.ID || !imp.ExpiresAt.Equal(a.clock.Now().Add(10*time.Minute).Truncate(time.Second)) {
+		t.Fatalf("impersonation = %+v", imp)
+	}
+	var me models.User
+	a.mustCall(http.StatusOK, http.MethodGet, "/me", imp.Token, nil, &me)
+	if me.ID != player.ID {
+		t.Fatalf("impersonation token acts as user %d, want %d", me.ID, player.ID)
+	}
+	for _, tc := range []struct {
+		method, path string
+		body         any
+	}{
+		{http.MethodPut, "/me/password", map[string]string{"current_password": "correct-horse-battery", "new_password": "another-horse-battery"}},
+		{http.MethodPost, "/me/self-exclusion", map[string]any{"days": 7}},
+		{http.MethodPost, "/promo/redeem", map[string]any{"code": "WELCOME"}},
+		{http.MethodPost, "/games/1/launch", nil},
+		{http.MethodPost, impersonate, map[string]any{"reason": "again"}},
+	} {
+		if status, _ := a.call(tc.method, tc.path, imp.Token, tc.body); status != http.StatusForbidden {
+			t.Errorf("%s %s while impersonating: status %d, want 403", tc.method, tc.path, status)
+		}
+	}
+
+	for name, tc := range map[string]struct {
+		path, token string
+		body        any
+		want        int
+	}{
+		"a player":           {impersonate, playerToken, map[string]any{"reason": "x"}, http.StatusForbidden},
+		"without a reason":   {impersonate, staffToken, map[string]any{"reason": " "}, http.StatusBadRequest},
+		"for too long":       {impersonate, staffToken, map[string]any{"reason": "x", "expires_in_minutes": 600}, http.StatusBadRequest},
+		"themselves":         {fmt.Sprintf("/admin/users/%d/impersonate", support.ID), staffToken, map[string]any{"reason": "x"}, http.StatusBadRequest},
+		"another staff user": {fmt.Sprintf("/admin/users/%d/impersonate", support.ID), otherStaffToken, map[string]any{"reason": "x"}, http.StatusForbidden},
+	} {
+		if status, _ := a.call(http.MethodPost, tc.path, tc.token, tc.body); status != tc.want {
+			t.Errorf("impersonation by %s: status %d, want %d", name, status, tc.want)
+		}
+	}
+
+	var list []models.Impersonation
+	a.mustCall(http.StatusOK, http.MethodGet, fmt.Sprintf("/admin/impersonations?user_id=%d", player.ID), otherStaffToken, nil, &list)
+	if len(list) != 1 || list[0].ID != imp.ID || list[0].Token != "" || list[0].Reason != "ticket 4411: bet slip missing" {
+		t.Fatalf("listed impersonations = %+v, want the one started, without its token", list)
+	}
+	a.mustCall(http.StatusOK, http.MethodDelete, fmt.Sprintf("/admin/impersonations/%d", imp.ID), otherStaffToken, nil, nil)
+	if status, body := a.do(http.MethodGet, "/me", imp.Token, nil); status != http.StatusUnauthorized || !bytes.Contains(body, []byte("revoked")) {
+		t.Fatalf("revoked impersonation: status %d, body %s", status, body)
+	}
+	if status, _ := a.call(http.MethodDelete, fmt.Sprintf("/admin/impersonations/%d", imp.ID), staffToken, nil); status != http.StatusNotFound {
+		t.Fatalf("revoking twice: status %d, want 404", status)
+	}
+
+	var short models.Impersonation
+	a.mustCall(http.StatusCreated, http.MethodPost, impersonate, staffToken, map[string]any{"reason": "ticket 4412", "expires_in_minutes": 1}, &short)
+	a.clock.Advance(2 * time.Minute)
+	if status, _ := a.call(http.MethodGet, "/me", short.Token, nil); status != http.StatusUnauthorized {
+		t.Fatalf("expired impersonation: status %d, want 401", status)
+	}
+}
+
+// TestAccountMergeScenario has an admin preview and then merge a player's
+// second account into their first, after which the books still balance and
+// the duplicate can no longer be used.
+func TestAccountMergeScenario(t *testing.T) {
+	a := newApp(t)
+	admin, adminToken := a.registerAs("merger", 37, models.AdminUser)
+	main, mainToken := a.registerAs("twin", 38, models.NormalUser)
+	dup, dupToken := a.registerAs("twin2", 39, models.NormalUser)
+	a.mustCall(http.StatusOK, http.MethodPost, fmt.Sprintf("/admin/users/%d/balance-adjustments", main.ID), adminToken, map[string]any{"amount": 50, "note": "welcome", "key": "m1"}, nil)
+	a.mustCall(http.StatusOK, http.MethodPost, fmt.Sprintf("/admin/users/%d/balance-adjustments", dup.ID), adminToken, map[string]any{"amount": 25, "note": "welcome", "key": "d1"}, nil)
+	merge := fmt.Sprintf("/admin/users/%d/merge", main.ID)
+
+	var preview models.AccountMerge
+	a.mustCall(http.StatusOK, http.MethodPost, merge, adminToken, map[string]any{"duplicate_id": dup.ID, "dry_run": true}, &preview)
+	if !preview.DryRun || preview.ID != 0 || preview.Balance != initBalance+25 || preview.Transactions != 3 || preview.LoginAttempts != 1 {
+		t.Fatalf("preview = %+v", preview)
+	}
+	var me models.User
+	a.mustCall(http.StatusOK, http.MethodGet, "/me", dupToken, nil, &me)
+	if me.Balance != initBalance+25 || me.MergedInto != nil {
+		t.Fatalf("duplicate after a dry run = %+v", me)
+	}
+
+	for name, tc := range map[string]struct {
+		path, token string
+		body        any
+		want        int
+	}{
+		"a player":          {merge, mainToken, map[string]any{"duplicate_id": dup.ID}, http.StatusForbidden},
+		"without duplicate": {merge, adminToken, map[string]any{}, http.StatusBadRequest},
+		"into itself":       {merge, adminToken, map[string]any{"duplicate_id": main.ID}, http.StatusBadRequest},
+		"of staff":          {merge, adminToken, map[string]any{"duplicate_id": admin.ID}, http.StatusBadRequest},
+		"of nobody":         {merge, adminToken, map[string]any{"duplicate_id": 9999}, http.StatusNotFound},
+	} {
+		if status, _ := a.call(http.MethodPost, tc.path, tc.token, tc.body); status != tc.want {
+			t.Errorf("merge by %s: status %d, want %d", name, status, tc.want)
+		}
+	}
+
+	var merged models.AccountMerge
+	a.mustCall(http.StatusOK, http.MethodPost, merge, adminToken, map[string]any{"duplicate_id": dup.ID}, &merged)
+	if merged.ID == 0 || merged.DryRun || merged.Balance != preview.Balance || merged.Transactions != 3 {
+		t.Fatalf("merge = %+v", merged)
+	}
+	a.mustCall(http.StatusOK, http.MethodGet, "/me", mainToken, nil, &me)
+	if me.Balance != 2*initBalance+75 {
+		t.Fatalf("kept account balance = %v, want %v", me.Balance, 2*initBalance+75)
+	}
+	if status, _ := a.call(http.MethodGet, "/me", dupToken, nil); status != http.StatusUnauthorized {
+		t.Errorf("duplicate's token after the merge: status %d, want 401", status)
+	}
+	if status, _ := a.call(http.MethodPost, "/login", "", map[string]string{"identifier": "twin2", "password": "correct-horse-battery"}); status != http.StatusForbidden {
+		t.Errorf("duplicate signing in after the merge: status %d, want 403", status)
+	}
+	if status, _ := a.call(http.MethodPost, merge, adminToken, map[string]any{"duplicate_id": dup.ID}); status != http.StatusConflict {
+		t.Errorf("merging twice: status %d, want 409", status)
+	}
+
+	var report models.ReconciliationReport
+	a.mustCall(http.StatusOK, http.MethodPost, "/admin/reconciliation/run", adminToken, nil, &report)
+	if report.UsersChecked != 2 || report.Mismatches != 0 {
+		t.Fatalf("reconciliation after the merge = %+v", report)
+	}
+}
+
+func TestUsernamePolicyScenario(t *testing.T) {
+	a := newApp(t)
+	_, adminToken := a.registerAs("namekeeper", 40, models.AdminUser)
+	player, playerToken := a.registerAs("Casey", 41, models.NormalUser)
+	a.mustCall(http.StatusCreated, http.MethodPost, "/admin/reserved-usernames", adminToken, map[string]string{"name": "Croupier"}, nil)
+	a.mustCall(http.StatusCreated, http.MethodPost, "/admin/reserved-usernames", adminToken, map[string]string{"name": "heck", "kind": models.UsernameBlocked}, nil)
+	if status, _ := a.call(http.MethodPost, "/admin/reserved-usernames", adminToken, map[string]string{"name": "croupier"}); status != http.StatusConflict {
+		t.Errorf("adding a listed name: status %d, want 409", status)
+	}
+	if status, _ := a.call(http.MethodPost, "/admin/reserved-usernames", playerToken, map[string]string{"name": "dealer"}); status != http.StatusForbidden {
+		t.Errorf("a player adding a name: status %d, want 403", status)
+	}
+
+	for username, want := range map[string]string{
+		"x":             usernames.RuleLength,
+		"two words":     usernames.RuleCharset,
+		".dotted":       usernames.RuleEdges,
+		"CR0UPIER":      usernames.RuleReserved,
+		"what_the_h3ck": usernames.RuleBlocked,
+	} {
+		status, data := a.call(http.MethodPost, "/register", "", map[string]string{
+			"username": username, "email": "someone@example.com", "phone": "+12025550042", "password": "correct-horse-battery",
+		})
+		var v usernames.Violation
+		if status != http.StatusBadRequest || json.Unmarshal(data, &v) != nil || v.Field != "username" || v.Rule != want {
+			t.Errorf("registering %q: status %d, data %s; want 400 with rule %s", username, status, data, want)
+		}
+	}
+	if status, _ := a.call(http.MethodPost, "/register", "", map[string]string{
+		"username": "CASEY", "email": "casey2@example.com", "phone": "+12025550042", "password": "correct-horse-battery",
+	}); status != http.StatusConflict {
+		t.Errorf("registering a taken name in other case: status %d, want 409", status)
+	}
+
+	if status, _ := a.call(http.MethodPatch, "/me", playerToken, map[string]any{"username": "croupier", "version": player.Version}); status != http.StatusBadRequest {
+		t.Errorf("renaming to a reserved name: status %d, want 400", status)
+	}
+	var renamed models.User
+	a.mustCall(http.StatusOK, http.MethodPatch, "/me", playerToken, map[string]any{"username": "casey.b", "version": player.Version}, &renamed)
+	if renamed.Username != "casey.b" {
+		t.Fatalf("renamed = %+v", renamed)
+	}
+
+	var names []models.ReservedName
+	a.mustCall(http.StatusOK, http.MethodGet, "/admin/reserved-usernames", adminToken, nil, &names)
+	if len(names) != 2 || names[0].Name != "croupier" || names[1].Kind != models.UsernameBlocked || names[0].Source != models.ReservedNameSourceAdmin {
+		t.Fatalf("reserved names = %+v", names)
+	}
+	a.mustCall(http.StatusOK, http.MethodDelete, "/admin/reserved-usernames/croupier", adminToken, nil, nil)
+	if status, _ := a.call(http.MethodDelete, "/admin/reserved-usernames/croupier", adminToken, nil); status != http.StatusNotFound {
+		t.Errorf("deleting twice: status %d, want 404", status)
+	}
+	a.register("croupier", 42)
+}
+
+func TestEmailCheckScenario(t *testing.T) {
+	a := newApp(t)
+	_, adminToken := a.registerAs("mailwarden", 43, models.AdminUser)
+	player, playerToken := a.registerAs("throwaway", 44, models.NormalUser)
+
+	for email, want := range map[string]string{
+		"someone":                   "email must be a valid address",
+		"Someone <a@example.com>":   "email must be a valid address",
+		"someone@mailinator.com":    "disposable email addresses are not allowed",
+		"someone@eu.mailinator.com": "disposable email addresses are not allowed",
+		"someone@nomail.invalid":    "email domain does not accept mail",
+	} {
+		status, raw := a.do(http.MethodPost, "/register", "", map[string]string{
+			"username": "someone", "email": email, "phone": "+12025550045", "password": "correct-horse-battery",
+		})
+		if status != http.StatusBadRequest || !strings.Contains(string(raw), want) {
+			t.Errorf("registering with %q: status %d, body %s; want 400 %q", email, status, raw, want)
+		}
+	}
+	if status, _ := a.call(http.MethodPatch, "/me", playerToken, map[string]any{"email": "me@mailinator.com", "version": player.Version}); status != http.StatusBadRequest {
+		t.Errorf("switching to a disposable address: status %d, want 400", status)
+	}
+
+	if err := os.WriteFile(a.disposable, []byte("# providers\nmailinator.com\nyopmail.com\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if status, _ := a.call(http.MethodPost, "/admin/disposable-email-domains/refresh", playerToken, nil); status != http.StatusForbidden {
+		t.Errorf("a player refreshing the list: status %d, want 403", status)
+	}
+	var list models.DisposableDomainList
+	a.mustCall(http.StatusOK, http.MethodPost, "/admin/disposable-email-domains/refresh", adminToken, nil, &list)
+	if list.Domains != 2 || list.Source != a.disposable || list.RefreshedAt == nil {
+		t.Fatalf("refreshed list = %+v", list)
+	}
+	if status, _ := a.call(http.MethodPost, "/register", "", map[string]string{
+		"username": "someone", "email": "someone@yopmail.com", "phone": "+12025550045", "password": "correct-horse-battery",
+	}); status != http.StatusBadRequest {
+		t.Errorf("registering with a newly listed domain: status %d, want 400", status)
+	}
+
+	if err := os.Remove(a.disposable); err != nil {
+		t.Fatal(err)
+	}
+	if status, _ := a.call(http.MethodPost, "/admin/disposable-email-domains/refresh", adminToken, nil); status != http.StatusBadGateway {
+		t.Errorf("refreshing a missing list: status %d, want 502", status)
+	}
+	a.mustCall(http.StatusOK, http.MethodGet, "/admin/disposable-email-domains", adminToken, nil, &list)
+	if list.Domains != 2 || list.Error == "" {
+		t.Fatalf("list after a failed refresh = %+v", list)
+	}
+}
+
+func TestCaptchaScenario(t *testing.T) {
+	a := newAppWithConfig(t, func(cfg *config.Config) {
+		cfg.Captcha = config.CaptchaConfig{
+			Provider:           "turnstile",
+			SiteKey:            "e2e-site-key",
+			Signup:             true,
+			LoginAfterFailures: 2,
+			LoginWindow:        15 * time.Minute,
+			BypassToken:        "e2e-captcha",
+		}
+	})
+	signup := map[string]string{
+		"username": "humanplayer", "email": "humanplayer@example.com", "phone": "+12025550046", "password": "correct-horse-battery",
+	}
+
+	var prompt struct {
+		Captcha struct {
+			Provider string `json:"provider"`
+			SiteKey  string `json:"site_key"`
+		} `json:"captcha"`
+	}
+	a.mustCall(http.StatusPreconditionRequired, http.MethodPost, "/register", "", signup, &prompt)
+	if prompt.Captcha.Provider != "turnstile" || prompt.Captcha.SiteKey != "e2e-site-key" {
+		t.Fatalf("captcha prompt = %+v", prompt)
+	}
+	signup["captcha_token"] = "forged"
+	a.mustCall(http.StatusUnprocessableEntity, http.MethodPost, "/register", "", signup, nil)
+	signup["captcha_token"] = "e2e-captcha"
+	var player models.User
+	a.mustCall(http.StatusCreated, http.MethodPost, "/register", "", signup, &player)
+
+	wrong := map[string]string{"identifier": "humanplayer", "password": "wrong-horse-battery"}
+	a.mustCall(http.StatusUnauthorized, http.MethodPost, "/login", "", wrong, nil)
+	a.mustCall(http.StatusUnauthorized, http.MethodPost, "/login", "", wrong, nil)
+	creds := map[string]string{"identifier": "humanplayer", "password": "correct-horse-battery"}
+	a.mustCall(http.StatusPreconditionRequired, http.MethodPost, "/login", "", creds, nil)
+	attempts, _ := a.store.ListLoginAttempts(context.Background(), models.LoginAttemptFilter{UserID: player.ID}, 1)
+	if len(attempts) != 1 || attempts[0].FailureReason != models.LoginCaptchaFailed {
+		t.Fatalf("latest login attempt = %+v, want %s", attempts, models.LoginCaptchaFailed)
+	}
+	// Identifiers that match no account are gated on the IP too.
+	a.mustCall(http.StatusPreconditionRequired, http.MethodPost, "/login", "", map[string]string{"identifier": "nobody", "password": "whatever-it-is"}, nil)
+	creds["captcha_token"] = "e2e-captcha"
+	a.mustCall(http.StatusOK, http.MethodPost, "/login", "", creds, nil)
+	delete(creds, "captcha_token")
+	a.mustCall(http.StatusOK, http.MethodPost, "/login", "", creds, nil)
+}
+
+// TestWalletHoldScenario reserves part of a player's balance for an unsettled
+// bet and checks it is reported apart and cannot be debited.
+func TestWalletHoldScenario(t *testing.T) {
+	a := newApp(t)
+	_, adminToken := a.registerAs("cashier", 47, models.AdminUser)
+	player, playerToken := a.registerAs("punter", 48, models.NormalUser)
+	hold, err := a.store.CreateHold(context.Background(), models.WalletHold{UserID: player.ID, Amount: 900, Reference: "bet-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var balance dto.WalletBalanceResponse
+	a.mustCall(http.StatusOK, http.MethodGet, "/wallet/balance", playerToken, nil, &balance)
+	if balance.Total != initBalance || balance.Held != 900 || balance.Available != initBalance-900 || balance.AvailableMoney.Formatted != "$100.00" {
+		t.Fatalf("balance with a hold = %+v", balance)
+	}
+	adjust := fmt.Sprintf("/admin/users/%d/balance-adjustments", player.ID)
+	a.mustCall(http.StatusConflict, http.MethodPost, adjust, adminToken, map[string]any{"amount": -500, "note": "chargeback", "key": "c1"}, nil)
+
+	if _, err := a.store.ReleaseHold(context.Background(), hold.ID); err != nil {
+		t.Fatal(err)
+	}
+	a.mustCall(http.StatusOK, http.MethodPost, adjust, adminToken, map[string]any{"amount": -500, "note": "chargeback", "key": "c2"}, nil)
+	a.mustCall(http.StatusOK, http.MethodGet, "/wallet/balance", playerToken, nil, &balance)
+	if balance.Total != initBalance-500 || balance.Held != 0 || balance.Available != initBalance-500 {
+		t.Fatalf("balance after the release = %+v", balance)
+	}
+}
+
+// TestTrialBalanceScenario posts a welcome bonus, a deposit, an adjustment
+// and a captured hold, and checks each lands on both sides of the journal.
+func TestTrialBalanceScenario(t *testing.T) {
+	sandbox := payments.NewSandbox("sandbox-secret")
+	a := newApp(t, server.WithPaymentProvider("sandbox", sandbox))
+	_, adminToken := a.registerAs("controller", 49, models.AdminUser)
+	player, playerToken := a.registerAs("bettor", 50, models.NormalUser)
+
+	var intent payments.Intent
+	a.mustCall(http.StatusCreated, http.MethodPost, "/payments/sandbox/deposits", playerToken, map[string]any{"amount": 25}, &intent)
+	payload, _ := json.Marshal(map[string]any{"id": "evt_tb", "type": payments.EventDepositSucceeded, "reference": intent.Reference, "user_id": player.ID, "amount": 25})
+	if status, _ := a.doWithHeader(http.MethodPost, "/integrations/sandbox/callbacks", "", json.RawMessage(payload), http.Header{payments.SandboxSignatureHeader: {sandbox.Sign(payload)}}); status != http.StatusOK {
+		t.Fatalf("signed callback: status %d, want 200", status)
+	}
+	a.mustCall(http.StatusOK, http.MethodPost, fmt.Sprintf("/admin/users/%d/balance-adjustments", player.ID), adminToken, map[string]any{"amount": -100, "note": "goodwill reversal", "key": "tb1"}, nil)
+	hold, err := a.store.CreateHold(context.Background(), models.WalletHold{UserID: player.ID, Amount: 50, Reference: "bet-tb"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.store.CaptureHold(context.Background(), hold.ID, models.TransactionBetSettlement); err != nil {
+		t.Fatal(err)
+	}
+
+	if status, _ := a.call(http.MethodGet, "/admin/ledger/trial-balance", playerToken, nil); status != http.StatusForbidden {
+		t.Fatalf("player reading the trial balance: status %d, want 403", status)
+	}
+	var report models.TrialBalance
+	a.mustCall(http.StatusOK, http.MethodGet, "/admin/ledger/trial-balance", adminToken, nil, &report)
+	want := []models.TrialBalanceLine{
+		{Account: models.AccountBonusPool, Kind: models.AccountBonusPool, Accounts: 1, Debits: 2 * initBalance, Balance: 2 * initBalance},
+		{Account: "gateway:sandbox", Kind: models.AccountGateway, Accounts: 1, Debits: 25, Balance: 25},
+		{Account: models.AccountHouse, Kind: models.AccountHouse, Accounts: 1, Credits: 150, Balance: -150},
+		{Account: models.AccountWallet, Kind: models.AccountWallet, Accounts: 2, Debits: 150, Credits: 2*initBalance + 25, Balance: -(2*initBalance - 125)},
+	}
+	if !report.Balanced || report.TotalDebits != 2*initBalance+175 || !slices.Equal(report.Lines, want) {
+		t.Fatalf("trial balance = %+v", report)
+	}
+}
+
+// TestSeamlessWalletScenario plays a round through a game provider's signed
+// wallet callbacks: a stake retried by the provider is taken once, rolled
+// back, and a win is paid.
+func TestSeamlessWalletScenario(t *testing.T) {
+	a := newAppWithConfig(t, func(cfg *config.Config) {
+		cfg.Games.Secrets = map[string]string{"reels": "reels-secret"}
+	})
+	player, token := a.registerAs("spinner", 51, models.NormalUser)
+	var launch struct {
+		Token string `json:"token"`
+	}
+	a.mustCall(http.StatusCreated, http.MethodPost, "/games/reels:starburst/launch", token, nil, &launch)
+	callback := func(provider, secret string, body map[string]any) (int, seamless.Result) {
+		t.Helper()
+		body["token"] = launch.Token
+		payload, _ := json.Marshal(body)
+		header := http.Header{seamless.SignatureHeader: {webhook.Sign(secret, a.clock.Now().Unix(), payload)}}
+		status, raw := a.doWithHeader(http.MethodPost, "/providers/"+provider+"/callbacks", "", json.RawMessage(payload), header)
+		var envelope struct {
+			Data seamless.Result `json:"data"`
+		}
+		_ = json.Unmarshal(raw, &envelope)
+		return status, envelope.Data
+	}
+
+	if status, _ := callback("slots", "reels-secret", map[string]any{"action": "balance"}); status != http.StatusNotFound {
+		t.Fatalf("callback from an unknown provider: status %d, want 404", status)
+	}
+	if status, _ := callback("reels", "forged", map[string]any{"action": "balance"}); status != http.StatusUnauthorized {
+		t.Fatalf("forged callback: status %d, want 401", status)
+	}
+	if status, _ := callback("reels", "reels-secret", map[string]any{"action": "cashout"}); status != http.StatusBadRequest {
+		t.Fatalf("unknown action: status %d, want 400", status)
+	}
+	status, auth := callback("reels", "reels-secret", map[string]any{"action": "authenticate"})
+	if status != http.StatusOK || auth.UserID != player.ID || auth.Balance != initBalance || auth.Currency != "USD" {
+		t.Fatalf("authenticate: status %d, result %+v", status, auth)
+	}
+	for i := range 2 {
+		status, debit := callback("reels", "reels-secret", map[string]any{"action": "debit", "transaction_id": "tx-1", "amount": 100})
+		if status != http.StatusOK || debit.Balance != initBalance-100 || debit.Duplicate != (i == 1) {
+			t.Fatalf("debit %d: status %d, result %+v", i, status, debit)
+		}
+	}
+	if status, cancelled := callback("reels", "reels-secret", map[string]any{"action": "rollback", "reference_id": "tx-9"}); status != http.StatusOK || cancelled.TransactionID != 0 {
+		t.Fatalf("rollback of a debit not taken yet: status %d, result %+v", status, cancelled)
+	}
+	if status, _ := callback("reels", "reels-secret", map[string]any{"action": "debit", "transaction_id": "tx-9", "amount": 100}); status != http.StatusConflict {
+		t.Fatalf("debit after its rollback: status %d, want 409", status)
+	}
+	if status, rolled := callback("reels", "reels-secret", map[string]any{"action": "rollback", "reference_id": "tx-1"}); status != http.StatusOK || rolled.Balance != initBalance {
+		t.Fatalf("rollback: status %d, result %+v", status, rolled)
+	}
+	if status, win := callback("reels", "reels-secret", map[string]any{"action": "credit", "transaction_id": "tx-2", "amount": 50}); status != http.StatusOK || win.Balance != initBalance+50 {
+		t.Fatalf("credit: status %d, result %+v", status, win)
+	}
+
+	var me models.User
+	a.mustCall(http.StatusOK, http.MethodGet, "/me", token, nil, &me)
+	if me.Balance != initBalance+50 {
+		t.Fatalf("balance after the round = %v, want %v", me.Balance, float64(initBalance+50))
+	}
+}
+
+func TestJackpotScenario(t *testing.T) {
+	a := newAppWithConfig(t, func(cfg *config.Config) {
+		cfg.Games.Secrets = map[string]string{"reels": "reels-secret"}
+		cfg.Games.Jackpots = map[string]float64{"mega": 5, "mini": 1}
+	})
+	_, adminToken := a.registerAs("root", 52, models.AdminUser)
+	player, token := a.registerAs("lucky", 53, models.NormalUser)
+	var launch struct {
+		Token string `json:"token"`
+	}
+	a.mustCall(http.StatusCreated, http.MethodPost, "/games/reels:starburst/launch", token, nil, &launch)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, a.url+"/jackpots/stream", nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil || resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("GET /jackpots/stream: %v, %+v", err, resp)
+	}
+	defer resp.Body.Close()
+	lines := bufio.NewReader(resp.Body)
+	next := func() []models.Jackpot {
+		t.Helper()
+		for {
+			line, err := lines.ReadString('\n')
+			if err != nil {
+				t.Fatalf("read stream: %v", err)
+			}
+			if data, ok := strings.CutPrefix(strings.TrimSuffix(line, "\n"), "data: "); ok {
+				var pots []models.Jackpot
+				if err := json.Unmarshal([]byte(data), &pots); err != nil {
+					t.Fatalf("event data %q: %v", data, err)
+				}
+				return pots
+			}
+		}
+	}
+	if pots := next(); len(pots) != 2 || pots[0].Name != "mega" || pots[0].Pot != 0 || pots[1].ContributionPercent != 1 {
+		t.Fatalf("pots on connect = %+v", pots)
+	}
+
+	payload, _ := json.Marshal(map[string]any{"action": "debit", "token": launch.Token, "transaction_id": "tx-1", "amount": 200})
+	header := http.Header{seamless.SignatureHeader: {webhook.Sign("reels-secret", a.clock.Now().Unix(), payload)}}
+	if status, raw := a.doWithHeader(http.MethodPost, "/providers/reels/callbacks", "", json.RawMessage(payload), header); status != http.StatusOK {
+		t.Fatalf("stake: status %d, body %s", status, raw)
+	}
+	if pots := next(); pots[0].Pot != 10 || pots[1].Pot != 2 {
+		t.Fatalf("pots pushed after the stake = %+v, want 5%% and 1%% of it", pots)
+	}
+	var listed []models.Jackpot
+	a.mustCall(http.StatusOK, http.MethodGet, "/jackpots", "", nil, &listed)
+	if len(listed) != 2 || listed[0].Pot != 10 || listed[0].UpdatedAt == nil {
+		t.Fatalf("GET /jackpots = %+v", listed)
+	}
+
+	win := func(jackpot string, userID int64, key string) (int, models.Transaction) {
+		t.Helper()
+		var entry models.Transaction
+		status, data := a.call(http.MethodPost, "/admin/jackpots/"+jackpot+"/wins", adminToken, map[string]any{"user_id": userID, "key": key})
+		_ = json.Unmarshal(data, &entry)
+		return status, entry
+	}
+	status, paid := win("mega", player.ID, "round-7")
+	if status != http.StatusOK || paid.Amount != 10 || paid.Reason != models.TransactionJackpotWin || paid.BalanceAfter != initBalance-200+10 {
+		t.Fatalf("win: status %d, entry %+v", status, paid)
+	}
+	if status, again := win("mega", player.ID, "round-7"); status != http.StatusOK || again.ID != paid.ID {
+		t.Fatalf("retried win: status %d, entry %+v, want the original payout", status, again)
+	}
+	if status, _ := win("mega", player.ID+1, "round-7"); status != http.StatusConflict {
+		t.Fatalf("win key reused for another player: status %d, want 409", status)
+	}
+	if status, _ := win("mega", player.ID, "round-8"); status != http.StatusConflict {
+		t.Fatalf("win of an empty pot: status %d, want 409", status)
+	}
+	if status, _ := win("grand", player.ID, "round-9"); status != http.StatusNotFound {
+		t.Fatalf("win of an unknown jackpot: status %d, want 404", status)
+	}
+	if status, _ := win("mini", player.ID+99, "round-10"); status != http.StatusNotFound {
+		t.Fatalf("win for an unknown player: status %d, want 404", status)
+	}
+	if pots := next(); pots[0].Pot != 0 || pots[1].Pot != 2 {
+		t.Fatalf("pots pushed after the win = %+v, want mega emptied", pots)
+	}
+
+	var report models.TrialBalance
+	a.mustCall(http.StatusOK, http.MethodGet, "/admin/ledger/trial-balance", adminToken, nil, &report)
+	balances := map[string]float64{}
+	for _, l := range report.Lines {
+		balances[l.Account] = l.Balance
+	}
+	if !report.Balanced || balances["jackpot:mega"] != 0 || balances["jackpot:mini"] != -2 || balances["house"] != -188 {
+		t.Fatalf("trial balance = %+v, want the pots booked out of the house's share of the stake", report)
+	}
+}
+
+func TestTournamentScenario(t *testing.T) {
+	a := newAppWithConfig(t, func(cfg *config.Config) {
+		cfg.Games.TournamentCloseInterval = 10 * time.Millisecond
+	})
+	_, adminToken := a.registerAs("root", 54, models.AdminUser)
+	ana, anaToken := a.registerAs("ana", 55, models.NormalUser)
+	ben, benToken := a.registerAs("ben", 56, models.NormalUser)
+	cat, _ := a.registerAs("cat", 57, models.NormalUser)
+
+	spec := map[string]any{
+		"name":         "Spring Slam",
+		"entry_fee":    25,
+		"starts_at":    a.clock.Now().Add(time.Minute),
+		"ends_at":      a.clock.Now().Add(30 * time.Minute),
+		"prize_shares": []float64{60, 30},
+	}
+	if status, _ := a.call(http.MethodPost, "/admin/tournaments", anaToken, spec); status != http.StatusForbidden {
+		t.Fatalf("player creating a tournament: status %d, want 403", status)
+	}
+	spec["prize_shares"] = []float64{80, 30}
+	if status, _ := a.call(http.MethodPost, "/admin/tournaments", adminToken, spec); status != http.StatusBadRequest {
+		t.Fatalf("prize shares over the pool: status %d, want 400", status)
+	}
+	spec["prize_shares"] = []float64{60, 30}
+	var created models.Tournament
+	a.mustCall(http.StatusCreated, http.MethodPost, "/admin/tournaments", adminToken, spec, &created)
+	path := fmt.Sprintf("/tournaments/%d", created.ID)
+
+	var entry models.TournamentEntry
+	a.mustCall(http.StatusCreated, http.MethodPost, path+"/entries", anaToken, nil, &entry)
+	if entry.UserID != ana.ID || entry.Username != "ana" {
+		t.Fatalf("entry = %+v", entry)
+	}
+	a.mustCall(http.StatusCreated, http.MethodPost, path+"/entries", benToken, nil, nil)
+	if status, _ := a.call(http.MethodPost, path+"/entries", anaToken, nil); status != http.StatusConflict {
+		t.Fatalf("second entry: status %d, want 409", status)
+	}
+
+	// Scores come from the game server's API key, never from the players.
+	var key models.APIKey
+	a.mustCall(http.StatusCreated, http.MethodPost, "/admin/api-keys", adminToken, map[string]any{"name": "game-server", "scopes": []string{models.PermTournamentsScore}}, &key)
+	scores := "/admin" + path + "/scores"
+	score := func(userID int64, value float64) (int, models.TournamentEntry) {
+		t.Helper()
+		resp, raw := a.send(http.MethodPost, scores, "", map[string]any{"user_id": userID, "score": value}, http.Header{"X-Api-Key": {key.Key}})
+		var envelope struct {
+			Data models.TournamentEntry `json:"data"`
+		}
+		_ = json.Unmarshal(raw, &envelope)
+		return resp.StatusCode, envelope.Data
+	}
+	if status, _ := a.call(http.MethodPost, scores, anaToken, map[string]any{"user_id": ana.ID, "score": 1000}); status != http.StatusForbidden {
+		t.Fatalf("player reporting their own score: status %d, want 403", status)
+	}
+	if status, _ := score(ana.ID, 10); status != http.StatusConflict {
+		t.Fatalf("score before the start: status %d, want 409", status)
+	}
+
+	a.clock.Advance(2 * time.Minute)
+	if status, _ := score(cat.ID, 10); status != http.StatusConflict {
+		t.Fatalf("score without an entry: status %d, want 409", status)
+	}
+	if status, _ := score(ana.ID, -1); status != http.StatusBadRequest {
+		t.Fatalf("negative score: status %d, want 400", status)
+	}
+	if status, _ := score(ben.ID, 120); status != http.StatusOK {
+		t.Fatalf("ben's score: status %d, want 200", status)
+	}
+	score(ana.ID, 90)
+	if status, entry := score(ana.ID, 40); status != http.StatusOK || entry.Score != 90 {
+		t.Fatalf("entry after a lower score = %d, %+v, want the best kept", status, entry)
+	}
+
+	var standings models.TournamentStandings
+	a.mustCall(http.StatusOK, http.MethodGet, path, anaToken, nil, &standings)
+	if standings.Tournament.PrizePool != 50 || standings.Tournament.Entrants != 2 || len(standings.Entries) != 2 || standings.Entries[0].UserID != ben.ID || standings.Entries[0].Prize != 30 {
+		t.Fatalf("standings = %+v", standings)
+	}
+
+	a.clock.Advance(30 * time.Minute)
+	if status, _ := score(ana.ID, 500); status != http.StatusConflict {
+		t.Fatalf("score after the end: status %d, want 409", status)
+	}
+	eventually(t, "tournament closed", func() bool {
+		a.mustCall(http.StatusOK, http.MethodGet, path, anaToken, nil, &standings)
+		return standings.Tournament.Status == models.TournamentClosed
+	})
+	if standings.Entries[1].UserID != ana.ID || standings.Entries[1].Rank != 2 || standings.Entries[1].Prize != 15 {
+		t.Fatalf("closed standings = %+v", standings)
+	}
+	var balance struct {
+		Total float64 `json:"total"`
+	}
+	a.mustCall(http.StatusOK, http.MethodGet, "/wallet/balance", benToken, nil, &balance)
+	if balance.Total != initBalance-25+30 {
+		t.Fatalf("winner balance = %v, want the fee taken and the prize paid", balance.Total)
+	}
+	email := waitForEmail(t, a, "ben@example.com", "You placed #1 in Spring Slam")
+	if !strings.Contains(email.Body, "prize of 30.00") {
+		t.Fatalf("prize email = %q", email.Body)
+	}
+
+	var listed []models.Tournament
+	a.mustCall(http.StatusOK, http.MethodGet, "/tournaments", anaToken, nil, &listed)
+	if len(listed) != 1 || listed[0].ID != created.ID || listed[0].ClosedAt == nil {
+		t.Fatalf("GET /tournaments = %+v", listed)
+	}
+}
+
+func TestMessageInboxScenario(t *testing.T) {
+	a := newApp(t)
+	_, adminToken := a.registerAs("root", 58, models.AdminUser)
+	player, token := a.registerAs("mia", 59, models.NormalUser)
+	_, vipToken := a.registerAs("vera", 60, models.VIPUser)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, a.url+"/me/messages/stream", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil || resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("GET /me/messages/stream: %v, %+v", err, resp)
+	}
+	defer resp.Body.Close()
+	lines := bufio.NewReader(resp.Body)
+	next := func() (string, models.Message) {
+		t.Helper()
+		var id string
+		for {
+			line, err := lines.ReadString('\n')
+			if err != nil {
+				t.Fatalf("read stream: %v", err)
+			}
+			line = strings.TrimSuffix(line, "\n")
+			if v, ok := strings.CutPrefix(line, "id: "); ok {
+				id = v
+			}
+			if data, ok := strings.CutPrefix(line, "data: "); ok {
+				var m models.Message
+				if err := json.Unmarshal([]byte(data), &m); err != nil {
+					t.Fatalf("event data %q: %v", data, err)
+				}
+				return id, m
+			}
+		}
+	}
+
+	if status, _ := a.call(http.MethodPost, "/admin/messages/broadcasts", token, map[string]any{"subject": "Hi", "body": "All"}); status != http.StatusForbidden {
+		t.Fatalf("player broadcasting: status %d, want 403", status)
+	}
+	if status, _ := a.call(http.MethodPost, "/admin/messages/broadcasts", adminToken, map[string]any{"subject": "", "body": "All"}); status != http.StatusBadRequest {
+		t.Fatalf("announcement without a subject: status %d, want 400", status)
+	}
+	var sent struct {
+		Recipients int `json:"recipients"`
+	}
+	a.mustCall(http.StatusOK, http.MethodPost, "/admin/messages/broadcasts", adminToken, map[string]any{"subject": "VIP weekend", "body": "Double points.", "roles": []string{models.VIPUser}}, &sent)
+	if sent.Recipients != 1 {
+		t.Fatalf("VIP announcement reached %d, want 1", sent.Recipients)
+	}
+	a.mustCall(http.StatusOK, http.MethodPost, "/admin/messages/broadcasts", adminToken, map[string]any{"subject": "Maintenance", "body": "Down at 02:00."}, &sent)
+	if sent.Recipients != 3 {
+		t.Fatalf("announcement reached %d, want 3", sent.Recipients)
+	}
+	if id, pushed := next(); pushed.Subject != "Maintenance" || id != fmt.Sprint(pushed.ID) {
+		t.Fatalf("pushed %s: %+v, want the announcement", id, pushed)
+	}
+
+	if status, _ := a.call(http.MethodPost, fmt.Sprintf("/admin/users/%d/messages", player.ID+99), adminToken, map[string]any{"subject": "Hi", "body": "There"}); status != http.StatusNotFound {
+		t.Fatalf("message to an unknown user: status %d, want 404", status)
+	}
+	var support models.Message
+	a.mustCall(http.StatusCreated, http.MethodPost, fmt.Sprintf("/admin/users/%d/messages", player.ID), adminToken, map[string]any{"subject": "Your withdrawal", "body": "It was paid."}, &support)
+	if _, pushed := next(); pushed.ID != support.ID || pushed.Kind != models.MessageSupport {
+		t.Fatalf("pushed %+v, want the support message", pushed)
+	}
+
+	var inbox models.Inbox
+	a.mustCall(http.StatusOK, http.MethodGet, "/me/messages", token, nil, &inbox)
+	if inbox.Unread != 2 || len(inbox.Messages) != 2 || inbox.Messages[0].ID != support.ID {
+		t.Fatalf("inbox = %+v", inbox)
+	}
+	var read struct {
+		Unread int `json:"unread"`
+	}
+	a.mustCall(http.StatusOK, http.MethodPost, "/me/messages/read", token, map[string]any{"ids": []int64{support.ID}}, &read)
+	if read.Unread != 1 {
+		t.Fatalf("unread after reading one = %d, want 1", read.Unread)
+	}
+	a.mustCall(http.StatusOK, http.MethodGet, "/me/messages?unread=true", token, nil, &inbox)
+	if len(inbox.Messages) != 1 || inbox.Messages[0].Subject != "Maintenance" {
+		t.Fatalf("unread messages = %+v", inbox)
+	}
+	a.mustCall(http.StatusOK, http.MethodPost, "/me/messages/read", token, nil, &read)
+	if read.Unread != 0 {
+		t.Fatalf("unread after reading all = %d, want 0", read.Unread)
+	}
+	a.mustCall(http.StatusOK, http.MethodGet, "/me/messages", vipToken, nil, &inbox)
+	if inbox.Unread != 2 {
+		t.Fatalf("VIP inbox = %+v, want both announcements unread", inbox)
+	}
+	if status, _ := a.call(http.MethodGet, "/me/messages?limit=0", token, nil); status != http.StatusBadRequest {
+		t.Fatalf("limit 0: status %d, want 400", status)
+	}
+}
+
+func TestSupportTicketScenario(t *testing.T) {
+	a := newApp(t)
+	_, staffToken := a.registerAs("sara", 61, models.StaffUser)
+	player, token := a.registerAs("noah", 62, models.NormalUser)
+	_, vvipToken := a.registerAs("vivi", 63, models.VVIPUser)
+
+	if status, _ := a.call(http.MethodPost, "/support/tickets", token, map[string]any{"subject": "Help", "body": " "}); status != http.StatusBadRequest {
+		t.Fatalf("ticket without a body: status %d, want 400", status)
+	}
+	var opened models.SupportThread
+	a.mustCall(http.StatusCreated, http.MethodPost, "/support/tickets", token, map[string]any{"subject": "Deposit missing", "body": "I paid 50 but see nothing."}, &opened)
+	if opened.Ticket.Status != models.TicketOpen || opened.Ticket.Priority != models.TicketPriorityNormal || len(opened.Replies) != 1 {
+		t.Fatalf("opened ticket = %+v", opened)
+	}
+	a.clock.Advance(time.Minute)
+	var vip models.SupportThread
+	a.mustCall(http.StatusCreated, http.MethodPost, "/support/tickets", vvipToken, map[string]any{"subject": "Raise my limit", "body": "Please."}, &vip)
+	if vip.Ticket.Priority != models.TicketPriorityHigh {
+		t.Fatalf("VVIP ticket priority = %q, want high", vip.Ticket.Priority)
+	}
+
+	if status, _ := a.call(http.MethodGet, "/admin/support/tickets", token, nil); status != http.StatusForbidden {
+		t.Fatalf("player reading the queue: status %d, want 403", status)
+	}
+	var queue []models.SupportTicket
+	a.mustCall(http.StatusOK, http.MethodGet, "/admin/support/tickets", staffToken, nil, &queue)
+	if len(queue) != 2 || queue[0].ID != vip.Ticket.ID || queue[1].ID != opened.Ticket.ID {
+		t.Fatalf("queue = %+v, want the VVIP ticket first", queue)
+	}
+	ticketPath := fmt.Sprintf("/support/tickets/%d", opened.Ticket.ID)
+	if status, _ := a.call(http.MethodGet, ticketPath, vvipToken, nil); status != http.StatusNotFound {
+		t.Fatalf("reading another player's ticket: status %d, want 404", status)
+	}
+
+	a.mustCall(http.StatusCreated, http.MethodPost, "/admin"+ticketPath+"/replies", staffToken, map[string]any{"body": "Could you send the receipt?"}, nil)
+	var inbox models.Inbox
+	a.mustCall(http.StatusOK, http.MethodGet, "/me/messages", token, nil, &inbox)
+	if inbox.Unread != 1 || inbox.Messages[0].Subject != "Re: Deposit missing" {
+		t.Fatalf("inbox = %+v, want the staff reply", inbox)
+	}
+
+	upload := func(name, contentType, body string) (int, models.TicketAttachment) {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodPost, a.url+ticketPath+"/attachments?name="+name, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("Content-Type", contentType)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("upload %s: %v", name, err)
+		}
+		defer resp.Body.Close()
+		var envelope struct {
+			Data models.TicketAttachment `json:"data"`
+		}
+		_ = json.NewDecoder(resp.Body).Decode(&envelope)
+		return resp.StatusCode, envelope.Data
+	}
+	if status, _ := upload("run.sh", "application/x-sh", "echo"); status != http.StatusUnsupportedMediaType {
+		t.Fatalf("shell script upload: status %d, want 415", status)
+	}
+	status, attachment := upload("receipt.txt", "text/plain", "paid 50 at 10:02")
+	if status != http.StatusCreated || attachment.Size != 16 || attachment.URL == "" {
+		t.Fatalf("receipt upload: status %d, %+v", status, attachment)
+	}
+	a.mustCall(http.StatusCreated, http.MethodPost, ticketPath+"/replies", token, map[string]any{"body": "Attached."}, nil)
+
+	var thread models.SupportThread
+	a.mustCall(http.StatusOK, http.MethodGet, "/admin"+ticketPath, staffToken, nil, &thread)
+	if thread.Ticket.Status != models.TicketOpen || len(thread.Replies) != 3 || !thread.Replies[1].Staff || len(thread.Attachments) != 1 {
+		t.Fatalf("thread = %+v", thread)
+	}
+	resp, err := http.Get(a.url + strings.TrimPrefix(thread.Attachments[0].URL, "http://blobs.invalid"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || string(content) != "paid 50 at 10:02" {
+		t.Fatalf("attachment download: status %d, %q", resp.StatusCode, content)
+	}
+
+	if status, _ := a.call(http.MethodPatch, "/admin"+ticketPath, staffToken, map[string]any{"status": "lost"}); status != http.StatusBadRequest {
+		t.Fatalf("unknown status: status %d, want 400", status)
+	}
+	var ticket models.SupportTicket
+	a.mustCall(http.StatusOK, http.MethodPost, ticketPath+"/close", token, nil, &ticket)
+	if ticket.Status != models.TicketClosed || ticket.ClosedAt == nil || ticket.UserID != player.ID {
+		t.Fatalf("closed ticket = %+v", ticket)
+	}
+	if status, _ := a.call(http.MethodPost, ticketPath+"/replies", token, map[string]any{"body": "Wait"}); status != http.StatusConflict {
+		t.Fatalf("reply to a closed ticket: status %d, want 409", status)
+	}
+	if status, _ := a.call(http.MethodPatch, "/admin"+ticketPath, staffToken, map[string]any{"status": models.TicketPending}); status != http.StatusConflict {
+		t.Fatalf("closed to pending: status %d, want 409", status)
+	}
+	a.mustCall(http.StatusOK, http.MethodPatch, "/admin"+ticketPath, staffToken, map[string]any{"status": models.TicketOpen}, &ticket)
+	var mine []models.SupportTicket
+	a.mustCall(http.StatusOK, http.MethodGet, "/support/tickets", token, nil, &mine)
+	if len(mine) != 1 || mine[0].Status != models.TicketOpen || mine[0].ClosedAt != nil {
+		t.Fatalf("player's tickets = %+v", mine)
+	}
+}
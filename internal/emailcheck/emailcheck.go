@@ -0,0 +1,207 @@
+// Package emailcheck vets the email addresses accounts are opened with: their
+// syntax, optionally whether their domain accepts mail, and whether it is a
+// known disposable email provider.
+package emailcheck
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/mail"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hongminglow/all-in-be/internal/clock"
+	"github.com/hongminglow/all-in-be/internal/models"
+	"github.com/hongminglow/all-in-be/internal/tracing"
+)
+
+// Reasons Check refuses an address.
+var (
+	ErrInvalid      = errors.New("email must be a valid address")
+	ErrNoMailServer = errors.New("email domain does not accept mail")
+	ErrDisposable   = errors.New("disposable email addresses are not allowed")
+	// ErrNoList is returned by Refresh when no disposable domain list is
+	// configured.
+	ErrNoList = errors.New("no disposable email domain list is configured")
+)
+
+// Resolver looks up MX records; *net.Resolver implements it.
+type Resolver interface {
+	LookupMX(ctx context.Context, name string) ([]*net.MX, error)
+}
+
+// Options configure a Checker.
+type Options struct {
+	// Resolver checks that domains have mail servers; nil skips the check.
+	Resolver Resolver
+	// MXTimeout bounds each MX lookup.
+	MXTimeout time.Duration
+	// DisposableList is a file or http(s) URL listing disposable domains,
+	// one per line with # comments. Subdomains of a listed domain are
+	// refused too. Empty disables the check.
+	DisposableList string
+	Client         *http.Client
+}
+
+// Checker vets addresses. The disposable domain list is read by Refresh and
+// reread on the next check after Expire.
+type Checker struct {
+	opts  Options
+	clock clock.Clock
+
+	mu      sync.RWMutex
+	domains map[string]bool
+	list    models.DisposableDomainList
+	stale   bool
+}
+
+// New builds a checker; call Refresh to read the disposable domain list.
+func New(opts Options, clk clock.Clock) *Checker {
+	if opts.Client == nil {
+		opts.Client = &http.Client{Timeout: 30 * time.Second, Transport: tracing.Transport(nil)}
+	}
+	return &Checker{opts: opts, clock: clk, list: models.DisposableDomainList{Source: opts.DisposableList}}
+}
+
+// Check returns ErrInvalid, ErrDisposable or ErrNoMailServer for an address
+// that may not open an account. MX lookups that fail for reasons other than
+// the domain having no mail servers let the address through.
+func (c *Checker) Check(ctx context.Context, email string) error {
+	addr, err := mail.ParseAddress(email)
+	if err != nil || addr.Name != "" || addr.Address != email {
+		return ErrInvalid
+	}
+	at := strings.LastIndexByte(email, '@')
+	domain := strings.ToLower(email[at+1:])
+	if !strings.Contains(domain, ".") || strings.HasPrefix(domain, "[") || strings.HasSuffix(domain, ".") {
+		return ErrInvalid
+	}
+	if c.disposable(ctx, domain) {
+		return ErrDisposable
+	}
+	if c.opts.Resolver == nil {
+		return nil
+	}
+	lookupCtx, cancel := context.WithTimeout(ctx, c.opts.MXTimeout)
+	defer cancel()
+	records, err := c.opts.Resolver.LookupMX(lookupCtx, domain)
+	var dnsErr *net.DNSError
+	switch {
+	case errors.As(err, &dnsErr) && dnsErr.IsNotFound:
+		return ErrNoMailServer
+	case err != nil:
+		log.Printf("emailcheck: look up MX of %s: %v", domain, err)
+		return nil
+	case len(records) == 0, len(records) == 1 && records[0].Host == ".":
+		// A lone "." host is a null MX: the domain declares it takes no mail.
+		return ErrNoMailServer
+	}
+	return nil
+}
+
+// disposable reports whether domain or one of its parents is listed.
+func (c *Checker) disposable(ctx context.Context, domain string) bool {
+	c.mu.Lock()
+	stale := c.stale
+	c.stale = false
+	c.mu.Unlock()
+	if stale {
+		if _, err := c.Refresh(ctx); err != nil {
+			log.Printf("emailcheck: %v", err)
+		}
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	for {
+		if c.domains[domain] {
+			return true
+		}
+		_, parent, ok := strings.Cut(domain, ".")
+		if !ok {
+			return false
+		}
+		domain = parent
+	}
+}
+
+// List describes the disposable domain list in use.
+func (c *Checker) List() models.DisposableDomainList {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.list
+}
+
+// Expire makes the next check reread the disposable domain list, for when
+// another instance refreshed it.
+func (c *Checker) Expire() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.stale = c.opts.DisposableList != ""
+}
+
+// Refresh rereads the disposable domain list. On error the previous list
+// stays in use and the error is recorded in List.
+func (c *Checker) Refresh(ctx context.Context) (models.DisposableDomainList, error) {
+	if c.opts.DisposableList == "" {
+		return models.DisposableDomainList{}, ErrNoList
+	}
+	domains, err := c.load(ctx)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err != nil {
+		c.list.Error = err.Error()
+		return c.list, err
+	}
+	now := c.clock.Now()
+	c.domains = domains
+	c.list = models.DisposableDomainList{Source: c.opts.DisposableList, Domains: len(domains), RefreshedAt: &now}
+	return c.list, nil
+}
+
+func (c *Checker) load(ctx context.Context) (map[string]bool, error) {
+	source := c.opts.DisposableList
+	var r io.Reader
+	if strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://") {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, source, nil)
+		if err != nil {
+			return nil, fmt.Errorf("fetch disposable domains: %w", err)
+		}
+		resp, err := c.opts.Client.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("fetch disposable domains: %w", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("fetch disposable domains: status %d", resp.StatusCode)
+		}
+		r = resp.Body
+	} else {
+		f, err := os.Open(source)
+		if err != nil {
+			return nil, fmt.Errorf("open disposable domains: %w", err)
+		}
+		defer f.Close()
+		r = f
+	}
+
+	domains := make(map[string]bool)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		if domain := strings.Trim(strings.ToLower(strings.TrimSpace(line)), "@."); domain != "" {
+			domains[domain] = true
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read disposable domains: %w", err)
+	}
+	return domains, nil
+}
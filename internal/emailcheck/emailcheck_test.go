@@ -0,0 +1,102 @@
+package emailcheck
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hongminglow/all-in-be/internal/storage/storagetest"
+)
+
+// fakeResolver answers MX lookups from a map; unknown domains do not exist.
+type fakeResolver map[string][]*net.MX
+
+func (r fakeResolver) LookupMX(_ context.Context, name string) ([]*net.MX, error) {
+	if name == "flaky.example" {
+		return nil, &net.DNSError{Err: "server misbehaving", Name: name, IsTemporary: true}
+	}
+	records, ok := r[name]
+	if !ok {
+		return nil, &net.DNSError{Err: "no such host", Name: name, IsNotFound: true}
+	}
+	return records, nil
+}
+
+func TestCheck(t *testing.T) {
+	list := filepath.Join(t.TempDir(), "disposable.txt")
+	if err := os.WriteFile(list, []byte("# throwaway providers\nmailinator.com\nTempMail.example\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	checker := New(Options{
+		Resolver: fakeResolver{
+			"example.com":    {{Host: "mx.example.com.", Pref: 10}},
+			"nomail.example": {{Host: ".", Pref: 0}},
+		},
+		MXTimeout:      time.Second,
+		DisposableList: list,
+	}, storagetest.NewFakeClock(time.Now()))
+	if got, err := checker.Refresh(context.Background()); err != nil || got.Domains != 2 {
+		t.Fatalf("Refresh = %+v, %v", got, err)
+	}
+	for email, want := range map[string]error{
+		"player@example.com":          nil,
+		"player@flaky.example":        nil,
+		"Player <player@example.com>": ErrInvalid,
+		"player@localhost":            ErrInvalid,
+		"player@[192.0.2.1]":          ErrInvalid,
+		"not-an-address":              ErrInvalid,
+		"player@mailinator.com":       ErrDisposable,
+		"player@eu.tempmail.example":  ErrDisposable,
+		"player@missing.example":      ErrNoMailServer,
+		"player@nomail.example":       ErrNoMailServer,
+	} {
+		if err := checker.Check(context.Background(), email); !errors.Is(err, want) {
+			t.Errorf("Check(%q) = %v; want %v", email, err, want)
+		}
+	}
+}
+
+func TestRefreshKeepsListOnError(t *testing.T) {
+	var domains atomic.Value
+	domains.Store("mailinator.com\n")
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if domains.Load() == "" {
+			http.Error(w, "gone", http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(domains.Load().(string)))
+	}))
+	defer srv.Close()
+
+	checker := New(Options{DisposableList: srv.URL}, storagetest.NewFakeClock(time.Now()))
+	ctx := context.Background()
+	if _, err := checker.Refresh(ctx); err != nil {
+		t.Fatal(err)
+	}
+	domains.Store("")
+	if _, err := checker.Refresh(ctx); err == nil {
+		t.Fatal("Refresh succeeded after the list went away")
+	}
+	if got := checker.List(); got.Domains != 1 || got.Error == "" {
+		t.Errorf("List after a failed refresh = %+v", got)
+	}
+	if err := checker.Check(ctx, "player@mailinator.com"); !errors.Is(err, ErrDisposable) {
+		t.Errorf("Check after a failed refresh = %v; want ErrDisposable", err)
+	}
+
+	domains.Store("guerrillamail.com\n")
+	checker.Expire()
+	if err := checker.Check(ctx, "player@mailinator.com"); err != nil {
+		t.Errorf("Check after Expire = %v; want the list reread", err)
+	}
+	if _, err := New(Options{}, nil).Refresh(ctx); !errors.Is(err, ErrNoList) {
+		t.Errorf("Refresh without a list = %v; want ErrNoList", err)
+	}
+}
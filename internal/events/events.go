@@ -0,0 +1,183 @@
+// Package events carries domain events from the code that causes them to the
+// subsystems that react to them (notifications, webhooks, ...). Handlers run
+// asynchronously on the job queue, so publishing never blocks on consumers.
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/hongminglow/all-in-be/internal/clock"
+	"github.com/hongminglow/all-in-be/internal/jobs"
+)
+
+// Domain event types.
+const (
+	TypeUserRegistered = "user.registered"
+	TypeUserLoggedIn   = "user.logged_in"
+	TypeBalanceChanged = "balance.changed"
+)
+
+// UserRegistered is published after a new account is stored.
+type UserRegistered struct {
+	UserID   int64  `json:"user_id"`
+	Username string `json:"username"`
+	Email    string `json:"email"`
+	Phone    string `json:"phone"`
+}
+
+// UserLoggedIn is published after a successful password sign-in.
+type UserLoggedIn struct {
+	UserID    int64  `json:"user_id"`
+	Username  string `json:"username"`
+	Email     string `json:"email"`
+	DeviceID  string `json:"device_id,omitempty"`
+	UserAgent string `json:"user_agent"`
+	// AcceptLanguage and AcceptEncoding help tell apart devices that send the
+	// same User-Agent but no device ID.
+	AcceptLanguage string `json:"accept_language,omitempty"`
+	AcceptEncoding string `json:"accept_encoding,omitempty"`
+	IP             string `json:"ip"`
+	// Country is the ISO 3166 code reported by the edge, if any.
+	Country string `json:"country,omitempty"`
+	// NewDevice is set when the user had signed in before, but not from this
+	// device or country.
+	NewDevice bool `json:"new_device,omitempty"`
+}
+
+// Balance change reasons.
+const (
+	ReasonDeposit    = "deposit"
+	ReasonWithdrawal = "withdrawal"
+)
+
+// BalanceChanged is published after a user's balance moves.
+type BalanceChanged struct {
+	UserID  int64   `json:"user_id"`
+	Delta   float64 `json:"delta"`
+	Balance float64 `json:"balance"`
+	Reason  string  `json:"reason"`
+}
+
+// Event is the envelope every backend transports.
+type Event struct {
+	ID         string    `json:"id"`
+	Type       string    `json:"type"`
+	OccurredAt time.Time `json:"occurred_at"`
+	// Region is the deployment region that published the event, if configured.
+	Region string          `json:"region,omitempty"`
+	Data   json.RawMessage `json:"data"`
+}
+
+// Decode unmarshals the event data into v.
+func (e Event) Decode(v any) error {
+	if err := json.Unmarshal(e.Data, v); err != nil {
+		return fmt.Errorf("decode %s event %s: %w", e.Type, e.ID, err)
+	}
+	return nil
+}
+
+// Handler reacts to an event. A returned error makes the job queue retry it.
+type Handler func(ctx context.Context, e Event) error
+
+// Publisher emits domain events.
+type Publisher interface {
+	Publish(ctx context.Context, eventType string, data any) error
+}
+
+// Subscriber registers handlers for an event type.
+type Subscriber interface {
+	Subscribe(eventType string, h Handler) error
+}
+
+// Bus is a Publisher and Subscriber with a lifecycle.
+type Bus interface {
+	Publisher
+	Subscriber
+	Close() error
+}
+
+// envelopes builds events with IDs and timestamps from the injected sources.
+type envelopes struct {
+	clock  clock.Clock
+	ids    clock.IDGenerator
+	region string
+}
+
+func (b envelopes) build(eventType string, data any) (Event, error) {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return Event{}, fmt.Errorf("encode %s event: %w", eventType, err)
+	}
+	return Event{ID: b.ids.NewID(), Type: eventType, OccurredAt: b.clock.Now().UTC(), Region: b.region, Data: raw}, nil
+}
+
+// registry holds subscriptions and runs matching handlers on the job queue.
+type registry struct {
+	queue *jobs.Queue
+
+	mu     sync.RWMutex
+	byType map[string][]Handler
+}
+
+func newRegistry(queue *jobs.Queue) *registry {
+	return &registry{queue: queue, byType: make(map[string][]Handler)}
+}
+
+// add registers h and reports whether it is the first handler for eventType.
+func (r *registry) add(eventType string, h Handler) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.byType[eventType] = append(r.byType[eventType], h)
+	return len(r.byType[eventType]) == 1
+}
+
+func (r *registry) dispatch(e Event) error {
+	r.mu.RLock()
+	handlers := r.byType[e.Type]
+	r.mu.RUnlock()
+	for i, h := range handlers {
+		job := jobs.Job{
+			Type: "event",
+			Name: fmt.Sprintf("event %s #%d (%s)", e.Type, i, e.ID),
+			Run: func(ctx context.Context, _ int) error {
+				return h(ctx, e)
+			},
+		}
+		if err := r.queue.Enqueue(job); err != nil {
+			return fmt.Errorf("dispatch %s event: %w", e.Type, err)
+		}
+	}
+	return nil
+}
+
+// InProcess delivers events to handlers registered in the same process.
+type InProcess struct {
+	envelopes
+	handlers *registry
+}
+
+// NewInProcess returns a bus that runs handlers on queue.
+func NewInProcess(queue *jobs.Queue, clk clock.Clock, ids clock.IDGenerator, region string) *InProcess {
+	return &InProcess{envelopes: envelopes{clock: clk, ids: ids, region: region}, handlers: newRegistry(queue)}
+}
+
+func (b *InProcess) Publish(_ context.Context, eventType string, data any) error {
+	e, err := b.build(eventType, data)
+	if err != nil {
+		return err
+	}
+	return b.handlers.dispatch(e)
+}
+
+func (b *InProcess) Subscribe(eventType string, h Handler) error {
+	b.handlers.add(eventType, h)
+	return nil
+}
+
+func (b *InProcess) Close() error {
+	return nil
+}
@@ -0,0 +1,200 @@
+package events
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hongminglow/all-in-be/internal/jobs"
+	"github.com/hongminglow/all-in-be/internal/storage/storagetest"
+)
+
+func collect(t *testing.T, sub Subscriber, eventType string) (func() []UserRegistered, *sync.WaitGroup) {
+	t.Helper()
+	var mu sync.Mutex
+	var got []UserRegistered
+	var wg sync.WaitGroup
+	err := sub.Subscribe(eventType, func(_ context.Context, e Event) error {
+		defer wg.Done()
+		var payload UserRegistered
+		if err := e.Decode(&payload); err != nil {
+			return err
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		got = append(got, payload)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("subscribe: %v", err)
+	}
+	return func() []UserRegistered {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]UserRegistered(nil), got...)
+	}, &wg
+}
+
+func waitFor(t *testing.T, wg *sync.WaitGroup) {
+	t.Helper()
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(3 * time.Second):
+		t.Fatal("timed out waiting for handlers")
+	}
+}
+
+func TestInProcessDeliversToSubscribers(t *testing.T) {
+	queue := jobs.NewQueue(2, 8, time.Millisecond)
+	defer queue.Close(context.Background())
+	bus := NewInProcess(queue, storagetest.NewFakeClock(time.Unix(0, 0)), &storagetest.SequentialIDs{}, "")
+
+	first, wg := collect(t, bus, TypeUserRegistered)
+	second, wg2 := collect(t, bus, TypeUserRegistered)
+	wg.Add(1)
+	wg2.Add(1)
+	if err := bus.Publish(context.Background(), TypeUserRegistered, UserRegistered{UserID: 7, Username: "alice"}); err != nil {
+		t.Fatalf("publish: %v", err)
+	}
+	if err := bus.Publish(context.Background(), TypeBalanceChanged, BalanceChanged{UserID: 7}); err != nil {
+		t.Fatalf("publish unrelated event: %v", err)
+	}
+	waitFor(t, wg)
+	waitFor(t, wg2)
+	if got := first(); len(got) != 1 || got[0].UserID != 7 {
+		t.Fatalf("first subscriber got %+v", got)
+	}
+	if got := second(); len(got) != 1 || got[0].Username != "alice" {
+		t.Fatalf("second subscriber got %+v", got)
+	}
+}
+
+// fakeNATSServer implements enough of the core protocol to route PUBs to SUBs.
+type fakeNATSServer struct {
+	ln net.Listener
+
+	mu       sync.Mutex
+	connects []string
+	subs     map[string][]fakeSub
+}
+
+type fakeSub struct {
+	w     *bufio.Writer
+	mu    *sync.Mutex
+	sid   string
+	queue string
+}
+
+func startFakeNATS(t *testing.T) *fakeNATSServer {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	s := &fakeNATSServer{ln: ln, subs: make(map[string][]fakeSub)}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	t.Cleanup(func() { ln.Close() })
+	return s
+}
+
+func (s *fakeNATSServer) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	w := bufio.NewWriter(conn)
+	var wmu sync.Mutex
+	write := func(format string, args ...any) {
+		wmu.Lock()
+		defer wmu.Unlock()
+		fmt.Fprintf(w, format, args...)
+		w.Flush()
+	}
+	write("INFO {\"server_id\":\"fake\",\"max_payload\":1048576}\r\n")
+	for {
+		line, err := readLine(r)
+		if err != nil {
+			return
+		}
+		fields := strings.Fields(line)
+		switch {
+		case strings.HasPrefix(line, "CONNECT "):
+			s.mu.Lock()
+			s.connects = append(s.connects, strings.TrimPrefix(line, "CONNECT "))
+			s.mu.Unlock()
+		case line == "PING":
+			write("PONG\r\n")
+		case fields[0] == "SUB":
+			s.mu.Lock()
+			s.subs[fields[1]] = append(s.subs[fields[1]], fakeSub{w: w, mu: &wmu, sid: fields[3], queue: fields[2]})
+			s.mu.Unlock()
+		case fields[0] == "PUB":
+			payload, err := readPayload(r, "MSG "+line)
+			if err != nil {
+				return
+			}
+			s.mu.Lock()
+			targets := s.subs[fields[1]]
+			s.mu.Unlock()
+			if len(targets) > 0 {
+				// Queue-group semantics: one member receives each message.
+				sub := targets[0]
+				sub.mu.Lock()
+				fmt.Fprintf(sub.w, "MSG %s %s %d\r\n%s\r\n", fields[1], sub.sid, len(payload), payload)
+				sub.w.Flush()
+				sub.mu.Unlock()
+			}
+		}
+	}
+}
+
+func TestNATSRoundTrip(t *testing.T) {
+	server := startFakeNATS(t)
+	queue := jobs.NewQueue(2, 8, time.Millisecond)
+	defer queue.Close(context.Background())
+
+	cfg := NATSConfig{URL: "nats://svc:hunter2@" + server.ln.Addr().String(), SubjectPrefix: "allin.events", QueueGroup: "workers"}
+	bus, err := NewNATS(context.Background(), cfg, queue, storagetest.NewFakeClock(time.Unix(0, 0)), &storagetest.SequentialIDs{Prefix: "evt"}, "eu-west")
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer bus.Close()
+
+	got, wg := collect(t, bus, TypeUserRegistered)
+	wg.Add(1)
+	// The SUB is written before Publish, but give the fake server a moment to register it.
+	time.Sleep(50 * time.Millisecond)
+	if err := bus.Publish(context.Background(), TypeUserRegistered, UserRegistered{UserID: 42, Email: "a@example.com"}); err != nil {
+		t.Fatalf("publish: %v", err)
+	}
+	waitFor(t, wg)
+	if events := got(); len(events) != 1 || events[0].UserID != 42 {
+		t.Fatalf("received %+v", events)
+	}
+
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	if len(server.connects) != 1 || !strings.Contains(server.connects[0], `"user":"svc"`) || !strings.Contains(server.connects[0], `"pass":"hunter2"`) {
+		t.Fatalf("CONNECT options = %v", server.connects)
+	}
+	subs := server.subs["allin.events.user.registered"]
+	if len(subs) != 1 || subs[0].queue != "workers" {
+		t.Fatalf("subscriptions = %+v", server.subs)
+	}
+}
@@ -0,0 +1,305 @@
+package events
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hongminglow/all-in-be/internal/clock"
+	"github.com/hongminglow/all-in-be/internal/jobs"
+)
+
+// NATSConfig locates the NATS server and names the subjects events use.
+type NATSConfig struct {
+	// URL is nats://[user:pass@]host:port, or nats://token@host:port for token auth.
+	URL string
+	// SubjectPrefix is prepended to event types, e.g. "allin.events" gives
+	// subjects like "allin.events.user.registered".
+	SubjectPrefix string
+	// QueueGroup load-balances each event across instances so it is handled once.
+	QueueGroup string
+}
+
+// NATS publishes events to a NATS server and consumes them through a queue
+// group. It speaks the core NATS text protocol directly and reconnects with
+// backoff, resubscribing when the connection drops. Core NATS is at-most-once:
+// events published while disconnected fail and are not buffered.
+type NATS struct {
+	envelopes
+	handlers *registry
+	cfg      NATSConfig
+	addr     string
+	connect  []byte
+
+	mu      sync.Mutex
+	conn    net.Conn
+	w       *bufio.Writer
+	subs    map[string]int
+	nextSID int
+	closed  bool
+	done    chan struct{}
+}
+
+// NewNATS connects to the server and starts consuming. Handlers run on queue.
+func NewNATS(ctx context.Context, cfg NATSConfig, queue *jobs.Queue, clk clock.Clock, ids clock.IDGenerator, region string) (*NATS, error) {
+	u, err := url.Parse(cfg.URL)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("invalid NATS URL %q", cfg.URL)
+	}
+	addr := u.Host
+	if u.Port() == "" {
+		addr = net.JoinHostPort(u.Hostname(), "4222")
+	}
+	options := map[string]any{"verbose": false, "pedantic": false, "name": "all-in-be", "lang": "go", "protocol": 1}
+	if u.User != nil {
+		if pass, ok := u.User.Password(); ok {
+			options["user"] = u.User.Username()
+			options["pass"] = pass
+		} else {
+			options["auth_token"] = u.User.Username()
+		}
+	}
+	connect, err := json.Marshal(options)
+	if err != nil {
+		return nil, err
+	}
+
+	b := &NATS{
+		envelopes: envelopes{clock: clk, ids: ids, region: region},
+		handlers:  newRegistry(queue),
+		cfg:       cfg,
+		addr:      addr,
+		connect:   connect,
+		subs:      make(map[string]int),
+		done:      make(chan struct{}),
+	}
+	conn, r, err := b.dial(ctx)
+	if err != nil {
+		return nil, err
+	}
+	b.conn = conn
+	b.w = bufio.NewWriter(conn)
+	go b.readLoop(conn, r)
+	return b, nil
+}
+
+func (b *NATS) subject(eventType string) string {
+	return b.cfg.SubjectPrefix + "." + eventType
+}
+
+// dial opens a connection and completes the INFO/CONNECT/PING handshake.
+func (b *NATS) dial(ctx context.Context) (net.Conn, *bufio.Reader, error) {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", b.addr)
+	if err != nil {
+		return nil, nil, fmt.Errorf("dial nats: %w", err)
+	}
+	_ = conn.SetDeadline(time.Now().Add(10 * time.Second))
+	r := bufio.NewReader(conn)
+	if line, err := readLine(r); err != nil || !strings.HasPrefix(line, "INFO ") {
+		conn.Close()
+		return nil, nil, fmt.Errorf("nats handshake: expected INFO, got %q: %v", line, err)
+	}
+	if _, err := fmt.Fprintf(conn, "CONNECT %s\r\nPING\r\n", b.connect); err != nil {
+		conn.Close()
+		return nil, nil, fmt.Errorf("nats handshake: %w", err)
+	}
+	for {
+		line, err := readLine(r)
+		if err != nil {
+			conn.Close()
+			return nil, nil, fmt.Errorf("nats handshake: %w", err)
+		}
+		if line == "PONG" {
+			break
+		}
+		if strings.HasPrefix(line, "-ERR") {
+			conn.Close()
+			return nil, nil, fmt.Errorf("nats handshake: %s", line)
+		}
+	}
+	_ = conn.SetDeadline(time.Time{})
+	return conn, r, nil
+}
+
+// Publish sends the event to its subject.
+func (b *NATS) Publish(_ context.Context, eventType string, data any) error {
+	e, err := b.build(eventType, data)
+	if err != nil {
+		return err
+	}
+	payload, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.w == nil {
+		return errors.New("nats: not connected")
+	}
+	fmt.Fprintf(b.w, "PUB %s %d\r\n", b.subject(eventType), len(payload))
+	b.w.Write(payload)
+	b.w.WriteString("\r\n")
+	return b.w.Flush()
+}
+
+// Subscribe registers h and subscribes to the event's subject on first use.
+func (b *NATS) Subscribe(eventType string, h Handler) error {
+	if !b.handlers.add(eventType, h) {
+		return nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.nextSID++
+	b.subs[eventType] = b.nextSID
+	if b.w == nil {
+		return nil // subscribed on reconnect
+	}
+	return b.sub(eventType, b.nextSID)
+}
+
+// sub writes a SUB command; b.mu must be held.
+func (b *NATS) sub(eventType string, sid int) error {
+	fmt.Fprintf(b.w, "SUB %s %s %d\r\n", b.subject(eventType), b.cfg.QueueGroup, sid)
+	return b.w.Flush()
+}
+
+// Close disconnects and stops consuming.
+func (b *NATS) Close() error {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return nil
+	}
+	b.closed = true
+	conn := b.conn
+	b.mu.Unlock()
+	if conn != nil {
+		conn.Close()
+	}
+	<-b.done
+	return nil
+}
+
+func (b *NATS) readLoop(conn net.Conn, r *bufio.Reader) {
+	defer close(b.done)
+	for {
+		err := b.consume(r)
+		b.mu.Lock()
+		closed := b.closed
+		b.conn, b.w = nil, nil
+		b.mu.Unlock()
+		conn.Close()
+		if closed {
+			return
+		}
+		log.Printf("nats: connection lost: %v", err)
+		if conn, r = b.reconnect(); conn == nil {
+			return
+		}
+	}
+}
+
+// consume processes server messages until the connection fails.
+func (b *NATS) consume(r *bufio.Reader) error {
+	for {
+		line, err := readLine(r)
+		if err != nil {
+			return err
+		}
+		switch {
+		case strings.HasPrefix(line, "MSG "):
+			payload, err := readPayload(r, line)
+			if err != nil {
+				return err
+			}
+			var e Event
+			if err := json.Unmarshal(payload, &e); err != nil {
+				log.Printf("nats: dropping malformed event: %v", err)
+				continue
+			}
+			if err := b.handlers.dispatch(e); err != nil {
+				log.Printf("nats: %v", err)
+			}
+		case line == "PING":
+			b.mu.Lock()
+			if b.w != nil {
+				b.w.WriteString("PONG\r\n")
+				b.w.Flush()
+			}
+			b.mu.Unlock()
+		case strings.HasPrefix(line, "-ERR"):
+			log.Printf("nats: server error: %s", line)
+		}
+	}
+}
+
+// reconnect dials with exponential backoff until it succeeds or the bus is closed,
+// then restores every subscription.
+func (b *NATS) reconnect() (net.Conn, *bufio.Reader) {
+	delay := 500 * time.Millisecond
+	for {
+		b.mu.Lock()
+		closed := b.closed
+		b.mu.Unlock()
+		if closed {
+			return nil, nil
+		}
+		conn, r, err := b.dial(context.Background())
+		if err == nil {
+			b.mu.Lock()
+			if b.closed {
+				b.mu.Unlock()
+				conn.Close()
+				return nil, nil
+			}
+			b.conn, b.w = conn, bufio.NewWriter(conn)
+			for eventType, sid := range b.subs {
+				if err := b.sub(eventType, sid); err != nil {
+					log.Printf("nats: resubscribe %s: %v", eventType, err)
+				}
+			}
+			b.mu.Unlock()
+			log.Printf("nats: reconnected to %s", b.addr)
+			return conn, r
+		}
+		log.Printf("nats: reconnect failed: %v", err)
+		time.Sleep(delay)
+		delay = min(delay*2, 30*time.Second)
+	}
+}
+
+func readLine(r *bufio.Reader) (string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// readPayload reads the body announced by "MSG <subject> <sid> [reply-to] <#bytes>".
+func readPayload(r *bufio.Reader, header string) ([]byte, error) {
+	fields := strings.Fields(header)
+	if len(fields) < 4 {
+		return nil, fmt.Errorf("malformed MSG header %q", header)
+	}
+	size, err := strconv.Atoi(fields[len(fields)-1])
+	if err != nil || size < 0 {
+		return nil, fmt.Errorf("malformed MSG size in %q", header)
+	}
+	buf := make([]byte, size+2)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return nil, err
+	}
+	return buf[:size], nil
+}
@@ -0,0 +1,128 @@
+// Package feed tells players about their balance as it moves, for the
+// GET /events stream. The ledger is the feed: every entry is an event with the
+// entry's ID, so a client that reconnects with the last ID it saw resumes
+// where it left off, on any instance. Streams are woken as soon as this
+// instance hears of a movement and poll the ledger in between, which catches
+// movements announced to other instances and those never announced at all,
+// such as provider callbacks.
+package feed
+
+import (
+	"context"
+	"sync"
+
+	"github.com/hongminglow/all-in-be/internal/models"
+	"github.com/hongminglow/all-in-be/internal/storage"
+)
+
+// Event types, by the reason of the ledger entry.
+const (
+	TypeBalanceChanged = "balance.changed"
+	TypeBonusGranted   = "bonus.granted"
+	TypeBetSettled     = "bet.settled"
+)
+
+// BatchSize bounds the events Since returns at once.
+const BatchSize = 100
+
+// Event is one ledger entry as the player sees it.
+type Event struct {
+	ID   int64
+	Type string
+	Data models.Transaction
+}
+
+// Service reads users' events and wakes their open streams.
+type Service struct {
+	store storage.WalletStore
+
+	mu      sync.Mutex
+	watches map[int64]map[chan struct{}]struct{}
+	done    chan struct{}
+	closed  bool
+}
+
+// NewService builds a service reading events from the ledger in store.
+func NewService(store storage.WalletStore) *Service {
+	return &Service{store: store, watches: make(map[int64]map[chan struct{}]struct{}), done: make(chan struct{})}
+}
+
+// Cursor returns the ID of the user's newest event, where a stream that
+// resumes nothing starts.
+func (s *Service) Cursor(ctx context.Context, userID int64) (int64, error) {
+	return s.store.LastTransactionID(ctx, userID)
+}
+
+// Since returns up to BatchSize of the user's events after the one with the
+// given ID, oldest first.
+func (s *Service) Since(ctx context.Context, userID, after int64) ([]Event, error) {
+	entries, err := s.store.ListTransactionsAfter(ctx, userID, after, BatchSize)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]Event, 0, len(entries))
+	for _, entry := range entries {
+		out = append(out, Event{ID: entry.ID, Type: eventType(entry.Reason), Data: entry})
+	}
+	return out, nil
+}
+
+func eventType(reason string) string {
+	switch reason {
+	case models.TransactionBonusGrant, models.TransactionWelcomeBonus, models.TransactionPromoCredit:
+		return TypeBonusGranted
+	case models.TransactionBetSettlement:
+		return TypeBetSettled
+	default:
+		return TypeBalanceChanged
+	}
+}
+
+// Watch registers a stream of the user's events. wake receives when Notify
+// is called for the user; stop unregisters it.
+func (s *Service) Watch(userID int64) (wake <-chan struct{}, stop func()) {
+	ch := make(chan struct{}, 1)
+	s.mu.Lock()
+	if s.watches[userID] == nil {
+		s.watches[userID] = make(map[chan struct{}]struct{})
+	}
+	s.watches[userID][ch] = struct{}{}
+	s.mu.Unlock()
+	return ch, func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		delete(s.watches[userID], ch)
+		if len(s.watches[userID]) == 0 {
+			delete(s.watches, userID)
+		}
+	}
+}
+
+// Notify wakes the user's streams on this instance. It never blocks: a
+// stream that has not caught up with the last wake-up reads this movement too.
+func (s *Service) Notify(userID int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for ch := range s.watches[userID] {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Done is closed once Close is called; streams end when it is.
+func (s *Service) Done() <-chan struct{} {
+	return s.done
+}
+
+// Close ends every stream so the HTTP server can shut down; clients
+// reconnect to another instance and resume from their last event.
+func (s *Service) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.closed {
+		s.closed = true
+		close(s.done)
+	}
+}
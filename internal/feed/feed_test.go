@@ -0,0 +1,78 @@
+package feed
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/hongminglow/all-in-be/internal/models"
+	"github.com/hongminglow/all-in-be/internal/storage/storagetest"
+)
+
+func TestSinceNamesEventsByLedgerReason(t *testing.T) {
+	ctx := context.Background()
+	store := storagetest.NewMemoryStore(storagetest.NewFakeClock(time.Date(2026, 3, 11, 9, 0, 0, 0, time.UTC)))
+	user, err := store.CreateUser(ctx, models.User{Username: "ana", Email: "ana@example.com", Phone: "+60123456789", Role: models.NormalUser})
+	if err != nil {
+		t.Fatal(err)
+	}
+	service := NewService(store)
+	if cursor, err := service.Cursor(ctx, user.ID); cursor != 0 || err != nil {
+		t.Fatalf("cursor of an empty ledger = %d, %v", cursor, err)
+	}
+	for _, reason := range []string{models.TransactionDeposit, models.TransactionBonusGrant, models.TransactionBetSettlement, models.TransactionPromoCredit} {
+		if _, err := store.ApplyTransaction(ctx, models.Transaction{UserID: user.ID, Amount: 10, Reason: reason}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	all, err := service.Since(ctx, user.ID, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{TypeBalanceChanged, TypeBonusGranted, TypeBetSettled, TypeBonusGranted}
+	if len(all) != len(want) {
+		t.Fatalf("events = %+v", all)
+	}
+	for i, e := range all {
+		if e.Type != want[i] || e.ID != e.Data.ID {
+			t.Errorf("event %d = %+v, want type %s", i, e, want[i])
+		}
+	}
+	if rest, _ := service.Since(ctx, user.ID, all[1].ID); len(rest) != 2 || rest[0].ID != all[2].ID {
+		t.Fatalf("events after %d = %+v", all[1].ID, rest)
+	}
+	if cursor, _ := service.Cursor(ctx, user.ID); cursor != all[3].ID {
+		t.Fatalf("cursor = %d, want the newest entry %d", cursor, all[3].ID)
+	}
+}
+
+func TestNotifyWakesOnlyTheUsersStreams(t *testing.T) {
+	service := NewService(nil)
+	wake, stop := service.Watch(1)
+	other, stopOther := service.Watch(2)
+	defer stopOther()
+
+	service.Notify(1)
+	service.Notify(1)
+	select {
+	case <-wake:
+	default:
+		t.Fatal("the user's stream was not woken")
+	}
+	select {
+	case <-other:
+		t.Fatal("another user's stream was woken")
+	default:
+	}
+
+	stop()
+	service.Notify(1)
+	service.Close()
+	service.Close()
+	select {
+	case <-wake:
+		t.Fatal("a stopped stream was woken")
+	case <-service.Done():
+	}
+}
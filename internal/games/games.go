@@ -0,0 +1,183 @@
+// Package games launches players into games hosted by external providers.
+// A launch hands the provider a random token naming a new game session; the
+// provider's first wallet callback starts the session and every later one
+// must fall within it, so bets cannot be placed on expired sessions or on
+// other players' sessions. The player's responsible gaming limits shorten
+// sessions, cap the day's losses and, during an exclusion, close games.
+package games
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/hongminglow/all-in-be/internal/clock"
+	"github.com/hongminglow/all-in-be/internal/config"
+	"github.com/hongminglow/all-in-be/internal/integrations"
+	"github.com/hongminglow/all-in-be/internal/limits"
+	"github.com/hongminglow/all-in-be/internal/models"
+	"github.com/hongminglow/all-in-be/internal/storage"
+)
+
+// ErrUnknownGame is returned for launches of a game at no configured provider.
+var ErrUnknownGame = errors.New("unknown game")
+
+// Reasons Check rejects a callback.
+var (
+	ErrUnknownSession = fmt.Errorf("%w: unknown session", integrations.ErrSessionRejected)
+	ErrForeignSession = fmt.Errorf("%w: session belongs to another player or provider", integrations.ErrSessionRejected)
+	ErrSessionExpired = fmt.Errorf("%w: session expired", integrations.ErrSessionRejected)
+	ErrLossLimit      = fmt.Errorf("%w: %w", integrations.ErrSessionRejected, limits.ErrLossLimit)
+	ErrExcluded       = fmt.Errorf("%w: %w", integrations.ErrSessionRejected, limits.ErrExcluded)
+)
+
+// Store is the data sessions are launched and checked against.
+type Store interface {
+	storage.GameSessionStore
+	storage.GamingLimitStore
+}
+
+// Ticket is what a launch hands the player.
+type Ticket struct {
+	Session models.GameSession
+	// Token names the session in the provider's callbacks. It is not stored.
+	Token string
+	// URL opens the game at the provider with the token.
+	URL string
+}
+
+// Service launches game sessions and checks provider callbacks against them.
+// It implements integrations.SessionChecker.
+type Service struct {
+	store Store
+	clock clock.Clock
+	cfg   config.GamesConfig
+}
+
+// NewService builds a service launching games at the providers in cfg.
+func NewService(store Store, clk clock.Clock, cfg config.GamesConfig) *Service {
+	return &Service{store: store, clock: clk, cfg: cfg}
+}
+
+// Launch opens a session for the user at the game with the given ID, given as
+// provider:game. The provider has LaunchTTL to start it. Excluded players
+// get limits.ErrExcluded.
+func (s *Service) Launch(ctx context.Context, user models.User, gameID string) (Ticket, error) {
+	provider, game, ok := strings.Cut(gameID, ":")
+	launchURL, known := s.cfg.Providers[provider]
+	if !ok || !known || game == "" {
+		return Ticket{}, ErrUnknownGame
+	}
+	playerLimits, err := limits.Find(ctx, s.store, user.ID)
+	if err != nil {
+		return Ticket{}, err
+	}
+	if playerLimits.Excluded(s.clock.Now()) {
+		return Ticket{}, limits.ErrExcluded
+	}
+	token, hash, err := newToken()
+	if err != nil {
+		return Ticket{}, err
+	}
+	session, err := s.store.CreateGameSession(ctx, models.GameSession{
+		UserID:    user.ID,
+		Provider:  provider,
+		GameID:    game,
+		TokenHash: hash,
+		ExpiresAt: s.clock.Now().Add(s.cfg.LaunchTTL),
+	})
+	if err != nil {
+		return Ticket{}, fmt.Errorf("create game session: %w", err)
+	}
+	u, err := url.Parse(launchURL)
+	if err != nil {
+		return Ticket{}, fmt.Errorf("parse %s launch URL: %w", provider, err)
+	}
+	query := u.Query()
+	query.Set("game", game)
+	query.Set("token", token)
+	u.RawQuery = query.Encode()
+	return Ticket{Session: session, Token: token, URL: u.String()}, nil
+}
+
+// Check accepts a callback received at the given time only within a live
+// session of the user at provider, and stakes only within the player's limits.
+// The first callback starts the session, which then lasts SessionTTL or the
+// player's session limit, whichever is shorter.
+func (s *Service) Check(ctx context.Context, tx storage.Repositories, provider, token string, userID int64, amount float64, at time.Time) error {
+	session, err := s.Find(ctx, tx, provider, token)
+	if err != nil {
+		return err
+	}
+	if session.UserID != userID {
+		return ErrForeignSession
+	}
+	if !at.Before(session.ExpiresAt) {
+		return ErrSessionExpired
+	}
+	switch err := limits.CheckStake(ctx, tx, userID, amount, at); {
+	case errors.Is(err, limits.ErrLossLimit):
+		return ErrLossLimit
+	case errors.Is(err, limits.ErrExcluded):
+		return ErrExcluded
+	case err != nil:
+		return err
+	}
+	if session.StartedAt != nil {
+		return nil
+	}
+	playerLimits, err := limits.Find(ctx, tx, userID)
+	if err != nil {
+		return err
+	}
+	ttl := s.cfg.SessionTTL
+	if m := playerLimits.Effective(at).SessionMinutes; m != nil && time.Duration(*m)*time.Minute < ttl {
+		ttl = time.Duration(*m) * time.Minute
+	}
+	err = tx.StartGameSession(ctx, session.ID, at, at.Add(ttl))
+	if errors.Is(err, storage.ErrNotFound) {
+		// A concurrent first callback started it.
+		return nil
+	}
+	return err
+}
+
+// Find returns the session token names at provider, whether or not it is
+// live, e.g. to pay out a bet placed before it expired.
+func (s *Service) Find(ctx context.Context, tx storage.GameSessionStore, provider, token string) (models.GameSession, error) {
+	if token == "" {
+		return models.GameSession{}, ErrUnknownSession
+	}
+	session, err := tx.FindGameSession(ctx, hashToken(token))
+	if errors.Is(err, storage.ErrNotFound) {
+		return models.GameSession{}, ErrUnknownSession
+	}
+	if err != nil {
+		return models.GameSession{}, fmt.Errorf("find game session: %w", err)
+	}
+	if session.Provider != provider {
+		return models.GameSession{}, ErrForeignSession
+	}
+	return session, nil
+}
+
+func newToken() (token, hash string, err error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", err
+	}
+	token = base64.RawURLEncoding.EncodeToString(buf)
+	return token, hashToken(token), nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,113 @@
+package games
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/hongminglow/all-in-be/internal/config"
+	"github.com/hongminglow/all-in-be/internal/integrations"
+	"github.com/hongminglow/all-in-be/internal/limits"
+	"github.com/hongminglow/all-in-be/internal/models"
+	"github.com/hongminglow/all-in-be/internal/storage"
+	"github.com/hongminglow/all-in-be/internal/storage/storagetest"
+)
+
+func TestLaunchAndCheck(t *testing.T) {
+	ctx := context.Background()
+	clk := storagetest.NewFakeClock(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	store := storagetest.NewMemoryStore(clk)
+	ana, _ := store.CreateUser(ctx, models.User{Username: "ana", Email: "ana@example.com", Role: models.NormalUser})
+	service := NewService(store, clk, config.GamesConfig{
+		Providers:  map[string]string{"reels": "https://reels.example/play?operator=allin"},
+		LaunchTTL:  2 * time.Minute,
+		SessionTTL: time.Hour,
+	})
+	check := func(token string, userID int64, at time.Time) error {
+		return store.WithTx(ctx, func(tx storage.Repositories) error {
+			return service.Check(ctx, tx, "reels", token, userID, -1, at)
+		})
+	}
+
+	for _, id := range []string{"starburst", "arcade:pong", "reels:"} {
+		if _, err := service.Launch(ctx, ana, id); !errors.Is(err, ErrUnknownGame) {
+			t.Fatalf("launch %q: err = %v, want ErrUnknownGame", id, err)
+		}
+	}
+	ticket, err := service.Launch(ctx, ana, "reels:starburst")
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, _ := url.Parse(ticket.URL)
+	if q := u.Query(); q.Get("operator") != "allin" || q.Get("game") != "starburst" || q.Get("token") != ticket.Token {
+		t.Fatalf("launch URL = %s", ticket.URL)
+	}
+
+	launchedAt := clk.Now()
+	if err := check(ticket.Token, ana.ID, launchedAt.Add(time.Minute)); err != nil {
+		t.Fatalf("first callback: %v", err)
+	}
+	clk.Advance(2 * time.Hour)
+	if err := check(ticket.Token, ana.ID, clk.Now()); !errors.Is(err, ErrSessionExpired) || !errors.Is(err, integrations.ErrSessionRejected) {
+		t.Fatalf("late callback: err = %v, want ErrSessionExpired", err)
+	}
+	if err := check(ticket.Token, ana.ID, launchedAt.Add(30*time.Minute)); err != nil {
+		t.Fatalf("replay of a callback received within the session: %v", err)
+	}
+	if err := check(ticket.Token, ana.ID+1, launchedAt.Add(30*time.Minute)); !errors.Is(err, ErrForeignSession) {
+		t.Fatalf("another player's callback: err = %v, want ErrForeignSession", err)
+	}
+}
+
+func TestLimitsShortenAndCloseSessions(t *testing.T) {
+	ctx := context.Background()
+	clk := storagetest.NewFakeClock(time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC))
+	store := storagetest.NewMemoryStore(clk)
+	ana, _ := store.CreateUser(ctx, models.User{Username: "ana", Email: "ana@example.com", Role: models.NormalUser, Balance: 100})
+	service := NewService(store, clk, config.GamesConfig{
+		Providers:  map[string]string{"reels": "https://reels.example/play"},
+		LaunchTTL:  2 * time.Minute,
+		SessionTTL: time.Hour,
+	})
+	loss, minutes := 30.0, 20
+	if _, err := store.SaveGamingLimits(ctx, models.GamingLimits{UserID: ana.ID, Limits: models.LimitSet{DailyLoss: &loss, SessionMinutes: &minutes}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.ApplyTransaction(ctx, models.Transaction{UserID: ana.ID, Amount: -25, Reason: models.TransactionBetSettlement}); err != nil {
+		t.Fatal(err)
+	}
+	check := func(token string, amount float64, at time.Time) error {
+		return store.WithTx(ctx, func(tx storage.Repositories) error {
+			return service.Check(ctx, tx, "reels", token, ana.ID, amount, at)
+		})
+	}
+
+	ticket, err := service.Launch(ctx, ana, "reels:starburst")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := check(ticket.Token, -10, clk.Now()); !errors.Is(err, ErrLossLimit) || !errors.Is(err, integrations.ErrSessionRejected) {
+		t.Fatalf("stake past the loss limit: err = %v, want ErrLossLimit", err)
+	}
+	if err := check(ticket.Token, -5, clk.Now()); err != nil {
+		t.Fatalf("stake up to the loss limit: %v", err)
+	}
+	if err := check(ticket.Token, 8, clk.Now().Add(19*time.Minute)); err != nil {
+		t.Fatalf("win within the session limit: %v", err)
+	}
+	if err := check(ticket.Token, 8, clk.Now().Add(21*time.Minute)); !errors.Is(err, ErrSessionExpired) {
+		t.Fatalf("callback past the session limit: err = %v, want ErrSessionExpired", err)
+	}
+
+	until := clk.Now().Add(24 * time.Hour)
+	store.SaveGamingLimits(ctx, models.GamingLimits{UserID: ana.ID, ExcludedUntil: &until})
+	if _, err := service.Launch(ctx, ana, "reels:starburst"); !errors.Is(err, limits.ErrExcluded) {
+		t.Fatalf("launch while excluded: err = %v, want limits.ErrExcluded", err)
+	}
+	clk.Advance(25 * time.Hour)
+	if _, err := service.Launch(ctx, ana, "reels:starburst"); err != nil {
+		t.Fatalf("launch after the exclusion ended: %v", err)
+	}
+}
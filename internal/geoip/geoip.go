@@ -0,0 +1,32 @@
+// Package geoip resolves client IP addresses to countries for jurisdiction
+// checks and audit records. Lookups read a MaxMind DB file (GeoLite2 or
+// GeoIP2 Country/City); the resolved country travels in the request context.
+package geoip
+
+import (
+	"context"
+	"strings"
+)
+
+type contextKey struct{}
+
+// WithCountry returns a copy of ctx carrying the caller's country code.
+func WithCountry(ctx context.Context, country string) context.Context {
+	return context.WithValue(ctx, contextKey{}, country)
+}
+
+// CountryFromContext returns the caller's ISO country code, or "" when it is unknown.
+func CountryFromContext(ctx context.Context) string {
+	country, _ := ctx.Value(contextKey{}).(string)
+	return country
+}
+
+// Normalize upper-cases an ISO 3166-1 alpha-2 code and returns "" for
+// anything else, including the XX (unknown) and T1 (Tor) codes CDNs send.
+func Normalize(code string) string {
+	country := strings.ToUpper(strings.TrimSpace(code))
+	if len(country) != 2 || country == "XX" || country == "T1" {
+		return ""
+	}
+	return country
+}
@@ -0,0 +1,352 @@
+package geoip
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"net/netip"
+	"os"
+)
+
+// metadataMarker precedes the metadata map at the end of every MaxMind DB file.
+var metadataMarker = []byte("\xAB\xCD\xEFMaxMind.com")
+
+// maxMetadataSize bounds how far from the end of the file the marker is searched for.
+const maxMetadataSize = 128 * 1024
+
+// dataSectionSeparator is the run of zero bytes between the search tree and the data section.
+const dataSectionSeparator = 16
+
+// DB is a MaxMind DB file (e.g. GeoLite2-Country.mmdb or GeoIP2-City.mmdb)
+// loaded into memory. It is safe for concurrent use.
+type DB struct {
+	tree       []byte
+	data       decoder
+	nodeCount  uint
+	recordSize uint
+	ipVersion  uint
+	// ipv4Start is the node reached after the 96 leading zero bits of an
+	// IPv4-mapped address in an IPv6 tree.
+	ipv4Start uint
+}
+
+// Open reads the database at path.
+func Open(path string) (*DB, error) {
+	buf, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("open geoip database: %w", err)
+	}
+	db, err := New(buf)
+	if err != nil {
+		return nil, fmt.Errorf("open geoip database %s: %w", path, err)
+	}
+	return db, nil
+}
+
+// New parses a database already held in memory.
+func New(buf []byte) (*DB, error) {
+	start := len(buf) - maxMetadataSize
+	if start < 0 {
+		start = 0
+	}
+	at := bytes.LastIndex(buf[start:], metadataMarker)
+	if at < 0 {
+		return nil, errors.New("not a MaxMind DB: metadata marker not found")
+	}
+	metaStart := start + at + len(metadataMarker)
+	raw, _, err := decoder{buf: buf[metaStart:]}.decode(0)
+	if err != nil {
+		return nil, fmt.Errorf("decode metadata: %w", err)
+	}
+	meta, ok := raw.(map[string]any)
+	if !ok {
+		return nil, errors.New("metadata is not a map")
+	}
+	db := &DB{
+		nodeCount:  metaUint(meta, "node_count"),
+		recordSize: metaUint(meta, "record_size"),
+		ipVersion:  metaUint(meta, "ip_version"),
+	}
+	if major := metaUint(meta, "binary_format_major_version"); major != 2 {
+		return nil, fmt.Errorf("unsupported binary format version %d", major)
+	}
+	switch db.recordSize {
+	case 24, 28, 32:
+	default:
+		return nil, fmt.Errorf("unsupported record size %d", db.recordSize)
+	}
+	if db.ipVersion != 4 && db.ipVersion != 6 {
+		return nil, fmt.Errorf("unsupported ip version %d", db.ipVersion)
+	}
+	treeSize := db.nodeCount * db.recordSize / 4
+	if treeSize+dataSectionSeparator > uint(start+at) {
+		return nil, errors.New("search tree is larger than the file")
+	}
+	db.tree = buf[:treeSize]
+	db.data = decoder{buf: buf[treeSize+dataSectionSeparator : start+at]}
+
+	if db.ipVersion == 6 {
+		node := uint(0)
+		for i := 0; i < 96 && node < db.nodeCount; i++ {
+			node = db.record(node, 0)
+		}
+		db.ipv4Start = node
+	}
+	return db, nil
+}
+
+// Country returns the ISO 3166-1 alpha-2 code of the country ip is located
+// in, falling back to the country it is registered in. It returns "" when the
+// database has no entry for ip.
+func (db *DB) Country(ip netip.Addr) (string, error) {
+	record, err := db.lookup(ip)
+	if err != nil || record == nil {
+		return "", err
+	}
+	for _, key := range []string{"country", "registered_country"} {
+		if country, ok := record[key].(map[string]any); ok {
+			if code, ok := country["iso_code"].(string); ok && code != "" {
+				return code, nil
+			}
+		}
+	}
+	return "", nil
+}
+
+// lookup walks the search tree for ip and decodes the record it ends at.
+func (db *DB) lookup(ip netip.Addr) (map[string]any, error) {
+	ip = ip.Unmap()
+	node := uint(0)
+	var addr []byte
+	switch {
+	case ip.Is4() && db.ipVersion == 6:
+		node = db.ipv4Start
+		v4 := ip.As4()
+		addr = v4[:]
+	case ip.Is4():
+		v4 := ip.As4()
+		addr = v4[:]
+	case ip.Is6() && db.ipVersion == 6:
+		v6 := ip.As16()
+		addr = v6[:]
+	default:
+		return nil, nil
+	}
+	for i := 0; i < len(addr)*8 && node < db.nodeCount; i++ {
+		bit := uint(addr[i/8]>>(7-i%8)) & 1
+		node = db.record(node, bit)
+	}
+	if node <= db.nodeCount {
+		return nil, nil
+	}
+	offset := node - db.nodeCount - dataSectionSeparator
+	value, _, err := db.data.decode(offset)
+	if err != nil {
+		return nil, fmt.Errorf("decode record for %s: %w", ip, err)
+	}
+	record, _ := value.(map[string]any)
+	return record, nil
+}
+
+// record reads the left (bit 0) or right (bit 1) record of node.
+func (db *DB) record(node, bit uint) uint {
+	width := db.recordSize / 4
+	n := db.tree[node*width : (node+1)*width]
+	switch db.recordSize {
+	case 24:
+		n = n[bit*3:]
+		return uint(n[0])<<16 | uint(n[1])<<8 | uint(n[2])
+	case 28:
+		if bit == 0 {
+			return uint(n[3]&0xF0)<<20 | uint(n[0])<<16 | uint(n[1])<<8 | uint(n[2])
+		}
+		return uint(n[3]&0x0F)<<24 | uint(n[4])<<16 | uint(n[5])<<8 | uint(n[6])
+	default:
+		return uint(binary.BigEndian.Uint32(n[bit*4:]))
+	}
+}
+
+func metaUint(meta map[string]any, key string) uint {
+	v, _ := meta[key].(uint64)
+	return uint(v)
+}
+
+// Data section field types.
+const (
+	typeExtended = iota
+	typePointer
+	typeString
+	typeDouble
+	typeBytes
+	typeUint16
+	typeUint32
+	typeMap
+	typeInt32
+	typeUint64
+	typeUint128
+	typeArray
+	typeContainer
+	typeEndMarker
+	typeBool
+	typeFloat
+)
+
+var errTruncated = errors.New("truncated data section")
+
+// decoder reads values from a data section. Offsets, including pointers,
+// are relative to the start of buf.
+type decoder struct {
+	buf []byte
+}
+
+// decode reads the value at offset and returns it with the offset just past it.
+// Maps decode to map[string]any, arrays to []any, unsigned integers to uint64
+// (uint128 to []byte), int32 to int64 and floats to float64.
+func (d decoder) decode(offset uint) (any, uint, error) {
+	kind, size, offset, err := d.control(offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	if kind == typePointer {
+		value, _, err := d.decodeAt(size)
+		return value, offset, err
+	}
+	return d.value(kind, size, offset)
+}
+
+// decodeAt follows a pointer; the target may not itself be a pointer.
+func (d decoder) decodeAt(offset uint) (any, uint, error) {
+	kind, size, offset, err := d.control(offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	if kind == typePointer {
+		return nil, 0, errors.New("pointer to a pointer")
+	}
+	return d.value(kind, size, offset)
+}
+
+// control parses a field's control byte. For pointers size is the target offset.
+func (d decoder) control(offset uint) (kind int, size uint, next uint, err error) {
+	if offset >= uint(len(d.buf)) {
+		return 0, 0, 0, errTruncated
+	}
+	ctrl := d.buf[offset]
+	offset++
+	kind = int(ctrl >> 5)
+	if kind == typePointer {
+		ptrSize := uint(ctrl>>3) & 0x3
+		if offset+ptrSize+1 > uint(len(d.buf)) {
+			return 0, 0, 0, errTruncated
+		}
+		b := d.buf[offset : offset+ptrSize+1]
+		v := uint(ctrl & 0x7)
+		switch ptrSize {
+		case 0:
+			size = v<<8 | uint(b[0])
+		case 1:
+			size = (v<<16 | uint(b[0])<<8 | uint(b[1])) + 2048
+		case 2:
+			size = (v<<24 | uint(b[0])<<16 | uint(b[1])<<8 | uint(b[2])) + 526336
+		default:
+			size = uint(binary.BigEndian.Uint32(b))
+		}
+		return kind, size, offset + ptrSize + 1, nil
+	}
+	if kind == typeExtended {
+		if offset >= uint(len(d.buf)) {
+			return 0, 0, 0, errTruncated
+		}
+		kind = 7 + int(d.buf[offset])
+		offset++
+	}
+	size = uint(ctrl & 0x1f)
+	if size >= 29 {
+		n := size - 28
+		if offset+n > uint(len(d.buf)) {
+			return 0, 0, 0, errTruncated
+		}
+		b := d.buf[offset : offset+n]
+		switch n {
+		case 1:
+			size = 29 + uint(b[0])
+		case 2:
+			size = 285 + (uint(b[0])<<8 | uint(b[1]))
+		default:
+			size = 65821 + (uint(b[0])<<16 | uint(b[1])<<8 | uint(b[2]))
+		}
+		offset += n
+	}
+	return kind, size, offset, nil
+}
+
+func (d decoder) value(kind int, size, offset uint) (any, uint, error) {
+	switch kind {
+	case typeMap:
+		m := make(map[string]any, size)
+		for i := uint(0); i < size; i++ {
+			key, next, err := d.decode(offset)
+			if err != nil {
+				return nil, 0, err
+			}
+			k, ok := key.(string)
+			if !ok {
+				return nil, 0, fmt.Errorf("map key is %T, not a string", key)
+			}
+			if m[k], offset, err = d.decode(next); err != nil {
+				return nil, 0, err
+			}
+		}
+		return m, offset, nil
+	case typeArray:
+		a := make([]any, 0, size)
+		for i := uint(0); i < size; i++ {
+			v, next, err := d.decode(offset)
+			if err != nil {
+				return nil, 0, err
+			}
+			a = append(a, v)
+			offset = next
+		}
+		return a, offset, nil
+	case typeBool:
+		return size != 0, offset, nil
+	}
+	if offset+size > uint(len(d.buf)) {
+		return nil, 0, errTruncated
+	}
+	b := d.buf[offset : offset+size]
+	next := offset + size
+	switch kind {
+	case typeString:
+		return string(b), next, nil
+	case typeBytes, typeUint128:
+		return append([]byte(nil), b...), next, nil
+	case typeDouble:
+		if size != 8 {
+			return nil, 0, fmt.Errorf("double of size %d", size)
+		}
+		return math.Float64frombits(binary.BigEndian.Uint64(b)), next, nil
+	case typeFloat:
+		if size != 4 {
+			return nil, 0, fmt.Errorf("float of size %d", size)
+		}
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(b))), next, nil
+	case typeUint16, typeUint32, typeUint64:
+		var v uint64
+		for _, c := range b {
+			v = v<<8 | uint64(c)
+		}
+		return v, next, nil
+	case typeInt32:
+		var v uint32
+		for _, c := range b {
+			v = v<<8 | uint32(c)
+		}
+		return int64(int32(v)), next, nil
+	default:
+		return nil, 0, fmt.Errorf("unsupported field type %d", kind)
+	}
+}
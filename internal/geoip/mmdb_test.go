@@ -0,0 +1,147 @@
+package geoip
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// testDB builds an IPv6 MaxMind DB mapping each network to a country record.
+// Records after the first reuse the "country" and "iso_code" keys through
+// pointers, as real databases do.
+func testDB(t *testing.T, recordSize int, networks map[string]string) []byte {
+	t.Helper()
+	type node struct{ rec [2]int }
+	const empty, dataFlag = -1, 1 << 30
+	nodes := []node{{rec: [2]int{empty, empty}}}
+
+	var data bytes.Buffer
+	keyOffsets := map[string]int{}
+	writeKey := func(key string) {
+		if at, ok := keyOffsets[key]; ok {
+			data.Write([]byte{1<<5 | byte(at>>8), byte(at)})
+			return
+		}
+		keyOffsets[key] = data.Len()
+		writeString(&data, key)
+	}
+	for cidr, country := range networks {
+		prefix := netip.MustParsePrefix(cidr)
+		addr, bits := prefix.Addr().As16(), prefix.Bits()
+		if prefix.Addr().Is4() {
+			// IPv4 networks live under ::/96, not the IPv4-mapped ::ffff:0:0/96.
+			v4 := prefix.Addr().As4()
+			addr = [16]byte{12: v4[0], 13: v4[1], 14: v4[2], 15: v4[3]}
+			bits += 96
+		}
+		offset := data.Len()
+		data.WriteByte(7<<5 | 1)
+		writeKey("country")
+		data.WriteByte(7<<5 | 1)
+		writeKey("iso_code")
+		writeString(&data, country)
+
+		n := 0
+		for i := 0; i < bits; i++ {
+			bit := int(addr[i/8]>>(7-i%8)) & 1
+			if i == bits-1 {
+				nodes[n].rec[bit] = dataFlag | offset
+				break
+			}
+			if nodes[n].rec[bit] == empty {
+				nodes = append(nodes, node{rec: [2]int{empty, empty}})
+				nodes[n].rec[bit] = len(nodes) - 1
+			}
+			n = nodes[n].rec[bit]
+		}
+	}
+
+	var file bytes.Buffer
+	for _, n := range nodes {
+		var values [2]uint32
+		for i, rec := range n.rec {
+			switch {
+			case rec == empty:
+				values[i] = uint32(len(nodes))
+			case rec&dataFlag != 0:
+				values[i] = uint32(len(nodes) + dataSectionSeparator + rec&^dataFlag)
+			default:
+				values[i] = uint32(rec)
+			}
+		}
+		switch recordSize {
+		case 24:
+			file.Write([]byte{byte(values[0] >> 16), byte(values[0] >> 8), byte(values[0]), byte(values[1] >> 16), byte(values[1] >> 8), byte(values[1])})
+		case 28:
+			file.Write([]byte{byte(values[0] >> 16), byte(values[0] >> 8), byte(values[0]), byte(values[0]>>24)<<4 | byte(values[1]>>24), byte(values[1] >> 16), byte(values[1] >> 8), byte(values[1])})
+		default:
+			file.Write(binary.BigEndian.AppendUint32(nil, values[0]))
+			file.Write(binary.BigEndian.AppendUint32(nil, values[1]))
+		}
+	}
+	file.Write(make([]byte, dataSectionSeparator))
+	file.Write(data.Bytes())
+	file.Write(metadataMarker)
+	file.WriteByte(7<<5 | 5)
+	writeString(&file, "node_count")
+	file.Write([]byte{6<<5 | 4})
+	file.Write(binary.BigEndian.AppendUint32(nil, uint32(len(nodes))))
+	writeString(&file, "record_size")
+	file.Write([]byte{5<<5 | 2, 0, byte(recordSize)})
+	writeString(&file, "ip_version")
+	file.Write([]byte{5<<5 | 1, 6})
+	writeString(&file, "binary_format_major_version")
+	file.Write([]byte{5<<5 | 1, 2})
+	writeString(&file, "database_type")
+	writeString(&file, "Test-Country")
+	return file.Bytes()
+}
+
+func writeString(buf *bytes.Buffer, s string) {
+	buf.WriteByte(2<<5 | byte(len(s)))
+	buf.WriteString(s)
+}
+
+func TestCountry(t *testing.T) {
+	networks := map[string]string{
+		"203.0.113.0/24":  "MY",
+		"198.51.100.0/25": "SG",
+		"2001:db8::/32":   "US",
+	}
+	for _, recordSize := range []int{24, 28, 32} {
+		db, err := New(testDB(t, recordSize, networks))
+		if err != nil {
+			t.Fatalf("record size %d: %v", recordSize, err)
+		}
+		for ip, want := range map[string]string{
+			"203.0.113.9":        "MY",
+			"::ffff:203.0.113.9": "MY",
+			"198.51.100.1":       "SG",
+			"198.51.100.200":     "",
+			"192.0.2.1":          "",
+			"2001:db8::1":        "US",
+			"2001:db9::1":        "",
+		} {
+			got, err := db.Country(netip.MustParseAddr(ip))
+			if err != nil || got != want {
+				t.Errorf("record size %d: Country(%s) = %q, %v; want %q", recordSize, ip, got, err, want)
+			}
+		}
+	}
+}
+
+func TestOpenRejectsOtherFiles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "country.mmdb")
+	if err := os.WriteFile(path, []byte("not a database"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Open(path); err == nil {
+		t.Fatal("Open accepted a file without MaxMind metadata")
+	}
+	if _, err := Open(filepath.Join(t.TempDir(), "missing.mmdb")); err == nil {
+		t.Fatal("Open accepted a missing file")
+	}
+}
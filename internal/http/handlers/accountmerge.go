@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/hongminglow/all-in-be/internal/accounts"
+	"github.com/hongminglow/all-in-be/internal/http/respond"
+	"github.com/hongminglow/all-in-be/internal/middleware"
+	"github.com/hongminglow/all-in-be/internal/models"
+	"github.com/hongminglow/all-in-be/internal/models/dto"
+	"github.com/hongminglow/all-in-be/internal/storage"
+)
+
+// AccountMergeHandler folds a player's duplicate account into their main one.
+type AccountMergeHandler struct {
+	merger *accounts.Merger
+}
+
+// NewAccountMergeHandler constructs the handler.
+func NewAccountMergeHandler(merger *accounts.Merger) *AccountMergeHandler {
+	return &AccountMergeHandler{merger: merger}
+}
+
+// Register attaches the admin route. It must be mounted behind middleware.Authenticate.
+func (h *AccountMergeHandler) Register(mux Router) {
+	mux.Handle("POST /admin/users/{id}/merge", middleware.RequirePermission(models.PermUsersMerge, http.HandlerFunc(h.handleMerge)))
+}
+
+// handleMerge merges the duplicate named in the body into the user in the
+// path, or with dry_run reports what a merge would move.
+func (h *AccountMergeHandler) handleMerge(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+	var req dto.MergeAccountRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respond.Error(w, http.StatusBadRequest, "invalid JSON payload")
+		return
+	}
+	if req.DuplicateID <= 0 {
+		respond.Error(w, http.StatusBadRequest, "duplicate_id is required")
+		return
+	}
+	actor, _ := middleware.UserFromContext(r.Context())
+	merge, err := h.merger.Merge(r.Context(), id, req.DuplicateID, actor.ID, req.DryRun)
+	switch {
+	case err == nil:
+	case errors.Is(err, storage.ErrNotFound):
+		respond.Error(w, http.StatusNotFound, "user not found")
+		return
+	case errors.Is(err, accounts.ErrMergeSelf), errors.Is(err, accounts.ErrMergeNotPlayer):
+		respond.Error(w, http.StatusBadRequest, err.Error())
+		return
+	case errors.Is(err, accounts.ErrAlreadyMerged), errors.Is(err, accounts.ErrMergeWithdrawals), errors.Is(err, accounts.ErrMergeFundsHeld),
+		errors.Is(err, accounts.ErrMergeLegalHold), errors.Is(err, accounts.ErrMergeSelfExcluded):
+		respond.Error(w, http.StatusConflict, err.Error())
+		return
+	case errors.Is(err, storage.ErrVersionConflict):
+		respond.Error(w, http.StatusConflict, "an account changed during the merge; try again")
+		return
+	default:
+		log.Printf("merge user %d into user %d: %v", req.DuplicateID, id, err)
+		respond.Error(w, http.StatusInternalServerError, "failed to merge accounts")
+		return
+	}
+	if merge.DryRun {
+		respond.JSON(w, http.StatusOK, "dry run; nothing was merged", merge)
+		return
+	}
+	log.Printf("account merge %d: user %d merged user %d into user %d, moving %.2f", merge.ID, actor.ID, merge.DuplicateID, merge.UserID, merge.Balance)
+	respond.JSON(w, http.StatusOK, "accounts merged", merge)
+}
@@ -0,0 +1,124 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/hongminglow/all-in-be/internal/accounts"
+	"github.com/hongminglow/all-in-be/internal/auth"
+	"github.com/hongminglow/all-in-be/internal/http/respond"
+	"github.com/hongminglow/all-in-be/internal/middleware"
+	"github.com/hongminglow/all-in-be/internal/models"
+	"github.com/hongminglow/all-in-be/internal/models/dto"
+	"github.com/hongminglow/all-in-be/internal/storage"
+)
+
+// API key lifetimes in days.
+const (
+	defaultAPIKeyDays = 90
+	maxAPIKeyDays     = 365
+)
+
+// APIKeyHandler lets staff issue and revoke the API keys machine clients,
+// such as provider backends, authenticate with.
+type APIKeyHandler struct {
+	store storage.APIKeyStore
+	keys  *auth.APIKeys
+}
+
+// NewAPIKeyHandler constructs the handler.
+func NewAPIKeyHandler(store storage.APIKeyStore, keys *auth.APIKeys) *APIKeyHandler {
+	return &APIKeyHandler{store: store, keys: keys}
+}
+
+// Register attaches the admin routes. They must be mounted behind middleware.Authenticate.
+func (h *APIKeyHandler) Register(mux Router) {
+	mux.Handle("GET /admin/api-keys", middleware.RequirePermission(models.PermIntegrations, http.HandlerFunc(h.handleList)))
+	mux.Handle("POST /admin/api-keys", middleware.RequirePermission(models.PermIntegrations, http.HandlerFunc(h.handleCreate)))
+	mux.Handle("DELETE /admin/api-keys/{id}", middleware.RequirePermission(models.PermIntegrations, http.HandlerFunc(h.handleRevoke)))
+}
+
+func (h *APIKeyHandler) handleList(w http.ResponseWriter, r *http.Request) {
+	keys, err := h.store.ListAPIKeys(r.Context())
+	if err != nil {
+		log.Printf("list api keys error: %v", err)
+		respond.Error(w, http.StatusInternalServerError, "failed to list API keys")
+		return
+	}
+	respond.JSON(w, http.StatusOK, "API keys fetched", keys)
+}
+
+// handleCreate issues a key acting for the caller. Its scopes must be
+// permissions the caller holds, so a key never grants more than its creator.
+func (h *APIKeyHandler) handleCreate(w http.ResponseWriter, r *http.Request) {
+	if _, ok := middleware.APIKeyFromContext(r.Context()); ok {
+		respond.Error(w, http.StatusForbidden, "API keys cannot issue API keys")
+		return
+	}
+	var req dto.CreateAPIKeyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respond.Error(w, http.StatusBadRequest, "invalid JSON payload")
+		return
+	}
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		respond.Error(w, http.StatusBadRequest, "name is required")
+		return
+	}
+	if len(req.Scopes) == 0 {
+		respond.Error(w, http.StatusBadRequest, "scopes must list at least one permission")
+		return
+	}
+	actor, _ := middleware.UserFromContext(r.Context())
+	scopes, err := accounts.GrantedScopes(req.Scopes, actor)
+	if err != nil {
+		respond.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	for _, scope := range scopes {
+		if !middleware.HasPermission(r.Context(), scope) {
+			respond.Error(w, http.StatusBadRequest, fmt.Sprintf("scope %q is outside your token's scopes", scope))
+			return
+		}
+	}
+	days := req.ExpiresInDays
+	if days == 0 {
+		days = defaultAPIKeyDays
+	}
+	if days < 0 || days > maxAPIKeyDays {
+		respond.Error(w, http.StatusBadRequest, fmt.Sprintf("expires_in_days must be between 1 and %d", maxAPIKeyDays))
+		return
+	}
+	key, err := h.keys.Create(r.Context(), name, scopes, time.Duration(days)*24*time.Hour, actor.ID)
+	if err != nil {
+		log.Printf("create api key error: %v", err)
+		respond.Error(w, http.StatusInternalServerError, "failed to create API key")
+		return
+	}
+	respond.Created(w, fmt.Sprintf("/admin/api-keys/%d", key.ID), "API key created; store the key, it will not be shown again", key)
+}
+
+// handleRevoke revokes a key (DELETE). The key is kept, marked revoked.
+func (h *APIKeyHandler) handleRevoke(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+	actor, _ := middleware.UserFromContext(r.Context())
+	key, err := h.store.RevokeAPIKey(r.Context(), id, actor.ID)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			respond.Error(w, http.StatusNotFound, "active API key not found")
+			return
+		}
+		log.Printf("revoke api key error: %v", err)
+		respond.Error(w, http.StatusInternalServerError, "failed to revoke API key")
+		return
+	}
+	respond.JSON(w, http.StatusOK, "API key revoked", key)
+}
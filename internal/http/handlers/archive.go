@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/hongminglow/all-in-be/internal/archive"
+	"github.com/hongminglow/all-in-be/internal/http/respond"
+	"github.com/hongminglow/all-in-be/internal/middleware"
+	"github.com/hongminglow/all-in-be/internal/models"
+)
+
+// Archiver moves cold rows out of the hot tables.
+type Archiver interface {
+	Run(ctx context.Context) ([]models.ArchiveRun, error)
+}
+
+// ArchiveHandler lets operators run the archiver now instead of waiting for
+// its schedule, e.g. after lowering the retention window.
+type ArchiveHandler struct {
+	archiver Archiver
+}
+
+// NewArchiveHandler constructs the handler.
+func NewArchiveHandler(archiver Archiver) *ArchiveHandler {
+	return &ArchiveHandler{archiver: archiver}
+}
+
+// Register attaches the admin route. It must be mounted behind middleware.Authenticate.
+func (h *ArchiveHandler) Register(mux Router) {
+	mux.Handle("POST /admin/archive/run", middleware.RequirePermission(models.PermConfigManage, http.HandlerFunc(h.handleRun)))
+}
+
+func (h *ArchiveHandler) handleRun(w http.ResponseWriter, r *http.Request) {
+	runs, err := h.archiver.Run(r.Context())
+	if errors.Is(err, archive.ErrDisabled) {
+		respond.Error(w, http.StatusConflict, "archiving is disabled; set ARCHIVE_AFTER_MONTHS to enable it")
+		return
+	}
+	if err != nil {
+		log.Printf("archive run: %v", err)
+		respond.Error(w, http.StatusInternalServerError, "failed to archive records")
+		return
+	}
+	respond.JSON(w, http.StatusOK, "records archived", runs)
+}
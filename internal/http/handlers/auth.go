@@ -6,139 +6,171 @@ import (
 	"log"
 	"net/http"
 	"strings"
-	"unicode/utf8"
-
-	"golang.org/x/crypto/bcrypt"
 
+	"github.com/hongminglow/all-in-be/internal/accounts"
 	"github.com/hongminglow/all-in-be/internal/auth"
+	"github.com/hongminglow/all-in-be/internal/captcha"
 	"github.com/hongminglow/all-in-be/internal/config"
+	"github.com/hongminglow/all-in-be/internal/emailcheck"
 	"github.com/hongminglow/all-in-be/internal/http/respond"
-	"github.com/hongminglow/all-in-be/internal/models"
+	"github.com/hongminglow/all-in-be/internal/middleware"
 	"github.com/hongminglow/all-in-be/internal/models/dto"
+	"github.com/hongminglow/all-in-be/internal/phone"
+	"github.com/hongminglow/all-in-be/internal/security"
 	"github.com/hongminglow/all-in-be/internal/storage"
+	"github.com/hongminglow/all-in-be/internal/usernames"
 )
 
 // AuthHandler owns register/login endpoints backed by Neon Auth & Postgres.
 type AuthHandler struct {
-	store  storage.UserStore
-	tokens *auth.TokenManager
-	cfg    *config.Config
+	accounts *accounts.Service
+	sessions *security.Service
+	cfg      *config.Config
 }
 
-// NewAuthHandler constructs the handler.
-func NewAuthHandler(store storage.UserStore, tokens *auth.TokenManager, cfg *config.Config) *AuthHandler {
-	return &AuthHandler{store: store, tokens: tokens, cfg: cfg}
+// NewAuthHandler constructs the handler. sessions revokes the sessions of
+// users signing out.
+func NewAuthHandler(service *accounts.Service, sessions *security.Service, cfg *config.Config) *AuthHandler {
+	return &AuthHandler{accounts: service, sessions: sessions, cfg: cfg}
 }
 
 // Register attaches auth routes to the mux.
-func (h *AuthHandler) Register(mux *http.ServeMux) {
-	mux.HandleFunc("/register", h.handleRegister)
-	mux.HandleFunc("/login", h.handleLogin)
+func (h *AuthHandler) Register(mux Router) {
+	mux.HandleFunc("POST /login", h.handleLogin)
+}
+
+// RegisterLogout attaches /logout. It must be mounted behind
+// middleware.Identify so the signed-in caller's sessions can be revoked.
+func (h *AuthHandler) RegisterLogout(mux Router) {
+	mux.HandleFunc("POST /logout", h.handleLogout)
+}
+
+// RegisterSignup attaches /register, kept apart from Register so it can be
+// mounted behind jurisdiction checks.
+func (h *AuthHandler) RegisterSignup(mux Router) {
+	mux.HandleFunc("POST /register", h.handleRegister)
 }
 
 func (h *AuthHandler) handleRegister(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
 	var req dto.RegisterRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		respond.Error(w, http.StatusBadRequest, "invalid JSON payload")
 		return
 	}
-	phone := normalizePhone(req)
-	if err := validateCredentials(req.Username, req.Email, phone, req.Password); err != nil {
+	created, err := h.accounts.Register(r.Context(), accounts.Registration{
+		Username:     req.Username,
+		Email:        req.Email,
+		Phone:        rawPhone(req),
+		Password:     req.Password,
+		CaptchaToken: req.CaptchaToken,
+		IP:           requestDevice(r).IP,
+	})
+	var violation *usernames.Violation
+	switch {
+	case h.captchaFailed(w, err):
+	case errors.As(err, &violation):
+		respond.JSON(w, http.StatusBadRequest, violation.Message, violation)
+	case errors.Is(err, accounts.ErrMissingFields), errors.Is(err, accounts.ErrPasswordTooShort), errors.Is(err, accounts.ErrPasswordTooLong),
+		errors.Is(err, phone.ErrInvalid), errors.Is(err, phone.ErrRegionRequired),
+		errors.Is(err, emailcheck.ErrInvalid), errors.Is(err, emailcheck.ErrDisposable), errors.Is(err, emailcheck.ErrNoMailServer):
 		respond.Error(w, http.StatusBadRequest, err.Error())
-		return
-	}
-	passwordHash, err := hashPassword(req.Password)
-	if err != nil {
-		respond.Error(w, http.StatusInternalServerError, "failed to hash password")
-		return
+	case errors.Is(err, storage.ErrAlreadyExists):
+		respond.Error(w, http.StatusConflict, "user already exists")
+	case err != nil:
+		log.Printf("create user error: %v", err)
+		respond.Error(w, http.StatusInternalServerError, "failed to create user")
+	default:
+		respond.Created(w, "/me", "User created successfully", created)
 	}
-
-	user := models.User{
-		Username:     strings.TrimSpace(req.Username),
-		Email:        strings.TrimSpace(req.Email),
-		Phone:        phone,
-		Role:         models.NormalUser,
-		Balance:      h.cfg.InitBalance,
-		PasswordHash: passwordHash,
-	}
-	created, err := h.store.CreateUser(r.Context(), user)
-	if err != nil {
-		switch {
-		case errors.Is(err, storage.ErrAlreadyExists):
-			respond.Error(w, http.StatusConflict, "user already exists")
-		default:
-			log.Printf("create user error: %v", err)
-			respond.Error(w, http.StatusInternalServerError, "failed to create user")
-		}
-		return
-	}
-
-	respond.JSON(w, http.StatusOK, "User created successfully", created)
 }
 
 func (h *AuthHandler) handleLogin(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
 	var req dto.LoginRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		respond.Error(w, http.StatusBadRequest, "invalid JSON payload")
 		return
 	}
-	if strings.TrimSpace(req.Identifier) == "" || strings.TrimSpace(req.Password) == "" {
-		respond.Error(w, http.StatusBadRequest, "identifier and password are required")
+	creds := accounts.Credentials{Identifier: req.Identifier, Password: req.Password, Scopes: req.Scopes, CaptchaToken: req.CaptchaToken}
+	session, err := h.accounts.Login(r.Context(), creds, requestDevice(r))
+	var scopeErr *accounts.ScopeError
+	switch {
+	case err == nil:
+	case h.captchaFailed(w, err):
 		return
-	}
-	user, err := h.store.FindByUsernameOrEmail(r.Context(), strings.TrimSpace(req.Identifier))
-	if err != nil {
-		if errors.Is(err, storage.ErrNotFound) {
-			// Log the error even for not found to help debug if it's a join failure
-			log.Printf("login failed: user not found or join failed for identifier %s: %v", req.Identifier, err)
-			respond.Error(w, http.StatusUnauthorized, "invalid credentials")
-			return
-		}
-		log.Printf("login failed: error fetching user %s: %v", req.Identifier, err)
-		respond.Error(w, http.StatusInternalServerError, "failed to fetch user")
+	case errors.Is(err, accounts.ErrMissingCredentials), errors.As(err, &scopeErr):
+		respond.Error(w, http.StatusBadRequest, err.Error())
 		return
-	}
-	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.Password)); err != nil {
-		respond.Error(w, http.StatusUnauthorized, "invalid credentials")
+	case errors.Is(err, accounts.ErrInvalidCredentials):
+		respond.Error(w, http.StatusUnauthorized, err.Error())
+		return
+	case errors.Is(err, accounts.ErrResetRequired), errors.Is(err, accounts.ErrAccountMerged), errors.Is(err, accounts.ErrNetworkBlocked),
+		errors.Is(err, accounts.ErrVerificationUnavailable), errors.Is(err, accounts.ErrDeviceUnconfirmed):
+		respond.Error(w, http.StatusForbidden, err.Error())
+		return
+	case errors.Is(err, storage.ErrUnavailable):
+		log.Printf("login failed: %v", err)
+		respond.Error(w, http.StatusServiceUnavailable, "service temporarily unavailable, please retry")
+		return
+	default:
+		log.Printf("login failed: %v", err)
+		respond.Error(w, http.StatusInternalServerError, "failed to sign in")
 		return
 	}
-	token, err := h.tokens.Generate(user)
-	if err != nil {
-		respond.Error(w, http.StatusInternalServerError, "failed to generate token")
+	if req.UseCookie || h.cfg.Cookie.Always {
+		h.setTokenCookie(w, session.Token, int(session.TTL.Seconds()))
+		respond.JSON(w, http.StatusOK, "login successful", dto.LoginResponse{Scopes: session.Scopes, NewDevice: session.NewDevice, User: session.User})
 		return
 	}
-	respond.JSON(w, http.StatusOK, "login successful", dto.LoginResponse{Token: token, User: user})
+	respond.JSON(w, http.StatusOK, "login successful", dto.LoginResponse{Token: session.Token, Scopes: session.Scopes, NewDevice: session.NewDevice, User: session.User})
 }
 
-func normalizePhone(req dto.RegisterRequest) string {
-	if trimmed := strings.TrimSpace(req.Phone); trimmed != "" {
-		return trimmed
-	}
-	return strings.TrimSpace(req.PhoneNumber)
+// captchaFailed responds to the captcha errors, with the widget to render
+// when one is required, and reports whether err was one.
+func (h *AuthHandler) captchaFailed(w http.ResponseWriter, err error) bool {
+	switch {
+	case errors.Is(err, captcha.ErrRequired):
+		required := dto.CaptchaRequired{Provider: h.cfg.Captcha.Provider, SiteKey: h.cfg.Captcha.SiteKey}
+		respond.JSON(w, http.StatusPreconditionRequired, "captcha required", map[string]any{"captcha": required})
+	case errors.Is(err, captcha.ErrInvalid):
+		respond.Error(w, http.StatusUnprocessableEntity, err.Error())
+	case errors.Is(err, captcha.ErrUnavailable):
+		respond.Error(w, http.StatusServiceUnavailable, err.Error())
+	default:
+		return false
+	}
+	return true
 }
 
-func validateCredentials(username, email, phone, password string) error {
-	if strings.TrimSpace(username) == "" || strings.TrimSpace(email) == "" || strings.TrimSpace(phone) == "" {
-		return errors.New("username, email, and phone are required")
-	}
-	if len(strings.TrimSpace(password)) < 8 || !utf8.ValidString(password) {
-		return errors.New("password must be at least 8 characters")
+// handleLogout revokes the caller's sessions and clears the session cookie.
+// Callers without a usable token only have the cookie cleared.
+func (h *AuthHandler) handleLogout(w http.ResponseWriter, r *http.Request) {
+	if user, ok := middleware.UserFromContext(r.Context()); ok {
+		if err := h.sessions.SignOut(r.Context(), user.ID); err != nil {
+			log.Printf("sign out user %d: %v", user.ID, err)
+			respond.Error(w, http.StatusInternalServerError, "failed to sign out")
+			return
+		}
 	}
-	return nil
+	h.setTokenCookie(w, "", -1)
+	respond.JSON(w, http.StatusOK, "logout successful", nil)
 }
 
-func hashPassword(password string) (string, error) {
-	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
-	if err != nil {
-		return "", err
+func (h *AuthHandler) setTokenCookie(w http.ResponseWriter, token string, maxAge int) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     auth.CookieName,
+		Value:    token,
+		Path:     "/",
+		Domain:   h.cfg.Cookie.Domain,
+		MaxAge:   maxAge,
+		HttpOnly: true,
+		Secure:   h.cfg.Cookie.Secure,
+		SameSite: h.cfg.Cookie.SameSite,
+	})
+}
+
+func rawPhone(req dto.RegisterRequest) string {
+	if trimmed := strings.TrimSpace(req.Phone); trimmed != "" {
+		return trimmed
 	}
-	return string(hash), nil
+	return strings.TrimSpace(req.PhoneNumber)
 }
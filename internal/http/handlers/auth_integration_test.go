@@ -15,7 +15,9 @@ import (
 
 	"github.com/joho/godotenv"
 
+	"github.com/hongminglow/all-in-be/internal/accounts"
 	"github.com/hongminglow/all-in-be/internal/auth"
+	"github.com/hongminglow/all-in-be/internal/clock"
 	"github.com/hongminglow/all-in-be/internal/config"
 	"github.com/hongminglow/all-in-be/internal/models"
 	"github.com/hongminglow/all-in-be/internal/storage/postgres"
@@ -34,7 +36,7 @@ func TestAuthIntegration(t *testing.T) {
 	}
 
 	ctx := context.Background()
-	store, err := postgres.NewUserStore(ctx, dbURL)
+	store, err := postgres.NewUserStore(ctx, dbURL, postgres.PoolConfig{}, os.Getenv("PHONE_DEFAULT_REGION"))
 	if err != nil {
 		t.Fatalf("init store: %v", err)
 	}
@@ -43,18 +45,19 @@ func TestAuthIntegration(t *testing.T) {
 	secret := mustGetEnv(t, "JWT_SECRET")
 	issuer := mustGetEnv(t, "JWT_ISSUER")
 	ttl := mustGetTTL(t)
-	tokens := auth.NewTokenManager(secret, issuer, ttl)
+	tokens := auth.NewTokenManager(auth.SigningKey{Secret: secret}, nil, issuer, "", ttl, clock.System{}, clock.UUID{})
 
 	mux := http.NewServeMux()
-	authHandler := NewAuthHandler(store, tokens, &config.Config{})
+	authHandler := NewAuthHandler(accounts.NewService(store, nil, nil, nil, nil, nil, tokens, nil, nil, &config.Config{}), nil, &config.Config{})
 	authHandler.Register(mux)
+	authHandler.RegisterSignup(mux)
 
 	ts := httptest.NewServer(mux)
 	defer ts.Close()
 
 	username := fmt.Sprintf("apitest_%d", time.Now().UnixNano())
 	email := fmt.Sprintf("%s@example.com", username)
-	phone := fmt.Sprintf("+1555%07d", time.Now().UnixNano()%1_000_0000)
+	phone := fmt.Sprintf("+1202555%04d", time.Now().UnixNano()%10_000)
 	password := fmt.Sprintf("Pass!%d", time.Now().UnixNano())
 
 	registerBody := map[string]string{
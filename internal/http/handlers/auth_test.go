@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/hongminglow/all-in-be/internal/accounts"
+	"github.com/hongminglow/all-in-be/internal/config"
+	"github.com/hongminglow/all-in-be/internal/models"
+	"github.com/hongminglow/all-in-be/internal/storage"
+)
+
+// createOnlyUsers is a Store that accepts every CreateUser call and the
+// events staged with it.
+type createOnlyUsers struct {
+	storage.Store
+	created []models.User
+}
+
+func (s *createOnlyUsers) WithTx(_ context.Context, fn func(tx storage.Repositories) error) error {
+	return fn(s)
+}
+
+func (s *createOnlyUsers) AddOutboxEvent(_ context.Context, event models.OutboxEvent) (models.OutboxEvent, error) {
+	return event, nil
+}
+
+func (s *createOnlyUsers) CreateUser(_ context.Context, user models.User) (models.User, error) {
+	user.ID = int64(len(s.created) + 1)
+	s.created = append(s.created, user)
+	return user, nil
+}
+
+// FuzzRegisterDecode feeds arbitrary bodies to /register. Malformed or invalid
+// input must be rejected with 400; anything accepted must be stored in
+// normalized form. A 5xx means validation let through input a later step
+// cannot handle.
+func FuzzRegisterDecode(f *testing.F) {
+	f.Add(`{"username":"alice","email":"alice@example.com","phone":"+12025550123","password":"Passw0rd!"}`)
+	f.Add(`{"username":" bob ","email":"bob@example.com","phoneNumber":"012-345 6789","password":"longenough"}`)
+	f.Add(`{"username":1}`)
+	f.Add(`not json`)
+
+	accounts.BcryptCost = bcrypt.MinCost
+	f.Fuzz(func(t *testing.T, body string) {
+		store := &createOnlyUsers{}
+		cfg := &config.Config{PhoneRegion: "MY"}
+		h := NewAuthHandler(accounts.NewService(store, nil, nil, nil, nil, nil, nil, nil, nil, cfg), nil, cfg)
+		rec := httptest.NewRecorder()
+		h.handleRegister(rec, httptest.NewRequest(http.MethodPost, "/register", strings.NewReader(body)))
+
+		switch rec.Code {
+		case http.StatusCreated:
+			if len(store.created) != 1 {
+				t.Fatalf("accepted without storing a user: %s", body)
+			}
+			user := store.created[0]
+			if user.Username != strings.TrimSpace(user.Username) || user.Username == "" {
+				t.Fatalf("username not normalized: %q", user.Username)
+			}
+			if !strings.HasPrefix(user.Phone, "+") {
+				t.Fatalf("phone not E.164: %q", user.Phone)
+			}
+		case http.StatusBadRequest:
+			if len(store.created) != 0 {
+				t.Fatalf("rejected input was stored: %s", body)
+			}
+		default:
+			t.Fatalf("unexpected status %d for %q: %s", rec.Code, body, rec.Body.String())
+		}
+	})
+}
@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strings"
+
+	"github.com/hongminglow/all-in-be/internal/http/respond"
+	"github.com/hongminglow/all-in-be/internal/middleware"
+	"github.com/hongminglow/all-in-be/internal/models"
+	"github.com/hongminglow/all-in-be/internal/models/dto"
+	"github.com/hongminglow/all-in-be/internal/storage"
+	"github.com/hongminglow/all-in-be/internal/wallet"
+)
+
+// maxAdjustmentKey bounds the client-chosen operation key.
+const maxAdjustmentKey = 100
+
+// BalanceAdjustmentHandler lets admins credit or debit a balance by hand, for
+// goodwill credits or to correct a provider error. Every adjustment is a
+// ledger entry keyed by the client, so retrying a request applies it once.
+// An adjustment decided on a stale view of the account can name the user's
+// version, in If-Match or the body, to be refused with a 409 instead.
+type BalanceAdjustmentHandler struct {
+	store  storage.UserStore
+	wallet *wallet.Service
+}
+
+// NewBalanceAdjustmentHandler constructs the handler.
+func NewBalanceAdjustmentHandler(store storage.UserStore, w *wallet.Service) *BalanceAdjustmentHandler {
+	return &BalanceAdjustmentHandler{store: store, wallet: w}
+}
+
+// Register attaches the admin route. It must be mounted behind middleware.Authenticate.
+func (h *BalanceAdjustmentHandler) Register(mux Router) {
+	mux.Handle("POST /admin/users/{id}/balance-adjustments", middleware.RequirePermission(models.PermBalanceAdjust, http.HandlerFunc(h.handleAdjust)))
+}
+
+func (h *BalanceAdjustmentHandler) handleAdjust(w http.ResponseWriter, r *http.Request) {
+	userID, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+	var req dto.BalanceAdjustmentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respond.Error(w, http.StatusBadRequest, "invalid JSON payload")
+		return
+	}
+	req.Note, req.Key = strings.TrimSpace(req.Note), strings.TrimSpace(req.Key)
+	switch {
+	case math.IsNaN(req.Amount) || math.IsInf(req.Amount, 0) || math.Round(req.Amount*100) == 0:
+		respond.Error(w, http.StatusBadRequest, "amount must be a non-zero number")
+		return
+	case req.Note == "":
+		respond.Error(w, http.StatusBadRequest, "note is required")
+		return
+	case req.Key == "" || len(req.Key) > maxAdjustmentKey:
+		respond.Error(w, http.StatusBadRequest, fmt.Sprintf("key is required and at most %d bytes", maxAdjustmentKey))
+		return
+	}
+	version, ok := expectedVersion(w, r, req.Version)
+	if !ok {
+		return
+	}
+	actor, _ := middleware.UserFromContext(r.Context())
+	if actor.ID == userID {
+		respond.Error(w, http.StatusForbidden, "you cannot adjust your own balance")
+		return
+	}
+	if _, err := h.store.FindByID(r.Context(), userID); err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			respond.Error(w, http.StatusNotFound, "user not found")
+			return
+		}
+		log.Printf("find user error: %v", err)
+		respond.Error(w, http.StatusInternalServerError, "failed to adjust balance")
+		return
+	}
+
+	entry, err := h.wallet.Apply(r.Context(), wallet.Operation{Kind: models.OperationBalanceAdjustment, Key: req.Key, Version: version}, models.Transaction{
+		UserID:    userID,
+		Amount:    req.Amount,
+		Reason:    models.TransactionAdjustment,
+		Reference: fmt.Sprintf("%s (by user %d)", req.Note, actor.ID),
+	})
+	switch {
+	case errors.Is(err, storage.ErrInsufficientFunds):
+		respond.Error(w, http.StatusConflict, "the balance is too low for this debit")
+	case errors.Is(err, wallet.ErrOperationConflict):
+		respond.Error(w, http.StatusConflict, "key was already used for a different adjustment")
+	case errors.Is(err, storage.ErrVersionConflict):
+		respond.Error(w, http.StatusConflict, "user was changed by someone else; reload and try again")
+	case err != nil:
+		log.Printf("adjust balance of user %d: %v", userID, err)
+		respond.Error(w, http.StatusInternalServerError, "failed to adjust balance")
+	default:
+		respond.JSON(w, http.StatusOK, "balance adjusted", entry)
+	}
+}
@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/hongminglow/all-in-be/internal/bulk"
+	"github.com/hongminglow/all-in-be/internal/http/respond"
+	"github.com/hongminglow/all-in-be/internal/middleware"
+	"github.com/hongminglow/all-in-be/internal/models"
+	"github.com/hongminglow/all-in-be/internal/models/dto"
+	"github.com/hongminglow/all-in-be/internal/storage"
+)
+
+// BulkJobRunner starts and controls bulk jobs.
+type BulkJobRunner interface {
+	Submit(ctx context.Context, kind string, createdBy int64) (models.BulkJob, error)
+	Resume(ctx context.Context, id int64) (models.BulkJob, error)
+	Cancel(ctx context.Context, id int64) (models.BulkJob, error)
+}
+
+// BulkJobHandler lets operators start long-running jobs, follow their
+// progress, and resume or cancel them after a failure.
+type BulkJobHandler struct {
+	store  storage.BulkJobStore
+	runner BulkJobRunner
+}
+
+// NewBulkJobHandler constructs the handler.
+func NewBulkJobHandler(store storage.BulkJobStore, runner BulkJobRunner) *BulkJobHandler {
+	return &BulkJobHandler{store: store, runner: runner}
+}
+
+// Register attaches the admin routes. They must be mounted behind middleware.Authenticate.
+func (h *BulkJobHandler) Register(mux Router) {
+	mux.Handle("GET /admin/jobs", middleware.RequirePermission(models.PermConfigManage, http.HandlerFunc(h.handleList)))
+	mux.Handle("POST /admin/jobs", middleware.RequirePermission(models.PermConfigManage, http.HandlerFunc(h.handleStart)))
+	mux.Handle("GET /admin/jobs/{id}", middleware.RequirePermission(models.PermConfigManage, http.HandlerFunc(h.handleGet)))
+	mux.Handle("POST /admin/jobs/{id}/resume", middleware.RequirePermission(models.PermConfigManage, http.HandlerFunc(h.handleResume)))
+	mux.Handle("POST /admin/jobs/{id}/cancel", middleware.RequirePermission(models.PermConfigManage, http.HandlerFunc(h.handleCancel)))
+}
+
+// handleList lists the latest jobs, newest first.
+func (h *BulkJobHandler) handleList(w http.ResponseWriter, r *http.Request) {
+	jobs, err := h.store.ListBulkJobs(r.Context(), 50)
+	if err != nil {
+		log.Printf("list bulk jobs error: %v", err)
+		respond.Error(w, http.StatusInternalServerError, "failed to fetch jobs")
+		return
+	}
+	respond.JSON(w, http.StatusOK, "jobs fetched", jobs)
+}
+
+// handleStart answers 202 Accepted: the job runs in the background, and its
+// Location reports progress.
+func (h *BulkJobHandler) handleStart(w http.ResponseWriter, r *http.Request) {
+	var req dto.StartBulkJobRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respond.Error(w, http.StatusBadRequest, "invalid JSON payload")
+		return
+	}
+	actor, _ := middleware.UserFromContext(r.Context())
+	job, err := h.runner.Submit(r.Context(), req.Kind, actor.ID)
+	if err != nil {
+		if errors.Is(err, bulk.ErrUnknownKind) {
+			respond.Error(w, http.StatusBadRequest, "kind must be "+models.BulkJobArchive)
+			return
+		}
+		log.Printf("start bulk job error: %v", err)
+		respond.Error(w, http.StatusInternalServerError, "failed to start job")
+		return
+	}
+	w.Header().Set("Location", fmt.Sprintf("/admin/jobs/%d", job.ID))
+	respond.JSON(w, http.StatusAccepted, "job started", job)
+}
+
+func (h *BulkJobHandler) handleGet(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+	job, err := h.store.FindBulkJob(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			respond.Error(w, http.StatusNotFound, "job not found")
+			return
+		}
+		log.Printf("find bulk job error: %v", err)
+		respond.Error(w, http.StatusInternalServerError, "failed to fetch job")
+		return
+	}
+	respond.JSON(w, http.StatusOK, "job fetched", job)
+}
+
+// handleResume restarts a failed job from its last checkpoint.
+func (h *BulkJobHandler) handleResume(w http.ResponseWriter, r *http.Request) {
+	h.control(w, r, h.runner.Resume, "job resumed", "only failed jobs can be resumed")
+}
+
+// handleCancel stops a running or failed job for good.
+func (h *BulkJobHandler) handleCancel(w http.ResponseWriter, r *http.Request) {
+	h.control(w, r, h.runner.Cancel, "job cancelled", "only running or failed jobs can be cancelled")
+}
+
+func (h *BulkJobHandler) control(w http.ResponseWriter, r *http.Request, action func(context.Context, int64) (models.BulkJob, error), message, conflict string) {
+	id, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+	job, err := action(r.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, bulk.ErrNotAllowed):
+			respond.Error(w, http.StatusConflict, conflict)
+		case errors.Is(err, storage.ErrNotFound):
+			respond.Error(w, http.StatusNotFound, "job not found")
+		default:
+			log.Printf("%s: update bulk job %d error: %v", message, id, err)
+			respond.Error(w, http.StatusInternalServerError, "failed to update job")
+		}
+		return
+	}
+	respond.JSON(w, http.StatusOK, message, job)
+}
@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/hongminglow/all-in-be/internal/cache"
+	"github.com/hongminglow/all-in-be/internal/http/respond"
+	"github.com/hongminglow/all-in-be/internal/middleware"
+	"github.com/hongminglow/all-in-be/internal/models"
+	"github.com/hongminglow/all-in-be/internal/models/dto"
+)
+
+// CacheHandler lets other services drop the in-process caches of every
+// instance after changing the data behind them, e.g. with a service account
+// token scoped to config:manage.
+type CacheHandler struct {
+	invalidator *cache.Invalidator
+}
+
+// NewCacheHandler constructs the handler.
+func NewCacheHandler(invalidator *cache.Invalidator) *CacheHandler {
+	return &CacheHandler{invalidator: invalidator}
+}
+
+// Register attaches the internal routes. They must be mounted behind middleware.Authenticate.
+func (h *CacheHandler) Register(mux Router) {
+	mux.Handle("GET /internal/caches", middleware.RequirePermission(models.PermConfigManage, http.HandlerFunc(h.handleList)))
+	mux.Handle("POST /internal/caches/invalidate", middleware.RequirePermission(models.PermConfigManage, http.HandlerFunc(h.handleInvalidate)))
+}
+
+func (h *CacheHandler) handleList(w http.ResponseWriter, _ *http.Request) {
+	respond.JSON(w, http.StatusOK, "caches fetched", dto.CacheInvalidationResponse{
+		Caches:    h.invalidator.Names(),
+		Broadcast: h.invalidator.Broadcasts(),
+	})
+}
+
+func (h *CacheHandler) handleInvalidate(w http.ResponseWriter, r *http.Request) {
+	var req dto.InvalidateCachesRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respond.Error(w, http.StatusBadRequest, "invalid JSON payload")
+		return
+	}
+	if len(req.Caches) == 0 {
+		respond.Error(w, http.StatusBadRequest, "caches must name at least one of: "+strings.Join(h.invalidator.Names(), ", "))
+		return
+	}
+	err := h.invalidator.Invalidate(r.Context(), req.Caches...)
+	switch {
+	case errors.Is(err, cache.ErrUnknownCache):
+		respond.Error(w, http.StatusBadRequest, err.Error()+"; known caches: "+strings.Join(h.invalidator.Names(), ", "))
+	case err != nil:
+		// The caches are already dropped here; only the other instances missed it.
+		log.Printf("invalidate caches %v: %v", req.Caches, err)
+		respond.Error(w, http.StatusBadGateway, "caches dropped on this instance only; broadcast failed")
+	default:
+		respond.JSON(w, http.StatusOK, "caches invalidated", dto.CacheInvalidationResponse{
+			Caches:    req.Caches,
+			Broadcast: h.invalidator.Broadcasts(),
+		})
+	}
+}
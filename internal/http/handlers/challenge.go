@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/hongminglow/all-in-be/internal/challenge"
+	"github.com/hongminglow/all-in-be/internal/geoip"
+	"github.com/hongminglow/all-in-be/internal/http/respond"
+	"github.com/hongminglow/all-in-be/internal/middleware"
+	"github.com/hongminglow/all-in-be/internal/models"
+	"github.com/hongminglow/all-in-be/internal/models/dto"
+	"github.com/hongminglow/all-in-be/internal/security"
+	"github.com/hongminglow/all-in-be/internal/storage"
+)
+
+// ChallengeTokenHeader carries the token of a passed challenge when an action
+// that required it is retried.
+const ChallengeTokenHeader = "X-Challenge-Token"
+
+// ChallengeHandler lets users answer the step-up challenges risky actions
+// hand out.
+type ChallengeHandler struct {
+	service *challenge.Service
+}
+
+// NewChallengeHandler constructs the handler.
+func NewChallengeHandler(service *challenge.Service) *ChallengeHandler {
+	return &ChallengeHandler{service: service}
+}
+
+// Register attaches the route. It must be mounted behind middleware.Authenticate
+// and middleware.LoadUser.
+func (h *ChallengeHandler) Register(mux Router) {
+	mux.HandleFunc("POST /challenges/{id}/verify", h.handleVerify)
+}
+
+func (h *ChallengeHandler) handleVerify(w http.ResponseWriter, r *http.Request) {
+	user, ok := middleware.UserFromContext(r.Context())
+	if !ok {
+		respond.Error(w, http.StatusUnauthorized, "authentication required")
+		return
+	}
+	id, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+	var req dto.VerifyChallengeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respond.Error(w, http.StatusBadRequest, "invalid JSON payload")
+		return
+	}
+	token, c, err := h.service.Verify(r.Context(), user, id, challenge.Answer{
+		Password:     req.Password,
+		Code:         strings.TrimSpace(req.Code),
+		CaptchaToken: strings.TrimSpace(req.CaptchaToken),
+		IP:           middleware.ClientIP(r),
+	})
+	switch {
+	case errors.Is(err, storage.ErrNotFound):
+		respond.Error(w, http.StatusNotFound, "challenge not found")
+	case errors.Is(err, challenge.ErrIncorrect):
+		respond.Error(w, http.StatusUnprocessableEntity, "incorrect answer")
+	case errors.Is(err, challenge.ErrTooManyAttempts), errors.Is(err, challenge.ErrExpired), errors.Is(err, challenge.ErrPassed):
+		respond.Error(w, http.StatusGone, err.Error()+"; retry the action for a new challenge")
+	case err != nil:
+		log.Printf("verify challenge %d for user %d: %v", id, user.ID, err)
+		respond.Error(w, http.StatusInternalServerError, "failed to verify challenge")
+	default:
+		respond.JSON(w, http.StatusOK, "challenge passed", dto.ChallengePassedResponse{Token: token, ExpiresAt: c.ExpiresAt})
+	}
+}
+
+// requireChallenge reports whether the action in req may go ahead. When it
+// may not, it has already answered 428 with the challenge to pass first.
+func requireChallenge(w http.ResponseWriter, r *http.Request, challenges *challenge.Service, user models.User, req challenge.Request) bool {
+	if challenges == nil {
+		return true
+	}
+	if req.Device == "" {
+		req.Device = requestDevice(r).Fingerprint()
+	}
+	c, err := challenges.Require(r.Context(), user, req, strings.TrimSpace(r.Header.Get(ChallengeTokenHeader)))
+	switch {
+	case errors.Is(err, challenge.ErrRequired):
+		respond.JSON(w, http.StatusPreconditionRequired, "step-up challenge required", map[string]any{"challenge": c})
+		return false
+	case err != nil:
+		log.Printf("%s challenge for user %d: %v", req.Action, user.ID, err)
+		respond.Error(w, http.StatusInternalServerError, "failed to check challenge")
+		return false
+	}
+	return true
+}
+
+// requestDevice describes the device a request comes from.
+func requestDevice(r *http.Request) security.Device {
+	return security.Device{
+		ID:             strings.TrimSpace(r.Header.Get("X-Device-ID")),
+		UserAgent:      r.UserAgent(),
+		AcceptLanguage: r.Header.Get("Accept-Language"),
+		AcceptEncoding: r.Header.Get("Accept-Encoding"),
+		IP:             middleware.ClientIP(r),
+		Country:        geoip.CountryFromContext(r.Context()),
+	}
+}
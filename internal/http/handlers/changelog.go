@@ -0,0 +1,37 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/hongminglow/all-in-be/internal/changelog"
+	"github.com/hongminglow/all-in-be/internal/http/respond"
+)
+
+// ChangelogHandler serves the structured API release notes.
+type ChangelogHandler struct {
+	releases []changelog.Release
+}
+
+// NewChangelogHandler constructs the handler from parsed releases.
+func NewChangelogHandler(releases []changelog.Release) *ChangelogHandler {
+	return &ChangelogHandler{releases: releases}
+}
+
+// Register attaches the /changelog route.
+func (h *ChangelogHandler) Register(mux Router) {
+	mux.HandleFunc("GET /changelog", h.handle)
+}
+
+// handle lists releases newest first. ?since=<version> returns only releases newer than it.
+func (h *ChangelogHandler) handle(w http.ResponseWriter, r *http.Request) {
+	releases := h.releases
+	if since := r.URL.Query().Get("since"); since != "" {
+		for i, release := range h.releases {
+			if release.Version == since {
+				releases = h.releases[:i]
+				break
+			}
+		}
+	}
+	respond.JSON(w, http.StatusOK, "changelog fetched", releases)
+}
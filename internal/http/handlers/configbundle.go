@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/hongminglow/all-in-be/internal/configbundle"
+	"github.com/hongminglow/all-in-be/internal/http/respond"
+	"github.com/hongminglow/all-in-be/internal/middleware"
+	"github.com/hongminglow/all-in-be/internal/models"
+	"github.com/hongminglow/all-in-be/internal/storage"
+)
+
+// ConfigBundleHandler exports and imports tenant configuration bundles so settings
+// tested in one environment can be promoted to another.
+type ConfigBundleHandler struct {
+	store      storage.Store
+	invalidate func()
+}
+
+// NewConfigBundleHandler constructs the handler. invalidate is called after a
+// successful import so cached configuration is reloaded.
+func NewConfigBundleHandler(store storage.Store, invalidate func()) *ConfigBundleHandler {
+	return &ConfigBundleHandler{store: store, invalidate: invalidate}
+}
+
+// Register attaches the admin routes. They must be mounted behind middleware.Authenticate.
+func (h *ConfigBundleHandler) Register(mux Router) {
+	mux.Handle("GET /admin/config/export", middleware.RequirePermission(models.PermConfigManage, http.HandlerFunc(h.handleExport)))
+	mux.Handle("POST /admin/config/import", middleware.RequirePermission(models.PermConfigManage, http.HandlerFunc(h.handleImport)))
+}
+
+func (h *ConfigBundleHandler) handleExport(w http.ResponseWriter, r *http.Request) {
+	tenant := strings.TrimSpace(r.URL.Query().Get("tenant"))
+	bundle, err := configbundle.Export(r.Context(), h.store, tenant)
+	if err != nil {
+		log.Printf("export config bundle error: %v", err)
+		respond.Error(w, http.StatusInternalServerError, "failed to export configuration")
+		return
+	}
+	respond.JSON(w, http.StatusOK, "configuration exported", bundle)
+}
+
+// handleImport applies a bundle atomically. With ?dry_run=true it only returns the diff.
+func (h *ConfigBundleHandler) handleImport(w http.ResponseWriter, r *http.Request) {
+	dryRun, _ := strconv.ParseBool(r.URL.Query().Get("dry_run"))
+	var bundle configbundle.Bundle
+	if err := json.NewDecoder(r.Body).Decode(&bundle); err != nil {
+		respond.Error(w, http.StatusBadRequest, "invalid JSON payload")
+		return
+	}
+	if err := configbundle.Validate(bundle, models.RouteClasses); err != nil {
+		respond.Error(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+	actor, _ := middleware.UserFromContext(r.Context())
+	diff, err := configbundle.Apply(r.Context(), h.store, actor.ID, bundle, dryRun)
+	if err != nil {
+		log.Printf("import config bundle error: %v", err)
+		respond.Error(w, http.StatusInternalServerError, "failed to import configuration")
+		return
+	}
+	if dryRun {
+		respond.JSON(w, http.StatusOK, "configuration diff computed", diff)
+		return
+	}
+	h.invalidate()
+	respond.JSON(w, http.StatusOK, "configuration imported", diff)
+}
@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/hongminglow/all-in-be/internal/confighistory"
+	"github.com/hongminglow/all-in-be/internal/http/respond"
+	"github.com/hongminglow/all-in-be/internal/middleware"
+	"github.com/hongminglow/all-in-be/internal/models"
+	"github.com/hongminglow/all-in-be/internal/storage"
+)
+
+const (
+	defaultHistoryLimit = 50
+	maxHistoryLimit     = 500
+)
+
+// ConfigHistoryHandler exposes the configuration change history and rollback.
+type ConfigHistoryHandler struct {
+	store      storage.Store
+	invalidate func()
+}
+
+// NewConfigHistoryHandler constructs the handler. invalidate is called after a
+// rollback so cached configuration is reloaded.
+func NewConfigHistoryHandler(store storage.Store, invalidate func()) *ConfigHistoryHandler {
+	return &ConfigHistoryHandler{store: store, invalidate: invalidate}
+}
+
+// Register attaches the admin routes. They must be mounted behind middleware.Authenticate.
+func (h *ConfigHistoryHandler) Register(mux Router) {
+	mux.Handle("GET /admin/config/history", middleware.RequirePermission(models.PermConfigManage, http.HandlerFunc(h.handleList)))
+	mux.Handle("POST /admin/config/history/{id}/rollback", middleware.RequirePermission(models.PermConfigManage, http.HandlerFunc(h.handleRollback)))
+}
+
+// handleList returns recent changes, newest first. Supports ?entity= and ?limit=.
+func (h *ConfigHistoryHandler) handleList(w http.ResponseWriter, r *http.Request) {
+	limit := defaultHistoryLimit
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed < 1 || parsed > maxHistoryLimit {
+			respond.Error(w, http.StatusBadRequest, "limit must be between 1 and 500")
+			return
+		}
+		limit = parsed
+	}
+	changes, err := h.store.ListConfigChanges(r.Context(), r.URL.Query().Get("entity"), limit)
+	if err != nil {
+		log.Printf("list config history error: %v", err)
+		respond.Error(w, http.StatusInternalServerError, "failed to list configuration history")
+		return
+	}
+	respond.JSON(w, http.StatusOK, "configuration history fetched", changes)
+}
+
+func (h *ConfigHistoryHandler) handleRollback(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+	actor, _ := middleware.UserFromContext(r.Context())
+	var restored models.ConfigChange
+	err := h.store.WithTx(r.Context(), func(tx storage.Repositories) error {
+		var err error
+		restored, err = confighistory.Rollback(r.Context(), tx, actor.ID, id)
+		return err
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, storage.ErrNotFound):
+			respond.Error(w, http.StatusNotFound, "history entry not found or nothing to roll back")
+		case errors.Is(err, confighistory.ErrUnsupportedEntity):
+			respond.Error(w, http.StatusUnprocessableEntity, err.Error())
+		default:
+			log.Printf("rollback config change %d error: %v", id, err)
+			respond.Error(w, http.StatusInternalServerError, "failed to roll back configuration")
+		}
+		return
+	}
+	h.invalidate()
+	respond.JSON(w, http.StatusOK, "configuration rolled back", restored)
+}
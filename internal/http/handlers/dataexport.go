@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/hongminglow/all-in-be/internal/dataexport"
+	"github.com/hongminglow/all-in-be/internal/http/respond"
+	"github.com/hongminglow/all-in-be/internal/middleware"
+	"github.com/hongminglow/all-in-be/internal/storage"
+)
+
+// DataExportHandler lets users download a copy of their personal data.
+type DataExportHandler struct {
+	exports *dataexport.Service
+}
+
+// NewDataExportHandler constructs the handler.
+func NewDataExportHandler(exports *dataexport.Service) *DataExportHandler {
+	return &DataExportHandler{exports: exports}
+}
+
+// Register attaches the routes. They must be mounted behind middleware.Authenticate.
+func (h *DataExportHandler) Register(mux Router) {
+	mux.HandleFunc("GET /me/export", h.handleList)
+	mux.HandleFunc("POST /me/export", h.handleRequest)
+	mux.HandleFunc("GET /me/export/{id}", h.handleExport)
+}
+
+func (h *DataExportHandler) handleList(w http.ResponseWriter, r *http.Request) {
+	user, ok := middleware.UserFromContext(r.Context())
+	if !ok {
+		respond.Error(w, http.StatusUnauthorized, "authentication required")
+		return
+	}
+	exports, err := h.exports.List(r.Context(), user.ID)
+	if err != nil {
+		log.Printf("list data exports for user %d: %v", user.ID, err)
+		respond.Error(w, http.StatusInternalServerError, "failed to list data exports")
+		return
+	}
+	respond.JSON(w, http.StatusOK, "data exports fetched", exports)
+}
+
+func (h *DataExportHandler) handleRequest(w http.ResponseWriter, r *http.Request) {
+	user, ok := middleware.UserFromContext(r.Context())
+	if !ok {
+		respond.Error(w, http.StatusUnauthorized, "authentication required")
+		return
+	}
+	export, err := h.exports.Request(r.Context(), user.ID)
+	if err != nil {
+		log.Printf("request data export for user %d: %v", user.ID, err)
+		respond.Error(w, http.StatusInternalServerError, "failed to start data export")
+		return
+	}
+	w.Header().Set("Location", fmt.Sprintf("/me/export/%d", export.ID))
+	respond.JSON(w, http.StatusAccepted, "data export started", export)
+}
+
+func (h *DataExportHandler) handleExport(w http.ResponseWriter, r *http.Request) {
+	user, ok := middleware.UserFromContext(r.Context())
+	if !ok {
+		respond.Error(w, http.StatusUnauthorized, "authentication required")
+		return
+	}
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil || id <= 0 {
+		respond.Error(w, http.StatusBadRequest, "invalid export id")
+		return
+	}
+	export, err := h.exports.Find(r.Context(), user.ID, id)
+	if errors.Is(err, storage.ErrNotFound) {
+		respond.Error(w, http.StatusNotFound, "data export not found")
+		return
+	}
+	if err != nil {
+		log.Printf("find data export %d: %v", id, err)
+		respond.Error(w, http.StatusInternalServerError, "failed to fetch data export")
+		return
+	}
+	respond.JSON(w, http.StatusOK, "data export fetched", export)
+}
@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/hongminglow/all-in-be/internal/http/respond"
+	"github.com/hongminglow/all-in-be/internal/middleware"
+	"github.com/hongminglow/all-in-be/internal/models"
+	"github.com/hongminglow/all-in-be/internal/storage"
+)
+
+// IndexAdvisor analyzes the database workload for missing indexes.
+type IndexAdvisor interface {
+	Run(ctx context.Context) (models.DatabaseInsightsReport, error)
+}
+
+// DatabaseInsightsHandler shows the index advisor's latest report and lets
+// operators run it now, e.g. after a release changed the queries.
+type DatabaseInsightsHandler struct {
+	store   storage.DatabaseInsightsStore
+	advisor IndexAdvisor
+}
+
+// NewDatabaseInsightsHandler constructs the handler.
+func NewDatabaseInsightsHandler(store storage.DatabaseInsightsStore, advisor IndexAdvisor) *DatabaseInsightsHandler {
+	return &DatabaseInsightsHandler{store: store, advisor: advisor}
+}
+
+// Register attaches the admin routes. They must be mounted behind middleware.Authenticate.
+func (h *DatabaseInsightsHandler) Register(mux Router) {
+	mux.Handle("GET /admin/database/insights", middleware.RequirePermission(models.PermStatsRead, http.HandlerFunc(h.handleLatest)))
+	mux.Handle("POST /admin/database/insights/run", middleware.RequirePermission(models.PermConfigManage, http.HandlerFunc(h.handleRun)))
+}
+
+func (h *DatabaseInsightsHandler) handleLatest(w http.ResponseWriter, r *http.Request) {
+	report, err := h.store.LatestDatabaseInsightsReport(r.Context())
+	switch {
+	case errors.Is(err, storage.ErrNotFound):
+		respond.Error(w, http.StatusNotFound, "no database insights report yet")
+	case err != nil:
+		log.Printf("fetch database insights report: %v", err)
+		respond.Error(w, http.StatusInternalServerError, "failed to fetch database insights")
+	default:
+		respond.JSON(w, http.StatusOK, "database insights fetched", report)
+	}
+}
+
+func (h *DatabaseInsightsHandler) handleRun(w http.ResponseWriter, r *http.Request) {
+	report, err := h.advisor.Run(r.Context())
+	if err != nil {
+		log.Printf("analyze database workload: %v", err)
+		respond.Error(w, http.StatusInternalServerError, "failed to analyze database workload")
+		return
+	}
+	respond.JSON(w, http.StatusOK, "database workload analyzed", report)
+}
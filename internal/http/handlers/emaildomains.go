@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/hongminglow/all-in-be/internal/emailcheck"
+	"github.com/hongminglow/all-in-be/internal/http/respond"
+	"github.com/hongminglow/all-in-be/internal/middleware"
+	"github.com/hongminglow/all-in-be/internal/models"
+)
+
+// DisposableEmailHandler shows and refreshes the disposable email domain list
+// sign-ups are checked against.
+type DisposableEmailHandler struct {
+	checker    *emailcheck.Checker
+	invalidate func()
+}
+
+// NewDisposableEmailHandler constructs the handler. invalidate is called on
+// every refresh so the other instances reread the list too.
+func NewDisposableEmailHandler(checker *emailcheck.Checker, invalidate func()) *DisposableEmailHandler {
+	return &DisposableEmailHandler{checker: checker, invalidate: invalidate}
+}
+
+// Register attaches the admin routes. They must be mounted behind middleware.Authenticate.
+func (h *DisposableEmailHandler) Register(mux Router) {
+	mux.Handle("GET /admin/disposable-email-domains", middleware.RequirePermission(models.PermConfigManage, http.HandlerFunc(h.handleGet)))
+	mux.Handle("POST /admin/disposable-email-domains/refresh", middleware.RequirePermission(models.PermConfigManage, http.HandlerFunc(h.handleRefresh)))
+}
+
+func (h *DisposableEmailHandler) handleGet(w http.ResponseWriter, r *http.Request) {
+	respond.JSON(w, http.StatusOK, "disposable email domains fetched", h.checker.List())
+}
+
+// handleRefresh rereads the list from its file or URL.
+func (h *DisposableEmailHandler) handleRefresh(w http.ResponseWriter, r *http.Request) {
+	h.invalidate()
+	list, err := h.checker.Refresh(r.Context())
+	switch {
+	case errors.Is(err, emailcheck.ErrNoList):
+		respond.Error(w, http.StatusNotFound, err.Error())
+	case err != nil:
+		log.Printf("refresh disposable email domains error: %v", err)
+		respond.JSON(w, http.StatusBadGateway, "failed to refresh disposable email domains", list)
+	default:
+		respond.JSON(w, http.StatusOK, "disposable email domains refreshed", list)
+	}
+}
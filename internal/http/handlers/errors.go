@@ -0,0 +1,27 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/hongminglow/all-in-be/internal/http/respond"
+)
+
+// ErrorCatalogHandler serves the catalog of error codes, so client SDKs and
+// QA can program against them instead of scraping messages.
+type ErrorCatalogHandler struct {
+	codes []respond.ErrorCode
+}
+
+// NewErrorCatalogHandler constructs the handler from the catalog.
+func NewErrorCatalogHandler(codes []respond.ErrorCode) *ErrorCatalogHandler {
+	return &ErrorCatalogHandler{codes: codes}
+}
+
+// Register attaches the /errors route.
+func (h *ErrorCatalogHandler) Register(mux Router) {
+	mux.HandleFunc("GET /errors", h.handle)
+}
+
+func (h *ErrorCatalogHandler) handle(w http.ResponseWriter, r *http.Request) {
+	respond.JSON(w, http.StatusOK, "error catalog fetched", h.codes)
+}
@@ -0,0 +1,149 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/hongminglow/all-in-be/internal/config"
+	"github.com/hongminglow/all-in-be/internal/feed"
+	"github.com/hongminglow/all-in-be/internal/http/respond"
+	"github.com/hongminglow/all-in-be/internal/middleware"
+)
+
+const (
+	// streamWriteGrace is how long a stream write may take beyond the next
+	// heartbeat before the connection is given up.
+	streamWriteGrace = 10 * time.Second
+	// Defaults for an unset config.EventsConfig.
+	defaultStreamPollInterval = 2 * time.Second
+	defaultStreamHeartbeat    = 15 * time.Second
+)
+
+// EventStreamHandler streams the caller's balance movements as Server-Sent
+// Events, for clients that cannot hold a WebSocket open.
+type EventStreamHandler struct {
+	feed *feed.Service
+	cfg  config.EventsConfig
+}
+
+// NewEventStreamHandler constructs the handler.
+func NewEventStreamHandler(f *feed.Service, cfg config.EventsConfig) *EventStreamHandler {
+	if cfg.StreamPollInterval <= 0 {
+		cfg.StreamPollInterval = defaultStreamPollInterval
+	}
+	if cfg.StreamHeartbeat <= 0 {
+		cfg.StreamHeartbeat = defaultStreamHeartbeat
+	}
+	return &EventStreamHandler{feed: f, cfg: cfg}
+}
+
+// Register attaches the stream route. It must be mounted behind middleware.Authenticate.
+func (h *EventStreamHandler) Register(mux Router) {
+	mux.HandleFunc("GET /events", h.handle)
+}
+
+// handle resumes after the Last-Event-ID header, which EventSource sends when
+// it reconnects, or ?last_event_id=; without either the stream starts with the
+// next movement.
+func (h *EventStreamHandler) handle(w http.ResponseWriter, r *http.Request) {
+	user, ok := middleware.UserFromContext(r.Context())
+	if !ok {
+		respond.Error(w, http.StatusUnauthorized, "authentication required")
+		return
+	}
+	raw := r.Header.Get("Last-Event-ID")
+	if raw == "" {
+		raw = r.URL.Query().Get("last_event_id")
+	}
+	var after int64
+	if raw != "" {
+		parsed, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil || parsed < 0 {
+			respond.Error(w, http.StatusBadRequest, "Last-Event-ID must be an event ID")
+			return
+		}
+		after = parsed
+	} else {
+		cursor, err := h.feed.Cursor(r.Context(), user.ID)
+		if err != nil {
+			log.Printf("open event stream for user %d: %v", user.ID, err)
+			respond.Error(w, http.StatusInternalServerError, "failed to open event stream")
+			return
+		}
+		after = cursor
+	}
+	wake, stop := h.feed.Watch(user.ID)
+	defer stop()
+
+	// The stream outlives the server's read and write timeouts: nothing more
+	// is read, and every write gets until just after the next heartbeat.
+	rc := http.NewResponseController(w)
+	_ = rc.SetReadDeadline(time.Time{})
+	send := func(write func(io.Writer) error) bool {
+		_ = rc.SetWriteDeadline(time.Now().Add(h.cfg.StreamHeartbeat + streamWriteGrace))
+		return write(w) == nil && rc.Flush() == nil
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	// Proxies such as nginx would otherwise buffer the stream.
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	retry := fmt.Sprintf("retry: %d\n\n", h.cfg.StreamPollInterval.Milliseconds())
+	if !send(func(w io.Writer) error { _, err := io.WriteString(w, retry); return err }) {
+		return
+	}
+
+	poll := time.NewTicker(h.cfg.StreamPollInterval)
+	defer poll.Stop()
+	heartbeat := time.NewTicker(h.cfg.StreamHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		for {
+			events, err := h.feed.Since(r.Context(), user.ID, after)
+			if err != nil {
+				if r.Context().Err() == nil {
+					log.Printf("event stream for user %d: %v", user.ID, err)
+				}
+				return
+			}
+			for _, e := range events {
+				if !send(func(w io.Writer) error { return writeEvent(w, e) }) {
+					return
+				}
+				after = e.ID
+			}
+			if len(events) > 0 {
+				heartbeat.Reset(h.cfg.StreamHeartbeat)
+			}
+			if len(events) < feed.BatchSize {
+				break
+			}
+		}
+		select {
+		case <-r.Context().Done():
+			return
+		case <-h.feed.Done():
+			return
+		case <-wake:
+		case <-poll.C:
+		case <-heartbeat.C:
+			if !send(func(w io.Writer) error { _, err := io.WriteString(w, ": keepalive\n\n"); return err }) {
+				return
+			}
+		}
+	}
+}
+
+func writeEvent(w io.Writer, e feed.Event) error {
+	data, err := json.Marshal(e.Data)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", e.ID, e.Type, data)
+	return err
+}
@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/hongminglow/all-in-be/internal/chaos"
+	"github.com/hongminglow/all-in-be/internal/http/respond"
+	"github.com/hongminglow/all-in-be/internal/middleware"
+	"github.com/hongminglow/all-in-be/internal/models"
+	"github.com/hongminglow/all-in-be/internal/models/dto"
+)
+
+// FaultHandler manages fault-injection rules at runtime.
+type FaultHandler struct {
+	injector *chaos.Injector
+}
+
+// NewFaultHandler constructs the handler.
+func NewFaultHandler(injector *chaos.Injector) *FaultHandler {
+	return &FaultHandler{injector: injector}
+}
+
+// Register attaches the admin routes. They must be mounted behind middleware.Authenticate.
+func (h *FaultHandler) Register(mux Router) {
+	mux.Handle("GET /admin/faults", middleware.RequirePermission(models.PermConfigManage, http.HandlerFunc(h.handleList)))
+	mux.Handle("POST /admin/faults", middleware.RequirePermission(models.PermConfigManage, http.HandlerFunc(h.handleCreate)))
+	mux.Handle("DELETE /admin/faults/{id}", middleware.RequirePermission(models.PermConfigManage, http.HandlerFunc(h.handleFault)))
+}
+
+func (h *FaultHandler) handleList(w http.ResponseWriter, r *http.Request) {
+	respond.JSON(w, http.StatusOK, "fault rules fetched", h.injector.Rules())
+}
+
+func (h *FaultHandler) handleCreate(w http.ResponseWriter, r *http.Request) {
+	var req dto.CreateFaultRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respond.Error(w, http.StatusBadRequest, "invalid JSON payload")
+		return
+	}
+	rule := chaos.Rule{
+		PathPrefix:  req.PathPrefix,
+		Method:      req.Method,
+		Percentage:  req.Percentage,
+		LatencyMS:   req.LatencyMS,
+		ErrorStatus: req.ErrorStatus,
+		DropDB:      req.DropDB,
+	}
+	if req.TTLSeconds > 0 {
+		rule.ExpiresAt = time.Now().Add(time.Duration(req.TTLSeconds) * time.Second)
+	}
+	if err := rule.Validate(); err != nil {
+		respond.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	added := h.injector.Add(rule)
+	respond.Created(w, fmt.Sprintf("/admin/faults/%d", added.ID), "fault rule created", added)
+}
+
+func (h *FaultHandler) handleFault(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+	if !h.injector.Remove(id) {
+		respond.Error(w, http.StatusNotFound, "fault rule not found")
+		return
+	}
+	respond.JSON(w, http.StatusOK, "fault rule deleted", nil)
+}
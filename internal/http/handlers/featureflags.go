@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"maps"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/hongminglow/all-in-be/internal/config"
+	"github.com/hongminglow/all-in-be/internal/http/respond"
+	"github.com/hongminglow/all-in-be/internal/middleware"
+	"github.com/hongminglow/all-in-be/internal/models"
+	"github.com/hongminglow/all-in-be/internal/models/dto"
+	"github.com/hongminglow/all-in-be/internal/storage"
+)
+
+var flagNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9._-]{0,63}$`)
+
+// FeatureFlagHandler lists feature flags and switches them at runtime. Flags
+// set here override FEATURE_FLAGS until set again.
+type FeatureFlagHandler struct {
+	store storage.FeatureFlagStore
+
+	mu       sync.RWMutex
+	defaults config.FeatureFlags
+}
+
+// NewFeatureFlagHandler constructs the handler with the flags FEATURE_FLAGS switches on.
+func NewFeatureFlagHandler(store storage.FeatureFlagStore, defaults config.FeatureFlags) *FeatureFlagHandler {
+	return &FeatureFlagHandler{store: store, defaults: defaults}
+}
+
+// SetDefaults replaces the FEATURE_FLAGS flags after a configuration reload.
+func (h *FeatureFlagHandler) SetDefaults(defaults config.FeatureFlags) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.defaults = defaults
+}
+
+// Register attaches the admin routes. They must be mounted behind middleware.Authenticate.
+func (h *FeatureFlagHandler) Register(mux Router) {
+	mux.Handle("GET /admin/feature-flags", middleware.RequirePermission(models.PermConfigManage, http.HandlerFunc(h.handleList)))
+	mux.Handle("PUT /admin/feature-flags/{name}", middleware.RequirePermission(models.PermConfigManage, http.HandlerFunc(h.handleSet)))
+}
+
+// handleList returns every known flag by name: those FEATURE_FLAGS switches on
+// and those set through the API, which win.
+func (h *FeatureFlagHandler) handleList(w http.ResponseWriter, r *http.Request) {
+	stored, err := h.store.ListFeatureFlags(r.Context())
+	if err != nil {
+		log.Printf("list feature flags error: %v", err)
+		respond.Error(w, http.StatusInternalServerError, "failed to fetch feature flags")
+		return
+	}
+	flags := make(map[string]models.FeatureFlag)
+	h.mu.RLock()
+	for name, enabled := range h.defaults {
+		flags[name] = models.FeatureFlag{Name: name, Enabled: enabled, Source: models.FlagSourceConfig}
+	}
+	h.mu.RUnlock()
+	for _, f := range stored {
+		flags[f.Name] = f
+	}
+	list := slices.SortedFunc(maps.Values(flags), func(a, b models.FeatureFlag) int { return strings.Compare(a.Name, b.Name) })
+	respond.JSON(w, http.StatusOK, "feature flags fetched", list)
+}
+
+// handleSet switches one flag with PUT {"enabled":true|false}.
+func (h *FeatureFlagHandler) handleSet(w http.ResponseWriter, r *http.Request) {
+	name := strings.ToLower(strings.TrimSpace(r.PathValue("name")))
+	if !flagNamePattern.MatchString(name) {
+		respond.Error(w, http.StatusBadRequest, "flag names are up to 64 lowercase letters, digits, dots, dashes and underscores")
+		return
+	}
+	var req dto.SetFeatureFlagRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respond.Error(w, http.StatusBadRequest, "invalid JSON payload")
+		return
+	}
+	if req.Enabled == nil {
+		respond.Error(w, http.StatusBadRequest, "enabled is required")
+		return
+	}
+	actor, _ := middleware.UserFromContext(r.Context())
+	saved, err := h.store.SetFeatureFlag(r.Context(), models.FeatureFlag{Name: name, Enabled: *req.Enabled, UpdatedBy: actor.ID})
+	if err != nil {
+		log.Printf("set feature flag error: %v", err)
+		respond.Error(w, http.StatusInternalServerError, "failed to save feature flag")
+		return
+	}
+	respond.JSON(w, http.StatusOK, "feature flag saved", saved)
+}
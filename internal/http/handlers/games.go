@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/hongminglow/all-in-be/internal/games"
+	"github.com/hongminglow/all-in-be/internal/http/respond"
+	"github.com/hongminglow/all-in-be/internal/limits"
+	"github.com/hongminglow/all-in-be/internal/middleware"
+	"github.com/hongminglow/all-in-be/internal/models"
+	"github.com/hongminglow/all-in-be/internal/models/dto"
+)
+
+// GameHandler launches players into provider-hosted games.
+type GameHandler struct {
+	games *games.Service
+}
+
+// NewGameHandler constructs the handler.
+func NewGameHandler(service *games.Service) *GameHandler {
+	return &GameHandler{games: service}
+}
+
+// Register attaches the route. It must be mounted behind middleware.Authenticate.
+func (h *GameHandler) Register(mux Router) {
+	mux.Handle("POST /games/{id}/launch", middleware.RequirePermission(models.PermGamePlay, middleware.RefuseImpersonation(http.HandlerFunc(h.handleLaunch))))
+}
+
+func (h *GameHandler) handleLaunch(w http.ResponseWriter, r *http.Request) {
+	user, ok := middleware.UserFromContext(r.Context())
+	if !ok {
+		respond.Error(w, http.StatusUnauthorized, "authentication required")
+		return
+	}
+	ticket, err := h.games.Launch(r.Context(), user, r.PathValue("id"))
+	switch {
+	case errors.Is(err, games.ErrUnknownGame):
+		respond.Error(w, http.StatusNotFound, "game not found")
+	case errors.Is(err, limits.ErrExcluded):
+		respond.Error(w, http.StatusForbidden, "games are closed during a self-exclusion")
+	case err != nil:
+		log.Printf("launch game %q for user %d: %v", r.PathValue("id"), user.ID, err)
+		respond.Error(w, http.StatusInternalServerError, "failed to launch game")
+	default:
+		respond.Created(w, "", "game launched", dto.GameLaunchResponse{
+			SessionID: ticket.Session.ID,
+			LaunchURL: ticket.URL,
+			Token:     ticket.Token,
+			ExpiresAt: ticket.Session.ExpiresAt,
+		})
+	}
+}
@@ -18,15 +18,11 @@ func NewHealthHandler(startedAt time.Time) *HealthHandler {
 }
 
 // Register wires the handler into a ServeMux.
-func (h *HealthHandler) Register(mux *http.ServeMux) {
-	mux.HandleFunc("/health", h.handle)
+func (h *HealthHandler) Register(mux Router) {
+	mux.HandleFunc("GET /health", h.handle)
 }
 
 func (h *HealthHandler) handle(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
-		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
 	respond.JSON(w, http.StatusOK, "service healthy", map[string]string{
 		"status": "ok",
 		"uptime": time.Since(h.startedAt).Truncate(time.Second).String(),
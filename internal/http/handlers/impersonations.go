@@ -0,0 +1,153 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hongminglow/all-in-be/internal/auth"
+	"github.com/hongminglow/all-in-be/internal/http/respond"
+	"github.com/hongminglow/all-in-be/internal/middleware"
+	"github.com/hongminglow/all-in-be/internal/models"
+	"github.com/hongminglow/all-in-be/internal/models/dto"
+	"github.com/hongminglow/all-in-be/internal/storage"
+)
+
+// Impersonation token lifetimes in minutes.
+const (
+	defaultImpersonationMinutes = 15
+	maxImpersonationMinutes     = 60
+)
+
+// impersonationListLimit caps how many impersonations are listed.
+const impersonationListLimit = 100
+
+// ImpersonationHandler lets support staff act as a player to reproduce an
+// issue they reported, and lists and revokes those impersonations.
+type ImpersonationHandler struct {
+	users          storage.UserStore
+	store          storage.ImpersonationStore
+	impersonations *auth.Impersonations
+}
+
+// NewImpersonationHandler constructs the handler.
+func NewImpersonationHandler(users storage.UserStore, store storage.ImpersonationStore, impersonations *auth.Impersonations) *ImpersonationHandler {
+	return &ImpersonationHandler{users: users, store: store, impersonations: impersonations}
+}
+
+// Register attaches the admin routes. They must be mounted behind middleware.Authenticate.
+func (h *ImpersonationHandler) Register(mux Router) {
+	mux.Handle("POST /admin/users/{id}/impersonate", middleware.RequirePermission(models.PermUsersImpersonate, http.HandlerFunc(h.handleStart)))
+	mux.Handle("GET /admin/impersonations", middleware.RequirePermission(models.PermUsersImpersonate, http.HandlerFunc(h.handleList)))
+	mux.Handle("DELETE /admin/impersonations/{id}", middleware.RequirePermission(models.PermUsersImpersonate, http.HandlerFunc(h.handleRevoke)))
+}
+
+// handleStart issues a short-lived token acting as a player. Only people can
+// impersonate, and only players can be impersonated.
+func (h *ImpersonationHandler) handleStart(w http.ResponseWriter, r *http.Request) {
+	if _, ok := middleware.APIKeyFromContext(r.Context()); ok {
+		respond.Error(w, http.StatusForbidden, "API keys cannot impersonate users")
+		return
+	}
+	if _, ok := middleware.ImpersonationFromContext(r.Context()); ok {
+		respond.Error(w, http.StatusForbidden, "not allowed while impersonating a user")
+		return
+	}
+	id, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+	var req dto.ImpersonateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respond.Error(w, http.StatusBadRequest, "invalid JSON payload")
+		return
+	}
+	reason := strings.TrimSpace(req.Reason)
+	if reason == "" {
+		respond.Error(w, http.StatusBadRequest, "reason is required")
+		return
+	}
+	minutes := req.ExpiresInMinutes
+	if minutes == 0 {
+		minutes = defaultImpersonationMinutes
+	}
+	if minutes < 0 || minutes > maxImpersonationMinutes {
+		respond.Error(w, http.StatusBadRequest, fmt.Sprintf("expires_in_minutes must be between 1 and %d", maxImpersonationMinutes))
+		return
+	}
+	actor, _ := middleware.UserFromContext(r.Context())
+	if id == actor.ID {
+		respond.Error(w, http.StatusBadRequest, "you cannot impersonate yourself")
+		return
+	}
+	target, err := h.users.FindByID(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			respond.Error(w, http.StatusNotFound, "user not found")
+			return
+		}
+		log.Printf("find user error: %v", err)
+		respond.Error(w, http.StatusInternalServerError, "failed to fetch user")
+		return
+	}
+	if !slices.Contains(models.PlayerRoles, target.Role) {
+		respond.Error(w, http.StatusForbidden, "only players can be impersonated")
+		return
+	}
+	imp, err := h.impersonations.Start(r.Context(), target, actor.ID, reason, time.Duration(minutes)*time.Minute)
+	if err != nil {
+		log.Printf("impersonate user %d for user %d: %v", target.ID, actor.ID, err)
+		respond.Error(w, http.StatusInternalServerError, "failed to start impersonation")
+		return
+	}
+	log.Printf("impersonation %d: user %d started acting as user %d until %s: %s", imp.ID, actor.ID, target.ID, imp.ExpiresAt.Format(time.RFC3339), reason)
+	respond.Created(w, fmt.Sprintf("/admin/impersonations/%d", imp.ID), "impersonation started; the token will not be shown again", imp)
+}
+
+// handleList returns recent impersonations, of one player with ?user_id=.
+func (h *ImpersonationHandler) handleList(w http.ResponseWriter, r *http.Request) {
+	var userID int64
+	if raw := r.URL.Query().Get("user_id"); raw != "" {
+		id, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil || id <= 0 {
+			respond.Error(w, http.StatusBadRequest, "invalid user_id")
+			return
+		}
+		userID = id
+	}
+	list, err := h.store.ListImpersonations(r.Context(), userID, impersonationListLimit)
+	if err != nil {
+		log.Printf("list impersonations error: %v", err)
+		respond.Error(w, http.StatusInternalServerError, "failed to list impersonations")
+		return
+	}
+	respond.JSON(w, http.StatusOK, "impersonations fetched", list)
+}
+
+// handleRevoke ends an impersonation (DELETE); its token stops working at
+// once. The record is kept, marked revoked.
+func (h *ImpersonationHandler) handleRevoke(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+	actor, _ := middleware.UserFromContext(r.Context())
+	imp, err := h.store.RevokeImpersonation(r.Context(), id, actor.ID)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			respond.Error(w, http.StatusNotFound, "active impersonation not found")
+			return
+		}
+		log.Printf("revoke impersonation error: %v", err)
+		respond.Error(w, http.StatusInternalServerError, "failed to revoke impersonation")
+		return
+	}
+	log.Printf("impersonation %d: revoked by user %d", imp.ID, actor.ID)
+	respond.JSON(w, http.StatusOK, "impersonation revoked", imp)
+}
@@ -0,0 +1,151 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+
+	"github.com/hongminglow/all-in-be/internal/http/respond"
+	"github.com/hongminglow/all-in-be/internal/integrations"
+	"github.com/hongminglow/all-in-be/internal/middleware"
+	"github.com/hongminglow/all-in-be/internal/models"
+	"github.com/hongminglow/all-in-be/internal/storage"
+)
+
+// maxCallbackBytes caps the size of a provider callback body.
+const maxCallbackBytes = 1 << 20
+
+var inboundStatuses = []string{models.InboundReceived, models.InboundProcessed, models.InboundFailed, models.InboundDuplicate}
+
+// CallbackHandler receives callbacks from external providers.
+type CallbackHandler struct {
+	service *integrations.Service
+}
+
+// NewCallbackHandler constructs the handler.
+func NewCallbackHandler(service *integrations.Service) *CallbackHandler {
+	return &CallbackHandler{service: service}
+}
+
+// Register attaches the public callback route. Providers authenticate by
+// signature, which each processor verifies.
+func (h *CallbackHandler) Register(mux Router) {
+	mux.HandleFunc("POST /integrations/{provider}/callbacks", h.handleCallback)
+}
+
+func (h *CallbackHandler) handleCallback(w http.ResponseWriter, r *http.Request) {
+	payload, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxCallbackBytes))
+	if err != nil {
+		respond.Error(w, http.StatusRequestEntityTooLarge, "callback body too large")
+		return
+	}
+	provider := r.PathValue("provider")
+	delivery, err := h.service.Receive(r.Context(), provider, r.Header, payload)
+	switch {
+	case err == nil:
+		respond.JSON(w, http.StatusOK, "callback "+delivery.Status, nil)
+	case errors.Is(err, integrations.ErrUnknownProvider):
+		respond.Error(w, http.StatusNotFound, "unknown provider")
+	case errors.Is(err, integrations.ErrUnverified):
+		log.Printf("reject %s callback: %v", provider, err)
+		respond.Error(w, http.StatusUnauthorized, "callback could not be verified")
+	case errors.Is(err, integrations.ErrSessionRejected):
+		respond.Error(w, http.StatusForbidden, err.Error())
+	default:
+		// A 5xx asks the provider to retry; the stored delivery can also be replayed.
+		log.Printf("process %s callback %d: %v", provider, delivery.ID, err)
+		respond.Error(w, http.StatusInternalServerError, "failed to process callback")
+	}
+}
+
+// InboundDeliveryHandler lets administrators inspect stored provider callbacks
+// and replay ones that failed.
+type InboundDeliveryHandler struct {
+	store   storage.IntegrationStore
+	service *integrations.Service
+}
+
+// NewInboundDeliveryHandler constructs the handler.
+func NewInboundDeliveryHandler(store storage.IntegrationStore, service *integrations.Service) *InboundDeliveryHandler {
+	return &InboundDeliveryHandler{store: store, service: service}
+}
+
+// Register attaches the admin routes. They must be mounted behind middleware.Authenticate.
+func (h *InboundDeliveryHandler) Register(mux Router) {
+	mux.Handle("GET /admin/integrations/deliveries", middleware.RequirePermission(models.PermIntegrations, http.HandlerFunc(h.handleList)))
+	mux.Handle("GET /admin/integrations/deliveries/{id}", middleware.RequirePermission(models.PermIntegrations, http.HandlerFunc(h.handleGet)))
+	mux.Handle("POST /admin/integrations/deliveries/{id}/replay", middleware.RequirePermission(models.PermIntegrations, http.HandlerFunc(h.handleReplay)))
+}
+
+// handleList returns the newest deliveries first. Supports ?provider=, ?status=, ?event_id= and ?limit=.
+func (h *InboundDeliveryHandler) handleList(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	filter := models.InboundDeliveryFilter{Provider: q.Get("provider"), Status: q.Get("status"), EventID: q.Get("event_id")}
+	if filter.Status != "" && !slices.Contains(inboundStatuses, filter.Status) {
+		respond.Error(w, http.StatusBadRequest, "unknown status "+strconv.Quote(filter.Status))
+		return
+	}
+	limit := defaultDeliveryLimit
+	if raw := q.Get("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed < 1 || parsed > maxDeliveryLimit {
+			respond.Error(w, http.StatusBadRequest, "limit must be between 1 and 500")
+			return
+		}
+		limit = parsed
+	}
+	deliveries, err := h.store.ListInboundDeliveries(r.Context(), filter, limit)
+	if err != nil {
+		log.Printf("list inbound deliveries error: %v", err)
+		respond.Error(w, http.StatusInternalServerError, "failed to list deliveries")
+		return
+	}
+	respond.JSON(w, http.StatusOK, "deliveries fetched", deliveries)
+}
+
+func (h *InboundDeliveryHandler) handleGet(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+	delivery, err := h.store.FindInboundDelivery(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			respond.Error(w, http.StatusNotFound, "delivery not found")
+			return
+		}
+		log.Printf("find inbound delivery error: %v", err)
+		respond.Error(w, http.StatusInternalServerError, "failed to fetch delivery")
+		return
+	}
+	respond.JSON(w, http.StatusOK, "delivery fetched", delivery)
+}
+
+func (h *InboundDeliveryHandler) handleReplay(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+	delivery, err := h.service.Replay(r.Context(), id)
+	switch {
+	case err == nil:
+		respond.JSON(w, http.StatusOK, "delivery replayed", delivery)
+	case errors.Is(err, integrations.ErrProcessingFailed):
+		log.Printf("replay inbound delivery %d: %v", id, err)
+		respond.Error(w, http.StatusInternalServerError, "replay failed: "+delivery.Error)
+	case errors.Is(err, storage.ErrNotFound):
+		respond.Error(w, http.StatusNotFound, "delivery not found")
+	case errors.Is(err, integrations.ErrAlreadyApplied):
+		respond.Error(w, http.StatusConflict, "event was already applied")
+	case errors.Is(err, integrations.ErrSessionRejected):
+		respond.Error(w, http.StatusConflict, err.Error())
+	case errors.Is(err, integrations.ErrUnknownProvider):
+		respond.Error(w, http.StatusConflict, "provider is no longer configured")
+	default:
+		log.Printf("replay inbound delivery %d: %v", id, err)
+		respond.Error(w, http.StatusInternalServerError, "failed to replay delivery")
+	}
+}
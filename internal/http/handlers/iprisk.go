@@ -0,0 +1,146 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/hongminglow/all-in-be/internal/confighistory"
+	"github.com/hongminglow/all-in-be/internal/geoip"
+	"github.com/hongminglow/all-in-be/internal/http/respond"
+	"github.com/hongminglow/all-in-be/internal/middleware"
+	"github.com/hongminglow/all-in-be/internal/models"
+	"github.com/hongminglow/all-in-be/internal/models/dto"
+	"github.com/hongminglow/all-in-be/internal/storage"
+)
+
+const (
+	defaultIPRiskEventLimit = 50
+	maxIPRiskEventLimit     = 500
+)
+
+// IPRiskHandler lets administrators set what happens to sign-ins, sign-ups and
+// withdrawals from VPN, proxy and datacenter IPs, and lets staff review the
+// requests that were flagged, stepped up or blocked. Policy changes are
+// recorded in the configuration history.
+type IPRiskHandler struct {
+	store      storage.Store
+	invalidate func()
+}
+
+// NewIPRiskHandler constructs the handler. invalidate is called after every
+// policy change so cached policies are reloaded.
+func NewIPRiskHandler(store storage.Store, invalidate func()) *IPRiskHandler {
+	return &IPRiskHandler{store: store, invalidate: invalidate}
+}
+
+// Register attaches the admin routes. They must be mounted behind middleware.Authenticate.
+func (h *IPRiskHandler) Register(mux Router) {
+	mux.Handle("GET /admin/ip-risk/policies", middleware.RequirePermission(models.PermConfigManage, http.HandlerFunc(h.handleListPolicies)))
+	mux.Handle("PUT /admin/ip-risk/policies", middleware.RequirePermission(models.PermConfigManage, http.HandlerFunc(h.upsert)))
+	mux.Handle("DELETE /admin/ip-risk/policies", middleware.RequirePermission(models.PermConfigManage, http.HandlerFunc(h.delete)))
+	mux.Handle("GET /admin/ip-risk/events", middleware.RequirePermission(models.PermSecurityRead, http.HandlerFunc(h.handleEvents)))
+}
+
+func (h *IPRiskHandler) handleListPolicies(w http.ResponseWriter, r *http.Request) {
+	policies, err := h.store.ListIPRiskPolicies(r.Context())
+	if err != nil {
+		log.Printf("list ip risk policies error: %v", err)
+		respond.Error(w, http.StatusInternalServerError, "failed to list ip risk policies")
+		return
+	}
+	respond.JSON(w, http.StatusOK, "ip risk policies fetched", policies)
+}
+
+func (h *IPRiskHandler) upsert(w http.ResponseWriter, r *http.Request) {
+	var req dto.UpsertIPRiskPolicyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respond.Error(w, http.StatusBadRequest, "invalid JSON payload")
+		return
+	}
+	action := strings.ToLower(strings.TrimSpace(req.Action))
+	if !slices.Contains(models.IPRiskActions, action) {
+		respond.Error(w, http.StatusBadRequest, "action must be one of: "+strings.Join(models.IPRiskActions, ", "))
+		return
+	}
+	country, ok := policyCountry(req.Country)
+	if !ok {
+		respond.Error(w, http.StatusBadRequest, "country must be an ISO 3166-1 alpha-2 code or empty")
+		return
+	}
+	actor, _ := middleware.UserFromContext(r.Context())
+	var saved models.IPRiskPolicy
+	err := h.store.WithTx(r.Context(), func(tx storage.Repositories) error {
+		var err error
+		saved, err = confighistory.SaveIPRiskPolicy(r.Context(), tx, actor.ID, models.IPRiskPolicy{
+			Tenant:  strings.TrimSpace(req.Tenant),
+			Country: country,
+			Action:  action,
+		})
+		return err
+	})
+	if err != nil {
+		log.Printf("upsert ip risk policy error: %v", err)
+		respond.Error(w, http.StatusInternalServerError, "failed to save ip risk policy")
+		return
+	}
+	h.invalidate()
+	respond.JSON(w, http.StatusOK, "ip risk policy saved", saved)
+}
+
+func (h *IPRiskHandler) delete(w http.ResponseWriter, r *http.Request) {
+	tenant := strings.TrimSpace(r.URL.Query().Get("tenant"))
+	country, ok := policyCountry(r.URL.Query().Get("country"))
+	if !ok {
+		respond.Error(w, http.StatusBadRequest, "country must be an ISO 3166-1 alpha-2 code or empty")
+		return
+	}
+	actor, _ := middleware.UserFromContext(r.Context())
+	err := h.store.WithTx(r.Context(), func(tx storage.Repositories) error {
+		return confighistory.DeleteIPRiskPolicy(r.Context(), tx, actor.ID, tenant, country)
+	})
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			respond.Error(w, http.StatusNotFound, "ip risk policy not found")
+			return
+		}
+		log.Printf("delete ip risk policy error: %v", err)
+		respond.Error(w, http.StatusInternalServerError, "failed to delete ip risk policy")
+		return
+	}
+	h.invalidate()
+	respond.JSON(w, http.StatusOK, "ip risk policy deleted", nil)
+}
+
+// handleEvents returns recent risky requests, newest first. Supports ?limit=.
+func (h *IPRiskHandler) handleEvents(w http.ResponseWriter, r *http.Request) {
+	limit := defaultIPRiskEventLimit
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n <= 0 || n > maxIPRiskEventLimit {
+			respond.Error(w, http.StatusBadRequest, "limit must be between 1 and 500")
+			return
+		}
+		limit = n
+	}
+	events, err := h.store.ListIPRiskEvents(r.Context(), limit)
+	if err != nil {
+		log.Printf("list ip risk events error: %v", err)
+		respond.Error(w, http.StatusInternalServerError, "failed to list ip risk events")
+		return
+	}
+	respond.JSON(w, http.StatusOK, "ip risk events fetched", events)
+}
+
+// policyCountry normalizes a policy's country; empty is the catch-all.
+func policyCountry(raw string) (string, bool) {
+	if strings.TrimSpace(raw) == "" {
+		return "", true
+	}
+	country := geoip.Normalize(raw)
+	return country, country != ""
+}
@@ -0,0 +1,161 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/hongminglow/all-in-be/internal/config"
+	"github.com/hongminglow/all-in-be/internal/http/respond"
+	"github.com/hongminglow/all-in-be/internal/jackpots"
+	"github.com/hongminglow/all-in-be/internal/middleware"
+	"github.com/hongminglow/all-in-be/internal/models"
+	"github.com/hongminglow/all-in-be/internal/models/dto"
+	"github.com/hongminglow/all-in-be/internal/storage"
+	"github.com/hongminglow/all-in-be/internal/wallet"
+)
+
+// maxJackpotWinKey bounds the client-chosen win key.
+const maxJackpotWinKey = 100
+
+// JackpotHandler shows the jackpot pots to anyone and lets admins pay a pot
+// out to its winner.
+type JackpotHandler struct {
+	jackpots *jackpots.Service
+	cfg      config.EventsConfig
+}
+
+// NewJackpotHandler constructs the handler. The stream polls and sends
+// heartbeats as often as the event stream does.
+func NewJackpotHandler(j *jackpots.Service, cfg config.EventsConfig) *JackpotHandler {
+	if cfg.StreamPollInterval <= 0 {
+		cfg.StreamPollInterval = defaultStreamPollInterval
+	}
+	if cfg.StreamHeartbeat <= 0 {
+		cfg.StreamHeartbeat = defaultStreamHeartbeat
+	}
+	return &JackpotHandler{jackpots: j, cfg: cfg}
+}
+
+// Register attaches the public routes.
+func (h *JackpotHandler) Register(mux Router) {
+	mux.HandleFunc("GET /jackpots", h.handleList)
+	mux.HandleFunc("GET /jackpots/stream", h.handleStream)
+}
+
+// RegisterAdmin attaches the win route. It must be mounted behind
+// middleware.Authenticate.
+func (h *JackpotHandler) RegisterAdmin(mux Router) {
+	mux.Handle("POST /admin/jackpots/{name}/wins", middleware.RequirePermission(models.PermBalanceAdjust, http.HandlerFunc(h.handleWin)))
+}
+
+func (h *JackpotHandler) handleList(w http.ResponseWriter, r *http.Request) {
+	pots, err := h.jackpots.List(r.Context())
+	if err != nil {
+		log.Printf("list jackpots: %v", err)
+		respond.Error(w, http.StatusInternalServerError, "failed to list jackpots")
+		return
+	}
+	respond.JSON(w, http.StatusOK, "jackpots fetched", pots)
+}
+
+// handleStream sends the pots as a "jackpots" Server-Sent Event when the
+// stream opens and again whenever they change. There is nothing to resume:
+// a client that reconnects gets the current pots.
+func (h *JackpotHandler) handleStream(w http.ResponseWriter, r *http.Request) {
+	wake, stop := h.jackpots.Watch()
+	defer stop()
+
+	rc := http.NewResponseController(w)
+	_ = rc.SetReadDeadline(time.Time{})
+	send := func(write func(io.Writer) error) bool {
+		_ = rc.SetWriteDeadline(time.Now().Add(h.cfg.StreamHeartbeat + streamWriteGrace))
+		return write(w) == nil && rc.Flush() == nil
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	retry := fmt.Sprintf("retry: %d\n\n", h.cfg.StreamPollInterval.Milliseconds())
+	if !send(func(w io.Writer) error { _, err := io.WriteString(w, retry); return err }) {
+		return
+	}
+
+	poll := time.NewTicker(h.cfg.StreamPollInterval)
+	defer poll.Stop()
+	heartbeat := time.NewTicker(h.cfg.StreamHeartbeat)
+	defer heartbeat.Stop()
+	var last []byte
+	for {
+		pots, err := h.jackpots.List(r.Context())
+		if err != nil {
+			if r.Context().Err() == nil {
+				log.Printf("jackpot stream: %v", err)
+			}
+			return
+		}
+		data, err := json.Marshal(pots)
+		if err != nil {
+			return
+		}
+		if !bytes.Equal(data, last) {
+			if !send(func(w io.Writer) error { _, err := fmt.Fprintf(w, "event: jackpots\ndata: %s\n\n", data); return err }) {
+				return
+			}
+			last = data
+			heartbeat.Reset(h.cfg.StreamHeartbeat)
+		}
+		select {
+		case <-r.Context().Done():
+			return
+		case <-h.jackpots.Done():
+			return
+		case <-wake:
+		case <-poll.C:
+		case <-heartbeat.C:
+			if !send(func(w io.Writer) error { _, err := io.WriteString(w, ": keepalive\n\n"); return err }) {
+				return
+			}
+		}
+	}
+}
+
+func (h *JackpotHandler) handleWin(w http.ResponseWriter, r *http.Request) {
+	var req dto.JackpotWinRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respond.Error(w, http.StatusBadRequest, "invalid JSON payload")
+		return
+	}
+	req.Key = strings.TrimSpace(req.Key)
+	switch {
+	case req.UserID <= 0:
+		respond.Error(w, http.StatusBadRequest, "user_id is required")
+		return
+	case req.Key == "" || len(req.Key) > maxJackpotWinKey:
+		respond.Error(w, http.StatusBadRequest, fmt.Sprintf("key is required and at most %d bytes", maxJackpotWinKey))
+		return
+	}
+
+	entry, err := h.jackpots.Win(r.Context(), r.PathValue("name"), req.UserID, req.Key)
+	switch {
+	case errors.Is(err, jackpots.ErrUnknownJackpot):
+		respond.Error(w, http.StatusNotFound, "jackpot not found")
+	case errors.Is(err, storage.ErrNotFound):
+		respond.Error(w, http.StatusNotFound, "user not found")
+	case errors.Is(err, jackpots.ErrEmptyJackpot):
+		respond.Error(w, http.StatusConflict, "the jackpot is empty")
+	case errors.Is(err, wallet.ErrOperationConflict):
+		respond.Error(w, http.StatusConflict, "key was already used for a different win")
+	case err != nil:
+		log.Printf("pay jackpot %s to user %d: %v", r.PathValue("name"), req.UserID, err)
+		respond.Error(w, http.StatusInternalServerError, "failed to pay jackpot")
+	default:
+		respond.JSON(w, http.StatusOK, "jackpot paid", entry)
+	}
+}
@@ -0,0 +1,154 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/hongminglow/all-in-be/internal/http/respond"
+	"github.com/hongminglow/all-in-be/internal/middleware"
+	"github.com/hongminglow/all-in-be/internal/models"
+	"github.com/hongminglow/all-in-be/internal/money"
+	"github.com/hongminglow/all-in-be/internal/storage"
+)
+
+const (
+	defaultLeaderboardLimit = 20
+	// maxLeaderboardLimit is also how many entries are cached per board, so
+	// every limit is served from one cached page.
+	maxLeaderboardLimit = 100
+)
+
+// leaderboardWindows lists the windows each metric is ranked over.
+var leaderboardWindows = map[string][]string{
+	models.LeaderboardBalance:     {models.LeaderboardAllTime},
+	models.LeaderboardWinnings:    {models.LeaderboardDaily, models.LeaderboardWeekly, models.LeaderboardAllTime},
+	models.LeaderboardGamesPlayed: {models.LeaderboardDaily, models.LeaderboardWeekly, models.LeaderboardAllTime},
+}
+
+type cachedBoard struct {
+	entries   []models.LeaderboardEntry
+	fetchedAt time.Time
+}
+
+// LeaderboardHandler ranks players by balance, winnings or games played. The
+// top of each board is cached in process; the caller's own standing is looked
+// up on every request.
+type LeaderboardHandler struct {
+	store storage.LeaderboardStore
+	ttl   time.Duration
+
+	mu     sync.Mutex
+	boards map[[2]string]cachedBoard
+}
+
+// NewLeaderboardHandler constructs the handler. A zero ttl disables caching.
+func NewLeaderboardHandler(store storage.LeaderboardStore, ttl time.Duration) *LeaderboardHandler {
+	return &LeaderboardHandler{store: store, ttl: ttl, boards: map[[2]string]cachedBoard{}}
+}
+
+// Invalidate drops the cached results so the next request reloads them.
+func (h *LeaderboardHandler) Invalidate() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	clear(h.boards)
+}
+
+// Register attaches the /leaderboard route. It must be mounted behind middleware.Authenticate.
+func (h *LeaderboardHandler) Register(mux Router) {
+	mux.Handle("GET /leaderboard", middleware.ETag(http.HandlerFunc(h.handle)))
+}
+
+// handle supports ?metric= (default winnings), ?window= (default all-time) and ?limit=.
+func (h *LeaderboardHandler) handle(w http.ResponseWriter, r *http.Request) {
+	user, ok := middleware.UserFromContext(r.Context())
+	if !ok {
+		respond.Error(w, http.StatusUnauthorized, "authentication required")
+		return
+	}
+	q := r.URL.Query()
+	metric, window := q.Get("metric"), q.Get("window")
+	if metric == "" {
+		metric = models.LeaderboardWinnings
+	}
+	if window == "" {
+		window = models.LeaderboardAllTime
+	}
+	windows, ok := leaderboardWindows[metric]
+	if !ok {
+		respond.Error(w, http.StatusBadRequest, "metric must be balance, winnings or games_played")
+		return
+	}
+	if !slices.Contains(windows, window) {
+		respond.Error(w, http.StatusBadRequest, metric+" is not ranked "+window)
+		return
+	}
+	limit := defaultLeaderboardLimit
+	if raw := q.Get("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed < 1 || parsed > maxLeaderboardLimit {
+			respond.Error(w, http.StatusBadRequest, "limit must be between 1 and 100")
+			return
+		}
+		limit = parsed
+	}
+
+	entries, ok := h.cached(metric, window)
+	if !ok {
+		var err error
+		if entries, err = h.store.ListLeaderboard(r.Context(), metric, window, maxLeaderboardLimit); err != nil {
+			log.Printf("list %s %s leaderboard: %v", window, metric, err)
+			respond.Error(w, http.StatusInternalServerError, "failed to fetch leaderboard")
+			return
+		}
+		for i := range entries {
+			entries[i].Player = displayName(entries[i].Username, entries[i].PublicActivity)
+		}
+		h.mu.Lock()
+		h.boards[[2]string{metric, window}] = cachedBoard{entries: entries, fetchedAt: time.Now()}
+		h.mu.Unlock()
+	}
+	// The cached entries are shared, so they are copied before being formatted.
+	board := models.Leaderboard{Metric: metric, Window: window, Entries: slices.Clone(entries[:min(len(entries), limit)])}
+	me, err := h.store.FindLeaderboardEntry(r.Context(), metric, window, user.ID)
+	switch {
+	case err == nil:
+		// Players see their own name even when it is masked for others.
+		me.Player = me.Username
+		board.Me = &me
+	case !errors.Is(err, storage.ErrNotFound):
+		log.Printf("find leaderboard entry for user %d: %v", user.ID, err)
+		respond.Error(w, http.StatusInternalServerError, "failed to fetch leaderboard")
+		return
+	}
+	if metric != models.LeaderboardGamesPlayed {
+		format := money.FormatFromContext(r.Context())
+		board.MoneyFormat = &format
+		for i := range board.Entries {
+			board.Entries[i].ValueMoney = moneyAmount(format, board.Entries[i].Value)
+		}
+		if board.Me != nil {
+			board.Me.ValueMoney = moneyAmount(format, board.Me.Value)
+		}
+	}
+	respond.JSON(w, http.StatusOK, "leaderboard fetched", board)
+}
+
+func moneyAmount(format models.MoneyFormat, amount float64) *models.MoneyAmount {
+	formatted := money.Amount(format, amount)
+	return &formatted
+}
+
+func (h *LeaderboardHandler) cached(metric, window string) ([]models.LeaderboardEntry, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	board, ok := h.boards[[2]string{metric, window}]
+	if !ok || time.Since(board.fetchedAt) > h.ttl {
+		return nil, false
+	}
+	return board.entries, true
+}
@@ -0,0 +1,40 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/hongminglow/all-in-be/internal/http/respond"
+	"github.com/hongminglow/all-in-be/internal/middleware"
+	"github.com/hongminglow/all-in-be/internal/models"
+	"github.com/hongminglow/all-in-be/internal/storage"
+)
+
+// LedgerHandler reports on the double-entry ledger.
+type LedgerHandler struct {
+	store storage.LedgerStore
+}
+
+// NewLedgerHandler constructs the handler.
+func NewLedgerHandler(store storage.LedgerStore) *LedgerHandler {
+	return &LedgerHandler{store: store}
+}
+
+// Register attaches the admin routes. They must be mounted behind middleware.Authenticate.
+func (h *LedgerHandler) Register(mux Router) {
+	mux.Handle("GET /admin/ledger/trial-balance", middleware.RequirePermission(models.PermStatsRead, http.HandlerFunc(h.handleTrialBalance)))
+}
+
+func (h *LedgerHandler) handleTrialBalance(w http.ResponseWriter, r *http.Request) {
+	lines, err := h.store.TrialBalance(r.Context())
+	if err != nil {
+		log.Printf("trial balance: %v", err)
+		respond.Error(w, http.StatusInternalServerError, "failed to build trial balance")
+		return
+	}
+	report := models.NewTrialBalance(lines)
+	if !report.Balanced {
+		log.Printf("trial balance: debits %.2f and credits %.2f differ", report.TotalDebits, report.TotalCredits)
+	}
+	respond.JSON(w, http.StatusOK, "trial balance fetched", report)
+}
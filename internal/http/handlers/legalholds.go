@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/hongminglow/all-in-be/internal/http/respond"
+	"github.com/hongminglow/all-in-be/internal/middleware"
+	"github.com/hongminglow/all-in-be/internal/models"
+	"github.com/hongminglow/all-in-be/internal/models/dto"
+	"github.com/hongminglow/all-in-be/internal/storage"
+)
+
+// LegalHoldHandler places and releases legal holds, which keep a user's
+// records out of retention processing during disputes and regulator requests.
+type LegalHoldHandler struct {
+	store storage.Repositories
+}
+
+// NewLegalHoldHandler constructs the handler.
+func NewLegalHoldHandler(store storage.Repositories) *LegalHoldHandler {
+	return &LegalHoldHandler{store: store}
+}
+
+// Register attaches the admin routes. They must be mounted behind middleware.Authenticate.
+func (h *LegalHoldHandler) Register(mux Router) {
+	mux.Handle("GET /admin/users/{id}/legal-holds", middleware.RequirePermission(models.PermLegalHold, http.HandlerFunc(h.handleList)))
+	mux.Handle("POST /admin/users/{id}/legal-holds", middleware.RequirePermission(models.PermLegalHold, http.HandlerFunc(h.handlePlace)))
+	mux.Handle("DELETE /admin/users/{id}/legal-holds/{holdID}", middleware.RequirePermission(models.PermLegalHold, http.HandlerFunc(h.handleRelease)))
+}
+
+// handleList lists the user's holds, newest first.
+func (h *LegalHoldHandler) handleList(w http.ResponseWriter, r *http.Request) {
+	userID, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+	if _, err := h.store.FindByID(r.Context(), userID); err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			respond.Error(w, http.StatusNotFound, "user not found")
+			return
+		}
+		log.Printf("find user error: %v", err)
+		respond.Error(w, http.StatusInternalServerError, "failed to fetch legal holds")
+		return
+	}
+	holds, err := h.store.ListLegalHolds(r.Context(), userID)
+	if err != nil {
+		log.Printf("list legal holds error: %v", err)
+		respond.Error(w, http.StatusInternalServerError, "failed to fetch legal holds")
+		return
+	}
+	respond.JSON(w, http.StatusOK, "legal holds fetched", holds)
+}
+
+// handlePlace places a new hold on the user.
+func (h *LegalHoldHandler) handlePlace(w http.ResponseWriter, r *http.Request) {
+	userID, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+	var req dto.PlaceLegalHoldRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respond.Error(w, http.StatusBadRequest, "invalid JSON payload")
+		return
+	}
+	req.Reason = strings.TrimSpace(req.Reason)
+	if req.Reason == "" {
+		respond.Error(w, http.StatusBadRequest, "reason is required")
+		return
+	}
+	if req.Dataset != "" && !slices.Contains(models.ArchiveDatasets, req.Dataset) {
+		respond.Error(w, http.StatusBadRequest, "dataset must be one of "+strings.Join(models.ArchiveDatasets, ", ")+", or empty for all")
+		return
+	}
+	actor, _ := middleware.UserFromContext(r.Context())
+	hold, err := h.store.PlaceLegalHold(r.Context(), models.LegalHold{
+		UserID:   userID,
+		Dataset:  req.Dataset,
+		Reason:   req.Reason,
+		PlacedBy: actor.ID,
+	})
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			respond.Error(w, http.StatusNotFound, "user not found")
+			return
+		}
+		log.Printf("place legal hold error: %v", err)
+		respond.Error(w, http.StatusInternalServerError, "failed to place legal hold")
+		return
+	}
+	respond.Created(w, fmt.Sprintf("/admin/users/%d/legal-holds/%d", userID, hold.ID), "legal hold placed", hold)
+}
+
+// handleRelease ends a hold (DELETE). The hold is kept, marked released.
+func (h *LegalHoldHandler) handleRelease(w http.ResponseWriter, r *http.Request) {
+	userID, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+	holdID, ok := pathID(w, r, "holdID")
+	if !ok {
+		return
+	}
+	actor, _ := middleware.UserFromContext(r.Context())
+	hold, err := h.store.ReleaseLegalHold(r.Context(), userID, holdID, actor.ID)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			respond.Error(w, http.StatusNotFound, "active legal hold not found")
+			return
+		}
+		log.Printf("release legal hold error: %v", err)
+		respond.Error(w, http.StatusInternalServerError, "failed to release legal hold")
+		return
+	}
+	respond.JSON(w, http.StatusOK, "legal hold released", hold)
+}
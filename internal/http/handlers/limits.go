@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/hongminglow/all-in-be/internal/http/respond"
+	"github.com/hongminglow/all-in-be/internal/limits"
+	"github.com/hongminglow/all-in-be/internal/middleware"
+	"github.com/hongminglow/all-in-be/internal/models"
+	"github.com/hongminglow/all-in-be/internal/models/dto"
+	"github.com/hongminglow/all-in-be/internal/storage"
+)
+
+// maxExclusionDays is the longest self-exclusion a player can ask for at once.
+const maxExclusionDays = 5 * 365
+
+// LimitHandler lets players set responsible gaming limits and exclude
+// themselves from games, and support staff read them.
+type LimitHandler struct {
+	store  storage.UserStore
+	limits *limits.Service
+}
+
+// NewLimitHandler constructs the handler.
+func NewLimitHandler(store storage.UserStore, service *limits.Service) *LimitHandler {
+	return &LimitHandler{store: store, limits: service}
+}
+
+// Register attaches the routes. They must be mounted behind middleware.Authenticate.
+func (h *LimitHandler) Register(mux Router) {
+	mux.HandleFunc("GET /me/limits", h.handleGetOwn)
+	mux.Handle("PUT /me/limits", middleware.RefuseImpersonation(http.HandlerFunc(h.handleSet)))
+	mux.Handle("POST /me/self-exclusion", middleware.RefuseImpersonation(http.HandlerFunc(h.handleExclude)))
+	mux.Handle("GET /admin/users/{id}/limits", middleware.RequirePermission(models.PermUsersRead, http.HandlerFunc(h.handleGet)))
+}
+
+func (h *LimitHandler) handleGetOwn(w http.ResponseWriter, r *http.Request) {
+	user, ok := middleware.UserFromContext(r.Context())
+	if !ok {
+		respond.Error(w, http.StatusUnauthorized, "authentication required")
+		return
+	}
+	h.respondLimits(w, r, user.ID)
+}
+
+func (h *LimitHandler) handleGet(w http.ResponseWriter, r *http.Request) {
+	userID, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+	if _, err := h.store.FindByID(r.Context(), userID); err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			respond.Error(w, http.StatusNotFound, "user not found")
+			return
+		}
+		log.Printf("find user error: %v", err)
+		respond.Error(w, http.StatusInternalServerError, "failed to fetch limits")
+		return
+	}
+	h.respondLimits(w, r, userID)
+}
+
+func (h *LimitHandler) respondLimits(w http.ResponseWriter, r *http.Request, userID int64) {
+	current, err := h.limits.Get(r.Context(), userID)
+	if err != nil {
+		log.Printf("get gaming limits of user %d: %v", userID, err)
+		respond.Error(w, http.StatusInternalServerError, "failed to fetch limits")
+		return
+	}
+	respond.JSON(w, http.StatusOK, "limits fetched", current)
+}
+
+// handleSet replaces the caller's limits. Omitted or null limits are lifted.
+func (h *LimitHandler) handleSet(w http.ResponseWriter, r *http.Request) {
+	user, ok := middleware.UserFromContext(r.Context())
+	if !ok {
+		respond.Error(w, http.StatusUnauthorized, "authentication required")
+		return
+	}
+	var req dto.GamingLimitsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respond.Error(w, http.StatusBadRequest, "invalid JSON payload")
+		return
+	}
+	updated, err := h.limits.Set(r.Context(), user.ID, models.LimitSet{
+		DailyDeposit:   req.DailyDeposit,
+		DailyLoss:      req.DailyLoss,
+		SessionMinutes: req.SessionMinutes,
+	})
+	switch {
+	case errors.Is(err, limits.ErrInvalidLimit):
+		respond.Error(w, http.StatusBadRequest, fmt.Sprintf("limits must be positive, and session_minutes at most %d", limits.MaxSessionMinutes))
+	case err != nil:
+		log.Printf("set gaming limits of user %d: %v", user.ID, err)
+		respond.Error(w, http.StatusInternalServerError, "failed to set limits")
+	case updated.Pending != nil:
+		respond.JSON(w, http.StatusOK, "stricter limits applied; looser ones take effect at pending_from", updated)
+	default:
+		respond.JSON(w, http.StatusOK, "limits applied", updated)
+	}
+}
+
+// handleExclude closes games to the caller for the given number of days.
+func (h *LimitHandler) handleExclude(w http.ResponseWriter, r *http.Request) {
+	user, ok := middleware.UserFromContext(r.Context())
+	if !ok {
+		respond.Error(w, http.StatusUnauthorized, "authentication required")
+		return
+	}
+	var req dto.SelfExclusionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respond.Error(w, http.StatusBadRequest, "invalid JSON payload")
+		return
+	}
+	if req.Days < 1 || req.Days > maxExclusionDays {
+		respond.Error(w, http.StatusBadRequest, fmt.Sprintf("days must be between 1 and %d", maxExclusionDays))
+		return
+	}
+	updated, err := h.limits.Exclude(r.Context(), user.ID, time.Duration(req.Days)*24*time.Hour)
+	switch {
+	case errors.Is(err, limits.ErrShortenExclusion):
+		respond.Error(w, http.StatusConflict, "an exclusion in force cannot be shortened")
+	case err != nil:
+		log.Printf("exclude user %d: %v", user.ID, err)
+		respond.Error(w, http.StatusInternalServerError, "failed to start the exclusion")
+	default:
+		respond.JSON(w, http.StatusOK, "games are closed until excluded_until", updated)
+	}
+}
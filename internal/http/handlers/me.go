@@ -0,0 +1,38 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/hongminglow/all-in-be/internal/http/respond"
+	"github.com/hongminglow/all-in-be/internal/middleware"
+	"github.com/hongminglow/all-in-be/internal/models/dto"
+	"github.com/hongminglow/all-in-be/internal/money"
+)
+
+// MeHandler serves the authenticated caller's own profile.
+type MeHandler struct{}
+
+// NewMeHandler constructs the handler.
+func NewMeHandler() *MeHandler {
+	return &MeHandler{}
+}
+
+// Register attaches the /me route. It must be mounted behind middleware.Authenticate
+// and middleware.LoadUser.
+func (h *MeHandler) Register(mux Router) {
+	mux.Handle("GET /me", middleware.ETag(http.HandlerFunc(h.handleMe)))
+}
+
+func (h *MeHandler) handleMe(w http.ResponseWriter, r *http.Request) {
+	user, ok := middleware.UserFromContext(r.Context())
+	if !ok {
+		respond.Error(w, http.StatusUnauthorized, "authentication required")
+		return
+	}
+	format := money.FormatFromContext(r.Context())
+	respond.JSON(w, http.StatusOK, "profile fetched", dto.MeResponse{
+		User:         user,
+		BalanceMoney: money.Amount(format, user.Balance),
+		MoneyFormat:  format,
+	})
+}
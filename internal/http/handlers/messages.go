@@ -0,0 +1,249 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/hongminglow/all-in-be/internal/config"
+	"github.com/hongminglow/all-in-be/internal/http/respond"
+	"github.com/hongminglow/all-in-be/internal/inbox"
+	"github.com/hongminglow/all-in-be/internal/middleware"
+	"github.com/hongminglow/all-in-be/internal/models"
+	"github.com/hongminglow/all-in-be/internal/models/dto"
+	"github.com/hongminglow/all-in-be/internal/storage"
+)
+
+const (
+	defaultMessageLimit = 50
+	maxMessageLimit     = 100
+)
+
+// MessageHandler serves players their inbox and lets staff write to it.
+type MessageHandler struct {
+	inbox *inbox.Service
+	cfg   config.EventsConfig
+}
+
+// NewMessageHandler constructs the handler. The stream polls and sends
+// heartbeats as often as the event stream does.
+func NewMessageHandler(in *inbox.Service, cfg config.EventsConfig) *MessageHandler {
+	if cfg.StreamPollInterval <= 0 {
+		cfg.StreamPollInterval = defaultStreamPollInterval
+	}
+	if cfg.StreamHeartbeat <= 0 {
+		cfg.StreamHeartbeat = defaultStreamHeartbeat
+	}
+	return &MessageHandler{inbox: in, cfg: cfg}
+}
+
+// Register attaches the routes. They must be mounted behind middleware.Authenticate.
+func (h *MessageHandler) Register(mux Router) {
+	mux.HandleFunc("GET /me/messages", h.handleList)
+	mux.HandleFunc("GET /me/messages/stream", h.handleStream)
+	mux.Handle("POST /me/messages/read", middleware.RefuseImpersonation(http.HandlerFunc(h.handleRead)))
+	mux.Handle("POST /admin/users/{id}/messages", middleware.RequirePermission(models.PermMessagesSend, http.HandlerFunc(h.handleSend)))
+	mux.Handle("POST /admin/messages/broadcasts", middleware.RequirePermission(models.PermMessagesSend, http.HandlerFunc(h.handleBroadcast)))
+}
+
+// handleList returns the newest messages, only unread ones with
+// ?unread=true, and the unread count.
+func (h *MessageHandler) handleList(w http.ResponseWriter, r *http.Request) {
+	user, ok := middleware.UserFromContext(r.Context())
+	if !ok {
+		respond.Error(w, http.StatusUnauthorized, "authentication required")
+		return
+	}
+	limit := defaultMessageLimit
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed < 1 || parsed > maxMessageLimit {
+			respond.Error(w, http.StatusBadRequest, "limit must be between 1 and 100")
+			return
+		}
+		limit = parsed
+	}
+	var unreadOnly bool
+	if raw := r.URL.Query().Get("unread"); raw != "" {
+		parsed, err := strconv.ParseBool(raw)
+		if err != nil {
+			respond.Error(w, http.StatusBadRequest, "unread must be true or false")
+			return
+		}
+		unreadOnly = parsed
+	}
+	messages, err := h.inbox.Inbox(r.Context(), user.ID, unreadOnly, limit)
+	if err != nil {
+		log.Printf("list messages of user %d: %v", user.ID, err)
+		respond.Error(w, http.StatusInternalServerError, "failed to list messages")
+		return
+	}
+	respond.JSON(w, http.StatusOK, "messages fetched", messages)
+}
+
+func (h *MessageHandler) handleRead(w http.ResponseWriter, r *http.Request) {
+	user, ok := middleware.UserFromContext(r.Context())
+	if !ok {
+		respond.Error(w, http.StatusUnauthorized, "authentication required")
+		return
+	}
+	var req dto.MarkMessagesReadRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		respond.Error(w, http.StatusBadRequest, "invalid JSON payload")
+		return
+	}
+	unread, err := h.inbox.MarkRead(r.Context(), user.ID, req.IDs)
+	if err != nil {
+		log.Printf("mark messages of user %d read: %v", user.ID, err)
+		respond.Error(w, http.StatusInternalServerError, "failed to mark messages read")
+		return
+	}
+	respond.JSON(w, http.StatusOK, "messages marked read", dto.MarkMessagesReadResponse{Unread: unread})
+}
+
+func (h *MessageHandler) handleSend(w http.ResponseWriter, r *http.Request) {
+	sender, _ := middleware.UserFromContext(r.Context())
+	userID, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+	var req dto.SendMessageRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respond.Error(w, http.StatusBadRequest, "invalid JSON payload")
+		return
+	}
+	m, err := h.inbox.Send(r.Context(), sender.ID, userID, req.Subject, req.Body)
+	switch {
+	case errors.Is(err, inbox.ErrInvalidMessage):
+		respond.Error(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, storage.ErrNotFound):
+		respond.Error(w, http.StatusNotFound, "user not found")
+	case err != nil:
+		log.Printf("send message to user %d: %v", userID, err)
+		respond.Error(w, http.StatusInternalServerError, "failed to send message")
+	default:
+		respond.Created(w, "", "message sent", m)
+	}
+}
+
+func (h *MessageHandler) handleBroadcast(w http.ResponseWriter, r *http.Request) {
+	sender, _ := middleware.UserFromContext(r.Context())
+	var req dto.BroadcastMessageRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respond.Error(w, http.StatusBadRequest, "invalid JSON payload")
+		return
+	}
+	n, err := h.inbox.Broadcast(r.Context(), sender.ID, req.Subject, req.Body, req.Roles)
+	switch {
+	case errors.Is(err, inbox.ErrInvalidMessage):
+		respond.Error(w, http.StatusBadRequest, err.Error())
+	case err != nil:
+		log.Printf("broadcast message: %v", err)
+		respond.Error(w, http.StatusInternalServerError, "failed to broadcast message")
+	default:
+		respond.JSON(w, http.StatusOK, "announcement sent", dto.BroadcastMessageResponse{Recipients: n})
+	}
+}
+
+// handleStream sends each new message as a "message" Server-Sent Event whose
+// ID is the message's. Like GET /events it resumes after the Last-Event-ID
+// header or ?last_event_id=, and otherwise starts with the next message.
+func (h *MessageHandler) handleStream(w http.ResponseWriter, r *http.Request) {
+	user, ok := middleware.UserFromContext(r.Context())
+	if !ok {
+		respond.Error(w, http.StatusUnauthorized, "authentication required")
+		return
+	}
+	raw := r.Header.Get("Last-Event-ID")
+	if raw == "" {
+		raw = r.URL.Query().Get("last_event_id")
+	}
+	var after int64
+	if raw != "" {
+		parsed, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil || parsed < 0 {
+			respond.Error(w, http.StatusBadRequest, "Last-Event-ID must be an event ID")
+			return
+		}
+		after = parsed
+	} else {
+		cursor, err := h.inbox.Cursor(r.Context(), user.ID)
+		if err != nil {
+			log.Printf("open message stream for user %d: %v", user.ID, err)
+			respond.Error(w, http.StatusInternalServerError, "failed to open message stream")
+			return
+		}
+		after = cursor
+	}
+	wake, stop := h.inbox.Watch(user.ID)
+	defer stop()
+
+	rc := http.NewResponseController(w)
+	_ = rc.SetReadDeadline(time.Time{})
+	send := func(write func(io.Writer) error) bool {
+		_ = rc.SetWriteDeadline(time.Now().Add(h.cfg.StreamHeartbeat + streamWriteGrace))
+		return write(w) == nil && rc.Flush() == nil
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	retry := fmt.Sprintf("retry: %d\n\n", h.cfg.StreamPollInterval.Milliseconds())
+	if !send(func(w io.Writer) error { _, err := io.WriteString(w, retry); return err }) {
+		return
+	}
+
+	poll := time.NewTicker(h.cfg.StreamPollInterval)
+	defer poll.Stop()
+	heartbeat := time.NewTicker(h.cfg.StreamHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		for {
+			messages, err := h.inbox.Since(r.Context(), user.ID, after)
+			if err != nil {
+				if r.Context().Err() == nil {
+					log.Printf("message stream for user %d: %v", user.ID, err)
+				}
+				return
+			}
+			for _, m := range messages {
+				if !send(func(w io.Writer) error { return writeMessage(w, m) }) {
+					return
+				}
+				after = m.ID
+			}
+			if len(messages) > 0 {
+				heartbeat.Reset(h.cfg.StreamHeartbeat)
+			}
+			if len(messages) < inbox.BatchSize {
+				break
+			}
+		}
+		select {
+		case <-r.Context().Done():
+			return
+		case <-h.inbox.Done():
+			return
+		case <-wake:
+		case <-poll.C:
+		case <-heartbeat.C:
+			if !send(func(w io.Writer) error { _, err := io.WriteString(w, ": keepalive\n\n"); return err }) {
+				return
+			}
+		}
+	}
+}
+
+func writeMessage(w io.Writer, m models.Message) error {
+	data, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: message\ndata: %s\n\n", m.ID, data)
+	return err
+}
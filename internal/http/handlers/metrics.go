@@ -0,0 +1,213 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hongminglow/all-in-be/internal/http/respond"
+	"github.com/hongminglow/all-in-be/internal/jobs"
+	"github.com/hongminglow/all-in-be/internal/middleware"
+	"github.com/hongminglow/all-in-be/internal/models"
+	"github.com/hongminglow/all-in-be/internal/storage"
+)
+
+// MetricsHandler exposes operational metrics and, to holders of stats:read,
+// business KPIs in the Prometheus text format.
+type MetricsHandler struct {
+	db      storage.HealthChecker
+	queue   JobQueue
+	reports storage.ReconciliationStore
+	kpis    storage.StatsStore
+	kpiTTL  time.Duration
+
+	mu       sync.Mutex
+	cached   models.BusinessKPIs
+	cachedAt time.Time
+}
+
+// NewMetricsHandler constructs the handler. queue and reports may be nil.
+// The KPIs scan the whole ledger, so they are reused for kpiTTL rather than
+// recomputed on every scrape.
+func NewMetricsHandler(db storage.HealthChecker, queue JobQueue, reports storage.ReconciliationStore, kpis storage.StatsStore, kpiTTL time.Duration) *MetricsHandler {
+	return &MetricsHandler{db: db, queue: queue, reports: reports, kpis: kpis, kpiTTL: kpiTTL}
+}
+
+// Register attaches the /metrics route.
+func (h *MetricsHandler) Register(mux Router) {
+	mux.HandleFunc("GET /metrics", h.handle)
+}
+
+// RegisterBusiness attaches the business KPI route. It must be mounted
+// behind middleware.Authenticate; scrapers use an API key scoped to
+// stats:read.
+func (h *MetricsHandler) RegisterBusiness(mux Router) {
+	mux.Handle("GET /admin/metrics", middleware.RequirePermission(models.PermStatsRead, http.HandlerFunc(h.handleBusiness)))
+}
+
+// poolMetrics describes each exported pool series.
+var poolMetrics = []struct {
+	name  string
+	kind  string
+	help  string
+	value func(storage.PoolStats) float64
+}{
+	{"db_pool_max_conns", "gauge", "Maximum connections the pool may open.", func(s storage.PoolStats) float64 { return float64(s.MaxConns) }},
+	{"db_pool_total_conns", "gauge", "Connections currently open.", func(s storage.PoolStats) float64 { return float64(s.TotalConns) }},
+	{"db_pool_idle_conns", "gauge", "Open connections not in use.", func(s storage.PoolStats) float64 { return float64(s.IdleConns) }},
+	{"db_pool_acquired_conns", "gauge", "Connections currently checked out.", func(s storage.PoolStats) float64 { return float64(s.AcquiredConns) }},
+	{"db_pool_acquires_total", "counter", "Successful connection acquisitions.", func(s storage.PoolStats) float64 { return float64(s.AcquireCount) }},
+	{"db_pool_empty_acquires_total", "counter", "Acquisitions that had to wait because no idle connection was available.", func(s storage.PoolStats) float64 { return float64(s.EmptyAcquireCount) }},
+	{"db_pool_canceled_acquires_total", "counter", "Acquisitions canceled by their context.", func(s storage.PoolStats) float64 { return float64(s.CanceledAcquireCount) }},
+	{"db_pool_acquire_seconds_total", "counter", "Total time spent acquiring connections.", func(s storage.PoolStats) float64 { return s.AcquireDuration.Seconds() }},
+}
+
+// jobMetrics describes each exported job queue series.
+var jobMetrics = []struct {
+	name  string
+	kind  string
+	help  string
+	value func(jobs.TypeStats) float64
+}{
+	{"jobs_queue_depth", "gauge", "Jobs waiting to run, including those held by a pause.", func(s jobs.TypeStats) float64 { return float64(s.Depth) }},
+	{"jobs_oldest_age_seconds", "gauge", "How long the longest-waiting job has been queued.", func(s jobs.TypeStats) float64 { return s.OldestAgeSeconds }},
+	{"jobs_running", "gauge", "Jobs currently running, including retry backoff.", func(s jobs.TypeStats) float64 { return float64(s.Running) }},
+	{"jobs_succeeded_total", "counter", "Jobs that finished successfully.", func(s jobs.TypeStats) float64 { return float64(s.Succeeded) }},
+	{"jobs_failed_total", "counter", "Jobs abandoned after exhausting their attempts.", func(s jobs.TypeStats) float64 { return float64(s.Failed) }},
+	{"jobs_retries_total", "counter", "Failed attempts that were retried.", func(s jobs.TypeStats) float64 { return float64(s.Retries) }},
+	{"jobs_paused", "gauge", "1 while the job type is paused.", func(s jobs.TypeStats) float64 {
+		if s.Paused {
+			return 1
+		}
+		return 0
+	}},
+}
+
+// laneMetrics describes each exported priority lane series.
+var laneMetrics = []struct {
+	name  string
+	kind  string
+	help  string
+	value func(jobs.LaneStats) float64
+}{
+	{"jobs_lane_depth", "gauge", "Jobs waiting in the priority lane.", func(s jobs.LaneStats) float64 { return float64(s.Depth) }},
+	{"jobs_lane_oldest_age_seconds", "gauge", "How long the lane's longest-waiting job has been queued.", func(s jobs.LaneStats) float64 { return s.OldestAgeSeconds }},
+	{"jobs_lane_reserved_workers", "gauge", "Workers that only run jobs from the lane.", func(s jobs.LaneStats) float64 { return float64(s.ReservedWorkers) }},
+}
+
+// reconciliationMetrics describes each exported series of the latest
+// reconciliation report.
+var reconciliationMetrics = []struct {
+	name  string
+	help  string
+	value func(models.ReconciliationReport) float64
+}{
+	{"balance_reconciliation_mismatches", "Users whose balance disagreed with their ledger in the latest reconciliation.", func(r models.ReconciliationReport) float64 { return float64(r.Mismatches) }},
+	{"balance_reconciliation_users_checked", "Users checked by the latest reconciliation.", func(r models.ReconciliationReport) float64 { return float64(r.UsersChecked) }},
+	{"balance_reconciliation_last_run_timestamp_seconds", "When the latest reconciliation ran.", func(r models.ReconciliationReport) float64 { return float64(r.CreatedAt.Unix()) }},
+}
+
+// kpiMetrics describes each exported business KPI series. Gross gaming
+// revenue falls when players win, so it is a gauge rather than a counter.
+var kpiMetrics = []struct {
+	name  string
+	kind  string
+	help  string
+	value func(models.BusinessKPIs) float64
+}{
+	{"business_registrations_total", "counter", "Accounts registered.", func(k models.BusinessKPIs) float64 { return float64(k.Registrations) }},
+	{"business_deposits_total", "counter", "Deposits credited.", func(k models.BusinessKPIs) float64 { return float64(k.Deposits) }},
+	{"business_deposits_amount_total", "counter", "Value of deposits credited.", func(k models.BusinessKPIs) float64 { return k.DepositsAmount }},
+	{"business_bets_total", "counter", "Bets settled.", func(k models.BusinessKPIs) float64 { return float64(k.Bets) }},
+	{"business_ggr_amount", "gauge", "Gross gaming revenue: stakes lost by players minus winnings paid on settled bets.", func(k models.BusinessKPIs) float64 { return k.GGR }},
+}
+
+func (h *MetricsHandler) handle(w http.ResponseWriter, r *http.Request) {
+	pools := h.db.PoolStats()
+	var b strings.Builder
+	for _, m := range poolMetrics {
+		if len(pools) == 0 {
+			break
+		}
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s %s\n", m.name, m.help, m.name, m.kind)
+		for _, pool := range pools {
+			fmt.Fprintf(&b, "%s{pool=%q} %g\n", m.name, pool.Name, m.value(pool))
+		}
+	}
+	if h.queue != nil {
+		queues := h.queue.Stats()
+		for _, m := range jobMetrics {
+			if len(queues) == 0 {
+				break
+			}
+			fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s %s\n", m.name, m.help, m.name, m.kind)
+			for _, q := range queues {
+				fmt.Fprintf(&b, "%s{type=%q} %g\n", m.name, q.Type, m.value(q))
+			}
+		}
+		lanes := h.queue.Lanes()
+		for _, m := range laneMetrics {
+			fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s %s\n", m.name, m.help, m.name, m.kind)
+			for _, l := range lanes {
+				fmt.Fprintf(&b, "%s{priority=%q} %g\n", m.name, l.Priority, m.value(l))
+			}
+		}
+	}
+	if h.reports != nil {
+		reports, err := h.reports.ListReconciliationReports(r.Context(), 1)
+		if err != nil {
+			log.Printf("metrics: latest reconciliation report: %v", err)
+		}
+		for _, m := range reconciliationMetrics {
+			if len(reports) == 0 {
+				break
+			}
+			fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s gauge\n%s %g\n", m.name, m.help, m.name, m.name, m.value(reports[0]))
+		}
+	}
+	writeMetrics(w, b.String())
+}
+
+// handleBusiness exports the business KPIs.
+func (h *MetricsHandler) handleBusiness(w http.ResponseWriter, r *http.Request) {
+	kpis, err := h.businessKPIs(r.Context())
+	if err != nil {
+		log.Printf("metrics: business kpis: %v", err)
+		respond.Error(w, http.StatusInternalServerError, "failed to load business metrics")
+		return
+	}
+	var b strings.Builder
+	for _, m := range kpiMetrics {
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s %s\n%s %g\n", m.name, m.help, m.name, m.kind, m.name, m.value(kpis))
+	}
+	writeMetrics(w, b.String())
+}
+
+func writeMetrics(w http.ResponseWriter, body string) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	_, _ = w.Write([]byte(body))
+}
+
+// businessKPIs returns the cached KPIs, recomputing them once kpiTTL has
+// passed. It only fails when no KPIs were computed before.
+func (h *MetricsHandler) businessKPIs(ctx context.Context) (models.BusinessKPIs, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if !h.cachedAt.IsZero() && time.Since(h.cachedAt) < h.kpiTTL {
+		return h.cached, nil
+	}
+	kpis, err := h.kpis.BusinessKPIs(ctx)
+	if err != nil {
+		if !h.cachedAt.IsZero() {
+			log.Printf("metrics: business kpis: %v; serving the ones from %s", err, h.cachedAt.Format(time.RFC3339))
+			return h.cached, nil
+		}
+		return models.BusinessKPIs{}, err
+	}
+	h.cached, h.cachedAt = kpis, time.Now()
+	return kpis, nil
+}
@@ -0,0 +1,161 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hongminglow/all-in-be/internal/jobs"
+	"github.com/hongminglow/all-in-be/internal/models"
+	"github.com/hongminglow/all-in-be/internal/storage"
+)
+
+type fakeHealth struct {
+	err     error
+	pools   []storage.PoolStats
+	breaker storage.BreakerState
+}
+
+func (f fakeHealth) Ping(context.Context) error     { return f.err }
+func (f fakeHealth) PoolStats() []storage.PoolStats { return f.pools }
+func (f fakeHealth) Breaker() storage.BreakerState  { return f.breaker }
+
+func TestMetricsExposesPoolStats(t *testing.T) {
+	db := fakeHealth{pools: []storage.PoolStats{
+		{Name: "primary", MaxConns: 10, AcquiredConns: 3, AcquireCount: 42},
+		{Name: "replica", MaxConns: 5},
+	}}
+	rec := httptest.NewRecorder()
+	NewMetricsHandler(db, nil, nil, nil, 0).handle(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	body := rec.Body.String()
+	for _, want := range []string{
+		"# TYPE db_pool_acquired_conns gauge",
+		`db_pool_acquired_conns{pool="primary"} 3`,
+		`db_pool_max_conns{pool="replica"} 5`,
+		`db_pool_acquires_total{pool="primary"} 42`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("metrics missing %q:\n%s", want, body)
+		}
+	}
+}
+
+type fakeQueue struct {
+	stats []jobs.TypeStats
+	lanes []jobs.LaneStats
+}
+
+func (f fakeQueue) Stats() []jobs.TypeStats { return f.stats }
+func (f fakeQueue) Lanes() []jobs.LaneStats { return f.lanes }
+func (fakeQueue) Pause(string)              {}
+func (fakeQueue) Resume(string)             {}
+
+func TestMetricsExposesQueueStats(t *testing.T) {
+	queue := fakeQueue{stats: []jobs.TypeStats{
+		{Type: "webhook", Depth: 7, OldestAgeSeconds: 12.5, Failed: 2, Paused: true},
+	}, lanes: []jobs.LaneStats{
+		{Priority: jobs.PriorityHigh, Depth: 1, ReservedWorkers: 1},
+		{Priority: jobs.PriorityLow, Depth: 40, OldestAgeSeconds: 3},
+	}}
+	rec := httptest.NewRecorder()
+	NewMetricsHandler(fakeHealth{}, queue, nil, nil, 0).handle(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	body := rec.Body.String()
+	for _, want := range []string{
+		"# TYPE jobs_queue_depth gauge",
+		`jobs_queue_depth{type="webhook"} 7`,
+		`jobs_oldest_age_seconds{type="webhook"} 12.5`,
+		`jobs_failed_total{type="webhook"} 2`,
+		`jobs_paused{type="webhook"} 1`,
+		`jobs_lane_depth{priority="low"} 40`,
+		`jobs_lane_reserved_workers{priority="high"} 1`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("metrics missing %q:\n%s", want, body)
+		}
+	}
+}
+
+type fakeKPIs struct {
+	calls *int
+	kpis  models.BusinessKPIs
+	err   error
+}
+
+func (fakeKPIs) AdminStats(context.Context, int) (models.AdminStats, error) {
+	return models.AdminStats{}, nil
+}
+
+func (f fakeKPIs) BusinessKPIs(context.Context) (models.BusinessKPIs, error) {
+	*f.calls++
+	return f.kpis, f.err
+}
+
+func TestBusinessMetricsAreKeptOffTheOperationalOnes(t *testing.T) {
+	calls := 0
+	kpis := fakeKPIs{calls: &calls, kpis: models.BusinessKPIs{Registrations: 12, Deposits: 4, DepositsAmount: 250.5, Bets: 30, GGR: -42.25}}
+	h := NewMetricsHandler(fakeHealth{}, nil, nil, kpis, time.Minute)
+	for range 2 {
+		rec := httptest.NewRecorder()
+		h.handleBusiness(rec, httptest.NewRequest(http.MethodGet, "/admin/metrics", nil))
+		body := rec.Body.String()
+		for _, want := range []string{
+			"# TYPE business_registrations_total counter",
+			"business_registrations_total 12\n",
+			"business_deposits_amount_total 250.5\n",
+			"business_bets_total 30\n",
+			"# TYPE business_ggr_amount gauge",
+			"business_ggr_amount -42.25\n",
+		} {
+			if !strings.Contains(body, want) {
+				t.Errorf("business metrics missing %q:\n%s", want, body)
+			}
+		}
+	}
+	if calls != 1 {
+		t.Fatalf("BusinessKPIs called %d times, want 1 within the TTL", calls)
+	}
+
+	rec := httptest.NewRecorder()
+	h.handle(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if strings.Contains(rec.Body.String(), "business_") {
+		t.Fatalf("/metrics exposes business KPIs:\n%s", rec.Body.String())
+	}
+
+	failing := NewMetricsHandler(fakeHealth{}, nil, nil, fakeKPIs{calls: &calls, err: errors.New("replica down")}, time.Minute)
+	rec = httptest.NewRecorder()
+	failing.handleBusiness(rec, httptest.NewRequest(http.MethodGet, "/admin/metrics", nil))
+	if rec.Code != http.StatusInternalServerError {
+		t.Fatalf("business metrics without any KPIs: status %d, want 500", rec.Code)
+	}
+}
+
+func TestReadinessFailsWhenDatabaseIsDown(t *testing.T) {
+	rec := httptest.NewRecorder()
+	NewReadinessHandler(fakeHealth{err: errors.New("dial tcp: refused")}).handle(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("status = %d, want 503", rec.Code)
+	}
+}
+
+func TestReadinessFailsWhileBreakerIsOpen(t *testing.T) {
+	for state, want := range map[string]int{
+		storage.BreakerClosed:   http.StatusOK,
+		storage.BreakerHalfOpen: http.StatusOK,
+		storage.BreakerOpen:     http.StatusServiceUnavailable,
+	} {
+		rec := httptest.NewRecorder()
+		NewReadinessHandler(fakeHealth{breaker: storage.BreakerState{State: state}}).handle(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+		if rec.Code != want {
+			t.Errorf("breaker %s: status = %d, want %d", state, rec.Code, want)
+		}
+		if !strings.Contains(rec.Body.String(), `"state":"`+state+`"`) {
+			t.Errorf("breaker %s: body does not report it: %s", state, rec.Body.String())
+		}
+	}
+}
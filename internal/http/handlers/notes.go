@@ -0,0 +1,172 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/hongminglow/all-in-be/internal/http/respond"
+	"github.com/hongminglow/all-in-be/internal/middleware"
+	"github.com/hongminglow/all-in-be/internal/models"
+	"github.com/hongminglow/all-in-be/internal/models/dto"
+	"github.com/hongminglow/all-in-be/internal/storage"
+)
+
+// NotesHandler exposes internal staff notes on user accounts.
+type NotesHandler struct {
+	store storage.Repositories
+}
+
+// NewNotesHandler constructs the handler.
+func NewNotesHandler(store storage.Repositories) *NotesHandler {
+	return &NotesHandler{store: store}
+}
+
+// Register attaches the admin note routes. They must be mounted behind middleware.Authenticate.
+func (h *NotesHandler) Register(mux Router) {
+	mux.Handle("GET /admin/users/{id}/notes", middleware.RequirePermission(models.PermNotesRead, http.HandlerFunc(h.listNotes)))
+	mux.Handle("POST /admin/users/{id}/notes", middleware.RequirePermission(models.PermNotesWrite, http.HandlerFunc(h.createNote)))
+	mux.Handle("PATCH /admin/users/{id}/notes/{noteID}", middleware.RequirePermission(models.PermNotesWrite, http.HandlerFunc(h.updateNote)))
+	mux.Handle("GET /admin/users/{id}/notes/{noteID}/history", middleware.RequirePermission(models.PermNotesRead, http.HandlerFunc(h.listHistory)))
+}
+
+func (h *NotesHandler) listNotes(w http.ResponseWriter, r *http.Request) {
+	userID, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+	notes, err := h.store.ListNotes(r.Context(), userID)
+	if err != nil {
+		log.Printf("list notes error: %v", err)
+		respond.Error(w, http.StatusInternalServerError, "failed to list notes")
+		return
+	}
+	respond.JSON(w, http.StatusOK, "notes fetched", notes)
+}
+
+func (h *NotesHandler) createNote(w http.ResponseWriter, r *http.Request) {
+	userID, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+	var req dto.CreateNoteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respond.Error(w, http.StatusBadRequest, "invalid JSON payload")
+		return
+	}
+	body := strings.TrimSpace(req.Body)
+	if body == "" {
+		respond.Error(w, http.StatusBadRequest, "body is required")
+		return
+	}
+	if _, err := h.store.FindByID(r.Context(), userID); err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			respond.Error(w, http.StatusNotFound, "user not found")
+			return
+		}
+		log.Printf("create note: load user %d: %v", userID, err)
+		respond.Error(w, http.StatusInternalServerError, "failed to load user")
+		return
+	}
+
+	author, _ := middleware.UserFromContext(r.Context())
+	created, err := h.store.CreateNote(r.Context(), models.UserNote{
+		UserID:   userID,
+		AuthorID: author.ID,
+		Body:     body,
+		Pinned:   req.Pinned,
+	})
+	if err != nil {
+		log.Printf("create note error: %v", err)
+		respond.Error(w, http.StatusInternalServerError, "failed to create note")
+		return
+	}
+	respond.Created(w, fmt.Sprintf("/admin/users/%d/notes/%d", userID, created.ID), "note created", created)
+}
+
+func (h *NotesHandler) updateNote(w http.ResponseWriter, r *http.Request) {
+	userID, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+	noteID, ok := pathID(w, r, "noteID")
+	if !ok {
+		return
+	}
+	var req dto.UpdateNoteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respond.Error(w, http.StatusBadRequest, "invalid JSON payload")
+		return
+	}
+	note, err := h.store.FindNote(r.Context(), userID, noteID)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			respond.Error(w, http.StatusNotFound, "note not found")
+			return
+		}
+		log.Printf("update note: load note %d: %v", noteID, err)
+		respond.Error(w, http.StatusInternalServerError, "failed to load note")
+		return
+	}
+	if req.Body != nil {
+		body := strings.TrimSpace(*req.Body)
+		if body == "" {
+			respond.Error(w, http.StatusBadRequest, "body cannot be empty")
+			return
+		}
+		note.Body = body
+	}
+	if req.Pinned != nil {
+		note.Pinned = *req.Pinned
+	}
+
+	editor, _ := middleware.UserFromContext(r.Context())
+	updated, err := h.store.UpdateNote(r.Context(), note, editor.ID)
+	if err != nil {
+		log.Printf("update note error: %v", err)
+		respond.Error(w, http.StatusInternalServerError, "failed to update note")
+		return
+	}
+	respond.JSON(w, http.StatusOK, "note updated", updated)
+}
+
+func (h *NotesHandler) listHistory(w http.ResponseWriter, r *http.Request) {
+	userID, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+	noteID, ok := pathID(w, r, "noteID")
+	if !ok {
+		return
+	}
+	if _, err := h.store.FindNote(r.Context(), userID, noteID); err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			respond.Error(w, http.StatusNotFound, "note not found")
+			return
+		}
+		log.Printf("note history: load note %d: %v", noteID, err)
+		respond.Error(w, http.StatusInternalServerError, "failed to load note")
+		return
+	}
+	revisions, err := h.store.ListNoteRevisions(r.Context(), noteID)
+	if err != nil {
+		log.Printf("list note revisions error: %v", err)
+		respond.Error(w, http.StatusInternalServerError, "failed to list note history")
+		return
+	}
+	respond.JSON(w, http.StatusOK, "note history fetched", revisions)
+}
+
+// pathID parses a positive integer path parameter, writing a 400 when it is malformed.
+func pathID(w http.ResponseWriter, r *http.Request, name string) (int64, bool) {
+	id, err := strconv.ParseInt(r.PathValue(name), 10, 64)
+	if err != nil || id <= 0 {
+		respond.Error(w, http.StatusBadRequest, "invalid "+name)
+		return 0, false
+	}
+	return id, true
+}
@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/hongminglow/all-in-be/internal/http/respond"
+	"github.com/hongminglow/all-in-be/internal/middleware"
+	"github.com/hongminglow/all-in-be/internal/models"
+	"github.com/hongminglow/all-in-be/internal/models/dto"
+	"github.com/hongminglow/all-in-be/internal/oidc"
+	"github.com/hongminglow/all-in-be/internal/storage"
+)
+
+// OAuthClientHandler lets staff register the companion apps that sign users
+// in through the OpenID Connect provider.
+type OAuthClientHandler struct {
+	store storage.OAuthStore
+}
+
+// NewOAuthClientHandler constructs the handler.
+func NewOAuthClientHandler(store storage.OAuthStore) *OAuthClientHandler {
+	return &OAuthClientHandler{store: store}
+}
+
+// Register attaches the admin routes. They must be mounted behind middleware.Authenticate.
+func (h *OAuthClientHandler) Register(mux Router) {
+	mux.Handle("GET /admin/oauth-clients", middleware.RequirePermission(models.PermIntegrations, http.HandlerFunc(h.handleList)))
+	mux.Handle("POST /admin/oauth-clients", middleware.RequirePermission(models.PermIntegrations, http.HandlerFunc(h.create)))
+	mux.Handle("DELETE /admin/oauth-clients/{id}", middleware.RequirePermission(models.PermIntegrations, http.HandlerFunc(h.handleClient)))
+}
+
+func (h *OAuthClientHandler) handleList(w http.ResponseWriter, r *http.Request) {
+	clients, err := h.store.ListOAuthClients(r.Context())
+	if err != nil {
+		log.Printf("list oauth clients error: %v", err)
+		respond.Error(w, http.StatusInternalServerError, "failed to list oauth clients")
+		return
+	}
+	respond.JSON(w, http.StatusOK, "oauth clients fetched", clients)
+}
+
+func (h *OAuthClientHandler) create(w http.ResponseWriter, r *http.Request) {
+	var req dto.CreateOAuthClientRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respond.Error(w, http.StatusBadRequest, "invalid JSON payload")
+		return
+	}
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		respond.Error(w, http.StatusBadRequest, "name is required")
+		return
+	}
+	if len(req.RedirectURIs) == 0 {
+		respond.Error(w, http.StatusBadRequest, "redirect_uris must list at least one URI")
+		return
+	}
+	for _, uri := range req.RedirectURIs {
+		if !validRedirectURI(uri) {
+			respond.Error(w, http.StatusBadRequest, "redirect URI "+uri+" must be absolute, without a fragment, and https unless it is a loopback or app URI")
+			return
+		}
+	}
+	client := models.OAuthClient{
+		ID:           newClientID(),
+		Name:         name,
+		RedirectURIs: req.RedirectURIs,
+		Confidential: req.Confidential,
+	}
+	var secret string
+	if client.Confidential {
+		var err error
+		if secret, client.SecretHash, err = oidc.NewClientSecret(); err != nil {
+			log.Printf("create oauth client error: %v", err)
+			respond.Error(w, http.StatusInternalServerError, "failed to create oauth client")
+			return
+		}
+	}
+	created, err := h.store.CreateOAuthClient(r.Context(), client)
+	if err != nil {
+		log.Printf("create oauth client error: %v", err)
+		respond.Error(w, http.StatusInternalServerError, "failed to create oauth client")
+		return
+	}
+	location := "/admin/oauth-clients/" + url.PathEscape(created.ID)
+	if secret == "" {
+		respond.Created(w, location, "oauth client created", created)
+		return
+	}
+	created.Secret = secret
+	respond.Created(w, location, "oauth client created; store the secret, it will not be shown again", created)
+}
+
+func (h *OAuthClientHandler) handleClient(w http.ResponseWriter, r *http.Request) {
+	if err := h.store.DeleteOAuthClient(r.Context(), r.PathValue("id")); err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			respond.Error(w, http.StatusNotFound, "oauth client not found")
+			return
+		}
+		log.Printf("delete oauth client error: %v", err)
+		respond.Error(w, http.StatusInternalServerError, "failed to delete oauth client")
+		return
+	}
+	respond.JSON(w, http.StatusOK, "oauth client deleted", nil)
+}
+
+// validRedirectURI accepts https URIs, http on a loopback host for desktop
+// apps, and private-use schemes such as com.example.app:/callback for mobile
+// apps (RFC 8252). Redirect URIs are matched exactly, so fragments never fit.
+func validRedirectURI(raw string) bool {
+	u, err := url.Parse(raw)
+	if err != nil || u.Scheme == "" || strings.Contains(raw, "#") {
+		return false
+	}
+	switch u.Scheme {
+	case "https":
+		return u.Host != ""
+	case "http":
+		host := u.Hostname()
+		return host == "localhost" || host == "127.0.0.1" || host == "::1"
+	default:
+		return strings.Contains(u.Scheme, ".")
+	}
+}
+
+func newClientID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,154 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/hongminglow/all-in-be/internal/http/respond"
+	"github.com/hongminglow/all-in-be/internal/middleware"
+	"github.com/hongminglow/all-in-be/internal/oidc"
+)
+
+// OIDCHandler serves the OpenID Connect provider endpoints. Their responses
+// follow the OAuth and OIDC specs rather than the API's envelope, since they
+// are read by off-the-shelf client libraries.
+type OIDCHandler struct {
+	provider *oidc.Provider
+}
+
+// NewOIDCHandler constructs the handler.
+func NewOIDCHandler(provider *oidc.Provider) *OIDCHandler {
+	return &OIDCHandler{provider: provider}
+}
+
+// Register attaches the discovery, key set and userinfo routes.
+func (h *OIDCHandler) Register(mux Router) {
+	mux.HandleFunc("GET /.well-known/openid-configuration", h.handleDiscovery)
+	mux.HandleFunc("GET /.well-known/jwks.json", h.handleJWKS)
+	mux.HandleFunc("GET /userinfo", h.handleUserInfo)
+	mux.HandleFunc("POST /userinfo", h.handleUserInfo)
+}
+
+// RegisterSignIn attaches /authorize and /token. They must be mounted behind
+// middleware.Identify so /authorize sees the signed-in user.
+func (h *OIDCHandler) RegisterSignIn(mux Router) {
+	mux.HandleFunc("GET /authorize", h.handleAuthorize)
+	mux.HandleFunc("POST /token", h.handleToken)
+}
+
+func (h *OIDCHandler) handleDiscovery(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "public, max-age=3600")
+	writeOAuthJSON(w, http.StatusOK, h.provider.Discovery())
+}
+
+func (h *OIDCHandler) handleJWKS(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "public, max-age=3600")
+	writeOAuthJSON(w, http.StatusOK, h.provider.JWKS())
+}
+
+// handleAuthorize sends a signed-in user back to the client with a code and
+// anyone else to the login page, which returns them here afterwards.
+func (h *OIDCHandler) handleAuthorize(w http.ResponseWriter, r *http.Request) {
+	req := oidc.ParseAuthorizeRequest(r.URL.Query())
+	if !h.redirectError(w, r, req, h.provider.Validate(r.Context(), req)) {
+		return
+	}
+	user, ok := middleware.UserFromContext(r.Context())
+	// A scoped token is an automation credential, not a signed-in person.
+	if _, scoped := middleware.ScopesFromContext(r.Context()); !ok || scoped {
+		location, err := h.provider.LoginRedirect(req, r.URL.RequestURI())
+		if !h.redirectError(w, r, req, err) {
+			return
+		}
+		http.Redirect(w, r, location, http.StatusFound)
+		return
+	}
+	location, err := h.provider.Authorize(r.Context(), user, req)
+	if !h.redirectError(w, r, req, err) {
+		return
+	}
+	http.Redirect(w, r, location, http.StatusFound)
+}
+
+// redirectError reports whether err is nil, otherwise answering the request:
+// errors about the request go back to the client's redirect URI, unless the
+// client or redirect URI itself is what is wrong.
+func (h *OIDCHandler) redirectError(w http.ResponseWriter, r *http.Request, req oidc.AuthorizeRequest, err error) bool {
+	var oauthErr *oidc.Error
+	switch {
+	case err == nil:
+		return true
+	case errors.As(err, &oauthErr):
+		http.Redirect(w, r, oidc.ErrorRedirect(req, oauthErr), http.StatusFound)
+	case errors.Is(err, oidc.ErrUnknownClient), errors.Is(err, oidc.ErrRedirectURI):
+		respond.Error(w, http.StatusBadRequest, err.Error())
+	default:
+		log.Printf("authorize client %q: %v", req.ClientID, err)
+		respond.Error(w, http.StatusInternalServerError, "failed to authorize")
+	}
+	return false
+}
+
+func (h *OIDCHandler) handleToken(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "no-store")
+	if err := r.ParseForm(); err != nil {
+		writeOAuthJSON(w, http.StatusBadRequest, &oidc.Error{Code: "invalid_request", Description: "the body must be form encoded"})
+		return
+	}
+	req := oidc.TokenRequest{
+		GrantType:    r.PostForm.Get("grant_type"),
+		Code:         r.PostForm.Get("code"),
+		RedirectURI:  r.PostForm.Get("redirect_uri"),
+		ClientID:     r.PostForm.Get("client_id"),
+		ClientSecret: r.PostForm.Get("client_secret"),
+		CodeVerifier: r.PostForm.Get("code_verifier"),
+	}
+	if id, secret, ok := r.BasicAuth(); ok {
+		req.ClientID, req.ClientSecret = id, secret
+	}
+	tokens, err := h.provider.Exchange(r.Context(), req)
+	if err != nil {
+		h.oauthError(w, err)
+		return
+	}
+	writeOAuthJSON(w, http.StatusOK, tokens)
+}
+
+func (h *OIDCHandler) handleUserInfo(w http.ResponseWriter, r *http.Request) {
+	scheme, token, _ := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !strings.EqualFold(scheme, "Bearer") || strings.TrimSpace(token) == "" {
+		w.Header().Set("WWW-Authenticate", `Bearer`)
+		writeOAuthJSON(w, http.StatusUnauthorized, &oidc.Error{Code: "invalid_request", Description: "a bearer access token is required"})
+		return
+	}
+	claims, err := h.provider.UserInfo(r.Context(), strings.TrimSpace(token))
+	if err != nil {
+		w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+		h.oauthError(w, err)
+		return
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	writeOAuthJSON(w, http.StatusOK, claims)
+}
+
+func (h *OIDCHandler) oauthError(w http.ResponseWriter, err error) {
+	var oauthErr *oidc.Error
+	if errors.As(err, &oauthErr) {
+		writeOAuthJSON(w, oauthErr.Status, oauthErr)
+		return
+	}
+	log.Printf("oidc: %v", err)
+	writeOAuthJSON(w, http.StatusInternalServerError, &oidc.Error{Code: "server_error"})
+}
+
+// writeOAuthJSON writes v as a bare JSON body, without the API envelope.
+func writeOAuthJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("oidc: encode response: %v", err)
+	}
+}
@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/hongminglow/all-in-be/internal/confighistory"
+	"github.com/hongminglow/all-in-be/internal/http/respond"
+	"github.com/hongminglow/all-in-be/internal/middleware"
+	"github.com/hongminglow/all-in-be/internal/models"
+	"github.com/hongminglow/all-in-be/internal/models/dto"
+	"github.com/hongminglow/all-in-be/internal/onboarding"
+	"github.com/hongminglow/all-in-be/internal/storage"
+)
+
+// OnboardingHandler shows users their welcome journey and lets administrators
+// configure each tenant's journey. Journey changes are recorded in the
+// configuration history.
+type OnboardingHandler struct {
+	store    storage.Store
+	journeys *onboarding.Service
+}
+
+// NewOnboardingHandler constructs the handler.
+func NewOnboardingHandler(store storage.Store, journeys *onboarding.Service) *OnboardingHandler {
+	return &OnboardingHandler{store: store, journeys: journeys}
+}
+
+// Register attaches the routes. They must be mounted behind middleware.Authenticate.
+func (h *OnboardingHandler) Register(mux Router) {
+	mux.HandleFunc("GET /me/onboarding", h.handleMine)
+	mux.Handle("GET /admin/onboarding/journeys", middleware.RequirePermission(models.PermConfigManage, http.HandlerFunc(h.list)))
+	mux.Handle("PUT /admin/onboarding/journeys", middleware.RequirePermission(models.PermConfigManage, http.HandlerFunc(h.save)))
+	mux.Handle("DELETE /admin/onboarding/journeys", middleware.RequirePermission(models.PermConfigManage, http.HandlerFunc(h.delete)))
+}
+
+func (h *OnboardingHandler) handleMine(w http.ResponseWriter, r *http.Request) {
+	user, ok := middleware.UserFromContext(r.Context())
+	if !ok {
+		respond.Error(w, http.StatusUnauthorized, "authentication required")
+		return
+	}
+	status, err := h.journeys.Status(r.Context(), user.ID)
+	if err != nil {
+		log.Printf("onboarding status for user %d: %v", user.ID, err)
+		respond.Error(w, http.StatusInternalServerError, "failed to fetch onboarding")
+		return
+	}
+	respond.JSON(w, http.StatusOK, "onboarding fetched", status)
+}
+
+func (h *OnboardingHandler) list(w http.ResponseWriter, r *http.Request) {
+	journeys, err := h.store.ListOnboardingJourneys(r.Context())
+	if err != nil {
+		log.Printf("list onboarding journeys: %v", err)
+		respond.Error(w, http.StatusInternalServerError, "failed to list onboarding journeys")
+		return
+	}
+	respond.JSON(w, http.StatusOK, "onboarding journeys fetched", journeys)
+}
+
+func (h *OnboardingHandler) save(w http.ResponseWriter, r *http.Request) {
+	var req dto.SaveOnboardingJourneyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respond.Error(w, http.StatusBadRequest, "invalid JSON payload")
+		return
+	}
+	journey := models.OnboardingJourney{Tenant: strings.TrimSpace(req.Tenant), Steps: req.Steps}
+	for i := range journey.Steps {
+		journey.Steps[i].Key = strings.TrimSpace(journey.Steps[i].Key)
+		journey.Steps[i].Title = strings.TrimSpace(journey.Steps[i].Title)
+	}
+	if err := onboarding.Validate(journey); err != nil {
+		respond.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	actor, _ := middleware.UserFromContext(r.Context())
+	var saved models.OnboardingJourney
+	err := h.store.WithTx(r.Context(), func(tx storage.Repositories) error {
+		var err error
+		saved, err = confighistory.SaveOnboardingJourney(r.Context(), tx, actor.ID, journey)
+		return err
+	})
+	if err != nil {
+		log.Printf("save onboarding journey: %v", err)
+		respond.Error(w, http.StatusInternalServerError, "failed to save onboarding journey")
+		return
+	}
+	respond.JSON(w, http.StatusOK, "onboarding journey saved", saved)
+}
+
+func (h *OnboardingHandler) delete(w http.ResponseWriter, r *http.Request) {
+	tenant := strings.TrimSpace(r.URL.Query().Get("tenant"))
+	actor, _ := middleware.UserFromContext(r.Context())
+	err := h.store.WithTx(r.Context(), func(tx storage.Repositories) error {
+		return confighistory.DeleteOnboardingJourney(r.Context(), tx, actor.ID, tenant)
+	})
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			respond.Error(w, http.StatusNotFound, "onboarding journey not found")
+			return
+		}
+		log.Printf("delete onboarding journey: %v", err)
+		respond.Error(w, http.StatusInternalServerError, "failed to delete onboarding journey")
+		return
+	}
+	respond.JSON(w, http.StatusOK, "onboarding journey deleted", nil)
+}
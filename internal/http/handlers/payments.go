@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/hongminglow/all-in-be/internal/http/respond"
+	"github.com/hongminglow/all-in-be/internal/limits"
+	"github.com/hongminglow/all-in-be/internal/middleware"
+	"github.com/hongminglow/all-in-be/internal/models/dto"
+	"github.com/hongminglow/all-in-be/internal/payments"
+)
+
+// PaymentHandler starts deposits at payment providers. The wallet is credited
+// when the provider's callback reports the deposit settled.
+type PaymentHandler struct {
+	payments *payments.Service
+}
+
+// NewPaymentHandler constructs the handler.
+func NewPaymentHandler(service *payments.Service) *PaymentHandler {
+	return &PaymentHandler{payments: service}
+}
+
+// Register attaches the route. It must be mounted behind middleware.Authenticate
+// and middleware.BlockCountries.
+func (h *PaymentHandler) Register(mux Router) {
+	mux.Handle("POST /payments/{provider}/deposits", middleware.RefuseImpersonation(http.HandlerFunc(h.handleDeposit)))
+}
+
+func (h *PaymentHandler) handleDeposit(w http.ResponseWriter, r *http.Request) {
+	user, ok := middleware.UserFromContext(r.Context())
+	if !ok {
+		respond.Error(w, http.StatusUnauthorized, "authentication required")
+		return
+	}
+	var req dto.DepositRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respond.Error(w, http.StatusBadRequest, "invalid JSON payload")
+		return
+	}
+	provider := r.PathValue("provider")
+	intent, err := h.payments.Deposit(r.Context(), provider, user.ID, req.Amount)
+	switch {
+	case errors.Is(err, payments.ErrUnknownProvider):
+		respond.Error(w, http.StatusNotFound, "payment provider not found")
+	case errors.Is(err, payments.ErrInvalidAmount):
+		respond.Error(w, http.StatusBadRequest, "amount must be positive")
+	case errors.Is(err, limits.ErrExcluded):
+		respond.Error(w, http.StatusForbidden, "deposits are closed during a self-exclusion")
+	case errors.Is(err, limits.ErrDepositLimit):
+		respond.Error(w, http.StatusUnprocessableEntity, "the deposit would exceed your daily deposit limit")
+	case errors.Is(err, payments.ErrDeclined):
+		respond.Error(w, http.StatusUnprocessableEntity, "the payment provider declined the deposit")
+	case err != nil:
+		log.Printf("start %s deposit for user %d: %v", provider, user.ID, err)
+		respond.Error(w, http.StatusServiceUnavailable, "payment provider unavailable")
+	default:
+		respond.Created(w, "", "deposit started", intent)
+	}
+}
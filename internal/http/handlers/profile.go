@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/hongminglow/all-in-be/internal/accounts"
+	"github.com/hongminglow/all-in-be/internal/emailcheck"
+	"github.com/hongminglow/all-in-be/internal/http/respond"
+	"github.com/hongminglow/all-in-be/internal/middleware"
+	"github.com/hongminglow/all-in-be/internal/models"
+	"github.com/hongminglow/all-in-be/internal/models/dto"
+	"github.com/hongminglow/all-in-be/internal/phone"
+	"github.com/hongminglow/all-in-be/internal/storage"
+	"github.com/hongminglow/all-in-be/internal/usernames"
+)
+
+// ProfileHandler edits usernames, emails and phone numbers. Every edit names
+// the user's version it was based on, so two people editing the same account
+// cannot silently overwrite each other: the second edit gets a 409.
+type ProfileHandler struct {
+	accounts *accounts.Service
+}
+
+// NewProfileHandler constructs the handler.
+func NewProfileHandler(service *accounts.Service) *ProfileHandler {
+	return &ProfileHandler{accounts: service}
+}
+
+// Register attaches the routes. They must be mounted behind middleware.Authenticate.
+func (h *ProfileHandler) Register(mux Router) {
+	mux.Handle("PATCH /me", middleware.RefuseImpersonation(http.HandlerFunc(h.handleSelf)))
+	mux.Handle("PATCH /admin/users/{id}", middleware.RequirePermission(models.PermUsersWrite, http.HandlerFunc(h.handleUser)))
+}
+
+func (h *ProfileHandler) handleSelf(w http.ResponseWriter, r *http.Request) {
+	user, ok := middleware.UserFromContext(r.Context())
+	if !ok {
+		respond.Error(w, http.StatusUnauthorized, "authentication required")
+		return
+	}
+	h.update(w, r, user.ID)
+}
+
+func (h *ProfileHandler) handleUser(w http.ResponseWriter, r *http.Request) {
+	userID, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+	h.update(w, r, userID)
+}
+
+func (h *ProfileHandler) update(w http.ResponseWriter, r *http.Request, userID int64) {
+	var req dto.UpdateProfileRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respond.Error(w, http.StatusBadRequest, "invalid JSON payload")
+		return
+	}
+	version, ok := expectedVersion(w, r, req.Version)
+	if !ok {
+		return
+	}
+	if version == 0 {
+		respond.Error(w, http.StatusPreconditionRequired, "If-Match or version is required")
+		return
+	}
+	updated, err := h.accounts.UpdateProfile(r.Context(), userID, version, accounts.ProfileUpdate{
+		Username: req.Username,
+		Email:    req.Email,
+		Phone:    req.Phone,
+	})
+	var violation *usernames.Violation
+	switch {
+	case errors.As(err, &violation):
+		respond.JSON(w, http.StatusBadRequest, violation.Message, violation)
+	case errors.Is(err, accounts.ErrBlankProfileField), errors.Is(err, phone.ErrInvalid), errors.Is(err, phone.ErrRegionRequired),
+		errors.Is(err, emailcheck.ErrInvalid), errors.Is(err, emailcheck.ErrDisposable), errors.Is(err, emailcheck.ErrNoMailServer):
+		respond.Error(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, storage.ErrNotFound):
+		respond.Error(w, http.StatusNotFound, "user not found")
+	case errors.Is(err, storage.ErrVersionConflict):
+		respond.Error(w, http.StatusConflict, "user was changed by someone else; reload and try again")
+	case errors.Is(err, storage.ErrAlreadyExists):
+		respond.Error(w, http.StatusConflict, "user already exists")
+	case err != nil:
+		log.Printf("update profile of user %d: %v", userID, err)
+		respond.Error(w, http.StatusInternalServerError, "failed to update profile")
+	default:
+		respond.JSON(w, http.StatusOK, "profile updated", updated)
+	}
+}
+
+// expectedVersion reads the user version an update is based on from the
+// If-Match header, as in If-Match: "3", or else from the body. It returns 0
+// when neither is given and answers 400 when they are invalid or disagree.
+func expectedVersion(w http.ResponseWriter, r *http.Request, body *int64) (int64, bool) {
+	var version int64
+	if raw := strings.TrimSpace(r.Header.Get("If-Match")); raw != "" {
+		parsed, err := strconv.ParseInt(strings.Trim(strings.TrimPrefix(raw, "W/"), `"`), 10, 64)
+		if err != nil || parsed <= 0 {
+			respond.Error(w, http.StatusBadRequest, "If-Match must be a user version")
+			return 0, false
+		}
+		version = parsed
+	}
+	if body != nil {
+		switch {
+		case *body <= 0:
+			respond.Error(w, http.StatusBadRequest, "version must be positive")
+			return 0, false
+		case version != 0 && version != *body:
+			respond.Error(w, http.StatusBadRequest, "If-Match and version disagree")
+			return 0, false
+		}
+		version = *body
+	}
+	return version, true
+}
@@ -0,0 +1,266 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/hongminglow/all-in-be/internal/http/respond"
+	"github.com/hongminglow/all-in-be/internal/middleware"
+	"github.com/hongminglow/all-in-be/internal/models"
+	"github.com/hongminglow/all-in-be/internal/models/dto"
+	"github.com/hongminglow/all-in-be/internal/money"
+	"github.com/hongminglow/all-in-be/internal/promo"
+	"github.com/hongminglow/all-in-be/internal/storage"
+)
+
+const (
+	defaultPromoRedemptionLimit = 50
+	maxPromoRedemptionLimit     = 500
+)
+
+var promoCodePattern = regexp.MustCompile(`^[A-Z0-9_-]{3,32}$`)
+
+// PromoHandler lets players redeem promo codes, admins issue and withdraw
+// them, and staff report on redemptions per campaign.
+type PromoHandler struct {
+	store  storage.Store
+	promos *promo.Service
+}
+
+// NewPromoHandler constructs the handler.
+func NewPromoHandler(store storage.Store, promos *promo.Service) *PromoHandler {
+	return &PromoHandler{store: store, promos: promos}
+}
+
+// Register attaches the routes. They must be mounted behind middleware.Authenticate.
+func (h *PromoHandler) Register(mux Router) {
+	mux.Handle("POST /promo/redeem", middleware.RefuseImpersonation(http.HandlerFunc(h.handleRedeem)))
+	mux.Handle("GET /admin/promo-codes", middleware.RequirePermission(models.PermConfigManage, http.HandlerFunc(h.handleListCodes)))
+	mux.Handle("POST /admin/promo-codes", middleware.RequirePermission(models.PermConfigManage, http.HandlerFunc(h.create)))
+	mux.Handle("GET /admin/promo-codes/{id}", middleware.RequirePermission(models.PermConfigManage, http.HandlerFunc(h.handleCode)))
+	mux.Handle("PATCH /admin/promo-codes/{id}", middleware.RequirePermission(models.PermConfigManage, http.HandlerFunc(h.update)))
+	mux.Handle("GET /admin/promo-campaigns", middleware.RequirePermission(models.PermStatsRead, http.HandlerFunc(h.handleCampaigns)))
+	mux.Handle("GET /admin/promo-campaigns/{campaign}/redemptions", middleware.RequirePermission(models.PermStatsRead, http.HandlerFunc(h.handleRedemptions)))
+}
+
+func (h *PromoHandler) handleRedeem(w http.ResponseWriter, r *http.Request) {
+	user, ok := middleware.UserFromContext(r.Context())
+	if !ok {
+		respond.Error(w, http.StatusUnauthorized, "authentication required")
+		return
+	}
+	var req dto.RedeemPromoRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respond.Error(w, http.StatusBadRequest, "invalid JSON payload")
+		return
+	}
+	if strings.TrimSpace(req.Code) == "" {
+		respond.Error(w, http.StatusBadRequest, "code is required")
+		return
+	}
+	redemption, entry, err := h.promos.Redeem(r.Context(), user, req.Code)
+	switch {
+	case errors.Is(err, promo.ErrUnknownCode):
+		respond.Error(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, promo.ErrNotEligible):
+		respond.Error(w, http.StatusForbidden, err.Error())
+	case errors.Is(err, promo.ErrUnavailable), errors.Is(err, promo.ErrRedeemed), errors.Is(err, promo.ErrExhausted):
+		respond.Error(w, http.StatusConflict, err.Error())
+	case err != nil:
+		log.Printf("redeem promo code for user %d: %v", user.ID, err)
+		respond.Error(w, http.StatusInternalServerError, "failed to redeem promo code")
+	default:
+		format := money.FormatFromContext(r.Context())
+		respond.JSON(w, http.StatusOK, "promo code redeemed", dto.RedeemPromoResponse{
+			Redemption:   redemption,
+			Balance:      entry.BalanceAfter,
+			BalanceMoney: money.Amount(format, entry.BalanceAfter),
+			MoneyFormat:  format,
+		})
+	}
+}
+
+func (h *PromoHandler) handleListCodes(w http.ResponseWriter, r *http.Request) {
+	codes, err := h.store.ListPromoCodes(r.Context())
+	if err != nil {
+		log.Printf("list promo codes error: %v", err)
+		respond.Error(w, http.StatusInternalServerError, "failed to list promo codes")
+		return
+	}
+	respond.JSON(w, http.StatusOK, "promo codes fetched", codes)
+}
+
+func (h *PromoHandler) create(w http.ResponseWriter, r *http.Request) {
+	var req dto.CreatePromoCodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respond.Error(w, http.StatusBadRequest, "invalid JSON payload")
+		return
+	}
+	code := models.PromoCode{
+		Code:           promo.Normalize(req.Code),
+		Campaign:       strings.TrimSpace(req.Campaign),
+		Amount:         math.Round(req.Amount*100) / 100,
+		MaxRedemptions: req.MaxRedemptions,
+		PerUserLimit:   req.PerUserLimit,
+		Roles:          req.Roles,
+		ExpiresAt:      req.ExpiresAt,
+		Active:         true,
+	}
+	if code.PerUserLimit == 0 {
+		code.PerUserLimit = 1
+	}
+	switch {
+	case !promoCodePattern.MatchString(code.Code):
+		respond.Error(w, http.StatusBadRequest, "code must be 3-32 letters, digits, hyphens or underscores")
+		return
+	case code.Campaign == "":
+		respond.Error(w, http.StatusBadRequest, "campaign is required")
+		return
+	case code.Amount <= 0:
+		respond.Error(w, http.StatusBadRequest, "amount must be positive")
+		return
+	}
+	if !h.validLimits(w, r, &code) {
+		return
+	}
+	created, err := h.store.CreatePromoCode(r.Context(), code)
+	if errors.Is(err, storage.ErrAlreadyExists) {
+		respond.Error(w, http.StatusConflict, "promo code already exists")
+		return
+	}
+	if err != nil {
+		log.Printf("create promo code error: %v", err)
+		respond.Error(w, http.StatusInternalServerError, "failed to create promo code")
+		return
+	}
+	respond.Created(w, fmt.Sprintf("/admin/promo-codes/%d", created.ID), "promo code created", created)
+}
+
+func (h *PromoHandler) handleCode(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+	code, err := h.store.FindPromoCode(r.Context(), id)
+	if errors.Is(err, storage.ErrNotFound) {
+		respond.Error(w, http.StatusNotFound, "promo code not found")
+		return
+	}
+	if err != nil {
+		log.Printf("find promo code %d: %v", id, err)
+		respond.Error(w, http.StatusInternalServerError, "failed to fetch promo code")
+		return
+	}
+	respond.JSON(w, http.StatusOK, "promo code fetched", code)
+}
+
+func (h *PromoHandler) update(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+	var req dto.UpdatePromoCodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respond.Error(w, http.StatusBadRequest, "invalid JSON payload")
+		return
+	}
+	code, err := h.store.FindPromoCode(r.Context(), id)
+	if errors.Is(err, storage.ErrNotFound) {
+		respond.Error(w, http.StatusNotFound, "promo code not found")
+		return
+	}
+	if err != nil {
+		log.Printf("find promo code %d: %v", id, err)
+		respond.Error(w, http.StatusInternalServerError, "failed to update promo code")
+		return
+	}
+	if req.MaxRedemptions != nil {
+		code.MaxRedemptions = *req.MaxRedemptions
+	}
+	if req.PerUserLimit != nil {
+		code.PerUserLimit = *req.PerUserLimit
+	}
+	if req.Roles != nil {
+		code.Roles = *req.Roles
+	}
+	if req.ExpiresAt != nil {
+		code.ExpiresAt = req.ExpiresAt
+	}
+	if req.Active != nil {
+		code.Active = *req.Active
+	}
+	if !h.validLimits(w, r, &code) {
+		return
+	}
+	updated, err := h.store.UpdatePromoCode(r.Context(), code)
+	if err != nil {
+		log.Printf("update promo code %d: %v", id, err)
+		respond.Error(w, http.StatusInternalServerError, "failed to update promo code")
+		return
+	}
+	respond.JSON(w, http.StatusOK, "promo code updated", updated)
+}
+
+// validLimits checks the code's limits and that its roles exist, writing a
+// 400 when they are invalid.
+func (h *PromoHandler) validLimits(w http.ResponseWriter, r *http.Request, code *models.PromoCode) bool {
+	if code.MaxRedemptions < 0 || code.PerUserLimit < 1 {
+		respond.Error(w, http.StatusBadRequest, "max_redemptions must be 0 (unlimited) or more and per_user_limit at least 1")
+		return false
+	}
+	if len(code.Roles) == 0 {
+		return true
+	}
+	roles, err := h.store.ListRoles(r.Context())
+	if err != nil {
+		log.Printf("list roles error: %v", err)
+		respond.Error(w, http.StatusInternalServerError, "failed to save promo code")
+		return false
+	}
+	for i, name := range code.Roles {
+		code.Roles[i] = strings.TrimSpace(name)
+		if !slices.ContainsFunc(roles, func(role models.Role) bool { return role.RoleName == code.Roles[i] }) {
+			respond.Error(w, http.StatusBadRequest, "unknown role "+strconv.Quote(code.Roles[i]))
+			return false
+		}
+	}
+	return true
+}
+
+func (h *PromoHandler) handleCampaigns(w http.ResponseWriter, r *http.Request) {
+	reports, err := h.store.ListPromoCampaignReports(r.Context())
+	if err != nil {
+		log.Printf("list promo campaign reports error: %v", err)
+		respond.Error(w, http.StatusInternalServerError, "failed to list promo campaigns")
+		return
+	}
+	respond.JSON(w, http.StatusOK, "promo campaigns fetched", reports)
+}
+
+// handleRedemptions returns a campaign's redemptions, newest first. Supports ?limit=.
+func (h *PromoHandler) handleRedemptions(w http.ResponseWriter, r *http.Request) {
+	limit := defaultPromoRedemptionLimit
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n <= 0 || n > maxPromoRedemptionLimit {
+			respond.Error(w, http.StatusBadRequest, "limit must be between 1 and 500")
+			return
+		}
+		limit = n
+	}
+	redemptions, err := h.store.ListPromoRedemptions(r.Context(), r.PathValue("campaign"), limit)
+	if err != nil {
+		log.Printf("list promo redemptions error: %v", err)
+		respond.Error(w, http.StatusInternalServerError, "failed to list promo redemptions")
+		return
+	}
+	respond.JSON(w, http.StatusOK, "promo redemptions fetched", redemptions)
+}
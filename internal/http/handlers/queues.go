@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/hongminglow/all-in-be/internal/http/respond"
+	"github.com/hongminglow/all-in-be/internal/jobs"
+	"github.com/hongminglow/all-in-be/internal/middleware"
+	"github.com/hongminglow/all-in-be/internal/models"
+)
+
+// JobQueue is the part of the background job queue the handlers monitor and control.
+type JobQueue interface {
+	Stats() []jobs.TypeStats
+	Lanes() []jobs.LaneStats
+	Pause(typ string)
+	Resume(typ string)
+}
+
+// QueueHandler lets operators watch the background job queue and pause a job
+// type during an incident, e.g. webhooks while a downstream is failing.
+type QueueHandler struct {
+	queue JobQueue
+}
+
+// NewQueueHandler constructs the handler.
+func NewQueueHandler(queue JobQueue) *QueueHandler {
+	return &QueueHandler{queue: queue}
+}
+
+// Register attaches the admin routes. They must be mounted behind middleware.Authenticate.
+func (h *QueueHandler) Register(mux Router) {
+	mux.Handle("GET /admin/queues", middleware.RequirePermission(models.PermConfigManage, http.HandlerFunc(h.handleList)))
+	mux.Handle("POST /admin/queues/{type}/pause", middleware.RequirePermission(models.PermConfigManage, http.HandlerFunc(h.handlePause)))
+	mux.Handle("POST /admin/queues/{type}/resume", middleware.RequirePermission(models.PermConfigManage, http.HandlerFunc(h.handleResume)))
+}
+
+func (h *QueueHandler) handleList(w http.ResponseWriter, r *http.Request) {
+	respond.JSON(w, http.StatusOK, "queues fetched", h.queue.Stats())
+}
+
+// handlePause holds back new and queued jobs of the type on this instance.
+func (h *QueueHandler) handlePause(w http.ResponseWriter, r *http.Request) {
+	h.queue.Pause(r.PathValue("type"))
+	respond.JSON(w, http.StatusOK, "job type paused", h.queue.Stats())
+}
+
+func (h *QueueHandler) handleResume(w http.ResponseWriter, r *http.Request) {
+	h.queue.Resume(r.PathValue("type"))
+	respond.JSON(w, http.StatusOK, "job type resumed", h.queue.Stats())
+}
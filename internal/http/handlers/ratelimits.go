@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/hongminglow/all-in-be/internal/confighistory"
+	"github.com/hongminglow/all-in-be/internal/http/respond"
+	"github.com/hongminglow/all-in-be/internal/middleware"
+	"github.com/hongminglow/all-in-be/internal/models"
+	"github.com/hongminglow/all-in-be/internal/models/dto"
+	"github.com/hongminglow/all-in-be/internal/storage"
+)
+
+// RateLimitHandler lets administrators manage rate-limit policies at runtime.
+// Every change is recorded in the configuration history.
+type RateLimitHandler struct {
+	store      storage.Store
+	invalidate func()
+}
+
+// NewRateLimitHandler constructs the handler. invalidate is called after every change
+// so cached policies are reloaded.
+func NewRateLimitHandler(store storage.Store, invalidate func()) *RateLimitHandler {
+	return &RateLimitHandler{store: store, invalidate: invalidate}
+}
+
+// Register attaches the admin routes. They must be mounted behind middleware.Authenticate.
+func (h *RateLimitHandler) Register(mux Router) {
+	mux.Handle("GET /admin/rate-limits", middleware.RequirePermission(models.PermConfigManage, http.HandlerFunc(h.list)))
+	mux.Handle("PUT /admin/rate-limits", middleware.RequirePermission(models.PermConfigManage, http.HandlerFunc(h.upsert)))
+	mux.Handle("DELETE /admin/rate-limits", middleware.RequirePermission(models.PermConfigManage, http.HandlerFunc(h.delete)))
+}
+
+func (h *RateLimitHandler) list(w http.ResponseWriter, r *http.Request) {
+	policies, err := h.store.ListRateLimitPolicies(r.Context())
+	if err != nil {
+		log.Printf("list rate limit policies error: %v", err)
+		respond.Error(w, http.StatusInternalServerError, "failed to list rate limit policies")
+		return
+	}
+	respond.JSON(w, http.StatusOK, "rate limit policies fetched", policies)
+}
+
+func (h *RateLimitHandler) upsert(w http.ResponseWriter, r *http.Request) {
+	var req dto.UpsertRateLimitPolicyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respond.Error(w, http.StatusBadRequest, "invalid JSON payload")
+		return
+	}
+	routeClass := strings.TrimSpace(req.RouteClass)
+	if !validRouteClass(routeClass) {
+		respond.Error(w, http.StatusBadRequest, "route_class must be one of: auth, api")
+		return
+	}
+	if req.Requests <= 0 || req.WindowSeconds <= 0 {
+		respond.Error(w, http.StatusBadRequest, "requests and window_seconds must be positive")
+		return
+	}
+	actor, _ := middleware.UserFromContext(r.Context())
+	var saved models.RateLimitPolicy
+	err := h.store.WithTx(r.Context(), func(tx storage.Repositories) error {
+		var err error
+		saved, err = confighistory.SaveRateLimit(r.Context(), tx, actor.ID, models.RateLimitPolicy{
+			Tenant:        strings.TrimSpace(req.Tenant),
+			RouteClass:    routeClass,
+			Requests:      req.Requests,
+			WindowSeconds: req.WindowSeconds,
+		})
+		return err
+	})
+	if err != nil {
+		log.Printf("upsert rate limit policy error: %v", err)
+		respond.Error(w, http.StatusInternalServerError, "failed to save rate limit policy")
+		return
+	}
+	h.invalidate()
+	respond.JSON(w, http.StatusOK, "rate limit policy saved", saved)
+}
+
+func (h *RateLimitHandler) delete(w http.ResponseWriter, r *http.Request) {
+	tenant := strings.TrimSpace(r.URL.Query().Get("tenant"))
+	routeClass := strings.TrimSpace(r.URL.Query().Get("route_class"))
+	actor, _ := middleware.UserFromContext(r.Context())
+	err := h.store.WithTx(r.Context(), func(tx storage.Repositories) error {
+		return confighistory.DeleteRateLimit(r.Context(), tx, actor.ID, tenant, routeClass)
+	})
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			respond.Error(w, http.StatusNotFound, "rate limit policy not found")
+			return
+		}
+		log.Printf("delete rate limit policy error: %v", err)
+		respond.Error(w, http.StatusInternalServerError, "failed to delete rate limit policy")
+		return
+	}
+	h.invalidate()
+	respond.JSON(w, http.StatusOK, "rate limit policy deleted", nil)
+}
+
+func validRouteClass(class string) bool {
+	for _, known := range models.RouteClasses {
+		if class == known {
+			return true
+		}
+	}
+	return false
+}
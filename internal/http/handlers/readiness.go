@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/hongminglow/all-in-be/internal/http/respond"
+	"github.com/hongminglow/all-in-be/internal/storage"
+)
+
+// readinessTimeout bounds the database ping so a hung connection fails the probe.
+const readinessTimeout = 2 * time.Second
+
+// ReadinessHandler reports whether the service can take traffic: unlike /health,
+// it fails while the database is unreachable or the circuit breaker in front of
+// it is open.
+type ReadinessHandler struct {
+	db storage.HealthChecker
+}
+
+// NewReadinessHandler constructs the handler.
+func NewReadinessHandler(db storage.HealthChecker) *ReadinessHandler {
+	return &ReadinessHandler{db: db}
+}
+
+// Register attaches the /readyz route.
+func (h *ReadinessHandler) Register(mux Router) {
+	mux.HandleFunc("GET /readyz", h.handle)
+}
+
+func (h *ReadinessHandler) handle(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), readinessTimeout)
+	defer cancel()
+	pools := h.db.PoolStats()
+	if pools == nil {
+		pools = []storage.PoolStats{}
+	}
+	breaker := h.db.Breaker()
+	err := h.db.Ping(ctx)
+	if err != nil {
+		log.Printf("readiness: database ping failed: %v", err)
+	}
+	if err != nil || breaker.State == storage.BreakerOpen {
+		respond.JSON(w, http.StatusServiceUnavailable, "database unavailable", map[string]any{
+			"status":  "unavailable",
+			"pools":   pools,
+			"breaker": breaker,
+		})
+		return
+	}
+	respond.JSON(w, http.StatusOK, "service ready", map[string]any{
+		"status":  "ready",
+		"pools":   pools,
+		"breaker": breaker,
+	})
+}
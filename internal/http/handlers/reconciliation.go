@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/hongminglow/all-in-be/internal/http/respond"
+	"github.com/hongminglow/all-in-be/internal/middleware"
+	"github.com/hongminglow/all-in-be/internal/models"
+	"github.com/hongminglow/all-in-be/internal/storage"
+)
+
+const (
+	defaultReportLimit = 30
+	maxReportLimit     = 365
+)
+
+// Reconciler checks stored balances against the ledger.
+type Reconciler interface {
+	Run(ctx context.Context) (models.ReconciliationReport, error)
+}
+
+// ReconciliationHandler shows balance reconciliation reports and lets
+// operators run a reconciliation now, e.g. to confirm a correction.
+type ReconciliationHandler struct {
+	store      storage.ReconciliationStore
+	reconciler Reconciler
+}
+
+// NewReconciliationHandler constructs the handler.
+func NewReconciliationHandler(store storage.ReconciliationStore, reconciler Reconciler) *ReconciliationHandler {
+	return &ReconciliationHandler{store: store, reconciler: reconciler}
+}
+
+// Register attaches the admin routes. They must be mounted behind middleware.Authenticate.
+func (h *ReconciliationHandler) Register(mux Router) {
+	mux.Handle("GET /admin/reconciliation-reports", middleware.RequirePermission(models.PermStatsRead, http.HandlerFunc(h.handleList)))
+	mux.Handle("POST /admin/reconciliation/run", middleware.RequirePermission(models.PermConfigManage, http.HandlerFunc(h.handleRun)))
+}
+
+// handleList supports ?limit=, newest report first.
+func (h *ReconciliationHandler) handleList(w http.ResponseWriter, r *http.Request) {
+	limit := defaultReportLimit
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed < 1 || parsed > maxReportLimit {
+			respond.Error(w, http.StatusBadRequest, "limit must be between 1 and 365")
+			return
+		}
+		limit = parsed
+	}
+	reports, err := h.store.ListReconciliationReports(r.Context(), limit)
+	if err != nil {
+		log.Printf("list reconciliation reports: %v", err)
+		respond.Error(w, http.StatusInternalServerError, "failed to list reconciliation reports")
+		return
+	}
+	respond.JSON(w, http.StatusOK, "reconciliation reports fetched", reports)
+}
+
+func (h *ReconciliationHandler) handleRun(w http.ResponseWriter, r *http.Request) {
+	report, err := h.reconciler.Run(r.Context())
+	if err != nil {
+		log.Printf("reconcile balances: %v", err)
+		respond.Error(w, http.StatusInternalServerError, "failed to reconcile balances")
+		return
+	}
+	respond.JSON(w, http.StatusOK, "balances reconciled", report)
+}
@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/hongminglow/all-in-be/internal/config"
+	"github.com/hongminglow/all-in-be/internal/http/respond"
+)
+
+// RegionHandler tells clients which region served them and where the other
+// regional deployments are, so they can pin themselves to the nearest one or to
+// their account's home region.
+type RegionHandler struct {
+	cfg config.RegionConfig
+}
+
+// NewRegionHandler constructs the handler.
+func NewRegionHandler(cfg config.RegionConfig) *RegionHandler {
+	return &RegionHandler{cfg: cfg}
+}
+
+// Register attaches the /region route.
+func (h *RegionHandler) Register(mux Router) {
+	mux.HandleFunc("GET /region", h.handle)
+}
+
+type regionEntry struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+}
+
+func (h *RegionHandler) handle(w http.ResponseWriter, r *http.Request) {
+	peers := make([]regionEntry, 0, len(h.cfg.Peers))
+	for _, peer := range h.cfg.Peers {
+		peers = append(peers, regionEntry{Name: peer.Name, URL: peer.URL})
+	}
+	respond.JSON(w, http.StatusOK, "region fetched", map[string]any{
+		"region": h.cfg.Name,
+		"peers":  peers,
+	})
+}
@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hongminglow/all-in-be/internal/http/respond"
+	"github.com/hongminglow/all-in-be/internal/middleware"
+	"github.com/hongminglow/all-in-be/internal/models"
+	"github.com/hongminglow/all-in-be/internal/models/dto"
+	"github.com/hongminglow/all-in-be/internal/reports"
+	"github.com/hongminglow/all-in-be/internal/storage"
+)
+
+// OperatorReportHandler lists operator reports and lets admins ask for one
+// outside the daily and weekly schedule.
+type OperatorReportHandler struct {
+	reports *reports.Service
+}
+
+// NewOperatorReportHandler constructs the handler.
+func NewOperatorReportHandler(service *reports.Service) *OperatorReportHandler {
+	return &OperatorReportHandler{reports: service}
+}
+
+// Register attaches the admin routes. They must be mounted behind middleware.Authenticate.
+func (h *OperatorReportHandler) Register(mux Router) {
+	mux.Handle("GET /admin/reports", middleware.RequirePermission(models.PermStatsRead, http.HandlerFunc(h.handleList)))
+	mux.Handle("POST /admin/reports", middleware.RequirePermission(models.PermStatsRead, http.HandlerFunc(h.handleRequest)))
+	mux.Handle("GET /admin/reports/{id}", middleware.RequirePermission(models.PermStatsRead, http.HandlerFunc(h.handleFind)))
+}
+
+// handleList supports ?limit=, newest report first.
+func (h *OperatorReportHandler) handleList(w http.ResponseWriter, r *http.Request) {
+	limit := defaultReportLimit
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed < 1 || parsed > maxReportLimit {
+			respond.Error(w, http.StatusBadRequest, "limit must be between 1 and 365")
+			return
+		}
+		limit = parsed
+	}
+	list, err := h.reports.List(r.Context(), limit)
+	if err != nil {
+		log.Printf("list operator reports: %v", err)
+		respond.Error(w, http.StatusInternalServerError, "failed to list reports")
+		return
+	}
+	respond.JSON(w, http.StatusOK, "reports fetched", list)
+}
+
+// handleRequest queues a report, emailed to the caller once it is ready.
+func (h *OperatorReportHandler) handleRequest(w http.ResponseWriter, r *http.Request) {
+	actor, _ := middleware.UserFromContext(r.Context())
+	var req dto.OperatorReportRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respond.Error(w, http.StatusBadRequest, "invalid JSON payload")
+		return
+	}
+	if !slices.Contains(models.ReportPeriods, req.Period) {
+		respond.Error(w, http.StatusBadRequest, "period must be one of "+strings.Join(models.ReportPeriods, ", "))
+		return
+	}
+	from, err := time.Parse(time.DateOnly, req.From)
+	if err != nil {
+		respond.Error(w, http.StatusBadRequest, "from must be a date such as 2026-01-31")
+		return
+	}
+	to := from
+	if req.Period == models.ReportCustom {
+		if to, err = time.Parse(time.DateOnly, req.To); err != nil {
+			respond.Error(w, http.StatusBadRequest, "to must be a date such as 2026-01-31")
+			return
+		}
+	}
+	report, err := h.reports.Request(r.Context(), req.Period, from, to, actor.ID)
+	if errors.Is(err, reports.ErrInvalidPeriod) {
+		respond.Error(w, http.StatusBadRequest, fmt.Sprintf("the period must start by today, and custom ones must end on or after from and span at most %d days", reports.MaxCustomDays))
+		return
+	}
+	if err != nil {
+		log.Printf("request operator report: %v", err)
+		respond.Error(w, http.StatusInternalServerError, "failed to start report")
+		return
+	}
+	w.Header().Set("Location", fmt.Sprintf("/admin/reports/%d", report.ID))
+	respond.JSON(w, http.StatusAccepted, "report started", report)
+}
+
+func (h *OperatorReportHandler) handleFind(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+	report, err := h.reports.Find(r.Context(), id)
+	if errors.Is(err, storage.ErrNotFound) {
+		respond.Error(w, http.StatusNotFound, "report not found")
+		return
+	}
+	if err != nil {
+		log.Printf("find operator report %d: %v", id, err)
+		respond.Error(w, http.StatusInternalServerError, "failed to fetch report")
+		return
+	}
+	respond.JSON(w, http.StatusOK, "report fetched", report)
+}
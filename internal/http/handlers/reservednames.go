@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/hongminglow/all-in-be/internal/http/respond"
+	"github.com/hongminglow/all-in-be/internal/middleware"
+	"github.com/hongminglow/all-in-be/internal/models"
+	"github.com/hongminglow/all-in-be/internal/models/dto"
+	"github.com/hongminglow/all-in-be/internal/storage"
+	"github.com/hongminglow/all-in-be/internal/usernames"
+)
+
+// ReservedNameHandler manages the reserved names and blocked words usernames
+// are checked against. Entries from USERNAME_RESERVED_FILE are listed but can
+// only be changed in the file.
+type ReservedNameHandler struct {
+	store  storage.ReservedNameStore
+	policy *usernames.Policy
+}
+
+// NewReservedNameHandler constructs the handler; policy supplies the file entries.
+func NewReservedNameHandler(store storage.ReservedNameStore, policy *usernames.Policy) *ReservedNameHandler {
+	return &ReservedNameHandler{store: store, policy: policy}
+}
+
+// Register attaches the admin routes. They must be mounted behind middleware.Authenticate.
+func (h *ReservedNameHandler) Register(mux Router) {
+	mux.Handle("GET /admin/reserved-usernames", middleware.RequirePermission(models.PermConfigManage, http.HandlerFunc(h.handleList)))
+	mux.Handle("POST /admin/reserved-usernames", middleware.RequirePermission(models.PermConfigManage, http.HandlerFunc(h.handleAdd)))
+	mux.Handle("DELETE /admin/reserved-usernames/{name}", middleware.RequirePermission(models.PermConfigManage, http.HandlerFunc(h.handleDelete)))
+}
+
+// handleList returns the file and stored entries together, by name.
+func (h *ReservedNameHandler) handleList(w http.ResponseWriter, r *http.Request) {
+	stored, err := h.store.ListReservedNames(r.Context())
+	if err != nil {
+		log.Printf("list reserved names error: %v", err)
+		respond.Error(w, http.StatusInternalServerError, "failed to list reserved usernames")
+		return
+	}
+	names := slices.Concat(h.policy.FileEntries(), stored)
+	slices.SortStableFunc(names, func(a, b models.ReservedName) int { return cmp.Compare(a.Name, b.Name) })
+	respond.JSON(w, http.StatusOK, "reserved usernames fetched", names)
+}
+
+// handleAdd stores an entry with POST {"name":"admin","kind":"reserved"}.
+func (h *ReservedNameHandler) handleAdd(w http.ResponseWriter, r *http.Request) {
+	var req dto.AddReservedNameRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respond.Error(w, http.StatusBadRequest, "invalid JSON payload")
+		return
+	}
+	name := strings.ToLower(strings.TrimSpace(req.Name))
+	if usernames.Fold(name) == "" {
+		respond.Error(w, http.StatusBadRequest, "name must contain a letter or digit")
+		return
+	}
+	kind := strings.ToLower(strings.TrimSpace(req.Kind))
+	if kind == "" {
+		kind = models.UsernameReserved
+	}
+	if !slices.Contains(models.UsernameEntryKinds, kind) {
+		respond.Error(w, http.StatusBadRequest, "kind must be one of: "+strings.Join(models.UsernameEntryKinds, ", "))
+		return
+	}
+	actor, _ := middleware.UserFromContext(r.Context())
+	saved, err := h.store.AddReservedName(r.Context(), models.ReservedName{Name: name, Kind: kind, CreatedBy: actor.ID})
+	switch {
+	case errors.Is(err, storage.ErrAlreadyExists):
+		respond.Error(w, http.StatusConflict, "reserved username already exists")
+	case err != nil:
+		log.Printf("add reserved name error: %v", err)
+		respond.Error(w, http.StatusInternalServerError, "failed to save reserved username")
+	default:
+		respond.Created(w, "/admin/reserved-usernames/"+url.PathEscape(saved.Name), "reserved username saved", saved)
+	}
+}
+
+func (h *ReservedNameHandler) handleDelete(w http.ResponseWriter, r *http.Request) {
+	err := h.store.DeleteReservedName(r.Context(), strings.ToLower(strings.TrimSpace(r.PathValue("name"))))
+	switch {
+	case errors.Is(err, storage.ErrNotFound):
+		respond.Error(w, http.StatusNotFound, "reserved username not found")
+	case err != nil:
+		log.Printf("delete reserved name error: %v", err)
+		respond.Error(w, http.StatusInternalServerError, "failed to delete reserved username")
+	default:
+		respond.JSON(w, http.StatusOK, "reserved username deleted", nil)
+	}
+}
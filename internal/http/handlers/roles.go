@@ -0,0 +1,346 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/hongminglow/all-in-be/internal/confighistory"
+	"github.com/hongminglow/all-in-be/internal/http/respond"
+	"github.com/hongminglow/all-in-be/internal/middleware"
+	"github.com/hongminglow/all-in-be/internal/models"
+	"github.com/hongminglow/all-in-be/internal/models/dto"
+	"github.com/hongminglow/all-in-be/internal/storage"
+)
+
+var (
+	roleNamePattern       = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{1,31}$`)
+	permissionNamePattern = regexp.MustCompile(`^[a-z0-9_-]+:[a-z0-9_-]+$`)
+)
+
+// RoleHandler lets administrators manage roles, permissions, and which roles
+// grant which permissions. Tokens carry the permissions they were issued
+// with, so a change bumps the permissions version of the users it affects and
+// their tokens are refused until they sign in again. Every change is recorded
+// in the configuration history.
+type RoleHandler struct {
+	store      storage.Store
+	invalidate func()
+}
+
+// NewRoleHandler constructs the handler. invalidate is called after every
+// change that affects users' permissions so cached session state is reloaded.
+func NewRoleHandler(store storage.Store, invalidate func()) *RoleHandler {
+	return &RoleHandler{store: store, invalidate: invalidate}
+}
+
+// Register attaches the admin routes. They must be mounted behind middleware.Authenticate.
+func (h *RoleHandler) Register(mux Router) {
+	mux.Handle("GET /admin/roles", middleware.RequirePermission(models.PermRolesManage, http.HandlerFunc(h.handleListRoles)))
+	mux.Handle("POST /admin/roles", middleware.RequirePermission(models.PermRolesManage, http.HandlerFunc(h.createRole)))
+	mux.Handle("PATCH /admin/roles/{id}", middleware.RequirePermission(models.PermRolesManage, http.HandlerFunc(h.updateRole)))
+	mux.Handle("DELETE /admin/roles/{id}", middleware.RequirePermission(models.PermRolesManage, http.HandlerFunc(h.deleteRole)))
+	mux.Handle("PUT /admin/roles/{id}/permissions/{permissionID}", middleware.RequirePermission(models.PermRolesManage, http.HandlerFunc(h.handleGrant)))
+	mux.Handle("DELETE /admin/roles/{id}/permissions/{permissionID}", middleware.RequirePermission(models.PermRolesManage, http.HandlerFunc(h.handleGrant)))
+	mux.Handle("GET /admin/permissions", middleware.RequirePermission(models.PermRolesManage, http.HandlerFunc(h.handleListPermissions)))
+	mux.Handle("POST /admin/permissions", middleware.RequirePermission(models.PermRolesManage, http.HandlerFunc(h.createPermission)))
+	mux.Handle("PATCH /admin/permissions/{id}", middleware.RequirePermission(models.PermRolesManage, http.HandlerFunc(h.updatePermission)))
+	mux.Handle("DELETE /admin/permissions/{id}", middleware.RequirePermission(models.PermRolesManage, http.HandlerFunc(h.deletePermission)))
+}
+
+func (h *RoleHandler) handleListRoles(w http.ResponseWriter, r *http.Request) {
+	roles, err := h.store.ListRoles(r.Context())
+	if err != nil {
+		log.Printf("list roles error: %v", err)
+		respond.Error(w, http.StatusInternalServerError, "failed to list roles")
+		return
+	}
+	respond.JSON(w, http.StatusOK, "roles fetched", roles)
+}
+
+func (h *RoleHandler) createRole(w http.ResponseWriter, r *http.Request) {
+	var req dto.CreateRoleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respond.Error(w, http.StatusBadRequest, "invalid JSON payload")
+		return
+	}
+	name := strings.TrimSpace(req.Role)
+	if !roleNamePattern.MatchString(name) {
+		respond.Error(w, http.StatusBadRequest, "role must be 2-32 lowercase letters, digits, or dashes")
+		return
+	}
+	permissions, err := h.store.ListPermissions(r.Context())
+	if err != nil {
+		log.Printf("list permissions error: %v", err)
+		respond.Error(w, http.StatusInternalServerError, "failed to create role")
+		return
+	}
+	ids := make([]int64, 0, len(req.Permissions))
+	for _, want := range req.Permissions {
+		i := slices.IndexFunc(permissions, func(p models.Permission) bool { return p.PermissionName == want })
+		if i < 0 {
+			respond.Error(w, http.StatusBadRequest, "unknown permission "+strconv.Quote(want))
+			return
+		}
+		ids = append(ids, permissions[i].ID)
+	}
+	actor, _ := middleware.UserFromContext(r.Context())
+	var created models.Role
+	err = h.store.WithTx(r.Context(), func(tx storage.Repositories) error {
+		created, err = confighistory.CreateRole(r.Context(), tx, actor.ID, models.Role{
+			RoleName:        name,
+			RoleDescription: strings.TrimSpace(req.Description),
+		}, ids)
+		return err
+	})
+	if err != nil {
+		if errors.Is(err, storage.ErrAlreadyExists) {
+			respond.Error(w, http.StatusConflict, "role already exists")
+			return
+		}
+		log.Printf("create role error: %v", err)
+		respond.Error(w, http.StatusInternalServerError, "failed to create role")
+		return
+	}
+	respond.Created(w, fmt.Sprintf("/admin/roles/%d", created.ID), "role created", created)
+}
+
+func (h *RoleHandler) updateRole(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+	var req dto.UpdateRoleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respond.Error(w, http.StatusBadRequest, "invalid JSON payload")
+		return
+	}
+	actor, _ := middleware.UserFromContext(r.Context())
+	var updated models.Role
+	err := h.store.WithTx(r.Context(), func(tx storage.Repositories) error {
+		role, err := tx.FindRole(r.Context(), id)
+		if err != nil {
+			return err
+		}
+		if req.Role != nil {
+			name := strings.TrimSpace(*req.Role)
+			if name != role.RoleName && slices.Contains(models.BuiltinRoles, role.RoleName) {
+				return errBuiltin
+			}
+			if !roleNamePattern.MatchString(name) {
+				return errInvalidName
+			}
+			role.RoleName = name
+		}
+		if req.Description != nil {
+			role.RoleDescription = strings.TrimSpace(*req.Description)
+		}
+		updated, err = confighistory.UpdateRole(r.Context(), tx, actor.ID, role)
+		return err
+	})
+	if err != nil {
+		h.fail(w, "update role", "role", err)
+		return
+	}
+	h.invalidate()
+	respond.JSON(w, http.StatusOK, "role updated", updated)
+}
+
+func (h *RoleHandler) deleteRole(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+	actor, _ := middleware.UserFromContext(r.Context())
+	err := h.store.WithTx(r.Context(), func(tx storage.Repositories) error {
+		role, err := tx.FindRole(r.Context(), id)
+		if err != nil {
+			return err
+		}
+		if slices.Contains(models.BuiltinRoles, role.RoleName) {
+			return errBuiltin
+		}
+		return confighistory.DeleteRole(r.Context(), tx, actor.ID, id)
+	})
+	if err != nil {
+		h.fail(w, "delete role", "role", err)
+		return
+	}
+	respond.JSON(w, http.StatusOK, "role deleted", nil)
+}
+
+// handleGrant grants (PUT) or revokes (DELETE) one permission on a role.
+func (h *RoleHandler) handleGrant(w http.ResponseWriter, r *http.Request) {
+	roleID, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+	permissionID, ok := pathID(w, r, "permissionID")
+	if !ok {
+		return
+	}
+	granted := r.Method == http.MethodPut
+	actor, _ := middleware.UserFromContext(r.Context())
+	var role models.Role
+	err := h.store.WithTx(r.Context(), func(tx storage.Repositories) error {
+		var err error
+		role, err = confighistory.SetRolePermission(r.Context(), tx, actor.ID, roleID, permissionID, granted)
+		if err != nil {
+			return err
+		}
+		// Keep at least the admin role able to undo mistakes made here.
+		if role.RoleName == models.AdminUser && !slices.Contains(role.Permissions, models.PermRolesManage) {
+			return errLockout
+		}
+		return nil
+	})
+	if err != nil {
+		h.fail(w, "set role permission", "role or permission", err)
+		return
+	}
+	h.invalidate()
+	message := "permission granted"
+	if !granted {
+		message = "permission revoked"
+	}
+	respond.JSON(w, http.StatusOK, message, role)
+}
+
+func (h *RoleHandler) handleListPermissions(w http.ResponseWriter, r *http.Request) {
+	permissions, err := h.store.ListPermissions(r.Context())
+	if err != nil {
+		log.Printf("list permissions error: %v", err)
+		respond.Error(w, http.StatusInternalServerError, "failed to list permissions")
+		return
+	}
+	respond.JSON(w, http.StatusOK, "permissions fetched", permissions)
+}
+
+func (h *RoleHandler) createPermission(w http.ResponseWriter, r *http.Request) {
+	var req dto.CreatePermissionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respond.Error(w, http.StatusBadRequest, "invalid JSON payload")
+		return
+	}
+	name := strings.TrimSpace(req.Name)
+	if !permissionNamePattern.MatchString(name) {
+		respond.Error(w, http.StatusBadRequest, `name must look like "resource:action"`)
+		return
+	}
+	actor, _ := middleware.UserFromContext(r.Context())
+	var created models.Permission
+	err := h.store.WithTx(r.Context(), func(tx storage.Repositories) error {
+		var err error
+		created, err = confighistory.CreatePermission(r.Context(), tx, actor.ID, models.Permission{
+			PermissionName:        name,
+			PermissionDescription: strings.TrimSpace(req.Description),
+		})
+		return err
+	})
+	if err != nil {
+		if errors.Is(err, storage.ErrAlreadyExists) {
+			respond.Error(w, http.StatusConflict, "permission already exists")
+			return
+		}
+		log.Printf("create permission error: %v", err)
+		respond.Error(w, http.StatusInternalServerError, "failed to create permission")
+		return
+	}
+	respond.Created(w, fmt.Sprintf("/admin/permissions/%d", created.ID), "permission created", created)
+}
+
+func (h *RoleHandler) updatePermission(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+	var req dto.UpdatePermissionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respond.Error(w, http.StatusBadRequest, "invalid JSON payload")
+		return
+	}
+	actor, _ := middleware.UserFromContext(r.Context())
+	var updated models.Permission
+	err := h.store.WithTx(r.Context(), func(tx storage.Repositories) error {
+		p, err := tx.FindPermission(r.Context(), id)
+		if err != nil {
+			return err
+		}
+		if req.Name != nil {
+			name := strings.TrimSpace(*req.Name)
+			if name != p.PermissionName && slices.Contains(models.BuiltinPermissions, p.PermissionName) {
+				return errBuiltin
+			}
+			if !permissionNamePattern.MatchString(name) {
+				return errInvalidName
+			}
+			p.PermissionName = name
+		}
+		if req.Description != nil {
+			p.PermissionDescription = strings.TrimSpace(*req.Description)
+		}
+		updated, err = confighistory.UpdatePermission(r.Context(), tx, actor.ID, p)
+		return err
+	})
+	if err != nil {
+		h.fail(w, "update permission", "permission", err)
+		return
+	}
+	h.invalidate()
+	respond.JSON(w, http.StatusOK, "permission updated", updated)
+}
+
+func (h *RoleHandler) deletePermission(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+	actor, _ := middleware.UserFromContext(r.Context())
+	err := h.store.WithTx(r.Context(), func(tx storage.Repositories) error {
+		p, err := tx.FindPermission(r.Context(), id)
+		if err != nil {
+			return err
+		}
+		if slices.Contains(models.BuiltinPermissions, p.PermissionName) {
+			return errBuiltin
+		}
+		return confighistory.DeletePermission(r.Context(), tx, actor.ID, id)
+	})
+	if err != nil {
+		h.fail(w, "delete permission", "permission", err)
+		return
+	}
+	h.invalidate()
+	respond.JSON(w, http.StatusOK, "permission deleted", nil)
+}
+
+var (
+	errBuiltin     = errors.New("built-in roles and permissions cannot be renamed or deleted")
+	errInvalidName = errors.New(`invalid name: roles are 2-32 lowercase letters, digits, or dashes and permissions look like "resource:action"`)
+	errLockout     = errors.New("the admin role must keep " + models.PermRolesManage)
+)
+
+// fail maps the errors of a role or permission change to a response.
+func (h *RoleHandler) fail(w http.ResponseWriter, action, entity string, err error) {
+	switch {
+	case errors.Is(err, storage.ErrNotFound):
+		respond.Error(w, http.StatusNotFound, entity+" not found")
+	case errors.Is(err, storage.ErrAlreadyExists):
+		respond.Error(w, http.StatusConflict, "name already in use")
+	case errors.Is(err, storage.ErrInUse):
+		respond.Error(w, http.StatusConflict, "role is still assigned to users")
+	case errors.Is(err, errInvalidName):
+		respond.Error(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, errBuiltin), errors.Is(err, errLockout):
+		respond.Error(w, http.StatusConflict, err.Error())
+	default:
+		log.Printf("%s error: %v", action, err)
+		respond.Error(w, http.StatusInternalServerError, "failed to "+action)
+	}
+}
@@ -0,0 +1,13 @@
+package handlers
+
+import "net/http"
+
+// Router is the registration surface handlers attach their routes to. Both
+// *http.ServeMux and the grouped router in internal/server satisfy it.
+// Patterns name their method, as in "GET /admin/users/{id}/notes", and
+// handlers read path parameters with r.PathValue. The mux answers other
+// methods with 405 and an Allow header, so handlers need not check r.Method.
+type Router interface {
+	Handle(pattern string, handler http.Handler)
+	HandleFunc(pattern string, handler func(http.ResponseWriter, *http.Request))
+}
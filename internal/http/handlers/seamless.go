@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/hongminglow/all-in-be/internal/http/respond"
+	"github.com/hongminglow/all-in-be/internal/integrations"
+	"github.com/hongminglow/all-in-be/internal/seamless"
+	"github.com/hongminglow/all-in-be/internal/storage"
+	"github.com/hongminglow/all-in-be/internal/wallet"
+)
+
+// SeamlessWalletHandler answers game providers' wallet callbacks.
+type SeamlessWalletHandler struct {
+	service *seamless.Service
+}
+
+// NewSeamlessWalletHandler constructs the handler.
+func NewSeamlessWalletHandler(service *seamless.Service) *SeamlessWalletHandler {
+	return &SeamlessWalletHandler{service: service}
+}
+
+// Register attaches the public callback route. Providers authenticate by
+// signature, which the service verifies.
+func (h *SeamlessWalletHandler) Register(mux Router) {
+	mux.HandleFunc("POST /providers/{provider}/callbacks", h.handleCallback)
+}
+
+func (h *SeamlessWalletHandler) handleCallback(w http.ResponseWriter, r *http.Request) {
+	payload, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxCallbackBytes))
+	if err != nil {
+		respond.Error(w, http.StatusRequestEntityTooLarge, "callback body too large")
+		return
+	}
+	provider := r.PathValue("provider")
+	result, err := h.service.Receive(r.Context(), provider, r.Header, payload)
+	switch {
+	case err == nil:
+		respond.JSON(w, http.StatusOK, "callback processed", result)
+	case errors.Is(err, integrations.ErrUnknownProvider):
+		respond.Error(w, http.StatusNotFound, "unknown provider")
+	case errors.Is(err, integrations.ErrUnverified):
+		log.Printf("reject %s wallet callback: %v", provider, err)
+		respond.Error(w, http.StatusUnauthorized, "callback could not be verified")
+	case errors.Is(err, seamless.ErrInvalidRequest):
+		respond.Error(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, integrations.ErrSessionRejected):
+		respond.Error(w, http.StatusForbidden, err.Error())
+	case errors.Is(err, seamless.ErrRolledBack):
+		respond.Error(w, http.StatusConflict, "transaction_id was rolled back before the debit arrived")
+	case errors.Is(err, storage.ErrInsufficientFunds):
+		respond.Error(w, http.StatusConflict, "the balance is too low for this bet")
+	case errors.Is(err, wallet.ErrOperationConflict):
+		respond.Error(w, http.StatusConflict, "transaction_id was already used for a different amount")
+	default:
+		log.Printf("process %s wallet callback: %v", provider, err)
+		respond.Error(w, http.StatusInternalServerError, "failed to process callback")
+	}
+}
@@ -0,0 +1,406 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"html/template"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/hongminglow/all-in-be/internal/accounts"
+	"github.com/hongminglow/all-in-be/internal/challenge"
+	"github.com/hongminglow/all-in-be/internal/http/respond"
+	"github.com/hongminglow/all-in-be/internal/middleware"
+	"github.com/hongminglow/all-in-be/internal/models"
+	"github.com/hongminglow/all-in-be/internal/models/dto"
+	"github.com/hongminglow/all-in-be/internal/security"
+	"github.com/hongminglow/all-in-be/internal/storage"
+)
+
+const maxSecurityCases = 100
+
+const (
+	defaultLoginLimit = 50
+	maxLoginLimit     = 200
+)
+
+// alertPage is the HTML shown for login alert links, which are opened from an
+// email client rather than the app.
+var alertPage = template.Must(template.New("alert").Parse(`<!doctype html>
+<html><head><meta charset="utf-8"><title>ALL-IN account security</title></head>
+<body>
+<p>{{.Message}}</p>
+{{if .Action}}<form method="post"><button type="submit">{{.Action}}</button></form>{{end}}
+</body></html>
+`))
+
+type alertPageData struct {
+	Message string
+	Action  string
+}
+
+// LoginAlertHandler serves the approve/deny links sent in login alert emails
+// and the links that confirm a new device. GET shows a confirmation button and
+// only POST acts, so mail scanners that prefetch links cannot lock an account
+// or trust a device.
+type LoginAlertHandler struct {
+	service *security.Service
+}
+
+// NewLoginAlertHandler constructs the handler.
+func NewLoginAlertHandler(service *security.Service) *LoginAlertHandler {
+	return &LoginAlertHandler{service: service}
+}
+
+// Register attaches the public login alert and device confirmation routes.
+func (h *LoginAlertHandler) Register(mux Router) {
+	mux.HandleFunc("GET /login-alerts/{token}/approve", alertPrompt("Confirm that this sign-in was you.", "This was me"))
+	mux.HandleFunc("POST /login-alerts/{token}/approve", alertAction(h.service.Approve, "Thanks, this sign-in has been confirmed."))
+	mux.HandleFunc("GET /login-alerts/{token}/deny", alertPrompt("Report that this sign-in was not you. Every session will be signed out and you will need to choose a new password.", "This wasn't me"))
+	mux.HandleFunc("POST /login-alerts/{token}/deny", alertAction(h.service.Deny, "Your account has been secured. We have emailed you a link to choose a new password."))
+	mux.HandleFunc("GET /device-confirmations/{token}", alertPrompt("Confirm the new device you just tried to sign in from.", "Trust this device"))
+	mux.HandleFunc("POST /device-confirmations/{token}", alertAction(h.service.ConfirmDevice, "Thanks, the device is confirmed. You can sign in from it now."))
+}
+
+// alertPrompt renders the page asking the user to confirm action.
+func alertPrompt(prompt, action string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		renderAlertPage(w, http.StatusOK, alertPageData{Message: prompt, Action: action})
+	}
+}
+
+// alertAction carries out act for the link's token and renders the outcome.
+func alertAction(act func(ctx context.Context, token string) error, done string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		err := act(r.Context(), r.PathValue("token"))
+		switch {
+		case err == nil:
+			renderAlertPage(w, http.StatusOK, alertPageData{Message: done})
+		case errors.Is(err, security.ErrInvalidToken):
+			renderAlertPage(w, http.StatusNotFound, alertPageData{Message: "This link is invalid."})
+		case errors.Is(err, security.ErrAlertResolved):
+			renderAlertPage(w, http.StatusConflict, alertPageData{Message: "This sign-in has already been reviewed."})
+		default:
+			log.Printf("login alert %s: %v", r.URL.Path, err)
+			renderAlertPage(w, http.StatusInternalServerError, alertPageData{Message: "Something went wrong. Please try again."})
+		}
+	}
+}
+
+func renderAlertPage(w http.ResponseWriter, status int, data alertPageData) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	if err := alertPage.Execute(w, data); err != nil {
+		log.Printf("render login alert page: %v", err)
+	}
+}
+
+// PasswordResetHandler lets users request a reset link and choose a new password.
+type PasswordResetHandler struct {
+	service *security.Service
+}
+
+// NewPasswordResetHandler constructs the handler.
+func NewPasswordResetHandler(service *security.Service) *PasswordResetHandler {
+	return &PasswordResetHandler{service: service}
+}
+
+// Register attaches the password reset routes.
+func (h *PasswordResetHandler) Register(mux Router) {
+	mux.HandleFunc("POST /password/forgot", h.handleForgot)
+	mux.HandleFunc("POST /password/reset", h.handleReset)
+}
+
+func (h *PasswordResetHandler) handleForgot(w http.ResponseWriter, r *http.Request) {
+	var req dto.ForgotPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respond.Error(w, http.StatusBadRequest, "invalid JSON payload")
+		return
+	}
+	if strings.TrimSpace(req.Identifier) == "" {
+		respond.Error(w, http.StatusBadRequest, "identifier is required")
+		return
+	}
+	if err := h.service.RequestPasswordReset(r.Context(), strings.TrimSpace(req.Identifier)); err != nil {
+		log.Printf("password reset request: %v", err)
+		respond.Error(w, http.StatusInternalServerError, "failed to request password reset")
+		return
+	}
+	respond.JSON(w, http.StatusOK, "if the account exists, a reset link has been sent", nil)
+}
+
+func (h *PasswordResetHandler) handleReset(w http.ResponseWriter, r *http.Request) {
+	var req dto.ResetPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respond.Error(w, http.StatusBadRequest, "invalid JSON payload")
+		return
+	}
+	if strings.TrimSpace(req.Token) == "" {
+		respond.Error(w, http.StatusBadRequest, "token is required")
+		return
+	}
+	if err := accounts.ValidatePassword(req.Password); err != nil {
+		respond.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	passwordHash, err := accounts.HashPassword(req.Password)
+	if err != nil {
+		respond.Error(w, http.StatusInternalServerError, "failed to hash password")
+		return
+	}
+	if err := h.service.ResetPassword(r.Context(), strings.TrimSpace(req.Token), passwordHash); err != nil {
+		if errors.Is(err, security.ErrInvalidToken) {
+			respond.Error(w, http.StatusBadRequest, "invalid or expired token")
+			return
+		}
+		log.Printf("password reset: %v", err)
+		respond.Error(w, http.StatusInternalServerError, "failed to reset password")
+		return
+	}
+	respond.JSON(w, http.StatusOK, "password updated", nil)
+}
+
+// PasswordChangeHandler lets signed-in users choose a new password. From a new
+// device the change may also require a step-up challenge.
+type PasswordChangeHandler struct {
+	service    *security.Service
+	challenges *challenge.Service
+}
+
+// NewPasswordChangeHandler constructs the handler. challenges may be nil.
+func NewPasswordChangeHandler(service *security.Service, challenges *challenge.Service) *PasswordChangeHandler {
+	return &PasswordChangeHandler{service: service, challenges: challenges}
+}
+
+// Register attaches the route. It must be mounted behind middleware.Authenticate
+// and middleware.LoadUser.
+func (h *PasswordChangeHandler) Register(mux Router) {
+	mux.Handle("PUT /me/password", middleware.RefuseImpersonation(http.HandlerFunc(h.handle)))
+}
+
+func (h *PasswordChangeHandler) handle(w http.ResponseWriter, r *http.Request) {
+	user, ok := middleware.UserFromContext(r.Context())
+	if !ok {
+		respond.Error(w, http.StatusUnauthorized, "authentication required")
+		return
+	}
+	var req dto.ChangePasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respond.Error(w, http.StatusBadRequest, "invalid JSON payload")
+		return
+	}
+	if bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.CurrentPassword)) != nil {
+		respond.Error(w, http.StatusForbidden, "current password is incorrect")
+		return
+	}
+	if err := accounts.ValidatePassword(req.NewPassword); err != nil {
+		respond.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if !requireChallenge(w, r, h.challenges, user, challenge.Request{Action: models.ActionPasswordChange}) {
+		return
+	}
+	passwordHash, err := accounts.HashPassword(req.NewPassword)
+	if err != nil {
+		respond.Error(w, http.StatusInternalServerError, "failed to hash password")
+		return
+	}
+	if err := h.service.ChangePassword(r.Context(), user.ID, passwordHash); err != nil {
+		log.Printf("change password for user %d: %v", user.ID, err)
+		respond.Error(w, http.StatusInternalServerError, "failed to change password")
+		return
+	}
+	respond.JSON(w, http.StatusOK, "password changed; sign in again", nil)
+}
+
+// SecurityCaseHandler lists security cases for staff.
+type SecurityCaseHandler struct {
+	store storage.SecurityStore
+}
+
+// NewSecurityCaseHandler constructs the handler.
+func NewSecurityCaseHandler(store storage.SecurityStore) *SecurityCaseHandler {
+	return &SecurityCaseHandler{store: store}
+}
+
+// Register attaches the admin route. It must be mounted behind middleware.Authenticate.
+func (h *SecurityCaseHandler) Register(mux Router) {
+	mux.Handle("GET /admin/security-cases", middleware.RequirePermission(models.PermSecurityRead, http.HandlerFunc(h.handleList)))
+}
+
+func (h *SecurityCaseHandler) handleList(w http.ResponseWriter, r *http.Request) {
+	cases, err := h.store.ListSecurityCases(r.Context(), maxSecurityCases)
+	if err != nil {
+		log.Printf("list security cases: %v", err)
+		respond.Error(w, http.StatusInternalServerError, "failed to list security cases")
+		return
+	}
+	respond.JSON(w, http.StatusOK, "security cases fetched", cases)
+}
+
+// ForcedResetHandler lets support staff lock an account they believe is
+// compromised until its owner resets the password. Callers without
+// roles:manage may only lock players' accounts.
+type ForcedResetHandler struct {
+	users   storage.UserStore
+	service *security.Service
+}
+
+// NewForcedResetHandler constructs the handler.
+func NewForcedResetHandler(users storage.UserStore, service *security.Service) *ForcedResetHandler {
+	return &ForcedResetHandler{users: users, service: service}
+}
+
+// Register attaches the admin route. It must be mounted behind middleware.Authenticate.
+func (h *ForcedResetHandler) Register(mux Router) {
+	mux.Handle("POST /admin/users/{id}/force-password-reset", middleware.RequirePermission(models.PermUsersLock, http.HandlerFunc(h.handleForce)))
+}
+
+func (h *ForcedResetHandler) handleForce(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+	target, err := h.users.FindByID(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			respond.Error(w, http.StatusNotFound, "user not found")
+			return
+		}
+		log.Printf("find user error: %v", err)
+		respond.Error(w, http.StatusInternalServerError, "failed to fetch user")
+		return
+	}
+	if !slices.Contains(models.PlayerRoles, target.Role) && !middleware.HasPermission(r.Context(), models.PermRolesManage) {
+		respond.Error(w, http.StatusForbidden, "only players' accounts can be locked without "+models.PermRolesManage)
+		return
+	}
+	opened, err := h.service.ForcePasswordReset(r.Context(), id)
+	if err != nil {
+		log.Printf("force password reset for user %d: %v", id, err)
+		respond.Error(w, http.StatusInternalServerError, "failed to force password reset")
+		return
+	}
+	respond.JSON(w, http.StatusOK, "password reset forced", opened)
+}
+
+// LoginHistoryHandler shows sign-in attempts: users see their own, and
+// support staff can search everyone's while investigating an incident.
+type LoginHistoryHandler struct {
+	store storage.SecurityStore
+}
+
+// NewLoginHistoryHandler constructs the handler.
+func NewLoginHistoryHandler(store storage.SecurityStore) *LoginHistoryHandler {
+	return &LoginHistoryHandler{store: store}
+}
+
+// Register attaches the routes. They must be mounted behind middleware.Authenticate.
+func (h *LoginHistoryHandler) Register(mux Router) {
+	mux.HandleFunc("GET /me/logins", h.handleMine)
+	mux.Handle("GET /admin/logins", middleware.RequirePermission(models.PermSecurityRead, http.HandlerFunc(h.handleSearch)))
+}
+
+func (h *LoginHistoryHandler) handleMine(w http.ResponseWriter, r *http.Request) {
+	user, ok := middleware.UserFromContext(r.Context())
+	if !ok {
+		respond.Error(w, http.StatusUnauthorized, "authentication required")
+		return
+	}
+	limit, ok := loginLimit(w, r)
+	if !ok {
+		return
+	}
+	h.list(w, r, models.LoginAttemptFilter{UserID: user.ID}, limit)
+}
+
+func (h *LoginHistoryHandler) handleSearch(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	filter := models.LoginAttemptFilter{IP: q.Get("ip"), IncludeArchived: true}
+	if raw := q.Get("user_id"); raw != "" {
+		id, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil || id <= 0 {
+			respond.Error(w, http.StatusBadRequest, "invalid user_id")
+			return
+		}
+		filter.UserID = id
+	}
+	if raw := q.Get("success"); raw != "" {
+		success, err := strconv.ParseBool(raw)
+		if err != nil {
+			respond.Error(w, http.StatusBadRequest, "success must be true or false")
+			return
+		}
+		filter.Success = &success
+	}
+	limit, ok := loginLimit(w, r)
+	if !ok {
+		return
+	}
+	h.list(w, r, filter, limit)
+}
+
+func (h *LoginHistoryHandler) list(w http.ResponseWriter, r *http.Request, filter models.LoginAttemptFilter, limit int) {
+	attempts, err := h.store.ListLoginAttempts(r.Context(), filter, limit)
+	if err != nil {
+		log.Printf("list login attempts: %v", err)
+		respond.Error(w, http.StatusInternalServerError, "failed to list login history")
+		return
+	}
+	respond.JSON(w, http.StatusOK, "login history fetched", attempts)
+}
+
+// loginLimit parses the optional limit query parameter, writing a 400 when it is out of range.
+func loginLimit(w http.ResponseWriter, r *http.Request) (int, bool) {
+	raw := r.URL.Query().Get("limit")
+	if raw == "" {
+		return defaultLoginLimit, true
+	}
+	limit, err := strconv.Atoi(raw)
+	if err != nil || limit < 1 || limit > maxLoginLimit {
+		respond.Error(w, http.StatusBadRequest, "limit must be between 1 and 200")
+		return 0, false
+	}
+	return limit, true
+}
+
+// SecurityCenterHandler serves the account security overview on the settings page.
+type SecurityCenterHandler struct {
+	service    *security.Service
+	sessionTTL time.Duration
+}
+
+// NewSecurityCenterHandler constructs the handler. sessionTTL is the lifetime
+// of the tokens issued at sign-in.
+func NewSecurityCenterHandler(service *security.Service, sessionTTL time.Duration) *SecurityCenterHandler {
+	return &SecurityCenterHandler{service: service, sessionTTL: sessionTTL}
+}
+
+// Register attaches the route. It must be mounted behind middleware.Authenticate
+// and middleware.LoadUser.
+func (h *SecurityCenterHandler) Register(mux Router) {
+	mux.HandleFunc("GET /me/security", h.handle)
+}
+
+func (h *SecurityCenterHandler) handle(w http.ResponseWriter, r *http.Request) {
+	user, ok := middleware.UserFromContext(r.Context())
+	if !ok {
+		respond.Error(w, http.StatusUnauthorized, "authentication required")
+		return
+	}
+	overview, err := h.service.Overview(r.Context(), user, h.sessionTTL)
+	if err != nil {
+		log.Printf("security overview for user %d: %v", user.ID, err)
+		respond.Error(w, http.StatusInternalServerError, "failed to load security overview")
+		return
+	}
+	respond.JSON(w, http.StatusOK, "security overview fetched", overview)
+}
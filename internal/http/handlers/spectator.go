@@ -0,0 +1,165 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/hongminglow/all-in-be/internal/http/respond"
+	"github.com/hongminglow/all-in-be/internal/middleware"
+	"github.com/hongminglow/all-in-be/internal/models"
+	"github.com/hongminglow/all-in-be/internal/models/dto"
+	"github.com/hongminglow/all-in-be/internal/money"
+	"github.com/hongminglow/all-in-be/internal/storage"
+)
+
+// bigWinsShown is how many wins the public feed lists. It is fixed so every
+// caller shares one cached page.
+const bigWinsShown = 20
+
+// SpectatorHandler serves public activity feeds that marketing sites embed
+// without API credentials. Results are cached briefly, in process and by
+// browsers and CDNs, so widget traffic doesn't reach Postgres.
+type SpectatorHandler struct {
+	store     storage.SpectatorStore
+	minAmount float64
+	ttl       time.Duration
+
+	mu        sync.Mutex
+	wins      []models.BigWin
+	fetchedAt time.Time
+}
+
+// NewSpectatorHandler constructs the handler. Wins below minAmount are not
+// shown and a zero ttl disables caching.
+func NewSpectatorHandler(store storage.SpectatorStore, minAmount float64, ttl time.Duration) *SpectatorHandler {
+	return &SpectatorHandler{store: store, minAmount: minAmount, ttl: ttl}
+}
+
+// Invalidate drops the cached results so the next request reloads them.
+func (h *SpectatorHandler) Invalidate() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.wins, h.fetchedAt = nil, time.Time{}
+}
+
+// Register attaches the public routes.
+func (h *SpectatorHandler) Register(mux Router) {
+	mux.HandleFunc("GET /public/big-wins", h.handleBigWins)
+}
+
+func (h *SpectatorHandler) handleBigWins(w http.ResponseWriter, r *http.Request) {
+	wins, ok := h.cached()
+	if !ok {
+		var err error
+		if wins, err = h.store.RecentBigWins(r.Context(), h.minAmount, bigWinsShown); err != nil {
+			log.Printf("list big wins: %v", err)
+			respond.Error(w, http.StatusInternalServerError, "failed to list big wins")
+			return
+		}
+		for i := range wins {
+			wins[i].Player = displayName(wins[i].Username, wins[i].PublicActivity)
+		}
+		h.mu.Lock()
+		h.wins, h.fetchedAt = wins, time.Now()
+		h.mu.Unlock()
+	}
+	// The cached wins are shared, so they are copied before being formatted.
+	wins = slices.Clone(wins)
+	format := money.FormatFromContext(r.Context())
+	for i := range wins {
+		wins[i].AmountMoney = money.Amount(format, wins[i].Amount)
+	}
+	w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(h.ttl.Seconds())))
+	respond.JSON(w, http.StatusOK, "big wins fetched", wins)
+}
+
+func (h *SpectatorHandler) cached() ([]models.BigWin, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.wins == nil || time.Since(h.fetchedAt) > h.ttl {
+		return nil, false
+	}
+	return h.wins, true
+}
+
+// displayName shows the username of players who opted into public activity
+// and masks everyone else's to its first and last letters, e.g. "a***e".
+func displayName(username, activity string) string {
+	if activity == models.ActivityPublic {
+		return username
+	}
+	first, _ := utf8.DecodeRuneInString(username)
+	if utf8.RuneCountInString(username) < 4 {
+		return string(first) + "***"
+	}
+	last, _ := utf8.DecodeLastRuneInString(username)
+	return string(first) + "***" + string(last)
+}
+
+// PrivacyHandler lets users choose how they appear in public activity feeds.
+type PrivacyHandler struct {
+	store storage.SpectatorStore
+}
+
+// NewPrivacyHandler constructs the handler.
+func NewPrivacyHandler(store storage.SpectatorStore) *PrivacyHandler {
+	return &PrivacyHandler{store: store}
+}
+
+// Register attaches the /me/privacy route. It must be mounted behind middleware.Authenticate.
+func (h *PrivacyHandler) Register(mux Router) {
+	mux.HandleFunc("GET /me/privacy", h.handleGet)
+	mux.HandleFunc("PUT /me/privacy", h.handleSave)
+}
+
+func (h *PrivacyHandler) handleGet(w http.ResponseWriter, r *http.Request) {
+	user, ok := middleware.UserFromContext(r.Context())
+	if !ok {
+		respond.Error(w, http.StatusUnauthorized, "authentication required")
+		return
+	}
+	settings, err := h.store.FindPrivacySettings(r.Context(), user.ID)
+	if err != nil {
+		log.Printf("find privacy settings for user %d: %v", user.ID, err)
+		respond.Error(w, http.StatusInternalServerError, "failed to fetch privacy settings")
+		return
+	}
+	respond.JSON(w, http.StatusOK, "privacy settings fetched", settings)
+}
+
+func (h *PrivacyHandler) handleSave(w http.ResponseWriter, r *http.Request) {
+	user, ok := middleware.UserFromContext(r.Context())
+	if !ok {
+		respond.Error(w, http.StatusUnauthorized, "authentication required")
+		return
+	}
+	var req dto.UpdatePrivacyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respond.Error(w, http.StatusBadRequest, "invalid JSON payload")
+		return
+	}
+	switch req.PublicActivity {
+	case models.ActivityMasked, models.ActivityPublic, models.ActivityHidden:
+	default:
+		respond.Error(w, http.StatusBadRequest, "public_activity must be masked, public or hidden")
+		return
+	}
+	settings, err := h.store.SavePrivacySettings(r.Context(), models.PrivacySettings{UserID: user.ID, PublicActivity: req.PublicActivity})
+	if errors.Is(err, storage.ErrNotFound) {
+		respond.Error(w, http.StatusUnauthorized, "authentication required")
+		return
+	}
+	if err != nil {
+		log.Printf("save privacy settings for user %d: %v", user.ID, err)
+		respond.Error(w, http.StatusInternalServerError, "failed to save privacy settings")
+		return
+	}
+	respond.JSON(w, http.StatusOK, "privacy settings saved", settings)
+}
@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/hongminglow/all-in-be/internal/http/respond"
+	"github.com/hongminglow/all-in-be/internal/middleware"
+	"github.com/hongminglow/all-in-be/internal/models"
+	"github.com/hongminglow/all-in-be/internal/storage"
+)
+
+const (
+	defaultStatsDays = 30
+	maxStatsDays     = 90
+)
+
+// StatsHandler serves aggregate metrics for the operator dashboard, caching results
+// briefly so dashboards polling in parallel don't each hit Postgres.
+type StatsHandler struct {
+	store storage.StatsStore
+	ttl   time.Duration
+
+	mu    sync.Mutex
+	cache map[int]models.AdminStats
+}
+
+// NewStatsHandler constructs the handler. A zero ttl disables caching.
+func NewStatsHandler(store storage.StatsStore, ttl time.Duration) *StatsHandler {
+	return &StatsHandler{store: store, ttl: ttl, cache: make(map[int]models.AdminStats)}
+}
+
+// Invalidate drops the cached results so the next request reloads them.
+func (h *StatsHandler) Invalidate() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	clear(h.cache)
+}
+
+// Register attaches the admin stats route. It must be mounted behind middleware.Authenticate.
+func (h *StatsHandler) Register(mux Router) {
+	mux.Handle("GET /admin/stats", middleware.RequirePermission(models.PermStatsRead, http.HandlerFunc(h.handle)))
+}
+
+func (h *StatsHandler) handle(w http.ResponseWriter, r *http.Request) {
+	days := defaultStatsDays
+	if raw := r.URL.Query().Get("days"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed < 1 || parsed > maxStatsDays {
+			respond.Error(w, http.StatusBadRequest, "days must be between 1 and 90")
+			return
+		}
+		days = parsed
+	}
+
+	if stats, ok := h.cached(days); ok {
+		respond.JSON(w, http.StatusOK, "stats fetched", stats)
+		return
+	}
+	stats, err := h.store.AdminStats(r.Context(), days)
+	if err != nil {
+		log.Printf("admin stats error: %v", err)
+		respond.Error(w, http.StatusInternalServerError, "failed to compute stats")
+		return
+	}
+	h.mu.Lock()
+	h.cache[days] = stats
+	h.mu.Unlock()
+	respond.JSON(w, http.StatusOK, "stats fetched", stats)
+}
+
+func (h *StatsHandler) cached(days int) (models.AdminStats, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	stats, ok := h.cache[days]
+	if !ok || time.Since(stats.GeneratedAt) > h.ttl {
+		return models.AdminStats{}, false
+	}
+	return stats, true
+}
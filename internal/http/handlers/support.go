@@ -0,0 +1,245 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/hongminglow/all-in-be/internal/http/respond"
+	"github.com/hongminglow/all-in-be/internal/middleware"
+	"github.com/hongminglow/all-in-be/internal/models"
+	"github.com/hongminglow/all-in-be/internal/models/dto"
+	"github.com/hongminglow/all-in-be/internal/storage"
+	"github.com/hongminglow/all-in-be/internal/support"
+)
+
+const (
+	defaultTicketLimit = 50
+	maxTicketLimit     = 100
+)
+
+// SupportHandler serves players their support tickets and staff the queue.
+type SupportHandler struct {
+	support *support.Service
+}
+
+// NewSupportHandler constructs the handler.
+func NewSupportHandler(service *support.Service) *SupportHandler {
+	return &SupportHandler{support: service}
+}
+
+// Register attaches the routes. They must be mounted behind middleware.Authenticate.
+func (h *SupportHandler) Register(mux Router) {
+	mux.Handle("POST /support/tickets", middleware.RefuseImpersonation(http.HandlerFunc(h.handleOpen)))
+	mux.HandleFunc("GET /support/tickets", h.handleMine)
+	mux.HandleFunc("GET /support/tickets/{id}", h.handleThread(false))
+	mux.Handle("POST /support/tickets/{id}/replies", middleware.RefuseImpersonation(h.handleReply(false)))
+	mux.Handle("POST /support/tickets/{id}/close", middleware.RefuseImpersonation(http.HandlerFunc(h.handleClose)))
+	mux.Handle("POST /support/tickets/{id}/attachments", middleware.RefuseImpersonation(h.handleAttach(false)))
+
+	mux.Handle("GET /admin/support/tickets", middleware.RequirePermission(models.PermSupportManage, http.HandlerFunc(h.handleQueue)))
+	mux.Handle("GET /admin/support/tickets/{id}", middleware.RequirePermission(models.PermSupportManage, h.handleThread(true)))
+	mux.Handle("POST /admin/support/tickets/{id}/replies", middleware.RequirePermission(models.PermSupportManage, h.handleReply(true)))
+	mux.Handle("PATCH /admin/support/tickets/{id}", middleware.RequirePermission(models.PermSupportManage, http.HandlerFunc(h.handleSetStatus)))
+	mux.Handle("POST /admin/support/tickets/{id}/attachments", middleware.RequirePermission(models.PermSupportManage, h.handleAttach(true)))
+}
+
+func (h *SupportHandler) handleOpen(w http.ResponseWriter, r *http.Request) {
+	user, ok := middleware.UserFromContext(r.Context())
+	if !ok {
+		respond.Error(w, http.StatusUnauthorized, "authentication required")
+		return
+	}
+	var req dto.OpenTicketRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respond.Error(w, http.StatusBadRequest, "invalid JSON payload")
+		return
+	}
+	thread, err := h.support.Open(r.Context(), user, req.Subject, req.Body)
+	switch {
+	case errors.Is(err, support.ErrInvalidTicket):
+		respond.Error(w, http.StatusBadRequest, err.Error())
+	case err != nil:
+		log.Printf("open ticket for user %d: %v", user.ID, err)
+		respond.Error(w, http.StatusInternalServerError, "failed to open ticket")
+	default:
+		respond.Created(w, "/support/tickets/"+strconv.FormatInt(thread.Ticket.ID, 10), "ticket opened", thread)
+	}
+}
+
+func (h *SupportHandler) handleMine(w http.ResponseWriter, r *http.Request) {
+	user, ok := middleware.UserFromContext(r.Context())
+	if !ok {
+		respond.Error(w, http.StatusUnauthorized, "authentication required")
+		return
+	}
+	limit, ok := ticketLimit(w, r)
+	if !ok {
+		return
+	}
+	tickets, err := h.support.Mine(r.Context(), user.ID, limit)
+	if err != nil {
+		log.Printf("list tickets of user %d: %v", user.ID, err)
+		respond.Error(w, http.StatusInternalServerError, "failed to list tickets")
+		return
+	}
+	respond.JSON(w, http.StatusOK, "tickets fetched", tickets)
+}
+
+// handleQueue lists the tickets staff should work on, optionally only
+// those in ?status=.
+func (h *SupportHandler) handleQueue(w http.ResponseWriter, r *http.Request) {
+	limit, ok := ticketLimit(w, r)
+	if !ok {
+		return
+	}
+	tickets, err := h.support.Queue(r.Context(), r.URL.Query().Get("status"), limit)
+	switch {
+	case errors.Is(err, support.ErrInvalidTicket):
+		respond.Error(w, http.StatusBadRequest, err.Error())
+	case err != nil:
+		log.Printf("list ticket queue: %v", err)
+		respond.Error(w, http.StatusInternalServerError, "failed to list tickets")
+	default:
+		respond.JSON(w, http.StatusOK, "tickets fetched", tickets)
+	}
+}
+
+// handleThread serves a ticket's thread; players only see their own tickets.
+func (h *SupportHandler) handleThread(staff bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, ok := middleware.UserFromContext(r.Context())
+		if !ok {
+			respond.Error(w, http.StatusUnauthorized, "authentication required")
+			return
+		}
+		id, ok := pathID(w, r, "id")
+		if !ok {
+			return
+		}
+		thread, err := h.support.Thread(r.Context(), id, user.ID, staff)
+		switch {
+		case errors.Is(err, storage.ErrNotFound):
+			respond.Error(w, http.StatusNotFound, "ticket not found")
+		case err != nil:
+			log.Printf("load ticket %d: %v", id, err)
+			respond.Error(w, http.StatusInternalServerError, "failed to load ticket")
+		default:
+			respond.JSON(w, http.StatusOK, "ticket fetched", thread)
+		}
+	}
+}
+
+func (h *SupportHandler) handleReply(staff bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, ok := middleware.UserFromContext(r.Context())
+		if !ok {
+			respond.Error(w, http.StatusUnauthorized, "authentication required")
+			return
+		}
+		id, ok := pathID(w, r, "id")
+		if !ok {
+			return
+		}
+		var req dto.TicketReplyRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			respond.Error(w, http.StatusBadRequest, "invalid JSON payload")
+			return
+		}
+		reply, err := h.support.Reply(r.Context(), id, user, staff, req.Body)
+		if err != nil {
+			writeSupportError(w, id, "reply to", err)
+			return
+		}
+		respond.Created(w, "", "reply added", reply)
+	}
+}
+
+func (h *SupportHandler) handleClose(w http.ResponseWriter, r *http.Request) {
+	h.setStatus(w, r, false, models.TicketClosed)
+}
+
+func (h *SupportHandler) handleSetStatus(w http.ResponseWriter, r *http.Request) {
+	var req dto.SetTicketStatusRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respond.Error(w, http.StatusBadRequest, "invalid JSON payload")
+		return
+	}
+	h.setStatus(w, r, true, req.Status)
+}
+
+func (h *SupportHandler) setStatus(w http.ResponseWriter, r *http.Request, staff bool, status string) {
+	user, ok := middleware.UserFromContext(r.Context())
+	if !ok {
+		respond.Error(w, http.StatusUnauthorized, "authentication required")
+		return
+	}
+	id, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+	ticket, err := h.support.SetStatus(r.Context(), id, user.ID, staff, status)
+	if err != nil {
+		writeSupportError(w, id, "update", err)
+		return
+	}
+	respond.JSON(w, http.StatusOK, "ticket updated", ticket)
+}
+
+// handleAttach stores the raw request body as an attachment named by
+// ?name=, with the request's Content-Type.
+func (h *SupportHandler) handleAttach(staff bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, ok := middleware.UserFromContext(r.Context())
+		if !ok {
+			respond.Error(w, http.StatusUnauthorized, "authentication required")
+			return
+		}
+		id, ok := pathID(w, r, "id")
+		if !ok {
+			return
+		}
+		a, err := h.support.Attach(r.Context(), id, user.ID, staff, r.URL.Query().Get("name"), r.Header.Get("Content-Type"), r.Body)
+		switch {
+		case errors.Is(err, support.ErrAttachmentTooLarge):
+			respond.Error(w, http.StatusRequestEntityTooLarge, "attachment too large")
+		case errors.Is(err, support.ErrUnsupportedType):
+			respond.Error(w, http.StatusUnsupportedMediaType, "attachment must be a PNG, JPEG, GIF, PDF or plain text file")
+		case err != nil:
+			writeSupportError(w, id, "attach to", err)
+		default:
+			respond.Created(w, "", "attachment added", a)
+		}
+	}
+}
+
+func writeSupportError(w http.ResponseWriter, id int64, action string, err error) {
+	switch {
+	case errors.Is(err, support.ErrInvalidTicket):
+		respond.Error(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, storage.ErrNotFound):
+		respond.Error(w, http.StatusNotFound, "ticket not found")
+	case errors.Is(err, support.ErrTicketClosed):
+		respond.Error(w, http.StatusConflict, "ticket is closed")
+	case errors.Is(err, support.ErrInvalidTransition):
+		respond.Error(w, http.StatusConflict, "ticket status change not allowed")
+	default:
+		log.Printf("%s ticket %d: %v", action, id, err)
+		respond.Error(w, http.StatusInternalServerError, "failed to update ticket")
+	}
+}
+
+func ticketLimit(w http.ResponseWriter, r *http.Request) (int, bool) {
+	limit := defaultTicketLimit
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed < 1 || parsed > maxTicketLimit {
+			respond.Error(w, http.StatusBadRequest, "limit must be between 1 and 100")
+			return 0, false
+		}
+		limit = parsed
+	}
+	return limit, true
+}
@@ -0,0 +1,171 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/hongminglow/all-in-be/internal/http/respond"
+	"github.com/hongminglow/all-in-be/internal/limits"
+	"github.com/hongminglow/all-in-be/internal/middleware"
+	"github.com/hongminglow/all-in-be/internal/models"
+	"github.com/hongminglow/all-in-be/internal/models/dto"
+	"github.com/hongminglow/all-in-be/internal/storage"
+	"github.com/hongminglow/all-in-be/internal/tournaments"
+)
+
+const (
+	defaultTournamentLimit = 50
+	maxTournamentLimit     = 200
+)
+
+// TournamentHandler lists tournaments and their standings, lets players enter,
+// lets game servers report scores and lets admins schedule new ones.
+type TournamentHandler struct {
+	tournaments *tournaments.Service
+}
+
+// NewTournamentHandler constructs the handler.
+func NewTournamentHandler(service *tournaments.Service) *TournamentHandler {
+	return &TournamentHandler{tournaments: service}
+}
+
+// Register attaches the routes. They must be mounted behind middleware.Authenticate.
+func (h *TournamentHandler) Register(mux Router) {
+	mux.HandleFunc("GET /tournaments", h.handleList)
+	mux.HandleFunc("GET /tournaments/{id}", h.handleStandings)
+	mux.Handle("POST /tournaments/{id}/entries", middleware.RequirePermission(models.PermGamePlay, middleware.RefuseImpersonation(http.HandlerFunc(h.handleEnter))))
+	mux.Handle("POST /admin/tournaments", middleware.RequirePermission(models.PermConfigManage, http.HandlerFunc(h.handleCreate)))
+	mux.Handle("POST /admin/tournaments/{id}/scores", middleware.RequirePermission(models.PermTournamentsScore, http.HandlerFunc(h.handleScore)))
+}
+
+func (h *TournamentHandler) handleList(w http.ResponseWriter, r *http.Request) {
+	limit := defaultTournamentLimit
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed < 1 || parsed > maxTournamentLimit {
+			respond.Error(w, http.StatusBadRequest, "limit must be between 1 and 200")
+			return
+		}
+		limit = parsed
+	}
+	list, err := h.tournaments.List(r.Context(), limit)
+	if err != nil {
+		log.Printf("list tournaments: %v", err)
+		respond.Error(w, http.StatusInternalServerError, "failed to list tournaments")
+		return
+	}
+	respond.JSON(w, http.StatusOK, "tournaments fetched", list)
+}
+
+func (h *TournamentHandler) handleStandings(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+	standings, err := h.tournaments.Standings(r.Context(), id)
+	switch {
+	case errors.Is(err, storage.ErrNotFound):
+		respond.Error(w, http.StatusNotFound, "tournament not found")
+	case err != nil:
+		log.Printf("standings of tournament %d: %v", id, err)
+		respond.Error(w, http.StatusInternalServerError, "failed to fetch standings")
+	default:
+		respond.JSON(w, http.StatusOK, "standings fetched", standings)
+	}
+}
+
+func (h *TournamentHandler) handleEnter(w http.ResponseWriter, r *http.Request) {
+	user, ok := middleware.UserFromContext(r.Context())
+	if !ok {
+		respond.Error(w, http.StatusUnauthorized, "authentication required")
+		return
+	}
+	id, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+	entry, err := h.tournaments.Enter(r.Context(), user.ID, id)
+	switch {
+	case errors.Is(err, storage.ErrNotFound):
+		respond.Error(w, http.StatusNotFound, "tournament not found")
+	case errors.Is(err, tournaments.ErrEnded):
+		respond.Error(w, http.StatusConflict, "the tournament has ended")
+	case errors.Is(err, storage.ErrAlreadyExists):
+		respond.Error(w, http.StatusConflict, "you have already entered this tournament")
+	case errors.Is(err, storage.ErrInsufficientFunds):
+		respond.Error(w, http.StatusConflict, "the balance is too low for the entry fee")
+	case errors.Is(err, limits.ErrExcluded):
+		respond.Error(w, http.StatusForbidden, "tournaments are closed during a self-exclusion")
+	case err != nil:
+		log.Printf("enter user %d in tournament %d: %v", user.ID, id, err)
+		respond.Error(w, http.StatusInternalServerError, "failed to enter tournament")
+	default:
+		respond.Created(w, "", "tournament entered", entry)
+	}
+}
+
+// handleScore records a score reported by a trusted source, such as a game
+// server's API key. Players never report their own scores, since the scores
+// decide who is paid the prize pool.
+func (h *TournamentHandler) handleScore(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+	var req dto.TournamentScoreRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respond.Error(w, http.StatusBadRequest, "invalid JSON payload")
+		return
+	}
+	if req.UserID <= 0 || req.Score == nil {
+		respond.Error(w, http.StatusBadRequest, "user_id and score are required")
+		return
+	}
+	entry, err := h.tournaments.SubmitScore(r.Context(), req.UserID, id, *req.Score)
+	switch {
+	case errors.Is(err, tournaments.ErrInvalidScore):
+		respond.Error(w, http.StatusBadRequest, "score must be a non-negative number")
+	case errors.Is(err, storage.ErrNotFound):
+		respond.Error(w, http.StatusNotFound, "tournament not found")
+	case errors.Is(err, tournaments.ErrNotEntered):
+		respond.Error(w, http.StatusConflict, "the user has not entered this tournament")
+	case errors.Is(err, tournaments.ErrNotStarted):
+		respond.Error(w, http.StatusConflict, "the tournament has not started")
+	case errors.Is(err, tournaments.ErrEnded):
+		respond.Error(w, http.StatusConflict, "the tournament has ended")
+	case err != nil:
+		log.Printf("record score of user %d in tournament %d: %v", req.UserID, id, err)
+		respond.Error(w, http.StatusInternalServerError, "failed to record score")
+	default:
+		respond.JSON(w, http.StatusOK, "score recorded", entry)
+	}
+}
+
+func (h *TournamentHandler) handleCreate(w http.ResponseWriter, r *http.Request) {
+	actor, _ := middleware.UserFromContext(r.Context())
+	var req dto.CreateTournamentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respond.Error(w, http.StatusBadRequest, "invalid JSON payload")
+		return
+	}
+	created, err := h.tournaments.Create(r.Context(), models.Tournament{
+		Name:        req.Name,
+		EntryFee:    req.EntryFee,
+		StartsAt:    req.StartsAt,
+		EndsAt:      req.EndsAt,
+		PrizeShares: req.PrizeShares,
+		CreatedBy:   actor.ID,
+	})
+	switch {
+	case errors.Is(err, tournaments.ErrInvalidTournament):
+		respond.Error(w, http.StatusBadRequest, err.Error())
+	case err != nil:
+		log.Printf("create tournament: %v", err)
+		respond.Error(w, http.StatusInternalServerError, "failed to create tournament")
+	default:
+		respond.Created(w, "/tournaments/"+strconv.FormatInt(created.ID, 10), "tournament created", created)
+	}
+}
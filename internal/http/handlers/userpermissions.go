@@ -0,0 +1,169 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"slices"
+
+	"github.com/hongminglow/all-in-be/internal/http/respond"
+	"github.com/hongminglow/all-in-be/internal/middleware"
+	"github.com/hongminglow/all-in-be/internal/models"
+	"github.com/hongminglow/all-in-be/internal/models/dto"
+	"github.com/hongminglow/all-in-be/internal/storage"
+)
+
+// UserPermissionHandler grants or revokes individual permissions for one user
+// on top of their role, e.g. bonus:claim for a single player.
+//
+// Callers without roles:manage may only change players' overrides, and only
+// for permissions some player role already grants, so support staff cannot
+// hand out staff permissions. Nobody may change their own overrides. A change
+// refuses the user's current tokens until they sign in again.
+type UserPermissionHandler struct {
+	store      storage.Repositories
+	invalidate func()
+}
+
+// NewUserPermissionHandler constructs the handler. invalidate is called after
+// every change so cached session state is reloaded.
+func NewUserPermissionHandler(store storage.Repositories, invalidate func()) *UserPermissionHandler {
+	return &UserPermissionHandler{store: store, invalidate: invalidate}
+}
+
+// Register attaches the admin routes. They must be mounted behind middleware.Authenticate.
+func (h *UserPermissionHandler) Register(mux Router) {
+	mux.Handle("GET /admin/users/{id}/permissions", middleware.RequirePermission(models.PermUserOverrides, http.HandlerFunc(h.handleList)))
+	mux.Handle("PUT /admin/users/{id}/permissions/{permissionID}", middleware.RequirePermission(models.PermUserOverrides, http.HandlerFunc(h.handleOverride)))
+	mux.Handle("DELETE /admin/users/{id}/permissions/{permissionID}", middleware.RequirePermission(models.PermUserOverrides, http.HandlerFunc(h.handleOverride)))
+}
+
+// handleList returns the user's effective permissions and the overrides behind them.
+func (h *UserPermissionHandler) handleList(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+	user, err := h.store.FindByID(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			respond.Error(w, http.StatusNotFound, "user not found")
+			return
+		}
+		log.Printf("find user error: %v", err)
+		respond.Error(w, http.StatusInternalServerError, "failed to fetch permissions")
+		return
+	}
+	overrides, err := h.store.ListPermissionOverrides(r.Context(), id)
+	if err != nil {
+		log.Printf("list permission overrides error: %v", err)
+		respond.Error(w, http.StatusInternalServerError, "failed to fetch permissions")
+		return
+	}
+	respond.JSON(w, http.StatusOK, "permissions fetched", dto.UserPermissionsResponse{
+		Role:        user.Role,
+		Permissions: user.Permissions,
+		Overrides:   overrides,
+	})
+}
+
+// handleOverride sets (PUT {"allow":true|false}) or clears (DELETE) one override.
+func (h *UserPermissionHandler) handleOverride(w http.ResponseWriter, r *http.Request) {
+	userID, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+	permissionID, ok := pathID(w, r, "permissionID")
+	if !ok {
+		return
+	}
+	var req dto.SetPermissionOverrideRequest
+	if r.Method == http.MethodPut {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			respond.Error(w, http.StatusBadRequest, "invalid JSON payload")
+			return
+		}
+		if req.Allow == nil {
+			respond.Error(w, http.StatusBadRequest, "allow is required")
+			return
+		}
+	}
+	actor, _ := middleware.UserFromContext(r.Context())
+	if actor.ID == userID {
+		respond.Error(w, http.StatusForbidden, "you cannot change your own permissions")
+		return
+	}
+	if status, msg := h.authorize(r, userID, permissionID); status != 0 {
+		respond.Error(w, status, msg)
+		return
+	}
+
+	if r.Method == http.MethodDelete {
+		if err := h.store.DeletePermissionOverride(r.Context(), userID, permissionID); err != nil {
+			if errors.Is(err, storage.ErrNotFound) {
+				respond.Error(w, http.StatusNotFound, "override not found")
+				return
+			}
+			log.Printf("delete permission override error: %v", err)
+			respond.Error(w, http.StatusInternalServerError, "failed to clear override")
+			return
+		}
+		h.invalidate()
+		respond.JSON(w, http.StatusOK, "override cleared", nil)
+		return
+	}
+	saved, err := h.store.SetPermissionOverride(r.Context(), models.PermissionOverride{
+		UserID:       userID,
+		PermissionID: permissionID,
+		Allow:        *req.Allow,
+		SetBy:        actor.ID,
+	})
+	if err != nil {
+		log.Printf("set permission override error: %v", err)
+		respond.Error(w, http.StatusInternalServerError, "failed to save override")
+		return
+	}
+	h.invalidate()
+	respond.JSON(w, http.StatusOK, "override saved", saved)
+}
+
+// authorize applies the limits on callers without roles:manage. It returns a
+// zero status when the change is allowed.
+func (h *UserPermissionHandler) authorize(r *http.Request, userID, permissionID int64) (int, string) {
+	ctx := r.Context()
+	target, err := h.store.FindByID(ctx, userID)
+	if errors.Is(err, storage.ErrNotFound) {
+		return http.StatusNotFound, "user not found"
+	}
+	if err != nil {
+		log.Printf("find user error: %v", err)
+		return http.StatusInternalServerError, "failed to check permissions"
+	}
+	permission, err := h.store.FindPermission(ctx, permissionID)
+	if errors.Is(err, storage.ErrNotFound) {
+		return http.StatusNotFound, "permission not found"
+	}
+	if err != nil {
+		log.Printf("find permission error: %v", err)
+		return http.StatusInternalServerError, "failed to check permissions"
+	}
+	if middleware.HasPermission(ctx, models.PermRolesManage) {
+		return 0, ""
+	}
+	if !slices.Contains(models.PlayerRoles, target.Role) {
+		return http.StatusForbidden, "only players' permissions can be changed without " + models.PermRolesManage
+	}
+	roles, err := h.store.ListRoles(ctx)
+	if err != nil {
+		log.Printf("list roles error: %v", err)
+		return http.StatusInternalServerError, "failed to check permissions"
+	}
+	playerFacing := slices.ContainsFunc(roles, func(role models.Role) bool {
+		return slices.Contains(models.PlayerRoles, role.RoleName) && slices.Contains(role.Permissions, permission.PermissionName)
+	})
+	if !playerFacing {
+		return http.StatusForbidden, permission.PermissionName + " is not a player permission"
+	}
+	return 0, ""
+}
@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/hongminglow/all-in-be/internal/http/respond"
+	"github.com/hongminglow/all-in-be/internal/middleware"
+	"github.com/hongminglow/all-in-be/internal/models"
+	"github.com/hongminglow/all-in-be/internal/models/dto"
+	"github.com/hongminglow/all-in-be/internal/storage"
+)
+
+const (
+	defaultSearchLimit = 20
+	maxSearchLimit     = 100
+	// minSearchLength is the shortest query the trigram indexes can serve.
+	minSearchLength = 3
+)
+
+// UserSearchHandler lets support staff find accounts by partial username,
+// email, or phone number.
+type UserSearchHandler struct {
+	store storage.UserStore
+}
+
+// NewUserSearchHandler constructs the handler.
+func NewUserSearchHandler(store storage.UserStore) *UserSearchHandler {
+	return &UserSearchHandler{store: store}
+}
+
+// Register attaches the admin route. It must be mounted behind middleware.Authenticate.
+func (h *UserSearchHandler) Register(mux Router) {
+	mux.Handle("GET /admin/users/search", middleware.RequirePermission(models.PermUsersRead, http.HandlerFunc(h.handleSearch)))
+}
+
+// handleSearch supports ?q=, ?limit= and ?cursor= from a previous page's next_cursor.
+func (h *UserSearchHandler) handleSearch(w http.ResponseWriter, r *http.Request) {
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	if utf8.RuneCountInString(query) < minSearchLength {
+		respond.Error(w, http.StatusBadRequest, "q must be at least 3 characters")
+		return
+	}
+	limit := defaultSearchLimit
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed < 1 || parsed > maxSearchLimit {
+			respond.Error(w, http.StatusBadRequest, "limit must be between 1 and 100")
+			return
+		}
+		limit = parsed
+	}
+	var after *models.UserSearchCursor
+	if raw := r.URL.Query().Get("cursor"); raw != "" {
+		cursor, err := decodeSearchCursor(raw)
+		if err != nil {
+			respond.Error(w, http.StatusBadRequest, "invalid cursor")
+			return
+		}
+		after = &cursor
+	}
+
+	// Fetch one extra row to learn whether another page exists.
+	results, err := h.store.SearchUsers(r.Context(), query, after, limit+1)
+	if err != nil {
+		log.Printf("search users: %v", err)
+		respond.Error(w, http.StatusInternalServerError, "failed to search users")
+		return
+	}
+	resp := dto.UserSearchResponse{Users: make([]models.User, 0, min(len(results), limit))}
+	if len(results) > limit {
+		results = results[:limit]
+		last := results[limit-1]
+		resp.NextCursor = encodeSearchCursor(models.UserSearchCursor{Rank: last.Rank, ID: last.User.ID})
+	}
+	for _, result := range results {
+		resp.Users = append(resp.Users, result.User)
+	}
+	respond.JSON(w, http.StatusOK, "users fetched", resp)
+}
+
+func encodeSearchCursor(c models.UserSearchCursor) string {
+	raw, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+func decodeSearchCursor(s string) (models.UserSearchCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return models.UserSearchCursor{}, err
+	}
+	var c models.UserSearchCursor
+	err = json.Unmarshal(raw, &c)
+	return c, err
+}
@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/hongminglow/all-in-be/internal/http/respond"
+	"github.com/hongminglow/all-in-be/internal/middleware"
+	"github.com/hongminglow/all-in-be/internal/models/dto"
+	"github.com/hongminglow/all-in-be/internal/money"
+	"github.com/hongminglow/all-in-be/internal/wallet"
+)
+
+// WalletHandler serves the caller's own balance, split into what is on hold
+// for unsettled bets and what is available to spend.
+type WalletHandler struct {
+	wallet *wallet.Service
+}
+
+// NewWalletHandler constructs the handler.
+func NewWalletHandler(w *wallet.Service) *WalletHandler {
+	return &WalletHandler{wallet: w}
+}
+
+// Register attaches the wallet route. It must be mounted behind middleware.Authenticate.
+func (h *WalletHandler) Register(mux Router) {
+	mux.HandleFunc("GET /wallet/balance", h.handleBalance)
+}
+
+func (h *WalletHandler) handleBalance(w http.ResponseWriter, r *http.Request) {
+	user, ok := middleware.UserFromContext(r.Context())
+	if !ok {
+		respond.Error(w, http.StatusUnauthorized, "authentication required")
+		return
+	}
+	balance, err := h.wallet.Balance(r.Context(), user.ID)
+	if err != nil {
+		log.Printf("wallet balance for user %d: %v", user.ID, err)
+		respond.Error(w, http.StatusInternalServerError, "failed to fetch balance")
+		return
+	}
+	format := money.FormatFromContext(r.Context())
+	respond.JSON(w, http.StatusOK, "balance fetched", dto.WalletBalanceResponse{
+		WalletBalance:  balance,
+		TotalMoney:     money.Amount(format, balance.Total),
+		HeldMoney:      money.Amount(format, balance.Held),
+		AvailableMoney: money.Amount(format, balance.Available),
+		MoneyFormat:    format,
+	})
+}
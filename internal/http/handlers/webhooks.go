@@ -0,0 +1,146 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/hongminglow/all-in-be/internal/http/respond"
+	"github.com/hongminglow/all-in-be/internal/middleware"
+	"github.com/hongminglow/all-in-be/internal/models"
+	"github.com/hongminglow/all-in-be/internal/models/dto"
+	"github.com/hongminglow/all-in-be/internal/storage"
+)
+
+const (
+	defaultDeliveryLimit = 50
+	maxDeliveryLimit     = 500
+)
+
+// WebhookHandler lets administrators register outbound webhook endpoints and
+// inspect their delivery log.
+type WebhookHandler struct {
+	store storage.WebhookStore
+}
+
+// NewWebhookHandler constructs the handler.
+func NewWebhookHandler(store storage.WebhookStore) *WebhookHandler {
+	return &WebhookHandler{store: store}
+}
+
+// Register attaches the admin routes. They must be mounted behind middleware.Authenticate.
+func (h *WebhookHandler) Register(mux Router) {
+	mux.Handle("GET /admin/webhooks", middleware.RequirePermission(models.PermConfigManage, http.HandlerFunc(h.list)))
+	mux.Handle("POST /admin/webhooks", middleware.RequirePermission(models.PermConfigManage, http.HandlerFunc(h.create)))
+	mux.Handle("DELETE /admin/webhooks/{id}", middleware.RequirePermission(models.PermConfigManage, http.HandlerFunc(h.handleEndpoint)))
+	mux.Handle("GET /admin/webhooks/{id}/deliveries", middleware.RequirePermission(models.PermConfigManage, http.HandlerFunc(h.handleDeliveries)))
+}
+
+func (h *WebhookHandler) list(w http.ResponseWriter, r *http.Request) {
+	endpoints, err := h.store.ListWebhookEndpoints(r.Context())
+	if err != nil {
+		log.Printf("list webhook endpoints error: %v", err)
+		respond.Error(w, http.StatusInternalServerError, "failed to list webhook endpoints")
+		return
+	}
+	for i := range endpoints {
+		endpoints[i].Secret = ""
+	}
+	respond.JSON(w, http.StatusOK, "webhook endpoints fetched", endpoints)
+}
+
+func (h *WebhookHandler) create(w http.ResponseWriter, r *http.Request) {
+	var req dto.CreateWebhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respond.Error(w, http.StatusBadRequest, "invalid JSON payload")
+		return
+	}
+	target, err := url.Parse(strings.TrimSpace(req.URL))
+	if err != nil || (target.Scheme != "https" && target.Scheme != "http") || target.Host == "" {
+		respond.Error(w, http.StatusBadRequest, "url must be an absolute http(s) URL")
+		return
+	}
+	if len(req.Events) == 0 {
+		respond.Error(w, http.StatusBadRequest, "events must list at least one event type")
+		return
+	}
+	for _, event := range req.Events {
+		if !slices.Contains(models.WebhookEvents, event) {
+			respond.Error(w, http.StatusBadRequest, "unknown event type "+strconv.Quote(event))
+			return
+		}
+	}
+	secret := strings.TrimSpace(req.Secret)
+	if secret == "" {
+		secret = newWebhookSecret()
+	}
+	actor, _ := middleware.UserFromContext(r.Context())
+	created, err := h.store.CreateWebhookEndpoint(r.Context(), models.WebhookEndpoint{
+		URL:       target.String(),
+		Secret:    secret,
+		Events:    slices.Compact(slices.Sorted(slices.Values(req.Events))),
+		Active:    true,
+		CreatedBy: actor.ID,
+	})
+	if err != nil {
+		log.Printf("create webhook endpoint error: %v", err)
+		respond.Error(w, http.StatusInternalServerError, "failed to create webhook endpoint")
+		return
+	}
+	respond.Created(w, fmt.Sprintf("/admin/webhooks/%d", created.ID), "webhook endpoint created; store the secret, it will not be shown again", created)
+}
+
+func (h *WebhookHandler) handleEndpoint(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+	if err := h.store.DeleteWebhookEndpoint(r.Context(), id); err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			respond.Error(w, http.StatusNotFound, "webhook endpoint not found")
+			return
+		}
+		log.Printf("delete webhook endpoint error: %v", err)
+		respond.Error(w, http.StatusInternalServerError, "failed to delete webhook endpoint")
+		return
+	}
+	respond.JSON(w, http.StatusOK, "webhook endpoint deleted", nil)
+}
+
+// handleDeliveries returns an endpoint's recent delivery attempts, newest first. Supports ?limit=.
+func (h *WebhookHandler) handleDeliveries(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+	limit := defaultDeliveryLimit
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed < 1 || parsed > maxDeliveryLimit {
+			respond.Error(w, http.StatusBadRequest, "limit must be between 1 and 500")
+			return
+		}
+		limit = parsed
+	}
+	deliveries, err := h.store.ListWebhookDeliveries(r.Context(), id, limit)
+	if err != nil {
+		log.Printf("list webhook deliveries error: %v", err)
+		respond.Error(w, http.StatusInternalServerError, "failed to list webhook deliveries")
+		return
+	}
+	respond.JSON(w, http.StatusOK, "webhook deliveries fetched", deliveries)
+}
+
+func newWebhookSecret() string {
+	b := make([]byte, 32)
+	_, _ = rand.Read(b)
+	return "whsec_" + hex.EncodeToString(b)
+}
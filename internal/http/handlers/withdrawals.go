@@ -0,0 +1,201 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/hongminglow/all-in-be/internal/challenge"
+	"github.com/hongminglow/all-in-be/internal/http/respond"
+	"github.com/hongminglow/all-in-be/internal/middleware"
+	"github.com/hongminglow/all-in-be/internal/models"
+	"github.com/hongminglow/all-in-be/internal/models/dto"
+	"github.com/hongminglow/all-in-be/internal/payments"
+	"github.com/hongminglow/all-in-be/internal/storage"
+)
+
+const (
+	defaultWithdrawalLimit = 100
+	maxWithdrawalLimit     = 500
+)
+
+var withdrawalStatuses = []string{
+	models.WithdrawalPending, models.WithdrawalApproved, models.WithdrawalRejected, models.WithdrawalPaid, models.WithdrawalFailed,
+}
+
+// WithdrawalHandler takes players' withdrawal requests and lets admins
+// approve or reject those held for review.
+type WithdrawalHandler struct {
+	store      storage.WithdrawalStore
+	payments   *payments.Service
+	challenges *challenge.Service
+}
+
+// NewWithdrawalHandler constructs the handler. challenges may be nil.
+func NewWithdrawalHandler(store storage.WithdrawalStore, service *payments.Service, challenges *challenge.Service) *WithdrawalHandler {
+	return &WithdrawalHandler{store: store, payments: service, challenges: challenges}
+}
+
+// Register attaches the listing and review routes. They must be mounted
+// behind middleware.Authenticate.
+func (h *WithdrawalHandler) Register(mux Router) {
+	mux.HandleFunc("GET /me/withdrawals", h.handleListOwn)
+	mux.Handle("GET /admin/withdrawals", middleware.RequirePermission(models.PermWithdrawalsReview, http.HandlerFunc(h.handleList)))
+	mux.Handle("POST /admin/withdrawals/{id}/approve", middleware.RequirePermission(models.PermWithdrawalsReview, http.HandlerFunc(h.handleApprove)))
+	mux.Handle("POST /admin/withdrawals/{id}/reject", middleware.RequirePermission(models.PermWithdrawalsReview, http.HandlerFunc(h.handleReject)))
+}
+
+// RegisterRequest attaches the route that requests a withdrawal. It must be
+// mounted behind middleware.Authenticate, middleware.LoadUser, so step-up
+// codes go to the stored email, and the withdrawal IP screen.
+func (h *WithdrawalHandler) RegisterRequest(mux Router) {
+	mux.Handle("POST /payments/{provider}/withdrawals", middleware.RefuseImpersonation(http.HandlerFunc(h.handleRequest)))
+}
+
+func (h *WithdrawalHandler) handleRequest(w http.ResponseWriter, r *http.Request) {
+	user, ok := middleware.UserFromContext(r.Context())
+	if !ok {
+		respond.Error(w, http.StatusUnauthorized, "authentication required")
+		return
+	}
+	var req dto.WithdrawalRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respond.Error(w, http.StatusBadRequest, "invalid JSON payload")
+		return
+	}
+	if !requireChallenge(w, r, h.challenges, user, challenge.Request{Action: models.ActionWithdrawal, Amount: req.Amount}) {
+		return
+	}
+	provider := r.PathValue("provider")
+	request, err := h.payments.Withdraw(r.Context(), provider, user.ID, req.Amount)
+	if errors.Is(err, payments.ErrPayoutUnconfirmed) {
+		// The amount stays held and the provider's callback settles it.
+		log.Printf("pay out withdrawal %d: %v", request.ID, err)
+		err = nil
+	}
+	switch {
+	case errors.Is(err, payments.ErrUnknownProvider):
+		respond.Error(w, http.StatusNotFound, "payment provider not found")
+	case errors.Is(err, payments.ErrInvalidAmount):
+		respond.Error(w, http.StatusBadRequest, "amount must be positive")
+	case errors.Is(err, storage.ErrInsufficientFunds):
+		respond.Error(w, http.StatusConflict, "the balance is too low for this withdrawal")
+	case err != nil:
+		log.Printf("request %s withdrawal for user %d: %v", provider, user.ID, err)
+		respond.Error(w, http.StatusInternalServerError, "failed to request withdrawal")
+	default:
+		respond.Created(w, "", "withdrawal requested", request)
+	}
+}
+
+// handleListOwn returns the caller's withdrawals, newest first.
+func (h *WithdrawalHandler) handleListOwn(w http.ResponseWriter, r *http.Request) {
+	user, ok := middleware.UserFromContext(r.Context())
+	if !ok {
+		respond.Error(w, http.StatusUnauthorized, "authentication required")
+		return
+	}
+	requests, err := h.store.ListWithdrawals(r.Context(), models.WithdrawalFilter{UserID: user.ID}, defaultWithdrawalLimit)
+	if err != nil {
+		log.Printf("list withdrawals of user %d: %v", user.ID, err)
+		respond.Error(w, http.StatusInternalServerError, "failed to list withdrawals")
+		return
+	}
+	respond.JSON(w, http.StatusOK, "withdrawals fetched", requests)
+}
+
+// handleList returns the newest withdrawals first. Supports ?status=,
+// ?user_id= and ?limit=.
+func (h *WithdrawalHandler) handleList(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	filter := models.WithdrawalFilter{Status: q.Get("status")}
+	if filter.Status != "" && !slices.Contains(withdrawalStatuses, filter.Status) {
+		respond.Error(w, http.StatusBadRequest, "unknown status "+strconv.Quote(filter.Status))
+		return
+	}
+	if raw := q.Get("user_id"); raw != "" {
+		id, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil || id < 1 {
+			respond.Error(w, http.StatusBadRequest, "user_id must be a positive integer")
+			return
+		}
+		filter.UserID = id
+	}
+	limit := defaultWithdrawalLimit
+	if raw := q.Get("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed < 1 || parsed > maxWithdrawalLimit {
+			respond.Error(w, http.StatusBadRequest, "limit must be between 1 and 500")
+			return
+		}
+		limit = parsed
+	}
+	requests, err := h.store.ListWithdrawals(r.Context(), filter, limit)
+	if err != nil {
+		log.Printf("list withdrawals error: %v", err)
+		respond.Error(w, http.StatusInternalServerError, "failed to list withdrawals")
+		return
+	}
+	respond.JSON(w, http.StatusOK, "withdrawals fetched", requests)
+}
+
+func (h *WithdrawalHandler) handleApprove(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+	actor, _ := middleware.UserFromContext(r.Context())
+	request, err := h.payments.Approve(r.Context(), id, actor.ID)
+	if errors.Is(err, payments.ErrPayoutUnconfirmed) {
+		log.Printf("pay out withdrawal %d: %v", id, err)
+		err = nil
+	}
+	if !h.reviewed(w, err, id) {
+		return
+	}
+	respond.JSON(w, http.StatusOK, "withdrawal approved", request)
+}
+
+func (h *WithdrawalHandler) handleReject(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+	var req dto.RejectWithdrawalRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respond.Error(w, http.StatusBadRequest, "invalid JSON payload")
+		return
+	}
+	if req.Note = strings.TrimSpace(req.Note); req.Note == "" {
+		respond.Error(w, http.StatusBadRequest, "note is required")
+		return
+	}
+	actor, _ := middleware.UserFromContext(r.Context())
+	request, err := h.payments.Reject(r.Context(), id, actor.ID, req.Note)
+	if !h.reviewed(w, err, id) {
+		return
+	}
+	respond.JSON(w, http.StatusOK, "withdrawal rejected", request)
+}
+
+// reviewed reports whether a review succeeded, answering the error otherwise.
+func (h *WithdrawalHandler) reviewed(w http.ResponseWriter, err error, id int64) bool {
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, storage.ErrNotFound):
+		respond.Error(w, http.StatusNotFound, "withdrawal not found")
+	case errors.Is(err, payments.ErrOwnWithdrawal):
+		respond.Error(w, http.StatusForbidden, "you cannot review your own withdrawal")
+	case errors.Is(err, payments.ErrNotAllowed):
+		respond.Error(w, http.StatusConflict, "withdrawal is not pending review")
+	default:
+		log.Printf("review withdrawal %d: %v", id, err)
+		respond.Error(w, http.StatusInternalServerError, "failed to review withdrawal")
+	}
+	return false
+}
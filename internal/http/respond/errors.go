@@ -0,0 +1,41 @@
+package respond
+
+import "net/http"
+
+// ErrorCode documents one value of the envelope's code on error responses.
+// The message alongside it is for people and may change; the code is the
+// stable part clients branch on.
+type ErrorCode struct {
+	Code int `json:"code"`
+	// Name is a stable identifier for SDKs, e.g. "not_found".
+	Name        string `json:"name"`
+	Status      string `json:"status"`
+	Description string `json:"description"`
+	// Retryable reports that the same request may succeed later unchanged.
+	Retryable bool `json:"retryable"`
+}
+
+// ErrorCatalog lists every code the API answers errors with, in order. A test
+// checks that each status passed to Error appears here.
+var ErrorCatalog = []ErrorCode{
+	errorCode(http.StatusBadRequest, "bad_request", "The request is malformed or fails validation; the message names the offending field.", false),
+	errorCode(http.StatusUnauthorized, "unauthorized", "No valid bearer token, session cookie or API key was presented, or the credentials given are wrong.", false),
+	errorCode(http.StatusForbidden, "forbidden", "The caller is signed in but lacks the permission or token scope the route requires.", false),
+	errorCode(http.StatusNotFound, "not_found", "The resource does not exist or is not visible to the caller.", false),
+	errorCode(http.StatusMethodNotAllowed, "method_not_allowed", "The path exists but not for this method; the Allow header lists the methods it takes. The body is plain text.", false),
+	errorCode(http.StatusConflict, "conflict", "The request clashes with the current state, e.g. a username already taken or a job that cannot be resumed.", false),
+	errorCode(http.StatusGone, "gone", "The step-up challenge expired or ran out of attempts; retry the original action for a new one.", false),
+	errorCode(http.StatusRequestEntityTooLarge, "request_entity_too_large", "The request body exceeds the route's limit.", false),
+	errorCode(http.StatusUnsupportedMediaType, "unsupported_media_type", "The request body's Content-Type is not one the route accepts, e.g. an attachment of a type that is not allowed.", false),
+	errorCode(http.StatusUnprocessableEntity, "unprocessable_entity", "The request is well formed but cannot be applied, e.g. a wrong challenge answer or an invalid config bundle.", false),
+	errorCode(http.StatusPreconditionRequired, "precondition_required", "The action needs a step-up challenge or a CAPTCHA first; data.challenge or data.captcha describes it. Answer it and repeat the action.", false),
+	errorCode(http.StatusTooManyRequests, "too_many_requests", "The caller is rate limited; wait for the Retry-After header's seconds.", true),
+	errorCode(http.StatusUnavailableForLegalReasons, "unavailable_for_legal_reasons", "The service is not offered in the caller's country.", false),
+	errorCode(http.StatusInternalServerError, "internal_server_error", "An unexpected error; the details are in the server logs.", true),
+	errorCode(http.StatusBadGateway, "bad_gateway", "An upstream dependency failed after part of the work was done on this instance, e.g. a cache invalidation broadcast.", true),
+	errorCode(http.StatusServiceUnavailable, "service_unavailable", "A dependency such as the database is unavailable; retry with backoff.", true),
+}
+
+func errorCode(code int, name, description string, retryable bool) ErrorCode {
+	return ErrorCode{Code: code, Name: name, Status: http.StatusText(code), Description: description, Retryable: retryable}
+}
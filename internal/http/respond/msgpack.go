@@ -0,0 +1,203 @@
+package respond
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// msgpackEncoder writes MessagePack. Values are first encoded as JSON and the
+// JSON tokens are translated one by one, so field names, omitempty, custom
+// marshalers and field order are exactly those of the JSON responses. Times
+// stay RFC 3339 strings and byte slices base64 strings, as in JSON.
+type msgpackEncoder struct{}
+
+func (msgpackEncoder) ContentType() string { return "application/msgpack" }
+
+func (msgpackEncoder) Encode(w io.Writer, v any) error {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	value, err := readValue(dec)
+	if err != nil {
+		return err
+	}
+	bw := bufio.NewWriter(w)
+	writeMsgpack(bw, value)
+	return bw.Flush()
+}
+
+// object keeps the members of a JSON object in order.
+type object []member
+
+type member struct {
+	key   string
+	value any
+}
+
+// readValue reads one JSON value as nil, bool, string, json.Number, []any
+// or object.
+func readValue(dec *json.Decoder) (any, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	delim, ok := tok.(json.Delim)
+	if !ok {
+		return tok, nil
+	}
+	switch delim {
+	case '[':
+		var items []any
+		for dec.More() {
+			item, err := readValue(dec)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, item)
+		}
+		if _, err := dec.Token(); err != nil {
+			return nil, err
+		}
+		if items == nil {
+			items = []any{}
+		}
+		return items, nil
+	case '{':
+		var obj object
+		for dec.More() {
+			key, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			value, err := readValue(dec)
+			if err != nil {
+				return nil, err
+			}
+			obj = append(obj, member{key: key.(string), value: value})
+		}
+		if _, err := dec.Token(); err != nil {
+			return nil, err
+		}
+		return obj, nil
+	default:
+		return nil, fmt.Errorf("unexpected JSON delimiter %q", delim)
+	}
+}
+
+// writeMsgpack encodes a value produced by readValue. Errors surface from
+// the final Flush.
+func writeMsgpack(w *bufio.Writer, v any) {
+	switch v := v.(type) {
+	case nil:
+		w.WriteByte(0xc0)
+	case bool:
+		if v {
+			w.WriteByte(0xc3)
+		} else {
+			w.WriteByte(0xc2)
+		}
+	case string:
+		writeString(w, v)
+	case json.Number:
+		writeNumber(w, v)
+	case []any:
+		writeHeader(w, len(v), 0x90, 16, 0xdc, 0xdd)
+		for _, item := range v {
+			writeMsgpack(w, item)
+		}
+	case object:
+		writeHeader(w, len(v), 0x80, 16, 0xde, 0xdf)
+		for _, m := range v {
+			writeString(w, m.key)
+			writeMsgpack(w, m.value)
+		}
+	}
+}
+
+func writeString(w *bufio.Writer, s string) {
+	switch n := len(s); {
+	case n < 32:
+		w.WriteByte(0xa0 | byte(n))
+	case n <= math.MaxUint8:
+		w.Write([]byte{0xd9, byte(n)})
+	default:
+		writeHeader(w, n, 0, 0, 0xda, 0xdb)
+	}
+	w.WriteString(s)
+}
+
+// writeHeader writes the length of an array, map or long string: in the fix
+// format below fixMax, then the 16-bit or the 32-bit one.
+func writeHeader(w *bufio.Writer, n int, fix byte, fixMax int, code16, code32 byte) {
+	switch {
+	case n < fixMax:
+		w.WriteByte(fix | byte(n))
+	case n <= math.MaxUint16:
+		w.WriteByte(code16)
+		w.Write(binary.BigEndian.AppendUint16(nil, uint16(n)))
+	default:
+		w.WriteByte(code32)
+		w.Write(binary.BigEndian.AppendUint32(nil, uint32(n)))
+	}
+}
+
+// writeNumber encodes integers in the smallest format that holds them and
+// everything else as a float64.
+func writeNumber(w *bufio.Writer, n json.Number) {
+	s := n.String()
+	if !strings.ContainsAny(s, ".eE") {
+		if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+			writeInt(w, i)
+			return
+		}
+		if u, err := strconv.ParseUint(s, 10, 64); err == nil {
+			w.WriteByte(0xcf)
+			w.Write(binary.BigEndian.AppendUint64(nil, u))
+			return
+		}
+	}
+	f, _ := n.Float64()
+	w.WriteByte(0xcb)
+	w.Write(binary.BigEndian.AppendUint64(nil, math.Float64bits(f)))
+}
+
+func writeInt(w *bufio.Writer, i int64) {
+	switch {
+	case i >= 0 && i <= math.MaxInt8:
+		w.WriteByte(byte(i))
+	case i >= -32 && i < 0:
+		w.WriteByte(byte(int8(i)))
+	case i > 0 && i <= math.MaxUint8:
+		w.Write([]byte{0xcc, byte(i)})
+	case i > 0 && i <= math.MaxUint16:
+		w.WriteByte(0xcd)
+		w.Write(binary.BigEndian.AppendUint16(nil, uint16(i)))
+	case i > 0 && i <= math.MaxUint32:
+		w.WriteByte(0xce)
+		w.Write(binary.BigEndian.AppendUint32(nil, uint32(i)))
+	case i > 0:
+		w.WriteByte(0xcf)
+		w.Write(binary.BigEndian.AppendUint64(nil, uint64(i)))
+	case i >= math.MinInt8:
+		w.Write([]byte{0xd0, byte(int8(i))})
+	case i >= math.MinInt16:
+		w.WriteByte(0xd1)
+		w.Write(binary.BigEndian.AppendUint16(nil, uint16(int16(i))))
+	case i >= math.MinInt32:
+		w.WriteByte(0xd2)
+		w.Write(binary.BigEndian.AppendUint32(nil, uint32(int32(i))))
+	default:
+		w.WriteByte(0xd3)
+		w.Write(binary.BigEndian.AppendUint64(nil, uint64(i)))
+	}
+}
@@ -0,0 +1,107 @@
+package respond
+
+import (
+	"encoding/json"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Encoder writes response bodies in one media type.
+type Encoder interface {
+	ContentType() string
+	Encode(w io.Writer, v any) error
+}
+
+var (
+	// JSONEncoder writes JSON, the default.
+	JSONEncoder Encoder = jsonEncoder{}
+	// MsgPackEncoder writes MessagePack, for bandwidth-sensitive clients.
+	MsgPackEncoder Encoder = msgpackEncoder{}
+)
+
+// mediaTypes maps the media types clients may ask for to their encoder.
+var mediaTypes = map[string]Encoder{
+	"application/json":        JSONEncoder,
+	"application/msgpack":     MsgPackEncoder,
+	"application/x-msgpack":   MsgPackEncoder,
+	"application/vnd.msgpack": MsgPackEncoder,
+	"application/*":           JSONEncoder,
+	"*/*":                     JSONEncoder,
+}
+
+type jsonEncoder struct{}
+
+func (jsonEncoder) ContentType() string { return "application/json" }
+
+func (jsonEncoder) Encode(w io.Writer, v any) error { return json.NewEncoder(w).Encode(v) }
+
+// Negotiate picks the encoder for an Accept header: the supported media type
+// with the highest q-value, the first listed winning ties. JSON is used when
+// the header is empty or names nothing supported, so callers never get a 406.
+func Negotiate(accept string) Encoder {
+	best, bestQ := JSONEncoder, 0.0
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		enc, ok := mediaTypes[mediaType]
+		if !ok {
+			continue
+		}
+		q := 1.0
+		if raw, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(raw, 64); err != nil {
+				continue
+			}
+		}
+		if q > bestQ {
+			best, bestQ = enc, q
+		}
+	}
+	return best
+}
+
+// encodingWriter carries the encoder negotiated for a request.
+type encodingWriter struct {
+	http.ResponseWriter
+	enc Encoder
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (w encodingWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// WithEncoder returns a writer whose responses from this package are
+// encoded with enc.
+func WithEncoder(w http.ResponseWriter, enc Encoder) http.ResponseWriter {
+	return encodingWriter{ResponseWriter: w, enc: enc}
+}
+
+// encoderFor finds the encoder set by WithEncoder and defaults to JSON.
+func encoderFor(w http.ResponseWriter) Encoder {
+	if v, ok := unwrapTo[encodingWriter](w); ok {
+		return v.enc
+	}
+	return JSONEncoder
+}
+
+// unwrapTo finds the writer of type T this package wrapped around a
+// response, looking through writers wrapped around it in turn.
+func unwrapTo[T http.ResponseWriter](w http.ResponseWriter) (T, bool) {
+	for {
+		if v, ok := w.(T); ok {
+			return v, true
+		}
+		u, ok := w.(interface{ Unwrap() http.ResponseWriter })
+		if !ok {
+			var zero T
+			return zero, false
+		}
+		w = u.Unwrap()
+	}
+}
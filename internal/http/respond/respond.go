@@ -1,7 +1,6 @@
 package respond
 
 import (
-	"encoding/json"
 	"log"
 	"net/http"
 )
@@ -14,19 +13,53 @@ type Envelope struct {
 }
 
 // JSON writes a success or informational response using the common envelope.
+// Despite the name, the body is MessagePack when the request negotiated it
+// (see WithEncoder).
 func JSON(w http.ResponseWriter, status int, message string, data any) {
 	write(w, status, Envelope{Code: status, Message: message, Data: data})
 }
 
+// Created writes a 201 Created response for a new resource, with a Location
+// header naming its URL. Location is left out when empty, for resources that
+// have no URL of their own.
+func Created(w http.ResponseWriter, location, message string, data any) {
+	if location != "" {
+		w.Header().Set("Location", location)
+	}
+	JSON(w, http.StatusCreated, message, data)
+}
+
 // Error writes an error response with the shared envelope structure.
 func Error(w http.ResponseWriter, status int, message string) {
 	write(w, status, Envelope{Code: status, Message: message})
 }
 
+// localizingWriter carries the translation negotiated for a request.
+type localizingWriter struct {
+	http.ResponseWriter
+	localize func(string) string
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (w localizingWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// WithLocalizer returns a writer whose envelope messages from this package
+// are passed through localize, which translates them into the caller's
+// language.
+func WithLocalizer(w http.ResponseWriter, localize func(message string) string) http.ResponseWriter {
+	return localizingWriter{ResponseWriter: w, localize: localize}
+}
+
 func write(w http.ResponseWriter, status int, payload Envelope) {
-	w.Header().Set("Content-Type", "application/json")
+	if lw, ok := unwrapTo[localizingWriter](w); ok && payload.Message != "" {
+		payload.Message = lw.localize(payload.Message)
+	}
+	enc := encoderFor(w)
+	w.Header().Set("Content-Type", enc.ContentType())
 	w.WriteHeader(status)
-	if err := json.NewEncoder(w).Encode(payload); err != nil {
+	if err := enc.Encode(w, payload); err != nil {
 		log.Printf("respond: encode payload failed: %v", err)
 	}
 }
@@ -0,0 +1,176 @@
+package respond
+
+import (
+	"bytes"
+	"encoding/hex"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNegotiate(t *testing.T) {
+	for _, tc := range []struct {
+		accept string
+		want   Encoder
+	}{
+		{"", JSONEncoder},
+		{"application/msgpack", MsgPackEncoder},
+		{"application/x-msgpack", MsgPackEncoder},
+		{"application/json, application/msgpack", JSONEncoder},
+		{"application/json;q=0.5, application/vnd.msgpack", MsgPackEncoder},
+		{"application/msgpack;q=0, */*", JSONEncoder},
+		{"text/html, application/xhtml+xml, */*;q=0.8", JSONEncoder},
+		{"text/csv", JSONEncoder},
+	} {
+		if got := Negotiate(tc.accept); got != tc.want {
+			t.Errorf("Negotiate(%q) = %s, want %s", tc.accept, got.ContentType(), tc.want.ContentType())
+		}
+	}
+}
+
+func TestMsgPackEncoding(t *testing.T) {
+	for name, tc := range map[string]struct {
+		value any
+		want  string
+	}{
+		"envelope": {
+			Envelope{Code: 200, Message: "ok", Data: map[string]any{"a": 1}},
+			"83 a4636f6465 ccc8 a76d657373616765 a26f6b a464617461 81 a161 01",
+		},
+		"omitempty": {Envelope{Code: 404, Message: ""}, "82 a4636f6465 cd0194 a76d657373616765 a0"},
+		"scalars":   {[]any{nil, true, false, -1, -33, 1.5}, "96 c0 c3 c2 ff d0df cb3ff8000000000000"},
+		"integers":  {[]int64{127, 65536, -129, -40000, 1 << 40}, "95 7f ce00010000 d1ff7f d2ffff63c0 cf0000010000000000"},
+		"empty":     {[]string{}, "90"},
+		"time":      {time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC), "b4 " + hex.EncodeToString([]byte("2026-01-02T03:04:05Z"))},
+	} {
+		var buf bytes.Buffer
+		if err := MsgPackEncoder.Encode(&buf, tc.value); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if got, want := hex.EncodeToString(buf.Bytes()), strings.ReplaceAll(tc.want, " ", ""); got != want {
+			t.Errorf("%s: encoded %s, want %s", name, got, want)
+		}
+	}
+}
+
+func TestMsgPackLengths(t *testing.T) {
+	for name, tc := range map[string]struct {
+		value  any
+		header string
+	}{
+		"str8":    {strings.Repeat("x", 32), "d920"},
+		"str16":   {strings.Repeat("x", 256), "da0100"},
+		"array16": {make([]int, 16), "dc0010"},
+		"map16":   {map[string]int{"a": 0, "b": 1, "c": 2, "d": 3, "e": 4, "f": 5, "g": 6, "h": 7, "i": 8, "j": 9, "k": 10, "l": 11, "m": 12, "n": 13, "o": 14, "p": 15}, "de0010"},
+	} {
+		var buf bytes.Buffer
+		if err := MsgPackEncoder.Encode(&buf, tc.value); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if got := hex.EncodeToString(buf.Bytes()); !strings.HasPrefix(got, tc.header) {
+			t.Errorf("%s: encoded %.12s..., want header %s", name, got, tc.header)
+		}
+	}
+}
+
+func TestWriteUsesNegotiatedEncoder(t *testing.T) {
+	rec := httptest.NewRecorder()
+	JSON(rec, http.StatusOK, "ok", nil)
+	if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+		t.Fatalf("default Content-Type = %q", ct)
+	}
+
+	rec = httptest.NewRecorder()
+	Error(wrapped{WithEncoder(rec, MsgPackEncoder)}, http.StatusTeapot, "short and stout")
+	if ct := rec.Header().Get("Content-Type"); ct != "application/msgpack" {
+		t.Fatalf("negotiated Content-Type = %q", ct)
+	}
+	if rec.Code != http.StatusTeapot || rec.Body.Bytes()[0] != 0x82 {
+		t.Fatalf("status %d, body % x", rec.Code, rec.Body.Bytes())
+	}
+}
+
+func TestWriteLocalizesMessages(t *testing.T) {
+	rec := httptest.NewRecorder()
+	w := WithEncoder(WithLocalizer(rec, strings.ToUpper), MsgPackEncoder)
+	Error(wrapped{w}, http.StatusNotFound, "user not found")
+	if !bytes.Contains(rec.Body.Bytes(), []byte("USER NOT FOUND")) || rec.Header().Get("Content-Type") != "application/msgpack" {
+		t.Fatalf("localized body % x", rec.Body.Bytes())
+	}
+}
+
+// wrapped stands for middleware that wraps the writer after negotiation.
+type wrapped struct{ http.ResponseWriter }
+
+func (w wrapped) Unwrap() http.ResponseWriter { return w.ResponseWriter }
+
+// benchmarkPayload resembles a leaderboard page, one of the larger responses.
+func benchmarkPayload() Envelope {
+	type entry struct {
+		Rank     int       `json:"rank"`
+		UserID   int64     `json:"user_id"`
+		Username string    `json:"username"`
+		Value    float64   `json:"value"`
+		Since    time.Time `json:"since"`
+	}
+	entries := make([]entry, 100)
+	for i := range entries {
+		entries[i] = entry{Rank: i + 1, UserID: int64(1000 + i), Username: "player_" + strings.Repeat("x", i%12), Value: float64(100000-i*37) + 0.25, Since: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)}
+	}
+	return Envelope{Code: http.StatusOK, Message: "leaderboard fetched", Data: map[string]any{"entries": entries}}
+}
+
+func BenchmarkEncode(b *testing.B) {
+	payload := benchmarkPayload()
+	for _, enc := range []Encoder{JSONEncoder, MsgPackEncoder} {
+		b.Run(enc.ContentType(), func(b *testing.B) {
+			var size bytes.Buffer
+			if err := enc.Encode(&size, payload); err != nil {
+				b.Fatal(err)
+			}
+			b.ReportAllocs()
+			for b.Loop() {
+				if err := enc.Encode(io.Discard, payload); err != nil {
+					b.Fatal(err)
+				}
+			}
+			b.ReportMetric(float64(size.Len()), "body-bytes")
+		})
+	}
+}
+
+// TestErrorCatalogCoversResponses scans the server's source for error
+// statuses passed to Error and JSON, so a new status cannot ship without its
+// catalog entry.
+func TestErrorCatalogCoversResponses(t *testing.T) {
+	catalogued := make(map[string]bool)
+	for _, c := range ErrorCatalog {
+		catalogued["Status"+strings.ReplaceAll(c.Status, " ", "")] = true
+	}
+	used := regexp.MustCompile(`respond\.(?:Error|JSON)\(\w+, http\.(Status\w+)`)
+	err := filepath.WalkDir("../..", func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || !strings.HasSuffix(path, ".go") || strings.HasSuffix(path, "_test.go") {
+			return err
+		}
+		src, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		for _, m := range used.FindAllStringSubmatch(string(src), -1) {
+			if status := m[1]; status != "StatusOK" && status != "StatusAccepted" && !catalogued[status] {
+				t.Errorf("%s answers http.%s, which is missing from ErrorCatalog", path, status)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
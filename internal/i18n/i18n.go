@@ -0,0 +1,202 @@
+// Package i18n translates the messages of API responses. Messages are
+// written in English in the code and double as catalog keys: each embedded
+// catalog in catalogs/ maps them to one other language. Keys may hold
+// {name} placeholders for the parts of a message built at run time, such as
+// a limit or a list of allowed values; the matched text is carried over to
+// the translation unchanged. Messages a catalog does not know stay English.
+package i18n
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"path"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+//go:embed catalogs/*.json
+var catalogFS embed.FS
+
+// English is the language messages are written in, and the default.
+const English = "en"
+
+// placeholder matches {name} in catalog keys and translations.
+var placeholder = regexp.MustCompile(`\{(\w+)\}`)
+
+// Translator holds the parsed catalogs.
+type Translator struct {
+	catalogs map[string]catalog
+}
+
+type catalog struct {
+	exact    map[string]string
+	patterns []pattern
+}
+
+// pattern is a catalog key with placeholders.
+type pattern struct {
+	match    *regexp.Regexp
+	template string
+	// literal is the length of the key without its placeholders.
+	literal int
+}
+
+// Load parses the embedded catalogs. Each file in catalogs/ is named after
+// its language, e.g. zh.json.
+func Load() (*Translator, error) {
+	files, err := catalogFS.ReadDir("catalogs")
+	if err != nil {
+		return nil, err
+	}
+	t := &Translator{catalogs: make(map[string]catalog)}
+	for _, f := range files {
+		language := strings.TrimSuffix(f.Name(), path.Ext(f.Name()))
+		raw, err := catalogFS.ReadFile("catalogs/" + f.Name())
+		if err != nil {
+			return nil, err
+		}
+		var messages map[string]string
+		if err := json.Unmarshal(raw, &messages); err != nil {
+			return nil, fmt.Errorf("decode catalog %s: %w", f.Name(), err)
+		}
+		c, err := parseCatalog(messages)
+		if err != nil {
+			return nil, fmt.Errorf("catalog %s: %w", f.Name(), err)
+		}
+		t.catalogs[language] = c
+	}
+	return t, nil
+}
+
+func parseCatalog(messages map[string]string) (catalog, error) {
+	c := catalog{exact: make(map[string]string)}
+	for key, translation := range messages {
+		if translation == "" {
+			return catalog{}, fmt.Errorf("%q has no translation", key)
+		}
+		names := placeholder.FindAllStringSubmatch(key, -1)
+		for _, used := range placeholder.FindAllStringSubmatch(translation, -1) {
+			if !slices.ContainsFunc(names, func(n []string) bool { return n[1] == used[1] }) {
+				return catalog{}, fmt.Errorf("translation of %q uses unknown placeholder %s", key, used[0])
+			}
+		}
+		if len(names) == 0 {
+			c.exact[key] = translation
+			continue
+		}
+		literal := placeholder.ReplaceAllString(key, "")
+		if literal == "" {
+			return catalog{}, fmt.Errorf("%q is only placeholders", key)
+		}
+		var expr strings.Builder
+		expr.WriteString("^")
+		last := 0
+		for _, loc := range placeholder.FindAllStringSubmatchIndex(key, -1) {
+			expr.WriteString(regexp.QuoteMeta(key[last:loc[0]]))
+			expr.WriteString("(?P<" + key[loc[2]:loc[3]] + ">.+)")
+			last = loc[1]
+		}
+		expr.WriteString(regexp.QuoteMeta(key[last:]) + "$")
+		match, err := regexp.Compile(expr.String())
+		if err != nil {
+			return catalog{}, fmt.Errorf("%q: %w", key, err)
+		}
+		// The translation becomes a template for Regexp.Expand.
+		template := placeholder.ReplaceAllString(strings.ReplaceAll(translation, "$", "$$"), "$${$1}")
+		c.patterns = append(c.patterns, pattern{match: match, template: template, literal: len(literal)})
+	}
+	// The key with the most fixed text is the most specific one.
+	slices.SortStableFunc(c.patterns, func(a, b pattern) int { return b.literal - a.literal })
+	return c, nil
+}
+
+// Languages lists English and every language with a catalog, sorted.
+func (t *Translator) Languages() []string {
+	languages := []string{English}
+	for language := range t.catalogs {
+		languages = append(languages, language)
+	}
+	slices.Sort(languages)
+	return languages
+}
+
+// Negotiate picks the language for an Accept-Language header: the first
+// preference with a catalog, matched on the whole tag or its primary
+// subtag, so zh-CN and zh-Hans both get zh. English is the fallback.
+func (t *Translator) Negotiate(acceptLanguage string) string {
+	for _, tag := range Preferences(acceptLanguage) {
+		language, _, _ := strings.Cut(strings.ToLower(tag), "-")
+		if _, ok := t.catalogs[language]; ok || language == English {
+			return language
+		}
+	}
+	return English
+}
+
+// Translate returns message in language, or message itself when the language
+// or its catalog entry is missing.
+func (t *Translator) Translate(language, message string) string {
+	if translated, ok := t.lookup(language, message); ok {
+		return translated
+	}
+	return message
+}
+
+func (t *Translator) lookup(language, message string) (string, bool) {
+	c, ok := t.catalogs[language]
+	if !ok {
+		return "", false
+	}
+	if translated, ok := c.exact[message]; ok {
+		return translated, true
+	}
+	for _, p := range c.patterns {
+		m := p.match.FindStringSubmatchIndex(message)
+		if m == nil {
+			continue
+		}
+		return string(p.match.ExpandString(nil, p.template, message, m)), true
+	}
+	return "", false
+}
+
+// Preferences returns the language tags of an Accept-Language header, most
+// preferred first, leaving out the wildcard and refused (q=0) entries.
+func Preferences(header string) []string {
+	type weighted struct {
+		tag string
+		q   float64
+	}
+	var tags []weighted
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		q := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		if tag = strings.TrimSpace(tag); tag != "" && tag != "*" && q > 0 {
+			tags = append(tags, weighted{tag, q})
+		}
+	}
+	slices.SortStableFunc(tags, func(a, b weighted) int {
+		switch {
+		case a.q > b.q:
+			return -1
+		case a.q < b.q:
+			return 1
+		}
+		return 0
+	})
+	out := make([]string, len(tags))
+	for i, t := range tags {
+		out[i] = t.tag
+	}
+	return out
+}
@@ -0,0 +1,121 @@
+package i18n
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestNegotiate(t *testing.T) {
+	translator, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		header, want string
+	}{
+		{"", English},
+		{"zh-CN,zh;q=0.9,en;q=0.8", "zh"},
+		{"zh-Hant", "zh"},
+		{"ms-MY", "ms"},
+		{"fr-FR, ms;q=0.5", "ms"},
+		{"en-GB, zh;q=0.5", English},
+		{"zh;q=0, ms", "ms"},
+		{"de, *", English},
+	} {
+		if got := translator.Negotiate(tc.header); got != tc.want {
+			t.Errorf("Negotiate(%q) = %q, want %q", tc.header, got, tc.want)
+		}
+	}
+	if got := translator.Languages(); strings.Join(got, ",") != "en,ms,zh" {
+		t.Errorf("Languages() = %v", got)
+	}
+}
+
+func TestTranslate(t *testing.T) {
+	translator, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		language, message, want string
+	}{
+		{"zh", "user not found", "未找到用户"},
+		{"ms", "user not found", "pengguna tidak dijumpai"},
+		{"zh", "days must be between 1 and 1825", "days 必须介于 1 到 1825 之间"},
+		{"ms", "unknown role \"croupier\"", "peranan \"croupier\" tidak diketahui"},
+		{"zh", "winnings is not ranked all_time", "winnings 没有 all_time 排名"},
+		// Exact entries win over patterns that also match.
+		{"zh", "invalid token", "令牌无效"},
+		{"zh", "invalid holdID", "holdID 无效"},
+		{"zh", "a message nobody translated", "a message nobody translated"},
+		{English, "user not found", "user not found"},
+		{"fr", "user not found", "user not found"},
+	} {
+		if got := translator.Translate(tc.language, tc.message); got != tc.want {
+			t.Errorf("Translate(%s, %q) = %q, want %q", tc.language, tc.message, got, tc.want)
+		}
+	}
+}
+
+func TestParseCatalogRejectsUnknownPlaceholders(t *testing.T) {
+	for name, messages := range map[string]map[string]string{
+		"unknown placeholder": {"days must be at most {max}": "{min}"},
+		"only placeholders":   {"{message}": "{message}"},
+		"empty":               {"user not found": ""},
+	} {
+		if _, err := parseCatalog(messages); err == nil {
+			t.Errorf("%s: parsed without error", name)
+		}
+	}
+}
+
+// TestCatalogsCoverResponses scans handlers and middleware for the messages
+// they answer with, so a new message cannot ship untranslated. Messages
+// built by concatenation are left to the catalogs' patterns.
+func TestCatalogsCoverResponses(t *testing.T) {
+	translator, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	literal := `("(?:[^"\\]|\\.)*"|` + "`[^`]*`" + `)(\s*\+)?`
+	used := []*regexp.Regexp{
+		regexp.MustCompile(`http\.Status\w+, ` + literal),
+		regexp.MustCompile(`respond\.Created\(\w+, (?:"[^"]*"|fmt\.Sprintf\([^)]*\)), ` + literal),
+	}
+	for _, dir := range []string{"../http/handlers", "../middleware"} {
+		err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() || !strings.HasSuffix(path, ".go") || strings.HasSuffix(path, "_test.go") {
+				return err
+			}
+			src, err := os.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			for _, re := range used {
+				for _, m := range re.FindAllStringSubmatch(string(src), -1) {
+					if m[2] != "" {
+						continue
+					}
+					message, err := strconv.Unquote(m[1])
+					if err != nil {
+						t.Fatalf("%s: unquote %s: %v", path, m[1], err)
+					}
+					for language := range translator.catalogs {
+						if _, ok := translator.lookup(language, message); !ok {
+							t.Errorf("%s answers %q, which is missing from the %s catalog", path, message, language)
+						}
+					}
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,192 @@
+// Package inbox keeps the messages staff send to players: support messages
+// written to one player and announcements broadcast to many, each stored as a
+// copy in every recipient's inbox.
+//
+// New messages are pushed over the GET /me/messages/stream Server-Sent Events
+// stream. As with the ledger feed, the messages are the stream: each is an
+// event with the message's ID, so a client that reconnects resumes where it
+// left off. Streams are woken as soon as this instance stores a message and
+// poll in between, which catches messages sent through other instances.
+package inbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/hongminglow/all-in-be/internal/clock"
+	"github.com/hongminglow/all-in-be/internal/models"
+	"github.com/hongminglow/all-in-be/internal/storage"
+)
+
+// BatchSize bounds the messages Since returns at once.
+const BatchSize = 100
+
+// Bounds on what staff may write.
+const (
+	maxSubject = 200
+	maxBody    = 10000
+)
+
+// ErrInvalidMessage is returned, wrapped with the reason, for a message that
+// cannot be sent.
+var ErrInvalidMessage = errors.New("invalid message")
+
+// Service stores messages and wakes the streams of their recipients.
+type Service struct {
+	store storage.MessageStore
+	clock clock.Clock
+
+	mu      sync.Mutex
+	watches map[int64]map[chan struct{}]struct{}
+	done    chan struct{}
+	closed  bool
+}
+
+// NewService builds a service keeping messages in store.
+func NewService(store storage.MessageStore, clk clock.Clock) *Service {
+	return &Service{store: store, clock: clk, watches: make(map[int64]map[chan struct{}]struct{}), done: make(chan struct{})}
+}
+
+// Inbox returns up to limit of the user's messages, newest first and only
+// unread ones when unreadOnly is set, with their unread count.
+func (s *Service) Inbox(ctx context.Context, userID int64, unreadOnly bool, limit int) (models.Inbox, error) {
+	messages, err := s.store.ListMessages(ctx, userID, unreadOnly, limit)
+	if err != nil {
+		return models.Inbox{}, err
+	}
+	unread, err := s.store.CountUnreadMessages(ctx, userID)
+	if err != nil {
+		return models.Inbox{}, err
+	}
+	return models.Inbox{Messages: messages, Unread: unread}, nil
+}
+
+// Send writes a support message from staff member senderID to the user. It
+// returns storage.ErrNotFound when there is no such user.
+func (s *Service) Send(ctx context.Context, senderID, userID int64, subject, body string) (models.Message, error) {
+	m, err := compose(models.MessageSupport, senderID, subject, body)
+	if err != nil {
+		return models.Message{}, err
+	}
+	m.UserID = userID
+	if m, err = s.store.CreateMessage(ctx, m); err != nil {
+		return models.Message{}, err
+	}
+	s.Notify(userID)
+	return m, nil
+}
+
+// Broadcast puts an announcement from staff member senderID in the inbox of
+// every user with one of roles, or of every user when roles is empty, and
+// returns how many got it. Users who sign up later do not.
+func (s *Service) Broadcast(ctx context.Context, senderID int64, subject, body string, roles []string) (int, error) {
+	m, err := compose(models.MessageAnnouncement, senderID, subject, body)
+	if err != nil {
+		return 0, err
+	}
+	n, err := s.store.BroadcastMessage(ctx, m, roles)
+	if err != nil {
+		return 0, err
+	}
+	s.notifyAll()
+	return n, nil
+}
+
+// MarkRead marks the user's messages with the given IDs, or all of them when
+// ids is empty, read and returns how many are still unread. IDs of messages
+// that are not the user's or already read are ignored.
+func (s *Service) MarkRead(ctx context.Context, userID int64, ids []int64) (int, error) {
+	if _, err := s.store.MarkMessagesRead(ctx, userID, ids, s.clock.Now()); err != nil {
+		return 0, err
+	}
+	return s.store.CountUnreadMessages(ctx, userID)
+}
+
+// Cursor returns the ID of the user's newest message, where a stream that
+// resumes nothing starts.
+func (s *Service) Cursor(ctx context.Context, userID int64) (int64, error) {
+	return s.store.LastMessageID(ctx, userID)
+}
+
+// Since returns up to BatchSize of the user's messages after the one with
+// the given ID, oldest first.
+func (s *Service) Since(ctx context.Context, userID, after int64) ([]models.Message, error) {
+	return s.store.ListMessagesAfter(ctx, userID, after, BatchSize)
+}
+
+func compose(kind string, senderID int64, subject, body string) (models.Message, error) {
+	subject, body = strings.TrimSpace(subject), strings.TrimSpace(body)
+	switch {
+	case subject == "" || len(subject) > maxSubject:
+		return models.Message{}, fmt.Errorf("%w: subject is required and at most %d bytes", ErrInvalidMessage, maxSubject)
+	case body == "" || len(body) > maxBody:
+		return models.Message{}, fmt.Errorf("%w: body is required and at most %d bytes", ErrInvalidMessage, maxBody)
+	}
+	return models.Message{Kind: kind, Subject: subject, Body: body, SenderID: senderID}, nil
+}
+
+// Watch registers a stream of the user's messages. wake receives when
+// Notify is called for the user; stop unregisters it.
+func (s *Service) Watch(userID int64) (wake <-chan struct{}, stop func()) {
+	ch := make(chan struct{}, 1)
+	s.mu.Lock()
+	if s.watches[userID] == nil {
+		s.watches[userID] = make(map[chan struct{}]struct{})
+	}
+	s.watches[userID][ch] = struct{}{}
+	s.mu.Unlock()
+	return ch, func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		delete(s.watches[userID], ch)
+		if len(s.watches[userID]) == 0 {
+			delete(s.watches, userID)
+		}
+	}
+}
+
+// Notify wakes the user's streams on this instance. It never blocks: a
+// stream that has not caught up with the last wake-up reads this message too.
+func (s *Service) Notify(userID int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	wake(s.watches[userID])
+}
+
+// notifyAll wakes every stream on this instance; streams of users a
+// broadcast left out find nothing new.
+func (s *Service) notifyAll() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, watches := range s.watches {
+		wake(watches)
+	}
+}
+
+func wake(watches map[chan struct{}]struct{}) {
+	for ch := range watches {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Done is closed once Close is called; streams end when it is.
+func (s *Service) Done() <-chan struct{} {
+	return s.done
+}
+
+// Close ends every stream so the HTTP server can shut down; clients
+// reconnect to another instance and resume from their last message.
+func (s *Service) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.closed {
+		s.closed = true
+		close(s.done)
+	}
+}
@@ -0,0 +1,90 @@
+package inbox
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/hongminglow/all-in-be/internal/models"
+	"github.com/hongminglow/all-in-be/internal/storage"
+	"github.com/hongminglow/all-in-be/internal/storage/storagetest"
+)
+
+func TestBroadcastReachesTheRolesAndCountsUnread(t *testing.T) {
+	ctx := context.Background()
+	clk := storagetest.NewFakeClock(time.Date(2026, 6, 1, 9, 0, 0, 0, time.UTC))
+	store := storagetest.NewMemoryStore(clk)
+	staff, _ := store.CreateUser(ctx, models.User{Username: "sam", Email: "sam@example.com", Role: models.StaffUser})
+	ana, _ := store.CreateUser(ctx, models.User{Username: "ana", Email: "ana@example.com", Role: models.NormalUser})
+	vic, _ := store.CreateUser(ctx, models.User{Username: "vic", Email: "vic@example.com", Role: models.VIPUser})
+	service := NewService(store, clk)
+
+	if _, err := service.Broadcast(ctx, staff.ID, " ", "body", nil); !errors.Is(err, ErrInvalidMessage) {
+		t.Fatalf("announcement without a subject: err = %v, want ErrInvalidMessage", err)
+	}
+	if n, err := service.Broadcast(ctx, staff.ID, "VIP weekend", "Double points.", []string{models.VIPUser}); err != nil || n != 1 {
+		t.Fatalf("VIP broadcast reached %d, %v; want 1", n, err)
+	}
+	if n, err := service.Broadcast(ctx, staff.ID, "Maintenance", "Down at 02:00.", nil); err != nil || n != 3 {
+		t.Fatalf("broadcast to everyone reached %d, %v; want 3", n, err)
+	}
+	if _, err := service.Send(ctx, staff.ID, ana.ID+100, "Hello", "Hi"); !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("message to nobody: err = %v, want ErrNotFound", err)
+	}
+	reply, err := service.Send(ctx, staff.ID, ana.ID, "  Your withdrawal ", "It was paid.")
+	if err != nil || reply.Kind != models.MessageSupport || reply.Subject != "Your withdrawal" {
+		t.Fatalf("support message = %+v, %v", reply, err)
+	}
+
+	got, err := service.Inbox(ctx, ana.ID, false, 10)
+	if err != nil || got.Unread != 2 || len(got.Messages) != 2 || got.Messages[0].ID != reply.ID || got.Messages[1].Kind != models.MessageAnnouncement {
+		t.Fatalf("ana's inbox = %+v, %v", got, err)
+	}
+	vip, _ := service.Inbox(ctx, vic.ID, false, 10)
+	if vip.Unread != 2 {
+		t.Fatalf("vic's inbox = %+v, want both announcements", vip)
+	}
+
+	// Another user's message and an unknown ID are ignored.
+	if unread, err := service.MarkRead(ctx, ana.ID, []int64{reply.ID, vip.Messages[0].ID, 9999}); err != nil || unread != 1 {
+		t.Fatalf("unread after reading one = %d, %v; want 1", unread, err)
+	}
+	if still, _ := service.Inbox(ctx, vic.ID, true, 10); still.Unread != 2 {
+		t.Fatalf("vic's inbox = %+v, want it untouched", still)
+	}
+	unreadOnly, _ := service.Inbox(ctx, ana.ID, true, 10)
+	if len(unreadOnly.Messages) != 1 || unreadOnly.Messages[0].ReadAt != nil {
+		t.Fatalf("ana's unread messages = %+v", unreadOnly)
+	}
+	if unread, err := service.MarkRead(ctx, ana.ID, nil); err != nil || unread != 0 {
+		t.Fatalf("unread after reading all = %d, %v", unread, err)
+	}
+}
+
+func TestStreamsResumeAfterTheLastMessage(t *testing.T) {
+	ctx := context.Background()
+	clk := storagetest.NewFakeClock(time.Date(2026, 6, 1, 9, 0, 0, 0, time.UTC))
+	store := storagetest.NewMemoryStore(clk)
+	staff, _ := store.CreateUser(ctx, models.User{Username: "sam", Email: "sam@example.com", Role: models.StaffUser})
+	ana, _ := store.CreateUser(ctx, models.User{Username: "ana", Email: "ana@example.com", Role: models.NormalUser})
+	service := NewService(store, clk)
+	wake, stop := service.Watch(ana.ID)
+	defer stop()
+
+	first, _ := service.Send(ctx, staff.ID, ana.ID, "One", "First.")
+	if _, err := service.Broadcast(ctx, staff.ID, "Two", "Second.", nil); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-wake:
+	default:
+		t.Fatal("stream was not woken by the new messages")
+	}
+	if cursor, _ := service.Cursor(ctx, ana.ID); cursor <= first.ID {
+		t.Fatalf("cursor = %d, want past %d", cursor, first.ID)
+	}
+	if rest, err := service.Since(ctx, ana.ID, first.ID); err != nil || len(rest) != 1 || rest[0].Subject != "Two" {
+		t.Fatalf("messages after the first = %+v, %v", rest, err)
+	}
+}
@@ -0,0 +1,187 @@
+// Package integrations receives callbacks from external providers such as
+// payment processors. Every verified callback is stored verbatim before it is
+// processed, so a delivery that failed (for example during a provider or
+// database outage) can be inspected and replayed by an administrator.
+//
+// Processing is idempotent per provider event: the event is claimed in the same
+// transaction as the processor's writes, so retries from the provider and
+// manual replays never apply an event twice.
+package integrations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/hongminglow/all-in-be/internal/clock"
+	"github.com/hongminglow/all-in-be/internal/models"
+	"github.com/hongminglow/all-in-be/internal/storage"
+)
+
+var (
+	// ErrUnknownProvider is returned for callbacks to a provider with no registered processor.
+	ErrUnknownProvider = errors.New("unknown provider")
+	// ErrUnverified is returned when a processor rejects a callback's signature or shape.
+	ErrUnverified = errors.New("callback could not be verified")
+	// ErrAlreadyApplied is returned by Replay when the delivery's event was
+	// already applied, by this delivery or another one.
+	ErrAlreadyApplied = errors.New("event already applied")
+	// ErrProcessingFailed is returned when a stored delivery could not be applied.
+	ErrProcessingFailed = errors.New("processing failed")
+	// ErrSessionRejected is returned, wrapped with the reason, for a game
+	// provider's callback outside a live game session. Retrying cannot help.
+	ErrSessionRejected = errors.New("game session rejected")
+)
+
+// redactedHeaders are not persisted with a delivery.
+var redactedHeaders = []string{"Authorization", "Cookie", "Proxy-Authorization"}
+
+// Processor handles the callbacks of one provider.
+type Processor interface {
+	// Verify authenticates a callback and returns the provider's ID for the
+	// event it carries.
+	Verify(header http.Header, payload []byte) (eventID string, err error)
+	// Apply acts on the event. It runs in the transaction that claims the
+	// event, so its writes commit at most once per event.
+	Apply(ctx context.Context, tx storage.Repositories, payload []byte) error
+}
+
+// GameProcessor is a Processor for a game provider. Its callbacks move money
+// for bets, so they are applied only within the live game session they name.
+type GameProcessor interface {
+	Processor
+	// Session returns the game session token a callback carries, the user
+	// it is for, and the amount it moves: negative for a stake, positive for
+	// a win.
+	Session(payload []byte) (token string, userID int64, amount float64, err error)
+}
+
+// SessionChecker decides whether game providers' callbacks fall within a live
+// session.
+type SessionChecker interface {
+	// Check runs in the transaction that applies a callback moving amount,
+	// received at the given time. It returns ErrSessionRejected, wrapped with
+	// the reason, unless token names a live session of the user at provider
+	// and the player's limits allow the amount.
+	Check(ctx context.Context, tx storage.Repositories, provider, token string, userID int64, amount float64, at time.Time) error
+}
+
+// Service records and processes provider callbacks.
+type Service struct {
+	store      storage.Store
+	clock      clock.Clock
+	processors map[string]Processor
+	sessions   SessionChecker
+}
+
+// NewService builds a service that dispatches callbacks to processors by
+// provider name. Callbacks of GameProcessors are checked with sessions.
+func NewService(store storage.Store, clk clock.Clock, processors map[string]Processor, sessions SessionChecker) *Service {
+	return &Service{store: store, clock: clk, processors: processors, sessions: sessions}
+}
+
+// Receive verifies a callback, stores it, and processes it. The returned
+// delivery reflects the outcome; ErrProcessingFailed means it was stored but
+// could not be applied, and the provider should retry. ErrSessionRejected
+// means it was stored but will never be applied.
+func (s *Service) Receive(ctx context.Context, provider string, header http.Header, payload []byte) (models.InboundDelivery, error) {
+	p, ok := s.processors[provider]
+	if !ok {
+		return models.InboundDelivery{}, ErrUnknownProvider
+	}
+	eventID, err := p.Verify(header, payload)
+	if err != nil {
+		return models.InboundDelivery{}, fmt.Errorf("%w: %v", ErrUnverified, err)
+	}
+	delivery, err := s.store.RecordInboundDelivery(ctx, models.InboundDelivery{
+		Provider: provider,
+		EventID:  eventID,
+		Headers:  flattenHeaders(header),
+		Payload:  string(payload),
+		Status:   models.InboundReceived,
+	})
+	if err != nil {
+		return models.InboundDelivery{}, fmt.Errorf("record inbound delivery: %w", err)
+	}
+	return s.process(ctx, p, delivery)
+}
+
+// Replay processes a stored delivery again. It returns ErrAlreadyApplied
+// without side effects when the event has already been applied.
+func (s *Service) Replay(ctx context.Context, id int64) (models.InboundDelivery, error) {
+	delivery, err := s.store.FindInboundDelivery(ctx, id)
+	if err != nil {
+		return models.InboundDelivery{}, err
+	}
+	p, ok := s.processors[delivery.Provider]
+	if !ok {
+		return delivery, ErrUnknownProvider
+	}
+	delivery, err = s.process(ctx, p, delivery)
+	if err == nil && delivery.Status == models.InboundDuplicate {
+		return delivery, ErrAlreadyApplied
+	}
+	return delivery, err
+}
+
+func (s *Service) process(ctx context.Context, p Processor, delivery models.InboundDelivery) (models.InboundDelivery, error) {
+	err := s.store.WithTx(ctx, func(tx storage.Repositories) error {
+		if err := tx.ClaimInboundEvent(ctx, delivery.Provider, delivery.EventID, delivery.ID); err != nil {
+			return err
+		}
+		if game, ok := p.(GameProcessor); ok {
+			if err := s.checkSession(ctx, tx, game, delivery); err != nil {
+				return err
+			}
+		}
+		return p.Apply(ctx, tx, []byte(delivery.Payload))
+	})
+	status, errMsg := models.InboundProcessed, ""
+	switch {
+	case errors.Is(err, storage.ErrAlreadyExists):
+		status, err = models.InboundDuplicate, nil
+	case errors.Is(err, ErrSessionRejected):
+		status, errMsg = models.InboundFailed, err.Error()
+	case err != nil:
+		status, errMsg = models.InboundFailed, err.Error()
+		err = fmt.Errorf("%w: %v", ErrProcessingFailed, err)
+	}
+	now := s.clock.Now()
+	if uerr := s.store.UpdateInboundDelivery(ctx, delivery.ID, status, errMsg, now); uerr != nil {
+		return delivery, errors.Join(err, fmt.Errorf("update inbound delivery: %w", uerr))
+	}
+	delivery.Status = status
+	delivery.Error = errMsg
+	delivery.Attempts++
+	delivery.LastAttemptAt = &now
+	return delivery, err
+}
+
+// checkSession rejects a game provider's callback outside a live session.
+// Sessions are checked as of the time the callback arrived, so replaying a
+// delivery that failed during an outage still applies it.
+func (s *Service) checkSession(ctx context.Context, tx storage.Repositories, p GameProcessor, delivery models.InboundDelivery) error {
+	token, userID, amount, err := p.Session([]byte(delivery.Payload))
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrSessionRejected, err)
+	}
+	if s.sessions == nil {
+		return fmt.Errorf("%w: game sessions are not enabled", ErrSessionRejected)
+	}
+	return s.sessions.Check(ctx, tx, delivery.Provider, token, userID, amount, delivery.ReceivedAt)
+}
+
+func flattenHeaders(header http.Header) map[string]string {
+	flat := make(map[string]string, len(header))
+	for name, values := range header {
+		if len(values) > 0 {
+			flat[name] = values[0]
+		}
+	}
+	for _, name := range redactedHeaders {
+		delete(flat, name)
+	}
+	return flat
+}
@@ -0,0 +1,168 @@
+// Package iprisk screens client IPs at sign-in, registration and withdrawal.
+// A pluggable Provider says whether an address is a VPN, proxy or datacenter
+// address, and the policy for the tenant and the caller's country decides
+// whether to let it through, flag it for review, ask for step-up verification
+// or block it. Every risky request that is not explicitly allowed is recorded.
+package iprisk
+
+import (
+	"context"
+	"log"
+	"net/netip"
+	"sync"
+	"time"
+
+	"github.com/hongminglow/all-in-be/internal/models"
+	"github.com/hongminglow/all-in-be/internal/storage"
+)
+
+// Provider is an IP intelligence source.
+type Provider interface {
+	Assess(ctx context.Context, ip netip.Addr) (Assessment, error)
+}
+
+// signalNames lists every signal a Provider may raise.
+var signalNames = []string{models.SignalVPN, models.SignalProxy, models.SignalDatacenter}
+
+// Assessment is what a Provider knows about an address.
+type Assessment struct {
+	VPN        bool
+	Proxy      bool
+	Datacenter bool
+}
+
+// Signals lists the raised signals in a fixed order.
+func (a Assessment) Signals() []string {
+	var signals []string
+	if a.VPN {
+		signals = append(signals, models.SignalVPN)
+	}
+	if a.Proxy {
+		signals = append(signals, models.SignalProxy)
+	}
+	if a.Datacenter {
+		signals = append(signals, models.SignalDatacenter)
+	}
+	return signals
+}
+
+func (a *Assessment) set(signal string) {
+	switch signal {
+	case models.SignalVPN:
+		a.VPN = true
+	case models.SignalProxy:
+		a.Proxy = true
+	case models.SignalDatacenter:
+		a.Datacenter = true
+	}
+}
+
+// Request describes the request being screened.
+type Request struct {
+	Checkpoint string
+	Tenant     string
+	IP         string
+	Country    string
+	// UserID is the account involved, if it is known yet.
+	UserID *int64
+}
+
+// Screen applies the IP risk policies. A nil provider screens nothing.
+type Screen struct {
+	provider Provider
+	store    storage.IPRiskStore
+	fallback string
+	ttl      time.Duration
+
+	mu       sync.Mutex
+	loadedAt time.Time
+	policies map[[2]string]string
+}
+
+// NewScreen builds a screen whose policies are reloaded from store every ttl.
+// fallback is the action for risky IPs no stored policy covers.
+func NewScreen(provider Provider, store storage.IPRiskStore, fallback string, ttl time.Duration) *Screen {
+	return &Screen{provider: provider, store: store, fallback: fallback, ttl: ttl}
+}
+
+// Invalidate drops the cached policies so the next check reloads them.
+func (s *Screen) Invalidate() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.loadedAt = time.Time{}
+}
+
+// Check returns the action to take on req: models.IPRiskAllow for a clean
+// address, otherwise the resolved policy's action. Lookup and storage errors
+// are logged and the request is allowed, so an outage of the provider does
+// not lock players out.
+func (s *Screen) Check(ctx context.Context, req Request) string {
+	if s.provider == nil {
+		return models.IPRiskAllow
+	}
+	ip, err := netip.ParseAddr(req.IP)
+	if err != nil {
+		return models.IPRiskAllow
+	}
+	assessment, err := s.provider.Assess(ctx, ip)
+	if err != nil {
+		log.Printf("ip risk: assess %s: %v", ip, err)
+		return models.IPRiskAllow
+	}
+	signals := assessment.Signals()
+	if len(signals) == 0 {
+		return models.IPRiskAllow
+	}
+	action := s.action(ctx, req.Tenant, req.Country)
+	// There is no account to verify yet when signing up.
+	if action == models.IPRiskStepUp && req.Checkpoint == models.CheckpointRegistration {
+		action = models.IPRiskFlag
+	}
+	if action == models.IPRiskAllow {
+		return action
+	}
+	_, err = s.store.RecordIPRiskEvent(ctx, models.IPRiskEvent{
+		UserID:     req.UserID,
+		IP:         ip.String(),
+		Country:    req.Country,
+		Checkpoint: req.Checkpoint,
+		Signals:    signals,
+		Action:     action,
+	})
+	if err != nil {
+		log.Printf("ip risk: record %s event for %s: %v", req.Checkpoint, ip, err)
+	}
+	return action
+}
+
+// action resolves the policy for the tenant and country: the tenant's policy
+// for the country, then its catch-all, then the default tenant's for the
+// country and its catch-all, and finally the configured fallback.
+func (s *Screen) action(ctx context.Context, tenant, country string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if time.Since(s.loadedAt) > s.ttl {
+		s.reload(ctx)
+	}
+	for _, key := range [][2]string{{tenant, country}, {tenant, ""}, {"", country}, {"", ""}} {
+		if action, ok := s.policies[key]; ok {
+			return action
+		}
+	}
+	return s.fallback
+}
+
+// reload refreshes the cache. On failure the previous policies stay in effect.
+func (s *Screen) reload(ctx context.Context) {
+	s.loadedAt = time.Now()
+	list, err := s.store.ListIPRiskPolicies(ctx)
+	if err != nil {
+		log.Printf("ip risk: reload policies: %v", err)
+		return
+	}
+	policies := make(map[[2]string]string, len(list))
+	for _, p := range list {
+		policies[[2]string{p.Tenant, p.Country}] = p.Action
+	}
+	s.policies = policies
+}
@@ -0,0 +1,129 @@
+package iprisk
+
+import (
+	"context"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/hongminglow/all-in-be/internal/clock"
+	"github.com/hongminglow/all-in-be/internal/models"
+	"github.com/hongminglow/all-in-be/internal/storage/storagetest"
+)
+
+func writeList(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "risky.txt")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestListAssess(t *testing.T) {
+	list, err := OpenList(writeList(t, `
+# hosting ranges
+203.0.113.0/24 datacenter
+203.0.113.7    vpn proxy # exit node
+::ffff:198.51.100.0/120 proxy
+2001:db8::/32 vpn
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for ip, want := range map[string][]string{
+		"203.0.113.9":        {models.SignalDatacenter},
+		"203.0.113.7":        {models.SignalVPN, models.SignalProxy, models.SignalDatacenter},
+		"::ffff:203.0.113.7": {models.SignalVPN, models.SignalProxy, models.SignalDatacenter},
+		"198.51.100.20":      {models.SignalProxy},
+		"2001:db8::1":        {models.SignalVPN},
+		"192.0.2.1":          nil,
+	} {
+		a, err := list.Assess(context.Background(), netip.MustParseAddr(ip))
+		if err != nil || !slices.Equal(a.Signals(), want) {
+			t.Errorf("Assess(%s) = %v, %v; want %v", ip, a.Signals(), err, want)
+		}
+	}
+}
+
+func TestOpenListRejectsBadLines(t *testing.T) {
+	for _, content := range []string{"203.0.113.0/24\n", "not-an-ip vpn\n", "203.0.113.0/24 tor\n"} {
+		if _, err := OpenList(writeList(t, content)); err == nil {
+			t.Errorf("OpenList accepted %q", content)
+		}
+	}
+}
+
+type fakeProvider map[string]Assessment
+
+func (p fakeProvider) Assess(_ context.Context, ip netip.Addr) (Assessment, error) {
+	return p[ip.String()], nil
+}
+
+func TestScreenResolvesPolicies(t *testing.T) {
+	ctx := context.Background()
+	store := storagetest.NewMemoryStore(clock.System{})
+	for _, p := range []models.IPRiskPolicy{
+		{Country: "", Action: models.IPRiskFlag},
+		{Country: "MY", Action: models.IPRiskBlock},
+		{Tenant: "acme", Country: "", Action: models.IPRiskStepUp},
+		{Tenant: "acme", Country: "SG", Action: models.IPRiskAllow},
+	} {
+		if _, err := store.UpsertIPRiskPolicy(ctx, p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	provider := fakeProvider{"203.0.113.7": {VPN: true}}
+	screen := NewScreen(provider, store, models.IPRiskBlock, time.Minute)
+
+	cases := []struct {
+		name string
+		req  Request
+		want string
+	}{
+		{name: "clean address", req: Request{Checkpoint: models.CheckpointLogin, IP: "192.0.2.1", Country: "MY"}, want: models.IPRiskAllow},
+		{name: "country policy", req: Request{Checkpoint: models.CheckpointLogin, IP: "203.0.113.7", Country: "MY"}, want: models.IPRiskBlock},
+		{name: "default catch-all", req: Request{Checkpoint: models.CheckpointLogin, IP: "203.0.113.7", Country: "US"}, want: models.IPRiskFlag},
+		{name: "tenant country", req: Request{Checkpoint: models.CheckpointLogin, Tenant: "acme", IP: "203.0.113.7", Country: "SG"}, want: models.IPRiskAllow},
+		{name: "tenant catch-all", req: Request{Checkpoint: models.CheckpointLogin, Tenant: "acme", IP: "203.0.113.7", Country: "MY"}, want: models.IPRiskStepUp},
+		{name: "no step-up at registration", req: Request{Checkpoint: models.CheckpointRegistration, Tenant: "acme", IP: "203.0.113.7"}, want: models.IPRiskFlag},
+	}
+	for _, tc := range cases {
+		if got := screen.Check(ctx, tc.req); got != tc.want {
+			t.Errorf("%s: Check = %q, want %q", tc.name, got, tc.want)
+		}
+	}
+
+	events, err := store.ListIPRiskEvents(ctx, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 4 {
+		t.Fatalf("recorded %d events, want 4 (allowed requests are not recorded)", len(events))
+	}
+
+	// Without any stored policy the configured fallback applies once the cache is dropped.
+	for _, p := range []struct{ tenant, country string }{{"", ""}, {"", "MY"}} {
+		if err := store.DeleteIPRiskPolicy(ctx, p.tenant, p.country); err != nil {
+			t.Fatal(err)
+		}
+	}
+	req := Request{Checkpoint: models.CheckpointLogin, IP: "203.0.113.7", Country: "US"}
+	if got := screen.Check(ctx, req); got != models.IPRiskFlag {
+		t.Fatalf("cached Check = %q, want the cached flag", got)
+	}
+	screen.Invalidate()
+	if got := screen.Check(ctx, req); got != models.IPRiskBlock {
+		t.Fatalf("Check after Invalidate = %q, want the fallback block", got)
+	}
+}
+
+func TestScreenWithoutProvider(t *testing.T) {
+	screen := NewScreen(nil, storagetest.NewMemoryStore(clock.System{}), models.IPRiskBlock, time.Minute)
+	if got := screen.Check(context.Background(), Request{IP: "203.0.113.7"}); got != models.IPRiskAllow {
+		t.Fatalf("Check = %q, want allow", got)
+	}
+}
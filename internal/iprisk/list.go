@@ -0,0 +1,99 @@
+package iprisk
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net/netip"
+	"os"
+	"slices"
+	"strings"
+)
+
+// List is a Provider backed by a file of networks and the signals they raise,
+// such as an exported datacenter range list or a commercial VPN feed. Each
+// line is a CIDR followed by one or more of vpn, proxy and datacenter; blank
+// lines and # comments are ignored:
+//
+//	# hosting provider ranges
+//	203.0.113.0/24 datacenter
+//	198.51.100.7/32 vpn proxy
+type List struct {
+	// byBits maps each prefix length in the list to its masked networks, so a
+	// lookup costs one map probe per distinct length.
+	byBits map[int]map[netip.Prefix][]string
+	bits   []int
+}
+
+// OpenList reads a list file.
+func OpenList(path string) (*List, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open ip risk list: %w", err)
+	}
+	defer f.Close()
+
+	l := &List{byBits: map[int]map[netip.Prefix][]string{}}
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		prefix, err := netip.ParsePrefix(fields[0])
+		if err != nil {
+			if addr, addrErr := netip.ParseAddr(fields[0]); addrErr == nil {
+				prefix, err = addr.Prefix(addr.BitLen())
+			}
+		}
+		if err != nil || len(fields) < 2 {
+			return nil, fmt.Errorf("ip risk list %s:%d: want a network followed by signals", path, n)
+		}
+		signals := fields[1:]
+		for _, signal := range signals {
+			if !slices.Contains(signalNames, signal) {
+				return nil, fmt.Errorf("ip risk list %s:%d: unknown signal %q", path, n, signal)
+			}
+		}
+		prefix = unmap(prefix).Masked()
+		if l.byBits[prefix.Bits()] == nil {
+			l.byBits[prefix.Bits()] = map[netip.Prefix][]string{}
+			l.bits = append(l.bits, prefix.Bits())
+		}
+		l.byBits[prefix.Bits()][prefix] = append(l.byBits[prefix.Bits()][prefix], signals...)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read ip risk list: %w", err)
+	}
+	return l, nil
+}
+
+// Assess reports the signals of every listed network containing ip.
+func (l *List) Assess(_ context.Context, ip netip.Addr) (Assessment, error) {
+	ip = ip.Unmap()
+	var a Assessment
+	for _, bits := range l.bits {
+		if bits > ip.BitLen() {
+			continue
+		}
+		prefix, err := ip.Prefix(bits)
+		if err != nil {
+			continue
+		}
+		for _, signal := range l.byBits[bits][prefix] {
+			a.set(signal)
+		}
+	}
+	return a, nil
+}
+
+// unmap stores IPv4-mapped IPv6 networks as plain IPv4 ones.
+func unmap(p netip.Prefix) netip.Prefix {
+	if !p.Addr().Is4In6() || p.Bits() < 96 {
+		return p
+	}
+	return netip.PrefixFrom(p.Addr().Unmap(), p.Bits()-96)
+}
+
+var _ Provider = (*List)(nil)
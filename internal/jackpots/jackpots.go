@@ -0,0 +1,212 @@
+// Package jackpots feeds jackpot pots from stakes and pays them out.
+//
+// Every stake the seamless wallet takes adds each configured jackpot's
+// percentage of it to the jackpot's pot, in the transaction that takes the
+// stake. The contribution comes out of the house's share of the stake, so a
+// stake rolled back later leaves it in the pot. A win empties the pot into
+// the winner's wallet as one ledger entry.
+//
+// Pots are public. Open GET /jackpots/stream connections are woken as soon as
+// this instance moves a pot and poll it in between, which catches pots moved
+// on other instances.
+package jackpots
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"math"
+	"slices"
+	"sync"
+
+	"github.com/hongminglow/all-in-be/internal/events"
+	"github.com/hongminglow/all-in-be/internal/models"
+	"github.com/hongminglow/all-in-be/internal/outbox"
+	"github.com/hongminglow/all-in-be/internal/storage"
+	"github.com/hongminglow/all-in-be/internal/wallet"
+)
+
+var (
+	// ErrUnknownJackpot is returned for a jackpot that is not configured.
+	ErrUnknownJackpot = errors.New("unknown jackpot")
+	// ErrEmptyJackpot is returned for a win of a pot holding nothing.
+	ErrEmptyJackpot = errors.New("jackpot is empty")
+)
+
+// Store is where pots are kept and wins paid.
+type Store interface {
+	storage.UnitOfWork
+	storage.JackpotStore
+}
+
+// Service moves jackpot pots and wakes the streams watching them.
+type Service struct {
+	store    Store
+	outbox   *outbox.Relay
+	percents map[string]float64
+
+	mu      sync.Mutex
+	watches map[chan struct{}]struct{}
+	done    chan struct{}
+	closed  bool
+}
+
+// NewService builds a service feeding each jackpot in percents that
+// percentage of every stake. Wins are announced through the outbox relayed
+// by relay, which may be nil.
+func NewService(store Store, relay *outbox.Relay, percents map[string]float64) *Service {
+	return &Service{store: store, outbox: relay, percents: percents, watches: make(map[chan struct{}]struct{}), done: make(chan struct{})}
+}
+
+// Contribute adds every jackpot's share of a stake of the given size to its
+// pot in tx, the transaction that takes the stake, and reports whether any pot
+// grew. Call Notify once that transaction commits.
+func (s *Service) Contribute(ctx context.Context, tx storage.JackpotStore, stake float64, reference string) (bool, error) {
+	var grew bool
+	for _, name := range slices.Sorted(maps.Keys(s.percents)) {
+		share := math.Round(math.Abs(stake)*s.percents[name]) / 100
+		if share <= 0 {
+			continue
+		}
+		if _, err := tx.ContributeJackpot(ctx, name, share, reference); err != nil {
+			return false, err
+		}
+		grew = true
+	}
+	return grew, nil
+}
+
+// List returns every configured jackpot with its pot, ordered by name.
+func (s *Service) List(ctx context.Context) ([]models.Jackpot, error) {
+	pots, err := s.store.ListJackpots(ctx)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]models.Jackpot, 0, len(s.percents))
+	for _, name := range slices.Sorted(maps.Keys(s.percents)) {
+		jackpot := models.Jackpot{Name: name}
+		if i := slices.IndexFunc(pots, func(p models.Jackpot) bool { return p.Name == name }); i >= 0 {
+			jackpot = pots[i]
+		}
+		jackpot.ContributionPercent = s.percents[name]
+		out = append(out, jackpot)
+	}
+	return out, nil
+}
+
+// Win pays the whole pot of the named jackpot to the user. key names the win,
+// so a retried request gets the original payout back instead of the pot that
+// has grown since; reusing it for another user returns
+// wallet.ErrOperationConflict.
+func (s *Service) Win(ctx context.Context, name string, userID int64, key string) (models.Transaction, error) {
+	if _, ok := s.percents[name]; !ok {
+		return models.Transaction{}, ErrUnknownJackpot
+	}
+	op := wallet.Operation{Kind: models.OperationJackpotWin, Key: name + ":" + key}
+	var saved models.Transaction
+	var applied bool
+	err := s.store.WithTx(ctx, func(tx storage.Repositories) error {
+		prior, err := tx.FindOperation(ctx, op.Kind, op.Key)
+		if err == nil {
+			saved, err = replayWin(ctx, tx, prior, userID)
+			return err
+		}
+		if !errors.Is(err, storage.ErrNotFound) {
+			return fmt.Errorf("find %s operation: %w", op.Kind, err)
+		}
+		amount, err := tx.EmptyJackpot(ctx, name)
+		if err != nil {
+			return err
+		}
+		// A concurrent request with the same key may have won while this one
+		// waited for the pot's lock. Its win is replayed, and emptying what
+		// the pot has gained since is rolled back.
+		prior, err = tx.FindOperation(ctx, op.Kind, op.Key)
+		switch {
+		case err == nil:
+			if saved, err = replayWin(ctx, tx, prior, userID); err != nil {
+				return err
+			}
+			return errReplayed
+		case !errors.Is(err, storage.ErrNotFound):
+			return fmt.Errorf("find %s operation: %w", op.Kind, err)
+		case math.Round(amount*100) == 0:
+			return ErrEmptyJackpot
+		}
+		entry := models.Transaction{UserID: userID, Amount: amount, Reason: models.TransactionJackpotWin, Reference: op.Key}
+		if saved, applied, err = wallet.Apply(ctx, tx, op, entry); err != nil || !applied {
+			return err
+		}
+		return outbox.Add(ctx, tx, events.TypeBalanceChanged, wallet.BalanceChanged(saved))
+	})
+	if errors.Is(err, errReplayed) {
+		return saved, nil
+	}
+	if err != nil {
+		return models.Transaction{}, err
+	}
+	if applied {
+		s.outbox.Wake()
+		s.Notify()
+	}
+	return saved, nil
+}
+
+// errReplayed rolls back a win that found the key already paid.
+var errReplayed = errors.New("jackpot win replayed")
+
+// replayWin returns the payout of a win already made under the key. Only the
+// user is compared: the pot has usually grown since, so a retry asks for a
+// different amount.
+func replayWin(ctx context.Context, tx storage.WalletStore, prior models.Operation, userID int64) (models.Transaction, error) {
+	if prior.UserID != userID {
+		return models.Transaction{}, fmt.Errorf("%w: %s %q", wallet.ErrOperationConflict, prior.Kind, prior.Key)
+	}
+	if prior.TransactionID == nil {
+		return models.Transaction{}, fmt.Errorf("%s operation %q has no ledger entry", prior.Kind, prior.Key)
+	}
+	return tx.FindTransaction(ctx, *prior.TransactionID)
+}
+
+// Watch registers a stream of the pots. wake receives when Notify is called;
+// stop unregisters it.
+func (s *Service) Watch() (wake <-chan struct{}, stop func()) {
+	ch := make(chan struct{}, 1)
+	s.mu.Lock()
+	s.watches[ch] = struct{}{}
+	s.mu.Unlock()
+	return ch, func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		delete(s.watches, ch)
+	}
+}
+
+// Notify wakes every stream on this instance. It never blocks: a stream that
+// has not caught up with the last wake-up reads the newer pots anyway.
+func (s *Service) Notify() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for ch := range s.watches {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Done is closed once Close is called; streams end when it is.
+func (s *Service) Done() <-chan struct{} {
+	return s.done
+}
+
+// Close ends every stream so the HTTP server can shut down.
+func (s *Service) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.closed {
+		s.closed = true
+		close(s.done)
+	}
+}
@@ -0,0 +1,91 @@
+package jackpots
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/hongminglow/all-in-be/internal/models"
+	"github.com/hongminglow/all-in-be/internal/storage"
+	"github.com/hongminglow/all-in-be/internal/storage/storagetest"
+	"github.com/hongminglow/all-in-be/internal/wallet"
+)
+
+// racingStore runs race just before the next transaction and hides what it
+// wrote from that transaction's first FindOperation, the way a request that
+// looked its key up just before a concurrent one committed would see it.
+type racingStore struct {
+	*storagetest.MemoryStore
+	race func()
+}
+
+func (s *racingStore) WithTx(ctx context.Context, fn func(tx storage.Repositories) error) error {
+	race := s.race
+	if race == nil {
+		return s.MemoryStore.WithTx(ctx, fn)
+	}
+	s.race = nil
+	race()
+	return s.MemoryStore.WithTx(ctx, func(tx storage.Repositories) error {
+		return fn(&racingTx{Repositories: tx})
+	})
+}
+
+type racingTx struct {
+	storage.Repositories
+	looked bool
+}
+
+func (t *racingTx) FindOperation(ctx context.Context, kind, key string) (models.Operation, error) {
+	if !t.looked {
+		t.looked = true
+		return models.Operation{}, storage.ErrNotFound
+	}
+	return t.Repositories.FindOperation(ctx, kind, key)
+}
+
+func TestConcurrentWinWithTheSameKeyIsReplayed(t *testing.T) {
+	ctx := context.Background()
+	memory := storagetest.NewMemoryStore(storagetest.NewFakeClock(time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC)))
+	store := &racingStore{MemoryStore: memory}
+	pots := NewService(store, nil, map[string]float64{"mega": 1})
+	ana, err := memory.CreateUser(ctx, models.User{Username: "ana", Email: "ana@example.com", Role: models.NormalUser})
+	if err != nil {
+		t.Fatal(err)
+	}
+	bo, err := memory.CreateUser(ctx, models.User{Username: "bo", Email: "bo@example.com", Role: models.NormalUser})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := memory.ContributeJackpot(ctx, "mega", 250, "stake:1"); err != nil {
+		t.Fatal(err)
+	}
+
+	var first models.Transaction
+	store.race = func() {
+		if first, err = pots.Win(ctx, "mega", ana.ID, "spin-7"); err != nil {
+			t.Errorf("first win: %v", err)
+		}
+		// The pot grows again before the retry gets its lock.
+		if _, err := memory.ContributeJackpot(ctx, "mega", 5, "stake:2"); err != nil {
+			t.Error(err)
+		}
+	}
+	second, err := pots.Win(ctx, "mega", ana.ID, "spin-7")
+	if err != nil {
+		t.Fatalf("second win with the same key: %v, want the first payout replayed", err)
+	}
+	if first.Amount != 250 || second.ID != first.ID || second.Amount != first.Amount {
+		t.Fatalf("first = %+v, second = %+v; want the same 250 payout", first, second)
+	}
+	if _, err := pots.Win(ctx, "mega", bo.ID, "spin-7"); !errors.Is(err, wallet.ErrOperationConflict) {
+		t.Fatalf("the key reused by another user: err = %v, want ErrOperationConflict", err)
+	}
+	if third, err := pots.Win(ctx, "mega", ana.ID, "spin-8"); err != nil || third.Amount != 5 {
+		t.Fatalf("win under a new key = %+v, %v; want the 5 contributed since", third, err)
+	}
+	if _, err := pots.Win(ctx, "mega", ana.ID, "spin-9"); !errors.Is(err, ErrEmptyJackpot) {
+		t.Fatalf("win of the emptied pot under a new key: err = %v, want ErrEmptyJackpot", err)
+	}
+}
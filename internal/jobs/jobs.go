@@ -0,0 +1,409 @@
+// Package jobs runs background work on an in-process worker pool, retrying
+// failed jobs with exponential backoff. Pending jobs live in memory only and
+// are lost if the process exits before they run.
+//
+// Jobs wait in one of three priority lanes. Shared workers take the most
+// urgent job available, and workers can be reserved for a single lane so it
+// keeps moving while the shared ones are busy. A job that has waited longer
+// than the queue's max wait is taken ahead of more urgent work, so a steady
+// stream of high-priority jobs cannot starve the lower lanes.
+//
+// Jobs are grouped by type for monitoring, and a type can be paused during an
+// incident: its jobs are still accepted but held back until it is resumed.
+package jobs
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"log"
+	"slices"
+	"sync"
+	"time"
+)
+
+// ErrQueueFull is returned when the queue cannot accept more work.
+var ErrQueueFull = errors.New("job queue is full")
+
+// ErrQueueClosed is returned after Close has been called.
+var ErrQueueClosed = errors.New("job queue is closed")
+
+// Priority selects the lane a job waits in.
+type Priority string
+
+const (
+	// PriorityHigh is for money movement and security mail, e.g. payouts and password resets.
+	PriorityHigh Priority = "high"
+	// PriorityNormal is the default.
+	PriorityNormal Priority = "normal"
+	// PriorityLow is for bulk work that can wait, e.g. marketing mail.
+	PriorityLow Priority = "low"
+)
+
+// Priorities lists the lanes from most to least urgent.
+var Priorities = []Priority{PriorityHigh, PriorityNormal, PriorityLow}
+
+// lane returns p's index in Priorities; unknown priorities are normal.
+func (p Priority) lane() int {
+	if i := slices.Index(Priorities, p); i >= 0 {
+		return i
+	}
+	return 1
+}
+
+// Job is a unit of background work.
+type Job struct {
+	// Type groups jobs for stats and pausing, e.g. "webhook". Name is used when empty.
+	Type string
+	// Name identifies the job in logs.
+	Name string
+	// Priority defaults to PriorityNormal.
+	Priority Priority
+	// Run performs the work. attempt starts at 1; returning an error schedules a retry.
+	Run func(ctx context.Context, attempt int) error
+	// MaxAttempts overrides the queue default when positive.
+	MaxAttempts int
+}
+
+func (j Job) typ() string {
+	if j.Type != "" {
+		return j.Type
+	}
+	return j.Name
+}
+
+// TypeStats is a snapshot of one job type.
+type TypeStats struct {
+	Type string `json:"type"`
+	// Depth counts jobs waiting to run, including those held by a pause.
+	Depth int `json:"depth"`
+	// OldestAgeSeconds is how long the longest-waiting job has been queued.
+	OldestAgeSeconds float64 `json:"oldest_age_seconds"`
+	Running          int     `json:"running"`
+	// Succeeded and Failed count finished jobs; Failed ones exhausted their attempts.
+	Succeeded int64 `json:"succeeded"`
+	Failed    int64 `json:"failed"`
+	// Retries counts failed attempts that were retried.
+	Retries int64 `json:"retries"`
+	Paused  bool  `json:"paused"`
+}
+
+// LaneStats is a snapshot of one priority lane.
+type LaneStats struct {
+	Priority Priority `json:"priority"`
+	// Depth counts jobs waiting in the lane, excluding those held by a pause.
+	Depth            int     `json:"depth"`
+	OldestAgeSeconds float64 `json:"oldest_age_seconds"`
+	// ReservedWorkers only run jobs from this lane.
+	ReservedWorkers int `json:"reserved_workers"`
+}
+
+type queued struct {
+	job Job
+	at  time.Time
+}
+
+type typeState struct {
+	// waiting holds the enqueue times of pending jobs, oldest first.
+	waiting   []time.Time
+	held      []queued
+	running   int
+	succeeded int64
+	failed    int64
+	retries   int64
+	paused    bool
+}
+
+// Option configures a Queue.
+type Option func(*Queue)
+
+// WithReservedWorkers starts n extra workers that only run jobs of priority p.
+func WithReservedWorkers(p Priority, n int) Option {
+	return func(q *Queue) { q.reserved[p.lane()] += n }
+}
+
+// WithMaxWait sets how long a job may wait before it is taken ahead of more
+// urgent lanes. Zero turns this off and lanes are served strictly by priority.
+func WithMaxWait(d time.Duration) Option {
+	return func(q *Queue) { q.maxWait = d }
+}
+
+// Queue executes jobs on a fixed pool of workers.
+type Queue struct {
+	size     int
+	attempts int
+	backoff  time.Duration
+	timeout  time.Duration
+	maxWait  time.Duration
+	reserved [3]int
+
+	mu      sync.Mutex
+	ready   *sync.Cond
+	lanes   [3][]queued
+	pending int
+	closed  bool
+	types   map[string]*typeState
+	wg      sync.WaitGroup
+}
+
+// NewQueue starts workers shared goroutines plus any reserved by opts. size
+// bounds the number of pending jobs and backoff is the delay before the first
+// retry, doubling on each further attempt. Jobs waiting 30 seconds are
+// promoted past higher lanes unless WithMaxWait says otherwise.
+func NewQueue(workers, size int, backoff time.Duration, opts ...Option) *Queue {
+	q := &Queue{
+		size:     size,
+		attempts: 3,
+		backoff:  backoff,
+		timeout:  30 * time.Second,
+		maxWait:  30 * time.Second,
+		types:    make(map[string]*typeState),
+	}
+	q.ready = sync.NewCond(&q.mu)
+	for _, opt := range opts {
+		opt(q)
+	}
+	all := []int{0, 1, 2}
+	for range workers {
+		q.wg.Add(1)
+		go q.work(all)
+	}
+	for lane, n := range q.reserved {
+		for range n {
+			q.wg.Add(1)
+			go q.work([]int{lane})
+		}
+	}
+	return q
+}
+
+// Enqueue schedules job without blocking.
+func (q *Queue) Enqueue(job Job) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return ErrQueueClosed
+	}
+	if q.pending >= q.size {
+		return ErrQueueFull
+	}
+	entry := queued{job: job, at: time.Now()}
+	lane := job.Priority.lane()
+	q.lanes[lane] = append(q.lanes[lane], entry)
+	q.pending++
+	st := q.state(job.typ())
+	st.waiting = append(st.waiting, entry.at)
+	q.ready.Broadcast()
+	return nil
+}
+
+// Pause holds back jobs of the given type until Resume. Jobs already running finish.
+func (q *Queue) Pause(typ string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.state(typ).paused = true
+}
+
+// Resume puts the jobs held while the type was paused back at the front of
+// their lanes and lets new ones through.
+func (q *Queue) Resume(typ string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	st := q.state(typ)
+	st.paused = false
+	if q.closed {
+		return
+	}
+	for i := len(st.held) - 1; i >= 0; i-- {
+		entry := st.held[i]
+		lane := entry.job.Priority.lane()
+		q.lanes[lane] = slices.Insert(q.lanes[lane], 0, entry)
+		q.pending++
+	}
+	st.held = nil
+	q.ready.Broadcast()
+}
+
+// Stats returns a snapshot of every job type seen so far, sorted by type.
+func (q *Queue) Stats() []TypeStats {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	now := time.Now()
+	stats := make([]TypeStats, 0, len(q.types))
+	for typ, st := range q.types {
+		s := TypeStats{
+			Type:      typ,
+			Depth:     len(st.waiting),
+			Running:   st.running,
+			Succeeded: st.succeeded,
+			Failed:    st.failed,
+			Retries:   st.retries,
+			Paused:    st.paused,
+		}
+		if len(st.waiting) > 0 {
+			s.OldestAgeSeconds = now.Sub(st.waiting[0]).Seconds()
+		}
+		stats = append(stats, s)
+	}
+	slices.SortFunc(stats, func(a, b TypeStats) int { return cmp.Compare(a.Type, b.Type) })
+	return stats
+}
+
+// Lanes returns a snapshot of each priority lane, most urgent first.
+func (q *Queue) Lanes() []LaneStats {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	now := time.Now()
+	stats := make([]LaneStats, len(Priorities))
+	for i, p := range Priorities {
+		stats[i] = LaneStats{Priority: p, Depth: len(q.lanes[i]), ReservedWorkers: q.reserved[i]}
+		if len(q.lanes[i]) > 0 {
+			stats[i].OldestAgeSeconds = now.Sub(q.lanes[i][0].at).Seconds()
+		}
+	}
+	return stats
+}
+
+// Close stops accepting work and waits for queued jobs to finish or ctx to end.
+// Jobs held by a pause are dropped.
+func (q *Queue) Close(ctx context.Context) error {
+	q.mu.Lock()
+	q.closed = true
+	q.ready.Broadcast()
+	q.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		q.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for typ, st := range q.types {
+		if len(st.held) > 0 {
+			log.Printf("job queue closed with %d paused %s jobs dropped", len(st.held), typ)
+		}
+	}
+	return nil
+}
+
+// state returns the stats for typ, creating them on first use. mu must be held.
+func (q *Queue) state(typ string) *typeState {
+	st, ok := q.types[typ]
+	if !ok {
+		st = &typeState{}
+		q.types[typ] = st
+	}
+	return st
+}
+
+// work runs jobs from lanes until the queue is closed and those lanes are empty.
+func (q *Queue) work(lanes []int) {
+	defer q.wg.Done()
+	for {
+		entry, ok := q.next(lanes)
+		if !ok {
+			return
+		}
+		q.start(entry)
+	}
+}
+
+// next removes the job to run next from lanes, blocking until there is one.
+// Jobs of a paused type are set aside on the way. ok is false once the queue
+// is closed and lanes are drained.
+func (q *Queue) next(lanes []int) (queued, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for {
+		if lane := q.pick(lanes, time.Now()); lane >= 0 {
+			entry := q.lanes[lane][0]
+			q.lanes[lane] = q.lanes[lane][1:]
+			q.pending--
+			if st := q.state(entry.job.typ()); st.paused {
+				st.held = append(st.held, entry)
+				continue
+			}
+			return entry, true
+		}
+		if q.closed {
+			return queued{}, false
+		}
+		q.ready.Wait()
+	}
+}
+
+// pick returns the most urgent non-empty lane, unless a less urgent lane's
+// oldest job has waited past maxWait and longer than that lane's, or -1 if
+// every lane is empty. mu must be held.
+func (q *Queue) pick(lanes []int, now time.Time) int {
+	best := -1
+	for _, lane := range lanes {
+		if len(q.lanes[lane]) == 0 {
+			continue
+		}
+		if best < 0 {
+			best = lane
+			continue
+		}
+		head := q.lanes[lane][0].at
+		if q.maxWait > 0 && now.Sub(head) >= q.maxWait && head.Before(q.lanes[best][0].at) {
+			best = lane
+		}
+	}
+	return best
+}
+
+// start moves a pending job to running and runs it.
+func (q *Queue) start(entry queued) {
+	q.mu.Lock()
+	st := q.state(entry.job.typ())
+	if i := slices.Index(st.waiting, entry.at); i >= 0 {
+		st.waiting = slices.Delete(st.waiting, i, i+1)
+	}
+	st.running++
+	q.mu.Unlock()
+
+	err := q.run(entry.job, func() {
+		q.mu.Lock()
+		st.retries++
+		q.mu.Unlock()
+	})
+
+	q.mu.Lock()
+	st.running--
+	if err != nil {
+		st.failed++
+	} else {
+		st.succeeded++
+	}
+	q.mu.Unlock()
+}
+
+func (q *Queue) run(job Job, retried func()) error {
+	maxAttempts := q.attempts
+	if job.MaxAttempts > 0 {
+		maxAttempts = job.MaxAttempts
+	}
+	delay := q.backoff
+	for attempt := 1; ; attempt++ {
+		ctx, cancel := context.WithTimeout(context.Background(), q.timeout)
+		err := job.Run(ctx, attempt)
+		cancel()
+		if err == nil {
+			return nil
+		}
+		if attempt >= maxAttempts {
+			log.Printf("job %s: giving up after %d attempts: %v", job.Name, attempt, err)
+			return err
+		}
+		retried()
+		time.Sleep(delay)
+		delay *= 2
+	}
+}
@@ -0,0 +1,187 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestQueueRetriesAndDrains(t *testing.T) {
+	q := NewQueue(1, 4, time.Millisecond)
+
+	var calls, succeeded atomic.Int32
+	job := Job{Name: "flaky", Run: func(_ context.Context, attempt int) error {
+		calls.Add(1)
+		if attempt == 1 {
+			return errors.New("provider down")
+		}
+		succeeded.Add(1)
+		return nil
+	}}
+	if err := q.Enqueue(job); err != nil {
+		t.Fatalf("enqueue: %v", err)
+	}
+	if err := q.Close(context.Background()); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+	if calls.Load() != 2 || succeeded.Load() != 1 {
+		t.Fatalf("expected success on second attempt, got %d calls / %d successes", calls.Load(), succeeded.Load())
+	}
+	if err := q.Enqueue(job); !errors.Is(err, ErrQueueClosed) {
+		t.Fatalf("expected ErrQueueClosed, got %v", err)
+	}
+}
+
+func TestQueueGivesUpAfterMaxAttempts(t *testing.T) {
+	q := NewQueue(1, 1, time.Millisecond)
+
+	var calls atomic.Int32
+	_ = q.Enqueue(Job{Name: "broken", MaxAttempts: 4, Run: func(context.Context, int) error {
+		calls.Add(1)
+		return errors.New("always fails")
+	}})
+	_ = q.Close(context.Background())
+	if calls.Load() != 4 {
+		t.Fatalf("expected 4 attempts, got %d", calls.Load())
+	}
+}
+
+func TestQueuePauseHoldsJobsUntilResume(t *testing.T) {
+	q := NewQueue(1, 4, time.Millisecond)
+	q.Pause("webhook")
+
+	ran := make(chan string, 4)
+	_ = q.Enqueue(Job{Type: "webhook", Name: "held", Run: func(context.Context, int) error {
+		ran <- "webhook"
+		return nil
+	}})
+	_ = q.Enqueue(Job{Type: "notify", Name: "flows", Run: func(context.Context, int) error {
+		ran <- "notify"
+		return nil
+	}})
+	if got := <-ran; got != "notify" {
+		t.Fatalf("first job run = %s, want notify while webhook is paused", got)
+	}
+
+	stats := q.Stats()
+	if len(stats) != 2 || stats[1].Type != "webhook" || stats[1].Depth != 1 || !stats[1].Paused {
+		t.Fatalf("stats while paused = %+v", stats)
+	}
+	select {
+	case got := <-ran:
+		t.Fatalf("%s job ran while paused", got)
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	q.Resume("webhook")
+	if got := <-ran; got != "webhook" {
+		t.Fatalf("resumed job = %s", got)
+	}
+	if err := q.Close(context.Background()); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+	for _, s := range q.Stats() {
+		if s.Depth != 0 || s.Succeeded != 1 || s.Paused {
+			t.Fatalf("stats after resume = %+v", s)
+		}
+	}
+}
+
+func TestQueueStatsCountFailures(t *testing.T) {
+	q := NewQueue(1, 4, time.Millisecond)
+	_ = q.Enqueue(Job{Type: "event", Name: "broken", MaxAttempts: 3, Run: func(context.Context, int) error {
+		return errors.New("always fails")
+	}})
+	_ = q.Close(context.Background())
+
+	stats := q.Stats()
+	if len(stats) != 1 || stats[0].Failed != 1 || stats[0].Retries != 2 || stats[0].Succeeded != 0 {
+		t.Fatalf("stats = %+v, want 1 failure after 2 retries", stats)
+	}
+}
+
+// blockWorker occupies q's single shared worker until the returned func is called.
+func blockWorker(t *testing.T, q *Queue) func() {
+	t.Helper()
+	started, release := make(chan struct{}), make(chan struct{})
+	if err := q.Enqueue(Job{Name: "gate", Run: func(context.Context, int) error {
+		close(started)
+		<-release
+		return nil
+	}}); err != nil {
+		t.Fatalf("enqueue gate: %v", err)
+	}
+	<-started
+	return func() { close(release) }
+}
+
+func TestQueueRunsHigherPrioritiesFirst(t *testing.T) {
+	q := NewQueue(1, 8, time.Millisecond)
+	release := blockWorker(t, q)
+
+	ran := make(chan Priority, 3)
+	for _, p := range []Priority{PriorityLow, "", PriorityHigh} {
+		_ = q.Enqueue(Job{Name: "ordered", Priority: p, Run: func(context.Context, int) error {
+			ran <- p
+			return nil
+		}})
+	}
+	lanes := q.Lanes()
+	if lanes[0].Depth != 1 || lanes[1].Depth != 1 || lanes[2].Depth != 1 {
+		t.Fatalf("lanes before release = %+v", lanes)
+	}
+	release()
+	_ = q.Close(context.Background())
+	close(ran)
+
+	var order []Priority
+	for p := range ran {
+		order = append(order, p)
+	}
+	if len(order) != 3 || order[0] != PriorityHigh || order[1] != "" || order[2] != PriorityLow {
+		t.Fatalf("run order = %q, want high, normal, low", order)
+	}
+}
+
+func TestQueuePromotesStarvedJobs(t *testing.T) {
+	q := NewQueue(1, 8, time.Millisecond, WithMaxWait(5*time.Millisecond))
+	release := blockWorker(t, q)
+
+	ran := make(chan Priority, 2)
+	run := func(p Priority) func(context.Context, int) error {
+		return func(context.Context, int) error {
+			ran <- p
+			return nil
+		}
+	}
+	_ = q.Enqueue(Job{Name: "starved", Priority: PriorityLow, Run: run(PriorityLow)})
+	time.Sleep(10 * time.Millisecond)
+	_ = q.Enqueue(Job{Name: "urgent", Priority: PriorityHigh, Run: run(PriorityHigh)})
+	release()
+	if got := <-ran; got != PriorityLow {
+		t.Fatalf("first job = %s, want the low job that waited past max wait", got)
+	}
+	_ = q.Close(context.Background())
+}
+
+func TestQueueReservedWorkersServeTheirLane(t *testing.T) {
+	q := NewQueue(1, 8, time.Millisecond, WithReservedWorkers(PriorityHigh, 1))
+	release := blockWorker(t, q)
+	defer release()
+
+	ran := make(chan struct{})
+	_ = q.Enqueue(Job{Name: "payout", Priority: PriorityHigh, Run: func(context.Context, int) error {
+		close(ran)
+		return nil
+	}})
+	select {
+	case <-ran:
+	case <-time.After(time.Second):
+		t.Fatal("high-priority job waited for the busy shared worker")
+	}
+	if lanes := q.Lanes(); lanes[0].ReservedWorkers != 1 {
+		t.Fatalf("lanes = %+v, want one reserved high worker", lanes)
+	}
+}
@@ -0,0 +1,70 @@
+// Package leaderboard refreshes the standings snapshots the leaderboards are
+// read from. Ranking straight off the ledger on every request would scan it
+// for each caller; a snapshot trades a few minutes of staleness for that.
+package leaderboard
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/hongminglow/all-in-be/internal/storage"
+)
+
+// Service refreshes the standings on a schedule.
+type Service struct {
+	store    storage.LeaderboardStore
+	interval time.Duration
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewService builds a refresher; call Start to run it every interval.
+func NewService(store storage.LeaderboardStore, interval time.Duration) *Service {
+	return &Service{store: store, interval: interval}
+}
+
+// Refresh recomputes the standings now.
+func (s *Service) Refresh(ctx context.Context) error {
+	return s.store.RefreshLeaderboards(ctx)
+}
+
+// Start refreshes the standings every interval until Close. It does nothing
+// when no interval is configured.
+func (s *Service) Start() {
+	if s.interval <= 0 || s.stop != nil {
+		return
+	}
+	s.stop, s.done = make(chan struct{}), make(chan struct{})
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-s.stop
+		cancel()
+	}()
+	go func() {
+		defer close(s.done)
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := s.Refresh(ctx); err != nil && ctx.Err() == nil {
+					log.Printf("leaderboard: %v", err)
+				}
+			}
+		}
+	}()
+}
+
+// Close stops the schedule, cancelling a refresh in progress, and waits for it to end.
+func (s *Service) Close() {
+	if s.stop == nil {
+		return
+	}
+	close(s.stop)
+	<-s.done
+	s.stop = nil
+}
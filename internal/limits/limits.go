@@ -0,0 +1,225 @@
+// Package limits keeps the responsible gaming limits players set for
+// themselves and checks money movements against them: a daily deposit cap
+// checked when a deposit starts, a daily loss limit and a session length
+// checked by the game session service, and a cooling-off or self-exclusion
+// period during which games are closed to the player.
+//
+// Tightening a limit takes effect at once. Loosening or removing one only
+// takes effect after a delay, and an exclusion cannot be shortened.
+package limits
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/hongminglow/all-in-be/internal/clock"
+	"github.com/hongminglow/all-in-be/internal/models"
+	"github.com/hongminglow/all-in-be/internal/storage"
+)
+
+var (
+	// ErrDepositLimit is returned for a deposit that would take the day's
+	// settled deposits past the player's cap.
+	ErrDepositLimit = errors.New("daily deposit limit reached")
+	// ErrLossLimit is returned for a stake that would take the day's net loss
+	// past the player's limit.
+	ErrLossLimit = errors.New("daily loss limit reached")
+	// ErrExcluded is returned for players cooling off or self-excluded.
+	ErrExcluded = errors.New("player is self-excluded")
+	// ErrInvalidLimit is returned for limits and periods that are not positive.
+	ErrInvalidLimit = errors.New("limits must be positive")
+	// ErrShortenExclusion is returned for an exclusion ending before the one
+	// already in force.
+	ErrShortenExclusion = errors.New("an exclusion cannot be shortened")
+)
+
+// MaxSessionMinutes is the longest session limit a player can set.
+const MaxSessionMinutes = 24 * 60
+
+// Store is the data limits are checked against.
+type Store interface {
+	storage.GamingLimitStore
+	SumTransactions(ctx context.Context, userID int64, reason string, since time.Time) (float64, error)
+}
+
+// Find returns the player's limits, which are all off for players who never
+// set any.
+func Find(ctx context.Context, store storage.GamingLimitStore, userID int64) (models.GamingLimits, error) {
+	g, err := store.FindGamingLimits(ctx, userID)
+	if errors.Is(err, storage.ErrNotFound) {
+		return models.GamingLimits{UserID: userID}, nil
+	}
+	if err != nil {
+		return models.GamingLimits{}, fmt.Errorf("find gaming limits: %w", err)
+	}
+	return g, nil
+}
+
+// CheckDeposit refuses deposits by excluded players and deposits that would
+// take the day's settled deposits past the cap in force at the given time.
+// Deposits still waiting for their callback do not count.
+func CheckDeposit(ctx context.Context, store Store, userID int64, amount float64, at time.Time) error {
+	g, err := Find(ctx, store, userID)
+	if err != nil {
+		return err
+	}
+	if g.Excluded(at) {
+		return ErrExcluded
+	}
+	limit := g.Effective(at).DailyDeposit
+	if limit == nil {
+		return nil
+	}
+	deposited, err := store.SumTransactions(ctx, userID, models.TransactionDeposit, startOfDay(at))
+	if err != nil {
+		return err
+	}
+	if cents(deposited)+cents(amount) > cents(*limit) {
+		return ErrDepositLimit
+	}
+	return nil
+}
+
+// CheckStake checks a bet settlement of amount, negative for a stake, placed
+// at the given time. Excluded players cannot stake, and no stake may take the
+// day's net loss on bet settlements past the limit. Wins always settle, so
+// bets placed before an exclusion still pay out.
+func CheckStake(ctx context.Context, store Store, userID int64, amount float64, at time.Time) error {
+	if cents(amount) >= 0 {
+		return nil
+	}
+	g, err := Find(ctx, store, userID)
+	if err != nil {
+		return err
+	}
+	if g.Excluded(at) {
+		return ErrExcluded
+	}
+	limit := g.Effective(at).DailyLoss
+	if limit == nil {
+		return nil
+	}
+	net, err := store.SumTransactions(ctx, userID, models.TransactionBetSettlement, startOfDay(at))
+	if err != nil {
+		return err
+	}
+	if -(cents(net) + cents(amount)) > cents(*limit) {
+		return ErrLossLimit
+	}
+	return nil
+}
+
+// Service changes the limits players set for themselves.
+type Service struct {
+	store         storage.GamingLimitStore
+	clock         clock.Clock
+	increaseDelay time.Duration
+}
+
+// NewService builds a service that holds back looser limits for increaseDelay.
+func NewService(store storage.GamingLimitStore, clk clock.Clock, increaseDelay time.Duration) *Service {
+	return &Service{store: store, clock: clk, increaseDelay: increaseDelay}
+}
+
+// Get returns the player's limits.
+func (s *Service) Get(ctx context.Context, userID int64) (models.GamingLimits, error) {
+	return Find(ctx, s.store, userID)
+}
+
+// Set asks for the player's limits to become next. Limits that are tighter
+// than those in force apply at once; if any is looser, next as a whole waits
+// as pending for the increase delay. Asking again restarts the wait.
+func (s *Service) Set(ctx context.Context, userID int64, next models.LimitSet) (models.GamingLimits, error) {
+	if !valid(next) {
+		return models.GamingLimits{}, ErrInvalidLimit
+	}
+	g, err := Find(ctx, s.store, userID)
+	if err != nil {
+		return models.GamingLimits{}, err
+	}
+	now := s.clock.Now()
+	current := g.Effective(now)
+	applied := current
+	if stricter(next.DailyDeposit, current.DailyDeposit) {
+		applied.DailyDeposit = next.DailyDeposit
+	}
+	if stricter(next.DailyLoss, current.DailyLoss) {
+		applied.DailyLoss = next.DailyLoss
+	}
+	if stricter(minutes(next.SessionMinutes), minutes(current.SessionMinutes)) {
+		applied.SessionMinutes = next.SessionMinutes
+	}
+	loosened := stricter(current.DailyDeposit, next.DailyDeposit) ||
+		stricter(current.DailyLoss, next.DailyLoss) ||
+		stricter(minutes(current.SessionMinutes), minutes(next.SessionMinutes))
+
+	g.Limits, g.Pending, g.PendingFrom = applied, nil, nil
+	if loosened {
+		from := now.Add(s.increaseDelay)
+		g.Pending, g.PendingFrom = &next, &from
+	}
+	g.UpdatedAt = now
+	return s.save(ctx, g)
+}
+
+// Exclude closes games to the player for period from now. An exclusion in
+// force can be extended but not shortened.
+func (s *Service) Exclude(ctx context.Context, userID int64, period time.Duration) (models.GamingLimits, error) {
+	if period <= 0 {
+		return models.GamingLimits{}, ErrInvalidLimit
+	}
+	g, err := Find(ctx, s.store, userID)
+	if err != nil {
+		return models.GamingLimits{}, err
+	}
+	now := s.clock.Now()
+	until := now.Add(period)
+	if g.ExcludedUntil != nil && until.Before(*g.ExcludedUntil) {
+		return models.GamingLimits{}, ErrShortenExclusion
+	}
+	g.ExcludedUntil, g.UpdatedAt = &until, now
+	return s.save(ctx, g)
+}
+
+func (s *Service) save(ctx context.Context, g models.GamingLimits) (models.GamingLimits, error) {
+	saved, err := s.store.SaveGamingLimits(ctx, g)
+	if err != nil {
+		return models.GamingLimits{}, fmt.Errorf("save gaming limits: %w", err)
+	}
+	return saved, nil
+}
+
+func valid(l models.LimitSet) bool {
+	for _, amount := range []*float64{l.DailyDeposit, l.DailyLoss} {
+		if amount != nil && (math.IsNaN(*amount) || math.IsInf(*amount, 0) || cents(*amount) <= 0) {
+			return false
+		}
+	}
+	return l.SessionMinutes == nil || (*l.SessionMinutes > 0 && *l.SessionMinutes <= MaxSessionMinutes)
+}
+
+// stricter reports whether limit a is stricter than b, where nil is no limit.
+func stricter(a, b *float64) bool {
+	return a != nil && (b == nil || cents(*a) < cents(*b))
+}
+
+func minutes(m *int) *float64 {
+	if m == nil {
+		return nil
+	}
+	f := float64(*m)
+	return &f
+}
+
+// startOfDay is the midnight UTC daily limits count from.
+func startOfDay(at time.Time) time.Time {
+	return at.UTC().Truncate(24 * time.Hour)
+}
+
+// cents compares amounts the way the NUMERIC(24,2) columns store them.
+func cents(amount float64) int64 {
+	return int64(math.Round(amount * 100))
+}
@@ -0,0 +1,67 @@
+package limits
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/hongminglow/all-in-be/internal/models"
+	"github.com/hongminglow/all-in-be/internal/storage/storagetest"
+)
+
+func amount(v float64) *float64 { return &v }
+
+func TestLoosenedLimitsWaitForTheDelay(t *testing.T) {
+	ctx := context.Background()
+	clk := storagetest.NewFakeClock(time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC))
+	store := storagetest.NewMemoryStore(clk)
+	ana, _ := store.CreateUser(ctx, models.User{Username: "ana", Email: "ana@example.com", Role: models.NormalUser})
+	service := NewService(store, clk, 24*time.Hour)
+
+	if _, err := service.Set(ctx, ana.ID, models.LimitSet{DailyDeposit: amount(0)}); !errors.Is(err, ErrInvalidLimit) {
+		t.Fatalf("zero limit: err = %v, want ErrInvalidLimit", err)
+	}
+	set, err := service.Set(ctx, ana.ID, models.LimitSet{DailyDeposit: amount(100), DailyLoss: amount(50)})
+	if err != nil || set.Pending != nil || *set.Limits.DailyDeposit != 100 || *set.Limits.DailyLoss != 50 {
+		t.Fatalf("first limits = %+v, %v; want them applied at once", set, err)
+	}
+
+	// Lowering one limit applies at once even while lifting the other waits.
+	set, err = service.Set(ctx, ana.ID, models.LimitSet{DailyDeposit: amount(40)})
+	if err != nil || *set.Limits.DailyDeposit != 40 || set.Limits.DailyLoss == nil || *set.Limits.DailyLoss != 50 {
+		t.Fatalf("limits in force = %+v, %v; want the lower deposit cap and the loss limit kept", set.Limits, err)
+	}
+	if set.Pending == nil || set.Pending.DailyLoss != nil || !set.PendingFrom.Equal(clk.Now().Add(24*time.Hour)) {
+		t.Fatalf("pending = %+v from %v; want the lifted loss limit in 24h", set.Pending, set.PendingFrom)
+	}
+	clk.Advance(24 * time.Hour)
+	if got := set.Effective(clk.Now()); got.DailyLoss != nil || *got.DailyDeposit != 40 {
+		t.Fatalf("limits after the delay = %+v", got)
+	}
+
+	if err := CheckDeposit(ctx, store, ana.ID, 40, clk.Now()); err != nil {
+		t.Fatalf("deposit up to the cap: %v", err)
+	}
+	store.ApplyTransaction(ctx, models.Transaction{UserID: ana.ID, Amount: 30, Reason: models.TransactionDeposit})
+	if err := CheckDeposit(ctx, store, ana.ID, 10.01, clk.Now()); !errors.Is(err, ErrDepositLimit) {
+		t.Fatalf("deposit past the cap: err = %v, want ErrDepositLimit", err)
+	}
+	clk.Advance(24 * time.Hour)
+	if err := CheckDeposit(ctx, store, ana.ID, 40, clk.Now()); err != nil {
+		t.Fatalf("deposit the next day: %v", err)
+	}
+
+	if _, err := service.Exclude(ctx, ana.ID, 7*24*time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := service.Exclude(ctx, ana.ID, 24*time.Hour); !errors.Is(err, ErrShortenExclusion) {
+		t.Fatalf("shorter exclusion: err = %v, want ErrShortenExclusion", err)
+	}
+	if err := CheckDeposit(ctx, store, ana.ID, 1, clk.Now()); !errors.Is(err, ErrExcluded) {
+		t.Fatalf("deposit while excluded: err = %v, want ErrExcluded", err)
+	}
+	if err := CheckStake(ctx, store, ana.ID, 5, clk.Now()); err != nil {
+		t.Fatalf("win while excluded: %v", err)
+	}
+}
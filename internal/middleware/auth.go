@@ -0,0 +1,337 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/hongminglow/all-in-be/internal/auth"
+	"github.com/hongminglow/all-in-be/internal/http/respond"
+	"github.com/hongminglow/all-in-be/internal/models"
+	"github.com/hongminglow/all-in-be/internal/storage"
+	"github.com/hongminglow/all-in-be/internal/tracing"
+)
+
+type contextKey string
+
+const (
+	userContextKey   contextKey = "user"
+	scopeContextKey  contextKey = "scopes"
+	apiKeyContextKey contextKey = "api_key"
+	claimsContextKey contextKey = "claims"
+	// impersonationContextKey holds the impersonation an impersonation token
+	// belongs to.
+	impersonationContextKey contextKey = "impersonation"
+)
+
+// Authenticate requires a valid bearer token (or session cookie), or an API
+// key in the X-API-Key header, and loads the caller into the request context.
+// An API key's caller is the staff member who created it, limited to the
+// routes its scopes cover as if they had signed in with a scoped token. Requests made with
+// an impersonation token are checked against impersonations and logged.
+// Tokens are checked for revocation against sessions; API keys load their
+// creator from users.
+func Authenticate(tokens *auth.TokenManager, sessions *auth.Sessions, keys *auth.APIKeys, impersonations *auth.Impersonations, users storage.UserStore, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, status, message := identify(r, tokens, sessions, keys, impersonations, users)
+		if status != 0 {
+			respond.Error(w, status, message)
+			return
+		}
+		if imp, ok := ImpersonationFromContext(ctx); ok {
+			log.Printf("%simpersonation %d: user %d acting as user %d: %s %s", tracing.LogPrefix(ctx), imp.ID, imp.ActorID, imp.UserID, r.Method, r.URL.Path)
+		}
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// Identify is Authenticate for routes that also serve anonymous callers: the
+// caller is loaded when they present a usable token and the request goes on
+// without a user otherwise. Only storage errors are refused. API keys are not
+// accepted, since these routes serve people, and neither are impersonation
+// tokens.
+func Identify(tokens *auth.TokenManager, sessions *auth.Sessions, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, status, message := identify(r, tokens, sessions, nil, nil, nil)
+		if status == http.StatusInternalServerError {
+			respond.Error(w, status, message)
+			return
+		}
+		if status != 0 {
+			ctx = r.Context()
+		}
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// identify puts the caller named by the request's token or API key into the
+// returned context, or reports the status and message to refuse the request
+// with. A token's caller is taken from its claims, so authorization needs no
+// database lookup; only the cached session state is checked. keys and
+// impersonations are nil where API keys and impersonation tokens are not
+// accepted, and users is only needed with keys.
+func identify(r *http.Request, tokens *auth.TokenManager, sessions *auth.Sessions, keys *auth.APIKeys, impersonations *auth.Impersonations, users storage.UserStore) (context.Context, int, string) {
+	if secret := r.Header.Get(auth.APIKeyHeader); secret != "" && keys != nil {
+		return identifyKey(r, secret, keys, users)
+	}
+	raw, ok := bearerToken(r)
+	if !ok {
+		return nil, http.StatusUnauthorized, "missing bearer token"
+	}
+	claims, err := tokens.Parse(raw)
+	if err != nil {
+		return nil, http.StatusUnauthorized, "invalid token"
+	}
+	var imp models.Impersonation
+	if claims.ActorID != 0 {
+		if impersonations == nil {
+			return nil, http.StatusUnauthorized, "invalid token"
+		}
+		imp, err = impersonations.Verify(r.Context(), claims)
+		switch {
+		case errors.Is(err, auth.ErrInvalidImpersonation):
+			return nil, http.StatusUnauthorized, "invalid token"
+		case errors.Is(err, auth.ErrImpersonationRevoked):
+			return nil, http.StatusUnauthorized, "impersonation revoked"
+		case err != nil:
+			log.Printf("authenticate: verify impersonation of user %d: %v", claims.UserID, err)
+			return nil, http.StatusInternalServerError, "failed to verify impersonation"
+		}
+	}
+	state, err := sessions.State(r.Context(), claims.UserID)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil, http.StatusUnauthorized, "invalid token"
+		}
+		log.Printf("authenticate: load sessions of user %d: %v", claims.UserID, err)
+		return nil, http.StatusInternalServerError, "failed to load user"
+	}
+	// iat has second precision, so tokens issued during the second of the
+	// revocation stay valid; a user who signs in right after resetting their
+	// password must not be logged straight back out.
+	if state.RevokedAt != nil && claims.IssuedAt.Before(state.RevokedAt.Truncate(time.Second)) {
+		return nil, http.StatusUnauthorized, "session revoked"
+	}
+	// Tokens that survive the revocation above still cannot be used while
+	// the account is locked.
+	if state.ResetRequired {
+		return nil, http.StatusForbidden, "password reset required"
+	}
+	if state.MergedInto != nil {
+		return nil, http.StatusUnauthorized, "account was merged into another"
+	}
+	// Permissions come from the claims, so a token issued before they last
+	// changed would keep any that were revoked since.
+	if claims.PermissionsVersion < state.PermissionsVersion {
+		return nil, http.StatusForbidden, "permissions changed; sign in again"
+	}
+	ctx := context.WithValue(r.Context(), userContextKey, claims.User())
+	ctx = context.WithValue(ctx, claimsContextKey, claims)
+	if imp.ID != 0 {
+		ctx = context.WithValue(ctx, impersonationContextKey, imp)
+	}
+	if claims.Scopes != nil {
+		ctx = context.WithValue(ctx, scopeContextKey, claims.Scopes)
+	}
+	return ctx, 0, ""
+}
+
+// identifyKey loads the creator of the API key, narrowed to its scopes. The
+// key is held to the creator's sessions: revoking them, as a password change
+// or lock does, retires the keys they created before, and so does merging the
+// account away. A key always counts as scoped, so ScopeGate keeps it off
+// every route outside its scopes.
+func identifyKey(r *http.Request, secret string, keys *auth.APIKeys, users storage.UserStore) (context.Context, int, string) {
+	key, err := keys.Verify(r.Context(), secret)
+	switch {
+	case errors.Is(err, auth.ErrInvalidAPIKey), errors.Is(err, auth.ErrAPIKeyRevoked), errors.Is(err, auth.ErrAPIKeyExpired):
+		return nil, http.StatusUnauthorized, err.Error()
+	case err != nil:
+		log.Printf("authenticate: verify api key: %v", err)
+		return nil, http.StatusInternalServerError, "failed to verify API key"
+	}
+	user, err := users.FindByID(r.Context(), key.CreatedBy)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil, http.StatusUnauthorized, auth.ErrInvalidAPIKey.Error()
+		}
+		log.Printf("authenticate: load user %d for api key %d: %v", key.CreatedBy, key.ID, err)
+		return nil, http.StatusInternalServerError, "failed to load user"
+	}
+	if user.SessionsRevokedAt != nil && key.CreatedAt.Before(*user.SessionsRevokedAt) {
+		return nil, http.StatusUnauthorized, "session revoked"
+	}
+	if user.PasswordResetRequired {
+		return nil, http.StatusForbidden, "password reset required"
+	}
+	if user.MergedInto != nil {
+		return nil, http.StatusUnauthorized, "account was merged into another"
+	}
+	scopes := key.Scopes
+	if scopes == nil {
+		scopes = []string{}
+	}
+	ctx := context.WithValue(r.Context(), userContextKey, user)
+	ctx = context.WithValue(ctx, scopeContextKey, scopes)
+	ctx = context.WithValue(ctx, apiKeyContextKey, key)
+	return ctx, 0, ""
+}
+
+// RequirePermission rejects authenticated callers whose role lacks the named
+// permission, or whose token was issued with scopes that leave it out. It must
+// run after Authenticate. The permission is also the scope the route declares
+// to ScopeGate.
+func RequirePermission(permission string, next http.Handler) http.Handler {
+	return permissionGate{permission: permission, next: next}
+}
+
+// permissionGate is the handler RequirePermission returns.
+type permissionGate struct {
+	permission string
+	next       http.Handler
+}
+
+func (g permissionGate) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	user, ok := UserFromContext(r.Context())
+	if !ok {
+		respond.Error(w, http.StatusUnauthorized, "authentication required")
+		return
+	}
+	if !user.HasPermission(g.permission) {
+		respond.Error(w, http.StatusForbidden, "insufficient permissions")
+		return
+	}
+	if scopes, ok := ScopesFromContext(r.Context()); ok && !slices.Contains(scopes, g.permission) {
+		respond.Error(w, http.StatusForbidden, "token scope does not include "+g.permission)
+		return
+	}
+	g.next.ServeHTTP(w, r)
+}
+
+// ScopeGate makes scoped tokens and API keys deny by default: a route whose
+// handler is not wrapped in RequirePermission declares no scope, so it
+// refuses callers whose token carries one, whatever their role allows.
+// Routes must be wrapped as registered, before any other middleware.
+func ScopeGate(next http.Handler) http.Handler {
+	if _, ok := next.(permissionGate); ok {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, scoped := ScopesFromContext(r.Context()); scoped {
+			respond.Error(w, http.StatusForbidden, "this route is not available to scoped tokens")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// HasPermission reports whether the caller holds permission and, for a scoped
+// token, whether its scopes include it.
+func HasPermission(ctx context.Context, permission string) bool {
+	user, ok := UserFromContext(ctx)
+	if !ok || !user.HasPermission(permission) {
+		return false
+	}
+	scopes, scoped := ScopesFromContext(ctx)
+	return !scoped || slices.Contains(scopes, permission)
+}
+
+// UserFromContext returns the caller Authenticate identified, if any. For a
+// token this is what its claims say of the user; routes that need the rest of
+// the account are wrapped in LoadUser.
+func UserFromContext(ctx context.Context) (models.User, bool) {
+	user, ok := ctx.Value(userContextKey).(models.User)
+	return user, ok
+}
+
+// LoadUser replaces the caller Authenticate put in the context with their
+// stored account, for routes that need more than a token says, such as the
+// balance or the password hash. It must run after Authenticate.
+func LoadUser(users storage.UserStore, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		caller, ok := UserFromContext(r.Context())
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+		user, err := users.FindByID(r.Context(), caller.ID)
+		if errors.Is(err, storage.ErrNotFound) {
+			respond.Error(w, http.StatusUnauthorized, "invalid token")
+			return
+		}
+		if err != nil {
+			log.Printf("load user %d: %v", caller.ID, err)
+			respond.Error(w, http.StatusInternalServerError, "failed to load user")
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), userContextKey, user)))
+	})
+}
+
+// APIKeyFromContext returns the API key the caller authenticated with, if any.
+func APIKeyFromContext(ctx context.Context) (models.APIKey, bool) {
+	key, ok := ctx.Value(apiKeyContextKey).(models.APIKey)
+	return key, ok
+}
+
+// RefuseImpersonation rejects requests made with an impersonation token, for
+// routes only the player may use themselves, such as changing their password
+// or moving money. It must run after Authenticate.
+func RefuseImpersonation(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := ImpersonationFromContext(r.Context()); ok {
+			respond.Error(w, http.StatusForbidden, "not allowed while impersonating a user")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// ImpersonationFromContext returns the impersonation the caller's token
+// belongs to, if they are a staff member acting as the user.
+func ImpersonationFromContext(ctx context.Context) (models.Impersonation, bool) {
+	imp, ok := ctx.Value(impersonationContextKey).(models.Impersonation)
+	return imp, ok
+}
+
+// ClaimsFromContext returns the claims of the token Authenticate accepted.
+// ok is false for anonymous callers and API keys.
+func ClaimsFromContext(ctx context.Context) (auth.Claims, bool) {
+	claims, ok := ctx.Value(claimsContextKey).(auth.Claims)
+	return claims, ok
+}
+
+// TokenTenant resolves the tenant from the caller's token. It must run after
+// Authenticate; anonymous callers get the default tenant.
+func TokenTenant(r *http.Request) string {
+	claims, _ := ClaimsFromContext(r.Context())
+	return claims.Tenant
+}
+
+// ScopesFromContext returns the scopes of the caller's token. ok is false for
+// unrestricted tokens.
+func ScopesFromContext(ctx context.Context) ([]string, bool) {
+	scopes, ok := ctx.Value(scopeContextKey).([]string)
+	return scopes, ok
+}
+
+// bearerToken reads the token from the Authorization header, falling back to the
+// HttpOnly cookie set for browser clients.
+func bearerToken(r *http.Request) (string, bool) {
+	if header := r.Header.Get("Authorization"); header != "" {
+		scheme, token, found := strings.Cut(header, " ")
+		if !found || !strings.EqualFold(scheme, "Bearer") {
+			return "", false
+		}
+		token = strings.TrimSpace(token)
+		return token, token != ""
+	}
+	if cookie, err := r.Cookie(auth.CookieName); err == nil && cookie.Value != "" {
+		return cookie.Value, true
+	}
+	return "", false
+}
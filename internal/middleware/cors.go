@@ -2,38 +2,99 @@ package middleware
 
 import (
 	"net/http"
+	"regexp"
+	"strconv"
 	"strings"
+	"sync/atomic"
+	"time"
 )
 
-// CORS adds Access-Control headers for allowed origins and short-circuits OPTIONS requests.
-func CORS(allowedOrigins []string, next http.Handler) http.Handler {
+// CORSPolicy describes which cross-origin requests are allowed and what browsers may see.
+type CORSPolicy struct {
+	// AllowedOrigins holds exact origins, "*" for any origin, or subdomain wildcards
+	// such as "https://*.example.com".
+	AllowedOrigins []string
+	// OriginPatterns are matched against the full Origin header when no entry in
+	// AllowedOrigins matches.
+	OriginPatterns   []*regexp.Regexp
+	AllowedMethods   []string
+	AllowedHeaders   []string
+	ExposedHeaders   []string
+	AllowCredentials bool
+	MaxAge           time.Duration
+}
+
+// CORS adds Access-Control headers for allowed origins and answers preflight requests.
+func CORS(policy CORSPolicy, next http.Handler) http.Handler {
 	allowAll := false
-	normalized := make([]string, 0, len(allowedOrigins))
-	for _, origin := range allowedOrigins {
-		if origin == "*" {
+	var exact []string
+	var wildcards []originWildcard
+	for _, origin := range policy.AllowedOrigins {
+		origin = strings.ToLower(origin)
+		switch {
+		case origin == "*":
 			allowAll = true
-			break
+		case strings.Contains(origin, "://*."):
+			scheme, suffix, _ := strings.Cut(origin, "://*")
+			wildcards = append(wildcards, originWildcard{scheme: scheme + "://", suffix: suffix})
+		default:
+			exact = append(exact, origin)
+		}
+	}
+	allowMethods := strings.Join(policy.AllowedMethods, ", ")
+	allowHeaders := strings.Join(policy.AllowedHeaders, ", ")
+	exposeHeaders := strings.Join(policy.ExposedHeaders, ", ")
+	maxAge := strconv.Itoa(int(policy.MaxAge.Seconds()))
+
+	allowed := func(origin string) bool {
+		lower := strings.ToLower(origin)
+		if allowAll || containsOrigin(exact, lower) {
+			return true
+		}
+		for _, wc := range wildcards {
+			if wc.matches(lower) {
+				return true
+			}
+		}
+		for _, pattern := range policy.OriginPatterns {
+			if pattern.MatchString(origin) {
+				return true
+			}
 		}
-		normalized = append(normalized, strings.ToLower(origin))
+		return false
 	}
 
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		origin := r.Header.Get("Origin")
+		preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+
 		if origin != "" {
-			if allowAll || containsOrigin(normalized, origin) {
-				if allowAll {
+			// Responses differ per origin unless every origin gets the literal "*".
+			if !allowAll || policy.AllowCredentials {
+				w.Header().Add("Vary", "Origin")
+			}
+			if allowed(origin) {
+				if allowAll && !policy.AllowCredentials {
 					w.Header().Set("Access-Control-Allow-Origin", "*")
 				} else {
 					w.Header().Set("Access-Control-Allow-Origin", origin)
 				}
-				w.Header().Set("Vary", "Origin")
-				w.Header().Set("Access-Control-Allow-Credentials", "true")
-				w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
-				w.Header().Set("Access-Control-Allow-Methods", "GET,POST,PUT,PATCH,DELETE,OPTIONS")
+				if policy.AllowCredentials {
+					w.Header().Set("Access-Control-Allow-Credentials", "true")
+				}
+				if preflight {
+					w.Header().Set("Access-Control-Allow-Methods", allowMethods)
+					w.Header().Set("Access-Control-Allow-Headers", allowHeaders)
+					if policy.MaxAge > 0 {
+						w.Header().Set("Access-Control-Max-Age", maxAge)
+					}
+				} else if exposeHeaders != "" {
+					w.Header().Set("Access-Control-Expose-Headers", exposeHeaders)
+				}
 			}
 		}
 
-		if r.Method == http.MethodOptions {
+		if preflight {
 			w.WriteHeader(http.StatusNoContent)
 			return
 		}
@@ -42,6 +103,43 @@ func CORS(allowedOrigins []string, next http.Handler) http.Handler {
 	})
 }
 
+// ReloadableCORS applies a CORS policy that can be replaced while serving.
+type ReloadableCORS struct {
+	next    http.Handler
+	handler atomic.Pointer[http.Handler]
+}
+
+// NewReloadableCORS wraps next with policy until SetPolicy replaces it.
+func NewReloadableCORS(policy CORSPolicy, next http.Handler) *ReloadableCORS {
+	c := &ReloadableCORS{next: next}
+	c.SetPolicy(policy)
+	return c
+}
+
+// SetPolicy swaps the policy for subsequent requests.
+func (c *ReloadableCORS) SetPolicy(policy CORSPolicy) {
+	handler := CORS(policy, c.next)
+	c.handler.Store(&handler)
+}
+
+func (c *ReloadableCORS) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	(*c.handler.Load()).ServeHTTP(w, r)
+}
+
+// originWildcard matches any subdomain of suffix (".example.com") under scheme ("https://").
+type originWildcard struct {
+	scheme string
+	suffix string
+}
+
+func (wc originWildcard) matches(origin string) bool {
+	if !strings.HasPrefix(origin, wc.scheme) || !strings.HasSuffix(origin, wc.suffix) {
+		return false
+	}
+	sub := origin[len(wc.scheme) : len(origin)-len(wc.suffix)]
+	return sub != "" && !strings.ContainsAny(sub, "/:")
+}
+
 func containsOrigin(allowed []string, origin string) bool {
 	origin = strings.ToLower(origin)
 	for _, candidate := range allowed {
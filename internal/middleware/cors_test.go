@@ -0,0 +1,77 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestCORS(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	credentialed := CORSPolicy{
+		AllowedOrigins:   []string{"https://app.example.com", "https://*.partner.io"},
+		OriginPatterns:   []*regexp.Regexp{regexp.MustCompile(`^http://localhost:\d+$`)},
+		AllowedMethods:   []string{"GET", "POST"},
+		AllowedHeaders:   []string{"Content-Type"},
+		ExposedHeaders:   []string{"Retry-After"},
+		AllowCredentials: true,
+		MaxAge:           10 * time.Minute,
+	}
+
+	cases := []struct {
+		name       string
+		policy     CORSPolicy
+		method     string
+		origin     string
+		preflight  bool
+		wantOrigin string
+		wantCreds  string
+		wantMaxAge string
+		wantExpose string
+		wantVary   bool
+		wantCode   int
+	}{
+		{name: "wildcard without credentials", policy: CORSPolicy{AllowedOrigins: []string{"*"}}, method: http.MethodGet, origin: "https://any.test", wantOrigin: "*", wantCode: http.StatusOK},
+		{name: "exact origin echoed with credentials", policy: credentialed, method: http.MethodGet, origin: "https://app.example.com", wantOrigin: "https://app.example.com", wantCreds: "true", wantExpose: "Retry-After", wantVary: true, wantCode: http.StatusOK},
+		{name: "subdomain wildcard", policy: credentialed, method: http.MethodGet, origin: "https://eu.partner.io", wantOrigin: "https://eu.partner.io", wantCreds: "true", wantExpose: "Retry-After", wantVary: true, wantCode: http.StatusOK},
+		{name: "bare wildcard domain is not a subdomain", policy: credentialed, method: http.MethodGet, origin: "https://partner.io", wantVary: true, wantCode: http.StatusOK},
+		{name: "regex pattern", policy: credentialed, method: http.MethodGet, origin: "http://localhost:5173", wantOrigin: "http://localhost:5173", wantCreds: "true", wantExpose: "Retry-After", wantVary: true, wantCode: http.StatusOK},
+		{name: "disallowed origin", policy: credentialed, method: http.MethodGet, origin: "https://evil.test", wantVary: true, wantCode: http.StatusOK},
+		{name: "preflight", policy: credentialed, method: http.MethodOptions, origin: "https://app.example.com", preflight: true, wantOrigin: "https://app.example.com", wantCreds: "true", wantMaxAge: "600", wantVary: true, wantCode: http.StatusNoContent},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, "/health", nil)
+			req.Header.Set("Origin", tc.origin)
+			if tc.preflight {
+				req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+			}
+			rec := httptest.NewRecorder()
+			CORS(tc.policy, ok).ServeHTTP(rec, req)
+
+			h := rec.Header()
+			if rec.Code != tc.wantCode {
+				t.Errorf("status = %d, want %d", rec.Code, tc.wantCode)
+			}
+			if got := h.Get("Access-Control-Allow-Origin"); got != tc.wantOrigin {
+				t.Errorf("Allow-Origin = %q, want %q", got, tc.wantOrigin)
+			}
+			if got := h.Get("Access-Control-Allow-Credentials"); got != tc.wantCreds {
+				t.Errorf("Allow-Credentials = %q, want %q", got, tc.wantCreds)
+			}
+			if got := h.Get("Access-Control-Max-Age"); got != tc.wantMaxAge {
+				t.Errorf("Max-Age = %q, want %q", got, tc.wantMaxAge)
+			}
+			if got := h.Get("Access-Control-Expose-Headers"); got != tc.wantExpose {
+				t.Errorf("Expose-Headers = %q, want %q", got, tc.wantExpose)
+			}
+			if got := h.Get("Vary") == "Origin"; got != tc.wantVary {
+				t.Errorf("Vary: Origin present = %v, want %v", got, tc.wantVary)
+			}
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hongminglow/all-in-be/internal/http/respond"
+)
+
+func TestETag(t *testing.T) {
+	balance := 100
+	handler := Negotiate(ETag(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Has("fail") {
+			respond.Error(w, http.StatusServiceUnavailable, "try later")
+			return
+		}
+		respond.JSON(w, http.StatusOK, "profile fetched", map[string]int{"balance": balance})
+	})))
+	get := func(path string, header http.Header) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		for k, v := range header {
+			req.Header[k] = v
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	first := get("/me", nil)
+	tag := first.Header().Get("ETag")
+	if first.Code != http.StatusOK || tag == "" || first.Body.Len() == 0 || first.Header().Get("Cache-Control") != "private, no-cache" {
+		t.Fatalf("first response: %d, headers %v", first.Code, first.Header())
+	}
+	for _, inm := range []string{tag, `"stale", ` + tag, "W/" + tag, "*"} {
+		rec := get("/me", http.Header{"If-None-Match": {inm}})
+		if rec.Code != http.StatusNotModified || rec.Body.Len() != 0 || rec.Header().Get("ETag") != tag || rec.Header().Get("Content-Type") != "" {
+			t.Fatalf("If-None-Match %s: %d, headers %v, body %q", inm, rec.Code, rec.Header(), rec.Body)
+		}
+	}
+
+	if packed := get("/me", http.Header{"Accept": {"application/msgpack"}}); packed.Header().Get("ETag") == tag {
+		t.Fatal("MessagePack body shares the JSON body's tag")
+	}
+	balance = 90
+	if rec := get("/me", http.Header{"If-None-Match": {tag}}); rec.Code != http.StatusOK || rec.Header().Get("ETag") == tag {
+		t.Fatalf("changed body: %d, tag %s", rec.Code, rec.Header().Get("ETag"))
+	}
+	if rec := get("/me?fail", http.Header{"If-None-Match": {"*"}}); rec.Code != http.StatusServiceUnavailable || rec.Header().Get("ETag") != "" || rec.Body.Len() == 0 {
+		t.Fatalf("error response: %d, headers %v", rec.Code, rec.Header())
+	}
+}
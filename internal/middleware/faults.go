@@ -0,0 +1,48 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/hongminglow/all-in-be/internal/chaos"
+	"github.com/hongminglow/all-in-be/internal/http/respond"
+)
+
+// faultAdminPrefix is never faulted so a bad rule can always be removed.
+const faultAdminPrefix = "/admin/faults"
+
+// FaultInjection applies the injector's rules to matching requests: it delays them,
+// answers with an error status, or marks their context so database calls fail.
+// Affected responses carry an X-Fault-Injected header.
+func FaultInjection(injector *chaos.Injector, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, faultAdminPrefix) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		fault, fired := injector.Decide(r.Method, r.URL.Path)
+		if !fired {
+			next.ServeHTTP(w, r)
+			return
+		}
+		w.Header().Set("X-Fault-Injected", "true")
+		if fault.Latency > 0 {
+			timer := time.NewTimer(fault.Latency)
+			select {
+			case <-timer.C:
+			case <-r.Context().Done():
+				timer.Stop()
+				return
+			}
+		}
+		if fault.ErrorStatus != 0 {
+			respond.Error(w, fault.ErrorStatus, "injected fault")
+			return
+		}
+		if fault.DropDB {
+			r = r.WithContext(chaos.WithDBFailure(r.Context()))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"log"
+	"net/http"
+	"net/netip"
+
+	"github.com/hongminglow/all-in-be/internal/geoip"
+	"github.com/hongminglow/all-in-be/internal/http/respond"
+)
+
+// CountryLocator resolves an IP address to an ISO country code, "" if unknown.
+type CountryLocator interface {
+	Country(ip netip.Addr) (string, error)
+}
+
+// GeoIP tags each request with the caller's country, read with
+// geoip.CountryFromContext. The locator is consulted first when set; the CDN's
+// country header is used when it is nil or has no entry for the address.
+func GeoIP(locator CountryLocator, header string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		country := ""
+		if locator != nil {
+			if ip, err := netip.ParseAddr(ClientIP(r)); err == nil {
+				code, err := locator.Country(ip)
+				if err != nil {
+					log.Printf("geoip lookup %s: %v", ip, err)
+				}
+				country = geoip.Normalize(code)
+			}
+		}
+		if country == "" && header != "" {
+			country = geoip.Normalize(r.Header.Get(header))
+		}
+		next.ServeHTTP(w, r.WithContext(geoip.WithCountry(r.Context(), country)))
+	})
+}
+
+// BlockCountries refuses requests tagged by GeoIP with one of the blocked
+// countries. Requests from an unknown country are let through.
+func BlockCountries(blocked []string, next http.Handler) http.Handler {
+	set := make(map[string]bool, len(blocked))
+	for _, country := range blocked {
+		set[geoip.Normalize(country)] = true
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if country := geoip.CountryFromContext(r.Context()); country != "" && set[country] {
+			respond.Error(w, http.StatusUnavailableForLegalReasons, "this service is not available in your country")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
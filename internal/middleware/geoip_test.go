@@ -0,0 +1,58 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+
+	"github.com/hongminglow/all-in-be/internal/geoip"
+)
+
+type fakeLocator map[string]string
+
+func (l fakeLocator) Country(ip netip.Addr) (string, error) {
+	return l[ip.String()], nil
+}
+
+func TestGeoIPBlocksCountries(t *testing.T) {
+	var seen string
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = geoip.CountryFromContext(r.Context())
+		w.WriteHeader(http.StatusOK)
+	})
+	locator := fakeLocator{"203.0.113.9": "US", "198.51.100.1": "MY"}
+
+	cases := []struct {
+		name        string
+		locator     CountryLocator
+		remoteAddr  string
+		header      string
+		wantCountry string
+		wantCode    int
+	}{
+		{name: "database match", locator: locator, remoteAddr: "198.51.100.1:1234", wantCountry: "MY", wantCode: http.StatusOK},
+		{name: "database overrides header", locator: locator, remoteAddr: "203.0.113.9:1234", header: "MY", wantCode: http.StatusUnavailableForLegalReasons},
+		{name: "header when database has no entry", locator: locator, remoteAddr: "192.0.2.1:1234", header: "sg", wantCountry: "SG", wantCode: http.StatusOK},
+		{name: "header without database", remoteAddr: "203.0.113.9:1234", header: "us", wantCode: http.StatusUnavailableForLegalReasons},
+		{name: "unknown country is allowed", remoteAddr: "203.0.113.9:1234", header: "XX", wantCode: http.StatusOK},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			seen = ""
+			req := httptest.NewRequest(http.MethodPost, "/register", nil)
+			req.RemoteAddr = tc.remoteAddr
+			if tc.header != "" {
+				req.Header.Set("CF-IPCountry", tc.header)
+			}
+			rec := httptest.NewRecorder()
+			GeoIP(tc.locator, "CF-IPCountry", BlockCountries([]string{"us", "KP"}, ok)).ServeHTTP(rec, req)
+			if rec.Code != tc.wantCode {
+				t.Fatalf("status = %d, want %d", rec.Code, tc.wantCode)
+			}
+			if seen != tc.wantCountry {
+				t.Fatalf("country = %q, want %q", seen, tc.wantCountry)
+			}
+		})
+	}
+}
@@ -0,0 +1,45 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/hongminglow/all-in-be/internal/geoip"
+	"github.com/hongminglow/all-in-be/internal/http/respond"
+	"github.com/hongminglow/all-in-be/internal/iprisk"
+	"github.com/hongminglow/all-in-be/internal/models"
+)
+
+// IPScreener decides what to do about a request from the client's network,
+// returning one of models.IPRiskActions.
+type IPScreener interface {
+	Check(ctx context.Context, req iprisk.Request) string
+}
+
+// ScreenIP applies the IP risk policy at checkpoint, identifying the caller
+// when Authenticate has run first. Only sign-in can verify a user, so a
+// step_up outcome is refused here like block.
+func ScreenIP(screener IPScreener, tenant TenantResolver, checkpoint string, next http.Handler) http.Handler {
+	if tenant == nil {
+		tenant = func(*http.Request) string { return "" }
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req := iprisk.Request{
+			Checkpoint: checkpoint,
+			Tenant:     tenant(r),
+			IP:         ClientIP(r),
+			Country:    geoip.CountryFromContext(r.Context()),
+		}
+		if user, ok := UserFromContext(r.Context()); ok {
+			req.UserID = &user.ID
+		}
+		switch screener.Check(r.Context(), req) {
+		case models.IPRiskBlock:
+			respond.Error(w, http.StatusForbidden, "requests from this network are not allowed; turn off any VPN or proxy and try again")
+		case models.IPRiskStepUp:
+			respond.Error(w, http.StatusForbidden, "additional verification is required for requests from this network")
+		default:
+			next.ServeHTTP(w, r)
+		}
+	})
+}
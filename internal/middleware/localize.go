@@ -0,0 +1,33 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/hongminglow/all-in-be/internal/http/respond"
+	"github.com/hongminglow/all-in-be/internal/i18n"
+)
+
+// Localize translates the messages handlers write through the respond
+// package into the language negotiated from the Accept-Language header,
+// named in Content-Language. Responses vary on the header so caches keep one
+// copy per language.
+func Localize(translator *i18n.Translator, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		language := translator.Negotiate(r.Header.Get("Accept-Language"))
+		addVary(w.Header(), "Accept-Language")
+		w.Header().Set("Content-Language", language)
+		next.ServeHTTP(respond.WithLocalizer(w, func(message string) string {
+			return translator.Translate(language, message)
+		}), r)
+	})
+}
+
+// addVary adds name to the Vary header unless another middleware already did.
+func addVary(h http.Header, name string) {
+	for _, v := range h.Values("Vary") {
+		if v == name {
+			return
+		}
+	}
+	h.Add("Vary", name)
+}
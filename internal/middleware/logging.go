@@ -1,16 +1,163 @@
 package middleware
 
 import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
 	"log"
+	"log/slog"
+	"math/rand/v2"
+	"mime"
 	"net/http"
+	"net/url"
+	"strings"
 	"time"
+
+	"github.com/hongminglow/all-in-be/internal/tracing"
 )
 
-// Logging wraps an http.Handler and records request metadata.
-func Logging(next http.Handler) http.Handler {
+// BodyLogging selects the requests whose bodies Logging records. The zero
+// value records none.
+type BodyLogging struct {
+	// SampleRate is the fraction of requests, from 0 to 1, that are recorded.
+	SampleRate float64
+	// MaxBytes caps how much of each body is kept; longer bodies are logged
+	// by size only, since a truncated body cannot be redacted reliably.
+	MaxBytes int
+}
+
+// Logging wraps an http.Handler and records request metadata, prefixed with
+// the trace and span IDs when the request is traced. A sample of requests, per
+// bodies, is also written as a JSON record with the request and response
+// bodies, redacted of passwords, tokens and card numbers.
+func Logging(bodies BodyLogging, next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		start := time.Now()
-		next.ServeHTTP(w, r)
-		log.Printf("%s %s %s", r.Method, r.URL.Path, time.Since(start))
+		if bodies.SampleRate <= 0 || bodies.MaxBytes <= 0 || rand.Float64() >= bodies.SampleRate {
+			next.ServeHTTP(w, r)
+			log.Printf("%s%s %s %s", tracing.LogPrefix(r.Context()), r.Method, r.URL.Path, time.Since(start))
+			return
+		}
+		request := &capture{max: bodies.MaxBytes}
+		if r.Body != nil && r.Body != http.NoBody {
+			r.Body = &capturingBody{ReadCloser: r.Body, capture: request}
+		}
+		rec := &bodyRecorder{ResponseWriter: w, capture: capture{max: bodies.MaxBytes}}
+		next.ServeHTTP(rec, r)
+		elapsed := time.Since(start)
+		log.Printf("%s%s %s %s", tracing.LogPrefix(r.Context()), r.Method, r.URL.Path, elapsed)
+		logExchange(r.Context(), r, rec, request, elapsed)
 	})
 }
+
+func logExchange(ctx context.Context, r *http.Request, rec *bodyRecorder, request *capture, elapsed time.Duration) {
+	status := rec.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	attrs := []slog.Attr{
+		slog.String("method", r.Method),
+		slog.String("path", r.URL.Path),
+		slog.Int("status", status),
+		slog.Float64("duration_ms", float64(elapsed.Microseconds())/1000),
+	}
+	if sc := tracing.SpanContextFromContext(ctx); sc.IsValid() {
+		attrs = append(attrs, slog.String("trace_id", sc.TraceID.String()), slog.String("span_id", sc.SpanID.String()))
+	}
+	if r.URL.RawQuery != "" {
+		if query, err := url.ParseQuery(r.URL.RawQuery); err == nil {
+			attrs = append(attrs, slog.Any("query", redactValues(query)))
+		}
+	}
+	if request.total > 0 {
+		attrs = append(attrs, slog.Any("request_body", request.loggable(r.Header.Get("Content-Type"))))
+	}
+	if rec.total > 0 {
+		attrs = append(attrs, slog.Any("response_body", rec.loggable(rec.Header().Get("Content-Type"))))
+	}
+	slog.New(slog.NewJSONHandler(log.Writer(), nil)).LogAttrs(ctx, slog.LevelInfo, "http exchange", attrs...)
+}
+
+// capture keeps the first max bytes of a body and counts the rest.
+type capture struct {
+	max   int
+	buf   bytes.Buffer
+	total int
+	skip  bool
+}
+
+func (c *capture) record(p []byte) {
+	c.total += len(p)
+	if room := c.max - c.buf.Len(); room > 0 && !c.skip {
+		c.buf.Write(p[:min(room, len(p))])
+	}
+}
+
+// loggable returns the redacted body, or a description of it when it is
+// not JSON or a form, was skipped, or was longer than max.
+func (c *capture) loggable(contentType string) any {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	describe := fmt.Sprintf("[%d bytes of %s]", c.total, fallbackMediaType(mediaType))
+	if c.skip || c.total > c.buf.Len() {
+		return describe
+	}
+	switch {
+	case isJSONMediaType(mediaType):
+		if redacted, ok := redactJSON(c.buf.Bytes()); ok {
+			return redacted
+		}
+	case mediaType == "application/x-www-form-urlencoded":
+		if form, err := url.ParseQuery(c.buf.String()); err == nil {
+			return redactValues(form)
+		}
+	}
+	return describe
+}
+
+func fallbackMediaType(mediaType string) string {
+	if mediaType == "" {
+		return "unknown type"
+	}
+	return mediaType
+}
+
+// capturingBody records a request body as the handler reads it, so requests
+// the handler never reads in full are not held in memory.
+type capturingBody struct {
+	io.ReadCloser
+	capture *capture
+}
+
+func (b *capturingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.capture.record(p[:n])
+	return n, err
+}
+
+// bodyRecorder passes a response through while recording its status and
+// body. Event streams are passed through unrecorded.
+type bodyRecorder struct {
+	http.ResponseWriter
+	capture
+	status int
+}
+
+func (b *bodyRecorder) WriteHeader(status int) {
+	if b.status == 0 {
+		b.status = status
+		b.skip = strings.HasPrefix(b.Header().Get("Content-Type"), "text/event-stream")
+	}
+	b.ResponseWriter.WriteHeader(status)
+}
+
+func (b *bodyRecorder) Write(p []byte) (int, error) {
+	if b.status == 0 {
+		b.WriteHeader(http.StatusOK)
+	}
+	b.record(p)
+	return b.ResponseWriter.Write(p)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (b *bodyRecorder) Unwrap() http.ResponseWriter { return b.ResponseWriter }
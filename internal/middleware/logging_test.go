@@ -0,0 +1,102 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/hongminglow/all-in-be/internal/http/respond"
+)
+
+func TestLoggingRedactsSampledBodies(t *testing.T) {
+	var out bytes.Buffer
+	log.SetOutput(&out)
+	log.SetFlags(0)
+	t.Cleanup(func() {
+		log.SetOutput(os.Stderr)
+		log.SetFlags(log.LstdFlags)
+	})
+
+	handler := Logging(BodyLogging{SampleRate: 1, MaxBytes: 1024}, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body["password"] != "hunter22" {
+			t.Errorf("handler read %v, %v; want the original body", body, err)
+		}
+		respond.JSON(w, http.StatusCreated, "logged in", map[string]string{"access_token": "eyJhbGci", "username": "ana"})
+	}))
+	req := httptest.NewRequest(http.MethodPost, "/auth/login?refresh_token=abc&page=2", strings.NewReader(
+		`{"username":"ana","password":"hunter22","note":"card 4111 1111 1111 1111","order":"1234567890123"}`))
+	req.Header.Set("Content-Type", "application/json")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 || !strings.HasPrefix(lines[0], "POST /auth/login ") {
+		t.Fatalf("log = %q, want the request line and the exchange", out.String())
+	}
+	var record struct {
+		Msg      string              `json:"msg"`
+		Status   int                 `json:"status"`
+		Query    map[string][]string `json:"query"`
+		Request  map[string]string   `json:"request_body"`
+		Response struct {
+			Data map[string]string `json:"data"`
+		} `json:"response_body"`
+	}
+	if err := json.Unmarshal([]byte(lines[1]), &record); err != nil {
+		t.Fatalf("exchange %q is not JSON: %v", lines[1], err)
+	}
+	if record.Msg != "http exchange" || record.Status != http.StatusCreated {
+		t.Errorf("record = %+v", record)
+	}
+	if record.Query["refresh_token"][0] != redacted || record.Query["page"][0] != "2" {
+		t.Errorf("query = %v", record.Query)
+	}
+	want := map[string]string{"username": "ana", "password": redacted, "note": "card " + redacted, "order": "1234567890123"}
+	for key, value := range want {
+		if record.Request[key] != value {
+			t.Errorf("request_body.%s = %q, want %q", key, record.Request[key], value)
+		}
+	}
+	if record.Response.Data["access_token"] != redacted || record.Response.Data["username"] != "ana" {
+		t.Errorf("response_body data = %v", record.Response.Data)
+	}
+	if strings.Contains(out.String(), "hunter22") || strings.Contains(out.String(), "eyJhbGci") {
+		t.Errorf("log leaks a secret: %s", out.String())
+	}
+}
+
+func TestLoggingDescribesBodiesItCannotRedact(t *testing.T) {
+	var out bytes.Buffer
+	log.SetOutput(&out)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+
+	handler := Logging(BodyLogging{SampleRate: 1, MaxBytes: 8}, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.WriteHeader(http.StatusOK)
+		_, _ = io.WriteString(w, "data: {}\n\n")
+		if err := http.NewResponseController(w).Flush(); err != nil {
+			t.Errorf("flush through the logger: %v", err)
+		}
+	}))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/events", nil))
+	if !strings.Contains(out.String(), `"response_body":"[10 bytes of text/event-stream]"`) {
+		t.Errorf("log = %s, want the stream described by size", out.String())
+	}
+
+	out.Reset()
+	handler = Logging(BodyLogging{SampleRate: 1, MaxBytes: 8}, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.ReadAll(r.Body)
+	}))
+	req := httptest.NewRequest(http.MethodPost, "/me", strings.NewReader(`{"password":"too long to keep"}`))
+	req.Header.Set("Content-Type", "application/json")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	if strings.Contains(out.String(), "too long") || !strings.Contains(out.String(), "bytes of application/json]") {
+		t.Errorf("log = %s, want the oversized body described by size", out.String())
+	}
+}
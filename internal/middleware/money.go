@@ -0,0 +1,18 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/hongminglow/all-in-be/internal/money"
+)
+
+// MoneyFormat tags each request with the money format negotiated from its
+// Accept-Language header, read with money.FormatFromContext. Responses vary
+// on the header so caches keep one copy per language.
+func MoneyFormat(formatter *money.Formatter, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		addVary(w.Header(), "Accept-Language")
+		format := formatter.Negotiate(r.Header.Get("Accept-Language"))
+		next.ServeHTTP(w, r.WithContext(money.WithFormat(r.Context(), format)))
+	})
+}
@@ -0,0 +1,16 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/hongminglow/all-in-be/internal/http/respond"
+)
+
+// Negotiate encodes the responses handlers write through the respond package
+// in the format the Accept header prefers: JSON or MessagePack.
+func Negotiate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept")
+		next.ServeHTTP(respond.WithEncoder(w, respond.Negotiate(r.Header.Get("Accept"))), r)
+	})
+}
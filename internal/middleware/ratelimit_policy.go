@@ -0,0 +1,144 @@
+package middleware
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/hongminglow/all-in-be/internal/models"
+)
+
+// TenantResolver extracts the tenant a request belongs to. An empty string selects
+// the default policies.
+type TenantResolver func(r *http.Request) string
+
+// PolicySource lists the stored rate-limit policies.
+type PolicySource interface {
+	ListRateLimitPolicies(ctx context.Context) ([]models.RateLimitPolicy, error)
+}
+
+// RateLimitPolicies resolves rate-limit policies per tenant and route class from the
+// database, caching them for ttl. Resolution order is the tenant's own policy, then
+// the default tenant's policy, then the static fallback for the route class. Route
+// classes with no policy at all are not limited.
+type RateLimitPolicies struct {
+	store     PolicySource
+	ttl       time.Duration
+	fallbacks map[string]models.RateLimitPolicy
+	tenant    TenantResolver
+
+	mu       sync.Mutex
+	loadedAt time.Time
+	policies map[policyKey]models.RateLimitPolicy
+	limiters map[policyKey]*policyLimiter
+}
+
+type policyKey struct {
+	tenant     string
+	routeClass string
+}
+
+type policyLimiter struct {
+	policy  models.RateLimitPolicy
+	limiter *RateLimiter
+}
+
+// NewRateLimitPolicies builds a resolver backed by store. fallbacks are keyed by route class.
+func NewRateLimitPolicies(store PolicySource, ttl time.Duration, tenant TenantResolver, fallbacks ...models.RateLimitPolicy) *RateLimitPolicies {
+	if tenant == nil {
+		tenant = func(*http.Request) string { return "" }
+	}
+	return &RateLimitPolicies{
+		store:     store,
+		ttl:       ttl,
+		fallbacks: fallbacksByClass(fallbacks),
+		tenant:    tenant,
+		limiters:  make(map[policyKey]*policyLimiter),
+	}
+}
+
+// SetFallbacks replaces the static policies used when the database has none,
+// e.g. after a configuration reload.
+func (p *RateLimitPolicies) SetFallbacks(fallbacks ...models.RateLimitPolicy) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.fallbacks = fallbacksByClass(fallbacks)
+}
+
+func fallbacksByClass(fallbacks []models.RateLimitPolicy) map[string]models.RateLimitPolicy {
+	byClass := make(map[string]models.RateLimitPolicy, len(fallbacks))
+	for _, p := range fallbacks {
+		byClass[p.RouteClass] = p
+	}
+	return byClass
+}
+
+// Invalidate drops the cached policies so the next request reloads them.
+func (p *RateLimitPolicies) Invalidate() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.loadedAt = time.Time{}
+}
+
+// limiter returns the limiter for the tenant and route class, or nil when unlimited.
+func (p *RateLimitPolicies) limiter(ctx context.Context, tenant, routeClass string) *RateLimiter {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if time.Since(p.loadedAt) > p.ttl {
+		p.reload(ctx)
+	}
+
+	policy, ok := p.policies[policyKey{tenant, routeClass}]
+	if !ok {
+		policy, ok = p.policies[policyKey{"", routeClass}]
+	}
+	if !ok {
+		policy, ok = p.fallbacks[routeClass]
+	}
+	if !ok {
+		return nil
+	}
+
+	key := policyKey{policy.Tenant, routeClass}
+	current, exists := p.limiters[key]
+	if !exists || current.policy.Requests != policy.Requests || current.policy.WindowSeconds != policy.WindowSeconds {
+		current = &policyLimiter{policy: policy, limiter: NewRateLimiter(policy.Requests, policy.Window())}
+		p.limiters[key] = current
+	}
+	return current.limiter
+}
+
+// reload refreshes the cache. On failure the previous policies stay in effect.
+func (p *RateLimitPolicies) reload(ctx context.Context) {
+	p.loadedAt = time.Now()
+	list, err := p.store.ListRateLimitPolicies(ctx)
+	if err != nil {
+		log.Printf("rate limit: reload policies: %v", err)
+		return
+	}
+	policies := make(map[policyKey]models.RateLimitPolicy, len(list))
+	for _, policy := range list {
+		policies[policyKey{policy.Tenant, policy.RouteClass}] = policy
+	}
+	p.policies = policies
+}
+
+// PolicyRateLimit limits requests for routeClass using the policy resolved for the
+// request's tenant, rejecting with 429 once the client IP exhausts its budget.
+func PolicyRateLimit(policies *RateLimitPolicies, routeClass string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		limiter := policies.limiter(r.Context(), policies.tenant(r), routeClass)
+		if limiter == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if allowed, wait := limiter.Allow(ClientIP(r)); !allowed {
+			tooManyRequests(w, wait)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
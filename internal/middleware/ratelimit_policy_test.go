@@ -0,0 +1,52 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/hongminglow/all-in-be/internal/models"
+)
+
+type fakePolicyStore struct {
+	policies []models.RateLimitPolicy
+	loads    int
+}
+
+func (f *fakePolicyStore) ListRateLimitPolicies(context.Context) ([]models.RateLimitPolicy, error) {
+	f.loads++
+	return f.policies, nil
+}
+
+func TestRateLimitPoliciesResolution(t *testing.T) {
+	store := &fakePolicyStore{policies: []models.RateLimitPolicy{
+		{Tenant: "", RouteClass: models.RouteClassAPI, Requests: 100, WindowSeconds: 60},
+		{Tenant: "gold", RouteClass: models.RouteClassAPI, Requests: 1000, WindowSeconds: 60},
+	}}
+	fallback := models.RateLimitPolicy{RouteClass: models.RouteClassAuth, Requests: 5, WindowSeconds: 60}
+	policies := NewRateLimitPolicies(store, time.Minute, func(r *http.Request) string { return "" }, fallback)
+	ctx := context.Background()
+
+	if got := policies.limiter(ctx, "gold", models.RouteClassAPI); got == nil || got.burst != 1000 {
+		t.Fatalf("gold tenant should get its own policy, got %+v", got)
+	}
+	if got := policies.limiter(ctx, "bronze", models.RouteClassAPI); got == nil || got.burst != 100 {
+		t.Fatalf("unknown tenant should get the default tenant policy, got %+v", got)
+	}
+	if got := policies.limiter(ctx, "gold", models.RouteClassAuth); got == nil || got.burst != 5 {
+		t.Fatalf("route class without rows should use the fallback, got %+v", got)
+	}
+	if got := policies.limiter(ctx, "", "unknown"); got != nil {
+		t.Fatal("route class without any policy should be unlimited")
+	}
+	if store.loads != 1 {
+		t.Fatalf("policies loaded %d times, want 1 (cached)", store.loads)
+	}
+
+	store.policies[0].Requests = 50
+	policies.Invalidate()
+	if got := policies.limiter(ctx, "", models.RouteClassAPI); got == nil || got.burst != 50 {
+		t.Fatalf("invalidate should pick up the changed policy, got %+v", got)
+	}
+}
@@ -0,0 +1,60 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRateLimiterRefills(t *testing.T) {
+	now := time.Unix(0, 0)
+	limiter := NewRateLimiter(2, time.Minute)
+	limiter.now = func() time.Time { return now }
+
+	for i := 0; i < 2; i++ {
+		if ok, _ := limiter.Allow("a"); !ok {
+			t.Fatalf("request %d should be allowed", i+1)
+		}
+	}
+	ok, wait := limiter.Allow("a")
+	if ok {
+		t.Fatal("third request should be limited")
+	}
+	if wait != 30*time.Second {
+		t.Fatalf("wait = %s, want 30s", wait)
+	}
+	if ok, _ := limiter.Allow("b"); !ok {
+		t.Fatal("other clients must have their own bucket")
+	}
+
+	now = now.Add(30 * time.Second)
+	if ok, _ := limiter.Allow("a"); !ok {
+		t.Fatal("one token should have refilled after 30s")
+	}
+}
+
+func TestRateLimitMiddleware(t *testing.T) {
+	limiter := NewRateLimiter(1, time.Minute)
+	handler := RateLimit(limiter, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, "/login", nil)
+	req.RemoteAddr = "203.0.113.7:5555"
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("first status = %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("second status = %d, want 429", rec.Code)
+	}
+	if rec.Header().Get("Retry-After") != "60" {
+		t.Fatalf("Retry-After = %q, want 60", rec.Header().Get("Retry-After"))
+	}
+}
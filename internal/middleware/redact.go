@@ -0,0 +1,129 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+const redacted = "[REDACTED]"
+
+// sensitiveKeyParts mark a field as secret wherever they appear in its name,
+// ignoring case, underscores and dashes: refresh_token, newPassword, card_number.
+var sensitiveKeyParts = []string{"password", "passwd", "secret", "token", "authorization", "cookie", "apikey", "card", "cvv", "cvc"}
+
+// sensitiveKeys are secret only as whole names, since they are too short to
+// match inside others: one-time and OAuth codes, PINs and API keys.
+var sensitiveKeys = map[string]bool{"code": true, "otp": true, "pin": true, "key": true}
+
+// cardLike matches runs of 13 to 19 digits, optionally grouped by spaces or
+// dashes; those passing the Luhn check are redacted from any value.
+var cardLike = regexp.MustCompile(`\b\d(?:[ -]?\d){12,18}\b`)
+
+func isSensitiveKey(key string) bool {
+	normalized := strings.NewReplacer("_", "", "-", "").Replace(strings.ToLower(key))
+	if sensitiveKeys[normalized] {
+		return true
+	}
+	for _, part := range sensitiveKeyParts {
+		if strings.Contains(normalized, part) {
+			return true
+		}
+	}
+	return false
+}
+
+func isJSONMediaType(mediaType string) bool {
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+// redactJSON returns body with secret fields and card numbers replaced, or
+// false when it is not valid JSON.
+func redactJSON(body []byte) (json.RawMessage, bool) {
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	var value any
+	if err := dec.Decode(&value); err != nil {
+		return nil, false
+	}
+	out, err := json.Marshal(redactValue(value))
+	if err != nil {
+		return nil, false
+	}
+	return out, true
+}
+
+func redactValue(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		for key, field := range v {
+			if isSensitiveKey(key) && field != nil {
+				v[key] = redacted
+			} else {
+				v[key] = redactValue(field)
+			}
+		}
+		return v
+	case []any:
+		for i, item := range v {
+			v[i] = redactValue(item)
+		}
+		return v
+	case string:
+		return redactCards(v)
+	case json.Number:
+		if redactCards(v.String()) != v.String() {
+			return redacted
+		}
+		return v
+	default:
+		return v
+	}
+}
+
+// redactValues redacts a query string or form.
+func redactValues(values url.Values) map[string][]string {
+	out := make(map[string][]string, len(values))
+	for key, list := range values {
+		masked := make([]string, len(list))
+		for i, value := range list {
+			if isSensitiveKey(key) {
+				masked[i] = redacted
+			} else {
+				masked[i] = redactCards(value)
+			}
+		}
+		out[key] = masked
+	}
+	return out
+}
+
+func redactCards(s string) string {
+	return cardLike.ReplaceAllStringFunc(s, func(match string) string {
+		if luhnValid(match) {
+			return redacted
+		}
+		return match
+	})
+}
+
+func luhnValid(number string) bool {
+	sum, double := 0, false
+	for i := len(number) - 1; i >= 0; i-- {
+		c := number[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+		d := int(c - '0')
+		if double {
+			if d *= 2; d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+	return sum%10 == 0
+}
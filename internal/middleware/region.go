@@ -0,0 +1,12 @@
+package middleware
+
+import "net/http"
+
+// RegionHeader reports the serving region in X-Region so clients and load
+// balancers can tell which deployment answered.
+func RegionHeader(region string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Region", region)
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,38 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hongminglow/all-in-be/internal/tracing"
+)
+
+type discardSpans struct{}
+
+func (discardSpans) ExportSpans(context.Context, []*tracing.Span) error { return nil }
+
+func TestTracingContinuesIncomingTrace(t *testing.T) {
+	tracer := tracing.NewTracer(discardSpans{}, 1)
+	tracing.SetTracer(tracer)
+	t.Cleanup(func() {
+		tracing.SetTracer(nil)
+		tracer.Shutdown(context.Background())
+	})
+
+	var got tracing.SpanContext
+	handler := Tracing(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = tracing.SpanContextFromContext(r.Context())
+	}))
+	req := httptest.NewRequest(http.MethodGet, "/me", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	if got.TraceID.String() != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Fatalf("trace id = %s, want the caller's", got.TraceID)
+	}
+	if got.SpanID.String() == "00f067aa0ba902b7" {
+		t.Fatal("handler should see a new server span, not the remote parent")
+	}
+}
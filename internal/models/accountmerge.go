@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// AccountMerge records a player's duplicate account folded into their main
+// account: what was moved and who did it.
+type AccountMerge struct {
+	ID int64 `json:"id,omitempty"`
+	// UserID is the account that was kept.
+	UserID      int64 `json:"user_id"`
+	DuplicateID int64 `json:"duplicate_id"`
+	MergedBy    int64 `json:"merged_by"`
+	// Balance is the duplicate's balance, added to the kept account.
+	Balance       float64 `json:"balance"`
+	Transactions  int     `json:"transactions"`
+	Operations    int     `json:"operations"`
+	LoginAttempts int     `json:"login_attempts"`
+	Devices       int     `json:"devices"`
+	// DryRun marks a merge that was worked out and rolled back.
+	DryRun    bool      `json:"dry_run,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
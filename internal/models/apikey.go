@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// APIKey authenticates a machine client, such as a payment or game provider's
+// backend, through the X-API-Key header. A key acts for the staff member who
+// created it, limited to its scopes, so it can never do more than they can.
+type APIKey struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+	// Prefix is the start of the key, shown so operators can tell keys apart.
+	Prefix  string   `json:"prefix"`
+	KeyHash string   `json:"-"`
+	Scopes  []string `json:"scopes"`
+	// Key is only returned when the key is created.
+	Key       string     `json:"key,omitempty"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	CreatedBy int64      `json:"created_by"`
+	CreatedAt time.Time  `json:"created_at"`
+	RevokedBy *int64     `json:"revoked_by,omitempty"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+}
@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// Archivable datasets.
+const (
+	ArchiveLedger       = "ledger"
+	ArchiveLoginHistory = "login_history"
+)
+
+// ArchiveDatasets lists every dataset the archiver moves.
+var ArchiveDatasets = []string{ArchiveLedger, ArchiveLoginHistory}
+
+// ArchiveRun reports how many rows of a dataset one archiver pass moved.
+type ArchiveRun struct {
+	Dataset string `json:"dataset"`
+	// Before is the cutoff: rows created earlier were archived.
+	Before time.Time `json:"before"`
+	Moved  int       `json:"moved"`
+}
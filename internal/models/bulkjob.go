@@ -0,0 +1,43 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Bulk job kinds.
+const (
+	// BulkJobArchive archives cold rows as a one-off backfill, e.g. after the
+	// retention window was lowered.
+	BulkJobArchive = "archive"
+)
+
+// Bulk job statuses.
+const (
+	BulkJobRunning   = "running"
+	BulkJobSucceeded = "succeeded"
+	BulkJobFailed    = "failed"
+	BulkJobCancelled = "cancelled"
+)
+
+// BulkJob is long-running work done a batch at a time. The checkpoint saved
+// after each batch lets the job resume where it stopped after a crash or a
+// failure instead of starting over.
+type BulkJob struct {
+	ID     int64  `json:"id"`
+	Kind   string `json:"kind"`
+	Status string `json:"status"`
+	// Checkpoint is the kind's own progress marker; it is empty until the
+	// first batch is done.
+	Checkpoint json.RawMessage `json:"checkpoint,omitempty"`
+	// Processed counts the items handled so far, as reported by each batch.
+	Processed int64  `json:"processed"`
+	Batches   int64  `json:"batches"`
+	Error     string `json:"error,omitempty"`
+	// Owner identifies the instance running the job.
+	Owner      string     `json:"-"`
+	CreatedBy  int64      `json:"created_by"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+}
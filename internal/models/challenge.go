@@ -0,0 +1,52 @@
+package models
+
+import "time"
+
+// Ways a user can prove it is really them before a risky action.
+const (
+	// ChallengeReauth asks for the account password again.
+	ChallengeReauth = "reauth"
+	// ChallengeOTP emails a one-time code to the account's address.
+	ChallengeOTP = "otp"
+	// ChallengeCaptcha asks for a CAPTCHA solved in the client.
+	ChallengeCaptcha = "captcha"
+)
+
+// ChallengeMethods lists every challenge method.
+var ChallengeMethods = []string{ChallengeReauth, ChallengeOTP, ChallengeCaptcha}
+
+// Actions a step-up challenge can guard.
+const (
+	ActionPasswordChange = "password_change"
+	ActionWithdrawal     = "withdrawal"
+)
+
+// ChallengePolicy decides when an action needs a challenge and which one.
+type ChallengePolicy struct {
+	Method string
+	// MinAmount exempts smaller amounts; zero challenges any amount.
+	MinAmount float64
+	// NewDeviceOnly exempts devices the user has signed in from for a while.
+	NewDeviceOnly bool
+}
+
+// Challenge is a step-up verification issued for one action. Once passed it
+// is redeemed, once, by the token handed out at that point.
+type Challenge struct {
+	ID     int64  `json:"id"`
+	UserID int64  `json:"-"`
+	Action string `json:"action"`
+	Method string `json:"method"`
+	// CodeHash is the hash of the code sent for an OTP challenge.
+	CodeHash string `json:"-"`
+	// Attempts counts wrong answers.
+	Attempts  int        `json:"-"`
+	TokenHash string     `json:"-"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	PassedAt  *time.Time `json:"-"`
+	UsedAt    *time.Time `json:"-"`
+	CreatedAt time.Time  `json:"created_at"`
+	// SiteKey is the public CAPTCHA key the client renders the widget with.
+	// It is not stored.
+	SiteKey string `json:"site_key,omitempty"`
+}
@@ -0,0 +1,31 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Configuration entities tracked in the change history.
+const (
+	ConfigEntityRateLimit  = "rate_limit"
+	ConfigEntityRole       = "role"
+	ConfigEntityPermission = "permission"
+	ConfigEntityOnboarding = "onboarding_journey"
+	ConfigEntityIPRisk     = "ip_risk_policy"
+)
+
+// ConfigChange is one versioned change to admin-managed configuration. Before is
+// null for creations and After is null for deletions. Country is where the
+// change was made from, if it could be located.
+type ConfigChange struct {
+	ID                int64           `json:"id"`
+	Entity            string          `json:"entity"`
+	EntityKey         string          `json:"entity_key"`
+	Before            json.RawMessage `json:"before"`
+	After             json.RawMessage `json:"after"`
+	ChangedBy         int64           `json:"changed_by"`
+	ChangedByUsername string          `json:"changed_by_username"`
+	Country           string          `json:"country,omitempty"`
+	RollbackOf        *int64          `json:"rollback_of,omitempty"`
+	ChangedAt         time.Time       `json:"changed_at"`
+}
@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// Data export statuses.
+const (
+	ExportPending = "pending"
+	ExportReady   = "ready"
+	ExportFailed  = "failed"
+)
+
+// DataExport is a user's request for a copy of their personal data.
+type DataExport struct {
+	ID     int64  `json:"id"`
+	UserID int64  `json:"user_id"`
+	Status string `json:"status"`
+	// BlobKey locates the archive once it is ready.
+	BlobKey     string     `json:"-"`
+	CreatedAt   time.Time  `json:"created_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	// DownloadURL is a signed link to a ready archive, filled in when the
+	// export is served.
+	DownloadURL string `json:"download_url,omitempty"`
+}
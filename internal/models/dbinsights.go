@@ -0,0 +1,52 @@
+package models
+
+import "time"
+
+// QueryStat is one normalized statement's totals from pg_stat_statements.
+type QueryStat struct {
+	QueryID int64  `json:"query_id"`
+	Query   string `json:"query"`
+	Calls   int64  `json:"calls"`
+	// TotalMillis and MeanMillis are execution times in milliseconds.
+	TotalMillis float64 `json:"total_ms"`
+	MeanMillis  float64 `json:"mean_ms"`
+	Rows        int64   `json:"rows"`
+}
+
+// TableScanStat counts how a table has been read since statistics were last reset.
+type TableScanStat struct {
+	Table       string `json:"table"`
+	SeqScans    int64  `json:"seq_scans"`
+	SeqRowsRead int64  `json:"seq_rows_read"`
+	IndexScans  int64  `json:"index_scans"`
+	LiveRows    int64  `json:"live_rows"`
+	// Columns lists the table's columns and IndexedColumns the leading column
+	// of each of its indexes; both feed index suggestions.
+	Columns        []string `json:"-"`
+	IndexedColumns []string `json:"-"`
+}
+
+// IndexSuggestion is an index that would serve filters the workload runs
+// often or slowly.
+type IndexSuggestion struct {
+	Table   string   `json:"table"`
+	Columns []string `json:"columns"`
+	// Statement creates the index without blocking writes.
+	Statement string `json:"statement"`
+	// QueryIDs are the captured statements filtering on Columns.
+	QueryIDs []int64 `json:"query_ids"`
+	// Calls is how often those statements ran.
+	Calls int64 `json:"calls"`
+}
+
+// DatabaseInsightsReport is the outcome of one index advisor run.
+type DatabaseInsightsReport struct {
+	ID int64 `json:"id"`
+	// StatementsAvailable is false when pg_stat_statements is not installed;
+	// the report then has no slow queries or suggestions.
+	StatementsAvailable bool              `json:"statements_available"`
+	SlowQueries         []QueryStat       `json:"slow_queries"`
+	SeqScanTables       []TableScanStat   `json:"seq_scan_tables"`
+	Suggestions         []IndexSuggestion `json:"suggestions"`
+	CreatedAt           time.Time         `json:"created_at"`
+}
@@ -0,0 +1,8 @@
+package dto
+
+type MergeAccountRequest struct {
+	// DuplicateID is the account folded into the one in the path.
+	DuplicateID int64 `json:"duplicate_id"`
+	// DryRun reports what would be moved without merging.
+	DryRun bool `json:"dry_run"`
+}
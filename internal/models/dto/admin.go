@@ -0,0 +1,26 @@
+package dto
+
+type BalanceAdjustmentRequest struct {
+	Amount float64 `json:"amount"`
+	Note   string  `json:"note"`
+	Key    string  `json:"key"`
+	// Version, if set, refuses the adjustment once the user has changed.
+	Version *int64 `json:"version"`
+}
+
+type SetFeatureFlagRequest struct {
+	Enabled *bool `json:"enabled"`
+}
+
+type OperatorReportRequest struct {
+	Period string `json:"period"`
+	// From and To are dates, as in "2026-01-31".
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+type JackpotWinRequest struct {
+	UserID int64 `json:"user_id"`
+	// Key names the win so a retried request pays once.
+	Key string `json:"key"`
+}
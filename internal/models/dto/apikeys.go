@@ -0,0 +1,8 @@
+package dto
+
+type CreateAPIKeyRequest struct {
+	Name   string   `json:"name"`
+	Scopes []string `json:"scopes"`
+	// ExpiresInDays defaults to 90.
+	ExpiresInDays int `json:"expires_in_days"`
+}
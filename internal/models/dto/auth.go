@@ -8,14 +8,49 @@ type RegisterRequest struct {
 	Phone       string `json:"phone"`
 	PhoneNumber string `json:"phoneNumber"`
 	Password    string `json:"password"`
+	// CaptchaToken is the solved CAPTCHA, when sign-ups require one.
+	CaptchaToken string `json:"captcha_token"`
 }
 
 type LoginRequest struct {
 	Identifier string `json:"identifier"`
 	Password   string `json:"password"`
+	// UseCookie asks for the token as an HttpOnly cookie instead of in the body.
+	UseCookie bool `json:"useCookie"`
+	// Scopes narrows the token to a subset of the user's permissions.
+	Scopes []string `json:"scopes"`
+	// CaptchaToken is the solved CAPTCHA, required after repeated failed
+	// sign-ins.
+	CaptchaToken string `json:"captcha_token"`
+}
+
+// CaptchaRequired tells the client which CAPTCHA widget to render before
+// retrying.
+type CaptchaRequired struct {
+	Provider string `json:"provider"`
+	SiteKey  string `json:"site_key,omitempty"`
 }
 
 type LoginResponse struct {
-	Token string      `json:"token"`
-	User  models.User `json:"user"`
+	Token  string   `json:"token,omitempty"`
+	Scopes []string `json:"scopes,omitempty"`
+	// NewDevice is set when the user had signed in before, but not from this
+	// device or country.
+	NewDevice bool        `json:"new_device,omitempty"`
+	User      models.User `json:"user"`
+}
+
+type ForgotPasswordRequest struct {
+	Identifier string `json:"identifier"`
+}
+
+type ResetPasswordRequest struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+type UserSearchResponse struct {
+	Users []models.User `json:"users"`
+	// NextCursor fetches the following page; it is empty on the last page.
+	NextCursor string `json:"next_cursor,omitempty"`
 }
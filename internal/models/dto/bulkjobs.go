@@ -0,0 +1,5 @@
+package dto
+
+type StartBulkJobRequest struct {
+	Kind string `json:"kind"`
+}
@@ -0,0 +1,10 @@
+package dto
+
+type InvalidateCachesRequest struct {
+	Caches []string `json:"caches"`
+}
+
+type CacheInvalidationResponse struct {
+	Caches    []string `json:"caches"`
+	Broadcast bool     `json:"broadcast"`
+}
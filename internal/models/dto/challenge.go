@@ -0,0 +1,20 @@
+package dto
+
+import "time"
+
+type VerifyChallengeRequest struct {
+	Password     string `json:"password"`
+	Code         string `json:"code"`
+	CaptchaToken string `json:"captcha_token"`
+}
+
+type ChallengePassedResponse struct {
+	// Token goes in the X-Challenge-Token header when retrying the action.
+	Token     string    `json:"challenge_token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password"`
+}
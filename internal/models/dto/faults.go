@@ -0,0 +1,12 @@
+package dto
+
+type CreateFaultRequest struct {
+	PathPrefix  string  `json:"path_prefix"`
+	Method      string  `json:"method"`
+	Percentage  float64 `json:"percentage"`
+	LatencyMS   int     `json:"latency_ms"`
+	ErrorStatus int     `json:"error_status"`
+	DropDB      bool    `json:"drop_db"`
+	// TTLSeconds removes the rule automatically after this long; 0 keeps it until deleted.
+	TTLSeconds int `json:"ttl_seconds"`
+}
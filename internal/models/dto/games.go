@@ -0,0 +1,26 @@
+package dto
+
+import "time"
+
+type GameLaunchResponse struct {
+	SessionID int64 `json:"session_id"`
+	// LaunchURL opens the game at the provider with Token.
+	LaunchURL string    `json:"launch_url"`
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+type CreateTournamentRequest struct {
+	Name     string    `json:"name"`
+	EntryFee float64   `json:"entry_fee"`
+	StartsAt time.Time `json:"starts_at"`
+	EndsAt   time.Time `json:"ends_at"`
+	// PrizeShares are the percentages of the pool paid to each place.
+	PrizeShares []float64 `json:"prize_shares"`
+}
+
+// TournamentScoreRequest reports an entrant's score from a game server.
+type TournamentScoreRequest struct {
+	UserID int64    `json:"user_id"`
+	Score  *float64 `json:"score"`
+}
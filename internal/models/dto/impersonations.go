@@ -0,0 +1,8 @@
+package dto
+
+type ImpersonateRequest struct {
+	// Reason is recorded with the impersonation, e.g. a support ticket.
+	Reason string `json:"reason"`
+	// ExpiresInMinutes defaults to 15.
+	ExpiresInMinutes int `json:"expires_in_minutes"`
+}
@@ -0,0 +1,9 @@
+package dto
+
+type UpsertIPRiskPolicyRequest struct {
+	// Tenant is empty for the default tenant.
+	Tenant string `json:"tenant"`
+	// Country is an ISO 3166-1 alpha-2 code, or empty for every other country.
+	Country string `json:"country"`
+	Action  string `json:"action"`
+}
@@ -0,0 +1,6 @@
+package dto
+
+type PlaceLegalHoldRequest struct {
+	Reason  string `json:"reason"`
+	Dataset string `json:"dataset"`
+}
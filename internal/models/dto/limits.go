@@ -0,0 +1,11 @@
+package dto
+
+type GamingLimitsRequest struct {
+	DailyDeposit   *float64 `json:"daily_deposit"`
+	DailyLoss      *float64 `json:"daily_loss"`
+	SessionMinutes *int     `json:"session_minutes"`
+}
+
+type SelfExclusionRequest struct {
+	Days int `json:"days"`
+}
@@ -0,0 +1,28 @@
+package dto
+
+import "github.com/hongminglow/all-in-be/internal/models"
+
+type MeResponse struct {
+	models.User
+	BalanceMoney models.MoneyAmount `json:"balance_money"`
+	MoneyFormat  models.MoneyFormat `json:"money_format"`
+}
+
+// UpdateProfileRequest edits the fields that are set. Version is the user's
+// version the edit is based on, unless the If-Match header carries it.
+type UpdateProfileRequest struct {
+	Username *string `json:"username"`
+	Email    *string `json:"email"`
+	Phone    *string `json:"phone"`
+	Version  *int64  `json:"version"`
+}
+
+// WalletBalanceResponse is the caller's balance with each figure formatted
+// for their locale.
+type WalletBalanceResponse struct {
+	models.WalletBalance
+	TotalMoney     models.MoneyAmount `json:"total_money"`
+	HeldMoney      models.MoneyAmount `json:"held_money"`
+	AvailableMoney models.MoneyAmount `json:"available_money"`
+	MoneyFormat    models.MoneyFormat `json:"money_format"`
+}
@@ -0,0 +1,31 @@
+package dto
+
+// SendMessageRequest writes a support message to one player.
+type SendMessageRequest struct {
+	Subject string `json:"subject"`
+	Body    string `json:"body"`
+}
+
+// BroadcastMessageRequest sends an announcement to every player with one of
+// Roles, or to everyone when Roles is empty.
+type BroadcastMessageRequest struct {
+	Subject string   `json:"subject"`
+	Body    string   `json:"body"`
+	Roles   []string `json:"roles"`
+}
+
+// BroadcastMessageResponse tells how many inboxes an announcement reached.
+type BroadcastMessageResponse struct {
+	Recipients int `json:"recipients"`
+}
+
+// MarkMessagesReadRequest names the messages to mark read; none marks them
+// all.
+type MarkMessagesReadRequest struct {
+	IDs []int64 `json:"ids"`
+}
+
+// MarkMessagesReadResponse is the caller's unread count afterwards.
+type MarkMessagesReadResponse struct {
+	Unread int `json:"unread"`
+}
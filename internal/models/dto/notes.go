@@ -0,0 +1,11 @@
+package dto
+
+type CreateNoteRequest struct {
+	Body   string `json:"body"`
+	Pinned bool   `json:"pinned"`
+}
+
+type UpdateNoteRequest struct {
+	Body   *string `json:"body"`
+	Pinned *bool   `json:"pinned"`
+}
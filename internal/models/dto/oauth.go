@@ -0,0 +1,8 @@
+package dto
+
+type CreateOAuthClientRequest struct {
+	Name         string   `json:"name"`
+	RedirectURIs []string `json:"redirect_uris"`
+	// Confidential clients get a secret to authenticate to /token with.
+	Confidential bool `json:"confidential"`
+}
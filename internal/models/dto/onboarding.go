@@ -0,0 +1,8 @@
+package dto
+
+import "github.com/hongminglow/all-in-be/internal/models"
+
+type SaveOnboardingJourneyRequest struct {
+	Tenant string                  `json:"tenant"`
+	Steps  []models.OnboardingStep `json:"steps"`
+}
@@ -0,0 +1,13 @@
+package dto
+
+type DepositRequest struct {
+	Amount float64 `json:"amount"`
+}
+
+type WithdrawalRequest struct {
+	Amount float64 `json:"amount"`
+}
+
+type RejectWithdrawalRequest struct {
+	Note string `json:"note"`
+}
@@ -0,0 +1,5 @@
+package dto
+
+type UpdatePrivacyRequest struct {
+	PublicActivity string `json:"public_activity"`
+}
@@ -0,0 +1,36 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/hongminglow/all-in-be/internal/models"
+)
+
+type CreatePromoCodeRequest struct {
+	Code           string     `json:"code"`
+	Campaign       string     `json:"campaign"`
+	Amount         float64    `json:"amount"`
+	MaxRedemptions int        `json:"max_redemptions"`
+	PerUserLimit   int        `json:"per_user_limit"`
+	Roles          []string   `json:"roles"`
+	ExpiresAt      *time.Time `json:"expires_at"`
+}
+
+type UpdatePromoCodeRequest struct {
+	MaxRedemptions *int       `json:"max_redemptions"`
+	PerUserLimit   *int       `json:"per_user_limit"`
+	Roles          *[]string  `json:"roles"`
+	ExpiresAt      *time.Time `json:"expires_at"`
+	Active         *bool      `json:"active"`
+}
+
+type RedeemPromoRequest struct {
+	Code string `json:"code"`
+}
+
+type RedeemPromoResponse struct {
+	Redemption   models.PromoRedemption `json:"redemption"`
+	Balance      float64                `json:"balance"`
+	BalanceMoney models.MoneyAmount     `json:"balance_money"`
+	MoneyFormat  models.MoneyFormat     `json:"money_format"`
+}
@@ -0,0 +1,8 @@
+package dto
+
+type UpsertRateLimitPolicyRequest struct {
+	Tenant        string `json:"tenant"`
+	RouteClass    string `json:"route_class"`
+	Requests      int    `json:"requests"`
+	WindowSeconds int    `json:"window_seconds"`
+}
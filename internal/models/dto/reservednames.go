@@ -0,0 +1,7 @@
+package dto
+
+type AddReservedNameRequest struct {
+	Name string `json:"name"`
+	// Kind is reserved (the default) or blocked.
+	Kind string `json:"kind"`
+}
@@ -0,0 +1,34 @@
+package dto
+
+import "github.com/hongminglow/all-in-be/internal/models"
+
+type CreateRoleRequest struct {
+	Role        string   `json:"role"`
+	Description string   `json:"description"`
+	Permissions []string `json:"permissions"`
+}
+
+type UpdateRoleRequest struct {
+	Role        *string `json:"role"`
+	Description *string `json:"description"`
+}
+
+type CreatePermissionRequest struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+type UpdatePermissionRequest struct {
+	Name        *string `json:"name"`
+	Description *string `json:"description"`
+}
+
+type SetPermissionOverrideRequest struct {
+	Allow *bool `json:"allow"`
+}
+
+type UserPermissionsResponse struct {
+	Role        string                      `json:"role"`
+	Permissions []string                    `json:"permissions"`
+	Overrides   []models.PermissionOverride `json:"overrides"`
+}
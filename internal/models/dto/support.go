@@ -0,0 +1,17 @@
+package dto
+
+// OpenTicketRequest files a support ticket; Body is its first reply.
+type OpenTicketRequest struct {
+	Subject string `json:"subject"`
+	Body    string `json:"body"`
+}
+
+// TicketReplyRequest adds a reply to a ticket's thread.
+type TicketReplyRequest struct {
+	Body string `json:"body"`
+}
+
+// SetTicketStatusRequest moves a ticket to open, pending or closed.
+type SetTicketStatusRequest struct {
+	Status string `json:"status"`
+}
@@ -0,0 +1,8 @@
+package dto
+
+type CreateWebhookRequest struct {
+	URL    string   `json:"url"`
+	Events []string `json:"events"`
+	// Secret is generated when empty.
+	Secret string `json:"secret"`
+}
@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// DisposableDomainList describes the list of disposable email domains sign-ups
+// are checked against.
+type DisposableDomainList struct {
+	// Source is the file or URL the list is read from.
+	Source  string `json:"source"`
+	Domains int    `json:"domains"`
+	// RefreshedAt is when the list was last read successfully.
+	RefreshedAt *time.Time `json:"refreshed_at,omitempty"`
+	// Error is why the last refresh failed, if it did; the previous list
+	// stays in use.
+	Error string `json:"error,omitempty"`
+}
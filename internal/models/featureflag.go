@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// Where a feature flag's state comes from.
+const (
+	// FlagSourceConfig flags are switched on by FEATURE_FLAGS.
+	FlagSourceConfig = "config"
+	// FlagSourceAdmin flags were set through the admin API, which overrides
+	// FEATURE_FLAGS.
+	FlagSourceAdmin = "admin"
+)
+
+// FeatureFlag is the state of one feature switch.
+type FeatureFlag struct {
+	Name      string     `json:"name"`
+	Enabled   bool       `json:"enabled"`
+	Source    string     `json:"source"`
+	UpdatedBy int64      `json:"updated_by,omitempty"`
+	UpdatedAt *time.Time `json:"updated_at,omitempty"`
+}
@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// GameSession is a player's session at a provider's game. It is created by a
+// launch and started by the provider's first wallet callback; every later
+// callback must fall within it.
+type GameSession struct {
+	ID       int64  `json:"id"`
+	UserID   int64  `json:"user_id"`
+	Provider string `json:"provider"`
+	// GameID is the game's code at the provider.
+	GameID    string `json:"game_id"`
+	TokenHash string `json:"-"`
+	// ExpiresAt is the deadline for the first callback until the session
+	// starts, and the end of the session after that.
+	ExpiresAt time.Time  `json:"expires_at"`
+	StartedAt *time.Time `json:"started_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
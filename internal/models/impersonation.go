@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// Impersonation lets a staff member act as a player for a short time, to
+// reproduce an issue the player reported. Its token carries an act claim
+// naming the staff member and stops working once it is revoked or expires.
+type Impersonation struct {
+	ID      int64  `json:"id"`
+	UserID  int64  `json:"user_id"`
+	ActorID int64  `json:"actor_id"`
+	Reason  string `json:"reason"`
+	// TokenID is the jti of the impersonation token.
+	TokenID string `json:"-"`
+	// Token is only returned when the impersonation starts.
+	Token     string     `json:"token,omitempty"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	CreatedAt time.Time  `json:"created_at"`
+	RevokedBy *int64     `json:"revoked_by,omitempty"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+}
@@ -0,0 +1,37 @@
+package models
+
+import "time"
+
+// Inbound delivery statuses.
+const (
+	InboundReceived  = "received"
+	InboundProcessed = "processed"
+	InboundFailed    = "failed"
+	// InboundDuplicate marks a delivery whose event was already applied by an
+	// earlier delivery, e.g. a provider retry.
+	InboundDuplicate = "duplicate"
+)
+
+// InboundDelivery is one callback received from an external provider, kept
+// verbatim so it can be inspected and re-processed later.
+type InboundDelivery struct {
+	ID       int64  `json:"id"`
+	Provider string `json:"provider"`
+	// EventID is the provider's identifier for the event; it is applied at most once.
+	EventID string            `json:"event_id"`
+	Headers map[string]string `json:"headers"`
+	// Payload is the raw request body.
+	Payload       string     `json:"payload"`
+	Status        string     `json:"status"`
+	Error         string     `json:"error,omitempty"`
+	Attempts      int        `json:"attempts"`
+	ReceivedAt    time.Time  `json:"received_at"`
+	LastAttemptAt *time.Time `json:"last_attempt_at,omitempty"`
+}
+
+// InboundDeliveryFilter narrows a delivery listing. Empty fields match everything.
+type InboundDeliveryFilter struct {
+	Provider string
+	Status   string
+	EventID  string
+}
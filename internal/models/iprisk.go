@@ -0,0 +1,55 @@
+package models
+
+import "time"
+
+// Actions an IP risk policy takes when a client IP looks like a VPN, proxy or
+// datacenter address.
+const (
+	// IPRiskAllow ignores the signals, e.g. for a jurisdiction where VPNs are common.
+	IPRiskAllow = "allow"
+	// IPRiskFlag lets the request through and records it for review.
+	IPRiskFlag = "flag"
+	// IPRiskStepUp asks the user to confirm the device by email first.
+	IPRiskStepUp = "step_up"
+	// IPRiskBlock refuses the request.
+	IPRiskBlock = "block"
+)
+
+// IPRiskActions lists every action a policy may take.
+var IPRiskActions = []string{IPRiskAllow, IPRiskFlag, IPRiskStepUp, IPRiskBlock}
+
+// Checkpoints where client IPs are screened.
+const (
+	CheckpointLogin        = "login"
+	CheckpointRegistration = "registration"
+	CheckpointWithdrawal   = "withdrawal"
+)
+
+// Signals an IP intelligence provider can raise.
+const (
+	SignalVPN        = "vpn"
+	SignalProxy      = "proxy"
+	SignalDatacenter = "datacenter"
+)
+
+// IPRiskPolicy is the action taken on risky IPs for a tenant and jurisdiction.
+// An empty Tenant is the default tenant and an empty Country matches any
+// country the tenant has no row for.
+type IPRiskPolicy struct {
+	Tenant    string    `json:"tenant"`
+	Country   string    `json:"country"`
+	Action    string    `json:"action"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// IPRiskEvent records a request from a risky IP and what was done about it.
+type IPRiskEvent struct {
+	ID         int64     `json:"id"`
+	UserID     *int64    `json:"user_id,omitempty"`
+	IP         string    `json:"ip"`
+	Country    string    `json:"country"`
+	Checkpoint string    `json:"checkpoint"`
+	Signals    []string  `json:"signals"`
+	Action     string    `json:"action"`
+	CreatedAt  time.Time `json:"created_at"`
+}
@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// Jackpot is a pot fed by a share of every stake and paid out whole to
+// whoever wins it.
+type Jackpot struct {
+	Name string `json:"name"`
+	// ContributionPercent is the share of each stake added to the pot.
+	ContributionPercent float64 `json:"contribution_percent"`
+	Pot                 float64 `json:"pot"`
+	// UpdatedAt is when the pot last grew or was won; it is nil for a pot
+	// no stake has reached yet.
+	UpdatedAt *time.Time `json:"updated_at,omitempty"`
+}
@@ -0,0 +1,40 @@
+package models
+
+// Leaderboard metrics.
+const (
+	LeaderboardBalance     = "balance"
+	LeaderboardWinnings    = "winnings"
+	LeaderboardGamesPlayed = "games_played"
+)
+
+// Leaderboard windows. Balance is only ranked all-time, as it has no history.
+const (
+	LeaderboardDaily   = "daily"
+	LeaderboardWeekly  = "weekly"
+	LeaderboardAllTime = "all-time"
+)
+
+// LeaderboardEntry is one player's standing. Player is the display name chosen
+// by their privacy settings.
+type LeaderboardEntry struct {
+	Rank   int     `json:"rank"`
+	Player string  `json:"player"`
+	Value  float64 `json:"value"`
+	// ValueMoney is Value as money, set on the balance and winnings boards.
+	ValueMoney *MoneyAmount `json:"value_money,omitempty"`
+	// UserID, Username and PublicActivity are the player's; they never leave the server.
+	UserID         int64  `json:"-"`
+	Username       string `json:"-"`
+	PublicActivity string `json:"-"`
+}
+
+// Leaderboard is the top of one metric and window, with the requester's own
+// standing; Me is nil when they are not ranked.
+type Leaderboard struct {
+	Metric  string             `json:"metric"`
+	Window  string             `json:"window"`
+	Entries []LeaderboardEntry `json:"entries"`
+	Me      *LeaderboardEntry  `json:"me"`
+	// MoneyFormat is set on boards ranking amounts of money.
+	MoneyFormat *MoneyFormat `json:"money_format,omitempty"`
+}
@@ -0,0 +1,220 @@
+package models
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Ledger account kinds. An account's code is its kind, followed for wallets
+// and gateways by ":" and the user ID or provider name.
+const (
+	// AccountWallet is a player's balance; every user has one.
+	AccountWallet = "wallet"
+	// AccountHouse is the operator's own money: bets won and lost and
+	// manual adjustments are booked against it.
+	AccountHouse = "house"
+	// AccountBonusPool pays for bonuses, welcome bonuses and promo credits.
+	AccountBonusPool = "bonus_pool"
+	// AccountGateway is money held at or owed to a payment provider; there
+	// is one per provider.
+	AccountGateway = "gateway"
+	// AccountClearing carries transfers between wallets, such as account
+	// merges, so each wallet's entry balances on its own.
+	AccountClearing = "clearing"
+	// AccountEquity is the other side of opening balances: money wallets
+	// held before the ledger was double-entry.
+	AccountEquity = "equity"
+	// AccountJackpot is a jackpot's pot, owed to its next winner; there is
+	// one per jackpot.
+	AccountJackpot = "jackpot"
+)
+
+// Reasons of journal entries that record no wallet entry.
+const (
+	// JournalOpeningBalance opens a wallet account with the balance its user
+	// already had.
+	JournalOpeningBalance = "opening_balance"
+	// JournalJackpotContribution moves a share of a stake from the house to
+	// a jackpot's pot.
+	JournalJackpotContribution = "jackpot_contribution"
+)
+
+// WalletAccount returns the code of the user's wallet account.
+func WalletAccount(userID int64) string {
+	return AccountWallet + ":" + strconv.FormatInt(userID, 10)
+}
+
+// GatewayAccount returns the code of the provider's gateway account.
+func GatewayAccount(provider string) string {
+	if provider == "" {
+		provider = "unknown"
+	}
+	return AccountGateway + ":" + provider
+}
+
+// JackpotAccount returns the code of the jackpot's pot account.
+func JackpotAccount(name string) string {
+	return AccountJackpot + ":" + name
+}
+
+// AccountKind returns the kind of the account with the code.
+func AccountKind(code string) string {
+	kind, _, _ := strings.Cut(code, ":")
+	return kind
+}
+
+// CounterAccount returns the account on the other side of a wallet entry.
+// Payments are booked against the gateway named by the "provider:reference"
+// reference they carry, and jackpot wins against the pot named by their
+// "jackpot:key" reference.
+func CounterAccount(entry Transaction) string {
+	switch entry.Reason {
+	case TransactionDeposit, TransactionWithdrawal, TransactionPayoutReversal:
+		provider, _, found := strings.Cut(entry.Reference, ":")
+		if !found {
+			provider = ""
+		}
+		return GatewayAccount(provider)
+	case TransactionJackpotWin:
+		name, _, _ := strings.Cut(entry.Reference, ":")
+		return JackpotAccount(name)
+	case TransactionBonusGrant, TransactionWelcomeBonus, TransactionPromoCredit:
+		return AccountBonusPool
+	case TransactionAccountMerge:
+		return AccountClearing
+	default:
+		return AccountHouse
+	}
+}
+
+// JournalLine debits or credits one account; exactly one of the two is
+// positive.
+type JournalLine struct {
+	Account string  `json:"account"`
+	Debit   float64 `json:"debit"`
+	Credit  float64 `json:"credit"`
+}
+
+// JournalEntry is one double-entry posting. Its lines' debits and credits
+// add up to the same total.
+type JournalEntry struct {
+	ID int64 `json:"id"`
+	// TransactionID is the wallet entry the posting records; opening
+	// balances have none.
+	TransactionID *int64        `json:"transaction_id,omitempty"`
+	Reason        string        `json:"reason"`
+	Reference     string        `json:"reference,omitempty"`
+	Lines         []JournalLine `json:"lines"`
+}
+
+// JournalFor returns the posting of a wallet entry: a credit to the wallet is
+// a debit to its counter account, and a debit the reverse. A zero amount
+// posts no lines.
+func JournalFor(entry Transaction) JournalEntry {
+	journal := JournalEntry{Reason: entry.Reason, Reference: entry.Reference}
+	if entry.ID != 0 {
+		journal.TransactionID = &entry.ID
+	}
+	amount := roundCents(math.Abs(entry.Amount))
+	if amount == 0 {
+		return journal
+	}
+	wallet := JournalLine{Account: WalletAccount(entry.UserID)}
+	counter := JournalLine{Account: CounterAccount(entry)}
+	if entry.Amount > 0 {
+		counter.Debit, wallet.Credit = amount, amount
+	} else {
+		wallet.Debit, counter.Credit = amount, amount
+	}
+	journal.Lines = []JournalLine{counter, wallet}
+	return journal
+}
+
+// OpeningJournal returns the posting that opens the user's wallet account
+// with the balance they already had, against equity.
+func OpeningJournal(userID int64, balance float64) JournalEntry {
+	journal := JournalEntry{Reason: JournalOpeningBalance, Reference: "user:" + strconv.FormatInt(userID, 10)}
+	if balance = roundCents(balance); balance > 0 {
+		journal.Lines = []JournalLine{
+			{Account: AccountEquity, Debit: balance},
+			{Account: WalletAccount(userID), Credit: balance},
+		}
+	}
+	return journal
+}
+
+// JackpotContributionJournal returns the posting that moves amount of the
+// stake with the reference from the house to the jackpot's pot.
+func JackpotContributionJournal(name string, amount float64, reference string) JournalEntry {
+	journal := JournalEntry{Reason: JournalJackpotContribution, Reference: reference}
+	if amount = roundCents(amount); amount > 0 {
+		journal.Lines = []JournalLine{
+			{Account: AccountHouse, Debit: amount},
+			{Account: JackpotAccount(name), Credit: amount},
+		}
+	}
+	return journal
+}
+
+// Balanced reports whether the entry has lines, each of which either debits
+// or credits a positive amount, and whether its debits equal its credits.
+func (j JournalEntry) Balanced() bool {
+	if len(j.Lines) < 2 {
+		return false
+	}
+	var sum int64
+	for _, l := range j.Lines {
+		debit, credit := cents(l.Debit), cents(l.Credit)
+		if debit < 0 || credit < 0 || (debit == 0) == (credit == 0) {
+			return false
+		}
+		sum += debit - credit
+	}
+	return sum == 0
+}
+
+// TrialBalanceLine totals the postings to one account, or to every account
+// of a kind: player wallets share a single line.
+type TrialBalanceLine struct {
+	Account  string  `json:"account"`
+	Kind     string  `json:"kind"`
+	Accounts int     `json:"accounts"`
+	Debits   float64 `json:"debits"`
+	Credits  float64 `json:"credits"`
+	// Balance is Debits less Credits. Wallets owe players their money, so
+	// theirs is negative.
+	Balance float64 `json:"balance"`
+}
+
+// TrialBalance lists every account's totals. The ledger is sound when the
+// debits of all accounts equal their credits.
+type TrialBalance struct {
+	Lines        []TrialBalanceLine `json:"lines"`
+	TotalDebits  float64            `json:"total_debits"`
+	TotalCredits float64            `json:"total_credits"`
+	Balanced     bool               `json:"balanced"`
+}
+
+// NewTrialBalance adds up the lines' totals and fills in each line's balance.
+func NewTrialBalance(lines []TrialBalanceLine) TrialBalance {
+	report := TrialBalance{Lines: lines}
+	var debits, credits int64
+	for i := range report.Lines {
+		l := &report.Lines[i]
+		l.Balance = roundCents(l.Debits - l.Credits)
+		debits += cents(l.Debits)
+		credits += cents(l.Credits)
+	}
+	report.TotalDebits, report.TotalCredits = float64(debits)/100, float64(credits)/100
+	report.Balanced = debits == credits
+	return report
+}
+
+func cents(amount float64) int64 {
+	return int64(math.Round(amount * 100))
+}
+
+func roundCents(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
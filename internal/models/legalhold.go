@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+// LegalHold keeps a user's records where they are, exempt from retention
+// processing such as archiving, while a dispute or regulator request is open.
+// Released holds are kept as a record of the hold.
+type LegalHold struct {
+	ID     int64 `json:"id"`
+	UserID int64 `json:"user_id"`
+	// Dataset limits the hold to one of ArchiveDatasets; empty holds them all.
+	Dataset    string     `json:"dataset,omitempty"`
+	Reason     string     `json:"reason"`
+	PlacedBy   int64      `json:"placed_by"`
+	PlacedAt   time.Time  `json:"placed_at"`
+	ReleasedBy *int64     `json:"released_by,omitempty"`
+	ReleasedAt *time.Time `json:"released_at,omitempty"`
+}
+
+// Covers reports whether the hold is active and applies to dataset.
+func (h LegalHold) Covers(dataset string) bool {
+	return h.ReleasedAt == nil && (h.Dataset == "" || h.Dataset == dataset)
+}
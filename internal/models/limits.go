@@ -0,0 +1,41 @@
+package models
+
+import "time"
+
+// LimitSet is a set of responsible gaming limits. A nil limit is off. Daily
+// limits count from midnight UTC.
+type LimitSet struct {
+	DailyDeposit *float64 `json:"daily_deposit"`
+	// DailyLoss caps the net amount lost on bet settlements.
+	DailyLoss *float64 `json:"daily_loss"`
+	// SessionMinutes caps how long a game session accepts bets once started.
+	SessionMinutes *int `json:"session_minutes"`
+}
+
+// GamingLimits are the responsible gaming limits a player set for themselves.
+// Tighter limits apply at once; looser ones wait as Pending until PendingFrom,
+// so a player cannot lift a limit in the heat of a losing streak.
+type GamingLimits struct {
+	UserID      int64      `json:"user_id"`
+	Limits      LimitSet   `json:"limits"`
+	Pending     *LimitSet  `json:"pending,omitempty"`
+	PendingFrom *time.Time `json:"pending_from,omitempty"`
+	// ExcludedUntil ends a cooling-off or self-exclusion. Until then the
+	// player cannot launch games or bet in sessions already open.
+	ExcludedUntil *time.Time `json:"excluded_until,omitempty"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+// Effective returns the limits in force at the given time.
+func (g GamingLimits) Effective(at time.Time) LimitSet {
+	if g.Pending != nil && g.PendingFrom != nil && !at.Before(*g.PendingFrom) {
+		return *g.Pending
+	}
+	return g.Limits
+}
+
+// Excluded reports whether the player is cooling off or self-excluded at the
+// given time.
+func (g GamingLimits) Excluded(at time.Time) bool {
+	return g.ExcludedUntil != nil && at.Before(*g.ExcludedUntil)
+}
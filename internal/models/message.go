@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+// Message kinds.
+const (
+	// MessageAnnouncement is a copy of a message staff broadcast to many users.
+	MessageAnnouncement = "announcement"
+	// MessageSupport is written by staff to one user.
+	MessageSupport = "support"
+)
+
+// Message is one message in a user's inbox.
+type Message struct {
+	ID       int64  `json:"id"`
+	UserID   int64  `json:"user_id"`
+	Kind     string `json:"kind"`
+	Subject  string `json:"subject"`
+	Body     string `json:"body"`
+	SenderID int64  `json:"sender_id"`
+	// ReadAt is unset until the user marks the message read.
+	ReadAt    *time.Time `json:"read_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// Inbox is a page of a user's messages, newest first, with how many of all
+// their messages are unread.
+type Inbox struct {
+	Messages []Message `json:"messages"`
+	Unread   int       `json:"unread"`
+}
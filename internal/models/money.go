@@ -0,0 +1,30 @@
+package models
+
+// Where the currency symbol goes relative to the number.
+const (
+	SymbolBefore = "before"
+	SymbolAfter  = "after"
+)
+
+// MoneyFormat tells clients how to show amounts to the caller: the wallet's
+// currency and the number conventions of the caller's locale.
+type MoneyFormat struct {
+	Currency string `json:"currency"`
+	Locale   string `json:"locale"`
+	// MinorUnits is the number of decimals of the currency, e.g. 2 for cents
+	// and 0 for yen. Minor amounts are the amount times 10^MinorUnits.
+	MinorUnits     int    `json:"minor_units"`
+	Symbol         string `json:"symbol"`
+	SymbolPosition string `json:"symbol_position"`
+	// SymbolSpacing puts a no-break space between the symbol and the number.
+	SymbolSpacing     bool   `json:"symbol_spacing"`
+	DecimalSeparator  string `json:"decimal_separator"`
+	GroupingSeparator string `json:"grouping_separator"`
+}
+
+// MoneyAmount is an amount in the currency's minor units, with its display
+// string in the caller's format.
+type MoneyAmount struct {
+	Minor     int64  `json:"minor"`
+	Formatted string `json:"formatted"`
+}
@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+// UserNote is an internal staff note attached to a user account.
+type UserNote struct {
+	ID             int64     `json:"id"`
+	UserID         int64     `json:"user_id"`
+	AuthorID       int64     `json:"author_id"`
+	AuthorUsername string    `json:"author_username"`
+	Body           string    `json:"body"`
+	Pinned         bool      `json:"pinned"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// NoteRevision records the body a note had before an edit.
+type NoteRevision struct {
+	ID             int64     `json:"id"`
+	NoteID         int64     `json:"note_id"`
+	Body           string    `json:"body"`
+	EditorID       int64     `json:"editor_id"`
+	EditorUsername string    `json:"editor_username"`
+	EditedAt       time.Time `json:"edited_at"`
+}
@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+// OAuthClient is a companion app allowed to sign users in through the
+// OpenID Connect provider.
+type OAuthClient struct {
+	ID           string   `json:"client_id"`
+	Name         string   `json:"name"`
+	RedirectURIs []string `json:"redirect_uris"`
+	// Confidential clients authenticate to /token with a secret; public ones,
+	// such as mobile apps and SPAs, rely on PKCE alone.
+	Confidential bool   `json:"confidential"`
+	SecretHash   string `json:"-"`
+	// Secret is only returned when the client is created.
+	Secret    string    `json:"client_secret,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// AuthorizationCode is a single-use grant issued by /authorize and redeemed
+// at /token.
+type AuthorizationCode struct {
+	CodeHash    string
+	ClientID    string
+	UserID      int64
+	RedirectURI string
+	Scope       string
+	Nonce       string
+	// CodeChallenge is the S256 PKCE challenge the redeemer must answer.
+	CodeChallenge string
+	ExpiresAt     time.Time
+}
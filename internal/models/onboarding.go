@@ -0,0 +1,46 @@
+package models
+
+import "time"
+
+// OnboardingStep is one step of a welcome journey. Template is the email sent
+// when the user reaches the step and again as a nudge; CompleteOn and NudgeOn
+// name the domain events that complete the step and that prompt a nudge.
+// Steps without CompleteOn are completed by the feature they describe.
+type OnboardingStep struct {
+	Key        string `json:"key"`
+	Title      string `json:"title"`
+	Template   string `json:"template,omitempty"`
+	CompleteOn string `json:"complete_on,omitempty"`
+	NudgeOn    string `json:"nudge_on,omitempty"`
+}
+
+// OnboardingJourney is a tenant's ordered list of onboarding steps. An empty
+// Tenant is the default journey used when a tenant has none of its own.
+type OnboardingJourney struct {
+	Tenant    string           `json:"tenant"`
+	Steps     []OnboardingStep `json:"steps"`
+	UpdatedAt time.Time        `json:"updated_at"`
+}
+
+// OnboardingProgress records a user's progress through one step.
+type OnboardingProgress struct {
+	UserID      int64      `json:"user_id"`
+	StepKey     string     `json:"step_key"`
+	NudgedAt    *time.Time `json:"nudged_at,omitempty"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+}
+
+// OnboardingStatus is a user's view of their journey.
+type OnboardingStatus struct {
+	Tenant string `json:"tenant"`
+	// Current is the first incomplete step, empty once the journey is done.
+	Current string                 `json:"current,omitempty"`
+	Steps   []OnboardingStepStatus `json:"steps"`
+}
+
+// OnboardingStepStatus is one step of an OnboardingStatus.
+type OnboardingStepStatus struct {
+	Key         string     `json:"key"`
+	Title       string     `json:"title"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+}
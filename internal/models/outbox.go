@@ -0,0 +1,17 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// OutboxEvent is a domain event stored in the same transaction as the change
+// it describes, kept until the relay has published it.
+type OutboxEvent struct {
+	ID     int64           `json:"id"`
+	Type   string          `json:"type"`
+	Data   json.RawMessage `json:"data"`
+	SentAt *time.Time      `json:"sent_at,omitempty"`
+	// CreatedAt is when the change committed, or close to it.
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -1,7 +1,62 @@
 package models
 
+import "time"
+
+// Permission names granted to roles through the role_permissions table.
+const (
+	PermGamePlay        = "game:play"
+	PermBonusClaim      = "bonus:claim"
+	PermSupportPriority = "support:priority"
+	PermNotesRead       = "notes:read"
+	PermNotesWrite      = "notes:write"
+	PermConfigManage    = "config:manage"
+	PermStatsRead       = "stats:read"
+	PermSecurityRead    = "security:read"
+	PermUsersRead       = "users:read"
+	PermIntegrations    = "integrations:manage"
+	PermRolesManage     = "roles:manage"
+	PermUserOverrides   = "users:permissions"
+	PermUsersLock       = "users:lock"
+	PermLegalHold       = "legal:hold"
+	PermBalanceAdjust   = "balance:adjust"
+	// PermWithdrawalsReview approves and rejects withdrawals held for review.
+	PermWithdrawalsReview = "withdrawals:review"
+	// PermUsersWrite edits other users' usernames, emails and phone numbers.
+	PermUsersWrite = "users:write"
+	// PermUsersImpersonate signs in as a player to reproduce their issues.
+	PermUsersImpersonate = "users:impersonate"
+	// PermUsersMerge folds a player's duplicate account into their main one.
+	PermUsersMerge = "users:merge"
+	// PermMessagesSend writes to players' inboxes and broadcasts announcements.
+	PermMessagesSend = "messages:send"
+	// PermSupportManage works the support ticket queue.
+	PermSupportManage = "support:manage"
+	// PermTournamentsScore reports tournament scores, for game servers
+	// rather than players.
+	PermTournamentsScore = "tournaments:score"
+)
+
+// BuiltinPermissions are checked by code, so they cannot be renamed or deleted.
+var BuiltinPermissions = []string{
+	PermGamePlay, PermBonusClaim, PermSupportPriority, PermNotesRead, PermNotesWrite, PermConfigManage,
+	PermStatsRead, PermSecurityRead, PermUsersRead, PermIntegrations, PermRolesManage, PermUserOverrides,
+	PermUsersLock, PermLegalHold, PermBalanceAdjust, PermWithdrawalsReview, PermUsersWrite, PermUsersImpersonate,
+	PermUsersMerge, PermMessagesSend, PermSupportManage, PermTournamentsScore,
+}
+
 type Permission struct {
 	ID                    int64  `json:"id"`
 	PermissionName        string `json:"name"`
 	PermissionDescription string `json:"description"`
 }
+
+// PermissionOverride grants (Allow) or revokes one permission for a single user
+// on top of their role. A revocation wins over the role's grant.
+type PermissionOverride struct {
+	UserID       int64     `json:"user_id"`
+	PermissionID int64     `json:"permission_id"`
+	Permission   string    `json:"permission"`
+	Allow        bool      `json:"allow"`
+	SetBy        int64     `json:"set_by"`
+	SetAt        time.Time `json:"set_at"`
+}
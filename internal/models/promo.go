@@ -0,0 +1,48 @@
+package models
+
+import "time"
+
+// PromoCode is an admin-issued code that credits a fixed amount to the wallet
+// of the player who redeems it.
+type PromoCode struct {
+	ID   int64  `json:"id"`
+	Code string `json:"code"`
+	// Campaign groups codes for redemption reporting.
+	Campaign string  `json:"campaign"`
+	Amount   float64 `json:"amount"`
+	// MaxRedemptions caps redemptions across all players; 0 is unlimited.
+	MaxRedemptions int `json:"max_redemptions"`
+	// PerUserLimit is how many times one player may redeem the code.
+	PerUserLimit int `json:"per_user_limit"`
+	// Roles restricts the code to players with one of the roles; empty allows everyone.
+	Roles     []string   `json:"roles"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	// Active is cleared to withdraw a code before it expires.
+	Active      bool      `json:"active"`
+	Redemptions int       `json:"redemptions"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// PromoRedemption is one player's redemption of a promo code.
+type PromoRedemption struct {
+	ID       int64   `json:"id"`
+	CodeID   int64   `json:"code_id"`
+	Code     string  `json:"code"`
+	Campaign string  `json:"campaign"`
+	UserID   int64   `json:"user_id"`
+	Amount   float64 `json:"amount"`
+	// TransactionID is the ledger entry that credited the wallet.
+	TransactionID int64     `json:"transaction_id"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// PromoCampaignReport summarizes the redemptions of a campaign's codes.
+type PromoCampaignReport struct {
+	Campaign    string `json:"campaign"`
+	Codes       int    `json:"codes"`
+	Redemptions int    `json:"redemptions"`
+	// Players counts distinct redeeming players.
+	Players        int        `json:"players"`
+	Amount         float64    `json:"amount"`
+	LastRedeemedAt *time.Time `json:"last_redeemed_at,omitempty"`
+}
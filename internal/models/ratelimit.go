@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+// Route classes that rate-limit policies can target.
+const (
+	RouteClassAuth = "auth"
+	RouteClassAPI  = "api"
+)
+
+// RouteClasses lists every route class a policy may target.
+var RouteClasses = []string{RouteClassAuth, RouteClassAPI}
+
+// RateLimitPolicy caps requests per client for a route class. An empty Tenant is
+// the default policy applied when no tenant-specific row exists.
+type RateLimitPolicy struct {
+	Tenant        string    `json:"tenant"`
+	RouteClass    string    `json:"route_class"`
+	Requests      int       `json:"requests"`
+	WindowSeconds int       `json:"window_seconds"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// Window returns the policy's refill window.
+func (p RateLimitPolicy) Window() time.Duration {
+	return time.Duration(p.WindowSeconds) * time.Second
+}
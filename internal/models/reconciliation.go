@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+// BalanceDiscrepancy is a user whose stored balance disagrees with their ledger.
+type BalanceDiscrepancy struct {
+	UserID   int64  `json:"user_id"`
+	Username string `json:"username"`
+	// Balance is users.balance.
+	Balance float64 `json:"balance"`
+	// LedgerBalance is the balance before the user's first ledger entry plus
+	// every entry since.
+	LedgerBalance float64 `json:"ledger_balance"`
+	// Difference is Balance minus LedgerBalance.
+	Difference float64 `json:"difference"`
+}
+
+// ReconciliationReport is the outcome of one balance reconciliation run.
+type ReconciliationReport struct {
+	ID           int64 `json:"id"`
+	UsersChecked int   `json:"users_checked"`
+	Mismatches   int   `json:"mismatches"`
+	// Discrepancies lists the mismatched users by ID; it is capped, so it may
+	// be shorter than Mismatches.
+	Discrepancies []BalanceDiscrepancy `json:"discrepancies"`
+	CreatedAt     time.Time            `json:"created_at"`
+}
@@ -0,0 +1,57 @@
+package models
+
+import "time"
+
+// Operator report periods. Daily and weekly reports cover a UTC day or an ISO
+// week starting Monday; custom ones cover any run of whole days.
+const (
+	ReportDaily  = "daily"
+	ReportWeekly = "weekly"
+	ReportCustom = "custom"
+)
+
+// ReportPeriods lists the valid report periods.
+var ReportPeriods = []string{ReportDaily, ReportWeekly, ReportCustom}
+
+// Operator report statuses.
+const (
+	ReportPending = "pending"
+	ReportReady   = "ready"
+	ReportFailed  = "failed"
+)
+
+// ReportMetrics are the figures an operator report covers.
+type ReportMetrics struct {
+	Signups int64 `json:"signups"`
+	// GGR is gross gaming revenue: stakes lost minus wins paid, taken from
+	// bet settlements.
+	GGR           float64 `json:"ggr"`
+	Deposits      int64   `json:"deposits"`
+	DepositAmount float64 `json:"deposit_amount"`
+	// Withdrawals counts the withdrawal requests made in the period that
+	// were not rejected and have not failed.
+	Withdrawals      int64   `json:"withdrawals"`
+	WithdrawalAmount float64 `json:"withdrawal_amount"`
+}
+
+// OperatorReport is a generated summary of activity over [PeriodStart,
+// PeriodEnd), kept as CSV and PDF files in the blob store.
+type OperatorReport struct {
+	ID          int64     `json:"id"`
+	Period      string    `json:"period"`
+	PeriodStart time.Time `json:"period_start"`
+	PeriodEnd   time.Time `json:"period_end"`
+	Status      string    `json:"status"`
+	// Metrics are set once the report is ready.
+	Metrics *ReportMetrics `json:"metrics,omitempty"`
+	// RequestedBy is the admin who asked for an ad-hoc report; it is nil for
+	// scheduled ones.
+	RequestedBy *int64     `json:"requested_by,omitempty"`
+	Error       string     `json:"error,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	// CSVURL and PDFURL are signed links to a ready report's files, filled
+	// in when the report is served.
+	CSVURL string `json:"csv_url,omitempty"`
+	PDFURL string `json:"pdf_url,omitempty"`
+}
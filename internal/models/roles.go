@@ -4,11 +4,19 @@ const (
 	NormalUser = "player"
 	VIPUser    = "vip-player"
 	VVIPUser   = "vvip-player"
+	StaffUser  = "staff"
+	AdminUser  = "admin"
 )
 
+// PlayerRoles are the roles held by customers rather than staff.
+var PlayerRoles = []string{NormalUser, VIPUser, VVIPUser}
+
+// BuiltinRoles are referenced by code, so they cannot be renamed or deleted.
+var BuiltinRoles = []string{NormalUser, VIPUser, VVIPUser, StaffUser, AdminUser}
+
 type Role struct {
-	ID              int64   `json:"id"`
-	RoleName        string  `json:"role"`
-	RoleDescription string  `json:"description"`
-	Permission      []int64 `json:"permission"`
+	ID              int64    `json:"id"`
+	RoleName        string   `json:"role"`
+	RoleDescription string   `json:"description"`
+	Permissions     []string `json:"permissions"`
 }
@@ -0,0 +1,14 @@
+package models
+
+// UserSearchCursor marks the last result of a page. Results are ordered by
+// Rank descending, then ID ascending.
+type UserSearchCursor struct {
+	Rank float64 `json:"r"`
+	ID   int64   `json:"id"`
+}
+
+// UserSearchResult is a matching user and how closely it matched.
+type UserSearchResult struct {
+	User User
+	Rank float64
+}
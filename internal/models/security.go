@@ -0,0 +1,176 @@
+package models
+
+import "time"
+
+// Login alert states.
+const (
+	LoginAlertPending  = "pending"
+	LoginAlertApproved = "approved"
+	LoginAlertDenied   = "denied"
+)
+
+// Security case states.
+const (
+	SecurityCaseOpen = "open"
+)
+
+// Reasons a login attempt failed.
+const (
+	LoginUnknownUser     = "unknown_user"
+	LoginInvalidPassword = "invalid_password"
+	LoginResetRequired   = "password_reset_required"
+	// LoginDeviceUnconfirmed means the password was right but the user's role
+	// requires new devices to be confirmed by email first.
+	LoginDeviceUnconfirmed = "device_unconfirmed"
+	// LoginIPBlocked means an IP risk policy refused the client's network.
+	LoginIPBlocked = "ip_blocked"
+	// LoginAccountMerged means the account was merged into another as a
+	// duplicate.
+	LoginAccountMerged = "account_merged"
+	// LoginCaptchaFailed means a CAPTCHA was required after repeated failures
+	// and was missing or not solved.
+	LoginCaptchaFailed = "captcha_failed"
+)
+
+// LoginAttempt is one sign-in attempt, successful or not.
+type LoginAttempt struct {
+	ID int64 `json:"id"`
+	// UserID is nil when the identifier matched no account.
+	UserID *int64 `json:"user_id,omitempty"`
+	// Identifier is the username or email the client signed in with.
+	Identifier    string    `json:"identifier"`
+	Success       bool      `json:"success"`
+	FailureReason string    `json:"failure_reason,omitempty"`
+	IP            string    `json:"ip"`
+	UserAgent     string    `json:"user_agent"`
+	Country       string    `json:"country,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// LoginAttemptFilter narrows a login history listing; zero fields match everything.
+type LoginAttemptFilter struct {
+	UserID  int64
+	IP      string
+	Success *bool
+	// IncludeArchived also searches attempts moved to the archive.
+	IncludeArchived bool
+}
+
+// LoginDevice is a device and country combination a user has signed in from.
+type LoginDevice struct {
+	UserID int64 `json:"user_id"`
+	// Fingerprint identifies the device: the client's X-Device-ID when sent,
+	// otherwise a hash of its User-Agent and Accept-Language/Encoding headers.
+	Fingerprint string    `json:"fingerprint"`
+	Country     string    `json:"country"`
+	FirstSeen   time.Time `json:"first_seen"`
+	LastSeen    time.Time `json:"last_seen"`
+}
+
+// LoginAlert asks a user to confirm a sign-in from an unfamiliar device or country.
+type LoginAlert struct {
+	ID          int64      `json:"id"`
+	UserID      int64      `json:"user_id"`
+	Fingerprint string     `json:"fingerprint"`
+	Country     string     `json:"country"`
+	IP          string     `json:"ip"`
+	UserAgent   string     `json:"user_agent"`
+	TokenHash   string     `json:"-"`
+	Status      string     `json:"status"`
+	CreatedAt   time.Time  `json:"created_at"`
+	ResolvedAt  *time.Time `json:"resolved_at,omitempty"`
+}
+
+// SecurityCase records an account compromise report for staff follow-up.
+type SecurityCase struct {
+	ID           int64     `json:"id"`
+	UserID       int64     `json:"user_id"`
+	Username     string    `json:"username"`
+	LoginAlertID *int64    `json:"login_alert_id,omitempty"`
+	Reason       string    `json:"reason"`
+	Status       string    `json:"status"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// PasswordReset is a single-use token that lets a user choose a new password.
+type PasswordReset struct {
+	UserID    int64
+	TokenHash string
+	ExpiresAt time.Time
+}
+
+// DeviceConfirmation is a single-use token that trusts a new device for a user
+// whose role requires new devices to be confirmed before they can sign in.
+type DeviceConfirmation struct {
+	UserID      int64
+	Fingerprint string
+	Country     string
+	TokenHash   string
+	ExpiresAt   time.Time
+}
+
+// Security event types shown in a user's security overview.
+const (
+	SecurityEventLoginFailed     = "login_failed"
+	SecurityEventNewDevice       = "new_device"
+	SecurityEventLoginAlert      = "login_alert"
+	SecurityEventSessionsRevoked = "sessions_revoked"
+)
+
+// Sign-in methods an account can use.
+const (
+	AuthMethodPassword = "password"
+	// AuthMethodEmail receives password reset and device confirmation links.
+	AuthMethodEmail = "email"
+)
+
+// SecurityOverview is a user's account security at a glance, for the
+// settings page.
+type SecurityOverview struct {
+	Sessions           []ActiveSession          `json:"sessions"`
+	Devices            []LoginDevice            `json:"devices"`
+	AuthMethods        []AuthMethod             `json:"auth_methods"`
+	TwoFactor          TwoFactorStatus          `json:"two_factor"`
+	RecentEvents       []SecurityEvent          `json:"recent_events"`
+	RecommendedActions []SecurityRecommendation `json:"recommended_actions"`
+}
+
+// ActiveSession is a successful sign-in whose token has not expired or been
+// revoked. Tokens are stateless, so sessions are derived from the login history.
+type ActiveSession struct {
+	IP         string    `json:"ip"`
+	UserAgent  string    `json:"user_agent"`
+	Country    string    `json:"country,omitempty"`
+	SignedInAt time.Time `json:"signed_in_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+// AuthMethod is one way the account signs in or recovers access.
+type AuthMethod struct {
+	Type   string `json:"type"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// TwoFactorStatus reports whether sign-ins from new devices need a second
+// factor. The only one today is an emailed device confirmation, required by
+// role.
+type TwoFactorStatus struct {
+	Enabled bool   `json:"enabled"`
+	Method  string `json:"method,omitempty"`
+}
+
+// SecurityEvent is a notable security event on the account.
+type SecurityEvent struct {
+	Type string `json:"type"`
+	// Detail is the failure reason or alert status, where there is one.
+	Detail  string    `json:"detail,omitempty"`
+	IP      string    `json:"ip,omitempty"`
+	Country string    `json:"country,omitempty"`
+	At      time.Time `json:"at"`
+}
+
+// SecurityRecommendation is an action the user should take, with why.
+type SecurityRecommendation struct {
+	Action string `json:"action"`
+	Reason string `json:"reason"`
+}
@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// How a user appears in public activity feeds such as recent big wins.
+const (
+	// ActivityMasked shows a shortened username, e.g. "a***e". It is the default.
+	ActivityMasked = "masked"
+	ActivityPublic = "public"
+	ActivityHidden = "hidden"
+)
+
+// PrivacySettings are a user's choices about public exposure.
+type PrivacySettings struct {
+	UserID         int64     `json:"user_id"`
+	PublicActivity string    `json:"public_activity"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// BigWin is a large bet settlement as shown to spectators. Player is the
+// display name chosen by the winner's privacy settings.
+type BigWin struct {
+	Player      string      `json:"player"`
+	Amount      float64     `json:"amount"`
+	AmountMoney MoneyAmount `json:"amount_money"`
+	WonAt       time.Time   `json:"won_at"`
+	// Username and PublicActivity are the winner's; they never leave the server.
+	Username       string `json:"-"`
+	PublicActivity string `json:"-"`
+}
@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// AdminStats aggregates platform metrics for the operator dashboard.
+type AdminStats struct {
+	TotalUsers    int64        `json:"total_users"`
+	TotalBalance  float64      `json:"total_balance"`
+	SignupsPerDay []DailyCount `json:"signups_per_day"`
+	GeneratedAt   time.Time    `json:"generated_at"`
+}
+
+// DailyCount is a count bucketed by UTC day (YYYY-MM-DD).
+type DailyCount struct {
+	Day   string `json:"day"`
+	Count int64  `json:"count"`
+}
+
+// BusinessKPIs are the platform's lifetime business totals, exported as
+// metrics.
+type BusinessKPIs struct {
+	Registrations  int64
+	Deposits       int64
+	DepositsAmount float64
+	// Bets counts settled bets.
+	Bets int64
+	// GGR is gross gaming revenue: what players lost on settled bets minus
+	// what they won.
+	GGR float64
+}
@@ -0,0 +1,65 @@
+package models
+
+import "time"
+
+// Support ticket statuses. A ticket is open while it waits for staff and
+// pending while it waits for the player.
+const (
+	TicketOpen    = "open"
+	TicketPending = "pending"
+	TicketClosed  = "closed"
+)
+
+// Support ticket priorities. Players holding PermSupportPriority get high
+// priority tickets, which the staff queue lists first.
+const (
+	TicketPriorityNormal = "normal"
+	TicketPriorityHigh   = "high"
+)
+
+// SupportTicket is a player's request for help.
+type SupportTicket struct {
+	ID        int64      `json:"id"`
+	UserID    int64      `json:"user_id"`
+	Username  string     `json:"username"`
+	Subject   string     `json:"subject"`
+	Status    string     `json:"status"`
+	Priority  string     `json:"priority"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	ClosedAt  *time.Time `json:"closed_at,omitempty"`
+}
+
+// TicketReply is one message in a ticket's thread; the first is the
+// player's description of their problem.
+type TicketReply struct {
+	ID             int64  `json:"id"`
+	TicketID       int64  `json:"ticket_id"`
+	AuthorID       int64  `json:"author_id"`
+	AuthorUsername string `json:"author_username"`
+	// Staff is set for replies written from the staff queue.
+	Staff     bool      `json:"staff"`
+	Body      string    `json:"body"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TicketAttachment is a file added to a ticket, kept in the blob store.
+type TicketAttachment struct {
+	ID          int64  `json:"id"`
+	TicketID    int64  `json:"ticket_id"`
+	UploaderID  int64  `json:"uploader_id"`
+	Name        string `json:"name"`
+	ContentType string `json:"content_type"`
+	Size        int64  `json:"size"`
+	BlobKey     string `json:"-"`
+	// URL is a signed download link, filled in when the attachment is shown.
+	URL       string    `json:"url,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// SupportThread is a ticket with its replies, oldest first, and attachments.
+type SupportThread struct {
+	Ticket      SupportTicket      `json:"ticket"`
+	Replies     []TicketReply      `json:"replies"`
+	Attachments []TicketAttachment `json:"attachments"`
+}
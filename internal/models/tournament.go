@@ -0,0 +1,53 @@
+package models
+
+import "time"
+
+// Tournament states. A tournament takes entries until it ends and scores
+// while it runs; closing it ranks the entrants and pays the prizes.
+const (
+	TournamentOpen   = "open"
+	TournamentClosed = "closed"
+)
+
+// Tournament is a competition players pay an entry fee to join. The fees make
+// up the prize pool, which is shared out by rank when it closes.
+type Tournament struct {
+	ID       int64     `json:"id"`
+	Name     string    `json:"name"`
+	EntryFee float64   `json:"entry_fee"`
+	StartsAt time.Time `json:"starts_at"`
+	EndsAt   time.Time `json:"ends_at"`
+	// PrizeShares are the percentages of the prize pool paid to the first,
+	// second and following places. What they leave over is the house's.
+	PrizeShares []float64 `json:"prize_shares"`
+	PrizePool   float64   `json:"prize_pool"`
+	Entrants    int       `json:"entrants"`
+	Status      string    `json:"status"`
+	CreatedBy   int64     `json:"created_by"`
+	CreatedAt   time.Time `json:"created_at"`
+	// ClosedAt is when the prizes were paid.
+	ClosedAt *time.Time `json:"closed_at,omitempty"`
+}
+
+// TournamentEntry is one player's place in a tournament.
+type TournamentEntry struct {
+	TournamentID int64  `json:"tournament_id"`
+	UserID       int64  `json:"user_id"`
+	Username     string `json:"username"`
+	// Score is the player's best submitted score; ScoredAt is when they
+	// first reached it and is nil until they submit one.
+	Score    float64    `json:"score"`
+	ScoredAt *time.Time `json:"scored_at,omitempty"`
+	// Rank is the player's place among those with a score, 1 being first.
+	// Standings fill it in; it is 0 for players without a score.
+	Rank int `json:"rank,omitempty"`
+	// Prize is what the player was paid at close.
+	Prize     float64   `json:"prize,omitempty"`
+	EnteredAt time.Time `json:"entered_at"`
+}
+
+// TournamentStandings is a tournament with its entrants in rank order.
+type TournamentStandings struct {
+	Tournament Tournament        `json:"tournament"`
+	Entries    []TournamentEntry `json:"entries"`
+}
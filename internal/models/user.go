@@ -4,13 +4,38 @@ import "time"
 
 // User captures application-facing fields for an authenticated identity.
 type User struct {
-	ID           int64     `json:"id"`
-	Username     string    `json:"username"`
-	Email        string    `json:"email"`
-	Phone        string    `json:"phone"`
-	Role         string    `json:"role"`
-	Permissions  []string  `json:"permissions"`
-	Balance      float64   `json:"balance"`
-	PasswordHash string    `json:"-"`
-	CreatedAt    time.Time `json:"created_at"`
+	ID           int64    `json:"id"`
+	Username     string   `json:"username"`
+	Email        string   `json:"email"`
+	Phone        string   `json:"phone"`
+	Role         string   `json:"role"`
+	Permissions  []string `json:"permissions"`
+	Balance      float64  `json:"balance"`
+	PasswordHash string   `json:"-"`
+	// HomeRegion is the region that registered the user and owns their wallet.
+	HomeRegion string `json:"home_region,omitempty"`
+	// PasswordResetRequired blocks sign-in until the password is changed.
+	PasswordResetRequired bool `json:"password_reset_required,omitempty"`
+	// SessionsRevokedAt invalidates every token issued at or before it.
+	SessionsRevokedAt *time.Time `json:"-"`
+	// MergedInto is the account this one was merged into as a duplicate; a
+	// merged account can no longer sign in.
+	MergedInto *int64 `json:"merged_into,omitempty"`
+	// Version goes up with every change to the profile or balance, so an edit
+	// based on a stale copy can be refused.
+	Version int64 `json:"version"`
+	// PermissionsVersion goes up whenever the user's permissions change, so
+	// tokens carrying the previous ones can be refused.
+	PermissionsVersion int64     `json:"-"`
+	CreatedAt          time.Time `json:"created_at"`
+}
+
+// HasPermission reports whether the user's role or overrides grant the named permission.
+func (u User) HasPermission(name string) bool {
+	for _, p := range u.Permissions {
+		if p == name {
+			return true
+		}
+	}
+	return false
 }
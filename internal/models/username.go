@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+// How a reserved name entry is matched against usernames.
+const (
+	// UsernameReserved refuses usernames equal to the entry, such as "admin".
+	UsernameReserved = "reserved"
+	// UsernameBlocked refuses usernames containing the entry anywhere, for
+	// profanity.
+	UsernameBlocked = "blocked"
+)
+
+// UsernameEntryKinds lists the valid ReservedName kinds.
+var UsernameEntryKinds = []string{UsernameReserved, UsernameBlocked}
+
+// Where a reserved name entry comes from.
+const (
+	// ReservedNameSourceFile entries are read from USERNAME_RESERVED_FILE.
+	ReservedNameSourceFile = "file"
+	// ReservedNameSourceAdmin entries were added through the admin API.
+	ReservedNameSourceAdmin = "admin"
+)
+
+// ReservedName is a word usernames may not be, or may not contain.
+type ReservedName struct {
+	Name      string     `json:"name"`
+	Kind      string     `json:"kind"`
+	Source    string     `json:"source"`
+	CreatedBy int64      `json:"created_by,omitempty"`
+	CreatedAt *time.Time `json:"created_at,omitempty"`
+}
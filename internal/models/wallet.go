@@ -0,0 +1,123 @@
+package models
+
+import "time"
+
+// Ledger transaction reasons.
+const (
+	TransactionDeposit       = "deposit"
+	TransactionWithdrawal    = "withdrawal"
+	TransactionBetSettlement = "bet_settlement"
+	TransactionBonusGrant    = "bonus_grant"
+	// TransactionWelcomeBonus is the starting balance granted at sign-up.
+	TransactionWelcomeBonus = "welcome_bonus"
+	TransactionPromoCredit  = "promo_credit"
+	TransactionAdjustment   = "adjustment"
+	// TransactionPayoutReversal returns a withdrawal's held amount after a
+	// rejection or a failed payout.
+	TransactionPayoutReversal = "payout_reversal"
+	// TransactionAccountMerge moves a duplicate account's balance to the
+	// account it was merged into: a debit on one, a credit on the other.
+	TransactionAccountMerge = "account_merge"
+	// TransactionJackpotWin pays a jackpot's pot to its winner.
+	TransactionJackpotWin = "jackpot_win"
+	// TransactionTournamentEntry and TransactionTournamentPrize take a
+	// tournament's entry fee and pay its prizes.
+	TransactionTournamentEntry = "tournament_entry"
+	TransactionTournamentPrize = "tournament_prize"
+)
+
+// Transaction is one entry in a user's balance ledger.
+type Transaction struct {
+	ID     int64 `json:"id"`
+	UserID int64 `json:"user_id"`
+	// Amount is positive for credits and negative for debits.
+	Amount float64 `json:"amount"`
+	// BalanceAfter is the user's balance once the entry was applied.
+	BalanceAfter float64 `json:"balance_after"`
+	Reason       string  `json:"reason"`
+	// Reference ties the entry to its source, e.g. a bet or provider payment ID.
+	Reference string `json:"reference,omitempty"`
+	// MergedFrom is the duplicate account the entry was moved from by an
+	// account merge; BalanceAfter is then that account's balance.
+	MergedFrom *int64    `json:"merged_from,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// Wallet hold states.
+const (
+	HoldActive   = "active"
+	HoldCaptured = "captured"
+	HoldReleased = "released"
+)
+
+// WalletHold reserves part of a user's balance, e.g. the stake of a bet
+// placed but not settled. An active hold stays in the balance but cannot be
+// spent; capturing it debits the amount and releasing it frees it again.
+type WalletHold struct {
+	ID     int64   `json:"id"`
+	UserID int64   `json:"user_id"`
+	Amount float64 `json:"amount"`
+	// Reference ties the hold to its source, e.g. a bet ID. It is unique
+	// per user.
+	Reference string `json:"reference"`
+	Status    string `json:"status"`
+	// TransactionID is the ledger debit of a captured hold.
+	TransactionID *int64     `json:"transaction_id,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	SettledAt     *time.Time `json:"settled_at,omitempty"`
+}
+
+// WalletBalance splits a user's balance into what active holds reserve and
+// what is left to spend.
+type WalletBalance struct {
+	Total     float64 `json:"total"`
+	Held      float64 `json:"held"`
+	Available float64 `json:"available"`
+}
+
+// Operation kinds for internal balance movements that must apply exactly once.
+const (
+	OperationBetSettlement = "bet_settlement"
+	OperationBonusGrant    = "bonus_grant"
+	// OperationWelcomeBonus keys are the new user's ID.
+	OperationWelcomeBonus  = "welcome_bonus"
+	OperationWebhookCredit = "webhook_credit"
+	// OperationPromoRedemption keys are "codeID:userID:n" for the user's nth
+	// redemption of the code.
+	OperationPromoRedemption = "promo_redemption"
+	// OperationBalanceAdjustment keys are chosen by the admin client, so a
+	// retried request applies once.
+	OperationBalanceAdjustment = "balance_adjustment"
+	// OperationDeposit, OperationPayout and OperationPayoutReversal keys are
+	// "provider:reference" for the payment's reference.
+	OperationDeposit        = "deposit"
+	OperationPayout         = "payout"
+	OperationPayoutReversal = "payout_reversal"
+	// OperationGameDebit, OperationGameCredit and OperationGameRollback keys
+	// are "provider:transaction_id" for the game provider's transaction; a
+	// rollback's is the debit it reverses.
+	OperationGameDebit    = "game_debit"
+	OperationGameCredit   = "game_credit"
+	OperationGameRollback = "game_rollback"
+	// OperationJackpotWin keys are "jackpot:key" for the key the win was
+	// triggered with.
+	OperationJackpotWin = "jackpot_win"
+	// OperationTournamentEntry and OperationTournamentPrize keys are
+	// "tournamentID:userID".
+	OperationTournamentEntry = "tournament_entry"
+	OperationTournamentPrize = "tournament_prize"
+)
+
+// Operation records that an internal balance movement, identified by its
+// kind and key, has been applied to the ledger.
+type Operation struct {
+	Kind   string  `json:"kind"`
+	Key    string  `json:"key"`
+	UserID int64   `json:"user_id"`
+	Amount float64 `json:"amount"`
+	// TransactionID is the ledger entry the operation produced; it is nil
+	// only inside the transaction that claims the operation, and for a game
+	// rollback recorded before the debit it cancels.
+	TransactionID *int64    `json:"transaction_id,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+}
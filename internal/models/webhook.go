@@ -0,0 +1,55 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Webhook event types delivered to subscribed endpoints.
+const (
+	EventUserCreated    = "user.created"
+	EventWalletDeposit  = "wallet.deposit"
+	EventWalletWithdraw = "wallet.withdraw"
+	EventKYCApproved    = "kyc.approved"
+	EventUserNewDevice  = "user.new_device"
+)
+
+// WebhookEvents lists every event type an endpoint may subscribe to.
+var WebhookEvents = []string{EventUserCreated, EventWalletDeposit, EventWalletWithdraw, EventKYCApproved, EventUserNewDevice}
+
+// WebhookEndpoint is an external URL that receives signed event notifications.
+type WebhookEndpoint struct {
+	ID  int64  `json:"id"`
+	URL string `json:"url"`
+	// Secret signs deliveries. It is only returned when the endpoint is created.
+	Secret    string    `json:"secret,omitempty"`
+	Events    []string  `json:"events"`
+	Active    bool      `json:"active"`
+	CreatedBy int64     `json:"created_by"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Subscribed reports whether the endpoint wants events of the given type.
+func (e WebhookEndpoint) Subscribed(event string) bool {
+	for _, ev := range e.Events {
+		if ev == event {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookDelivery records one attempt to deliver an event to an endpoint.
+type WebhookDelivery struct {
+	ID         int64           `json:"id"`
+	EndpointID int64           `json:"endpoint_id"`
+	EventID    string          `json:"event_id"`
+	Event      string          `json:"event"`
+	Payload    json.RawMessage `json:"payload"`
+	Attempt    int             `json:"attempt"`
+	StatusCode int             `json:"status_code,omitempty"`
+	Error      string          `json:"error,omitempty"`
+	Success    bool            `json:"success"`
+	DurationMS int64           `json:"duration_ms"`
+	CreatedAt  time.Time       `json:"created_at"`
+}
@@ -0,0 +1,42 @@
+package models
+
+import "time"
+
+// Withdrawal statuses. Requests below the approval threshold start out
+// approved; the others wait as pending for an admin. An approved request's
+// payout has been sent, and the provider's callback marks it paid or failed.
+const (
+	WithdrawalPending  = "pending"
+	WithdrawalApproved = "approved"
+	WithdrawalRejected = "rejected"
+	WithdrawalPaid     = "paid"
+	WithdrawalFailed   = "failed"
+)
+
+// WithdrawalRequest is a player's request to be paid out. Its amount is held
+// by a ledger debit from the moment it is made, so it cannot be spent while
+// the request is reviewed; rejected and failed requests get it back.
+type WithdrawalRequest struct {
+	ID       int64  `json:"id"`
+	UserID   int64  `json:"user_id"`
+	Provider string `json:"provider"`
+	// Reference names the payout in the provider's callbacks.
+	Reference string  `json:"reference"`
+	Amount    float64 `json:"amount"`
+	Status    string  `json:"status"`
+	// HoldID is the ledger entry that debited the amount.
+	HoldID int64 `json:"hold_transaction_id"`
+	// ReviewedBy is the admin who approved or rejected the request; it is
+	// nil for requests approved automatically.
+	ReviewedBy *int64 `json:"reviewed_by,omitempty"`
+	// Note is the reason given for a rejection or the cause of a failure.
+	Note      string    `json:"note,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// WithdrawalFilter narrows a withdrawal listing. Empty fields match everything.
+type WithdrawalFilter struct {
+	UserID int64
+	Status string
+}
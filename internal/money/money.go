@@ -0,0 +1,203 @@
+// Package money formats amounts for display. A deployment keeps balances in
+// one currency (MONEY_CURRENCY); how amounts are written depends on the
+// caller's locale, negotiated from Accept-Language among the configured ones.
+// Money-bearing responses carry each amount in minor units with its formatted
+// string, plus the format itself so clients can render amounts they compute.
+package money
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/hongminglow/all-in-be/internal/i18n"
+	"github.com/hongminglow/all-in-be/internal/models"
+)
+
+type currency struct {
+	symbol     string
+	minorUnits int
+}
+
+// currencies are the ISO 4217 codes a deployment can keep balances in.
+var currencies = map[string]currency{
+	"AUD": {"A$", 2},
+	"BRL": {"R$", 2},
+	"CAD": {"CA$", 2},
+	"CNY": {"¥", 2},
+	"EUR": {"€", 2},
+	"GBP": {"£", 2},
+	"IDR": {"Rp", 2},
+	"INR": {"₹", 2},
+	"JPY": {"¥", 0},
+	"KRW": {"₩", 0},
+	"MYR": {"RM", 2},
+	"PHP": {"₱", 2},
+	"SGD": {"S$", 2},
+	"THB": {"฿", 2},
+	"USD": {"$", 2},
+	"VND": {"₫", 0},
+}
+
+type convention struct {
+	decimal, grouping string
+	after, spacing    bool
+}
+
+// locales are the number conventions amounts can be written in.
+var locales = map[string]convention{
+	"de-DE": {",", ".", true, true},
+	"en-AU": {".", ",", false, false},
+	"en-GB": {".", ",", false, false},
+	"en-US": {".", ",", false, false},
+	"es-ES": {",", ".", true, true},
+	"fr-FR": {",", "\u202f", true, true},
+	"id-ID": {",", ".", false, false},
+	"it-IT": {",", ".", true, true},
+	"ja-JP": {".", ",", false, false},
+	"ko-KR": {".", ",", false, false},
+	"ms-MY": {".", ",", false, false},
+	"pt-BR": {",", ".", false, true},
+	"th-TH": {".", ",", false, false},
+	"vi-VN": {",", ".", true, true},
+	"zh-CN": {".", ",", false, false},
+}
+
+// KnownCurrency reports whether code is a supported ISO 4217 currency.
+func KnownCurrency(code string) bool {
+	_, ok := currencies[code]
+	return ok
+}
+
+// KnownLocale reports whether tag names supported number conventions.
+func KnownLocale(tag string) bool {
+	_, ok := locales[tag]
+	return ok
+}
+
+// Locales lists every supported locale.
+func Locales() []string {
+	tags := make([]string, 0, len(locales))
+	for tag := range locales {
+		tags = append(tags, tag)
+	}
+	slices.Sort(tags)
+	return tags
+}
+
+// Formatter picks the money format for each caller.
+type Formatter struct {
+	currency      string
+	defaultLocale string
+	locales       []string
+}
+
+// NewFormatter formats amounts in currency for the given locales, falling
+// back to defaultLocale. No locales means every supported one.
+func NewFormatter(currency, defaultLocale string, enabled []string) (*Formatter, error) {
+	if !KnownCurrency(currency) {
+		return nil, fmt.Errorf("unsupported currency %q", currency)
+	}
+	if len(enabled) == 0 {
+		enabled = Locales()
+	}
+	for _, tag := range append([]string{defaultLocale}, enabled...) {
+		if !KnownLocale(tag) {
+			return nil, fmt.Errorf("unsupported locale %q", tag)
+		}
+	}
+	return &Formatter{currency: currency, defaultLocale: defaultLocale, locales: enabled}, nil
+}
+
+// Negotiate returns the format for an Accept-Language header: the first
+// acceptable language, by weight, matching an enabled locale exactly or by
+// language, or the default locale.
+func (f *Formatter) Negotiate(acceptLanguage string) models.MoneyFormat {
+	for _, tag := range i18n.Preferences(acceptLanguage) {
+		for _, enabled := range f.locales {
+			if strings.EqualFold(enabled, tag) {
+				return f.Format(enabled)
+			}
+		}
+		language, _, _ := strings.Cut(tag, "-")
+		for _, enabled := range f.locales {
+			if prefix, _, _ := strings.Cut(enabled, "-"); strings.EqualFold(prefix, language) {
+				return f.Format(enabled)
+			}
+		}
+	}
+	return f.Format(f.defaultLocale)
+}
+
+// Format returns the format of a supported locale.
+func (f *Formatter) Format(locale string) models.MoneyFormat {
+	c, l := currencies[f.currency], locales[locale]
+	position := models.SymbolBefore
+	if l.after {
+		position = models.SymbolAfter
+	}
+	return models.MoneyFormat{
+		Currency:          f.currency,
+		Locale:            locale,
+		MinorUnits:        c.minorUnits,
+		Symbol:            c.symbol,
+		SymbolPosition:    position,
+		SymbolSpacing:     l.spacing,
+		DecimalSeparator:  l.decimal,
+		GroupingSeparator: l.grouping,
+	}
+}
+
+// Amount converts an amount to minor units and formats it.
+func Amount(format models.MoneyFormat, amount float64) models.MoneyAmount {
+	minor := int64(math.Round(amount * math.Pow10(format.MinorUnits)))
+	return models.MoneyAmount{Minor: minor, Formatted: formatMinor(format, minor)}
+}
+
+func formatMinor(format models.MoneyFormat, minor int64) string {
+	sign := ""
+	if minor < 0 {
+		sign, minor = "-", -minor
+	}
+	scale := int64(math.Pow10(format.MinorUnits))
+	digits := strconv.FormatInt(minor/scale, 10)
+	var b strings.Builder
+	for i, d := range digits {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			b.WriteString(format.GroupingSeparator)
+		}
+		b.WriteRune(d)
+	}
+	if format.MinorUnits > 0 {
+		fmt.Fprintf(&b, "%s%0*d", format.DecimalSeparator, format.MinorUnits, minor%scale)
+	}
+	space := ""
+	if format.SymbolSpacing {
+		space = "\u00a0"
+	}
+	if format.SymbolPosition == models.SymbolAfter {
+		return sign + b.String() + space + format.Symbol
+	}
+	return sign + format.Symbol + space + b.String()
+}
+
+type contextKey struct{}
+
+// WithFormat returns a copy of ctx carrying the caller's money format.
+func WithFormat(ctx context.Context, format models.MoneyFormat) context.Context {
+	return context.WithValue(ctx, contextKey{}, format)
+}
+
+// FormatFromContext returns the caller's money format, or US dollars in
+// en-US when none was negotiated.
+func FormatFromContext(ctx context.Context) models.MoneyFormat {
+	if format, ok := ctx.Value(contextKey{}).(models.MoneyFormat); ok {
+		return format
+	}
+	return fallback.Format("en-US")
+}
+
+var fallback = &Formatter{currency: "USD", defaultLocale: "en-US", locales: []string{"en-US"}}
@@ -0,0 +1,62 @@
+package money
+
+import (
+	"testing"
+)
+
+func TestAmount(t *testing.T) {
+	usd, _ := NewFormatter("USD", "en-US", nil)
+	jpy, _ := NewFormatter("JPY", "ja-JP", nil)
+	for _, tc := range []struct {
+		name  string
+		f     *Formatter
+		loc   string
+		in    float64
+		minor int64
+		want  string
+	}{
+		{"en-US", usd, "en-US", 1234567.891, 123456789, "$1,234,567.89"},
+		{"de-DE", usd, "de-DE", 1234.5, 123450, "1.234,50\u00a0$"},
+		{"fr-FR", usd, "fr-FR", 1234.5, 123450, "1\u202f234,50\u00a0$"},
+		{"pt-BR", usd, "pt-BR", 0.05, 5, "$\u00a00,05"},
+		{"negative", usd, "en-US", -1000, -100000, "-$1,000.00"},
+		{"no minor units", jpy, "ja-JP", 1500.4, 1500, "¥1,500"},
+		{"small", usd, "en-US", 999, 99900, "$999.00"},
+	} {
+		got := Amount(tc.f.Format(tc.loc), tc.in)
+		if got.Minor != tc.minor || got.Formatted != tc.want {
+			t.Errorf("%s: Amount(%v) = %+v, want %d %q", tc.name, tc.in, got, tc.minor, tc.want)
+		}
+	}
+}
+
+func TestNegotiate(t *testing.T) {
+	f, err := NewFormatter("EUR", "en-GB", []string{"en-GB", "de-DE", "fr-FR"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for header, want := range map[string]string{
+		"":                             "en-GB",
+		"de-DE":                        "de-DE",
+		"FR-fr":                        "fr-FR",
+		"de-AT":                        "de-DE",
+		"ja-JP, fr;q=0.8":              "fr-FR",
+		"de;q=0.5, fr;q=0.9":           "fr-FR",
+		"*, de;q=0":                    "en-GB",
+		"es-ES":                        "en-GB",
+		"en-US;q=0.8, de-DE;q=invalid": "en-GB",
+	} {
+		if got := f.Negotiate(header).Locale; got != want {
+			t.Errorf("Negotiate(%q) = %s, want %s", header, got, want)
+		}
+	}
+}
+
+func TestNewFormatterRejectsUnknownCodes(t *testing.T) {
+	if _, err := NewFormatter("XYZ", "en-US", nil); err == nil {
+		t.Error("unknown currency accepted")
+	}
+	if _, err := NewFormatter("USD", "en-US", []string{"xx-XX"}); err == nil {
+		t.Error("unknown locale accepted")
+	}
+}
@@ -0,0 +1,49 @@
+package notify
+
+import (
+	"context"
+
+	"github.com/hongminglow/all-in-be/internal/jobs"
+)
+
+// Deliverer sends a single notification synchronously.
+type Deliverer interface {
+	Deliver(ctx context.Context, n Notification) error
+}
+
+// Async is a Notifier that delivers on a background job queue, so callers
+// never wait on a provider. Failed deliveries are retried by the queue.
+type Async struct {
+	target Deliverer
+	queue  *jobs.Queue
+}
+
+// NewAsync delivers notifications through target on queue.
+func NewAsync(target Deliverer, queue *jobs.Queue) *Async {
+	return &Async{target: target, queue: queue}
+}
+
+// priorities places templates on the job queue's lanes: account security and
+// withdrawal mail goes ahead of everything else, and onboarding mail, which
+// is marketing, waits behind it. Other templates run at normal priority.
+var priorities = map[string]jobs.Priority{
+	TemplatePasswordReset:          jobs.PriorityHigh,
+	TemplateLoginAlert:             jobs.PriorityHigh,
+	TemplateDeviceConfirmation:     jobs.PriorityHigh,
+	TemplateWithdrawalConfirmation: jobs.PriorityHigh,
+	TemplateChallengeCode:          jobs.PriorityHigh,
+	TemplateWelcome:                jobs.PriorityLow,
+	TemplateOnboardingNudge:        jobs.PriorityLow,
+}
+
+// Notify enqueues n and returns immediately.
+func (a *Async) Notify(_ context.Context, n Notification) error {
+	return a.queue.Enqueue(jobs.Job{
+		Type:     "notify",
+		Name:     "notify " + string(n.Channel) + "/" + n.Template,
+		Priority: priorities[n.Template],
+		Run: func(ctx context.Context, _ int) error {
+			return a.target.Deliver(ctx, n)
+		},
+	})
+}
@@ -0,0 +1,89 @@
+// Package notify renders templated messages and delivers them by email or SMS.
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// Channel selects how a notification is delivered.
+type Channel string
+
+const (
+	ChannelEmail Channel = "email"
+	ChannelSMS   Channel = "sms"
+)
+
+// Template names shipped with the service.
+const (
+	TemplateWelcome                = "welcome"
+	TemplatePasswordReset          = "password_reset"
+	TemplateWithdrawalConfirmation = "withdrawal_confirmation"
+	TemplateLoginAlert             = "login_alert"
+	TemplateDeviceConfirmation     = "device_confirmation"
+	TemplateOnboardingNudge        = "onboarding_nudge"
+	TemplateChallengeCode          = "challenge_code"
+	TemplateOperatorReport         = "operator_report"
+	TemplateTournamentPrize        = "tournament_prize"
+)
+
+// ErrNoProvider is returned when a notification targets a channel without a configured sender.
+var ErrNoProvider = errors.New("no provider configured for channel")
+
+// EmailSender delivers a rendered email.
+type EmailSender interface {
+	SendEmail(ctx context.Context, to, subject, body string) error
+}
+
+// SMSSender delivers a rendered text message.
+type SMSSender interface {
+	SendSMS(ctx context.Context, to, body string) error
+}
+
+// Notification is a request to render Template with Data and deliver it to To.
+type Notification struct {
+	Channel  Channel
+	To       string
+	Template string
+	Data     any
+}
+
+// Notifier queues notifications for delivery.
+type Notifier interface {
+	Notify(ctx context.Context, n Notification) error
+}
+
+// Service renders notifications and hands them to the configured providers.
+type Service struct {
+	templates *Templates
+	email     EmailSender
+	sms       SMSSender
+}
+
+// NewService builds a service. Either sender may be nil to disable that channel.
+func NewService(templates *Templates, email EmailSender, sms SMSSender) *Service {
+	return &Service{templates: templates, email: email, sms: sms}
+}
+
+// Deliver renders and sends n synchronously.
+func (s *Service) Deliver(ctx context.Context, n Notification) error {
+	msg, err := s.templates.Render(n.Channel, n.Template, n.Data)
+	if err != nil {
+		return err
+	}
+	switch n.Channel {
+	case ChannelEmail:
+		if s.email == nil {
+			return fmt.Errorf("%w: %s", ErrNoProvider, n.Channel)
+		}
+		return s.email.SendEmail(ctx, n.To, msg.Subject, msg.Body)
+	case ChannelSMS:
+		if s.sms == nil {
+			return fmt.Errorf("%w: %s", ErrNoProvider, n.Channel)
+		}
+		return s.sms.SendSMS(ctx, n.To, msg.Body)
+	default:
+		return fmt.Errorf("unknown channel %q", n.Channel)
+	}
+}
@@ -0,0 +1,41 @@
+package notify
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTemplatesRender(t *testing.T) {
+	templates, err := LoadTemplates()
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+
+	msg, err := templates.Render(ChannelEmail, TemplateWithdrawalConfirmation, map[string]any{
+		"Username": "alice", "Amount": 12.5, "Reference": "WD-1",
+	})
+	if err != nil {
+		t.Fatalf("render: %v", err)
+	}
+	if msg.Subject != "Withdrawal of 12.50 confirmed" {
+		t.Fatalf("unexpected subject %q", msg.Subject)
+	}
+	if !strings.Contains(msg.Body, "Reference: WD-1") {
+		t.Fatalf("body missing reference: %q", msg.Body)
+	}
+
+	sms, err := templates.Render(ChannelSMS, TemplateWelcome, map[string]any{"Username": "bob"})
+	if err != nil {
+		t.Fatalf("render sms: %v", err)
+	}
+	if sms.Subject != "" || !strings.Contains(sms.Body, "bob") {
+		t.Fatalf("unexpected sms %+v", sms)
+	}
+
+	if _, err := templates.Render(ChannelEmail, TemplateWelcome, map[string]any{}); err == nil {
+		t.Fatal("expected missing field to fail")
+	}
+	if _, err := templates.Render(ChannelEmail, "nope", nil); err == nil {
+		t.Fatal("expected unknown template to fail")
+	}
+}
@@ -0,0 +1,120 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/smtp"
+	"strings"
+	"time"
+
+	"github.com/hongminglow/all-in-be/internal/tracing"
+)
+
+// LogSender writes messages to the log instead of delivering them. It is the
+// development default for both channels.
+type LogSender struct{}
+
+func (LogSender) SendEmail(_ context.Context, to, subject, body string) error {
+	log.Printf("notify email to=%s subject=%q\n%s", to, subject, body)
+	return nil
+}
+
+func (LogSender) SendSMS(_ context.Context, to, body string) error {
+	log.Printf("notify sms to=%s body=%q", to, body)
+	return nil
+}
+
+// SMTPSender delivers email through an SMTP relay using PLAIN auth when credentials are set.
+type SMTPSender struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+}
+
+func (s SMTPSender) SendEmail(_ context.Context, to, subject, body string) error {
+	var auth smtp.Auth
+	if s.Username != "" {
+		auth = smtp.PlainAuth("", s.Username, s.Password, s.Host)
+	}
+	msg := strings.Join([]string{
+		"From: " + s.From,
+		"To: " + to,
+		"Subject: " + subject,
+		"MIME-Version: 1.0",
+		"Content-Type: text/plain; charset=UTF-8",
+		"",
+		body,
+	}, "\r\n")
+	if err := smtp.SendMail(net.JoinHostPort(s.Host, s.Port), auth, s.From, []string{to}, []byte(msg)); err != nil {
+		return fmt.Errorf("smtp send: %w", err)
+	}
+	return nil
+}
+
+// SendGridSender delivers email through the SendGrid v3 mail API.
+type SendGridSender struct {
+	APIKey string
+	From   string
+	// Endpoint defaults to the public SendGrid API.
+	Endpoint string
+	Client   *http.Client
+}
+
+func (s SendGridSender) SendEmail(ctx context.Context, to, subject, body string) error {
+	endpoint := s.Endpoint
+	if endpoint == "" {
+		endpoint = "https://api.sendgrid.com/v3/mail/send"
+	}
+	client := s.Client
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second, Transport: tracing.Transport(nil)}
+	}
+	payload, err := json.Marshal(map[string]any{
+		"personalizations": []map[string]any{{"to": []map[string]string{{"email": to}}}},
+		"from":             map[string]string{"email": s.From},
+		"subject":          subject,
+		"content":          []map[string]string{{"type": "text/plain", "value": body}},
+	})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+s.APIKey)
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("sendgrid send: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("sendgrid send: status %d: %s", resp.StatusCode, strings.TrimSpace(string(detail)))
+	}
+	return nil
+}
+
+// TwilioStub stands in for a Twilio SMS integration. It validates that the
+// account is configured and logs the message without calling the API.
+type TwilioStub struct {
+	AccountSID string
+	From       string
+}
+
+func (t TwilioStub) SendSMS(_ context.Context, to, body string) error {
+	if t.AccountSID == "" || t.From == "" {
+		return fmt.Errorf("twilio: account SID and from number are required")
+	}
+	log.Printf("twilio stub: account=%s from=%s to=%s body=%q", t.AccountSID, t.From, to, body)
+	return nil
+}
@@ -0,0 +1,76 @@
+package notify
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+//go:embed templates/*.tmpl
+var templateFS embed.FS
+
+// Message is a rendered notification.
+type Message struct {
+	Subject string
+	Body    string
+}
+
+// Templates holds the parsed message templates. Each file in templates/ is
+// named <name>.<channel>.tmpl; email templates define "subject" and "body",
+// SMS templates define only "body".
+type Templates struct {
+	byKey map[string]*template.Template
+}
+
+// LoadTemplates parses the embedded templates.
+func LoadTemplates() (*Templates, error) {
+	files, err := templateFS.ReadDir("templates")
+	if err != nil {
+		return nil, err
+	}
+	t := &Templates{byKey: make(map[string]*template.Template)}
+	for _, f := range files {
+		key := strings.TrimSuffix(f.Name(), ".tmpl")
+		parsed, err := template.New(key).Option("missingkey=error").ParseFS(templateFS, "templates/"+f.Name())
+		if err != nil {
+			return nil, fmt.Errorf("parse template %s: %w", f.Name(), err)
+		}
+		if parsed.Lookup("body") == nil {
+			return nil, fmt.Errorf("template %s does not define a body", f.Name())
+		}
+		t.byKey[key] = parsed
+	}
+	return t, nil
+}
+
+// Render executes the named template for channel with data.
+func (t *Templates) Render(channel Channel, name string, data any) (Message, error) {
+	tmpl, ok := t.byKey[name+"."+string(channel)]
+	if !ok {
+		return Message{}, fmt.Errorf("no %s template named %q", channel, name)
+	}
+	var msg Message
+	if tmpl.Lookup("subject") != nil {
+		subject, err := execute(tmpl, "subject", data)
+		if err != nil {
+			return Message{}, err
+		}
+		msg.Subject = subject
+	}
+	body, err := execute(tmpl, "body", data)
+	if err != nil {
+		return Message{}, err
+	}
+	msg.Body = body
+	return msg, nil
+}
+
+func execute(tmpl *template.Template, block string, data any) (string, error) {
+	var buf bytes.Buffer
+	if err := tmpl.ExecuteTemplate(&buf, block, data); err != nil {
+		return "", fmt.Errorf("render %s/%s: %w", tmpl.Name(), block, err)
+	}
+	return strings.TrimSpace(buf.String()), nil
+}
@@ -0,0 +1,501 @@
+// Package oidc lets companion apps sign players in with their ALL-IN
+// accounts. It is an OpenID Connect provider for the authorization code flow
+// with PKCE: /authorize issues a single-use code to a registered redirect URI,
+// /token trades it for an RS256 ID token and access token, and /userinfo
+// answers for the access token. Clients are registered by staff and trusted,
+// so there is no consent screen.
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/hongminglow/all-in-be/internal/clock"
+	"github.com/hongminglow/all-in-be/internal/config"
+	"github.com/hongminglow/all-in-be/internal/models"
+	"github.com/hongminglow/all-in-be/internal/storage"
+)
+
+// CodeTTL is how long an authorization code may wait to be redeemed.
+const CodeTTL = 2 * time.Minute
+
+// Scopes the provider understands; openid is required on every request.
+const (
+	ScopeOpenID  = "openid"
+	ScopeProfile = "profile"
+	ScopeEmail   = "email"
+)
+
+var supportedScopes = []string{ScopeOpenID, ScopeProfile, ScopeEmail}
+
+// accessTokenType is the JWT typ of access tokens (RFC 9068), which keeps an
+// ID token from being presented as one.
+const accessTokenType = "at+jwt"
+
+var (
+	// ErrUnknownClient means client_id names no registered client.
+	ErrUnknownClient = errors.New("unknown client")
+	// ErrRedirectURI means redirect_uri is not registered for the client.
+	// Neither error may be sent back to the redirect URI.
+	ErrRedirectURI = errors.New("redirect_uri is not registered for this client")
+)
+
+// Error is an OAuth 2.0 error response.
+type Error struct {
+	Code        string `json:"error"`
+	Description string `json:"error_description,omitempty"`
+	// Status is the HTTP status for /token and /userinfo responses.
+	Status int `json:"-"`
+}
+
+func (e *Error) Error() string {
+	return e.Code + ": " + e.Description
+}
+
+func oauthError(status int, code, description string) *Error {
+	return &Error{Code: code, Description: description, Status: status}
+}
+
+// Store is the data the provider reads and the codes it keeps.
+type Store interface {
+	storage.UserStore
+	storage.OAuthStore
+}
+
+// Provider issues and verifies the provider's codes and tokens.
+type Provider struct {
+	store    Store
+	key      *rsa.PrivateKey
+	keyID    string
+	issuer   string
+	loginURL string
+	ttl      time.Duration
+	clock    clock.Clock
+}
+
+// NewProvider loads the signing key named by cfg and constructs the provider.
+func NewProvider(store Store, cfg config.OIDCConfig, clk clock.Clock) (*Provider, error) {
+	key, err := LoadKey(cfg.SigningKeyPath)
+	if err != nil {
+		return nil, err
+	}
+	return &Provider{
+		store:    store,
+		key:      key,
+		keyID:    thumbprint(&key.PublicKey),
+		issuer:   cfg.Issuer,
+		loginURL: cfg.LoginURL,
+		ttl:      cfg.TokenTTL,
+		clock:    clk,
+	}, nil
+}
+
+// LoadKey reads a PEM RSA private key in PKCS #1 or PKCS #8 form.
+func LoadKey(path string) (*rsa.PrivateKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read oidc signing key: %w", err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("oidc signing key %s is not PEM encoded", path)
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("parse oidc signing key: %w", err)
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("oidc signing key %s is not an RSA key", path)
+	}
+	return key, nil
+}
+
+// Discovery returns the OpenID Provider Metadata document.
+func (p *Provider) Discovery() map[string]any {
+	return map[string]any{
+		"issuer":                                p.issuer,
+		"authorization_endpoint":                p.issuer + "/authorize",
+		"token_endpoint":                        p.issuer + "/token",
+		"userinfo_endpoint":                     p.issuer + "/userinfo",
+		"jwks_uri":                              p.issuer + "/.well-known/jwks.json",
+		"scopes_supported":                      supportedScopes,
+		"response_types_supported":              []string{"code"},
+		"grant_types_supported":                 []string{"authorization_code"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{jwt.SigningMethodRS256.Alg()},
+		"code_challenge_methods_supported":      []string{"S256"},
+		"token_endpoint_auth_methods_supported": []string{"none", "client_secret_basic", "client_secret_post"},
+		"claims_supported":                      []string{"sub", "iss", "aud", "exp", "iat", "nonce", "preferred_username", "email"},
+	}
+}
+
+// JWKS returns the JSON Web Key Set clients verify tokens with.
+func (p *Provider) JWKS() map[string]any {
+	pub := &p.key.PublicKey
+	return map[string]any{"keys": []map[string]string{{
+		"kty": "RSA",
+		"use": "sig",
+		"alg": jwt.SigningMethodRS256.Alg(),
+		"kid": p.keyID,
+		"n":   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+		"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+	}}}
+}
+
+// AuthorizeRequest is the query of an /authorize request.
+type AuthorizeRequest struct {
+	ClientID            string
+	RedirectURI         string
+	ResponseType        string
+	Scope               string
+	State               string
+	Nonce               string
+	CodeChallenge       string
+	CodeChallengeMethod string
+	Prompt              string
+}
+
+// ParseAuthorizeRequest reads an /authorize query.
+func ParseAuthorizeRequest(q url.Values) AuthorizeRequest {
+	return AuthorizeRequest{
+		ClientID:            q.Get("client_id"),
+		RedirectURI:         q.Get("redirect_uri"),
+		ResponseType:        q.Get("response_type"),
+		Scope:               q.Get("scope"),
+		State:               q.Get("state"),
+		Nonce:               q.Get("nonce"),
+		CodeChallenge:       q.Get("code_challenge"),
+		CodeChallengeMethod: q.Get("code_challenge_method"),
+		Prompt:              q.Get("prompt"),
+	}
+}
+
+// Validate checks an authorization request. ErrUnknownClient and
+// ErrRedirectURI must be shown to the user; an *Error goes back to the client
+// through ErrorRedirect.
+func (p *Provider) Validate(ctx context.Context, req AuthorizeRequest) error {
+	client, err := p.store.FindOAuthClient(ctx, req.ClientID)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return ErrUnknownClient
+		}
+		return fmt.Errorf("find oauth client: %w", err)
+	}
+	if !slices.Contains(client.RedirectURIs, req.RedirectURI) {
+		return ErrRedirectURI
+	}
+	if req.ResponseType != "code" {
+		return oauthError(http.StatusBadRequest, "unsupported_response_type", "only the code response type is supported")
+	}
+	scopes := strings.Fields(req.Scope)
+	if !slices.Contains(scopes, ScopeOpenID) {
+		return oauthError(http.StatusBadRequest, "invalid_scope", "scope must include openid")
+	}
+	for _, scope := range scopes {
+		if !slices.Contains(supportedScopes, scope) {
+			return oauthError(http.StatusBadRequest, "invalid_scope", "unsupported scope "+scope)
+		}
+	}
+	// PKCE protects public clients from code interception and costs
+	// confidential ones nothing, so every client must use it.
+	if req.CodeChallenge == "" || req.CodeChallengeMethod != "S256" {
+		return oauthError(http.StatusBadRequest, "invalid_request", "code_challenge with code_challenge_method S256 is required")
+	}
+	return nil
+}
+
+// LoginRedirect returns the login page URL that sends the user back to
+// authorizeURL once they are signed in, or an *Error when the client asked
+// for prompt=none.
+func (p *Provider) LoginRedirect(req AuthorizeRequest, authorizeURL string) (string, error) {
+	if req.Prompt == "none" {
+		return "", oauthError(http.StatusBadRequest, "login_required", "the user is not signed in")
+	}
+	return withQuery(p.loginURL, url.Values{"return_to": {authorizeURL}}), nil
+}
+
+// Authorize issues a code for a validated request and returns the redirect
+// URI to send the user to.
+func (p *Provider) Authorize(ctx context.Context, user models.User, req AuthorizeRequest) (string, error) {
+	code, hash, err := newSecret()
+	if err != nil {
+		return "", fmt.Errorf("generate authorization code: %w", err)
+	}
+	err = p.store.CreateAuthorizationCode(ctx, models.AuthorizationCode{
+		CodeHash:      hash,
+		ClientID:      req.ClientID,
+		UserID:        user.ID,
+		RedirectURI:   req.RedirectURI,
+		Scope:         strings.Join(strings.Fields(req.Scope), " "),
+		Nonce:         req.Nonce,
+		CodeChallenge: req.CodeChallenge,
+		ExpiresAt:     p.clock.Now().Add(CodeTTL),
+	})
+	if err != nil {
+		return "", fmt.Errorf("create authorization code: %w", err)
+	}
+	return withQuery(req.RedirectURI, url.Values{"code": {code}, "state": {req.State}}), nil
+}
+
+// ErrorRedirect returns the redirect URI carrying e back to the client.
+func ErrorRedirect(req AuthorizeRequest, e *Error) string {
+	return withQuery(req.RedirectURI, url.Values{"error": {e.Code}, "error_description": {e.Description}, "state": {req.State}})
+}
+
+// TokenRequest is the form of a /token request.
+type TokenRequest struct {
+	GrantType    string
+	Code         string
+	RedirectURI  string
+	ClientID     string
+	ClientSecret string
+	CodeVerifier string
+}
+
+// TokenResponse is a successful /token response.
+type TokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int    `json:"expires_in"`
+	IDToken     string `json:"id_token"`
+	Scope       string `json:"scope"`
+}
+
+// Exchange redeems an authorization code. Failures the client caused are
+// returned as *Error.
+func (p *Provider) Exchange(ctx context.Context, req TokenRequest) (TokenResponse, error) {
+	if req.GrantType != "authorization_code" {
+		return TokenResponse{}, oauthError(http.StatusBadRequest, "unsupported_grant_type", "only authorization_code is supported")
+	}
+	if req.Code == "" || req.CodeVerifier == "" {
+		return TokenResponse{}, oauthError(http.StatusBadRequest, "invalid_request", "code and code_verifier are required")
+	}
+	client, err := p.store.FindOAuthClient(ctx, req.ClientID)
+	if errors.Is(err, storage.ErrNotFound) {
+		return TokenResponse{}, oauthError(http.StatusUnauthorized, "invalid_client", "unknown client")
+	}
+	if err != nil {
+		return TokenResponse{}, fmt.Errorf("find oauth client: %w", err)
+	}
+	if client.Confidential && subtle.ConstantTimeCompare([]byte(HashSecret(req.ClientSecret)), []byte(client.SecretHash)) != 1 {
+		return TokenResponse{}, oauthError(http.StatusUnauthorized, "invalid_client", "client authentication failed")
+	}
+
+	// The code is spent even when the checks below fail, so whoever holds a
+	// stolen code gets a single guess at the verifier.
+	now := p.clock.Now()
+	code, err := p.store.ConsumeAuthorizationCode(ctx, HashSecret(req.Code), now)
+	if errors.Is(err, storage.ErrNotFound) {
+		return TokenResponse{}, oauthError(http.StatusBadRequest, "invalid_grant", "the code is invalid, expired or already used")
+	}
+	if err != nil {
+		return TokenResponse{}, fmt.Errorf("consume authorization code: %w", err)
+	}
+	if code.ClientID != client.ID || code.RedirectURI != req.RedirectURI {
+		return TokenResponse{}, oauthError(http.StatusBadRequest, "invalid_grant", "the code was issued to another client or redirect_uri")
+	}
+	challenge := sha256.Sum256([]byte(req.CodeVerifier))
+	if subtle.ConstantTimeCompare([]byte(base64.RawURLEncoding.EncodeToString(challenge[:])), []byte(code.CodeChallenge)) != 1 {
+		return TokenResponse{}, oauthError(http.StatusBadRequest, "invalid_grant", "code_verifier does not match the code_challenge")
+	}
+	user, err := p.store.FindByID(ctx, code.UserID)
+	if errors.Is(err, storage.ErrNotFound) {
+		return TokenResponse{}, oauthError(http.StatusBadRequest, "invalid_grant", "the account no longer exists")
+	}
+	if err != nil {
+		return TokenResponse{}, fmt.Errorf("find user: %w", err)
+	}
+	if user.PasswordResetRequired {
+		return TokenResponse{}, oauthError(http.StatusBadRequest, "invalid_grant", "the account must reset its password")
+	}
+	if user.MergedInto != nil {
+		return TokenResponse{}, oauthError(http.StatusBadRequest, "invalid_grant", "the account was merged into another")
+	}
+
+	claims := p.userClaims(user, code.Scope)
+	claims["aud"] = client.ID
+	claims["iat"] = now.Unix()
+	claims["exp"] = now.Add(p.ttl).Unix()
+	if code.Nonce != "" {
+		claims["nonce"] = code.Nonce
+	}
+	idToken, err := p.sign(claims, "JWT")
+	if err != nil {
+		return TokenResponse{}, err
+	}
+	accessToken, err := p.sign(jwt.MapClaims{
+		"iss":       p.issuer,
+		"sub":       strconv.FormatInt(user.ID, 10),
+		"aud":       p.issuer,
+		"client_id": client.ID,
+		"scope":     code.Scope,
+		"iat":       now.Unix(),
+		"exp":       now.Add(p.ttl).Unix(),
+	}, accessTokenType)
+	if err != nil {
+		return TokenResponse{}, err
+	}
+	return TokenResponse{
+		AccessToken: accessToken,
+		TokenType:   "Bearer",
+		ExpiresIn:   int(p.ttl.Seconds()),
+		IDToken:     idToken,
+		Scope:       code.Scope,
+	}, nil
+}
+
+// UserInfo returns the claims an access token's scope grants. Tokens of
+// accounts whose sessions were revoked or that must reset their password are
+// refused, as the API's own tokens are.
+func (p *Provider) UserInfo(ctx context.Context, accessToken string) (map[string]any, error) {
+	invalid := oauthError(http.StatusUnauthorized, "invalid_token", "the access token is invalid or expired")
+	token, err := jwt.Parse(accessToken, func(token *jwt.Token) (any, error) {
+		if typ, _ := token.Header["typ"].(string); typ != accessTokenType {
+			return nil, errors.New("not an access token")
+		}
+		return &p.key.PublicKey, nil
+	},
+		jwt.WithIssuer(p.issuer),
+		jwt.WithAudience(p.issuer),
+		jwt.WithValidMethods([]string{jwt.SigningMethodRS256.Alg()}),
+		jwt.WithTimeFunc(p.clock.Now),
+		jwt.WithIssuedAt(),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil {
+		return nil, invalid
+	}
+	claims := token.Claims.(jwt.MapClaims)
+	sub, _ := claims.GetSubject()
+	id, err := strconv.ParseInt(sub, 10, 64)
+	if err != nil {
+		return nil, invalid
+	}
+	user, err := p.store.FindByID(ctx, id)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, invalid
+	}
+	if err != nil {
+		return nil, fmt.Errorf("find user: %w", err)
+	}
+	iat, err := claims.GetIssuedAt()
+	if err != nil || iat == nil {
+		return nil, invalid
+	}
+	// Second precision, as in middleware.Authenticate.
+	if user.SessionsRevokedAt != nil && iat.Before(user.SessionsRevokedAt.Truncate(time.Second)) {
+		return nil, invalid
+	}
+	// As in middleware.Authenticate: a merge revokes the sessions too, but
+	// only to the second.
+	if user.PasswordResetRequired || user.MergedInto != nil {
+		return nil, invalid
+	}
+	scope, _ := claims["scope"].(string)
+	info := p.userClaims(user, scope)
+	delete(info, "iss")
+	return info, nil
+}
+
+// userClaims returns the identity claims scope grants.
+func (p *Provider) userClaims(user models.User, scope string) jwt.MapClaims {
+	scopes := strings.Fields(scope)
+	claims := jwt.MapClaims{"iss": p.issuer, "sub": strconv.FormatInt(user.ID, 10)}
+	if slices.Contains(scopes, ScopeProfile) {
+		claims["preferred_username"] = user.Username
+	}
+	if slices.Contains(scopes, ScopeEmail) {
+		claims["email"] = user.Email
+	}
+	return claims
+}
+
+func (p *Provider) sign(claims jwt.MapClaims, typ string) (string, error) {
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = p.keyID
+	token.Header["typ"] = typ
+	signed, err := token.SignedString(p.key)
+	if err != nil {
+		return "", fmt.Errorf("sign token: %w", err)
+	}
+	return signed, nil
+}
+
+// NewClientSecret returns a random client secret and the hash stored in its place.
+func NewClientSecret() (secret, hash string, err error) {
+	secret, hash, err = newSecret()
+	if err != nil {
+		return "", "", fmt.Errorf("generate client secret: %w", err)
+	}
+	return secret, hash, nil
+}
+
+// HashSecret returns the stored form of a client secret or authorization code.
+func HashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// newSecret returns a random URL-safe value and its hash.
+func newSecret() (string, string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", err
+	}
+	secret := base64.RawURLEncoding.EncodeToString(buf)
+	return secret, HashSecret(secret), nil
+}
+
+// thumbprint is the RFC 7638 JWK thumbprint of key, used as its kid.
+func thumbprint(key *rsa.PublicKey) string {
+	jwk, _ := json.Marshal(struct {
+		E   string `json:"e"`
+		Kty string `json:"kty"`
+		N   string `json:"n"`
+	}{
+		E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		Kty: "RSA",
+		N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+	})
+	sum := sha256.Sum256(jwk)
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// withQuery adds the non-empty params to a URL that may already have a query.
+func withQuery(raw string, params url.Values) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return raw
+	}
+	q := u.Query()
+	for key := range params {
+		if value := params.Get(key); value != "" {
+			q.Set(key, value)
+		}
+	}
+	u.RawQuery = q.Encode()
+	return u.String()
+}
@@ -0,0 +1,245 @@
+// Package onboarding runs welcome journeys: ordered steps, such as verifying
+// an email address, setting deposit limits or claiming a welcome bonus, that
+// are configured per tenant in the database rather than hardcoded at
+// registration. A user is emailed each step's template on reaching it, domain
+// events complete steps, and further events re-send the current step's
+// template as a nudge at most once per nudge interval.
+package onboarding
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/hongminglow/all-in-be/internal/clock"
+	"github.com/hongminglow/all-in-be/internal/events"
+	"github.com/hongminglow/all-in-be/internal/models"
+	"github.com/hongminglow/all-in-be/internal/notify"
+	"github.com/hongminglow/all-in-be/internal/storage"
+)
+
+// defaultTenant is the journey every user follows: there is no tenant model
+// yet, so users cannot belong to another one.
+const defaultTenant = ""
+
+// Triggers lists the domain events a step may be completed or nudged by.
+var Triggers = []string{events.TypeUserRegistered, events.TypeUserLoggedIn, events.TypeBalanceChanged}
+
+// Templates lists the emails a step may send.
+var Templates = []string{notify.TemplateWelcome, notify.TemplateOnboardingNudge}
+
+// ErrUnknownStep is returned by Complete for a step not in the user's journey.
+var ErrUnknownStep = errors.New("unknown onboarding step")
+
+var stepKeyPattern = regexp.MustCompile(`^[a-z0-9_]{1,64}$`)
+
+// Store is the storage the service needs.
+type Store interface {
+	storage.UserStore
+	storage.OnboardingStore
+}
+
+// Service moves users through their tenant's journey.
+type Service struct {
+	store    Store
+	notifier notify.Notifier
+	clock    clock.Clock
+	interval time.Duration
+}
+
+// NewService builds a service that nudges a user about a step at most once
+// per nudgeInterval.
+func NewService(store Store, notifier notify.Notifier, clk clock.Clock, nudgeInterval time.Duration) *Service {
+	return &Service{store: store, notifier: notifier, clock: clk, interval: nudgeInterval}
+}
+
+// Subscribe connects the service to the events that drive journeys.
+func (s *Service) Subscribe(bus events.Subscriber) error {
+	for _, typ := range Triggers {
+		if err := bus.Subscribe(typ, s.handle); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Journey returns the tenant's journey, falling back to the default journey.
+func (s *Service) Journey(ctx context.Context, tenant string) (models.OnboardingJourney, error) {
+	journey, err := s.store.FindOnboardingJourney(ctx, tenant)
+	if errors.Is(err, storage.ErrNotFound) && tenant != defaultTenant {
+		journey, err = s.store.FindOnboardingJourney(ctx, defaultTenant)
+	}
+	if errors.Is(err, storage.ErrNotFound) {
+		return models.OnboardingJourney{Tenant: tenant, Steps: []models.OnboardingStep{}}, nil
+	}
+	return journey, err
+}
+
+// Status reports the user's progress through their journey.
+func (s *Service) Status(ctx context.Context, userID int64) (models.OnboardingStatus, error) {
+	journey, progress, err := s.load(ctx, userID)
+	if err != nil {
+		return models.OnboardingStatus{}, err
+	}
+	status := models.OnboardingStatus{Tenant: journey.Tenant, Steps: make([]models.OnboardingStepStatus, 0, len(journey.Steps))}
+	for _, step := range journey.Steps {
+		completedAt := progress[step.Key].CompletedAt
+		if completedAt == nil && status.Current == "" {
+			status.Current = step.Key
+		}
+		status.Steps = append(status.Steps, models.OnboardingStepStatus{Key: step.Key, Title: step.Title, CompletedAt: completedAt})
+	}
+	return status, nil
+}
+
+// Complete marks a step complete for features that finish it directly rather
+// than through a domain event. Completing the current step emails the next one.
+func (s *Service) Complete(ctx context.Context, userID int64, stepKey string) error {
+	journey, progress, err := s.load(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !slices.ContainsFunc(journey.Steps, func(step models.OnboardingStep) bool { return step.Key == stepKey }) {
+		return ErrUnknownStep
+	}
+	current, ok := currentStep(journey, progress)
+	if err := s.store.CompleteOnboardingStep(ctx, userID, stepKey, s.clock.Now()); err != nil {
+		if errors.Is(err, storage.ErrAlreadyExists) {
+			return nil
+		}
+		return err
+	}
+	if !ok || current.Key != stepKey {
+		return nil
+	}
+	return s.enterNext(ctx, userID, journey, progress, stepKey)
+}
+
+func (s *Service) handle(ctx context.Context, e events.Event) error {
+	var subject struct {
+		UserID int64 `json:"user_id"`
+	}
+	if err := e.Decode(&subject); err != nil {
+		return err
+	}
+	if subject.UserID == 0 {
+		return nil
+	}
+	journey, progress, err := s.load(ctx, subject.UserID)
+	if err != nil {
+		return err
+	}
+	current, ok := currentStep(journey, progress)
+	if !ok {
+		return nil
+	}
+	switch {
+	case e.Type == events.TypeUserRegistered:
+		return s.nudge(ctx, subject.UserID, current, time.Time{})
+	case current.CompleteOn == e.Type:
+		err := s.store.CompleteOnboardingStep(ctx, subject.UserID, current.Key, s.clock.Now())
+		if errors.Is(err, storage.ErrAlreadyExists) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		return s.enterNext(ctx, subject.UserID, journey, progress, current.Key)
+	case current.NudgeOn == e.Type:
+		return s.nudge(ctx, subject.UserID, current, s.clock.Now().Add(-s.interval))
+	}
+	return nil
+}
+
+// enterNext emails the first incomplete step after the one just completed.
+func (s *Service) enterNext(ctx context.Context, userID int64, journey models.OnboardingJourney, progress map[string]models.OnboardingProgress, completed string) error {
+	progress[completed] = models.OnboardingProgress{CompletedAt: new(time.Time)}
+	next, ok := currentStep(journey, progress)
+	if !ok {
+		return nil
+	}
+	return s.nudge(ctx, userID, next, time.Time{})
+}
+
+// nudge emails the step's template unless the user was nudged about it after since.
+func (s *Service) nudge(ctx context.Context, userID int64, step models.OnboardingStep, since time.Time) error {
+	if step.Template == "" {
+		return nil
+	}
+	err := s.store.ClaimOnboardingNudge(ctx, userID, step.Key, s.clock.Now(), since)
+	if errors.Is(err, storage.ErrAlreadyExists) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	user, err := s.store.FindByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	return s.notifier.Notify(ctx, notify.Notification{
+		Channel:  notify.ChannelEmail,
+		To:       user.Email,
+		Template: step.Template,
+		Data:     map[string]any{"Username": user.Username, "Step": step.Title},
+	})
+}
+
+func (s *Service) load(ctx context.Context, userID int64) (models.OnboardingJourney, map[string]models.OnboardingProgress, error) {
+	journey, err := s.Journey(ctx, defaultTenant)
+	if err != nil {
+		return models.OnboardingJourney{}, nil, err
+	}
+	rows, err := s.store.ListOnboardingProgress(ctx, userID)
+	if err != nil {
+		return models.OnboardingJourney{}, nil, err
+	}
+	progress := make(map[string]models.OnboardingProgress, len(rows))
+	for _, p := range rows {
+		progress[p.StepKey] = p
+	}
+	return journey, progress, nil
+}
+
+// currentStep returns the first incomplete step.
+func currentStep(journey models.OnboardingJourney, progress map[string]models.OnboardingProgress) (models.OnboardingStep, bool) {
+	for _, step := range journey.Steps {
+		if progress[step.Key].CompletedAt == nil {
+			return step, true
+		}
+	}
+	return models.OnboardingStep{}, false
+}
+
+// Validate checks a journey an administrator submitted.
+func Validate(journey models.OnboardingJourney) error {
+	if len(journey.Steps) == 0 {
+		return errors.New("a journey needs at least one step")
+	}
+	seen := make(map[string]bool, len(journey.Steps))
+	for i, step := range journey.Steps {
+		if !stepKeyPattern.MatchString(step.Key) {
+			return fmt.Errorf("steps[%d]: key must be 1-64 lowercase letters, digits or underscores", i)
+		}
+		if seen[step.Key] {
+			return fmt.Errorf("steps[%d]: duplicate key %q", i, step.Key)
+		}
+		seen[step.Key] = true
+		if strings.TrimSpace(step.Title) == "" {
+			return fmt.Errorf("steps[%d]: title is required", i)
+		}
+		if step.Template != "" && !slices.Contains(Templates, step.Template) {
+			return fmt.Errorf("steps[%d]: template must be one of: %s", i, strings.Join(Templates, ", "))
+		}
+		for _, trigger := range []string{step.CompleteOn, step.NudgeOn} {
+			if trigger != "" && !slices.Contains(Triggers, trigger) {
+				return fmt.Errorf("steps[%d]: events must be one of: %s", i, strings.Join(Triggers, ", "))
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+package onboarding
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hongminglow/all-in-be/internal/events"
+	"github.com/hongminglow/all-in-be/internal/models"
+	"github.com/hongminglow/all-in-be/internal/notify"
+	"github.com/hongminglow/all-in-be/internal/storage/storagetest"
+)
+
+type recordingNotifier struct {
+	mu   sync.Mutex
+	sent []string
+}
+
+func (n *recordingNotifier) Notify(_ context.Context, msg notify.Notification) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.sent = append(n.sent, msg.Template+":"+msg.Data.(map[string]any)["Step"].(string))
+	return nil
+}
+
+func (n *recordingNotifier) take() []string {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	sent := n.sent
+	n.sent = nil
+	return sent
+}
+
+func TestJourneyAdvancesThroughSteps(t *testing.T) {
+	ctx := context.Background()
+	clk := storagetest.NewFakeClock(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	store := storagetest.NewMemoryStore(clk)
+	if _, err := store.SaveOnboardingJourney(ctx, models.OnboardingJourney{Steps: []models.OnboardingStep{
+		{Key: "verify_email", Title: "Verify your email", Template: notify.TemplateWelcome},
+		{Key: "first_deposit", Title: "Make a deposit", Template: notify.TemplateOnboardingNudge, CompleteOn: events.TypeBalanceChanged, NudgeOn: events.TypeUserLoggedIn},
+		{Key: "claim_welcome_bonus", Title: "Claim your bonus", Template: notify.TemplateOnboardingNudge},
+	}}); err != nil {
+		t.Fatalf("save journey: %v", err)
+	}
+	user, err := store.CreateUser(ctx, models.User{Username: "ana", Email: "ana@example.com", Role: models.NormalUser})
+	if err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	notifier := &recordingNotifier{}
+	journeys := NewService(store, notifier, clk, 24*time.Hour)
+	publish := func(typ string) {
+		t.Helper()
+		data, _ := json.Marshal(map[string]any{"user_id": user.ID})
+		if err := journeys.handle(ctx, events.Event{Type: typ, OccurredAt: clk.Now(), Data: data}); err != nil {
+			t.Fatalf("handle %s: %v", typ, err)
+		}
+	}
+	expect := func(step string, want ...string) {
+		t.Helper()
+		if got := notifier.take(); len(got) != len(want) || (len(want) == 1 && got[0] != want[0]) {
+			t.Fatalf("sent %v, want %v", got, want)
+		}
+		if status, _ := journeys.Status(ctx, user.ID); status.Current != step {
+			t.Fatalf("current step = %q, want %q", status.Current, step)
+		}
+	}
+
+	publish(events.TypeUserRegistered)
+	publish(events.TypeUserRegistered)
+	expect("verify_email", "welcome:Verify your email")
+	publish(events.TypeBalanceChanged)
+	expect("verify_email")
+
+	if err := journeys.Complete(ctx, user.ID, "verify_email"); err != nil {
+		t.Fatalf("complete: %v", err)
+	}
+	expect("first_deposit", "onboarding_nudge:Make a deposit")
+	publish(events.TypeUserLoggedIn)
+	expect("first_deposit")
+	clk.Advance(25 * time.Hour)
+	publish(events.TypeUserLoggedIn)
+	expect("first_deposit", "onboarding_nudge:Make a deposit")
+
+	publish(events.TypeBalanceChanged)
+	expect("claim_welcome_bonus", "onboarding_nudge:Claim your bonus")
+	if err := journeys.Complete(ctx, user.ID, "claim_welcome_bonus"); err != nil {
+		t.Fatalf("complete: %v", err)
+	}
+	expect("")
+	if err := journeys.Complete(ctx, user.ID, "kyc"); err != ErrUnknownStep {
+		t.Fatalf("complete unknown step: err = %v, want ErrUnknownStep", err)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	for name, tc := range map[string]struct {
+		steps []models.OnboardingStep
+		ok    bool
+	}{
+		"valid":            {steps: []models.OnboardingStep{{Key: "set_limits", Title: "Set limits", CompleteOn: events.TypeUserLoggedIn}}, ok: true},
+		"empty":            {steps: nil},
+		"bad key":          {steps: []models.OnboardingStep{{Key: "Set Limits", Title: "Set limits"}}},
+		"duplicate key":    {steps: []models.OnboardingStep{{Key: "a", Title: "A"}, {Key: "a", Title: "B"}}},
+		"unknown template": {steps: []models.OnboardingStep{{Key: "a", Title: "A", Template: "password_reset"}}},
+		"unknown event":    {steps: []models.OnboardingStep{{Key: "a", Title: "A", NudgeOn: "bonus.claimed"}}},
+	} {
+		if err := Validate(models.OnboardingJourney{Steps: tc.steps}); (err == nil) != tc.ok {
+			t.Errorf("%s: Validate = %v, want ok=%v", name, err, tc.ok)
+		}
+	}
+}
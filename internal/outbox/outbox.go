@@ -0,0 +1,179 @@
+// Package outbox makes domain events as durable as the changes they describe.
+// Services add an event in the same unit of work as the change, and once it
+// commits a relay publishes the event to the bus and marks it sent. If the
+// process stops in between, the next relay pass, on this instance or another,
+// publishes it instead, so every committed change is announced at least once.
+// A crash after publishing but before marking the event sent announces it
+// again; consumers must tolerate the occasional duplicate.
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/hongminglow/all-in-be/internal/clock"
+	"github.com/hongminglow/all-in-be/internal/config"
+	"github.com/hongminglow/all-in-be/internal/events"
+	"github.com/hongminglow/all-in-be/internal/models"
+	"github.com/hongminglow/all-in-be/internal/storage"
+)
+
+// batchSize bounds the events published per transaction and the sent events
+// deleted per pass.
+const batchSize = 100
+
+// defaultInterval stands in for an unset EventsConfig.OutboxInterval.
+const defaultInterval = 5 * time.Second
+
+// Add stages an event in tx. It is published once tx commits and dropped
+// with it if tx rolls back.
+func Add(ctx context.Context, tx storage.OutboxStore, eventType string, data any) error {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("encode %s event: %w", eventType, err)
+	}
+	if _, err := tx.AddOutboxEvent(ctx, models.OutboxEvent{Type: eventType, Data: raw}); err != nil {
+		return fmt.Errorf("stage %s event: %w", eventType, err)
+	}
+	return nil
+}
+
+// Relay publishes committed outbox events to the bus.
+type Relay struct {
+	store storage.Store
+	bus   events.Publisher
+	clock clock.Clock
+	cfg   config.EventsConfig
+
+	wake chan struct{}
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewRelay builds a relay; call Start to run it.
+func NewRelay(store storage.Store, bus events.Publisher, clk clock.Clock, cfg config.EventsConfig) *Relay {
+	return &Relay{store: store, bus: bus, clock: clk, cfg: cfg, wake: make(chan struct{}, 1)}
+}
+
+// Wake makes a started relay publish now rather than at its next tick.
+// Services call it after committing a unit of work that added events. It
+// does nothing on a nil Relay, so services can run without one in tests.
+func (r *Relay) Wake() {
+	if r == nil {
+		return
+	}
+	select {
+	case r.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Flush publishes pending events until none are left and returns how many it
+// published. Events published before a failure stay marked sent.
+func (r *Relay) Flush(ctx context.Context) (int, error) {
+	total := 0
+	for {
+		n, err := r.publishBatch(ctx)
+		total += n
+		if err != nil || n < batchSize {
+			return total, err
+		}
+	}
+}
+
+// publishBatch publishes one batch of events, oldest first, and marks them
+// sent in the transaction that locked them. It stops at the first event the
+// bus refuses so later events are not published ahead of it.
+func (r *Relay) publishBatch(ctx context.Context) (int, error) {
+	var sent []int64
+	var publishErr error
+	err := r.store.WithTx(ctx, func(tx storage.Repositories) error {
+		sent, publishErr = nil, nil
+		pending, err := tx.PendingOutboxEvents(ctx, batchSize)
+		if err != nil {
+			return err
+		}
+		for _, e := range pending {
+			if err := r.bus.Publish(ctx, e.Type, e.Data); err != nil {
+				publishErr = fmt.Errorf("publish outbox event %d: %w", e.ID, err)
+				break
+			}
+			sent = append(sent, e.ID)
+		}
+		if len(sent) == 0 {
+			return nil
+		}
+		return tx.MarkOutboxEventsSent(ctx, sent, r.clock.Now())
+	})
+	if err != nil {
+		return 0, err
+	}
+	return len(sent), publishErr
+}
+
+// prune deletes events sent longer ago than the retention period.
+func (r *Relay) prune(ctx context.Context) error {
+	before := r.clock.Now().Add(-r.cfg.OutboxRetention)
+	for {
+		n, err := r.store.DeleteSentOutboxEvents(ctx, before, batchSize)
+		if err != nil || n < batchSize {
+			return err
+		}
+	}
+}
+
+// Start publishes whatever a previous run left behind, then relays on every
+// Wake and every cfg.OutboxInterval until Close, pruning sent events on the
+// interval.
+func (r *Relay) Start() {
+	if r.stop != nil {
+		return
+	}
+	r.stop, r.done = make(chan struct{}), make(chan struct{})
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-r.stop
+		cancel()
+	}()
+	r.Wake()
+	go func() {
+		defer close(r.done)
+		interval := r.cfg.OutboxInterval
+		if interval <= 0 {
+			interval = defaultInterval
+		}
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := r.prune(ctx); err != nil && ctx.Err() == nil {
+					log.Printf("outbox: prune sent events: %v", err)
+				}
+			case <-r.wake:
+			}
+			if _, err := r.Flush(ctx); err != nil && ctx.Err() == nil {
+				log.Printf("outbox: %v", err)
+			}
+		}
+	}()
+}
+
+// Close stops the relay and publishes the events committed meanwhile, so a
+// graceful shutdown leaves nothing behind for the next start.
+func (r *Relay) Close() {
+	if r.stop == nil {
+		return
+	}
+	close(r.stop)
+	<-r.done
+	r.stop = nil
+	if _, err := r.Flush(context.Background()); err != nil {
+		log.Printf("outbox: final flush: %v", err)
+	}
+}
@@ -0,0 +1,73 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/hongminglow/all-in-be/internal/config"
+	"github.com/hongminglow/all-in-be/internal/events"
+	"github.com/hongminglow/all-in-be/internal/storage"
+	"github.com/hongminglow/all-in-be/internal/storage/storagetest"
+)
+
+// flakyBus refuses events while down and records the rest.
+type flakyBus struct {
+	down      bool
+	published []string
+}
+
+func (b *flakyBus) Publish(_ context.Context, eventType string, _ any) error {
+	if b.down {
+		return errors.New("bus unavailable")
+	}
+	b.published = append(b.published, eventType)
+	return nil
+}
+
+func TestRelayPublishesCommittedEventsOnce(t *testing.T) {
+	ctx := context.Background()
+	clk := storagetest.NewFakeClock(time.Date(2026, 3, 11, 9, 0, 0, 0, time.UTC))
+	store := storagetest.NewMemoryStore(clk)
+	bus := &flakyBus{down: true}
+	relay := NewRelay(store, bus, clk, config.EventsConfig{OutboxRetention: time.Hour})
+
+	stage := func(eventType string, fail error) error {
+		return store.WithTx(ctx, func(tx storage.Repositories) error {
+			if err := Add(ctx, tx, eventType, events.BalanceChanged{UserID: 1, Delta: 5}); err != nil {
+				return err
+			}
+			return fail
+		})
+	}
+	if err := stage(events.TypeUserRegistered, nil); err != nil {
+		t.Fatal(err)
+	}
+	rolledBack := errors.New("rolled back")
+	if err := stage("never.happened", rolledBack); !errors.Is(err, rolledBack) {
+		t.Fatal(err)
+	}
+	if err := stage(events.TypeBalanceChanged, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if n, err := relay.Flush(ctx); n != 0 || err == nil {
+		t.Fatalf("flush with the bus down = %d, %v; want an error and nothing sent", n, err)
+	}
+	bus.down = false
+	if n, err := relay.Flush(ctx); n != 2 || err != nil {
+		t.Fatalf("flush = %d, %v; want both committed events", n, err)
+	}
+	if n, _ := relay.Flush(ctx); n != 0 || len(bus.published) != 2 || bus.published[0] != events.TypeUserRegistered {
+		t.Fatalf("published %v, want each committed event once, in order", bus.published)
+	}
+
+	clk.Advance(2 * time.Hour)
+	if err := relay.prune(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if deleted, _ := store.DeleteSentOutboxEvents(ctx, clk.Now(), batchSize); deleted != 0 {
+		t.Fatalf("%d sent events outlived the retention period", deleted)
+	}
+}
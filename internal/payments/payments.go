@@ -0,0 +1,226 @@
+// Package payments moves money between players and payment service providers
+// (PSPs). Each PSP is a Provider driver; the package owns everything the
+// wallet sees, so adding a PSP never touches balance logic.
+//
+// Deposits credit the wallet only when the provider's signed callback reports
+// them settled. Withdrawals hold the amount with a ledger debit when they are
+// requested, wait for an admin's approval above a threshold, and are then paid
+// out; the hold is returned if the request is rejected or the payout fails. Callbacks reach drivers through the integrations pipeline, so they
+// are stored, replayable and applied at most once.
+package payments
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/hongminglow/all-in-be/internal/clock"
+	"github.com/hongminglow/all-in-be/internal/integrations"
+	"github.com/hongminglow/all-in-be/internal/limits"
+	"github.com/hongminglow/all-in-be/internal/models"
+	"github.com/hongminglow/all-in-be/internal/storage"
+	"github.com/hongminglow/all-in-be/internal/wallet"
+)
+
+var (
+	// ErrUnknownProvider is returned for a provider with no registered driver.
+	ErrUnknownProvider = errors.New("unknown payment provider")
+	// ErrInvalidAmount is returned for amounts that are not a positive number of cents.
+	ErrInvalidAmount = errors.New("amount must be positive")
+	// ErrDeclined is wrapped by drivers when the provider definitely refused a
+	// deposit or payout. Any other error leaves the outcome unknown.
+	ErrDeclined = errors.New("payment declined")
+	// ErrNotAllowed is returned when a withdrawal is not in a status the
+	// review applies to.
+	ErrNotAllowed = errors.New("withdrawal cannot make that transition")
+	// ErrOwnWithdrawal is returned when an admin reviews their own withdrawal.
+	ErrOwnWithdrawal = errors.New("withdrawals cannot be reviewed by their requester")
+	// ErrPayoutUnconfirmed is returned, wrapped with the cause, when an
+	// approved payout may or may not have reached the provider. The amount
+	// stays held until the provider's callback settles it.
+	ErrPayoutUnconfirmed = errors.New("payout not confirmed by the provider")
+)
+
+// Callback event types, as decoded by drivers.
+const (
+	EventDepositSucceeded = "deposit.succeeded"
+	EventDepositFailed    = "deposit.failed"
+	EventPayoutSucceeded  = "payout.succeeded"
+	EventPayoutFailed     = "payout.failed"
+)
+
+// Request asks a provider to move money for a player.
+type Request struct {
+	// Reference is our ID for the payment, echoed back in the provider's
+	// callbacks.
+	Reference string
+	UserID    int64
+	Amount    float64
+}
+
+// Intent is a provider's answer to a Request.
+type Intent struct {
+	Reference string `json:"reference"`
+	// ProviderID is the provider's own ID for the payment, if it gave one.
+	ProviderID string `json:"provider_id,omitempty"`
+	// RedirectURL is where the player completes a deposit, if the provider
+	// hosts a checkout.
+	RedirectURL string `json:"redirect_url,omitempty"`
+}
+
+// Event is a provider callback decoded by its driver.
+type Event struct {
+	// ID is the provider's ID for the event, used to apply it at most once.
+	ID        string
+	Type      string
+	Reference string
+	UserID    int64
+	Amount    float64
+}
+
+// Provider is the driver for one PSP.
+type Provider interface {
+	// CreateDeposit starts a deposit. The wallet is credited once the
+	// provider's callback reports it settled.
+	CreateDeposit(ctx context.Context, req Request) (Intent, error)
+	// CreatePayout sends money to the player. The amount is already held;
+	// wrap ErrDeclined to have it returned.
+	CreatePayout(ctx context.Context, req Request) (Intent, error)
+	// HandleCallback authenticates a callback and decodes the event it
+	// carries. A callback that fails verification is not stored.
+	HandleCallback(header http.Header, payload []byte) (Event, error)
+	// ParseCallback decodes a callback HandleCallback accepted earlier, when
+	// it is applied or replayed. It must not check the signature again, which
+	// may have expired by then.
+	ParseCallback(payload []byte) (Event, error)
+}
+
+// Store is the data payments are recorded in and checked against.
+type Store interface {
+	storage.WithdrawalStore
+	limits.Store
+}
+
+// Service starts deposits and payouts at registered providers.
+type Service struct {
+	store       Store
+	wallet      *wallet.Service
+	clock       clock.Clock
+	ids         clock.IDGenerator
+	providers   map[string]Provider
+	approvalMin float64
+}
+
+// NewService builds a service over the given drivers, keyed by provider name.
+// Payment references are generated with ids. Withdrawals of approvalMin or
+// more wait for an admin's approval.
+func NewService(store Store, ledger *wallet.Service, clk clock.Clock, ids clock.IDGenerator, providers map[string]Provider, approvalMin float64) *Service {
+	return &Service{store: store, wallet: ledger, clock: clk, ids: ids, providers: providers, approvalMin: approvalMin}
+}
+
+// Deposit starts a deposit of amount for userID at provider. Deposits past
+// the player's daily cap get limits.ErrDepositLimit, and excluded players
+// limits.ErrExcluded.
+func (s *Service) Deposit(ctx context.Context, provider string, userID int64, amount float64) (Intent, error) {
+	p, ok := s.providers[provider]
+	if !ok {
+		return Intent{}, ErrUnknownProvider
+	}
+	if !validAmount(amount) {
+		return Intent{}, ErrInvalidAmount
+	}
+	if err := limits.CheckDeposit(ctx, s.store, userID, amount, s.clock.Now()); err != nil {
+		return Intent{}, err
+	}
+	return p.CreateDeposit(ctx, Request{Reference: s.ids.NewID(), UserID: userID, Amount: amount})
+}
+
+// processor feeds a driver's callbacks through the integrations pipeline.
+type processor struct {
+	name     string
+	provider Provider
+	clock    clock.Clock
+}
+
+// NewProcessor adapts the driver registered as name into the integrations
+// processor for its callbacks. Withdrawals are settled as of clk.
+func NewProcessor(name string, p Provider, clk clock.Clock) integrations.Processor {
+	return &processor{name: name, provider: p, clock: clk}
+}
+
+func (p *processor) Verify(header http.Header, payload []byte) (string, error) {
+	event, err := p.provider.HandleCallback(header, payload)
+	if err != nil {
+		return "", err
+	}
+	if event.ID == "" {
+		return "", errors.New("callback has no event id")
+	}
+	return event.ID, nil
+}
+
+// Apply credits settled deposits and settles withdrawals, returning the held
+// amount when the payout failed. Other events are recorded with their
+// delivery and need nothing from the wallet.
+func (p *processor) Apply(ctx context.Context, tx storage.Repositories, payload []byte) error {
+	event, err := p.provider.ParseCallback(payload)
+	if err != nil {
+		return err
+	}
+	key := p.name + ":" + event.Reference
+	switch event.Type {
+	case EventDepositSucceeded:
+		if event.UserID == 0 || !validAmount(event.Amount) {
+			return fmt.Errorf("deposit %s: invalid user or amount", key)
+		}
+		_, _, err := wallet.Apply(ctx, tx, wallet.Operation{Kind: models.OperationDeposit, Key: key}, models.Transaction{
+			UserID:    event.UserID,
+			Amount:    event.Amount,
+			Reason:    models.TransactionDeposit,
+			Reference: key,
+		})
+		return err
+	case EventPayoutSucceeded, EventPayoutFailed:
+		request, err := tx.FindWithdrawalByReference(ctx, p.name, event.Reference)
+		if err != nil {
+			return fmt.Errorf("find withdrawal %s: %w", key, err)
+		}
+		if event.Type == EventPayoutSucceeded {
+			return settle(ctx, tx, request, []string{models.WithdrawalApproved}, models.WithdrawalPaid, "", p.clock.Now())
+		}
+		// A payout can bounce after it was reported paid. What was held is
+		// returned, whatever amount the callback claims.
+		err = settle(ctx, tx, request, []string{models.WithdrawalApproved, models.WithdrawalPaid}, models.WithdrawalFailed, "the provider reported the payout failed", p.clock.Now())
+		if err != nil {
+			return err
+		}
+		_, _, err = wallet.Apply(ctx, tx, wallet.Operation{Kind: models.OperationPayoutReversal, Key: key}, refund(request))
+		return err
+	}
+	return nil
+}
+
+// settle moves a withdrawal to a status set by its payout's callback. A
+// request already there is left alone, since a provider may report a payout
+// in several events.
+func settle(ctx context.Context, tx storage.WithdrawalStore, request models.WithdrawalRequest, from []string, status, note string, at time.Time) error {
+	if request.Status == status {
+		return nil
+	}
+	if _, err := tx.TransitionWithdrawal(ctx, request.ID, from, status, nil, note, at); err != nil {
+		return fmt.Errorf("mark withdrawal %d %s: %w", request.ID, status, err)
+	}
+	return nil
+}
+
+func validAmount(amount float64) bool {
+	return !math.IsNaN(amount) && !math.IsInf(amount, 0) && cents(amount) > 0
+}
+
+// cents compares amounts the way the NUMERIC(24,2) columns store them.
+func cents(amount float64) int64 {
+	return int64(math.Round(amount * 100))
+}
@@ -0,0 +1,189 @@
+package payments
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/hongminglow/all-in-be/internal/integrations"
+	"github.com/hongminglow/all-in-be/internal/models"
+	"github.com/hongminglow/all-in-be/internal/storage"
+	"github.com/hongminglow/all-in-be/internal/storage/storagetest"
+	"github.com/hongminglow/all-in-be/internal/wallet"
+)
+
+type sequentialIDs struct{ n int }
+
+func (g *sequentialIDs) NewID() string {
+	g.n++
+	return fmt.Sprintf("pay-%d", g.n)
+}
+
+// decliningSandbox is a sandbox whose payouts are refused or time out.
+type decliningSandbox struct {
+	*Sandbox
+	err error
+}
+
+func (p decliningSandbox) CreatePayout(context.Context, Request) (Intent, error) {
+	return Intent{}, p.err
+}
+
+func newTestStore(t *testing.T) (*storagetest.MemoryStore, models.User, models.User) {
+	t.Helper()
+	store := storagetest.NewMemoryStore(storagetest.NewFakeClock(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)))
+	user, err := store.CreateUser(context.Background(), models.User{Username: "ana", Email: "ana@example.com", Role: models.NormalUser, Balance: 100})
+	if err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	admin, err := store.CreateUser(context.Background(), models.User{Username: "ops", Email: "ops@example.com", Role: models.AdminUser})
+	if err != nil {
+		t.Fatalf("create admin: %v", err)
+	}
+	return store, user, admin
+}
+
+func newTestService(store *storagetest.MemoryStore, providers map[string]Provider, approvalMin float64) *Service {
+	return NewService(store, wallet.NewService(store, nil), storagetest.NewFakeClock(time.Now()), &sequentialIDs{}, providers, approvalMin)
+}
+
+func balance(t *testing.T, store storage.UserStore, id int64) float64 {
+	t.Helper()
+	user, err := store.FindByID(context.Background(), id)
+	if err != nil {
+		t.Fatalf("find user: %v", err)
+	}
+	return user.Balance
+}
+
+func TestWithdrawalsHoldTheAmountUntilReviewed(t *testing.T) {
+	ctx := context.Background()
+	store, user, admin := newTestStore(t)
+	driver := &decliningSandbox{Sandbox: NewSandbox("secret")}
+	service := newTestService(store, map[string]Provider{"psp": driver}, 50)
+
+	small, err := service.Withdraw(ctx, "psp", user.ID, 20)
+	if err != nil || small.Status != models.WithdrawalApproved || small.ReviewedBy != nil {
+		t.Fatalf("withdrawal below the threshold = %+v, %v; want approved automatically", small, err)
+	}
+	large, err := service.Withdraw(ctx, "psp", user.ID, 60)
+	if err != nil || large.Status != models.WithdrawalPending {
+		t.Fatalf("withdrawal above the threshold = %+v, %v; want pending", large, err)
+	}
+	if got := balance(t, store, user.ID); got != 20 {
+		t.Fatalf("balance with 80 held = %v, want 20", got)
+	}
+	if _, err := service.Withdraw(ctx, "psp", user.ID, 30); !errors.Is(err, storage.ErrInsufficientFunds) {
+		t.Fatalf("withdrawal of held funds: err = %v, want ErrInsufficientFunds", err)
+	}
+	if list, _ := store.ListWithdrawals(ctx, models.WithdrawalFilter{UserID: user.ID}, 10); len(list) != 2 {
+		t.Fatalf("withdrawals = %+v, want the failed hold rolled back with its request", list)
+	}
+
+	if _, err := service.Approve(ctx, large.ID, user.ID); !errors.Is(err, ErrOwnWithdrawal) {
+		t.Fatalf("self-approval: err = %v, want ErrOwnWithdrawal", err)
+	}
+	rejected, err := service.Reject(ctx, large.ID, admin.ID, "source of funds unclear")
+	if err != nil || rejected.Status != models.WithdrawalRejected || rejected.ReviewedBy == nil || *rejected.ReviewedBy != admin.ID {
+		t.Fatalf("rejected = %+v, %v", rejected, err)
+	}
+	if got := balance(t, store, user.ID); got != 80 {
+		t.Fatalf("balance after the rejection = %v, want the 60 held returned", got)
+	}
+	if _, err := service.Approve(ctx, large.ID, admin.ID); !errors.Is(err, ErrNotAllowed) {
+		t.Fatalf("approving a rejected withdrawal: err = %v, want ErrNotAllowed", err)
+	}
+	if _, err := service.Reject(ctx, large.ID, admin.ID, "again"); !errors.Is(err, ErrNotAllowed) {
+		t.Fatalf("rejecting twice: err = %v, want ErrNotAllowed", err)
+	}
+
+	large, _ = service.Withdraw(ctx, "psp", user.ID, 60)
+	driver.err = fmt.Errorf("%w: account closed", ErrDeclined)
+	declined, err := service.Approve(ctx, large.ID, admin.ID)
+	if err != nil || declined.Status != models.WithdrawalFailed || declined.Note == "" {
+		t.Fatalf("approved withdrawal the provider declined = %+v, %v; want failed", declined, err)
+	}
+	if got := balance(t, store, user.ID); got != 80 {
+		t.Fatalf("balance after a declined payout = %v, want 80", got)
+	}
+
+	driver.err = errors.New("timeout")
+	unconfirmed, err := service.Withdraw(ctx, "psp", user.ID, 10)
+	if !errors.Is(err, ErrPayoutUnconfirmed) || unconfirmed.Status != models.WithdrawalApproved {
+		t.Fatalf("payout that timed out = %+v, %v; want approved and ErrPayoutUnconfirmed", unconfirmed, err)
+	}
+	if got := balance(t, store, user.ID); got != 70 {
+		t.Fatalf("balance after a payout of unknown outcome = %v, want 70 until its callback", got)
+	}
+	if _, err := service.Withdraw(ctx, "other", user.ID, 5); !errors.Is(err, ErrUnknownProvider) {
+		t.Fatalf("unknown provider: err = %v, want ErrUnknownProvider", err)
+	}
+}
+
+func TestCallbacksMoveTheWalletOnce(t *testing.T) {
+	ctx := context.Background()
+	store, user, _ := newTestStore(t)
+	sandbox := NewSandbox("secret")
+	service := newTestService(store, map[string]Provider{"sandbox": sandbox}, 1000)
+	clk := storagetest.NewFakeClock(time.Now())
+	callbacks := integrations.NewService(store, clk, map[string]integrations.Processor{"sandbox": NewProcessor("sandbox", sandbox, clk)}, nil)
+	post := func(event map[string]any) (models.InboundDelivery, error) {
+		t.Helper()
+		payload, _ := json.Marshal(event)
+		return callbacks.Receive(ctx, "sandbox", http.Header{SandboxSignatureHeader: {sandbox.Sign(payload)}}, payload)
+	}
+
+	deposit, err := service.Deposit(ctx, "sandbox", user.ID, 25)
+	if err != nil || deposit.RedirectURL == "" {
+		t.Fatalf("deposit = %+v, %v", deposit, err)
+	}
+	settled := map[string]any{"id": "evt-1", "type": EventDepositSucceeded, "reference": deposit.Reference, "user_id": user.ID, "amount": 25}
+	for range 2 {
+		if _, err := post(settled); err != nil {
+			t.Fatalf("deposit callback: %v", err)
+		}
+	}
+	// A second event for the same deposit must not credit it again.
+	settled["id"] = "evt-2"
+	if _, err := post(settled); err != nil {
+		t.Fatalf("second deposit event: %v", err)
+	}
+	if got := balance(t, store, user.ID); got != 125 {
+		t.Fatalf("balance after the deposit = %v, want 125", got)
+	}
+
+	paid, _ := service.Withdraw(ctx, "sandbox", user.ID, 5)
+	if _, err := post(map[string]any{"id": "evt-3", "type": EventPayoutSucceeded, "reference": paid.Reference}); err != nil {
+		t.Fatalf("payout callback: %v", err)
+	}
+	if got, _ := store.FindWithdrawal(ctx, paid.ID); got.Status != models.WithdrawalPaid {
+		t.Fatalf("withdrawal after its payout succeeded = %+v, want paid", got)
+	}
+
+	bounced, _ := service.Withdraw(ctx, "sandbox", user.ID, 40)
+	failed := map[string]any{"id": "evt-4", "type": EventPayoutFailed, "reference": bounced.Reference, "user_id": user.ID, "amount": 4000}
+	for _, id := range []string{"evt-4", "evt-5"} {
+		failed["id"] = id
+		if _, err := post(failed); err != nil {
+			t.Fatalf("payout failure callback: %v", err)
+		}
+	}
+	if got, _ := store.FindWithdrawal(ctx, bounced.ID); got.Status != models.WithdrawalFailed {
+		t.Fatalf("withdrawal after its payout failed = %+v, want failed", got)
+	}
+	if got := balance(t, store, user.ID); got != 120 {
+		t.Fatalf("balance after a failed payout = %v, want the 40 held returned once", got)
+	}
+
+	failed["id"], failed["reference"] = "evt-6", "never-paid-out"
+	if d, err := post(failed); !errors.Is(err, integrations.ErrProcessingFailed) || d.Status != models.InboundFailed {
+		t.Fatalf("failure of an unknown payout = %+v, %v; want it stored as failed", d, err)
+	}
+	if _, err := callbacks.Receive(ctx, "sandbox", http.Header{SandboxSignatureHeader: {"00"}}, []byte(`{"id":"evt-7"}`)); !errors.Is(err, integrations.ErrUnverified) {
+		t.Fatalf("forged callback: err = %v, want ErrUnverified", err)
+	}
+}
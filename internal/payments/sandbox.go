@@ -0,0 +1,77 @@
+package payments
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+)
+
+// SandboxSignatureHeader carries the hex HMAC-SHA256 of a sandbox callback's
+// body, keyed with the sandbox secret.
+const SandboxSignatureHeader = "X-Sandbox-Signature"
+
+// Sandbox is a driver that moves no real money, for development and testing.
+// Deposits and payouts are accepted at once and settle when a callback signed
+// with the shared secret is posted, e.g. by a test or a QA tool using Sign.
+// Callbacks are JSON: {"id", "type", "reference", "user_id", "amount"}.
+type Sandbox struct {
+	secret []byte
+}
+
+// NewSandbox builds a sandbox driver that signs with secret.
+func NewSandbox(secret string) *Sandbox {
+	return &Sandbox{secret: []byte(secret)}
+}
+
+// Sign returns the signature header value for a callback body.
+func (s *Sandbox) Sign(payload []byte) string {
+	return hex.EncodeToString(s.sum(payload))
+}
+
+func (s *Sandbox) CreateDeposit(_ context.Context, req Request) (Intent, error) {
+	return Intent{
+		Reference:   req.Reference,
+		ProviderID:  "sbx_" + req.Reference,
+		RedirectURL: "https://sandbox.invalid/checkout?reference=" + url.QueryEscape(req.Reference),
+	}, nil
+}
+
+func (s *Sandbox) CreatePayout(_ context.Context, req Request) (Intent, error) {
+	return Intent{Reference: req.Reference, ProviderID: "sbx_" + req.Reference}, nil
+}
+
+func (s *Sandbox) HandleCallback(header http.Header, payload []byte) (Event, error) {
+	got, err := hex.DecodeString(header.Get(SandboxSignatureHeader))
+	if err != nil || !hmac.Equal(got, s.sum(payload)) {
+		return Event{}, errors.New("bad sandbox signature")
+	}
+	return s.ParseCallback(payload)
+}
+
+func (s *Sandbox) ParseCallback(payload []byte) (Event, error) {
+	var body struct {
+		ID        string  `json:"id"`
+		Type      string  `json:"type"`
+		Reference string  `json:"reference"`
+		UserID    int64   `json:"user_id"`
+		Amount    float64 `json:"amount"`
+	}
+	if err := json.Unmarshal(payload, &body); err != nil {
+		return Event{}, err
+	}
+	if body.Reference == "" {
+		return Event{}, errors.New("sandbox callback has no reference")
+	}
+	return Event(body), nil
+}
+
+func (s *Sandbox) sum(payload []byte) []byte {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
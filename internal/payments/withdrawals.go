@@ -0,0 +1,138 @@
+package payments
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/hongminglow/all-in-be/internal/models"
+	"github.com/hongminglow/all-in-be/internal/storage"
+	"github.com/hongminglow/all-in-be/internal/wallet"
+)
+
+// Withdraw holds amount of userID's balance and requests its payout at
+// provider. The hold fails with storage.ErrInsufficientFunds when the balance
+// is too low. Requests below the approval threshold are approved and paid out
+// at once; the others wait as pending for Approve or Reject.
+func (s *Service) Withdraw(ctx context.Context, provider string, userID int64, amount float64) (models.WithdrawalRequest, error) {
+	if _, ok := s.providers[provider]; !ok {
+		return models.WithdrawalRequest{}, ErrUnknownProvider
+	}
+	if !validAmount(amount) {
+		return models.WithdrawalRequest{}, ErrInvalidAmount
+	}
+	request := models.WithdrawalRequest{
+		UserID:    userID,
+		Provider:  provider,
+		Reference: s.ids.NewID(),
+		Amount:    amount,
+		Status:    models.WithdrawalPending,
+	}
+	if cents(amount) < cents(s.approvalMin) {
+		request.Status = models.WithdrawalApproved
+	}
+	key := provider + ":" + request.Reference
+	_, err := s.wallet.ApplyWith(ctx, wallet.Operation{Kind: models.OperationPayout, Key: key}, models.Transaction{
+		UserID:    userID,
+		Amount:    -amount,
+		Reason:    models.TransactionWithdrawal,
+		Reference: key,
+	}, func(tx storage.Repositories, hold models.Transaction) error {
+		request.HoldID = hold.ID
+		var err error
+		request, err = tx.CreateWithdrawal(ctx, request)
+		return err
+	})
+	if err != nil {
+		return models.WithdrawalRequest{}, err
+	}
+	if request.Status == models.WithdrawalApproved {
+		return s.pay(ctx, request)
+	}
+	return request, nil
+}
+
+// Approve approves a pending withdrawal on behalf of reviewer and sends its
+// payout.
+func (s *Service) Approve(ctx context.Context, id, reviewer int64) (models.WithdrawalRequest, error) {
+	if _, err := s.reviewable(ctx, id, reviewer); err != nil {
+		return models.WithdrawalRequest{}, err
+	}
+	request, err := s.store.TransitionWithdrawal(ctx, id, []string{models.WithdrawalPending}, models.WithdrawalApproved, &reviewer, "", s.clock.Now())
+	if errors.Is(err, storage.ErrNotFound) {
+		return models.WithdrawalRequest{}, ErrNotAllowed
+	}
+	if err != nil {
+		return models.WithdrawalRequest{}, err
+	}
+	return s.pay(ctx, request)
+}
+
+// Reject rejects a pending withdrawal on behalf of reviewer, for the reason
+// in note, and returns its hold.
+func (s *Service) Reject(ctx context.Context, id, reviewer int64, note string) (models.WithdrawalRequest, error) {
+	request, err := s.reviewable(ctx, id, reviewer)
+	if err != nil {
+		return models.WithdrawalRequest{}, err
+	}
+	return s.release(ctx, request, models.WithdrawalPending, models.WithdrawalRejected, &reviewer, note)
+}
+
+// reviewable returns a pending withdrawal that reviewer did not request.
+func (s *Service) reviewable(ctx context.Context, id, reviewer int64) (models.WithdrawalRequest, error) {
+	request, err := s.store.FindWithdrawal(ctx, id)
+	switch {
+	case err != nil:
+		return models.WithdrawalRequest{}, err
+	case request.UserID == reviewer:
+		return models.WithdrawalRequest{}, ErrOwnWithdrawal
+	case request.Status != models.WithdrawalPending:
+		return models.WithdrawalRequest{}, ErrNotAllowed
+	}
+	return request, nil
+}
+
+// pay sends an approved withdrawal's payout. A declined payout fails the
+// request and returns its hold.
+func (s *Service) pay(ctx context.Context, request models.WithdrawalRequest) (models.WithdrawalRequest, error) {
+	p, ok := s.providers[request.Provider]
+	if !ok {
+		return request, fmt.Errorf("%w: %w", ErrPayoutUnconfirmed, ErrUnknownProvider)
+	}
+	_, err := p.CreatePayout(ctx, Request{Reference: request.Reference, UserID: request.UserID, Amount: request.Amount})
+	switch {
+	case errors.Is(err, ErrDeclined):
+		return s.release(ctx, request, models.WithdrawalApproved, models.WithdrawalFailed, nil, err.Error())
+	case err != nil:
+		return request, fmt.Errorf("%w: %v", ErrPayoutUnconfirmed, err)
+	}
+	return request, nil
+}
+
+// release returns a withdrawal's hold as it moves from one status to another.
+func (s *Service) release(ctx context.Context, request models.WithdrawalRequest, from, status string, reviewer *int64, note string) (models.WithdrawalRequest, error) {
+	var released models.WithdrawalRequest
+	_, err := s.wallet.ApplyWith(ctx, wallet.Operation{Kind: models.OperationPayoutReversal, Key: request.Provider + ":" + request.Reference}, refund(request), func(tx storage.Repositories, _ models.Transaction) error {
+		var err error
+		released, err = tx.TransitionWithdrawal(ctx, request.ID, []string{from}, status, reviewer, note, s.clock.Now())
+		return err
+	})
+	switch {
+	case errors.Is(err, storage.ErrNotFound), err == nil && released.ID == 0:
+		// Moved on concurrently, or the hold was already returned.
+		return models.WithdrawalRequest{}, ErrNotAllowed
+	case err != nil:
+		return models.WithdrawalRequest{}, err
+	}
+	return released, nil
+}
+
+// refund is the ledger entry that returns a withdrawal's hold.
+func refund(request models.WithdrawalRequest) models.Transaction {
+	return models.Transaction{
+		UserID:    request.UserID,
+		Amount:    request.Amount,
+		Reason:    models.TransactionPayoutReversal,
+		Reference: request.Provider + ":" + request.Reference,
+	}
+}
@@ -0,0 +1,41 @@
+package phone
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/nyaruka/phonenumbers"
+)
+
+// ErrInvalid indicates the input could not be parsed as a dialable phone number.
+var ErrInvalid = errors.New("phone number is invalid")
+
+// ErrRegionRequired indicates a national-format number was given without a default region.
+var ErrRegionRequired = errors.New("phone number must include a country code (e.g. +60123456789)")
+
+// Normalize parses raw and returns it in E.164 form (e.g. +60123456789).
+// Numbers without a leading + are interpreted in defaultRegion, an ISO 3166-1
+// alpha-2 code; when defaultRegion is empty such numbers are rejected.
+func Normalize(raw, defaultRegion string) (string, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return "", ErrInvalid
+	}
+	region := strings.ToUpper(strings.TrimSpace(defaultRegion))
+	if !strings.HasPrefix(raw, "+") && region == "" {
+		return "", ErrRegionRequired
+	}
+	num, err := phonenumbers.Parse(raw, region)
+	if err != nil {
+		return "", ErrInvalid
+	}
+	if !phonenumbers.IsValidNumber(num) {
+		return "", ErrInvalid
+	}
+	return phonenumbers.Format(num, phonenumbers.E164), nil
+}
+
+// ValidRegion reports whether region is a country code the parser knows about.
+func ValidRegion(region string) bool {
+	return phonenumbers.GetCountryCodeForRegion(strings.ToUpper(region)) != 0
+}
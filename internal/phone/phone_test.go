@@ -0,0 +1,67 @@
+package phone
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	cases := []struct {
+		name   string
+		raw    string
+		region string
+		want   string
+		err    error
+	}{
+		{name: "international with punctuation", raw: "+1 (202) 555-0123", want: "+12025550123"},
+		{name: "already e164", raw: "+60123456789", want: "+60123456789"},
+		{name: "national with region", raw: "012-345 6789", region: "my", want: "+60123456789"},
+		{name: "national without region", raw: "012-345 6789", err: ErrRegionRequired},
+		{name: "unassigned area code", raw: "+15550001234", err: ErrInvalid},
+		{name: "garbage", raw: "+abc", err: ErrInvalid},
+		{name: "empty", raw: "   ", err: ErrInvalid},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := Normalize(tc.raw, tc.region)
+			if tc.err != nil {
+				if !errors.Is(err, tc.err) {
+					t.Fatalf("Normalize(%q) error = %v, want %v", tc.raw, err, tc.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Normalize(%q) unexpected error: %v", tc.raw, err)
+			}
+			if got != tc.want {
+				t.Fatalf("Normalize(%q) = %q, want %q", tc.raw, got, tc.want)
+			}
+		})
+	}
+}
+
+// FuzzNormalize checks that normalization never panics and that its output is
+// a fixed point: normalizing an E.164 result yields the same number.
+func FuzzNormalize(f *testing.F) {
+	f.Add("+1 (202) 555-0123", "")
+	f.Add("012-345 6789", "MY")
+	f.Add("+abc", "")
+	f.Add("00441234567890", "GB")
+	f.Fuzz(func(t *testing.T, raw, region string) {
+		got, err := Normalize(raw, region)
+		if err != nil {
+			if !errors.Is(err, ErrInvalid) && !errors.Is(err, ErrRegionRequired) {
+				t.Fatalf("Normalize(%q, %q) returned unexpected error type: %v", raw, region, err)
+			}
+			return
+		}
+		if !strings.HasPrefix(got, "+") {
+			t.Fatalf("Normalize(%q, %q) = %q, not E.164", raw, region, got)
+		}
+		again, err := Normalize(got, "")
+		if err != nil || again != got {
+			t.Fatalf("Normalize(%q) = %q, %v; want fixed point", got, again, err)
+		}
+	})
+}
@@ -0,0 +1,117 @@
+// Package promo redeems admin-issued promo codes. A redemption is checked
+// against the code's expiry, role restriction and usage limits, counted and
+// credited to the wallet in a single transaction, so concurrent redemptions
+// cannot overshoot a limit or credit a player twice.
+package promo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/hongminglow/all-in-be/internal/clock"
+	"github.com/hongminglow/all-in-be/internal/events"
+	"github.com/hongminglow/all-in-be/internal/models"
+	"github.com/hongminglow/all-in-be/internal/outbox"
+	"github.com/hongminglow/all-in-be/internal/storage"
+	"github.com/hongminglow/all-in-be/internal/wallet"
+)
+
+// Reasons a code cannot be redeemed.
+var (
+	ErrUnknownCode = errors.New("unknown promo code")
+	ErrUnavailable = errors.New("promo code is no longer available")
+	ErrNotEligible = errors.New("promo code is not available to your account")
+	ErrRedeemed    = errors.New("promo code already redeemed")
+	ErrExhausted   = errors.New("promo code has been fully redeemed")
+)
+
+// Normalize canonicalizes a code as typed by a player or an admin; codes are
+// case-insensitive.
+func Normalize(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// Service redeems promo codes.
+type Service struct {
+	store  storage.UnitOfWork
+	outbox *outbox.Relay
+	clock  clock.Clock
+}
+
+// NewService builds a service that announces credited balances through the
+// outbox relayed by relay, which may be nil.
+func NewService(store storage.UnitOfWork, relay *outbox.Relay, clk clock.Clock) *Service {
+	return &Service{store: store, outbox: relay, clock: clk}
+}
+
+// Redeem credits the code's amount to the user's wallet and returns the
+// redemption with the ledger entry that paid it.
+func (s *Service) Redeem(ctx context.Context, user models.User, code string) (models.PromoRedemption, models.Transaction, error) {
+	var redemption models.PromoRedemption
+	var entry models.Transaction
+	err := s.store.WithTx(ctx, func(tx storage.Repositories) error {
+		promo, err := tx.FindPromoCodeByCode(ctx, Normalize(code))
+		if errors.Is(err, storage.ErrNotFound) {
+			return ErrUnknownCode
+		}
+		if err != nil {
+			return fmt.Errorf("find promo code: %w", err)
+		}
+		if !promo.Active || (promo.ExpiresAt != nil && !s.clock.Now().Before(*promo.ExpiresAt)) {
+			return ErrUnavailable
+		}
+		if len(promo.Roles) > 0 && !slices.Contains(promo.Roles, user.Role) {
+			return ErrNotEligible
+		}
+		// Claiming first holds the code, so the per-user count below cannot
+		// race with another redemption of it.
+		promo, err = tx.ClaimPromoRedemption(ctx, promo.ID)
+		if errors.Is(err, storage.ErrLimitReached) {
+			return ErrExhausted
+		}
+		if err != nil {
+			return fmt.Errorf("claim promo redemption: %w", err)
+		}
+		n, err := tx.CountPromoRedemptions(ctx, promo.ID, user.ID)
+		if err != nil {
+			return fmt.Errorf("count promo redemptions: %w", err)
+		}
+		if n >= promo.PerUserLimit {
+			return ErrRedeemed
+		}
+		op := wallet.Operation{Kind: models.OperationPromoRedemption, Key: fmt.Sprintf("%d:%d:%d", promo.ID, user.ID, n+1)}
+		var applied bool
+		entry, applied, err = wallet.Apply(ctx, tx, op, models.Transaction{
+			UserID:    user.ID,
+			Amount:    promo.Amount,
+			Reason:    models.TransactionPromoCredit,
+			Reference: promo.Code,
+		})
+		if err != nil {
+			return err
+		}
+		if !applied {
+			return ErrRedeemed
+		}
+		redemption, err = tx.CreatePromoRedemption(ctx, models.PromoRedemption{
+			CodeID:        promo.ID,
+			Code:          promo.Code,
+			Campaign:      promo.Campaign,
+			UserID:        user.ID,
+			Amount:        entry.Amount,
+			TransactionID: entry.ID,
+		})
+		if err != nil {
+			return fmt.Errorf("record promo redemption: %w", err)
+		}
+		return outbox.Add(ctx, tx, events.TypeBalanceChanged, wallet.BalanceChanged(entry))
+	})
+	if err != nil {
+		return models.PromoRedemption{}, models.Transaction{}, err
+	}
+	s.outbox.Wake()
+	return redemption, entry, nil
+}
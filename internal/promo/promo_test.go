@@ -0,0 +1,128 @@
+package promo
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hongminglow/all-in-be/internal/config"
+	"github.com/hongminglow/all-in-be/internal/models"
+	"github.com/hongminglow/all-in-be/internal/outbox"
+	"github.com/hongminglow/all-in-be/internal/storage/storagetest"
+)
+
+type recordingPublisher struct {
+	mu     sync.Mutex
+	events []any
+}
+
+func (p *recordingPublisher) Publish(_ context.Context, _ string, data any) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.events = append(p.events, data)
+	return nil
+}
+
+func TestRedeemEnforcesRestrictions(t *testing.T) {
+	ctx := context.Background()
+	clk := storagetest.NewFakeClock(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	store := storagetest.NewMemoryStore(clk)
+	ana, _ := store.CreateUser(ctx, models.User{Username: "ana", Email: "ana@example.com", Role: models.NormalUser, Balance: 10})
+	ben, _ := store.CreateUser(ctx, models.User{Username: "ben", Email: "ben@example.com", Role: models.VVIPUser})
+	cat, _ := store.CreateUser(ctx, models.User{Username: "cat", Email: "cat@example.com", Role: models.VVIPUser})
+	expires := clk.Now().Add(time.Hour)
+	for _, c := range []models.PromoCode{
+		{Code: "WELCOME", Campaign: "launch", Amount: 5, PerUserLimit: 2, Active: true},
+		{Code: "VVIP", Campaign: "launch", Amount: 50, MaxRedemptions: 1, PerUserLimit: 1, Roles: []string{models.VVIPUser}, ExpiresAt: &expires, Active: true},
+		{Code: "OLD", Campaign: "retired", Amount: 1, PerUserLimit: 1},
+	} {
+		if _, err := store.CreatePromoCode(ctx, c); err != nil {
+			t.Fatal(err)
+		}
+	}
+	events := &recordingPublisher{}
+	relay := outbox.NewRelay(store, events, clk, config.EventsConfig{})
+	promos := NewService(store, relay, clk)
+
+	redemption, entry, err := promos.Redeem(ctx, ana, " welcome ")
+	if err != nil || entry.BalanceAfter != 15 || redemption.Campaign != "launch" || redemption.TransactionID != entry.ID {
+		t.Fatalf("redeem = %+v, %+v, %v", redemption, entry, err)
+	}
+	if _, entry, err := promos.Redeem(ctx, ana, "WELCOME"); err != nil || entry.BalanceAfter != 20 {
+		t.Fatalf("second redemption within the per-user limit: %+v, %v", entry, err)
+	}
+
+	cases := []struct {
+		name string
+		user models.User
+		code string
+		want error
+	}{
+		{name: "per-user limit", user: ana, code: "WELCOME", want: ErrRedeemed},
+		{name: "unknown", user: ana, code: "NOPE", want: ErrUnknownCode},
+		{name: "inactive", user: ana, code: "OLD", want: ErrUnavailable},
+		{name: "role restricted", user: ana, code: "VVIP", want: ErrNotEligible},
+	}
+	for _, tc := range cases {
+		if _, _, err := promos.Redeem(ctx, tc.user, tc.code); !errors.Is(err, tc.want) {
+			t.Errorf("%s: err = %v, want %v", tc.name, err, tc.want)
+		}
+	}
+
+	clk.Advance(2 * time.Hour)
+	if _, _, err := promos.Redeem(ctx, ben, "VVIP"); !errors.Is(err, ErrUnavailable) {
+		t.Fatalf("expired code: err = %v, want ErrUnavailable", err)
+	}
+	clk.Advance(-2 * time.Hour)
+	if _, _, err := promos.Redeem(ctx, ben, "VVIP"); err != nil {
+		t.Fatalf("eligible redemption: %v", err)
+	}
+	if _, _, err := promos.Redeem(ctx, cat, "VVIP"); !errors.Is(err, ErrExhausted) {
+		t.Fatalf("used-up code: err = %v, want ErrExhausted", err)
+	}
+
+	if got, _ := store.FindByID(ctx, cat.ID); got.Balance != 0 {
+		t.Fatalf("refused redemption credited %v", got.Balance)
+	}
+	code, _ := store.FindPromoCodeByCode(ctx, "VVIP")
+	if code.Redemptions != 1 {
+		t.Fatalf("VVIP redemptions = %d, want refused attempts rolled back", code.Redemptions)
+	}
+	if n, err := relay.Flush(ctx); err != nil || n != 3 || len(events.events) != 3 {
+		t.Fatalf("relayed %d balance changes (%v), want 3", n, err)
+	}
+
+	reports, err := store.ListPromoCampaignReports(ctx)
+	if err != nil || len(reports) != 2 {
+		t.Fatalf("reports = %+v, %v", reports, err)
+	}
+	if r := reports[0]; r.Campaign != "launch" || r.Codes != 2 || r.Redemptions != 3 || r.Players != 2 || r.Amount != 60 {
+		t.Fatalf("launch report = %+v", r)
+	}
+}
+
+func TestRedeemIsAtomicUnderConcurrency(t *testing.T) {
+	ctx := context.Background()
+	clk := storagetest.NewFakeClock(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	store := storagetest.NewMemoryStore(clk)
+	user, _ := store.CreateUser(ctx, models.User{Username: "dee", Email: "dee@example.com", Role: models.NormalUser})
+	if _, err := store.CreatePromoCode(ctx, models.PromoCode{Code: "ONCE", Campaign: "spring", Amount: 10, PerUserLimit: 1, Active: true}); err != nil {
+		t.Fatal(err)
+	}
+	promos := NewService(store, nil, clk)
+
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _, _ = promos.Redeem(ctx, user, "ONCE")
+		}()
+	}
+	wg.Wait()
+	if got, _ := store.FindByID(ctx, user.ID); got.Balance != 10 {
+		t.Fatalf("balance = %v, want a single credit", got.Balance)
+	}
+}
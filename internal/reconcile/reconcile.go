@@ -0,0 +1,98 @@
+// Package reconcile checks every user's stored balance against their ledger
+// on a schedule and records each run as a report, so drift caused by a bug or
+// a manual database edit is caught the next night rather than by a player.
+package reconcile
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"time"
+
+	"github.com/hongminglow/all-in-be/internal/config"
+	"github.com/hongminglow/all-in-be/internal/models"
+	"github.com/hongminglow/all-in-be/internal/storage"
+)
+
+// maxDiscrepancies bounds the mismatches kept in one report; Mismatches
+// still counts them all.
+const maxDiscrepancies = 1000
+
+// Service reconciles balances on a schedule and on demand.
+type Service struct {
+	store storage.ReconciliationStore
+	cfg   config.ReconcileConfig
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewService builds a reconciler; call Start to run it on cfg.Interval.
+func NewService(store storage.ReconciliationStore, cfg config.ReconcileConfig) *Service {
+	return &Service{store: store, cfg: cfg}
+}
+
+// Run compares every balance with the ledger and stores the report.
+func (s *Service) Run(ctx context.Context) (models.ReconciliationReport, error) {
+	checked, discrepancies, err := s.store.ReconcileBalances(ctx)
+	if err != nil {
+		return models.ReconciliationReport{}, err
+	}
+	report := models.ReconciliationReport{UsersChecked: checked, Mismatches: len(discrepancies)}
+	report.Discrepancies = discrepancies[:min(len(discrepancies), maxDiscrepancies)]
+	for i, d := range report.Discrepancies {
+		report.Discrepancies[i].Difference = math.Round((d.Balance-d.LedgerBalance)*100) / 100
+	}
+	report, err = s.store.CreateReconciliationReport(ctx, report)
+	if err != nil {
+		return models.ReconciliationReport{}, fmt.Errorf("save reconciliation report: %w", err)
+	}
+	return report, nil
+}
+
+// Start runs the reconciler every cfg.Interval until Close. It does nothing
+// when no interval is configured.
+func (s *Service) Start() {
+	if s.cfg.Interval <= 0 || s.stop != nil {
+		return
+	}
+	s.stop, s.done = make(chan struct{}), make(chan struct{})
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-s.stop
+		cancel()
+	}()
+	go func() {
+		defer close(s.done)
+		ticker := time.NewTicker(s.cfg.Interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				report, err := s.Run(ctx)
+				if err != nil {
+					if ctx.Err() == nil {
+						log.Printf("reconcile: %v", err)
+					}
+					continue
+				}
+				if report.Mismatches > 0 {
+					log.Printf("reconcile: %d of %d balances disagree with the ledger (report %d)", report.Mismatches, report.UsersChecked, report.ID)
+				}
+			}
+		}
+	}()
+}
+
+// Close stops the schedule, cancelling a run in progress, and waits for it to end.
+func (s *Service) Close() {
+	if s.stop == nil {
+		return
+	}
+	close(s.stop)
+	<-s.done
+	s.stop = nil
+}
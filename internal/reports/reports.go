@@ -0,0 +1,352 @@
+// Package reports generates operator reports: signups, gross gaming revenue,
+// deposits and withdrawals over a day, a week or a custom run of days. Each
+// report is built on the job queue, kept in the blob store as CSV and PDF,
+// and emailed with signed download links through the notification service.
+// Daily and weekly reports are generated on a schedule for the last full UTC
+// day and ISO week; admins can ask for others at any time.
+package reports
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/hongminglow/all-in-be/internal/blob"
+	"github.com/hongminglow/all-in-be/internal/clock"
+	"github.com/hongminglow/all-in-be/internal/config"
+	"github.com/hongminglow/all-in-be/internal/jobs"
+	"github.com/hongminglow/all-in-be/internal/models"
+	"github.com/hongminglow/all-in-be/internal/notify"
+	"github.com/hongminglow/all-in-be/internal/storage"
+)
+
+// MaxAttempts is how many times building a report is tried before it is
+// marked failed.
+const MaxAttempts = 3
+
+// MaxCustomDays bounds the days a custom report covers.
+const MaxCustomDays = 366
+
+const day = 24 * time.Hour
+
+// ErrInvalidPeriod is returned for an unknown period, or one that is empty,
+// too long or starts in the future.
+var ErrInvalidPeriod = errors.New("invalid report period")
+
+// Store is the data reports are computed from and recorded in.
+type Store interface {
+	storage.ReportStore
+	storage.UserStore
+}
+
+// Service generates operator reports on a schedule and on demand.
+type Service struct {
+	store    Store
+	blobs    blob.Store
+	queue    *jobs.Queue
+	notifier notify.Notifier
+	clock    clock.Clock
+	cfg      config.ReportsConfig
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewService builds the service; call Start to run the schedule.
+func NewService(store Store, blobs blob.Store, queue *jobs.Queue, notifier notify.Notifier, clk clock.Clock, cfg config.ReportsConfig) *Service {
+	return &Service{store: store, blobs: blobs, queue: queue, notifier: notifier, clock: clk, cfg: cfg}
+}
+
+// Bounds returns the [start, end) a report of period covers. Daily reports
+// cover the UTC day of from and weekly ones its ISO week; custom reports run
+// from the day of from through the day of to.
+func Bounds(period string, from, to time.Time) (time.Time, time.Time, error) {
+	start := from.UTC().Truncate(day)
+	switch period {
+	case models.ReportDaily:
+		return start, start.Add(day), nil
+	case models.ReportWeekly:
+		start = start.AddDate(0, 0, -(int(start.Weekday())+6)%7)
+		return start, start.AddDate(0, 0, 7), nil
+	case models.ReportCustom:
+		end := to.UTC().Truncate(day).Add(day)
+		if !end.After(start) || end.Sub(start) > MaxCustomDays*day {
+			return time.Time{}, time.Time{}, ErrInvalidPeriod
+		}
+		return start, end, nil
+	}
+	return time.Time{}, time.Time{}, ErrInvalidPeriod
+}
+
+// Request queues an ad-hoc report for the admin requestedBy, who is emailed
+// when it is ready. to is only read for custom reports.
+func (s *Service) Request(ctx context.Context, period string, from, to time.Time, requestedBy int64) (models.OperatorReport, error) {
+	start, end, err := Bounds(period, from, to)
+	if err != nil {
+		return models.OperatorReport{}, err
+	}
+	if start.After(s.clock.Now()) {
+		return models.OperatorReport{}, ErrInvalidPeriod
+	}
+	return s.create(ctx, models.OperatorReport{Period: period, PeriodStart: start, PeriodEnd: end, RequestedBy: &requestedBy})
+}
+
+// Find returns one report, with download links once it is ready.
+func (s *Service) Find(ctx context.Context, id int64) (models.OperatorReport, error) {
+	report, err := s.store.FindOperatorReport(ctx, id)
+	if err != nil {
+		return models.OperatorReport{}, err
+	}
+	return s.withLinks(ctx, report)
+}
+
+// List returns up to limit reports, newest first, with links to the ready ones.
+func (s *Service) List(ctx context.Context, limit int) ([]models.OperatorReport, error) {
+	reports, err := s.store.ListOperatorReports(ctx, limit)
+	if err != nil {
+		return nil, err
+	}
+	for i := range reports {
+		if reports[i], err = s.withLinks(ctx, reports[i]); err != nil {
+			return nil, err
+		}
+	}
+	return reports, nil
+}
+
+// RunDue queues the scheduled reports for the last full day and week unless
+// they exist already, e.g. from another instance.
+func (s *Service) RunDue(ctx context.Context) error {
+	yesterday := s.clock.Now().Add(-day)
+	lastWeek := s.clock.Now().AddDate(0, 0, -7)
+	for _, due := range []struct {
+		period string
+		at     time.Time
+	}{{models.ReportDaily, yesterday}, {models.ReportWeekly, lastWeek}} {
+		start, end, _ := Bounds(due.period, due.at, due.at)
+		_, err := s.create(ctx, models.OperatorReport{Period: due.period, PeriodStart: start, PeriodEnd: end})
+		if err != nil && !errors.Is(err, storage.ErrAlreadyExists) {
+			return err
+		}
+	}
+	return nil
+}
+
+// Start runs RunDue every cfg.Interval until Close. It does nothing when no
+// interval is configured.
+func (s *Service) Start() {
+	if s.cfg.Interval <= 0 || s.stop != nil {
+		return
+	}
+	s.stop, s.done = make(chan struct{}), make(chan struct{})
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-s.stop
+		cancel()
+	}()
+	go func() {
+		defer close(s.done)
+		ticker := time.NewTicker(s.cfg.Interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := s.RunDue(ctx); err != nil && ctx.Err() == nil {
+					log.Printf("reports: %v", err)
+				}
+			}
+		}
+	}()
+}
+
+// Close stops the schedule and waits for it to end. Reports already queued
+// are built by the job queue.
+func (s *Service) Close() {
+	if s.stop == nil {
+		return
+	}
+	close(s.stop)
+	<-s.done
+	s.stop = nil
+}
+
+// create records a pending report and queues it to be built.
+func (s *Service) create(ctx context.Context, report models.OperatorReport) (models.OperatorReport, error) {
+	report.Status = models.ReportPending
+	report, err := s.store.CreateOperatorReport(ctx, report)
+	if err != nil {
+		return models.OperatorReport{}, err
+	}
+	job := jobs.Job{
+		Type:        "operator_report",
+		Name:        fmt.Sprintf("%s operator report %d", report.Period, report.ID),
+		Priority:    jobs.PriorityLow,
+		MaxAttempts: MaxAttempts,
+		Run: func(ctx context.Context, attempt int) error {
+			err := s.build(ctx, report)
+			if err != nil && attempt >= MaxAttempts {
+				s.fail(report, err)
+			}
+			return err
+		},
+	}
+	if err := s.queue.Enqueue(job); err != nil {
+		s.fail(report, err)
+		return models.OperatorReport{}, fmt.Errorf("queue operator report: %w", err)
+	}
+	return report, nil
+}
+
+// build computes the figures, stores both files, marks the report ready and
+// emails it.
+func (s *Service) build(ctx context.Context, report models.OperatorReport) error {
+	metrics, err := s.store.ReportMetrics(ctx, report.PeriodStart, report.PeriodEnd)
+	if err != nil {
+		return err
+	}
+	report.Metrics = &metrics
+	csvFile, err := renderCSV(report)
+	if err != nil {
+		return err
+	}
+	for _, file := range []struct {
+		key, contentType string
+		data             []byte
+	}{
+		{csvKey(report.ID), "text/csv", csvFile},
+		{pdfKey(report.ID), "application/pdf", renderPDF(summary(report, s.clock.Now()))},
+	} {
+		if err := s.blobs.Put(ctx, file.key, bytes.NewReader(file.data), file.contentType); err != nil {
+			return fmt.Errorf("store operator report: %w", err)
+		}
+	}
+	err = s.store.FinishOperatorReport(ctx, report.ID, models.ReportReady, &metrics, "", s.clock.Now())
+	if errors.Is(err, storage.ErrNotFound) {
+		// An earlier attempt already finished and sent it.
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	report.Status = models.ReportReady
+	s.send(ctx, report)
+	return nil
+}
+
+// send emails a ready report to the admin who asked for it, or to the
+// configured recipients for scheduled ones. Delivery problems are logged; the
+// report stays downloadable from the admin API.
+func (s *Service) send(ctx context.Context, report models.OperatorReport) {
+	recipients := s.cfg.Recipients
+	if report.RequestedBy != nil {
+		admin, err := s.store.FindByID(ctx, *report.RequestedBy)
+		if err != nil {
+			log.Printf("reports: find requester of report %d: %v", report.ID, err)
+			return
+		}
+		recipients = []string{admin.Email}
+	}
+	if len(recipients) == 0 {
+		return
+	}
+	report, err := s.withLinks(ctx, report)
+	if err != nil {
+		log.Printf("reports: %v", err)
+		return
+	}
+	data := map[string]any{
+		"Period":      report.Period,
+		"Start":       report.PeriodStart.Format(time.DateOnly),
+		"End":         report.PeriodEnd.Add(-day).Format(time.DateOnly),
+		"Metrics":     report.Metrics,
+		"CSVURL":      report.CSVURL,
+		"PDFURL":      report.PDFURL,
+		"LinksExpire": s.clock.Now().Add(s.cfg.LinkTTL).UTC().Format(time.RFC1123),
+	}
+	for _, to := range recipients {
+		if err := s.notifier.Notify(ctx, notify.Notification{Channel: notify.ChannelEmail, To: to, Template: notify.TemplateOperatorReport, Data: data}); err != nil {
+			log.Printf("reports: email report %d to %s: %v", report.ID, to, err)
+		}
+	}
+}
+
+// fail marks a report that could not be built. It runs after the job's
+// context may have ended, so it uses its own.
+func (s *Service) fail(report models.OperatorReport, cause error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := s.store.FinishOperatorReport(ctx, report.ID, models.ReportFailed, nil, cause.Error(), s.clock.Now()); err != nil && !errors.Is(err, storage.ErrNotFound) {
+		log.Printf("reports: mark report %d failed: %v", report.ID, err)
+	}
+}
+
+func (s *Service) withLinks(ctx context.Context, report models.OperatorReport) (models.OperatorReport, error) {
+	if report.Status != models.ReportReady {
+		return report, nil
+	}
+	var err error
+	if report.CSVURL, err = s.blobs.SignedURL(ctx, csvKey(report.ID), s.cfg.LinkTTL); err != nil {
+		return models.OperatorReport{}, fmt.Errorf("sign operator report link: %w", err)
+	}
+	if report.PDFURL, err = s.blobs.SignedURL(ctx, pdfKey(report.ID), s.cfg.LinkTTL); err != nil {
+		return models.OperatorReport{}, fmt.Errorf("sign operator report link: %w", err)
+	}
+	return report, nil
+}
+
+func csvKey(id int64) string { return fmt.Sprintf("reports/%d.csv", id) }
+func pdfKey(id int64) string { return fmt.Sprintf("reports/%d.pdf", id) }
+
+// renderCSV writes the report as a header row and one row of figures, so
+// reports for successive periods can be concatenated into one sheet.
+func renderCSV(report models.OperatorReport) ([]byte, error) {
+	m := report.Metrics
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.WriteAll([][]string{
+		{"period", "period_start", "period_end", "signups", "ggr", "deposits", "deposit_amount", "withdrawals", "withdrawal_amount"},
+		{
+			report.Period,
+			report.PeriodStart.Format(time.RFC3339),
+			report.PeriodEnd.Format(time.RFC3339),
+			strconv.FormatInt(m.Signups, 10),
+			amount(m.GGR),
+			strconv.FormatInt(m.Deposits, 10),
+			amount(m.DepositAmount),
+			strconv.FormatInt(m.Withdrawals, 10),
+			amount(m.WithdrawalAmount),
+		},
+	})
+	if err := w.Error(); err != nil {
+		return nil, fmt.Errorf("write operator report CSV: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// summary lays the report out as the lines of its PDF.
+func summary(report models.OperatorReport, generatedAt time.Time) []string {
+	m := report.Metrics
+	return []string{
+		"ALL-IN operator report",
+		"",
+		fmt.Sprintf("Period:       %s, %s to %s (UTC)", report.Period, report.PeriodStart.Format(time.DateOnly), report.PeriodEnd.Add(-day).Format(time.DateOnly)),
+		fmt.Sprintf("Generated:    %s", generatedAt.UTC().Format(time.RFC3339)),
+		"",
+		fmt.Sprintf("%-24s %14s %16s", "Metric", "Count", "Amount"),
+		fmt.Sprintf("%-24s %14d %16s", "Signups", m.Signups, ""),
+		fmt.Sprintf("%-24s %14s %16s", "Gross gaming revenue", "", amount(m.GGR)),
+		fmt.Sprintf("%-24s %14d %16s", "Deposits", m.Deposits, amount(m.DepositAmount)),
+		fmt.Sprintf("%-24s %14d %16s", "Withdrawals", m.Withdrawals, amount(m.WithdrawalAmount)),
+	}
+}
+
+func amount(v float64) string {
+	return strconv.FormatFloat(v, 'f', 2, 64)
+}
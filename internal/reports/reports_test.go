@@ -0,0 +1,122 @@
+package reports
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hongminglow/all-in-be/internal/blob"
+	"github.com/hongminglow/all-in-be/internal/config"
+	"github.com/hongminglow/all-in-be/internal/jobs"
+	"github.com/hongminglow/all-in-be/internal/models"
+	"github.com/hongminglow/all-in-be/internal/notify"
+	"github.com/hongminglow/all-in-be/internal/storage/storagetest"
+)
+
+type recordingNotifier struct {
+	mu   sync.Mutex
+	sent []notify.Notification
+}
+
+func (n *recordingNotifier) Notify(_ context.Context, msg notify.Notification) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.sent = append(n.sent, msg)
+	return nil
+}
+
+func TestBounds(t *testing.T) {
+	// 2026-03-12 is a Thursday.
+	at := time.Date(2026, 3, 12, 17, 30, 0, 0, time.UTC)
+	for _, tc := range []struct {
+		period     string
+		to         time.Time
+		start, end string
+	}{
+		{models.ReportDaily, at, "2026-03-12", "2026-03-13"},
+		{models.ReportWeekly, at, "2026-03-09", "2026-03-16"},
+		{models.ReportCustom, at.AddDate(0, 0, 3), "2026-03-12", "2026-03-16"},
+	} {
+		start, end, err := Bounds(tc.period, at, tc.to)
+		if err != nil || start.Format(time.DateOnly) != tc.start || end.Format(time.DateOnly) != tc.end {
+			t.Errorf("%s bounds = %v, %v, %v; want %s to %s", tc.period, start, end, err, tc.start, tc.end)
+		}
+	}
+	if _, _, err := Bounds(models.ReportCustom, at, at.AddDate(0, 0, -1)); !errors.Is(err, ErrInvalidPeriod) {
+		t.Errorf("custom period ending before it starts: err = %v", err)
+	}
+	if _, _, err := Bounds("monthly", at, at); !errors.Is(err, ErrInvalidPeriod) {
+		t.Errorf("unknown period: err = %v", err)
+	}
+}
+
+func TestScheduledReportsAreBuiltAndSentOnce(t *testing.T) {
+	ctx := context.Background()
+	clk := storagetest.NewFakeClock(time.Date(2026, 3, 11, 9, 0, 0, 0, time.UTC))
+	store := storagetest.NewMemoryStore(clk)
+	ana, _ := store.CreateUser(ctx, models.User{Username: "ana", Email: "ana@example.com", Role: models.NormalUser})
+	store.ApplyTransaction(ctx, models.Transaction{UserID: ana.ID, Amount: 200, Reason: models.TransactionDeposit})
+	store.ApplyTransaction(ctx, models.Transaction{UserID: ana.ID, Amount: -30, Reason: models.TransactionBetSettlement})
+	store.ApplyTransaction(ctx, models.Transaction{UserID: ana.ID, Amount: 12.5, Reason: models.TransactionBetSettlement})
+	clk.Advance(24 * time.Hour)
+
+	blobs, err := blob.NewLocal(t.TempDir(), "http://blobs.invalid", "key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	queue := jobs.NewQueue(1, 10, time.Millisecond)
+	notifier := &recordingNotifier{}
+	service := NewService(store, blobs, queue, notifier, clk, config.ReportsConfig{Recipients: []string{"ops@example.com"}, LinkTTL: time.Hour})
+	for range 2 {
+		if err := service.RunDue(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := queue.Close(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	list, _ := service.List(ctx, 10)
+	if len(list) != 2 {
+		t.Fatalf("reports = %+v, want one daily and one weekly", list)
+	}
+	// Newest first: the weekly report was queued after the daily one.
+	weekly, daily := list[0], list[1]
+	if daily.Period != models.ReportDaily || daily.Status != models.ReportReady || daily.CSVURL == "" || daily.PDFURL == "" {
+		t.Fatalf("daily report = %+v", daily)
+	}
+	want := models.ReportMetrics{Signups: 1, GGR: 17.5, Deposits: 1, DepositAmount: 200}
+	if *daily.Metrics != want {
+		t.Fatalf("daily metrics = %+v, want %+v", *daily.Metrics, want)
+	}
+	if weekly.Period != models.ReportWeekly || weekly.Metrics.Signups != 0 {
+		t.Fatalf("last week's report = %+v, want it to leave out this week", weekly)
+	}
+
+	read := func(key string) []byte {
+		t.Helper()
+		body, err := blobs.Get(ctx, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer body.Close()
+		data, err := io.ReadAll(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return data
+	}
+	if csvFile := read(csvKey(daily.ID)); !bytes.Contains(csvFile, []byte("daily,2026-03-11T00:00:00Z,2026-03-12T00:00:00Z,1,17.50,1,200.00,0,0.00")) {
+		t.Fatalf("daily CSV = %q", csvFile)
+	}
+	if pdfFile := read(pdfKey(daily.ID)); !bytes.HasPrefix(pdfFile, []byte("%PDF-")) {
+		t.Fatalf("daily PDF = %q", pdfFile)
+	}
+	if len(notifier.sent) != 2 || notifier.sent[0].To != "ops@example.com" || notifier.sent[0].Template != notify.TemplateOperatorReport {
+		t.Fatalf("sent = %+v, want each report emailed once", notifier.sent)
+	}
+}
@@ -0,0 +1,319 @@
+// Package seamless implements the seamless wallet game providers call while
+// a player is in one of their games: the player's balance stays here and the
+// provider asks for it, takes stakes from it and pays wins into it.
+//
+// Callbacks are JSON POSTs signed like our outgoing webhooks: the
+// X-Provider-Signature header has the form "t=<unix seconds>,v1=<hex
+// HMAC-SHA256>", where the MAC is computed with the provider's secret over
+// "<t>.<raw body>". Each names the launch token of a game session and one of
+// the actions below.
+//
+// Stakes, wins and rollbacks are keyed by the provider's transaction ID and
+// applied with wallet.Apply as bet settlements, so a retried callback gets
+// the original ledger entry back instead of moving the balance twice. A
+// rollback that arrives before its debit is recorded without a ledger entry,
+// and the debit is refused when it comes. Each stake taken feeds the jackpots
+// in the same transaction.
+package seamless
+
+import (
+	"context"
+	"crypto/hmac"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hongminglow/all-in-be/internal/clock"
+	"github.com/hongminglow/all-in-be/internal/events"
+	"github.com/hongminglow/all-in-be/internal/games"
+	"github.com/hongminglow/all-in-be/internal/integrations"
+	"github.com/hongminglow/all-in-be/internal/jackpots"
+	"github.com/hongminglow/all-in-be/internal/models"
+	"github.com/hongminglow/all-in-be/internal/outbox"
+	"github.com/hongminglow/all-in-be/internal/storage"
+	"github.com/hongminglow/all-in-be/internal/wallet"
+	"github.com/hongminglow/all-in-be/internal/webhook"
+)
+
+// SignatureHeader carries a callback's signature.
+const SignatureHeader = "X-Provider-Signature"
+
+// signatureTolerance is how far a callback's signing time may be from ours.
+const signatureTolerance = 5 * time.Minute
+
+// Actions a callback can ask for.
+const (
+	// ActionAuthenticate starts the session the launch token names and
+	// returns the player.
+	ActionAuthenticate = "authenticate"
+	ActionBalance      = "balance"
+	// ActionDebit takes a stake.
+	ActionDebit = "debit"
+	// ActionCredit pays a win; zero closes a lost round without a movement.
+	ActionCredit = "credit"
+	// ActionRollback returns the stake of the debit named by reference_id, or
+	// cancels that debit if it has not arrived yet.
+	ActionRollback = "rollback"
+)
+
+var (
+	// ErrRolledBack is returned for a debit whose rollback arrived first.
+	ErrRolledBack = errors.New("transaction was rolled back")
+	// ErrInvalidRequest is returned, wrapped with the reason, for a verified
+	// callback that cannot be acted on.
+	ErrInvalidRequest = errors.New("invalid callback")
+)
+
+// Reasons a callback is invalid.
+var (
+	ErrMalformed          = fmt.Errorf("%w: body is not a callback object", ErrInvalidRequest)
+	ErrUnknownAction      = fmt.Errorf("%w: unknown action", ErrInvalidRequest)
+	ErrInvalidAmount      = fmt.Errorf("%w: amount must be positive, or zero for a credit", ErrInvalidRequest)
+	ErrMissingTransaction = fmt.Errorf("%w: missing transaction_id, or reference_id for a rollback", ErrInvalidRequest)
+)
+
+// Request is the body of a callback.
+type Request struct {
+	Action string `json:"action"`
+	// Token is the launch token of the game session.
+	Token string `json:"token"`
+	// TransactionID is the provider's ID for a debit or credit.
+	TransactionID string `json:"transaction_id,omitempty"`
+	// ReferenceID is the transaction ID of the debit a rollback returns.
+	ReferenceID string  `json:"reference_id,omitempty"`
+	Amount      float64 `json:"amount,omitempty"`
+}
+
+// Result answers every callback.
+type Result struct {
+	UserID   int64  `json:"user_id"`
+	Username string `json:"username"`
+	Currency string `json:"currency"`
+	// Balance is what the player has available to stake: their balance less
+	// what wallet holds reserve.
+	Balance float64 `json:"balance"`
+	// TransactionID is the ledger entry a debit, credit or rollback produced.
+	TransactionID int64 `json:"transaction_id,omitempty"`
+	// Duplicate reports that the transaction was applied by an earlier
+	// callback, whose entry is returned.
+	Duplicate bool `json:"duplicate,omitempty"`
+}
+
+// Service answers game providers' wallet callbacks.
+type Service struct {
+	store    storage.UnitOfWork
+	sessions *games.Service
+	jackpots *jackpots.Service
+	outbox   *outbox.Relay
+	clock    clock.Clock
+	secrets  map[string]string
+	currency string
+}
+
+// NewService builds a service verifying callbacks with the providers'
+// secrets and reporting balances in currency. Stakes feed the jackpots in
+// pots. Balance changes are announced through the outbox relayed by relay,
+// which may be nil.
+func NewService(store storage.UnitOfWork, sessions *games.Service, pots *jackpots.Service, relay *outbox.Relay, clk clock.Clock, secrets map[string]string, currency string) *Service {
+	return &Service{store: store, sessions: sessions, jackpots: pots, outbox: relay, clock: clk, secrets: secrets, currency: currency}
+}
+
+// Receive verifies a callback from provider and acts on it in one
+// transaction. It returns integrations.ErrUnknownProvider for a provider
+// with no secret, integrations.ErrUnverified for a bad signature and
+// integrations.ErrSessionRejected, wrapped with the reason, for a callback
+// outside a live session.
+func (s *Service) Receive(ctx context.Context, provider string, header http.Header, payload []byte) (Result, error) {
+	secret, ok := s.secrets[provider]
+	if !ok {
+		return Result{}, integrations.ErrUnknownProvider
+	}
+	if err := s.verify(secret, header.Get(SignatureHeader), payload); err != nil {
+		return Result{}, fmt.Errorf("%w: %v", integrations.ErrUnverified, err)
+	}
+	var req Request
+	if err := json.Unmarshal(payload, &req); err != nil {
+		return Result{}, ErrMalformed
+	}
+	if err := req.validate(); err != nil {
+		return Result{}, err
+	}
+
+	var result Result
+	var applied, grew bool
+	err := s.store.WithTx(ctx, func(tx storage.Repositories) error {
+		session, err := s.sessions.Find(ctx, tx, provider, req.Token)
+		if err != nil {
+			return err
+		}
+		var saved models.Transaction
+		if saved, applied, err = s.apply(ctx, tx, provider, session, req); err != nil {
+			return err
+		}
+		if applied {
+			if err := outbox.Add(ctx, tx, events.TypeBalanceChanged, wallet.BalanceChanged(saved)); err != nil {
+				return err
+			}
+		}
+		if applied && req.Action == ActionDebit {
+			if grew, err = s.jackpots.Contribute(ctx, tx, saved.Amount, saved.Reference); err != nil {
+				return err
+			}
+		}
+		if result, err = s.result(ctx, tx, session.UserID); err != nil {
+			return err
+		}
+		result.TransactionID, result.Duplicate = saved.ID, saved.ID != 0 && !applied
+		return nil
+	})
+	if err != nil {
+		return Result{}, err
+	}
+	if applied {
+		s.outbox.Wake()
+	}
+	if grew {
+		s.jackpots.Notify()
+	}
+	return result, nil
+}
+
+// apply carries out the action in session. Authentication, balance requests
+// and new stakes need the session live and the stake within the player's
+// limits; wins and rollbacks settle bets already placed, so they are paid
+// into expired sessions too. A rollback of a debit not taken yet claims its
+// key without a ledger entry, which refuses the debit.
+func (s *Service) apply(ctx context.Context, tx storage.Repositories, provider string, session models.GameSession, req Request) (models.Transaction, bool, error) {
+	now := s.clock.Now()
+	switch req.Action {
+	case ActionAuthenticate, ActionBalance:
+		return models.Transaction{}, false, s.sessions.Check(ctx, tx, provider, req.Token, session.UserID, 0, now)
+	case ActionDebit:
+		op := wallet.Operation{Kind: models.OperationGameDebit, Key: provider + ":" + req.TransactionID}
+		_, err := tx.FindOperation(ctx, op.Kind, op.Key)
+		if errors.Is(err, storage.ErrNotFound) {
+			if _, err = tx.FindOperation(ctx, models.OperationGameRollback, op.Key); err == nil {
+				return models.Transaction{}, false, ErrRolledBack
+			}
+		}
+		if errors.Is(err, storage.ErrNotFound) {
+			err = s.sessions.Check(ctx, tx, provider, req.Token, session.UserID, -req.Amount, now)
+		}
+		if err != nil {
+			return models.Transaction{}, false, err
+		}
+		return wallet.Apply(ctx, tx, op, bet(session.UserID, -req.Amount, op.Key))
+	case ActionCredit:
+		if cents(req.Amount) == 0 {
+			return models.Transaction{}, false, nil
+		}
+		op := wallet.Operation{Kind: models.OperationGameCredit, Key: provider + ":" + req.TransactionID}
+		return wallet.Apply(ctx, tx, op, bet(session.UserID, req.Amount, op.Key))
+	default:
+		key := provider + ":" + req.ReferenceID
+		debit, err := tx.FindOperation(ctx, models.OperationGameDebit, key)
+		if errors.Is(err, storage.ErrNotFound) {
+			return models.Transaction{}, false, cancel(ctx, tx, session.UserID, key)
+		}
+		if err != nil {
+			return models.Transaction{}, false, fmt.Errorf("find debit %s: %w", key, err)
+		}
+		if debit.UserID != session.UserID {
+			return models.Transaction{}, false, games.ErrForeignSession
+		}
+		op := wallet.Operation{Kind: models.OperationGameRollback, Key: key}
+		return wallet.Apply(ctx, tx, op, bet(session.UserID, -debit.Amount, key))
+	}
+}
+
+// cancel records the rollback of a debit that has not arrived as a claim of
+// its rollback key with no amount and no ledger entry.
+func cancel(ctx context.Context, tx storage.Repositories, userID int64, key string) error {
+	err := tx.ClaimOperation(ctx, models.Operation{Kind: models.OperationGameRollback, Key: key, UserID: userID})
+	if errors.Is(err, storage.ErrAlreadyExists) {
+		prior, err := tx.FindOperation(ctx, models.OperationGameRollback, key)
+		if err != nil {
+			return fmt.Errorf("find rollback %s: %w", key, err)
+		}
+		if prior.UserID != userID {
+			return games.ErrForeignSession
+		}
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("claim rollback %s: %w", key, err)
+	}
+	return nil
+}
+
+// result reports the player and their available balance.
+func (s *Service) result(ctx context.Context, tx storage.Repositories, userID int64) (Result, error) {
+	user, err := tx.FindByID(ctx, userID)
+	if err != nil {
+		return Result{}, fmt.Errorf("find user %d: %w", userID, err)
+	}
+	held, err := tx.HeldBalance(ctx, userID)
+	if err != nil {
+		return Result{}, err
+	}
+	return Result{
+		UserID:   user.ID,
+		Username: user.Username,
+		Currency: s.currency,
+		Balance:  math.Round((user.Balance-held)*100) / 100,
+	}, nil
+}
+
+// verify checks the signature header against the body and the clock.
+func (s *Service) verify(secret, signature string, payload []byte) error {
+	ts, _, _ := strings.Cut(strings.TrimPrefix(signature, "t="), ",")
+	sent, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return errors.New("no signature timestamp")
+	}
+	if skew := s.clock.Now().Sub(time.Unix(sent, 0)); skew > signatureTolerance || skew < -signatureTolerance {
+		return fmt.Errorf("signature is %s off", skew.Round(time.Second))
+	}
+	if !hmac.Equal([]byte(signature), []byte(webhook.Sign(secret, sent, payload))) {
+		return errors.New("bad signature")
+	}
+	return nil
+}
+
+func (r Request) validate() error {
+	switch r.Action {
+	case ActionAuthenticate, ActionBalance:
+		return nil
+	case ActionDebit, ActionCredit:
+		if r.TransactionID == "" {
+			return ErrMissingTransaction
+		}
+		if math.IsNaN(r.Amount) || math.IsInf(r.Amount, 0) || cents(r.Amount) < 0 || r.Action == ActionDebit && cents(r.Amount) == 0 {
+			return ErrInvalidAmount
+		}
+		return nil
+	case ActionRollback:
+		if r.ReferenceID == "" {
+			return ErrMissingTransaction
+		}
+		return nil
+	default:
+		return ErrUnknownAction
+	}
+}
+
+// bet is a stake, win or returned stake in the ledger. They count towards
+// the player's daily loss limit like other bet settlements.
+func bet(userID int64, amount float64, key string) models.Transaction {
+	return models.Transaction{UserID: userID, Amount: amount, Reason: models.TransactionBetSettlement, Reference: key}
+}
+
+// cents compares amounts the way the NUMERIC(24,2) columns store them.
+func cents(amount float64) int64 {
+	return int64(math.Round(amount * 100))
+}
@@ -0,0 +1,145 @@
+package seamless
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/hongminglow/all-in-be/internal/config"
+	"github.com/hongminglow/all-in-be/internal/games"
+	"github.com/hongminglow/all-in-be/internal/integrations"
+	"github.com/hongminglow/all-in-be/internal/jackpots"
+	"github.com/hongminglow/all-in-be/internal/models"
+	"github.com/hongminglow/all-in-be/internal/storage"
+	"github.com/hongminglow/all-in-be/internal/storage/storagetest"
+	"github.com/hongminglow/all-in-be/internal/wallet"
+	"github.com/hongminglow/all-in-be/internal/webhook"
+)
+
+func TestCallbacksMoveTheLedgerOnce(t *testing.T) {
+	ctx := context.Background()
+	clk := storagetest.NewFakeClock(time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC))
+	store := storagetest.NewMemoryStore(clk)
+	ana, _ := store.CreateUser(ctx, models.User{Username: "ana", Email: "ana@example.com", Role: models.NormalUser, Balance: 100})
+	sessions := games.NewService(store, clk, config.GamesConfig{
+		Providers:  map[string]string{"reels": "https://reels.example/play"},
+		LaunchTTL:  2 * time.Minute,
+		SessionTTL: time.Hour,
+	})
+	pots := jackpots.NewService(store, nil, map[string]float64{"mega": 10})
+	service := NewService(store, sessions, pots, nil, clk, map[string]string{"reels": "s3cret"}, "USD")
+	ticket, err := sessions.Launch(ctx, ana, "reels:starburst")
+	if err != nil {
+		t.Fatal(err)
+	}
+	call := func(req Request) (Result, error) {
+		t.Helper()
+		body, _ := json.Marshal(req)
+		header := http.Header{SignatureHeader: {webhook.Sign("s3cret", clk.Now().Unix(), body)}}
+		return service.Receive(ctx, "reels", header, body)
+	}
+
+	body := []byte(`{"action":"balance"}`)
+	if _, err := service.Receive(ctx, "reels", http.Header{SignatureHeader: {webhook.Sign("wrong", clk.Now().Unix(), body)}}, body); !errors.Is(err, integrations.ErrUnverified) {
+		t.Fatalf("forged signature: err = %v, want ErrUnverified", err)
+	}
+	stale := http.Header{SignatureHeader: {webhook.Sign("s3cret", clk.Now().Add(-time.Hour).Unix(), body)}}
+	if _, err := service.Receive(ctx, "reels", stale, body); !errors.Is(err, integrations.ErrUnverified) {
+		t.Fatalf("stale signature: err = %v, want ErrUnverified", err)
+	}
+	if _, err := call(Request{Action: ActionDebit, Token: ticket.Token, TransactionID: "d1"}); !errors.Is(err, ErrInvalidAmount) {
+		t.Fatalf("debit of nothing: err = %v, want ErrInvalidAmount", err)
+	}
+
+	auth, err := call(Request{Action: ActionAuthenticate, Token: ticket.Token})
+	if err != nil || auth != (Result{UserID: ana.ID, Username: "ana", Currency: "USD", Balance: 100}) {
+		t.Fatalf("authenticate = %+v, %v", auth, err)
+	}
+	stake := Request{Action: ActionDebit, Token: ticket.Token, TransactionID: "d1", Amount: 30}
+	first, err := call(stake)
+	if err != nil || first.Balance != 70 || first.TransactionID == 0 || first.Duplicate {
+		t.Fatalf("debit = %+v, %v", first, err)
+	}
+	if retried, err := call(stake); err != nil || retried.TransactionID != first.TransactionID || !retried.Duplicate || retried.Balance != 70 {
+		t.Fatalf("retried debit = %+v, %v", retried, err)
+	}
+	stake.Amount = 40
+	if _, err := call(stake); !errors.Is(err, wallet.ErrOperationConflict) {
+		t.Fatalf("debit ID reused for another amount: err = %v, want ErrOperationConflict", err)
+	}
+	if _, err := call(Request{Action: ActionDebit, Token: ticket.Token, TransactionID: "d2", Amount: 500}); !errors.Is(err, storage.ErrInsufficientFunds) {
+		t.Fatalf("stake beyond the balance: err = %v, want ErrInsufficientFunds", err)
+	}
+
+	// Once the session ends no new stake is taken, but the round still
+	// settles.
+	clk.Advance(2 * time.Hour)
+	if _, err := call(Request{Action: ActionDebit, Token: ticket.Token, TransactionID: "d3", Amount: 5}); !errors.Is(err, games.ErrSessionExpired) {
+		t.Fatalf("stake after the session: err = %v, want ErrSessionExpired", err)
+	}
+	if win, err := call(Request{Action: ActionCredit, Token: ticket.Token, TransactionID: "c1", Amount: 12.5}); err != nil || win.Balance != 82.5 {
+		t.Fatalf("credit = %+v, %v", win, err)
+	}
+	if cancelled, err := call(Request{Action: ActionRollback, Token: ticket.Token, ReferenceID: "d2"}); err != nil || cancelled.TransactionID != 0 || cancelled.Balance != 82.5 {
+		t.Fatalf("rollback of a refused debit = %+v, %v, want no ledger entry", cancelled, err)
+	}
+	for range 2 {
+		if rolled, err := call(Request{Action: ActionRollback, Token: ticket.Token, ReferenceID: "d1"}); err != nil || rolled.Balance != 112.5 {
+			t.Fatalf("rollback = %+v, %v", rolled, err)
+		}
+	}
+
+	ledger, _ := store.ListTransactions(ctx, ana.ID)
+	if len(ledger) != 3 || ledger[0].Reason != models.TransactionBetSettlement || ledger[0].Reference != "reels:d1" {
+		t.Fatalf("ledger = %+v, want the stake, the win and the rollback", ledger)
+	}
+	// Only the stake taken fed the jackpot, once, and its rollback left the
+	// contribution in the pot.
+	if list, err := pots.List(ctx); err != nil || len(list) != 1 || list[0].Pot != 3 {
+		t.Fatalf("jackpots = %+v, %v, want 10%% of the one stake", list, err)
+	}
+}
+
+func TestRollbackBeforeItsDebitRefusesTheDebit(t *testing.T) {
+	ctx := context.Background()
+	clk := storagetest.NewFakeClock(time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC))
+	store := storagetest.NewMemoryStore(clk)
+	ana, _ := store.CreateUser(ctx, models.User{Username: "ana", Email: "ana@example.com", Role: models.NormalUser, Balance: 100})
+	bob, _ := store.CreateUser(ctx, models.User{Username: "bob", Email: "bob@example.com", Role: models.NormalUser, Balance: 100})
+	sessions := games.NewService(store, clk, config.GamesConfig{
+		Providers:  map[string]string{"reels": "https://reels.example/play"},
+		LaunchTTL:  2 * time.Minute,
+		SessionTTL: time.Hour,
+	})
+	service := NewService(store, sessions, jackpots.NewService(store, nil, nil), nil, clk, map[string]string{"reels": "s3cret"}, "USD")
+	call := func(user models.User, req Request) (Result, error) {
+		t.Helper()
+		ticket, err := sessions.Launch(ctx, user, "reels:starburst")
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Token = ticket.Token
+		body, _ := json.Marshal(req)
+		header := http.Header{SignatureHeader: {webhook.Sign("s3cret", clk.Now().Unix(), body)}}
+		return service.Receive(ctx, "reels", header, body)
+	}
+
+	// The provider timed the debit out and rolled it back before it got here.
+	for range 2 {
+		if cancelled, err := call(ana, Request{Action: ActionRollback, ReferenceID: "d1"}); err != nil || cancelled.TransactionID != 0 || cancelled.Balance != 100 {
+			t.Fatalf("rollback before the debit = %+v, %v", cancelled, err)
+		}
+	}
+	if _, err := call(bob, Request{Action: ActionRollback, ReferenceID: "d1"}); !errors.Is(err, games.ErrForeignSession) {
+		t.Fatalf("another player's rollback of the same debit: err = %v, want ErrForeignSession", err)
+	}
+	if _, err := call(ana, Request{Action: ActionDebit, TransactionID: "d1", Amount: 30}); !errors.Is(err, ErrRolledBack) {
+		t.Fatalf("late debit: err = %v, want ErrRolledBack", err)
+	}
+	if ledger, _ := store.ListTransactions(ctx, ana.ID); len(ledger) != 0 {
+		t.Fatalf("ledger = %+v, want nothing moved", ledger)
+	}
+}
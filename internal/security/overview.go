@@ -0,0 +1,145 @@
+package security
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/hongminglow/all-in-be/internal/models"
+)
+
+const (
+	// overviewLogins bounds the sign-in history scanned for sessions and events.
+	overviewLogins = 100
+	// overviewEvents caps the events returned.
+	overviewEvents = 20
+	// failedLoginWindow and failedLoginThreshold decide when repeated failed
+	// sign-ins warrant a password change.
+	failedLoginWindow    = 24 * time.Hour
+	failedLoginThreshold = 3
+	// sessionThreshold is how many concurrent sessions prompt a review.
+	sessionThreshold = 5
+)
+
+// Recommended actions.
+const (
+	ActionReviewLoginAlert = "review_login_alert"
+	ActionChangePassword   = "change_password"
+	ActionReviewSessions   = "review_sessions"
+)
+
+// Overview assembles the user's security state for the settings page.
+// Sessions are sign-ins within the last sessionTTL that were not revoked.
+func (s *Service) Overview(ctx context.Context, user models.User, sessionTTL time.Duration) (models.SecurityOverview, error) {
+	logins, err := s.store.ListLoginAttempts(ctx, models.LoginAttemptFilter{UserID: user.ID}, overviewLogins)
+	if err != nil {
+		return models.SecurityOverview{}, fmt.Errorf("list login attempts: %w", err)
+	}
+	devices, err := s.store.ListLoginDevices(ctx, user.ID)
+	if err != nil {
+		return models.SecurityOverview{}, fmt.Errorf("list login devices: %w", err)
+	}
+	alerts, err := s.store.ListLoginAlerts(ctx, user.ID, overviewEvents)
+	if err != nil {
+		return models.SecurityOverview{}, fmt.Errorf("list login alerts: %w", err)
+	}
+
+	now := s.clock.Now()
+	overview := models.SecurityOverview{
+		Sessions:           []models.ActiveSession{},
+		Devices:            devices,
+		AuthMethods:        authMethods(user),
+		RecentEvents:       []models.SecurityEvent{},
+		RecommendedActions: []models.SecurityRecommendation{},
+	}
+	if slices.Contains(s.cfg.ConfirmDeviceRoles, user.Role) {
+		overview.TwoFactor = models.TwoFactorStatus{Enabled: true, Method: "email_device_confirmation"}
+	}
+
+	failures := 0
+	for _, l := range logins {
+		if !l.Success {
+			overview.RecentEvents = append(overview.RecentEvents, models.SecurityEvent{
+				Type: models.SecurityEventLoginFailed, Detail: l.FailureReason, IP: l.IP, Country: l.Country, At: l.CreatedAt,
+			})
+			if now.Sub(l.CreatedAt) < failedLoginWindow {
+				failures++
+			}
+			continue
+		}
+		// Same second-precision allowance as middleware.Authenticate.
+		revoked := user.SessionsRevokedAt != nil && l.CreatedAt.Before(user.SessionsRevokedAt.Truncate(time.Second))
+		if expires := l.CreatedAt.Add(sessionTTL); !revoked && expires.After(now) {
+			overview.Sessions = append(overview.Sessions, models.ActiveSession{
+				IP: l.IP, UserAgent: l.UserAgent, Country: l.Country, SignedInAt: l.CreatedAt, ExpiresAt: expires,
+			})
+		}
+	}
+	// The device the account was first used from is not news.
+	first := -1
+	for i, d := range devices {
+		if first < 0 || d.FirstSeen.Before(devices[first].FirstSeen) {
+			first = i
+		}
+	}
+	for i, d := range devices {
+		if i != first {
+			overview.RecentEvents = append(overview.RecentEvents, models.SecurityEvent{
+				Type: models.SecurityEventNewDevice, Country: d.Country, At: d.FirstSeen,
+			})
+		}
+	}
+	pending := 0
+	for _, a := range alerts {
+		overview.RecentEvents = append(overview.RecentEvents, models.SecurityEvent{
+			Type: models.SecurityEventLoginAlert, Detail: a.Status, IP: a.IP, Country: a.Country, At: a.CreatedAt,
+		})
+		if a.Status == models.LoginAlertPending {
+			pending++
+		}
+	}
+	if user.SessionsRevokedAt != nil {
+		overview.RecentEvents = append(overview.RecentEvents, models.SecurityEvent{
+			Type: models.SecurityEventSessionsRevoked, At: *user.SessionsRevokedAt,
+		})
+	}
+	slices.SortStableFunc(overview.RecentEvents, func(a, b models.SecurityEvent) int {
+		return cmp.Compare(b.At.UnixNano(), a.At.UnixNano())
+	})
+	if len(overview.RecentEvents) > overviewEvents {
+		overview.RecentEvents = overview.RecentEvents[:overviewEvents]
+	}
+
+	if pending > 0 {
+		overview.RecommendedActions = append(overview.RecommendedActions, models.SecurityRecommendation{
+			Action: ActionReviewLoginAlert,
+			Reason: "a sign-in from a new device or country is waiting for you to approve or deny it",
+		})
+	}
+	if failures >= failedLoginThreshold {
+		overview.RecommendedActions = append(overview.RecommendedActions, models.SecurityRecommendation{
+			Action: ActionChangePassword,
+			Reason: fmt.Sprintf("%d failed sign-in attempts in the last 24 hours", failures),
+		})
+	}
+	if len(overview.Sessions) >= sessionThreshold {
+		overview.RecommendedActions = append(overview.RecommendedActions, models.SecurityRecommendation{
+			Action: ActionReviewSessions,
+			Reason: fmt.Sprintf("signed in from %d places; resetting your password signs out everywhere", len(overview.Sessions)),
+		})
+	}
+	return overview, nil
+}
+
+func authMethods(user models.User) []models.AuthMethod {
+	methods := []models.AuthMethod{}
+	if user.PasswordHash != "" {
+		methods = append(methods, models.AuthMethod{Type: models.AuthMethodPassword})
+	}
+	if user.Email != "" {
+		methods = append(methods, models.AuthMethod{Type: models.AuthMethodEmail, Detail: user.Email})
+	}
+	return methods
+}
@@ -0,0 +1,467 @@
+// Package security watches sign-ins for unfamiliar devices and countries. The
+// user is emailed a pair of links: approving just closes the alert, denying it
+// locks the account until the password is reset and opens a case for staff.
+// Users in roles listed in SecurityConfig.ConfirmDeviceRoles cannot sign in
+// from a new device at all until they follow an emailed confirmation link.
+package security
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"time"
+
+	"github.com/hongminglow/all-in-be/internal/clock"
+	"github.com/hongminglow/all-in-be/internal/config"
+	"github.com/hongminglow/all-in-be/internal/events"
+	"github.com/hongminglow/all-in-be/internal/models"
+	"github.com/hongminglow/all-in-be/internal/notify"
+	"github.com/hongminglow/all-in-be/internal/storage"
+)
+
+// Security case reasons.
+const (
+	// ReasonLoginDenied is recorded when a user denies a sign-in.
+	ReasonLoginDenied = "login_denied"
+	// ReasonForcedReset is recorded when staff force a password reset.
+	ReasonForcedReset = "forced_reset"
+)
+
+var (
+	// ErrAlertResolved is returned when an alert link is used after the alert
+	// was already approved or denied.
+	ErrAlertResolved = errors.New("login alert already resolved")
+	// ErrInvalidToken is returned for unknown or expired alert and reset tokens.
+	ErrInvalidToken = errors.New("invalid or expired token")
+)
+
+// Service raises login alerts and carries out the user's response to them.
+type Service struct {
+	store    storage.Store
+	notifier notify.Notifier
+	clock    clock.Clock
+	cfg      config.SecurityConfig
+	revoked  func()
+}
+
+// NewService builds a service that sends its emails through notifier.
+// revoked, if not nil, is called after sessions are revoked, so cached
+// session state can be dropped.
+func NewService(store storage.Store, notifier notify.Notifier, clk clock.Clock, cfg config.SecurityConfig, revoked func()) *Service {
+	return &Service{store: store, notifier: notifier, clock: clk, cfg: cfg, revoked: revoked}
+}
+
+// Device describes where a sign-in comes from.
+type Device struct {
+	// ID is the client-supplied X-Device-ID, if any.
+	ID             string
+	UserAgent      string
+	AcceptLanguage string
+	AcceptEncoding string
+	IP             string
+	Country        string
+	// StepUp asks for the device to be confirmed by email unless the user has
+	// signed in from it before, whatever their role; it is set when the
+	// network looks risky.
+	StepUp bool
+}
+
+// Fingerprint identifies the device by its client-supplied ID, falling back
+// to a hash of its User-Agent and Accept headers for clients that do not send one.
+func (d Device) Fingerprint() string {
+	if d.ID != "" {
+		return "id:" + hashToken(d.ID)[:32]
+	}
+	return "ua:" + hashToken(d.UserAgent + "\n" + d.AcceptLanguage + "\n" + d.AcceptEncoding)[:32]
+}
+
+// legacyFingerprint is the User-Agent-only fingerprint recorded before the
+// Accept headers were hashed in. Devices stored under it are still familiar.
+func (d Device) legacyFingerprint() string {
+	if d.ID != "" {
+		return ""
+	}
+	return "ua:" + hashToken(d.UserAgent)[:32]
+}
+
+// unfamiliar reports whether a user who has signed in from the known devices
+// before is now signing in from a new device or country. A user's first
+// sign-in is trusted.
+func unfamiliar(known []models.LoginDevice, device Device) bool {
+	if len(known) == 0 {
+		return false
+	}
+	fingerprint, legacy := device.Fingerprint(), device.legacyFingerprint()
+	newDevice := !slices.ContainsFunc(known, func(d models.LoginDevice) bool {
+		return d.Fingerprint == fingerprint || (legacy != "" && d.Fingerprint == legacy)
+	})
+	newCountry := device.Country != "" && !slices.ContainsFunc(known, func(d models.LoginDevice) bool { return d.Country == device.Country })
+	return newDevice || newCountry
+}
+
+// DeviceCheck is the outcome of CheckDevice.
+type DeviceCheck struct {
+	// New is set when the device or country is unfamiliar.
+	New bool
+	// ConfirmationRequired is set when a confirmation link was emailed and the
+	// sign-in must be refused.
+	ConfirmationRequired bool
+}
+
+// CheckDevice runs before a sign-in is accepted. It reports whether the device
+// is new to the user and, when the user's role requires new devices to be
+// confirmed, emails a confirmation link.
+func (s *Service) CheckDevice(ctx context.Context, user models.User, device Device) (DeviceCheck, error) {
+	known, err := s.store.ListLoginDevices(ctx, user.ID)
+	if err != nil {
+		return DeviceCheck{}, err
+	}
+	// A first sign-in is normally trusted, but not when stepping up.
+	if !unfamiliar(known, device) && !(device.StepUp && len(known) == 0) {
+		return DeviceCheck{}, nil
+	}
+	if !device.StepUp && !slices.Contains(s.cfg.ConfirmDeviceRoles, user.Role) {
+		return DeviceCheck{New: true}, nil
+	}
+	token, hash, err := newToken()
+	if err != nil {
+		return DeviceCheck{}, err
+	}
+	now := s.clock.Now()
+	if err := s.store.CreateDeviceConfirmation(ctx, models.DeviceConfirmation{
+		UserID:      user.ID,
+		Fingerprint: device.Fingerprint(),
+		Country:     device.Country,
+		TokenHash:   hash,
+		ExpiresAt:   now.Add(s.cfg.DeviceConfirmationTTL),
+	}); err != nil {
+		return DeviceCheck{}, fmt.Errorf("create device confirmation: %w", err)
+	}
+	err = s.notifier.Notify(ctx, notify.Notification{
+		Channel:  notify.ChannelEmail,
+		To:       user.Email,
+		Template: notify.TemplateDeviceConfirmation,
+		Data: map[string]any{
+			"Username":   user.Username,
+			"Time":       now.UTC().Format(time.RFC1123),
+			"Device":     fallback(device.UserAgent, "unknown device"),
+			"Country":    fallback(device.Country, "unknown"),
+			"IP":         device.IP,
+			"ExpiresIn":  s.cfg.DeviceConfirmationTTL.String(),
+			"ConfirmURL": s.cfg.PublicURL + "/device-confirmations/" + url.PathEscape(token),
+		},
+	})
+	if err != nil {
+		return DeviceCheck{}, err
+	}
+	return DeviceCheck{New: true, ConfirmationRequired: true}, nil
+}
+
+// ConfirmDevice consumes a device confirmation token and trusts its device,
+// so the user's next sign-in from it goes through.
+func (s *Service) ConfirmDevice(ctx context.Context, token string) error {
+	now := s.clock.Now()
+	c, err := s.store.ConsumeDeviceConfirmation(ctx, hashToken(token), now)
+	if errors.Is(err, storage.ErrNotFound) {
+		return ErrInvalidToken
+	}
+	if err != nil {
+		return err
+	}
+	return s.store.TouchLoginDevice(ctx, models.LoginDevice{
+		UserID:      c.UserID,
+		Fingerprint: c.Fingerprint,
+		Country:     c.Country,
+		LastSeen:    now,
+	})
+}
+
+// HandleLogin is the events.TypeUserLoggedIn consumer. A user's first sign-in
+// is trusted; after that, a device or country not seen before raises an alert.
+func (s *Service) HandleLogin(ctx context.Context, e events.Event) error {
+	var login events.UserLoggedIn
+	if err := e.Decode(&login); err != nil {
+		return err
+	}
+	known, err := s.store.ListLoginDevices(ctx, login.UserID)
+	if err != nil {
+		return err
+	}
+	device := Device{
+		ID:             login.DeviceID,
+		UserAgent:      login.UserAgent,
+		AcceptLanguage: login.AcceptLanguage,
+		AcceptEncoding: login.AcceptEncoding,
+		IP:             login.IP,
+		Country:        login.Country,
+	}
+	seen := models.LoginDevice{
+		UserID:      login.UserID,
+		Fingerprint: device.Fingerprint(),
+		Country:     login.Country,
+		LastSeen:    e.OccurredAt,
+	}
+	if unfamiliar(known, device) {
+		if err := s.raiseAlert(ctx, login, seen, e.OccurredAt); err != nil {
+			return err
+		}
+	}
+	return s.store.TouchLoginDevice(ctx, seen)
+}
+
+func (s *Service) raiseAlert(ctx context.Context, login events.UserLoggedIn, device models.LoginDevice, at time.Time) error {
+	token, hash, err := newToken()
+	if err != nil {
+		return err
+	}
+	if _, err := s.store.CreateLoginAlert(ctx, models.LoginAlert{
+		UserID:      login.UserID,
+		Fingerprint: device.Fingerprint,
+		Country:     device.Country,
+		IP:          login.IP,
+		UserAgent:   login.UserAgent,
+		TokenHash:   hash,
+		Status:      models.LoginAlertPending,
+	}); err != nil {
+		return fmt.Errorf("create login alert: %w", err)
+	}
+	base := s.cfg.PublicURL + "/login-alerts/" + url.PathEscape(token)
+	return s.notifier.Notify(ctx, notify.Notification{
+		Channel:  notify.ChannelEmail,
+		To:       login.Email,
+		Template: notify.TemplateLoginAlert,
+		Data: map[string]any{
+			"Username":   login.Username,
+			"Time":       at.UTC().Format(time.RFC1123),
+			"Device":     fallback(login.UserAgent, "unknown device"),
+			"Country":    fallback(login.Country, "unknown"),
+			"IP":         login.IP,
+			"ApproveURL": base + "/approve",
+			"DenyURL":    base + "/deny",
+		},
+	})
+}
+
+// Approve marks the alert's sign-in as legitimate.
+func (s *Service) Approve(ctx context.Context, token string) error {
+	alert, err := s.pendingAlert(ctx, token)
+	if err != nil {
+		return err
+	}
+	return s.resolve(ctx, s.store, alert, models.LoginAlertApproved)
+}
+
+// Deny treats the alert's sign-in as an intrusion: every session is revoked,
+// the account requires a password reset, a security case is opened, and the
+// user is emailed a reset link.
+func (s *Service) Deny(ctx context.Context, token string) error {
+	alert, err := s.pendingAlert(ctx, token)
+	if err != nil {
+		return err
+	}
+	now := s.clock.Now()
+	var user models.User
+	var resetToken string
+	err = s.store.WithTx(ctx, func(tx storage.Repositories) error {
+		if err := s.resolve(ctx, tx, alert, models.LoginAlertDenied); err != nil {
+			return err
+		}
+		if err := tx.LockAccount(ctx, alert.UserID, now); err != nil {
+			return fmt.Errorf("lock account: %w", err)
+		}
+		if _, err := tx.CreateSecurityCase(ctx, models.SecurityCase{
+			UserID:       alert.UserID,
+			LoginAlertID: &alert.ID,
+			Reason:       ReasonLoginDenied,
+			Status:       models.SecurityCaseOpen,
+		}); err != nil {
+			return fmt.Errorf("open security case: %w", err)
+		}
+		if user, err = tx.FindByID(ctx, alert.UserID); err != nil {
+			return err
+		}
+		resetToken, err = s.createReset(ctx, tx, alert.UserID)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	s.sessionsRevoked()
+	return s.sendReset(ctx, user, resetToken)
+}
+
+// ForcePasswordReset locks an account staff believe is compromised: every
+// session is revoked, the password stops working until it is reset, a
+// security case is opened, and the user is emailed a reset link.
+func (s *Service) ForcePasswordReset(ctx context.Context, userID int64) (models.SecurityCase, error) {
+	var user models.User
+	var opened models.SecurityCase
+	var resetToken string
+	err := s.store.WithTx(ctx, func(tx storage.Repositories) error {
+		if err := tx.LockAccount(ctx, userID, s.clock.Now()); err != nil {
+			return err
+		}
+		var err error
+		if opened, err = tx.CreateSecurityCase(ctx, models.SecurityCase{
+			UserID: userID,
+			Reason: ReasonForcedReset,
+			Status: models.SecurityCaseOpen,
+		}); err != nil {
+			return fmt.Errorf("open security case: %w", err)
+		}
+		if user, err = tx.FindByID(ctx, userID); err != nil {
+			return err
+		}
+		resetToken, err = s.createReset(ctx, tx, userID)
+		return err
+	})
+	if err != nil {
+		return models.SecurityCase{}, err
+	}
+	s.sessionsRevoked()
+	if err := s.sendReset(ctx, user, resetToken); err != nil {
+		return models.SecurityCase{}, err
+	}
+	return opened, nil
+}
+
+// RequestPasswordReset emails a reset link to the account matching identifier.
+// Unknown identifiers succeed silently so the endpoint cannot be used to probe
+// for accounts.
+func (s *Service) RequestPasswordReset(ctx context.Context, identifier string) error {
+	user, err := s.store.FindByUsernameOrEmail(ctx, identifier)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	token, err := s.createReset(ctx, s.store, user.ID)
+	if err != nil {
+		return err
+	}
+	return s.sendReset(ctx, user, token)
+}
+
+// ResetPassword consumes a reset token and stores passwordHash. Sessions
+// issued before the reset stop working.
+func (s *Service) ResetPassword(ctx context.Context, token, passwordHash string) error {
+	now := s.clock.Now()
+	err := s.store.WithTx(ctx, func(tx storage.Repositories) error {
+		reset, err := tx.ConsumePasswordReset(ctx, hashToken(token), now)
+		if errors.Is(err, storage.ErrNotFound) {
+			return ErrInvalidToken
+		}
+		if err != nil {
+			return err
+		}
+		return tx.SetPassword(ctx, reset.UserID, passwordHash, now)
+	})
+	if err != nil {
+		return err
+	}
+	s.sessionsRevoked()
+	return nil
+}
+
+// ChangePassword stores passwordHash for a signed-in user. Every session,
+// including the caller's, stops working.
+func (s *Service) ChangePassword(ctx context.Context, userID int64, passwordHash string) error {
+	if err := s.store.SetPassword(ctx, userID, passwordHash, s.clock.Now()); err != nil {
+		return err
+	}
+	s.sessionsRevoked()
+	return nil
+}
+
+// SignOut revokes every session of the user, as signing out does: tokens
+// are not stored, so the one being signed out cannot be told from the rest.
+func (s *Service) SignOut(ctx context.Context, userID int64) error {
+	if err := s.store.RevokeSessions(ctx, userID, s.clock.Now()); err != nil {
+		return err
+	}
+	s.sessionsRevoked()
+	return nil
+}
+
+func (s *Service) sessionsRevoked() {
+	if s.revoked != nil {
+		s.revoked()
+	}
+}
+
+func (s *Service) pendingAlert(ctx context.Context, token string) (models.LoginAlert, error) {
+	alert, err := s.store.FindLoginAlertByToken(ctx, hashToken(token))
+	if errors.Is(err, storage.ErrNotFound) {
+		return models.LoginAlert{}, ErrInvalidToken
+	}
+	if err != nil {
+		return models.LoginAlert{}, err
+	}
+	if alert.Status != models.LoginAlertPending {
+		return models.LoginAlert{}, ErrAlertResolved
+	}
+	return alert, nil
+}
+
+func (s *Service) resolve(ctx context.Context, repo storage.SecurityStore, alert models.LoginAlert, status string) error {
+	err := repo.ResolveLoginAlert(ctx, alert.ID, status, s.clock.Now())
+	if errors.Is(err, storage.ErrNotFound) {
+		// Both links were followed at once and the other request won.
+		return ErrAlertResolved
+	}
+	return err
+}
+
+func (s *Service) createReset(ctx context.Context, repo storage.SecurityStore, userID int64) (string, error) {
+	token, hash, err := newToken()
+	if err != nil {
+		return "", err
+	}
+	reset := models.PasswordReset{UserID: userID, TokenHash: hash, ExpiresAt: s.clock.Now().Add(s.cfg.PasswordResetTTL)}
+	if err := repo.CreatePasswordReset(ctx, reset); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+func (s *Service) sendReset(ctx context.Context, user models.User, token string) error {
+	return s.notifier.Notify(ctx, notify.Notification{
+		Channel:  notify.ChannelEmail,
+		To:       user.Email,
+		Template: notify.TemplatePasswordReset,
+		Data: map[string]any{
+			"Username":  user.Username,
+			"ExpiresIn": s.cfg.PasswordResetTTL.String(),
+			"ResetURL":  s.cfg.PasswordResetURL + "?token=" + url.QueryEscape(token),
+		},
+	})
+}
+
+// newToken returns a random URL-safe token and the hash stored in its place.
+func newToken() (token, hash string, err error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", fmt.Errorf("generate token: %w", err)
+	}
+	token = base64.RawURLEncoding.EncodeToString(buf)
+	return token, hashToken(token), nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func fallback(value, def string) string {
+	if value == "" {
+		return def
+	}
+	return value
+}
@@ -0,0 +1,233 @@
+// Package seed fills a development database with fake but realistic data:
+// players of every tier with deposits, bets, withdrawals and bonuses, plus an
+// admin and a staff account. The data depends only on the seed, so everyone
+// seeding with the same one gets the same users and ledgers, and seeding more
+// users keeps the ones a smaller run created.
+package seed
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"slices"
+
+	"github.com/hongminglow/all-in-be/internal/models"
+	"github.com/hongminglow/all-in-be/internal/storage"
+	"github.com/hongminglow/all-in-be/internal/wallet"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// MaxUsers bounds Options.Users; phone numbers run out past it.
+const MaxUsers = 100_000
+
+// Options controls what Run creates.
+type Options struct {
+	// Users is the number of players to create.
+	Users int
+	Seed  uint64
+	// Password is set on every seeded account.
+	Password string
+	// InitBalance is the balance accounts open with, as at sign-up.
+	InitBalance float64
+	// Transactions bounds the deposits, bets and withdrawals per player.
+	Transactions int
+}
+
+// Summary counts what Run created.
+type Summary struct {
+	Roles        int
+	Permissions  int
+	Users        int
+	Skipped      int
+	Transactions int
+	Bonuses      int
+}
+
+var (
+	firstNames = []string{"ana", "ben", "chloe", "daniel", "emma", "farid", "grace", "hafiz", "irene", "jun", "kavya", "liam", "mei", "nadia", "omar", "priya", "quinn", "rahul", "sofia", "tomas", "umar", "vera", "wei", "xin", "yusuf", "zara"}
+	lastNames  = []string{"tan", "lim", "lee", "wong", "ng", "chan", "kumar", "singh", "rahman", "ismail", "smith", "garcia", "muller", "rossi", "silva", "kim", "park", "nguyen", "sato", "khan"}
+	// winMultipliers are what a winning stake pays, weighted towards small wins.
+	winMultipliers = []float64{1, 1, 1, 2, 2, 3, 5, 10}
+)
+
+// errExists stops the unit of work for an account an earlier run created.
+var errExists = errors.New("account exists")
+
+// Run creates any missing built-in roles and permissions, then the accounts
+// and their activity. Accounts that already exist are skipped with everything
+// they would have been given, so Run can be repeated against the same database.
+func Run(ctx context.Context, store storage.Store, opts Options) (Summary, error) {
+	if opts.Users < 0 || opts.Users > MaxUsers {
+		return Summary{}, fmt.Errorf("users must be between 0 and %d", MaxUsers)
+	}
+	var summary Summary
+	var err error
+	if summary.Roles, summary.Permissions, err = ensureRoles(ctx, store); err != nil {
+		return summary, err
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(opts.Password), bcrypt.DefaultCost)
+	if err != nil {
+		return summary, fmt.Errorf("hash password: %w", err)
+	}
+
+	accounts := []models.User{
+		{Username: "seed_admin", Role: models.AdminUser},
+		{Username: "seed_staff", Role: models.StaffUser},
+	}
+	for i := range opts.Users {
+		accounts = append(accounts, player(rand.New(rand.NewPCG(opts.Seed, uint64(i))), i))
+	}
+	for i, account := range accounts {
+		account.Email = account.Username + "@example.com"
+		account.Phone = fmt.Sprintf("+6012%07d", i)
+		account.Balance = opts.InitBalance
+		account.PasswordHash = string(hash)
+		// Activity draws from a stream of its own so each player's ledger
+		// stays the same however many players a run creates.
+		r := rand.New(rand.NewPCG(opts.Seed, uint64(i)|1<<63))
+		var created activity
+		err := store.WithTx(ctx, func(tx storage.Repositories) error {
+			user, err := tx.CreateUser(ctx, account)
+			if errors.Is(err, storage.ErrAlreadyExists) {
+				return errExists
+			}
+			if err != nil {
+				return fmt.Errorf("create %s: %w", account.Username, err)
+			}
+			if !slices.Contains(models.PlayerRoles, user.Role) {
+				return nil
+			}
+			created, err = play(ctx, tx, r, opts, user)
+			return err
+		})
+		switch {
+		case errors.Is(err, errExists):
+			summary.Skipped++
+		case err != nil:
+			return summary, err
+		default:
+			summary.Users++
+			summary.Transactions += created.transactions
+			summary.Bonuses += created.bonuses
+		}
+	}
+	return summary, nil
+}
+
+// ensureRoles creates the built-in roles and permissions the migrations
+// normally insert, in case a database is missing some, and returns how many
+// it created.
+func ensureRoles(ctx context.Context, store storage.RoleStore) (roles, permissions int, err error) {
+	existingRoles, err := store.ListRoles(ctx)
+	if err != nil {
+		return 0, 0, fmt.Errorf("list roles: %w", err)
+	}
+	for _, name := range models.BuiltinRoles {
+		if slices.ContainsFunc(existingRoles, func(r models.Role) bool { return r.RoleName == name }) {
+			continue
+		}
+		if _, err := store.CreateRole(ctx, models.Role{RoleName: name}); err != nil {
+			return roles, permissions, fmt.Errorf("create role %s: %w", name, err)
+		}
+		roles++
+	}
+	existingPermissions, err := store.ListPermissions(ctx)
+	if err != nil {
+		return roles, 0, fmt.Errorf("list permissions: %w", err)
+	}
+	for _, name := range models.BuiltinPermissions {
+		if slices.ContainsFunc(existingPermissions, func(p models.Permission) bool { return p.PermissionName == name }) {
+			continue
+		}
+		if _, err := store.CreatePermission(ctx, models.Permission{PermissionName: name}); err != nil {
+			return roles, permissions, fmt.Errorf("create permission %s: %w", name, err)
+		}
+		permissions++
+	}
+	return roles, permissions, nil
+}
+
+// player makes up the i-th player: mostly regular players, some VIPs and a
+// few VVIPs.
+func player(r *rand.Rand, i int) models.User {
+	role := models.NormalUser
+	switch tier := r.IntN(10); {
+	case tier == 9:
+		role = models.VVIPUser
+	case tier >= 7:
+		role = models.VIPUser
+	}
+	first := firstNames[r.IntN(len(firstNames))]
+	last := lastNames[r.IntN(len(lastNames))]
+	return models.User{Username: fmt.Sprintf("%s_%s%d", first, last, i+1), Role: role}
+}
+
+type activity struct {
+	transactions int
+	bonuses      int
+}
+
+// play grants the player's bonuses, higher tiers getting more, then runs
+// through their deposits, bets and withdrawals. Debits the balance cannot
+// cover are left out, as the real endpoints would refuse them.
+func play(ctx context.Context, tx storage.Repositories, r *rand.Rand, opts Options, user models.User) (activity, error) {
+	var done activity
+	balance := user.Balance
+	key := func(n int) string { return fmt.Sprintf("seed:%d:%s:%d", opts.Seed, user.Username, n) }
+
+	bonuses := r.IntN(2)
+	switch user.Role {
+	case models.VIPUser:
+		bonuses = 1 + r.IntN(2)
+	case models.VVIPUser:
+		bonuses = 1 + r.IntN(3)
+	}
+	for n := range bonuses {
+		entry, _, err := wallet.Apply(ctx, tx, wallet.Operation{Kind: models.OperationBonusGrant, Key: key(n)}, models.Transaction{
+			UserID:    user.ID,
+			Amount:    float64(10 * (1 + r.IntN(20))),
+			Reason:    models.TransactionBonusGrant,
+			Reference: "seed",
+		})
+		if err != nil {
+			return done, fmt.Errorf("grant bonus to %s: %w", user.Username, err)
+		}
+		balance = entry.BalanceAfter
+		done.bonuses++
+	}
+
+	for n := range r.IntN(opts.Transactions + 1) {
+		entry := models.Transaction{UserID: user.ID, Reference: key(bonuses + n)}
+		switch p := r.IntN(100); {
+		case p < 15:
+			entry.Amount = float64(50 * (1 + r.IntN(40)))
+			entry.Reason = models.TransactionDeposit
+		case p < 25:
+			entry.Amount = -float64(50 * (1 + r.IntN(20)))
+			entry.Reason = models.TransactionWithdrawal
+		default:
+			stake := float64(10 * (1 + r.IntN(50)))
+			entry.Amount = -stake
+			if r.IntN(100) < 45 {
+				entry.Amount = stake * winMultipliers[r.IntN(len(winMultipliers))]
+			}
+			entry.Reason = models.TransactionBetSettlement
+		}
+		if balance+entry.Amount < 0 {
+			continue
+		}
+		var err error
+		if entry.Reason == models.TransactionBetSettlement {
+			entry, _, err = wallet.Apply(ctx, tx, wallet.Operation{Kind: models.OperationBetSettlement, Key: entry.Reference}, entry)
+		} else {
+			entry, err = tx.ApplyTransaction(ctx, entry)
+		}
+		if err != nil {
+			return done, fmt.Errorf("record activity for %s: %w", user.Username, err)
+		}
+		balance = entry.BalanceAfter
+		done.transactions++
+	}
+	return done, nil
+}
@@ -0,0 +1,76 @@
+package seed
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/hongminglow/all-in-be/internal/models"
+	"github.com/hongminglow/all-in-be/internal/storage/storagetest"
+)
+
+func TestRunIsDeterministicAndRepeatable(t *testing.T) {
+	ctx := context.Background()
+	opts := Options{Users: 20, Seed: 7, Password: "seed-password", InitBalance: 1000, Transactions: 15}
+	newStore := func() *storagetest.MemoryStore {
+		return storagetest.NewMemoryStore(storagetest.NewFakeClock(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)))
+	}
+	first, second := newStore(), newStore()
+
+	summary, err := Run(ctx, first, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if summary.Users != 22 || summary.Skipped != 0 || summary.Transactions == 0 || summary.Bonuses == 0 {
+		t.Fatalf("summary = %+v", summary)
+	}
+	opts.Users = 25
+	if _, err := Run(ctx, second, opts); err != nil {
+		t.Fatal(err)
+	}
+
+	users, err := first.SearchUsers(ctx, "@example.com", nil, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(users) != 22 {
+		t.Fatalf("seeded %d users, want 22", len(users))
+	}
+	for _, result := range users {
+		u := result.User
+		other, err := second.FindByUsername(ctx, u.Username)
+		if err != nil {
+			t.Fatalf("%s missing from the larger run: %v", u.Username, err)
+		}
+		a, _ := first.ListTransactions(ctx, u.ID)
+		b, _ := second.ListTransactions(ctx, other.ID)
+		if len(a) != len(b) {
+			t.Fatalf("%s: %d entries, then %d", u.Username, len(a), len(b))
+		}
+		balance := opts.InitBalance
+		for j := range a {
+			if a[j].Amount != b[j].Amount || a[j].Reason != b[j].Reason {
+				t.Fatalf("%s entry %d: %+v, then %+v", u.Username, j, a[j], b[j])
+			}
+			balance += a[j].Amount
+			if balance < 0 {
+				t.Fatalf("%s went overdrawn", u.Username)
+			}
+		}
+		if mine, _ := first.FindByID(ctx, u.ID); mine.Balance != balance {
+			t.Fatalf("%s balance = %v, ledger sums to %v", u.Username, mine.Balance, balance)
+		}
+	}
+
+	again, err := Run(ctx, first, Options{Users: 20, Seed: 7, Password: "seed-password", InitBalance: 1000, Transactions: 15})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.Users != 0 || again.Skipped != 22 || again.Transactions != 0 {
+		t.Fatalf("second run = %+v, want every account skipped", again)
+	}
+	admin, err := first.FindByUsername(ctx, "seed_admin")
+	if err != nil || admin.Role != models.AdminUser {
+		t.Fatalf("seed_admin = %+v, %v", admin, err)
+	}
+}
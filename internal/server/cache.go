@@ -0,0 +1,25 @@
+package server
+
+import (
+	"context"
+	"time"
+
+	"github.com/hongminglow/all-in-be/internal/cache"
+	"github.com/hongminglow/all-in-be/internal/config"
+)
+
+// attachCacheTransport connects the configured invalidation transport to
+// invalidator. It returns nil when invalidations stay on this instance.
+func attachCacheTransport(cfg config.CacheConfig, invalidator *cache.Invalidator) (cache.Transport, error) {
+	if cfg.Backend != "redis" {
+		return nil, nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	redis, err := cache.NewRedis(ctx, cache.RedisConfig{URL: cfg.RedisURL, Channel: cfg.RedisChannel}, invalidator.Receive)
+	if err != nil {
+		return nil, err
+	}
+	invalidator.Attach(redis)
+	return redis, nil
+}
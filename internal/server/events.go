@@ -0,0 +1,102 @@
+package server
+
+import (
+	"context"
+	"time"
+
+	"github.com/hongminglow/all-in-be/internal/config"
+	"github.com/hongminglow/all-in-be/internal/events"
+	"github.com/hongminglow/all-in-be/internal/feed"
+	"github.com/hongminglow/all-in-be/internal/jobs"
+	"github.com/hongminglow/all-in-be/internal/models"
+	"github.com/hongminglow/all-in-be/internal/onboarding"
+	"github.com/hongminglow/all-in-be/internal/security"
+	"github.com/hongminglow/all-in-be/internal/webhook"
+)
+
+// newEventBus builds the configured event bus; handlers run on queue.
+func newEventBus(cfg config.EventsConfig, region string, queue *jobs.Queue, d deps) (events.Bus, error) {
+	if cfg.Backend != "nats" {
+		return events.NewInProcess(queue, d.clock, d.ids, region), nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	return events.NewNATS(ctx, events.NATSConfig{
+		URL:           cfg.NATSURL,
+		SubjectPrefix: cfg.NATSSubjectPrefix,
+		QueueGroup:    cfg.NATSQueueGroup,
+	}, queue, d.clock, d.ids, region)
+}
+
+// subscribeConsumers connects domain events to the subsystems that react to them.
+func subscribeConsumers(bus events.Subscriber, journeys *onboarding.Service, webhooks webhook.Publisher, logins *security.Service, streams *feed.Service) error {
+	if err := journeys.Subscribe(bus); err != nil {
+		return err
+	}
+
+	if err := bus.Subscribe(events.TypeUserRegistered, func(ctx context.Context, e events.Event) error {
+		var user events.UserRegistered
+		if err := e.Decode(&user); err != nil {
+			return err
+		}
+		return webhooks.Publish(ctx, models.EventUserCreated, map[string]any{
+			"user_id":  user.UserID,
+			"username": user.Username,
+			"email":    user.Email,
+		})
+	}); err != nil {
+		return err
+	}
+
+	if err := bus.Subscribe(events.TypeUserLoggedIn, logins.HandleLogin); err != nil {
+		return err
+	}
+
+	if err := bus.Subscribe(events.TypeUserLoggedIn, func(ctx context.Context, e events.Event) error {
+		var login events.UserLoggedIn
+		if err := e.Decode(&login); err != nil {
+			return err
+		}
+		if !login.NewDevice {
+			return nil
+		}
+		return webhooks.Publish(ctx, models.EventUserNewDevice, map[string]any{
+			"user_id":    login.UserID,
+			"username":   login.Username,
+			"ip":         login.IP,
+			"country":    login.Country,
+			"user_agent": login.UserAgent,
+		})
+	}); err != nil {
+		return err
+	}
+
+	// Streams poll for movements anyway; this only gets them there sooner.
+	if err := bus.Subscribe(events.TypeBalanceChanged, func(ctx context.Context, e events.Event) error {
+		var change events.BalanceChanged
+		if err := e.Decode(&change); err != nil {
+			return err
+		}
+		streams.Notify(change.UserID)
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	return bus.Subscribe(events.TypeBalanceChanged, func(ctx context.Context, e events.Event) error {
+		var change events.BalanceChanged
+		if err := e.Decode(&change); err != nil {
+			return err
+		}
+		var event string
+		switch change.Reason {
+		case events.ReasonDeposit:
+			event = models.EventWalletDeposit
+		case events.ReasonWithdrawal:
+			event = models.EventWalletWithdraw
+		default:
+			return nil
+		}
+		return webhooks.Publish(ctx, event, change)
+	})
+}
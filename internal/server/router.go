@@ -0,0 +1,51 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/hongminglow/all-in-be/internal/middleware"
+)
+
+// Middleware decorates an http.Handler.
+type Middleware func(http.Handler) http.Handler
+
+// Router registers routes on a shared ServeMux, wrapping each one in the
+// middleware stack of the group it was registered through.
+type Router struct {
+	mux   *http.ServeMux
+	chain []Middleware
+}
+
+// NewRouter returns a root router with an empty middleware stack.
+func NewRouter(mux *http.ServeMux) *Router {
+	return &Router{mux: mux}
+}
+
+// Use appends middleware to this router's stack. It only affects routes registered afterwards.
+func (r *Router) Use(mw ...Middleware) {
+	r.chain = append(r.chain, mw...)
+}
+
+// Group returns a child router that inherits the current stack followed by mw.
+func (r *Router) Group(mw ...Middleware) *Router {
+	chain := make([]Middleware, 0, len(r.chain)+len(mw))
+	chain = append(chain, r.chain...)
+	chain = append(chain, mw...)
+	return &Router{mux: r.mux, chain: chain}
+}
+
+// Handle registers handler for pattern behind the group's middleware.
+// The first middleware in the stack is the outermost. Routes the handler does
+// not gate with middleware.RequirePermission refuse scoped callers.
+func (r *Router) Handle(pattern string, handler http.Handler) {
+	handler = middleware.ScopeGate(handler)
+	for i := len(r.chain) - 1; i >= 0; i-- {
+		handler = r.chain[i](handler)
+	}
+	r.mux.Handle(pattern, handler)
+}
+
+// HandleFunc registers fn for pattern behind the group's middleware.
+func (r *Router) HandleFunc(pattern string, fn func(http.ResponseWriter, *http.Request)) {
+	r.Handle(pattern, http.HandlerFunc(fn))
+}
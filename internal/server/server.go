@@ -2,42 +2,563 @@ package server
 
 import (
 	"context"
+	"fmt"
+	"log"
+	"net"
 	"net/http"
 	"time"
 
+	"github.com/hongminglow/all-in-be/internal/accounts"
+	"github.com/hongminglow/all-in-be/internal/archive"
 	"github.com/hongminglow/all-in-be/internal/auth"
+	"github.com/hongminglow/all-in-be/internal/blob"
+	"github.com/hongminglow/all-in-be/internal/bulk"
+	"github.com/hongminglow/all-in-be/internal/cache"
+	"github.com/hongminglow/all-in-be/internal/captcha"
+	"github.com/hongminglow/all-in-be/internal/challenge"
+	"github.com/hongminglow/all-in-be/internal/changelog"
+	"github.com/hongminglow/all-in-be/internal/chaos"
+	"github.com/hongminglow/all-in-be/internal/clock"
 	"github.com/hongminglow/all-in-be/internal/config"
+	"github.com/hongminglow/all-in-be/internal/dataexport"
+	"github.com/hongminglow/all-in-be/internal/dbinsights"
+	"github.com/hongminglow/all-in-be/internal/emailcheck"
+	"github.com/hongminglow/all-in-be/internal/events"
+	"github.com/hongminglow/all-in-be/internal/feed"
+	"github.com/hongminglow/all-in-be/internal/games"
+	"github.com/hongminglow/all-in-be/internal/geoip"
 	"github.com/hongminglow/all-in-be/internal/http/handlers"
+	"github.com/hongminglow/all-in-be/internal/http/respond"
+	"github.com/hongminglow/all-in-be/internal/i18n"
+	"github.com/hongminglow/all-in-be/internal/inbox"
+	"github.com/hongminglow/all-in-be/internal/integrations"
+	"github.com/hongminglow/all-in-be/internal/iprisk"
+	"github.com/hongminglow/all-in-be/internal/jackpots"
+	"github.com/hongminglow/all-in-be/internal/jobs"
+	"github.com/hongminglow/all-in-be/internal/leaderboard"
+	"github.com/hongminglow/all-in-be/internal/limits"
 	"github.com/hongminglow/all-in-be/internal/middleware"
+	"github.com/hongminglow/all-in-be/internal/models"
+	"github.com/hongminglow/all-in-be/internal/money"
+	"github.com/hongminglow/all-in-be/internal/notify"
+	"github.com/hongminglow/all-in-be/internal/oidc"
+	"github.com/hongminglow/all-in-be/internal/onboarding"
+	"github.com/hongminglow/all-in-be/internal/outbox"
+	"github.com/hongminglow/all-in-be/internal/payments"
+	"github.com/hongminglow/all-in-be/internal/promo"
+	"github.com/hongminglow/all-in-be/internal/reconcile"
+	"github.com/hongminglow/all-in-be/internal/reports"
+	"github.com/hongminglow/all-in-be/internal/seamless"
+	"github.com/hongminglow/all-in-be/internal/security"
 	"github.com/hongminglow/all-in-be/internal/storage"
+	"github.com/hongminglow/all-in-be/internal/support"
+	"github.com/hongminglow/all-in-be/internal/tournaments"
+	"github.com/hongminglow/all-in-be/internal/usernames"
+	"github.com/hongminglow/all-in-be/internal/wallet"
+	"github.com/hongminglow/all-in-be/internal/webhook"
 )
 
 // Server wraps an http.Server with configured routes.
 type Server struct {
-	inner *http.Server
+	inner      *http.Server
+	blobs      blob.Store
+	events     events.Bus
+	outbox     *outbox.Relay
+	caches     cache.Transport
+	jobs       *jobs.Queue
+	cors       *middleware.ReloadableCORS
+	rateLimits *middleware.RateLimitPolicies
+	archiver   *archive.Service
+	bulkJobs   *bulk.Runner
+	reconciler *reconcile.Service
+	reports    *reports.Service
+	contests   *tournaments.Service
+	standings  *leaderboard.Service
+	advisor    *dbinsights.Service
+	flags      *handlers.FeatureFlagHandler
+}
+
+// Option overrides one of the server's runtime dependencies.
+type Option func(*deps)
+
+// deps holds runtime dependencies that tests may replace.
+type deps struct {
+	clock clock.Clock
+	ids   clock.IDGenerator
+	email notify.EmailSender
+	sms   notify.SMSSender
+	// processors handle inbound provider callbacks, keyed by provider name.
+	processors map[string]integrations.Processor
+	// payments are the payment provider drivers, keyed by provider name.
+	payments map[string]payments.Provider
+	ipIntel  iprisk.Provider
+	mx       emailcheck.Resolver
+}
+
+// WithClock replaces the wall clock, e.g. with a fake in tests.
+func WithClock(c clock.Clock) Option {
+	return func(d *deps) { d.clock = c }
+}
+
+// WithIDGenerator replaces the random UUID generator.
+func WithIDGenerator(g clock.IDGenerator) Option {
+	return func(d *deps) { d.ids = g }
+}
+
+// WithEmailSender overrides the configured email provider.
+func WithEmailSender(sender notify.EmailSender) Option {
+	return func(d *deps) { d.email = sender }
+}
+
+// WithSMSSender overrides the configured SMS provider.
+func WithSMSSender(sender notify.SMSSender) Option {
+	return func(d *deps) { d.sms = sender }
+}
+
+// WithIPIntelligence screens client IPs with provider instead of the
+// configured risky network list.
+func WithIPIntelligence(provider iprisk.Provider) Option {
+	return func(d *deps) { d.ipIntel = provider }
+}
+
+// WithMXResolver replaces the DNS resolver sign-up email domains are looked
+// up with when EMAIL_CHECK_MX is on.
+func WithMXResolver(r emailcheck.Resolver) Option {
+	return func(d *deps) { d.mx = r }
+}
+
+// WithProcessor registers the callback processor for an external provider,
+// served at /integrations/{provider}/callbacks.
+func WithProcessor(provider string, p integrations.Processor) Option {
+	return func(d *deps) { d.processors[provider] = p }
+}
+
+// WithPaymentProvider registers the driver for a payment provider. Its
+// deposits start at /payments/{provider}/deposits and its callbacks are
+// served at /integrations/{provider}/callbacks.
+func WithPaymentProvider(provider string, p payments.Provider) Option {
+	return func(d *deps) { d.payments[provider] = p }
 }
 
 // New wires up middleware, routes, and returns a ready server.
-func New(cfg config.Config, store storage.UserStore) *Server {
+func New(cfg config.Config, store storage.Store, opts ...Option) (*Server, error) {
+	d := deps{clock: clock.System{}, ids: clock.UUID{}, mx: net.DefaultResolver, processors: map[string]integrations.Processor{}, payments: map[string]payments.Provider{}}
+	if cfg.Payments.SandboxSecret != "" {
+		d.payments["sandbox"] = payments.NewSandbox(cfg.Payments.SandboxSecret)
+	}
+	for _, opt := range opts {
+		opt(&d)
+	}
+	for name, p := range d.payments {
+		if _, ok := d.processors[name]; ok {
+			return nil, fmt.Errorf("provider %q has both a callback processor and a payment driver", name)
+		}
+		d.processors[name] = payments.NewProcessor(name, p, d.clock)
+	}
+
 	mux := http.NewServeMux()
-	health := handlers.NewHealthHandler(time.Now())
-	health.Register(mux)
-	tokenManager := auth.NewTokenManager(cfg.JWTSecret, cfg.JWTIssuer, cfg.JWTTTL)
-	auth := handlers.NewAuthHandler(store, tokenManager, &cfg)
-	auth.Register(mux)
+	router := NewRouter(mux)
+	retiring := make([]auth.SigningKey, 0, len(cfg.JWT.Previous))
+	for _, key := range cfg.JWT.Previous {
+		retiring = append(retiring, auth.SigningKey{ID: key.ID, Secret: key.Secret})
+	}
+	tokenManager := auth.NewTokenManager(auth.SigningKey{ID: cfg.JWT.KeyID, Secret: cfg.JWT.Secret}, retiring, cfg.JWT.Issuer, cfg.Region.Name, cfg.JWT.TTL, d.clock, d.ids)
+	apiKeys := auth.NewAPIKeys(store, d.clock)
+	impersonations := auth.NewImpersonations(store, store, tokenManager)
+	// Requests resolve to the tenant in the caller's token; anonymous ones, and
+	// every one until users belong to tenants, to the default tenant.
+	rateLimits := middleware.NewRateLimitPolicies(store, cfg.RateLimit.PolicyTTL, middleware.TokenTenant, authFallback(cfg.RateLimit))
+	// Caches register here so changes made on one instance reach them all.
+	caches := cache.NewInvalidator(d.ids.NewID())
+	caches.Handle(cache.RateLimits, rateLimits.Invalidate)
+	sessions := auth.NewSessions(store, d.clock, cfg.JWT.SessionCacheTTL)
+	caches.Handle(cache.Sessions, sessions.Invalidate)
+
+	public := router.Group()
+	health := handlers.NewHealthHandler(d.clock.Now())
+	health.Register(public)
+	handlers.NewReadinessHandler(store).Register(public)
+	releases, err := changelog.Load()
+	if err != nil {
+		return nil, err
+	}
+	handlers.NewChangelogHandler(releases).Register(public)
+	handlers.NewErrorCatalogHandler(respond.ErrorCatalog).Register(public)
+	handlers.NewRegionHandler(cfg.Region).Register(public)
+	spectator := handlers.NewSpectatorHandler(store, cfg.Spectator.BigWinMin, cfg.Spectator.CacheTTL)
+	spectator.Register(public)
+	caches.Handle(cache.BigWins, spectator.Invalidate)
+	blobs, err := newBlobStore(cfg.Blob, public)
+	if err != nil {
+		return nil, err
+	}
+	locator, err := newCountryLocator(cfg.GeoIP)
+	if err != nil {
+		return nil, err
+	}
+	formatter, err := money.NewFormatter(cfg.Money.Currency, cfg.Money.DefaultLocale, cfg.Money.Locales)
+	if err != nil {
+		return nil, err
+	}
+	translator, err := i18n.Load()
+	if err != nil {
+		return nil, err
+	}
+
+	queueOpts := []jobs.Option{jobs.WithMaxWait(cfg.Jobs.MaxWait)}
+	for p, n := range cfg.Jobs.Reserved {
+		queueOpts = append(queueOpts, jobs.WithReservedWorkers(p, n))
+	}
+	queue := jobs.NewQueue(cfg.Jobs.Workers, cfg.Jobs.QueueSize, time.Second, queueOpts...)
+	metrics := handlers.NewMetricsHandler(store, queue, store, store, cfg.BusinessMetricsTTL)
+	metrics.Register(public)
+	notifications, err := newNotifier(cfg.Notify, queue, d)
+	if err != nil {
+		return nil, err
+	}
+	webhooks := webhook.NewDispatcher(store, queue, nil, d.clock, d.ids)
+	bus, err := newEventBus(cfg.Events, cfg.Region.Name, queue, d)
+	if err != nil {
+		return nil, err
+	}
+	relay := outbox.NewRelay(store, bus, d.clock, cfg.Events)
+	logins := security.NewService(store, notifications, d.clock, cfg.Security, caches.Func(cache.Sessions))
+	challenges := challenge.NewService(store, notifications, d.clock, cfg.Challenges)
+	journeys := onboarding.NewService(store, notifications, d.clock, cfg.Onboarding.NudgeInterval)
+	streams := feed.NewService(store)
+	if err := subscribeConsumers(bus, journeys, webhooks, logins, streams); err != nil {
+		bus.Close()
+		return nil, err
+	}
+
+	ipIntel, err := newIPIntelligence(cfg.IPRisk, d)
+	if err != nil {
+		bus.Close()
+		return nil, err
+	}
+	screen := iprisk.NewScreen(ipIntel, store, cfg.IPRisk.DefaultAction, cfg.IPRisk.PolicyTTL)
+	caches.Handle(cache.IPRisk, screen.Invalidate)
 
-	handler := middleware.CORS(cfg.CORSOrigins, middleware.Logging(mux))
+	limited := public.Group(func(next http.Handler) http.Handler {
+		return middleware.PolicyRateLimit(rateLimits, models.RouteClassAuth, next)
+	})
+	names, err := newUsernamePolicy(cfg.Usernames, store)
+	if err != nil {
+		bus.Close()
+		return nil, err
+	}
+	emails := newEmailChecker(cfg.EmailCheck, d)
+	caches.Handle(cache.DisposableEmails, emails.Expire)
+	users := accounts.NewService(store, logins, screen, names, emails, newCaptchaGate(cfg.Captcha, store, d), tokenManager, bus, relay, &cfg)
+	auth := handlers.NewAuthHandler(users, logins, &cfg)
+	auth.Register(limited)
+	auth.RegisterLogout(limited.Group(func(next http.Handler) http.Handler {
+		return middleware.Identify(tokenManager, sessions, next)
+	}))
+	// Sign-up and deposits are refused in blocked jurisdictions.
+	restricted := limited.Group(func(next http.Handler) http.Handler {
+		return middleware.BlockCountries(cfg.GeoIP.BlockedCountries, next)
+	})
+	auth.RegisterSignup(restricted.Group(func(next http.Handler) http.Handler {
+		return middleware.ScreenIP(screen, nil, models.CheckpointRegistration, next)
+	}))
+	handlers.NewPasswordResetHandler(logins).Register(limited)
+	handlers.NewLoginAlertHandler(logins).Register(public)
+	gameSessions := games.NewService(store, d.clock, cfg.Games)
+	callbacks := integrations.NewService(store, d.clock, d.processors, gameSessions)
+	handlers.NewCallbackHandler(callbacks).Register(public)
+	pots := jackpots.NewService(store, relay, cfg.Games.Jackpots)
+	jackpotsHandler := handlers.NewJackpotHandler(pots, cfg.Events)
+	jackpotsHandler.Register(public)
+	handlers.NewSeamlessWalletHandler(seamless.NewService(store, gameSessions, pots, relay, d.clock, cfg.Games.Secrets, cfg.Money.Currency)).Register(public)
+
+	authenticated := router.Group(func(next http.Handler) http.Handler {
+		return middleware.Authenticate(tokenManager, sessions, apiKeys, impersonations, store, next)
+	}, func(next http.Handler) http.Handler {
+		return middleware.PolicyRateLimit(rateLimits, models.RouteClassAPI, next)
+	})
+	// These routes need the caller's stored account, not just their token.
+	account := authenticated.Group(func(next http.Handler) http.Handler {
+		return middleware.LoadUser(store, next)
+	})
+	handlers.NewMeHandler().Register(account)
+	handlers.NewProfileHandler(users).Register(authenticated)
+	handlers.NewEventStreamHandler(streams, cfg.Events).Register(authenticated)
+	messages := inbox.NewService(store, d.clock)
+	handlers.NewMessageHandler(messages, cfg.Events).Register(authenticated)
+	handlers.NewSupportHandler(support.NewService(store, blobs, messages, cfg.Support)).Register(authenticated)
+	notes := handlers.NewNotesHandler(store)
+	notes.Register(authenticated)
+	handlers.NewRateLimitHandler(store, caches.Func(cache.RateLimits)).Register(authenticated)
+	stats := handlers.NewStatsHandler(store, cfg.StatsCacheTTL)
+	stats.Register(authenticated)
+	metrics.RegisterBusiness(authenticated)
+	caches.Handle(cache.Stats, stats.Invalidate)
+	handlers.NewConfigBundleHandler(store, caches.Func(cache.RateLimits)).Register(authenticated)
+	handlers.NewConfigHistoryHandler(store, caches.Func(cache.RateLimits, cache.IPRisk)).Register(authenticated)
+	handlers.NewWebhookHandler(store).Register(authenticated)
+	handlers.NewSecurityCaseHandler(store).Register(authenticated)
+	handlers.NewIPRiskHandler(store, caches.Func(cache.IPRisk)).Register(authenticated)
+	handlers.NewForcedResetHandler(store, logins).Register(authenticated)
+	handlers.NewLoginHistoryHandler(store).Register(authenticated)
+	handlers.NewSecurityCenterHandler(logins, cfg.JWT.TTL).Register(account)
+	handlers.NewPasswordChangeHandler(logins, challenges).Register(account)
+	handlers.NewChallengeHandler(challenges).Register(account)
+	handlers.NewOnboardingHandler(store, journeys).Register(authenticated)
+	handlers.NewPrivacyHandler(store).Register(authenticated)
+	boards := handlers.NewLeaderboardHandler(store, cfg.Leaderboard.CacheTTL)
+	boards.Register(authenticated)
+	caches.Handle(cache.Leaderboards, boards.Invalidate)
+	handlers.NewDataExportHandler(dataexport.NewService(store, blobs, queue, d.clock, cfg.Exports.LinkTTL)).Register(authenticated)
+	handlers.NewPromoHandler(store, promo.NewService(store, relay, d.clock)).Register(authenticated)
+	handlers.NewGameHandler(gameSessions).Register(authenticated)
+	handlers.NewLimitHandler(store, limits.NewService(store, d.clock, cfg.Games.LimitIncreaseDelay)).Register(authenticated)
+	handlers.NewUserSearchHandler(store).Register(authenticated)
+	handlers.NewInboundDeliveryHandler(store, callbacks).Register(authenticated)
+	handlers.NewQueueHandler(queue).Register(authenticated)
+	handlers.NewRoleHandler(store, caches.Func(cache.Sessions)).Register(authenticated)
+	handlers.NewUserPermissionHandler(store, caches.Func(cache.Sessions)).Register(authenticated)
+	handlers.NewAccountMergeHandler(accounts.NewMerger(store, d.clock, caches.Func(cache.Sessions))).Register(authenticated)
+	handlers.NewLegalHoldHandler(store).Register(authenticated)
+	ledger := wallet.NewService(store, relay)
+	handlers.NewBalanceAdjustmentHandler(store, ledger).Register(authenticated)
+	jackpotsHandler.RegisterAdmin(authenticated)
+	handlers.NewWalletHandler(ledger).Register(authenticated)
+	cashier := payments.NewService(store, ledger, d.clock, d.ids, d.payments, cfg.Payments.WithdrawalApprovalMin)
+	handlers.NewPaymentHandler(cashier).Register(authenticated.Group(func(next http.Handler) http.Handler {
+		return middleware.BlockCountries(cfg.GeoIP.BlockedCountries, next)
+	}))
+	withdrawals := handlers.NewWithdrawalHandler(store, cashier, challenges)
+	withdrawals.Register(authenticated)
+	withdrawals.RegisterRequest(account.Group(func(next http.Handler) http.Handler {
+		return middleware.ScreenIP(screen, middleware.TokenTenant, models.CheckpointWithdrawal, next)
+	}))
+	flags := handlers.NewFeatureFlagHandler(store, cfg.Features)
+	flags.Register(authenticated)
+	handlers.NewReservedNameHandler(store, names).Register(authenticated)
+	handlers.NewDisposableEmailHandler(emails, caches.Func(cache.DisposableEmails)).Register(authenticated)
+	archiver := archive.NewService(store, d.clock, cfg.Archive)
+	handlers.NewArchiveHandler(archiver).Register(authenticated)
+	bulkJobs := bulk.NewRunner(store, queue, d.clock, d.ids.NewID(), cfg.Jobs.BulkStaleAfter)
+	bulkJobs.Register(models.BulkJobArchive, archiver)
+	handlers.NewBulkJobHandler(store, bulkJobs).Register(authenticated)
+	reconciler := reconcile.NewService(store, cfg.Reconcile)
+	standings := leaderboard.NewService(store, cfg.Leaderboard.RefreshInterval)
+	handlers.NewReconciliationHandler(store, reconciler).Register(authenticated)
+	handlers.NewLedgerHandler(store).Register(authenticated)
+	operatorReports := reports.NewService(store, blobs, queue, notifications, d.clock, cfg.Reports)
+	handlers.NewOperatorReportHandler(operatorReports).Register(authenticated)
+	contests := tournaments.NewService(store, queue, notifications, relay, d.clock, cfg.Games.TournamentCloseInterval)
+	handlers.NewTournamentHandler(contests).Register(authenticated)
+	advisor := dbinsights.NewService(store, cfg.DBInsights)
+	handlers.NewDatabaseInsightsHandler(store, advisor).Register(authenticated)
+	handlers.NewOAuthClientHandler(store).Register(authenticated)
+	handlers.NewAPIKeyHandler(store, apiKeys).Register(authenticated)
+	handlers.NewImpersonationHandler(store, store, impersonations).Register(authenticated)
+	handlers.NewCacheHandler(caches).Register(authenticated)
+	if cfg.OIDC.SigningKeyPath != "" {
+		provider, err := oidc.NewProvider(store, cfg.OIDC, d.clock)
+		if err != nil {
+			bus.Close()
+			return nil, err
+		}
+		signIn := handlers.NewOIDCHandler(provider)
+		signIn.Register(public)
+		signIn.RegisterSignIn(limited.Group(func(next http.Handler) http.Handler {
+			return middleware.Identify(tokenManager, sessions, next)
+		}))
+	}
+
+	var root http.Handler = mux
+	if cfg.FaultInjection {
+		injector := chaos.NewInjector()
+		handlers.NewFaultHandler(injector).Register(authenticated)
+		root = middleware.FaultInjection(injector, mux)
+	}
+	root = middleware.GeoIP(locator, cfg.Security.CountryHeader, root)
+	root = middleware.MoneyFormat(formatter, root)
+	root = middleware.Localize(translator, root)
+	root = middleware.Negotiate(root)
+	if cfg.Region.Name != "" {
+		root = middleware.RegionHeader(cfg.Region.Name, root)
+	}
+
+	cors := middleware.NewReloadableCORS(corsPolicy(cfg.CORS), middleware.Tracing(middleware.Logging(middleware.BodyLogging{
+		SampleRate: cfg.Logging.BodySampleRate,
+		MaxBytes:   cfg.Logging.BodyMaxBytes,
+	}, root)))
 
 	httpServer := &http.Server{
 		Addr:              cfg.HTTPAddress(),
-		Handler:           handler,
+		Handler:           cors,
 		ReadHeaderTimeout: 5 * time.Second,
 		ReadTimeout:       10 * time.Second,
 		WriteTimeout:      10 * time.Second,
 		IdleTimeout:       120 * time.Second,
 	}
+	// Event streams never finish on their own; end them so Shutdown can.
+	httpServer.RegisterOnShutdown(streams.Close)
+	httpServer.RegisterOnShutdown(pots.Close)
+	httpServer.RegisterOnShutdown(messages.Close)
 
-	return &Server{inner: httpServer}
+	cacheTransport, err := attachCacheTransport(cfg.Cache, caches)
+	if err != nil {
+		bus.Close()
+		return nil, err
+	}
+
+	relay.Start()
+	archiver.Start()
+	bulkJobs.Start()
+	reconciler.Start()
+	operatorReports.Start()
+	contests.Start()
+	standings.Start()
+	advisor.Start()
+	return &Server{inner: httpServer, blobs: blobs, events: bus, outbox: relay, caches: cacheTransport, jobs: queue, cors: cors, rateLimits: rateLimits, archiver: archiver, bulkJobs: bulkJobs, reconciler: reconciler, reports: operatorReports, contests: contests, standings: standings, advisor: advisor, flags: flags}, nil
+}
+
+// Reload applies the hot-reloadable configuration sections: the CORS policy and
+// the static auth rate limit.
+func (s *Server) Reload(cfg config.Config) {
+	s.cors.SetPolicy(corsPolicy(cfg.CORS))
+	s.rateLimits.SetFallbacks(authFallback(cfg.RateLimit))
+	s.flags.SetDefaults(cfg.Features)
+}
+
+func corsPolicy(cfg config.CORSConfig) middleware.CORSPolicy {
+	return middleware.CORSPolicy{
+		AllowedOrigins:   cfg.AllowedOrigins,
+		OriginPatterns:   cfg.OriginPatterns,
+		AllowedMethods:   cfg.AllowedMethods,
+		AllowedHeaders:   cfg.AllowedHeaders,
+		ExposedHeaders:   cfg.ExposedHeaders,
+		AllowCredentials: cfg.AllowCredentials,
+		MaxAge:           cfg.MaxAge,
+	}
+}
+
+// authFallback is the auth route-class policy used when the database has none.
+func authFallback(cfg config.RateLimitConfig) models.RateLimitPolicy {
+	return models.RateLimitPolicy{
+		RouteClass:    models.RouteClassAuth,
+		Requests:      cfg.AuthRequests,
+		WindowSeconds: int(cfg.AuthWindow.Seconds()),
+	}
+}
+
+// newNotifier builds the configured providers, delivering through the job queue.
+func newNotifier(cfg config.NotifyConfig, queue *jobs.Queue, d deps) (notify.Notifier, error) {
+	templates, err := notify.LoadTemplates()
+	if err != nil {
+		return nil, err
+	}
+	var email notify.EmailSender = notify.LogSender{}
+	switch cfg.EmailProvider {
+	case "smtp":
+		email = notify.SMTPSender{Host: cfg.SMTPHost, Port: cfg.SMTPPort, Username: cfg.SMTPUsername, Password: cfg.SMTPPassword, From: cfg.FromEmail}
+	case "sendgrid":
+		email = notify.SendGridSender{APIKey: cfg.SendGridAPIKey, From: cfg.FromEmail}
+	}
+	var sms notify.SMSSender = notify.LogSender{}
+	if cfg.SMSProvider == "twilio" {
+		sms = notify.TwilioStub{AccountSID: cfg.TwilioSID, From: cfg.TwilioFrom}
+	}
+	if d.email != nil {
+		email = d.email
+	}
+	if d.sms != nil {
+		sms = d.sms
+	}
+	return notify.NewAsync(notify.NewService(templates, email, sms), queue), nil
+}
+
+// newBlobStore builds the configured file store. The local backend also serves
+// its signed URLs under /blobs/.
+func newBlobStore(cfg config.BlobConfig, public *Router) (blob.Store, error) {
+	if cfg.Backend == "s3" {
+		return blob.NewS3(cfg.S3, nil)
+	}
+	local, err := blob.NewLocal(cfg.LocalDir, cfg.PublicBaseURL, cfg.SigningKey)
+	if err != nil {
+		return nil, err
+	}
+	public.Handle("/blobs/", http.StripPrefix("/blobs", local.Handler()))
+	return local, nil
+}
+
+// newIPIntelligence returns the plugged-in IP intelligence provider, or the
+// configured risky network list. Without either, no IPs are screened.
+func newIPIntelligence(cfg config.IPRiskConfig, d deps) (iprisk.Provider, error) {
+	if d.ipIntel != nil {
+		return d.ipIntel, nil
+	}
+	if cfg.ListPath == "" {
+		return nil, nil
+	}
+	return iprisk.OpenList(cfg.ListPath)
+}
+
+// newUsernamePolicy builds the username rules, reading the reserved names
+// file when one is configured.
+func newUsernamePolicy(cfg config.UsernameConfig, store storage.ReservedNameStore) (*usernames.Policy, error) {
+	var file []models.ReservedName
+	if cfg.ReservedPath != "" {
+		var err error
+		if file, err = usernames.OpenList(cfg.ReservedPath); err != nil {
+			return nil, err
+		}
+	}
+	rules := usernames.Rules{MinLength: cfg.MinLength, MaxLength: cfg.MaxLength, Unicode: cfg.Unicode, Symbols: cfg.Symbols}
+	return usernames.NewPolicy(rules, file, store), nil
+}
+
+// newEmailChecker builds the sign-up email checks and reads the disposable
+// domain list. A list that cannot be read is logged and retried on refresh,
+// so a list URL being down does not keep the server from starting.
+func newEmailChecker(cfg config.EmailCheckConfig, d deps) *emailcheck.Checker {
+	opts := emailcheck.Options{MXTimeout: cfg.MXTimeout, DisposableList: cfg.DisposableList}
+	if cfg.CheckMX {
+		opts.Resolver = d.mx
+	}
+	checker := emailcheck.New(opts, d.clock)
+	if cfg.DisposableList != "" {
+		if _, err := checker.Refresh(context.Background()); err != nil {
+			log.Printf("emailcheck: %v", err)
+		}
+	}
+	return checker
+}
+
+// newCaptchaGate builds the CAPTCHA gate for sign-ups and sign-ins, or nil
+// when neither needs one.
+func newCaptchaGate(cfg config.CaptchaConfig, store storage.Store, d deps) accounts.CaptchaGate {
+	if !cfg.Signup && cfg.LoginAfterFailures == 0 {
+		return nil
+	}
+	verifier := captcha.Bypass{Token: cfg.BypassToken}
+	if cfg.Secret != "" {
+		verifier.Next = captcha.SiteVerify{URL: cfg.VerifyURL, Secret: cfg.Secret}
+	}
+	if cfg.BypassToken != "" {
+		log.Printf("captcha: CAPTCHA_BYPASS_TOKEN is set; do not use it in production")
+	}
+	policy := captcha.Policy{Signup: cfg.Signup, LoginAfterFailures: cfg.LoginAfterFailures, LoginWindow: cfg.LoginWindow}
+	return captcha.NewGate(verifier, store, d.clock, policy)
+}
+
+// newCountryLocator opens the configured GeoIP database. Without one, callers
+// are located by the CDN's country header alone.
+func newCountryLocator(cfg config.GeoIPConfig) (middleware.CountryLocator, error) {
+	if cfg.DatabasePath == "" {
+		return nil, nil
+	}
+	return geoip.Open(cfg.DatabasePath)
+}
+
+// Handler returns the fully wrapped root handler, e.g. for httptest servers.
+func (s *Server) Handler() http.Handler {
+	return s.inner.Handler
 }
 
 // Start begins serving HTTP traffic.
@@ -45,7 +566,28 @@ func (s *Server) Start() error {
 	return s.inner.ListenAndServe()
 }
 
-// Shutdown gracefully shuts down the server.
+// Shutdown gracefully shuts down the server, stops the scheduled jobs and
+// event consumers, then drains pending background jobs.
 func (s *Server) Shutdown(ctx context.Context) error {
-	return s.inner.Shutdown(ctx)
+	if err := s.inner.Shutdown(ctx); err != nil {
+		return err
+	}
+	s.archiver.Close()
+	s.bulkJobs.Close()
+	s.reconciler.Close()
+	s.reports.Close()
+	s.contests.Close()
+	s.standings.Close()
+	s.advisor.Close()
+	// Publish what the requests and jobs above committed before the bus closes.
+	s.outbox.Close()
+	if s.caches != nil {
+		if err := s.caches.Close(); err != nil {
+			return err
+		}
+	}
+	if err := s.events.Close(); err != nil {
+		return err
+	}
+	return s.jobs.Close(ctx)
 }
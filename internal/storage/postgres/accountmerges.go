@@ -0,0 +1,87 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hongminglow/all-in-be/internal/models"
+	"github.com/hongminglow/all-in-be/internal/storage"
+	"github.com/jackc/pgx/v5"
+)
+
+// MergeAccounts moves the duplicate's rows and marks it merged in one
+// transaction, or a savepoint inside the caller's. Devices both accounts
+// know are folded into the kept account's row.
+func (s *Store) MergeAccounts(ctx context.Context, merge models.AccountMerge) (models.AccountMerge, error) {
+	var saved models.AccountMerge
+	err := pgx.BeginFunc(ctx, s.db, func(tx pgx.Tx) error {
+		const markMerged = `
+		UPDATE users SET merged_into = $1, sessions_revoked_at = NOW(), version = version + 1
+		WHERE id = $2 AND merged_into IS NULL
+		AND EXISTS (SELECT 1 FROM users WHERE id = $1 AND merged_into IS NULL);
+		`
+		tag, err := tx.Exec(ctx, markMerged, merge.UserID, merge.DuplicateID)
+		if err != nil {
+			return fmt.Errorf("mark user %d merged: %w", merge.DuplicateID, err)
+		}
+		if tag.RowsAffected() == 0 {
+			var found int
+			if err := tx.QueryRow(ctx, `SELECT COUNT(*) FROM users WHERE id IN ($1, $2);`, merge.UserID, merge.DuplicateID).Scan(&found); err != nil {
+				return fmt.Errorf("mark user %d merged: %w", merge.DuplicateID, err)
+			}
+			if found < 2 {
+				return storage.ErrNotFound
+			}
+			return storage.ErrVersionConflict
+		}
+		if _, err := tx.Exec(ctx, `UPDATE users SET version = version + 1 WHERE id = $1;`, merge.UserID); err != nil {
+			return fmt.Errorf("bump user %d version: %w", merge.UserID, err)
+		}
+
+		// Entries moved by an earlier merge keep naming the account they
+		// started on.
+		moves := []struct {
+			query string
+			count *int
+		}{
+			{`UPDATE wallet_transactions SET merged_from = COALESCE(merged_from, user_id), user_id = $1 WHERE user_id = $2;`, &merge.Transactions},
+			{`UPDATE wallet_transactions_archive SET merged_from = COALESCE(merged_from, user_id), user_id = $1 WHERE user_id = $2;`, &merge.Transactions},
+			{`UPDATE operations SET user_id = $1 WHERE user_id = $2;`, &merge.Operations},
+			{`UPDATE login_history SET user_id = $1 WHERE user_id = $2;`, &merge.LoginAttempts},
+			{`UPDATE login_history_archive SET user_id = $1 WHERE user_id = $2;`, &merge.LoginAttempts},
+			{`
+			WITH moved AS (
+				DELETE FROM login_devices WHERE user_id = $2
+				RETURNING fingerprint, country, first_seen, last_seen
+			)
+			INSERT INTO login_devices (user_id, fingerprint, country, first_seen, last_seen)
+			SELECT $1, fingerprint, country, first_seen, last_seen FROM moved
+			ON CONFLICT (user_id, fingerprint, country) DO UPDATE
+			SET first_seen = LEAST(login_devices.first_seen, EXCLUDED.first_seen),
+				last_seen = GREATEST(login_devices.last_seen, EXCLUDED.last_seen);`, &merge.Devices},
+		}
+		for _, move := range moves {
+			tag, err := tx.Exec(ctx, move.query, merge.UserID, merge.DuplicateID)
+			if err != nil {
+				return fmt.Errorf("merge user %d into user %d: %w", merge.DuplicateID, merge.UserID, err)
+			}
+			*move.count += int(tag.RowsAffected())
+		}
+
+		const record = `
+		INSERT INTO account_merges (user_id, duplicate_id, merged_by, balance, transactions, operations, login_attempts, devices)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id, created_at;
+		`
+		saved = merge
+		if err := tx.QueryRow(ctx, record, merge.UserID, merge.DuplicateID, merge.MergedBy, merge.Balance,
+			merge.Transactions, merge.Operations, merge.LoginAttempts, merge.Devices).Scan(&saved.ID, &saved.CreatedAt); err != nil {
+			return fmt.Errorf("record account merge: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return models.AccountMerge{}, err
+	}
+	return saved, nil
+}
@@ -0,0 +1,78 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/hongminglow/all-in-be/internal/models"
+	"github.com/hongminglow/all-in-be/internal/storage"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const apiKeyColumns = `id, name, prefix, key_hash, scopes, expires_at, created_by, created_at, revoked_by, revoked_at`
+
+// ListAPIKeys returns every key, newest first.
+func (s *Store) ListAPIKeys(ctx context.Context) ([]models.APIKey, error) {
+	rows, err := s.db.Query(ctx, `SELECT `+apiKeyColumns+` FROM api_keys ORDER BY id DESC;`)
+	if err != nil {
+		return nil, fmt.Errorf("list api keys: %w", err)
+	}
+	defer rows.Close()
+
+	keys := []models.APIKey{}
+	for rows.Next() {
+		k, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, k)
+	}
+	return keys, rows.Err()
+}
+
+// CreateAPIKey stores a new key. It returns ErrNotFound when the creator does
+// not exist.
+func (s *Store) CreateAPIKey(ctx context.Context, key models.APIKey) (models.APIKey, error) {
+	const query = `
+	INSERT INTO api_keys (name, prefix, key_hash, scopes, expires_at, created_by)
+	VALUES ($1, $2, $3, $4, $5, $6)
+	RETURNING ` + apiKeyColumns + `;
+	`
+	saved, err := scanAPIKey(s.db.QueryRow(ctx, query, key.Name, key.Prefix, key.KeyHash, key.Scopes, key.ExpiresAt, key.CreatedBy))
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+		return models.APIKey{}, storage.ErrNotFound
+	}
+	if err != nil {
+		return models.APIKey{}, fmt.Errorf("create api key: %w", err)
+	}
+	return saved, nil
+}
+
+// FindAPIKeyByHash reads from the primary so a revocation takes effect at once.
+func (s *Store) FindAPIKeyByHash(ctx context.Context, keyHash string) (models.APIKey, error) {
+	return scanAPIKey(s.db.QueryRow(ctx, `SELECT `+apiKeyColumns+` FROM api_keys WHERE key_hash = $1;`, keyHash))
+}
+
+// RevokeAPIKey revokes an active key; a revoked one is reported as ErrNotFound.
+func (s *Store) RevokeAPIKey(ctx context.Context, id, revokedBy int64) (models.APIKey, error) {
+	const query = `
+	UPDATE api_keys SET revoked_by = $2, revoked_at = NOW()
+	WHERE id = $1 AND revoked_at IS NULL
+	RETURNING ` + apiKeyColumns + `;
+	`
+	return scanAPIKey(s.db.QueryRow(ctx, query, id, revokedBy))
+}
+
+func scanAPIKey(row pgx.Row) (models.APIKey, error) {
+	var k models.APIKey
+	if err := row.Scan(&k.ID, &k.Name, &k.Prefix, &k.KeyHash, &k.Scopes, &k.ExpiresAt, &k.CreatedBy, &k.CreatedAt, &k.RevokedBy, &k.RevokedAt); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.APIKey{}, storage.ErrNotFound
+		}
+		return models.APIKey{}, err
+	}
+	return k, nil
+}
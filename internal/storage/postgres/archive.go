@@ -0,0 +1,53 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/hongminglow/all-in-be/internal/models"
+)
+
+// archiveTables maps each dataset to its hot table; the archive table has the
+// same name with an _archive suffix and the same columns plus archived_at.
+var archiveTables = map[string]struct {
+	table   string
+	columns string
+}{
+	models.ArchiveLedger:       {"wallet_transactions", transactionColumns},
+	models.ArchiveLoginHistory: {"login_history", loginAttemptColumns},
+}
+
+// ArchiveRecords moves one batch in a single statement, so a row is never in
+// both tables or neither. Rows locked by another instance's batch are skipped,
+// as are rows of users under a legal hold.
+func (s *Store) ArchiveRecords(ctx context.Context, dataset string, before time.Time, limit int) (int, error) {
+	t, ok := archiveTables[dataset]
+	if !ok {
+		return 0, fmt.Errorf("archive: unknown dataset %q", dataset)
+	}
+	query := fmt.Sprintf(`
+	WITH moved AS (
+		DELETE FROM %[1]s
+		WHERE id IN (
+			SELECT id FROM %[1]s r
+			WHERE r.created_at < $1
+			AND NOT EXISTS (
+				SELECT 1 FROM legal_holds h
+				WHERE h.user_id = r.user_id AND h.released_at IS NULL AND h.dataset IN ('', $3)
+			)
+			ORDER BY id
+			LIMIT $2
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING %[2]s
+	)
+	INSERT INTO %[1]s_archive (%[2]s)
+	SELECT %[2]s FROM moved;
+	`, t.table, t.columns)
+	tag, err := s.db.Exec(ctx, query, before, limit, dataset)
+	if err != nil {
+		return 0, fmt.Errorf("archive %s: %w", dataset, err)
+	}
+	return int(tag.RowsAffected()), nil
+}
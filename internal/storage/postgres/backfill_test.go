@@ -0,0 +1,59 @@
+package postgres
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestPhoneBackfillNormalizesLegacyRowsInTheDefaultRegion(t *testing.T) {
+	stored := []storedPhone{
+		{id: 1, phone: "+60123456789"},
+		{id: 2, phone: "012-987 6543"},
+		{id: 3, phone: "+65 9123 4567"},
+		{id: 4, phone: "(03) 2181 1111"},
+		{id: 5, phone: "not a number"},
+	}
+	rewrites, err := planPhoneBackfill(stored, "MY")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []storedPhone{{id: 2, phone: "+60129876543"}, {id: 3, phone: "+6591234567"}, {id: 4, phone: "+60321811111"}}
+	if !slices.Equal(rewrites, want) {
+		t.Fatalf("rewrites = %+v, want %+v", rewrites, want)
+	}
+}
+
+func TestPhoneBackfillReportsUsersSharingANormalizedNumber(t *testing.T) {
+	stored := []storedPhone{
+		{id: 7, phone: "+60123456789"},
+		{id: 8, phone: "012-345 6789"},
+		{id: 9, phone: "+60 12-345 6789"},
+		{id: 10, phone: "+6591234567"},
+		{id: 11, phone: "garbage"},
+		{id: 12, phone: "garbage"},
+	}
+	_, err := planPhoneBackfill(stored, "MY")
+	if err == nil {
+		t.Fatal("planPhoneBackfill succeeded, want the shared numbers reported")
+	}
+	for _, part := range []string{"users [7 8 9] share +60123456789", "users [11 12] share garbage"} {
+		if !strings.Contains(err.Error(), part) {
+			t.Errorf("error %q does not mention %q", err, part)
+		}
+	}
+	if strings.Contains(err.Error(), "10") {
+		t.Errorf("error %q names user 10, whose number is their own", err)
+	}
+}
+
+func TestPhoneBackfillWithoutARegionLeavesNationalNumbers(t *testing.T) {
+	stored := []storedPhone{{id: 1, phone: "012-345 6789"}, {id: 2, phone: "+60 12-345 6780"}}
+	rewrites, err := planPhoneBackfill(stored, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []storedPhone{{id: 2, phone: "+60123456780"}}; !slices.Equal(rewrites, want) {
+		t.Fatalf("rewrites = %+v, want %+v", rewrites, want)
+	}
+}
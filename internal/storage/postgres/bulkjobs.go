@@ -0,0 +1,135 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/hongminglow/all-in-be/internal/models"
+	"github.com/hongminglow/all-in-be/internal/storage"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const bulkJobColumns = `id, kind, status, checkpoint, processed, batches, error, owner, created_by, created_at, updated_at, finished_at`
+
+// CreateBulkJob records a new job, running under job.Owner.
+func (s *Store) CreateBulkJob(ctx context.Context, job models.BulkJob) (models.BulkJob, error) {
+	const query = `
+	INSERT INTO bulk_jobs (kind, status, owner, created_by)
+	VALUES ($1, $2, $3, $4)
+	RETURNING ` + bulkJobColumns + `;
+	`
+	created, err := scanBulkJob(s.db.QueryRow(ctx, query, job.Kind, job.Status, job.Owner, job.CreatedBy))
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+			return models.BulkJob{}, storage.ErrNotFound
+		}
+		return models.BulkJob{}, fmt.Errorf("create bulk job: %w", err)
+	}
+	return created, nil
+}
+
+// FindBulkJob fetches one job from the primary, since its progress changes
+// with every batch.
+func (s *Store) FindBulkJob(ctx context.Context, id int64) (models.BulkJob, error) {
+	const query = `SELECT ` + bulkJobColumns + ` FROM bulk_jobs WHERE id = $1;`
+	return scanBulkJob(s.db.QueryRow(ctx, query, id))
+}
+
+// ListBulkJobs returns up to limit jobs, newest first.
+func (s *Store) ListBulkJobs(ctx context.Context, limit int) ([]models.BulkJob, error) {
+	const query = `SELECT ` + bulkJobColumns + ` FROM bulk_jobs ORDER BY id DESC LIMIT $1;`
+	rows, err := s.reader().Query(ctx, query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("list bulk jobs: %w", err)
+	}
+	return collectBulkJobs(rows)
+}
+
+// SaveBulkJobProgress records a finished batch of a job the owner still runs.
+func (s *Store) SaveBulkJobProgress(ctx context.Context, id int64, owner string, checkpoint []byte, processed int64, at time.Time) error {
+	const query = `
+	UPDATE bulk_jobs SET checkpoint = $3, processed = processed + $4, batches = batches + 1, updated_at = $5
+	WHERE id = $1 AND owner = $2 AND status = 'running';
+	`
+	tag, err := s.db.Exec(ctx, query, id, owner, checkpoint, processed, at)
+	if err != nil {
+		return fmt.Errorf("save bulk job progress: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return storage.ErrNotFound
+	}
+	return nil
+}
+
+// FinishBulkJob moves a job the owner still runs to its final status.
+func (s *Store) FinishBulkJob(ctx context.Context, id int64, owner, status, errMsg string, at time.Time) error {
+	const query = `
+	UPDATE bulk_jobs SET status = $3, error = $4, updated_at = $5, finished_at = $5
+	WHERE id = $1 AND owner = $2 AND status = 'running';
+	`
+	tag, err := s.db.Exec(ctx, query, id, owner, status, errMsg, at)
+	if err != nil {
+		return fmt.Errorf("finish bulk job: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return storage.ErrNotFound
+	}
+	return nil
+}
+
+// TransitionBulkJob moves a job in one of the from statuses to status.
+func (s *Store) TransitionBulkJob(ctx context.Context, id int64, from []string, status, owner string, at time.Time) (models.BulkJob, error) {
+	const query = `
+	UPDATE bulk_jobs SET status = $3, owner = $4, updated_at = $5,
+		error = CASE WHEN $3 = 'running' THEN '' ELSE error END,
+		finished_at = CASE WHEN $3 = 'running' THEN NULL ELSE $5 END
+	WHERE id = $1 AND status = ANY($2)
+	RETURNING ` + bulkJobColumns + `;
+	`
+	return scanBulkJob(s.db.QueryRow(ctx, query, id, from, status, owner, at))
+}
+
+// ClaimStaleBulkJobs takes over running jobs whose owner stopped saving progress.
+func (s *Store) ClaimStaleBulkJobs(ctx context.Context, owner string, staleBefore, at time.Time) ([]models.BulkJob, error) {
+	const query = `
+	UPDATE bulk_jobs SET owner = $1, updated_at = $3
+	WHERE status = 'running' AND updated_at < $2
+	RETURNING ` + bulkJobColumns + `;
+	`
+	rows, err := s.db.Query(ctx, query, owner, staleBefore, at)
+	if err != nil {
+		return nil, fmt.Errorf("claim stale bulk jobs: %w", err)
+	}
+	return collectBulkJobs(rows)
+}
+
+func collectBulkJobs(rows pgx.Rows) ([]models.BulkJob, error) {
+	defer rows.Close()
+
+	jobs := []models.BulkJob{}
+	for rows.Next() {
+		job, err := scanBulkJob(rows)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, rows.Err()
+}
+
+func scanBulkJob(row pgx.Row) (models.BulkJob, error) {
+	var j models.BulkJob
+	var checkpoint []byte
+	if err := row.Scan(&j.ID, &j.Kind, &j.Status, &checkpoint, &j.Processed, &j.Batches, &j.Error, &j.Owner, &j.CreatedBy, &j.CreatedAt, &j.UpdatedAt, &j.FinishedAt); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.BulkJob{}, storage.ErrNotFound
+		}
+		return models.BulkJob{}, err
+	}
+	j.Checkpoint = checkpoint
+	return j, nil
+}
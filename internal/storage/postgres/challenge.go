@@ -0,0 +1,82 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/hongminglow/all-in-be/internal/models"
+	"github.com/hongminglow/all-in-be/internal/storage"
+	"github.com/jackc/pgx/v5"
+)
+
+const challengeColumns = `id, user_id, action, method, code_hash, attempts, COALESCE(token_hash, ''), expires_at, passed_at, used_at, created_at`
+
+// CreateChallenge stores a pending challenge.
+func (s *Store) CreateChallenge(ctx context.Context, c models.Challenge) (models.Challenge, error) {
+	const query = `
+	INSERT INTO challenges (user_id, action, method, code_hash, expires_at)
+	VALUES ($1, $2, $3, $4, $5)
+	RETURNING ` + challengeColumns + `;
+	`
+	saved, err := scanChallenge(s.db.QueryRow(ctx, query, c.UserID, c.Action, c.Method, c.CodeHash, c.ExpiresAt))
+	if err != nil {
+		return models.Challenge{}, fmt.Errorf("create challenge: %w", err)
+	}
+	return saved, nil
+}
+
+// FindChallenge fetches a challenge by ID from the primary, since it is read
+// straight after being issued.
+func (s *Store) FindChallenge(ctx context.Context, id int64) (models.Challenge, error) {
+	return scanChallenge(s.db.QueryRow(ctx, `SELECT `+challengeColumns+` FROM challenges WHERE id = $1;`, id))
+}
+
+// FailChallenge counts a wrong answer to a pending challenge.
+func (s *Store) FailChallenge(ctx context.Context, id int64) (models.Challenge, error) {
+	const query = `
+	UPDATE challenges SET attempts = attempts + 1
+	WHERE id = $1 AND passed_at IS NULL
+	RETURNING ` + challengeColumns + `;
+	`
+	return scanChallenge(s.db.QueryRow(ctx, query, id))
+}
+
+// PassChallenge marks a pending challenge passed.
+func (s *Store) PassChallenge(ctx context.Context, id int64, tokenHash string, at, expiresAt time.Time) error {
+	const query = `
+	UPDATE challenges SET passed_at = $3, token_hash = $2, expires_at = $4
+	WHERE id = $1 AND passed_at IS NULL;
+	`
+	tag, err := s.db.Exec(ctx, query, id, tokenHash, at, expiresAt)
+	if err != nil {
+		return fmt.Errorf("pass challenge: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return storage.ErrNotFound
+	}
+	return nil
+}
+
+// UseChallenge redeems a passed challenge. The conditional update lets only
+// one of two concurrent requests carrying the same token through.
+func (s *Store) UseChallenge(ctx context.Context, userID int64, action, tokenHash string, now time.Time) (models.Challenge, error) {
+	const query = `
+	UPDATE challenges SET used_at = $4
+	WHERE token_hash = $3 AND user_id = $1 AND action = $2 AND used_at IS NULL AND expires_at > $4
+	RETURNING ` + challengeColumns + `;
+	`
+	return scanChallenge(s.db.QueryRow(ctx, query, userID, action, tokenHash, now))
+}
+
+func scanChallenge(row pgx.Row) (models.Challenge, error) {
+	var c models.Challenge
+	if err := row.Scan(&c.ID, &c.UserID, &c.Action, &c.Method, &c.CodeHash, &c.Attempts, &c.TokenHash, &c.ExpiresAt, &c.PassedAt, &c.UsedAt, &c.CreatedAt); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.Challenge{}, storage.ErrNotFound
+		}
+		return models.Challenge{}, err
+	}
+	return c, nil
+}
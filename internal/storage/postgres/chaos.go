@@ -0,0 +1,51 @@
+package postgres
+
+import (
+	"context"
+
+	"github.com/hongminglow/all-in-be/internal/chaos"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// faultDB fails every call whose context was marked by the fault-injection
+// middleware, simulating a dropped connection.
+type faultDB struct {
+	dbtx
+}
+
+func (f faultDB) Begin(ctx context.Context) (pgx.Tx, error) {
+	if chaos.DBFailure(ctx) {
+		return nil, chaos.ErrInjectedDBFailure
+	}
+	return f.dbtx.Begin(ctx)
+}
+
+func (f faultDB) Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error) {
+	if chaos.DBFailure(ctx) {
+		return pgconn.CommandTag{}, chaos.ErrInjectedDBFailure
+	}
+	return f.dbtx.Exec(ctx, sql, args...)
+}
+
+func (f faultDB) Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+	if chaos.DBFailure(ctx) {
+		return nil, chaos.ErrInjectedDBFailure
+	}
+	return f.dbtx.Query(ctx, sql, args...)
+}
+
+func (f faultDB) QueryRow(ctx context.Context, sql string, args ...any) pgx.Row {
+	if chaos.DBFailure(ctx) {
+		return errRow{err: chaos.ErrInjectedDBFailure}
+	}
+	return f.dbtx.QueryRow(ctx, sql, args...)
+}
+
+type errRow struct {
+	err error
+}
+
+func (r errRow) Scan(...any) error {
+	return r.err
+}
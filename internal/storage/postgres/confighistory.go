@@ -0,0 +1,115 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/hongminglow/all-in-be/internal/models"
+	"github.com/hongminglow/all-in-be/internal/storage"
+	"github.com/jackc/pgx/v5"
+)
+
+const configChangeColumns = `h.id, h.entity, h.entity_key, h.before, h.after, h.changed_by, u.username, h.country, h.rollback_of, h.changed_at`
+
+// RecordConfigChange appends an entry to the configuration history.
+func (s *Store) RecordConfigChange(ctx context.Context, change models.ConfigChange) (models.ConfigChange, error) {
+	const query = `
+	WITH inserted AS (
+		INSERT INTO config_history (entity, entity_key, before, after, changed_by, country, rollback_of)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING *
+	)
+	SELECT ` + configChangeColumns + `
+	FROM inserted h
+	JOIN users u ON u.id = h.changed_by;
+	`
+	row := s.db.QueryRow(ctx, query, change.Entity, change.EntityKey, nullJSON(change.Before), nullJSON(change.After), change.ChangedBy, change.Country, change.RollbackOf)
+	return scanConfigChange(row)
+}
+
+// FindConfigChange fetches a single history entry.
+func (s *Store) FindConfigChange(ctx context.Context, id int64) (models.ConfigChange, error) {
+	const query = `
+	SELECT ` + configChangeColumns + `
+	FROM config_history h
+	JOIN users u ON u.id = h.changed_by
+	WHERE h.id = $1;
+	`
+	return scanConfigChange(s.reader().QueryRow(ctx, query, id))
+}
+
+// ListConfigChanges returns the newest changes first, optionally filtered by entity.
+func (s *Store) ListConfigChanges(ctx context.Context, entity string, limit int) ([]models.ConfigChange, error) {
+	const query = `
+	SELECT ` + configChangeColumns + `
+	FROM config_history h
+	JOIN users u ON u.id = h.changed_by
+	WHERE $1 = '' OR h.entity = $1
+	ORDER BY h.changed_at DESC, h.id DESC
+	LIMIT $2;
+	`
+	rows, err := s.reader().Query(ctx, query, entity, limit)
+	if err != nil {
+		return nil, fmt.Errorf("list config changes: %w", err)
+	}
+	defer rows.Close()
+
+	changes := []models.ConfigChange{}
+	for rows.Next() {
+		change, err := scanConfigChange(rows)
+		if err != nil {
+			return nil, err
+		}
+		changes = append(changes, change)
+	}
+	return changes, rows.Err()
+}
+
+// ListConfigChangesBy returns every change the user made, oldest first.
+func (s *Store) ListConfigChangesBy(ctx context.Context, actorID int64) ([]models.ConfigChange, error) {
+	const query = `
+	SELECT ` + configChangeColumns + `
+	FROM config_history h
+	JOIN users u ON u.id = h.changed_by
+	WHERE h.changed_by = $1
+	ORDER BY h.id;
+	`
+	rows, err := s.reader().Query(ctx, query, actorID)
+	if err != nil {
+		return nil, fmt.Errorf("list config changes by user: %w", err)
+	}
+	defer rows.Close()
+
+	changes := []models.ConfigChange{}
+	for rows.Next() {
+		change, err := scanConfigChange(rows)
+		if err != nil {
+			return nil, err
+		}
+		changes = append(changes, change)
+	}
+	return changes, rows.Err()
+}
+
+func scanConfigChange(row pgx.Row) (models.ConfigChange, error) {
+	var change models.ConfigChange
+	var before, after []byte
+	if err := row.Scan(&change.ID, &change.Entity, &change.EntityKey, &before, &after, &change.ChangedBy, &change.ChangedByUsername, &change.Country, &change.RollbackOf, &change.ChangedAt); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.ConfigChange{}, storage.ErrNotFound
+		}
+		return models.ConfigChange{}, err
+	}
+	change.Before = before
+	change.After = after
+	return change, nil
+}
+
+// nullJSON maps an empty snapshot to SQL NULL.
+func nullJSON(raw []byte) any {
+	if len(raw) == 0 {
+		return nil
+	}
+	return string(raw)
+}
@@ -0,0 +1,87 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/hongminglow/all-in-be/internal/models"
+	"github.com/hongminglow/all-in-be/internal/storage"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const dataExportColumns = `id, user_id, status, blob_key, created_at, completed_at`
+
+// CreateDataExport records a new export request.
+func (s *Store) CreateDataExport(ctx context.Context, export models.DataExport) (models.DataExport, error) {
+	const query = `
+	INSERT INTO data_exports (user_id, status)
+	VALUES ($1, $2)
+	RETURNING ` + dataExportColumns + `;
+	`
+	created, err := scanDataExport(s.db.QueryRow(ctx, query, export.UserID, export.Status))
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+			return models.DataExport{}, storage.ErrNotFound
+		}
+		return models.DataExport{}, fmt.Errorf("create data export: %w", err)
+	}
+	return created, nil
+}
+
+// FindDataExport fetches one export from the primary, since its status
+// changes moments after it is created.
+func (s *Store) FindDataExport(ctx context.Context, id int64) (models.DataExport, error) {
+	const query = `SELECT ` + dataExportColumns + ` FROM data_exports WHERE id = $1;`
+	return scanDataExport(s.db.QueryRow(ctx, query, id))
+}
+
+// ListDataExports returns the user's exports, newest first.
+func (s *Store) ListDataExports(ctx context.Context, userID int64) ([]models.DataExport, error) {
+	const query = `SELECT ` + dataExportColumns + ` FROM data_exports WHERE user_id = $1 ORDER BY id DESC;`
+	rows, err := s.db.Query(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("list data exports: %w", err)
+	}
+	defer rows.Close()
+
+	exports := []models.DataExport{}
+	for rows.Next() {
+		e, err := scanDataExport(rows)
+		if err != nil {
+			return nil, err
+		}
+		exports = append(exports, e)
+	}
+	return exports, rows.Err()
+}
+
+// FinishDataExport records the outcome of a pending export.
+func (s *Store) FinishDataExport(ctx context.Context, id int64, status, blobKey string, at time.Time) error {
+	const query = `
+	UPDATE data_exports SET status = $2, blob_key = $3, completed_at = $4
+	WHERE id = $1 AND status = 'pending';
+	`
+	tag, err := s.db.Exec(ctx, query, id, status, blobKey, at)
+	if err != nil {
+		return fmt.Errorf("finish data export: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return storage.ErrNotFound
+	}
+	return nil
+}
+
+func scanDataExport(row pgx.Row) (models.DataExport, error) {
+	var e models.DataExport
+	if err := row.Scan(&e.ID, &e.UserID, &e.Status, &e.BlobKey, &e.CreatedAt, &e.CompletedAt); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.DataExport{}, storage.ErrNotFound
+		}
+		return models.DataExport{}, err
+	}
+	return e, nil
+}
@@ -0,0 +1,154 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/hongminglow/all-in-be/internal/models"
+	"github.com/hongminglow/all-in-be/internal/storage"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const databaseInsightsColumns = `id, statements_available, slow_queries, seq_scan_tables, suggestions, created_at`
+
+// QueryStats reads pg_stat_statements on the primary, where the writes and
+// the fallback reads run. Transaction control, utility statements and the
+// advisor's own queries are left out. The extension may be installed without
+// being preloaded, in which case reading it fails with
+// object_not_in_prerequisite_state; that counts as unavailable too.
+func (s *Store) QueryStats(ctx context.Context, limit int) ([]models.QueryStat, bool, error) {
+	var installed bool
+	if err := s.db.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM pg_extension WHERE extname = 'pg_stat_statements');`).Scan(&installed); err != nil {
+		return nil, false, fmt.Errorf("check pg_stat_statements: %w", err)
+	}
+	if !installed {
+		return nil, false, nil
+	}
+	const query = `
+	SELECT queryid, query, calls, total_exec_time, mean_exec_time, rows
+	FROM pg_stat_statements
+	WHERE dbid = (SELECT oid FROM pg_database WHERE datname = current_database())
+		AND queryid IS NOT NULL
+		AND query !~* '^\s*(BEGIN|COMMIT|ROLLBACK|SAVEPOINT|RELEASE|SET|SHOW|RESET|DEALLOCATE|DISCARD|CREATE|ALTER|DROP|VACUUM|ANALYZE)\y'
+		AND query !~ '\y(pg_stat_statements|pg_stat_user_tables|pg_catalog)\y'
+	ORDER BY total_exec_time DESC
+	LIMIT $1;
+	`
+	rows, err := s.db.Query(ctx, query, limit)
+	if err != nil {
+		if notPreloaded(err) {
+			return nil, false, nil
+		}
+		return nil, false, fmt.Errorf("read pg_stat_statements: %w", err)
+	}
+	defer rows.Close()
+
+	stats := []models.QueryStat{}
+	for rows.Next() {
+		var q models.QueryStat
+		if err := rows.Scan(&q.QueryID, &q.Query, &q.Calls, &q.TotalMillis, &q.MeanMillis, &q.Rows); err != nil {
+			return nil, false, err
+		}
+		stats = append(stats, q)
+	}
+	if err := rows.Err(); err != nil {
+		if notPreloaded(err) {
+			return nil, false, nil
+		}
+		return nil, false, err
+	}
+	return stats, true, nil
+}
+
+// notPreloaded reports whether err says pg_stat_statements is missing from
+// shared_preload_libraries.
+func notPreloaded(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "55000"
+}
+
+// TableScanStats reads pg_stat_user_tables for the current schema. Expression
+// indexes have no leading column and are skipped.
+func (s *Store) TableScanStats(ctx context.Context) ([]models.TableScanStat, error) {
+	const query = `
+	SELECT t.relname::text, COALESCE(t.seq_scan, 0), COALESCE(t.seq_tup_read, 0), COALESCE(t.idx_scan, 0), t.n_live_tup,
+		ARRAY(
+			SELECT a.attname::text FROM pg_attribute a
+			WHERE a.attrelid = t.relid AND a.attnum > 0 AND NOT a.attisdropped
+			ORDER BY a.attnum
+		),
+		ARRAY(
+			SELECT a.attname::text FROM pg_index i
+			JOIN pg_attribute a ON a.attrelid = i.indrelid AND a.attnum = i.indkey[0]
+			WHERE i.indrelid = t.relid
+		)
+	FROM pg_stat_user_tables t
+	WHERE t.schemaname = current_schema()
+	ORDER BY t.seq_tup_read DESC, t.relname;
+	`
+	rows, err := s.db.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("read table statistics: %w", err)
+	}
+	defer rows.Close()
+
+	tables := []models.TableScanStat{}
+	for rows.Next() {
+		var t models.TableScanStat
+		if err := rows.Scan(&t.Table, &t.SeqScans, &t.SeqRowsRead, &t.IndexScans, &t.LiveRows, &t.Columns, &t.IndexedColumns); err != nil {
+			return nil, err
+		}
+		tables = append(tables, t)
+	}
+	return tables, rows.Err()
+}
+
+// CreateDatabaseInsightsReport stores the outcome of an advisor run.
+func (s *Store) CreateDatabaseInsightsReport(ctx context.Context, report models.DatabaseInsightsReport) (models.DatabaseInsightsReport, error) {
+	const query = `
+	INSERT INTO database_insights_reports (statements_available, slow_queries, seq_scan_tables, suggestions)
+	VALUES ($1, $2, $3, $4)
+	RETURNING ` + databaseInsightsColumns + `;
+	`
+	var encoded [3][]byte
+	for i, v := range []any{report.SlowQueries, report.SeqScanTables, report.Suggestions} {
+		raw, err := json.Marshal(v)
+		if err != nil {
+			return models.DatabaseInsightsReport{}, err
+		}
+		encoded[i] = raw
+	}
+	created, err := scanDatabaseInsightsReport(s.db.QueryRow(ctx, query, report.StatementsAvailable, encoded[0], encoded[1], encoded[2]))
+	if err != nil {
+		return models.DatabaseInsightsReport{}, fmt.Errorf("create database insights report: %w", err)
+	}
+	return created, nil
+}
+
+// LatestDatabaseInsightsReport returns the newest report.
+func (s *Store) LatestDatabaseInsightsReport(ctx context.Context) (models.DatabaseInsightsReport, error) {
+	return scanDatabaseInsightsReport(s.reader().QueryRow(ctx, `SELECT `+databaseInsightsColumns+` FROM database_insights_reports ORDER BY id DESC LIMIT 1;`))
+}
+
+func scanDatabaseInsightsReport(row pgx.Row) (models.DatabaseInsightsReport, error) {
+	var r models.DatabaseInsightsReport
+	var slow, tables, suggestions []byte
+	if err := row.Scan(&r.ID, &r.StatementsAvailable, &slow, &tables, &suggestions, &r.CreatedAt); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.DatabaseInsightsReport{}, storage.ErrNotFound
+		}
+		return models.DatabaseInsightsReport{}, err
+	}
+	for _, field := range []struct {
+		raw []byte
+		dst any
+	}{{slow, &r.SlowQueries}, {tables, &r.SeqScanTables}, {suggestions, &r.Suggestions}} {
+		if err := json.Unmarshal(field.raw, field.dst); err != nil {
+			return models.DatabaseInsightsReport{}, fmt.Errorf("decode database insights report: %w", err)
+		}
+	}
+	return r, nil
+}
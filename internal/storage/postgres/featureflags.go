@@ -0,0 +1,52 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hongminglow/all-in-be/internal/models"
+	"github.com/jackc/pgx/v5"
+)
+
+// ListFeatureFlags returns the flags set through the admin API, by name.
+func (s *Store) ListFeatureFlags(ctx context.Context) ([]models.FeatureFlag, error) {
+	rows, err := s.db.Query(ctx, `SELECT name, enabled, updated_by, updated_at FROM feature_flags ORDER BY name;`)
+	if err != nil {
+		return nil, fmt.Errorf("list feature flags: %w", err)
+	}
+	defer rows.Close()
+
+	flags := []models.FeatureFlag{}
+	for rows.Next() {
+		f, err := scanFeatureFlag(rows)
+		if err != nil {
+			return nil, err
+		}
+		flags = append(flags, f)
+	}
+	return flags, rows.Err()
+}
+
+// SetFeatureFlag creates or replaces the flag's stored state.
+func (s *Store) SetFeatureFlag(ctx context.Context, flag models.FeatureFlag) (models.FeatureFlag, error) {
+	const query = `
+	INSERT INTO feature_flags (name, enabled, updated_by)
+	VALUES ($1, $2, $3)
+	ON CONFLICT (name) DO UPDATE
+	SET enabled = EXCLUDED.enabled, updated_by = EXCLUDED.updated_by, updated_at = NOW()
+	RETURNING name, enabled, updated_by, updated_at;
+	`
+	saved, err := scanFeatureFlag(s.db.QueryRow(ctx, query, flag.Name, flag.Enabled, flag.UpdatedBy))
+	if err != nil {
+		return models.FeatureFlag{}, fmt.Errorf("set feature flag: %w", err)
+	}
+	return saved, nil
+}
+
+func scanFeatureFlag(row pgx.Row) (models.FeatureFlag, error) {
+	f := models.FeatureFlag{Source: models.FlagSourceAdmin}
+	if err := row.Scan(&f.Name, &f.Enabled, &f.UpdatedBy, &f.UpdatedAt); err != nil {
+		return models.FeatureFlag{}, err
+	}
+	return f, nil
+}
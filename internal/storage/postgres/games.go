@@ -0,0 +1,63 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/hongminglow/all-in-be/internal/models"
+	"github.com/hongminglow/all-in-be/internal/storage"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const gameSessionColumns = `id, user_id, provider, game_id, token_hash, expires_at, started_at, created_at`
+
+// CreateGameSession stores a launched session.
+func (s *Store) CreateGameSession(ctx context.Context, session models.GameSession) (models.GameSession, error) {
+	const query = `
+	INSERT INTO game_sessions (user_id, provider, game_id, token_hash, expires_at)
+	VALUES ($1, $2, $3, $4, $5)
+	RETURNING ` + gameSessionColumns + `;
+	`
+	saved, err := scanGameSession(s.db.QueryRow(ctx, query, session.UserID, session.Provider, session.GameID, session.TokenHash, session.ExpiresAt))
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return models.GameSession{}, storage.ErrAlreadyExists
+		}
+		return models.GameSession{}, fmt.Errorf("create game session: %w", err)
+	}
+	return saved, nil
+}
+
+// FindGameSession fetches a session by token from the primary, since the
+// provider calls back right after the launch.
+func (s *Store) FindGameSession(ctx context.Context, tokenHash string) (models.GameSession, error) {
+	return scanGameSession(s.db.QueryRow(ctx, `SELECT `+gameSessionColumns+` FROM game_sessions WHERE token_hash = $1;`, tokenHash))
+}
+
+// StartGameSession marks a launched session started. The conditional update
+// lets only one of two concurrent first callbacks start it.
+func (s *Store) StartGameSession(ctx context.Context, id int64, at, expiresAt time.Time) error {
+	tag, err := s.db.Exec(ctx, `UPDATE game_sessions SET started_at = $2, expires_at = $3 WHERE id = $1 AND started_at IS NULL;`, id, at, expiresAt)
+	if err != nil {
+		return fmt.Errorf("start game session: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return storage.ErrNotFound
+	}
+	return nil
+}
+
+func scanGameSession(row pgx.Row) (models.GameSession, error) {
+	var g models.GameSession
+	if err := row.Scan(&g.ID, &g.UserID, &g.Provider, &g.GameID, &g.TokenHash, &g.ExpiresAt, &g.StartedAt, &g.CreatedAt); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.GameSession{}, storage.ErrNotFound
+		}
+		return models.GameSession{}, err
+	}
+	return g, nil
+}
@@ -0,0 +1,154 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/hongminglow/all-in-be/internal/models"
+	"github.com/hongminglow/all-in-be/internal/storage"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const holdColumns = `id, user_id, amount, reference, status, transaction_id, created_at, settled_at`
+
+// CreateHold adds the amount to users.held_balance and records the hold in
+// one statement. The update locks the user's row, so concurrent holds and
+// debits see each other's reservations.
+func (s *Store) CreateHold(ctx context.Context, hold models.WalletHold) (models.WalletHold, error) {
+	const query = `
+	WITH reserved AS (
+		UPDATE users SET held_balance = held_balance + $2
+		WHERE id = $1 AND balance - held_balance >= $2
+		RETURNING id
+	)
+	INSERT INTO wallet_holds (user_id, amount, reference, status)
+	SELECT id, $2, $3, 'active' FROM reserved
+	RETURNING ` + holdColumns + `;
+	`
+	created, err := scanHold(s.db.QueryRow(ctx, query, hold.UserID, hold.Amount, hold.Reference))
+	var pgErr *pgconn.PgError
+	switch {
+	case errors.As(err, &pgErr) && pgErr.Code == "23505":
+		return models.WalletHold{}, storage.ErrAlreadyExists
+	case errors.Is(err, storage.ErrNotFound):
+		var exists bool
+		if err := s.db.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM users WHERE id = $1);`, hold.UserID).Scan(&exists); err != nil {
+			return models.WalletHold{}, fmt.Errorf("create hold: %w", err)
+		}
+		if !exists {
+			return models.WalletHold{}, storage.ErrNotFound
+		}
+		return models.WalletHold{}, storage.ErrInsufficientFunds
+	case err != nil:
+		return models.WalletHold{}, fmt.Errorf("create hold: %w", err)
+	}
+	return created, nil
+}
+
+// FindHold reads from the primary, since holds are read to settle them.
+func (s *Store) FindHold(ctx context.Context, id int64) (models.WalletHold, error) {
+	return scanHold(s.db.QueryRow(ctx, `SELECT `+holdColumns+` FROM wallet_holds WHERE id = $1;`, id))
+}
+
+// CaptureHold locks the hold, moves its amount out of both the balance and
+// users.held_balance, writes the ledger entry and links it in one statement,
+// then posts the entry to the journal. A second capture waits for the first
+// and then finds the hold settled.
+func (s *Store) CaptureHold(ctx context.Context, id int64, reason string) (models.WalletHold, error) {
+	const query = `
+	WITH held AS (
+		SELECT id, user_id, amount, reference FROM wallet_holds
+		WHERE id = $1 AND status = 'active'
+		FOR UPDATE
+	), moved AS (
+		UPDATE users u
+		SET balance = u.balance - h.amount, held_balance = u.held_balance - h.amount, version = u.version + 1
+		FROM held h
+		WHERE u.id = h.user_id
+		RETURNING u.id, u.balance, h.amount, h.reference
+	), entry AS (
+		INSERT INTO wallet_transactions (user_id, amount, balance_after, reason, reference)
+		SELECT id, -amount, balance, $2, reference FROM moved
+		RETURNING id AS entry_id
+	)
+	UPDATE wallet_holds SET status = 'captured', transaction_id = entry.entry_id, settled_at = NOW()
+	FROM entry
+	WHERE wallet_holds.id = $1
+	RETURNING ` + holdColumns + `;
+	`
+	var captured models.WalletHold
+	err := pgx.BeginFunc(ctx, s.db, func(tx pgx.Tx) error {
+		var userID int64
+		if err := tx.QueryRow(ctx, `SELECT user_id FROM wallet_holds WHERE id = $1;`, id).Scan(&userID); err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return storage.ErrNotFound
+			}
+			return err
+		}
+		if err := openWallet(ctx, tx, userID); err != nil {
+			return err
+		}
+		var err error
+		if captured, err = scanHold(tx.QueryRow(ctx, query, id, reason)); err != nil {
+			return err
+		}
+		return postJournal(ctx, tx, models.JournalFor(models.Transaction{
+			ID:        *captured.TransactionID,
+			UserID:    captured.UserID,
+			Amount:    -captured.Amount,
+			Reason:    reason,
+			Reference: captured.Reference,
+		}))
+	})
+	if err != nil && !errors.Is(err, storage.ErrNotFound) {
+		return models.WalletHold{}, fmt.Errorf("capture hold %d: %w", id, err)
+	}
+	return captured, err
+}
+
+// ReleaseHold marks the hold released and frees its amount in one statement.
+func (s *Store) ReleaseHold(ctx context.Context, id int64) (models.WalletHold, error) {
+	const query = `
+	WITH released AS (
+		UPDATE wallet_holds SET status = 'released', settled_at = NOW()
+		WHERE id = $1 AND status = 'active'
+		RETURNING ` + holdColumns + `
+	), freed AS (
+		UPDATE users u SET held_balance = u.held_balance - r.amount
+		FROM released r
+		WHERE u.id = r.user_id
+	)
+	SELECT ` + holdColumns + ` FROM released;
+	`
+	released, err := scanHold(s.db.QueryRow(ctx, query, id))
+	if err != nil && !errors.Is(err, storage.ErrNotFound) {
+		return models.WalletHold{}, fmt.Errorf("release hold %d: %w", id, err)
+	}
+	return released, err
+}
+
+// HeldBalance reads the running total kept on the user's row.
+func (s *Store) HeldBalance(ctx context.Context, userID int64) (float64, error) {
+	var held float64
+	err := s.db.QueryRow(ctx, `SELECT held_balance FROM users WHERE id = $1;`, userID).Scan(&held)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, storage.ErrNotFound
+	}
+	if err != nil {
+		return 0, fmt.Errorf("held balance: %w", err)
+	}
+	return held, nil
+}
+
+func scanHold(row pgx.Row) (models.WalletHold, error) {
+	var h models.WalletHold
+	if err := row.Scan(&h.ID, &h.UserID, &h.Amount, &h.Reference, &h.Status, &h.TransactionID, &h.CreatedAt, &h.SettledAt); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.WalletHold{}, storage.ErrNotFound
+		}
+		return models.WalletHold{}, err
+	}
+	return h, nil
+}
@@ -0,0 +1,85 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/hongminglow/all-in-be/internal/models"
+	"github.com/hongminglow/all-in-be/internal/storage"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const impersonationColumns = `id, user_id, actor_id, reason, token_id, expires_at, created_at, revoked_by, revoked_at`
+
+// ListImpersonations returns up to limit impersonations of userID, or of
+// anyone when it is 0, newest first.
+func (s *Store) ListImpersonations(ctx context.Context, userID int64, limit int) ([]models.Impersonation, error) {
+	rows, err := s.db.Query(ctx, `
+	SELECT `+impersonationColumns+` FROM impersonations
+	WHERE $1 = 0 OR user_id = $1
+	ORDER BY id DESC
+	LIMIT $2;`, userID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("list impersonations: %w", err)
+	}
+	defer rows.Close()
+
+	out := []models.Impersonation{}
+	for rows.Next() {
+		imp, err := scanImpersonation(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, imp)
+	}
+	return out, rows.Err()
+}
+
+// CreateImpersonation stores a new impersonation. It returns ErrNotFound when
+// the user or the staff member does not exist.
+func (s *Store) CreateImpersonation(ctx context.Context, imp models.Impersonation) (models.Impersonation, error) {
+	const query = `
+	INSERT INTO impersonations (user_id, actor_id, reason, token_id, expires_at)
+	VALUES ($1, $2, $3, $4, $5)
+	RETURNING ` + impersonationColumns + `;
+	`
+	saved, err := scanImpersonation(s.db.QueryRow(ctx, query, imp.UserID, imp.ActorID, imp.Reason, imp.TokenID, imp.ExpiresAt))
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+		return models.Impersonation{}, storage.ErrNotFound
+	}
+	if err != nil {
+		return models.Impersonation{}, fmt.Errorf("create impersonation: %w", err)
+	}
+	return saved, nil
+}
+
+// FindImpersonationByToken reads from the primary so a revocation takes
+// effect at once.
+func (s *Store) FindImpersonationByToken(ctx context.Context, tokenID string) (models.Impersonation, error) {
+	return scanImpersonation(s.db.QueryRow(ctx, `SELECT `+impersonationColumns+` FROM impersonations WHERE token_id = $1;`, tokenID))
+}
+
+// RevokeImpersonation revokes an active impersonation; a revoked one is
+// reported as ErrNotFound.
+func (s *Store) RevokeImpersonation(ctx context.Context, id, revokedBy int64) (models.Impersonation, error) {
+	const query = `
+	UPDATE impersonations SET revoked_by = $2, revoked_at = NOW()
+	WHERE id = $1 AND revoked_at IS NULL
+	RETURNING ` + impersonationColumns + `;
+	`
+	return scanImpersonation(s.db.QueryRow(ctx, query, id, revokedBy))
+}
+
+func scanImpersonation(row pgx.Row) (models.Impersonation, error) {
+	var imp models.Impersonation
+	if err := row.Scan(&imp.ID, &imp.UserID, &imp.ActorID, &imp.Reason, &imp.TokenID, &imp.ExpiresAt, &imp.CreatedAt, &imp.RevokedBy, &imp.RevokedAt); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.Impersonation{}, storage.ErrNotFound
+		}
+		return models.Impersonation{}, err
+	}
+	return imp, nil
+}
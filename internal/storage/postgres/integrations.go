@@ -0,0 +1,106 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/hongminglow/all-in-be/internal/models"
+	"github.com/hongminglow/all-in-be/internal/storage"
+	"github.com/jackc/pgx/v5"
+)
+
+const inboundDeliveryColumns = `id, provider, event_id, headers, payload, status, error, attempts, received_at, last_attempt_at`
+
+// RecordInboundDelivery stores a callback as received.
+func (s *Store) RecordInboundDelivery(ctx context.Context, d models.InboundDelivery) (models.InboundDelivery, error) {
+	const query = `
+	INSERT INTO inbound_deliveries (provider, event_id, headers, payload, status)
+	VALUES ($1, $2, $3, $4, $5)
+	RETURNING ` + inboundDeliveryColumns + `;
+	`
+	row := s.db.QueryRow(ctx, query, d.Provider, d.EventID, d.Headers, d.Payload, d.Status)
+	return scanInboundDelivery(row)
+}
+
+// FindInboundDelivery fetches a delivery by ID.
+func (s *Store) FindInboundDelivery(ctx context.Context, id int64) (models.InboundDelivery, error) {
+	const query = `SELECT ` + inboundDeliveryColumns + ` FROM inbound_deliveries WHERE id = $1;`
+	return scanInboundDelivery(s.db.QueryRow(ctx, query, id))
+}
+
+// ListInboundDeliveries returns the newest deliveries matching filter first.
+func (s *Store) ListInboundDeliveries(ctx context.Context, filter models.InboundDeliveryFilter, limit int) ([]models.InboundDelivery, error) {
+	const query = `
+	SELECT ` + inboundDeliveryColumns + `
+	FROM inbound_deliveries
+	WHERE ($1 = '' OR provider = $1)
+		AND ($2 = '' OR status = $2)
+		AND ($3 = '' OR event_id = $3)
+	ORDER BY received_at DESC, id DESC
+	LIMIT $4;
+	`
+	rows, err := s.reader().Query(ctx, query, filter.Provider, filter.Status, filter.EventID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("list inbound deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	deliveries := []models.InboundDelivery{}
+	for rows.Next() {
+		d, err := scanInboundDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, rows.Err()
+}
+
+// UpdateInboundDelivery records the outcome of one processing attempt.
+func (s *Store) UpdateInboundDelivery(ctx context.Context, id int64, status, errMsg string, at time.Time) error {
+	const query = `
+	UPDATE inbound_deliveries
+	SET status = $2, error = $3, attempts = attempts + 1, last_attempt_at = $4
+	WHERE id = $1;
+	`
+	tag, err := s.db.Exec(ctx, query, id, status, errMsg, at)
+	if err != nil {
+		return fmt.Errorf("update inbound delivery: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return storage.ErrNotFound
+	}
+	return nil
+}
+
+// ClaimInboundEvent marks a provider event as applied. Concurrent claims for
+// the same event block on the primary key until the first transaction ends, so
+// only one of them can succeed.
+func (s *Store) ClaimInboundEvent(ctx context.Context, provider, eventID string, deliveryID int64) error {
+	const query = `
+	INSERT INTO inbound_events (provider, event_id, delivery_id)
+	VALUES ($1, $2, $3)
+	ON CONFLICT (provider, event_id) DO NOTHING;
+	`
+	tag, err := s.db.Exec(ctx, query, provider, eventID, deliveryID)
+	if err != nil {
+		return fmt.Errorf("claim inbound event: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return storage.ErrAlreadyExists
+	}
+	return nil
+}
+
+func scanInboundDelivery(row pgx.Row) (models.InboundDelivery, error) {
+	var d models.InboundDelivery
+	if err := row.Scan(&d.ID, &d.Provider, &d.EventID, &d.Headers, &d.Payload, &d.Status, &d.Error, &d.Attempts, &d.ReceivedAt, &d.LastAttemptAt); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.InboundDelivery{}, storage.ErrNotFound
+		}
+		return models.InboundDelivery{}, err
+	}
+	return d, nil
+}
@@ -0,0 +1,119 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/hongminglow/all-in-be/internal/models"
+	"github.com/hongminglow/all-in-be/internal/storage"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// ListIPRiskPolicies returns every configured policy.
+func (s *Store) ListIPRiskPolicies(ctx context.Context) ([]models.IPRiskPolicy, error) {
+	rows, err := s.reader().Query(ctx, `SELECT tenant, country, action, updated_at FROM ip_risk_policies ORDER BY tenant, country;`)
+	if err != nil {
+		return nil, fmt.Errorf("list ip risk policies: %w", err)
+	}
+	defer rows.Close()
+
+	policies := []models.IPRiskPolicy{}
+	for rows.Next() {
+		policy, err := scanIPRiskPolicy(rows)
+		if err != nil {
+			return nil, err
+		}
+		policies = append(policies, policy)
+	}
+	return policies, rows.Err()
+}
+
+// FindIPRiskPolicy fetches the policy for a tenant and country.
+func (s *Store) FindIPRiskPolicy(ctx context.Context, tenant, country string) (models.IPRiskPolicy, error) {
+	const query = `
+	SELECT tenant, country, action, updated_at
+	FROM ip_risk_policies
+	WHERE tenant = $1 AND country = $2;
+	`
+	return scanIPRiskPolicy(s.reader().QueryRow(ctx, query, tenant, country))
+}
+
+// UpsertIPRiskPolicy creates or replaces the policy for its tenant and country.
+func (s *Store) UpsertIPRiskPolicy(ctx context.Context, policy models.IPRiskPolicy) (models.IPRiskPolicy, error) {
+	const query = `
+	INSERT INTO ip_risk_policies (tenant, country, action)
+	VALUES ($1, $2, $3)
+	ON CONFLICT (tenant, country) DO UPDATE
+	SET action = EXCLUDED.action, updated_at = NOW()
+	RETURNING tenant, country, action, updated_at;
+	`
+	return scanIPRiskPolicy(s.db.QueryRow(ctx, query, policy.Tenant, policy.Country, policy.Action))
+}
+
+// DeleteIPRiskPolicy removes a policy so the tenant and country fall back to a broader one.
+func (s *Store) DeleteIPRiskPolicy(ctx context.Context, tenant, country string) error {
+	tag, err := s.db.Exec(ctx, `DELETE FROM ip_risk_policies WHERE tenant = $1 AND country = $2;`, tenant, country)
+	if err != nil {
+		return fmt.Errorf("delete ip risk policy: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return storage.ErrNotFound
+	}
+	return nil
+}
+
+// RecordIPRiskEvent stores a risky request.
+func (s *Store) RecordIPRiskEvent(ctx context.Context, event models.IPRiskEvent) (models.IPRiskEvent, error) {
+	const query = `
+	INSERT INTO ip_risk_events (user_id, ip, country, checkpoint, signals, action)
+	VALUES ($1, $2, $3, $4, $5, $6)
+	RETURNING id, created_at;
+	`
+	err := s.db.QueryRow(ctx, query, event.UserID, event.IP, event.Country, event.Checkpoint, event.Signals, event.Action).Scan(&event.ID, &event.CreatedAt)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+			return models.IPRiskEvent{}, storage.ErrNotFound
+		}
+		return models.IPRiskEvent{}, fmt.Errorf("record ip risk event: %w", err)
+	}
+	return event, nil
+}
+
+// ListIPRiskEvents returns the most recent events, newest first.
+func (s *Store) ListIPRiskEvents(ctx context.Context, limit int) ([]models.IPRiskEvent, error) {
+	const query = `
+	SELECT id, user_id, ip, country, checkpoint, signals, action, created_at
+	FROM ip_risk_events
+	ORDER BY created_at DESC, id DESC
+	LIMIT $1;
+	`
+	rows, err := s.reader().Query(ctx, query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("list ip risk events: %w", err)
+	}
+	defer rows.Close()
+
+	events := []models.IPRiskEvent{}
+	for rows.Next() {
+		var e models.IPRiskEvent
+		if err := rows.Scan(&e.ID, &e.UserID, &e.IP, &e.Country, &e.Checkpoint, &e.Signals, &e.Action, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
+
+func scanIPRiskPolicy(row pgx.Row) (models.IPRiskPolicy, error) {
+	var policy models.IPRiskPolicy
+	if err := row.Scan(&policy.Tenant, &policy.Country, &policy.Action, &policy.UpdatedAt); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.IPRiskPolicy{}, storage.ErrNotFound
+		}
+		return models.IPRiskPolicy{}, err
+	}
+	return policy, nil
+}
@@ -0,0 +1,71 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/hongminglow/all-in-be/internal/models"
+	"github.com/jackc/pgx/v5"
+)
+
+// ContributeJackpot grows the pot in place, so concurrent stakes add up
+// without a lost update: each waits for the row lock the one before holds
+// until it commits.
+func (s *Store) ContributeJackpot(ctx context.Context, name string, amount float64, reference string) (models.Jackpot, error) {
+	const query = `
+	INSERT INTO jackpots (name, pot, updated_at) VALUES ($1, $2, NOW())
+	ON CONFLICT (name) DO UPDATE SET pot = jackpots.pot + EXCLUDED.pot, updated_at = EXCLUDED.updated_at
+	RETURNING name, pot, updated_at;
+	`
+	var pot models.Jackpot
+	err := pgx.BeginFunc(ctx, s.db, func(tx pgx.Tx) error {
+		if err := tx.QueryRow(ctx, query, name, amount).Scan(&pot.Name, &pot.Pot, &pot.UpdatedAt); err != nil {
+			return err
+		}
+		return postJournal(ctx, tx, models.JackpotContributionJournal(name, amount, reference))
+	})
+	if err != nil {
+		return models.Jackpot{}, fmt.Errorf("contribute to jackpot %s: %w", name, err)
+	}
+	return pot, nil
+}
+
+// ListJackpots reads the pots from the replica.
+func (s *Store) ListJackpots(ctx context.Context) ([]models.Jackpot, error) {
+	rows, err := s.reader().Query(ctx, `SELECT name, pot, updated_at FROM jackpots ORDER BY name;`)
+	if err != nil {
+		return nil, fmt.Errorf("list jackpots: %w", err)
+	}
+	defer rows.Close()
+
+	pots := []models.Jackpot{}
+	for rows.Next() {
+		var pot models.Jackpot
+		if err := rows.Scan(&pot.Name, &pot.Pot, &pot.UpdatedAt); err != nil {
+			return nil, err
+		}
+		pots = append(pots, pot)
+	}
+	return pots, rows.Err()
+}
+
+// EmptyJackpot returns the pot as it was before the update; the row lock the
+// update takes holds off contributions until the win commits.
+func (s *Store) EmptyJackpot(ctx context.Context, name string) (float64, error) {
+	const query = `
+	UPDATE jackpots j SET pot = 0, updated_at = NOW()
+	FROM (SELECT name, pot FROM jackpots WHERE name = $1 FOR UPDATE) won
+	WHERE j.name = won.name
+	RETURNING won.pot;
+	`
+	var won float64
+	err := s.db.QueryRow(ctx, query, name).Scan(&won)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("empty jackpot %s: %w", name, err)
+	}
+	return won, nil
+}
@@ -0,0 +1,95 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/hongminglow/all-in-be/internal/models"
+	"github.com/hongminglow/all-in-be/internal/storage"
+	"github.com/jackc/pgx/v5"
+)
+
+// leaderboardColumns maps each metric and window to its leaderboard_stats column.
+var leaderboardColumns = map[[2]string]string{
+	{models.LeaderboardBalance, models.LeaderboardAllTime}:     "balance",
+	{models.LeaderboardWinnings, models.LeaderboardDaily}:      "winnings_daily",
+	{models.LeaderboardWinnings, models.LeaderboardWeekly}:     "winnings_weekly",
+	{models.LeaderboardWinnings, models.LeaderboardAllTime}:    "winnings_all_time",
+	{models.LeaderboardGamesPlayed, models.LeaderboardDaily}:   "games_daily",
+	{models.LeaderboardGamesPlayed, models.LeaderboardWeekly}:  "games_weekly",
+	{models.LeaderboardGamesPlayed, models.LeaderboardAllTime}: "games_all_time",
+}
+
+// visibleStandings selects the players ranked on a column, named value, with
+// their privacy setting; $1 is the default setting and $2 the hidden one.
+const visibleStandings = `
+	SELECT l.user_id, l.username, COALESCE(p.public_activity, $1) AS public_activity, l.%s::float8 AS value
+	FROM leaderboard_stats l
+	LEFT JOIN user_privacy p ON p.user_id = l.user_id
+	WHERE l.%[1]s > 0 AND COALESCE(p.public_activity, $1) <> $2
+`
+
+// RefreshLeaderboards rebuilds leaderboard_stats without blocking readers.
+func (s *Store) RefreshLeaderboards(ctx context.Context) error {
+	if _, err := s.db.Exec(ctx, `REFRESH MATERIALIZED VIEW CONCURRENTLY leaderboard_stats;`); err != nil {
+		return fmt.Errorf("refresh leaderboards: %w", err)
+	}
+	return nil
+}
+
+// ListLeaderboard ranks players on the replica; ties share a rank.
+func (s *Store) ListLeaderboard(ctx context.Context, metric, window string, limit int) ([]models.LeaderboardEntry, error) {
+	column, ok := leaderboardColumns[[2]string{metric, window}]
+	if !ok {
+		return nil, fmt.Errorf("list leaderboard: no %s %s ranking", window, metric)
+	}
+	query := `
+	WITH visible AS (` + fmt.Sprintf(visibleStandings, column) + `)
+	SELECT RANK() OVER (ORDER BY value DESC), user_id, username, public_activity, value
+	FROM visible
+	ORDER BY value DESC, user_id
+	LIMIT $3;
+	`
+	rows, err := s.reader().Query(ctx, query, models.ActivityMasked, models.ActivityHidden, limit)
+	if err != nil {
+		return nil, fmt.Errorf("list leaderboard: %w", err)
+	}
+	defer rows.Close()
+
+	entries := []models.LeaderboardEntry{}
+	for rows.Next() {
+		e, err := scanLeaderboardEntry(rows)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+// FindLeaderboardEntry ranks one player among the visible ones.
+func (s *Store) FindLeaderboardEntry(ctx context.Context, metric, window string, userID int64) (models.LeaderboardEntry, error) {
+	column, ok := leaderboardColumns[[2]string{metric, window}]
+	if !ok {
+		return models.LeaderboardEntry{}, fmt.Errorf("find leaderboard entry: no %s %s ranking", window, metric)
+	}
+	query := `
+	WITH visible AS (` + fmt.Sprintf(visibleStandings, column) + `)
+	SELECT (SELECT COUNT(*) FROM visible o WHERE o.value > v.value) + 1, v.user_id, v.username, v.public_activity, v.value
+	FROM visible v
+	WHERE v.user_id = $3;
+	`
+	return scanLeaderboardEntry(s.reader().QueryRow(ctx, query, models.ActivityMasked, models.ActivityHidden, userID))
+}
+
+func scanLeaderboardEntry(row pgx.Row) (models.LeaderboardEntry, error) {
+	var e models.LeaderboardEntry
+	if err := row.Scan(&e.Rank, &e.UserID, &e.Username, &e.PublicActivity, &e.Value); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.LeaderboardEntry{}, storage.ErrNotFound
+		}
+		return models.LeaderboardEntry{}, err
+	}
+	return e, nil
+}
@@ -0,0 +1,102 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/hongminglow/all-in-be/internal/models"
+	"github.com/hongminglow/all-in-be/internal/storage"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// openWallet creates the user's wallet account unless it exists, posting the
+// balance the user already had against equity. Migrations open the wallets
+// of every user at startup, so this only catches users created since.
+// Callers run it before they move the balance.
+func openWallet(ctx context.Context, tx pgx.Tx, userID int64) error {
+	const query = `
+	INSERT INTO ledger_accounts (code, kind, user_id) VALUES ($1, 'wallet', $2)
+	ON CONFLICT DO NOTHING
+	RETURNING (SELECT balance FROM users WHERE id = $2);
+	`
+	var balance float64
+	err := tx.QueryRow(ctx, query, models.WalletAccount(userID), userID).Scan(&balance)
+	var pgErr *pgconn.PgError
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		return nil
+	case errors.As(err, &pgErr) && pgErr.Code == "23503":
+		return storage.ErrNotFound
+	case err != nil:
+		return fmt.Errorf("open wallet of user %d: %w", userID, err)
+	}
+	if opening := models.OpeningJournal(userID, balance); len(opening.Lines) > 0 {
+		return postJournal(ctx, tx, opening)
+	}
+	return nil
+}
+
+// postJournal writes a balanced entry, creating the accounts other than
+// wallets on first use. The deferred journal_lines_balanced trigger checks
+// the balance again at commit.
+func postJournal(ctx context.Context, tx pgx.Tx, journal models.JournalEntry) error {
+	if !journal.Balanced() {
+		return storage.ErrUnbalancedJournal
+	}
+	var entryID int64
+	const entry = `INSERT INTO journal_entries (transaction_id, reason, reference) VALUES ($1, $2, $3) RETURNING id;`
+	if err := tx.QueryRow(ctx, entry, journal.TransactionID, journal.Reason, journal.Reference).Scan(&entryID); err != nil {
+		return fmt.Errorf("post journal entry: %w", err)
+	}
+	const line = `
+	WITH opened AS (
+		INSERT INTO ledger_accounts (code, kind) SELECT $2, $3 WHERE $3 <> 'wallet'
+		ON CONFLICT DO NOTHING
+		RETURNING id
+	)
+	INSERT INTO journal_lines (entry_id, account_id, debit, credit)
+	SELECT $1::BIGINT, id, $4::NUMERIC, $5::NUMERIC FROM opened
+	UNION ALL
+	SELECT $1, id, $4, $5 FROM ledger_accounts WHERE code = $2;
+	`
+	for _, l := range journal.Lines {
+		tag, err := tx.Exec(ctx, line, entryID, l.Account, models.AccountKind(l.Account), l.Debit, l.Credit)
+		if err != nil {
+			return fmt.Errorf("post journal line to %s: %w", l.Account, err)
+		}
+		if tag.RowsAffected() != 1 {
+			return fmt.Errorf("post journal line: account %s is not open", l.Account)
+		}
+	}
+	return nil
+}
+
+// TrialBalance reads the totals from the replica in one statement, so they
+// share a snapshot and whole entries are either in or out.
+func (s *Store) TrialBalance(ctx context.Context) ([]models.TrialBalanceLine, error) {
+	const query = `
+	SELECT CASE WHEN a.kind = 'wallet' THEN a.kind ELSE a.code END, a.kind,
+		COUNT(DISTINCT a.id), COALESCE(SUM(l.debit), 0), COALESCE(SUM(l.credit), 0)
+	FROM ledger_accounts a
+	LEFT JOIN journal_lines l ON l.account_id = a.id
+	GROUP BY 1, 2
+	ORDER BY 1;
+	`
+	rows, err := s.reader().Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("trial balance: %w", err)
+	}
+	defer rows.Close()
+
+	lines := []models.TrialBalanceLine{}
+	for rows.Next() {
+		var l models.TrialBalanceLine
+		if err := rows.Scan(&l.Account, &l.Kind, &l.Accounts, &l.Debits, &l.Credits); err != nil {
+			return nil, err
+		}
+		lines = append(lines, l)
+	}
+	return lines, rows.Err()
+}
@@ -0,0 +1,73 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/hongminglow/all-in-be/internal/models"
+	"github.com/hongminglow/all-in-be/internal/storage"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const legalHoldColumns = `id, user_id, dataset, reason, placed_by, placed_at, released_by, released_at`
+
+// PlaceLegalHold stores a new active hold. It returns ErrNotFound when the
+// user does not exist.
+func (s *Store) PlaceLegalHold(ctx context.Context, hold models.LegalHold) (models.LegalHold, error) {
+	const query = `
+	INSERT INTO legal_holds (user_id, dataset, reason, placed_by)
+	VALUES ($1, $2, $3, $4)
+	RETURNING ` + legalHoldColumns + `;
+	`
+	saved, err := scanLegalHold(s.db.QueryRow(ctx, query, hold.UserID, hold.Dataset, hold.Reason, hold.PlacedBy))
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+		return models.LegalHold{}, storage.ErrNotFound
+	}
+	if err != nil {
+		return models.LegalHold{}, fmt.Errorf("place legal hold: %w", err)
+	}
+	return saved, nil
+}
+
+// ListLegalHolds returns the user's holds, newest first.
+func (s *Store) ListLegalHolds(ctx context.Context, userID int64) ([]models.LegalHold, error) {
+	rows, err := s.db.Query(ctx, `SELECT `+legalHoldColumns+` FROM legal_holds WHERE user_id = $1 ORDER BY id DESC;`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("list legal holds: %w", err)
+	}
+	defer rows.Close()
+
+	holds := []models.LegalHold{}
+	for rows.Next() {
+		h, err := scanLegalHold(rows)
+		if err != nil {
+			return nil, err
+		}
+		holds = append(holds, h)
+	}
+	return holds, rows.Err()
+}
+
+// ReleaseLegalHold ends an active hold; a released one is reported as ErrNotFound.
+func (s *Store) ReleaseLegalHold(ctx context.Context, userID, id, releasedBy int64) (models.LegalHold, error) {
+	const query = `
+	UPDATE legal_holds SET released_by = $3, released_at = NOW()
+	WHERE id = $1 AND user_id = $2 AND released_at IS NULL
+	RETURNING ` + legalHoldColumns + `;
+	`
+	return scanLegalHold(s.db.QueryRow(ctx, query, id, userID, releasedBy))
+}
+
+func scanLegalHold(row pgx.Row) (models.LegalHold, error) {
+	var h models.LegalHold
+	if err := row.Scan(&h.ID, &h.UserID, &h.Dataset, &h.Reason, &h.PlacedBy, &h.PlacedAt, &h.ReleasedBy, &h.ReleasedAt); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.LegalHold{}, storage.ErrNotFound
+		}
+		return models.LegalHold{}, err
+	}
+	return h, nil
+}
@@ -0,0 +1,75 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/hongminglow/all-in-be/internal/models"
+	"github.com/hongminglow/all-in-be/internal/storage"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const gamingLimitColumns = `user_id, daily_deposit, daily_loss, session_minutes, pending_daily_deposit, pending_daily_loss, pending_session_minutes, pending_from, excluded_until, updated_at`
+
+// FindGamingLimits fetches a player's limits from the primary, since they are
+// checked inside the transactions that move money.
+func (s *Store) FindGamingLimits(ctx context.Context, userID int64) (models.GamingLimits, error) {
+	const query = `SELECT ` + gamingLimitColumns + ` FROM gaming_limits WHERE user_id = $1;`
+	return scanGamingLimits(s.db.QueryRow(ctx, query, userID))
+}
+
+// SaveGamingLimits upserts a player's limits.
+func (s *Store) SaveGamingLimits(ctx context.Context, g models.GamingLimits) (models.GamingLimits, error) {
+	const query = `
+	INSERT INTO gaming_limits (` + gamingLimitColumns + `)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+	ON CONFLICT (user_id) DO UPDATE SET
+		daily_deposit = EXCLUDED.daily_deposit,
+		daily_loss = EXCLUDED.daily_loss,
+		session_minutes = EXCLUDED.session_minutes,
+		pending_daily_deposit = EXCLUDED.pending_daily_deposit,
+		pending_daily_loss = EXCLUDED.pending_daily_loss,
+		pending_session_minutes = EXCLUDED.pending_session_minutes,
+		pending_from = EXCLUDED.pending_from,
+		excluded_until = EXCLUDED.excluded_until,
+		updated_at = EXCLUDED.updated_at
+	RETURNING ` + gamingLimitColumns + `;
+	`
+	var pending models.LimitSet
+	if g.Pending != nil {
+		pending = *g.Pending
+	}
+	saved, err := scanGamingLimits(s.db.QueryRow(ctx, query,
+		g.UserID, g.Limits.DailyDeposit, g.Limits.DailyLoss, g.Limits.SessionMinutes,
+		pending.DailyDeposit, pending.DailyLoss, pending.SessionMinutes, g.PendingFrom,
+		g.ExcludedUntil, g.UpdatedAt))
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+			return models.GamingLimits{}, storage.ErrNotFound
+		}
+		return models.GamingLimits{}, fmt.Errorf("save gaming limits: %w", err)
+	}
+	return saved, nil
+}
+
+// scanGamingLimits reads a row whose pending limits are set exactly when
+// pending_from is.
+func scanGamingLimits(row pgx.Row) (models.GamingLimits, error) {
+	var g models.GamingLimits
+	var pending models.LimitSet
+	if err := row.Scan(&g.UserID, &g.Limits.DailyDeposit, &g.Limits.DailyLoss, &g.Limits.SessionMinutes,
+		&pending.DailyDeposit, &pending.DailyLoss, &pending.SessionMinutes, &g.PendingFrom,
+		&g.ExcludedUntil, &g.UpdatedAt); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.GamingLimits{}, storage.ErrNotFound
+		}
+		return models.GamingLimits{}, err
+	}
+	if g.PendingFrom != nil {
+		g.Pending = &pending
+	}
+	return g, nil
+}
@@ -0,0 +1,131 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/hongminglow/all-in-be/internal/models"
+	"github.com/hongminglow/all-in-be/internal/storage"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const messageColumns = `id, user_id, kind, subject, body, sender_id, read_at, created_at`
+
+func (s *Store) CreateMessage(ctx context.Context, m models.Message) (models.Message, error) {
+	const query = `
+	INSERT INTO user_messages (user_id, kind, subject, body, sender_id)
+	VALUES ($1, $2, $3, $4, $5)
+	RETURNING ` + messageColumns + `;
+	`
+	created, err := scanMessage(s.db.QueryRow(ctx, query, m.UserID, m.Kind, m.Subject, m.Body, m.SenderID))
+	var pgErr *pgconn.PgError
+	switch {
+	case errors.As(err, &pgErr) && pgErr.Code == "23503":
+		return models.Message{}, storage.ErrNotFound
+	case err != nil:
+		return models.Message{}, fmt.Errorf("create message: %w", err)
+	}
+	return created, nil
+}
+
+// BroadcastMessage copies the message to every recipient in one statement,
+// so a broadcast is delivered to all of them or none.
+func (s *Store) BroadcastMessage(ctx context.Context, m models.Message, roles []string) (int, error) {
+	const query = `
+	INSERT INTO user_messages (user_id, kind, subject, body, sender_id)
+	SELECT id, $1, $2, $3, $4 FROM users
+	WHERE merged_into IS NULL AND (cardinality($5::TEXT[]) = 0 OR role = ANY($5))
+	ORDER BY id;
+	`
+	if roles == nil {
+		roles = []string{}
+	}
+	tag, err := s.db.Exec(ctx, query, m.Kind, m.Subject, m.Body, m.SenderID, roles)
+	if err != nil {
+		return 0, fmt.Errorf("broadcast message: %w", err)
+	}
+	return int(tag.RowsAffected()), nil
+}
+
+func (s *Store) ListMessages(ctx context.Context, userID int64, unreadOnly bool, limit int) ([]models.Message, error) {
+	const query = `
+	SELECT ` + messageColumns + ` FROM user_messages
+	WHERE user_id = $1 AND (NOT $2 OR read_at IS NULL)
+	ORDER BY id DESC LIMIT $3;
+	`
+	return s.listMessages(ctx, query, userID, unreadOnly, limit)
+}
+
+// ListMessagesAfter reads from the primary, like ListTransactionsAfter: a
+// stream woken for a new message must find it.
+func (s *Store) ListMessagesAfter(ctx context.Context, userID, after int64, limit int) ([]models.Message, error) {
+	const query = `
+	SELECT ` + messageColumns + ` FROM user_messages
+	WHERE user_id = $1 AND id > $2
+	ORDER BY id LIMIT $3;
+	`
+	return s.listMessages(ctx, query, userID, after, limit)
+}
+
+func (s *Store) listMessages(ctx context.Context, query string, args ...any) ([]models.Message, error) {
+	rows, err := s.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("list messages: %w", err)
+	}
+	defer rows.Close()
+
+	messages := []models.Message{}
+	for rows.Next() {
+		m, err := scanMessage(rows)
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, m)
+	}
+	return messages, rows.Err()
+}
+
+func (s *Store) LastMessageID(ctx context.Context, userID int64) (int64, error) {
+	var id int64
+	if err := s.db.QueryRow(ctx, `SELECT COALESCE(MAX(id), 0) FROM user_messages WHERE user_id = $1;`, userID).Scan(&id); err != nil {
+		return 0, fmt.Errorf("last message id: %w", err)
+	}
+	return id, nil
+}
+
+func (s *Store) CountUnreadMessages(ctx context.Context, userID int64) (int, error) {
+	var n int
+	if err := s.db.QueryRow(ctx, `SELECT COUNT(*) FROM user_messages WHERE user_id = $1 AND read_at IS NULL;`, userID).Scan(&n); err != nil {
+		return 0, fmt.Errorf("count unread messages: %w", err)
+	}
+	return n, nil
+}
+
+func (s *Store) MarkMessagesRead(ctx context.Context, userID int64, ids []int64, at time.Time) (int, error) {
+	const query = `
+	UPDATE user_messages SET read_at = $3
+	WHERE user_id = $1 AND read_at IS NULL AND (cardinality($2::BIGINT[]) = 0 OR id = ANY($2));
+	`
+	if ids == nil {
+		ids = []int64{}
+	}
+	tag, err := s.db.Exec(ctx, query, userID, ids, at)
+	if err != nil {
+		return 0, fmt.Errorf("mark messages read: %w", err)
+	}
+	return int(tag.RowsAffected()), nil
+}
+
+func scanMessage(row pgx.Row) (models.Message, error) {
+	var m models.Message
+	if err := row.Scan(&m.ID, &m.UserID, &m.Kind, &m.Subject, &m.Body, &m.SenderID, &m.ReadAt, &m.CreatedAt); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.Message{}, storage.ErrNotFound
+		}
+		return models.Message{}, err
+	}
+	return m, nil
+}
@@ -0,0 +1,138 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/hongminglow/all-in-be/internal/models"
+	"github.com/hongminglow/all-in-be/internal/storage"
+	"github.com/jackc/pgx/v5"
+)
+
+const noteColumns = `n.id, n.user_id, n.author_id, a.username, n.body, n.pinned, n.created_at, n.updated_at`
+
+// CreateNote inserts a staff note for a user.
+func (s *Store) CreateNote(ctx context.Context, note models.UserNote) (models.UserNote, error) {
+	const query = `
+	WITH inserted AS (
+		INSERT INTO user_notes (user_id, author_id, body, pinned)
+		VALUES ($1, $2, $3, $4)
+		RETURNING *
+	)
+	SELECT ` + noteColumns + `
+	FROM inserted n
+	JOIN users a ON a.id = n.author_id;
+	`
+	row := s.db.QueryRow(ctx, query, note.UserID, note.AuthorID, note.Body, note.Pinned)
+	return scanNote(row)
+}
+
+// ListNotes returns a user's notes, pinned notes first and newest first within each group.
+func (s *Store) ListNotes(ctx context.Context, userID int64) ([]models.UserNote, error) {
+	const query = `
+	SELECT ` + noteColumns + `
+	FROM user_notes n
+	JOIN users a ON a.id = n.author_id
+	WHERE n.user_id = $1
+	ORDER BY n.pinned DESC, n.created_at DESC;
+	`
+	rows, err := s.reader().Query(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("list notes: %w", err)
+	}
+	defer rows.Close()
+
+	notes := []models.UserNote{}
+	for rows.Next() {
+		note, err := scanNote(rows)
+		if err != nil {
+			return nil, err
+		}
+		notes = append(notes, note)
+	}
+	return notes, rows.Err()
+}
+
+// FindNote fetches a single note belonging to the given user.
+func (s *Store) FindNote(ctx context.Context, userID, noteID int64) (models.UserNote, error) {
+	const query = `
+	SELECT ` + noteColumns + `
+	FROM user_notes n
+	JOIN users a ON a.id = n.author_id
+	WHERE n.user_id = $1 AND n.id = $2;
+	`
+	row := s.reader().QueryRow(ctx, query, userID, noteID)
+	return scanNote(row)
+}
+
+// UpdateNote stores the new body and pinned flag, archiving the previous body as a
+// revision when it changed. Both writes happen in one transaction.
+func (s *Store) UpdateNote(ctx context.Context, note models.UserNote, editorID int64) (models.UserNote, error) {
+	var updated models.UserNote
+	err := pgx.BeginFunc(ctx, s.db, func(tx pgx.Tx) error {
+		const archive = `
+		INSERT INTO user_note_revisions (note_id, body, editor_id)
+		SELECT id, body, $3 FROM user_notes
+		WHERE id = $1 AND body <> $2;
+		`
+		if _, err := tx.Exec(ctx, archive, note.ID, note.Body, editorID); err != nil {
+			return fmt.Errorf("archive note revision: %w", err)
+		}
+		const query = `
+		WITH changed AS (
+			UPDATE user_notes
+			SET body = $3, pinned = $4, updated_at = NOW()
+			WHERE id = $1 AND user_id = $2
+			RETURNING *
+		)
+		SELECT ` + noteColumns + `
+		FROM changed n
+		JOIN users a ON a.id = n.author_id;
+		`
+		var err error
+		updated, err = scanNote(tx.QueryRow(ctx, query, note.ID, note.UserID, note.Body, note.Pinned))
+		return err
+	})
+	if err != nil {
+		return models.UserNote{}, err
+	}
+	return updated, nil
+}
+
+// ListNoteRevisions returns the edit history of a note, newest first.
+func (s *Store) ListNoteRevisions(ctx context.Context, noteID int64) ([]models.NoteRevision, error) {
+	const query = `
+	SELECT r.id, r.note_id, r.body, r.editor_id, e.username, r.edited_at
+	FROM user_note_revisions r
+	JOIN users e ON e.id = r.editor_id
+	WHERE r.note_id = $1
+	ORDER BY r.edited_at DESC, r.id DESC;
+	`
+	rows, err := s.reader().Query(ctx, query, noteID)
+	if err != nil {
+		return nil, fmt.Errorf("list note revisions: %w", err)
+	}
+	defer rows.Close()
+
+	revisions := []models.NoteRevision{}
+	for rows.Next() {
+		var rev models.NoteRevision
+		if err := rows.Scan(&rev.ID, &rev.NoteID, &rev.Body, &rev.EditorID, &rev.EditorUsername, &rev.EditedAt); err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, rev)
+	}
+	return revisions, rows.Err()
+}
+
+func scanNote(row pgx.Row) (models.UserNote, error) {
+	var note models.UserNote
+	if err := row.Scan(&note.ID, &note.UserID, &note.AuthorID, &note.AuthorUsername, &note.Body, &note.Pinned, &note.CreatedAt, &note.UpdatedAt); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.UserNote{}, storage.ErrNotFound
+		}
+		return models.UserNote{}, err
+	}
+	return note, nil
+}
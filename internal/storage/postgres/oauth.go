@@ -0,0 +1,114 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/hongminglow/all-in-be/internal/models"
+	"github.com/hongminglow/all-in-be/internal/storage"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const oauthClientColumns = `id, name, redirect_uris, confidential, secret_hash, created_at`
+
+// ListOAuthClients returns every client ordered by name.
+func (s *Store) ListOAuthClients(ctx context.Context) ([]models.OAuthClient, error) {
+	rows, err := s.reader().Query(ctx, `SELECT `+oauthClientColumns+` FROM oauth_clients ORDER BY name, id;`)
+	if err != nil {
+		return nil, fmt.Errorf("list oauth clients: %w", err)
+	}
+	defer rows.Close()
+
+	clients := []models.OAuthClient{}
+	for rows.Next() {
+		c, err := scanOAuthClient(rows)
+		if err != nil {
+			return nil, err
+		}
+		clients = append(clients, c)
+	}
+	return clients, rows.Err()
+}
+
+// FindOAuthClient fetches a client by ID.
+func (s *Store) FindOAuthClient(ctx context.Context, id string) (models.OAuthClient, error) {
+	return scanOAuthClient(s.reader().QueryRow(ctx, `SELECT `+oauthClientColumns+` FROM oauth_clients WHERE id = $1;`, id))
+}
+
+// CreateOAuthClient registers a client.
+func (s *Store) CreateOAuthClient(ctx context.Context, client models.OAuthClient) (models.OAuthClient, error) {
+	const query = `
+	INSERT INTO oauth_clients (id, name, redirect_uris, confidential, secret_hash)
+	VALUES ($1, $2, $3, $4, $5)
+	RETURNING ` + oauthClientColumns + `;
+	`
+	created, err := scanOAuthClient(s.db.QueryRow(ctx, query, client.ID, client.Name, client.RedirectURIs, client.Confidential, client.SecretHash))
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return models.OAuthClient{}, storage.ErrAlreadyExists
+		}
+		return models.OAuthClient{}, fmt.Errorf("create oauth client: %w", err)
+	}
+	return created, nil
+}
+
+// DeleteOAuthClient removes a client and its outstanding codes.
+func (s *Store) DeleteOAuthClient(ctx context.Context, id string) error {
+	tag, err := s.db.Exec(ctx, `DELETE FROM oauth_clients WHERE id = $1;`, id)
+	if err != nil {
+		return fmt.Errorf("delete oauth client: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return storage.ErrNotFound
+	}
+	return nil
+}
+
+// CreateAuthorizationCode stores a code hash.
+func (s *Store) CreateAuthorizationCode(ctx context.Context, code models.AuthorizationCode) error {
+	const query = `
+	INSERT INTO oauth_codes (code_hash, client_id, user_id, redirect_uri, scope, nonce, code_challenge, expires_at)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8);
+	`
+	if _, err := s.db.Exec(ctx, query, code.CodeHash, code.ClientID, code.UserID, code.RedirectURI, code.Scope, code.Nonce, code.CodeChallenge, code.ExpiresAt); err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+			return storage.ErrNotFound
+		}
+		return fmt.Errorf("create authorization code: %w", err)
+	}
+	return nil
+}
+
+// ConsumeAuthorizationCode deletes an unexpired code and returns it.
+func (s *Store) ConsumeAuthorizationCode(ctx context.Context, codeHash string, now time.Time) (models.AuthorizationCode, error) {
+	const query = `
+	DELETE FROM oauth_codes
+	WHERE code_hash = $1 AND expires_at > $2
+	RETURNING code_hash, client_id, user_id, redirect_uri, scope, nonce, code_challenge, expires_at;
+	`
+	var c models.AuthorizationCode
+	err := s.db.QueryRow(ctx, query, codeHash, now).Scan(&c.CodeHash, &c.ClientID, &c.UserID, &c.RedirectURI, &c.Scope, &c.Nonce, &c.CodeChallenge, &c.ExpiresAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.AuthorizationCode{}, storage.ErrNotFound
+		}
+		return models.AuthorizationCode{}, fmt.Errorf("consume authorization code: %w", err)
+	}
+	return c, nil
+}
+
+func scanOAuthClient(row pgx.Row) (models.OAuthClient, error) {
+	var c models.OAuthClient
+	if err := row.Scan(&c.ID, &c.Name, &c.RedirectURIs, &c.Confidential, &c.SecretHash, &c.CreatedAt); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.OAuthClient{}, storage.ErrNotFound
+		}
+		return models.OAuthClient{}, err
+	}
+	return c, nil
+}
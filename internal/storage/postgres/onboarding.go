@@ -0,0 +1,157 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/hongminglow/all-in-be/internal/models"
+	"github.com/hongminglow/all-in-be/internal/storage"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// ListOnboardingJourneys returns every journey ordered by tenant.
+func (s *Store) ListOnboardingJourneys(ctx context.Context) ([]models.OnboardingJourney, error) {
+	const query = `SELECT tenant, steps, updated_at FROM onboarding_journeys ORDER BY tenant;`
+	rows, err := s.reader().Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("list onboarding journeys: %w", err)
+	}
+	defer rows.Close()
+
+	journeys := []models.OnboardingJourney{}
+	for rows.Next() {
+		j, err := scanOnboardingJourney(rows)
+		if err != nil {
+			return nil, err
+		}
+		journeys = append(journeys, j)
+	}
+	return journeys, rows.Err()
+}
+
+// FindOnboardingJourney fetches the tenant's journey.
+func (s *Store) FindOnboardingJourney(ctx context.Context, tenant string) (models.OnboardingJourney, error) {
+	const query = `SELECT tenant, steps, updated_at FROM onboarding_journeys WHERE tenant = $1;`
+	return scanOnboardingJourney(s.reader().QueryRow(ctx, query, tenant))
+}
+
+// SaveOnboardingJourney creates or replaces the tenant's journey.
+func (s *Store) SaveOnboardingJourney(ctx context.Context, journey models.OnboardingJourney) (models.OnboardingJourney, error) {
+	const query = `
+	INSERT INTO onboarding_journeys (tenant, steps)
+	VALUES ($1, $2)
+	ON CONFLICT (tenant) DO UPDATE SET steps = EXCLUDED.steps, updated_at = NOW()
+	RETURNING tenant, steps, updated_at;
+	`
+	steps, err := json.Marshal(journey.Steps)
+	if err != nil {
+		return models.OnboardingJourney{}, err
+	}
+	saved, err := scanOnboardingJourney(s.db.QueryRow(ctx, query, journey.Tenant, steps))
+	if err != nil {
+		return models.OnboardingJourney{}, fmt.Errorf("save onboarding journey: %w", err)
+	}
+	return saved, nil
+}
+
+// DeleteOnboardingJourney removes the tenant's journey.
+func (s *Store) DeleteOnboardingJourney(ctx context.Context, tenant string) error {
+	tag, err := s.db.Exec(ctx, `DELETE FROM onboarding_journeys WHERE tenant = $1;`, tenant)
+	if err != nil {
+		return fmt.Errorf("delete onboarding journey: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return storage.ErrNotFound
+	}
+	return nil
+}
+
+// ListOnboardingProgress returns the user's progress rows.
+func (s *Store) ListOnboardingProgress(ctx context.Context, userID int64) ([]models.OnboardingProgress, error) {
+	const query = `
+	SELECT user_id, step_key, nudged_at, completed_at
+	FROM onboarding_progress
+	WHERE user_id = $1
+	ORDER BY step_key;
+	`
+	// Read from the primary: the journey engine decides what to send next
+	// from a step it may have completed moments ago.
+	rows, err := s.db.Query(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("list onboarding progress: %w", err)
+	}
+	defer rows.Close()
+
+	progress := []models.OnboardingProgress{}
+	for rows.Next() {
+		var p models.OnboardingProgress
+		if err := rows.Scan(&p.UserID, &p.StepKey, &p.NudgedAt, &p.CompletedAt); err != nil {
+			return nil, err
+		}
+		progress = append(progress, p)
+	}
+	return progress, rows.Err()
+}
+
+// CompleteOnboardingStep marks the step complete unless it already was.
+func (s *Store) CompleteOnboardingStep(ctx context.Context, userID int64, stepKey string, at time.Time) error {
+	const query = `
+	INSERT INTO onboarding_progress (user_id, step_key, completed_at)
+	VALUES ($1, $2, $3)
+	ON CONFLICT (user_id, step_key) DO UPDATE SET completed_at = EXCLUDED.completed_at
+	WHERE onboarding_progress.completed_at IS NULL;
+	`
+	tag, err := s.db.Exec(ctx, query, userID, stepKey, at)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+			return storage.ErrNotFound
+		}
+		return fmt.Errorf("complete onboarding step: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return storage.ErrAlreadyExists
+	}
+	return nil
+}
+
+// ClaimOnboardingNudge records a nudge unless one was recorded after since.
+func (s *Store) ClaimOnboardingNudge(ctx context.Context, userID int64, stepKey string, at, since time.Time) error {
+	const query = `
+	INSERT INTO onboarding_progress (user_id, step_key, nudged_at)
+	VALUES ($1, $2, $3)
+	ON CONFLICT (user_id, step_key) DO UPDATE SET nudged_at = EXCLUDED.nudged_at
+	WHERE onboarding_progress.nudged_at IS NULL OR onboarding_progress.nudged_at <= $4;
+	`
+	tag, err := s.db.Exec(ctx, query, userID, stepKey, at, since)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+			return storage.ErrNotFound
+		}
+		return fmt.Errorf("claim onboarding nudge: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return storage.ErrAlreadyExists
+	}
+	return nil
+}
+
+func scanOnboardingJourney(row pgx.Row) (models.OnboardingJourney, error) {
+	var j models.OnboardingJourney
+	var steps []byte
+	if err := row.Scan(&j.Tenant, &steps, &j.UpdatedAt); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.OnboardingJourney{}, storage.ErrNotFound
+		}
+		return models.OnboardingJourney{}, err
+	}
+	if err := json.Unmarshal(steps, &j.Steps); err != nil {
+		return models.OnboardingJourney{}, fmt.Errorf("decode onboarding steps: %w", err)
+	}
+	return j, nil
+}
@@ -0,0 +1,85 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/hongminglow/all-in-be/internal/models"
+	"github.com/jackc/pgx/v5"
+)
+
+const outboxColumns = `id, type, data, created_at, sent_at`
+
+// AddOutboxEvent stores an event; call it on the transaction making the
+// change the event describes.
+func (s *Store) AddOutboxEvent(ctx context.Context, event models.OutboxEvent) (models.OutboxEvent, error) {
+	const query = `INSERT INTO event_outbox (type, data) VALUES ($1, $2) RETURNING ` + outboxColumns + `;`
+	added, err := scanOutboxEvent(s.db.QueryRow(ctx, query, event.Type, []byte(event.Data)))
+	if err != nil {
+		return models.OutboxEvent{}, fmt.Errorf("add outbox event: %w", err)
+	}
+	return added, nil
+}
+
+// PendingOutboxEvents reads from the primary, locking the rows it returns.
+func (s *Store) PendingOutboxEvents(ctx context.Context, limit int) ([]models.OutboxEvent, error) {
+	const query = `
+	SELECT ` + outboxColumns + ` FROM event_outbox
+	WHERE sent_at IS NULL
+	ORDER BY id
+	LIMIT $1
+	FOR UPDATE SKIP LOCKED;
+	`
+	rows, err := s.db.Query(ctx, query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("list pending outbox events: %w", err)
+	}
+	defer rows.Close()
+
+	pending := []models.OutboxEvent{}
+	for rows.Next() {
+		event, err := scanOutboxEvent(rows)
+		if err != nil {
+			return nil, err
+		}
+		pending = append(pending, event)
+	}
+	return pending, rows.Err()
+}
+
+func (s *Store) MarkOutboxEventsSent(ctx context.Context, ids []int64, at time.Time) error {
+	const query = `UPDATE event_outbox SET sent_at = $2 WHERE id = ANY($1) AND sent_at IS NULL;`
+	if _, err := s.db.Exec(ctx, query, ids, at); err != nil {
+		return fmt.Errorf("mark outbox events sent: %w", err)
+	}
+	return nil
+}
+
+func (s *Store) DeleteSentOutboxEvents(ctx context.Context, before time.Time, limit int) (int, error) {
+	const query = `
+	DELETE FROM event_outbox
+	WHERE id IN (
+		SELECT id FROM event_outbox
+		WHERE sent_at < $1
+		ORDER BY sent_at
+		LIMIT $2
+		FOR UPDATE SKIP LOCKED
+	);
+	`
+	tag, err := s.db.Exec(ctx, query, before, limit)
+	if err != nil {
+		return 0, fmt.Errorf("delete sent outbox events: %w", err)
+	}
+	return int(tag.RowsAffected()), nil
+}
+
+func scanOutboxEvent(row pgx.Row) (models.OutboxEvent, error) {
+	var e models.OutboxEvent
+	var data []byte
+	if err := row.Scan(&e.ID, &e.Type, &data, &e.CreatedAt, &e.SentAt); err != nil {
+		return models.OutboxEvent{}, err
+	}
+	e.Data = data
+	return e, nil
+}
@@ -0,0 +1,159 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/hongminglow/all-in-be/internal/storage"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// PoolConfig tunes the pgx connection pools. Zero values keep the pgx defaults
+// (or whatever the connection string sets).
+type PoolConfig struct {
+	MaxConns          int32
+	MinConns          int32
+	MaxConnLifetime   time.Duration
+	MaxConnIdleTime   time.Duration
+	HealthCheckPeriod time.Duration
+	// ExecMode is one of cache_statement (pgx default), cache_describe,
+	// describe_exec, exec or simple_protocol. Neon's pooled (PgBouncer)
+	// endpoints need exec or simple_protocol because prepared statements do
+	// not survive across server connections.
+	ExecMode string
+	// StatementTimeout is sent as the statement_timeout session parameter, so
+	// Postgres cancels any statement that runs longer. Zero keeps the server's.
+	StatementTimeout time.Duration
+	// QueryTimeout bounds each query, including the wait for a connection,
+	// on the client side. Zero disables it.
+	QueryTimeout time.Duration
+	// Retry governs retries of transient errors and the circuit breaker on
+	// the primary. The zero value disables both.
+	Retry RetryPolicy
+}
+
+var execModes = map[string]pgx.QueryExecMode{
+	"cache_statement": pgx.QueryExecModeCacheStatement,
+	"cache_describe":  pgx.QueryExecModeCacheDescribe,
+	"describe_exec":   pgx.QueryExecModeDescribeExec,
+	"exec":            pgx.QueryExecModeExec,
+	"simple_protocol": pgx.QueryExecModeSimpleProtocol,
+}
+
+// newPool connects a pool configured by poolConfig.
+func newPool(ctx context.Context, databaseURL string, pc PoolConfig) (*pgxpool.Pool, error) {
+	cfg, err := poolConfig(databaseURL, pc)
+	if err != nil {
+		return nil, err
+	}
+	pool, err := pgxpool.NewWithConfig(ctx, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("connect to database: %w", err)
+	}
+	return pool, nil
+}
+
+// poolConfig parses databaseURL and applies the pool settings.
+func poolConfig(databaseURL string, pc PoolConfig) (*pgxpool.Config, error) {
+	cfg, err := pgxpool.ParseConfig(databaseURL)
+	if err != nil {
+		return nil, fmt.Errorf("parse database url: %w", err)
+	}
+	if pc.MaxConns > 0 {
+		cfg.MaxConns = pc.MaxConns
+	}
+	if pc.MinConns > 0 {
+		cfg.MinConns = pc.MinConns
+	}
+	if cfg.MinConns > cfg.MaxConns {
+		return nil, fmt.Errorf("pool min conns (%d) exceeds max conns (%d)", cfg.MinConns, cfg.MaxConns)
+	}
+	if pc.MaxConnLifetime > 0 {
+		cfg.MaxConnLifetime = pc.MaxConnLifetime
+	}
+	if pc.MaxConnIdleTime > 0 {
+		cfg.MaxConnIdleTime = pc.MaxConnIdleTime
+	}
+	if pc.HealthCheckPeriod > 0 {
+		cfg.HealthCheckPeriod = pc.HealthCheckPeriod
+	}
+	if pc.ExecMode != "" {
+		mode, ok := execModes[pc.ExecMode]
+		if !ok {
+			return nil, fmt.Errorf("unknown statement cache mode %q", pc.ExecMode)
+		}
+		cfg.ConnConfig.DefaultQueryExecMode = mode
+	}
+	if pc.StatementTimeout > 0 {
+		cfg.ConnConfig.RuntimeParams["statement_timeout"] = strconv.FormatInt(pc.StatementTimeout.Milliseconds(), 10)
+	}
+	if preparesStatements(cfg) {
+		cfg.AfterConnect = prepareUserLookups
+	}
+	cfg.ConnConfig.Tracer = queryTracer{}
+	return cfg, nil
+}
+
+// preparesStatements reports whether connections keep named prepared
+// statements. The exec and simple_protocol modes are for PgBouncer, where a
+// statement prepared on one server connection is missing on the next.
+func preparesStatements(cfg *pgxpool.Config) bool {
+	switch cfg.ConnConfig.DefaultQueryExecMode {
+	case pgx.QueryExecModeExec, pgx.QueryExecModeSimpleProtocol:
+		return false
+	}
+	return true
+}
+
+func prepareUserLookups(ctx context.Context, conn *pgx.Conn) error {
+	for name, sql := range userLookups {
+		if _, err := conn.Prepare(ctx, name, sql); err != nil {
+			return fmt.Errorf("prepare %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// Ping checks that the primary accepts queries.
+func (s *Store) Ping(ctx context.Context) error {
+	if s.pool == nil {
+		return nil
+	}
+	return s.pool.Ping(ctx)
+}
+
+// Breaker reports the state of the circuit breaker in front of the primary.
+func (s *Store) Breaker() storage.BreakerState {
+	return s.breaker.state()
+}
+
+// PoolStats reports connection usage for the primary pool and, when attached,
+// the read replica.
+func (s *Store) PoolStats() []storage.PoolStats {
+	var stats []storage.PoolStats
+	if s.pool != nil {
+		stats = append(stats, poolStats("primary", s.pool))
+	}
+	if s.replica != nil {
+		stats = append(stats, poolStats("replica", s.replica.pool))
+	}
+	return stats
+}
+
+func poolStats(name string, pool *pgxpool.Pool) storage.PoolStats {
+	st := pool.Stat()
+	return storage.PoolStats{
+		Name:                 name,
+		MaxConns:             st.MaxConns(),
+		TotalConns:           st.TotalConns(),
+		IdleConns:            st.IdleConns(),
+		AcquiredConns:        st.AcquiredConns(),
+		AcquireCount:         st.AcquireCount(),
+		EmptyAcquireCount:    st.EmptyAcquireCount(),
+		CanceledAcquireCount: st.CanceledAcquireCount(),
+		AcquireDuration:      st.AcquireDuration(),
+	}
+}
@@ -0,0 +1,205 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/hongminglow/all-in-be/internal/models"
+	"github.com/hongminglow/all-in-be/internal/storage"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const promoCodeColumns = `id, code, campaign, amount, max_redemptions, per_user_limit, roles, expires_at, active, redemptions, created_at`
+
+// ListPromoCodes returns every code, newest first.
+func (s *Store) ListPromoCodes(ctx context.Context) ([]models.PromoCode, error) {
+	rows, err := s.reader().Query(ctx, `SELECT `+promoCodeColumns+` FROM promo_codes ORDER BY id DESC;`)
+	if err != nil {
+		return nil, fmt.Errorf("list promo codes: %w", err)
+	}
+	defer rows.Close()
+
+	codes := []models.PromoCode{}
+	for rows.Next() {
+		c, err := scanPromoCode(rows)
+		if err != nil {
+			return nil, err
+		}
+		codes = append(codes, c)
+	}
+	return codes, rows.Err()
+}
+
+// FindPromoCode fetches a code by ID.
+func (s *Store) FindPromoCode(ctx context.Context, id int64) (models.PromoCode, error) {
+	return scanPromoCode(s.db.QueryRow(ctx, `SELECT `+promoCodeColumns+` FROM promo_codes WHERE id = $1;`, id))
+}
+
+// FindPromoCodeByCode fetches a code by its text. It reads the primary so a
+// redemption sees codes created moments ago.
+func (s *Store) FindPromoCodeByCode(ctx context.Context, code string) (models.PromoCode, error) {
+	return scanPromoCode(s.db.QueryRow(ctx, `SELECT `+promoCodeColumns+` FROM promo_codes WHERE code = $1;`, code))
+}
+
+// CreatePromoCode stores a new code.
+func (s *Store) CreatePromoCode(ctx context.Context, code models.PromoCode) (models.PromoCode, error) {
+	const query = `
+	INSERT INTO promo_codes (code, campaign, amount, max_redemptions, per_user_limit, roles, expires_at, active)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	RETURNING ` + promoCodeColumns + `;
+	`
+	created, err := scanPromoCode(s.db.QueryRow(ctx, query, code.Code, code.Campaign, code.Amount, code.MaxRedemptions, code.PerUserLimit, rolesOrEmpty(code.Roles), code.ExpiresAt, code.Active))
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return models.PromoCode{}, storage.ErrAlreadyExists
+		}
+		return models.PromoCode{}, fmt.Errorf("create promo code: %w", err)
+	}
+	return created, nil
+}
+
+// UpdatePromoCode saves a code's limits, roles, expiry and active flag.
+func (s *Store) UpdatePromoCode(ctx context.Context, code models.PromoCode) (models.PromoCode, error) {
+	const query = `
+	UPDATE promo_codes
+	SET max_redemptions = $2, per_user_limit = $3, roles = $4, expires_at = $5, active = $6
+	WHERE id = $1
+	RETURNING ` + promoCodeColumns + `;
+	`
+	updated, err := scanPromoCode(s.db.QueryRow(ctx, query, code.ID, code.MaxRedemptions, code.PerUserLimit, rolesOrEmpty(code.Roles), code.ExpiresAt, code.Active))
+	if err != nil && !errors.Is(err, storage.ErrNotFound) {
+		return models.PromoCode{}, fmt.Errorf("update promo code: %w", err)
+	}
+	return updated, err
+}
+
+// ClaimPromoRedemption increments the code's redemption count unless it is
+// used up. The row lock taken by the update makes concurrent redemptions of
+// the same code wait for each other.
+func (s *Store) ClaimPromoRedemption(ctx context.Context, id int64) (models.PromoCode, error) {
+	const query = `
+	UPDATE promo_codes SET redemptions = redemptions + 1
+	WHERE id = $1 AND (max_redemptions = 0 OR redemptions < max_redemptions)
+	RETURNING ` + promoCodeColumns + `;
+	`
+	claimed, err := scanPromoCode(s.db.QueryRow(ctx, query, id))
+	if !errors.Is(err, storage.ErrNotFound) {
+		if err != nil {
+			return models.PromoCode{}, fmt.Errorf("claim promo redemption: %w", err)
+		}
+		return claimed, nil
+	}
+	var exists bool
+	if err := s.db.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM promo_codes WHERE id = $1);`, id).Scan(&exists); err != nil {
+		return models.PromoCode{}, fmt.Errorf("claim promo redemption: %w", err)
+	}
+	if !exists {
+		return models.PromoCode{}, storage.ErrNotFound
+	}
+	return models.PromoCode{}, storage.ErrLimitReached
+}
+
+// CountPromoRedemptions returns how many times the user redeemed the code.
+func (s *Store) CountPromoRedemptions(ctx context.Context, codeID, userID int64) (int, error) {
+	var n int
+	err := s.db.QueryRow(ctx, `SELECT COUNT(*) FROM promo_redemptions WHERE code_id = $1 AND user_id = $2;`, codeID, userID).Scan(&n)
+	if err != nil {
+		return 0, fmt.Errorf("count promo redemptions: %w", err)
+	}
+	return n, nil
+}
+
+// CreatePromoRedemption records a redemption.
+func (s *Store) CreatePromoRedemption(ctx context.Context, redemption models.PromoRedemption) (models.PromoRedemption, error) {
+	const query = `
+	INSERT INTO promo_redemptions (code_id, user_id, amount, transaction_id)
+	VALUES ($1, $2, $3, $4)
+	RETURNING id, created_at;
+	`
+	err := s.db.QueryRow(ctx, query, redemption.CodeID, redemption.UserID, redemption.Amount, redemption.TransactionID).Scan(&redemption.ID, &redemption.CreatedAt)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+			return models.PromoRedemption{}, storage.ErrNotFound
+		}
+		return models.PromoRedemption{}, fmt.Errorf("create promo redemption: %w", err)
+	}
+	return redemption, nil
+}
+
+// ListPromoRedemptions returns the newest redemptions of the campaign's codes.
+func (s *Store) ListPromoRedemptions(ctx context.Context, campaign string, limit int) ([]models.PromoRedemption, error) {
+	const query = `
+	SELECT r.id, r.code_id, c.code, c.campaign, r.user_id, r.amount, r.transaction_id, r.created_at
+	FROM promo_redemptions r
+	JOIN promo_codes c ON c.id = r.code_id
+	WHERE c.campaign = $1
+	ORDER BY r.id DESC
+	LIMIT $2;
+	`
+	rows, err := s.reader().Query(ctx, query, campaign, limit)
+	if err != nil {
+		return nil, fmt.Errorf("list promo redemptions: %w", err)
+	}
+	defer rows.Close()
+
+	redemptions := []models.PromoRedemption{}
+	for rows.Next() {
+		var r models.PromoRedemption
+		if err := rows.Scan(&r.ID, &r.CodeID, &r.Code, &r.Campaign, &r.UserID, &r.Amount, &r.TransactionID, &r.CreatedAt); err != nil {
+			return nil, err
+		}
+		redemptions = append(redemptions, r)
+	}
+	return redemptions, rows.Err()
+}
+
+// ListPromoCampaignReports totals redemptions per campaign.
+func (s *Store) ListPromoCampaignReports(ctx context.Context) ([]models.PromoCampaignReport, error) {
+	const query = `
+	SELECT c.campaign, COUNT(DISTINCT c.id), COUNT(r.id), COUNT(DISTINCT r.user_id),
+	       COALESCE(SUM(r.amount), 0), MAX(r.created_at)
+	FROM promo_codes c
+	LEFT JOIN promo_redemptions r ON r.code_id = c.id
+	GROUP BY c.campaign
+	ORDER BY c.campaign;
+	`
+	rows, err := s.reader().Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("list promo campaign reports: %w", err)
+	}
+	defer rows.Close()
+
+	reports := []models.PromoCampaignReport{}
+	for rows.Next() {
+		var r models.PromoCampaignReport
+		if err := rows.Scan(&r.Campaign, &r.Codes, &r.Redemptions, &r.Players, &r.Amount, &r.LastRedeemedAt); err != nil {
+			return nil, err
+		}
+		reports = append(reports, r)
+	}
+	return reports, rows.Err()
+}
+
+func scanPromoCode(row pgx.Row) (models.PromoCode, error) {
+	var c models.PromoCode
+	err := row.Scan(&c.ID, &c.Code, &c.Campaign, &c.Amount, &c.MaxRedemptions, &c.PerUserLimit, &c.Roles, &c.ExpiresAt, &c.Active, &c.Redemptions, &c.CreatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.PromoCode{}, storage.ErrNotFound
+		}
+		return models.PromoCode{}, err
+	}
+	return c, nil
+}
+
+// rolesOrEmpty stores a nil role list as an empty array rather than NULL.
+func rolesOrEmpty(roles []string) []string {
+	if roles == nil {
+		return []string{}
+	}
+	return roles
+}
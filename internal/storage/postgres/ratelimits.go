@@ -0,0 +1,81 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/hongminglow/all-in-be/internal/models"
+	"github.com/hongminglow/all-in-be/internal/storage"
+	"github.com/jackc/pgx/v5"
+)
+
+// ListRateLimitPolicies returns every configured policy.
+func (s *Store) ListRateLimitPolicies(ctx context.Context) ([]models.RateLimitPolicy, error) {
+	const query = `
+	SELECT tenant, route_class, requests, window_seconds, updated_at
+	FROM rate_limit_policies
+	ORDER BY tenant, route_class;
+	`
+	rows, err := s.reader().Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("list rate limit policies: %w", err)
+	}
+	defer rows.Close()
+
+	policies := []models.RateLimitPolicy{}
+	for rows.Next() {
+		policy, err := scanRateLimitPolicy(rows)
+		if err != nil {
+			return nil, err
+		}
+		policies = append(policies, policy)
+	}
+	return policies, rows.Err()
+}
+
+// FindRateLimitPolicy fetches the policy for a tenant and route class.
+func (s *Store) FindRateLimitPolicy(ctx context.Context, tenant, routeClass string) (models.RateLimitPolicy, error) {
+	const query = `
+	SELECT tenant, route_class, requests, window_seconds, updated_at
+	FROM rate_limit_policies
+	WHERE tenant = $1 AND route_class = $2;
+	`
+	return scanRateLimitPolicy(s.reader().QueryRow(ctx, query, tenant, routeClass))
+}
+
+// UpsertRateLimitPolicy creates or replaces the policy for its tenant and route class.
+func (s *Store) UpsertRateLimitPolicy(ctx context.Context, policy models.RateLimitPolicy) (models.RateLimitPolicy, error) {
+	const query = `
+	INSERT INTO rate_limit_policies (tenant, route_class, requests, window_seconds)
+	VALUES ($1, $2, $3, $4)
+	ON CONFLICT (tenant, route_class) DO UPDATE
+	SET requests = EXCLUDED.requests, window_seconds = EXCLUDED.window_seconds, updated_at = NOW()
+	RETURNING tenant, route_class, requests, window_seconds, updated_at;
+	`
+	row := s.db.QueryRow(ctx, query, policy.Tenant, policy.RouteClass, policy.Requests, policy.WindowSeconds)
+	return scanRateLimitPolicy(row)
+}
+
+// DeleteRateLimitPolicy removes a policy so the route class falls back to its default.
+func (s *Store) DeleteRateLimitPolicy(ctx context.Context, tenant, routeClass string) error {
+	tag, err := s.db.Exec(ctx, `DELETE FROM rate_limit_policies WHERE tenant = $1 AND route_class = $2;`, tenant, routeClass)
+	if err != nil {
+		return fmt.Errorf("delete rate limit policy: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return storage.ErrNotFound
+	}
+	return nil
+}
+
+func scanRateLimitPolicy(row pgx.Row) (models.RateLimitPolicy, error) {
+	var policy models.RateLimitPolicy
+	if err := row.Scan(&policy.Tenant, &policy.RouteClass, &policy.Requests, &policy.WindowSeconds, &policy.UpdatedAt); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.RateLimitPolicy{}, storage.ErrNotFound
+		}
+		return models.RateLimitPolicy{}, err
+	}
+	return policy, nil
+}
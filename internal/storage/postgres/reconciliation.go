@@ -0,0 +1,127 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/hongminglow/all-in-be/internal/models"
+	"github.com/hongminglow/all-in-be/internal/storage"
+	"github.com/jackc/pgx/v5"
+)
+
+const reconciliationColumns = `id, users_checked, mismatches, discrepancies, created_at`
+
+// ReconcileBalances compares users.balance with the ledger on the replica. The
+// opening balance is read off the first entry (balance_after - amount): it is
+// zero since sign-ups get a welcome bonus entry, but older and seeded accounts
+// opened with a balance outside the ledger. Entries moved in by an
+// account merge carry the duplicate's balances, so each account's entries are
+// totalled from their own opening balance before they are added up. The count
+// and the mismatches come from one statement so they share a snapshot; the
+// outer join keeps the count when nothing differs.
+func (s *Store) ReconcileBalances(ctx context.Context) (int, []models.BalanceDiscrepancy, error) {
+	const query = `
+	WITH ledger AS (
+		SELECT id, user_id, amount, balance_after, merged_from FROM wallet_transactions
+		UNION ALL
+		SELECT id, user_id, amount, balance_after, merged_from FROM wallet_transactions_archive
+	), chains AS (
+		SELECT user_id, (array_agg(balance_after - amount ORDER BY id))[1] + SUM(amount) AS balance
+		FROM ledger
+		GROUP BY user_id, COALESCE(merged_from, user_id)
+	), totals AS (
+		SELECT user_id, SUM(balance) AS ledger_balance
+		FROM chains
+		GROUP BY user_id
+	), compared AS (
+		SELECT u.id, u.username, u.balance, t.ledger_balance
+		FROM users u
+		JOIN totals t ON t.user_id = u.id
+	)
+	SELECT (SELECT COUNT(*) FROM compared), c.id, c.username, c.balance, c.ledger_balance
+	FROM (SELECT 1) AS one
+	LEFT JOIN compared c ON c.balance <> c.ledger_balance
+	ORDER BY c.id;
+	`
+	rows, err := s.reader().Query(ctx, query)
+	if err != nil {
+		return 0, nil, fmt.Errorf("reconcile balances: %w", err)
+	}
+	defer rows.Close()
+
+	var checked int
+	discrepancies := []models.BalanceDiscrepancy{}
+	for rows.Next() {
+		var id *int64
+		var username *string
+		var balance, ledger *float64
+		if err := rows.Scan(&checked, &id, &username, &balance, &ledger); err != nil {
+			return 0, nil, err
+		}
+		if id == nil {
+			continue
+		}
+		discrepancies = append(discrepancies, models.BalanceDiscrepancy{
+			UserID:        *id,
+			Username:      *username,
+			Balance:       *balance,
+			LedgerBalance: *ledger,
+		})
+	}
+	return checked, discrepancies, rows.Err()
+}
+
+// CreateReconciliationReport stores the outcome of a run.
+func (s *Store) CreateReconciliationReport(ctx context.Context, report models.ReconciliationReport) (models.ReconciliationReport, error) {
+	const query = `
+	INSERT INTO reconciliation_reports (users_checked, mismatches, discrepancies)
+	VALUES ($1, $2, $3)
+	RETURNING ` + reconciliationColumns + `;
+	`
+	discrepancies, err := json.Marshal(report.Discrepancies)
+	if err != nil {
+		return models.ReconciliationReport{}, err
+	}
+	created, err := scanReconciliationReport(s.db.QueryRow(ctx, query, report.UsersChecked, report.Mismatches, discrepancies))
+	if err != nil {
+		return models.ReconciliationReport{}, fmt.Errorf("create reconciliation report: %w", err)
+	}
+	return created, nil
+}
+
+// ListReconciliationReports returns the newest reports first.
+func (s *Store) ListReconciliationReports(ctx context.Context, limit int) ([]models.ReconciliationReport, error) {
+	const query = `SELECT ` + reconciliationColumns + ` FROM reconciliation_reports ORDER BY id DESC LIMIT $1;`
+	rows, err := s.reader().Query(ctx, query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("list reconciliation reports: %w", err)
+	}
+	defer rows.Close()
+
+	reports := []models.ReconciliationReport{}
+	for rows.Next() {
+		report, err := scanReconciliationReport(rows)
+		if err != nil {
+			return nil, err
+		}
+		reports = append(reports, report)
+	}
+	return reports, rows.Err()
+}
+
+func scanReconciliationReport(row pgx.Row) (models.ReconciliationReport, error) {
+	var r models.ReconciliationReport
+	var discrepancies []byte
+	if err := row.Scan(&r.ID, &r.UsersChecked, &r.Mismatches, &discrepancies, &r.CreatedAt); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.ReconciliationReport{}, storage.ErrNotFound
+		}
+		return models.ReconciliationReport{}, err
+	}
+	if err := json.Unmarshal(discrepancies, &r.Discrepancies); err != nil {
+		return models.ReconciliationReport{}, fmt.Errorf("decode discrepancies: %w", err)
+	}
+	return r, nil
+}
@@ -0,0 +1,164 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// replicaCheckInterval is how often replica reachability and lag are measured.
+const replicaCheckInterval = 5 * time.Second
+
+// replicaLagQuery reports replay lag in seconds. A replica that has replayed all
+// WAL it received is caught up even if the primary has been idle for a while.
+const replicaLagQuery = `
+SELECT CASE
+	WHEN NOT pg_is_in_recovery() OR pg_last_wal_receive_lsn() = pg_last_wal_replay_lsn() THEN 0
+	ELSE COALESCE(EXTRACT(EPOCH FROM NOW() - pg_last_xact_replay_timestamp()), 0)
+END;
+`
+
+// replica is a read-only pool that serves FindBy*/List* queries while it is
+// reachable and no further behind the primary than maxLag.
+type replica struct {
+	pool    *pgxpool.Pool
+	db      dbtx
+	maxLag  time.Duration
+	healthy atomic.Bool
+	stop    context.CancelFunc
+	done    chan struct{}
+}
+
+// AttachReadReplica routes read-only queries to the replica at databaseURL.
+// Reads fall back to the primary while the replica is down or lagging by more
+// than maxLag, so they may be stale by up to maxLag.
+func (s *Store) AttachReadReplica(ctx context.Context, databaseURL string, maxLag time.Duration) error {
+	pool, err := newPool(ctx, databaseURL, s.poolConf)
+	if err != nil {
+		return fmt.Errorf("read replica: %w", err)
+	}
+
+	checkCtx, stop := context.WithCancel(context.Background())
+	r := &replica{pool: pool, db: withQueryTimeout(pool, s.poolConf.QueryTimeout), maxLag: maxLag, stop: stop, done: make(chan struct{})}
+	r.check(ctx)
+	go r.monitor(checkCtx)
+	s.replica = r
+	// A replica URL may pick an exec mode without prepared statements.
+	s.prepared = s.prepared && preparesStatements(pool.Config())
+	return nil
+}
+
+// reader returns the connection for read-only queries: the replica when it is
+// healthy, otherwise the primary (or the open transaction).
+func (s *Store) reader() dbtx {
+	if s.replica == nil || !s.replica.healthy.Load() {
+		return s.db
+	}
+	return replicaDB{replica: s.replica, primary: s.db}
+}
+
+func (r *replica) monitor(ctx context.Context) {
+	defer close(r.done)
+	ticker := time.NewTicker(replicaCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			r.check(ctx)
+		}
+	}
+}
+
+// check measures lag and flips the replica in or out of rotation.
+func (r *replica) check(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(ctx, replicaCheckInterval)
+	defer cancel()
+	var lagSeconds float64
+	if err := r.pool.QueryRow(ctx, replicaLagQuery).Scan(&lagSeconds); err != nil {
+		r.markDown(fmt.Errorf("lag check: %w", err))
+		return
+	}
+	lag := time.Duration(lagSeconds * float64(time.Second))
+	if lag > r.maxLag {
+		r.markDown(fmt.Errorf("lag %s exceeds %s", lag.Truncate(time.Millisecond), r.maxLag))
+		return
+	}
+	if !r.healthy.Swap(true) {
+		log.Printf("postgres: read replica in rotation (lag %s)", lag.Truncate(time.Millisecond))
+	}
+}
+
+func (r *replica) markDown(reason error) {
+	if r.healthy.Swap(false) {
+		log.Printf("postgres: read replica out of rotation, reading from primary: %v", reason)
+	}
+}
+
+func (r *replica) close() {
+	r.stop()
+	<-r.done
+	r.pool.Close()
+}
+
+// replicaDB sends reads to the replica and retries them on the primary when the
+// replica connection fails mid-query.
+type replicaDB struct {
+	replica *replica
+	primary dbtx
+}
+
+func (d replicaDB) Begin(ctx context.Context) (pgx.Tx, error) {
+	return d.primary.Begin(ctx)
+}
+
+func (d replicaDB) Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error) {
+	return d.primary.Exec(ctx, sql, args...)
+}
+
+func (d replicaDB) Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+	rows, err := d.replica.db.Query(ctx, sql, args...)
+	if err != nil && d.shouldFallBack(ctx, err) {
+		return d.primary.Query(ctx, sql, args...)
+	}
+	return rows, err
+}
+
+func (d replicaDB) QueryRow(ctx context.Context, sql string, args ...any) pgx.Row {
+	return fallbackRow{ctx: ctx, sql: sql, args: args, db: d, row: d.replica.db.QueryRow(ctx, sql, args...)}
+}
+
+// shouldFallBack reports whether err means the replica is unusable rather than
+// the query itself failing, taking the replica out of rotation if so.
+func (d replicaDB) shouldFallBack(ctx context.Context, err error) bool {
+	var pgErr *pgconn.PgError
+	if ctx.Err() != nil || errors.Is(err, pgx.ErrNoRows) || errors.As(err, &pgErr) {
+		return false
+	}
+	d.replica.markDown(err)
+	return true
+}
+
+type fallbackRow struct {
+	ctx  context.Context
+	sql  string
+	args []any
+	db   replicaDB
+	row  pgx.Row
+}
+
+func (r fallbackRow) Scan(dest ...any) error {
+	err := r.row.Scan(dest...)
+	if err != nil && r.db.shouldFallBack(r.ctx, err) {
+		return r.db.primary.QueryRow(r.ctx, r.sql, r.args...).Scan(dest...)
+	}
+	return err
+}
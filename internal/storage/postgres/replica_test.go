@@ -0,0 +1,58 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// rowDB is a dbtx whose QueryRow always scans the given error, counting calls.
+type rowDB struct {
+	dbtx
+	err   error
+	calls int
+}
+
+func (d *rowDB) QueryRow(context.Context, string, ...any) pgx.Row {
+	d.calls++
+	return errRow{err: d.err}
+}
+
+func TestReplicaFallsBackToPrimaryOnConnectionError(t *testing.T) {
+	primary := &rowDB{err: pgx.ErrNoRows}
+	r := &replica{db: &rowDB{err: errors.New("connection reset by peer")}}
+	r.healthy.Store(true)
+	s := &Store{db: primary, replica: r}
+
+	err := s.reader().QueryRow(context.Background(), "SELECT 1").Scan()
+	if !errors.Is(err, pgx.ErrNoRows) {
+		t.Fatalf("expected the primary's result, got %v", err)
+	}
+	if primary.calls != 1 {
+		t.Fatalf("primary queried %d times, want 1", primary.calls)
+	}
+	if r.healthy.Load() {
+		t.Fatal("replica should be out of rotation after a connection error")
+	}
+	if _, ok := s.reader().(*rowDB); !ok {
+		t.Fatal("reads should go to the primary while the replica is down")
+	}
+}
+
+func TestReplicaQueryErrorsAreNotRetried(t *testing.T) {
+	primary := &rowDB{}
+	r := &replica{db: &rowDB{err: &pgconn.PgError{Code: "42P01"}}}
+	r.healthy.Store(true)
+	s := &Store{db: primary, replica: r}
+
+	var pgErr *pgconn.PgError
+	if err := s.reader().QueryRow(context.Background(), "SELECT 1").Scan(); !errors.As(err, &pgErr) {
+		t.Fatalf("expected the replica's SQL error, got %v", err)
+	}
+	if primary.calls != 0 || !r.healthy.Load() {
+		t.Fatal("a SQL error from the replica must not fail over")
+	}
+}
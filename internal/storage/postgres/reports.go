@@ -0,0 +1,136 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/hongminglow/all-in-be/internal/models"
+	"github.com/hongminglow/all-in-be/internal/storage"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const operatorReportColumns = `id, period, period_start, period_end, status, metrics, requested_by, error, created_at, completed_at`
+
+// CreateOperatorReport records a pending report. The partial unique index on
+// scheduled reports turns a second one for the same period into ErrAlreadyExists.
+func (s *Store) CreateOperatorReport(ctx context.Context, report models.OperatorReport) (models.OperatorReport, error) {
+	const query = `
+	INSERT INTO operator_reports (period, period_start, period_end, status, requested_by)
+	VALUES ($1, $2, $3, $4, $5)
+	RETURNING ` + operatorReportColumns + `;
+	`
+	created, err := scanOperatorReport(s.db.QueryRow(ctx, query, report.Period, report.PeriodStart, report.PeriodEnd, report.Status, report.RequestedBy))
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			switch pgErr.Code {
+			case "23503":
+				return models.OperatorReport{}, storage.ErrNotFound
+			case "23505":
+				return models.OperatorReport{}, storage.ErrAlreadyExists
+			}
+		}
+		return models.OperatorReport{}, fmt.Errorf("create operator report: %w", err)
+	}
+	return created, nil
+}
+
+// FindOperatorReport fetches one report from the primary, since its status
+// changes moments after it is created.
+func (s *Store) FindOperatorReport(ctx context.Context, id int64) (models.OperatorReport, error) {
+	const query = `SELECT ` + operatorReportColumns + ` FROM operator_reports WHERE id = $1;`
+	return scanOperatorReport(s.db.QueryRow(ctx, query, id))
+}
+
+// ListOperatorReports returns the newest reports first.
+func (s *Store) ListOperatorReports(ctx context.Context, limit int) ([]models.OperatorReport, error) {
+	const query = `SELECT ` + operatorReportColumns + ` FROM operator_reports ORDER BY id DESC LIMIT $1;`
+	rows, err := s.reader().Query(ctx, query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("list operator reports: %w", err)
+	}
+	defer rows.Close()
+
+	reports := []models.OperatorReport{}
+	for rows.Next() {
+		report, err := scanOperatorReport(rows)
+		if err != nil {
+			return nil, err
+		}
+		reports = append(reports, report)
+	}
+	return reports, rows.Err()
+}
+
+// FinishOperatorReport records the outcome of a pending report.
+func (s *Store) FinishOperatorReport(ctx context.Context, id int64, status string, metrics *models.ReportMetrics, errMsg string, at time.Time) error {
+	const query = `
+	UPDATE operator_reports SET status = $2, metrics = $3, error = $4, completed_at = $5
+	WHERE id = $1 AND status = 'pending';
+	`
+	var encoded []byte
+	if metrics != nil {
+		var err error
+		if encoded, err = json.Marshal(metrics); err != nil {
+			return err
+		}
+	}
+	tag, err := s.db.Exec(ctx, query, id, status, encoded, errMsg, at)
+	if err != nil {
+		return fmt.Errorf("finish operator report: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return storage.ErrNotFound
+	}
+	return nil
+}
+
+// ReportMetrics totals signups, bet settlements, deposits and withdrawal
+// requests over [from, to) on the replica. Ledger entries are read from the
+// hot table only; reports cover recent periods, well short of the archive
+// cutoff.
+func (s *Store) ReportMetrics(ctx context.Context, from, to time.Time) (models.ReportMetrics, error) {
+	const query = `
+	WITH ledger AS (
+		SELECT reason, amount FROM wallet_transactions
+		WHERE created_at >= $1 AND created_at < $2 AND reason IN ('bet_settlement', 'deposit')
+	), withdrawals AS (
+		SELECT amount FROM withdrawal_requests
+		WHERE created_at >= $1 AND created_at < $2 AND status NOT IN ('rejected', 'failed')
+	)
+	SELECT
+		(SELECT COUNT(*) FROM users WHERE created_at >= $1 AND created_at < $2),
+		(SELECT COALESCE(-SUM(amount), 0) FROM ledger WHERE reason = 'bet_settlement'),
+		(SELECT COUNT(*) FROM ledger WHERE reason = 'deposit'),
+		(SELECT COALESCE(SUM(amount), 0) FROM ledger WHERE reason = 'deposit'),
+		(SELECT COUNT(*) FROM withdrawals),
+		(SELECT COALESCE(SUM(amount), 0) FROM withdrawals);
+	`
+	var m models.ReportMetrics
+	if err := s.reader().QueryRow(ctx, query, from, to).Scan(&m.Signups, &m.GGR, &m.Deposits, &m.DepositAmount, &m.Withdrawals, &m.WithdrawalAmount); err != nil {
+		return models.ReportMetrics{}, fmt.Errorf("compute report metrics: %w", err)
+	}
+	return m, nil
+}
+
+func scanOperatorReport(row pgx.Row) (models.OperatorReport, error) {
+	var r models.OperatorReport
+	var metrics []byte
+	if err := row.Scan(&r.ID, &r.Period, &r.PeriodStart, &r.PeriodEnd, &r.Status, &metrics, &r.RequestedBy, &r.Error, &r.CreatedAt, &r.CompletedAt); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.OperatorReport{}, storage.ErrNotFound
+		}
+		return models.OperatorReport{}, err
+	}
+	if metrics != nil {
+		r.Metrics = &models.ReportMetrics{}
+		if err := json.Unmarshal(metrics, r.Metrics); err != nil {
+			return models.OperatorReport{}, fmt.Errorf("decode report metrics: %w", err)
+		}
+	}
+	return r, nil
+}
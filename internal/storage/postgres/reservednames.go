@@ -0,0 +1,71 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/hongminglow/all-in-be/internal/models"
+	"github.com/hongminglow/all-in-be/internal/storage"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const reservedNameColumns = `name, kind, created_by, created_at`
+
+// ListReservedNames returns the entries added through the admin API, by name.
+func (s *Store) ListReservedNames(ctx context.Context) ([]models.ReservedName, error) {
+	rows, err := s.reader().Query(ctx, `SELECT `+reservedNameColumns+` FROM reserved_usernames ORDER BY name;`)
+	if err != nil {
+		return nil, fmt.Errorf("list reserved names: %w", err)
+	}
+	defer rows.Close()
+
+	names := []models.ReservedName{}
+	for rows.Next() {
+		n, err := scanReservedName(rows)
+		if err != nil {
+			return nil, err
+		}
+		names = append(names, n)
+	}
+	return names, rows.Err()
+}
+
+// AddReservedName stores a new entry.
+func (s *Store) AddReservedName(ctx context.Context, name models.ReservedName) (models.ReservedName, error) {
+	const query = `
+	INSERT INTO reserved_usernames (name, kind, created_by)
+	VALUES ($1, $2, $3)
+	RETURNING ` + reservedNameColumns + `;
+	`
+	saved, err := scanReservedName(s.db.QueryRow(ctx, query, name.Name, name.Kind, name.CreatedBy))
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		return models.ReservedName{}, storage.ErrAlreadyExists
+	}
+	if err != nil {
+		return models.ReservedName{}, fmt.Errorf("add reserved name: %w", err)
+	}
+	return saved, nil
+}
+
+// DeleteReservedName removes an entry.
+func (s *Store) DeleteReservedName(ctx context.Context, name string) error {
+	tag, err := s.db.Exec(ctx, `DELETE FROM reserved_usernames WHERE name = $1;`, name)
+	if err != nil {
+		return fmt.Errorf("delete reserved name: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return storage.ErrNotFound
+	}
+	return nil
+}
+
+func scanReservedName(row pgx.Row) (models.ReservedName, error) {
+	n := models.ReservedName{Source: models.ReservedNameSourceAdmin}
+	if err := row.Scan(&n.Name, &n.Kind, &n.CreatedBy, &n.CreatedAt); err != nil {
+		return models.ReservedName{}, err
+	}
+	return n, nil
+}
@@ -0,0 +1,289 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"math/rand/v2"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hongminglow/all-in-be/internal/storage"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// SQLSTATE codes for failures where Postgres rolled the work back and running
+// it again may succeed.
+const (
+	serializationFailure = "40001"
+	deadlockDetected     = "40P01"
+)
+
+// RetryPolicy controls how transient database errors are retried and when the
+// circuit breaker stops sending calls to a failing database.
+type RetryPolicy struct {
+	// MaxAttempts is how many times a call is tried in total. Zero or one
+	// disables retries.
+	MaxAttempts int
+	// BaseDelay is the backoff before the first retry; it doubles for each
+	// further retry up to MaxDelay, with jitter.
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+	// BreakerThreshold is how many calls in a row must fail with a connection
+	// error before the breaker opens. Zero disables the breaker.
+	BreakerThreshold int
+	// BreakerCooldown is how long an open breaker refuses calls before letting
+	// a single trial call through.
+	BreakerCooldown time.Duration
+}
+
+// backoff returns the wait before the given retry (1 for the first), between
+// half and all of the exponential delay.
+func (p RetryPolicy) backoff(retry int) time.Duration {
+	d := p.BaseDelay << (retry - 1)
+	if d <= 0 || (p.MaxDelay > 0 && d > p.MaxDelay) {
+		d = p.MaxDelay
+	}
+	if d <= 0 {
+		return 0
+	}
+	return d/2 + rand.N(d/2+1)
+}
+
+// retry calls fn until it succeeds, fails with an error transient does not
+// accept, or runs out of attempts.
+func (p RetryPolicy) retry(ctx context.Context, transient func(error) bool, fn func() error) error {
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil || attempt >= p.MaxAttempts || !transient(err) {
+			return err
+		}
+		timer := time.NewTimer(p.backoff(attempt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
+
+// retryable reports whether err is transient. Serialization failures,
+// deadlocks and failed connection attempts never leave a statement applied;
+// other connection errors may strike after the server ran it, so they are only
+// retried for idempotent statements.
+func retryable(err error, idempotent bool) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	if rolledBack(err) {
+		return true
+	}
+	var connectErr *pgconn.ConnectError
+	if errors.As(err, &connectErr) || pgconn.SafeToRetry(err) {
+		return true
+	}
+	return idempotent && connectionFailure(err)
+}
+
+// rolledBack reports whether Postgres aborted the work to resolve a conflict
+// with a concurrent transaction.
+func rolledBack(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && (pgErr.Code == serializationFailure || pgErr.Code == deadlockDetected)
+}
+
+// connectionFailure reports whether err means the database could not be
+// reached or dropped the connection, as opposed to rejecting the statement.
+func connectionFailure(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		// Class 08 is connection_exception; 57P01-57P03 are the server
+		// shutting down or not yet accepting connections.
+		return strings.HasPrefix(pgErr.Code, "08") || pgErr.Code == "57P01" || pgErr.Code == "57P02" || pgErr.Code == "57P03"
+	}
+	var connectErr *pgconn.ConnectError
+	var netErr net.Error
+	return errors.As(err, &connectErr) || errors.As(err, &netErr) || pgconn.SafeToRetry(err) ||
+		errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// readOnly reports whether sql is a plain SELECT, which can be rerun after a
+// dropped connection without applying anything twice.
+func readOnly(sql string) bool {
+	sql = strings.TrimSpace(sql)
+	return len(sql) >= 6 && strings.EqualFold(sql[:6], "SELECT")
+}
+
+// breaker stops calls to the database after threshold consecutive connection
+// failures. Once cooldown has passed it lets one trial call through: success
+// closes it again, failure restarts the cooldown.
+type breaker struct {
+	threshold int
+	cooldown  time.Duration
+	now       func() time.Time
+
+	mu       sync.Mutex
+	failures int
+	openedAt time.Time
+	trial    bool
+}
+
+// newBreaker returns nil, a breaker that never opens, when threshold is zero.
+func newBreaker(threshold int, cooldown time.Duration) *breaker {
+	if threshold <= 0 {
+		return nil
+	}
+	return &breaker{threshold: threshold, cooldown: cooldown, now: time.Now}
+}
+
+// allow returns storage.ErrUnavailable while the breaker is open.
+func (b *breaker) allow() error {
+	if b == nil {
+		return nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.openedAt.IsZero() {
+		return nil
+	}
+	if b.trial || b.now().Sub(b.openedAt) < b.cooldown {
+		return storage.ErrUnavailable
+	}
+	b.trial = true
+	return nil
+}
+
+// record updates the breaker with the outcome of an allowed call.
+func (b *breaker) record(err error) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	wasOpen := !b.openedAt.IsZero()
+	switch {
+	case connectionFailure(err):
+		b.failures++
+		if b.trial || (!wasOpen && b.failures >= b.threshold) {
+			b.openedAt = b.now()
+			if !wasOpen {
+				log.Printf("postgres: circuit breaker open after %d consecutive connection failures: %v", b.failures, err)
+			}
+		}
+	case errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded):
+		// The caller gave up; this says nothing about the database.
+	default:
+		b.failures = 0
+		b.openedAt = time.Time{}
+		if wasOpen {
+			log.Printf("postgres: circuit breaker closed")
+		}
+	}
+	b.trial = false
+}
+
+// state reports the breaker's current state.
+func (b *breaker) state() storage.BreakerState {
+	if b == nil {
+		return storage.BreakerState{State: storage.BreakerClosed}
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	st := storage.BreakerState{State: storage.BreakerClosed, ConsecutiveFailures: b.failures}
+	if !b.openedAt.IsZero() {
+		openedAt := b.openedAt
+		st.OpenedAt = &openedAt
+		st.State = storage.BreakerOpen
+		if b.trial || b.now().Sub(b.openedAt) >= b.cooldown {
+			st.State = storage.BreakerHalfOpen
+		}
+	}
+	return st
+}
+
+// retryDB retries transient errors outside transactions and routes every call
+// through the circuit breaker, so a brief connection drop (such as Neon
+// recycling a pooled connection) does not fail the request.
+type retryDB struct {
+	dbtx
+	policy  RetryPolicy
+	breaker *breaker
+}
+
+// withRetry wraps db unless the policy neither retries nor breaks.
+func withRetry(db dbtx, policy RetryPolicy, b *breaker) dbtx {
+	if policy.MaxAttempts <= 1 && b == nil {
+		return db
+	}
+	return retryDB{dbtx: db, policy: policy, breaker: b}
+}
+
+func (d retryDB) do(ctx context.Context, idempotent bool, fn func() error) error {
+	if err := d.breaker.allow(); err != nil {
+		return err
+	}
+	err := d.policy.retry(ctx, func(err error) bool { return retryable(err, idempotent) }, fn)
+	d.breaker.record(err)
+	return err
+}
+
+// Begin is retried like any statement: a transaction that failed to start has
+// done nothing.
+func (d retryDB) Begin(ctx context.Context) (pgx.Tx, error) {
+	var tx pgx.Tx
+	err := d.do(ctx, true, func() error {
+		var err error
+		tx, err = d.dbtx.Begin(ctx)
+		return err
+	})
+	return tx, err
+}
+
+func (d retryDB) Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error) {
+	var tag pgconn.CommandTag
+	err := d.do(ctx, readOnly(sql), func() error {
+		var err error
+		tag, err = d.dbtx.Exec(ctx, sql, args...)
+		return err
+	})
+	return tag, err
+}
+
+// Query retries until the query starts returning rows; errors while reading
+// them are the caller's.
+func (d retryDB) Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+	var rows pgx.Rows
+	err := d.do(ctx, readOnly(sql), func() error {
+		var err error
+		rows, err = d.dbtx.Query(ctx, sql, args...)
+		return err
+	})
+	return rows, err
+}
+
+func (d retryDB) QueryRow(ctx context.Context, sql string, args ...any) pgx.Row {
+	return retryRow{ctx: ctx, sql: sql, args: args, db: d}
+}
+
+// retryRow defers the query to Scan, where pgx reports QueryRow errors, so the
+// whole round trip can be retried.
+type retryRow struct {
+	ctx  context.Context
+	sql  string
+	args []any
+	db   retryDB
+}
+
+func (r retryRow) Scan(dest ...any) error {
+	return r.db.do(r.ctx, readOnly(r.sql), func() error {
+		return r.db.dbtx.QueryRow(r.ctx, r.sql, r.args...).Scan(dest...)
+	})
+}
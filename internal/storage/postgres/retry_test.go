@@ -0,0 +1,117 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/hongminglow/all-in-be/internal/storage"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// flakyDB fails the first len(errs) calls with the given errors.
+type flakyDB struct {
+	dbtx
+	errs  []error
+	calls int
+}
+
+func (d *flakyDB) next() error {
+	d.calls++
+	if d.calls <= len(d.errs) {
+		return d.errs[d.calls-1]
+	}
+	return nil
+}
+
+func (d *flakyDB) Exec(context.Context, string, ...any) (pgconn.CommandTag, error) {
+	return pgconn.CommandTag{}, d.next()
+}
+
+func (d *flakyDB) QueryRow(context.Context, string, ...any) pgx.Row {
+	return errRow{err: d.next()}
+}
+
+func TestRetryOnlyRepeatsSafeCalls(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond}
+	reset := io.ErrUnexpectedEOF
+	serialization := &pgconn.PgError{Code: serializationFailure}
+	cases := []struct {
+		name      string
+		sql       string
+		errs      []error
+		wantCalls int
+		wantErr   bool
+	}{
+		{name: "read after dropped connection", sql: "SELECT 1", errs: []error{reset, reset}, wantCalls: 3},
+		{name: "write after dropped connection", sql: "UPDATE users SET balance = 0", errs: []error{reset}, wantCalls: 1, wantErr: true},
+		{name: "write after serialization failure", sql: "UPDATE users SET balance = 0", errs: []error{serialization}, wantCalls: 2},
+		{name: "failed connection attempt", sql: "INSERT INTO users DEFAULT VALUES", errs: []error{&pgconn.ConnectError{}}, wantCalls: 2},
+		{name: "constraint violation", sql: "SELECT 1", errs: []error{&pgconn.PgError{Code: "23505"}}, wantCalls: 1, wantErr: true},
+		{name: "gives up after max attempts", sql: "SELECT 1", errs: []error{reset, reset, reset, reset}, wantCalls: 3, wantErr: true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			inner := &flakyDB{errs: tc.errs}
+			_, err := withRetry(inner, policy, nil).Exec(context.Background(), tc.sql)
+			if inner.calls != tc.wantCalls {
+				t.Fatalf("calls = %d, want %d", inner.calls, tc.wantCalls)
+			}
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("err = %v, want %v", err, tc.wantErr)
+			}
+		})
+	}
+
+	inner := &flakyDB{errs: []error{reset, pgx.ErrNoRows}}
+	err := withRetry(inner, policy, nil).QueryRow(context.Background(), "SELECT 1").Scan()
+	if !errors.Is(err, pgx.ErrNoRows) || inner.calls != 2 {
+		t.Fatalf("QueryRow: err = %v after %d calls, want no rows after 2", err, inner.calls)
+	}
+}
+
+func TestBreakerOpensAndRecovers(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	b := newBreaker(2, 10*time.Second)
+	b.now = func() time.Time { return now }
+	inner := &flakyDB{errs: []error{io.EOF, io.EOF, io.EOF}}
+	db := withRetry(inner, RetryPolicy{MaxAttempts: 1}, b)
+	exec := func() error {
+		_, err := db.Exec(context.Background(), "SELECT 1")
+		return err
+	}
+
+	_ = exec()
+	if st := b.state(); st.State != storage.BreakerClosed || st.ConsecutiveFailures != 1 {
+		t.Fatalf("after one failure: %+v", st)
+	}
+	_ = exec()
+	if st := b.state(); st.State != storage.BreakerOpen || st.OpenedAt == nil {
+		t.Fatalf("after threshold: %+v", st)
+	}
+	if err := exec(); !errors.Is(err, storage.ErrUnavailable) || inner.calls != 2 {
+		t.Fatalf("open breaker: err = %v, calls = %d; want ErrUnavailable without a call", err, inner.calls)
+	}
+
+	now = now.Add(10 * time.Second)
+	if st := b.state(); st.State != storage.BreakerHalfOpen {
+		t.Fatalf("after cooldown: %+v", st)
+	}
+	if err := exec(); !errors.Is(err, io.EOF) {
+		t.Fatalf("trial call: err = %v, want the database error", err)
+	}
+	if err := exec(); !errors.Is(err, storage.ErrUnavailable) {
+		t.Fatalf("failed trial should restart the cooldown, got %v", err)
+	}
+
+	now = now.Add(10 * time.Second)
+	if err := exec(); err != nil {
+		t.Fatalf("second trial: %v", err)
+	}
+	if st := b.state(); st.State != storage.BreakerClosed || st.ConsecutiveFailures != 0 {
+		t.Fatalf("after successful trial: %+v", st)
+	}
+}
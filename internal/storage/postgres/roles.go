@@ -0,0 +1,342 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/hongminglow/all-in-be/internal/models"
+	"github.com/hongminglow/all-in-be/internal/storage"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// rolePermissionNames selects the permission names granted to role r.
+const rolePermissionNames = `ARRAY(
+	SELECT p.permission_name
+	FROM role_permissions rp
+	JOIN permission p ON rp.permission_id = p.id
+	WHERE rp.role_id = r.id
+	ORDER BY p.id
+)`
+
+const roleColumns = `r.id, r.role_name, COALESCE(r.role_description, ''), ` + rolePermissionNames
+
+const permissionColumns = `id, permission_name, COALESCE(permission_description, '')`
+
+// permissionHolders matches the users u holding permission $1 through their
+// role or an override.
+const permissionHolders = `u.role IN (
+		SELECT r.role_name FROM role r JOIN role_permissions rp ON rp.role_id = r.id WHERE rp.permission_id = $1
+	) OR u.id IN (SELECT up.user_id FROM user_permissions up WHERE up.permission_id = $1)`
+
+// ListRoles returns every role with its permission names, by ID.
+func (s *Store) ListRoles(ctx context.Context) ([]models.Role, error) {
+	rows, err := s.db.Query(ctx, `SELECT `+roleColumns+` FROM role r ORDER BY r.id;`)
+	if err != nil {
+		return nil, fmt.Errorf("list roles: %w", err)
+	}
+	defer rows.Close()
+
+	roles := []models.Role{}
+	for rows.Next() {
+		role, err := scanRole(rows)
+		if err != nil {
+			return nil, err
+		}
+		roles = append(roles, role)
+	}
+	return roles, rows.Err()
+}
+
+// FindRole fetches a role with its permission names.
+func (s *Store) FindRole(ctx context.Context, id int64) (models.Role, error) {
+	return scanRole(s.db.QueryRow(ctx, `SELECT `+roleColumns+` FROM role r WHERE r.id = $1;`, id))
+}
+
+// CreateRole stores a role without permissions.
+func (s *Store) CreateRole(ctx context.Context, role models.Role) (models.Role, error) {
+	const query = `
+	INSERT INTO role AS r (role_name, role_description)
+	VALUES ($1, $2)
+	RETURNING ` + roleColumns + `;
+	`
+	created, err := scanRole(s.db.QueryRow(ctx, query, role.RoleName, role.RoleDescription))
+	return created, uniqueViolation(err)
+}
+
+// UpdateRole saves the role's name and description. Users reference roles by
+// name, so a rename moves them in the same statement and bumps their
+// permissions version.
+func (s *Store) UpdateRole(ctx context.Context, role models.Role) (models.Role, error) {
+	const query = `
+	WITH previous AS (
+		SELECT role_name FROM role WHERE id = $1
+	), moved AS (
+		UPDATE users SET role = $2, permissions_version = permissions_version + 1
+		WHERE role = (SELECT role_name FROM previous) AND role <> $2
+	)
+	UPDATE role AS r SET role_name = $2, role_description = $3
+	WHERE r.id = $1
+	RETURNING ` + roleColumns + `;
+	`
+	updated, err := scanRole(s.db.QueryRow(ctx, query, role.ID, role.RoleName, role.RoleDescription))
+	return updated, uniqueViolation(err)
+}
+
+// DeleteRole removes a role no user has, along with its grants.
+func (s *Store) DeleteRole(ctx context.Context, id int64) error {
+	const query = `
+	WITH target AS (
+		SELECT id, EXISTS (SELECT 1 FROM users u WHERE u.role = role.role_name) AS in_use
+		FROM role WHERE id = $1
+	), revoked AS (
+		DELETE FROM role_permissions WHERE role_id = $1 AND NOT (SELECT in_use FROM target)
+	), deleted AS (
+		DELETE FROM role WHERE id = $1 AND NOT (SELECT in_use FROM target)
+		RETURNING id
+	)
+	SELECT (SELECT in_use FROM target), EXISTS (SELECT 1 FROM deleted);
+	`
+	var inUse *bool
+	var deleted bool
+	if err := s.db.QueryRow(ctx, query, id).Scan(&inUse, &deleted); err != nil {
+		return fmt.Errorf("delete role: %w", err)
+	}
+	switch {
+	case inUse == nil:
+		return storage.ErrNotFound
+	case *inUse:
+		return storage.ErrInUse
+	}
+	return nil
+}
+
+// ListPermissions returns every permission by ID.
+func (s *Store) ListPermissions(ctx context.Context) ([]models.Permission, error) {
+	rows, err := s.db.Query(ctx, `SELECT `+permissionColumns+` FROM permission ORDER BY id;`)
+	if err != nil {
+		return nil, fmt.Errorf("list permissions: %w", err)
+	}
+	defer rows.Close()
+
+	permissions := []models.Permission{}
+	for rows.Next() {
+		p, err := scanPermission(rows)
+		if err != nil {
+			return nil, err
+		}
+		permissions = append(permissions, p)
+	}
+	return permissions, rows.Err()
+}
+
+// FindPermission fetches a permission by ID.
+func (s *Store) FindPermission(ctx context.Context, id int64) (models.Permission, error) {
+	return scanPermission(s.db.QueryRow(ctx, `SELECT `+permissionColumns+` FROM permission WHERE id = $1;`, id))
+}
+
+// CreatePermission stores a permission granted to no role.
+func (s *Store) CreatePermission(ctx context.Context, p models.Permission) (models.Permission, error) {
+	const query = `
+	INSERT INTO permission (permission_name, permission_description)
+	VALUES ($1, $2)
+	RETURNING ` + permissionColumns + `;
+	`
+	created, err := scanPermission(s.db.QueryRow(ctx, query, p.PermissionName, p.PermissionDescription))
+	return created, uniqueViolation(err)
+}
+
+// UpdatePermission saves the permission's name and description; a rename
+// bumps the permissions version of its holders.
+func (s *Store) UpdatePermission(ctx context.Context, p models.Permission) (models.Permission, error) {
+	const query = `
+	WITH renamed AS (
+		UPDATE users u SET permissions_version = u.permissions_version + 1
+		WHERE (SELECT permission_name FROM permission WHERE id = $1) <> $2
+		AND (` + permissionHolders + `)
+	)
+	UPDATE permission SET permission_name = $2, permission_description = $3
+	WHERE id = $1
+	RETURNING ` + permissionColumns + `;
+	`
+	updated, err := scanPermission(s.db.QueryRow(ctx, query, p.ID, p.PermissionName, p.PermissionDescription))
+	return updated, uniqueViolation(err)
+}
+
+// DeletePermission removes a permission, revokes it from every role and
+// bumps the permissions version of its holders.
+func (s *Store) DeletePermission(ctx context.Context, id int64) error {
+	const query = `
+	WITH bumped AS (
+		UPDATE users u SET permissions_version = u.permissions_version + 1
+		WHERE ` + permissionHolders + `
+	), revoked AS (
+		DELETE FROM role_permissions WHERE permission_id = $1
+	)
+	DELETE FROM permission WHERE id = $1;
+	`
+	tag, err := s.db.Exec(ctx, query, id)
+	if err != nil {
+		return fmt.Errorf("delete permission: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return storage.ErrNotFound
+	}
+	return nil
+}
+
+// GrantPermission adds the permission to the role and bumps the permissions
+// version of the role's users.
+func (s *Store) GrantPermission(ctx context.Context, roleID, permissionID int64) error {
+	const query = `
+	WITH granted AS (
+		INSERT INTO role_permissions (role_id, permission_id) VALUES ($1, $2)
+		ON CONFLICT DO NOTHING
+		RETURNING role_id
+	)
+	UPDATE users SET permissions_version = permissions_version + 1
+	WHERE role = (SELECT role_name FROM role WHERE id IN (SELECT role_id FROM granted));
+	`
+	if _, err := s.db.Exec(ctx, query, roleID, permissionID); err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+			return storage.ErrNotFound
+		}
+		return fmt.Errorf("grant permission: %w", err)
+	}
+	return nil
+}
+
+// RevokePermission removes the permission from the role and bumps the
+// permissions version of the role's users.
+func (s *Store) RevokePermission(ctx context.Context, roleID, permissionID int64) error {
+	const query = `
+	WITH revoked AS (
+		DELETE FROM role_permissions WHERE role_id = $1 AND permission_id = $2
+		RETURNING role_id
+	)
+	UPDATE users SET permissions_version = permissions_version + 1
+	WHERE role = (SELECT role_name FROM role WHERE id IN (SELECT role_id FROM revoked));
+	`
+	if _, err := s.db.Exec(ctx, query, roleID, permissionID); err != nil {
+		return fmt.Errorf("revoke permission: %w", err)
+	}
+	return nil
+}
+
+// ListPermissionOverrides returns a user's overrides by permission ID.
+func (s *Store) ListPermissionOverrides(ctx context.Context, userID int64) ([]models.PermissionOverride, error) {
+	const query = `
+	SELECT up.user_id, up.permission_id, p.permission_name, up.allow, up.set_by, up.set_at
+	FROM user_permissions up
+	JOIN permission p ON p.id = up.permission_id
+	WHERE up.user_id = $1
+	ORDER BY up.permission_id;
+	`
+	rows, err := s.db.Query(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("list permission overrides: %w", err)
+	}
+	defer rows.Close()
+
+	overrides := []models.PermissionOverride{}
+	for rows.Next() {
+		o, err := scanPermissionOverride(rows)
+		if err != nil {
+			return nil, err
+		}
+		overrides = append(overrides, o)
+	}
+	return overrides, rows.Err()
+}
+
+// SetPermissionOverride creates or replaces the user's override for the
+// permission and bumps their permissions version.
+func (s *Store) SetPermissionOverride(ctx context.Context, o models.PermissionOverride) (models.PermissionOverride, error) {
+	const query = `
+	WITH bumped AS (
+		UPDATE users SET permissions_version = permissions_version + 1 WHERE id = $1
+	), saved AS (
+		INSERT INTO user_permissions (user_id, permission_id, allow, set_by)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (user_id, permission_id) DO UPDATE
+		SET allow = EXCLUDED.allow, set_by = EXCLUDED.set_by, set_at = NOW()
+		RETURNING user_id, permission_id, allow, set_by, set_at
+	)
+	SELECT sv.user_id, sv.permission_id, p.permission_name, sv.allow, sv.set_by, sv.set_at
+	FROM saved sv
+	JOIN permission p ON p.id = sv.permission_id;
+	`
+	saved, err := scanPermissionOverride(s.db.QueryRow(ctx, query, o.UserID, o.PermissionID, o.Allow, o.SetBy))
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+		return models.PermissionOverride{}, storage.ErrNotFound
+	}
+	return saved, err
+}
+
+// DeletePermissionOverride removes the user's override so the role decides
+// again, and bumps their permissions version.
+func (s *Store) DeletePermissionOverride(ctx context.Context, userID, permissionID int64) error {
+	const query = `
+	WITH deleted AS (
+		DELETE FROM user_permissions WHERE user_id = $1 AND permission_id = $2
+		RETURNING user_id
+	), bumped AS (
+		UPDATE users SET permissions_version = permissions_version + 1
+		WHERE id IN (SELECT user_id FROM deleted)
+	)
+	SELECT EXISTS (SELECT 1 FROM deleted);
+	`
+	var deleted bool
+	if err := s.db.QueryRow(ctx, query, userID, permissionID).Scan(&deleted); err != nil {
+		return fmt.Errorf("delete permission override: %w", err)
+	}
+	if !deleted {
+		return storage.ErrNotFound
+	}
+	return nil
+}
+
+func scanPermissionOverride(row pgx.Row) (models.PermissionOverride, error) {
+	var o models.PermissionOverride
+	if err := row.Scan(&o.UserID, &o.PermissionID, &o.Permission, &o.Allow, &o.SetBy, &o.SetAt); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.PermissionOverride{}, storage.ErrNotFound
+		}
+		return models.PermissionOverride{}, err
+	}
+	return o, nil
+}
+
+func scanRole(row pgx.Row) (models.Role, error) {
+	var r models.Role
+	if err := row.Scan(&r.ID, &r.RoleName, &r.RoleDescription, &r.Permissions); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.Role{}, storage.ErrNotFound
+		}
+		return models.Role{}, err
+	}
+	return r, nil
+}
+
+func scanPermission(row pgx.Row) (models.Permission, error) {
+	var p models.Permission
+	if err := row.Scan(&p.ID, &p.PermissionName, &p.PermissionDescription); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.Permission{}, storage.ErrNotFound
+		}
+		return models.Permission{}, err
+	}
+	return p, nil
+}
+
+// uniqueViolation maps a unique constraint error to storage.ErrAlreadyExists.
+func uniqueViolation(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		return storage.ErrAlreadyExists
+	}
+	return err
+}
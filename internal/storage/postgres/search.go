@@ -0,0 +1,67 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/hongminglow/all-in-be/internal/models"
+)
+
+// searchUsersQuery ranks exact matches above prefix matches above other
+// substring matches, and orders within each tier by trigram similarity. The
+// ILIKE filter is served by the gin_trgm_ops indexes.
+const searchUsersQuery = `
+	WITH matches AS (
+		SELECT id, (
+			CASE
+				WHEN lower(username) = lower($1) OR lower(email) = lower($1) OR phone = $1 THEN 3
+				WHEN username ILIKE $2 OR email ILIKE $2 OR phone LIKE $2 THEN 2
+				ELSE 1
+			END + GREATEST(similarity(username, $1), similarity(email, $1), similarity(phone, $1))
+		)::real AS rank
+		FROM users
+		WHERE username ILIKE $3 OR email ILIKE $3 OR phone LIKE $3
+	)
+	SELECT ` + userColumns + `, m.rank
+	FROM matches m
+	JOIN users u ON u.id = m.id
+	JOIN role r ON u.role = r.role_name
+	WHERE $4::real IS NULL OR m.rank < $4 OR (m.rank = $4 AND m.id > $5)
+	ORDER BY m.rank DESC, m.id ASC
+	LIMIT $6;
+	`
+
+// SearchUsers finds users whose username, email, or phone contains query.
+func (s *Store) SearchUsers(ctx context.Context, query string, after *models.UserSearchCursor, limit int) ([]models.UserSearchResult, error) {
+	escaped := escapeLike(query)
+	var afterRank *float32
+	var afterID int64
+	if after != nil {
+		rank := float32(after.Rank)
+		afterRank, afterID = &rank, after.ID
+	}
+	rows, err := s.reader().Query(ctx, searchUsersQuery, query, escaped+"%", "%"+escaped+"%", afterRank, afterID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("search users: %w", err)
+	}
+	defer rows.Close()
+
+	results := []models.UserSearchResult{}
+	for rows.Next() {
+		var rank float32
+		user, err := scanUser(rows, &rank)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, models.UserSearchResult{User: user, Rank: float64(rank)})
+	}
+	return results, rows.Err()
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// escapeLike makes s match literally inside a LIKE pattern.
+func escapeLike(s string) string {
+	return likeEscaper.Replace(s)
+}
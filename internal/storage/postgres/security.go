@@ -0,0 +1,320 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/hongminglow/all-in-be/internal/models"
+	"github.com/hongminglow/all-in-be/internal/storage"
+	"github.com/jackc/pgx/v5"
+)
+
+const loginAlertColumns = `id, user_id, fingerprint, country, ip, user_agent, token_hash, status, created_at, resolved_at`
+
+const loginAttemptColumns = `id, user_id, identifier, success, failure_reason, ip, user_agent, country, created_at`
+
+// ListLoginDevices returns the devices a user has signed in from, most recent first.
+func (s *Store) ListLoginDevices(ctx context.Context, userID int64) ([]models.LoginDevice, error) {
+	const query = `
+	SELECT user_id, fingerprint, country, first_seen, last_seen
+	FROM login_devices
+	WHERE user_id = $1
+	ORDER BY last_seen DESC;
+	`
+	// Read from the primary: a lagging replica would report a device seen
+	// moments ago as new and send a spurious alert.
+	rows, err := s.db.Query(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("list login devices: %w", err)
+	}
+	defer rows.Close()
+
+	devices := []models.LoginDevice{}
+	for rows.Next() {
+		var d models.LoginDevice
+		if err := rows.Scan(&d.UserID, &d.Fingerprint, &d.Country, &d.FirstSeen, &d.LastSeen); err != nil {
+			return nil, err
+		}
+		devices = append(devices, d)
+	}
+	return devices, rows.Err()
+}
+
+// TouchLoginDevice records a sign-in from the device, creating it on first use.
+func (s *Store) TouchLoginDevice(ctx context.Context, device models.LoginDevice) error {
+	const query = `
+	INSERT INTO login_devices (user_id, fingerprint, country, first_seen, last_seen)
+	VALUES ($1, $2, $3, $4, $4)
+	ON CONFLICT (user_id, fingerprint, country) DO UPDATE SET last_seen = EXCLUDED.last_seen;
+	`
+	if _, err := s.db.Exec(ctx, query, device.UserID, device.Fingerprint, device.Country, device.LastSeen); err != nil {
+		return fmt.Errorf("touch login device: %w", err)
+	}
+	return nil
+}
+
+// CreateLoginAlert stores a pending alert.
+func (s *Store) CreateLoginAlert(ctx context.Context, alert models.LoginAlert) (models.LoginAlert, error) {
+	const query = `
+	INSERT INTO login_alerts (user_id, fingerprint, country, ip, user_agent, token_hash, status)
+	VALUES ($1, $2, $3, $4, $5, $6, $7)
+	RETURNING ` + loginAlertColumns + `;
+	`
+	row := s.db.QueryRow(ctx, query, alert.UserID, alert.Fingerprint, alert.Country, alert.IP, alert.UserAgent, alert.TokenHash, alert.Status)
+	return scanLoginAlert(row)
+}
+
+// FindLoginAlertByToken fetches the alert whose link token hashes to tokenHash.
+func (s *Store) FindLoginAlertByToken(ctx context.Context, tokenHash string) (models.LoginAlert, error) {
+	const query = `SELECT ` + loginAlertColumns + ` FROM login_alerts WHERE token_hash = $1;`
+	return scanLoginAlert(s.db.QueryRow(ctx, query, tokenHash))
+}
+
+// ListLoginAlerts returns the user's newest alerts.
+func (s *Store) ListLoginAlerts(ctx context.Context, userID int64, limit int) ([]models.LoginAlert, error) {
+	const query = `SELECT ` + loginAlertColumns + ` FROM login_alerts WHERE user_id = $1 ORDER BY id DESC LIMIT $2;`
+	rows, err := s.reader().Query(ctx, query, userID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("list login alerts: %w", err)
+	}
+	defer rows.Close()
+
+	alerts := []models.LoginAlert{}
+	for rows.Next() {
+		alert, err := scanLoginAlert(rows)
+		if err != nil {
+			return nil, err
+		}
+		alerts = append(alerts, alert)
+	}
+	return alerts, rows.Err()
+}
+
+// ResolveLoginAlert moves a pending alert to status.
+func (s *Store) ResolveLoginAlert(ctx context.Context, id int64, status string, at time.Time) error {
+	const query = `UPDATE login_alerts SET status = $2, resolved_at = $3 WHERE id = $1 AND status = 'pending';`
+	tag, err := s.db.Exec(ctx, query, id, status, at)
+	if err != nil {
+		return fmt.Errorf("resolve login alert: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return storage.ErrNotFound
+	}
+	return nil
+}
+
+// CreateSecurityCase opens a case for staff follow-up.
+func (s *Store) CreateSecurityCase(ctx context.Context, c models.SecurityCase) (models.SecurityCase, error) {
+	const query = `
+	WITH inserted AS (
+		INSERT INTO security_cases (user_id, login_alert_id, reason, status)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, user_id, login_alert_id, reason, status, created_at
+	)
+	SELECT i.id, i.user_id, u.username, i.login_alert_id, i.reason, i.status, i.created_at
+	FROM inserted i
+	JOIN users u ON u.id = i.user_id;
+	`
+	row := s.db.QueryRow(ctx, query, c.UserID, c.LoginAlertID, c.Reason, c.Status)
+	return scanSecurityCase(row)
+}
+
+// ListSecurityCases returns the newest cases first.
+func (s *Store) ListSecurityCases(ctx context.Context, limit int) ([]models.SecurityCase, error) {
+	const query = `
+	SELECT c.id, c.user_id, u.username, c.login_alert_id, c.reason, c.status, c.created_at
+	FROM security_cases c
+	JOIN users u ON u.id = c.user_id
+	ORDER BY c.created_at DESC, c.id DESC
+	LIMIT $1;
+	`
+	rows, err := s.reader().Query(ctx, query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("list security cases: %w", err)
+	}
+	defer rows.Close()
+
+	cases := []models.SecurityCase{}
+	for rows.Next() {
+		c, err := scanSecurityCase(rows)
+		if err != nil {
+			return nil, err
+		}
+		cases = append(cases, c)
+	}
+	return cases, rows.Err()
+}
+
+// LockAccount revokes the user's sessions and requires a password reset.
+func (s *Store) LockAccount(ctx context.Context, userID int64, at time.Time) error {
+	const query = `UPDATE users SET password_reset_required = TRUE, sessions_revoked_at = $2 WHERE id = $1;`
+	tag, err := s.db.Exec(ctx, query, userID, at)
+	if err != nil {
+		return fmt.Errorf("lock account: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return storage.ErrNotFound
+	}
+	return nil
+}
+
+// CreatePasswordReset stores a reset token hash.
+func (s *Store) CreatePasswordReset(ctx context.Context, reset models.PasswordReset) error {
+	const query = `INSERT INTO password_resets (token_hash, user_id, expires_at) VALUES ($1, $2, $3);`
+	if _, err := s.db.Exec(ctx, query, reset.TokenHash, reset.UserID, reset.ExpiresAt); err != nil {
+		return fmt.Errorf("create password reset: %w", err)
+	}
+	return nil
+}
+
+// ConsumePasswordReset deletes an unexpired reset and returns it.
+func (s *Store) ConsumePasswordReset(ctx context.Context, tokenHash string, now time.Time) (models.PasswordReset, error) {
+	const query = `
+	DELETE FROM password_resets
+	WHERE token_hash = $1 AND expires_at > $2
+	RETURNING user_id, token_hash, expires_at;
+	`
+	var reset models.PasswordReset
+	if err := s.db.QueryRow(ctx, query, tokenHash, now).Scan(&reset.UserID, &reset.TokenHash, &reset.ExpiresAt); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.PasswordReset{}, storage.ErrNotFound
+		}
+		return models.PasswordReset{}, fmt.Errorf("consume password reset: %w", err)
+	}
+	return reset, nil
+}
+
+// CreateDeviceConfirmation stores a device confirmation token hash.
+func (s *Store) CreateDeviceConfirmation(ctx context.Context, c models.DeviceConfirmation) error {
+	const query = `
+	INSERT INTO device_confirmations (token_hash, user_id, fingerprint, country, expires_at)
+	VALUES ($1, $2, $3, $4, $5);
+	`
+	if _, err := s.db.Exec(ctx, query, c.TokenHash, c.UserID, c.Fingerprint, c.Country, c.ExpiresAt); err != nil {
+		return fmt.Errorf("create device confirmation: %w", err)
+	}
+	return nil
+}
+
+// ConsumeDeviceConfirmation deletes an unexpired confirmation and returns it.
+func (s *Store) ConsumeDeviceConfirmation(ctx context.Context, tokenHash string, now time.Time) (models.DeviceConfirmation, error) {
+	const query = `
+	DELETE FROM device_confirmations
+	WHERE token_hash = $1 AND expires_at > $2
+	RETURNING user_id, fingerprint, country, token_hash, expires_at;
+	`
+	var c models.DeviceConfirmation
+	if err := s.db.QueryRow(ctx, query, tokenHash, now).Scan(&c.UserID, &c.Fingerprint, &c.Country, &c.TokenHash, &c.ExpiresAt); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.DeviceConfirmation{}, storage.ErrNotFound
+		}
+		return models.DeviceConfirmation{}, fmt.Errorf("consume device confirmation: %w", err)
+	}
+	return c, nil
+}
+
+// RecordLoginAttempt appends a sign-in attempt to the login history.
+func (s *Store) RecordLoginAttempt(ctx context.Context, a models.LoginAttempt) (models.LoginAttempt, error) {
+	const query = `
+	INSERT INTO login_history (user_id, identifier, success, failure_reason, ip, user_agent, country)
+	VALUES ($1, $2, $3, $4, $5, $6, $7)
+	RETURNING ` + loginAttemptColumns + `;
+	`
+	row := s.db.QueryRow(ctx, query, a.UserID, a.Identifier, a.Success, a.FailureReason, a.IP, a.UserAgent, a.Country)
+	saved, err := scanLoginAttempt(row)
+	if err != nil {
+		return models.LoginAttempt{}, fmt.Errorf("record login attempt: %w", err)
+	}
+	return saved, nil
+}
+
+// ListLoginAttempts returns the newest attempts matching filter first,
+// reading the archive too when the filter asks for it.
+func (s *Store) ListLoginAttempts(ctx context.Context, filter models.LoginAttemptFilter, limit int) ([]models.LoginAttempt, error) {
+	const query = `
+	SELECT ` + loginAttemptColumns + `
+	FROM (
+		SELECT ` + loginAttemptColumns + ` FROM login_history
+		UNION ALL
+		SELECT ` + loginAttemptColumns + ` FROM login_history_archive WHERE $5
+	) h
+	WHERE ($1 = 0 OR user_id = $1)
+		AND ($2 = '' OR ip = $2)
+		AND ($3::BOOLEAN IS NULL OR success = $3)
+	ORDER BY id DESC
+	LIMIT $4;
+	`
+	rows, err := s.reader().Query(ctx, query, filter.UserID, filter.IP, filter.Success, limit, filter.IncludeArchived)
+	if err != nil {
+		return nil, fmt.Errorf("list login attempts: %w", err)
+	}
+	defer rows.Close()
+
+	attempts := []models.LoginAttempt{}
+	for rows.Next() {
+		a, err := scanLoginAttempt(rows)
+		if err != nil {
+			return nil, err
+		}
+		attempts = append(attempts, a)
+	}
+	return attempts, rows.Err()
+}
+
+// SetPassword stores a new password hash and revokes older sessions.
+func (s *Store) SetPassword(ctx context.Context, userID int64, passwordHash string, at time.Time) error {
+	const query = `UPDATE users SET password_hash = $2, password_reset_required = FALSE, sessions_revoked_at = $3 WHERE id = $1;`
+	tag, err := s.db.Exec(ctx, query, userID, passwordHash, at)
+	if err != nil {
+		return fmt.Errorf("set password: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return storage.ErrNotFound
+	}
+	return nil
+}
+
+// RevokeSessions revokes the user's sessions issued up to at.
+func (s *Store) RevokeSessions(ctx context.Context, userID int64, at time.Time) error {
+	const query = `UPDATE users SET sessions_revoked_at = $2 WHERE id = $1;`
+	tag, err := s.db.Exec(ctx, query, userID, at)
+	if err != nil {
+		return fmt.Errorf("revoke sessions: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return storage.ErrNotFound
+	}
+	return nil
+}
+
+func scanLoginAlert(row pgx.Row) (models.LoginAlert, error) {
+	var a models.LoginAlert
+	if err := row.Scan(&a.ID, &a.UserID, &a.Fingerprint, &a.Country, &a.IP, &a.UserAgent, &a.TokenHash, &a.Status, &a.CreatedAt, &a.ResolvedAt); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.LoginAlert{}, storage.ErrNotFound
+		}
+		return models.LoginAlert{}, err
+	}
+	return a, nil
+}
+
+func scanLoginAttempt(row pgx.Row) (models.LoginAttempt, error) {
+	var a models.LoginAttempt
+	if err := row.Scan(&a.ID, &a.UserID, &a.Identifier, &a.Success, &a.FailureReason, &a.IP, &a.UserAgent, &a.Country, &a.CreatedAt); err != nil {
+		return models.LoginAttempt{}, err
+	}
+	return a, nil
+}
+
+func scanSecurityCase(row pgx.Row) (models.SecurityCase, error) {
+	var c models.SecurityCase
+	if err := row.Scan(&c.ID, &c.UserID, &c.Username, &c.LoginAlertID, &c.Reason, &c.Status, &c.CreatedAt); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.SecurityCase{}, storage.ErrNotFound
+		}
+		return models.SecurityCase{}, err
+	}
+	return c, nil
+}
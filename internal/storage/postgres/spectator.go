@@ -0,0 +1,82 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/hongminglow/all-in-be/internal/models"
+	"github.com/hongminglow/all-in-be/internal/storage"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// RecentBigWins returns the newest large bet settlements of users who have
+// not hidden their public activity.
+func (s *Store) RecentBigWins(ctx context.Context, minAmount float64, limit int) ([]models.BigWin, error) {
+	const query = `
+	SELECT t.amount, t.created_at, u.username, COALESCE(p.public_activity, $1)
+	FROM wallet_transactions t
+	JOIN users u ON u.id = t.user_id
+	LEFT JOIN user_privacy p ON p.user_id = t.user_id
+	WHERE t.reason = $2 AND t.amount >= $3 AND COALESCE(p.public_activity, $1) <> $4
+	ORDER BY t.id DESC
+	LIMIT $5;
+	`
+	rows, err := s.reader().Query(ctx, query, models.ActivityMasked, models.TransactionBetSettlement, minAmount, models.ActivityHidden, limit)
+	if err != nil {
+		return nil, fmt.Errorf("list big wins: %w", err)
+	}
+	defer rows.Close()
+
+	wins := []models.BigWin{}
+	for rows.Next() {
+		var w models.BigWin
+		if err := rows.Scan(&w.Amount, &w.WonAt, &w.Username, &w.PublicActivity); err != nil {
+			return nil, err
+		}
+		wins = append(wins, w)
+	}
+	return wins, rows.Err()
+}
+
+// FindPrivacySettings fetches the user's settings, defaulting to masked activity.
+func (s *Store) FindPrivacySettings(ctx context.Context, userID int64) (models.PrivacySettings, error) {
+	const query = `
+	SELECT u.id, COALESCE(p.public_activity, $2), COALESCE(p.updated_at, u.created_at)
+	FROM users u
+	LEFT JOIN user_privacy p ON p.user_id = u.id
+	WHERE u.id = $1;
+	`
+	return scanPrivacySettings(s.reader().QueryRow(ctx, query, userID, models.ActivityMasked))
+}
+
+// SavePrivacySettings creates or replaces the user's settings.
+func (s *Store) SavePrivacySettings(ctx context.Context, settings models.PrivacySettings) (models.PrivacySettings, error) {
+	const query = `
+	INSERT INTO user_privacy (user_id, public_activity)
+	VALUES ($1, $2)
+	ON CONFLICT (user_id) DO UPDATE SET public_activity = EXCLUDED.public_activity, updated_at = NOW()
+	RETURNING user_id, public_activity, updated_at;
+	`
+	saved, err := scanPrivacySettings(s.db.QueryRow(ctx, query, settings.UserID, settings.PublicActivity))
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+			return models.PrivacySettings{}, storage.ErrNotFound
+		}
+		return models.PrivacySettings{}, fmt.Errorf("save privacy settings: %w", err)
+	}
+	return saved, nil
+}
+
+func scanPrivacySettings(row pgx.Row) (models.PrivacySettings, error) {
+	var p models.PrivacySettings
+	if err := row.Scan(&p.UserID, &p.PublicActivity, &p.UpdatedAt); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.PrivacySettings{}, storage.ErrNotFound
+		}
+		return models.PrivacySettings{}, err
+	}
+	return p, nil
+}
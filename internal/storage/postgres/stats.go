@@ -0,0 +1,73 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/hongminglow/all-in-be/internal/models"
+)
+
+// AdminStats computes totals in a single scan of users and buckets recent signups by UTC day,
+// including days without signups.
+func (s *Store) AdminStats(ctx context.Context, days int) (models.AdminStats, error) {
+	stats := models.AdminStats{GeneratedAt: time.Now().UTC()}
+
+	const totals = `SELECT COUNT(*), COALESCE(SUM(balance), 0) FROM users;`
+	if err := s.reader().QueryRow(ctx, totals).Scan(&stats.TotalUsers, &stats.TotalBalance); err != nil {
+		return models.AdminStats{}, fmt.Errorf("user totals: %w", err)
+	}
+
+	const signups = `
+	SELECT to_char(d.day, 'YYYY-MM-DD'), COUNT(u.id)
+	FROM generate_series(
+		(NOW() AT TIME ZONE 'UTC')::date - ($1::int - 1),
+		(NOW() AT TIME ZONE 'UTC')::date,
+		INTERVAL '1 day'
+	) AS d(day)
+	LEFT JOIN users u
+		ON u.created_at >= d.day AT TIME ZONE 'UTC'
+		AND u.created_at < (d.day + INTERVAL '1 day') AT TIME ZONE 'UTC'
+	GROUP BY d.day
+	ORDER BY d.day;
+	`
+	rows, err := s.reader().Query(ctx, signups, days)
+	if err != nil {
+		return models.AdminStats{}, fmt.Errorf("signups per day: %w", err)
+	}
+	defer rows.Close()
+
+	stats.SignupsPerDay = make([]models.DailyCount, 0, days)
+	for rows.Next() {
+		var day models.DailyCount
+		if err := rows.Scan(&day.Day, &day.Count); err != nil {
+			return models.AdminStats{}, err
+		}
+		stats.SignupsPerDay = append(stats.SignupsPerDay, day)
+	}
+	return stats, rows.Err()
+}
+
+// BusinessKPIs totals registrations and the ledger.
+func (s *Store) BusinessKPIs(ctx context.Context) (models.BusinessKPIs, error) {
+	const query = `
+	WITH ledger AS (
+		SELECT amount, reason FROM wallet_transactions
+		UNION ALL
+		SELECT amount, reason FROM wallet_transactions_archive
+	)
+	SELECT
+		(SELECT COUNT(*) FROM users),
+		COUNT(*) FILTER (WHERE reason = 'deposit'),
+		COALESCE(SUM(amount) FILTER (WHERE reason = 'deposit'), 0),
+		COUNT(*) FILTER (WHERE reason = 'bet_settlement'),
+		COALESCE(-SUM(amount) FILTER (WHERE reason = 'bet_settlement'), 0)
+	FROM ledger;
+	`
+	var kpis models.BusinessKPIs
+	err := s.reader().QueryRow(ctx, query).Scan(&kpis.Registrations, &kpis.Deposits, &kpis.DepositsAmount, &kpis.Bets, &kpis.GGR)
+	if err != nil {
+		return models.BusinessKPIs{}, fmt.Errorf("business kpis: %w", err)
+	}
+	return kpis, nil
+}
@@ -4,51 +4,122 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"log"
+	"regexp"
+	"slices"
+	"strings"
 
 	"github.com/hongminglow/all-in-be/internal/models"
+	"github.com/hongminglow/all-in-be/internal/phone"
 	"github.com/hongminglow/all-in-be/internal/storage"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
-// Ensure Store satisfies the storage.UserStore interface at compile time.
-var _ storage.UserStore = (*Store)(nil)
+// Ensure Store satisfies the storage interfaces at compile time.
+var _ storage.Store = (*Store)(nil)
+
+// dbtx is the subset of pgx behaviour shared by the pool and an open transaction,
+// letting every query method run unchanged inside or outside a unit of work.
+type dbtx interface {
+	Begin(ctx context.Context) (pgx.Tx, error)
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
 
 // Store provides Postgres-backed persistence for users.
 type Store struct {
-	pool *pgxpool.Pool
+	pool     *pgxpool.Pool
+	poolConf PoolConfig
+	db       dbtx
+	replica  *replica
+	breaker  *breaker
+	// prepared is set when every connection has userLookups prepared.
+	prepared bool
 }
 
-// NewUserStore creates a new Store and runs migrations.
-func NewUserStore(ctx context.Context, databaseURL string) (*Store, error) {
-	cfg, err := pgxpool.ParseConfig(databaseURL)
+// NewUserStore runs migrations and then opens a pool with the given settings.
+// Phone numbers stored without a country code are migrated to E.164 as
+// numbers of phoneRegion, PHONE_DEFAULT_REGION.
+func NewUserStore(ctx context.Context, databaseURL string, poolConf PoolConfig, phoneRegion string) (*Store, error) {
+	cfg, err := poolConfig(databaseURL, poolConf)
 	if err != nil {
-		return nil, fmt.Errorf("parse database url: %w", err)
+		return nil, err
+	}
+	// Migrate over a dedicated connection first: pooled connections prepare
+	// statements against the schema as they open.
+	if err := migrateOnce(ctx, cfg.ConnConfig, phoneRegion); err != nil {
+		return nil, err
 	}
-
 	pool, err := pgxpool.NewWithConfig(ctx, cfg)
 	if err != nil {
 		return nil, fmt.Errorf("connect to database: %w", err)
 	}
+	b := newBreaker(poolConf.Retry.BreakerThreshold, poolConf.Retry.BreakerCooldown)
+	return &Store{
+		pool:     pool,
+		poolConf: poolConf,
+		db:       withRetry(withQueryTimeout(pool, poolConf.QueryTimeout), poolConf.Retry, b),
+		breaker:  b,
+		prepared: preparesStatements(cfg),
+	}, nil
+}
 
-	s := &Store{pool: pool}
-	if err := s.migrate(ctx); err != nil {
-		pool.Close()
-		return nil, err
+// migrateOnce applies migrations over a single connection. Migrations such as
+// index builds are exempt from the statement timeout.
+func migrateOnce(ctx context.Context, connConfig *pgx.ConnConfig, phoneRegion string) error {
+	connConfig = connConfig.Copy()
+	delete(connConfig.RuntimeParams, "statement_timeout")
+	conn, err := pgx.ConnectConfig(ctx, connConfig)
+	if err != nil {
+		return fmt.Errorf("connect to database: %w", err)
 	}
+	defer conn.Close(ctx)
+	return (&Store{db: conn}).migrate(ctx, phoneRegion)
+}
 
-	return s, nil
+// EnableFaultInjection makes queries fail for requests the chaos middleware marked.
+// It is only meant for non-production resilience testing.
+func (s *Store) EnableFaultInjection() {
+	s.db = faultDB{dbtx: s.db}
+	if s.replica != nil {
+		s.replica.db = faultDB{dbtx: s.replica.db}
+	}
 }
 
 // Close releases database resources.
 func (s *Store) Close() {
+	if s.replica != nil {
+		s.replica.close()
+	}
 	if s.pool != nil {
 		s.pool.Close()
 	}
 }
 
-func (s *Store) migrate(ctx context.Context) error {
+// WithTx runs fn inside a single database transaction. The repositories handed to fn
+// share that transaction; it commits when fn returns nil and rolls back otherwise.
+// Calling WithTx on a transaction-scoped store opens a savepoint.
+//
+// A transaction that fails with a serialization failure or deadlock is rolled
+// back and run again under the retry policy, so fn must not have effects
+// outside the database; publish events once WithTx has returned.
+func (s *Store) WithTx(ctx context.Context, fn func(tx storage.Repositories) error) error {
+	// Statements inside the transaction are not retried one by one: a failure
+	// aborts the transaction, so only a fresh one can succeed. Begin is
+	// already retried by s.db.
+	txConf := s.poolConf
+	txConf.Retry = RetryPolicy{}
+	return s.poolConf.Retry.retry(ctx, rolledBack, func() error {
+		return pgx.BeginFunc(ctx, s.db, func(tx pgx.Tx) error {
+			return fn(&Store{db: withQueryTimeout(tx, s.poolConf.QueryTimeout), poolConf: txConf, prepared: s.prepared})
+		})
+	})
+}
+
+func (s *Store) migrate(ctx context.Context, phoneRegion string) error {
 	stmts := []string{
 		`CREATE TABLE IF NOT EXISTS users (
 			id BIGSERIAL PRIMARY KEY,
@@ -73,24 +144,827 @@ func (s *Store) migrate(ctx context.Context) error {
 		`INSERT INTO permission (id, permission_name, permission_description) VALUES (1, 'game:play', 'Play games'), (2, 'bonus:claim', 'Claim bonuses'), (3, 'support:priority', 'Priority support') ON CONFLICT (id) DO NOTHING;`,
 		`CREATE TABLE IF NOT EXISTS role_permissions (role_id BIGINT NOT NULL, permission_id BIGINT NOT NULL, PRIMARY KEY (role_id, permission_id), FOREIGN KEY (role_id) REFERENCES role(id), FOREIGN KEY (permission_id) REFERENCES permission(id));`,
 		`INSERT INTO role_permissions (role_id, permission_id) VALUES (1, 1), (2, 1), (2, 2), (3, 1), (3, 2), (3, 3) ON CONFLICT DO NOTHING;`,
+		`INSERT INTO role (id, role_name, role_description) VALUES (4, 'staff', 'Support Staff') ON CONFLICT (id) DO UPDATE SET role_name = EXCLUDED.role_name;`,
+		`INSERT INTO permission (id, permission_name, permission_description) VALUES (4, 'notes:read', 'Read internal user notes'), (5, 'notes:write', 'Write internal user notes') ON CONFLICT (id) DO NOTHING;`,
+		`INSERT INTO role_permissions (role_id, permission_id) VALUES (4, 4), (4, 5) ON CONFLICT DO NOTHING;`,
+		`CREATE TABLE IF NOT EXISTS user_notes (
+			id BIGSERIAL PRIMARY KEY,
+			user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			author_id BIGINT NOT NULL REFERENCES users(id),
+			body TEXT NOT NULL,
+			pinned BOOLEAN NOT NULL DEFAULT FALSE,
+			created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+			updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+		);`,
+		`CREATE INDEX IF NOT EXISTS user_notes_user_idx ON user_notes (user_id, pinned DESC, created_at DESC);`,
+		`CREATE TABLE IF NOT EXISTS user_note_revisions (
+			id BIGSERIAL PRIMARY KEY,
+			note_id BIGINT NOT NULL REFERENCES user_notes(id) ON DELETE CASCADE,
+			body TEXT NOT NULL,
+			editor_id BIGINT NOT NULL REFERENCES users(id),
+			edited_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+		);`,
+		`INSERT INTO role (id, role_name, role_description) VALUES (5, 'admin', 'Administrator') ON CONFLICT (id) DO UPDATE SET role_name = EXCLUDED.role_name;`,
+		`INSERT INTO permission (id, permission_name, permission_description) VALUES (6, 'config:manage', 'Manage runtime configuration') ON CONFLICT (id) DO NOTHING;`,
+		`INSERT INTO role_permissions (role_id, permission_id) VALUES (5, 4), (5, 5), (5, 6) ON CONFLICT DO NOTHING;`,
+		`CREATE TABLE IF NOT EXISTS rate_limit_policies (
+			tenant TEXT NOT NULL DEFAULT '',
+			route_class TEXT NOT NULL,
+			requests INTEGER NOT NULL CHECK (requests > 0),
+			window_seconds INTEGER NOT NULL CHECK (window_seconds > 0),
+			updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+			PRIMARY KEY (tenant, route_class)
+		);`,
+		`INSERT INTO permission (id, permission_name, permission_description) VALUES (7, 'stats:read', 'View operator statistics') ON CONFLICT (id) DO NOTHING;`,
+		`INSERT INTO role_permissions (role_id, permission_id) VALUES (4, 7), (5, 7) ON CONFLICT DO NOTHING;`,
+		`CREATE INDEX IF NOT EXISTS users_created_at_idx ON users (created_at);`,
+		`CREATE TABLE IF NOT EXISTS config_history (
+			id BIGSERIAL PRIMARY KEY,
+			entity TEXT NOT NULL,
+			entity_key TEXT NOT NULL,
+			before JSONB,
+			after JSONB,
+			changed_by BIGINT NOT NULL REFERENCES users(id),
+			rollback_of BIGINT REFERENCES config_history(id),
+			changed_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+		);`,
+		`CREATE INDEX IF NOT EXISTS config_history_entity_idx ON config_history (entity, changed_at DESC);`,
+		`CREATE TABLE IF NOT EXISTS webhook_endpoints (
+			id BIGSERIAL PRIMARY KEY,
+			url TEXT NOT NULL,
+			secret TEXT NOT NULL,
+			events TEXT[] NOT NULL,
+			active BOOLEAN NOT NULL DEFAULT TRUE,
+			created_by BIGINT NOT NULL REFERENCES users(id),
+			created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+		);`,
+		`CREATE TABLE IF NOT EXISTS webhook_deliveries (
+			id BIGSERIAL PRIMARY KEY,
+			endpoint_id BIGINT NOT NULL REFERENCES webhook_endpoints(id) ON DELETE CASCADE,
+			event_id TEXT NOT NULL,
+			event TEXT NOT NULL,
+			payload JSONB NOT NULL,
+			attempt INTEGER NOT NULL,
+			status_code INTEGER,
+			error TEXT NOT NULL DEFAULT '',
+			success BOOLEAN NOT NULL,
+			duration_ms BIGINT NOT NULL,
+			created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+		);`,
+		`CREATE INDEX IF NOT EXISTS webhook_deliveries_endpoint_idx ON webhook_deliveries (endpoint_id, created_at DESC);`,
+		`ALTER TABLE users ADD COLUMN IF NOT EXISTS home_region TEXT NOT NULL DEFAULT '';`,
+		`ALTER TABLE users ADD COLUMN IF NOT EXISTS password_reset_required BOOLEAN NOT NULL DEFAULT FALSE;`,
+		`ALTER TABLE users ADD COLUMN IF NOT EXISTS sessions_revoked_at TIMESTAMPTZ;`,
+		`CREATE TABLE IF NOT EXISTS login_devices (
+			user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			fingerprint TEXT NOT NULL,
+			country TEXT NOT NULL,
+			first_seen TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+			last_seen TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+			PRIMARY KEY (user_id, fingerprint, country)
+		);`,
+		`CREATE TABLE IF NOT EXISTS login_alerts (
+			id BIGSERIAL PRIMARY KEY,
+			user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			fingerprint TEXT NOT NULL,
+			country TEXT NOT NULL,
+			ip TEXT NOT NULL,
+			user_agent TEXT NOT NULL,
+			token_hash TEXT UNIQUE NOT NULL,
+			status TEXT NOT NULL DEFAULT 'pending',
+			created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+			resolved_at TIMESTAMPTZ
+		);`,
+		`CREATE TABLE IF NOT EXISTS security_cases (
+			id BIGSERIAL PRIMARY KEY,
+			user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			login_alert_id BIGINT REFERENCES login_alerts(id),
+			reason TEXT NOT NULL,
+			status TEXT NOT NULL DEFAULT 'open',
+			created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+		);`,
+		`CREATE TABLE IF NOT EXISTS password_resets (
+			token_hash TEXT PRIMARY KEY,
+			user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			expires_at TIMESTAMPTZ NOT NULL
+		);`,
+		`INSERT INTO permission (id, permission_name, permission_description) VALUES (8, 'security:read', 'View account security cases') ON CONFLICT (id) DO NOTHING;`,
+		`INSERT INTO role_permissions (role_id, permission_id) VALUES (4, 8), (5, 8) ON CONFLICT DO NOTHING;`,
+		`CREATE EXTENSION IF NOT EXISTS pg_trgm;`,
+		// CONCURRENTLY keeps signups and logins running while large tables are indexed.
+		`CREATE INDEX CONCURRENTLY IF NOT EXISTS users_username_trgm_idx ON users USING gin (username gin_trgm_ops);`,
+		`CREATE INDEX CONCURRENTLY IF NOT EXISTS users_email_trgm_idx ON users USING gin (email gin_trgm_ops);`,
+		`CREATE INDEX CONCURRENTLY IF NOT EXISTS users_phone_trgm_idx ON users USING gin (phone gin_trgm_ops);`,
+		`INSERT INTO permission (id, permission_name, permission_description) VALUES (9, 'users:read', 'Search user accounts') ON CONFLICT (id) DO NOTHING;`,
+		`INSERT INTO role_permissions (role_id, permission_id) VALUES (4, 9), (5, 9) ON CONFLICT DO NOTHING;`,
+		`CREATE TABLE IF NOT EXISTS inbound_deliveries (
+			id BIGSERIAL PRIMARY KEY,
+			provider TEXT NOT NULL,
+			event_id TEXT NOT NULL,
+			headers JSONB NOT NULL,
+			payload TEXT NOT NULL,
+			status TEXT NOT NULL,
+			error TEXT NOT NULL DEFAULT '',
+			attempts INTEGER NOT NULL DEFAULT 0,
+			received_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+			last_attempt_at TIMESTAMPTZ
+		);`,
+		`CREATE INDEX IF NOT EXISTS inbound_deliveries_provider_idx ON inbound_deliveries (provider, received_at DESC);`,
+		`CREATE INDEX IF NOT EXISTS inbound_deliveries_event_idx ON inbound_deliveries (provider, event_id);`,
+		`CREATE TABLE IF NOT EXISTS inbound_events (
+			provider TEXT NOT NULL,
+			event_id TEXT NOT NULL,
+			delivery_id BIGINT NOT NULL REFERENCES inbound_deliveries(id),
+			applied_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+			PRIMARY KEY (provider, event_id)
+		);`,
+		`INSERT INTO permission (id, permission_name, permission_description) VALUES (10, 'integrations:manage', 'Inspect and replay provider callbacks') ON CONFLICT (id) DO NOTHING;`,
+		`INSERT INTO role_permissions (role_id, permission_id) VALUES (5, 10) ON CONFLICT DO NOTHING;`,
+		// Roles and permissions created through the API take IDs from 100 up,
+		// leaving the low range to the seeds above.
+		`CREATE SEQUENCE IF NOT EXISTS role_id_seq START 100 OWNED BY role.id;`,
+		`ALTER TABLE role ALTER COLUMN id SET DEFAULT nextval('role_id_seq');`,
+		`CREATE SEQUENCE IF NOT EXISTS permission_id_seq START 100 OWNED BY permission.id;`,
+		`ALTER TABLE permission ALTER COLUMN id SET DEFAULT nextval('permission_id_seq');`,
+		`INSERT INTO permission (id, permission_name, permission_description) VALUES (11, 'roles:manage', 'Manage roles and permissions') ON CONFLICT (id) DO NOTHING;`,
+		`INSERT INTO role_permissions (role_id, permission_id) VALUES (5, 11) ON CONFLICT DO NOTHING;`,
+		`CREATE TABLE IF NOT EXISTS user_permissions (
+			user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			permission_id BIGINT NOT NULL REFERENCES permission(id) ON DELETE CASCADE,
+			allow BOOLEAN NOT NULL,
+			set_by BIGINT NOT NULL REFERENCES users(id),
+			set_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+			PRIMARY KEY (user_id, permission_id)
+		);`,
+		`INSERT INTO permission (id, permission_name, permission_description) VALUES (12, 'users:permissions', 'Grant or revoke permissions for individual users') ON CONFLICT (id) DO NOTHING;`,
+		`INSERT INTO role_permissions (role_id, permission_id) VALUES (4, 12), (5, 12) ON CONFLICT DO NOTHING;`,
+		`CREATE TABLE IF NOT EXISTS wallet_transactions (
+			id BIGSERIAL PRIMARY KEY,
+			user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			amount NUMERIC(24,2) NOT NULL,
+			balance_after NUMERIC(24,2) NOT NULL,
+			reason TEXT NOT NULL,
+			reference TEXT NOT NULL DEFAULT '',
+			created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+		);`,
+		`CREATE INDEX IF NOT EXISTS wallet_transactions_user_idx ON wallet_transactions (user_id, id DESC);`,
+		`CREATE TABLE IF NOT EXISTS operations (
+			kind TEXT NOT NULL,
+			key TEXT NOT NULL,
+			user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			amount NUMERIC(24,2) NOT NULL,
+			transaction_id BIGINT REFERENCES wallet_transactions(id),
+			created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+			PRIMARY KEY (kind, key)
+		);`,
+		`CREATE TABLE IF NOT EXISTS login_history (
+			id BIGSERIAL PRIMARY KEY,
+			user_id BIGINT REFERENCES users(id) ON DELETE CASCADE,
+			identifier TEXT NOT NULL,
+			success BOOLEAN NOT NULL,
+			failure_reason TEXT NOT NULL DEFAULT '',
+			ip TEXT NOT NULL,
+			user_agent TEXT NOT NULL,
+			country TEXT NOT NULL DEFAULT '',
+			created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+		);`,
+		`CREATE INDEX IF NOT EXISTS login_history_user_idx ON login_history (user_id, id DESC);`,
+		`CREATE INDEX IF NOT EXISTS login_history_ip_idx ON login_history (ip, id DESC);`,
+		`CREATE INDEX IF NOT EXISTS wallet_transactions_created_idx ON wallet_transactions (created_at);`,
+		`CREATE INDEX IF NOT EXISTS login_history_created_idx ON login_history (created_at);`,
+		`CREATE TABLE IF NOT EXISTS wallet_transactions_archive (
+			id BIGINT PRIMARY KEY,
+			user_id BIGINT NOT NULL,
+			amount NUMERIC(24,2) NOT NULL,
+			balance_after NUMERIC(24,2) NOT NULL,
+			reason TEXT NOT NULL,
+			reference TEXT NOT NULL,
+			created_at TIMESTAMPTZ NOT NULL,
+			archived_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+		);`,
+		`CREATE INDEX IF NOT EXISTS wallet_transactions_archive_user_idx ON wallet_transactions_archive (user_id, id DESC);`,
+		`CREATE TABLE IF NOT EXISTS login_history_archive (
+			id BIGINT PRIMARY KEY,
+			user_id BIGINT,
+			identifier TEXT NOT NULL,
+			success BOOLEAN NOT NULL,
+			failure_reason TEXT NOT NULL,
+			ip TEXT NOT NULL,
+			user_agent TEXT NOT NULL,
+			country TEXT NOT NULL,
+			created_at TIMESTAMPTZ NOT NULL,
+			archived_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+		);`,
+		`CREATE INDEX IF NOT EXISTS login_history_archive_user_idx ON login_history_archive (user_id, id DESC);`,
+		`CREATE INDEX IF NOT EXISTS login_history_archive_ip_idx ON login_history_archive (ip, id DESC);`,
+		// Operations outlive the ledger rows they point at once those are archived.
+		`ALTER TABLE operations DROP CONSTRAINT IF EXISTS operations_transaction_id_fkey;`,
+		`CREATE TABLE IF NOT EXISTS device_confirmations (
+			token_hash TEXT PRIMARY KEY,
+			user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			fingerprint TEXT NOT NULL,
+			country TEXT NOT NULL DEFAULT '',
+			expires_at TIMESTAMPTZ NOT NULL
+		);`,
+		`CREATE TABLE IF NOT EXISTS onboarding_journeys (
+			tenant TEXT PRIMARY KEY,
+			steps JSONB NOT NULL,
+			updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+		);`,
+		// The default journey sends the welcome email, which registration used to send directly.
+		`INSERT INTO onboarding_journeys (tenant, steps)
+		VALUES ('', '[{"key":"welcome","title":"Welcome","template":"welcome"}]')
+		ON CONFLICT (tenant) DO NOTHING;`,
+		`CREATE TABLE IF NOT EXISTS onboarding_progress (
+			user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			step_key TEXT NOT NULL,
+			nudged_at TIMESTAMPTZ,
+			completed_at TIMESTAMPTZ,
+			PRIMARY KEY (user_id, step_key)
+		);`,
+		`ALTER TABLE config_history ADD COLUMN IF NOT EXISTS country TEXT NOT NULL DEFAULT '';`,
+		`CREATE TABLE IF NOT EXISTS user_privacy (
+			user_id BIGINT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+			public_activity TEXT NOT NULL,
+			updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+		);`,
+		`CREATE INDEX IF NOT EXISTS wallet_transactions_settlement_idx ON wallet_transactions (id DESC) WHERE reason = 'bet_settlement';`,
+		`CREATE TABLE IF NOT EXISTS data_exports (
+			id BIGSERIAL PRIMARY KEY,
+			user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			status TEXT NOT NULL,
+			blob_key TEXT NOT NULL DEFAULT '',
+			created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+			completed_at TIMESTAMPTZ
+		);`,
+		`CREATE INDEX IF NOT EXISTS data_exports_user_idx ON data_exports (user_id, id DESC);`,
+		`INSERT INTO permission (id, permission_name, permission_description) VALUES (13, 'users:lock', 'Force password resets on compromised accounts') ON CONFLICT (id) DO NOTHING;`,
+		`INSERT INTO role_permissions (role_id, permission_id) VALUES (4, 13), (5, 13) ON CONFLICT DO NOTHING;`,
+		`CREATE TABLE IF NOT EXISTS reconciliation_reports (
+			id BIGSERIAL PRIMARY KEY,
+			users_checked INTEGER NOT NULL,
+			mismatches INTEGER NOT NULL,
+			discrepancies JSONB NOT NULL,
+			created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+		);`,
+		// One row per player; windows are relative to the last refresh.
+		`CREATE MATERIALIZED VIEW IF NOT EXISTS leaderboard_stats AS
+		WITH settlements AS (
+			SELECT user_id, amount, created_at FROM wallet_transactions WHERE reason = 'bet_settlement'
+			UNION ALL
+			SELECT user_id, amount, created_at FROM wallet_transactions_archive WHERE reason = 'bet_settlement'
+		)
+		SELECT u.id AS user_id, u.username, u.balance,
+			COALESCE(SUM(s.amount) FILTER (WHERE s.amount > 0 AND s.created_at >= NOW() - INTERVAL '1 day'), 0) AS winnings_daily,
+			COALESCE(SUM(s.amount) FILTER (WHERE s.amount > 0 AND s.created_at >= NOW() - INTERVAL '7 days'), 0) AS winnings_weekly,
+			COALESCE(SUM(s.amount) FILTER (WHERE s.amount > 0), 0) AS winnings_all_time,
+			COUNT(s.user_id) FILTER (WHERE s.created_at >= NOW() - INTERVAL '1 day') AS games_daily,
+			COUNT(s.user_id) FILTER (WHERE s.created_at >= NOW() - INTERVAL '7 days') AS games_weekly,
+			COUNT(s.user_id) AS games_all_time
+		FROM users u
+		LEFT JOIN settlements s ON s.user_id = u.id
+		WHERE u.role IN ('player', 'vip-player', 'vvip-player')
+		GROUP BY u.id;`,
+		// REFRESH ... CONCURRENTLY needs a unique index.
+		`CREATE UNIQUE INDEX IF NOT EXISTS leaderboard_stats_user_idx ON leaderboard_stats (user_id);`,
+		`CREATE TABLE IF NOT EXISTS oauth_clients (
+			id TEXT PRIMARY KEY,
+			name TEXT NOT NULL,
+			redirect_uris TEXT[] NOT NULL,
+			confidential BOOLEAN NOT NULL,
+			secret_hash TEXT NOT NULL DEFAULT '',
+			created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+		);`,
+		`CREATE TABLE IF NOT EXISTS oauth_codes (
+			code_hash TEXT PRIMARY KEY,
+			client_id TEXT NOT NULL REFERENCES oauth_clients(id) ON DELETE CASCADE,
+			user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			redirect_uri TEXT NOT NULL,
+			scope TEXT NOT NULL,
+			nonce TEXT NOT NULL DEFAULT '',
+			code_challenge TEXT NOT NULL,
+			expires_at TIMESTAMPTZ NOT NULL
+		);`,
+		`CREATE TABLE IF NOT EXISTS ip_risk_policies (
+			tenant TEXT NOT NULL DEFAULT '',
+			country TEXT NOT NULL DEFAULT '',
+			action TEXT NOT NULL,
+			updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+			PRIMARY KEY (tenant, country)
+		);`,
+		`CREATE TABLE IF NOT EXISTS ip_risk_events (
+			id BIGSERIAL PRIMARY KEY,
+			user_id BIGINT REFERENCES users(id) ON DELETE SET NULL,
+			ip TEXT NOT NULL,
+			country TEXT NOT NULL DEFAULT '',
+			checkpoint TEXT NOT NULL,
+			signals TEXT[] NOT NULL,
+			action TEXT NOT NULL,
+			created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+		);`,
+		`CREATE INDEX IF NOT EXISTS ip_risk_events_created_idx ON ip_risk_events (created_at DESC);`,
+		`CREATE TABLE IF NOT EXISTS promo_codes (
+			id BIGSERIAL PRIMARY KEY,
+			code TEXT UNIQUE NOT NULL,
+			campaign TEXT NOT NULL,
+			amount NUMERIC(24,2) NOT NULL CHECK (amount > 0),
+			max_redemptions INT NOT NULL DEFAULT 0,
+			per_user_limit INT NOT NULL DEFAULT 1,
+			roles TEXT[] NOT NULL DEFAULT '{}',
+			expires_at TIMESTAMPTZ,
+			active BOOLEAN NOT NULL DEFAULT TRUE,
+			redemptions INT NOT NULL DEFAULT 0,
+			created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+		);`,
+		`CREATE INDEX IF NOT EXISTS promo_codes_campaign_idx ON promo_codes (campaign);`,
+		// Like operations, redemptions outlive the ledger rows they
+		// point at once those are archived.
+		`CREATE TABLE IF NOT EXISTS promo_redemptions (
+			id BIGSERIAL PRIMARY KEY,
+			code_id BIGINT NOT NULL REFERENCES promo_codes(id),
+			user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			amount NUMERIC(24,2) NOT NULL,
+			transaction_id BIGINT NOT NULL,
+			created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+		);`,
+		`CREATE INDEX IF NOT EXISTS promo_redemptions_code_user_idx ON promo_redemptions (code_id, user_id);`,
+		`CREATE INDEX IF NOT EXISTS login_alerts_user_idx ON login_alerts (user_id, id DESC);`,
+		`CREATE TABLE IF NOT EXISTS challenges (
+			id BIGSERIAL PRIMARY KEY,
+			user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			action TEXT NOT NULL,
+			method TEXT NOT NULL,
+			code_hash TEXT NOT NULL DEFAULT '',
+			attempts INT NOT NULL DEFAULT 0,
+			token_hash TEXT,
+			expires_at TIMESTAMPTZ NOT NULL,
+			passed_at TIMESTAMPTZ,
+			used_at TIMESTAMPTZ,
+			created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+		);`,
+		`CREATE UNIQUE INDEX IF NOT EXISTS challenges_token_idx ON challenges (token_hash) WHERE token_hash IS NOT NULL;`,
+		`CREATE TABLE IF NOT EXISTS game_sessions (
+			id BIGSERIAL PRIMARY KEY,
+			user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			provider TEXT NOT NULL,
+			game_id TEXT NOT NULL,
+			token_hash TEXT NOT NULL UNIQUE,
+			expires_at TIMESTAMPTZ NOT NULL,
+			started_at TIMESTAMPTZ,
+			created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+		);`,
+		`CREATE TABLE IF NOT EXISTS database_insights_reports (
+			id BIGSERIAL PRIMARY KEY,
+			statements_available BOOLEAN NOT NULL,
+			slow_queries JSONB NOT NULL,
+			seq_scan_tables JSONB NOT NULL,
+			suggestions JSONB NOT NULL,
+			created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+		);`,
+		`INSERT INTO permission (id, permission_name, permission_description) VALUES (14, 'legal:hold', 'Place and release legal holds on user data') ON CONFLICT (id) DO NOTHING;`,
+		`INSERT INTO role_permissions (role_id, permission_id) VALUES (5, 14) ON CONFLICT DO NOTHING;`,
+		`CREATE TABLE IF NOT EXISTS legal_holds (
+			id BIGSERIAL PRIMARY KEY,
+			user_id BIGINT NOT NULL REFERENCES users(id),
+			dataset TEXT NOT NULL DEFAULT '',
+			reason TEXT NOT NULL,
+			placed_by BIGINT NOT NULL REFERENCES users(id),
+			placed_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+			released_by BIGINT REFERENCES users(id),
+			released_at TIMESTAMPTZ
+		);`,
+		`CREATE INDEX IF NOT EXISTS legal_holds_active_idx ON legal_holds (user_id) WHERE released_at IS NULL;`,
+		`INSERT INTO permission (id, permission_name, permission_description) VALUES (15, 'balance:adjust', 'Credit or debit user balances manually') ON CONFLICT (id) DO NOTHING;`,
+		`INSERT INTO role_permissions (role_id, permission_id) VALUES (5, 15) ON CONFLICT DO NOTHING;`,
+		`CREATE TABLE IF NOT EXISTS feature_flags (
+			name TEXT PRIMARY KEY,
+			enabled BOOLEAN NOT NULL,
+			updated_by BIGINT NOT NULL REFERENCES users(id),
+			updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+		);`,
+		`CREATE TABLE IF NOT EXISTS bulk_jobs (
+			id BIGSERIAL PRIMARY KEY,
+			kind TEXT NOT NULL,
+			status TEXT NOT NULL,
+			checkpoint JSONB,
+			processed BIGINT NOT NULL DEFAULT 0,
+			batches BIGINT NOT NULL DEFAULT 0,
+			error TEXT NOT NULL DEFAULT '',
+			owner TEXT NOT NULL DEFAULT '',
+			created_by BIGINT NOT NULL REFERENCES users(id),
+			created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+			updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+			finished_at TIMESTAMPTZ
+		);`,
+		`CREATE INDEX IF NOT EXISTS bulk_jobs_running_idx ON bulk_jobs (updated_at) WHERE status = 'running';`,
+		`CREATE TABLE IF NOT EXISTS api_keys (
+			id BIGSERIAL PRIMARY KEY,
+			name TEXT NOT NULL,
+			prefix TEXT NOT NULL,
+			key_hash TEXT NOT NULL UNIQUE,
+			scopes TEXT[] NOT NULL,
+			expires_at TIMESTAMPTZ NOT NULL,
+			created_by BIGINT NOT NULL REFERENCES users(id),
+			created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+			revoked_by BIGINT REFERENCES users(id),
+			revoked_at TIMESTAMPTZ
+		);`,
+		`CREATE TABLE IF NOT EXISTS withdrawal_requests (
+			id BIGSERIAL PRIMARY KEY,
+			user_id BIGINT NOT NULL REFERENCES users(id),
+			provider TEXT NOT NULL,
+			reference TEXT NOT NULL,
+			amount NUMERIC(24,2) NOT NULL,
+			status TEXT NOT NULL,
+			hold_transaction_id BIGINT NOT NULL,
+			reviewed_by BIGINT REFERENCES users(id),
+			note TEXT NOT NULL DEFAULT '',
+			created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+			updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+			UNIQUE (provider, reference)
+		);`,
+		`CREATE INDEX IF NOT EXISTS withdrawal_requests_user_idx ON withdrawal_requests (user_id, id DESC);`,
+		`CREATE INDEX IF NOT EXISTS withdrawal_requests_pending_idx ON withdrawal_requests (id) WHERE status = 'pending';`,
+		`INSERT INTO permission (id, permission_name, permission_description) VALUES (16, 'withdrawals:review', 'Approve or reject withdrawal requests') ON CONFLICT (id) DO NOTHING;`,
+		`INSERT INTO role_permissions (role_id, permission_id) VALUES (5, 16) ON CONFLICT DO NOTHING;`,
+		`CREATE TABLE IF NOT EXISTS gaming_limits (
+			user_id BIGINT PRIMARY KEY REFERENCES users(id),
+			daily_deposit NUMERIC(24,2),
+			daily_loss NUMERIC(24,2),
+			session_minutes INT,
+			pending_daily_deposit NUMERIC(24,2),
+			pending_daily_loss NUMERIC(24,2),
+			pending_session_minutes INT,
+			pending_from TIMESTAMPTZ,
+			excluded_until TIMESTAMPTZ,
+			updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+		);`,
+		`CREATE TABLE IF NOT EXISTS operator_reports (
+			id BIGSERIAL PRIMARY KEY,
+			period TEXT NOT NULL,
+			period_start TIMESTAMPTZ NOT NULL,
+			period_end TIMESTAMPTZ NOT NULL,
+			status TEXT NOT NULL,
+			metrics JSONB,
+			requested_by BIGINT REFERENCES users(id),
+			error TEXT NOT NULL DEFAULT '',
+			created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+			completed_at TIMESTAMPTZ
+		);`,
+		`CREATE UNIQUE INDEX IF NOT EXISTS operator_reports_scheduled_idx ON operator_reports (period, period_start) WHERE requested_by IS NULL;`,
+		`CREATE TABLE IF NOT EXISTS event_outbox (
+			id BIGSERIAL PRIMARY KEY,
+			type TEXT NOT NULL,
+			data JSONB NOT NULL,
+			created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+			sent_at TIMESTAMPTZ
+		);`,
+		`CREATE INDEX IF NOT EXISTS event_outbox_pending_idx ON event_outbox (id) WHERE sent_at IS NULL;`,
+		`CREATE INDEX IF NOT EXISTS event_outbox_sent_idx ON event_outbox (sent_at) WHERE sent_at IS NOT NULL;`,
+		`ALTER TABLE users ADD COLUMN IF NOT EXISTS version BIGINT NOT NULL DEFAULT 1;`,
+		`INSERT INTO permission (id, permission_name, permission_description) VALUES (17, 'users:write', 'Edit user profiles') ON CONFLICT (id) DO NOTHING;`,
+		`INSERT INTO role_permissions (role_id, permission_id) VALUES (5, 17) ON CONFLICT DO NOTHING;`,
+		`CREATE TABLE IF NOT EXISTS impersonations (
+			id BIGSERIAL PRIMARY KEY,
+			user_id BIGINT NOT NULL REFERENCES users(id),
+			actor_id BIGINT NOT NULL REFERENCES users(id),
+			reason TEXT NOT NULL,
+			token_id TEXT NOT NULL UNIQUE,
+			expires_at TIMESTAMPTZ NOT NULL,
+			created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+			revoked_by BIGINT REFERENCES users(id),
+			revoked_at TIMESTAMPTZ
+		);`,
+		`CREATE INDEX IF NOT EXISTS impersonations_user_idx ON impersonations (user_id, id DESC);`,
+		`INSERT INTO permission (id, permission_name, permission_description) VALUES (18, 'users:impersonate', 'Act as a player to reproduce their issues') ON CONFLICT (id) DO NOTHING;`,
+		`INSERT INTO role_permissions (role_id, permission_id) VALUES (4, 18), (5, 18) ON CONFLICT DO NOTHING;`,
+		`ALTER TABLE users ADD COLUMN IF NOT EXISTS merged_into BIGINT REFERENCES users(id);`,
+		`ALTER TABLE wallet_transactions ADD COLUMN IF NOT EXISTS merged_from BIGINT;`,
+		`ALTER TABLE wallet_transactions_archive ADD COLUMN IF NOT EXISTS merged_from BIGINT;`,
+		`CREATE TABLE IF NOT EXISTS account_merges (
+			id BIGSERIAL PRIMARY KEY,
+			user_id BIGINT NOT NULL REFERENCES users(id),
+			duplicate_id BIGINT NOT NULL UNIQUE REFERENCES users(id),
+			merged_by BIGINT NOT NULL REFERENCES users(id),
+			balance NUMERIC(24,2) NOT NULL,
+			transactions INTEGER NOT NULL,
+			operations INTEGER NOT NULL,
+			login_attempts INTEGER NOT NULL,
+			devices INTEGER NOT NULL,
+			created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+		);`,
+		`INSERT INTO permission (id, permission_name, permission_description) VALUES (19, 'users:merge', 'Merge duplicate player accounts') ON CONFLICT (id) DO NOTHING;`,
+		`INSERT INTO role_permissions (role_id, permission_id) VALUES (5, 19) ON CONFLICT DO NOTHING;`,
+		`CREATE TABLE IF NOT EXISTS reserved_usernames (
+			name TEXT PRIMARY KEY,
+			kind TEXT NOT NULL,
+			created_by BIGINT NOT NULL REFERENCES users(id),
+			created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+		);`,
+		`ALTER TABLE users ADD COLUMN IF NOT EXISTS held_balance NUMERIC(24,2) NOT NULL DEFAULT 0;`,
+		`CREATE TABLE IF NOT EXISTS wallet_holds (
+			id BIGSERIAL PRIMARY KEY,
+			user_id BIGINT NOT NULL REFERENCES users(id),
+			amount NUMERIC(24,2) NOT NULL CHECK (amount > 0),
+			reference TEXT NOT NULL,
+			status TEXT NOT NULL,
+			transaction_id BIGINT,
+			created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+			settled_at TIMESTAMPTZ,
+			UNIQUE (user_id, reference)
+		);`,
+		`CREATE INDEX IF NOT EXISTS wallet_holds_active_idx ON wallet_holds (user_id) WHERE status = 'active';`,
+		`CREATE TABLE IF NOT EXISTS ledger_accounts (
+			id BIGSERIAL PRIMARY KEY,
+			code TEXT NOT NULL UNIQUE,
+			kind TEXT NOT NULL CHECK (kind IN ('wallet', 'house', 'bonus_pool', 'gateway', 'clearing', 'equity')),
+			user_id BIGINT UNIQUE REFERENCES users(id),
+			created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+			CHECK ((kind = 'wallet') = (user_id IS NOT NULL))
+		);`,
+		`CREATE TABLE IF NOT EXISTS journal_entries (
+			id BIGSERIAL PRIMARY KEY,
+			transaction_id BIGINT,
+			reason TEXT NOT NULL,
+			reference TEXT NOT NULL DEFAULT '',
+			created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+		);`,
+		`CREATE TABLE IF NOT EXISTS journal_lines (
+			id BIGSERIAL PRIMARY KEY,
+			entry_id BIGINT NOT NULL REFERENCES journal_entries(id),
+			account_id BIGINT NOT NULL REFERENCES ledger_accounts(id),
+			debit NUMERIC(24,2) NOT NULL DEFAULT 0 CHECK (debit >= 0),
+			credit NUMERIC(24,2) NOT NULL DEFAULT 0 CHECK (credit >= 0),
+			CHECK ((debit = 0) <> (credit = 0))
+		);`,
+		`CREATE INDEX IF NOT EXISTS journal_lines_entry_idx ON journal_lines (entry_id);`,
+		`CREATE INDEX IF NOT EXISTS journal_lines_account_idx ON journal_lines (account_id);`,
+		// Each entry must balance once all its lines are in, so the check is
+		// a constraint trigger deferred to commit.
+		`CREATE OR REPLACE FUNCTION check_journal_balanced() RETURNS trigger AS $$
+		BEGIN
+			IF (SELECT SUM(debit) - SUM(credit) FROM journal_lines WHERE entry_id = NEW.entry_id) <> 0 THEN
+				RAISE EXCEPTION 'journal entry % does not balance', NEW.entry_id USING ERRCODE = 'check_violation';
+			END IF;
+			RETURN NULL;
+		END;
+		$$ LANGUAGE plpgsql;`,
+		`DO $$
+		BEGIN
+			IF NOT EXISTS (SELECT 1 FROM pg_trigger WHERE tgname = 'journal_lines_balanced') THEN
+				CREATE CONSTRAINT TRIGGER journal_lines_balanced AFTER INSERT OR UPDATE ON journal_lines
+				DEFERRABLE INITIALLY DEFERRED FOR EACH ROW EXECUTE FUNCTION check_journal_balanced();
+			END IF;
+		END;
+		$$;`,
+		`INSERT INTO ledger_accounts (code, kind) VALUES ('equity', 'equity') ON CONFLICT DO NOTHING;`,
+		// Open a wallet account for every user without one, posting the
+		// balance they hold against equity.
+		`WITH opened AS (
+			INSERT INTO ledger_accounts (code, kind, user_id)
+			SELECT 'wallet:' || id, 'wallet', id FROM users
+			ON CONFLICT DO NOTHING
+			RETURNING id, user_id
+		), funded AS (
+			SELECT o.id AS account_id, u.id AS user_id, u.balance
+			FROM opened o JOIN users u ON u.id = o.user_id
+			WHERE u.balance > 0
+		), entries AS (
+			INSERT INTO journal_entries (reason, reference)
+			SELECT 'opening_balance', 'user:' || user_id FROM funded
+			RETURNING id, reference
+		)
+		INSERT INTO journal_lines (entry_id, account_id, debit, credit)
+		SELECT e.id, f.account_id, 0, f.balance
+		FROM entries e JOIN funded f ON e.reference = 'user:' || f.user_id
+		UNION ALL
+		SELECT e.id, (SELECT id FROM ledger_accounts WHERE code = 'equity'), f.balance, 0
+		FROM entries e JOIN funded f ON e.reference = 'user:' || f.user_id;`,
+		`ALTER TABLE ledger_accounts DROP CONSTRAINT IF EXISTS ledger_accounts_kind_check;`,
+		`ALTER TABLE ledger_accounts ADD CONSTRAINT ledger_accounts_kind_check
+			CHECK (kind IN ('wallet', 'house', 'bonus_pool', 'gateway', 'clearing', 'equity', 'jackpot'));`,
+		`CREATE TABLE IF NOT EXISTS jackpots (
+			name TEXT PRIMARY KEY,
+			pot NUMERIC(24,2) NOT NULL DEFAULT 0 CHECK (pot >= 0),
+			updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+		);`,
+		`CREATE TABLE IF NOT EXISTS tournaments (
+			id BIGSERIAL PRIMARY KEY,
+			name TEXT NOT NULL,
+			entry_fee NUMERIC(24,2) NOT NULL CHECK (entry_fee > 0),
+			starts_at TIMESTAMPTZ NOT NULL,
+			ends_at TIMESTAMPTZ NOT NULL,
+			prize_shares DOUBLE PRECISION[] NOT NULL,
+			prize_pool NUMERIC(24,2) NOT NULL DEFAULT 0,
+			entrants INTEGER NOT NULL DEFAULT 0,
+			status TEXT NOT NULL,
+			created_by BIGINT NOT NULL REFERENCES users(id),
+			created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+			closed_at TIMESTAMPTZ,
+			CHECK (ends_at > starts_at)
+		);`,
+		`CREATE INDEX IF NOT EXISTS tournaments_open_idx ON tournaments (ends_at) WHERE status = 'open';`,
+		`CREATE TABLE IF NOT EXISTS tournament_entries (
+			tournament_id BIGINT NOT NULL REFERENCES tournaments(id),
+			user_id BIGINT NOT NULL REFERENCES users(id),
+			score DOUBLE PRECISION NOT NULL DEFAULT 0,
+			scored_at TIMESTAMPTZ,
+			rank INTEGER NOT NULL DEFAULT 0,
+			prize NUMERIC(24,2) NOT NULL DEFAULT 0,
+			entered_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+			PRIMARY KEY (tournament_id, user_id)
+		);`,
+		`CREATE TABLE IF NOT EXISTS user_messages (
+			id BIGSERIAL PRIMARY KEY,
+			user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			kind TEXT NOT NULL,
+			subject TEXT NOT NULL,
+			body TEXT NOT NULL,
+			sender_id BIGINT NOT NULL REFERENCES users(id),
+			read_at TIMESTAMPTZ,
+			created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+		);`,
+		`CREATE INDEX IF NOT EXISTS user_messages_user_idx ON user_messages (user_id, id);`,
+		`CREATE INDEX IF NOT EXISTS user_messages_unread_idx ON user_messages (user_id) WHERE read_at IS NULL;`,
+		`INSERT INTO permission (id, permission_name, permission_description) VALUES (20, 'messages:send', 'Send inbox messages and announcements to players') ON CONFLICT (id) DO NOTHING;`,
+		`INSERT INTO role_permissions (role_id, permission_id) VALUES (4, 20), (5, 20) ON CONFLICT DO NOTHING;`,
+		`CREATE TABLE IF NOT EXISTS support_tickets (
+			id BIGSERIAL PRIMARY KEY,
+			user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			subject TEXT NOT NULL,
+			status TEXT NOT NULL CHECK (status IN ('open', 'pending', 'closed')),
+			priority TEXT NOT NULL CHECK (priority IN ('normal', 'high')),
+			created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+			updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+			closed_at TIMESTAMPTZ
+		);`,
+		`CREATE INDEX IF NOT EXISTS support_tickets_user_idx ON support_tickets (user_id, updated_at DESC);`,
+		`CREATE INDEX IF NOT EXISTS support_tickets_queue_idx ON support_tickets (status, updated_at);`,
+		`CREATE TABLE IF NOT EXISTS ticket_replies (
+			id BIGSERIAL PRIMARY KEY,
+			ticket_id BIGINT NOT NULL REFERENCES support_tickets(id) ON DELETE CASCADE,
+			author_id BIGINT NOT NULL REFERENCES users(id),
+			staff BOOLEAN NOT NULL DEFAULT FALSE,
+			body TEXT NOT NULL,
+			created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+		);`,
+		`CREATE INDEX IF NOT EXISTS ticket_replies_ticket_idx ON ticket_replies (ticket_id, id);`,
+		`CREATE TABLE IF NOT EXISTS ticket_attachments (
+			id BIGSERIAL PRIMARY KEY,
+			ticket_id BIGINT NOT NULL REFERENCES support_tickets(id) ON DELETE CASCADE,
+			uploader_id BIGINT NOT NULL REFERENCES users(id),
+			name TEXT NOT NULL,
+			content_type TEXT NOT NULL,
+			size BIGINT NOT NULL,
+			blob_key TEXT NOT NULL UNIQUE,
+			created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+		);`,
+		`CREATE INDEX IF NOT EXISTS ticket_attachments_ticket_idx ON ticket_attachments (ticket_id, id);`,
+		`INSERT INTO permission (id, permission_name, permission_description) VALUES (21, 'support:manage', 'Work the support ticket queue') ON CONFLICT (id) DO NOTHING;`,
+		`INSERT INTO role_permissions (role_id, permission_id) VALUES (4, 21), (5, 21) ON CONFLICT DO NOTHING;`,
+		`INSERT INTO permission (id, permission_name, permission_description) VALUES (22, 'tournaments:score', 'Report tournament scores') ON CONFLICT (id) DO NOTHING;`,
+		`INSERT INTO role_permissions (role_id, permission_id) VALUES (5, 22) ON CONFLICT DO NOTHING;`,
+		`ALTER TABLE users ADD COLUMN IF NOT EXISTS permissions_version BIGINT NOT NULL DEFAULT 0;`,
 	}
 	for _, stmt := range stmts {
-		if _, err := s.pool.Exec(ctx, stmt); err != nil {
+		if _, err := s.db.Exec(ctx, stmt); err != nil {
 			return fmt.Errorf("apply migrations: %w", err)
 		}
 	}
+	if err := s.backfillPhoneNumbers(ctx, phoneRegion); err != nil {
+		return fmt.Errorf("apply migrations: %w", err)
+	}
+	if _, err := s.db.Exec(ctx, `CREATE UNIQUE INDEX IF NOT EXISTS users_phone_unique_idx ON users (phone);`); err != nil {
+		return fmt.Errorf("apply migrations: %w", err)
+	}
+	if err := s.indexUsernamesIgnoringCase(ctx); err != nil {
+		return fmt.Errorf("apply migrations: %w", err)
+	}
+	return nil
+}
+
+// indexUsernamesIgnoringCase makes usernames unique regardless of case. While
+// usernames registered before differ only in case, the index is not created
+// and they are logged for manual cleanup; uniqueness stays case-sensitive
+// until then.
+func (s *Store) indexUsernamesIgnoringCase(ctx context.Context) error {
+	rows, err := s.db.Query(ctx, `SELECT lower(username), array_agg(id ORDER BY id) FROM users GROUP BY lower(username) HAVING COUNT(*) > 1;`)
+	if err != nil {
+		return fmt.Errorf("select usernames differing in case: %w", err)
+	}
+	clashes := 0
+	for rows.Next() {
+		var name string
+		var ids []int64
+		if err := rows.Scan(&name, &ids); err != nil {
+			rows.Close()
+			return err
+		}
+		log.Printf("username index: users %v are all named %q ignoring case", ids, name)
+		clashes++
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if clashes > 0 {
+		log.Printf("username index: not created until the %d usernames above are renamed", clashes)
+		return nil
+	}
+	_, err = s.db.Exec(ctx, `CREATE UNIQUE INDEX IF NOT EXISTS users_username_lower_idx ON users (lower(username));`)
+	return err
+}
+
+// backfillPhoneNumbers rewrites phone numbers stored before E.164
+// normalization, reading numbers without a country code as numbers of region.
+// It runs before users_phone_unique_idx is built: when several users' numbers
+// normalize to the same one, nothing is rewritten and the migration fails
+// with a report naming them, since they are one subscriber's accounts that
+// must be merged or corrected by hand. Numbers that cannot be parsed are left
+// as-is and logged.
+func (s *Store) backfillPhoneNumbers(ctx context.Context, region string) error {
+	rows, err := s.db.Query(ctx, `SELECT id, phone FROM users ORDER BY id;`)
+	if err != nil {
+		return fmt.Errorf("select phone numbers: %w", err)
+	}
+	var stored []storedPhone
+	for rows.Next() {
+		var sp storedPhone
+		if err := rows.Scan(&sp.id, &sp.phone); err != nil {
+			rows.Close()
+			return err
+		}
+		stored = append(stored, sp)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	rewrites, err := planPhoneBackfill(stored, region)
+	if err != nil {
+		return err
+	}
+	for _, sp := range rewrites {
+		if _, err := s.db.Exec(ctx, `UPDATE users SET phone = $2 WHERE id = $1;`, sp.id, sp.phone); err != nil {
+			return fmt.Errorf("backfill phone for user %d: %w", sp.id, err)
+		}
+	}
 	return nil
 }
 
+// storedPhone is a user's phone number as stored.
+type storedPhone struct {
+	id    int64
+	phone string
+}
+
+// e164 matches numbers already in E.164 form.
+var e164 = regexp.MustCompile(`^\+[1-9][0-9]{1,14}$`)
+
+// planPhoneBackfill returns the numbers among stored to rewrite in E.164
+// form, or an error naming the users whose numbers are the same once
+// normalized. Numbers that cannot be normalized are kept as stored, so two
+// identical ones are reported too.
+func planPhoneBackfill(stored []storedPhone, region string) ([]storedPhone, error) {
+	var rewrites []storedPhone
+	owners := make(map[string][]int64, len(stored))
+	for _, sp := range stored {
+		normalized := sp.phone
+		if !e164.MatchString(sp.phone) {
+			n, err := phone.Normalize(sp.phone, region)
+			if err != nil {
+				log.Printf("phone backfill: user %d has unparseable phone %q: %v", sp.id, sp.phone, err)
+			} else {
+				normalized = n
+				rewrites = append(rewrites, storedPhone{id: sp.id, phone: n})
+			}
+		}
+		owners[normalized] = append(owners[normalized], sp.id)
+	}
+	var clashes []string
+	for number, ids := range owners {
+		if len(ids) > 1 {
+			slices.Sort(ids)
+			clashes = append(clashes, fmt.Sprintf("users %v share %s", ids, number))
+		}
+	}
+	if len(clashes) > 0 {
+		slices.Sort(clashes)
+		return nil, fmt.Errorf("phone backfill: %d numbers belong to several users; merge or correct them before users_phone_unique_idx can be built: %s", len(clashes), strings.Join(clashes, "; "))
+	}
+	return rewrites, nil
+}
+
 // CreateUser inserts a new user row.
 func (s *Store) CreateUser(ctx context.Context, user models.User) (models.User, error) {
 	const query = `
 		WITH inserted AS (
-			INSERT INTO users (username, email, phone, role, balance, password_hash)
-			VALUES ($1, $2, $3, $4, $5, $6)
-			RETURNING id, username, email, phone, role, balance, password_hash, created_at
+			INSERT INTO users (username, email, phone, role, balance, password_hash, home_region)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)
+			RETURNING id, username, email, phone, role, balance, password_hash, home_region, password_reset_required, sessions_revoked_at, merged_into, version, permissions_version, created_at
 		)
-		SELECT i.id, i.username, i.email, i.phone, i.role, i.balance, i.password_hash, i.created_at, r.role_name,
+		SELECT i.id, i.username, i.email, i.phone, i.role, i.balance, i.password_hash, i.home_region, i.password_reset_required, i.sessions_revoked_at, i.merged_into, i.version, i.permissions_version, i.created_at, r.role_name,
 		(
 			SELECT COALESCE(array_agg(p.permission_name), '{}')
 			FROM role_permissions rp
@@ -100,7 +974,7 @@ func (s *Store) CreateUser(ctx context.Context, user models.User) (models.User,
 		FROM inserted i
 		JOIN role r ON i.role = r.role_name;
 		`
-	row := s.pool.QueryRow(ctx, query, user.Username, user.Email, user.Phone, user.Role, user.Balance, user.PasswordHash)
+	row := s.db.QueryRow(ctx, query, user.Username, user.Email, user.Phone, user.Role, user.Balance, user.PasswordHash, user.HomeRegion)
 	created, err := scanUser(row)
 	if err != nil {
 		var pgErr *pgconn.PgError
@@ -112,65 +986,106 @@ func (s *Store) CreateUser(ctx context.Context, user models.User) (models.User,
 	return created, nil
 }
 
-// FindByUsername fetches a user by username.
-func (s *Store) FindByUsername(ctx context.Context, username string) (models.User, error) {
-	const query = `
-	SELECT u.id, u.username, u.email, u.phone, u.role, u.balance, u.password_hash, u.created_at, r.role_name,
-	(
-		SELECT COALESCE(array_agg(p.permission_name), '{}')
-		FROM role_permissions rp
-		JOIN permission p ON rp.permission_id = p.id
-		WHERE rp.role_id = r.id
-	)
+// userColumns are the columns scanUser reads, from users u joined to role r.
+// Permissions are the role's grants plus the user's allow overrides, minus
+// their deny overrides.
+const userColumns = `
+	u.id, u.username, u.email, u.phone, u.role, u.balance, u.password_hash, u.home_region, u.password_reset_required, u.sessions_revoked_at, u.merged_into, u.version, u.permissions_version, u.created_at, r.role_name,
+	ARRAY(
+		SELECT p.permission_name
+		FROM permission p
+		LEFT JOIN user_permissions up ON up.user_id = u.id AND up.permission_id = p.id
+		WHERE COALESCE(up.allow, EXISTS (
+			SELECT 1 FROM role_permissions rp WHERE rp.role_id = r.id AND rp.permission_id = p.id
+		))
+		ORDER BY p.id
+	)`
+
+// userSelect loads users with their effective permissions.
+const userSelect = `SELECT ` + userColumns + `
 	FROM users u
 	JOIN role r ON u.role = r.role_name
-	WHERE u.username = $1;
 	`
-	row := s.pool.QueryRow(ctx, query, username)
-	return scanUser(row)
+
+// Names of the user lookups that run on every login and authenticated request.
+const (
+	stmtUserByID              = "find_user_by_id"
+	stmtUserByUsername        = "find_user_by_username"
+	stmtUserByEmail           = "find_user_by_email"
+	stmtUserByUsernameOrEmail = "find_user_by_username_or_email"
+)
+
+// userLookups are prepared by name on each new connection when the exec mode
+// keeps prepared statements, saving a parse and plan round trip per call.
+var userLookups = map[string]string{
+	stmtUserByID:              userSelect + `WHERE u.id = $1;`,
+	stmtUserByUsername:        userSelect + `WHERE u.username = $1;`,
+	stmtUserByEmail:           userSelect + `WHERE u.email = $1;`,
+	stmtUserByUsernameOrEmail: userSelect + `WHERE u.username = $1 OR u.email = $1 LIMIT 1;`,
+}
+
+// userLookup returns the prepared statement name for the lookup, or its SQL
+// when connections do not keep prepared statements.
+func (s *Store) userLookup(name string) string {
+	if s.prepared {
+		return name
+	}
+	return userLookups[name]
+}
+
+// FindByID fetches a user by primary key.
+func (s *Store) FindByID(ctx context.Context, id int64) (models.User, error) {
+	return scanUser(s.reader().QueryRow(ctx, s.userLookup(stmtUserByID), id))
+}
+
+// FindByUsername fetches a user by username.
+func (s *Store) FindByUsername(ctx context.Context, username string) (models.User, error) {
+	return scanUser(s.reader().QueryRow(ctx, s.userLookup(stmtUserByUsername), username))
 }
 
 // FindByEmail fetches a user by email address.
 func (s *Store) FindByEmail(ctx context.Context, email string) (models.User, error) {
-	const query = `
-	SELECT u.id, u.username, u.email, u.phone, u.role, u.balance, u.password_hash, u.created_at, r.role_name,
-	(
-		SELECT COALESCE(array_agg(p.permission_name), '{}')
-		FROM role_permissions rp
-		JOIN permission p ON rp.permission_id = p.id
-		WHERE rp.role_id = r.id
-	)
-	FROM users u
-	JOIN role r ON u.role = r.role_name
-	WHERE u.email = $1;
-	`
-	row := s.pool.QueryRow(ctx, query, email)
-	return scanUser(row)
+	return scanUser(s.reader().QueryRow(ctx, s.userLookup(stmtUserByEmail), email))
 }
 
 // FindByUsernameOrEmail fetches the first user matching the identifier as username or email.
 func (s *Store) FindByUsernameOrEmail(ctx context.Context, identifier string) (models.User, error) {
+	return scanUser(s.reader().QueryRow(ctx, s.userLookup(stmtUserByUsernameOrEmail), identifier))
+}
+
+// UpdateProfile saves the user's username, email and phone if their version
+// still matches.
+func (s *Store) UpdateProfile(ctx context.Context, user models.User) (models.User, error) {
 	const query = `
-	SELECT u.id, u.username, u.email, u.phone, u.role, u.balance, u.password_hash, u.created_at, r.role_name,
-	(
-		SELECT COALESCE(array_agg(p.permission_name), '{}')
-		FROM role_permissions rp
-		JOIN permission p ON rp.permission_id = p.id
-		WHERE rp.role_id = r.id
+	WITH updated AS (
+		UPDATE users SET username = $3, email = $4, phone = $5, version = version + 1
+		WHERE id = $1 AND version = $2
+		RETURNING *
 	)
-	FROM users u
-	JOIN role r ON u.role = r.role_name
-	WHERE u.username = $1 OR u.email = $1
-	LIMIT 1;
+	SELECT ` + userColumns + `
+	FROM updated u
+	JOIN role r ON u.role = r.role_name;
 	`
-	row := s.pool.QueryRow(ctx, query, identifier)
-	return scanUser(row)
+	updated, err := scanUser(s.db.QueryRow(ctx, query, user.ID, user.Version, user.Username, user.Email, user.Phone))
+	if !errors.Is(err, storage.ErrNotFound) {
+		return updated, uniqueViolation(err)
+	}
+	var exists bool
+	if err := s.db.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM users WHERE id = $1);`, user.ID).Scan(&exists); err != nil {
+		return models.User{}, fmt.Errorf("update profile: %w", err)
+	}
+	if !exists {
+		return models.User{}, storage.ErrNotFound
+	}
+	return models.User{}, storage.ErrVersionConflict
 }
 
-func scanUser(row pgx.Row) (models.User, error) {
+// scanUser reads userColumns, followed by any extra columns into extra.
+func scanUser(row pgx.Row, extra ...any) (models.User, error) {
 	var user models.User
 	var roleName string
-	if err := row.Scan(&user.ID, &user.Username, &user.Email, &user.Phone, &user.Role, &user.Balance, &user.PasswordHash, &user.CreatedAt, &roleName, &user.Permissions); err != nil {
+	dest := []any{&user.ID, &user.Username, &user.Email, &user.Phone, &user.Role, &user.Balance, &user.PasswordHash, &user.HomeRegion, &user.PasswordResetRequired, &user.SessionsRevokedAt, &user.MergedInto, &user.Version, &user.PermissionsVersion, &user.CreatedAt, &roleName, &user.Permissions}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return models.User{}, storage.ErrNotFound
 		}
@@ -0,0 +1,201 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/hongminglow/all-in-be/internal/models"
+	"github.com/hongminglow/all-in-be/internal/storage"
+	"github.com/jackc/pgx/v5"
+)
+
+const ticketColumns = `t.id, t.user_id, u.username, t.subject, t.status, t.priority, t.created_at, t.updated_at, t.closed_at`
+
+const ticketReplyColumns = `r.id, r.ticket_id, r.author_id, u.username, r.staff, r.body, r.created_at`
+
+const ticketAttachmentColumns = `id, ticket_id, uploader_id, name, content_type, size, blob_key, created_at`
+
+// CreateTicket inserts the ticket and its first reply in one statement.
+func (s *Store) CreateTicket(ctx context.Context, t models.SupportTicket, body string) (models.SupportTicket, error) {
+	const query = `
+	WITH inserted AS (
+		INSERT INTO support_tickets (user_id, subject, status, priority)
+		VALUES ($1, $2, $3, $4)
+		RETURNING *
+	), first AS (
+		INSERT INTO ticket_replies (ticket_id, author_id, staff, body)
+		SELECT id, user_id, FALSE, $5 FROM inserted
+	)
+	SELECT ` + ticketColumns + ` FROM inserted t JOIN users u ON u.id = t.user_id;
+	`
+	created, err := scanTicket(s.db.QueryRow(ctx, query, t.UserID, t.Subject, t.Status, t.Priority, body))
+	if err != nil {
+		return models.SupportTicket{}, fmt.Errorf("create ticket: %w", err)
+	}
+	return created, nil
+}
+
+func (s *Store) FindTicket(ctx context.Context, id int64) (models.SupportTicket, error) {
+	const query = `SELECT ` + ticketColumns + ` FROM support_tickets t JOIN users u ON u.id = t.user_id WHERE t.id = $1;`
+	return scanTicket(s.db.QueryRow(ctx, query, id))
+}
+
+func (s *Store) ListUserTickets(ctx context.Context, userID int64, limit int) ([]models.SupportTicket, error) {
+	const query = `
+	SELECT ` + ticketColumns + ` FROM support_tickets t JOIN users u ON u.id = t.user_id
+	WHERE t.user_id = $1
+	ORDER BY t.updated_at DESC, t.id DESC LIMIT $2;
+	`
+	return s.listTickets(ctx, query, userID, limit)
+}
+
+func (s *Store) ListTicketQueue(ctx context.Context, statuses []string, limit int) ([]models.SupportTicket, error) {
+	const query = `
+	SELECT ` + ticketColumns + ` FROM support_tickets t JOIN users u ON u.id = t.user_id
+	WHERE t.status = ANY($1)
+	ORDER BY t.priority = 'high' DESC, t.updated_at, t.id LIMIT $2;
+	`
+	return s.listTickets(ctx, query, statuses, limit)
+}
+
+func (s *Store) listTickets(ctx context.Context, query string, args ...any) ([]models.SupportTicket, error) {
+	rows, err := s.reader().Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("list tickets: %w", err)
+	}
+	defer rows.Close()
+
+	tickets := []models.SupportTicket{}
+	for rows.Next() {
+		t, err := scanTicket(rows)
+		if err != nil {
+			return nil, err
+		}
+		tickets = append(tickets, t)
+	}
+	return tickets, rows.Err()
+}
+
+// AddTicketReply moves the ticket and appends the reply in one statement,
+// so a ticket closed in the meantime gets neither.
+func (s *Store) AddTicketReply(ctx context.Context, reply models.TicketReply, from []string, status string) (models.TicketReply, error) {
+	const query = `
+	WITH moved AS (
+		UPDATE support_tickets SET status = $5, updated_at = NOW(), closed_at = NULL
+		WHERE id = $1 AND status = ANY($4)
+		RETURNING id
+	), inserted AS (
+		INSERT INTO ticket_replies (ticket_id, author_id, staff, body)
+		SELECT id, $2, $3, $6 FROM moved
+		RETURNING *
+	)
+	SELECT ` + ticketReplyColumns + ` FROM inserted r JOIN users u ON u.id = r.author_id;
+	`
+	saved, err := scanTicketReply(s.db.QueryRow(ctx, query, reply.TicketID, reply.AuthorID, reply.Staff, from, status, reply.Body))
+	if err != nil && !errors.Is(err, storage.ErrNotFound) {
+		return models.TicketReply{}, fmt.Errorf("add ticket reply: %w", err)
+	}
+	return saved, err
+}
+
+func (s *Store) ListTicketReplies(ctx context.Context, ticketID int64) ([]models.TicketReply, error) {
+	const query = `
+	SELECT ` + ticketReplyColumns + ` FROM ticket_replies r JOIN users u ON u.id = r.author_id
+	WHERE r.ticket_id = $1
+	ORDER BY r.id;
+	`
+	rows, err := s.db.Query(ctx, query, ticketID)
+	if err != nil {
+		return nil, fmt.Errorf("list ticket replies: %w", err)
+	}
+	defer rows.Close()
+
+	replies := []models.TicketReply{}
+	for rows.Next() {
+		r, err := scanTicketReply(rows)
+		if err != nil {
+			return nil, err
+		}
+		replies = append(replies, r)
+	}
+	return replies, rows.Err()
+}
+
+func (s *Store) SetTicketStatus(ctx context.Context, id int64, from []string, status string) (models.SupportTicket, error) {
+	const query = `
+	WITH moved AS (
+		UPDATE support_tickets
+		SET status = $3, updated_at = NOW(), closed_at = CASE WHEN $3 = 'closed' THEN NOW() END
+		WHERE id = $1 AND status = ANY($2)
+		RETURNING *
+	)
+	SELECT ` + ticketColumns + ` FROM moved t JOIN users u ON u.id = t.user_id;
+	`
+	return scanTicket(s.db.QueryRow(ctx, query, id, from, status))
+}
+
+func (s *Store) AddTicketAttachment(ctx context.Context, a models.TicketAttachment) (models.TicketAttachment, error) {
+	const query = `
+	INSERT INTO ticket_attachments (ticket_id, uploader_id, name, content_type, size, blob_key)
+	VALUES ($1, $2, $3, $4, $5, $6)
+	RETURNING ` + ticketAttachmentColumns + `;
+	`
+	saved, err := scanTicketAttachment(s.db.QueryRow(ctx, query, a.TicketID, a.UploaderID, a.Name, a.ContentType, a.Size, a.BlobKey))
+	if err != nil {
+		return models.TicketAttachment{}, fmt.Errorf("add ticket attachment: %w", err)
+	}
+	return saved, nil
+}
+
+func (s *Store) ListTicketAttachments(ctx context.Context, ticketID int64) ([]models.TicketAttachment, error) {
+	const query = `SELECT ` + ticketAttachmentColumns + ` FROM ticket_attachments WHERE ticket_id = $1 ORDER BY id;`
+	rows, err := s.db.Query(ctx, query, ticketID)
+	if err != nil {
+		return nil, fmt.Errorf("list ticket attachments: %w", err)
+	}
+	defer rows.Close()
+
+	attachments := []models.TicketAttachment{}
+	for rows.Next() {
+		a, err := scanTicketAttachment(rows)
+		if err != nil {
+			return nil, err
+		}
+		attachments = append(attachments, a)
+	}
+	return attachments, rows.Err()
+}
+
+func scanTicket(row pgx.Row) (models.SupportTicket, error) {
+	var t models.SupportTicket
+	if err := row.Scan(&t.ID, &t.UserID, &t.Username, &t.Subject, &t.Status, &t.Priority, &t.CreatedAt, &t.UpdatedAt, &t.ClosedAt); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.SupportTicket{}, storage.ErrNotFound
+		}
+		return models.SupportTicket{}, err
+	}
+	return t, nil
+}
+
+func scanTicketReply(row pgx.Row) (models.TicketReply, error) {
+	var r models.TicketReply
+	if err := row.Scan(&r.ID, &r.TicketID, &r.AuthorID, &r.AuthorUsername, &r.Staff, &r.Body, &r.CreatedAt); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.TicketReply{}, storage.ErrNotFound
+		}
+		return models.TicketReply{}, err
+	}
+	return r, nil
+}
+
+func scanTicketAttachment(row pgx.Row) (models.TicketAttachment, error) {
+	var a models.TicketAttachment
+	if err := row.Scan(&a.ID, &a.TicketID, &a.UploaderID, &a.Name, &a.ContentType, &a.Size, &a.BlobKey, &a.CreatedAt); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.TicketAttachment{}, storage.ErrNotFound
+		}
+		return models.TicketAttachment{}, err
+	}
+	return a, nil
+}
@@ -0,0 +1,74 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// timeoutDB gives every query its own deadline, so a connection that stalls
+// (for example while a suspended Neon compute wakes up) fails the request
+// instead of holding it past the HTTP write timeout.
+type timeoutDB struct {
+	dbtx
+	timeout time.Duration
+}
+
+// withQueryTimeout wraps db unless timeout is zero.
+func withQueryTimeout(db dbtx, timeout time.Duration) dbtx {
+	if timeout <= 0 {
+		return db
+	}
+	return timeoutDB{dbtx: db, timeout: timeout}
+}
+
+// Begin is not bounded: a transaction lives as long as its caller's context,
+// and WithTx bounds each statement run inside it.
+func (d timeoutDB) Begin(ctx context.Context) (pgx.Tx, error) {
+	return d.dbtx.Begin(ctx)
+}
+
+func (d timeoutDB) Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error) {
+	ctx, cancel := context.WithTimeout(ctx, d.timeout)
+	defer cancel()
+	return d.dbtx.Exec(ctx, sql, args...)
+}
+
+func (d timeoutDB) Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+	ctx, cancel := context.WithTimeout(ctx, d.timeout)
+	rows, err := d.dbtx.Query(ctx, sql, args...)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	return cancelRows{Rows: rows, cancel: cancel}, nil
+}
+
+func (d timeoutDB) QueryRow(ctx context.Context, sql string, args ...any) pgx.Row {
+	ctx, cancel := context.WithTimeout(ctx, d.timeout)
+	return cancelRow{row: d.dbtx.QueryRow(ctx, sql, args...), cancel: cancel}
+}
+
+// cancelRows releases the query's deadline once the rows are closed.
+type cancelRows struct {
+	pgx.Rows
+	cancel context.CancelFunc
+}
+
+func (r cancelRows) Close() {
+	r.Rows.Close()
+	r.cancel()
+}
+
+// cancelRow releases the query's deadline once the row is scanned.
+type cancelRow struct {
+	row    pgx.Row
+	cancel context.CancelFunc
+}
+
+func (r cancelRow) Scan(dest ...any) error {
+	defer r.cancel()
+	return r.row.Scan(dest...)
+}
@@ -0,0 +1,54 @@
+package postgres
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// ctxDB records the context each query was issued with.
+type ctxDB struct {
+	dbtx
+	ctx context.Context
+}
+
+func (d *ctxDB) Exec(ctx context.Context, _ string, _ ...any) (pgconn.CommandTag, error) {
+	d.ctx = ctx
+	return pgconn.CommandTag{}, nil
+}
+
+func (d *ctxDB) QueryRow(ctx context.Context, _ string, _ ...any) pgx.Row {
+	d.ctx = ctx
+	return errRow{}
+}
+
+func TestQueryTimeoutBoundsEachQuery(t *testing.T) {
+	inner := &ctxDB{}
+	db := withQueryTimeout(inner, time.Second)
+
+	if _, err := db.Exec(context.Background(), "SELECT 1"); err != nil {
+		t.Fatal(err)
+	}
+	if deadline, ok := inner.ctx.Deadline(); !ok || time.Until(deadline) > time.Second {
+		t.Fatalf("exec deadline = %v, %v; want within 1s", deadline, ok)
+	}
+	if inner.ctx.Err() == nil {
+		t.Fatal("exec context should be released when Exec returns")
+	}
+
+	row := db.QueryRow(context.Background(), "SELECT 1")
+	if inner.ctx.Err() != nil {
+		t.Fatal("row context must stay open until Scan")
+	}
+	_ = row.Scan()
+	if inner.ctx.Err() == nil {
+		t.Fatal("row context should be released after Scan")
+	}
+
+	if withQueryTimeout(inner, 0) != dbtx(inner) {
+		t.Fatal("a zero timeout should leave the connection unwrapped")
+	}
+}
@@ -0,0 +1,174 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/hongminglow/all-in-be/internal/models"
+	"github.com/hongminglow/all-in-be/internal/storage"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const tournamentColumns = `id, name, entry_fee, starts_at, ends_at, prize_shares, prize_pool, entrants, status, created_by, created_at, closed_at`
+
+const tournamentEntryColumns = `e.tournament_id, e.user_id, u.username, e.score, e.scored_at, e.rank, e.prize, e.entered_at`
+
+func (s *Store) CreateTournament(ctx context.Context, t models.Tournament) (models.Tournament, error) {
+	const query = `
+	INSERT INTO tournaments (name, entry_fee, starts_at, ends_at, prize_shares, status, created_by)
+	VALUES ($1, $2, $3, $4, $5, 'open', $6)
+	RETURNING ` + tournamentColumns + `;
+	`
+	created, err := scanTournament(s.db.QueryRow(ctx, query, t.Name, t.EntryFee, t.StartsAt, t.EndsAt, t.PrizeShares, t.CreatedBy))
+	if err != nil {
+		return models.Tournament{}, fmt.Errorf("create tournament: %w", err)
+	}
+	return created, nil
+}
+
+func (s *Store) FindTournament(ctx context.Context, id int64) (models.Tournament, error) {
+	return scanTournament(s.db.QueryRow(ctx, `SELECT `+tournamentColumns+` FROM tournaments WHERE id = $1;`, id))
+}
+
+func (s *Store) ListTournaments(ctx context.Context, limit int) ([]models.Tournament, error) {
+	return s.listTournaments(ctx, s.reader(), `SELECT `+tournamentColumns+` FROM tournaments ORDER BY starts_at DESC, id DESC LIMIT $1;`, limit)
+}
+
+// ListEndedTournaments reads from the primary: a replica lagging behind
+// would hand back tournaments already closed.
+func (s *Store) ListEndedTournaments(ctx context.Context, at time.Time) ([]models.Tournament, error) {
+	return s.listTournaments(ctx, s.db, `SELECT `+tournamentColumns+` FROM tournaments WHERE status = 'open' AND ends_at <= $1 ORDER BY ends_at, id;`, at)
+}
+
+func (s *Store) listTournaments(ctx context.Context, db dbtx, query string, arg any) ([]models.Tournament, error) {
+	rows, err := db.Query(ctx, query, arg)
+	if err != nil {
+		return nil, fmt.Errorf("list tournaments: %w", err)
+	}
+	defer rows.Close()
+
+	tournaments := []models.Tournament{}
+	for rows.Next() {
+		t, err := scanTournament(rows)
+		if err != nil {
+			return nil, err
+		}
+		tournaments = append(tournaments, t)
+	}
+	return tournaments, rows.Err()
+}
+
+// EnterTournament grows the pool and records the entry in one statement. The
+// update locks the tournament's row, so an entry cannot slip in while it is
+// being closed.
+func (s *Store) EnterTournament(ctx context.Context, tournamentID, userID int64, fee float64) (models.TournamentEntry, error) {
+	const query = `
+	WITH joined AS (
+		UPDATE tournaments SET prize_pool = prize_pool + $3, entrants = entrants + 1
+		WHERE id = $1 AND status = 'open'
+		RETURNING id
+	), entered AS (
+		INSERT INTO tournament_entries (tournament_id, user_id)
+		SELECT id, $2 FROM joined
+		RETURNING *
+	)
+	SELECT ` + tournamentEntryColumns + ` FROM entered e JOIN users u ON u.id = e.user_id;
+	`
+	entry, err := scanTournamentEntry(s.db.QueryRow(ctx, query, tournamentID, userID, fee))
+	var pgErr *pgconn.PgError
+	switch {
+	case errors.As(err, &pgErr) && pgErr.Code == "23505":
+		return models.TournamentEntry{}, storage.ErrAlreadyExists
+	case errors.As(err, &pgErr) && pgErr.Code == "23503":
+		return models.TournamentEntry{}, storage.ErrNotFound
+	case err != nil && !errors.Is(err, storage.ErrNotFound):
+		return models.TournamentEntry{}, fmt.Errorf("enter tournament: %w", err)
+	}
+	return entry, err
+}
+
+func (s *Store) RecordTournamentScore(ctx context.Context, tournamentID, userID int64, score float64, at time.Time) (models.TournamentEntry, error) {
+	const update = `
+	UPDATE tournament_entries SET score = $3, scored_at = $4
+	WHERE tournament_id = $1 AND user_id = $2 AND (scored_at IS NULL OR score < $3);
+	`
+	if _, err := s.db.Exec(ctx, update, tournamentID, userID, score, at); err != nil {
+		return models.TournamentEntry{}, fmt.Errorf("record tournament score: %w", err)
+	}
+	const query = `
+	SELECT ` + tournamentEntryColumns + `
+	FROM tournament_entries e JOIN users u ON u.id = e.user_id
+	WHERE e.tournament_id = $1 AND e.user_id = $2;
+	`
+	return scanTournamentEntry(s.db.QueryRow(ctx, query, tournamentID, userID))
+}
+
+func (s *Store) ListTournamentEntries(ctx context.Context, tournamentID int64) ([]models.TournamentEntry, error) {
+	const query = `
+	SELECT ` + tournamentEntryColumns + `
+	FROM tournament_entries e JOIN users u ON u.id = e.user_id
+	WHERE e.tournament_id = $1
+	ORDER BY e.scored_at IS NULL, e.score DESC, e.scored_at, e.entered_at, e.user_id;
+	`
+	rows, err := s.db.Query(ctx, query, tournamentID)
+	if err != nil {
+		return nil, fmt.Errorf("list tournament entries: %w", err)
+	}
+	defer rows.Close()
+
+	entries := []models.TournamentEntry{}
+	for rows.Next() {
+		entry, err := scanTournamentEntry(rows)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
+
+func (s *Store) CloseTournament(ctx context.Context, id int64, at time.Time) (models.Tournament, error) {
+	const query = `
+	UPDATE tournaments SET status = 'closed', closed_at = $2
+	WHERE id = $1 AND status = 'open' AND ends_at <= $2
+	RETURNING ` + tournamentColumns + `;
+	`
+	return scanTournament(s.db.QueryRow(ctx, query, id, at))
+}
+
+func (s *Store) SetTournamentResult(ctx context.Context, tournamentID, userID int64, rank int, prize float64) error {
+	const query = `UPDATE tournament_entries SET rank = $3, prize = $4 WHERE tournament_id = $1 AND user_id = $2;`
+	tag, err := s.db.Exec(ctx, query, tournamentID, userID, rank, prize)
+	if err != nil {
+		return fmt.Errorf("set tournament result: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return storage.ErrNotFound
+	}
+	return nil
+}
+
+func scanTournament(row pgx.Row) (models.Tournament, error) {
+	var t models.Tournament
+	if err := row.Scan(&t.ID, &t.Name, &t.EntryFee, &t.StartsAt, &t.EndsAt, &t.PrizeShares, &t.PrizePool, &t.Entrants, &t.Status, &t.CreatedBy, &t.CreatedAt, &t.ClosedAt); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.Tournament{}, storage.ErrNotFound
+		}
+		return models.Tournament{}, err
+	}
+	return t, nil
+}
+
+func scanTournamentEntry(row pgx.Row) (models.TournamentEntry, error) {
+	var e models.TournamentEntry
+	if err := row.Scan(&e.TournamentID, &e.UserID, &e.Username, &e.Score, &e.ScoredAt, &e.Rank, &e.Prize, &e.EnteredAt); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.TournamentEntry{}, storage.ErrNotFound
+		}
+		return models.TournamentEntry{}, err
+	}
+	return e, nil
+}
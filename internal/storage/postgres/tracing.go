@@ -0,0 +1,60 @@
+package postgres
+
+import (
+	"context"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/hongminglow/all-in-be/internal/tracing"
+	"github.com/jackc/pgx/v5"
+)
+
+// slowQueryThreshold is the duration above which queries are logged with their trace ID.
+const slowQueryThreshold = 500 * time.Millisecond
+
+// maxTracedSQL caps the statement text attached to spans and logs.
+const maxTracedSQL = 1024
+
+// queryTracer is a pgx.QueryTracer that records a client span per query and
+// logs slow statements so they can be matched to the request that issued them.
+type queryTracer struct{}
+
+type queryStartKey struct{}
+
+type queryStart struct {
+	at   time.Time
+	sql  string
+	span *tracing.Span
+}
+
+func (queryTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	sql := compactSQL(data.SQL)
+	ctx, span := tracing.Start(ctx, "db.query", tracing.KindClient,
+		tracing.String("db.system", "postgresql"),
+		tracing.String("db.query.text", sql),
+	)
+	return context.WithValue(ctx, queryStartKey{}, queryStart{at: time.Now(), sql: sql, span: span})
+}
+
+func (queryTracer) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	start, ok := ctx.Value(queryStartKey{}).(queryStart)
+	if !ok {
+		return
+	}
+	start.span.RecordError(data.Err)
+	start.span.SetAttributes(tracing.Int("db.response.rows_affected", data.CommandTag.RowsAffected()))
+	start.span.End()
+	if elapsed := time.Since(start.at); elapsed >= slowQueryThreshold {
+		log.Printf("%sslow query (%s): %s", tracing.LogPrefix(ctx), elapsed, start.sql)
+	}
+}
+
+// compactSQL collapses whitespace in the multi-line query constants and truncates them.
+func compactSQL(sql string) string {
+	sql = strings.Join(strings.Fields(sql), " ")
+	if len(sql) > maxTracedSQL {
+		sql = sql[:maxTracedSQL] + "..."
+	}
+	return sql
+}
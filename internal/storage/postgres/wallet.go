@@ -0,0 +1,215 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/hongminglow/all-in-be/internal/models"
+	"github.com/hongminglow/all-in-be/internal/storage"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const transactionColumns = `id, user_id, amount, balance_after, reason, reference, merged_from, created_at`
+
+// ApplyTransaction updates the balance and writes the ledger entry in one
+// statement, so the two cannot diverge even outside a unit of work, and posts
+// the entry to the journal in the same transaction. Debits may not dip into
+// the funds active holds reserve.
+func (s *Store) ApplyTransaction(ctx context.Context, entry models.Transaction) (models.Transaction, error) {
+	const query = `
+	WITH moved AS (
+		UPDATE users SET balance = balance + $2, version = version + 1
+		WHERE id = $1 AND balance + $2 >= 0 AND ($2 >= 0 OR balance + $2 >= held_balance)
+		RETURNING id, balance
+	)
+	INSERT INTO wallet_transactions (user_id, amount, balance_after, reason, reference)
+	SELECT id, $2, balance, $3, $4 FROM moved
+	RETURNING ` + transactionColumns + `;
+	`
+	var applied models.Transaction
+	err := pgx.BeginFunc(ctx, s.db, func(tx pgx.Tx) error {
+		// The wallet is opened first: that fails for a missing user, so no
+		// row moved below means the funds fell short.
+		if err := openWallet(ctx, tx, entry.UserID); err != nil {
+			return err
+		}
+		var err error
+		applied, err = scanTransaction(tx.QueryRow(ctx, query, entry.UserID, entry.Amount, entry.Reason, entry.Reference))
+		if errors.Is(err, storage.ErrNotFound) {
+			return storage.ErrInsufficientFunds
+		}
+		if err != nil {
+			return err
+		}
+		return postJournal(ctx, tx, models.JournalFor(applied))
+	})
+	if err != nil && !errors.Is(err, storage.ErrNotFound) && !errors.Is(err, storage.ErrInsufficientFunds) {
+		return models.Transaction{}, fmt.Errorf("apply transaction: %w", err)
+	}
+	return applied, err
+}
+
+// FindTransaction fetches a ledger entry by ID, archived or not.
+func (s *Store) FindTransaction(ctx context.Context, id int64) (models.Transaction, error) {
+	const query = `
+	SELECT ` + transactionColumns + ` FROM wallet_transactions WHERE id = $1
+	UNION ALL
+	SELECT ` + transactionColumns + ` FROM wallet_transactions_archive WHERE id = $1
+	LIMIT 1;
+	`
+	return scanTransaction(s.db.QueryRow(ctx, query, id))
+}
+
+// ListTransactions returns the user's whole ledger, archived entries included, oldest first.
+func (s *Store) ListTransactions(ctx context.Context, userID int64) ([]models.Transaction, error) {
+	const query = `
+	SELECT ` + transactionColumns + ` FROM wallet_transactions WHERE user_id = $1
+	UNION ALL
+	SELECT ` + transactionColumns + ` FROM wallet_transactions_archive WHERE user_id = $1
+	ORDER BY id;
+	`
+	rows, err := s.reader().Query(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("list transactions: %w", err)
+	}
+	defer rows.Close()
+
+	transactions := []models.Transaction{}
+	for rows.Next() {
+		t, err := scanTransaction(rows)
+		if err != nil {
+			return nil, err
+		}
+		transactions = append(transactions, t)
+	}
+	return transactions, rows.Err()
+}
+
+// ListTransactionsAfter reads the primary, so a movement is never missed
+// because a replica has yet to see it.
+func (s *Store) ListTransactionsAfter(ctx context.Context, userID, after int64, limit int) ([]models.Transaction, error) {
+	const query = `
+	SELECT ` + transactionColumns + ` FROM wallet_transactions
+	WHERE user_id = $1 AND id > $2
+	ORDER BY id
+	LIMIT $3;
+	`
+	rows, err := s.db.Query(ctx, query, userID, after, limit)
+	if err != nil {
+		return nil, fmt.Errorf("list transactions after %d: %w", after, err)
+	}
+	defer rows.Close()
+
+	transactions := []models.Transaction{}
+	for rows.Next() {
+		t, err := scanTransaction(rows)
+		if err != nil {
+			return nil, err
+		}
+		transactions = append(transactions, t)
+	}
+	return transactions, rows.Err()
+}
+
+// LastTransactionID returns the user's newest ledger entry ID, or 0.
+func (s *Store) LastTransactionID(ctx context.Context, userID int64) (int64, error) {
+	var id int64
+	if err := s.db.QueryRow(ctx, `SELECT COALESCE(MAX(id), 0) FROM wallet_transactions WHERE user_id = $1;`, userID).Scan(&id); err != nil {
+		return 0, fmt.Errorf("find last transaction: %w", err)
+	}
+	return id, nil
+}
+
+// ClaimOperation records an operation key. Concurrent claims for the same key
+// block on the primary key until the first transaction ends, so only one of
+// them can succeed.
+func (s *Store) ClaimOperation(ctx context.Context, op models.Operation) error {
+	const query = `
+	INSERT INTO operations (kind, key, user_id, amount)
+	VALUES ($1, $2, $3, $4)
+	ON CONFLICT (kind, key) DO NOTHING;
+	`
+	tag, err := s.db.Exec(ctx, query, op.Kind, op.Key, op.UserID, op.Amount)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+			return storage.ErrNotFound
+		}
+		return fmt.Errorf("claim operation: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return storage.ErrAlreadyExists
+	}
+	return nil
+}
+
+// FindOperation fetches a claimed operation by kind and key.
+func (s *Store) FindOperation(ctx context.Context, kind, key string) (models.Operation, error) {
+	const query = `SELECT kind, key, user_id, amount, transaction_id, created_at FROM operations WHERE kind = $1 AND key = $2;`
+	var op models.Operation
+	if err := s.db.QueryRow(ctx, query, kind, key).Scan(&op.Kind, &op.Key, &op.UserID, &op.Amount, &op.TransactionID, &op.CreatedAt); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.Operation{}, storage.ErrNotFound
+		}
+		return models.Operation{}, fmt.Errorf("find operation: %w", err)
+	}
+	return op, nil
+}
+
+// CompleteOperation stores the ledger entry a claimed operation produced.
+func (s *Store) CompleteOperation(ctx context.Context, kind, key string, transactionID int64) error {
+	const query = `UPDATE operations SET transaction_id = $3 WHERE kind = $1 AND key = $2;`
+	tag, err := s.db.Exec(ctx, query, kind, key, transactionID)
+	if err != nil {
+		return fmt.Errorf("complete operation: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return storage.ErrNotFound
+	}
+	return nil
+}
+
+// SumTransactions totals recent entries of one reason. Limits only look back
+// a day, well short of the archive cutoff, so archived entries are left out.
+func (s *Store) SumTransactions(ctx context.Context, userID int64, reason string, since time.Time) (float64, error) {
+	const query = `
+	SELECT COALESCE(SUM(amount), 0)
+	FROM wallet_transactions
+	WHERE user_id = $1 AND reason = $2 AND created_at >= $3;
+	`
+	var total float64
+	if err := s.db.QueryRow(ctx, query, userID, reason, since).Scan(&total); err != nil {
+		return 0, fmt.Errorf("sum transactions: %w", err)
+	}
+	return total, nil
+}
+
+// CheckUserVersion locks the user's row for the rest of the transaction, so
+// the version cannot change between the check and the caller's update.
+func (s *Store) CheckUserVersion(ctx context.Context, userID, version int64) error {
+	var current int64
+	err := s.db.QueryRow(ctx, `SELECT version FROM users WHERE id = $1 FOR UPDATE;`, userID).Scan(&current)
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		return storage.ErrNotFound
+	case err != nil:
+		return fmt.Errorf("check user version: %w", err)
+	case current != version:
+		return storage.ErrVersionConflict
+	}
+	return nil
+}
+
+func scanTransaction(row pgx.Row) (models.Transaction, error) {
+	var t models.Transaction
+	if err := row.Scan(&t.ID, &t.UserID, &t.Amount, &t.BalanceAfter, &t.Reason, &t.Reference, &t.MergedFrom, &t.CreatedAt); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.Transaction{}, storage.ErrNotFound
+		}
+		return models.Transaction{}, err
+	}
+	return t, nil
+}
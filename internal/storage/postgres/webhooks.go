@@ -0,0 +1,119 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/hongminglow/all-in-be/internal/models"
+	"github.com/hongminglow/all-in-be/internal/storage"
+	"github.com/jackc/pgx/v5"
+)
+
+const webhookEndpointColumns = `id, url, secret, events, active, created_by, created_at`
+
+const webhookDeliveryColumns = `id, endpoint_id, event_id, event, payload, attempt, COALESCE(status_code, 0), error, success, duration_ms, created_at`
+
+// CreateWebhookEndpoint registers a new delivery target.
+func (s *Store) CreateWebhookEndpoint(ctx context.Context, endpoint models.WebhookEndpoint) (models.WebhookEndpoint, error) {
+	const query = `
+	INSERT INTO webhook_endpoints (url, secret, events, active, created_by)
+	VALUES ($1, $2, $3, $4, $5)
+	RETURNING ` + webhookEndpointColumns + `;
+	`
+	row := s.db.QueryRow(ctx, query, endpoint.URL, endpoint.Secret, endpoint.Events, endpoint.Active, endpoint.CreatedBy)
+	return scanWebhookEndpoint(row)
+}
+
+// ListWebhookEndpoints returns all endpoints, oldest first.
+func (s *Store) ListWebhookEndpoints(ctx context.Context) ([]models.WebhookEndpoint, error) {
+	const query = `SELECT ` + webhookEndpointColumns + ` FROM webhook_endpoints ORDER BY id;`
+	rows, err := s.reader().Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("list webhook endpoints: %w", err)
+	}
+	defer rows.Close()
+
+	endpoints := []models.WebhookEndpoint{}
+	for rows.Next() {
+		endpoint, err := scanWebhookEndpoint(rows)
+		if err != nil {
+			return nil, err
+		}
+		endpoints = append(endpoints, endpoint)
+	}
+	return endpoints, rows.Err()
+}
+
+// DeleteWebhookEndpoint removes an endpoint and its delivery log.
+func (s *Store) DeleteWebhookEndpoint(ctx context.Context, id int64) error {
+	tag, err := s.db.Exec(ctx, `DELETE FROM webhook_endpoints WHERE id = $1;`, id)
+	if err != nil {
+		return fmt.Errorf("delete webhook endpoint: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return storage.ErrNotFound
+	}
+	return nil
+}
+
+// RecordWebhookDelivery appends a delivery attempt to the log.
+func (s *Store) RecordWebhookDelivery(ctx context.Context, d models.WebhookDelivery) (models.WebhookDelivery, error) {
+	const query = `
+	INSERT INTO webhook_deliveries (endpoint_id, event_id, event, payload, attempt, status_code, error, success, duration_ms)
+	VALUES ($1, $2, $3, $4, $5, NULLIF($6, 0), $7, $8, $9)
+	RETURNING ` + webhookDeliveryColumns + `;
+	`
+	row := s.db.QueryRow(ctx, query, d.EndpointID, d.EventID, d.Event, []byte(d.Payload), d.Attempt, d.StatusCode, d.Error, d.Success, d.DurationMS)
+	return scanWebhookDelivery(row)
+}
+
+// ListWebhookDeliveries returns an endpoint's newest delivery attempts first.
+func (s *Store) ListWebhookDeliveries(ctx context.Context, endpointID int64, limit int) ([]models.WebhookDelivery, error) {
+	const query = `
+	SELECT ` + webhookDeliveryColumns + `
+	FROM webhook_deliveries
+	WHERE endpoint_id = $1
+	ORDER BY created_at DESC, id DESC
+	LIMIT $2;
+	`
+	rows, err := s.reader().Query(ctx, query, endpointID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("list webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	deliveries := []models.WebhookDelivery{}
+	for rows.Next() {
+		delivery, err := scanWebhookDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	return deliveries, rows.Err()
+}
+
+func scanWebhookEndpoint(row pgx.Row) (models.WebhookEndpoint, error) {
+	var e models.WebhookEndpoint
+	if err := row.Scan(&e.ID, &e.URL, &e.Secret, &e.Events, &e.Active, &e.CreatedBy, &e.CreatedAt); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.WebhookEndpoint{}, storage.ErrNotFound
+		}
+		return models.WebhookEndpoint{}, err
+	}
+	return e, nil
+}
+
+func scanWebhookDelivery(row pgx.Row) (models.WebhookDelivery, error) {
+	var d models.WebhookDelivery
+	var payload []byte
+	if err := row.Scan(&d.ID, &d.EndpointID, &d.EventID, &d.Event, &payload, &d.Attempt, &d.StatusCode, &d.Error, &d.Success, &d.DurationMS, &d.CreatedAt); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.WebhookDelivery{}, storage.ErrNotFound
+		}
+		return models.WebhookDelivery{}, err
+	}
+	d.Payload = payload
+	return d, nil
+}
@@ -0,0 +1,100 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/hongminglow/all-in-be/internal/models"
+	"github.com/hongminglow/all-in-be/internal/storage"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const withdrawalColumns = `id, user_id, provider, reference, amount, status, hold_transaction_id, reviewed_by, note, created_at, updated_at`
+
+// CreateWithdrawal records a request whose hold has just been debited.
+func (s *Store) CreateWithdrawal(ctx context.Context, w models.WithdrawalRequest) (models.WithdrawalRequest, error) {
+	const query = `
+	INSERT INTO withdrawal_requests (user_id, provider, reference, amount, status, hold_transaction_id)
+	VALUES ($1, $2, $3, $4, $5, $6)
+	RETURNING ` + withdrawalColumns + `;
+	`
+	created, err := scanWithdrawal(s.db.QueryRow(ctx, query, w.UserID, w.Provider, w.Reference, w.Amount, w.Status, w.HoldID))
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			switch pgErr.Code {
+			case "23503":
+				return models.WithdrawalRequest{}, storage.ErrNotFound
+			case "23505":
+				return models.WithdrawalRequest{}, storage.ErrAlreadyExists
+			}
+		}
+		return models.WithdrawalRequest{}, fmt.Errorf("create withdrawal: %w", err)
+	}
+	return created, nil
+}
+
+// FindWithdrawal fetches one request from the primary, since it is read to
+// decide on its next transition.
+func (s *Store) FindWithdrawal(ctx context.Context, id int64) (models.WithdrawalRequest, error) {
+	const query = `SELECT ` + withdrawalColumns + ` FROM withdrawal_requests WHERE id = $1;`
+	return scanWithdrawal(s.db.QueryRow(ctx, query, id))
+}
+
+// FindWithdrawalByReference fetches the request a provider callback names.
+func (s *Store) FindWithdrawalByReference(ctx context.Context, provider, reference string) (models.WithdrawalRequest, error) {
+	const query = `SELECT ` + withdrawalColumns + ` FROM withdrawal_requests WHERE provider = $1 AND reference = $2;`
+	return scanWithdrawal(s.db.QueryRow(ctx, query, provider, reference))
+}
+
+// ListWithdrawals returns the newest requests matching filter first.
+func (s *Store) ListWithdrawals(ctx context.Context, filter models.WithdrawalFilter, limit int) ([]models.WithdrawalRequest, error) {
+	const query = `
+	SELECT ` + withdrawalColumns + `
+	FROM withdrawal_requests
+	WHERE ($1 = 0 OR user_id = $1)
+		AND ($2 = '' OR status = $2)
+	ORDER BY id DESC
+	LIMIT $3;
+	`
+	rows, err := s.reader().Query(ctx, query, filter.UserID, filter.Status, limit)
+	if err != nil {
+		return nil, fmt.Errorf("list withdrawals: %w", err)
+	}
+	defer rows.Close()
+
+	requests := []models.WithdrawalRequest{}
+	for rows.Next() {
+		w, err := scanWithdrawal(rows)
+		if err != nil {
+			return nil, err
+		}
+		requests = append(requests, w)
+	}
+	return requests, rows.Err()
+}
+
+// TransitionWithdrawal moves a request in one of the from statuses to status.
+func (s *Store) TransitionWithdrawal(ctx context.Context, id int64, from []string, status string, reviewedBy *int64, note string, at time.Time) (models.WithdrawalRequest, error) {
+	const query = `
+	UPDATE withdrawal_requests
+	SET status = $3, reviewed_by = COALESCE($4, reviewed_by), note = $5, updated_at = $6
+	WHERE id = $1 AND status = ANY($2)
+	RETURNING ` + withdrawalColumns + `;
+	`
+	return scanWithdrawal(s.db.QueryRow(ctx, query, id, from, status, reviewedBy, note, at))
+}
+
+func scanWithdrawal(row pgx.Row) (models.WithdrawalRequest, error) {
+	var w models.WithdrawalRequest
+	if err := row.Scan(&w.ID, &w.UserID, &w.Provider, &w.Reference, &w.Amount, &w.Status, &w.HoldID, &w.ReviewedBy, &w.Note, &w.CreatedAt, &w.UpdatedAt); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.WithdrawalRequest{}, storage.ErrNotFound
+		}
+		return models.WithdrawalRequest{}, err
+	}
+	return w, nil
+}
@@ -3,6 +3,7 @@ package storage
 import (
 	"context"
 	"errors"
+	"time"
 
 	"github.com/hongminglow/all-in-be/internal/models"
 )
@@ -13,10 +14,732 @@ var ErrNotFound = errors.New("record not found")
 // ErrAlreadyExists indicates a uniqueness conflict.
 var ErrAlreadyExists = errors.New("record already exists")
 
+// ErrInUse indicates a record cannot be deleted while others refer to it.
+var ErrInUse = errors.New("record is in use")
+
+// ErrInsufficientFunds indicates a debit larger than the user's balance.
+var ErrInsufficientFunds = errors.New("insufficient funds")
+
+// ErrLimitReached indicates a usage limit has been used up.
+var ErrLimitReached = errors.New("limit reached")
+
+// ErrVersionConflict indicates a record changed since the version the caller
+// based its update on.
+var ErrVersionConflict = errors.New("record was changed by someone else")
+
+// ErrUnbalancedJournal indicates a ledger posting whose debits and credits
+// differ.
+var ErrUnbalancedJournal = errors.New("journal entry does not balance")
+
+// ErrUnavailable indicates the database is failing and calls are being refused
+// until it recovers.
+var ErrUnavailable = errors.New("database unavailable")
+
 // UserStore captures persistence operations needed by handlers.
 type UserStore interface {
 	CreateUser(ctx context.Context, user models.User) (models.User, error)
+	FindByID(ctx context.Context, id int64) (models.User, error)
 	FindByUsername(ctx context.Context, username string) (models.User, error)
 	FindByEmail(ctx context.Context, email string) (models.User, error)
 	FindByUsernameOrEmail(ctx context.Context, identifier string) (models.User, error)
+	// SearchUsers returns up to limit users whose username, email, or phone
+	// contains query, best matches first, starting after the cursor when one
+	// is given.
+	SearchUsers(ctx context.Context, query string, after *models.UserSearchCursor, limit int) ([]models.UserSearchResult, error)
+	// UpdateProfile saves the user's username, email and phone and bumps their
+	// version. It returns ErrVersionConflict unless the user is still at
+	// user.Version and ErrAlreadyExists when a new value is taken.
+	UpdateProfile(ctx context.Context, user models.User) (models.User, error)
+}
+
+// NoteStore persists internal staff notes on user accounts.
+type NoteStore interface {
+	CreateNote(ctx context.Context, note models.UserNote) (models.UserNote, error)
+	ListNotes(ctx context.Context, userID int64) ([]models.UserNote, error)
+	FindNote(ctx context.Context, userID, noteID int64) (models.UserNote, error)
+	// UpdateNote saves the note's body and pinned flag; when the body changes the
+	// previous body is kept as a revision attributed to editorID.
+	UpdateNote(ctx context.Context, note models.UserNote, editorID int64) (models.UserNote, error)
+	ListNoteRevisions(ctx context.Context, noteID int64) ([]models.NoteRevision, error)
+}
+
+// RateLimitStore persists per-tenant, per-route-class rate-limit policies.
+type RateLimitStore interface {
+	ListRateLimitPolicies(ctx context.Context) ([]models.RateLimitPolicy, error)
+	FindRateLimitPolicy(ctx context.Context, tenant, routeClass string) (models.RateLimitPolicy, error)
+	UpsertRateLimitPolicy(ctx context.Context, policy models.RateLimitPolicy) (models.RateLimitPolicy, error)
+	DeleteRateLimitPolicy(ctx context.Context, tenant, routeClass string) error
+}
+
+// ConfigHistoryStore persists the versioned history of configuration changes.
+type ConfigHistoryStore interface {
+	RecordConfigChange(ctx context.Context, change models.ConfigChange) (models.ConfigChange, error)
+	FindConfigChange(ctx context.Context, id int64) (models.ConfigChange, error)
+	// ListConfigChanges returns the newest changes first, optionally filtered by entity.
+	ListConfigChanges(ctx context.Context, entity string, limit int) ([]models.ConfigChange, error)
+	// ListConfigChangesBy returns every change the user made, oldest first.
+	ListConfigChangesBy(ctx context.Context, actorID int64) ([]models.ConfigChange, error)
+}
+
+// StatsStore computes aggregate metrics for operators.
+type StatsStore interface {
+	// AdminStats returns platform totals plus signups for each of the last days UTC days.
+	AdminStats(ctx context.Context, days int) (models.AdminStats, error)
+	// BusinessKPIs returns lifetime totals, archived ledger entries included.
+	BusinessKPIs(ctx context.Context) (models.BusinessKPIs, error)
+}
+
+// WebhookStore persists outbound webhook endpoints and their delivery log.
+type WebhookStore interface {
+	CreateWebhookEndpoint(ctx context.Context, endpoint models.WebhookEndpoint) (models.WebhookEndpoint, error)
+	// ListWebhookEndpoints returns all endpoints including their secrets.
+	ListWebhookEndpoints(ctx context.Context) ([]models.WebhookEndpoint, error)
+	DeleteWebhookEndpoint(ctx context.Context, id int64) error
+	RecordWebhookDelivery(ctx context.Context, delivery models.WebhookDelivery) (models.WebhookDelivery, error)
+	// ListWebhookDeliveries returns an endpoint's newest delivery attempts first.
+	ListWebhookDeliveries(ctx context.Context, endpointID int64, limit int) ([]models.WebhookDelivery, error)
+}
+
+// SecurityStore persists sign-in history, login alerts, security cases,
+// password resets, and device confirmations.
+type SecurityStore interface {
+	ListLoginDevices(ctx context.Context, userID int64) ([]models.LoginDevice, error)
+	// TouchLoginDevice records a sign-in from the device, creating it on first use.
+	TouchLoginDevice(ctx context.Context, device models.LoginDevice) error
+	CreateLoginAlert(ctx context.Context, alert models.LoginAlert) (models.LoginAlert, error)
+	FindLoginAlertByToken(ctx context.Context, tokenHash string) (models.LoginAlert, error)
+	// ListLoginAlerts returns up to limit of the user's alerts, newest first.
+	ListLoginAlerts(ctx context.Context, userID int64, limit int) ([]models.LoginAlert, error)
+	// ResolveLoginAlert moves a pending alert to status. It returns ErrNotFound
+	// when the alert does not exist or was already resolved.
+	ResolveLoginAlert(ctx context.Context, id int64, status string, at time.Time) error
+	CreateSecurityCase(ctx context.Context, c models.SecurityCase) (models.SecurityCase, error)
+	// ListSecurityCases returns the newest cases first.
+	ListSecurityCases(ctx context.Context, limit int) ([]models.SecurityCase, error)
+	// LockAccount revokes every session issued up to at and requires a password
+	// reset before the user can sign in again.
+	LockAccount(ctx context.Context, userID int64, at time.Time) error
+	CreatePasswordReset(ctx context.Context, reset models.PasswordReset) error
+	// ConsumePasswordReset deletes the reset and returns it. It returns
+	// ErrNotFound when the token is unknown or expired at now.
+	ConsumePasswordReset(ctx context.Context, tokenHash string, now time.Time) (models.PasswordReset, error)
+	CreateDeviceConfirmation(ctx context.Context, confirmation models.DeviceConfirmation) error
+	// ConsumeDeviceConfirmation deletes the confirmation and returns it. It
+	// returns ErrNotFound when the token is unknown or expired at now.
+	ConsumeDeviceConfirmation(ctx context.Context, tokenHash string, now time.Time) (models.DeviceConfirmation, error)
+	RecordLoginAttempt(ctx context.Context, attempt models.LoginAttempt) (models.LoginAttempt, error)
+	// ListLoginAttempts returns the newest attempts matching filter first.
+	ListLoginAttempts(ctx context.Context, filter models.LoginAttemptFilter, limit int) ([]models.LoginAttempt, error)
+	// SetPassword stores a new password hash, clears any required reset, and
+	// revokes sessions issued up to at.
+	SetPassword(ctx context.Context, userID int64, passwordHash string, at time.Time) error
+	// RevokeSessions revokes every session of the user issued up to at.
+	RevokeSessions(ctx context.Context, userID int64, at time.Time) error
+}
+
+// IntegrationStore persists callbacks received from external providers and
+// the provider events that have been applied.
+type IntegrationStore interface {
+	RecordInboundDelivery(ctx context.Context, delivery models.InboundDelivery) (models.InboundDelivery, error)
+	FindInboundDelivery(ctx context.Context, id int64) (models.InboundDelivery, error)
+	// ListInboundDeliveries returns the newest deliveries matching filter first.
+	ListInboundDeliveries(ctx context.Context, filter models.InboundDeliveryFilter, limit int) ([]models.InboundDelivery, error)
+	// UpdateInboundDelivery records the outcome of one processing attempt.
+	UpdateInboundDelivery(ctx context.Context, id int64, status, errMsg string, at time.Time) error
+	// ClaimInboundEvent marks the provider's event as applied by deliveryID. It
+	// returns ErrAlreadyExists when the event was already claimed.
+	ClaimInboundEvent(ctx context.Context, provider, eventID string, deliveryID int64) error
+}
+
+// RoleStore manages roles, permissions, which roles grant which permissions,
+// and per-user overrides. Users are loaded with their role's permissions plus
+// allowed overrides, minus denied ones.
+type RoleStore interface {
+	// ListRoles returns every role with its permission names, by ID.
+	ListRoles(ctx context.Context) ([]models.Role, error)
+	FindRole(ctx context.Context, id int64) (models.Role, error)
+	// CreateRole stores the role's name and description; it returns
+	// ErrAlreadyExists when the name is taken.
+	CreateRole(ctx context.Context, role models.Role) (models.Role, error)
+	// UpdateRole saves the role's name and description, moving its users to a new name.
+	UpdateRole(ctx context.Context, role models.Role) (models.Role, error)
+	// DeleteRole returns ErrInUse while any user has the role.
+	DeleteRole(ctx context.Context, id int64) error
+	ListPermissions(ctx context.Context) ([]models.Permission, error)
+	FindPermission(ctx context.Context, id int64) (models.Permission, error)
+	CreatePermission(ctx context.Context, permission models.Permission) (models.Permission, error)
+	UpdatePermission(ctx context.Context, permission models.Permission) (models.Permission, error)
+	// DeletePermission also revokes it from every role.
+	DeletePermission(ctx context.Context, id int64) error
+	// GrantPermission and RevokePermission are no-ops when already applied.
+	GrantPermission(ctx context.Context, roleID, permissionID int64) error
+	RevokePermission(ctx context.Context, roleID, permissionID int64) error
+	// ListPermissionOverrides returns a user's overrides by permission ID.
+	ListPermissionOverrides(ctx context.Context, userID int64) ([]models.PermissionOverride, error)
+	// SetPermissionOverride creates or replaces the user's override for the permission.
+	SetPermissionOverride(ctx context.Context, override models.PermissionOverride) (models.PermissionOverride, error)
+	DeletePermissionOverride(ctx context.Context, userID, permissionID int64) error
+}
+
+// WalletStore keeps the balance ledger and the keys of the internal
+// operations already applied to it.
+type WalletStore interface {
+	// ApplyTransaction moves the user's balance by the entry's amount and
+	// records it, returning the entry with its ID and resulting balance, and
+	// bumps the user's version. A debit that would leave the balance below
+	// what the user's active holds reserve fails with ErrInsufficientFunds.
+	// The entry is also posted to the double-entry ledger, against
+	// models.CounterAccount, in the same transaction.
+	ApplyTransaction(ctx context.Context, entry models.Transaction) (models.Transaction, error)
+	// FindTransaction also finds archived entries.
+	FindTransaction(ctx context.Context, id int64) (models.Transaction, error)
+	// ListTransactions returns every entry of the user's ledger, archived ones
+	// included, oldest first.
+	ListTransactions(ctx context.Context, userID int64) ([]models.Transaction, error)
+	// ListTransactionsAfter returns up to limit of the user's entries with IDs
+	// above after, oldest first. Archived entries are left out.
+	ListTransactionsAfter(ctx context.Context, userID, after int64, limit int) ([]models.Transaction, error)
+	// LastTransactionID returns the ID of the user's newest entry that is not
+	// archived, or 0 if there is none.
+	LastTransactionID(ctx context.Context, userID int64) (int64, error)
+	// ClaimOperation records the operation's kind and key. It returns
+	// ErrAlreadyExists when the key was claimed before.
+	ClaimOperation(ctx context.Context, op models.Operation) error
+	FindOperation(ctx context.Context, kind, key string) (models.Operation, error)
+	// CompleteOperation links a claimed operation to the ledger entry it produced.
+	CompleteOperation(ctx context.Context, kind, key string, transactionID int64) error
+	// SumTransactions totals the amounts of the user's entries with the given
+	// reason created at or after since.
+	SumTransactions(ctx context.Context, userID int64, reason string, since time.Time) (float64, error)
+	// CheckUserVersion locks the user's row until the unit of work ends and
+	// returns ErrVersionConflict unless the user is at version.
+	CheckUserVersion(ctx context.Context, userID, version int64) error
+}
+
+// HoldStore reserves funds for bets placed but not yet settled. Each method
+// locks the user's row, so holds and ledger debits cannot overspend the
+// balance between them.
+type HoldStore interface {
+	// CreateHold reserves the hold's amount. It returns ErrInsufficientFunds
+	// when the balance less the user's active holds does not cover it,
+	// ErrNotFound when the user does not exist and ErrAlreadyExists when the
+	// user already has a hold with the reference.
+	CreateHold(ctx context.Context, hold models.WalletHold) (models.WalletHold, error)
+	FindHold(ctx context.Context, id int64) (models.WalletHold, error)
+	// CaptureHold debits an active hold's amount to the ledger with reason and
+	// the hold's reference, posting it like ApplyTransaction does, and marks
+	// it captured with the entry. It returns
+	// ErrNotFound when the hold does not exist or is no longer active.
+	CaptureHold(ctx context.Context, id int64, reason string) (models.WalletHold, error)
+	// ReleaseHold frees an active hold's amount. It returns ErrNotFound when
+	// the hold does not exist or is no longer active.
+	ReleaseHold(ctx context.Context, id int64) (models.WalletHold, error)
+	// HeldBalance totals the user's active holds.
+	HeldBalance(ctx context.Context, userID int64) (float64, error)
+}
+
+// LedgerStore reads the double-entry ledger. Every wallet account is opened
+// with the balance its user had, against equity, the first time it is
+// posted to, and every posting balances or is refused with
+// ErrUnbalancedJournal.
+type LedgerStore interface {
+	// TrialBalance totals the debits and credits posted to each account,
+	// player wallets folded into one line, ordered by account code.
+	TrialBalance(ctx context.Context) ([]models.TrialBalanceLine, error)
+}
+
+// JackpotStore keeps jackpot pots. A pot is created empty by its first
+// contribution and mirrors its ledger account: contributions are posted from
+// the house and wins are wallet entries against the pot.
+type JackpotStore interface {
+	// ContributeJackpot adds amount to the named pot in one atomic update
+	// and posts it from the house, with the stake's reference.
+	ContributeJackpot(ctx context.Context, name string, amount float64, reference string) (models.Jackpot, error)
+	// ListJackpots returns every pot, ordered by name. The returned pots
+	// carry no contribution percent.
+	ListJackpots(ctx context.Context) ([]models.Jackpot, error)
+	// EmptyJackpot locks the named pot until the transaction ends, empties
+	// it and returns what it held, or 0 for a pot never contributed to. The
+	// caller pays that amount out in the same transaction.
+	EmptyJackpot(ctx context.Context, name string) (float64, error)
+}
+
+// TournamentStore keeps tournaments and their entrants.
+type TournamentStore interface {
+	CreateTournament(ctx context.Context, t models.Tournament) (models.Tournament, error)
+	FindTournament(ctx context.Context, id int64) (models.Tournament, error)
+	// ListTournaments returns up to limit tournaments, latest start first.
+	ListTournaments(ctx context.Context, limit int) ([]models.Tournament, error)
+	// ListEndedTournaments returns the open tournaments that ended by at.
+	ListEndedTournaments(ctx context.Context, at time.Time) ([]models.Tournament, error)
+	// EnterTournament adds the user and fee to an open tournament. It
+	// returns ErrNotFound when the tournament is not open and
+	// ErrAlreadyExists when the user has entered it.
+	EnterTournament(ctx context.Context, tournamentID, userID int64, fee float64) (models.TournamentEntry, error)
+	// RecordTournamentScore keeps score as the entrant's best if it beats
+	// the one they have, and returns their entry. It returns ErrNotFound
+	// when the user has not entered the tournament.
+	RecordTournamentScore(ctx context.Context, tournamentID, userID int64, score float64, at time.Time) (models.TournamentEntry, error)
+	// ListTournamentEntries returns the entrants, best score first; ties go
+	// to whoever reached the score first and entrants without one come last.
+	ListTournamentEntries(ctx context.Context, tournamentID int64) ([]models.TournamentEntry, error)
+	// CloseTournament marks an open tournament that ended by at closed. Inside
+	// a unit of work the row stays locked until it ends, so entries cannot
+	// land while the prizes are paid. It returns ErrNotFound when there is no
+	// such tournament, e.g. because another instance closed it.
+	CloseTournament(ctx context.Context, id int64, at time.Time) (models.Tournament, error)
+	// SetTournamentResult records an entrant's final rank and prize.
+	SetTournamentResult(ctx context.Context, tournamentID, userID int64, rank int, prize float64) error
+}
+
+// MessageStore keeps users' inbox messages.
+type MessageStore interface {
+	// CreateMessage stores a message in one user's inbox. It returns
+	// ErrNotFound when there is no such user.
+	CreateMessage(ctx context.Context, m models.Message) (models.Message, error)
+	// BroadcastMessage stores a copy of m in the inbox of every user with one
+	// of roles, or of every user when roles is empty, leaving out accounts
+	// merged into another. It returns how many users got a copy.
+	BroadcastMessage(ctx context.Context, m models.Message, roles []string) (int, error)
+	// ListMessages returns up to limit of the user's messages, newest first,
+	// only the unread ones when unreadOnly is set.
+	ListMessages(ctx context.Context, userID int64, unreadOnly bool, limit int) ([]models.Message, error)
+	// ListMessagesAfter returns up to limit of the user's messages with IDs
+	// above after, oldest first.
+	ListMessagesAfter(ctx context.Context, userID, after int64, limit int) ([]models.Message, error)
+	// LastMessageID returns the ID of the user's newest message, or 0.
+	LastMessageID(ctx context.Context, userID int64) (int64, error)
+	CountUnreadMessages(ctx context.Context, userID int64) (int, error)
+	// MarkMessagesRead marks the user's unread messages with the given IDs,
+	// or all of them when ids is empty, read at at, and returns how many it
+	// marked.
+	MarkMessagesRead(ctx context.Context, userID int64, ids []int64, at time.Time) (int, error)
+}
+
+// SupportStore keeps support tickets, their threads and attachments.
+type SupportStore interface {
+	// CreateTicket stores the ticket with body as the first reply of its
+	// thread, written by the ticket's user.
+	CreateTicket(ctx context.Context, t models.SupportTicket, body string) (models.SupportTicket, error)
+	FindTicket(ctx context.Context, id int64) (models.SupportTicket, error)
+	// ListUserTickets returns up to limit of the user's tickets, most
+	// recently updated first.
+	ListUserTickets(ctx context.Context, userID int64, limit int) ([]models.SupportTicket, error)
+	// ListTicketQueue returns up to limit tickets with one of statuses, high
+	// priority first and then the longest waiting.
+	ListTicketQueue(ctx context.Context, statuses []string, limit int) ([]models.SupportTicket, error)
+	// AddTicketReply appends the reply to the thread of a ticket with one of
+	// the from statuses and moves the ticket to status. It returns
+	// ErrNotFound when there is no such ticket.
+	AddTicketReply(ctx context.Context, reply models.TicketReply, from []string, status string) (models.TicketReply, error)
+	// ListTicketReplies returns the ticket's thread, oldest first.
+	ListTicketReplies(ctx context.Context, ticketID int64) ([]models.TicketReply, error)
+	// SetTicketStatus moves a ticket with one of the from statuses to status.
+	// It returns ErrNotFound when there is no such ticket.
+	SetTicketStatus(ctx context.Context, id int64, from []string, status string) (models.SupportTicket, error)
+	AddTicketAttachment(ctx context.Context, a models.TicketAttachment) (models.TicketAttachment, error)
+	// ListTicketAttachments returns the ticket's attachments, oldest first.
+	ListTicketAttachments(ctx context.Context, ticketID int64) ([]models.TicketAttachment, error)
+}
+
+// ArchiveStore moves cold rows out of the hot tables. Lookups that must see
+// archived rows, such as FindTransaction, read from both.
+type ArchiveStore interface {
+	// ArchiveRecords moves up to limit of the dataset's rows created before
+	// cutoff into its archive, oldest first, and returns how many moved. Rows
+	// of users under a legal hold covering the dataset stay where they are.
+	ArchiveRecords(ctx context.Context, dataset string, before time.Time, limit int) (int, error)
+}
+
+// Repositories exposes the stores that can take part in a unit of work.
+type Repositories interface {
+	UserStore
+	NoteStore
+	RateLimitStore
+	ConfigHistoryStore
+	StatsStore
+	WebhookStore
+	SecurityStore
+	IntegrationStore
+	RoleStore
+	WalletStore
+	HoldStore
+	LedgerStore
+	JackpotStore
+	TournamentStore
+	MessageStore
+	SupportStore
+	ArchiveStore
+	OnboardingStore
+	SpectatorStore
+	DataExportStore
+	ReconciliationStore
+	LeaderboardStore
+	OAuthStore
+	IPRiskStore
+	PromoStore
+	ChallengeStore
+	GameSessionStore
+	DatabaseInsightsStore
+	LegalHoldStore
+	FeatureFlagStore
+	ReservedNameStore
+	BulkJobStore
+	APIKeyStore
+	ImpersonationStore
+	AccountMergeStore
+	WithdrawalStore
+	GamingLimitStore
+	ReportStore
+	OutboxStore
+}
+
+// BulkJobStore keeps the state and checkpoints of bulk jobs. Updates made on
+// behalf of a running job name its owner and return ErrNotFound once the job
+// is no longer running or another instance has taken it over.
+type BulkJobStore interface {
+	CreateBulkJob(ctx context.Context, job models.BulkJob) (models.BulkJob, error)
+	FindBulkJob(ctx context.Context, id int64) (models.BulkJob, error)
+	// ListBulkJobs returns up to limit jobs, newest first.
+	ListBulkJobs(ctx context.Context, limit int) ([]models.BulkJob, error)
+	// SaveBulkJobProgress records a finished batch: the checkpoint to resume
+	// from and the items it processed.
+	SaveBulkJobProgress(ctx context.Context, id int64, owner string, checkpoint []byte, processed int64, at time.Time) error
+	// FinishBulkJob moves a running job to a final status, or to failed with
+	// the error that stopped it.
+	FinishBulkJob(ctx context.Context, id int64, owner, status, errMsg string, at time.Time) error
+	// TransitionBulkJob moves a job whose status is one of from to status on
+	// behalf of owner, e.g. to resume or cancel it. Moving to running clears
+	// the error and the finish time. It returns ErrNotFound when the job does
+	// not exist or is in another status.
+	TransitionBulkJob(ctx context.Context, id int64, from []string, status, owner string, at time.Time) (models.BulkJob, error)
+	// ClaimStaleBulkJobs hands owner the running jobs last updated before
+	// staleBefore, whose instance has presumably stopped, and returns them.
+	ClaimStaleBulkJobs(ctx context.Context, owner string, staleBefore, at time.Time) ([]models.BulkJob, error)
+}
+
+// APIKeyStore keeps the API keys of machine clients.
+type APIKeyStore interface {
+	// ListAPIKeys returns every key, newest first, including revoked ones.
+	ListAPIKeys(ctx context.Context) ([]models.APIKey, error)
+	// CreateAPIKey returns ErrNotFound when the creator does not exist.
+	CreateAPIKey(ctx context.Context, key models.APIKey) (models.APIKey, error)
+	FindAPIKeyByHash(ctx context.Context, keyHash string) (models.APIKey, error)
+	// RevokeAPIKey returns ErrNotFound unless the key exists and is not
+	// revoked yet.
+	RevokeAPIKey(ctx context.Context, id, revokedBy int64) (models.APIKey, error)
+}
+
+// ImpersonationStore keeps the record of staff acting as players.
+type ImpersonationStore interface {
+	// ListImpersonations returns up to limit impersonations of userID, or of
+	// anyone when it is 0, newest first, including revoked ones.
+	ListImpersonations(ctx context.Context, userID int64, limit int) ([]models.Impersonation, error)
+	// CreateImpersonation returns ErrNotFound when the user or the staff
+	// member does not exist.
+	CreateImpersonation(ctx context.Context, imp models.Impersonation) (models.Impersonation, error)
+	FindImpersonationByToken(ctx context.Context, tokenID string) (models.Impersonation, error)
+	// RevokeImpersonation returns ErrNotFound unless the impersonation exists
+	// and is not revoked yet.
+	RevokeImpersonation(ctx context.Context, id, revokedBy int64) (models.Impersonation, error)
+}
+
+// AccountMergeStore folds duplicate accounts into the account they duplicate.
+type AccountMergeStore interface {
+	// MergeAccounts moves merge.DuplicateID's ledger, operations, login
+	// history and devices to merge.UserID, marks the duplicate merged with its
+	// sessions revoked, and records the merge with the counts of what was
+	// moved. Moved ledger entries keep their balance_after and are marked with
+	// the duplicate they came from; the balance is moved by the caller. It
+	// returns ErrNotFound when either user does not exist and
+	// ErrVersionConflict when either was already merged.
+	MergeAccounts(ctx context.Context, merge models.AccountMerge) (models.AccountMerge, error)
+}
+
+// WithdrawalStore keeps players' withdrawal requests.
+type WithdrawalStore interface {
+	// CreateWithdrawal returns ErrNotFound when the user does not exist.
+	CreateWithdrawal(ctx context.Context, w models.WithdrawalRequest) (models.WithdrawalRequest, error)
+	FindWithdrawal(ctx context.Context, id int64) (models.WithdrawalRequest, error)
+	FindWithdrawalByReference(ctx context.Context, provider, reference string) (models.WithdrawalRequest, error)
+	// ListWithdrawals returns up to limit requests matching filter, newest first.
+	ListWithdrawals(ctx context.Context, filter models.WithdrawalFilter, limit int) ([]models.WithdrawalRequest, error)
+	// TransitionWithdrawal moves a request whose status is one of from to
+	// status with note, recording reviewedBy unless it is nil. It returns
+	// ErrNotFound when the request does not exist or is in another status.
+	TransitionWithdrawal(ctx context.Context, id int64, from []string, status string, reviewedBy *int64, note string, at time.Time) (models.WithdrawalRequest, error)
+}
+
+// GamingLimitStore keeps players' responsible gaming limits.
+type GamingLimitStore interface {
+	// FindGamingLimits returns ErrNotFound for players who never set any.
+	FindGamingLimits(ctx context.Context, userID int64) (models.GamingLimits, error)
+	// SaveGamingLimits creates or replaces the player's limits. It returns
+	// ErrNotFound when the user does not exist.
+	SaveGamingLimits(ctx context.Context, limits models.GamingLimits) (models.GamingLimits, error)
+}
+
+// ReportStore keeps generated operator reports and computes their figures.
+type ReportStore interface {
+	// CreateOperatorReport returns ErrAlreadyExists for a second scheduled
+	// report of the same period, so instances sharing a schedule generate it
+	// once. Ad-hoc reports, which name who requested them, never clash.
+	CreateOperatorReport(ctx context.Context, report models.OperatorReport) (models.OperatorReport, error)
+	FindOperatorReport(ctx context.Context, id int64) (models.OperatorReport, error)
+	// ListOperatorReports returns up to limit reports, newest first.
+	ListOperatorReports(ctx context.Context, limit int) ([]models.OperatorReport, error)
+	// FinishOperatorReport records the outcome of a pending report. It
+	// returns ErrNotFound when the report is not pending.
+	FinishOperatorReport(ctx context.Context, id int64, status string, metrics *models.ReportMetrics, errMsg string, at time.Time) error
+	// ReportMetrics totals the activity in [from, to).
+	ReportMetrics(ctx context.Context, from, to time.Time) (models.ReportMetrics, error)
+}
+
+// OutboxStore holds domain events written in the same transaction as the
+// changes they describe until they are published.
+type OutboxStore interface {
+	AddOutboxEvent(ctx context.Context, event models.OutboxEvent) (models.OutboxEvent, error)
+	// PendingOutboxEvents returns up to limit unsent events, oldest first.
+	// Inside a unit of work the rows stay locked until it ends and rows locked
+	// by another one are skipped, so relays on several instances do not
+	// publish the same event.
+	PendingOutboxEvents(ctx context.Context, limit int) ([]models.OutboxEvent, error)
+	MarkOutboxEventsSent(ctx context.Context, ids []int64, at time.Time) error
+	// DeleteSentOutboxEvents removes up to limit events sent before the
+	// cutoff and returns how many it removed.
+	DeleteSentOutboxEvents(ctx context.Context, before time.Time, limit int) (int, error)
+}
+
+// ReservedNameStore keeps the reserved and blocked username entries added
+// through the admin API.
+type ReservedNameStore interface {
+	// ListReservedNames returns the stored entries by name.
+	ListReservedNames(ctx context.Context) ([]models.ReservedName, error)
+	// AddReservedName returns ErrAlreadyExists when the name is listed.
+	AddReservedName(ctx context.Context, name models.ReservedName) (models.ReservedName, error)
+	// DeleteReservedName returns ErrNotFound when the name is not listed.
+	DeleteReservedName(ctx context.Context, name string) error
+}
+
+// FeatureFlagStore keeps the feature flags set through the admin API.
+type FeatureFlagStore interface {
+	// ListFeatureFlags returns the stored flags by name.
+	ListFeatureFlags(ctx context.Context) ([]models.FeatureFlag, error)
+	// SetFeatureFlag creates or replaces the flag's stored state.
+	SetFeatureFlag(ctx context.Context, flag models.FeatureFlag) (models.FeatureFlag, error)
+}
+
+// LegalHoldStore tracks legal holds on user data.
+type LegalHoldStore interface {
+	PlaceLegalHold(ctx context.Context, hold models.LegalHold) (models.LegalHold, error)
+	// ListLegalHolds returns the user's holds, released ones included, newest first.
+	ListLegalHolds(ctx context.Context, userID int64) ([]models.LegalHold, error)
+	// ReleaseLegalHold ends one of the user's holds. It returns ErrNotFound
+	// unless the hold is active.
+	ReleaseLegalHold(ctx context.Context, userID, id, releasedBy int64) (models.LegalHold, error)
+}
+
+// ChallengeStore persists step-up challenges.
+type ChallengeStore interface {
+	CreateChallenge(ctx context.Context, challenge models.Challenge) (models.Challenge, error)
+	FindChallenge(ctx context.Context, id int64) (models.Challenge, error)
+	// FailChallenge counts a wrong answer to a challenge not yet passed and
+	// returns it. It returns ErrNotFound when there is no such challenge.
+	FailChallenge(ctx context.Context, id int64) (models.Challenge, error)
+	// PassChallenge marks the challenge passed, stores the hash of the token
+	// that redeems it and moves its expiry to expiresAt. It returns
+	// ErrNotFound unless the challenge was pending.
+	PassChallenge(ctx context.Context, id int64, tokenHash string, at, expiresAt time.Time) error
+	// UseChallenge redeems the user's passed challenge for action whose token
+	// hashes to tokenHash. It returns ErrNotFound when there is none, or it
+	// expired at now or was already used.
+	UseChallenge(ctx context.Context, userID int64, action, tokenHash string, now time.Time) (models.Challenge, error)
+}
+
+// GameSessionStore persists the game sessions players launch.
+type GameSessionStore interface {
+	CreateGameSession(ctx context.Context, session models.GameSession) (models.GameSession, error)
+	// FindGameSession returns the session whose token hashes to tokenHash.
+	FindGameSession(ctx context.Context, tokenHash string) (models.GameSession, error)
+	// StartGameSession marks the session started and moves its expiry to
+	// expiresAt. It returns ErrNotFound unless the session was not started yet.
+	StartGameSession(ctx context.Context, id int64, at, expiresAt time.Time) error
+}
+
+// PromoStore persists promo codes and their redemptions.
+type PromoStore interface {
+	// ListPromoCodes returns every code, newest first.
+	ListPromoCodes(ctx context.Context) ([]models.PromoCode, error)
+	FindPromoCode(ctx context.Context, id int64) (models.PromoCode, error)
+	FindPromoCodeByCode(ctx context.Context, code string) (models.PromoCode, error)
+	// CreatePromoCode returns ErrAlreadyExists when the code is taken.
+	CreatePromoCode(ctx context.Context, code models.PromoCode) (models.PromoCode, error)
+	// UpdatePromoCode saves the code's limits, roles, expiry and active flag.
+	UpdatePromoCode(ctx context.Context, code models.PromoCode) (models.PromoCode, error)
+	// ClaimPromoRedemption counts one more redemption of the code and, inside
+	// a unit of work, holds it until commit so concurrent redemptions queue.
+	// It returns ErrLimitReached when MaxRedemptions is used up.
+	ClaimPromoRedemption(ctx context.Context, id int64) (models.PromoCode, error)
+	// CountPromoRedemptions returns how many times the user redeemed the code.
+	CountPromoRedemptions(ctx context.Context, codeID, userID int64) (int, error)
+	CreatePromoRedemption(ctx context.Context, redemption models.PromoRedemption) (models.PromoRedemption, error)
+	// ListPromoRedemptions returns up to limit redemptions of the campaign's
+	// codes, newest first.
+	ListPromoRedemptions(ctx context.Context, campaign string, limit int) ([]models.PromoRedemption, error)
+	// ListPromoCampaignReports summarizes every campaign, by name.
+	ListPromoCampaignReports(ctx context.Context) ([]models.PromoCampaignReport, error)
+}
+
+// IPRiskStore persists IP risk policies and the risky requests they acted on.
+type IPRiskStore interface {
+	// ListIPRiskPolicies returns every policy ordered by tenant and country.
+	ListIPRiskPolicies(ctx context.Context) ([]models.IPRiskPolicy, error)
+	FindIPRiskPolicy(ctx context.Context, tenant, country string) (models.IPRiskPolicy, error)
+	// UpsertIPRiskPolicy creates or replaces the policy for its tenant and country.
+	UpsertIPRiskPolicy(ctx context.Context, policy models.IPRiskPolicy) (models.IPRiskPolicy, error)
+	DeleteIPRiskPolicy(ctx context.Context, tenant, country string) error
+	RecordIPRiskEvent(ctx context.Context, event models.IPRiskEvent) (models.IPRiskEvent, error)
+	// ListIPRiskEvents returns the most recent events, newest first.
+	ListIPRiskEvents(ctx context.Context, limit int) ([]models.IPRiskEvent, error)
+}
+
+// OAuthStore persists OpenID Connect clients and authorization codes.
+type OAuthStore interface {
+	// ListOAuthClients returns every client ordered by name.
+	ListOAuthClients(ctx context.Context) ([]models.OAuthClient, error)
+	FindOAuthClient(ctx context.Context, id string) (models.OAuthClient, error)
+	// CreateOAuthClient returns ErrAlreadyExists when the ID is taken.
+	CreateOAuthClient(ctx context.Context, client models.OAuthClient) (models.OAuthClient, error)
+	DeleteOAuthClient(ctx context.Context, id string) error
+	CreateAuthorizationCode(ctx context.Context, code models.AuthorizationCode) error
+	// ConsumeAuthorizationCode deletes an unexpired code and returns it, so a
+	// code is redeemed at most once; anything else is ErrNotFound.
+	ConsumeAuthorizationCode(ctx context.Context, codeHash string, now time.Time) (models.AuthorizationCode, error)
+}
+
+// LeaderboardStore ranks players from periodically refreshed snapshots.
+// Players who hid their public activity are left out.
+type LeaderboardStore interface {
+	// RefreshLeaderboards recomputes the snapshots every ranking reads.
+	RefreshLeaderboards(ctx context.Context) error
+	// ListLeaderboard returns up to limit players with a positive value for
+	// the metric and window, best first.
+	ListLeaderboard(ctx context.Context, metric, window string, limit int) ([]models.LeaderboardEntry, error)
+	// FindLeaderboardEntry returns the user's standing, or ErrNotFound when
+	// they are not ranked.
+	FindLeaderboardEntry(ctx context.Context, metric, window string, userID int64) (models.LeaderboardEntry, error)
+}
+
+// ReconciliationStore checks stored balances against the ledger and keeps
+// the results.
+type ReconciliationStore interface {
+	// ReconcileBalances recomputes the balance of every user with ledger
+	// entries, archived ones included, from a single snapshot. It returns how
+	// many users were checked and those whose stored balance differs, by ID.
+	ReconcileBalances(ctx context.Context) (int, []models.BalanceDiscrepancy, error)
+	CreateReconciliationReport(ctx context.Context, report models.ReconciliationReport) (models.ReconciliationReport, error)
+	// ListReconciliationReports returns up to limit reports, newest first.
+	ListReconciliationReports(ctx context.Context, limit int) ([]models.ReconciliationReport, error)
+}
+
+// DatabaseInsightsStore reads the database's own workload statistics and
+// keeps the index advisor's reports.
+type DatabaseInsightsStore interface {
+	// QueryStats returns up to limit statements of this database with the
+	// most total execution time. ok is false when pg_stat_statements is not
+	// available.
+	QueryStats(ctx context.Context, limit int) (stats []models.QueryStat, ok bool, err error)
+	// TableScanStats returns the scan counters of every table, with its
+	// columns and the leading columns of its indexes.
+	TableScanStats(ctx context.Context) ([]models.TableScanStat, error)
+	CreateDatabaseInsightsReport(ctx context.Context, report models.DatabaseInsightsReport) (models.DatabaseInsightsReport, error)
+	// LatestDatabaseInsightsReport returns ErrNotFound before the first run.
+	LatestDatabaseInsightsReport(ctx context.Context) (models.DatabaseInsightsReport, error)
+}
+
+// OnboardingStore persists per-tenant onboarding journeys and each user's
+// progress through them.
+type OnboardingStore interface {
+	// ListOnboardingJourneys returns every journey ordered by tenant.
+	ListOnboardingJourneys(ctx context.Context) ([]models.OnboardingJourney, error)
+	FindOnboardingJourney(ctx context.Context, tenant string) (models.OnboardingJourney, error)
+	// SaveOnboardingJourney creates or replaces the tenant's journey.
+	SaveOnboardingJourney(ctx context.Context, journey models.OnboardingJourney) (models.OnboardingJourney, error)
+	DeleteOnboardingJourney(ctx context.Context, tenant string) error
+	ListOnboardingProgress(ctx context.Context, userID int64) ([]models.OnboardingProgress, error)
+	// CompleteOnboardingStep marks the step complete at at. It returns
+	// ErrAlreadyExists when the step was already complete.
+	CompleteOnboardingStep(ctx context.Context, userID int64, stepKey string, at time.Time) error
+	// ClaimOnboardingNudge records a nudge for the step at at unless one was
+	// recorded after since, in which case it returns ErrAlreadyExists.
+	ClaimOnboardingNudge(ctx context.Context, userID int64, stepKey string, at, since time.Time) error
+}
+
+// SpectatorStore backs the public activity feeds and the privacy settings
+// that control how users appear in them.
+type SpectatorStore interface {
+	// RecentBigWins returns the newest bet settlements of at least minAmount,
+	// skipping users whose public activity is hidden.
+	RecentBigWins(ctx context.Context, minAmount float64, limit int) ([]models.BigWin, error)
+	// FindPrivacySettings returns the defaults for users who never saved any,
+	// and ErrNotFound for unknown users.
+	FindPrivacySettings(ctx context.Context, userID int64) (models.PrivacySettings, error)
+	SavePrivacySettings(ctx context.Context, settings models.PrivacySettings) (models.PrivacySettings, error)
+}
+
+// DataExportStore tracks users' requests for a copy of their data.
+type DataExportStore interface {
+	CreateDataExport(ctx context.Context, export models.DataExport) (models.DataExport, error)
+	FindDataExport(ctx context.Context, id int64) (models.DataExport, error)
+	// ListDataExports returns the user's exports, newest first.
+	ListDataExports(ctx context.Context, userID int64) ([]models.DataExport, error)
+	// FinishDataExport records the outcome of a pending export.
+	FinishDataExport(ctx context.Context, id int64, status, blobKey string, at time.Time) error
+}
+
+// UnitOfWork runs several store operations atomically. fn receives repositories
+// bound to one transaction, which commits when fn returns nil and rolls back otherwise.
+type UnitOfWork interface {
+	WithTx(ctx context.Context, fn func(tx Repositories) error) error
+}
+
+// PoolStats is a snapshot of one database connection pool.
+type PoolStats struct {
+	Name                 string        `json:"name"`
+	MaxConns             int32         `json:"max_conns"`
+	TotalConns           int32         `json:"total_conns"`
+	IdleConns            int32         `json:"idle_conns"`
+	AcquiredConns        int32         `json:"acquired_conns"`
+	AcquireCount         int64         `json:"acquire_count"`
+	EmptyAcquireCount    int64         `json:"empty_acquire_count"`
+	CanceledAcquireCount int64         `json:"canceled_acquire_count"`
+	AcquireDuration      time.Duration `json:"-"`
+}
+
+// Circuit breaker states.
+const (
+	BreakerClosed   = "closed"
+	BreakerOpen     = "open"
+	BreakerHalfOpen = "half_open"
+)
+
+// BreakerState is a snapshot of the circuit breaker in front of the database.
+type BreakerState struct {
+	State               string     `json:"state"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	OpenedAt            *time.Time `json:"opened_at,omitempty"`
+}
+
+// HealthChecker reports database reachability and connection usage.
+type HealthChecker interface {
+	Ping(ctx context.Context) error
+	PoolStats() []PoolStats
+	Breaker() BreakerState
+}
+
+// Store is the full persistence surface the server is wired against.
+type Store interface {
+	Repositories
+	UnitOfWork
+	HealthChecker
 }
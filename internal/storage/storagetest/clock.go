@@ -0,0 +1,60 @@
+// Package storagetest provides deterministic fakes for tests.
+package storagetest
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// FakeClock is a clock.Clock that only moves when told to.
+type FakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+// NewFakeClock returns a clock frozen at start.
+func NewFakeClock(start time.Time) *FakeClock {
+	return &FakeClock{now: start}
+}
+
+// Now returns the frozen time.
+func (c *FakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// Advance moves the clock forward by d.
+func (c *FakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// Set moves the clock to t.
+func (c *FakeClock) Set(t time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = t
+}
+
+// SequentialIDs is a clock.IDGenerator returning prefix-1, prefix-2, ...
+type SequentialIDs struct {
+	Prefix string
+
+	mu   sync.Mutex
+	next int
+}
+
+// NewID returns the next identifier in the sequence.
+func (g *SequentialIDs) NewID() string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.next++
+	prefix := g.Prefix
+	if prefix == "" {
+		prefix = "id"
+	}
+	return fmt.Sprintf("%s-%d", prefix, g.next)
+}
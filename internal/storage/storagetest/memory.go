@@ -0,0 +1,3276 @@
+package storagetest
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"maps"
+	"math"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hongminglow/all-in-be/internal/clock"
+	"github.com/hongminglow/all-in-be/internal/models"
+	"github.com/hongminglow/all-in-be/internal/storage"
+)
+
+// seedPermissions and seedRoles mirror the rows seeded by the Postgres migrations.
+var seedPermissions = []models.Permission{
+	{ID: 1, PermissionName: models.PermGamePlay, PermissionDescription: "Play games"},
+	{ID: 2, PermissionName: models.PermBonusClaim, PermissionDescription: "Claim bonuses"},
+	{ID: 3, PermissionName: models.PermSupportPriority, PermissionDescription: "Priority support"},
+	{ID: 4, PermissionName: models.PermNotesRead, PermissionDescription: "Read internal user notes"},
+	{ID: 5, PermissionName: models.PermNotesWrite, PermissionDescription: "Write internal user notes"},
+	{ID: 6, PermissionName: models.PermConfigManage, PermissionDescription: "Manage runtime configuration"},
+	{ID: 7, PermissionName: models.PermStatsRead, PermissionDescription: "View operator statistics"},
+	{ID: 8, PermissionName: models.PermSecurityRead, PermissionDescription: "View account security cases"},
+	{ID: 9, PermissionName: models.PermUsersRead, PermissionDescription: "Search user accounts"},
+	{ID: 10, PermissionName: models.PermIntegrations, PermissionDescription: "Inspect and replay provider callbacks"},
+	{ID: 11, PermissionName: models.PermRolesManage, PermissionDescription: "Manage roles and permissions"},
+	{ID: 12, PermissionName: models.PermUserOverrides, PermissionDescription: "Grant or revoke permissions for individual users"},
+	{ID: 13, PermissionName: models.PermUsersLock, PermissionDescription: "Force password resets on compromised accounts"},
+	{ID: 14, PermissionName: models.PermLegalHold, PermissionDescription: "Place and release legal holds on user data"},
+	{ID: 15, PermissionName: models.PermBalanceAdjust, PermissionDescription: "Credit or debit user balances manually"},
+	{ID: 16, PermissionName: models.PermWithdrawalsReview, PermissionDescription: "Approve or reject withdrawal requests"},
+	{ID: 17, PermissionName: models.PermUsersWrite, PermissionDescription: "Edit user profiles"},
+	{ID: 18, PermissionName: models.PermUsersImpersonate, PermissionDescription: "Act as a player to reproduce their issues"},
+	{ID: 19, PermissionName: models.PermUsersMerge, PermissionDescription: "Merge duplicate player accounts"},
+	{ID: 20, PermissionName: models.PermMessagesSend, PermissionDescription: "Send inbox messages and announcements to players"},
+	{ID: 21, PermissionName: models.PermSupportManage, PermissionDescription: "Work the support ticket queue"},
+	{ID: 22, PermissionName: models.PermTournamentsScore, PermissionDescription: "Report tournament scores"},
+}
+
+var seedRoles = []models.Role{
+	{ID: 1, RoleName: models.NormalUser, RoleDescription: "Normal User", Permissions: []string{models.PermGamePlay}},
+	{ID: 2, RoleName: models.VIPUser, RoleDescription: "VIP User", Permissions: []string{models.PermGamePlay, models.PermBonusClaim}},
+	{ID: 3, RoleName: models.VVIPUser, RoleDescription: "VVIP User", Permissions: []string{models.PermGamePlay, models.PermBonusClaim, models.PermSupportPriority}},
+	{ID: 4, RoleName: models.StaffUser, RoleDescription: "Support Staff", Permissions: []string{
+		models.PermNotesRead, models.PermNotesWrite, models.PermStatsRead, models.PermSecurityRead, models.PermUsersRead,
+		models.PermUserOverrides, models.PermUsersLock, models.PermUsersImpersonate, models.PermMessagesSend,
+		models.PermSupportManage,
+	}},
+	{ID: 5, RoleName: models.AdminUser, RoleDescription: "Administrator", Permissions: []string{
+		models.PermNotesRead, models.PermNotesWrite, models.PermConfigManage, models.PermStatsRead, models.PermSecurityRead,
+		models.PermUsersRead, models.PermIntegrations, models.PermRolesManage, models.PermUserOverrides,
+		models.PermUsersLock, models.PermLegalHold, models.PermBalanceAdjust, models.PermWithdrawalsReview,
+		models.PermUsersWrite, models.PermUsersImpersonate, models.PermUsersMerge, models.PermMessagesSend,
+		models.PermSupportManage, models.PermTournamentsScore,
+	}},
+}
+
+// seedJourney mirrors the default onboarding journey seeded by the Postgres migrations.
+var seedJourney = models.OnboardingJourney{Steps: []models.OnboardingStep{
+	{Key: "welcome", Title: "Welcome", Template: "welcome"},
+}}
+
+// firstCustomID matches the start of the Postgres role and permission ID sequences.
+const firstCustomID = 100
+
+// MemoryStore is an in-memory storage.Store for tests. Transactions are
+// serialized and roll back by restoring a snapshot, so writes made outside a
+// transaction while one is running are lost if it fails.
+type MemoryStore struct {
+	clock clock.Clock
+	txMu  sync.Mutex
+
+	mu    sync.Mutex
+	state memoryState
+}
+
+type memoryState struct {
+	users          []models.User
+	notes          []models.UserNote
+	revisions      []models.NoteRevision
+	rateLimits     map[[2]string]models.RateLimitPolicy
+	changes        []models.ConfigChange
+	webhooks       []models.WebhookEndpoint
+	deliveries     []models.WebhookDelivery
+	devices        []models.LoginDevice
+	alerts         []models.LoginAlert
+	cases          []models.SecurityCase
+	resets         []models.PasswordReset
+	confirms       []models.DeviceConfirmation
+	logins         []models.LoginAttempt
+	archived       memoryArchive
+	inbound        []models.InboundDelivery
+	claimed        map[[2]string]int64
+	roles          []models.Role
+	permissions    []models.Permission
+	overrides      []models.PermissionOverride
+	ledger         []models.Transaction
+	operations     map[[2]string]models.Operation
+	journeys       map[string]models.OnboardingJourney
+	progress       []models.OnboardingProgress
+	privacy        map[int64]models.PrivacySettings
+	exports        []models.DataExport
+	reports        []models.ReconciliationReport
+	standings      map[int64]map[[2]string]float64
+	clients        []models.OAuthClient
+	codes          []models.AuthorizationCode
+	ipPolicies     map[[2]string]models.IPRiskPolicy
+	ipEvents       []models.IPRiskEvent
+	promos         []models.PromoCode
+	redeemed       []models.PromoRedemption
+	challenges     []models.Challenge
+	games          []models.GameSession
+	insights       []models.DatabaseInsightsReport
+	holds          []models.LegalHold
+	flags          map[string]models.FeatureFlag
+	reservedNames  []models.ReservedName
+	bulkJobs       []models.BulkJob
+	apiKeys        []models.APIKey
+	impersonations []models.Impersonation
+	merges         []models.AccountMerge
+	withdrawals    []models.WithdrawalRequest
+	walletHolds    []models.WalletHold
+	// ledgerAccounts holds the codes of the open ledger accounts.
+	ledgerAccounts  []string
+	journal         []models.JournalEntry
+	jackpots        []models.Jackpot
+	tournaments     []models.Tournament
+	entries         []models.TournamentEntry
+	messages        []models.Message
+	tickets         []models.SupportTicket
+	ticketReplies   []models.TicketReply
+	attachments     []models.TicketAttachment
+	limits          []models.GamingLimits
+	operatorReports []models.OperatorReport
+	outbox          []models.OutboxEvent
+	// outboxSeq numbers outbox events apart from nextID, like the table's own
+	// sequence, so staging events does not shift the IDs of other records.
+	outboxSeq int64
+	// journalSeq numbers journal entries apart from nextID for the same reason.
+	journalSeq int64
+	nextID     int64
+}
+
+// memoryArchive holds rows moved out of the hot slices by ArchiveRecords.
+type memoryArchive struct {
+	ledger []models.Transaction
+	logins []models.LoginAttempt
+}
+
+func (st memoryState) clone() memoryState {
+	st.users = slices.Clone(st.users)
+	st.notes = slices.Clone(st.notes)
+	st.revisions = slices.Clone(st.revisions)
+	st.rateLimits = maps.Clone(st.rateLimits)
+	st.changes = slices.Clone(st.changes)
+	st.webhooks = slices.Clone(st.webhooks)
+	st.deliveries = slices.Clone(st.deliveries)
+	st.devices = slices.Clone(st.devices)
+	st.alerts = slices.Clone(st.alerts)
+	st.cases = slices.Clone(st.cases)
+	st.resets = slices.Clone(st.resets)
+	st.confirms = slices.Clone(st.confirms)
+	st.logins = slices.Clone(st.logins)
+	st.archived = memoryArchive{ledger: slices.Clone(st.archived.ledger), logins: slices.Clone(st.archived.logins)}
+	st.inbound = slices.Clone(st.inbound)
+	st.claimed = maps.Clone(st.claimed)
+	st.roles = cloneRoles(st.roles)
+	st.permissions = slices.Clone(st.permissions)
+	st.overrides = slices.Clone(st.overrides)
+	st.ledger = slices.Clone(st.ledger)
+	st.operations = maps.Clone(st.operations)
+	st.journeys = maps.Clone(st.journeys)
+	st.progress = slices.Clone(st.progress)
+	st.privacy = maps.Clone(st.privacy)
+	st.exports = slices.Clone(st.exports)
+	st.reports = slices.Clone(st.reports)
+	st.standings = maps.Clone(st.standings)
+	st.clients = slices.Clone(st.clients)
+	st.codes = slices.Clone(st.codes)
+	st.ipPolicies = maps.Clone(st.ipPolicies)
+	st.ipEvents = slices.Clone(st.ipEvents)
+	st.promos = slices.Clone(st.promos)
+	st.redeemed = slices.Clone(st.redeemed)
+	st.challenges = slices.Clone(st.challenges)
+	st.games = slices.Clone(st.games)
+	st.insights = slices.Clone(st.insights)
+	st.holds = slices.Clone(st.holds)
+	st.flags = maps.Clone(st.flags)
+	st.reservedNames = slices.Clone(st.reservedNames)
+	st.bulkJobs = slices.Clone(st.bulkJobs)
+	st.apiKeys = slices.Clone(st.apiKeys)
+	st.impersonations = slices.Clone(st.impersonations)
+	st.merges = slices.Clone(st.merges)
+	st.withdrawals = slices.Clone(st.withdrawals)
+	st.walletHolds = slices.Clone(st.walletHolds)
+	st.ledgerAccounts = slices.Clone(st.ledgerAccounts)
+	st.journal = slices.Clone(st.journal)
+	st.jackpots = slices.Clone(st.jackpots)
+	st.tournaments = slices.Clone(st.tournaments)
+	st.entries = slices.Clone(st.entries)
+	st.messages = slices.Clone(st.messages)
+	st.tickets = slices.Clone(st.tickets)
+	st.ticketReplies = slices.Clone(st.ticketReplies)
+	st.attachments = slices.Clone(st.attachments)
+	st.limits = slices.Clone(st.limits)
+	st.operatorReports = slices.Clone(st.operatorReports)
+	st.outbox = slices.Clone(st.outbox)
+	return st
+}
+
+var _ storage.Store = (*MemoryStore)(nil)
+
+// NewMemoryStore returns an empty store that timestamps records with clk.
+func NewMemoryStore(clk clock.Clock) *MemoryStore {
+	return &MemoryStore{clock: clk, state: memoryState{
+		rateLimits:  make(map[[2]string]models.RateLimitPolicy),
+		claimed:     make(map[[2]string]int64),
+		operations:  make(map[[2]string]models.Operation),
+		roles:       cloneRoles(seedRoles),
+		permissions: slices.Clone(seedPermissions),
+		journeys:    map[string]models.OnboardingJourney{"": seedJourney},
+		privacy:     make(map[int64]models.PrivacySettings),
+		ipPolicies:  make(map[[2]string]models.IPRiskPolicy),
+		flags:       make(map[string]models.FeatureFlag),
+	}}
+}
+
+// WithTx runs fn atomically against the store.
+func (s *MemoryStore) WithTx(ctx context.Context, fn func(tx storage.Repositories) error) error {
+	s.txMu.Lock()
+	defer s.txMu.Unlock()
+
+	s.mu.Lock()
+	snapshot := s.state.clone()
+	s.mu.Unlock()
+
+	if err := fn(s); err != nil {
+		s.mu.Lock()
+		s.state = snapshot
+		s.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+// SetRole changes a user's role, standing in for an admin action that has no API yet.
+func (s *MemoryStore) SetRole(userID int64, role string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i, ok := s.userIndex(userID)
+	if !ok {
+		return storage.ErrNotFound
+	}
+	s.state.users[i].Role = role
+	return nil
+}
+
+// SetBalance overwrites a user's balance without a ledger entry, standing in
+// for a direct database edit.
+func (s *MemoryStore) SetBalance(userID int64, balance float64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i, ok := s.userIndex(userID)
+	if !ok {
+		return storage.ErrNotFound
+	}
+	s.state.users[i].Balance = balance
+	s.state.users[i].Version++
+	return nil
+}
+
+func (s *MemoryStore) newID() int64 {
+	s.state.nextID++
+	return s.state.nextID
+}
+
+func (s *MemoryStore) userIndex(id int64) (int, bool) {
+	i := slices.IndexFunc(s.state.users, func(u models.User) bool { return u.ID == id })
+	return i, i >= 0
+}
+
+func (s *MemoryStore) findUser(match func(models.User) bool) (models.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, u := range s.state.users {
+		if match(u) {
+			return s.withPermissions(u), nil
+		}
+	}
+	return models.User{}, storage.ErrNotFound
+}
+
+// withPermissions fills in the permissions of the user's role and overrides,
+// in permission ID order like Postgres. s.mu must be held.
+func (s *MemoryStore) withPermissions(u models.User) models.User {
+	var granted []string
+	if i := slices.IndexFunc(s.state.roles, func(r models.Role) bool { return r.RoleName == u.Role }); i >= 0 {
+		granted = s.state.roles[i].Permissions
+	}
+	u.Permissions = []string{}
+	for _, p := range s.state.permissions {
+		allow := slices.Contains(granted, p.PermissionName)
+		if o, ok := s.override(u.ID, p.ID); ok {
+			allow = o.Allow
+		}
+		if allow {
+			u.Permissions = append(u.Permissions, p.PermissionName)
+		}
+	}
+	return u
+}
+
+func (s *MemoryStore) override(userID, permissionID int64) (models.PermissionOverride, bool) {
+	i := slices.IndexFunc(s.state.overrides, func(o models.PermissionOverride) bool {
+		return o.UserID == userID && o.PermissionID == permissionID
+	})
+	if i < 0 {
+		return models.PermissionOverride{}, false
+	}
+	return s.state.overrides[i], true
+}
+
+func (s *MemoryStore) username(id int64) string {
+	if i, ok := s.userIndex(id); ok {
+		return s.state.users[i].Username
+	}
+	return ""
+}
+
+// Ping always succeeds; there is no connection to lose.
+func (s *MemoryStore) Ping(context.Context) error {
+	return nil
+}
+
+// PoolStats reports no pools.
+func (s *MemoryStore) PoolStats() []storage.PoolStats {
+	return nil
+}
+
+// Breaker is always closed.
+func (s *MemoryStore) Breaker() storage.BreakerState {
+	return storage.BreakerState{State: storage.BreakerClosed}
+}
+
+// CreateUser stores a user, enforcing unique username, email, and phone.
+func (s *MemoryStore) CreateUser(_ context.Context, user models.User) (models.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !slices.ContainsFunc(s.state.roles, func(r models.Role) bool { return r.RoleName == user.Role }) {
+		return models.User{}, fmt.Errorf("unknown role %q", user.Role)
+	}
+	for _, u := range s.state.users {
+		// Usernames are unique regardless of case, like the lower(username) index.
+		if strings.EqualFold(u.Username, user.Username) || u.Email == user.Email || (user.Phone != "" && u.Phone == user.Phone) {
+			return models.User{}, storage.ErrAlreadyExists
+		}
+	}
+	user.ID = s.newID()
+	user.Version = 1
+	user.CreatedAt = s.clock.Now()
+	s.state.users = append(s.state.users, user)
+	return s.withPermissions(user), nil
+}
+
+func (s *MemoryStore) FindByID(_ context.Context, id int64) (models.User, error) {
+	return s.findUser(func(u models.User) bool { return u.ID == id })
+}
+
+func (s *MemoryStore) FindByUsername(_ context.Context, username string) (models.User, error) {
+	return s.findUser(func(u models.User) bool { return u.Username == username })
+}
+
+func (s *MemoryStore) FindByEmail(_ context.Context, email string) (models.User, error) {
+	return s.findUser(func(u models.User) bool { return u.Email == email })
+}
+
+func (s *MemoryStore) FindByUsernameOrEmail(_ context.Context, identifier string) (models.User, error) {
+	return s.findUser(func(u models.User) bool { return u.Username == identifier || u.Email == identifier })
+}
+
+func (s *MemoryStore) UpdateProfile(_ context.Context, user models.User) (models.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i, ok := s.userIndex(user.ID)
+	if !ok {
+		return models.User{}, storage.ErrNotFound
+	}
+	if s.state.users[i].Version != user.Version {
+		return models.User{}, storage.ErrVersionConflict
+	}
+	for _, u := range s.state.users {
+		if u.ID != user.ID && (strings.EqualFold(u.Username, user.Username) || u.Email == user.Email || u.Phone == user.Phone) {
+			return models.User{}, storage.ErrAlreadyExists
+		}
+	}
+	saved := &s.state.users[i]
+	saved.Username, saved.Email, saved.Phone = user.Username, user.Email, user.Phone
+	saved.Version++
+	return s.withPermissions(*saved), nil
+}
+
+// SearchUsers ranks exact matches over prefix matches over other substring
+// matches, like the Postgres query, but without trigram similarity.
+func (s *MemoryStore) SearchUsers(_ context.Context, query string, after *models.UserSearchCursor, limit int) ([]models.UserSearchResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	q := strings.ToLower(query)
+	results := []models.UserSearchResult{}
+	for _, u := range s.state.users {
+		var rank float64
+		for _, field := range []string{strings.ToLower(u.Username), strings.ToLower(u.Email), u.Phone} {
+			switch {
+			case field == q:
+				rank = max(rank, 3)
+			case strings.HasPrefix(field, q):
+				rank = max(rank, 2)
+			case strings.Contains(field, q):
+				rank = max(rank, 1)
+			}
+		}
+		if rank == 0 || (after != nil && (rank > after.Rank || (rank == after.Rank && u.ID <= after.ID))) {
+			continue
+		}
+		results = append(results, models.UserSearchResult{User: s.withPermissions(u), Rank: rank})
+	}
+	slices.SortFunc(results, func(a, b models.UserSearchResult) int {
+		return cmp.Or(cmp.Compare(b.Rank, a.Rank), cmp.Compare(a.User.ID, b.User.ID))
+	})
+	return results[:min(limit, len(results))], nil
+}
+
+func (s *MemoryStore) CreateNote(_ context.Context, note models.UserNote) (models.UserNote, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.userIndex(note.UserID); !ok {
+		return models.UserNote{}, storage.ErrNotFound
+	}
+	note.ID = s.newID()
+	note.AuthorUsername = s.username(note.AuthorID)
+	note.CreatedAt = s.clock.Now()
+	note.UpdatedAt = note.CreatedAt
+	s.state.notes = append(s.state.notes, note)
+	return note, nil
+}
+
+func (s *MemoryStore) ListNotes(_ context.Context, userID int64) ([]models.UserNote, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	notes := []models.UserNote{}
+	for _, n := range s.state.notes {
+		if n.UserID == userID {
+			notes = append(notes, n)
+		}
+	}
+	slices.SortStableFunc(notes, func(a, b models.UserNote) int {
+		if a.Pinned != b.Pinned {
+			if a.Pinned {
+				return -1
+			}
+			return 1
+		}
+		return b.CreatedAt.Compare(a.CreatedAt)
+	})
+	return notes, nil
+}
+
+func (s *MemoryStore) FindNote(_ context.Context, userID, noteID int64) (models.UserNote, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, n := range s.state.notes {
+		if n.ID == noteID && n.UserID == userID {
+			return n, nil
+		}
+	}
+	return models.UserNote{}, storage.ErrNotFound
+}
+
+func (s *MemoryStore) UpdateNote(_ context.Context, note models.UserNote, editorID int64) (models.UserNote, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, n := range s.state.notes {
+		if n.ID != note.ID || n.UserID != note.UserID {
+			continue
+		}
+		if n.Body != note.Body {
+			s.state.revisions = append(s.state.revisions, models.NoteRevision{
+				ID:             s.newID(),
+				NoteID:         n.ID,
+				Body:           n.Body,
+				EditorID:       editorID,
+				EditorUsername: s.username(editorID),
+				EditedAt:       s.clock.Now(),
+			})
+		}
+		n.Body = note.Body
+		n.Pinned = note.Pinned
+		n.UpdatedAt = s.clock.Now()
+		s.state.notes[i] = n
+		return n, nil
+	}
+	return models.UserNote{}, storage.ErrNotFound
+}
+
+func (s *MemoryStore) ListNoteRevisions(_ context.Context, noteID int64) ([]models.NoteRevision, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	revisions := []models.NoteRevision{}
+	for _, r := range s.state.revisions {
+		if r.NoteID == noteID {
+			revisions = append(revisions, r)
+		}
+	}
+	slices.Reverse(revisions)
+	return revisions, nil
+}
+
+func (s *MemoryStore) ListRateLimitPolicies(context.Context) ([]models.RateLimitPolicy, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	policies := slices.Collect(maps.Values(s.state.rateLimits))
+	slices.SortFunc(policies, func(a, b models.RateLimitPolicy) int {
+		return cmp.Or(cmp.Compare(a.Tenant, b.Tenant), cmp.Compare(a.RouteClass, b.RouteClass))
+	})
+	if policies == nil {
+		policies = []models.RateLimitPolicy{}
+	}
+	return policies, nil
+}
+
+func (s *MemoryStore) FindRateLimitPolicy(_ context.Context, tenant, routeClass string) (models.RateLimitPolicy, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	policy, ok := s.state.rateLimits[[2]string{tenant, routeClass}]
+	if !ok {
+		return models.RateLimitPolicy{}, storage.ErrNotFound
+	}
+	return policy, nil
+}
+
+func (s *MemoryStore) UpsertRateLimitPolicy(_ context.Context, policy models.RateLimitPolicy) (models.RateLimitPolicy, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	policy.UpdatedAt = s.clock.Now()
+	s.state.rateLimits[[2]string{policy.Tenant, policy.RouteClass}] = policy
+	return policy, nil
+}
+
+func (s *MemoryStore) DeleteRateLimitPolicy(_ context.Context, tenant, routeClass string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := [2]string{tenant, routeClass}
+	if _, ok := s.state.rateLimits[key]; !ok {
+		return storage.ErrNotFound
+	}
+	delete(s.state.rateLimits, key)
+	return nil
+}
+
+func (s *MemoryStore) RecordConfigChange(_ context.Context, change models.ConfigChange) (models.ConfigChange, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	change.ID = s.newID()
+	change.ChangedByUsername = s.username(change.ChangedBy)
+	change.ChangedAt = s.clock.Now()
+	s.state.changes = append(s.state.changes, change)
+	return change, nil
+}
+
+func (s *MemoryStore) FindConfigChange(_ context.Context, id int64) (models.ConfigChange, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, c := range s.state.changes {
+		if c.ID == id {
+			return c, nil
+		}
+	}
+	return models.ConfigChange{}, storage.ErrNotFound
+}
+
+func (s *MemoryStore) ListConfigChanges(_ context.Context, entity string, limit int) ([]models.ConfigChange, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	changes := []models.ConfigChange{}
+	for i := len(s.state.changes) - 1; i >= 0 && len(changes) < limit; i-- {
+		if c := s.state.changes[i]; entity == "" || c.Entity == entity {
+			changes = append(changes, c)
+		}
+	}
+	return changes, nil
+}
+
+func (s *MemoryStore) ListConfigChangesBy(_ context.Context, actorID int64) ([]models.ConfigChange, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	changes := []models.ConfigChange{}
+	for _, c := range s.state.changes {
+		if c.ChangedBy == actorID {
+			changes = append(changes, c)
+		}
+	}
+	return changes, nil
+}
+
+// AdminStats counts users and balances; signups are bucketed by the store clock's UTC day.
+func (s *MemoryStore) AdminStats(_ context.Context, days int) (models.AdminStats, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.clock.Now().UTC()
+	stats := models.AdminStats{GeneratedAt: now, SignupsPerDay: make([]models.DailyCount, 0, days)}
+	perDay := make(map[string]int64)
+	for _, u := range s.state.users {
+		stats.TotalUsers++
+		stats.TotalBalance += u.Balance
+		perDay[u.CreatedAt.UTC().Format(time.DateOnly)]++
+	}
+	for i := days - 1; i >= 0; i-- {
+		day := now.AddDate(0, 0, -i).Format(time.DateOnly)
+		stats.SignupsPerDay = append(stats.SignupsPerDay, models.DailyCount{Day: day, Count: perDay[day]})
+	}
+	return stats, nil
+}
+
+func (s *MemoryStore) BusinessKPIs(context.Context) (models.BusinessKPIs, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	kpis := models.BusinessKPIs{Registrations: int64(len(s.state.users))}
+	for _, ledger := range [][]models.Transaction{s.state.archived.ledger, s.state.ledger} {
+		for _, t := range ledger {
+			switch t.Reason {
+			case models.TransactionDeposit:
+				kpis.Deposits++
+				kpis.DepositsAmount += t.Amount
+			case models.TransactionBetSettlement:
+				kpis.Bets++
+				kpis.GGR -= t.Amount
+			}
+		}
+	}
+	kpis.DepositsAmount = math.Round(kpis.DepositsAmount*100) / 100
+	kpis.GGR = math.Round(kpis.GGR*100) / 100
+	return kpis, nil
+}
+
+func (s *MemoryStore) CreateWebhookEndpoint(_ context.Context, endpoint models.WebhookEndpoint) (models.WebhookEndpoint, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	endpoint.ID = s.newID()
+	endpoint.CreatedAt = s.clock.Now()
+	s.state.webhooks = append(s.state.webhooks, endpoint)
+	return endpoint, nil
+}
+
+func (s *MemoryStore) ListWebhookEndpoints(context.Context) ([]models.WebhookEndpoint, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]models.WebhookEndpoint{}, s.state.webhooks...), nil
+}
+
+func (s *MemoryStore) DeleteWebhookEndpoint(_ context.Context, id int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	before := len(s.state.webhooks)
+	s.state.webhooks = slices.DeleteFunc(s.state.webhooks, func(e models.WebhookEndpoint) bool { return e.ID == id })
+	if len(s.state.webhooks) == before {
+		return storage.ErrNotFound
+	}
+	s.state.deliveries = slices.DeleteFunc(s.state.deliveries, func(d models.WebhookDelivery) bool { return d.EndpointID == id })
+	return nil
+}
+
+func (s *MemoryStore) RecordWebhookDelivery(_ context.Context, delivery models.WebhookDelivery) (models.WebhookDelivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delivery.ID = s.newID()
+	delivery.CreatedAt = s.clock.Now()
+	s.state.deliveries = append(s.state.deliveries, delivery)
+	return delivery, nil
+}
+
+func (s *MemoryStore) ListWebhookDeliveries(_ context.Context, endpointID int64, limit int) ([]models.WebhookDelivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	deliveries := []models.WebhookDelivery{}
+	for i := len(s.state.deliveries) - 1; i >= 0 && len(deliveries) < limit; i-- {
+		if d := s.state.deliveries[i]; d.EndpointID == endpointID {
+			deliveries = append(deliveries, d)
+		}
+	}
+	return deliveries, nil
+}
+
+func (s *MemoryStore) ListLoginDevices(_ context.Context, userID int64) ([]models.LoginDevice, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	devices := []models.LoginDevice{}
+	for _, d := range s.state.devices {
+		if d.UserID == userID {
+			devices = append(devices, d)
+		}
+	}
+	slices.SortStableFunc(devices, func(a, b models.LoginDevice) int { return b.LastSeen.Compare(a.LastSeen) })
+	return devices, nil
+}
+
+func (s *MemoryStore) TouchLoginDevice(_ context.Context, device models.LoginDevice) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, d := range s.state.devices {
+		if d.UserID == device.UserID && d.Fingerprint == device.Fingerprint && d.Country == device.Country {
+			s.state.devices[i].LastSeen = device.LastSeen
+			return nil
+		}
+	}
+	device.FirstSeen = device.LastSeen
+	s.state.devices = append(s.state.devices, device)
+	return nil
+}
+
+func (s *MemoryStore) CreateLoginAlert(_ context.Context, alert models.LoginAlert) (models.LoginAlert, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	alert.ID = s.newID()
+	alert.CreatedAt = s.clock.Now()
+	s.state.alerts = append(s.state.alerts, alert)
+	return alert, nil
+}
+
+func (s *MemoryStore) ListLoginAlerts(_ context.Context, userID int64, limit int) ([]models.LoginAlert, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	alerts := []models.LoginAlert{}
+	for i := len(s.state.alerts) - 1; i >= 0 && len(alerts) < limit; i-- {
+		if s.state.alerts[i].UserID == userID {
+			alerts = append(alerts, s.state.alerts[i])
+		}
+	}
+	return alerts, nil
+}
+
+func (s *MemoryStore) FindLoginAlertByToken(_ context.Context, tokenHash string) (models.LoginAlert, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, a := range s.state.alerts {
+		if a.TokenHash == tokenHash {
+			return a, nil
+		}
+	}
+	return models.LoginAlert{}, storage.ErrNotFound
+}
+
+func (s *MemoryStore) ResolveLoginAlert(_ context.Context, id int64, status string, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, a := range s.state.alerts {
+		if a.ID == id && a.Status == models.LoginAlertPending {
+			s.state.alerts[i].Status = status
+			s.state.alerts[i].ResolvedAt = &at
+			return nil
+		}
+	}
+	return storage.ErrNotFound
+}
+
+func (s *MemoryStore) CreateSecurityCase(_ context.Context, c models.SecurityCase) (models.SecurityCase, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.userIndex(c.UserID); !ok {
+		return models.SecurityCase{}, storage.ErrNotFound
+	}
+	c.ID = s.newID()
+	c.Username = s.username(c.UserID)
+	c.CreatedAt = s.clock.Now()
+	s.state.cases = append(s.state.cases, c)
+	return c, nil
+}
+
+func (s *MemoryStore) ListSecurityCases(_ context.Context, limit int) ([]models.SecurityCase, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cases := []models.SecurityCase{}
+	for i := len(s.state.cases) - 1; i >= 0 && len(cases) < limit; i-- {
+		cases = append(cases, s.state.cases[i])
+	}
+	return cases, nil
+}
+
+func (s *MemoryStore) LockAccount(_ context.Context, userID int64, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i, ok := s.userIndex(userID)
+	if !ok {
+		return storage.ErrNotFound
+	}
+	s.state.users[i].PasswordResetRequired = true
+	s.state.users[i].SessionsRevokedAt = &at
+	return nil
+}
+
+func (s *MemoryStore) CreatePasswordReset(_ context.Context, reset models.PasswordReset) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.state.resets = append(s.state.resets, reset)
+	return nil
+}
+
+func (s *MemoryStore) ConsumePasswordReset(_ context.Context, tokenHash string, now time.Time) (models.PasswordReset, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := slices.IndexFunc(s.state.resets, func(r models.PasswordReset) bool { return r.TokenHash == tokenHash })
+	if i < 0 || !s.state.resets[i].ExpiresAt.After(now) {
+		return models.PasswordReset{}, storage.ErrNotFound
+	}
+	reset := s.state.resets[i]
+	s.state.resets = slices.Delete(s.state.resets, i, i+1)
+	return reset, nil
+}
+
+func (s *MemoryStore) CreateDeviceConfirmation(_ context.Context, c models.DeviceConfirmation) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.state.confirms = append(s.state.confirms, c)
+	return nil
+}
+
+func (s *MemoryStore) ConsumeDeviceConfirmation(_ context.Context, tokenHash string, now time.Time) (models.DeviceConfirmation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := slices.IndexFunc(s.state.confirms, func(c models.DeviceConfirmation) bool { return c.TokenHash == tokenHash })
+	if i < 0 || !s.state.confirms[i].ExpiresAt.After(now) {
+		return models.DeviceConfirmation{}, storage.ErrNotFound
+	}
+	c := s.state.confirms[i]
+	s.state.confirms = slices.Delete(s.state.confirms, i, i+1)
+	return c, nil
+}
+
+func (s *MemoryStore) RecordLoginAttempt(_ context.Context, a models.LoginAttempt) (models.LoginAttempt, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	a.ID = s.newID()
+	a.CreatedAt = s.clock.Now()
+	s.state.logins = append(s.state.logins, a)
+	return a, nil
+}
+
+func (s *MemoryStore) ListLoginAttempts(_ context.Context, filter models.LoginAttemptFilter, limit int) ([]models.LoginAttempt, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	logins := s.state.logins
+	if filter.IncludeArchived {
+		logins = slices.Concat(s.state.archived.logins, logins)
+	}
+	attempts := []models.LoginAttempt{}
+	for i := len(logins) - 1; i >= 0 && len(attempts) < limit; i-- {
+		a := logins[i]
+		if (filter.UserID == 0 || (a.UserID != nil && *a.UserID == filter.UserID)) &&
+			(filter.IP == "" || a.IP == filter.IP) &&
+			(filter.Success == nil || a.Success == *filter.Success) {
+			attempts = append(attempts, a)
+		}
+	}
+	return attempts, nil
+}
+
+func (s *MemoryStore) RevokeSessions(_ context.Context, userID int64, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i, ok := s.userIndex(userID)
+	if !ok {
+		return storage.ErrNotFound
+	}
+	s.state.users[i].SessionsRevokedAt = &at
+	return nil
+}
+
+func (s *MemoryStore) SetPassword(_ context.Context, userID int64, passwordHash string, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i, ok := s.userIndex(userID)
+	if !ok {
+		return storage.ErrNotFound
+	}
+	s.state.users[i].PasswordHash = passwordHash
+	s.state.users[i].PasswordResetRequired = false
+	s.state.users[i].SessionsRevokedAt = &at
+	return nil
+}
+
+func (s *MemoryStore) RecordInboundDelivery(_ context.Context, d models.InboundDelivery) (models.InboundDelivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	d.ID = s.newID()
+	d.ReceivedAt = s.clock.Now()
+	s.state.inbound = append(s.state.inbound, d)
+	return d, nil
+}
+
+func (s *MemoryStore) FindInboundDelivery(_ context.Context, id int64) (models.InboundDelivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := slices.IndexFunc(s.state.inbound, func(d models.InboundDelivery) bool { return d.ID == id })
+	if i < 0 {
+		return models.InboundDelivery{}, storage.ErrNotFound
+	}
+	return s.state.inbound[i], nil
+}
+
+func (s *MemoryStore) ListInboundDeliveries(_ context.Context, filter models.InboundDeliveryFilter, limit int) ([]models.InboundDelivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	deliveries := []models.InboundDelivery{}
+	for i := len(s.state.inbound) - 1; i >= 0 && len(deliveries) < limit; i-- {
+		d := s.state.inbound[i]
+		if (filter.Provider == "" || d.Provider == filter.Provider) &&
+			(filter.Status == "" || d.Status == filter.Status) &&
+			(filter.EventID == "" || d.EventID == filter.EventID) {
+			deliveries = append(deliveries, d)
+		}
+	}
+	return deliveries, nil
+}
+
+func (s *MemoryStore) UpdateInboundDelivery(_ context.Context, id int64, status, errMsg string, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := slices.IndexFunc(s.state.inbound, func(d models.InboundDelivery) bool { return d.ID == id })
+	if i < 0 {
+		return storage.ErrNotFound
+	}
+	s.state.inbound[i].Status = status
+	s.state.inbound[i].Error = errMsg
+	s.state.inbound[i].Attempts++
+	s.state.inbound[i].LastAttemptAt = &at
+	return nil
+}
+
+func (s *MemoryStore) ClaimInboundEvent(_ context.Context, provider, eventID string, deliveryID int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := [2]string{provider, eventID}
+	if _, ok := s.state.claimed[key]; ok {
+		return storage.ErrAlreadyExists
+	}
+	s.state.claimed[key] = deliveryID
+	return nil
+}
+
+func cloneRoles(roles []models.Role) []models.Role {
+	roles = slices.Clone(roles)
+	for i := range roles {
+		roles[i].Permissions = slices.Clone(roles[i].Permissions)
+	}
+	return roles
+}
+
+func (s *MemoryStore) roleIndex(id int64) (int, bool) {
+	i := slices.IndexFunc(s.state.roles, func(r models.Role) bool { return r.ID == id })
+	return i, i >= 0
+}
+
+func (s *MemoryStore) permissionIndex(id int64) (int, bool) {
+	i := slices.IndexFunc(s.state.permissions, func(p models.Permission) bool { return p.ID == id })
+	return i, i >= 0
+}
+
+// nextRBACID returns the next ID after firstCustomID that is unused in both lists.
+func (s *MemoryStore) nextRBACID() int64 {
+	id := int64(firstCustomID)
+	for _, r := range s.state.roles {
+		id = max(id, r.ID+1)
+	}
+	for _, p := range s.state.permissions {
+		id = max(id, p.ID+1)
+	}
+	return id
+}
+
+func (s *MemoryStore) ListRoles(context.Context) ([]models.Role, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return cloneRoles(s.state.roles), nil
+}
+
+func (s *MemoryStore) FindRole(_ context.Context, id int64) (models.Role, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i, ok := s.roleIndex(id)
+	if !ok {
+		return models.Role{}, storage.ErrNotFound
+	}
+	return cloneRoles(s.state.roles[i : i+1])[0], nil
+}
+
+func (s *MemoryStore) CreateRole(_ context.Context, role models.Role) (models.Role, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if slices.ContainsFunc(s.state.roles, func(r models.Role) bool { return r.RoleName == role.RoleName }) {
+		return models.Role{}, storage.ErrAlreadyExists
+	}
+	role.ID = s.nextRBACID()
+	role.Permissions = []string{}
+	s.state.roles = append(s.state.roles, role)
+	return role, nil
+}
+
+func (s *MemoryStore) UpdateRole(_ context.Context, role models.Role) (models.Role, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i, ok := s.roleIndex(role.ID)
+	if !ok {
+		return models.Role{}, storage.ErrNotFound
+	}
+	if slices.ContainsFunc(s.state.roles, func(r models.Role) bool { return r.RoleName == role.RoleName && r.ID != role.ID }) {
+		return models.Role{}, storage.ErrAlreadyExists
+	}
+	previous := s.state.roles[i].RoleName
+	for j := range s.state.users {
+		if s.state.users[j].Role == previous && previous != role.RoleName {
+			s.state.users[j].Role = role.RoleName
+			s.state.users[j].PermissionsVersion++
+		}
+	}
+	s.state.roles[i].RoleName = role.RoleName
+	s.state.roles[i].RoleDescription = role.RoleDescription
+	return cloneRoles(s.state.roles[i : i+1])[0], nil
+}
+
+func (s *MemoryStore) DeleteRole(_ context.Context, id int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i, ok := s.roleIndex(id)
+	if !ok {
+		return storage.ErrNotFound
+	}
+	name := s.state.roles[i].RoleName
+	if slices.ContainsFunc(s.state.users, func(u models.User) bool { return u.Role == name }) {
+		return storage.ErrInUse
+	}
+	s.state.roles = slices.Delete(s.state.roles, i, i+1)
+	return nil
+}
+
+func (s *MemoryStore) ListPermissions(context.Context) ([]models.Permission, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.state.permissions), nil
+}
+
+func (s *MemoryStore) FindPermission(_ context.Context, id int64) (models.Permission, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i, ok := s.permissionIndex(id)
+	if !ok {
+		return models.Permission{}, storage.ErrNotFound
+	}
+	return s.state.permissions[i], nil
+}
+
+func (s *MemoryStore) CreatePermission(_ context.Context, p models.Permission) (models.Permission, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if slices.ContainsFunc(s.state.permissions, func(q models.Permission) bool { return q.PermissionName == p.PermissionName }) {
+		return models.Permission{}, storage.ErrAlreadyExists
+	}
+	p.ID = s.nextRBACID()
+	s.state.permissions = append(s.state.permissions, p)
+	return p, nil
+}
+
+func (s *MemoryStore) UpdatePermission(_ context.Context, p models.Permission) (models.Permission, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i, ok := s.permissionIndex(p.ID)
+	if !ok {
+		return models.Permission{}, storage.ErrNotFound
+	}
+	if slices.ContainsFunc(s.state.permissions, func(q models.Permission) bool { return q.PermissionName == p.PermissionName && q.ID != p.ID }) {
+		return models.Permission{}, storage.ErrAlreadyExists
+	}
+	previous := s.state.permissions[i].PermissionName
+	if previous != p.PermissionName {
+		s.bumpPermissions(s.holdsPermission(p.ID, previous))
+	}
+	for j := range s.state.roles {
+		if k := slices.Index(s.state.roles[j].Permissions, previous); k >= 0 {
+			s.state.roles[j].Permissions[k] = p.PermissionName
+		}
+	}
+	s.state.permissions[i] = p
+	for j := range s.state.overrides {
+		if s.state.overrides[j].PermissionID == p.ID {
+			s.state.overrides[j].Permission = p.PermissionName
+		}
+	}
+	return p, nil
+}
+
+func (s *MemoryStore) DeletePermission(_ context.Context, id int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i, ok := s.permissionIndex(id)
+	if !ok {
+		return storage.ErrNotFound
+	}
+	name := s.state.permissions[i].PermissionName
+	s.bumpPermissions(s.holdsPermission(id, name))
+	for j := range s.state.roles {
+		s.state.roles[j].Permissions = slices.DeleteFunc(s.state.roles[j].Permissions, func(p string) bool { return p == name })
+	}
+	s.state.overrides = slices.DeleteFunc(s.state.overrides, func(o models.PermissionOverride) bool { return o.PermissionID == id })
+	s.state.permissions = slices.Delete(s.state.permissions, i, i+1)
+	return nil
+}
+
+func (s *MemoryStore) GrantPermission(_ context.Context, roleID, permissionID int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i, ok := s.roleIndex(roleID)
+	j, found := s.permissionIndex(permissionID)
+	if !ok || !found {
+		return storage.ErrNotFound
+	}
+	name := s.state.permissions[j].PermissionName
+	if !slices.Contains(s.state.roles[i].Permissions, name) {
+		s.state.roles[i].Permissions = append(s.state.roles[i].Permissions, name)
+		s.bumpPermissions(hasRole(s.state.roles[i].RoleName))
+	}
+	return nil
+}
+
+func (s *MemoryStore) RevokePermission(_ context.Context, roleID, permissionID int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i, ok := s.roleIndex(roleID)
+	j, found := s.permissionIndex(permissionID)
+	if !ok || !found {
+		return nil
+	}
+	name := s.state.permissions[j].PermissionName
+	if slices.Contains(s.state.roles[i].Permissions, name) {
+		s.state.roles[i].Permissions = slices.DeleteFunc(s.state.roles[i].Permissions, func(p string) bool { return p == name })
+		s.bumpPermissions(hasRole(s.state.roles[i].RoleName))
+	}
+	return nil
+}
+
+func (s *MemoryStore) ListPermissionOverrides(_ context.Context, userID int64) ([]models.PermissionOverride, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	overrides := []models.PermissionOverride{}
+	for _, o := range s.state.overrides {
+		if o.UserID == userID {
+			overrides = append(overrides, o)
+		}
+	}
+	slices.SortFunc(overrides, func(a, b models.PermissionOverride) int { return cmp.Compare(a.PermissionID, b.PermissionID) })
+	return overrides, nil
+}
+
+func (s *MemoryStore) SetPermissionOverride(_ context.Context, o models.PermissionOverride) (models.PermissionOverride, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	j, found := s.permissionIndex(o.PermissionID)
+	if _, ok := s.userIndex(o.UserID); !ok || !found {
+		return models.PermissionOverride{}, storage.ErrNotFound
+	}
+	o.Permission = s.state.permissions[j].PermissionName
+	o.SetAt = s.clock.Now()
+	s.state.overrides = slices.DeleteFunc(s.state.overrides, func(x models.PermissionOverride) bool {
+		return x.UserID == o.UserID && x.PermissionID == o.PermissionID
+	})
+	s.state.overrides = append(s.state.overrides, o)
+	s.bumpPermissions(func(u models.User) bool { return u.ID == o.UserID })
+	return o, nil
+}
+
+func (s *MemoryStore) DeletePermissionOverride(_ context.Context, userID, permissionID int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.override(userID, permissionID); !ok {
+		return storage.ErrNotFound
+	}
+	s.state.overrides = slices.DeleteFunc(s.state.overrides, func(o models.PermissionOverride) bool {
+		return o.UserID == userID && o.PermissionID == permissionID
+	})
+	s.bumpPermissions(func(u models.User) bool { return u.ID == userID })
+	return nil
+}
+
+// bumpPermissions bumps the permissions version of the users matching holds,
+// like the permissions_version updates of the role statements.
+func (s *MemoryStore) bumpPermissions(holds func(models.User) bool) {
+	for i := range s.state.users {
+		if holds(s.state.users[i]) {
+			s.state.users[i].PermissionsVersion++
+		}
+	}
+}
+
+// holdsPermission matches the users holding the permission through their
+// role or an override.
+func (s *MemoryStore) holdsPermission(id int64, name string) func(models.User) bool {
+	return func(u models.User) bool {
+		if _, ok := s.override(u.ID, id); ok {
+			return true
+		}
+		i := slices.IndexFunc(s.state.roles, func(r models.Role) bool { return r.RoleName == u.Role })
+		return i >= 0 && slices.Contains(s.state.roles[i].Permissions, name)
+	}
+}
+
+func hasRole(name string) func(models.User) bool {
+	return func(u models.User) bool { return u.Role == name }
+}
+
+func (s *MemoryStore) ApplyTransaction(_ context.Context, entry models.Transaction) (models.Transaction, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i, ok := s.userIndex(entry.UserID)
+	if !ok {
+		return models.Transaction{}, storage.ErrNotFound
+	}
+	// Round to cents like the NUMERIC(24,2) columns do.
+	balance := math.Round((s.state.users[i].Balance+entry.Amount)*100) / 100
+	if balance < 0 || entry.Amount < 0 && balance < s.heldBalance(entry.UserID) {
+		return models.Transaction{}, storage.ErrInsufficientFunds
+	}
+	entry.ID = s.newID()
+	entry.Amount = math.Round(entry.Amount*100) / 100
+	entry.BalanceAfter = balance
+	entry.CreatedAt = s.clock.Now()
+	if err := s.postJournal(models.JournalFor(entry)); err != nil {
+		return models.Transaction{}, err
+	}
+	s.state.users[i].Balance = balance
+	s.state.users[i].Version++
+	s.state.ledger = append(s.state.ledger, entry)
+	return entry, nil
+}
+
+func (s *MemoryStore) CreateHold(_ context.Context, hold models.WalletHold) (models.WalletHold, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i, ok := s.userIndex(hold.UserID)
+	if !ok {
+		return models.WalletHold{}, storage.ErrNotFound
+	}
+	if slices.ContainsFunc(s.state.walletHolds, func(h models.WalletHold) bool {
+		return h.UserID == hold.UserID && h.Reference == hold.Reference
+	}) {
+		return models.WalletHold{}, storage.ErrAlreadyExists
+	}
+	hold.Amount = math.Round(hold.Amount*100) / 100
+	if s.state.users[i].Balance-s.heldBalance(hold.UserID) < hold.Amount {
+		return models.WalletHold{}, storage.ErrInsufficientFunds
+	}
+	hold.ID = s.newID()
+	hold.Status = models.HoldActive
+	hold.TransactionID, hold.SettledAt = nil, nil
+	hold.CreatedAt = s.clock.Now()
+	s.state.walletHolds = append(s.state.walletHolds, hold)
+	return hold, nil
+}
+
+func (s *MemoryStore) FindHold(_ context.Context, id int64) (models.WalletHold, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := slices.IndexFunc(s.state.walletHolds, func(h models.WalletHold) bool { return h.ID == id })
+	if i < 0 {
+		return models.WalletHold{}, storage.ErrNotFound
+	}
+	return s.state.walletHolds[i], nil
+}
+
+func (s *MemoryStore) CaptureHold(_ context.Context, id int64, reason string) (models.WalletHold, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	h, ok := s.activeHold(id)
+	if !ok {
+		return models.WalletHold{}, storage.ErrNotFound
+	}
+	i, _ := s.userIndex(h.UserID)
+	now := s.clock.Now()
+	balance := math.Round((s.state.users[i].Balance-h.Amount)*100) / 100
+	entry := models.Transaction{ID: s.newID(), UserID: h.UserID, Amount: -h.Amount, BalanceAfter: balance, Reason: reason, Reference: h.Reference, CreatedAt: now}
+	if err := s.postJournal(models.JournalFor(entry)); err != nil {
+		return models.WalletHold{}, err
+	}
+	s.state.users[i].Balance = balance
+	s.state.users[i].Version++
+	s.state.ledger = append(s.state.ledger, entry)
+	h.Status, h.TransactionID, h.SettledAt = models.HoldCaptured, &entry.ID, &now
+	return *h, nil
+}
+
+func (s *MemoryStore) ReleaseHold(_ context.Context, id int64) (models.WalletHold, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	h, ok := s.activeHold(id)
+	if !ok {
+		return models.WalletHold{}, storage.ErrNotFound
+	}
+	now := s.clock.Now()
+	h.Status, h.SettledAt = models.HoldReleased, &now
+	return *h, nil
+}
+
+func (s *MemoryStore) HeldBalance(_ context.Context, userID int64) (float64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.userIndex(userID); !ok {
+		return 0, storage.ErrNotFound
+	}
+	return s.heldBalance(userID), nil
+}
+
+// activeHold finds an active hold; callers hold s.mu.
+func (s *MemoryStore) activeHold(id int64) (*models.WalletHold, bool) {
+	i := slices.IndexFunc(s.state.walletHolds, func(h models.WalletHold) bool { return h.ID == id && h.Status == models.HoldActive })
+	if i < 0 {
+		return nil, false
+	}
+	return &s.state.walletHolds[i], true
+}
+
+// heldBalance totals the user's active holds; callers hold s.mu.
+func (s *MemoryStore) heldBalance(userID int64) float64 {
+	var held float64
+	for _, h := range s.state.walletHolds {
+		if h.UserID == userID && h.Status == models.HoldActive {
+			held += h.Amount
+		}
+	}
+	return math.Round(held*100) / 100
+}
+
+// postJournal records an entry that balances, first opening the wallet
+// accounts it posts to with the balance their users hold, and other accounts
+// on first use; callers hold s.mu and post before they move the balance.
+func (s *MemoryStore) postJournal(journal models.JournalEntry) error {
+	if len(journal.Lines) == 0 {
+		return nil
+	}
+	if !journal.Balanced() {
+		return storage.ErrUnbalancedJournal
+	}
+	for _, l := range journal.Lines {
+		if slices.Contains(s.state.ledgerAccounts, l.Account) {
+			continue
+		}
+		s.state.ledgerAccounts = append(s.state.ledgerAccounts, l.Account)
+		if models.AccountKind(l.Account) != models.AccountWallet {
+			continue
+		}
+		i := slices.IndexFunc(s.state.users, func(u models.User) bool { return models.WalletAccount(u.ID) == l.Account })
+		if err := s.postJournal(models.OpeningJournal(s.state.users[i].ID, s.state.users[i].Balance)); err != nil {
+			return err
+		}
+	}
+	s.state.journalSeq++
+	journal.ID = s.state.journalSeq
+	s.state.journal = append(s.state.journal, journal)
+	return nil
+}
+
+func (s *MemoryStore) TrialBalance(_ context.Context) ([]models.TrialBalanceLine, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	line := func(code string) string {
+		if models.AccountKind(code) == models.AccountWallet {
+			return models.AccountWallet
+		}
+		return code
+	}
+	totals := map[string]*models.TrialBalanceLine{}
+	for _, code := range s.state.ledgerAccounts {
+		key := line(code)
+		if totals[key] == nil {
+			totals[key] = &models.TrialBalanceLine{Account: key, Kind: models.AccountKind(code)}
+		}
+		totals[key].Accounts++
+	}
+	for _, j := range s.state.journal {
+		for _, l := range j.Lines {
+			t := totals[line(l.Account)]
+			t.Debits = math.Round((t.Debits+l.Debit)*100) / 100
+			t.Credits = math.Round((t.Credits+l.Credit)*100) / 100
+		}
+	}
+	lines := []models.TrialBalanceLine{}
+	for _, key := range slices.Sorted(maps.Keys(totals)) {
+		lines = append(lines, *totals[key])
+	}
+	return lines, nil
+}
+
+func (s *MemoryStore) ContributeJackpot(_ context.Context, name string, amount float64, reference string) (models.Jackpot, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.postJournal(models.JackpotContributionJournal(name, amount, reference)); err != nil {
+		return models.Jackpot{}, err
+	}
+	now := s.clock.Now()
+	i := slices.IndexFunc(s.state.jackpots, func(j models.Jackpot) bool { return j.Name == name })
+	if i < 0 {
+		i = len(s.state.jackpots)
+		s.state.jackpots = append(s.state.jackpots, models.Jackpot{Name: name})
+	}
+	pot := &s.state.jackpots[i]
+	pot.Pot = math.Round((pot.Pot+amount)*100) / 100
+	pot.UpdatedAt = &now
+	return *pot, nil
+}
+
+func (s *MemoryStore) ListJackpots(_ context.Context) ([]models.Jackpot, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	pots := append([]models.Jackpot{}, s.state.jackpots...)
+	slices.SortFunc(pots, func(a, b models.Jackpot) int { return strings.Compare(a.Name, b.Name) })
+	return pots, nil
+}
+
+func (s *MemoryStore) EmptyJackpot(_ context.Context, name string) (float64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := slices.IndexFunc(s.state.jackpots, func(j models.Jackpot) bool { return j.Name == name })
+	if i < 0 {
+		return 0, nil
+	}
+	now := s.clock.Now()
+	won := s.state.jackpots[i].Pot
+	s.state.jackpots[i].Pot, s.state.jackpots[i].UpdatedAt = 0, &now
+	return won, nil
+}
+
+func (s *MemoryStore) CreateTournament(_ context.Context, t models.Tournament) (models.Tournament, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t.ID = s.newID()
+	t.EntryFee = math.Round(t.EntryFee*100) / 100
+	t.PrizeShares = slices.Clone(t.PrizeShares)
+	t.PrizePool, t.Entrants, t.Status, t.ClosedAt = 0, 0, models.TournamentOpen, nil
+	t.CreatedAt = s.clock.Now()
+	s.state.tournaments = append(s.state.tournaments, t)
+	return t, nil
+}
+
+func (s *MemoryStore) FindTournament(_ context.Context, id int64) (models.Tournament, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := slices.IndexFunc(s.state.tournaments, func(t models.Tournament) bool { return t.ID == id })
+	if i < 0 {
+		return models.Tournament{}, storage.ErrNotFound
+	}
+	return s.state.tournaments[i], nil
+}
+
+func (s *MemoryStore) ListTournaments(_ context.Context, limit int) ([]models.Tournament, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := append([]models.Tournament{}, s.state.tournaments...)
+	slices.SortStableFunc(out, func(a, b models.Tournament) int {
+		if c := b.StartsAt.Compare(a.StartsAt); c != 0 {
+			return c
+		}
+		return cmp.Compare(b.ID, a.ID)
+	})
+	if len(out) > limit {
+		out = out[:limit]
+	}
+	return out, nil
+}
+
+func (s *MemoryStore) ListEndedTournaments(_ context.Context, at time.Time) ([]models.Tournament, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := []models.Tournament{}
+	for _, t := range s.state.tournaments {
+		if t.Status == models.TournamentOpen && !t.EndsAt.After(at) {
+			out = append(out, t)
+		}
+	}
+	slices.SortStableFunc(out, func(a, b models.Tournament) int { return a.EndsAt.Compare(b.EndsAt) })
+	return out, nil
+}
+
+func (s *MemoryStore) EnterTournament(_ context.Context, tournamentID, userID int64, fee float64) (models.TournamentEntry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := slices.IndexFunc(s.state.tournaments, func(t models.Tournament) bool { return t.ID == tournamentID && t.Status == models.TournamentOpen })
+	u, ok := s.userIndex(userID)
+	if i < 0 || !ok {
+		return models.TournamentEntry{}, storage.ErrNotFound
+	}
+	if slices.ContainsFunc(s.state.entries, func(e models.TournamentEntry) bool { return e.TournamentID == tournamentID && e.UserID == userID }) {
+		return models.TournamentEntry{}, storage.ErrAlreadyExists
+	}
+	t := &s.state.tournaments[i]
+	t.PrizePool = math.Round((t.PrizePool+fee)*100) / 100
+	t.Entrants++
+	entry := models.TournamentEntry{TournamentID: tournamentID, UserID: userID, EnteredAt: s.clock.Now()}
+	s.state.entries = append(s.state.entries, entry)
+	entry.Username = s.state.users[u].Username
+	return entry, nil
+}
+
+func (s *MemoryStore) RecordTournamentScore(_ context.Context, tournamentID, userID int64, score float64, at time.Time) (models.TournamentEntry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := slices.IndexFunc(s.state.entries, func(e models.TournamentEntry) bool { return e.TournamentID == tournamentID && e.UserID == userID })
+	if i < 0 {
+		return models.TournamentEntry{}, storage.ErrNotFound
+	}
+	if e := &s.state.entries[i]; e.ScoredAt == nil || e.Score < score {
+		e.Score, e.ScoredAt = score, &at
+	}
+	return s.withUsername(s.state.entries[i]), nil
+}
+
+func (s *MemoryStore) ListTournamentEntries(_ context.Context, tournamentID int64) ([]models.TournamentEntry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := []models.TournamentEntry{}
+	for _, e := range s.state.entries {
+		if e.TournamentID == tournamentID {
+			out = append(out, s.withUsername(e))
+		}
+	}
+	slices.SortStableFunc(out, func(a, b models.TournamentEntry) int {
+		switch {
+		case (a.ScoredAt == nil) != (b.ScoredAt == nil):
+			if a.ScoredAt == nil {
+				return 1
+			}
+			return -1
+		case a.Score != b.Score:
+			return cmp.Compare(b.Score, a.Score)
+		case a.ScoredAt != nil && !a.ScoredAt.Equal(*b.ScoredAt):
+			return a.ScoredAt.Compare(*b.ScoredAt)
+		case !a.EnteredAt.Equal(b.EnteredAt):
+			return a.EnteredAt.Compare(b.EnteredAt)
+		}
+		return cmp.Compare(a.UserID, b.UserID)
+	})
+	return out, nil
+}
+
+func (s *MemoryStore) CloseTournament(_ context.Context, id int64, at time.Time) (models.Tournament, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := slices.IndexFunc(s.state.tournaments, func(t models.Tournament) bool {
+		return t.ID == id && t.Status == models.TournamentOpen && !t.EndsAt.After(at)
+	})
+	if i < 0 {
+		return models.Tournament{}, storage.ErrNotFound
+	}
+	t := &s.state.tournaments[i]
+	t.Status, t.ClosedAt = models.TournamentClosed, &at
+	return *t, nil
+}
+
+func (s *MemoryStore) SetTournamentResult(_ context.Context, tournamentID, userID int64, rank int, prize float64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := slices.IndexFunc(s.state.entries, func(e models.TournamentEntry) bool { return e.TournamentID == tournamentID && e.UserID == userID })
+	if i < 0 {
+		return storage.ErrNotFound
+	}
+	s.state.entries[i].Rank, s.state.entries[i].Prize = rank, math.Round(prize*100)/100
+	return nil
+}
+
+// withUsername fills in the entrant's username; callers hold s.mu.
+func (s *MemoryStore) withUsername(e models.TournamentEntry) models.TournamentEntry {
+	if i, ok := s.userIndex(e.UserID); ok {
+		e.Username = s.state.users[i].Username
+	}
+	return e
+}
+
+func (s *MemoryStore) CreateMessage(_ context.Context, m models.Message) (models.Message, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.userIndex(m.UserID); !ok {
+		return models.Message{}, storage.ErrNotFound
+	}
+	m.ID, m.ReadAt, m.CreatedAt = s.newID(), nil, s.clock.Now()
+	s.state.messages = append(s.state.messages, m)
+	return m, nil
+}
+
+func (s *MemoryStore) BroadcastMessage(_ context.Context, m models.Message, roles []string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := 0
+	for _, u := range s.state.users {
+		if u.MergedInto != nil || (len(roles) > 0 && !slices.Contains(roles, u.Role)) {
+			continue
+		}
+		copied := m
+		copied.ID, copied.UserID, copied.ReadAt, copied.CreatedAt = s.newID(), u.ID, nil, s.clock.Now()
+		s.state.messages = append(s.state.messages, copied)
+		n++
+	}
+	return n, nil
+}
+
+func (s *MemoryStore) ListMessages(_ context.Context, userID int64, unreadOnly bool, limit int) ([]models.Message, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := []models.Message{}
+	for _, m := range slices.Backward(s.state.messages) {
+		if m.UserID == userID && (!unreadOnly || m.ReadAt == nil) && len(out) < limit {
+			out = append(out, m)
+		}
+	}
+	return out, nil
+}
+
+func (s *MemoryStore) ListMessagesAfter(_ context.Context, userID, after int64, limit int) ([]models.Message, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := []models.Message{}
+	for _, m := range s.state.messages {
+		if m.UserID == userID && m.ID > after && len(out) < limit {
+			out = append(out, m)
+		}
+	}
+	return out, nil
+}
+
+func (s *MemoryStore) LastMessageID(_ context.Context, userID int64) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var id int64
+	for _, m := range s.state.messages {
+		if m.UserID == userID {
+			id = max(id, m.ID)
+		}
+	}
+	return id, nil
+}
+
+func (s *MemoryStore) CountUnreadMessages(_ context.Context, userID int64) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := 0
+	for _, m := range s.state.messages {
+		if m.UserID == userID && m.ReadAt == nil {
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (s *MemoryStore) MarkMessagesRead(_ context.Context, userID int64, ids []int64, at time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := 0
+	for i, m := range s.state.messages {
+		if m.UserID == userID && m.ReadAt == nil && (len(ids) == 0 || slices.Contains(ids, m.ID)) {
+			s.state.messages[i].ReadAt = &at
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (s *MemoryStore) CreateTicket(_ context.Context, t models.SupportTicket, body string) (models.SupportTicket, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.userIndex(t.UserID); !ok {
+		return models.SupportTicket{}, storage.ErrNotFound
+	}
+	t.ID, t.CreatedAt, t.ClosedAt = s.newID(), s.clock.Now(), nil
+	t.UpdatedAt = t.CreatedAt
+	s.state.tickets = append(s.state.tickets, t)
+	s.state.ticketReplies = append(s.state.ticketReplies, models.TicketReply{ID: s.newID(), TicketID: t.ID, AuthorID: t.UserID, Body: body, CreatedAt: t.CreatedAt})
+	return s.withTicketUsername(t), nil
+}
+
+func (s *MemoryStore) FindTicket(_ context.Context, id int64) (models.SupportTicket, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := slices.IndexFunc(s.state.tickets, func(t models.SupportTicket) bool { return t.ID == id })
+	if i < 0 {
+		return models.SupportTicket{}, storage.ErrNotFound
+	}
+	return s.withTicketUsername(s.state.tickets[i]), nil
+}
+
+func (s *MemoryStore) ListUserTickets(_ context.Context, userID int64, limit int) ([]models.SupportTicket, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := []models.SupportTicket{}
+	for _, t := range s.state.tickets {
+		if t.UserID == userID {
+			out = append(out, s.withTicketUsername(t))
+		}
+	}
+	slices.SortStableFunc(out, func(a, b models.SupportTicket) int {
+		if c := b.UpdatedAt.Compare(a.UpdatedAt); c != 0 {
+			return c
+		}
+		return cmp.Compare(b.ID, a.ID)
+	})
+	if len(out) > limit {
+		out = out[:limit]
+	}
+	return out, nil
+}
+
+func (s *MemoryStore) ListTicketQueue(_ context.Context, statuses []string, limit int) ([]models.SupportTicket, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := []models.SupportTicket{}
+	for _, t := range s.state.tickets {
+		if slices.Contains(statuses, t.Status) {
+			out = append(out, s.withTicketUsername(t))
+		}
+	}
+	slices.SortStableFunc(out, func(a, b models.SupportTicket) int {
+		if (a.Priority == models.TicketPriorityHigh) != (b.Priority == models.TicketPriorityHigh) {
+			if a.Priority == models.TicketPriorityHigh {
+				return -1
+			}
+			return 1
+		}
+		if c := a.UpdatedAt.Compare(b.UpdatedAt); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.ID, b.ID)
+	})
+	if len(out) > limit {
+		out = out[:limit]
+	}
+	return out, nil
+}
+
+func (s *MemoryStore) AddTicketReply(_ context.Context, reply models.TicketReply, from []string, status string) (models.TicketReply, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := slices.IndexFunc(s.state.tickets, func(t models.SupportTicket) bool { return t.ID == reply.TicketID && slices.Contains(from, t.Status) })
+	if i < 0 {
+		return models.TicketReply{}, storage.ErrNotFound
+	}
+	t := &s.state.tickets[i]
+	t.Status, t.UpdatedAt, t.ClosedAt = status, s.clock.Now(), nil
+	reply.ID, reply.CreatedAt = s.newID(), t.UpdatedAt
+	s.state.ticketReplies = append(s.state.ticketReplies, reply)
+	reply.AuthorUsername = s.username(reply.AuthorID)
+	return reply, nil
+}
+
+func (s *MemoryStore) ListTicketReplies(_ context.Context, ticketID int64) ([]models.TicketReply, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := []models.TicketReply{}
+	for _, r := range s.state.ticketReplies {
+		if r.TicketID == ticketID {
+			r.AuthorUsername = s.username(r.AuthorID)
+			out = append(out, r)
+		}
+	}
+	return out, nil
+}
+
+func (s *MemoryStore) SetTicketStatus(_ context.Context, id int64, from []string, status string) (models.SupportTicket, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := slices.IndexFunc(s.state.tickets, func(t models.SupportTicket) bool { return t.ID == id && slices.Contains(from, t.Status) })
+	if i < 0 {
+		return models.SupportTicket{}, storage.ErrNotFound
+	}
+	t := &s.state.tickets[i]
+	t.Status, t.UpdatedAt, t.ClosedAt = status, s.clock.Now(), nil
+	if status == models.TicketClosed {
+		t.ClosedAt = &t.UpdatedAt
+	}
+	return s.withTicketUsername(*t), nil
+}
+
+func (s *MemoryStore) AddTicketAttachment(_ context.Context, a models.TicketAttachment) (models.TicketAttachment, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !slices.ContainsFunc(s.state.tickets, func(t models.SupportTicket) bool { return t.ID == a.TicketID }) {
+		return models.TicketAttachment{}, storage.ErrNotFound
+	}
+	a.ID, a.URL, a.CreatedAt = s.newID(), "", s.clock.Now()
+	s.state.attachments = append(s.state.attachments, a)
+	return a, nil
+}
+
+func (s *MemoryStore) ListTicketAttachments(_ context.Context, ticketID int64) ([]models.TicketAttachment, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := []models.TicketAttachment{}
+	for _, a := range s.state.attachments {
+		if a.TicketID == ticketID {
+			out = append(out, a)
+		}
+	}
+	return out, nil
+}
+
+// withTicketUsername fills in the ticket owner's username; callers hold s.mu.
+func (s *MemoryStore) withTicketUsername(t models.SupportTicket) models.SupportTicket {
+	t.Username = s.username(t.UserID)
+	return t
+}
+
+// CheckUserVersion needs no lock: units of work already run one at a time.
+func (s *MemoryStore) CheckUserVersion(_ context.Context, userID, version int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i, ok := s.userIndex(userID)
+	if !ok {
+		return storage.ErrNotFound
+	}
+	if s.state.users[i].Version != version {
+		return storage.ErrVersionConflict
+	}
+	return nil
+}
+
+func (s *MemoryStore) ListTransactionsAfter(_ context.Context, userID, after int64, limit int) ([]models.Transaction, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	transactions := []models.Transaction{}
+	for _, t := range s.state.ledger {
+		if t.UserID == userID && t.ID > after && len(transactions) < limit {
+			transactions = append(transactions, t)
+		}
+	}
+	return transactions, nil
+}
+
+func (s *MemoryStore) LastTransactionID(_ context.Context, userID int64) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var id int64
+	for _, t := range s.state.ledger {
+		if t.UserID == userID {
+			id = max(id, t.ID)
+		}
+	}
+	return id, nil
+}
+
+func (s *MemoryStore) ListTransactions(_ context.Context, userID int64) ([]models.Transaction, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	transactions := []models.Transaction{}
+	for _, ledger := range [][]models.Transaction{s.state.archived.ledger, s.state.ledger} {
+		for _, t := range ledger {
+			if t.UserID == userID {
+				transactions = append(transactions, t)
+			}
+		}
+	}
+	slices.SortFunc(transactions, func(a, b models.Transaction) int { return cmp.Compare(a.ID, b.ID) })
+	return transactions, nil
+}
+
+func (s *MemoryStore) FindTransaction(_ context.Context, id int64) (models.Transaction, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, ledger := range [][]models.Transaction{s.state.ledger, s.state.archived.ledger} {
+		if i := slices.IndexFunc(ledger, func(t models.Transaction) bool { return t.ID == id }); i >= 0 {
+			return ledger[i], nil
+		}
+	}
+	return models.Transaction{}, storage.ErrNotFound
+}
+
+func (s *MemoryStore) SumTransactions(_ context.Context, userID int64, reason string, since time.Time) (float64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var total float64
+	for _, t := range s.state.ledger {
+		if t.UserID == userID && t.Reason == reason && !t.CreatedAt.Before(since) {
+			total += t.Amount
+		}
+	}
+	return math.Round(total*100) / 100, nil
+}
+
+func (s *MemoryStore) ClaimOperation(_ context.Context, op models.Operation) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := [2]string{op.Kind, op.Key}
+	if _, ok := s.state.operations[key]; ok {
+		return storage.ErrAlreadyExists
+	}
+	if _, ok := s.userIndex(op.UserID); !ok {
+		return storage.ErrNotFound
+	}
+	op.TransactionID = nil
+	op.CreatedAt = s.clock.Now()
+	s.state.operations[key] = op
+	return nil
+}
+
+func (s *MemoryStore) FindOperation(_ context.Context, kind, key string) (models.Operation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	op, ok := s.state.operations[[2]string{kind, key}]
+	if !ok {
+		return models.Operation{}, storage.ErrNotFound
+	}
+	return op, nil
+}
+
+func (s *MemoryStore) CompleteOperation(_ context.Context, kind, key string, transactionID int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	op, ok := s.state.operations[[2]string{kind, key}]
+	if !ok {
+		return storage.ErrNotFound
+	}
+	op.TransactionID = &transactionID
+	s.state.operations[[2]string{kind, key}] = op
+	return nil
+}
+
+func (s *MemoryStore) ArchiveRecords(_ context.Context, dataset string, before time.Time, limit int) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch dataset {
+	case models.ArchiveLedger:
+		return archiveOldest(&s.state.ledger, &s.state.archived.ledger, before, limit, func(t models.Transaction) (time.Time, bool) {
+			return t.CreatedAt, s.held(t.UserID, dataset)
+		}), nil
+	case models.ArchiveLoginHistory:
+		return archiveOldest(&s.state.logins, &s.state.archived.logins, before, limit, func(a models.LoginAttempt) (time.Time, bool) {
+			return a.CreatedAt, a.UserID != nil && s.held(*a.UserID, dataset)
+		}), nil
+	default:
+		return 0, fmt.Errorf("archive: unknown dataset %q", dataset)
+	}
+}
+
+// archiveOldest moves up to limit rows created before cutoff from hot to cold,
+// leaving held rows. Both slices stay in insertion (ID) order.
+func archiveOldest[T any](hot, cold *[]T, before time.Time, limit int, describe func(T) (created time.Time, held bool)) int {
+	moved := 0
+	*hot = slices.DeleteFunc(*hot, func(row T) bool {
+		if created, held := describe(row); moved < limit && created.Before(before) && !held {
+			*cold = append(*cold, row)
+			moved++
+			return true
+		}
+		return false
+	})
+	return moved
+}
+
+func (s *MemoryStore) ListOnboardingJourneys(_ context.Context) ([]models.OnboardingJourney, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	journeys := slices.Collect(maps.Values(s.state.journeys))
+	slices.SortFunc(journeys, func(a, b models.OnboardingJourney) int { return strings.Compare(a.Tenant, b.Tenant) })
+	return journeys, nil
+}
+
+func (s *MemoryStore) FindOnboardingJourney(_ context.Context, tenant string) (models.OnboardingJourney, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	journey, ok := s.state.journeys[tenant]
+	if !ok {
+		return models.OnboardingJourney{}, storage.ErrNotFound
+	}
+	return journey, nil
+}
+
+func (s *MemoryStore) SaveOnboardingJourney(_ context.Context, journey models.OnboardingJourney) (models.OnboardingJourney, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	journey.Steps = slices.Clone(journey.Steps)
+	journey.UpdatedAt = s.clock.Now()
+	s.state.journeys[journey.Tenant] = journey
+	return journey, nil
+}
+
+func (s *MemoryStore) DeleteOnboardingJourney(_ context.Context, tenant string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.state.journeys[tenant]; !ok {
+		return storage.ErrNotFound
+	}
+	delete(s.state.journeys, tenant)
+	return nil
+}
+
+func (s *MemoryStore) ListOnboardingProgress(_ context.Context, userID int64) ([]models.OnboardingProgress, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	progress := []models.OnboardingProgress{}
+	for _, p := range s.state.progress {
+		if p.UserID == userID {
+			progress = append(progress, p)
+		}
+	}
+	return progress, nil
+}
+
+func (s *MemoryStore) CompleteOnboardingStep(_ context.Context, userID int64, stepKey string, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p, err := s.onboardingProgress(userID, stepKey)
+	if err != nil {
+		return err
+	}
+	if p.CompletedAt != nil {
+		return storage.ErrAlreadyExists
+	}
+	p.CompletedAt = &at
+	return nil
+}
+
+func (s *MemoryStore) ClaimOnboardingNudge(_ context.Context, userID int64, stepKey string, at, since time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p, err := s.onboardingProgress(userID, stepKey)
+	if err != nil {
+		return err
+	}
+	if p.NudgedAt != nil && p.NudgedAt.After(since) {
+		return storage.ErrAlreadyExists
+	}
+	p.NudgedAt = &at
+	return nil
+}
+
+// onboardingProgress returns the user's progress row for the step, adding an
+// empty one if needed. s.mu must be held.
+func (s *MemoryStore) onboardingProgress(userID int64, stepKey string) (*models.OnboardingProgress, error) {
+	if _, ok := s.userIndex(userID); !ok {
+		return nil, storage.ErrNotFound
+	}
+	i := slices.IndexFunc(s.state.progress, func(p models.OnboardingProgress) bool { return p.UserID == userID && p.StepKey == stepKey })
+	if i < 0 {
+		s.state.progress = append(s.state.progress, models.OnboardingProgress{UserID: userID, StepKey: stepKey})
+		i = len(s.state.progress) - 1
+	}
+	return &s.state.progress[i], nil
+}
+
+func (s *MemoryStore) RecentBigWins(_ context.Context, minAmount float64, limit int) ([]models.BigWin, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	wins := []models.BigWin{}
+	for i := len(s.state.ledger) - 1; i >= 0 && len(wins) < limit; i-- {
+		t := s.state.ledger[i]
+		if t.Reason != models.TransactionBetSettlement || t.Amount < minAmount {
+			continue
+		}
+		u, ok := s.userIndex(t.UserID)
+		if !ok {
+			continue
+		}
+		activity := s.privacySettings(s.state.users[u]).PublicActivity
+		if activity == models.ActivityHidden {
+			continue
+		}
+		wins = append(wins, models.BigWin{Amount: t.Amount, WonAt: t.CreatedAt, Username: s.state.users[u].Username, PublicActivity: activity})
+	}
+	return wins, nil
+}
+
+func (s *MemoryStore) FindPrivacySettings(_ context.Context, userID int64) (models.PrivacySettings, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i, ok := s.userIndex(userID)
+	if !ok {
+		return models.PrivacySettings{}, storage.ErrNotFound
+	}
+	return s.privacySettings(s.state.users[i]), nil
+}
+
+func (s *MemoryStore) SavePrivacySettings(_ context.Context, settings models.PrivacySettings) (models.PrivacySettings, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.userIndex(settings.UserID); !ok {
+		return models.PrivacySettings{}, storage.ErrNotFound
+	}
+	settings.UpdatedAt = s.clock.Now()
+	s.state.privacy[settings.UserID] = settings
+	return settings, nil
+}
+
+// privacySettings returns the user's saved settings or the defaults. s.mu must be held.
+func (s *MemoryStore) privacySettings(user models.User) models.PrivacySettings {
+	if settings, ok := s.state.privacy[user.ID]; ok {
+		return settings
+	}
+	return models.PrivacySettings{UserID: user.ID, PublicActivity: models.ActivityMasked, UpdatedAt: user.CreatedAt}
+}
+
+func (s *MemoryStore) CreateDataExport(_ context.Context, export models.DataExport) (models.DataExport, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.userIndex(export.UserID); !ok {
+		return models.DataExport{}, storage.ErrNotFound
+	}
+	export.ID = s.newID()
+	export.CreatedAt = s.clock.Now()
+	s.state.exports = append(s.state.exports, export)
+	return export, nil
+}
+
+func (s *MemoryStore) FindDataExport(_ context.Context, id int64) (models.DataExport, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := slices.IndexFunc(s.state.exports, func(e models.DataExport) bool { return e.ID == id })
+	if i < 0 {
+		return models.DataExport{}, storage.ErrNotFound
+	}
+	return s.state.exports[i], nil
+}
+
+func (s *MemoryStore) ListDataExports(_ context.Context, userID int64) ([]models.DataExport, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	exports := []models.DataExport{}
+	for i := len(s.state.exports) - 1; i >= 0; i-- {
+		if s.state.exports[i].UserID == userID {
+			exports = append(exports, s.state.exports[i])
+		}
+	}
+	return exports, nil
+}
+
+func (s *MemoryStore) FinishDataExport(_ context.Context, id int64, status, blobKey string, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := slices.IndexFunc(s.state.exports, func(e models.DataExport) bool { return e.ID == id && e.Status == models.ExportPending })
+	if i < 0 {
+		return storage.ErrNotFound
+	}
+	s.state.exports[i].Status = status
+	s.state.exports[i].BlobKey = blobKey
+	s.state.exports[i].CompletedAt = &at
+	return nil
+}
+
+func (s *MemoryStore) ReconcileBalances(_ context.Context) (int, []models.BalanceDiscrepancy, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ledger := slices.Concat(s.state.archived.ledger, s.state.ledger)
+	slices.SortFunc(ledger, func(a, b models.Transaction) int { return cmp.Compare(a.ID, b.ID) })
+	// Entries moved in by a merge are totalled from the duplicate's own
+	// opening balance, like the Postgres query does.
+	opened := map[[2]int64]bool{}
+	totals := map[int64]float64{}
+	for _, t := range ledger {
+		chain := [2]int64{t.UserID, t.UserID}
+		if t.MergedFrom != nil {
+			chain[1] = *t.MergedFrom
+		}
+		if !opened[chain] {
+			opened[chain] = true
+			totals[t.UserID] += t.BalanceAfter - t.Amount
+		}
+		totals[t.UserID] += t.Amount
+	}
+	checked, discrepancies := 0, []models.BalanceDiscrepancy{}
+	for _, u := range s.state.users {
+		total, ok := totals[u.ID]
+		if !ok {
+			continue
+		}
+		checked++
+		// Round to cents like the NUMERIC(24,2) columns do.
+		if total = math.Round(total*100) / 100; total != u.Balance {
+			discrepancies = append(discrepancies, models.BalanceDiscrepancy{UserID: u.ID, Username: u.Username, Balance: u.Balance, LedgerBalance: total})
+		}
+	}
+	slices.SortFunc(discrepancies, func(a, b models.BalanceDiscrepancy) int { return cmp.Compare(a.UserID, b.UserID) })
+	return checked, discrepancies, nil
+}
+
+func (s *MemoryStore) CreateReconciliationReport(_ context.Context, report models.ReconciliationReport) (models.ReconciliationReport, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	report.ID = s.newID()
+	report.CreatedAt = s.clock.Now()
+	s.state.reports = append(s.state.reports, report)
+	return report, nil
+}
+
+func (s *MemoryStore) ListReconciliationReports(_ context.Context, limit int) ([]models.ReconciliationReport, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	reports := []models.ReconciliationReport{}
+	for i := len(s.state.reports) - 1; i >= 0 && len(reports) < limit; i-- {
+		reports = append(reports, s.state.reports[i])
+	}
+	return reports, nil
+}
+
+// RefreshLeaderboards snapshots each player's values, like the Postgres
+// materialized view; later ledger entries are not ranked until the next refresh.
+func (s *MemoryStore) RefreshLeaderboards(_ context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.clock.Now()
+	standings := map[int64]map[[2]string]float64{}
+	for _, u := range s.state.users {
+		if !slices.Contains(models.PlayerRoles, u.Role) {
+			continue
+		}
+		standings[u.ID] = map[[2]string]float64{{models.LeaderboardBalance, models.LeaderboardAllTime}: u.Balance}
+	}
+	windows := map[string]time.Time{
+		models.LeaderboardDaily:   now.Add(-24 * time.Hour),
+		models.LeaderboardWeekly:  now.Add(-7 * 24 * time.Hour),
+		models.LeaderboardAllTime: {},
+	}
+	for _, t := range slices.Concat(s.state.archived.ledger, s.state.ledger) {
+		values, ok := standings[t.UserID]
+		if !ok || t.Reason != models.TransactionBetSettlement {
+			continue
+		}
+		for window, since := range windows {
+			if t.CreatedAt.Before(since) {
+				continue
+			}
+			values[[2]string{models.LeaderboardGamesPlayed, window}]++
+			if t.Amount > 0 {
+				values[[2]string{models.LeaderboardWinnings, window}] += t.Amount
+			}
+		}
+	}
+	s.state.standings = standings
+	return nil
+}
+
+func (s *MemoryStore) ListLeaderboard(_ context.Context, metric, window string, limit int) ([]models.LeaderboardEntry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entries := s.visibleStandings(metric, window)
+	return entries[:min(len(entries), limit)], nil
+}
+
+func (s *MemoryStore) FindLeaderboardEntry(_ context.Context, metric, window string, userID int64) (models.LeaderboardEntry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, e := range s.visibleStandings(metric, window) {
+		if e.UserID == userID {
+			return e, nil
+		}
+	}
+	return models.LeaderboardEntry{}, storage.ErrNotFound
+}
+
+// visibleStandings ranks players who have not hidden their activity, best
+// first, with ties sharing a rank. s.mu must be held.
+func (s *MemoryStore) visibleStandings(metric, window string) []models.LeaderboardEntry {
+	entries := []models.LeaderboardEntry{}
+	for userID, values := range s.state.standings {
+		value := values[[2]string{metric, window}]
+		i, ok := s.userIndex(userID)
+		if value <= 0 || !ok {
+			continue
+		}
+		activity := s.privacySettings(s.state.users[i]).PublicActivity
+		if activity == models.ActivityHidden {
+			continue
+		}
+		entries = append(entries, models.LeaderboardEntry{UserID: userID, Username: s.state.users[i].Username, PublicActivity: activity, Value: value})
+	}
+	slices.SortFunc(entries, func(a, b models.LeaderboardEntry) int {
+		return cmp.Or(cmp.Compare(b.Value, a.Value), cmp.Compare(a.UserID, b.UserID))
+	})
+	for i := range entries {
+		entries[i].Rank = i + 1
+		if i > 0 && entries[i].Value == entries[i-1].Value {
+			entries[i].Rank = entries[i-1].Rank
+		}
+	}
+	return entries
+}
+
+func (s *MemoryStore) ListOAuthClients(_ context.Context) ([]models.OAuthClient, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	clients := slices.Clone(s.state.clients)
+	slices.SortFunc(clients, func(a, b models.OAuthClient) int { return cmp.Or(cmp.Compare(a.Name, b.Name), cmp.Compare(a.ID, b.ID)) })
+	return append([]models.OAuthClient{}, clients...), nil
+}
+
+func (s *MemoryStore) FindOAuthClient(_ context.Context, id string) (models.OAuthClient, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := slices.IndexFunc(s.state.clients, func(c models.OAuthClient) bool { return c.ID == id })
+	if i < 0 {
+		return models.OAuthClient{}, storage.ErrNotFound
+	}
+	return s.state.clients[i], nil
+}
+
+func (s *MemoryStore) CreateOAuthClient(_ context.Context, client models.OAuthClient) (models.OAuthClient, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if slices.ContainsFunc(s.state.clients, func(c models.OAuthClient) bool { return c.ID == client.ID }) {
+		return models.OAuthClient{}, storage.ErrAlreadyExists
+	}
+	client.Secret = ""
+	client.RedirectURIs = slices.Clone(client.RedirectURIs)
+	client.CreatedAt = s.clock.Now()
+	s.state.clients = append(s.state.clients, client)
+	return client, nil
+}
+
+func (s *MemoryStore) DeleteOAuthClient(_ context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := slices.IndexFunc(s.state.clients, func(c models.OAuthClient) bool { return c.ID == id })
+	if i < 0 {
+		return storage.ErrNotFound
+	}
+	s.state.clients = slices.Delete(s.state.clients, i, i+1)
+	s.state.codes = slices.DeleteFunc(s.state.codes, func(c models.AuthorizationCode) bool { return c.ClientID == id })
+	return nil
+}
+
+func (s *MemoryStore) CreateAuthorizationCode(_ context.Context, code models.AuthorizationCode) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, userOK := s.userIndex(code.UserID)
+	if !userOK || !slices.ContainsFunc(s.state.clients, func(c models.OAuthClient) bool { return c.ID == code.ClientID }) {
+		return storage.ErrNotFound
+	}
+	s.state.codes = append(s.state.codes, code)
+	return nil
+}
+
+func (s *MemoryStore) ConsumeAuthorizationCode(_ context.Context, codeHash string, now time.Time) (models.AuthorizationCode, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := slices.IndexFunc(s.state.codes, func(c models.AuthorizationCode) bool { return c.CodeHash == codeHash })
+	if i < 0 || !s.state.codes[i].ExpiresAt.After(now) {
+		return models.AuthorizationCode{}, storage.ErrNotFound
+	}
+	code := s.state.codes[i]
+	s.state.codes = slices.Delete(s.state.codes, i, i+1)
+	return code, nil
+}
+
+func (s *MemoryStore) ListIPRiskPolicies(context.Context) ([]models.IPRiskPolicy, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	policies := slices.Collect(maps.Values(s.state.ipPolicies))
+	slices.SortFunc(policies, func(a, b models.IPRiskPolicy) int {
+		return cmp.Or(cmp.Compare(a.Tenant, b.Tenant), cmp.Compare(a.Country, b.Country))
+	})
+	if policies == nil {
+		policies = []models.IPRiskPolicy{}
+	}
+	return policies, nil
+}
+
+func (s *MemoryStore) FindIPRiskPolicy(_ context.Context, tenant, country string) (models.IPRiskPolicy, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	policy, ok := s.state.ipPolicies[[2]string{tenant, country}]
+	if !ok {
+		return models.IPRiskPolicy{}, storage.ErrNotFound
+	}
+	return policy, nil
+}
+
+func (s *MemoryStore) UpsertIPRiskPolicy(_ context.Context, policy models.IPRiskPolicy) (models.IPRiskPolicy, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	policy.UpdatedAt = s.clock.Now()
+	s.state.ipPolicies[[2]string{policy.Tenant, policy.Country}] = policy
+	return policy, nil
+}
+
+func (s *MemoryStore) DeleteIPRiskPolicy(_ context.Context, tenant, country string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := [2]string{tenant, country}
+	if _, ok := s.state.ipPolicies[key]; !ok {
+		return storage.ErrNotFound
+	}
+	delete(s.state.ipPolicies, key)
+	return nil
+}
+
+func (s *MemoryStore) RecordIPRiskEvent(_ context.Context, event models.IPRiskEvent) (models.IPRiskEvent, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if event.UserID != nil {
+		if _, ok := s.userIndex(*event.UserID); !ok {
+			return models.IPRiskEvent{}, storage.ErrNotFound
+		}
+	}
+	event.ID = s.newID()
+	event.Signals = slices.Clone(event.Signals)
+	event.CreatedAt = s.clock.Now()
+	s.state.ipEvents = append(s.state.ipEvents, event)
+	return event, nil
+}
+
+func (s *MemoryStore) ListIPRiskEvents(_ context.Context, limit int) ([]models.IPRiskEvent, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	events := slices.Clone(s.state.ipEvents)
+	slices.Reverse(events)
+	if len(events) > limit {
+		events = events[:limit]
+	}
+	return append([]models.IPRiskEvent{}, events...), nil
+}
+
+func (s *MemoryStore) ListPromoCodes(context.Context) ([]models.PromoCode, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	codes := slices.Clone(s.state.promos)
+	slices.Reverse(codes)
+	if codes == nil {
+		codes = []models.PromoCode{}
+	}
+	return codes, nil
+}
+
+func (s *MemoryStore) FindPromoCode(_ context.Context, id int64) (models.PromoCode, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := slices.IndexFunc(s.state.promos, func(c models.PromoCode) bool { return c.ID == id })
+	if i < 0 {
+		return models.PromoCode{}, storage.ErrNotFound
+	}
+	return s.state.promos[i], nil
+}
+
+func (s *MemoryStore) FindPromoCodeByCode(_ context.Context, code string) (models.PromoCode, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := slices.IndexFunc(s.state.promos, func(c models.PromoCode) bool { return c.Code == code })
+	if i < 0 {
+		return models.PromoCode{}, storage.ErrNotFound
+	}
+	return s.state.promos[i], nil
+}
+
+func (s *MemoryStore) CreatePromoCode(_ context.Context, code models.PromoCode) (models.PromoCode, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if slices.ContainsFunc(s.state.promos, func(c models.PromoCode) bool { return c.Code == code.Code }) {
+		return models.PromoCode{}, storage.ErrAlreadyExists
+	}
+	code.ID = s.newID()
+	code.Roles = append([]string{}, code.Roles...)
+	code.Redemptions = 0
+	code.CreatedAt = s.clock.Now()
+	s.state.promos = append(s.state.promos, code)
+	return code, nil
+}
+
+func (s *MemoryStore) UpdatePromoCode(_ context.Context, code models.PromoCode) (models.PromoCode, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := slices.IndexFunc(s.state.promos, func(c models.PromoCode) bool { return c.ID == code.ID })
+	if i < 0 {
+		return models.PromoCode{}, storage.ErrNotFound
+	}
+	saved := s.state.promos[i]
+	saved.MaxRedemptions = code.MaxRedemptions
+	saved.PerUserLimit = code.PerUserLimit
+	saved.Roles = append([]string{}, code.Roles...)
+	saved.ExpiresAt = code.ExpiresAt
+	saved.Active = code.Active
+	s.state.promos[i] = saved
+	return saved, nil
+}
+
+func (s *MemoryStore) ClaimPromoRedemption(_ context.Context, id int64) (models.PromoCode, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := slices.IndexFunc(s.state.promos, func(c models.PromoCode) bool { return c.ID == id })
+	if i < 0 {
+		return models.PromoCode{}, storage.ErrNotFound
+	}
+	code := s.state.promos[i]
+	if code.MaxRedemptions > 0 && code.Redemptions >= code.MaxRedemptions {
+		return models.PromoCode{}, storage.ErrLimitReached
+	}
+	code.Redemptions++
+	s.state.promos[i] = code
+	return code, nil
+}
+
+func (s *MemoryStore) CountPromoRedemptions(_ context.Context, codeID, userID int64) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := 0
+	for _, r := range s.state.redeemed {
+		if r.CodeID == codeID && r.UserID == userID {
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (s *MemoryStore) CreatePromoRedemption(_ context.Context, redemption models.PromoRedemption) (models.PromoRedemption, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := slices.IndexFunc(s.state.promos, func(c models.PromoCode) bool { return c.ID == redemption.CodeID })
+	if _, ok := s.userIndex(redemption.UserID); !ok || i < 0 {
+		return models.PromoRedemption{}, storage.ErrNotFound
+	}
+	redemption.ID = s.newID()
+	redemption.Code = s.state.promos[i].Code
+	redemption.Campaign = s.state.promos[i].Campaign
+	redemption.CreatedAt = s.clock.Now()
+	s.state.redeemed = append(s.state.redeemed, redemption)
+	return redemption, nil
+}
+
+func (s *MemoryStore) ListPromoRedemptions(_ context.Context, campaign string, limit int) ([]models.PromoRedemption, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	redemptions := []models.PromoRedemption{}
+	for i := len(s.state.redeemed) - 1; i >= 0 && len(redemptions) < limit; i-- {
+		if s.state.redeemed[i].Campaign == campaign {
+			redemptions = append(redemptions, s.state.redeemed[i])
+		}
+	}
+	return redemptions, nil
+}
+
+func (s *MemoryStore) ListPromoCampaignReports(context.Context) ([]models.PromoCampaignReport, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	byCampaign := map[string]*models.PromoCampaignReport{}
+	players := map[string]map[int64]bool{}
+	for _, c := range s.state.promos {
+		if byCampaign[c.Campaign] == nil {
+			byCampaign[c.Campaign] = &models.PromoCampaignReport{Campaign: c.Campaign}
+			players[c.Campaign] = map[int64]bool{}
+		}
+		byCampaign[c.Campaign].Codes++
+	}
+	for _, r := range s.state.redeemed {
+		report := byCampaign[r.Campaign]
+		report.Redemptions++
+		report.Amount = math.Round((report.Amount+r.Amount)*100) / 100
+		players[r.Campaign][r.UserID] = true
+		report.Players = len(players[r.Campaign])
+		if at := r.CreatedAt; report.LastRedeemedAt == nil || at.After(*report.LastRedeemedAt) {
+			report.LastRedeemedAt = &at
+		}
+	}
+	reports := []models.PromoCampaignReport{}
+	for _, campaign := range slices.Sorted(maps.Keys(byCampaign)) {
+		reports = append(reports, *byCampaign[campaign])
+	}
+	return reports, nil
+}
+
+func (s *MemoryStore) CreateChallenge(_ context.Context, c models.Challenge) (models.Challenge, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.userIndex(c.UserID); !ok {
+		return models.Challenge{}, storage.ErrNotFound
+	}
+	c.ID = s.newID()
+	c.Attempts, c.TokenHash, c.PassedAt, c.UsedAt = 0, "", nil, nil
+	c.CreatedAt = s.clock.Now()
+	s.state.challenges = append(s.state.challenges, c)
+	return c, nil
+}
+
+func (s *MemoryStore) FindChallenge(_ context.Context, id int64) (models.Challenge, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := slices.IndexFunc(s.state.challenges, func(c models.Challenge) bool { return c.ID == id })
+	if i < 0 {
+		return models.Challenge{}, storage.ErrNotFound
+	}
+	return s.state.challenges[i], nil
+}
+
+func (s *MemoryStore) FailChallenge(_ context.Context, id int64) (models.Challenge, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := slices.IndexFunc(s.state.challenges, func(c models.Challenge) bool { return c.ID == id && c.PassedAt == nil })
+	if i < 0 {
+		return models.Challenge{}, storage.ErrNotFound
+	}
+	s.state.challenges[i].Attempts++
+	return s.state.challenges[i], nil
+}
+
+func (s *MemoryStore) PassChallenge(_ context.Context, id int64, tokenHash string, at, expiresAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := slices.IndexFunc(s.state.challenges, func(c models.Challenge) bool { return c.ID == id && c.PassedAt == nil })
+	if i < 0 {
+		return storage.ErrNotFound
+	}
+	c := &s.state.challenges[i]
+	c.PassedAt, c.TokenHash, c.ExpiresAt = &at, tokenHash, expiresAt
+	return nil
+}
+
+func (s *MemoryStore) UseChallenge(_ context.Context, userID int64, action, tokenHash string, now time.Time) (models.Challenge, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := slices.IndexFunc(s.state.challenges, func(c models.Challenge) bool {
+		return tokenHash != "" && c.TokenHash == tokenHash && c.UserID == userID && c.Action == action && c.UsedAt == nil && c.ExpiresAt.After(now)
+	})
+	if i < 0 {
+		return models.Challenge{}, storage.ErrNotFound
+	}
+	s.state.challenges[i].UsedAt = &now
+	return s.state.challenges[i], nil
+}
+
+func (s *MemoryStore) CreateGameSession(_ context.Context, session models.GameSession) (models.GameSession, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.userIndex(session.UserID); !ok {
+		return models.GameSession{}, storage.ErrNotFound
+	}
+	if slices.ContainsFunc(s.state.games, func(g models.GameSession) bool { return g.TokenHash == session.TokenHash }) {
+		return models.GameSession{}, storage.ErrAlreadyExists
+	}
+	session.ID = s.newID()
+	session.StartedAt = nil
+	session.CreatedAt = s.clock.Now()
+	s.state.games = append(s.state.games, session)
+	return session, nil
+}
+
+func (s *MemoryStore) FindGameSession(_ context.Context, tokenHash string) (models.GameSession, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := slices.IndexFunc(s.state.games, func(g models.GameSession) bool { return g.TokenHash == tokenHash })
+	if i < 0 {
+		return models.GameSession{}, storage.ErrNotFound
+	}
+	return s.state.games[i], nil
+}
+
+func (s *MemoryStore) StartGameSession(_ context.Context, id int64, at, expiresAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := slices.IndexFunc(s.state.games, func(g models.GameSession) bool { return g.ID == id && g.StartedAt == nil })
+	if i < 0 {
+		return storage.ErrNotFound
+	}
+	s.state.games[i].StartedAt, s.state.games[i].ExpiresAt = &at, expiresAt
+	return nil
+}
+
+// QueryStats reports pg_stat_statements as unavailable; there is no query workload to sample.
+func (s *MemoryStore) QueryStats(context.Context, int) ([]models.QueryStat, bool, error) {
+	return nil, false, nil
+}
+
+func (s *MemoryStore) TableScanStats(context.Context) ([]models.TableScanStat, error) {
+	return []models.TableScanStat{}, nil
+}
+
+func (s *MemoryStore) CreateDatabaseInsightsReport(_ context.Context, report models.DatabaseInsightsReport) (models.DatabaseInsightsReport, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	report.ID = s.newID()
+	report.CreatedAt = s.clock.Now()
+	s.state.insights = append(s.state.insights, report)
+	return report, nil
+}
+
+func (s *MemoryStore) LatestDatabaseInsightsReport(context.Context) (models.DatabaseInsightsReport, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.state.insights) == 0 {
+		return models.DatabaseInsightsReport{}, storage.ErrNotFound
+	}
+	return s.state.insights[len(s.state.insights)-1], nil
+}
+
+func (s *MemoryStore) PlaceLegalHold(_ context.Context, hold models.LegalHold) (models.LegalHold, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.userIndex(hold.UserID); !ok {
+		return models.LegalHold{}, storage.ErrNotFound
+	}
+	hold.ID = s.newID()
+	hold.PlacedAt = s.clock.Now()
+	hold.ReleasedBy, hold.ReleasedAt = nil, nil
+	s.state.holds = append(s.state.holds, hold)
+	return hold, nil
+}
+
+func (s *MemoryStore) ListLegalHolds(_ context.Context, userID int64) ([]models.LegalHold, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	holds := []models.LegalHold{}
+	for _, h := range slices.Backward(s.state.holds) {
+		if h.UserID == userID {
+			holds = append(holds, h)
+		}
+	}
+	return holds, nil
+}
+
+func (s *MemoryStore) ReleaseLegalHold(_ context.Context, userID, id, releasedBy int64) (models.LegalHold, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := slices.IndexFunc(s.state.holds, func(h models.LegalHold) bool { return h.ID == id && h.UserID == userID && h.ReleasedAt == nil })
+	if i < 0 {
+		return models.LegalHold{}, storage.ErrNotFound
+	}
+	now := s.clock.Now()
+	s.state.holds[i].ReleasedBy, s.state.holds[i].ReleasedAt = &releasedBy, &now
+	return s.state.holds[i], nil
+}
+
+// held reports whether an active hold covers the user's rows in dataset.
+// Callers hold s.mu.
+func (s *MemoryStore) held(userID int64, dataset string) bool {
+	return slices.ContainsFunc(s.state.holds, func(h models.LegalHold) bool { return h.UserID == userID && h.Covers(dataset) })
+}
+
+func (s *MemoryStore) ListFeatureFlags(context.Context) ([]models.FeatureFlag, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	flags := slices.Collect(maps.Values(s.state.flags))
+	slices.SortFunc(flags, func(a, b models.FeatureFlag) int { return strings.Compare(a.Name, b.Name) })
+	return flags, nil
+}
+
+func (s *MemoryStore) SetFeatureFlag(_ context.Context, flag models.FeatureFlag) (models.FeatureFlag, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.clock.Now()
+	flag.Source, flag.UpdatedAt = models.FlagSourceAdmin, &now
+	s.state.flags[flag.Name] = flag
+	return flag, nil
+}
+
+func (s *MemoryStore) ListReservedNames(_ context.Context) ([]models.ReservedName, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	names := append([]models.ReservedName{}, s.state.reservedNames...)
+	slices.SortFunc(names, func(a, b models.ReservedName) int { return strings.Compare(a.Name, b.Name) })
+	return names, nil
+}
+
+func (s *MemoryStore) AddReservedName(_ context.Context, name models.ReservedName) (models.ReservedName, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if slices.ContainsFunc(s.state.reservedNames, func(n models.ReservedName) bool { return n.Name == name.Name }) {
+		return models.ReservedName{}, storage.ErrAlreadyExists
+	}
+	now := s.clock.Now()
+	name.Source, name.CreatedAt = models.ReservedNameSourceAdmin, &now
+	s.state.reservedNames = append(s.state.reservedNames, name)
+	return name, nil
+}
+
+func (s *MemoryStore) DeleteReservedName(_ context.Context, name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := slices.IndexFunc(s.state.reservedNames, func(n models.ReservedName) bool { return n.Name == name })
+	if i < 0 {
+		return storage.ErrNotFound
+	}
+	s.state.reservedNames = slices.Delete(s.state.reservedNames, i, i+1)
+	return nil
+}
+
+func (s *MemoryStore) CreateBulkJob(_ context.Context, job models.BulkJob) (models.BulkJob, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !slices.ContainsFunc(s.state.users, func(u models.User) bool { return u.ID == job.CreatedBy }) {
+		return models.BulkJob{}, storage.ErrNotFound
+	}
+	job.ID = s.newID()
+	job.Checkpoint, job.Processed, job.Batches, job.Error, job.FinishedAt = nil, 0, 0, "", nil
+	job.CreatedAt = s.clock.Now()
+	job.UpdatedAt = job.CreatedAt
+	s.state.bulkJobs = append(s.state.bulkJobs, job)
+	return job, nil
+}
+
+func (s *MemoryStore) FindBulkJob(_ context.Context, id int64) (models.BulkJob, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := slices.IndexFunc(s.state.bulkJobs, func(j models.BulkJob) bool { return j.ID == id })
+	if i < 0 {
+		return models.BulkJob{}, storage.ErrNotFound
+	}
+	return s.state.bulkJobs[i], nil
+}
+
+func (s *MemoryStore) ListBulkJobs(_ context.Context, limit int) ([]models.BulkJob, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	jobs := slices.Clone(s.state.bulkJobs)
+	slices.Reverse(jobs)
+	return jobs[:min(limit, len(jobs))], nil
+}
+
+func (s *MemoryStore) SaveBulkJobProgress(_ context.Context, id int64, owner string, checkpoint []byte, processed int64, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	job := s.ownedBulkJob(id, owner)
+	if job == nil {
+		return storage.ErrNotFound
+	}
+	job.Checkpoint = slices.Clone(checkpoint)
+	job.Processed += processed
+	job.Batches++
+	job.UpdatedAt = at
+	return nil
+}
+
+func (s *MemoryStore) FinishBulkJob(_ context.Context, id int64, owner, status, errMsg string, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	job := s.ownedBulkJob(id, owner)
+	if job == nil {
+		return storage.ErrNotFound
+	}
+	job.Status, job.Error, job.UpdatedAt, job.FinishedAt = status, errMsg, at, &at
+	return nil
+}
+
+func (s *MemoryStore) TransitionBulkJob(_ context.Context, id int64, from []string, status, owner string, at time.Time) (models.BulkJob, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := slices.IndexFunc(s.state.bulkJobs, func(j models.BulkJob) bool { return j.ID == id && slices.Contains(from, j.Status) })
+	if i < 0 {
+		return models.BulkJob{}, storage.ErrNotFound
+	}
+	job := &s.state.bulkJobs[i]
+	job.Status, job.Owner, job.UpdatedAt = status, owner, at
+	if status == models.BulkJobRunning {
+		job.Error, job.FinishedAt = "", nil
+	} else {
+		job.FinishedAt = &at
+	}
+	return *job, nil
+}
+
+func (s *MemoryStore) ClaimStaleBulkJobs(_ context.Context, owner string, staleBefore, at time.Time) ([]models.BulkJob, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var claimed []models.BulkJob
+	for i := range s.state.bulkJobs {
+		job := &s.state.bulkJobs[i]
+		if job.Status == models.BulkJobRunning && job.UpdatedAt.Before(staleBefore) {
+			job.Owner, job.UpdatedAt = owner, at
+			claimed = append(claimed, *job)
+		}
+	}
+	return claimed, nil
+}
+
+// ownedBulkJob returns the job if owner still runs it. Callers hold s.mu.
+func (s *MemoryStore) ownedBulkJob(id int64, owner string) *models.BulkJob {
+	i := slices.IndexFunc(s.state.bulkJobs, func(j models.BulkJob) bool {
+		return j.ID == id && j.Owner == owner && j.Status == models.BulkJobRunning
+	})
+	if i < 0 {
+		return nil
+	}
+	return &s.state.bulkJobs[i]
+}
+
+func (s *MemoryStore) ListAPIKeys(context.Context) ([]models.APIKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	keys := slices.Clone(s.state.apiKeys)
+	slices.Reverse(keys)
+	return keys, nil
+}
+
+func (s *MemoryStore) CreateAPIKey(_ context.Context, key models.APIKey) (models.APIKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !slices.ContainsFunc(s.state.users, func(u models.User) bool { return u.ID == key.CreatedBy }) {
+		return models.APIKey{}, storage.ErrNotFound
+	}
+	key.ID, key.Key, key.CreatedAt = s.newID(), "", s.clock.Now()
+	key.Scopes = slices.Clone(key.Scopes)
+	s.state.apiKeys = append(s.state.apiKeys, key)
+	return key, nil
+}
+
+func (s *MemoryStore) FindAPIKeyByHash(_ context.Context, keyHash string) (models.APIKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := slices.IndexFunc(s.state.apiKeys, func(k models.APIKey) bool { return k.KeyHash == keyHash })
+	if i < 0 {
+		return models.APIKey{}, storage.ErrNotFound
+	}
+	return s.state.apiKeys[i], nil
+}
+
+func (s *MemoryStore) RevokeAPIKey(_ context.Context, id, revokedBy int64) (models.APIKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := slices.IndexFunc(s.state.apiKeys, func(k models.APIKey) bool { return k.ID == id && k.RevokedAt == nil })
+	if i < 0 {
+		return models.APIKey{}, storage.ErrNotFound
+	}
+	now := s.clock.Now()
+	s.state.apiKeys[i].RevokedBy, s.state.apiKeys[i].RevokedAt = &revokedBy, &now
+	return s.state.apiKeys[i], nil
+}
+
+func (s *MemoryStore) ListImpersonations(_ context.Context, userID int64, limit int) ([]models.Impersonation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := []models.Impersonation{}
+	for i := len(s.state.impersonations) - 1; i >= 0 && len(out) < limit; i-- {
+		if imp := s.state.impersonations[i]; userID == 0 || imp.UserID == userID {
+			out = append(out, imp)
+		}
+	}
+	return out, nil
+}
+
+func (s *MemoryStore) CreateImpersonation(_ context.Context, imp models.Impersonation) (models.Impersonation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, id := range []int64{imp.UserID, imp.ActorID} {
+		if !slices.ContainsFunc(s.state.users, func(u models.User) bool { return u.ID == id }) {
+			return models.Impersonation{}, storage.ErrNotFound
+		}
+	}
+	imp.ID, imp.Token, imp.CreatedAt = s.newID(), "", s.clock.Now()
+	s.state.impersonations = append(s.state.impersonations, imp)
+	return imp, nil
+}
+
+func (s *MemoryStore) FindImpersonationByToken(_ context.Context, tokenID string) (models.Impersonation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := slices.IndexFunc(s.state.impersonations, func(imp models.Impersonation) bool { return imp.TokenID == tokenID })
+	if i < 0 {
+		return models.Impersonation{}, storage.ErrNotFound
+	}
+	return s.state.impersonations[i], nil
+}
+
+func (s *MemoryStore) RevokeImpersonation(_ context.Context, id, revokedBy int64) (models.Impersonation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := slices.IndexFunc(s.state.impersonations, func(imp models.Impersonation) bool { return imp.ID == id && imp.RevokedAt == nil })
+	if i < 0 {
+		return models.Impersonation{}, storage.ErrNotFound
+	}
+	now := s.clock.Now()
+	s.state.impersonations[i].RevokedBy, s.state.impersonations[i].RevokedAt = &revokedBy, &now
+	return s.state.impersonations[i], nil
+}
+
+func (s *MemoryStore) MergeAccounts(_ context.Context, merge models.AccountMerge) (models.AccountMerge, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	kept, ok := s.userIndex(merge.UserID)
+	if !ok {
+		return models.AccountMerge{}, storage.ErrNotFound
+	}
+	dup, ok := s.userIndex(merge.DuplicateID)
+	if !ok {
+		return models.AccountMerge{}, storage.ErrNotFound
+	}
+	if s.state.users[kept].MergedInto != nil || s.state.users[dup].MergedInto != nil {
+		return models.AccountMerge{}, storage.ErrVersionConflict
+	}
+	now := s.clock.Now()
+	into := merge.UserID
+	s.state.users[dup].MergedInto, s.state.users[dup].SessionsRevokedAt = &into, &now
+	s.state.users[dup].Version++
+	s.state.users[kept].Version++
+
+	for _, ledger := range [][]models.Transaction{s.state.ledger, s.state.archived.ledger} {
+		for i, t := range ledger {
+			if t.UserID != merge.DuplicateID {
+				continue
+			}
+			if t.MergedFrom == nil {
+				from := t.UserID
+				ledger[i].MergedFrom = &from
+			}
+			ledger[i].UserID = merge.UserID
+			merge.Transactions++
+		}
+	}
+	for key, op := range s.state.operations {
+		if op.UserID == merge.DuplicateID {
+			op.UserID = merge.UserID
+			s.state.operations[key] = op
+			merge.Operations++
+		}
+	}
+	for _, logins := range [][]models.LoginAttempt{s.state.logins, s.state.archived.logins} {
+		for i, a := range logins {
+			if a.UserID != nil && *a.UserID == merge.DuplicateID {
+				logins[i].UserID = &into
+				merge.LoginAttempts++
+			}
+		}
+	}
+	var devices, moved []models.LoginDevice
+	for _, d := range s.state.devices {
+		if d.UserID == merge.DuplicateID {
+			moved = append(moved, d)
+		} else {
+			devices = append(devices, d)
+		}
+	}
+	for _, d := range moved {
+		merge.Devices++
+		i := slices.IndexFunc(devices, func(o models.LoginDevice) bool {
+			return o.UserID == merge.UserID && o.Fingerprint == d.Fingerprint && o.Country == d.Country
+		})
+		if i < 0 {
+			d.UserID = merge.UserID
+			devices = append(devices, d)
+			continue
+		}
+		if d.FirstSeen.Before(devices[i].FirstSeen) {
+			devices[i].FirstSeen = d.FirstSeen
+		}
+		if d.LastSeen.After(devices[i].LastSeen) {
+			devices[i].LastSeen = d.LastSeen
+		}
+	}
+	s.state.devices = devices
+
+	merge.ID = s.newID()
+	merge.CreatedAt = now
+	s.state.merges = append(s.state.merges, merge)
+	return merge, nil
+}
+
+func (s *MemoryStore) CreateWithdrawal(_ context.Context, w models.WithdrawalRequest) (models.WithdrawalRequest, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !slices.ContainsFunc(s.state.users, func(u models.User) bool { return u.ID == w.UserID }) {
+		return models.WithdrawalRequest{}, storage.ErrNotFound
+	}
+	if slices.ContainsFunc(s.state.withdrawals, func(o models.WithdrawalRequest) bool {
+		return o.Provider == w.Provider && o.Reference == w.Reference
+	}) {
+		return models.WithdrawalRequest{}, storage.ErrAlreadyExists
+	}
+	w.ID = s.newID()
+	w.CreatedAt = s.clock.Now()
+	w.UpdatedAt = w.CreatedAt
+	s.state.withdrawals = append(s.state.withdrawals, w)
+	return w, nil
+}
+
+func (s *MemoryStore) FindWithdrawal(_ context.Context, id int64) (models.WithdrawalRequest, error) {
+	return s.findWithdrawal(func(w models.WithdrawalRequest) bool { return w.ID == id })
+}
+
+func (s *MemoryStore) FindWithdrawalByReference(_ context.Context, provider, reference string) (models.WithdrawalRequest, error) {
+	return s.findWithdrawal(func(w models.WithdrawalRequest) bool { return w.Provider == provider && w.Reference == reference })
+}
+
+func (s *MemoryStore) findWithdrawal(match func(models.WithdrawalRequest) bool) (models.WithdrawalRequest, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := slices.IndexFunc(s.state.withdrawals, match)
+	if i < 0 {
+		return models.WithdrawalRequest{}, storage.ErrNotFound
+	}
+	return s.state.withdrawals[i], nil
+}
+
+func (s *MemoryStore) ListWithdrawals(_ context.Context, filter models.WithdrawalFilter, limit int) ([]models.WithdrawalRequest, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	requests := []models.WithdrawalRequest{}
+	for i := len(s.state.withdrawals) - 1; i >= 0 && len(requests) < limit; i-- {
+		w := s.state.withdrawals[i]
+		if (filter.UserID == 0 || w.UserID == filter.UserID) && (filter.Status == "" || w.Status == filter.Status) {
+			requests = append(requests, w)
+		}
+	}
+	return requests, nil
+}
+
+func (s *MemoryStore) TransitionWithdrawal(_ context.Context, id int64, from []string, status string, reviewedBy *int64, note string, at time.Time) (models.WithdrawalRequest, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := slices.IndexFunc(s.state.withdrawals, func(w models.WithdrawalRequest) bool { return w.ID == id && slices.Contains(from, w.Status) })
+	if i < 0 {
+		return models.WithdrawalRequest{}, storage.ErrNotFound
+	}
+	w := &s.state.withdrawals[i]
+	w.Status, w.Note, w.UpdatedAt = status, note, at
+	if reviewedBy != nil {
+		w.ReviewedBy = reviewedBy
+	}
+	return *w, nil
+}
+
+func (s *MemoryStore) FindGamingLimits(_ context.Context, userID int64) (models.GamingLimits, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := slices.IndexFunc(s.state.limits, func(g models.GamingLimits) bool { return g.UserID == userID })
+	if i < 0 {
+		return models.GamingLimits{}, storage.ErrNotFound
+	}
+	return s.state.limits[i], nil
+}
+
+func (s *MemoryStore) SaveGamingLimits(_ context.Context, g models.GamingLimits) (models.GamingLimits, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !slices.ContainsFunc(s.state.users, func(u models.User) bool { return u.ID == g.UserID }) {
+		return models.GamingLimits{}, storage.ErrNotFound
+	}
+	if i := slices.IndexFunc(s.state.limits, func(o models.GamingLimits) bool { return o.UserID == g.UserID }); i >= 0 {
+		s.state.limits[i] = g
+	} else {
+		s.state.limits = append(s.state.limits, g)
+	}
+	return g, nil
+}
+
+func (s *MemoryStore) CreateOperatorReport(_ context.Context, report models.OperatorReport) (models.OperatorReport, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if report.RequestedBy != nil {
+		if _, ok := s.userIndex(*report.RequestedBy); !ok {
+			return models.OperatorReport{}, storage.ErrNotFound
+		}
+	} else if slices.ContainsFunc(s.state.operatorReports, func(o models.OperatorReport) bool {
+		return o.RequestedBy == nil && o.Period == report.Period && o.PeriodStart.Equal(report.PeriodStart)
+	}) {
+		return models.OperatorReport{}, storage.ErrAlreadyExists
+	}
+	report.ID = s.newID()
+	report.CreatedAt = s.clock.Now()
+	s.state.operatorReports = append(s.state.operatorReports, report)
+	return report, nil
+}
+
+func (s *MemoryStore) FindOperatorReport(_ context.Context, id int64) (models.OperatorReport, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := slices.IndexFunc(s.state.operatorReports, func(r models.OperatorReport) bool { return r.ID == id })
+	if i < 0 {
+		return models.OperatorReport{}, storage.ErrNotFound
+	}
+	return s.state.operatorReports[i], nil
+}
+
+func (s *MemoryStore) ListOperatorReports(_ context.Context, limit int) ([]models.OperatorReport, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	reports := []models.OperatorReport{}
+	for i := len(s.state.operatorReports) - 1; i >= 0 && len(reports) < limit; i-- {
+		reports = append(reports, s.state.operatorReports[i])
+	}
+	return reports, nil
+}
+
+func (s *MemoryStore) FinishOperatorReport(_ context.Context, id int64, status string, metrics *models.ReportMetrics, errMsg string, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := slices.IndexFunc(s.state.operatorReports, func(r models.OperatorReport) bool { return r.ID == id && r.Status == models.ReportPending })
+	if i < 0 {
+		return storage.ErrNotFound
+	}
+	r := &s.state.operatorReports[i]
+	r.Status, r.Metrics, r.Error, r.CompletedAt = status, metrics, errMsg, &at
+	return nil
+}
+
+func (s *MemoryStore) ReportMetrics(_ context.Context, from, to time.Time) (models.ReportMetrics, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	within := func(t time.Time) bool { return !t.Before(from) && t.Before(to) }
+	var m models.ReportMetrics
+	for _, u := range s.state.users {
+		if within(u.CreatedAt) {
+			m.Signups++
+		}
+	}
+	for _, t := range s.state.ledger {
+		if !within(t.CreatedAt) {
+			continue
+		}
+		switch t.Reason {
+		case models.TransactionBetSettlement:
+			m.GGR -= t.Amount
+		case models.TransactionDeposit:
+			m.Deposits++
+			m.DepositAmount += t.Amount
+		}
+	}
+	for _, w := range s.state.withdrawals {
+		if within(w.CreatedAt) && w.Status != models.WithdrawalRejected && w.Status != models.WithdrawalFailed {
+			m.Withdrawals++
+			m.WithdrawalAmount += w.Amount
+		}
+	}
+	m.GGR = math.Round(m.GGR*100) / 100
+	m.DepositAmount = math.Round(m.DepositAmount*100) / 100
+	m.WithdrawalAmount = math.Round(m.WithdrawalAmount*100) / 100
+	return m, nil
+}
+
+func (s *MemoryStore) AddOutboxEvent(_ context.Context, event models.OutboxEvent) (models.OutboxEvent, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.state.outboxSeq++
+	event.ID = s.state.outboxSeq
+	event.CreatedAt = s.clock.Now()
+	event.SentAt = nil
+	s.state.outbox = append(s.state.outbox, event)
+	return event, nil
+}
+
+// PendingOutboxEvents needs no locking: units of work on the memory store run
+// one at a time.
+func (s *MemoryStore) PendingOutboxEvents(_ context.Context, limit int) ([]models.OutboxEvent, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	pending := []models.OutboxEvent{}
+	for _, e := range s.state.outbox {
+		if len(pending) == limit {
+			break
+		}
+		if e.SentAt == nil {
+			pending = append(pending, e)
+		}
+	}
+	return pending, nil
+}
+
+func (s *MemoryStore) MarkOutboxEventsSent(_ context.Context, ids []int64, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := range s.state.outbox {
+		if e := &s.state.outbox[i]; e.SentAt == nil && slices.Contains(ids, e.ID) {
+			e.SentAt = &at
+		}
+	}
+	return nil
+}
+
+func (s *MemoryStore) DeleteSentOutboxEvents(_ context.Context, before time.Time, limit int) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	deleted := 0
+	s.state.outbox = slices.DeleteFunc(s.state.outbox, func(e models.OutboxEvent) bool {
+		if deleted < limit && e.SentAt != nil && e.SentAt.Before(before) {
+			deleted++
+			return true
+		}
+		return false
+	})
+	return deleted, nil
+}
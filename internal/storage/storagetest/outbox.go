@@ -0,0 +1,44 @@
+package storagetest
+
+import (
+	"context"
+	"slices"
+	"sync"
+)
+
+// SentMessage is a message captured by Outbox.
+type SentMessage struct {
+	Channel string
+	To      string
+	Subject string
+	Body    string
+}
+
+// Outbox is a fake email and SMS provider that records every message.
+type Outbox struct {
+	mu   sync.Mutex
+	sent []SentMessage
+}
+
+func (o *Outbox) SendEmail(_ context.Context, to, subject, body string) error {
+	o.record(SentMessage{Channel: "email", To: to, Subject: subject, Body: body})
+	return nil
+}
+
+func (o *Outbox) SendSMS(_ context.Context, to, body string) error {
+	o.record(SentMessage{Channel: "sms", To: to, Body: body})
+	return nil
+}
+
+func (o *Outbox) record(m SentMessage) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.sent = append(o.sent, m)
+}
+
+// Sent returns the messages recorded so far.
+func (o *Outbox) Sent() []SentMessage {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return slices.Clone(o.sent)
+}
@@ -0,0 +1,279 @@
+// Package support runs the support desk: players open tickets, staff work
+// them from a queue, and both sides add replies and attachments to the
+// ticket's thread.
+//
+// A ticket is open while it waits for staff and pending while it waits for
+// the player, so each reply moves it to the other side. Tickets opened by
+// players holding the support:priority permission are high priority and
+// head the staff queue. Attachments are kept in the blob store and handed
+// out through signed links; a staff reply also lands in the player's inbox.
+package support
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/hongminglow/all-in-be/internal/blob"
+	"github.com/hongminglow/all-in-be/internal/config"
+	"github.com/hongminglow/all-in-be/internal/inbox"
+	"github.com/hongminglow/all-in-be/internal/models"
+	"github.com/hongminglow/all-in-be/internal/storage"
+)
+
+// Bounds on what a ticket may hold.
+const (
+	maxSubject = 150
+	maxBody    = 10000
+	maxName    = 255
+)
+
+// attachmentTypes are the content types an attachment may have.
+var attachmentTypes = []string{"image/png", "image/jpeg", "image/gif", "application/pdf", "text/plain"}
+
+var (
+	// ErrInvalidTicket is returned, wrapped with the reason, for a ticket or
+	// reply that cannot be saved.
+	ErrInvalidTicket = errors.New("invalid ticket")
+	// ErrTicketClosed is returned when replying to or attaching to a closed
+	// ticket.
+	ErrTicketClosed = errors.New("ticket is closed")
+	// ErrInvalidTransition is returned for a status change the ticket's
+	// current status does not allow.
+	ErrInvalidTransition = errors.New("ticket status change not allowed")
+	// ErrAttachmentTooLarge is returned for an attachment over the size limit.
+	ErrAttachmentTooLarge = errors.New("attachment too large")
+	// ErrUnsupportedType is returned for an attachment of a content type
+	// that is not accepted.
+	ErrUnsupportedType = errors.New("attachment type not supported")
+)
+
+// transitions lists, for each status, the statuses a ticket may move to it
+// from.
+var transitions = map[string][]string{
+	models.TicketOpen:    {models.TicketPending, models.TicketClosed},
+	models.TicketPending: {models.TicketOpen},
+	models.TicketClosed:  {models.TicketOpen, models.TicketPending},
+}
+
+// Service opens tickets and keeps their threads.
+type Service struct {
+	store    storage.SupportStore
+	blobs    blob.Store
+	messages *inbox.Service
+	cfg      config.SupportConfig
+}
+
+// NewService constructs the service. Staff replies are copied to the
+// player's inbox through messages.
+func NewService(store storage.SupportStore, blobs blob.Store, messages *inbox.Service, cfg config.SupportConfig) *Service {
+	return &Service{store: store, blobs: blobs, messages: messages, cfg: cfg}
+}
+
+// Open files a ticket for the user with body as its first reply. Its
+// priority comes from the user's support:priority permission.
+func (s *Service) Open(ctx context.Context, user models.User, subject, body string) (models.SupportThread, error) {
+	subject = strings.TrimSpace(subject)
+	if subject == "" || utf8.RuneCountInString(subject) > maxSubject {
+		return models.SupportThread{}, fmt.Errorf("%w: subject is required and at most %d characters", ErrInvalidTicket, maxSubject)
+	}
+	body, err := checkBody(body)
+	if err != nil {
+		return models.SupportThread{}, err
+	}
+	priority := models.TicketPriorityNormal
+	if user.HasPermission(models.PermSupportPriority) {
+		priority = models.TicketPriorityHigh
+	}
+	t, err := s.store.CreateTicket(ctx, models.SupportTicket{UserID: user.ID, Subject: subject, Status: models.TicketOpen, Priority: priority}, body)
+	if err != nil {
+		return models.SupportThread{}, err
+	}
+	return s.thread(ctx, t)
+}
+
+// Mine returns up to limit of the user's tickets, most recently active first.
+func (s *Service) Mine(ctx context.Context, userID int64, limit int) ([]models.SupportTicket, error) {
+	return s.store.ListUserTickets(ctx, userID, limit)
+}
+
+// Queue returns up to limit tickets in status, or open and pending ones when
+// status is empty, high priority first and then longest waiting first.
+func (s *Service) Queue(ctx context.Context, status string, limit int) ([]models.SupportTicket, error) {
+	statuses := []string{models.TicketOpen, models.TicketPending}
+	if status != "" {
+		if _, ok := transitions[status]; !ok {
+			return nil, fmt.Errorf("%w: status must be open, pending or closed", ErrInvalidTicket)
+		}
+		statuses = []string{status}
+	}
+	return s.store.ListTicketQueue(ctx, statuses, limit)
+}
+
+// Thread returns the ticket with its replies and attachments. Unless staff
+// is set, tickets of other users are reported as storage.ErrNotFound.
+func (s *Service) Thread(ctx context.Context, ticketID, viewerID int64, staff bool) (models.SupportThread, error) {
+	t, err := s.find(ctx, ticketID, viewerID, staff)
+	if err != nil {
+		return models.SupportThread{}, err
+	}
+	return s.thread(ctx, t)
+}
+
+// Reply adds body to the ticket's thread. A player's reply hands the ticket
+// to staff and a staff reply hands it back; staff replies also reach the
+// player's inbox.
+func (s *Service) Reply(ctx context.Context, ticketID int64, author models.User, staff bool, body string) (models.TicketReply, error) {
+	body, err := checkBody(body)
+	if err != nil {
+		return models.TicketReply{}, err
+	}
+	t, err := s.find(ctx, ticketID, author.ID, staff)
+	if err != nil {
+		return models.TicketReply{}, err
+	}
+	status := models.TicketOpen
+	if staff {
+		status = models.TicketPending
+	}
+	reply, err := s.store.AddTicketReply(ctx, models.TicketReply{TicketID: t.ID, AuthorID: author.ID, Staff: staff, Body: body}, []string{models.TicketOpen, models.TicketPending}, status)
+	if errors.Is(err, storage.ErrNotFound) {
+		// The ticket exists, so it was closed.
+		return models.TicketReply{}, ErrTicketClosed
+	}
+	if err != nil {
+		return models.TicketReply{}, err
+	}
+	if staff && s.messages != nil {
+		if _, err := s.messages.Send(ctx, author.ID, t.UserID, "Re: "+t.Subject, body); err != nil {
+			log.Printf("copy reply to ticket %d into inbox: %v", t.ID, err)
+		}
+	}
+	return reply, nil
+}
+
+// SetStatus moves the ticket to status. Staff may reopen, hand over and
+// close tickets; players may only close their own.
+func (s *Service) SetStatus(ctx context.Context, ticketID, actorID int64, staff bool, status string) (models.SupportTicket, error) {
+	from, ok := transitions[status]
+	if !ok {
+		return models.SupportTicket{}, fmt.Errorf("%w: status must be open, pending or closed", ErrInvalidTicket)
+	}
+	if !staff && status != models.TicketClosed {
+		return models.SupportTicket{}, ErrInvalidTransition
+	}
+	t, err := s.find(ctx, ticketID, actorID, staff)
+	if err != nil {
+		return models.SupportTicket{}, err
+	}
+	if !slices.Contains(from, t.Status) {
+		return models.SupportTicket{}, ErrInvalidTransition
+	}
+	t, err = s.store.SetTicketStatus(ctx, t.ID, from, status)
+	if errors.Is(err, storage.ErrNotFound) {
+		// The ticket moved in the meantime.
+		return models.SupportTicket{}, ErrInvalidTransition
+	}
+	return t, err
+}
+
+// Attach stores the file read from body on the ticket. Files over the
+// configured size or of an unsupported content type are refused.
+func (s *Service) Attach(ctx context.Context, ticketID, uploaderID int64, staff bool, name, contentType string, body io.Reader) (models.TicketAttachment, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || utf8.RuneCountInString(name) > maxName || strings.ContainsAny(name, "/\\") {
+		return models.TicketAttachment{}, fmt.Errorf("%w: attachment name must be a file name of at most %d characters", ErrInvalidTicket, maxName)
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil || !slices.Contains(attachmentTypes, mediaType) {
+		return models.TicketAttachment{}, ErrUnsupportedType
+	}
+	t, err := s.find(ctx, ticketID, uploaderID, staff)
+	if err != nil {
+		return models.TicketAttachment{}, err
+	}
+	if t.Status == models.TicketClosed {
+		return models.TicketAttachment{}, ErrTicketClosed
+	}
+	data, err := io.ReadAll(io.LimitReader(body, s.cfg.AttachmentMaxBytes+1))
+	if err != nil {
+		return models.TicketAttachment{}, fmt.Errorf("read attachment: %w", err)
+	}
+	if int64(len(data)) > s.cfg.AttachmentMaxBytes {
+		return models.TicketAttachment{}, ErrAttachmentTooLarge
+	}
+	if len(data) == 0 {
+		return models.TicketAttachment{}, fmt.Errorf("%w: attachment is empty", ErrInvalidTicket)
+	}
+	suffix := make([]byte, 16)
+	if _, err := rand.Read(suffix); err != nil {
+		return models.TicketAttachment{}, fmt.Errorf("generate attachment key: %w", err)
+	}
+	key := fmt.Sprintf("support/%d/%s", t.ID, hex.EncodeToString(suffix))
+	if err := s.blobs.Put(ctx, key, bytes.NewReader(data), mediaType); err != nil {
+		return models.TicketAttachment{}, fmt.Errorf("store attachment: %w", err)
+	}
+	a, err := s.store.AddTicketAttachment(ctx, models.TicketAttachment{TicketID: t.ID, UploaderID: uploaderID, Name: name, ContentType: mediaType, Size: int64(len(data)), BlobKey: key})
+	if err != nil {
+		if delErr := s.blobs.Delete(ctx, key); delErr != nil {
+			log.Printf("remove orphaned attachment %s: %v", key, delErr)
+		}
+		return models.TicketAttachment{}, err
+	}
+	return s.signed(ctx, a)
+}
+
+// find loads the ticket, hiding other users' tickets from players.
+func (s *Service) find(ctx context.Context, ticketID, viewerID int64, staff bool) (models.SupportTicket, error) {
+	t, err := s.store.FindTicket(ctx, ticketID)
+	if err != nil {
+		return models.SupportTicket{}, err
+	}
+	if !staff && t.UserID != viewerID {
+		return models.SupportTicket{}, storage.ErrNotFound
+	}
+	return t, nil
+}
+
+func (s *Service) thread(ctx context.Context, t models.SupportTicket) (models.SupportThread, error) {
+	replies, err := s.store.ListTicketReplies(ctx, t.ID)
+	if err != nil {
+		return models.SupportThread{}, err
+	}
+	attachments, err := s.store.ListTicketAttachments(ctx, t.ID)
+	if err != nil {
+		return models.SupportThread{}, err
+	}
+	for i, a := range attachments {
+		if attachments[i], err = s.signed(ctx, a); err != nil {
+			return models.SupportThread{}, err
+		}
+	}
+	return models.SupportThread{Ticket: t, Replies: replies, Attachments: attachments}, nil
+}
+
+func (s *Service) signed(ctx context.Context, a models.TicketAttachment) (models.TicketAttachment, error) {
+	url, err := s.blobs.SignedURL(ctx, a.BlobKey, s.cfg.AttachmentLinkTTL)
+	if err != nil {
+		return models.TicketAttachment{}, fmt.Errorf("sign attachment link: %w", err)
+	}
+	a.URL = url
+	return a, nil
+}
+
+func checkBody(body string) (string, error) {
+	body = strings.TrimSpace(body)
+	if body == "" || utf8.RuneCountInString(body) > maxBody {
+		return "", fmt.Errorf("%w: body is required and at most %d characters", ErrInvalidTicket, maxBody)
+	}
+	return body, nil
+}
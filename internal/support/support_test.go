@@ -0,0 +1,139 @@
+package support
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hongminglow/all-in-be/internal/blob"
+	"github.com/hongminglow/all-in-be/internal/config"
+	"github.com/hongminglow/all-in-be/internal/inbox"
+	"github.com/hongminglow/all-in-be/internal/models"
+	"github.com/hongminglow/all-in-be/internal/storage"
+	"github.com/hongminglow/all-in-be/internal/storage/storagetest"
+)
+
+func newTestService(t *testing.T) (*Service, *storagetest.MemoryStore, *storagetest.FakeClock, *inbox.Service) {
+	t.Helper()
+	clk := storagetest.NewFakeClock(time.Date(2026, 6, 1, 9, 0, 0, 0, time.UTC))
+	store := storagetest.NewMemoryStore(clk)
+	blobs, err := blob.NewLocal(t.TempDir(), "http://blobs.test", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	messages := inbox.NewService(store, clk)
+	return NewService(store, blobs, messages, config.SupportConfig{AttachmentMaxBytes: 16, AttachmentLinkTTL: time.Hour}), store, clk, messages
+}
+
+func loadUser(t *testing.T, store *storagetest.MemoryStore, name, role string) models.User {
+	t.Helper()
+	ctx := context.Background()
+	created, err := store.CreateUser(ctx, models.User{Username: name, Email: name + "@example.com", Role: role})
+	if err != nil {
+		t.Fatal(err)
+	}
+	user, err := store.FindByID(ctx, created.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return user
+}
+
+func TestVVIPTicketsHeadTheQueue(t *testing.T) {
+	ctx := context.Background()
+	service, store, clk, _ := newTestService(t)
+	ana := loadUser(t, store, "ana", models.NormalUser)
+	vic := loadUser(t, store, "vic", models.VVIPUser)
+
+	if _, err := service.Open(ctx, ana, " ", "help"); !errors.Is(err, ErrInvalidTicket) {
+		t.Fatalf("ticket without a subject: err = %v, want ErrInvalidTicket", err)
+	}
+	first, err := service.Open(ctx, ana, "Deposit missing", "I paid but the balance did not change.")
+	if err != nil || first.Ticket.Priority != models.TicketPriorityNormal || len(first.Replies) != 1 {
+		t.Fatalf("ana's ticket = %+v, %v", first, err)
+	}
+	clk.Advance(time.Minute)
+	second, err := service.Open(ctx, vic, "Withdrawal limit", "Please raise it.")
+	if err != nil || second.Ticket.Priority != models.TicketPriorityHigh {
+		t.Fatalf("vic's ticket = %+v, %v", second, err)
+	}
+
+	queue, err := service.Queue(ctx, "", 10)
+	if err != nil || len(queue) != 2 || queue[0].ID != second.Ticket.ID || queue[1].ID != first.Ticket.ID {
+		t.Fatalf("queue = %+v, %v; want vic's ticket first", queue, err)
+	}
+	if _, err := service.Queue(ctx, "lost", 10); !errors.Is(err, ErrInvalidTicket) {
+		t.Fatalf("queue of unknown status: err = %v, want ErrInvalidTicket", err)
+	}
+	if _, err := service.Thread(ctx, second.Ticket.ID, ana.ID, false); !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("ana reading vic's ticket: err = %v, want ErrNotFound", err)
+	}
+}
+
+func TestRepliesHandTheTicketOverUntilItCloses(t *testing.T) {
+	ctx := context.Background()
+	service, store, _, messages := newTestService(t)
+	ana := loadUser(t, store, "ana", models.NormalUser)
+	sam := loadUser(t, store, "sam", models.StaffUser)
+	opened, _ := service.Open(ctx, ana, "Locked out", "My account is locked.")
+	id := opened.Ticket.ID
+
+	if _, err := service.Reply(ctx, id, sam, true, "Unlocked it for you."); err != nil {
+		t.Fatal(err)
+	}
+	if thread, _ := service.Thread(ctx, id, ana.ID, false); thread.Ticket.Status != models.TicketPending || len(thread.Replies) != 2 || !thread.Replies[1].Staff {
+		t.Fatalf("thread after the staff reply = %+v", thread)
+	}
+	if got, _ := messages.Inbox(ctx, ana.ID, true, 10); got.Unread != 1 || got.Messages[0].Subject != "Re: Locked out" {
+		t.Fatalf("ana's inbox = %+v, want the staff reply", got)
+	}
+	if _, err := service.Reply(ctx, id, ana, false, "Thanks, it works."); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := service.SetStatus(ctx, id, ana.ID, false, models.TicketPending); !errors.Is(err, ErrInvalidTransition) {
+		t.Fatalf("player moving the ticket to pending: err = %v, want ErrInvalidTransition", err)
+	}
+	closed, err := service.SetStatus(ctx, id, ana.ID, false, models.TicketClosed)
+	if err != nil || closed.Status != models.TicketClosed || closed.ClosedAt == nil {
+		t.Fatalf("closed ticket = %+v, %v", closed, err)
+	}
+	if _, err := service.Reply(ctx, id, ana, false, "One more thing."); !errors.Is(err, ErrTicketClosed) {
+		t.Fatalf("reply to a closed ticket: err = %v, want ErrTicketClosed", err)
+	}
+	if _, err := service.SetStatus(ctx, id, sam.ID, true, models.TicketPending); !errors.Is(err, ErrInvalidTransition) {
+		t.Fatalf("closed to pending: err = %v, want ErrInvalidTransition", err)
+	}
+	reopened, err := service.SetStatus(ctx, id, sam.ID, true, models.TicketOpen)
+	if err != nil || reopened.Status != models.TicketOpen || reopened.ClosedAt != nil {
+		t.Fatalf("reopened ticket = %+v, %v", reopened, err)
+	}
+}
+
+func TestAttachmentsAreCheckedAndSigned(t *testing.T) {
+	ctx := context.Background()
+	service, store, _, _ := newTestService(t)
+	ana := loadUser(t, store, "ana", models.NormalUser)
+	bob := loadUser(t, store, "bob", models.NormalUser)
+	opened, _ := service.Open(ctx, ana, "Odd charge", "See the receipt.")
+	id := opened.Ticket.ID
+
+	if _, err := service.Attach(ctx, id, ana.ID, false, "run.sh", "application/x-sh", strings.NewReader("echo")); !errors.Is(err, ErrUnsupportedType) {
+		t.Fatalf("shell script: err = %v, want ErrUnsupportedType", err)
+	}
+	if _, err := service.Attach(ctx, id, ana.ID, false, "big.txt", "text/plain", strings.NewReader(strings.Repeat("x", 17))); !errors.Is(err, ErrAttachmentTooLarge) {
+		t.Fatalf("17 byte file: err = %v, want ErrAttachmentTooLarge", err)
+	}
+	if _, err := service.Attach(ctx, id, bob.ID, false, "a.txt", "text/plain", strings.NewReader("hi")); !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("attaching to another player's ticket: err = %v, want ErrNotFound", err)
+	}
+	a, err := service.Attach(ctx, id, ana.ID, false, "receipt.txt", "text/plain; charset=utf-8", strings.NewReader("paid 10"))
+	if err != nil || a.ContentType != "text/plain" || a.Size != 7 || !strings.HasPrefix(a.URL, "http://blobs.test/support/") {
+		t.Fatalf("attachment = %+v, %v", a, err)
+	}
+	thread, err := service.Thread(ctx, id, ana.ID, false)
+	if err != nil || len(thread.Attachments) != 1 || thread.Attachments[0].URL == "" {
+		t.Fatalf("thread = %+v, %v; want the signed attachment", thread, err)
+	}
+}
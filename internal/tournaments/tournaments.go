@@ -0,0 +1,345 @@
+// Package tournaments runs competitions players pay an entry fee to join.
+//
+// Entry fees are debited through the wallet and make up the prize pool.
+// Entrants submit scores while the tournament runs and keep their best. Once
+// it ends, a scheduled sweep queues a job that closes it, ranks the entrants
+// and pays each prize-winning place its share of the pool through the wallet,
+// then emails the winners.
+package tournaments
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hongminglow/all-in-be/internal/clock"
+	"github.com/hongminglow/all-in-be/internal/events"
+	"github.com/hongminglow/all-in-be/internal/jobs"
+	"github.com/hongminglow/all-in-be/internal/limits"
+	"github.com/hongminglow/all-in-be/internal/models"
+	"github.com/hongminglow/all-in-be/internal/notify"
+	"github.com/hongminglow/all-in-be/internal/outbox"
+	"github.com/hongminglow/all-in-be/internal/storage"
+	"github.com/hongminglow/all-in-be/internal/wallet"
+)
+
+// MaxAttempts is how many times closing a tournament is tried.
+const MaxAttempts = 3
+
+// MaxPrizePlaces bounds the places a tournament pays.
+const MaxPrizePlaces = 100
+
+// maxName bounds a tournament's name.
+const maxName = 100
+
+var (
+	// ErrInvalidTournament is returned, wrapped with the reason, for a
+	// tournament that cannot be created.
+	ErrInvalidTournament = errors.New("invalid tournament")
+	// ErrInvalidScore is returned for a negative or non-finite score.
+	ErrInvalidScore = errors.New("score must be a non-negative number")
+	// ErrNotStarted is returned for a score submitted before the start.
+	ErrNotStarted = errors.New("tournament has not started")
+	// ErrEnded is returned for entries and scores once the tournament ended.
+	ErrEnded = errors.New("tournament has ended")
+	// ErrNotEntered is returned for a score from a player who did not enter.
+	ErrNotEntered = errors.New("not entered in the tournament")
+)
+
+// Store is the data tournaments are run on.
+type Store interface {
+	storage.UnitOfWork
+	storage.TournamentStore
+	storage.UserStore
+}
+
+// Service creates tournaments, takes entries and scores, and closes them on
+// a schedule.
+type Service struct {
+	store    Store
+	queue    *jobs.Queue
+	notifier notify.Notifier
+	outbox   *outbox.Relay
+	clock    clock.Clock
+	interval time.Duration
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewService builds the service; call Start to close ended tournaments every
+// interval. Balance changes are announced through the outbox relayed by
+// relay, which may be nil.
+func NewService(store Store, queue *jobs.Queue, notifier notify.Notifier, relay *outbox.Relay, clk clock.Clock, interval time.Duration) *Service {
+	return &Service{store: store, queue: queue, notifier: notifier, outbox: relay, clock: clk, interval: interval}
+}
+
+// Create schedules a tournament. It must end in the future and pay at most
+// the whole pool.
+func (s *Service) Create(ctx context.Context, t models.Tournament) (models.Tournament, error) {
+	t.Name = strings.TrimSpace(t.Name)
+	var total float64
+	for _, share := range t.PrizeShares {
+		if math.IsNaN(share) || share <= 0 {
+			return models.Tournament{}, fmt.Errorf("%w: prize shares must be positive percentages", ErrInvalidTournament)
+		}
+		total += share
+	}
+	switch {
+	case t.Name == "" || len(t.Name) > maxName:
+		return models.Tournament{}, fmt.Errorf("%w: name is required and at most %d bytes", ErrInvalidTournament, maxName)
+	case math.IsNaN(t.EntryFee) || math.IsInf(t.EntryFee, 0) || math.Round(t.EntryFee*100) <= 0:
+		return models.Tournament{}, fmt.Errorf("%w: entry_fee must be positive", ErrInvalidTournament)
+	case !t.EndsAt.After(t.StartsAt) || !t.EndsAt.After(s.clock.Now()):
+		return models.Tournament{}, fmt.Errorf("%w: ends_at must be after starts_at and in the future", ErrInvalidTournament)
+	case len(t.PrizeShares) == 0 || len(t.PrizeShares) > MaxPrizePlaces:
+		return models.Tournament{}, fmt.Errorf("%w: prize_shares must pay between 1 and %d places", ErrInvalidTournament, MaxPrizePlaces)
+	case total > 100:
+		return models.Tournament{}, fmt.Errorf("%w: prize_shares must add up to at most 100 percent", ErrInvalidTournament)
+	}
+	return s.store.CreateTournament(ctx, t)
+}
+
+// List returns up to limit tournaments, latest start first.
+func (s *Service) List(ctx context.Context, limit int) ([]models.Tournament, error) {
+	return s.store.ListTournaments(ctx, limit)
+}
+
+// Standings returns the tournament with its entrants ranked. Until it
+// closes, the prizes shown are what each place would win now.
+func (s *Service) Standings(ctx context.Context, id int64) (models.TournamentStandings, error) {
+	t, err := s.store.FindTournament(ctx, id)
+	if err != nil {
+		return models.TournamentStandings{}, err
+	}
+	entries, err := s.store.ListTournamentEntries(ctx, id)
+	if err != nil {
+		return models.TournamentStandings{}, err
+	}
+	if t.Status == models.TournamentOpen {
+		entries = Rank(t, entries)
+	}
+	return models.TournamentStandings{Tournament: t, Entries: entries}, nil
+}
+
+// Enter debits the entry fee and adds the user to the tournament, which must
+// not have ended. Self-excluded players get limits.ErrExcluded, a second
+// entry storage.ErrAlreadyExists and a balance short of the fee
+// storage.ErrInsufficientFunds.
+func (s *Service) Enter(ctx context.Context, userID, id int64) (models.TournamentEntry, error) {
+	var entry models.TournamentEntry
+	err := s.store.WithTx(ctx, func(tx storage.Repositories) error {
+		t, err := tx.FindTournament(ctx, id)
+		if err != nil {
+			return err
+		}
+		now := s.clock.Now()
+		if t.Status != models.TournamentOpen || !now.Before(t.EndsAt) {
+			return ErrEnded
+		}
+		playerLimits, err := limits.Find(ctx, tx, userID)
+		if err != nil {
+			return err
+		}
+		if playerLimits.Excluded(now) {
+			return limits.ErrExcluded
+		}
+		if entry, err = tx.EnterTournament(ctx, id, userID, t.EntryFee); err != nil {
+			return err
+		}
+		op := wallet.Operation{Kind: models.OperationTournamentEntry, Key: key(id, userID)}
+		saved, _, err := wallet.Apply(ctx, tx, op, models.Transaction{UserID: userID, Amount: -t.EntryFee, Reason: models.TransactionTournamentEntry, Reference: reference(id)})
+		if err != nil {
+			return err
+		}
+		return outbox.Add(ctx, tx, events.TypeBalanceChanged, wallet.BalanceChanged(saved))
+	})
+	if err != nil {
+		return models.TournamentEntry{}, err
+	}
+	s.outbox.Wake()
+	return entry, nil
+}
+
+// SubmitScore records an entrant's score while the tournament runs and
+// returns their entry with their best score.
+func (s *Service) SubmitScore(ctx context.Context, userID, id int64, score float64) (models.TournamentEntry, error) {
+	if math.IsNaN(score) || math.IsInf(score, 0) || score < 0 {
+		return models.TournamentEntry{}, ErrInvalidScore
+	}
+	t, err := s.store.FindTournament(ctx, id)
+	if err != nil {
+		return models.TournamentEntry{}, err
+	}
+	now := s.clock.Now()
+	switch {
+	case now.Before(t.StartsAt):
+		return models.TournamentEntry{}, ErrNotStarted
+	case t.Status != models.TournamentOpen || !now.Before(t.EndsAt):
+		return models.TournamentEntry{}, ErrEnded
+	}
+	entry, err := s.store.RecordTournamentScore(ctx, id, userID, score, now)
+	if errors.Is(err, storage.ErrNotFound) {
+		return models.TournamentEntry{}, ErrNotEntered
+	}
+	return entry, err
+}
+
+// Rank numbers the entrants with a score, already in standings order, and
+// fills in what each prize-winning place is paid from the pool. Places no
+// entrant reached are not paid.
+func Rank(t models.Tournament, entries []models.TournamentEntry) []models.TournamentEntry {
+	pool := math.Round(t.PrizePool * 100)
+	for i := range entries {
+		entries[i].Rank, entries[i].Prize = 0, 0
+		if entries[i].ScoredAt == nil {
+			continue
+		}
+		entries[i].Rank = i + 1
+		if i < len(t.PrizeShares) {
+			// Prizes are rounded down to the cent so they never add up to
+			// more than the pool.
+			entries[i].Prize = math.Floor(pool*t.PrizeShares[i]/100+1e-9) / 100
+		}
+	}
+	return entries
+}
+
+// RunDue queues a job closing each tournament that has ended. Instances
+// sharing the schedule may queue the same one; it is closed once.
+func (s *Service) RunDue(ctx context.Context) error {
+	ended, err := s.store.ListEndedTournaments(ctx, s.clock.Now())
+	if err != nil {
+		return err
+	}
+	for _, t := range ended {
+		id := t.ID
+		job := jobs.Job{
+			Type:        "tournament_close",
+			Name:        fmt.Sprintf("close tournament %d", id),
+			MaxAttempts: MaxAttempts,
+			Run:         func(ctx context.Context, _ int) error { return s.Settle(ctx, id) },
+		}
+		if err := s.queue.Enqueue(job); err != nil {
+			return fmt.Errorf("queue closing tournament %d: %w", id, err)
+		}
+	}
+	return nil
+}
+
+// Settle closes an ended tournament, records every entrant's rank and pays
+// the prizes, all in one transaction, then emails the winners. A tournament
+// already closed is left alone.
+func (s *Service) Settle(ctx context.Context, id int64) error {
+	var t models.Tournament
+	var winners []models.TournamentEntry
+	err := s.store.WithTx(ctx, func(tx storage.Repositories) error {
+		var err error
+		if t, err = tx.CloseTournament(ctx, id, s.clock.Now()); err != nil {
+			return err
+		}
+		entries, err := tx.ListTournamentEntries(ctx, id)
+		if err != nil {
+			return err
+		}
+		for _, e := range Rank(t, entries) {
+			if e.Rank == 0 {
+				continue
+			}
+			if err := tx.SetTournamentResult(ctx, id, e.UserID, e.Rank, e.Prize); err != nil {
+				return err
+			}
+			if e.Prize <= 0 {
+				continue
+			}
+			op := wallet.Operation{Kind: models.OperationTournamentPrize, Key: key(id, e.UserID)}
+			saved, _, err := wallet.Apply(ctx, tx, op, models.Transaction{UserID: e.UserID, Amount: e.Prize, Reason: models.TransactionTournamentPrize, Reference: reference(id)})
+			if err != nil {
+				return err
+			}
+			if err := outbox.Add(ctx, tx, events.TypeBalanceChanged, wallet.BalanceChanged(saved)); err != nil {
+				return err
+			}
+			winners = append(winners, e)
+		}
+		return nil
+	})
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("close tournament %d: %w", id, err)
+	}
+	s.outbox.Wake()
+	for _, w := range winners {
+		s.congratulate(ctx, t, w)
+	}
+	return nil
+}
+
+// congratulate emails a winner. Delivery problems are logged; the prize is
+// already in their balance.
+func (s *Service) congratulate(ctx context.Context, t models.Tournament, winner models.TournamentEntry) {
+	user, err := s.store.FindByID(ctx, winner.UserID)
+	if err != nil {
+		log.Printf("tournaments: find winner %d of tournament %d: %v", winner.UserID, t.ID, err)
+		return
+	}
+	data := map[string]any{"Username": user.Username, "Tournament": t.Name, "Rank": winner.Rank, "Prize": winner.Prize}
+	if err := s.notifier.Notify(ctx, notify.Notification{Channel: notify.ChannelEmail, To: user.Email, Template: notify.TemplateTournamentPrize, Data: data}); err != nil {
+		log.Printf("tournaments: email winner %d of tournament %d: %v", winner.UserID, t.ID, err)
+	}
+}
+
+// Start runs RunDue every interval until Close. It does nothing when no
+// interval is configured.
+func (s *Service) Start() {
+	if s.interval <= 0 || s.stop != nil {
+		return
+	}
+	s.stop, s.done = make(chan struct{}), make(chan struct{})
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-s.stop
+		cancel()
+	}()
+	go func() {
+		defer close(s.done)
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := s.RunDue(ctx); err != nil && ctx.Err() == nil {
+					log.Printf("tournaments: %v", err)
+				}
+			}
+		}
+	}()
+}
+
+// Close stops the schedule and waits for it to end. Closings already queued
+// are run by the job queue.
+func (s *Service) Close() {
+	if s.stop == nil {
+		return
+	}
+	close(s.stop)
+	<-s.done
+	s.stop = nil
+}
+
+func key(id, userID int64) string {
+	return strconv.FormatInt(id, 10) + ":" + strconv.FormatInt(userID, 10)
+}
+
+func reference(id int64) string {
+	return "tournament:" + strconv.FormatInt(id, 10)
+}
@@ -0,0 +1,128 @@
+package tournaments
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hongminglow/all-in-be/internal/jobs"
+	"github.com/hongminglow/all-in-be/internal/models"
+	"github.com/hongminglow/all-in-be/internal/notify"
+	"github.com/hongminglow/all-in-be/internal/storage"
+	"github.com/hongminglow/all-in-be/internal/storage/storagetest"
+)
+
+type recordingNotifier struct {
+	mu   sync.Mutex
+	sent []notify.Notification
+}
+
+func (n *recordingNotifier) Notify(_ context.Context, msg notify.Notification) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.sent = append(n.sent, msg)
+	return nil
+}
+
+func TestRankPaysReachedPlacesRoundedDown(t *testing.T) {
+	at := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	tournament := models.Tournament{PrizePool: 10, PrizeShares: []float64{66.67, 33.33, 10}}
+	entries := Rank(tournament, []models.TournamentEntry{
+		{UserID: 1, Score: 90, ScoredAt: &at},
+		{UserID: 2, Score: 40, ScoredAt: &at},
+		{UserID: 3},
+	})
+	if entries[0].Rank != 1 || entries[0].Prize != 6.66 || entries[1].Rank != 2 || entries[1].Prize != 3.33 {
+		t.Fatalf("places = %+v, want the pool split down to the cent", entries)
+	}
+	if entries[2].Rank != 0 || entries[2].Prize != 0 {
+		t.Fatalf("entrant without a score = %+v, want no place", entries[2])
+	}
+}
+
+func TestTournamentIsEnteredScoredAndPaidOnce(t *testing.T) {
+	ctx := context.Background()
+	clk := storagetest.NewFakeClock(time.Date(2026, 5, 1, 9, 0, 0, 0, time.UTC))
+	store := storagetest.NewMemoryStore(clk)
+	admin, _ := store.CreateUser(ctx, models.User{Username: "root", Email: "root@example.com", Role: models.AdminUser})
+	ana, _ := store.CreateUser(ctx, models.User{Username: "ana", Email: "ana@example.com", Role: models.NormalUser, Balance: 100})
+	ben, _ := store.CreateUser(ctx, models.User{Username: "ben", Email: "ben@example.com", Role: models.NormalUser, Balance: 100})
+	queue := jobs.NewQueue(1, 16, time.Millisecond)
+	notifier := &recordingNotifier{}
+	service := NewService(store, queue, notifier, nil, clk, time.Minute)
+
+	if _, err := service.Create(ctx, models.Tournament{Name: "Spring", EntryFee: 10, StartsAt: clk.Now(), EndsAt: clk.Now().Add(time.Hour), PrizeShares: []float64{60, 50}}); !errors.Is(err, ErrInvalidTournament) {
+		t.Fatalf("shares over the pool: err = %v, want ErrInvalidTournament", err)
+	}
+	tournament, err := service.Create(ctx, models.Tournament{Name: "Spring", EntryFee: 10, StartsAt: clk.Now().Add(time.Minute), EndsAt: clk.Now().Add(time.Hour), PrizeShares: []float64{70, 20}, CreatedBy: admin.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, user := range []models.User{ana, ben} {
+		if _, err := service.Enter(ctx, user.ID, tournament.ID); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := service.Enter(ctx, ana.ID, tournament.ID); !errors.Is(err, storage.ErrAlreadyExists) {
+		t.Fatalf("second entry: err = %v, want ErrAlreadyExists", err)
+	}
+	if _, err := service.SubmitScore(ctx, ana.ID, tournament.ID, 10); !errors.Is(err, ErrNotStarted) {
+		t.Fatalf("score before the start: err = %v, want ErrNotStarted", err)
+	}
+	clk.Advance(5 * time.Minute)
+	if _, err := service.SubmitScore(ctx, admin.ID, tournament.ID, 10); !errors.Is(err, ErrNotEntered) {
+		t.Fatalf("score without an entry: err = %v, want ErrNotEntered", err)
+	}
+	for _, score := range []struct {
+		user  int64
+		score float64
+	}{{ana.ID, 50}, {ben.ID, 30}, {ana.ID, 20}} {
+		if _, err := service.SubmitScore(ctx, score.user, tournament.ID, score.score); err != nil {
+			t.Fatal(err)
+		}
+		clk.Advance(time.Minute)
+	}
+	standings, err := service.Standings(ctx, tournament.ID)
+	if err != nil || standings.Tournament.PrizePool != 20 || len(standings.Entries) != 2 {
+		t.Fatalf("standings = %+v, %v", standings, err)
+	}
+	if first := standings.Entries[0]; first.UserID != ana.ID || first.Score != 50 || first.Prize != 14 {
+		t.Fatalf("leader = %+v, want ana keeping her best score", first)
+	}
+
+	clk.Advance(time.Hour)
+	if _, err := service.Enter(ctx, admin.ID, tournament.ID); !errors.Is(err, ErrEnded) {
+		t.Fatalf("entry after the end: err = %v, want ErrEnded", err)
+	}
+	for range 2 {
+		if err := service.RunDue(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := queue.Close(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := service.Settle(ctx, tournament.ID); err != nil {
+		t.Fatalf("settling a closed tournament again: %v", err)
+	}
+
+	for _, want := range []struct {
+		user    models.User
+		balance float64
+	}{{ana, 104}, {ben, 94}} {
+		user, _ := store.FindByID(ctx, want.user.ID)
+		if user.Balance != want.balance {
+			t.Errorf("%s balance = %v, want %v", user.Username, user.Balance, want.balance)
+		}
+	}
+	closed, _ := service.Standings(ctx, tournament.ID)
+	if closed.Tournament.Status != models.TournamentClosed || closed.Entries[1].Rank != 2 || closed.Entries[1].Prize != 4 {
+		t.Fatalf("closed standings = %+v", closed)
+	}
+	if len(notifier.sent) != 2 || notifier.sent[0].To != "ana@example.com" || notifier.sent[0].Template != notify.TemplateTournamentPrize {
+		t.Fatalf("sent = %+v, want each winner emailed once", notifier.sent)
+	}
+}
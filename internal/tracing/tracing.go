@@ -0,0 +1,261 @@
+// Package tracing records distributed traces and exports them over OTLP/HTTP
+// (JSON encoding) to any OpenTelemetry collector. Trace context travels in
+// W3C traceparent headers.
+//
+// Instrumentation calls the package-level Start, which is a no-op returning a
+// nil *Span until SetTracer installs a tracer; every *Span method is nil-safe.
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// SpanKind mirrors the OTLP span kind enumeration.
+type SpanKind int
+
+const (
+	KindInternal SpanKind = 1
+	KindServer   SpanKind = 2
+	KindClient   SpanKind = 3
+)
+
+// Status codes mirror OTLP.
+const (
+	statusUnset = 0
+	statusError = 2
+)
+
+// TraceID identifies a trace.
+type TraceID [16]byte
+
+func (t TraceID) String() string { return hex.EncodeToString(t[:]) }
+
+// IsValid reports whether t is non-zero.
+func (t TraceID) IsValid() bool { return t != TraceID{} }
+
+// SpanID identifies a span within a trace.
+type SpanID [8]byte
+
+func (s SpanID) String() string { return hex.EncodeToString(s[:]) }
+
+// IsValid reports whether s is non-zero.
+func (s SpanID) IsValid() bool { return s != SpanID{} }
+
+// SpanContext is the part of a span that propagates across process boundaries.
+type SpanContext struct {
+	TraceID TraceID
+	SpanID  SpanID
+	Sampled bool
+}
+
+// IsValid reports whether the context carries a trace.
+func (sc SpanContext) IsValid() bool { return sc.TraceID.IsValid() && sc.SpanID.IsValid() }
+
+// Attribute is a key/value pair attached to a span. Values may be strings,
+// bools, integers, or floats.
+type Attribute struct {
+	Key   string
+	Value any
+}
+
+// String returns a string attribute.
+func String(key, value string) Attribute { return Attribute{Key: key, Value: value} }
+
+// Int returns an integer attribute.
+func Int(key string, value int64) Attribute { return Attribute{Key: key, Value: value} }
+
+// Span is an in-progress operation. A nil *Span ignores every call.
+type Span struct {
+	tracer *Tracer
+	sc     SpanContext
+	parent SpanID
+	kind   SpanKind
+	start  time.Time
+
+	mu            sync.Mutex
+	name          string
+	end           time.Time
+	attrs         []Attribute
+	statusCode    int
+	statusMessage string
+	ended         bool
+}
+
+// SpanContext returns the span's propagation context.
+func (s *Span) SpanContext() SpanContext {
+	if s == nil {
+		return SpanContext{}
+	}
+	return s.sc
+}
+
+// SetName replaces the span name, e.g. once the matched route is known.
+func (s *Span) SetName(name string) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.name = name
+}
+
+// SetAttributes adds attributes to the span.
+func (s *Span) SetAttributes(attrs ...Attribute) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.attrs = append(s.attrs, attrs...)
+}
+
+// RecordError marks the span as failed.
+func (s *Span) RecordError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+	s.SetError(err.Error())
+}
+
+// SetError marks the span as failed with message.
+func (s *Span) SetError(message string) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.statusCode = statusError
+	s.statusMessage = message
+}
+
+// End completes the span and queues it for export if sampled.
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.end = s.tracer.now()
+	s.mu.Unlock()
+	if s.sc.Sampled {
+		s.tracer.exporter.enqueue(s)
+	}
+}
+
+// Tracer creates spans and hands finished ones to its exporter.
+type Tracer struct {
+	exporter    *batcher
+	sampleRatio float64
+	now         func() time.Time
+}
+
+// NewTracer returns a tracer sampling sampleRatio of new traces (child spans
+// follow their parent's decision) and exporting through exporter.
+func NewTracer(exporter Exporter, sampleRatio float64) *Tracer {
+	return &Tracer{exporter: newBatcher(exporter), sampleRatio: sampleRatio, now: time.Now}
+}
+
+// Shutdown flushes pending spans and stops the exporter.
+func (t *Tracer) Shutdown(ctx context.Context) error {
+	return t.exporter.shutdown(ctx)
+}
+
+func (t *Tracer) start(ctx context.Context, name string, kind SpanKind, attrs []Attribute) (context.Context, *Span) {
+	parent := SpanContextFromContext(ctx)
+	span := &Span{tracer: t, name: name, kind: kind, start: t.now(), attrs: attrs}
+	if parent.IsValid() {
+		span.sc = SpanContext{TraceID: parent.TraceID, SpanID: newSpanID(), Sampled: parent.Sampled}
+		span.parent = parent.SpanID
+	} else {
+		traceID := newTraceID()
+		span.sc = SpanContext{TraceID: traceID, SpanID: newSpanID(), Sampled: t.sample(traceID)}
+	}
+	return context.WithValue(ctx, spanKey{}, span), span
+}
+
+// sample decides deterministically from the trace ID, so every service that
+// uses the same ratio agrees on a root decision.
+func (t *Tracer) sample(id TraceID) bool {
+	if t.sampleRatio >= 1 {
+		return true
+	}
+	if t.sampleRatio <= 0 {
+		return false
+	}
+	bound := uint64(t.sampleRatio * (1 << 63))
+	return binary.BigEndian.Uint64(id[8:])>>1 < bound
+}
+
+var global atomic.Pointer[Tracer]
+
+// SetTracer installs the tracer used by Start; nil disables tracing.
+func SetTracer(t *Tracer) {
+	global.Store(t)
+}
+
+// Start begins a span as a child of the span or remote context in ctx.
+func Start(ctx context.Context, name string, kind SpanKind, attrs ...Attribute) (context.Context, *Span) {
+	t := global.Load()
+	if t == nil {
+		return ctx, nil
+	}
+	return t.start(ctx, name, kind, attrs)
+}
+
+type spanKey struct{}
+
+type remoteKey struct{}
+
+// SpanContextFromContext returns the active span's context, or the remote
+// parent extracted from an incoming request.
+func SpanContextFromContext(ctx context.Context) SpanContext {
+	if span, ok := ctx.Value(spanKey{}).(*Span); ok {
+		return span.sc
+	}
+	if sc, ok := ctx.Value(remoteKey{}).(SpanContext); ok {
+		return sc
+	}
+	return SpanContext{}
+}
+
+// ContextWithRemote returns ctx carrying a parent received from another service.
+func ContextWithRemote(ctx context.Context, sc SpanContext) context.Context {
+	return context.WithValue(ctx, remoteKey{}, sc)
+}
+
+// LogPrefix returns "trace_id=... span_id=... " for the active span, or "".
+// Prepend it to log lines so they can be joined with traces.
+func LogPrefix(ctx context.Context) string {
+	sc := SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		return ""
+	}
+	return fmt.Sprintf("trace_id=%s span_id=%s ", sc.TraceID, sc.SpanID)
+}
+
+func newTraceID() TraceID {
+	var id TraceID
+	for !id.IsValid() {
+		_, _ = rand.Read(id[:])
+	}
+	return id
+}
+
+func newSpanID() SpanID {
+	var id SpanID
+	for !id.IsValid() {
+		_, _ = rand.Read(id[:])
+	}
+	return id
+}
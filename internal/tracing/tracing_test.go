@@ -0,0 +1,120 @@
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+type recordingExporter struct {
+	mu    sync.Mutex
+	spans []*Span
+}
+
+func (e *recordingExporter) ExportSpans(_ context.Context, spans []*Span) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.spans = append(e.spans, spans...)
+	return nil
+}
+
+func TestTraceparentRoundTrip(t *testing.T) {
+	const header = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	h := http.Header{}
+	h.Set("traceparent", header)
+	ctx := Extract(context.Background(), h)
+	sc := SpanContextFromContext(ctx)
+	if sc.TraceID.String() != "4bf92f3577b34da6a3ce929d0e0e4736" || sc.SpanID.String() != "00f067aa0ba902b7" || !sc.Sampled {
+		t.Fatalf("extracted %+v", sc)
+	}
+	out := http.Header{}
+	Inject(ctx, out)
+	if out.Get("traceparent") != header {
+		t.Fatalf("injected %q", out.Get("traceparent"))
+	}
+
+	for _, bad := range []string{"", "00-0000-00f067aa0ba902b7-01", "00-00000000000000000000000000000000-00f067aa0ba902b7-01", "ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"} {
+		h := http.Header{}
+		h.Set("traceparent", bad)
+		if sc := SpanContextFromContext(Extract(context.Background(), h)); sc.IsValid() {
+			t.Fatalf("accepted malformed traceparent %q", bad)
+		}
+	}
+}
+
+func TestSpansNestAndExport(t *testing.T) {
+	exporter := &recordingExporter{}
+	tracer := NewTracer(exporter, 1)
+	SetTracer(tracer)
+	defer SetTracer(nil)
+
+	ctx, root := Start(context.Background(), "POST /login", KindServer)
+	_, child := Start(ctx, "db.query", KindClient, String("db.system", "postgresql"))
+	child.RecordError(errors.New("boom"))
+	child.End()
+	root.End()
+	if err := tracer.Shutdown(context.Background()); err != nil {
+		t.Fatalf("shutdown: %v", err)
+	}
+
+	if len(exporter.spans) != 2 {
+		t.Fatalf("exported %d spans, want 2", len(exporter.spans))
+	}
+	if child.sc.TraceID != root.sc.TraceID || child.parent != root.sc.SpanID {
+		t.Fatal("child span is not linked to its parent")
+	}
+	if child.statusCode != statusError {
+		t.Fatal("error not recorded on child span")
+	}
+	if got := LogPrefix(ctx); got != "trace_id="+root.sc.TraceID.String()+" span_id="+root.sc.SpanID.String()+" " {
+		t.Fatalf("log prefix %q", got)
+	}
+}
+
+func TestStartWithoutTracerIsNoop(t *testing.T) {
+	ctx, span := Start(context.Background(), "noop", KindInternal)
+	span.SetAttributes(String("k", "v"))
+	span.End()
+	if span != nil || LogPrefix(ctx) != "" {
+		t.Fatal("expected a no-op span without a tracer")
+	}
+}
+
+func TestOTLPExporterPayload(t *testing.T) {
+	var body map[string]any
+	var headers http.Header
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		headers = r.Header.Clone()
+		raw, _ := io.ReadAll(r.Body)
+		_ = json.Unmarshal(raw, &body)
+	}))
+	defer collector.Close()
+
+	exporter := NewOTLPExporter(collector.URL+"/v1/traces", "all-in-be", map[string]string{"X-Api-Key": "k"})
+	tracer := &Tracer{now: func() time.Time { return time.Unix(1, 0) }}
+	_, span := tracer.start(context.Background(), "GET /me", KindServer, []Attribute{Int("http.response.status_code", 200)})
+	span.end = time.Unix(2, 0)
+	if err := exporter.ExportSpans(context.Background(), []*Span{span}); err != nil {
+		t.Fatalf("export: %v", err)
+	}
+
+	if headers.Get("Content-Type") != "application/json" || headers.Get("X-Api-Key") != "k" {
+		t.Fatalf("headers = %v", headers)
+	}
+	resource := body["resourceSpans"].([]any)[0].(map[string]any)
+	spans := resource["scopeSpans"].([]any)[0].(map[string]any)["spans"].([]any)
+	got := spans[0].(map[string]any)
+	if got["name"] != "GET /me" || got["startTimeUnixNano"] != "1000000000" || got["traceId"] != span.sc.TraceID.String() {
+		t.Fatalf("span = %v", got)
+	}
+	attr := got["attributes"].([]any)[0].(map[string]any)
+	if attr["value"].(map[string]any)["intValue"] != "200" {
+		t.Fatalf("attribute = %v", attr)
+	}
+}
@@ -0,0 +1,54 @@
+package usernames
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/hongminglow/all-in-be/internal/models"
+)
+
+// OpenList reads a reserved names file. Each line is a word, optionally
+// followed by its kind: reserved (the default) refuses usernames equal to the
+// word, blocked refuses usernames containing it. Blank lines and # comments
+// are ignored:
+//
+//	# staff and system names
+//	admin
+//	support reserved
+//	# profanity
+//	badword blocked
+func OpenList(path string) ([]models.ReservedName, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open reserved names: %w", err)
+	}
+	defer f.Close()
+
+	var entries []models.ReservedName
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) > 2 || Fold(fields[0]) == "" {
+			return nil, fmt.Errorf("reserved names %s:%d: want a word optionally followed by its kind", path, n)
+		}
+		entry := models.ReservedName{Name: strings.ToLower(fields[0]), Kind: models.UsernameReserved, Source: models.ReservedNameSourceFile}
+		if len(fields) == 2 {
+			entry.Kind = strings.ToLower(fields[1])
+		}
+		if !slices.Contains(models.UsernameEntryKinds, entry.Kind) {
+			return nil, fmt.Errorf("reserved names %s:%d: unknown kind %q", path, n, fields[1])
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read reserved names: %w", err)
+	}
+	return entries, nil
+}
@@ -0,0 +1,153 @@
+// Package usernames decides which usernames may be registered: their length,
+// the characters they use, and the reserved names and blocked words they may
+// not match, read from a file and from the reserved_usernames table.
+package usernames
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/hongminglow/all-in-be/internal/models"
+	"github.com/hongminglow/all-in-be/internal/storage"
+)
+
+// Rules a Violation names.
+const (
+	RuleLength   = "length"
+	RuleCharset  = "charset"
+	RuleEdges    = "edges"
+	RuleReserved = "reserved"
+	RuleBlocked  = "blocked"
+)
+
+// Violation is returned for a username that breaks the policy. Its message
+// is for people; Field and Rule are for clients to point at the input.
+type Violation struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"-"`
+}
+
+func (v *Violation) Error() string { return v.Message }
+
+func violation(rule, message string) *Violation {
+	return &Violation{Field: "username", Rule: rule, Message: message}
+}
+
+// Rules are the shape a username must have.
+type Rules struct {
+	// MinLength and MaxLength bound the length in characters; 0 leaves a
+	// side open.
+	MinLength, MaxLength int
+	// Unicode allows letters and digits of any script, not only ASCII ones.
+	Unicode bool
+	// Symbols are the other characters allowed, never first or last.
+	Symbols string
+}
+
+// Policy checks usernames against Rules and the reserved names.
+type Policy struct {
+	rules Rules
+	file  []models.ReservedName
+	store storage.ReservedNameStore
+}
+
+// NewPolicy builds the policy. file holds the entries of the reserved names
+// file, if any; store holds those added through the admin API.
+func NewPolicy(rules Rules, file []models.ReservedName, store storage.ReservedNameStore) *Policy {
+	return &Policy{rules: rules, file: file, store: store}
+}
+
+// FileEntries returns the entries read from the reserved names file.
+func (p *Policy) FileEntries() []models.ReservedName {
+	return slices.Clone(p.file)
+}
+
+// Check returns a *Violation for the first rule username breaks, or an error
+// when the stored entries cannot be read.
+func (p *Policy) Check(ctx context.Context, username string) error {
+	if v := p.checkShape(username); v != nil {
+		return v
+	}
+	stored, err := p.store.ListReservedNames(ctx)
+	if err != nil {
+		return fmt.Errorf("list reserved names: %w", err)
+	}
+	folded := Fold(username)
+	for _, entry := range slices.Concat(p.file, stored) {
+		word := Fold(entry.Name)
+		switch {
+		case entry.Kind == models.UsernameBlocked && strings.Contains(folded, word):
+			return violation(RuleBlocked, "username contains a word that is not allowed")
+		case entry.Kind == models.UsernameReserved && folded == word:
+			return violation(RuleReserved, "username is reserved")
+		}
+	}
+	return nil
+}
+
+func (p *Policy) checkShape(username string) *Violation {
+	n := utf8.RuneCountInString(username)
+	if (p.rules.MinLength > 0 && n < p.rules.MinLength) || (p.rules.MaxLength > 0 && n > p.rules.MaxLength) {
+		return violation(RuleLength, p.lengthMessage())
+	}
+	for _, r := range username {
+		if !p.alphanumeric(r) && !strings.ContainsRune(p.rules.Symbols, r) {
+			return violation(RuleCharset, p.charsetMessage())
+		}
+	}
+	first, _ := utf8.DecodeRuneInString(username)
+	last, _ := utf8.DecodeLastRuneInString(username)
+	if !p.alphanumeric(first) || !p.alphanumeric(last) {
+		return violation(RuleEdges, "username must start and end with a letter or digit")
+	}
+	return nil
+}
+
+func (p *Policy) alphanumeric(r rune) bool {
+	if p.rules.Unicode {
+		return unicode.IsLetter(r) || unicode.IsDigit(r)
+	}
+	return r < utf8.RuneSelf && (unicode.IsLetter(r) || unicode.IsDigit(r))
+}
+
+func (p *Policy) lengthMessage() string {
+	switch {
+	case p.rules.MaxLength == 0:
+		return fmt.Sprintf("username must be at least %d characters", p.rules.MinLength)
+	case p.rules.MinLength == 0:
+		return fmt.Sprintf("username must be at most %d characters", p.rules.MaxLength)
+	}
+	return fmt.Sprintf("username must be between %d and %d characters", p.rules.MinLength, p.rules.MaxLength)
+}
+
+func (p *Policy) charsetMessage() string {
+	letters := "the letters a-z"
+	if p.rules.Unicode {
+		letters = "letters"
+	}
+	if p.rules.Symbols == "" {
+		return fmt.Sprintf("username may only contain %s and digits", letters)
+	}
+	return fmt.Sprintf("username may only contain %s, digits and %s", letters, strings.Join(strings.Split(p.rules.Symbols, ""), " "))
+}
+
+// lookalikes maps the digits and symbols commonly swapped for letters.
+var lookalikes = strings.NewReplacer("0", "o", "1", "i", "3", "e", "4", "a", "5", "s", "7", "t", "@", "a", "$", "s")
+
+// Fold reduces a name to the form reserved names are compared in: lower
+// case, lookalike digits read as letters, and anything but letters and
+// digits dropped, so "Ad.m1n" matches "admin".
+func Fold(name string) string {
+	name = lookalikes.Replace(strings.ToLower(name))
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return r
+		}
+		return -1
+	}, name)
+}
@@ -0,0 +1,78 @@
+package usernames
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hongminglow/all-in-be/internal/models"
+	"github.com/hongminglow/all-in-be/internal/storage/storagetest"
+)
+
+func writeList(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "reserved.txt")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestCheck(t *testing.T) {
+	ctx := context.Background()
+	store := storagetest.NewMemoryStore(storagetest.NewFakeClock(time.Now()))
+	if _, err := store.AddReservedName(ctx, models.ReservedName{Name: "support", Kind: models.UsernameReserved}); err != nil {
+		t.Fatal(err)
+	}
+	file, err := OpenList(writeList(t, `
+# staff
+Admin
+darn blocked # profanity
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	policy := NewPolicy(Rules{MinLength: 3, MaxLength: 12, Symbols: "._-"}, file, store)
+	for username, want := range map[string]string{
+		"player_one":    "",
+		"administrator": RuleLength,
+		"ab":            RuleLength,
+		"jo hn":         RuleCharset,
+		"josé":          RuleCharset,
+		"_john":         RuleEdges,
+		"john.":         RuleEdges,
+		"ADMIN":         RuleReserved,
+		"ad.m1n":        RuleReserved,
+		"adminz":        "",
+		"Support":       RuleReserved,
+		"xxd4rnxx":      RuleBlocked,
+	} {
+		err := policy.Check(ctx, username)
+		var v *Violation
+		switch {
+		case want == "" && err != nil:
+			t.Errorf("Check(%q) = %v; want nil", username, err)
+		case want != "" && (!errors.As(err, &v) || v.Rule != want || v.Field != "username"):
+			t.Errorf("Check(%q) = %v; want a %s violation", username, err, want)
+		}
+	}
+
+	unicode := NewPolicy(Rules{MinLength: 2, MaxLength: 4, Unicode: true}, nil, store)
+	if err := unicode.Check(ctx, "josé"); err != nil {
+		t.Errorf("Check(josé) with Unicode = %v; want nil", err)
+	}
+	if err := unicode.Check(ctx, "j.o"); err == nil || err.Error() != "username may only contain letters and digits" {
+		t.Errorf("Check(j.o) with no symbols = %v", err)
+	}
+}
+
+func TestOpenListRejectsBadLines(t *testing.T) {
+	for _, content := range []string{"admin staff\n", "admin reserved extra\n", "...\n"} {
+		if _, err := OpenList(writeList(t, content)); err == nil {
+			t.Errorf("OpenList accepted %q", content)
+		}
+	}
+}
@@ -0,0 +1,205 @@
+// Package wallet moves user balances through the transaction ledger.
+//
+// Internal balance movements such as bet settlements, bonus grants and
+// provider credits carry an operation key. The key is claimed in the same
+// transaction as the ledger entry, so a retried job or a replayed event
+// applies the movement at most once and gets the original entry back.
+package wallet
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/hongminglow/all-in-be/internal/events"
+	"github.com/hongminglow/all-in-be/internal/models"
+	"github.com/hongminglow/all-in-be/internal/outbox"
+	"github.com/hongminglow/all-in-be/internal/storage"
+)
+
+// ErrOperationConflict is returned when an applied operation key is reused for
+// a different user or amount, which points to a caller bug rather than a retry.
+var ErrOperationConflict = errors.New("operation key reused for a different balance movement")
+
+// Operation names one internal balance movement. Kind is one of the
+// models.Operation* kinds and Key is unique within it, e.g. the bet ID.
+type Operation struct {
+	Kind string
+	Key  string
+	// Version, when set, is the user's version the movement was decided on.
+	// The movement fails with storage.ErrVersionConflict if the user has
+	// changed since; a replay of an applied operation is not checked.
+	Version int64
+}
+
+// Apply records entry in the ledger unless op was applied before, in which case
+// the entry it produced is returned and applied is false. tx must belong to a
+// unit of work so the claim and the ledger entry commit together; this is how
+// integration processors credit deposits.
+func Apply(ctx context.Context, tx storage.WalletStore, op Operation, entry models.Transaction) (_ models.Transaction, applied bool, _ error) {
+	claim := models.Operation{Kind: op.Kind, Key: op.Key, UserID: entry.UserID, Amount: entry.Amount}
+	err := tx.ClaimOperation(ctx, claim)
+	if errors.Is(err, storage.ErrAlreadyExists) {
+		prior, err := replay(ctx, tx, claim)
+		return prior, false, err
+	}
+	if err != nil {
+		return models.Transaction{}, false, fmt.Errorf("claim %s operation: %w", op.Kind, err)
+	}
+	if op.Version != 0 {
+		if err := tx.CheckUserVersion(ctx, entry.UserID, op.Version); err != nil {
+			return models.Transaction{}, false, err
+		}
+	}
+	saved, err := tx.ApplyTransaction(ctx, entry)
+	if err != nil {
+		return models.Transaction{}, false, err
+	}
+	if err := tx.CompleteOperation(ctx, op.Kind, op.Key, saved.ID); err != nil {
+		return models.Transaction{}, false, fmt.Errorf("complete %s operation: %w", op.Kind, err)
+	}
+	return saved, true, nil
+}
+
+// replay returns the ledger entry of an operation that was already applied.
+func replay(ctx context.Context, tx storage.WalletStore, claim models.Operation) (models.Transaction, error) {
+	prior, err := tx.FindOperation(ctx, claim.Kind, claim.Key)
+	if err != nil {
+		return models.Transaction{}, fmt.Errorf("find %s operation: %w", claim.Kind, err)
+	}
+	if prior.UserID != claim.UserID || cents(prior.Amount) != cents(claim.Amount) {
+		return models.Transaction{}, fmt.Errorf("%w: %s %q", ErrOperationConflict, claim.Kind, claim.Key)
+	}
+	if prior.TransactionID == nil {
+		return models.Transaction{}, fmt.Errorf("%s operation %q has no ledger entry", claim.Kind, claim.Key)
+	}
+	return tx.FindTransaction(ctx, *prior.TransactionID)
+}
+
+// cents compares amounts the way the NUMERIC(24,2) columns store them.
+func cents(amount float64) int64 {
+	return int64(math.Round(amount * 100))
+}
+
+// Service applies operations in transactions of their own and announces the
+// balance changes they cause.
+type Service struct {
+	store  storage.UnitOfWork
+	outbox *outbox.Relay
+}
+
+// NewService builds a service that announces balance changes through the
+// outbox relayed by relay, which may be nil.
+func NewService(store storage.UnitOfWork, relay *outbox.Relay) *Service {
+	return &Service{store: store, outbox: relay}
+}
+
+// Apply runs Apply in a new transaction and adds events.TypeBalanceChanged to
+// the outbox in the same transaction when the balance moved. Replays add
+// nothing, so consumers see each movement once.
+func (s *Service) Apply(ctx context.Context, op Operation, entry models.Transaction) (models.Transaction, error) {
+	return s.ApplyWith(ctx, op, entry, nil)
+}
+
+// ApplyWith is Apply with more writes in the same transaction: then runs
+// after the entry is saved, unless op is a replay, and the movement is rolled
+// back if then fails.
+func (s *Service) ApplyWith(ctx context.Context, op Operation, entry models.Transaction, then func(tx storage.Repositories, saved models.Transaction) error) (models.Transaction, error) {
+	var saved models.Transaction
+	var applied bool
+	err := s.store.WithTx(ctx, func(tx storage.Repositories) error {
+		var err error
+		saved, applied, err = Apply(ctx, tx, op, entry)
+		if err != nil || !applied {
+			return err
+		}
+		if then != nil {
+			if err := then(tx, saved); err != nil {
+				return err
+			}
+		}
+		return outbox.Add(ctx, tx, events.TypeBalanceChanged, BalanceChanged(saved))
+	})
+	if err != nil {
+		return models.Transaction{}, err
+	}
+	if applied {
+		s.outbox.Wake()
+	}
+	return saved, nil
+}
+
+// PlaceHold reserves amount of the user's available balance under reference,
+// e.g. for a bet placed but not settled. It returns storage.ErrInsufficientFunds
+// when the balance less the active holds does not cover it and
+// storage.ErrAlreadyExists when the reference already has a hold.
+func (s *Service) PlaceHold(ctx context.Context, userID int64, amount float64, reference string) (models.WalletHold, error) {
+	var hold models.WalletHold
+	err := s.store.WithTx(ctx, func(tx storage.Repositories) error {
+		var err error
+		hold, err = tx.CreateHold(ctx, models.WalletHold{UserID: userID, Amount: amount, Reference: reference})
+		return err
+	})
+	return hold, err
+}
+
+// CaptureHold debits an active hold to the ledger with reason and adds
+// events.TypeBalanceChanged to the outbox in the same transaction. It returns
+// storage.ErrNotFound when the hold does not exist or was already settled.
+func (s *Service) CaptureHold(ctx context.Context, id int64, reason string) (models.WalletHold, error) {
+	var hold models.WalletHold
+	err := s.store.WithTx(ctx, func(tx storage.Repositories) error {
+		var err error
+		if hold, err = tx.CaptureHold(ctx, id, reason); err != nil {
+			return err
+		}
+		saved, err := tx.FindTransaction(ctx, *hold.TransactionID)
+		if err != nil {
+			return fmt.Errorf("find debit of hold %d: %w", id, err)
+		}
+		return outbox.Add(ctx, tx, events.TypeBalanceChanged, BalanceChanged(saved))
+	})
+	if err != nil {
+		return models.WalletHold{}, err
+	}
+	s.outbox.Wake()
+	return hold, nil
+}
+
+// ReleaseHold frees an active hold without touching the balance. It returns
+// storage.ErrNotFound when the hold does not exist or was already settled.
+func (s *Service) ReleaseHold(ctx context.Context, id int64) (models.WalletHold, error) {
+	var hold models.WalletHold
+	err := s.store.WithTx(ctx, func(tx storage.Repositories) error {
+		var err error
+		hold, err = tx.ReleaseHold(ctx, id)
+		return err
+	})
+	return hold, err
+}
+
+// Balance reports the user's total balance, what active holds reserve of it
+// and what is available to spend, read together in one transaction.
+func (s *Service) Balance(ctx context.Context, userID int64) (models.WalletBalance, error) {
+	var balance models.WalletBalance
+	err := s.store.WithTx(ctx, func(tx storage.Repositories) error {
+		user, err := tx.FindByID(ctx, userID)
+		if err != nil {
+			return err
+		}
+		held, err := tx.HeldBalance(ctx, userID)
+		if err != nil {
+			return err
+		}
+		available := math.Round((user.Balance-held)*100) / 100
+		balance = models.WalletBalance{Total: user.Balance, Held: held, Available: available}
+		return nil
+	})
+	return balance, err
+}
+
+// BalanceChanged describes the balance movement of a saved ledger entry.
+func BalanceChanged(saved models.Transaction) events.BalanceChanged {
+	return events.BalanceChanged{UserID: saved.UserID, Delta: saved.Amount, Balance: saved.BalanceAfter, Reason: saved.Reason}
+}
@@ -0,0 +1,180 @@
+package wallet
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hongminglow/all-in-be/internal/clock"
+	"github.com/hongminglow/all-in-be/internal/config"
+	"github.com/hongminglow/all-in-be/internal/models"
+	"github.com/hongminglow/all-in-be/internal/outbox"
+	"github.com/hongminglow/all-in-be/internal/storage"
+	"github.com/hongminglow/all-in-be/internal/storage/storagetest"
+)
+
+type recordingPublisher struct {
+	mu     sync.Mutex
+	events []any
+}
+
+func (p *recordingPublisher) Publish(_ context.Context, _ string, data any) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.events = append(p.events, data)
+	return nil
+}
+
+func TestApplyIsExactlyOncePerOperation(t *testing.T) {
+	ctx := context.Background()
+	store := storagetest.NewMemoryStore(storagetest.NewFakeClock(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)))
+	user, err := store.CreateUser(ctx, models.User{Username: "ana", Email: "ana@example.com", Role: models.NormalUser, Balance: 10})
+	if err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	events := &recordingPublisher{}
+	relay := outbox.NewRelay(store, events, clock.System{}, config.EventsConfig{})
+	wallet := NewService(store, relay)
+
+	op := Operation{Kind: models.OperationBetSettlement, Key: "bet-42"}
+	entry := models.Transaction{UserID: user.ID, Amount: 25.5, Reason: models.TransactionBetSettlement, Reference: "bet-42"}
+	first, err := wallet.Apply(ctx, op, entry)
+	if err != nil {
+		t.Fatalf("apply: %v", err)
+	}
+	retried, err := wallet.Apply(ctx, op, entry)
+	if err != nil {
+		t.Fatalf("retry: %v", err)
+	}
+	if retried != first || first.BalanceAfter != 35.5 {
+		t.Fatalf("retry = %+v, want original entry %+v with balance 35.5", retried, first)
+	}
+	if got, _ := store.FindByID(ctx, user.ID); got.Balance != 35.5 {
+		t.Fatalf("balance = %v, want a single credit", got.Balance)
+	}
+	if n, err := relay.Flush(ctx); err != nil || n != 1 || len(events.events) != 1 {
+		t.Fatalf("relayed %d balance changes (%v), want 1", n, err)
+	}
+
+	entry.Amount = 30
+	if _, err := wallet.Apply(ctx, op, entry); !errors.Is(err, ErrOperationConflict) {
+		t.Fatalf("reused key with a new amount: err = %v, want ErrOperationConflict", err)
+	}
+}
+
+func TestApplyRollsBackTheClaimOnFailure(t *testing.T) {
+	ctx := context.Background()
+	store := storagetest.NewMemoryStore(storagetest.NewFakeClock(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)))
+	user, err := store.CreateUser(ctx, models.User{Username: "ben", Email: "ben@example.com", Role: models.NormalUser, Balance: 5})
+	if err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	wallet := NewService(store, nil)
+
+	op := Operation{Kind: models.OperationWebhookCredit, Key: "psp:evt-1"}
+	debit := models.Transaction{UserID: user.ID, Amount: -8, Reason: models.TransactionWithdrawal}
+	if _, err := wallet.Apply(ctx, op, debit); !errors.Is(err, storage.ErrInsufficientFunds) {
+		t.Fatalf("overdraft: err = %v, want ErrInsufficientFunds", err)
+	}
+	if _, err := store.FindOperation(ctx, op.Kind, op.Key); !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("failed operation stayed claimed: %v", err)
+	}
+
+	debit.Amount = -5
+	if saved, err := wallet.Apply(ctx, op, debit); err != nil || saved.BalanceAfter != 0 {
+		t.Fatalf("retry after failure = %+v, %v", saved, err)
+	}
+}
+
+func TestHoldsReserveAvailableFunds(t *testing.T) {
+	ctx := context.Background()
+	store := storagetest.NewMemoryStore(storagetest.NewFakeClock(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)))
+	user, err := store.CreateUser(ctx, models.User{Username: "cam", Email: "cam@example.com", Role: models.NormalUser, Balance: 100})
+	if err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	events := &recordingPublisher{}
+	relay := outbox.NewRelay(store, events, clock.System{}, config.EventsConfig{})
+	wallet := NewService(store, relay)
+
+	stake, err := wallet.PlaceHold(ctx, user.ID, 60, "bet-1")
+	if err != nil {
+		t.Fatalf("place hold: %v", err)
+	}
+	if _, err := wallet.PlaceHold(ctx, user.ID, 50, "bet-2"); !errors.Is(err, storage.ErrInsufficientFunds) {
+		t.Fatalf("hold beyond the available balance: err = %v, want ErrInsufficientFunds", err)
+	}
+	if _, err := wallet.PlaceHold(ctx, user.ID, 10, "bet-1"); !errors.Is(err, storage.ErrAlreadyExists) {
+		t.Fatalf("second hold for a reference: err = %v, want ErrAlreadyExists", err)
+	}
+	withdrawal := models.Transaction{UserID: user.ID, Amount: -50, Reason: models.TransactionWithdrawal}
+	if _, err := store.ApplyTransaction(ctx, withdrawal); !errors.Is(err, storage.ErrInsufficientFunds) {
+		t.Fatalf("debit of held funds: err = %v, want ErrInsufficientFunds", err)
+	}
+	if got, _ := wallet.Balance(ctx, user.ID); got != (models.WalletBalance{Total: 100, Held: 60, Available: 40}) {
+		t.Fatalf("balance with a hold = %+v", got)
+	}
+
+	captured, err := wallet.CaptureHold(ctx, stake.ID, models.TransactionBetSettlement)
+	if err != nil || captured.Status != models.HoldCaptured || captured.TransactionID == nil {
+		t.Fatalf("capture = %+v, %v", captured, err)
+	}
+	if _, err := wallet.ReleaseHold(ctx, stake.ID); !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("release after capture: err = %v, want ErrNotFound", err)
+	}
+	if got, _ := wallet.Balance(ctx, user.ID); got != (models.WalletBalance{Total: 40, Held: 0, Available: 40}) {
+		t.Fatalf("balance after the capture = %+v", got)
+	}
+	if n, err := relay.Flush(ctx); err != nil || n != 1 {
+		t.Fatalf("relayed %d balance changes (%v), want the capture", n, err)
+	}
+
+	refund, err := wallet.PlaceHold(ctx, user.ID, 40, "bet-3")
+	if err != nil {
+		t.Fatalf("place hold: %v", err)
+	}
+	if released, err := wallet.ReleaseHold(ctx, refund.ID); err != nil || released.Status != models.HoldReleased {
+		t.Fatalf("release = %+v, %v", released, err)
+	}
+	if got, _ := wallet.Balance(ctx, user.ID); got.Available != 40 {
+		t.Fatalf("balance after the release = %+v", got)
+	}
+}
+
+func TestEntriesArePostedToTheJournal(t *testing.T) {
+	ctx := context.Background()
+	store := storagetest.NewMemoryStore(storagetest.NewFakeClock(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)))
+	user, err := store.CreateUser(ctx, models.User{Username: "dee", Email: "dee@example.com", Role: models.NormalUser, Balance: 10})
+	if err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	wallet := NewService(store, nil)
+
+	deposit := models.Transaction{UserID: user.ID, Amount: 20, Reason: models.TransactionDeposit, Reference: "psp:pay-1"}
+	if _, err := wallet.Apply(ctx, Operation{Kind: models.OperationDeposit, Key: "psp:pay-1"}, deposit); err != nil {
+		t.Fatalf("deposit: %v", err)
+	}
+	overdraft := models.Transaction{UserID: user.ID, Amount: -50, Reason: models.TransactionWithdrawal, Reference: "psp:out-1"}
+	if _, err := store.ApplyTransaction(ctx, overdraft); !errors.Is(err, storage.ErrInsufficientFunds) {
+		t.Fatalf("overdraft: err = %v, want ErrInsufficientFunds", err)
+	}
+
+	// The balance the user had before their first entry opens their wallet
+	// against equity; the refused debit posts nothing.
+	lines, err := store.TrialBalance(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	report := models.NewTrialBalance(lines)
+	want := []models.TrialBalanceLine{
+		{Account: models.AccountEquity, Kind: models.AccountEquity, Accounts: 1, Debits: 10, Balance: 10},
+		{Account: "gateway:psp", Kind: models.AccountGateway, Accounts: 1, Debits: 20, Balance: 20},
+		{Account: models.AccountWallet, Kind: models.AccountWallet, Accounts: 1, Credits: 30, Balance: -30},
+	}
+	if !report.Balanced || report.TotalCredits != 30 || !slices.Equal(report.Lines, want) {
+		t.Fatalf("trial balance = %+v", report)
+	}
+}
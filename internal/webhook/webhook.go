@@ -0,0 +1,147 @@
+// Package webhook delivers signed event notifications to endpoints registered
+// by administrators.
+//
+// Each delivery is a JSON POST of an Envelope. The X-Webhook-Signature header
+// has the form "t=<unix seconds>,v1=<hex HMAC-SHA256>", where the MAC is
+// computed with the endpoint secret over "<t>.<raw body>". Receivers should
+// recompute it and reject stale timestamps.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/hongminglow/all-in-be/internal/clock"
+	"github.com/hongminglow/all-in-be/internal/jobs"
+	"github.com/hongminglow/all-in-be/internal/models"
+	"github.com/hongminglow/all-in-be/internal/storage"
+	"github.com/hongminglow/all-in-be/internal/tracing"
+)
+
+// MaxAttempts is how many times a delivery is tried before it is abandoned.
+const MaxAttempts = 5
+
+// Envelope is the body POSTed to endpoints.
+type Envelope struct {
+	ID        string    `json:"id"`
+	Type      string    `json:"type"`
+	CreatedAt time.Time `json:"created_at"`
+	Data      any       `json:"data"`
+}
+
+// Publisher emits domain events to subscribed webhook endpoints.
+type Publisher interface {
+	Publish(ctx context.Context, event string, data any) error
+}
+
+// Dispatcher fans events out to subscribed endpoints and delivers them on the
+// job queue, logging every attempt.
+type Dispatcher struct {
+	store  storage.WebhookStore
+	queue  *jobs.Queue
+	client *http.Client
+	clock  clock.Clock
+	ids    clock.IDGenerator
+}
+
+// NewDispatcher builds a dispatcher. client may be nil for a default with a 10s timeout.
+func NewDispatcher(store storage.WebhookStore, queue *jobs.Queue, client *http.Client, clk clock.Clock, ids clock.IDGenerator) *Dispatcher {
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second, Transport: tracing.Transport(nil)}
+	}
+	return &Dispatcher{store: store, queue: queue, client: client, clock: clk, ids: ids}
+}
+
+// Publish queues event for every active endpoint subscribed to it.
+func (d *Dispatcher) Publish(ctx context.Context, event string, data any) error {
+	endpoints, err := d.store.ListWebhookEndpoints(ctx)
+	if err != nil {
+		return fmt.Errorf("load webhook endpoints: %w", err)
+	}
+	envelope := Envelope{ID: d.ids.NewID(), Type: event, CreatedAt: d.clock.Now().UTC(), Data: data}
+	body, err := json.Marshal(envelope)
+	if err != nil {
+		return fmt.Errorf("encode webhook event: %w", err)
+	}
+	for _, endpoint := range endpoints {
+		if !endpoint.Active || !endpoint.Subscribed(event) {
+			continue
+		}
+		job := jobs.Job{
+			Type:        "webhook",
+			Name:        fmt.Sprintf("webhook %s -> endpoint %d", event, endpoint.ID),
+			MaxAttempts: MaxAttempts,
+			Run: func(ctx context.Context, attempt int) error {
+				return d.deliver(ctx, endpoint, envelope, body, attempt)
+			},
+		}
+		if err := d.queue.Enqueue(job); err != nil {
+			return fmt.Errorf("queue webhook for endpoint %d: %w", endpoint.ID, err)
+		}
+	}
+	return nil
+}
+
+func (d *Dispatcher) deliver(ctx context.Context, endpoint models.WebhookEndpoint, envelope Envelope, body []byte, attempt int) error {
+	start := d.clock.Now()
+	status, sendErr := d.send(ctx, endpoint, envelope, body)
+	record := models.WebhookDelivery{
+		EndpointID: endpoint.ID,
+		EventID:    envelope.ID,
+		Event:      envelope.Type,
+		Payload:    body,
+		Attempt:    attempt,
+		StatusCode: status,
+		Success:    sendErr == nil,
+		DurationMS: d.clock.Now().Sub(start).Milliseconds(),
+	}
+	if sendErr != nil {
+		record.Error = sendErr.Error()
+	}
+	if _, err := d.store.RecordWebhookDelivery(context.Background(), record); err != nil {
+		log.Printf("record webhook delivery for endpoint %d: %v", endpoint.ID, err)
+	}
+	return sendErr
+}
+
+func (d *Dispatcher) send(ctx context.Context, endpoint models.WebhookEndpoint, envelope Envelope, body []byte) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "all-in-webhooks/1")
+	req.Header.Set("X-Webhook-Id", envelope.ID)
+	req.Header.Set("X-Webhook-Event", envelope.Type)
+	req.Header.Set("X-Webhook-Signature", Sign(endpoint.Secret, d.clock.Now().Unix(), body))
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("endpoint responded %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// Sign returns the X-Webhook-Signature value for body sent at timestamp.
+func Sign(secret string, timestamp int64, body []byte) string {
+	ts := strconv.FormatInt(timestamp, 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(ts))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "t=" + ts + ",v1=" + hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,97 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hongminglow/all-in-be/internal/jobs"
+	"github.com/hongminglow/all-in-be/internal/models"
+	"github.com/hongminglow/all-in-be/internal/storage"
+	"github.com/hongminglow/all-in-be/internal/storage/storagetest"
+)
+
+type fakeWebhookStore struct {
+	storage.WebhookStore
+	endpoints []models.WebhookEndpoint
+
+	mu         sync.Mutex
+	deliveries []models.WebhookDelivery
+}
+
+func (s *fakeWebhookStore) ListWebhookEndpoints(context.Context) ([]models.WebhookEndpoint, error) {
+	return s.endpoints, nil
+}
+
+func (s *fakeWebhookStore) RecordWebhookDelivery(_ context.Context, d models.WebhookDelivery) (models.WebhookDelivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.deliveries = append(s.deliveries, d)
+	return d, nil
+}
+
+func TestDispatcherSignsAndRetries(t *testing.T) {
+	var mu sync.Mutex
+	var requests []*http.Request
+	var bodies [][]byte
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		defer mu.Unlock()
+		requests = append(requests, r)
+		bodies = append(bodies, body)
+		if len(requests) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer target.Close()
+
+	store := &fakeWebhookStore{endpoints: []models.WebhookEndpoint{
+		{ID: 1, URL: target.URL, Secret: "s3cret", Events: []string{models.EventUserCreated}, Active: true},
+		{ID: 2, URL: target.URL, Secret: "other", Events: []string{models.EventWalletDeposit}, Active: true},
+		{ID: 3, URL: target.URL, Secret: "off", Events: []string{models.EventUserCreated}, Active: false},
+	}}
+	clk := storagetest.NewFakeClock(time.Unix(1_700_000_000, 0))
+	queue := jobs.NewQueue(1, 8, time.Millisecond)
+	d := NewDispatcher(store, queue, nil, clk, &storagetest.SequentialIDs{Prefix: "evt"})
+
+	if err := d.Publish(context.Background(), models.EventUserCreated, map[string]int64{"user_id": 7}); err != nil {
+		t.Fatalf("publish: %v", err)
+	}
+	if err := queue.Close(context.Background()); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+
+	if len(requests) != 2 {
+		t.Fatalf("expected one failed and one successful attempt, got %d requests", len(requests))
+	}
+	last := requests[1]
+	if got, want := last.Header.Get("X-Webhook-Signature"), Sign("s3cret", 1_700_000_000, bodies[1]); got != want {
+		t.Fatalf("signature = %q, want %q", got, want)
+	}
+	if last.Header.Get("X-Webhook-Id") != "evt-1" || last.Header.Get("X-Webhook-Event") != models.EventUserCreated {
+		t.Fatalf("unexpected headers %v", last.Header)
+	}
+	var envelope Envelope
+	if err := json.Unmarshal(bodies[1], &envelope); err != nil || envelope.Type != models.EventUserCreated {
+		t.Fatalf("bad envelope %s: %v", bodies[1], err)
+	}
+
+	if len(store.deliveries) != 2 {
+		t.Fatalf("expected two logged attempts, got %d", len(store.deliveries))
+	}
+	first, second := store.deliveries[0], store.deliveries[1]
+	if first.Success || first.StatusCode != http.StatusServiceUnavailable || first.Attempt != 1 {
+		t.Fatalf("first attempt = %+v", first)
+	}
+	if !second.Success || second.Attempt != 2 || second.EndpointID != 1 {
+		t.Fatalf("second attempt = %+v", second)
+	}
+}